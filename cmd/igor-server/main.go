@@ -16,6 +16,9 @@ import (
 var (
 	configFilepath = flag.String("config", "", "path to configuration file")
 	version        = flag.Bool("v", false, "version info")
+	migrate        = flag.Bool("migrate", false, "apply pending database schema migrations and exit")
+	migrateDryRun  = flag.Bool("migrate-dry-run", false, "print pending database schema migrations and exit")
+	migrateTo      = flag.Int("migrate-to", 0, "with -migrate or -migrate-dry-run, the schema version to migrate to instead of the latest\n(an older version than the database rolls migrations back)")
 )
 
 func main() {
@@ -26,5 +29,10 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *migrate || *migrateDryRun {
+		igorserver.Migrate(configFilepath, *migrateTo, *migrateDryRun)
+		os.Exit(0)
+	}
+
 	igorserver.Execute(configFilepath)
 }
@@ -15,6 +15,7 @@ import (
 var (
 	configFilepath = flag.String("config", "", "path to configuration file")
 	version        = flag.Bool("v", false, "version info")
+	status         = flag.Bool("status", false, "version info and igor-server health")
 )
 
 func main() {
@@ -25,5 +26,11 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *status {
+		fmt.Println(common.GetVersion("IgorWeb Server", false))
+		igorweb.PrintStatus(configFilepath)
+		os.Exit(0)
+	}
+
 	igorweb.Execute(configFilepath)
 }
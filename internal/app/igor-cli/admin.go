@@ -0,0 +1,743 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorcli
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"igor2/internal/pkg/api"
+	"igor2/internal/pkg/common"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+func newAdminCmd() *cobra.Command {
+
+	cmdAdmin := &cobra.Command{
+		Use:   "admin",
+		Short: "Perform a server maintenance command " + adminOnly,
+		Long: `
+Admin primary command. A sub-command must be invoked to do anything.
+
+Admin commands perform maintenance tasks on the igor server itself.
+
+` + sBold("All admin commands are admin-only.") + `
+`,
+	}
+
+	cmdAdmin.AddCommand(newAdminBackupCmd())
+	cmdAdmin.AddCommand(newAdminFsckCmd())
+	cmdAdmin.AddCommand(newAdminHooksCmd())
+	cmdAdmin.AddCommand(newAdminPxeAuditCmd())
+	cmdAdmin.AddCommand(newAdminSessionsCmd())
+	cmdAdmin.AddCommand(newAdminSupportBundleCmd())
+	cmdAdmin.AddCommand(newAdminRunTaskCmd())
+	cmdAdmin.AddCommand(newAdminSimClockCmd())
+	return cmdAdmin
+}
+
+func newAdminBackupCmd() *cobra.Command {
+
+	cmdBackup := &cobra.Command{
+		Use:   "backup [--to PATH]",
+		Short: "Make a backup of the igor database " + adminOnly,
+		Long: `
+Writes a snapshot of the igor database while the server continues to run. The
+snapshot file is named with the time it was taken, ex. igor-20230415-101500.db
+
+By default the snapshot is written to the backup folder set in the server
+config. After each backup, the oldest snapshots in that folder are removed so
+only the configured number of them are kept.
+
+` + optionalFlags + `
+
+Use the --to flag to write the snapshot to a different folder on the igor
+server. The path must be absolute and the folder must already exist. Snapshots
+written to a different folder are never removed by igor.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			to, _ := flagset.GetString("to")
+			printBackup(doDbBackup(to))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+
+	var to string
+	cmdBackup.Flags().StringVar(&to, "to", "", "server folder to write the backup into")
+	_ = registerFlagArgsFunc(cmdBackup, "to", []string{"PATH"})
+
+	return cmdBackup
+}
+
+func doDbBackup(to string) *common.ResponseBodyBackup {
+
+	params := map[string]interface{}{}
+	if to = strings.TrimSpace(to); to != "" {
+		params["to"] = to
+	}
+
+	body := doSend(http.MethodPost, api.AdminBackup, params)
+	rb := common.ResponseBodyBackup{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func printBackup(rb *common.ResponseBodyBackup) {
+
+	if !rb.IsSuccess() {
+		printRespSimple(rb)
+	}
+
+	backup := rb.Data["backup"]
+	fmt.Printf("backup written to: %s\n", backup.Path)
+	fmt.Printf("size: %d bytes\n", backup.Size)
+	fmt.Printf("time taken: %s\n", backup.Duration)
+	if len(backup.Pruned) > 0 {
+		fmt.Printf("old backups removed: %s\n", strings.Join(backup.Pruned, ", "))
+	}
+}
+
+func newAdminHooksCmd() *cobra.Command {
+
+	cmdHooks := &cobra.Command{
+		Use:   "hooks [--failed]",
+		Short: "Show recent event hook runs " + adminOnly,
+		Long: `
+Shows the most recent runs of event hooks on the igor server, newest first.
+Hooks are executables in the server's hooks folder named for the event that
+runs them: reservation-start, reservation-end, hosts-dropped and
+maintenance-start.
+
+A hook that fails or times out never affects the operation that ran it, so
+this is the place to check whether hooks are working. Each run lists the
+request ID of the operation that caused it, which also appears in the server
+log.
+
+` + optionalFlags + `
+
+Use the --failed flag to only show hook runs that did not succeed.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			failed, _ := flagset.GetBool("failed")
+			printHookStatus(doReadHookStatus(failed))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+
+	var failed bool
+	cmdHooks.Flags().BoolVar(&failed, "failed", false, "only show hook runs that failed")
+
+	return cmdHooks
+}
+
+func doReadHookStatus(failed bool) *common.ResponseBodyHooks {
+
+	apiPath := api.AdminHooks
+	if failed {
+		apiPath += "?failed=true"
+	}
+
+	body := doSend(http.MethodGet, apiPath, nil)
+	rb := common.ResponseBodyHooks{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func printHookStatus(rb *common.ResponseBodyHooks) {
+
+	if !rb.IsSuccess() {
+		printRespSimple(rb)
+	}
+
+	hooks := rb.Data["hooks"]
+	if len(hooks) == 0 {
+		printSimple("no hook runs to show", cRespWarn)
+		return
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"STARTED", "EVENT", "RESERVATION", "REQUEST-ID", "DURATION", "EXIT", "ERROR"})
+
+	for _, h := range hooks {
+		result := "ok"
+		if h.Error != "" {
+			result = h.Error
+			if h.Output != "" {
+				result += "\n" + h.Output
+			}
+		}
+		tw.AppendRow([]interface{}{
+			time.Unix(h.Started, 0).In(cli.tzLoc).Format(common.DateTimeCompactFormat),
+			h.Event,
+			h.Reservation,
+			h.RequestID,
+			h.Duration,
+			h.ExitCode,
+			result,
+		})
+	}
+
+	if simplePrint {
+		tw.Style().Options.SeparateRows = false
+		tw.Style().Options.SeparateColumns = true
+		tw.Style().Options.DrawBorder = false
+	} else {
+		tw.SetStyle(igorTableStyle)
+	}
+
+	fmt.Printf("\n" + tw.Render() + "\n\n")
+}
+
+func newAdminFsckCmd() *cobra.Command {
+
+	cmdFsck := &cobra.Command{
+		Use:   "fsck [--repair]",
+		Short: "Check database consistency " + adminOnly,
+		Long: `
+Checks that the reservations, permissions, hosts and profiles in the igor
+database are consistent with one another, and lists each problem found.
+Problems are reported as:
+
+  missing-owner            a reservation's owner no longer exists
+  missing-group            a reservation's group no longer exists
+  missing-permission       a permission the reservation's owner, co-owners or
+                           group should have is missing
+  orphan-permission        a permission refers to a reservation or host that
+                           doesn't exist, or is a node action permission no
+                           active reservation grants
+  reserved-host            a host is reserved but no active reservation holds it
+  orphan-reservation-host  a reservation host row refers to a deleted
+                           reservation or host
+  orphan-profile           a default profile isn't used by any reservation
+  banned-kernel-arg        a distro, profile or user's default kernel args use
+                           an arg the server now bans
+
+Use the --repair flag to fix the problems that can be fixed safely: missing
+permissions are granted again based on the reservation as it is now, and
+orphan permissions, reservation host rows and default profiles are deleted.
+Repairs are made in a single transaction and each one is logged by the server.
+Reservations missing an owner or group, reserved hosts and banned kernel args
+must be looked at by an admin.
+
+The server runs the check without repairing anything at startup and logs how
+many problems it found.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			repair, _ := cmd.Flags().GetBool("repair")
+			printFsck(doFsck(repair))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+
+	var repair bool
+	cmdFsck.Flags().BoolVar(&repair, "repair", false, "fix the problems that can be fixed safely")
+
+	return cmdFsck
+}
+
+func doFsck(repair bool) *common.ResponseBodyFsck {
+	method := http.MethodGet
+	if repair {
+		method = http.MethodPost
+	}
+	body := doSend(method, api.AdminFsck, nil)
+	rb := common.ResponseBodyFsck{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func printFsck(rb *common.ResponseBodyFsck) {
+
+	if !rb.IsSuccess() {
+		printRespSimple(rb)
+	}
+
+	problems := rb.Data["problems"]
+	if len(problems) == 0 {
+		printSimple("no problems found", cRespSuccess)
+		return
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"PROBLEM", "RESERVATION", "HOST", "GROUP", "ID", "DETAIL", "REPAIRED"})
+
+	for _, p := range problems {
+		id := ""
+		if p.ID != 0 {
+			id = strconv.Itoa(p.ID)
+		}
+		repaired := ""
+		if p.Repaired {
+			repaired = "yes"
+		}
+		tw.AppendRow([]interface{}{
+			p.Problem,
+			p.Reservation,
+			p.Host,
+			p.Group,
+			id,
+			p.Detail,
+			repaired,
+		})
+	}
+
+	if simplePrint {
+		tw.Style().Options.SeparateRows = false
+		tw.Style().Options.SeparateColumns = true
+		tw.Style().Options.DrawBorder = false
+	} else {
+		tw.SetStyle(igorTableStyle)
+	}
+
+	fmt.Printf("\n" + tw.Render() + "\n\n")
+}
+
+func newAdminPxeAuditCmd() *cobra.Command {
+
+	cmdPxeAudit := &cobra.Command{
+		Use:   "pxe-audit",
+		Short: "Check PXE config files against installed reservations " + adminOnly,
+		Long: `
+Compares the PXE config files on the igor server with the ones the installed
+reservations should have, and lists each file that doesn't match. Problems are
+reported as:
+
+  missing            an installed host has no PXE config file
+  untracked          the file is there but igor has no record of writing it
+  wrong-reservation  the file was written for a different reservation
+  modified           the file was changed outside of igor after it was written
+  orphan             the file doesn't belong to any installed reservation
+  stale-record       igor wrote the file but it no longer exists
+
+Before each install igor saves a checksummed copy of the files it is about to
+overwrite in the igor-pxe-backups folder under the TFTP root. Igor also refuses
+to overwrite a PXE config file that belongs to a different active reservation.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printPxeAudit(doReadPxeAudit())
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+
+	return cmdPxeAudit
+}
+
+func doReadPxeAudit() *common.ResponseBodyPxeAudit {
+	body := doSend(http.MethodGet, api.AdminPxeAudit, nil)
+	rb := common.ResponseBodyPxeAudit{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func printPxeAudit(rb *common.ResponseBodyPxeAudit) {
+
+	if !rb.IsSuccess() {
+		printRespSimple(rb)
+	}
+
+	problems := rb.Data["problems"]
+	if len(problems) == 0 {
+		printSimple("no discrepancies found", cRespSuccess)
+		return
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"PATH", "HOST", "RESERVATION", "PROBLEM", "DETAIL"})
+
+	for _, p := range problems {
+		tw.AppendRow([]interface{}{
+			p.Path,
+			p.Host,
+			p.Reservation,
+			p.Problem,
+			p.Detail,
+		})
+	}
+
+	if simplePrint {
+		tw.Style().Options.SeparateRows = false
+		tw.Style().Options.SeparateColumns = true
+		tw.Style().Options.DrawBorder = false
+	} else {
+		tw.SetStyle(igorTableStyle)
+	}
+
+	fmt.Printf("\n" + tw.Render() + "\n\n")
+}
+
+func newAdminSessionsCmd() *cobra.Command {
+
+	cmdSessions := &cobra.Command{
+		Use:   "sessions [--user NAME] | --revoke ID | --revoke-user NAME",
+		Short: "Show or revoke login sessions " + adminOnly,
+		Long: `
+Shows the login tokens the igor server has issued that have not yet expired,
+newest first. Each session lists the user it belongs to, when it was issued,
+when it expires, when it was last used, and whether it was issued to the CLI
+or igorweb along with the address the login came from.
+
+Tokens are only accepted while their session exists. Revoking a session makes
+its token stop working on the next request that uses it, so the user has to
+log in again. Expired sessions and lapsed elevations are removed by the server
+on its own.
+
+` + optionalFlags + `
+
+Use the --user flag to only show the sessions of the named user.
+
+Use the --revoke flag to revoke the session with the given ID.
+
+Use the --revoke-user flag to revoke every session of the named user, such as
+when their laptop is lost or stolen. This also cancels any elevation the user
+holds.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			userName, _ := flagset.GetString("user")
+			revoke, _ := flagset.GetString("revoke")
+			revokeUser, _ := flagset.GetString("revoke-user")
+			if flagset.Changed("revoke") || flagset.Changed("revoke-user") {
+				printRespSimple(doRevokeSessions(revoke, revokeUser))
+			} else {
+				printSessions(doReadSessions(userName))
+			}
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+
+	var userName,
+		revoke,
+		revokeUser string
+
+	cmdSessions.Flags().StringVar(&userName, "user", "", "only show sessions of this user")
+	cmdSessions.Flags().StringVar(&revoke, "revoke", "", "revoke the session with this ID")
+	cmdSessions.Flags().StringVar(&revokeUser, "revoke-user", "", "revoke all sessions of this user")
+	cmdSessions.MarkFlagsMutuallyExclusive("user", "revoke", "revoke-user")
+	_ = registerFlagArgsFunc(cmdSessions, "user", []string{"NAME"})
+	_ = registerFlagArgsFunc(cmdSessions, "revoke", []string{"ID"})
+	_ = registerFlagArgsFunc(cmdSessions, "revoke-user", []string{"NAME"})
+
+	return cmdSessions
+}
+
+func doReadSessions(userName string) *common.ResponseBodySessions {
+
+	apiPath := api.AdminSessions
+	if userName = strings.TrimSpace(userName); userName != "" {
+		apiPath += "?" + url.Values{"user": {userName}}.Encode()
+	}
+
+	body := doSend(http.MethodGet, apiPath, nil)
+	rb := common.ResponseBodySessions{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func doRevokeSessions(sessionID, userName string) *common.ResponseBodyBasic {
+
+	query := url.Values{}
+	if sessionID = strings.TrimSpace(sessionID); sessionID != "" {
+		query.Set("id", sessionID)
+	}
+	if userName = strings.TrimSpace(userName); userName != "" {
+		query.Set("user", userName)
+	}
+
+	body := doSend(http.MethodDelete, api.AdminSessions+"?"+query.Encode(), nil)
+	return unmarshalBasicResponse(body)
+}
+
+func printSessions(rb *common.ResponseBodySessions) {
+
+	if !rb.IsSuccess() {
+		printRespSimple(rb)
+	}
+
+	sessions := rb.Data["sessions"]
+	if len(sessions) == 0 {
+		printSimple("no active sessions to show", cRespWarn)
+		return
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"ID", "USER", "ISSUED", "EXPIRES", "LAST-USED", "SOURCE"})
+
+	for _, s := range sessions {
+		source := s.Source
+		if s.Address != "" {
+			source += " (" + s.Address + ")"
+		}
+		tw.AppendRow([]interface{}{
+			s.ID,
+			s.User,
+			time.Unix(s.Issued, 0).In(cli.tzLoc).Format(common.DateTimeCompactFormat),
+			time.Unix(s.Expires, 0).In(cli.tzLoc).Format(common.DateTimeCompactFormat),
+			time.Unix(s.LastUsed, 0).In(cli.tzLoc).Format(common.DateTimeCompactFormat),
+			source,
+		})
+	}
+
+	if simplePrint {
+		tw.Style().Options.SeparateRows = false
+		tw.Style().Options.SeparateColumns = true
+		tw.Style().Options.DrawBorder = false
+	} else {
+		tw.SetStyle(igorTableStyle)
+	}
+
+	fmt.Printf("\n" + tw.Render() + "\n\n")
+}
+
+func newAdminSimClockCmd() *cobra.Command {
+
+	cmdSimClock := &cobra.Command{
+		Use:   "sim-clock ADVANCE",
+		Short: "Move the scheduler clock forward in simulation mode " + adminOnly,
+		Long: `
+Moves the clock igor's scheduler runs on forward by the given amount. This is
+only available when the server runs in simulation mode, where host power and
+VLANs are simulated so igor can be tried out without a real cluster.
+
+Reservations that end, start or leave maintenance within the skipped time do so
+right away, the same as they would when that time arrives. This lets the whole
+life of a reservation be shown in minutes. The clock can't be moved back.
+
+` + requiredArgs + `
+
+  ADVANCE : how far to move the clock, ex. 2h or 1d12h
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printRespSimple(doAdvanceSimClock(args[0]))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+
+	return cmdSimClock
+}
+
+func doAdvanceSimClock(advance string) *common.ResponseBodyBasic {
+	body := doSend(http.MethodPost, api.AdminSimClock, map[string]interface{}{"advance": advance})
+	return unmarshalBasicResponse(body)
+}
+
+func newAdminRunTaskCmd() *cobra.Command {
+
+	cmdRunTask := &cobra.Command{
+		Use:   "run-task TASK",
+		Short: "Run a periodic server task right away " + adminOnly,
+		Long: `
+Runs one of the tasks igor-server does on a schedule right away instead of
+waiting for its next run, ex. to install reservations after fixing what kept
+them from starting. If a scheduled run of the task is underway it finishes
+first. The task keeps its usual schedule afterward.
+
+` + requiredArgs + `
+
+  TASK : the task to run, ex. installReservations
+
+The tasks, their schedules and how their last runs went are listed at the end
+of 'igor stats'.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printRespSimple(doRunTask(args[0]))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+
+	return cmdRunTask
+}
+
+func doRunTask(task string) *common.ResponseBodyBasic {
+	body := doSend(http.MethodPost, api.AdminTasks, map[string]interface{}{"task": task})
+	return unmarshalBasicResponse(body)
+}
+
+func newAdminSupportBundleCmd() *cobra.Command {
+
+	cmdSupportBundle := &cobra.Command{
+		Use:   "support-bundle [--out FILE] [--log-lines N]",
+		Short: "Gather server diagnostics for a problem report " + adminOnly,
+		Long: `
+Gathers what is needed to look into a problem with the igor server into a
+single bundle: version and build info, the effective server config, the
+database schema version and the row count of each table, a summary of the
+cluster, the most recent warnings and errors from the server log, the status
+of the periodic server tasks and Go runtime stats. It also lists the events
+the server writes to its log, each with a stable name and the fields it
+carries, for use when setting up log alerts.
+
+Passwords and other secrets in the config are masked and email addresses are
+removed from the config and log lines, so the bundle can be passed along with
+a problem report. Only one bundle can be gathered a minute.
+
+` + optionalFlags + `
+
+Use the --out flag to write the bundle to a file instead of printing it. If
+FILE ends in .tar.gz or .tgz, each part of the bundle is written as its own
+JSON file in a compressed archive. Otherwise the bundle is written as a single
+JSON file.
+
+Use the --log-lines flag to set how many of the most recent warning and error
+log lines are included. The default is 100 and the server keeps up to 500.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			out, _ := flagset.GetString("out")
+			lines, _ := flagset.GetInt("log-lines")
+			if !flagset.Changed("log-lines") {
+				lines = -1
+			}
+			writeSupportBundle(doReadDiagnostics(lines), out)
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+
+	var out string
+	var lines int
+	cmdSupportBundle.Flags().StringVar(&out, "out", "", "file to write the bundle to")
+	cmdSupportBundle.Flags().IntVar(&lines, "log-lines", 100, "number of recent warn/error log lines to include")
+	_ = registerFlagArgsFunc(cmdSupportBundle, "out", []string{"FILE"})
+	_ = registerFlagArgsFunc(cmdSupportBundle, "log-lines", []string{"N"})
+
+	return cmdSupportBundle
+}
+
+func doReadDiagnostics(lines int) *common.ResponseBodyDiagnostics {
+
+	apiPath := api.AdminDiagnostics
+	if lines >= 0 {
+		apiPath += "?lines=" + strconv.Itoa(lines)
+	}
+
+	body := doSend(http.MethodGet, apiPath, nil)
+	rb := common.ResponseBodyDiagnostics{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func writeSupportBundle(rb *common.ResponseBodyDiagnostics, out string) {
+
+	if !rb.IsSuccess() {
+		printRespSimple(rb)
+	}
+
+	diag := rb.Data["diagnostics"]
+	lowerOut := strings.ToLower(out)
+
+	switch {
+	case out == "":
+		bundle, err := json.MarshalIndent(diag, "", "  ")
+		checkClientErr(err)
+		fmt.Println(string(bundle))
+		return
+	case strings.HasSuffix(lowerOut, ".tar.gz") || strings.HasSuffix(lowerOut, ".tgz"):
+		checkClientErr(writeSupportBundleTarGz(&diag, out))
+	default:
+		bundle, err := json.MarshalIndent(diag, "", "  ")
+		checkClientErr(err)
+		checkClientErr(os.WriteFile(out, append(bundle, '\n'), 0600))
+	}
+
+	printSimple("support bundle written to "+out, cRespSuccess)
+}
+
+// writeSupportBundleTarGz writes each part of the diagnostics as its own JSON file in a tar.gz
+// archive, under a folder named for the time the bundle was gathered.
+func writeSupportBundleTarGz(diag *common.DiagnosticsData, out string) error {
+
+	generated := time.Unix(diag.Generated, 0)
+	folder := "igor-support-" + generated.Format("20060102-150405")
+	parts := []struct {
+		name string
+		data interface{}
+	}{
+		{"version", map[string]interface{}{"generated": diag.Generated, "version": diag.Version}},
+		{"config", diag.Config},
+		{"database", diag.Database},
+		{"cluster", diag.Cluster},
+		{"log", diag.Log},
+		{"tasks", diag.Tasks},
+		{"runtime", diag.Runtime},
+		{"log-events", diag.Events},
+	}
+
+	f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gzipWriter := gzip.NewWriter(f)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, p := range parts {
+		content, mErr := json.MarshalIndent(p.data, "", "  ")
+		if mErr != nil {
+			return mErr
+		}
+		header := &tar.Header{Name: folder + "/" + p.name + ".json", Mode: 0600, Size: int64(len(content)), ModTime: generated}
+		if err = tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err = tarWriter.Write(content); err != nil {
+			return err
+		}
+	}
+
+	if err = tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
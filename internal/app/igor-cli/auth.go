@@ -5,6 +5,8 @@
 package igorcli
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
@@ -14,6 +16,7 @@ import (
 	"net/http"
 	"os"
 	"os/user"
+	"time"
 )
 
 func newResetSecretCmd() *cobra.Command {
@@ -45,15 +48,29 @@ func doJwtReset() *common.ResponseBodyBasic {
 // CLIENT COMMANDS...
 
 func newLoginCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "login",
+	cmdLogin := &cobra.Command{
+		Use:   "login [--oidc]",
 		Short: "Starts a new auth session",
 		Long: `
 Gets a valid authentication token for the user. This action will ask for the
 user's account credentials when executed.
+
+When igor signs users in through an identity provider, use the --oidc flag
+instead. Igor prints a URL and a code. Open the URL in a browser on any device,
+enter the code and sign in there. The command returns once the sign-in finishes.
+The igor-admin account always logs in with its password and never uses --oidc.
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 
+			if useOidc, _ := cmd.Flags().GetBool("oidc"); useOidc {
+				response, lErr := doOidcLogin()
+				if lErr != nil {
+					return lErr
+				}
+				printRespSimple(response)
+				return nil
+			}
+
 			osUser, osErr := user.Current()
 			if osErr != nil {
 				return osErr
@@ -74,6 +91,8 @@ user's account credentials when executed.
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNoArgs,
 	}
+	cmdLogin.Flags().Bool("oidc", false, "sign in through the identity provider with a device code")
+	return cmdLogin
 }
 
 func doLogin(username string, password string) (*common.ResponseBodyBasic, error) {
@@ -104,6 +123,77 @@ func doLogin(username string, password string) (*common.ResponseBodyBasic, error
 	return unmarshalBasicResponse(&body), nil
 }
 
+// doOidcLogin signs the user in with the OIDC device code flow. The user finishes signing in with the
+// provider in a browser while the client polls igor for the resulting auth token.
+func doOidcLogin() (*common.ResponseBodyBasic, error) {
+
+	req, _ := http.NewRequest(http.MethodPost, cli.IgorServerAddr+api.LoginOidcDevice, nil)
+	setUserAgent(req)
+	resp := sendRequest(req)
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if readErr != nil {
+		checkClientErr(readErr)
+	}
+
+	rb := common.ResponseBodyOidcDevice{}
+	if err := json.Unmarshal(body, &rb); err != nil {
+		checkUnmarshalErr(err)
+	}
+	device, ok := rb.Data["device"]
+	if !rb.IsSuccess() || !ok {
+		return unmarshalBasicResponse(&body), nil
+	}
+
+	if device.VerificationURIComplete != "" {
+		fmt.Printf("To sign in, open %s\nand confirm the code %s\n", device.VerificationURIComplete, device.UserCode)
+	} else {
+		fmt.Printf("To sign in, open %s\nand enter the code %s\n", device.VerificationURI, device.UserCode)
+	}
+
+	interval := time.Duration(device.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+	reqBody, _ := json.Marshal(map[string]string{"deviceCode": device.DeviceCode})
+
+	for time.Now().Before(deadline) {
+
+		time.Sleep(interval)
+
+		req, _ = http.NewRequest(http.MethodPost, cli.IgorServerAddr+api.LoginOidcToken, bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		setUserAgent(req)
+		resp = sendRequest(req)
+		body, readErr = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			checkClientErr(readErr)
+		}
+		if resp.StatusCode == http.StatusAccepted {
+			continue
+		}
+
+		tokenRb := unmarshalBasicResponse(&body)
+		if !tokenRb.IsSuccess() {
+			return tokenRb, nil
+		}
+		lastAccessUser, _ = tokenRb.Data["user"].(string)
+		cookies := resp.Cookies()
+		for i, c := range cookies {
+			if c.Name == "auth_token" {
+				if err := writeAuthToken(cookies[i]); err != nil {
+					return nil, err
+				}
+				if err := writeLastAccessUser(); err != nil {
+					fmt.Printf("%v\n", err)
+				}
+			}
+		}
+		return tokenRb, nil
+	}
+
+	return nil, fmt.Errorf("the sign-in code expired before it was used -- run 'igor login --oidc' again")
+}
+
 // these client commands don't call the server
 
 func newLogoutCmd() *cobra.Command {
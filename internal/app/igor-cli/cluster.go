@@ -5,11 +5,13 @@
 package igorcli
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"igor2/internal/pkg/api"
 	"igor2/internal/pkg/common"
 	"net/http"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -38,6 +40,8 @@ to issue external commands.
 	cmdClusters.AddCommand(newClusterConfigCmd())
 	cmdClusters.AddCommand(newClusterShowCmd())
 	cmdClusters.AddCommand(newClusterUpdateMotdCmd())
+	cmdClusters.AddCommand(newClusterRenamePrefixCmd())
+	cmdClusters.AddCommand(newClusterRenumberCmd())
 	return cmdClusters
 }
 
@@ -74,7 +78,9 @@ Sets (or unsets) a "message of the day" to be displayed on igor clients.
 
 The MESSAGE argument should be a double-quoted string containing the message
 to be displayed when 'igor show' is run. To unset the message use the same 
-command with "" as the argument.
+command with "" as the argument. The message may be up to 1024 characters and
+use limited markdown: [text](https://link), **bold**, *italics* and ` + "`code`" + `.
+It is shown formatted in igorweb and as written here.
 
 ` + optionalFlags + `
 
@@ -118,6 +124,168 @@ func doMotdUpdate(motd string, urgent bool) *common.ResponseBodyBasic {
 	return unmarshalBasicResponse(body)
 }
 
+func newClusterRenamePrefixCmd() *cobra.Command {
+
+	cmdRenamePrefix := &cobra.Command{
+		Use:   "rename-prefix OLD NEW [--dry-run] [-x]",
+		Short: "Change the cluster host name prefix " + adminOnly,
+		Long: `
+Changes the prefix of the cluster from OLD to NEW and renames every host to
+match, ex. kn1 becomes jn1. Host and console names that were given custom
+values in the cluster config are kept. Permissions that name the hosts and
+the PXE ledger are updated in the same step, then 'igor-clusters.yaml' is
+rewritten after a backup is made of the old file. Reservation history is not
+changed.
+
+The change is refused while any reservation is being installed. Restart
+igor-server afterward so power status checks use the new host names.
+
+` + optionalFlags + `
+
+Use the --dry-run flag to list every record that would change without
+changing anything.
+
+Use the -x flag to render screen output without pretty formatting.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			dryRun, _ := flagset.GetBool("dry-run")
+			simplePrint = flagset.Changed("simple")
+			printClusterChanges(doRenameClusterPrefix(args[0], args[1], dryRun))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return []string{"OLD"}, cobra.ShellCompDirectiveNoFileComp
+			} else if len(args) == 1 {
+				return []string{"NEW"}, cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+
+	cmdRenamePrefix.Flags().Bool("dry-run", false, "list the changes without making them")
+	cmdRenamePrefix.Flags().BoolVarP(&simplePrint, "simple", "x", false, "use simple text output")
+	return cmdRenamePrefix
+}
+
+func newClusterRenumberCmd() *cobra.Command {
+
+	cmdRenumber := &cobra.Command{
+		Use:   "renumber --map FILE [--dry-run] [-x]",
+		Short: "Change the sequence numbers of cluster hosts " + adminOnly,
+		Long: `
+Gives hosts new sequence numbers and renames them to match, ex. kn5 becomes
+kn12. The FILE has one host per line with its current number followed by its
+new one, separated by spaces. Blank lines and lines starting with # are
+ignored. Numbers can be swapped in the same file.
+
+  # rack 2 was re-cabled
+  5 12
+  6 5
+
+Permissions that name the hosts and the PXE ledger are updated in the same
+step, then 'igor-clusters.yaml' is rewritten after a backup is made of the old
+file. Reservation history is not changed.
+
+The change is refused while any reservation is being installed. Restart
+igor-server afterward so power status checks use the new host names.
+
+` + requiredFlags + `
+
+Use the --map flag to give the path of the FILE.
+
+` + optionalFlags + `
+
+Use the --dry-run flag to list every record that would change without
+changing anything.
+
+Use the -x flag to render screen output without pretty formatting.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			mapFile, _ := flagset.GetString("map")
+			dryRun, _ := flagset.GetBool("dry-run")
+			simplePrint = flagset.Changed("simple")
+			seqMap, err := readRenumberMap(mapFile)
+			checkClientErr(err)
+			printClusterChanges(doRenumberClusterHosts(seqMap, dryRun))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+
+	cmdRenumber.Flags().String("map", "", "file of current and new host numbers")
+	cmdRenumber.Flags().Bool("dry-run", false, "list the changes without making them")
+	cmdRenumber.Flags().BoolVarP(&simplePrint, "simple", "x", false, "use simple text output")
+	_ = cmdRenumber.MarkFlagRequired("map")
+	return cmdRenumber
+}
+
+// readRenumberMap reads a file of 'OLD NEW' host sequence number pairs.
+func readRenumberMap(path string) (map[string]int, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	seqMap := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s line %d: expected 'OLD NEW' but found '%s'", path, lineNum, line)
+		}
+		oldSeq, oErr := strconv.Atoi(fields[0])
+		newSeq, nErr := strconv.Atoi(fields[1])
+		if oErr != nil || nErr != nil {
+			return nil, fmt.Errorf("%s line %d: host numbers must be whole numbers, found '%s'", path, lineNum, line)
+		}
+		key := strconv.Itoa(oldSeq)
+		if _, dup := seqMap[key]; dup {
+			return nil, fmt.Errorf("%s line %d: host %d is renumbered more than once", path, lineNum, oldSeq)
+		}
+		seqMap[key] = newSeq
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(seqMap) == 0 {
+		return nil, fmt.Errorf("%s has no hosts to renumber", path)
+	}
+	return seqMap, nil
+}
+
+func doRenameClusterPrefix(oldPrefix, newPrefix string, dryRun bool) *common.ResponseBodyClusterChanges {
+	params := map[string]interface{}{"old": oldPrefix, "new": newPrefix, "dryRun": dryRun}
+	body := doSend(http.MethodPatch, api.ClusterRenamePfx, params)
+	rb := common.ResponseBodyClusterChanges{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func doRenumberClusterHosts(seqMap map[string]int, dryRun bool) *common.ResponseBodyClusterChanges {
+	params := map[string]interface{}{"map": seqMap, "dryRun": dryRun}
+	body := doSend(http.MethodPatch, api.ClusterRenumber, params)
+	rb := common.ResponseBodyClusterChanges{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
 func newClusterShowCmd() *cobra.Command {
 
 	cmdShowClusters := &cobra.Command{
@@ -130,8 +298,9 @@ Shows information about a cluster's metadata (not its nodes).
 
 Use the -d flag to write the current configuration of the cluster as stored in
 the database to a new 'igor-clusters.yaml' file, storing the old version as a
-timestamped backup file in the same directory. You will still get the normal
-output display on the terminal along with a message about file creation.
+backup file in the same directory named with the time, a sequence number and
+your user name. You will still get the normal output display on the terminal
+along with a message about file creation.
 
 Use the -x flag to render screen output without pretty formatting.
 
@@ -300,3 +469,35 @@ func printClusters(rb *common.ResponseBodyClusters) {
 	}
 
 }
+
+func printClusterChanges(rb *common.ResponseBodyClusterChanges) {
+
+	if !rb.IsSuccess() {
+		printRespSimple(rb)
+	}
+
+	checkColorLevel()
+
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"RECORD", "NAME", "FIELD", "OLD", "NEW"})
+	for _, c := range rb.Data["changes"] {
+		tw.AppendRow([]interface{}{
+			c.Record,
+			c.Name,
+			c.Field,
+			c.Old,
+			c.New,
+		})
+	}
+
+	if simplePrint {
+		tw.Style().Options.SeparateRows = false
+		tw.Style().Options.SeparateColumns = true
+		tw.Style().Options.DrawBorder = false
+	} else {
+		tw.SetStyle(igorTableStyle)
+	}
+
+	fmt.Printf("\n" + tw.Render() + "\n\n")
+	printRespSimple(rb)
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorcli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRenumberMap(t *testing.T) {
+
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "map.txt")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	seqMap, err := readRenumberMap(write("# swap two hosts\n5 6\n\n  6   5  \n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"5": 6, "6": 5}, seqMap)
+
+	_, err = readRenumberMap(write("5\n"))
+	assert.ErrorContains(t, err, "line 1")
+	_, err = readRenumberMap(write("5 kn6\n"))
+	assert.ErrorContains(t, err, "whole numbers")
+	_, err = readRenumberMap(write("5 6\n5 7\n"))
+	assert.ErrorContains(t, err, "more than once")
+	_, err = readRenumberMap(write("# nothing\n"))
+	assert.ErrorContains(t, err, "no hosts")
+	_, err = readRenumberMap(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
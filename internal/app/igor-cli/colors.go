@@ -5,6 +5,8 @@
 package igorcli
 
 import (
+	"os"
+
 	"github.com/gookit/color"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
@@ -19,6 +21,7 @@ const (
 	BgResYes     = 2   // node reserved and writable (green)
 	BgResNo      = 5   // node reserved and not writable (magenta)
 	BgBlocked    = 3   // node blocked (yellow)
+	BgDraining   = 130 // node draining (dark orange)
 	BgRestricted = 213 // node restricted from user (bright pink)
 	BgError      = 75  // node install error (bright cyan)
 )
@@ -30,11 +33,16 @@ var (
 	// no ANSI color coding.
 	simplePrint bool
 
+	// noHeader suppresses the server time and MOTD lines and table titles so output can be compared
+	// between runs.
+	noHeader bool
+
 	cUnreservedUp      = color.S256(FgUp, BgUnreserved)
 	cUnreservedDown    = color.S256(FgDown, BgUnreserved).AddOpts(color.OpBold)
 	cUnreservedPowerNA = color.S256(FgPowerNA, BgUnreserved).AddOpts(color.OpBold)
 	cInstError         = color.S256(FgUp, BgError).AddOpts(color.OpBold)
 	cBlockedUp         = color.S256(FgUp, BgBlocked).AddOpts(color.OpBold)
+	cDrainingUp        = color.S256(FgUp, BgDraining).AddOpts(color.OpBold)
 	cRestrictedUp      = color.S256(FgUp, BgRestricted)
 
 	cOwnerRes = color.S256(15, 2)
@@ -93,10 +101,25 @@ func sItalic(text string) string {
 	return color.OpItalic.Sprint(text)
 }
 
+// colorDisabled returns true if output should not use color, either because the user asked for
+// plain output or because stdout is not a terminal that supports it.
+func colorDisabled() bool {
+	return simplePrint || noColor || envNoColor || !stdoutIsTerminal() || color.TermColorLevel() == color.LevelNo
+}
+
+// stdoutIsTerminal returns true if stdout is a terminal rather than a pipe or file.
+func stdoutIsTerminal() bool {
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
 // checkColorLevel turn off terminal color if not supported
 func checkColorLevel() {
 
-	if simplePrint || noColor || envNoColor || color.TermColorLevel() == color.LevelNo {
+	if colorDisabled() {
 		text.DisableColors()
 		color.Disable()
 	}
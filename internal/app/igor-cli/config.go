@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"igor2/internal/pkg/api"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -27,12 +28,17 @@ type Config struct {
 		Port uint16 `yaml:"port"`
 	} `yaml:"server"`
 	Client struct {
-		CertFile      string `yaml:"certFile"`
-		KeyFile       string `yaml:"keyFile"`
-		CaCert        string `yaml:"caCert"`
-		Timezone      string `yaml:"timezone"`
-		AuthLocal     *bool  `yaml:"authLocal"`
-		PasswordLabel string `yaml:"passwordLabel"`
+		CertFile           string `yaml:"certFile"`
+		KeyFile            string `yaml:"keyFile"`
+		CaCert             string `yaml:"caCert"`
+		CaBundle           string `yaml:"caBundle"`
+		Proxy              string `yaml:"proxy"`
+		InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
+		Timezone           string `yaml:"timezone"`
+		AuthLocal          *bool  `yaml:"authLocal"`
+		PasswordLabel      string `yaml:"passwordLabel"`
+		DateFormat         string `yaml:"dateFormat"`
+		ConfirmDelete      bool   `yaml:"confirmDelete"`
 	} `yaml:"client"`
 }
 
@@ -122,33 +128,53 @@ func initConfigCheck() {
 func newServerConfigCmd() *cobra.Command {
 
 	cmdConfig := &cobra.Command{
-		Use:   "settings",
+		Use:   "settings [-a | --nodes NODES]",
 		Short: "View igor server settings",
 		Long: `
 Displays igor-server settings. The output is in JSON format.
+
+Use --nodes to also see the longest reservation you can make on a set of
+nodes, given as a range (ex. kn[1-16]). This is the same limit applied when
+you create or extend a reservation on those nodes: the smallest time limit
+among the host policies of the nodes, capped by the server's maximum
+reservation time. The time limit of each host policy involved is listed. If
+you are logged in and a policy gives one of your groups its own time limit,
+that limit is used and the group is named. A policy that caps how many of its
+hosts one user can hold at once also shows the cap as maxPerUser.
 `,
 		Args: cobra.NoArgs,
 		Run: func(cmd *cobra.Command, _ []string) {
 			flagset := cmd.Flags()
 			setAll := flagset.Changed("all")
-			doServerConfig(setAll)
+			nodes, _ := flagset.GetString("nodes")
+			if setAll && nodes != "" {
+				checkClientErr(fmt.Errorf("--all and --nodes cannot be used together"))
+			}
+			doServerConfig(setAll, nodes)
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNoArgs,
 	}
 
 	var allSettings bool
+	var nodes string
 	cmdConfig.Flags().BoolVarP(&allSettings, "all", "a", false, "get complete settings config "+adminOnly)
+	cmdConfig.Flags().StringVar(&nodes, "nodes", "", "show the max reservation time for a range of nodes")
+	_ = registerFlagArgsFunc(cmdConfig, "nodes", []string{"NODES"})
 
 	return cmdConfig
 }
 
-func doServerConfig(setAll bool) {
+func doServerConfig(setAll bool, nodes string) {
 
 	var body *[]byte
 
 	if setAll {
 		body = doSend(http.MethodGet, api.Config, nil)
+	} else if nodes != "" {
+		params := url.Values{}
+		params.Set("nodes", nodes)
+		body = doSend(http.MethodGet, api.PublicSettings+"?"+params.Encode(), nil)
 	} else {
 		body = doSend(http.MethodGet, api.PublicSettings, nil)
 	}
@@ -0,0 +1,113 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorcli
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	dateFormatUS  = "us"
+	dateFormatEU  = "eu"
+	dateFormatISO = "iso"
+)
+
+// dateFormatFlag holds the per-invocation date format override set on the root command.
+var dateFormatFlag string
+
+// resolveDateFormat determines the style used to print reservation dates. A format given as a flag wins
+// over the dateFormat setting in the config file. The default is the US style of month before day.
+func resolveDateFormat(flagFmt, confFmt string) (string, error) {
+
+	dateFmt, source := strings.TrimSpace(flagFmt), srcFlag
+	if dateFmt == "" {
+		dateFmt, source = strings.TrimSpace(confFmt), srcConfig
+	}
+
+	switch strings.ToLower(dateFmt) {
+	case "":
+		return dateFormatUS, nil
+	case dateFormatUS:
+		return dateFormatUS, nil
+	case dateFormatEU:
+		return dateFormatEU, nil
+	case dateFormatISO:
+		return dateFormatISO, nil
+	default:
+		return "", fmt.Errorf("unrecognized date format '%s' from %s - must be one of %s, %s or %s",
+			dateFmt, source, dateFormatISO, dateFormatUS, dateFormatEU)
+	}
+}
+
+// cliDateFormat returns the date format for this invocation of the client, exiting if it isn't valid.
+func cliDateFormat() string {
+	dateFmt, err := resolveDateFormat(dateFormatFlag, cli.Client.DateFormat)
+	checkClientErr(err)
+	return dateFmt
+}
+
+// showTimeLayouts returns the layouts for the three parts of a reservation time printed by the show
+// command: the leading part, the middle part (which carries yearFmt when needed), and the clock. The
+// parts are kept separate so the show command can pad them into columns.
+func showTimeLayouts(dateFmt string, yearFmt string, simple bool) (lead, middle, clock string) {
+
+	switch dateFmt {
+	case dateFormatISO:
+		if simple {
+			return "", "2006-01-02T", "15:04"
+		}
+		return "", "2006-01-02 ", "15:04"
+	case dateFormatEU:
+		if simple {
+			return "02-", "Jan-06.", "15:04"
+		}
+		return "02 ", "Jan " + yearFmt, "15:04"
+	default:
+		if simple {
+			return "Jan-", "02-06.", "15:04"
+		}
+		return "Jan ", "2 " + yearFmt, "3:04 PM"
+	}
+}
+
+// resTimeLayouts returns the layouts used for reservation start and end times when listing reservations.
+// The start layout of the table includes the time zone. Reservations that end more than a year from now
+// are printed with the year.
+func resTimeLayouts(dateFmt string, farOut bool, simple bool) (start, end string) {
+
+	switch dateFmt {
+	case dateFormatISO:
+		if simple {
+			if farOut {
+				return "2006-01-02T15:04-07:00", "2006-01-02T15:04-07:00"
+			}
+			return "2006-01-02T15:04", "2006-01-02T15:04"
+		}
+		return "2006-01-02 15:04 MST", "2006-01-02 15:04"
+	case dateFormatEU:
+		if simple {
+			if farOut {
+				return "02-Jan-06.15:04-07:00", "02-Jan-06.15:04-07:00"
+			}
+			return "2 Jan 15:04", "2 Jan 15:04"
+		}
+		if farOut {
+			return "2 Jan 2006 15:04 MST", "2 Jan 2006 15:04"
+		}
+		return "2 Jan 15:04 MST", "2 Jan 15:04"
+	default:
+		if simple {
+			if farOut {
+				return "Jan-02-06.15:04-07:00", "Jan-02-06.15:04-07:00"
+			}
+			return "Jan 2 3:04 PM", "Jan 2 3:04 PM"
+		}
+		if farOut {
+			return "Jan 2 2006 3:04 PM MST", "Jan 2 2006 3:04 PM"
+		}
+		return "Jan 2 3:04 PM MST", "Jan 2 3:04 PM"
+	}
+}
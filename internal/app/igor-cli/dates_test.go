@@ -0,0 +1,52 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorcli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDateFormat(t *testing.T) {
+
+	// flag beats config
+	dateFmt, err := resolveDateFormat("ISO", "eu")
+	assert.NoError(t, err)
+	assert.Equal(t, dateFormatISO, dateFmt)
+
+	dateFmt, err = resolveDateFormat("", "eu")
+	assert.NoError(t, err)
+	assert.Equal(t, dateFormatEU, dateFmt)
+
+	dateFmt, err = resolveDateFormat("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, dateFormatUS, dateFmt)
+
+	_, err = resolveDateFormat("", "uk")
+	assert.ErrorContains(t, err, "config")
+}
+
+func TestDateLayouts(t *testing.T) {
+
+	dts := time.Date(2024, time.February, 3, 14, 30, 0, 0, time.UTC)
+
+	start, end := resTimeLayouts(dateFormatISO, false, false)
+	assert.Equal(t, "2024-02-03 14:30 UTC", dts.Format(start))
+	assert.Equal(t, "2024-02-03 14:30", dts.Format(end))
+
+	start, _ = resTimeLayouts(dateFormatEU, false, false)
+	assert.Equal(t, "3 Feb 14:30 UTC", dts.Format(start))
+
+	start, _ = resTimeLayouts(dateFormatUS, false, false)
+	assert.Equal(t, "Feb 3 2:30 PM UTC", dts.Format(start))
+
+	lead, middle, clock := showTimeLayouts(dateFormatISO, "", false)
+	assert.Equal(t, "2024-02-03 14:30", dts.Format(lead+middle+clock))
+
+	lead, middle, clock = showTimeLayouts(dateFormatEU, "2006 ", false)
+	assert.Equal(t, "03 Feb 2024 14:30", dts.Format(lead+middle+clock))
+}
@@ -8,17 +8,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"igor2/internal/pkg/api"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 
 	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func newDistroCmd() *cobra.Command {
@@ -49,7 +54,9 @@ func newDistroCreateCmd() *cobra.Command {
 		Use: "create NAME {--copy-distro DISTRO | --use-distro-image DISTRO |\n" +
 			"              --kernel PATH/TO/KFILE.KERNEL --initrd PATH/TO/IFILE.INITRD |\n" +
 			" 			   --kstaged FILENAME.KERNEL --istaged FILENAME.INITRD |\n" +
+			"              --kernel-url URL --initrd-url URL |\n" +
 			" 			   -d FOLDER/PATH} | --image-ref IMAGEREF} \n" +
+			"               [--copy-fields FIELD1,... | --no-copy-desc --no-copy-kargs --no-copy-kickstart]\n" +
 			"               [-g GRP1...] [--kickstart KICKSTART]\n" +
 			"              [-k KARGS]  [-p PUBLIC] [--desc \"DESCRIPTION\"]",
 		Short: "Create a distro",
@@ -76,20 +83,35 @@ another igor user if desired.
   --kernel/--initrd : The full path to the kernel and initrd files to be uploaded
       and registered to use in the new distro. This assumes the upload feature
       has been enabled in the configuration. Files must have extension names
-      .kernel and .initrd respectively.
+      .kernel and .initrd respectively, optionally followed by .xz or .zst if
+      compressed with xz or zstd. The server decompresses them.
   --kstaged/--istaged : the file names of the kernel and initrd files
 	  that have been placed in the igor_staged_images path by the admin.
+  --kernel-url/--initrd-url : HTTPS URLs of the kernel and initrd files for
+      the server to download and register. This assumes the upload feature
+      has been enabled and the host is one the server allows images to be
+      downloaded from. Use --kernel-sha256/--initrd-sha256 to give the
+      digests the downloaded files must match.
   -d : path to the folder containing the distribution if local install
   --copy-distro : The name of an existing distro to base the new distro on.
-      User must be the owner of the existing distro. New distro will inherit
-      the description, image, kickstart script, and kernel args of the existing
-      distro.
+      User must be the owner of the existing distro or it must be public. New
+      distro will inherit the description, image, kickstart script, and kernel
+      args of the existing distro unless told otherwise (see below).
   --use-distro-image : The name of an existing distro to base the new distro's
       image on. User must be the owner of the existing distro. New distro will
       inherit only the image of the existing distro.
 
 ` + optionalFlags + `
 
+When using --copy-distro, use the --copy-fields flag to list the attributes to
+inherit from the existing distro, chosen from description, image, kickstart and
+kernelArgs. The image is always inherited. Or use --no-copy-desc, --no-copy-kargs
+or --no-copy-kickstart to leave out single attributes. A description, kernel
+args or kickstart given with their own flags replace the inherited ones. The
+kickstart of a public distro owned by someone else is only inherited if you
+own the kickstart. The response lists which attributes were inherited and
+which were given.
+
 Use the -k flag to add kernel arguments to this distro. These should be ` + sItalic("critical") + `
 for the image to boot properly. Any additional or optional kernel args should
 be associated to a profile created using the distro. Use 'igor profile create 
@@ -136,21 +158,37 @@ team.
 			public, _ := flagset.GetBool("public")
 			isDefault, _ := flagset.GetBool("default")
 			kickstart, _ := flagset.GetString("kickstart")
-			res, err := doCreateDistro(args[0], kernel, initrd, kstaged, istaged, dpath, copyDistro, useDistroImage, imageRef, desc, groups, kargs, kickstart, public, isDefault)
+			copyFields, cfErr := distroCopyFields(flagset)
+			if cfErr != nil {
+				return cfErr
+			}
+			kurl, _ := flagset.GetString("kernel-url")
+			iurl, _ := flagset.GetString("initrd-url")
+			ksha, _ := flagset.GetString("kernel-sha256")
+			isha, _ := flagset.GetString("initrd-sha256")
+			if len(copyFields) > 0 && copyDistro == "" {
+				return fmt.Errorf("--copy-fields and --no-copy-* flags can only be used with --copy-distro")
+			}
+			res, err := doCreateDistro(args[0], kernel, initrd, kstaged, istaged, kurl, iurl, ksha, isha, dpath, copyDistro, copyFields, useDistroImage, imageRef, desc, groups, kargs, kickstart, public, isDefault)
 			if err != nil {
 				return err
 			}
-			printRespSimple(res)
+			printDistroCopied(res)
+			printImageFetched(res)
 			return nil
 		},
 		DisableFlagsInUseLine: true,
-		ValidArgsFunction:     validateNameArg,
+		ValidArgsFunction:     validateNewNameArg(naming.Distro),
 	}
 
 	var kernel,
 		initrd,
 		kstaged,
 		istaged,
+		kurl,
+		iurl,
+		ksha,
+		isha,
 		dpath,
 		copyDistro,
 		useDistroImage,
@@ -158,15 +196,26 @@ team.
 		desc,
 		kargs,
 		kickstart string
-	var groups []string
+	var groups, copyFields []string
 
 	cmdCreateDistro.Flags().StringVar(&kernel, "kernel", "", "full local path to a .kernel file")
 	cmdCreateDistro.Flags().StringVar(&initrd, "initrd", "", "full local path to a .initrd file")
 	cmdCreateDistro.Flags().StringVar(&kstaged, "kstaged", "", "name of the .kernel file already placed in the staged_images folder on the Igor server")
 	cmdCreateDistro.Flags().StringVar(&istaged, "istaged", "", "name of the .initrd file already placed in the staged_images folder on the Igor server")
+	cmdCreateDistro.Flags().StringVar(&kurl, "kernel-url", "", "HTTPS URL the server downloads the kernel file from")
+	cmdCreateDistro.Flags().StringVar(&iurl, "initrd-url", "", "HTTPS URL the server downloads the initrd file from")
+	cmdCreateDistro.Flags().StringVar(&ksha, "kernel-sha256", "", "expected sha256 digest of the downloaded kernel file")
+	cmdCreateDistro.Flags().StringVar(&isha, "initrd-sha256", "", "expected sha256 digest of the downloaded initrd file")
 	cmdCreateDistro.Flags().StringVarP(&dpath, "distro", "d", "", "path to the distro folder to upload")
 	// cmdCreateDistro.Flags().StringSlice("boot", boot, "the compatible boot system to use the image with ['bios','uefi']")
 	cmdCreateDistro.Flags().StringVar(&copyDistro, "copy-distro", "", "name of an already existing distro to duplicate")
+	cmdCreateDistro.Flags().StringSliceVar(&copyFields, "copy-fields", nil, "attributes to inherit with --copy-distro (description,image,kickstart,kernelArgs)")
+	cmdCreateDistro.Flags().Bool("no-copy-desc", false, "don't inherit the description with --copy-distro")
+	cmdCreateDistro.Flags().Bool("no-copy-kargs", false, "don't inherit the kernel args with --copy-distro")
+	cmdCreateDistro.Flags().Bool("no-copy-kickstart", false, "don't inherit the kickstart with --copy-distro")
+	cmdCreateDistro.MarkFlagsMutuallyExclusive("copy-fields", "no-copy-desc")
+	cmdCreateDistro.MarkFlagsMutuallyExclusive("copy-fields", "no-copy-kargs")
+	cmdCreateDistro.MarkFlagsMutuallyExclusive("copy-fields", "no-copy-kickstart")
 	cmdCreateDistro.Flags().StringVar(&useDistroImage, "use-distro-image", "", "name of an already existing distro to use image from")
 	cmdCreateDistro.Flags().StringVar(&imageRef, "image-ref", "", "the image reference ID (provided by admin)")
 	cmdCreateDistro.Flags().StringVar(&desc, "desc", "", "description of the distro")
@@ -179,6 +228,7 @@ team.
 	_ = cmdCreateDistro.MarkFlagFilename("initrd", "initrd")
 	_ = registerFlagArgsFunc(cmdCreateDistro, "copy-distro", []string{"DIST"})
 	_ = registerFlagArgsFunc(cmdCreateDistro, "use-distro-image", []string{"DIST"})
+	_ = registerFlagArgsFunc(cmdCreateDistro, "copy-fields", []string{"FIELD1"})
 	_ = registerFlagArgsFunc(cmdCreateDistro, "image-ref", []string{"IMAGEREF"})
 	_ = registerFlagArgsFunc(cmdCreateDistro, "desc", []string{"\"DESCRIPTION\""})
 	_ = registerFlagArgsFunc(cmdCreateDistro, "groups", []string{"GRP1"})
@@ -192,7 +242,8 @@ func newDistroShowCmd() *cobra.Command {
 
 	cmdShowDistros := &cobra.Command{
 		Use: "show [-n NAME1,...] [-o OWNER1,...] [-g GRP1,...] [--image-ids ID1,...]\n" +
-			"       [--kernels KERN1,...] [--initrds INIT1,...] [-x] [--default]",
+			"       [--kernels KERN1,...] [--initrds INIT1,...] [-x] [--default] [--verify]\n" +
+			"       [--unused DURATION]",
 		Short: "Show distro information",
 		Long: `
 Shows distro information, returning matches to specified parameters. If no
@@ -207,6 +258,20 @@ Use the -n, -o, -g, --image-ids, --kernel and --initrd flags to narrow results.
 Multiple values for a given flag should be comma-delimited.
 
 Use the -x flag to render screen output without pretty formatting.
+
+Use the --verify flag to check that the image files each distro boots from are
+still present on the igor server. The output will include the size and last
+modified time of each file. Missing files are flagged and the command exits
+with a non-zero status if any are found. For admins, file sizes are also
+compared to the sizes recorded when the image was registered, if available.
+
+Use the --unused flag to list only the distros that no reservation has been
+created or reimaged with for the given duration (ex. 180d), that no existing
+reservation uses and that no profile made in that time points to. These are
+candidates to delete to free space in the image store.
+
+Admins and distro owners will also see how many times each distro has been
+used and when it was last used.
 `,
 		Args: cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -219,7 +284,9 @@ Use the -x flag to render screen output without pretty formatting.
 			initrds, _ := flagset.GetStringSlice("initrds")
 			byDefault, _ := flagset.GetBool("default")
 			simplePrint = flagset.Changed("simple")
-			printDistros(doShowDistros(names, owners, groups, imageIDs, kernels, initrds, byDefault))
+			verify := flagset.Changed("verify")
+			unused, _ := flagset.GetString("unused")
+			printDistros(doShowDistros(names, owners, groups, imageIDs, kernels, initrds, byDefault, verify, unused), verify)
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNoArgs,
@@ -239,6 +306,8 @@ Use the -x flag to render screen output without pretty formatting.
 	cmdShowDistros.Flags().StringSliceVar(&kernels, "kernels", nil, "search by kernel file(s)")
 	cmdShowDistros.Flags().StringSliceVar(&initrds, "initrds", nil, "search by initrd file(s)")
 	cmdShowDistros.Flags().Bool("default", false, "show default distro")
+	cmdShowDistros.Flags().Bool("verify", false, "check that image files are present on the server")
+	cmdShowDistros.Flags().String("unused", "", "only show distros not used by any reservation for this long (ex. 180d)")
 	cmdShowDistros.Flags().BoolVarP(&simplePrint, "simple", "x", false, "use simple text output")
 	_ = registerFlagArgsFunc(cmdShowDistros, "names", []string{"NAME1"})
 	_ = registerFlagArgsFunc(cmdShowDistros, "owners", []string{"OWNER1"})
@@ -246,6 +315,7 @@ Use the -x flag to render screen output without pretty formatting.
 	_ = registerFlagArgsFunc(cmdShowDistros, "image-ids", []string{"ID1"})
 	_ = registerFlagArgsFunc(cmdShowDistros, "kernels", []string{"KERN1"})
 	_ = registerFlagArgsFunc(cmdShowDistros, "initrds", []string{"INIT1"})
+	_ = registerFlagArgsFunc(cmdShowDistros, "unused", []string{"DURATION"})
 
 	return cmdShowDistros
 }
@@ -253,8 +323,8 @@ Use the -x flag to render screen output without pretty formatting.
 func newDistroEditCmd() *cobra.Command {
 
 	cmdEditDistro := &cobra.Command{
-		Use: "edit NAME { [-n NEWNAME | -o OWNER | -a GRP1,... | -r GRP1,... |\n" +
-			"       -k KARGS | --desc \"DESCRIPTION\" | -p ] }",
+		Use: "edit NAME { [-n NEWNAME | -o OWNER | -a GRP1,... | -r GRP1,... [--notify] |\n" +
+			"       -k KARGS | --desc \"DESCRIPTION\" | --notes-file FILE | -p ] }",
 		Short: "Edit distro information",
 		Long: `
 Edits distro information. This can only be done by the distro owner or an admin.
@@ -277,6 +347,18 @@ OS image.
 Use the -a and -r flags to add or remove groups from distro access respectively.
 Separate multiple group names with commas.
 
+If removing groups leaves the owners of running or future reservations using
+the distro without access to it, those reservations are listed in a warning.
+The reservations keep running but their owners can't use the distro again.
+Add the --notify flag to also email those owners.
+
+Use the --notes-file flag to set usage notes that tell users how to get started
+with the distro, such as its default login. The file is markdown and can be up
+to 8KB. Paragraphs, # headings, - bullet lists, ` + "```" + ` code blocks, ` + "`code`" + ` and
+**bold** are supported and any HTML is removed. The notes are included in the
+email sent when a reservation using the distro starts and are shown by
+'igor distro show -x' and 'igor res bootinfo'. Use an empty file to remove them.
+
 Use the -p flag to change this distro to public, allowing anyone to use it. The
 distro will be owned by igor-admin and can only be modified or deleted by the
 admin team. This is a permanent change.
@@ -307,7 +389,17 @@ distro.
 			public, _ := flagset.GetBool("public")
 			isDefault, _ := flagset.GetBool("default")
 			defaultRemove, _ := flagset.GetBool("default-remove")
-			printRespSimple(doEditDistro(args[0], name, owner, desc, add, remove, kargs, public, isDefault, defaultRemove))
+			notify, _ := flagset.GetBool("notify")
+			var notes *string
+			if notesFile, _ := flagset.GetString("notes-file"); notesFile != "" {
+				content, err := os.ReadFile(notesFile)
+				if err != nil {
+					checkClientErr(err)
+				}
+				notesStr := string(content)
+				notes = &notesStr
+			}
+			printRespSimple(doEditDistro(args[0], name, owner, desc, add, remove, kargs, notes, public, isDefault, defaultRemove, notify))
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNameArg,
@@ -325,7 +417,9 @@ distro.
 	cmdEditDistro.Flags().StringVar(&desc, "desc", "", "update the description of the distro")
 	cmdEditDistro.Flags().StringSliceVarP(&add, "add", "a", nil, "group(s) to add to distro access")
 	cmdEditDistro.Flags().StringSliceVarP(&remove, "remove", "r", nil, "group(s) to remove from distro access")
+	cmdEditDistro.Flags().Bool("notify", false, "email owners of reservations that lose access to the distro")
 	cmdEditDistro.Flags().StringVarP(&kargs, "kernel-args", "k", "", "update the kernel arguments of the distro")
+	cmdEditDistro.Flags().String("notes-file", "", "markdown file of usage notes for the distro")
 	cmdEditDistro.Flags().BoolP("public", "p", false, "make this distro public (anyone can use, can't undo)")
 	cmdEditDistro.Flags().Bool("default", false, "make this distro default (used during post-reservation maintenance phase)")
 	cmdEditDistro.Flags().Bool("default-remove", false, "remove the default designation from this distro")
@@ -335,14 +429,15 @@ distro.
 	_ = registerFlagArgsFunc(cmdEditDistro, "add", []string{"GRP1"})
 	_ = registerFlagArgsFunc(cmdEditDistro, "remove", []string{"GRP1"})
 	_ = registerFlagArgsFunc(cmdEditDistro, "kernel-args", []string{"\"KARGS\""})
+	_ = cmdEditDistro.MarkFlagFilename("notes-file")
 
 	return cmdEditDistro
 }
 
 func newDistroDelCmd() *cobra.Command {
 
-	return &cobra.Command{
-		Use:   "del NAME",
+	cmdDelDistro := &cobra.Command{
+		Use:   "del NAME [--force]",
 		Short: "Delete a distro",
 		Long: `
 Deletes an igor distro. This can only be done by the distro owner or an admin.
@@ -351,24 +446,39 @@ Deletes an igor distro. This can only be done by the distro owner or an admin.
 
   NAME : distro name
 
+` + optionalFlags + `
+
+Use the --force flag to also delete every profile using the distro, whoever
+owns it. This is only allowed for an elevated admin. Nothing is deleted if any
+of the profiles is used by a current or future reservation; the reservations
+are listed instead. The owners of the deleted profiles are sent an email
+listing what was removed.
+
 ` + notesOnUsage + `
 
 A distro cannot be deleted if it is associated to an existing profile. Any 
-profiles using the distro must be deleted first. If a distro is deleted and it
-is the last to be using an image (ex. kernel/initrd pair), then the image will
-also be destroyed automatically.
+profiles using the distro must be deleted first, or --force used. If a distro
+is deleted and it is the last to be using an image (ex. kernel/initrd pair),
+then the image will also be destroyed automatically.
 `,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			printRespSimple(doDeleteDistro(args[0]))
+			force, _ := cmd.Flags().GetBool("force")
+			printRespSimple(doDeleteDistro(args[0], force))
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNameArg,
 	}
+
+	var force bool
+	cmdDelDistro.Flags().BoolVar(&force, "force", false, "also delete all profiles using the distro "+adminOnly)
+
+	return cmdDelDistro
 }
 
-func doCreateDistro(name, kfile, ifile, kstaged, istaged, dpath, eDistro, eKI, kiref, desc string, groups []string, kargs string, kickstart string, public, isDefault bool) (*common.ResponseBodyBasic, error) {
+func doCreateDistro(name, kfile, ifile, kstaged, istaged, kurl, iurl, ksha, isha, dpath, eDistro string, copyFields []string, eKI, kiref, desc string, groups []string, kargs string, kickstart string, public, isDefault bool) (*common.ResponseBodyBasic, error) {
 
+	checkNewName(naming.Distro, name)
 	params := map[string]interface{}{}
 	params["name"] = name
 	// params["boot"] = boot
@@ -378,14 +488,19 @@ func doCreateDistro(name, kfile, ifile, kstaged, istaged, dpath, eDistro, eKI, k
 	} else if kstaged != "" && istaged != "" {
 		params["kStaged"] = kstaged
 		params["iStaged"] = istaged
+	} else if kurl != "" && iurl != "" {
+		addImageURLParams(params, kurl, iurl, ksha, isha)
 	} else if eDistro != "" {
 		params["copyDistro"] = eDistro
+		if len(copyFields) > 0 {
+			params["copyFields"] = strings.Join(copyFields, ",")
+		}
 	} else if eKI != "" {
 		params["useDistroImage"] = eKI
 	} else if kiref != "" {
 		params["imageRef"] = kiref
 	} else {
-		return nil, fmt.Errorf("error - one of the following is required: kernel and initrd OR kstaged and istaged OR copy-distro OR use-distro-image OR image-ref")
+		return nil, fmt.Errorf("error - one of the following is required: kernel and initrd OR kstaged and istaged OR kernel-url and initrd-url OR copy-distro OR use-distro-image OR image-ref")
 	}
 	if dpath != "" {
 		params["dPath"] = dpath
@@ -417,7 +532,52 @@ func doCreateDistro(name, kfile, ifile, kstaged, istaged, dpath, eDistro, eKI, k
 	}
 }
 
-func doShowDistros(names []string, owners []string, groups []string, imageIDs []string, kernels []string, initrds []string, byDefault bool) *common.ResponseBodyDistros {
+// distroCopyFields returns the distro attributes to inherit with --copy-distro. It is empty if the server
+// should use its default of inheriting everything.
+func distroCopyFields(flagset *pflag.FlagSet) ([]string, error) {
+
+	if flagset.Changed("copy-fields") {
+		fields, _ := flagset.GetStringSlice("copy-fields")
+		if !slices.Contains(fields, "image") {
+			return nil, fmt.Errorf("--copy-fields must include image")
+		}
+		return fields, nil
+	}
+
+	noCopy := map[string]string{"description": "no-copy-desc", "kickstart": "no-copy-kickstart", "kernelArgs": "no-copy-kargs"}
+	var fields []string
+	for _, f := range []string{"description", "image", "kickstart", "kernelArgs"} {
+		if flag, ok := noCopy[f]; !ok || !flagset.Changed(flag) {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == len(noCopy)+1 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// printDistroCopied lists the attributes a distro made with --copy-distro inherited and those given for it.
+func printDistroCopied(rb *common.ResponseBodyBasic) {
+
+	copiedData, ok := rb.Data["copied"]
+	if !rb.IsSuccess() || !ok {
+		return
+	}
+
+	var copied common.DistroCopyData
+	raw, err := json.Marshal(copiedData)
+	checkUnmarshalErr(err)
+	checkUnmarshalErr(json.Unmarshal(raw, &copied))
+
+	fmt.Printf("copied from distro '%s'\n", copied.Source)
+	fmt.Printf("  inherited: %s\n", strings.Join(copied.Inherited, ", "))
+	if len(copied.Specified) > 0 {
+		fmt.Printf("  specified: %s\n", strings.Join(copied.Specified, ", "))
+	}
+}
+
+func doShowDistros(names []string, owners []string, groups []string, imageIDs []string, kernels []string, initrds []string, byDefault, verify bool, unused string) *common.ResponseBodyDistros {
 
 	var params string
 	if len(names) > 0 {
@@ -453,6 +613,12 @@ func doShowDistros(names []string, owners []string, groups []string, imageIDs []
 	if byDefault {
 		params += "default=true&"
 	}
+	if verify {
+		params += "verify=true&"
+	}
+	if unused != "" {
+		params += "unused=" + unused + "&"
+	}
 	if params != "" {
 		params = strings.TrimSuffix(params, "&")
 		params = "?" + params
@@ -465,10 +631,11 @@ func doShowDistros(names []string, owners []string, groups []string, imageIDs []
 	return &rb
 }
 
-func doEditDistro(name string, newName string, owner string, desc string, add []string, remove []string, kargs string, public, isDefault, defaultRemove bool) *common.ResponseBodyBasic {
+func doEditDistro(name string, newName string, owner string, desc string, add []string, remove []string, kargs string, notes *string, public, isDefault, defaultRemove, notify bool) *common.ResponseBodyBasic {
 	apiPath := api.Distros + "/" + name
 	params := make(map[string]interface{})
 	if newName != "" {
+		checkNewName(naming.Distro, newName)
 		params["name"] = newName
 	}
 	if owner != "" {
@@ -482,10 +649,16 @@ func doEditDistro(name string, newName string, owner string, desc string, add []
 	}
 	if len(remove) > 0 {
 		params["removeGroup"] = remove
+		if notify {
+			params["notify"] = "true"
+		}
 	}
 	if kargs != "" {
 		params["kernelArgs"] = kargs
 	}
+	if notes != nil {
+		params["usageNotes"] = *notes
+	}
 	if public {
 		params["public"] = "true"
 	}
@@ -499,13 +672,16 @@ func doEditDistro(name string, newName string, owner string, desc string, add []
 	return unmarshalBasicResponse(body)
 }
 
-func doDeleteDistro(name string) *common.ResponseBodyBasic {
+func doDeleteDistro(name string, force bool) *common.ResponseBodyBasic {
 	apiPath := api.Distros + "/" + name
+	if force {
+		apiPath += "?force=true"
+	}
 	body := doSend(http.MethodDelete, apiPath, nil)
 	return unmarshalBasicResponse(body)
 }
 
-func printDistros(rb *common.ResponseBodyDistros) {
+func printDistros(rb *common.ResponseBodyDistros, verify bool) {
 
 	checkAndSetColorLevel(rb)
 
@@ -518,6 +694,12 @@ func printDistros(rb *common.ResponseBodyDistros) {
 		return strings.ToLower(distroList[i].Name) < strings.ToLower(distroList[j].Name)
 	})
 
+	imageMissing := false
+	showUsage := false
+	for _, d := range distroList {
+		showUsage = showUsage || d.Usage != nil
+	}
+
 	if simplePrint {
 
 		var distroInfo string
@@ -527,7 +709,7 @@ func printDistros(rb *common.ResponseBodyDistros) {
 			distroInfo += "  -DESCRIPTION: " + d.Description + "\n"
 			distroInfo += "  -OWNER:       " + d.Owner + "\n"
 			distroInfo += "  -PUBLIC:      " + strconv.FormatBool(d.IsPublic) + "\n"
-			distroInfo += "  -GROUPS:      " + strings.Join(d.Groups, ",") + "\n"
+			distroInfo += "  -GROUPS:      " + sortedJoin(d.Groups, ",") + "\n"
 			distroInfo += "  -TYPE:        " + d.ImageType + "\n"
 			distroInfo += "  -KERNEL:      " + d.Kernel + "\n"
 			distroInfo += "  -INITRD:      " + d.Initrd + "\n"
@@ -535,29 +717,54 @@ func printDistros(rb *common.ResponseBodyDistros) {
 			if d.Kickstart != "" {
 				distroInfo += "  -KICKSTART:   " + d.Kickstart + "\n"
 			}
+			if verify {
+				distroInfo += "  -IMAGE-FILES: " + strings.Join(imageFileStatus(d), "\n               ") + "\n"
+				imageMissing = imageMissing || d.ImageMissing
+			}
+			if d.Usage != nil {
+				distroInfo += "  -USAGE:       " + imageUsageStatus(d.Usage) + "\n"
+			}
+			if d.UsageNotes != "" {
+				distroInfo += "  -NOTES:\n" + indentNotes(d.UsageNotes) + "\n"
+			}
 			fmt.Print(distroInfo + "\n\n")
 		}
 
 	} else {
 
 		tw := table.NewWriter()
-		tw.AppendHeader(table.Row{"NAME", "DESCRIPTION", "OWNER", "PUBLIC?", "GROUPS", "TYPE", "KERNEL", "INITRD", "KICKSTART", "KERNEL-ARGS"})
+		header := table.Row{"NAME", "DESCRIPTION", "OWNER", "PUBLIC?", "GROUPS", "TYPE", "KERNEL", "INITRD", "KICKSTART", "KERNEL-ARGS"}
+		if verify {
+			header = append(header, "IMAGE FILES")
+		}
+		if showUsage {
+			header = append(header, "USAGE")
+		}
+		tw.AppendHeader(header)
 		tw.AppendSeparator()
 
 		for _, d := range distroList {
 
-			tw.AppendRow([]interface{}{
+			row := []interface{}{
 				d.Name,
 				d.Description,
 				d.Owner,
 				d.IsPublic,
-				strings.Join(d.Groups, "\n"),
+				sortedJoin(d.Groups, "\n"),
 				d.ImageType,
 				d.Kernel,
 				d.Initrd,
 				d.Kickstart,
 				d.KernelArgs,
-			})
+			}
+			if verify {
+				row = append(row, strings.Join(imageFileStatus(d), "\n"))
+				imageMissing = imageMissing || d.ImageMissing
+			}
+			if showUsage {
+				row = append(row, imageUsageStatus(d.Usage))
+			}
+			tw.AppendRow(row)
 		}
 
 		tw.SetColumnConfigs([]table.ColumnConfig{
@@ -571,4 +778,47 @@ func printDistros(rb *common.ResponseBodyDistros) {
 		fmt.Printf("\n" + tw.Render() + "\n\n")
 	}
 
+	if imageMissing {
+		checkClientErr(fmt.Errorf("one or more distros are missing image files"))
+	}
+}
+
+// imageFileStatus returns a line for each image file of the distro describing whether it is
+// present on the server, with missing files and size changes highlighted.
+func imageFileStatus(d common.DistroData) []string {
+	var lines []string
+	for _, f := range d.ImageFiles {
+		if !f.Present {
+			lines = append(lines, cAlert.Sprintf("%s: MISSING", f.Name))
+			continue
+		}
+		line := fmt.Sprintf("%s: %d bytes, modified %s", f.Name, f.Size,
+			getLocTime(time.Unix(f.ModTime, 0)).Format(common.DateTimeCompactFormat))
+		if f.SizeMismatch {
+			line = cAlert.Sprintf("%s (registered size %d bytes)", line, f.RegisteredSize)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// imageUsageStatus describes how often and how recently a distro or profile was used. It is empty
+// if the user can't see usage stats for it.
+func imageUsageStatus(u *common.ImageUsageData) string {
+	if u == nil {
+		return ""
+	}
+	if u.LastUsed == 0 {
+		return "never used"
+	}
+	return fmt.Sprintf("%d uses, last %s", u.UseCount, getLocTime(time.Unix(u.LastUsed, 0)).Format(common.DateTimeCompactFormat))
+}
+
+// indentNotes indents each line of distro usage notes to print under a heading.
+func indentNotes(notes string) string {
+	lines := strings.Split(strings.TrimRight(notes, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = "    " + l
+	}
+	return strings.Join(lines, "\n")
 }
@@ -0,0 +1,195 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorcli
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igor2/internal/pkg/common"
+)
+
+// The golden files under testdata are the contract for -x/--simple output that scripts rely on. A
+// change that alters them, such as a new column, has to update them with 'go test -run Golden -update'
+// so the change to the output is made on purpose.
+var updateGolden = flag.Bool("update", false, "update the golden files of simple output")
+
+// goldenTime is the time the golden output is printed at, so dates don't change between runs.
+var goldenTime = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// setGoldenVars sets the globals that printing depends on to fixed values for golden output.
+func setGoldenVars(t *testing.T) {
+	origLoc, origNow, origFmt := cli.tzLoc, igorCliNow, dateFormatFlag
+	t.Cleanup(func() {
+		cli.tzLoc, igorCliNow, dateFormatFlag = origLoc, origNow, origFmt
+		simplePrint = false
+	})
+	cli.tzLoc = time.UTC
+	igorCliNow = goldenTime
+	dateFormatFlag = dateFormatISO
+	simplePrint = true
+}
+
+// captureStdout returns what print writes to stdout.
+func captureStdout(t *testing.T, print func()) string {
+	orig := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+
+	out := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = io.Copy(&buf, r)
+		out <- buf.String()
+	}()
+	print()
+	require.NoError(t, w.Close())
+	return <-out
+}
+
+// checkGolden compares output with the named golden file, or rewrites the file when -update is given.
+func checkGolden(t *testing.T, name, output string) {
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, []byte(output), 0644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), output)
+}
+
+func TestGoldenReservationsSimple(t *testing.T) {
+
+	setGoldenVars(t)
+	rb := &common.ResponseBodyReservations{Data: map[string][]common.ReservationData{"reservations": {
+		{
+			Name:        "later",
+			Description: "second",
+			Owner:       "bob",
+			Group:       "sith",
+			Profile:     "bob_centos",
+			Distro:      "centos",
+			HostRange:   "kn[5-6]",
+			Vlan:        101,
+			Start:       goldenTime.Add(24 * time.Hour).Unix(),
+			End:         goldenTime.Add(48 * time.Hour).Unix(),
+			OrigEnd:     goldenTime.Add(48 * time.Hour).Unix(),
+			State:       common.ResStateFuture,
+		},
+		{
+			Name:        "sooner",
+			Description: "first",
+			Owner:       "alice",
+			CoOwners:    []string{"dave", "carol"},
+			NotifyAlso:  []string{"zed@example.com", "amy@example.com"},
+			Group:       "jedis",
+			Profile:     "alice_ubuntu",
+			Distro:      "ubuntu",
+			HostRange:   "kn[1-4]",
+			Vlan:        100,
+			Start:       goldenTime.Add(-time.Hour).Unix(),
+			End:         goldenTime.Add(2 * time.Hour).Unix(),
+			OrigEnd:     goldenTime.Add(time.Hour).Unix(),
+			ExtendCount: 1,
+			Installed:   true,
+			State:       common.ResStateActive,
+		},
+	}}}
+
+	checkGolden(t, "reservations_simple", captureStdout(t, func() { printReservations(rb, time.Time{}) }))
+}
+
+func TestGoldenHostsSimple(t *testing.T) {
+
+	setGoldenVars(t)
+	rb := &common.ResponseBodyHosts{Data: map[string][]common.HostData{"hosts": {
+		{
+			Name:         "kn2",
+			SequenceID:   2,
+			HostName:     "kn2.example.com",
+			IP:           "10.0.0.2",
+			Mac:          "00:00:00:00:00:02",
+			State:        "available",
+			Powered:      "false",
+			BootMode:     "bios",
+			HostPolicy:   "default",
+			AccessGroups: []string{"all"},
+		},
+		{
+			Name:         "kn1",
+			SequenceID:   1,
+			HostName:     "kn1.example.com",
+			IP:           "10.0.0.1",
+			Mac:          "00:00:00:00:00:01",
+			State:        "reserved",
+			Powered:      "true",
+			BootMode:     "uefi",
+			HostPolicy:   "weekdays",
+			AccessGroups: []string{"sith", "jedis"},
+			Reservations: []string{"sooner"},
+		},
+	}}}
+
+	checkGolden(t, "hosts_simple", captureStdout(t, func() { printHosts(rb) }))
+}
+
+func TestGoldenGroupsSimple(t *testing.T) {
+
+	setGoldenVars(t)
+	rb := &common.ResponseBodyGroups{Data: map[string][]common.GroupData{
+		"owner": {{
+			Name:         "jedis",
+			Description:  "the good guys",
+			Owners:       []string{"yoda", "luke"},
+			Members:      []string{"yoda", "obiwan", "luke"},
+			Distros:      []string{"ubuntu", "centos"},
+			Policies:     []string{"weekdays"},
+			Reservations: []string{"sooner", "again"},
+		}},
+		"member": {{
+			Name:        "sith",
+			Description: "the bad guys",
+			Owners:      []string{"vader"},
+			Members:     []string{"vader", "maul"},
+		}},
+	}}
+
+	checkGolden(t, "groups_simple", captureStdout(t, func() { printShowGroups(rb, false) }))
+}
+
+func TestGoldenUsersSimple(t *testing.T) {
+
+	setGoldenVars(t)
+	rb := &common.ResponseBodyUsers{Data: map[string][]common.UserData{"users": {
+		{
+			Name:         "luke",
+			FullName:     "Luke Skywalker",
+			Email:        "luke@example.com",
+			Groups:       []string{"pilots", "jedis"},
+			JoinDate:     goldenTime.Add(-24 * time.Hour).Unix(),
+			DefaultGroup: "jedis",
+			NotifyPrefs:  "res-cc=final,group=no",
+		},
+		{
+			Name:     "han",
+			FullName: "Han Solo",
+			Email:    "han@example.com",
+			Groups:   []string{"smugglers"},
+			JoinDate: goldenTime.Unix(),
+		},
+	}}}
+
+	checkGolden(t, "users_simple", captureStdout(t, func() { printShowUsers(rb, true) }))
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"igor2/internal/pkg/api"
 	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
 	"net/http"
 	"sort"
 	"strings"
@@ -98,7 +99,7 @@ fail if the user running the group creation command lacks this permission.
 			printRespSimple(doCreateGroup(args[0], isLDAP, desc, owners, members))
 		},
 		DisableFlagsInUseLine: true,
-		ValidArgsFunction:     validateNameArg,
+		ValidArgsFunction:     validateNewNameArg(naming.Group),
 	}
 
 	var desc string
@@ -120,7 +121,7 @@ fail if the user running the group creation command lacks this permission.
 func newGroupShowCmd() *cobra.Command {
 
 	cmdShowGroups := &cobra.Command{
-		Use:   "show [{-n USER1,... | -o OWNER1,...}] [-m] [-x]",
+		Use:   "show [{-n USER1,... | -o OWNER1,...}] [-m] [-a] [-x]",
 		Short: "Show group information",
 		Long: `
 Shows group information. If no optional parameters are provided then all groups
@@ -133,6 +134,10 @@ should be comma-delimited.
 
 Use the -m flag to display members in the group. (Can result in long output.)
 
+Use the -a flag to list the reservations, distros and host policies each group
+gives its members access to. This is only shown for groups you own, or for all
+groups if you are an admin. Long lists are cut short with a count of the rest.
+
 Use the -x flag to render screen output without pretty formatting.
 `,
 		Args: cobra.NoArgs,
@@ -141,8 +146,9 @@ Use the -x flag to render screen output without pretty formatting.
 			names, _ := flagset.GetStringSlice("names")
 			owners, _ := flagset.GetStringSlice("owners")
 			showMembers := flagset.Changed("members")
+			showAccess := flagset.Changed("access")
 			simplePrint = flagset.Changed("simple")
-			printShowGroups(doShowGroups(names, owners, showMembers))
+			printShowGroups(doShowGroups(names, owners, showMembers, showAccess), showAccess)
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNoArgs,
@@ -150,10 +156,12 @@ Use the -x flag to render screen output without pretty formatting.
 
 	var names,
 		owners []string
-	var showMembers bool
+	var showMembers,
+		showAccess bool
 	cmdShowGroups.Flags().StringSliceVarP(&names, "names", "n", nil, "search by group name(s)")
 	cmdShowGroups.Flags().StringSliceVarP(&owners, "owners", "o", nil, "search by owner name(s)")
 	cmdShowGroups.Flags().BoolVarP(&showMembers, "members", "m", false, "include members in output")
+	cmdShowGroups.Flags().BoolVarP(&showAccess, "access", "a", false, "include what each owned group grants access to")
 	cmdShowGroups.Flags().BoolVarP(&simplePrint, "simple", "x", false, "use simple text output")
 
 	_ = registerFlagArgsFunc(cmdShowGroups, "names", []string{"USER1"})
@@ -166,7 +174,9 @@ func newGroupEditCmd() *cobra.Command {
 
 	cmdEditGroup := &cobra.Command{
 		Use: "edit NAME [-n NEWNAME] {[-o OWNER1,...] [-w OWNER1,...] | \n" +
-			"                [-a MEMBER1,...] [-r MEMBER1,...]} [--desc \"DESCRIPTION\"]",
+			"                [-a MEMBER1,...] [-r MEMBER1,...]} [--desc \"DESCRIPTION\"]\n" +
+			"                [--default-distro DISTRO] [--default-duration DUR]\n" +
+			"                [--vlan-range MIN-MAX] [--fail-fast]",
 		Short: "Edit group information",
 		Long: `
 Edits group information. This can only be done by the group owner or an admin.
@@ -175,7 +185,9 @@ Changes to the group are emailed out to those affected.
 ` + notesOnUsage + `
 
 This command cannot be used on an LDAP-synced group. Modify the group's proper-
-ties using the network's LDAP interface instead.
+ties using the network's LDAP interface instead. The only exception is the
+reservation defaults and VLAN range described below, which an admin can set on
+any group.
 
 ` + requiredArgs + `
 
@@ -197,9 +209,36 @@ Use the -a flag to add a list of users to the group. ` + sItalic("Note: adding a
 
 Use the -r flag to remove a list of users from the group.
 
-
+Use the --fail-fast flag with -a or -r to stop at the first user that can't be
+added or removed and leave the group unchanged. Without it the users that can
+be are, and a table lists those that failed and why.
 
 ` + descFlagText + `
+
+` + sBold("RESERVATION DEFAULTS:") + `
+
+A group can provide defaults for reservations made with it. They apply when the
+group is given with 'igor res create -g' or is the member's default group (see
+'igor user edit --default-group') and the matching create flag isn't used.
+
+Use the --default-distro flag to set the distro used when no distro or profile
+is given. The distro must be shared with the group.
+
+Use the --default-duration flag to set the reservation length used when no end
+time is given, ex. 3d or 4h30m. It cannot be less than the minimum reservation
+length.
+
+Use 'none' as the value of either flag to clear that default.
+
+` + sBold("VLAN RANGE:") + `
+
+Use the --vlan-range flag to limit the VLANs used by reservations made with the
+group, ex. 100-149. Automatically assigned VLANs are picked from this range, and
+a VLAN given with 'igor res create -v' must fall within it, including one taken
+from another reservation. The range must be within the VLANs igor manages (see
+'igor settings'). Reservations already using a VLAN outside a new range keep it;
+'igor vlan show' flags them. Use 'none' to clear the range. ` + sItalic("Only an admin can\n"+
+			"set the VLAN range of a group.") + `
 `,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
@@ -210,14 +249,21 @@ Use the -r flag to remove a list of users from the group.
 			rmvOwners, _ := flagset.GetStringSlice("rmv-owners")
 			add, _ := flagset.GetStringSlice("add")
 			remove, _ := flagset.GetStringSlice("remove")
-			printRespSimple(doEditGroup(args[0], name, addOwners, rmvOwners, desc, add, remove))
+			defDistro, _ := flagset.GetString("default-distro")
+			defDuration, _ := flagset.GetString("default-duration")
+			vlanRange, _ := flagset.GetString("vlan-range")
+			failFast, _ := flagset.GetBool("fail-fast")
+			printBatchResults(doEditGroup(args[0], name, addOwners, rmvOwners, desc, add, remove, defDistro, defDuration, vlanRange, failFast))
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNameArg,
 	}
 
 	var name,
-		desc string
+		desc,
+		defDistro,
+		defDuration,
+		vlanRange string
 	var addUsers,
 		rmvUsers,
 		addOwners,
@@ -228,12 +274,19 @@ Use the -r flag to remove a list of users from the group.
 	cmdEditGroup.Flags().StringSliceVarP(&rmvOwners, "rmv-owners", "w", nil, "comma-delimited owners to remove")
 	cmdEditGroup.Flags().StringSliceVarP(&addUsers, "add", "a", nil, "comma-delimited users to add")
 	cmdEditGroup.Flags().StringSliceVarP(&rmvUsers, "remove", "r", nil, "comma-delimited users to remove")
+	cmdEditGroup.Flags().StringVar(&defDistro, "default-distro", "", "default distro for reservations, or 'none'")
+	cmdEditGroup.Flags().StringVar(&defDuration, "default-duration", "", "default length of reservations, or 'none'")
+	cmdEditGroup.Flags().StringVar(&vlanRange, "vlan-range", "", "VLANs reservations of the group can use, or 'none'")
+	cmdEditGroup.Flags().Bool("fail-fast", false, "stop at the first user that can't be added or removed")
 	_ = registerFlagArgsFunc(cmdEditGroup, "name", []string{"NAME"})
 	_ = registerFlagArgsFunc(cmdEditGroup, "desc", []string{"\"DESCRIPTION\""})
 	_ = registerFlagArgsFunc(cmdEditGroup, "add-owners", []string{"OWNER1"})
 	_ = registerFlagArgsFunc(cmdEditGroup, "rmv-owners", []string{"OWNER1"})
 	_ = registerFlagArgsFunc(cmdEditGroup, "add", []string{"USER1"})
 	_ = registerFlagArgsFunc(cmdEditGroup, "remove", []string{"USER1"})
+	_ = registerFlagArgsFunc(cmdEditGroup, "default-distro", []string{"DISTRO"})
+	_ = registerFlagArgsFunc(cmdEditGroup, "default-duration", []string{"DUR"})
+	_ = registerFlagArgsFunc(cmdEditGroup, "vlan-range", []string{"MIN-MAX"})
 
 	return cmdEditGroup
 }
@@ -273,6 +326,7 @@ the group. It does not affect the LDAP group service object itself.
 
 func doCreateGroup(name string, isLDAP bool, desc string, owners []string, members []string) *common.ResponseBodyBasic {
 
+	checkNewName(naming.Group, name)
 	params := map[string]interface{}{}
 	params["name"] = name
 	if isLDAP {
@@ -291,7 +345,7 @@ func doCreateGroup(name string, isLDAP bool, desc string, owners []string, membe
 	return unmarshalBasicResponse(body)
 }
 
-func doShowGroups(names []string, owners []string, showMembers bool) *common.ResponseBodyGroups {
+func doShowGroups(names []string, owners []string, showMembers, showAccess bool) *common.ResponseBodyGroups {
 
 	var params string
 	if len(names) > 0 {
@@ -305,7 +359,10 @@ func doShowGroups(names []string, owners []string, showMembers bool) *common.Res
 		}
 	}
 	if showMembers {
-		params += "showMembers=true"
+		params += "showMembers=true&"
+	}
+	if showAccess {
+		params += "showAccess=true"
 	}
 	if params != "" {
 		params = strings.TrimSuffix(params, "&")
@@ -319,10 +376,11 @@ func doShowGroups(names []string, owners []string, showMembers bool) *common.Res
 	return &rb
 }
 
-func doEditGroup(name string, newName string, addOwners []string, rmvOwners []string, desc string, add []string, remove []string) *common.ResponseBodyBasic {
+func doEditGroup(name string, newName string, addOwners []string, rmvOwners []string, desc string, add []string, remove []string, defDistro, defDuration, vlanRange string, failFast bool) *common.ResponseBodyBatch {
 	apiPath := api.Groups + "/" + name
 	params := make(map[string]interface{})
 	if newName != "" {
+		checkNewName(naming.Group, newName)
 		params["name"] = newName
 	}
 	if len(addOwners) > 0 {
@@ -340,9 +398,21 @@ func doEditGroup(name string, newName string, addOwners []string, rmvOwners []st
 	if len(remove) > 0 {
 		params["remove"] = remove
 	}
+	if failFast && (len(add) > 0 || len(remove) > 0) {
+		params["failFast"] = true
+	}
+	if defDistro != "" {
+		params["defaultDistro"] = defDistro
+	}
+	if defDuration != "" {
+		params["defaultDuration"] = defDuration
+	}
+	if vlanRange != "" {
+		params["vlanRange"] = vlanRange
+	}
 
 	body := doSend(http.MethodPatch, apiPath, params)
-	return unmarshalBasicResponse(body)
+	return unmarshalBatchResponse(body)
 }
 
 func doDeleteGroup(name string) *common.ResponseBodyBasic {
@@ -351,7 +421,7 @@ func doDeleteGroup(name string) *common.ResponseBodyBasic {
 	return unmarshalBasicResponse(body)
 }
 
-func printShowGroups(rb *common.ResponseBodyGroups) {
+func printShowGroups(rb *common.ResponseBodyGroups, showAccess bool) {
 
 	checkAndSetColorLevel(rb)
 
@@ -384,21 +454,27 @@ func printShowGroups(rb *common.ResponseBodyGroups) {
 			if len(g.Members) == 0 {
 				members = "<not shown>"
 			} else {
-				members = strings.Join(g.Members, ",")
+				members = sortedJoin(g.Members, ",")
 			}
 			if len(g.Owners) == 1 {
 				owners = g.Owners[0]
 			} else {
-				owners = strings.Join(g.Owners, ",")
+				owners = sortedJoin(g.Owners, ",")
 			}
 
 			groupInfo = "GROUP: " + g.Name + "\n"
 			groupInfo += "  -DESCRIPTION:  " + g.Description + "\n"
 			groupInfo += "  -OWNERS:       " + owners + "\n"
 			groupInfo += "  -MEMBERS:      " + members + "\n"
-			groupInfo += "  -DISTROS:      " + strings.Join(g.Distros, ",") + "\n"
-			groupInfo += "  -RESERVATIONS: " + strings.Join(g.Reservations, ",") + "\n"
-			groupInfo += "  -POLICIES:     " + strings.Join(g.Policies, ",") + "\n\n"
+			groupInfo += "  -DISTROS:      " + sortedJoin(g.Distros, ",") + "\n"
+			groupInfo += "  -RESERVATIONS: " + sortedJoin(g.Reservations, ",") + "\n"
+			groupInfo += "  -POLICIES:     " + sortedJoin(g.Policies, ",") + "\n"
+			groupInfo += "  -RES DEFAULTS: " + groupResDefaults(g, ", ") + "\n"
+			groupInfo += "  -VLAN RANGE:   " + g.VlanRange + "\n"
+			if showAccess {
+				groupInfo += "  -ACCESS:\n" + groupAccess(g.Access, "      ") + "\n"
+			}
+			groupInfo += "\n"
 			if i < len(owned)-1 {
 				groupInfo += "-------------------------------\n\n"
 			}
@@ -408,7 +484,11 @@ func printShowGroups(rb *common.ResponseBodyGroups) {
 	} else {
 
 		tw := table.NewWriter()
-		tw.AppendHeader(table.Row{"NAME", "DESCRIPTION", "OWNERS", "MEMBERS", "DISTROS", "RESERVATIONS", "POLICIES"})
+		header := table.Row{"NAME", "DESCRIPTION", "OWNERS", "MEMBERS", "DISTROS", "RESERVATIONS", "POLICIES", "RES DEFAULTS", "VLAN RANGE"}
+		if showAccess {
+			header = append(header, "ACCESS")
+		}
+		tw.AppendHeader(header)
 
 		for _, g := range groupList {
 
@@ -420,23 +500,29 @@ func printShowGroups(rb *common.ResponseBodyGroups) {
 			if len(g.Members) == 0 {
 				members = "<not shown>"
 			} else {
-				members = strings.Join(g.Members, "\n")
+				members = sortedJoin(g.Members, "\n")
 			}
 			if len(g.Owners) == 1 {
 				owners = g.Owners[0]
 			} else {
-				owners = strings.Join(g.Owners, "\n")
+				owners = sortedJoin(g.Owners, "\n")
 			}
 
-			tw.AppendRow([]interface{}{
+			row := table.Row{
 				g.Name,
 				g.Description,
 				owners,
 				members,
-				strings.Join(g.Distros, "\n"),
-				strings.Join(g.Reservations, "\n"),
-				strings.Join(g.Policies, "\n"),
-			})
+				sortedJoin(g.Distros, "\n"),
+				sortedJoin(g.Reservations, "\n"),
+				sortedJoin(g.Policies, "\n"),
+				groupResDefaults(g, "\n"),
+				g.VlanRange,
+			}
+			if showAccess {
+				row = append(row, groupAccess(g.Access, ""))
+			}
+			tw.AppendRow(row)
 		}
 
 		tw.SetColumnConfigs([]table.ColumnConfig{
@@ -451,3 +537,42 @@ func printShowGroups(rb *common.ResponseBodyGroups) {
 	}
 
 }
+
+// groupAccess renders what a group grants access to as an indented list of each kind of resource.
+// Names the server left out of a list are counted at the end of it.
+func groupAccess(a *common.GroupAccessData, indent string) string {
+	if a == nil {
+		return indent + "<not shown>"
+	}
+	lists := []struct {
+		title string
+		list  common.GroupAccessList
+	}{
+		{"RESERVATIONS", a.Reservations},
+		{"DISTROS", a.Distros},
+		{"POLICIES", a.HostPolicies},
+	}
+	var lines []string
+	for _, l := range lists {
+		lines = append(lines, fmt.Sprintf("%s%s (%d)", indent, l.title, l.list.Count))
+		for _, n := range l.list.Names {
+			lines = append(lines, indent+"  "+n)
+		}
+		if more := l.list.More(); more > 0 {
+			lines = append(lines, fmt.Sprintf("%s  ... and %d more", indent, more))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// groupResDefaults lists the reservation defaults set on a group.
+func groupResDefaults(g common.GroupData, sep string) string {
+	var defaults []string
+	if g.DefaultDistro != "" {
+		defaults = append(defaults, "distro: "+g.DefaultDistro)
+	}
+	if g.DefaultDuration != "" {
+		defaults = append(defaults, "duration: "+g.DefaultDuration)
+	}
+	return strings.Join(defaults, sep)
+}
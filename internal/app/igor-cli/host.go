@@ -9,9 +9,11 @@ import (
 	"fmt"
 	"igor2/internal/pkg/api"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"igor2/internal/pkg/common"
 
@@ -37,6 +39,10 @@ needed. This includes assigning a policy to a host.
 	cmdHost.AddCommand(newHostDelCmd())
 	cmdHost.AddCommand(newHostBlockCmd())
 	cmdHost.AddCommand(newHostUnblockCmd())
+	cmdHost.AddCommand(newHostDrainCmd())
+	cmdHost.AddCommand(newHostUndrainCmd())
+	cmdHost.AddCommand(newHostExplainCmd())
+	cmdHost.AddCommand(newHostHistoryCmd())
 	return cmdHost
 }
 
@@ -89,9 +95,20 @@ Use the --powered flag to only display powered nodes. Set it to false to only
 display unpowered nodes.
 
 When searching by state (-s) acceptable parameters are ` + sBold("available") + `, ` + sBold("reserved") + `,
-` + sBold("blocked") + ` and ` + sBold("error") + `.
+` + sBold("blocked") + `, ` + sBold("draining") + ` and ` + sBold("error") + `.
 
-Use the -x flag to render screen output without pretty formatting.
+A blocked node lists who blocked it, when and the reason given, if any, under
+its state. In simple output these are the BLOCKED-BY, BLOCKED-AT and
+BLOCK-REASON columns, shown when any listed node is blocked.
+
+Admins also see a USAGE-HRS column with the hours each node has been held by
+reservations that have ended. The scheduler can use it to prefer the least
+used nodes.
+
+Use the -x flag to render screen output without pretty formatting. If the
+server is configured with a console URL, the simple output also has a CONSOLE
+column with the link to each node's serial console. Links are only shown to
+admins and to members of the reservation using the node.
 `,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -140,7 +157,7 @@ Use the -x flag to render screen output without pretty formatting.
 	cmdShowHosts.Flags().BoolVar(&powerVal, "powered", true, "filter on powered or unpowered nodes")
 	cmdShowHosts.Flags().BoolVarP(&simplePrint, "simple", "x", false, "use simple text output")
 
-	_ = registerFlagArgsFunc(cmdShowHosts, "states", []string{"available", "reserved", "blocked", "error"})
+	_ = registerFlagArgsFunc(cmdShowHosts, "states", []string{"available", "reserved", "blocked", "draining", "error"})
 	_ = registerFlagArgsFunc(cmdShowHosts, "names", []string{"NODES"})
 	_ = registerFlagArgsFunc(cmdShowHosts, "hostnames", []string{"HOSTNAME1"})
 	_ = registerFlagArgsFunc(cmdShowHosts, "IPs", []string{"IP1"})
@@ -155,21 +172,32 @@ Use the -x flag to render screen output without pretty formatting.
 func newHostEditCmd() *cobra.Command {
 
 	cmdEditHost := &cobra.Command{
-		Use:   "edit NAME {[-p POLICY] [-d HOSTNAME] [-b BOOT] [-e ETH] [-i IP] [-m MACID]}",
+		Use:   "edit NODES {[-p POLICY] [-d HOSTNAME] [-b BOOT] [-e ETH] [-i IP] [-m MACID] [--poll-interval SECONDS] [--console NAME]}",
 		Short: "Edit host information " + adminOnly,
 		Long: `
 Edits host information.
 
-Editing a host forces an update to the 'igor-clusters.yaml' file with the 
-previous version backed up under a modified name.
+Editing hosts forces an update to the 'igor-clusters.yaml' file with the 
+previous version backed up under a modified name. The file is rewritten a few
+seconds after the edit so that changes made close together, including all the
+hosts of a single edit, are saved with one rewrite and one backup.
+
+The result for each host is shown as changed, unchanged (the host already had
+the given values) or error.
 
 ` + requiredArgs + `
-  NAME : host name
+  NODES : a host name or range of hosts
+    * name list is comma-delimited: kn1,kn2,kn3,...
+    * range is the form prefix[n,m-n,...] where m,n are integers representing
+      a single or contiguous ranges of hosts, ex. kn[3,7-9,22-35,47]
 
 ` + optionalFlags + `
 
-Use the -p flag to assign a policy to a host. Mass policy/host assignment is
-possible using the 'igor policy apply' command.
+The -d, -i, -m and --console flags can only be used when editing a single host
+since each host must have its own hostname, IP, MAC address and console name.
+
+Use the -p flag to assign a policy to the hosts. This is the same as using the
+'igor policy apply' command.
 
 Use the -d flag to set a hostname or host alias that is different from the
 host's name. Igor assumes the host's hostname follows the convention:
@@ -185,10 +213,26 @@ Use the -b flag to change the boot type of the host (bios or uefi).
 
 Use the -i flag to change the host's IP.
 
-Use the -e flag to change the host's ethernet switch identifier.
+Use the -e flag to change the host's ethernet switch identifier. A host with
+more than one interface cabled to the switch gives every port along with the
+role of its interface (data, mgmt or extra), for example:
+
+  igor host edit kn7 --eth data=Ethernet1/7,mgmt=Ethernet2/7
+
+This replaces all of the host's ports. When a host with several ports is shown,
+the ones the server puts in a reservation's VLAN are marked with *.
 
 Use the -m flag to change the MAC address.
 
+Use the --poll-interval flag to change how often, in seconds, the host's power
+status is polled when the server is configured with a power status command.
+Use 0 to go back to the server's default interval.
+
+Use the --console flag to set the name of the host on the console server when
+it isn't the host's igor name. It is used to make the host's console link when
+the server has a console URL configured. Use an empty name (--console "") to
+go back to using the host's igor name.
+
 ` + adminOnlyBanner + `
 `,
 		Args: cobra.ExactArgs(1),
@@ -200,7 +244,16 @@ Use the -m flag to change the MAC address.
 			ip, _ := flagset.GetString("ip")
 			eth, _ := flagset.GetString("eth")
 			mac, _ := flagset.GetString("mac")
-			printRespSimple(doEditHost(args[0], boot, hostname, hostPolicy, ip, eth, mac))
+			pollInterval := -1
+			if flagset.Changed("poll-interval") {
+				pollInterval, _ = flagset.GetInt("poll-interval")
+			}
+			var console *string
+			if flagset.Changed("console") {
+				consoleName, _ := flagset.GetString("console")
+				console = &consoleName
+			}
+			printHostEdit(doEditHost(args[0], boot, hostname, hostPolicy, ip, eth, mac, pollInterval, console))
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNameArg,
@@ -211,19 +264,25 @@ Use the -m flag to change the MAC address.
 		eth,
 		hostname,
 		hostPolicy,
-		mac string
+		mac,
+		console string
+	var pollInterval int
 
 	cmdEditHost.Flags().StringVarP(&hostPolicy, "policy", "p", "", "name of policy to assign to this host")
 	cmdEditHost.Flags().StringVarP(&hostname, "hostname", "d", "", "hostname of the host")
 	cmdEditHost.Flags().StringVarP(&boot, "boot", "b", "", "boot type of the host (bios or uefi)")
 	cmdEditHost.Flags().StringVarP(&ip, "ip", "i", "", "ipv4 address")
 	cmdEditHost.Flags().StringVarP(&mac, "mac", "m", "", "MAC address")
-	cmdEditHost.Flags().StringVarP(&eth, "eth", "e", "", "eth config string")
+	cmdEditHost.Flags().StringVarP(&eth, "eth", "e", "", "switch port, or role=port pairs for each port of the host")
+	cmdEditHost.Flags().IntVar(&pollInterval, "poll-interval", 0, "seconds between power status polls (0 for default)")
+	cmdEditHost.Flags().StringVar(&console, "console", "", "name of the host on the console server (empty for the host name)")
 	_ = registerFlagArgsFunc(cmdEditHost, "policy", []string{"POLICY"})
 	_ = registerFlagArgsFunc(cmdEditHost, "hostname", []string{"HOSTNAME"})
 	_ = registerFlagArgsFunc(cmdEditHost, "ip", []string{"IP"})
 	_ = registerFlagArgsFunc(cmdEditHost, "mac", []string{"MACID"})
 	_ = registerFlagArgsFunc(cmdEditHost, "eth", []string{"ETH"})
+	_ = registerFlagArgsFunc(cmdEditHost, "poll-interval", []string{"SECONDS"})
+	_ = registerFlagArgsFunc(cmdEditHost, "console", []string{"NAME"})
 
 	return cmdEditHost
 }
@@ -273,9 +332,10 @@ Executes the given power command on a set of hosts specified either explicitly
 or through a reservation name.
 
 Power commands can be executed by any admin or any user that owns or belongs
-to a group that has an active reservation on the specified hosts. Power 
-commands will not be honored if the network status of the node is reported to
-be in an error state.
+to a group that has an active reservation on the specified hosts, unless the
+policy of one of the hosts makes power commands admin-only. Power commands will
+not be honored if the network status of the node is reported to be in an error
+state.
 
 ` + requiredArgs + ` (choose one)
 
@@ -296,18 +356,19 @@ be in an error state.
 ` + notesOnUsage + `
 
 Power commands are routed through Igor to an external IPMI service that tells
-igor immediately if the command was successfully submitted. The actual booting
-of a host can fail for many other reasons. Attempts to power command a node
-should therefore be followed up with close monitoring to check that the boot
-completed, sometimes taking as long as a few minutes before the power status
-changes.
+igor immediately if the command was successfully submitted for each host. Hosts
+the command failed for are listed with the reason, and the exit status is
+non-zero if there were any. The actual booting of a host can fail for many other
+reasons. Attempts to power command a node should therefore be followed up with
+close monitoring to check that the boot completed, sometimes taking as long as a
+few minutes before the power status changes.
 `,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			flagset := cmd.Flags()
 			nodes, _ := flagset.GetString("nodes")
 			reservation, _ := flagset.GetString("res")
-			printRespSimple(doPowerHosts(args[0], nodes, reservation))
+			printBatchResults(doPowerHosts(args[0], nodes, reservation))
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -332,7 +393,7 @@ changes.
 func newHostBlockCmd() *cobra.Command {
 
 	cmdBlockHosts := &cobra.Command{
-		Use:   "block NODES",
+		Use:   "block NODES [--reason REASON] [--fail-fast]",
 		Short: "Block hosts from being reserved " + adminOnly,
 		Long: `
 Blocks hosts from being reserved. In this state hosts are unavailable for
@@ -357,13 +418,23 @@ A host cannot be blocked if it has any current or future reservation; the
 reservation must expire, be deleted, or edited to drop the node first.
 
 Blocked hosts will still be displayed in 'igor show' but with an indicator of
-their blocked status.
+their blocked status. 'igor host show' also lists who blocked each host, when
+and why.
+
+` + optionalFlags + `
+
+Use the --reason flag to record why the hosts are being blocked, ex. "bad DIMM".
+The reason is cleared when the hosts are unblocked.
+
+` + failFastUsage + `
 
 ` + adminOnlyBanner + `
 `,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			printRespSimple(doBlockHost(true, args[0]))
+			failFast, _ := cmd.Flags().GetBool("fail-fast")
+			reason, _ := cmd.Flags().GetString("reason")
+			printBatchResults(doBlockHost(true, args[0], reason, failFast))
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -374,6 +445,11 @@ their blocked status.
 		},
 	}
 
+	var reason string
+	cmdBlockHosts.Flags().StringVar(&reason, "reason", "", "reason the hosts are being blocked")
+	cmdBlockHosts.Flags().Bool("fail-fast", false, "stop at the first host that fails and change none")
+	_ = registerFlagArgsFunc(cmdBlockHosts, "reason", []string{"REASON"})
+
 	return cmdBlockHosts
 
 }
@@ -381,7 +457,7 @@ their blocked status.
 func newHostUnblockCmd() *cobra.Command {
 
 	cmdUnblockHosts := &cobra.Command{
-		Use:   "unblock NODES",
+		Use:   "unblock NODES [--fail-fast]",
 		Short: "Return hosts to reservable status " + adminOnly,
 		Long: `
 Removes a blocked status on one or more nodes. See the help section of the
@@ -396,11 +472,16 @@ Once executed the specified hosts will be able to accept reservations.
     * range is the form prefix[n,m-n,...] where m,n are integers representing
       a single or contiguous ranges of hosts, ex. kn[3,7-9,22-35,47]
 
+` + optionalFlags + `
+
+` + failFastUsage + `
+
 ` + adminOnlyBanner + `
 `,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			printRespSimple(doBlockHost(false, args[0]))
+			failFast, _ := cmd.Flags().GetBool("fail-fast")
+			printBatchResults(doBlockHost(false, args[0], "", failFast))
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -411,9 +492,174 @@ Once executed the specified hosts will be able to accept reservations.
 		},
 	}
 
+	cmdUnblockHosts.Flags().Bool("fail-fast", false, "stop at the first host that fails and change none")
+
 	return cmdUnblockHosts
 }
 
+func newHostDrainCmd() *cobra.Command {
+
+	cmdDrainHosts := &cobra.Command{
+		Use:   "drain NODES [--reason REASON]",
+		Short: "Let hosts finish their current reservation then block them " + adminOnly,
+		Long: `
+Drains hosts. A draining host lets its current reservation run to completion
+but is excluded from all new reservations. Extensions of any reservation using
+a draining host are refused.
+
+When the reservation running on a draining host ends, the host is moved to the
+blocked state so it stays out of the reservation pool until an admin acts on
+it. A host with no active reservation has nothing to drain and is blocked
+immediately.
+
+` + requiredArgs + `
+
+  NODES  - a name list or range of hosts
+    * name list is comma-delimited: kn1,kn2,kn3,...
+    * range is the form prefix[n,m-n,...] where m,n are integers representing
+      a single or contiguous ranges of hosts, ex. kn[3,7-9,22-35,47]
+
+` + optionalFlags + `
+
+Use the --reason flag to record why the host is being drained. The reason is
+displayed in 'igor host show' and in the message given to users who attempt
+to extend a reservation on the host.
+
+Draining hosts are displayed in 'igor show' with their own indicator.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			reason, _ := cmd.Flags().GetString("reason")
+			printRespSimple(doDrainHost(true, args[0], reason))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return []string{"NODES"}, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+
+	var reason string
+	cmdDrainHosts.Flags().StringVar(&reason, "reason", "", "reason the hosts are being drained")
+	_ = registerFlagArgsFunc(cmdDrainHosts, "reason", []string{"REASON"})
+
+	return cmdDrainHosts
+}
+
+func newHostExplainCmd() *cobra.Command {
+
+	cmdExplainHost := &cobra.Command{
+		Use:   "explain NODE --user USER [--at DATETIME] [--duration DURATION] [--json]",
+		Short: "Explain whether a user can reserve a host " + adminOnly,
+		Long: `
+Explains whether a user is able to reserve a host by name during a window of
+time. Each check igor makes when a reservation is created is evaluated in order
+and reported as passing or failing:
+
+  host state              : the host is not blocked, draining or in error
+  policy group membership : the user belongs to an access group of the host's
+                            policy
+  policy unavailability   : the host's policy doesn't make it unavailable
+                            during the window
+  max reservation time    : the window fits the policy's time limit and the
+                            scheduling window
+  reservation conflicts   : no other reservation overlaps the window
+  node reserve limit      : the node count doesn't exceed the server limit
+
+The first failing check and its reason are called out at the end. The checks
+are the same ones used when creating a reservation so the result matches what
+the user would see.
+
+` + requiredArgs + `
+
+  NODE : host name
+
+` + requiredFlags + `
+
+  --user USER : the user to evaluate access for
+
+` + optionalFlags + `
+
+Use the --at flag to set the start of the window using the format:
+` + exStartDts() + `. If not given, the window starts now.
+
+Use the --duration flag to set the length of the window. This can be an integer
+value in minutes or a duration string like 2d or 3h30m. If not given, the
+server's default reservation duration is used.
+
+Use the --json flag to print the result as JSON.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			userName, _ := flagset.GetString("user")
+			at, _ := flagset.GetString("at")
+			dur, _ := flagset.GetString("duration")
+			asJson, _ := flagset.GetBool("json")
+			printHostExplain(doExplainHost(args[0], userName, at, dur), asJson)
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNameArg,
+	}
+
+	var userName, at, dur string
+	var asJson bool
+	cmdExplainHost.Flags().StringVarP(&userName, "user", "u", "", "user to evaluate access for")
+	cmdExplainHost.Flags().StringVar(&at, "at", "", "start of the reservation window")
+	cmdExplainHost.Flags().StringVar(&dur, "duration", "", "length of the reservation window")
+	cmdExplainHost.Flags().BoolVar(&asJson, "json", false, "print the result as JSON")
+	_ = cmdExplainHost.MarkFlagRequired("user")
+	_ = registerFlagArgsFunc(cmdExplainHost, "user", []string{"USER"})
+	_ = registerFlagArgsFunc(cmdExplainHost, "at", []string{"DATETIME"})
+	_ = registerFlagArgsFunc(cmdExplainHost, "duration", []string{"DURATION"})
+
+	return cmdExplainHost
+}
+
+func newHostUndrainCmd() *cobra.Command {
+
+	cmdUndrainHosts := &cobra.Command{
+		Use:   "undrain NODES",
+		Short: "Return draining hosts to reservable status " + adminOnly,
+		Long: `
+Removes a draining status on one or more nodes. See the help section of the
+'igor host drain' command for info on draining nodes.
+
+Once executed the specified hosts will be able to accept reservations. Hosts
+that have already finished draining are blocked and must be returned to
+service with 'igor host unblock' instead.
+
+` + requiredArgs + `
+
+  NODES  - a name list or range of hosts
+    * name list is comma-delimited: kn1,kn2,kn3,...
+    * range is the form prefix[n,m-n,...] where m,n are integers representing
+      a single or contiguous ranges of hosts, ex. kn[3,7-9,22-35,47]
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printRespSimple(doDrainHost(false, args[0], ""))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return []string{"NODES"}, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+
+	return cmdUndrainHosts
+}
+
 func doShowHosts(names string, hostnames []string, eths []string, ips []string, macs []string, hostPolicies []string, reservations []string, states []string, powered *bool) *common.ResponseBodyHosts {
 
 	var params string
@@ -470,7 +716,7 @@ func doShowHosts(names string, hostnames []string, eths []string, ips []string,
 	return &rb
 }
 
-func doEditHost(name, boot, hostname, hostPolicy, ip, eth, mac string) *common.ResponseBodyBasic {
+func doEditHost(name, boot, hostname, hostPolicy, ip, eth, mac string, pollInterval int, console *string) *common.ResponseBodyHostEdit {
 	apiPath := api.Hosts + "/" + name
 	params := make(map[string]interface{})
 	if hostname != "" {
@@ -491,8 +737,38 @@ func doEditHost(name, boot, hostname, hostPolicy, ip, eth, mac string) *common.R
 	if mac != "" {
 		params["mac"] = mac
 	}
+	if pollInterval >= 0 {
+		params["pollInterval"] = pollInterval
+	}
+	if console != nil {
+		params["console"] = *console
+	}
 	body := doSend(http.MethodPatch, apiPath, params)
-	return unmarshalBasicResponse(body)
+	rb := common.ResponseBodyHostEdit{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func printHostEdit(rb *common.ResponseBodyHostEdit) {
+
+	results := rb.Data["hosts"]
+	if !rb.IsSuccess() || len(results) <= 1 {
+		printRespSimple(rb)
+	}
+
+	checkColorLevel()
+	for _, hr := range results {
+		switch hr.Result {
+		case "changed":
+			fmt.Printf("  %-12s %s\n", hr.Host, cRespSuccess.Sprint(hr.Result))
+		case "unchanged":
+			fmt.Printf("  %-12s %s\n", hr.Host, hr.Result)
+		default:
+			fmt.Printf("  %-12s %s - %s\n", hr.Host, cRespWarn.Sprint(hr.Result), hr.Error)
+		}
+	}
+	printRespSimple(rb)
 }
 
 func doDeleteHost(name string) *common.ResponseBodyBasic {
@@ -501,7 +777,7 @@ func doDeleteHost(name string) *common.ResponseBodyBasic {
 	return unmarshalBasicResponse(body)
 }
 
-func doPowerHosts(command string, nodes string, reservation string) *common.ResponseBodyBasic {
+func doPowerHosts(command string, nodes string, reservation string) *common.ResponseBodyBatch {
 	params := make(map[string]interface{})
 	params["cmd"] = command
 	// let the server reject if both are blank/set
@@ -513,17 +789,49 @@ func doPowerHosts(command string, nodes string, reservation string) *common.Resp
 	}
 
 	body := doSend(http.MethodPatch, api.HostsPower, params)
-	return unmarshalBasicResponse(body)
+	return unmarshalBatchResponse(body)
 }
 
-func doBlockHost(block bool, hosts string) *common.ResponseBodyBasic {
+func doBlockHost(block bool, hosts string, reason string, failFast bool) *common.ResponseBodyBatch {
 	params := make(map[string]interface{})
 	params["block"] = block
 	params["hosts"] = hosts
+	if reason != "" {
+		params["reason"] = reason
+	}
+	if failFast {
+		params["failFast"] = true
+	}
 	body := doSend(http.MethodPatch, api.HostsBlock, params)
+	return unmarshalBatchResponse(body)
+}
+
+func doDrainHost(drain bool, hosts string, reason string) *common.ResponseBodyBasic {
+	params := make(map[string]interface{})
+	params["drain"] = drain
+	params["hosts"] = hosts
+	if reason != "" {
+		params["reason"] = reason
+	}
+	body := doSend(http.MethodPatch, api.HostsDrain, params)
 	return unmarshalBasicResponse(body)
 }
 
+// powerPollInfo returns details about the host's power status polling when the server provides them.
+func powerPollInfo(h common.HostData) string {
+	var info string
+	if h.PowerChecked > 0 {
+		info += "\nchecked " + getLocTime(time.Unix(h.PowerChecked, 0)).Format(common.DateTimeCompactFormat)
+	}
+	if h.PowerPollInterval > 0 {
+		info += fmt.Sprintf("\nevery %ds", h.PowerPollInterval)
+	}
+	if h.PowerPollLatency != "" {
+		info += "\ntook " + h.PowerPollLatency
+	}
+	return info
+}
+
 func printHosts(rb *common.ResponseBodyHosts) {
 
 	checkAndSetColorLevel(rb)
@@ -545,6 +853,8 @@ func printHosts(rb *common.ResponseBodyHosts) {
 			return hsReserved.Sprint(state)
 		case "blocked":
 			return cBlockedUp.Sprint(state)
+		case "draining":
+			return cDrainingUp.Sprint(state)
 		default:
 			return cInstError.Sprintf(state)
 		}
@@ -563,24 +873,93 @@ func printHosts(rb *common.ResponseBodyHosts) {
 		}
 	}
 
+	// console links are long so they are only shown in simple output
+	showConsole := false
+	if simplePrint {
+		for _, h := range hosts {
+			if h.Console != "" {
+				showConsole = true
+				break
+			}
+		}
+	}
+
+	// simple output gives who blocked each host, when and why their own columns
+	showBlocked := false
+	if simplePrint {
+		for _, h := range hosts {
+			if h.BlockedAt != 0 {
+				showBlocked = true
+				break
+			}
+		}
+	}
+	_, blockedFmt := resTimeLayouts(cliDateFormat(), false, simplePrint)
+
+	// usage is only sent to admins
+	showUsage := false
+	for _, h := range hosts {
+		if h.UsageHours > 0 {
+			showUsage = true
+			break
+		}
+	}
+
 	tw := table.NewWriter()
-	tw.AppendHeader(table.Row{"NODE", "STATE", "POWER", "BOOT-TYPE", "MACID", "HOSTNAME", "IP", "ETH", "POLICY", "ACCESS-GROUPS", "RESTRICTED", "RESERVATIONS"})
+	header := table.Row{"NODE", "STATE", "POWER", "BOOT-TYPE", "MACID", "HOSTNAME", "IP", "ETH", "POLICY", "ACCESS-GROUPS", "RESTRICTED", "RESERVATIONS"}
+	if showBlocked {
+		header = append(header, "BLOCKED-BY", "BLOCKED-AT", "BLOCK-REASON")
+	}
+	if showUsage {
+		header = append(header, "USAGE-HRS")
+	}
+	if showConsole {
+		header = append(header, "CONSOLE")
+	}
+	tw.AppendHeader(header)
 
 	for _, h := range hosts {
-		tw.AppendRow([]interface{}{
+		state := stateColor(h.State)
+		if h.DrainReason != "" {
+			state += "\n" + h.DrainReason
+		}
+		if h.BlockedAt != 0 && !simplePrint {
+			state += "\nby " + h.BlockedBy + "\n" + getLocTime(time.Unix(h.BlockedAt, 0)).Format(blockedFmt)
+			if h.BlockReason != "" {
+				state += "\n" + h.BlockReason
+			}
+		}
+		if h.InstallError != "" {
+			state += "\n" + cInstError.Sprint("install error")
+		}
+		row := table.Row{
 			sBold(h.Name),
-			stateColor(h.State),
-			powerColor(h.Powered),
+			state,
+			powerColor(h.Powered) + powerPollInfo(h),
 			h.BootMode,
 			h.Mac,
 			h.HostName,
 			h.IP,
-			h.Eth,
+			hostPortsInfo(h),
 			h.HostPolicy,
-			strings.Join(h.AccessGroups, "\n"),
+			sortedJoin(h.AccessGroups, "\n"),
 			h.Restricted,
 			strings.Join(h.Reservations, "\n"),
-		})
+		}
+		if showBlocked {
+			var blockedAt string
+			if h.BlockedAt != 0 {
+				blockedAt = getLocTime(time.Unix(h.BlockedAt, 0)).Format(blockedFmt)
+			}
+			row = append(row, h.BlockedBy, blockedAt, h.BlockReason)
+		}
+		if showUsage {
+			row = append(row, h.UsageHours)
+		}
+		if showConsole {
+			row = append(row, h.Console)
+		}
+		tw.AppendRow(row)
 	}
 
 	if simplePrint {
@@ -594,3 +973,211 @@ func printHosts(rb *common.ResponseBodyHosts) {
 	fmt.Printf("\n" + tw.Render() + "\n\n")
 
 }
+
+func newHostHistoryCmd() *cobra.Command {
+
+	cmdHostHistory := &cobra.Command{
+		Use:   "history NODE --from DATETIME [--to DATETIME] [--json]",
+		Short: "Show the reservations that held a host during a time window",
+		Long: `
+Shows the reservations that held a host at any point during a window of time,
+including reservations that are over or were deleted early. Each is listed
+with its owner, group, profile and distro and the times it actually held the
+host, so a reservation deleted early ends when it was deleted and a host that
+was dropped from a reservation ends when it was dropped. The OUTCOME column
+shows how a reservation that is over ended.
+
+The results come from the reservation history igor keeps, so they reach back
+as far as those records do.
+
+` + requiredArgs + `
+
+  NODE : host name
+
+` + requiredFlags + `
+
+  --from DATETIME : start of the time window
+
+` + optionalFlags + `
+
+Use the --to flag to set the end of the time window. If not given, the window
+ends now. Both flags use the format: ` + exStartDts() + `.
+
+Use the --json flag to print the result as JSON.
+
+Host history requires admin elevated privilege unless the server allows
+owners to look it up, in which case the owner or a co-owner of a running
+reservation can see the history of the hosts it holds.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			from, _ := flagset.GetString("from")
+			to, _ := flagset.GetString("to")
+			asJson, _ := flagset.GetBool("json")
+			printHostHistory(args[0], doHostHistory(args[0], from, to), asJson)
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNameArg,
+	}
+
+	var from, to string
+	var asJson bool
+	cmdHostHistory.Flags().StringVar(&from, "from", "", "start of the time window")
+	cmdHostHistory.Flags().StringVar(&to, "to", "", "end of the time window")
+	cmdHostHistory.Flags().BoolVar(&asJson, "json", false, "print the result as JSON")
+	_ = cmdHostHistory.MarkFlagRequired("from")
+	_ = registerFlagArgsFunc(cmdHostHistory, "from", []string{"DATETIME"})
+	_ = registerFlagArgsFunc(cmdHostHistory, "to", []string{"DATETIME"})
+
+	return cmdHostHistory
+}
+
+func doExplainHost(name, userName, at, dur string) *common.ResponseBodyHostExplain {
+
+	params := url.Values{}
+	params.Set("host", name)
+	params.Set("user", userName)
+	if at != "" {
+		if _, err := common.ParseTimeFormat(at); err != nil {
+			checkClientErr(err)
+		}
+		startTime, _ := time.ParseInLocation(common.DateTimeCompactFormat, at, cli.tzLoc)
+		params.Set("start", strconv.FormatInt(startTime.Unix(), 10))
+	}
+	if dur != "" {
+		params.Set("duration", dur)
+	}
+
+	body := doSend(http.MethodGet, api.HostsExplain+"?"+params.Encode(), nil)
+	rb := common.ResponseBodyHostExplain{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func doHostHistory(name, from, to string) *common.ResponseBodyHostHistory {
+
+	params := url.Values{}
+	params.Set("host", name)
+	for key, val := range map[string]string{"from": from, "to": to} {
+		if val == "" {
+			continue
+		}
+		if _, err := common.ParseTimeFormat(val); err != nil {
+			checkClientErr(err)
+		}
+		t, _ := time.ParseInLocation(common.DateTimeCompactFormat, val, cli.tzLoc)
+		params.Set(key, strconv.FormatInt(t.Unix(), 10))
+	}
+
+	body := doSend(http.MethodGet, api.HostsHistory+"?"+params.Encode(), nil)
+	rb := common.ResponseBodyHostHistory{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func printHostHistory(name string, rb *common.ResponseBodyHostHistory, asJson bool) {
+
+	if !rb.IsSuccess() {
+		if asJson {
+			printRespJsonFailure(rb)
+		}
+		printRespSimple(rb)
+	}
+
+	history := rb.Data["history"]
+	if asJson {
+		historyData, err := json.MarshalIndent(history, "", "   ")
+		if err != nil {
+			checkClientErr(err)
+		}
+		fmt.Println(string(historyData))
+		return
+	}
+
+	if len(history) == 0 {
+		printRespSimple(rb)
+		return
+	}
+
+	checkColorLevel()
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"RESERVATION", "OWNER", "GROUP", "PROFILE", "DISTRO", "START", "END", "OUTCOME"})
+	for _, h := range history {
+		tw.AppendRow(table.Row{
+			sBold(h.Reservation),
+			h.Owner,
+			h.Group,
+			h.Profile,
+			h.Distro,
+			getLocTime(time.Unix(h.Start, 0)).Format(common.DateTimeCompactFormat),
+			getLocTime(time.Unix(h.End, 0)).Format(common.DateTimeCompactFormat),
+			h.Outcome,
+		})
+	}
+	tw.SetStyle(igorTableStyle)
+
+	fmt.Printf("\nreservations holding host %s\n", sBold(name))
+	fmt.Printf("\n" + tw.Render() + "\n\n")
+}
+
+func printHostExplain(rb *common.ResponseBodyHostExplain, asJson bool) {
+
+	if !rb.IsSuccess() {
+		if asJson {
+			printRespJsonFailure(rb)
+		}
+		printRespSimple(rb)
+	}
+
+	explain := rb.Data["explain"]
+	if asJson {
+		explainData, err := json.MarshalIndent(explain, "", "   ")
+		if err != nil {
+			checkClientErr(err)
+		}
+		fmt.Println(string(explainData))
+		return
+	}
+
+	checkColorLevel()
+	start := getLocTime(time.Unix(explain.Start, 0)).Format(common.DateTimeCompactFormat)
+	end := getLocTime(time.Unix(explain.End, 0)).Format(common.DateTimeCompactFormat)
+	elevated := ""
+	if explain.Elevated {
+		elevated = " (elevated)"
+	}
+	fmt.Printf("\nhost %s for user %s%s from %s to %s\n\n", sBold(explain.Host), sBold(explain.User), elevated, start, end)
+
+	for _, g := range explain.Gates {
+		result := pUp.Sprint("PASS")
+		if !g.Pass {
+			result = pDown.Sprint("FAIL")
+		}
+		fmt.Printf("  %s  %-24s %s\n", result, g.Name, g.Reason)
+	}
+
+	if explain.Allowed {
+		fmt.Printf("\n%s\n\n", cRespSuccess.Sprint("the user can reserve this host for the given time"))
+	} else {
+		fmt.Printf("\n%s %s\n\n", cRespWarn.Sprint("the user cannot reserve this host - first failure:"), explain.FirstFailure)
+	}
+}
+
+// hostPortsInfo returns the switch ports of a host for display. A host with only a data port shows
+// just the port as it always has.
+func hostPortsInfo(h common.HostData) string {
+	if len(h.Ports) <= 1 {
+		return h.Eth
+	}
+	lines := make([]string, len(h.Ports))
+	for i, p := range h.Ports {
+		lines[i] = p.Role + "=" + p.Port
+		if p.Isolated {
+			lines[i] += "*"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
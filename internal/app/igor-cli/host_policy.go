@@ -5,11 +5,15 @@
 package igorcli
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"igor2/internal/pkg/api"
 	"net/http"
+	"net/url"
+	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +21,7 @@ import (
 	"github.com/jedib0t/go-pretty/v6/text"
 
 	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
 
 	"github.com/spf13/cobra"
 )
@@ -31,8 +36,8 @@ Policy primary command. A sub-command must be invoked to do anything.
 
 Policies define access restrictions to the host they are assigned to. These
 restrictions can include specific groups that can access the host, the max time
-the host can be reserved, and scheduling times when the host is unavailable to
-be reserved.
+the host can be reserved, scheduling times when the host is unavailable to be
+reserved, and how many of the policy's hosts one user can hold at once.
 
 Policies are a powerful tool for tailoring parts of a cluster (or all of it)
 with different rules about reservation scheduling.
@@ -77,11 +82,11 @@ which only applies the default max duration of a reservation to all nodes.
 func newHostPolicyCreateCmd() *cobra.Command {
 
 	cmdCreateHostPolicy := &cobra.Command{
-		Use:   "create NAME {[-t MAXTIME -g GRP1,... -u \"EXP1\",...]}",
+		Use:   "create NAME {[-t MAXTIME -g GRP1,... -e GRP1,... -u \"EXP1\",... --max-per-user N]}",
 		Short: "Create a policy " + adminOnly,
 		Long: `
 Creates a new igor policy. A policy is a defined set of restrictions that can
-be assigned to hosts. All three varieties of policy restrictions may be
+be assigned to hosts. All of the policy restrictions below may be
 combined into a single policy.
 
 See 'igor policy -h' for a description of what a policy is and use cases.
@@ -105,6 +110,12 @@ this policy is associated with. A reservation must specify a group that matches
 to this list in order to use this policy's hosts. Policies that don't use this
 flag allow any user to reserve a host.
 
+Use the -e flag to set one or more groups whose members can never reserve the
+hosts this policy is associated with, even if they are in a group given with -g
+or the policy is open to all users. Exclusions are checked after the groups
+allowed access.
+Ex. -e contractors (all users except members of contractors)
+
 ` + sBold("RESTRICT BY SCHEDULE:") + `
 
 Use the -u flag to set one or more periods during which the policy will not
@@ -128,6 +139,13 @@ given in the same form as the -t flag described above.
 
 Together, a complete expression would look like "0 0 * * 6:3d2h"
 
+` + sBold("RESTRICT BY HOSTS PER USER:") + `
+
+Use the --max-per-user flag to cap how many of this policy's hosts one user can
+hold at once, counting the hosts in all of their current and future
+reservations. A reservation or extension that would take a user over the cap
+is refused. Elevated admins are exempt. The default of 0 sets no cap.
+
 ` + adminOnlyBanner + `
 `,
 		Example: `
@@ -141,8 +159,10 @@ until Jan 2 at 8 AM.
 			flagset := cmd.Flags()
 			maxResTime, _ := flagset.GetString("max-time")
 			groups, _ := flagset.GetStringSlice("groups")
+			excluded, _ := flagset.GetStringSlice("exclude-groups")
 			unavailable, _ := flagset.GetStringSlice("unavail")
-			if res, err := doCreateHostPolicy(args[0], maxResTime, groups, unavailable); err != nil {
+			maxPerUser, _ := flagset.GetInt("max-per-user")
+			if res, err := doCreateHostPolicy(args[0], maxResTime, groups, excluded, unavailable, maxPerUser); err != nil {
 				return err
 			} else {
 				printRespSimple(res)
@@ -150,18 +170,23 @@ until Jan 2 at 8 AM.
 			}
 		},
 		DisableFlagsInUseLine: true,
-		ValidArgsFunction:     validateNameArg,
+		ValidArgsFunction:     validateNewNameArg(naming.Policy),
 	}
 
 	var maxTime string
-	var groups, unavailable []string
+	var groups, excluded, unavailable []string
+	var maxPerUser int
 
 	cmdCreateHostPolicy.Flags().StringVarP(&maxTime, "max-time", "t", "", "max time limit for reserving hosts assigned to this policy")
 	cmdCreateHostPolicy.Flags().StringSliceVarP(&groups, "groups", "g", nil, "comma-delimited list of groups to grant access")
+	cmdCreateHostPolicy.Flags().StringSliceVarP(&excluded, "exclude-groups", "e", nil, "comma-delimited list of groups to deny access")
 	cmdCreateHostPolicy.Flags().StringSliceVarP(&unavailable, "unavail", "u", nil, "comma-delimited list of schedule block entries")
+	cmdCreateHostPolicy.Flags().IntVar(&maxPerUser, "max-per-user", 0, "most of this policy's hosts one user can hold at once")
 	_ = registerFlagArgsFunc(cmdCreateHostPolicy, "max-time", []string{"MAXTIME"})
 	_ = registerFlagArgsFunc(cmdCreateHostPolicy, "groups", []string{"GRP1"})
+	_ = registerFlagArgsFunc(cmdCreateHostPolicy, "exclude-groups", []string{"GRP1"})
 	_ = registerFlagArgsFunc(cmdCreateHostPolicy, "unavail", []string{"\"EXP1\""})
+	_ = registerFlagArgsFunc(cmdCreateHostPolicy, "max-per-user", []string{"N"})
 
 	return cmdCreateHostPolicy
 }
@@ -214,7 +239,11 @@ func newHostPolicyEditCmd() *cobra.Command {
 
 	cmdEditHostPolicy := &cobra.Command{
 		Use: "edit NAME { [-n NEWNAME] [-t MAXTIME] [-g GRP1,...] [-r GRP1,...]\n" +
-			"            [-u \"EXP1\",...] [-x \"EXP1\",...] }",
+			"            [-e GRP1,...] [--remove-exclude-groups GRP1,...]\n" +
+			"            [--max-time-for GRP1=MAXTIME,...] [--remove-max-time-for GRP1,...]\n" +
+			"            [-u \"EXP1\",...] [-x \"EXP1\",...]\n" +
+			"            [--restrict-actions ACT1,...] [--allow-actions ACT1,...]\n" +
+			"            [--max-per-user N] }",
 		Short: "Edit a policy " + adminOnly,
 		Long: `
 Edits policy information.
@@ -236,13 +265,39 @@ unit-less number is treated as minutes. Days are defined as 24*60 minutes and
 do not take Daylight Savings offsets into account. 
 Ex. 3d | 5h32m | 12d2m | 90 (= 90m)
 
+Use the --max-time-for flag to give members of a group a different time limit
+on this policy's hosts than the one set with -t. The value is the group name and
+time limit joined by '=', using the same units as -t.
+Ex. --max-time-for ml-team=14d,ops=5d
+A user in more than one group with a time limit on the policy gets the longest
+one. Users in none of them get the limit set with -t. Use the
+--remove-max-time-for flag to remove the time limit of a group.
+
 Use the -g flag to add groups and the -r flag to remove groups from the policy.
 If the last group is removed from the policy, then all users will be able to
 reserve its hosts.
 
+Use the -e flag to exclude groups from the policy and the --remove-exclude-groups
+flag to remove exclusions. Members of an excluded group can't reserve the
+policy's hosts even if they are in one of its groups or the policy is open to
+all users.
+
 Use the -u flag to add unavailability periods and the -x flag to remove them
 from the policy.
 
+Members of a reservation's group can perform node actions on its hosts while
+the reservation is active: power (on/off/cycle), reimage and console (seeing
+the console link). Use the --restrict-actions flag to make one or more of these
+actions admin-only on the policy's hosts and the --allow-actions flag to give
+them back to reservation members.
+Ex. --restrict-actions reimage,console
+
+Use the --max-per-user flag to cap how many of the policy's hosts one user can
+hold at once across their current and future reservations. Elevated admins are
+exempt. A value of 0 removes the cap. Lowering the cap doesn't change existing
+reservations, but a user over it can't extend them or reserve more of the
+policy's hosts.
+
 ` + adminOnlyBanner + `
 `,
 		Args: cobra.ExactArgs(1),
@@ -252,9 +307,21 @@ from the policy.
 			maxResTime, _ := flagset.GetString("max-time")
 			groupAdd, _ := flagset.GetStringSlice("add-groups")
 			groupRemove, _ := flagset.GetStringSlice("remove-groups")
+			excludeAdd, _ := flagset.GetStringSlice("exclude-groups")
+			excludeRemove, _ := flagset.GetStringSlice("remove-exclude-groups")
 			unavailableAdd, _ := flagset.GetStringSlice("add-unavail")
 			unavailableRemove, _ := flagset.GetStringSlice("remove-unavail")
-			if res, err := doEditHostPolicy(args[0], name, maxResTime, groupAdd, groupRemove, unavailableAdd, unavailableRemove); err != nil {
+			groupLimits, _ := flagset.GetStringSlice("max-time-for")
+			groupLimitsRemove, _ := flagset.GetStringSlice("remove-max-time-for")
+			restrict, _ := flagset.GetStringSlice("restrict-actions")
+			allow, _ := flagset.GetStringSlice("allow-actions")
+			var maxPerUser *int
+			if flagset.Changed("max-per-user") {
+				n, _ := flagset.GetInt("max-per-user")
+				maxPerUser = &n
+			}
+			if res, err := doEditHostPolicy(args[0], name, maxResTime, groupAdd, groupRemove, excludeAdd, excludeRemove, unavailableAdd, unavailableRemove,
+				groupLimits, groupLimitsRemove, restrict, allow, maxPerUser); err != nil {
 				return err
 			} else {
 				printRespSimple(res)
@@ -269,21 +336,42 @@ from the policy.
 		duration string
 	var groupA,
 		groupR,
+		excludeA,
+		excludeR,
 		unavailableA,
-		unavailableR []string
+		unavailableR,
+		groupLimits,
+		groupLimitsR,
+		restrict,
+		allow []string
+	var maxPerUser int
 
 	cmdEditHostPolicy.Flags().StringVarP(&name, "name", "n", "", "new name to assign to this policy")
 	cmdEditHostPolicy.Flags().StringVarP(&duration, "max-time", "t", "", "max time limit for reservations under this policy")
 	cmdEditHostPolicy.Flags().StringSliceVarP(&groupA, "add-groups", "g", nil, "comma-delimited list of groups to grant access")
 	cmdEditHostPolicy.Flags().StringSliceVarP(&groupR, "remove-groups", "r", nil, "comma-delimited list of groups to remove access")
+	cmdEditHostPolicy.Flags().StringSliceVarP(&excludeA, "exclude-groups", "e", nil, "comma-delimited list of groups to deny access")
+	cmdEditHostPolicy.Flags().StringSliceVar(&excludeR, "remove-exclude-groups", nil, "comma-delimited list of groups to stop denying access")
 	cmdEditHostPolicy.Flags().StringSliceVarP(&unavailableA, "add-unavail", "u", nil, "comma-delimited list of schedule block entries to add")
 	cmdEditHostPolicy.Flags().StringSliceVarP(&unavailableR, "remove-unavail", "x", nil, "comma-delimited list of schedule block entries to remove")
+	cmdEditHostPolicy.Flags().StringSliceVar(&groupLimits, "max-time-for", nil, "comma-delimited list of group=time limits to set")
+	cmdEditHostPolicy.Flags().StringSliceVar(&groupLimitsR, "remove-max-time-for", nil, "comma-delimited list of groups to remove time limits from")
+	cmdEditHostPolicy.Flags().StringSliceVar(&restrict, "restrict-actions", nil, "comma-delimited list of node actions to make admin-only")
+	cmdEditHostPolicy.Flags().StringSliceVar(&allow, "allow-actions", nil, "comma-delimited list of node actions to give back to reservation members")
+	cmdEditHostPolicy.Flags().IntVar(&maxPerUser, "max-per-user", 0, "most of this policy's hosts one user can hold at once, 0 for no cap")
 	_ = registerFlagArgsFunc(cmdEditHostPolicy, "name", []string{"NAME"})
 	_ = registerFlagArgsFunc(cmdEditHostPolicy, "max-time", []string{"MAXTIME"})
 	_ = registerFlagArgsFunc(cmdEditHostPolicy, "add-groups", []string{"GRP1"})
 	_ = registerFlagArgsFunc(cmdEditHostPolicy, "remove-groups", []string{"GRP1"})
+	_ = registerFlagArgsFunc(cmdEditHostPolicy, "exclude-groups", []string{"GRP1"})
+	_ = registerFlagArgsFunc(cmdEditHostPolicy, "remove-exclude-groups", []string{"GRP1"})
 	_ = registerFlagArgsFunc(cmdEditHostPolicy, "add-unavail", []string{"EXP1"})
 	_ = registerFlagArgsFunc(cmdEditHostPolicy, "remove-unavail", []string{"EXP1"})
+	_ = registerFlagArgsFunc(cmdEditHostPolicy, "max-time-for", []string{"GRP1=MAXTIME"})
+	_ = registerFlagArgsFunc(cmdEditHostPolicy, "remove-max-time-for", []string{"GRP1"})
+	_ = registerFlagArgsFunc(cmdEditHostPolicy, "restrict-actions", []string{"power", "reimage", "console"})
+	_ = registerFlagArgsFunc(cmdEditHostPolicy, "allow-actions", []string{"power", "reimage", "console"})
+	_ = registerFlagArgsFunc(cmdEditHostPolicy, "max-per-user", []string{"N"})
 
 	return cmdEditHostPolicy
 }
@@ -317,11 +405,16 @@ the reservation expires or is deleted. Such a reservation can only be extended
 if the reservation's owner, group and time parameters are compliant with the
 new policy's restrictions.
 
+` + optionalFlags + `
+
+` + failFastUsage + `
+
 ` + adminOnlyBanner + `
 `,
 		Args: cobra.ExactArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
-			printRespSimple(doApplyHostPolicy(args[0], args[1]))
+			failFast, _ := cmd.Flags().GetBool("fail-fast")
+			printBatchResults(doApplyHostPolicy(args[0], args[1], failFast))
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -332,44 +425,101 @@ new policy's restrictions.
 		},
 	}
 
+	cmdApplyHostPolicy.Flags().Bool("fail-fast", false, "stop at the first node that fails and change none")
+
 	return cmdApplyHostPolicy
 }
 
 func newHostPolicyDelCmd() *cobra.Command {
 
 	cmdDeleteHostPolicy := &cobra.Command{
-		Use:   "del NAME",
+		Use:   "del NAME [--reassign-to[=POLICY] [--yes]]",
 		Short: "Delete a policy " + adminOnly,
 		Long: `
-Deletes an igor policy. A policy cannot be deleted if it is applied to a host.
-Change the policy on the affected host first.
+Deletes an igor policy. A policy cannot be deleted while it is applied to any
+nodes unless those nodes are moved to another policy at the same time.
 
 ` + requiredArgs + `
 
   NAME : policy name
 
+` + optionalFlags + `
+
+Use the --reassign-to flag to move any nodes that still have the policy to
+another one and delete the policy in a single step. If no POLICY is given the
+nodes go back to the default policy. The affected nodes are shown and you must
+type the policy name to confirm. Use the --yes flag to skip the confirmation,
+such as in scripts.
+
+Reservations already on the moved nodes are honored until they expire or are
+deleted, the same as when a policy is applied to nodes.
+
 ` + adminOnlyBanner + `
 `,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			printRespSimple(doDeleteHostPolicy(args[0]))
+			flagset := cmd.Flags()
+			var reassignTo *string
+			if flagset.Changed("reassign-to") {
+				policy, _ := flagset.GetString("reassign-to")
+				reassignTo = &policy
+				if yes, _ := flagset.GetBool("yes"); !yes {
+					confirmHostPolicyDelete(args[0], policy)
+				}
+			}
+			printRespSimple(doDeleteHostPolicy(args[0], reassignTo))
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNameArg,
 	}
 
+	var reassignTo string
+	var yes bool
+	cmdDeleteHostPolicy.Flags().StringVar(&reassignTo, "reassign-to", "", "move nodes with this policy to another policy (default policy if none given)")
+	cmdDeleteHostPolicy.Flags().Lookup("reassign-to").NoOptDefVal = "default"
+	cmdDeleteHostPolicy.Flags().BoolVarP(&yes, "yes", "y", false, "reassign nodes without asking for confirmation")
+
 	return cmdDeleteHostPolicy
 }
 
-func doCreateHostPolicy(name string, maxResTime string, groups []string, unavailable []string) (*common.ResponseBodyBasic, error) {
+// confirmHostPolicyDelete shows the nodes that will be moved to another policy and exits unless the
+// user types the name of the policy being deleted.
+func confirmHostPolicyDelete(name, reassignTo string) {
+	rb := doShowHostPolicy([]string{name}, nil, nil)
+	if !rb.IsSuccess() {
+		printRespSimple(rb)
+	}
+	hpList := rb.Data["hostPolicies"]
+	if len(hpList) == 0 || hpList[0].Hosts == "" {
+		return
+	}
+	fmt.Printf("nodes %s will be moved from policy '%s' to '%s'\n", hpList[0].Hosts, name, reassignTo)
+	fmt.Print("\ntype the policy name to delete it: ")
+	reader := bufio.NewReader(os.Stdin)
+	answer, _ := reader.ReadString('\n')
+	if strings.TrimSpace(answer) != name {
+		checkClientErr(fmt.Errorf("name did not match -- policy '%s' was not deleted", name))
+	}
+}
+
+func doCreateHostPolicy(name string, maxResTime string, groups []string, excluded []string, unavailable []string, maxPerUser int) (*common.ResponseBodyBasic, error) {
 
+	checkNewName(naming.Policy, name)
 	params := map[string]interface{}{"name": name}
 	if maxResTime != "" {
 		params["maxResTime"] = maxResTime
 	}
+	if maxPerUser < 0 {
+		return nil, fmt.Errorf("--max-per-user must not be negative")
+	} else if maxPerUser > 0 {
+		params["maxPerUser"] = maxPerUser
+	}
 	if len(groups) > 0 {
 		params["accessGroups"] = groups
 	}
+	if len(excluded) > 0 {
+		params["excludedGroups"] = excluded
+	}
 	if len(unavailable) > 0 {
 		var sb []map[string]string
 		for _, block := range unavailable {
@@ -419,10 +569,13 @@ func doShowHostPolicy(names []string, groups []string, hosts []string) *common.R
 	return &rb
 }
 
-func doEditHostPolicy(name string, newName string, maxResTime string, groupAdd []string, groupRemove []string, unavailableAdd []string, unavailableRemove []string) (*common.ResponseBodyBasic, error) {
+func doEditHostPolicy(name string, newName string, maxResTime string, groupAdd []string, groupRemove []string, excludeAdd []string, excludeRemove []string,
+	unavailableAdd []string, unavailableRemove []string,
+	groupLimits []string, groupLimitsRemove []string, restrictActions []string, allowActions []string, maxPerUser *int) (*common.ResponseBodyBasic, error) {
 	apiPath := api.HostPolicy + "/" + name
 	params := make(map[string]interface{})
 	if newName != "" {
+		checkNewName(naming.Policy, newName)
 		params["name"] = newName
 	}
 	if maxResTime != "" {
@@ -434,6 +587,12 @@ func doEditHostPolicy(name string, newName string, maxResTime string, groupAdd [
 	if len(groupRemove) > 0 {
 		params["removeGroups"] = groupRemove
 	}
+	if len(excludeAdd) > 0 {
+		params["addExcludedGroups"] = excludeAdd
+	}
+	if len(excludeRemove) > 0 {
+		params["removeExcludedGroups"] = excludeRemove
+	}
 	if len(unavailableAdd) > 0 {
 		var sba []map[string]string
 		for _, block := range unavailableAdd {
@@ -462,21 +621,53 @@ func doEditHostPolicy(name string, newName string, maxResTime string, groupAdd [
 			params["removeNotAvailable"] = sbr
 		}
 	}
+	if len(groupLimits) > 0 {
+		limits := make(map[string]string)
+		for _, gl := range groupLimits {
+			group, limit, found := strings.Cut(gl, "=")
+			if !found || strings.TrimSpace(group) == "" || strings.TrimSpace(limit) == "" {
+				return nil, fmt.Errorf("group time limit '%s' must be of the form GROUP=MAXTIME", gl)
+			}
+			limits[strings.TrimSpace(group)] = strings.TrimSpace(limit)
+		}
+		params["groupLimits"] = limits
+	}
+	if len(groupLimitsRemove) > 0 {
+		params["removeGroupLimits"] = groupLimitsRemove
+	}
+	if len(restrictActions) > 0 {
+		params["restrictActions"] = restrictActions
+	}
+	if len(allowActions) > 0 {
+		params["allowActions"] = allowActions
+	}
+	if maxPerUser != nil {
+		if *maxPerUser < 0 {
+			return nil, fmt.Errorf("--max-per-user must not be negative")
+		}
+		params["maxPerUser"] = *maxPerUser
+	}
 	body := doSend(http.MethodPatch, apiPath, params)
 	return unmarshalBasicResponse(body), nil
 }
 
-func doApplyHostPolicy(policyName string, nodeList string) *common.ResponseBodyBasic {
+func doApplyHostPolicy(policyName string, nodeList string, failFast bool) *common.ResponseBodyBatch {
 	params := make(map[string]interface{})
 	params["policy"] = policyName
 	params["nodeList"] = nodeList
+	if failFast {
+		params["failFast"] = true
+	}
 	apiPath := api.HostApplyPolicy
 	body := doSend(http.MethodPatch, apiPath, params)
-	return unmarshalBasicResponse(body)
+	return unmarshalBatchResponse(body)
 }
 
-func doDeleteHostPolicy(name string) *common.ResponseBodyBasic {
+func doDeleteHostPolicy(name string, reassignTo *string) *common.ResponseBodyBasic {
 	apiPath := api.HostPolicy + "/" + name
+	if reassignTo != nil {
+		apiPath += "?reassignTo=" + url.QueryEscape(*reassignTo)
+	}
 	body := doSend(http.MethodDelete, apiPath, nil)
 	return unmarshalBasicResponse(body)
 }
@@ -509,15 +700,27 @@ func printPolicies(rb *common.ResponseBodyPolicies) {
 			hpinfo = "POLICY: " + hp.Name + "\n"
 			hpinfo += "  -HOSTS:         " + hp.Hosts + "\n"
 			hpinfo += "  -MAX-RES-TIME:  " + common.FormatDuration(maxResTime, true) + "\n"
-			hpinfo += "  -ACCESS-GROUPS: " + strings.Join(hp.AccessGroups, ",") + "\n"
+			if len(hp.GroupLimits) > 0 {
+				hpinfo += "  -GROUP-LIMITS:  " + strings.Join(groupLimitLines(hp.GroupLimits, "="), ",") + "\n"
+			}
+			hpinfo += "  -ACCESS-GROUPS: " + sortedJoin(hp.AccessGroups, ",") + "\n"
+			if len(hp.ExcludedGroups) > 0 {
+				hpinfo += "  -EXCEPT-GROUPS: " + sortedJoin(hp.ExcludedGroups, ",") + "\n"
+			}
 			hpinfo += "  -NOT-AVAIL:     " + strings.Join(nas, ",") + "\n"
+			if hp.MaxPerUser > 0 {
+				hpinfo += "  -MAX-PER-USER:  " + strconv.Itoa(hp.MaxPerUser) + "\n"
+			}
+			if len(hp.RestrictedActions) > 0 {
+				hpinfo += "  -ADMIN-ONLY:    " + strings.Join(hp.RestrictedActions, ",") + "\n"
+			}
 			fmt.Print(hpinfo + "\n\n")
 		}
 
 	} else {
 
 		tw := table.NewWriter()
-		tw.AppendHeader(table.Row{"NAME", "HOSTS", "MAX-RES-TIME", "ACCESS-GROUPS", "NOT-AVAIL"})
+		tw.AppendHeader(table.Row{"NAME", "HOSTS", "MAX-RES-TIME", "GROUP-LIMITS", "ACCESS-GROUPS", "NOT-AVAIL", "ADMIN-ONLY", "MAX-PER-USER"})
 		tw.AppendSeparator()
 
 		for _, hp := range hpList {
@@ -532,13 +735,17 @@ func printPolicies(rb *common.ResponseBodyPolicies) {
 				hp.Name,
 				hp.Hosts,
 				common.FormatDuration(maxResTime, true),
-				strings.Join(hp.AccessGroups, "\n"),
+				strings.Join(groupLimitLines(hp.GroupLimits, " "), "\n"),
+				strings.Join(accessGroupLines(hp), "\n"),
 				strings.Join(nas, "\n"),
+				strings.Join(hp.RestrictedActions, "\n"),
+				maxPerUserCell(hp.MaxPerUser),
 			})
 		}
 
 		tw.SetColumnConfigs([]table.ColumnConfig{
 			{Name: "MAX-RES-TIME", Align: text.AlignRight},
+			{Name: "MAX-PER-USER", Align: text.AlignRight},
 			{Name: "KERNEL-ARGS", WidthMax: 40},
 		})
 
@@ -547,3 +754,36 @@ func printPolicies(rb *common.ResponseBodyPolicies) {
 	}
 
 }
+
+// maxPerUserCell shows the per-user host cap of a policy, or nothing if it has none.
+func maxPerUserCell(maxPerUser int) string {
+	if maxPerUser <= 0 {
+		return ""
+	}
+	return strconv.Itoa(maxPerUser)
+}
+
+// accessGroupLines lists the access groups of a policy followed by the groups it excludes, each
+// marked as an exception.
+func accessGroupLines(hp common.HostPolicyData) []string {
+	lines := append([]string{}, hp.AccessGroups...)
+	sort.Strings(lines)
+	excluded := append([]string{}, hp.ExcludedGroups...)
+	sort.Strings(excluded)
+	for _, g := range excluded {
+		lines = append(lines, "except "+g)
+	}
+	return lines
+}
+
+// groupLimitLines lists the group time limits of a policy sorted by group, each as the group name and
+// its time limit joined by sep.
+func groupLimitLines(limits map[string]string, sep string) []string {
+	var lines []string
+	for group, limit := range limits {
+		dur, _ := time.ParseDuration(limit)
+		lines = append(lines, group+sep+common.FormatDuration(dur, false))
+	}
+	sort.Strings(lines)
+	return lines
+}
@@ -52,6 +52,7 @@ func newImageRegisterCmd() *cobra.Command {
 	cmdRegisterImage := &cobra.Command{
 		Use: "register {-k FILENAME.KERNEL -i FILENAME.INITRD |\n" +
 			" 		--kstaged FILENAME.KERNEL --istaged FILENAME.INITRD |\n" +
+			" 		--kernel-url URL --initrd-url URL [--kernel-sha256 SUM --initrd-sha256 SUM] |\n" +
 			" 		-d FOLDER/PATH} --boot {bios,uefi}\n" +
 			"		[-l --localBoot {true|false} -b --breed BREED]\n",
 		Short: "Register image files or distro",
@@ -67,6 +68,9 @@ command is used when uploading is not enabled for users.
   -i : name/path to the initrd file. If including a distro for local boot,
   		include the initrd file name if using a custom name. Otherwise,
   		Igor will look for a default name based on OS breed.
+  --kernel-url/--initrd-url : HTTPS URLs of the kernel and initrd files. The
+  		server downloads them into its staged-images directory. Only hosts
+  		listed in the server's imageFetch.allowedHosts setting can be used.
   -d : path to the folder containing the distribution if local install
   --boot: at least one or more comma-separated strings indicating this 
   		image's compatible boot methods. Available values are: bios,uefi
@@ -74,6 +78,9 @@ command is used when uploading is not enabled for users.
 ` + optionalFlags + `
 
   -l : true if included, designate the image for local boot
+  --kernel-sha256/--initrd-sha256 : the expected sha256 digest of the file
+  		downloaded from --kernel-url/--initrd-url. Registration fails if the
+  		downloaded file doesn't match.
   -b : breed of image. (Required if local boot flag -l is included)
   		Available values are:
   		debian, freebsd, generic, nexenta,
@@ -88,6 +95,17 @@ create a distro:
 When registering an image using this action, the image files must have already
 been placed in igor server's designated staged-images directory. See the
 server.imageStageDir setting in the server config for directory path.
+
+Kernel and initrd files can be given compressed with xz or zstd, named with a
+.xz or .zst extension (ex. fs.initrd.zst), whether uploaded, staged or
+downloaded. The server decompresses them when the image is registered, and
+depending on its imageCompression.store setting may keep initrd files
+compressed for the kernel to unpack at boot. A --kernel-sha256/--initrd-sha256
+digest given for a compressed file is checked against its decompressed content.
+
+When registering by URL the response lists the sha256 digest of each file that
+was downloaded and the file name it is stored under. For a compressed file the
+digest is of its decompressed content.
 `,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -100,18 +118,22 @@ server.imageStageDir setting in the server config for directory path.
 			boot, _ := flagset.GetStringSlice("boot")
 			localBoot, _ := flagset.GetBool("localBoot")
 			breed, _ := flagset.GetString("breed")
-			res, err := doRegisterImage(kstaged, istaged, kpath, ipath, dpath, boot, breed, localBoot)
+			kurl, _ := flagset.GetString("kernel-url")
+			iurl, _ := flagset.GetString("initrd-url")
+			ksha, _ := flagset.GetString("kernel-sha256")
+			isha, _ := flagset.GetString("initrd-sha256")
+			res, err := doRegisterImage(kstaged, istaged, kpath, ipath, dpath, kurl, iurl, ksha, isha, boot, breed, localBoot)
 			if err != nil {
 				return err
 			}
-			printRespSimple(res)
+			printImageFetched(res)
 			return nil
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNoArgs,
 	}
 
-	var kstaged, istaged, kpath, ipath, dpath, breed string
+	var kstaged, istaged, kpath, ipath, dpath, kurl, iurl, ksha, isha, breed string
 	var boot []string
 	var localBoot bool
 	cmdRegisterImage.Flags().StringVar(&kstaged, "kstaged", "", "name of the .kernel file already staged in the staged_images folder on the Igor server")
@@ -119,6 +141,10 @@ server.imageStageDir setting in the server config for directory path.
 	cmdRegisterImage.Flags().StringVarP(&kpath, "kernel", "k", "", "name/path of the .kernel file to upload")
 	cmdRegisterImage.Flags().StringVarP(&ipath, "initrd", "i", "", "name/path of the .initrd file to upload")
 	cmdRegisterImage.Flags().StringVarP(&dpath, "distro", "d", "", "path to the distro folder to upload")
+	cmdRegisterImage.Flags().StringVar(&kurl, "kernel-url", "", "HTTPS URL the server downloads the kernel file from")
+	cmdRegisterImage.Flags().StringVar(&iurl, "initrd-url", "", "HTTPS URL the server downloads the initrd file from")
+	cmdRegisterImage.Flags().StringVar(&ksha, "kernel-sha256", "", "expected sha256 digest of the downloaded kernel file")
+	cmdRegisterImage.Flags().StringVar(&isha, "initrd-sha256", "", "expected sha256 digest of the downloaded initrd file")
 	cmdRegisterImage.Flags().StringSlice("boot", boot, "the compatible boot system to use the image with")
 	cmdRegisterImage.Flags().StringVarP(&breed, "breed", "b", "", "name of the OS breed")
 	cmdRegisterImage.Flags().BoolVarP(&localBoot, "localBoot", "l", false, "true = image is intended for local install/boot")
@@ -188,7 +214,7 @@ will also be destroyed automatically.
 	}
 }
 
-func doRegisterImage(kstaged, istaged, kpath, ipath, dpath string, boot []string, breed string, localBoot bool) (*common.ResponseBodyBasic, error) {
+func doRegisterImage(kstaged, istaged, kpath, ipath, dpath, kurl, iurl, ksha, isha string, boot []string, breed string, localBoot bool) (*common.ResponseBodyBasic, error) {
 
 	params := map[string]interface{}{}
 	params["boot"] = boot
@@ -206,6 +232,8 @@ func doRegisterImage(kstaged, istaged, kpath, ipath, dpath string, boot []string
 		} else if kstaged != "" && istaged != "" {
 			params["kstaged"] = kstaged
 			params["istaged"] = istaged
+		} else if kurl != "" && iurl != "" {
+			addImageURLParams(params, kurl, iurl, ksha, isha)
 		}
 		if kpath != "" {
 			params["kpath"] = kpath
@@ -221,8 +249,10 @@ func doRegisterImage(kstaged, istaged, kpath, ipath, dpath string, boot []string
 		} else if kpath != "" && ipath != "" {
 			params["kernelFile"] = openFile(kpath)
 			params["initrdFile"] = openFile(ipath)
+		} else if kurl != "" && iurl != "" {
+			addImageURLParams(params, kurl, iurl, ksha, isha)
 		} else {
-			return nil, fmt.Errorf("paths to either uploadable kernel/initrd files or staged files names are required for image registration")
+			return nil, fmt.Errorf("paths to either uploadable kernel/initrd files, staged files names or kernel/initrd URLs are required for image registration")
 		}
 
 	}
@@ -234,6 +264,47 @@ func doRegisterImage(kstaged, istaged, kpath, ipath, dpath string, boot []string
 	return unmarshalBasicResponse(body), nil
 }
 
+// addImageURLParams adds the params asking the server to download the kernel and initrd files.
+func addImageURLParams(params map[string]interface{}, kurl, iurl, ksha, isha string) {
+	params["kernelUrl"] = kurl
+	params["initrdUrl"] = iurl
+	if ksha != "" {
+		params["kernelSha256"] = ksha
+	}
+	if isha != "" {
+		params["initrdSha256"] = isha
+	}
+}
+
+// printImageFetched prints the response of a request that can register an image, followed by
+// the digest and stored name of any files the server downloaded for it.
+func printImageFetched(rb *common.ResponseBodyBasic) {
+
+	fetchedData, ok := rb.Data["fetched"]
+	if !rb.IsSuccess() || !ok {
+		printRespSimple(rb)
+		return
+	}
+
+	var fetched []common.ImageFetchData
+	raw, err := json.Marshal(fetchedData)
+	checkUnmarshalErr(err)
+	checkUnmarshalErr(json.Unmarshal(raw, &fetched))
+
+	for _, f := range fetched {
+		fmt.Printf("  %s\n    stored as %s\n    sha256 %s\n", f.URL, f.File, f.SHA256)
+	}
+	printRespSimple(rb)
+}
+
+// imageFileCell is the name of an image file followed by its sizes if it was registered compressed.
+func imageFileCell(name string, compressed, decompressed int64) string {
+	if compressed == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s\n(%d bytes compressed, %d expanded)", name, compressed, decompressed)
+}
+
 func doShowImages() *common.ResponseBodyImages {
 	var params string
 	apiPath := api.Images + params
@@ -271,12 +342,12 @@ func printImages(rb *common.ResponseBodyImages) {
 			di.Name,
 			di.ImageID,
 			di.ImageType,
-			di.Kernel,
-			di.Initrd,
+			imageFileCell(di.Kernel, di.KernelCompressedSize, di.KernelDecompressedSize),
+			imageFileCell(di.Initrd, di.InitrdCompressedSize, di.InitrdDecompressedSize),
 			di.Breed,
 			di.Boot,
 			di.Local,
-			strings.Join(di.Distros, "\n"),
+			sortedJoin(di.Distros, "\n"),
 		})
 	}
 
@@ -0,0 +1,126 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorcli
+
+import (
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"igor2/internal/pkg/api"
+	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
+)
+
+func newNetworkCmd() *cobra.Command {
+
+	cmdNetwork := &cobra.Command{
+		Use:   "network",
+		Short: "Perform a named network command",
+		Long: `
+Named network primary command. A sub-command must be invoked to do anything.
+
+A named network holds a VLAN for a group so it outlives the reservations that
+use it. Members of the group can give its name to the -v flag when creating or
+editing a reservation. The VLAN is never given to other reservations while the
+named network exists. Use 'igor vlan show' to list the named networks and the
+reservations using them.`,
+	}
+
+	cmdNetwork.AddCommand(newNetworkCreateCmd())
+	cmdNetwork.AddCommand(newNetworkDelCmd())
+
+	return cmdNetwork
+}
+
+func newNetworkCreateCmd() *cobra.Command {
+
+	cmdCreateNetwork := &cobra.Command{
+		Use:   "create NAME -g GROUP [-v VLAN]",
+		Short: "Create a named network",
+		Long: `
+Creates a named network that holds a VLAN for a group. You must be a member of
+the group, which can't be 'all' or a private group.
+
+` + requiredArgs + `
+
+  NAME : named network name
+
+` + requiredFlags + `
+
+Use the -g flag to give the group that owns the named network. Its members can
+use and delete the named network.
+
+` + optionalFlags + `
+
+Use the -v flag to keep a VLAN already in use, given as a VLAN id number or the
+name of one of your reservations, the same as 'igor res create -v'. This keeps
+the VLAN of an existing reservation after it ends. Without this flag the next
+free VLAN in the range allowed for the group is used.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			group, _ := flagset.GetString("group")
+			vlan, _ := flagset.GetString("vlan")
+			printRespSimple(doCreateNetwork(args[0], group, vlan))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNewNameArg(naming.Generic),
+	}
+
+	cmdCreateNetwork.Flags().StringP("group", "g", "", "group that owns the named network")
+	cmdCreateNetwork.Flags().StringP("vlan", "v", "", "vlan number or existing res name")
+	_ = registerFlagArgsFunc(cmdCreateNetwork, "group", []string{"GROUP"})
+	_ = registerFlagArgsFunc(cmdCreateNetwork, "vlan", []string{"ID/RES"})
+	_ = cmdCreateNetwork.MarkFlagRequired("group")
+
+	return cmdCreateNetwork
+}
+
+func newNetworkDelCmd() *cobra.Command {
+
+	cmdDeleteNetwork := &cobra.Command{
+		Use:   "del NAME",
+		Short: "Delete a named network",
+		Long: `
+Deletes a named network and returns its VLAN to the pool. This can only be done
+by a member of the group that owns it or an admin. A named network can't be
+deleted while a reservation that hasn't ended is using it.
+
+` + requiredArgs + `
+
+  NAME : named network name
+
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printRespSimple(doDeleteNetwork(args[0]))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNameArg,
+	}
+
+	return cmdDeleteNetwork
+}
+
+func doCreateNetwork(name, group, vlan string) *common.ResponseBodyBasic {
+
+	checkNewName(naming.Generic, name)
+	params := map[string]interface{}{}
+	params["name"] = name
+	params["group"] = group
+	if vlan != "" {
+		params["vlan"] = vlan
+	}
+	body := doSend(http.MethodPost, api.VlanNetworks, params)
+	return unmarshalBasicResponse(body)
+}
+
+func doDeleteNetwork(name string) *common.ResponseBodyBasic {
+	apiPath := api.VlanNetworks + "/" + name
+	body := doSend(http.MethodDelete, apiPath, nil)
+	return unmarshalBasicResponse(body)
+}
@@ -5,11 +5,14 @@
 package igorcli
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/gookit/color"
+	"github.com/jedib0t/go-pretty/v6/table"
 
 	"igor2/internal/pkg/common"
 )
@@ -20,7 +23,8 @@ const (
 )
 
 // printRespSimple prints the message portion of ResponseBody to
-// STDOUT with color based on the status field.
+// STDOUT with color based on the status field. A failed response is
+// printed with its error code and exits with the code's exit status.
 func printRespSimple(rb common.ResponseBody) {
 
 	checkColorLevel()
@@ -36,6 +40,10 @@ func printRespSimple(rb common.ResponseBody) {
 		}
 	}
 
+	if code := rb.GetErrorCode(); code != "" && !rb.IsSuccess() {
+		msg += " [" + code + "]"
+	}
+
 	var final string
 	if rb.IsSuccess() {
 		final = cRespSuccess.Sprint(msg)
@@ -49,9 +57,90 @@ func printRespSimple(rb common.ResponseBody) {
 	}
 
 	fmt.Println(final)
+	if !rb.IsSuccess() {
+		os.Exit(common.ErrorExitCode(rb.GetErrorCode()))
+	}
 	os.Exit(0)
 }
 
+// printBatchResults prints the outcome of an operation on many resources. The items that succeeded
+// are condensed into a row for each thing done to them and each failed item gets a row with the
+// reason. If any item failed the counts are printed as a warning and the exit status is that of the
+// ErrPartial code. A failed response that still has results, such as when every item failed, has
+// them printed before its message.
+func printBatchResults(rb *common.ResponseBodyBatch) {
+
+	results := rb.Data["results"]
+	if len(results) == 0 {
+		printRespSimple(rb)
+	}
+
+	checkColorLevel()
+
+	var okMessages []string
+	okNames := map[string][]string{}
+	for _, r := range results {
+		if r.Result == common.BatchItemOK {
+			if _, seen := okNames[r.Message]; !seen {
+				okMessages = append(okMessages, r.Message)
+			}
+			okNames[r.Message] = append(okNames[r.Message], r.Name)
+		}
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"RESULT", "NAME", "MESSAGE"})
+	for _, msg := range okMessages {
+		tw.AppendRow([]interface{}{cRespSuccess.Sprint(common.BatchItemOK), common.UnsplitList(okNames[msg]), msg})
+	}
+	for _, r := range results {
+		if r.Result != common.BatchItemOK {
+			tw.AppendRow([]interface{}{cRespWarn.Sprint(r.Result), r.Name, r.Message})
+		}
+	}
+
+	if simplePrint {
+		tw.Style().Options.SeparateRows = false
+		tw.Style().Options.SeparateColumns = true
+		tw.Style().Options.DrawBorder = false
+	} else {
+		tw.SetStyle(igorTableStyle)
+	}
+
+	fmt.Printf("\n" + tw.Render() + "\n\n")
+
+	if !rb.IsSuccess() {
+		printRespSimple(rb)
+	}
+	if _, failed := common.CountBatchResults(results); failed == 0 {
+		printRespSimple(rb)
+	}
+	fmt.Println(cRespWarn.Sprint(respPrefix + strings.TrimSpace(rb.GetMessage()) + " [" + common.ErrPartial + "]"))
+	os.Exit(common.ErrorExitCode(common.ErrPartial))
+}
+
+// sortedJoin joins a sorted copy of vals with sep, so lists of names print in the same order every time.
+func sortedJoin(vals []string, sep string) string {
+	sorted := append([]string{}, vals...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, sep)
+}
+
+// printRespJsonFailure prints the status, message and error code of a failed
+// ResponseBody as JSON for commands run in JSON mode, then exits with the
+// code's exit status.
+func printRespJsonFailure(rb common.ResponseBody) {
+	failure := map[string]string{
+		"status":    rb.GetStatus(),
+		"message":   rb.GetMessage(),
+		"errorCode": rb.GetErrorCode(),
+	}
+	failData, err := json.MarshalIndent(failure, "", "   ")
+	checkClientErr(err)
+	fmt.Println(string(failData))
+	os.Exit(common.ErrorExitCode(rb.GetErrorCode()))
+}
+
 // printSimple prints out non-error igor responses that originate in the cli or
 // when the server response needs more context.
 func printSimple(msg string, mType color.Color) {
@@ -87,6 +176,18 @@ var requiredArgs = sBold("REQUIRED ARGS:")
 var requiredFlags = sBold("REQUIRED FLAGS:")
 var optionalFlags = sBold("OPTIONAL FLAGS:")
 var notesOnUsage = sBold("NOTES ON USAGE:")
+
 var descFlagText = `Use the --desc flag to set a description should one be desired. This is a text
 field up to 256 characters and enclosed in quotes, ex: "A simple description."
 Descriptions are visible to all users.`
+var resDescMarkdownText = `A reservation description may use limited markdown: [text](https://link),
+**bold**, *italics* and ` + "`code`" + `. It is shown formatted in email and igorweb and as
+written here.`
+var resEndPowerText = `Use the --end-power flag to choose what happens to the nodes when the reserva-
+tion ends. 'off' (the default) powers them off and puts them through any main-
+tenance period. 'leave-on' leaves them running, such as for a burn-in watched
+from outside igor, and makes them available again right away. Leaving nodes on
+may need admin approval if the cluster admin team requires it.`
+var failFastUsage = `Use the --fail-fast flag to stop at the first item that fails and leave all
+of them unchanged. Without it the items that can be changed are, and a table
+lists those that failed and why.`
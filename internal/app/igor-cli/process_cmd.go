@@ -39,6 +39,11 @@ var (
 // doSend calls the appropriate method handler to send a request to igor-server
 // and hands back the raw bytes of the HTTP response body.
 func doSend(action string, apiPath string, params map[string]interface{}) *[]byte {
+	return doSendWithHeaders(action, apiPath, params, nil)
+}
+
+// doSendWithHeaders is the same as doSend but includes the given headers with the request.
+func doSendWithHeaders(action string, apiPath string, params map[string]interface{}, headers map[string]string) *[]byte {
 
 	endPoint := cli.IgorServerAddr + apiPath
 	osUser, _ := user.Current()
@@ -52,7 +57,7 @@ func doSend(action string, apiPath string, params map[string]interface{}) *[]byt
 	case http.MethodGet, http.MethodDelete:
 		_, _, body = processRequestWithNoBody(action, endPoint)
 	case http.MethodPost, http.MethodPatch, http.MethodPut:
-		_, _, body = processRequestWithBody(action, endPoint, params)
+		_, _, body = processRequestWithBody(action, endPoint, params, headers)
 	default:
 		checkClientErr(fmt.Errorf("BAD ACTION RECEIVED - actions allowed: get, post, patch, put, delete"))
 	}
@@ -112,7 +117,7 @@ func doSendMultiform(action string, apiPath string, params map[string]interface{
 	return body
 }
 
-func processRequestWithBody(method string, endPoint string, params map[string]interface{}) (string, http.Header, *[]byte) {
+func processRequestWithBody(method string, endPoint string, params map[string]interface{}, headers map[string]string) (string, http.Header, *[]byte) {
 	reqData, err := json.Marshal(params)
 	if err != nil {
 		checkClientErr(err)
@@ -122,6 +127,9 @@ func processRequestWithBody(method string, endPoint string, params map[string]in
 		checkClientErr(err)
 	}
 	req.Header.Set(common.ContentType, common.MAppJson)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
 	return doRequest(req)
 }
 
@@ -153,13 +161,58 @@ func doRequest(req *http.Request) (string, http.Header, *[]byte) {
 	setAuthToken(req)
 	resp := sendRequest(req)
 	defer resp.Body.Close()
+	checkServerVersion(resp.Header)
 	body, readErr := io.ReadAll(resp.Body)
 	if readErr != nil {
 		checkClientErr(readErr)
 	}
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		printBodyTooLarge(req, body)
+	}
 	return resp.Status, resp.Header, &body
 }
 
+// printBodyTooLarge tells the user a request was refused for being larger than igor-server accepts
+// and what the limit is, then exits. The limit is taken from the response, or from the server
+// settings if something in front of the server sent the response without it.
+func printBodyTooLarge(req *http.Request, body []byte) {
+
+	rb := common.NewResponseBody()
+	var limitMB int
+	if json.Unmarshal(body, rb) == nil {
+		if limit, ok := rb.Data["limitMB"].(float64); ok {
+			limitMB = int(limit)
+		}
+	}
+
+	if limitMB == 0 {
+		var settings struct {
+			Data struct {
+				Igor struct {
+					MaxBodyMB   int `json:"maxBodyMB"`
+					MaxUploadMB int `json:"maxUploadMB"`
+				} `json:"igor"`
+			} `json:"data"`
+		}
+		_, _, sBody := processRequestWithNoBody(http.MethodGet, cli.IgorServerAddr+api.PublicSettings)
+		if json.Unmarshal(*sBody, &settings) == nil {
+			limitMB = settings.Data.Igor.MaxBodyMB
+			if strings.HasPrefix(req.Header.Get(common.ContentType), common.MFormData) {
+				limitMB = settings.Data.Igor.MaxUploadMB
+			}
+		}
+	}
+
+	msg := "the request is too large for igor-server"
+	if limitMB > 0 {
+		msg += fmt.Sprintf(", which accepts at most %d MB for it", limitMB)
+	}
+	rb.Message = msg
+	rb.SetStatus(http.StatusRequestEntityTooLarge)
+	rb.SetErrorCode(common.ErrBodyTooLarge)
+	printRespSimple(rb)
+}
+
 func sendRequest(req *http.Request) *http.Response {
 	client := getClient()
 	resp, err := client.Do(req)
@@ -179,20 +232,29 @@ func getClient() *http.Client {
 
 	var cert tls.Certificate
 	var certErr error
-	var caCertPool *x509.CertPool
 
 	if cli.Client.CertFile != "" && cli.Client.KeyFile != "" {
 		cert, certErr = tls.LoadX509KeyPair(cli.Client.CertFile, cli.Client.KeyFile)
 		if certErr != nil {
 			checkClientErr(fmt.Errorf("error creating x509 keypair from %s and %s", cli.Client.CertFile, cli.Client.KeyFile))
 		}
+	}
 
-		caCert, err := os.ReadFile(cli.Client.CaCert)
-		if err != nil {
-			checkClientErr(fmt.Errorf("error reading CA cert file %s: %s", cli.Client.CaCert, err))
-		}
-		caCertPool = x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
+	caBundle, _ := resolveCaBundle(connFlags.caBundle, cli.Client.CaBundle)
+	caCertPool, poolErr := loadRootCAs(cli.Client.CaCert, caBundle)
+	if poolErr != nil {
+		checkClientErr(poolErr)
+	}
+
+	if skipVerify() {
+		_, _ = fmt.Fprintln(os.Stderr, cAlert.Sprint("WARNING: TLS certificate verification of igor-server is DISABLED. "+
+			"Your credentials and traffic can be intercepted. Use a CA bundle instead."))
+	}
+
+	serverURL, _ := url.Parse(cli.IgorServerAddr)
+	proxyURL, _, proxyErr := resolveProxy(connFlags.proxy, cli.Client.Proxy, serverURL.Host, os.Getenv)
+	if proxyErr != nil {
+		checkClientErr(proxyErr)
 	}
 
 	client := &http.Client{
@@ -201,13 +263,13 @@ func getClient() *http.Client {
 				Certificates:       []tls.Certificate{cert},
 				RootCAs:            caCertPool,
 				MinVersion:         tls.VersionTLS12,
-				InsecureSkipVerify: true,
+				InsecureSkipVerify: skipVerify(),
 			},
 			TLSHandshakeTimeout: time.Second * 5,
 			MaxIdleConns:        100,
 			MaxConnsPerHost:     100,
 			MaxIdleConnsPerHost: 100,
-			Proxy:               http.ProxyFromEnvironment,
+			Proxy:               http.ProxyURL(proxyURL),
 		},
 		CheckRedirect: func(r *http.Request, via []*http.Request) error {
 			setUserAgent(r)
@@ -367,6 +429,13 @@ func unmarshalBasicResponse(body *[]byte) *common.ResponseBodyBasic {
 	return rb
 }
 
+func unmarshalBatchResponse(body *[]byte) *common.ResponseBodyBatch {
+	rb := &common.ResponseBodyBatch{}
+	err := json.Unmarshal(*body, rb)
+	checkUnmarshalErr(err)
+	return rb
+}
+
 // checkUnmarshalErr prints a message if the unmarshaling the response body failed
 func checkUnmarshalErr(err error) {
 	if err != nil {
@@ -387,19 +456,28 @@ func connProblem(err error) bool {
 	var urlErr *url.Error
 	if errors.As(err, &urlErr) {
 		if urlErr.Timeout() {
-			checkClientErr(fmt.Errorf("connection timeout"))
+			checkClientErr(fmt.Errorf("connection timeout\n\t%s", connSettingsInfo()))
+		}
+		var certErr *tls.CertificateVerificationError
+		var authErr x509.UnknownAuthorityError
+		var hostErr x509.HostnameError
+		if errors.As(urlErr.Err, &certErr) || errors.As(urlErr.Err, &authErr) || errors.As(urlErr.Err, &hostErr) {
+			checkClientErr(fmt.Errorf("unable to verify igor-server certificate -- %v\n\t%s\n"+
+				"use --ca-bundle or the caBundle client setting to trust the CA that signed the server certificate",
+				urlErr.Err, connSettingsInfo()))
 		}
 		var opErr *net.OpError
 		if errors.As(urlErr.Err, &opErr) {
 			var scErr *os.SyscallError
 			if errors.As(opErr.Err, &scErr) {
 				if errors.Is(scErr.Err, syscall.ECONNREFUSED) {
-					checkClientErr(fmt.Errorf("connection refused -- check igor-server address... also is igor-server running?"))
+					checkClientErr(fmt.Errorf("connection refused -- check igor-server address... also is igor-server running?\n\t%s", connSettingsInfo()))
 				} else {
-					checkClientErr(scErr.Err)
+					checkClientErr(fmt.Errorf("%v\n\t%s", scErr.Err, connSettingsInfo()))
 				}
 			}
 		}
+		checkClientErr(fmt.Errorf("%v\n\t%s", urlErr.Err, connSettingsInfo()))
 	}
 	return false
 }
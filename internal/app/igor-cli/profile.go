@@ -15,6 +15,7 @@ import (
 	"github.com/jedib0t/go-pretty/v6/table"
 
 	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
 
 	"github.com/spf13/cobra"
 )
@@ -70,7 +71,9 @@ Once created, only the owner is allowed to edit or delete the profile.
 
 Use the -k flag to add kernel arguments that will be executed after any kernel
 arguments specified in the distro, if present. Use a double-quotes around the
-field if it contains spaces.
+field if it contains spaces. Line breaks are collapsed to spaces, and
+control characters and curly quotes are rejected. The full kernel line built
+from the distro and profile args is printed after the profile is created.
 
 ` + descFlagText + `
 `,
@@ -81,6 +84,7 @@ field if it contains spaces.
 			kargs, _ := flagset.GetString("kargs")
 			res := doCreateProfile(args[0], args[1], desc, kargs)
 			printRespSimple(res)
+			printKernelLine(res)
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -112,7 +116,9 @@ Shows profile information, returning matches to specified parameters. If no
 parameters are provided then all profiles will be returned.
 
 Output will provide the name of the profile and its owner, name of the
-associated distro, and any profile kernel args, if present.
+associated distro, and any profile kernel args, if present. Admins and profile
+owners will also see how many times each profile has been used and when it was
+last used.
 
 ` + optionalFlags + `
 
@@ -181,7 +187,9 @@ the field if it contains spaces.
 			name, _ := flagset.GetString("name")
 			desc, _ := flagset.GetString("desc")
 			kargs, _ := flagset.GetString("kernel-args")
-			printRespSimple(doEditProfile(args[0], name, desc, kargs))
+			rb := doEditProfile(args[0], name, desc, kargs)
+			printRespSimple(rb)
+			printKernelLine(rb)
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNameArg,
@@ -231,6 +239,7 @@ A profile cannot be deleted if it is associated with a reservation.
 
 func doCreateProfile(name, distro, desc, kargs string) *common.ResponseBodyBasic {
 
+	checkNewName(naming.Profile, name)
 	params := map[string]interface{}{}
 	params["name"] = name
 	params["distro"] = distro
@@ -284,6 +293,7 @@ func doEditProfile(name, newName, desc, kargs string) *common.ResponseBodyBasic
 	apiPath := api.Profiles + "/" + name
 	params := map[string]interface{}{}
 	if newName != "" {
+		checkNewName(naming.Profile, newName)
 		params["name"] = newName
 	}
 	if desc != "" {
@@ -317,6 +327,11 @@ func printProfiles(rb *common.ResponseBodyProfiles) {
 		return strings.ToLower(profileList[i].Name) < strings.ToLower(profileList[j].Name)
 	})
 
+	showUsage := false
+	for _, p := range profileList {
+		showUsage = showUsage || p.Usage != nil
+	}
+
 	if simplePrint {
 
 		var profileInfo string
@@ -327,24 +342,35 @@ func printProfiles(rb *common.ResponseBodyProfiles) {
 			profileInfo += "  -OWNER:       " + d.Owner + "\n"
 			profileInfo += "  -DISTRO:      " + d.Distro + "\n"
 			profileInfo += "  -KERNEL-ARGS: " + d.KernelArgs + "\n"
+			if d.Usage != nil {
+				profileInfo += "  -USAGE:       " + imageUsageStatus(d.Usage) + "\n"
+			}
 			fmt.Print(profileInfo + "\n\n")
 		}
 
 	} else {
 
 		tw := table.NewWriter()
-		tw.AppendHeader(table.Row{"NAME", "DESCRIPTION", "OWNER", "DISTRO", "KERNEL-ARGS"})
+		header := table.Row{"NAME", "DESCRIPTION", "OWNER", "DISTRO", "KERNEL-ARGS"}
+		if showUsage {
+			header = append(header, "USAGE")
+		}
+		tw.AppendHeader(header)
 		tw.AppendSeparator()
 
 		for _, p := range profileList {
 
-			tw.AppendRow([]interface{}{
+			row := []interface{}{
 				p.Name,
 				p.Description,
 				p.Owner,
 				p.Distro,
 				p.KernelArgs,
-			})
+			}
+			if showUsage {
+				row = append(row, imageUsageStatus(p.Usage))
+			}
+			tw.AppendRow(row)
 		}
 
 		tw.SetColumnConfigs([]table.ColumnConfig{
@@ -5,10 +5,13 @@
 package igorcli
 
 import (
+	"bufio"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"igor2/internal/pkg/api"
 	"net/http"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -17,6 +20,7 @@ import (
 	"github.com/jedib0t/go-pretty/v6/table"
 
 	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
 
 	"github.com/spf13/cobra"
 )
@@ -68,8 +72,20 @@ Consult with your cluster admin team for further guidance.
 	}
 
 	cmdRes.AddCommand(newResCreateCmd())
+	cmdRes.AddCommand(newResQuickCmd())
 	cmdRes.AddCommand(newResShowCmd())
 	cmdRes.AddCommand(newResEditCmd())
+	cmdRes.AddCommand(newResReimageCmd())
+	cmdRes.AddCommand(newResBootInfoCmd())
+	cmdRes.AddCommand(newResShareCmd())
+	cmdRes.AddCommand(newResPauseCmd())
+	cmdRes.AddCommand(newResResumeCmd())
+	cmdRes.AddCommand(newResClaimCmd())
+	cmdRes.AddCommand(newResPendingCmd())
+	cmdRes.AddCommand(newResApproveCmd())
+	cmdRes.AddCommand(newResDenyCmd())
+	cmdRes.AddCommand(newResBulkExtendCmd())
+	cmdRes.AddCommand(newResBulkChownCmd())
 	cmdRes.AddCommand(newResDelCmd())
 
 	return cmdRes
@@ -78,9 +94,10 @@ Consult with your cluster admin team for further guidance.
 func newResCreateCmd() *cobra.Command {
 
 	cmdCreateRes := &cobra.Command{
-		Use: "create NAME -n NODES {-p PROFILE | -d DISTRO} [-s START -e END \n" +
-			"           -g GROUP -v VLAN -k \"KARGS\" --desc \"DESCRIPTION\" --no-cycle\n" +
-			"           (-o OWNER)]",
+		Use: "create NAME -n NODES [-p PROFILE | -d DISTRO] [-s START -e END \n" +
+			"           -g GROUP -v VLAN -k \"KARGS\" --desc \"DESCRIPTION\" --no-cycle --clamp\n" +
+			"           --min-nodes N --end-power {off|leave-on} --no-default-kargs --asap\n" +
+			"           (-o OWNER [--grant-access])]",
 		Short: "Create a reservation",
 		Long: `
 Create a reservation on one or more cluster nodes. A reservation requires a
@@ -105,16 +122,41 @@ information.
      >> OR <<
   -d DISTRO : the name of a distro
 
+The -p/-d flag can be left out if the reservation's group has a default distro.
+
 If only required arguments are provided the reservation starts immediately with
 the default length determined by the cluster admin team.
 
+` + sBold("DEFAULTS:") + `
+
+A value not given on the command line is filled in using this order:
+
+  1. the flags given to this command
+  2. your default group (see 'igor user edit -h') is used when -g isn't given,
+     and the defaults of the reservation's group supply the distro and length
+     (see 'igor group edit -h')
+  3. the defaults of the igor server
+
+The response lists any values that were filled in from defaults.
+
 ` + optionalFlags + `
 
 Use the -s flag to set a start time for the reservation (other than now). Use
 the format: ` + exStartDts() + `. (There is no seconds field.) It must be set
 at least 5 minutes into the future and cannot start beyond the schedule window
 as set by the cluster admin team. If this flag is not used the reservation
-begins immediately.
+begins immediately. If a node count is asked for and not enough nodes are free
+at the start time, the response gives the earliest time there are and how many
+are free at the start time. If named nodes can't be used, a table lists each
+one with the reason and the nodes that could be used are suggested.
+
+Use the --asap flag with named nodes to reserve them for the earliest time
+they are all free together for the length asked for, instead of failing when
+some are busy. The search starts from now or from the -s time and ends at the
+schedule window limit. The reservation keeps its length, so an -e datetime
+moves later along with the start. The response says when the reservation will
+begin. If the nodes are never all free together, a table lists the last
+reservation in the way on each node.
 
 Use the -e flag to set the end time/duration of a reservation. The expression 
 can either be a datetime format or an interval specified in days(d), hours(h)
@@ -127,21 +169,42 @@ window limit as specified by your cluster admin team. If not specified the
 default length is used. Default reservation time limits are viewable by
 running the command: 'igor settings'
 
+If the requested end time exceeds these limits the reservation is rejected and
+the response states the latest end time that can be granted. Use the --clamp
+flag to instead shorten the end time to that limit. The response will report
+the end time that was granted.
+
 Use the -o flag to set a different owner for the reservation than the person
 making it. This flag can only be used by admins and the action is called out in
-the application log.
+the application log. The new owner is sent an email saying which admin made the
+reservation for them. The owner must already have access to the distro used by
+the reservation. Add the --grant-access flag to give the owner access to the
+distro as part of making the reservation.
 
 Use the -g flag to set a group that will have access to this reservation. Group
 membership confers the ability to extend or delete the reservation and to issue
 power commands to its assigned nodes. The reservation creator must be a member
-of the provided group.
-
-Use the -v flag to set a VLAN id number or name of an existing reservation. If
-a number is provided, the new reservation will use the specified VLAN value if
-not already taken. (The id range is available by running the 'igor settings' 
-command.) If a name is provided, the VLAN of the new reservation is set to the
-same VLAN as the named reservation. If this flag is not used on a VLAN-enabled
-cluster then an id will be automatically assigned.
+of the provided group. If the server requires a group for every reservation
+(requireReservationGroup in 'igor settings') the -g flag or a default group must
+be used.
+
+Use the -v flag to set a VLAN id number, the name of a named network or the
+name of an existing reservation. If a number is provided, the new reservation
+will use the specified VLAN value if not already taken. (The id range is avail-
+able by running the 'igor settings' command.) If a named network is given, the
+reservation uses its VLAN; you must be a member of the group that owns it (see
+'igor network'). If a reservation name is provided, the VLAN of the new reser-
+vation is set to the same VLAN as the named reservation. If this flag is not
+used on a VLAN-enabled cluster then an id will be automatically assigned. If
+the reservation's group has a VLAN range (see 'igor group edit') the VLAN must
+fall within it.
+
+Use the --min-nodes flag to set the fewest nodes the reservation can start
+with. Nodes that are blocked or in an error state when the reservation starts
+are dropped, or replaced with free nodes if igor chose them. If fewer than this
+many nodes are left the reservation fails to start and its members are told
+why. If not set, a percentage of the requested nodes set by the cluster admin
+team is used.
 
 Use the --no-cycle flag to prevent the reservation's nodes from being power-
 cycled when it becomes active. This will leave the nodes in whatever power
@@ -151,9 +214,19 @@ Use the -k flag to set kernel arguments you would like to append to the
 chosen distro to use with this reservation. Kernel args can only be used in
 conjunction with distros. If you wish to change/append a kernel arg to a
 profile, then you should update the profile first before using it in a new
-reservation. 
+reservation. Line breaks in kernel args are collapsed to spaces; control
+characters and curly quotes are rejected. The full kernel line the nodes will
+boot with is printed after the reservation is made.
+
+Any default kernel args you set with 'igor user edit --default-kargs' are added
+after those of the distro or profile and before any given with -k, which win
+when both set the same arg. Use --no-default-kargs to leave them off this
+reservation.
+
+` + resEndPowerText + `
 
 ` + descFlagText + `
+` + resDescMarkdownText + `
 `,
 		Example: `
 
@@ -179,6 +252,13 @@ igor res create Twit2 -p twitserv -n dq74,dq9 -s ` + exStartDts() + ` -e 6d -v T
   Requests a reservation named 'Twit2' using the profile 'twitserv' on three
   nodes starting ` + exStartDay() + ` for six days and shares the same vlan used
   by the reservation 'Twit1'.
+
+
+igor res create fpga-run -d cent7 -n kn[30-33] -e 2d --asap
+
+  * Waits for busy named nodes.
+  Requests a two-day reservation named 'fpga-run' on nodes kn30-33 that starts
+  as soon as all four are free at the same time.
 `,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
@@ -198,10 +278,24 @@ igor res create Twit2 -p twitserv -n dq74,dq9 -s ` + exStartDts() + ` -e 6d -v T
 				noCycleVal, _ := flagset.GetBool("no-cycle")
 				noCycle = &noCycleVal
 			}
-			printRespSimple(doCreateReservation(args[0], distro, profile, owner, group, desc, start, end, vlan, nodes, kernelArgs, noCycle))
+			clamp := flagset.Changed("clamp")
+			grantAccess := flagset.Changed("grant-access")
+			if grantAccess && owner == "" {
+				checkClientErr(fmt.Errorf("--grant-access can only be used with the -o flag"))
+			}
+			minNodes, _ := flagset.GetInt("min-nodes")
+			endPower, _ := flagset.GetString("end-power")
+			noDefaultKargs := flagset.Changed("no-default-kargs")
+			asap := flagset.Changed("asap")
+			if _, err := strconv.Atoi(nodes); err == nil && asap {
+				checkClientErr(fmt.Errorf("--asap can only be used with named nodes, not a node count"))
+			}
+			rb := doCreateReservation(args[0], distro, profile, owner, group, desc, start, end, vlan, nodes, kernelArgs, endPower, noCycle, clamp, grantAccess, noDefaultKargs, asap, minNodes)
+			_, defaultKargs := rb.Data["defaultKernelArgs"]
+			printResCreate(rb, kernelArgs != "" || defaultKargs)
 		},
 		DisableFlagsInUseLine: true,
-		ValidArgsFunction:     validateNameArg,
+		ValidArgsFunction:     validateNewNameArg(naming.Reservation),
 	}
 
 	var nodes,
@@ -213,8 +307,14 @@ igor res create Twit2 -p twitserv -n dq74,dq9 -s ` + exStartDts() + ` -e 6d -v T
 		group,
 		vlan,
 		kernelArgs,
+		endPower,
 		distro string
-	var noCycle bool
+	var noCycle,
+		clamp,
+		grantAccess,
+		noDefaultKargs,
+		asap bool
+	var minNodes int
 
 	cmdCreateRes.Flags().StringVarP(&distro, "distro", "d", "", "distro to use")
 	cmdCreateRes.Flags().StringVarP(&profile, "profile", "p", "", "profile to use")
@@ -223,10 +323,16 @@ igor res create Twit2 -p twitserv -n dq74,dq9 -s ` + exStartDts() + ` -e 6d -v T
 	cmdCreateRes.Flags().StringVarP(&end, "end", "e", "", "end time (other than default)")
 	cmdCreateRes.Flags().StringVarP(&owner, "owner", "o", "", "assign different owner "+adminOnly)
 	cmdCreateRes.Flags().StringVarP(&group, "group", "g", "", "group allowed to access")
-	cmdCreateRes.Flags().StringVarP(&vlan, "vlan", "v", "", "vlan number or existing res name")
+	cmdCreateRes.Flags().StringVarP(&vlan, "vlan", "v", "", "vlan number, named network or existing res name")
 	cmdCreateRes.Flags().StringVarP(&kernelArgs, "kernel-args", "k", "", "kernel args to append to a distro")
 	cmdCreateRes.Flags().StringVar(&desc, "desc", "", "description of the reservation")
 	cmdCreateRes.Flags().BoolVar(&noCycle, "no-cycle", false, "do not power cycle nodes at startup")
+	cmdCreateRes.Flags().BoolVar(&clamp, "clamp", false, "shorten end time to the maximum allowed instead of failing")
+	cmdCreateRes.Flags().BoolVar(&grantAccess, "grant-access", false, "give the owner access to the distro "+adminOnly)
+	cmdCreateRes.Flags().BoolVar(&noDefaultKargs, "no-default-kargs", false, "leave off your default kernel args")
+	cmdCreateRes.Flags().BoolVar(&asap, "asap", false, "start when the named nodes are all free if they are busy")
+	cmdCreateRes.Flags().IntVar(&minNodes, "min-nodes", 0, "fewest nodes the reservation can start with")
+	cmdCreateRes.Flags().StringVar(&endPower, "end-power", "", "power state of the nodes when the reservation ends (off|leave-on)")
 
 	_ = cmdCreateRes.MarkFlagRequired("nodes")
 
@@ -238,18 +344,66 @@ igor res create Twit2 -p twitserv -n dq74,dq9 -s ` + exStartDts() + ` -e 6d -v T
 	_ = registerFlagArgsFunc(cmdCreateRes, "end", []string{"DATE/DUR"})
 	_ = registerFlagArgsFunc(cmdCreateRes, "owner", []string{"USER"})
 	_ = registerFlagArgsFunc(cmdCreateRes, "group", []string{"GROUP"})
-	_ = registerFlagArgsFunc(cmdCreateRes, "vlan", []string{"ID/RES"})
+	_ = registerFlagArgsFunc(cmdCreateRes, "vlan", []string{"ID/NET/RES"})
 	_ = registerFlagArgsFunc(cmdCreateRes, "kernel-args", []string{"\"KARGS\""})
 	_ = registerFlagArgsFunc(cmdCreateRes, "desc", []string{"\"DESCRIPTION\""})
+	_ = registerFlagArgsFunc(cmdCreateRes, "end-power", []string{"off", "leave-on"})
 
 	return cmdCreateRes
 }
 
+func newResQuickCmd() *cobra.Command {
+
+	cmdQuickRes := &cobra.Command{
+		Use:   "quick [-e END]",
+		Short: "Reserve one node with the default distro",
+		Long: `
+Reserves a single node chosen by igor, booting the default distro set by the
+cluster admin team, starting now. The reservation is named for you and today's
+date, ex. alice-` + time.Now().Format("20060102") + `, and the node name and end time are
+printed when it is made.
+
+A quick reservation is a normal reservation in every other way. Use 'igor res
+edit', 'igor res del' and the other reservation commands to manage it
+afterward. Use 'igor res create' when you need more than one node, a different
+distro or any of its other options.
+
+The command fails if the cluster has no default distro configured or no node
+is free for the length of the reservation.
+
+` + optionalFlags + `
+
+Use the -e flag to set the end time/duration of the reservation. The expression
+can either be a datetime format or an interval specified in days(d), hours(h)
+and minutes(m) in that order, ex. ` + exEndDts() + ` | 4h | 90m. If not specified the
+default length is used.
+`,
+		Example: `
+igor res quick -e 4h
+
+  Reserves one node for four hours.
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			end, _ := cmd.Flags().GetString("end")
+			printRespSimple(doQuickReservation(end))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+
+	var end string
+	cmdQuickRes.Flags().StringVarP(&end, "end", "e", "", "end time (other than default)")
+	_ = registerFlagArgsFunc(cmdQuickRes, "end", []string{"DATE/DUR"})
+
+	return cmdQuickRes
+}
+
 func newResShowCmd() *cobra.Command {
 
 	cmdShowRes := &cobra.Command{
 		Use: "show [-n NAME1,...] [-o OWNER1,...] [-d DIST1,...] [-p PROF1,...]\n" +
-			"       [-g GR1,...] [-x]",
+			"       [-g GR1,...] [--ends-before DATETIME|DURATION] [-x]",
 		Short: "Show reservation information",
 		Long: `
 Shows reservation information, returning matches to specified parameters. By
@@ -263,10 +417,17 @@ Use the -a flag to include all reservations in the search.
 Use the -n, -o, -d, -p and -g flags to narrow results. Multiple values for a
 given flag should be comma-delimited.
 
-Use the -x flag to render screen output without pretty formatting.
+Use the --ends-before flag to only list reservations that end on or before the
+given time. The value can be a datetime in the format ` + exStartDts() + ` or a
+duration from now in days(d), hours(h) and minutes(m), such as 3d. The end
+times of the listed reservations are highlighted.
+
+Use the -x flag to render screen output without pretty formatting. Elevated
+admins also see the node count and length each reservation originally asked
+for as the REQUESTED field.
 `,
 		Args: cobra.NoArgs,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			flagset := cmd.Flags()
 			var showAll *bool
 			showAllVal, _ := flagset.GetBool("all")
@@ -277,7 +438,16 @@ Use the -x flag to render screen output without pretty formatting.
 			profiles, _ := flagset.GetStringSlice("profiles")
 			groups, _ := flagset.GetStringSlice("groups")
 			simplePrint = flagset.Changed("simple")
-			printReservations(doShowReservation(showAll, names, distros, profiles, owners, groups))
+			var deadline time.Time
+			if flagset.Changed("ends-before") {
+				endsBefore, _ := flagset.GetString("ends-before")
+				var err error
+				if deadline, err = parseEndsBefore(endsBefore, igorCliNow); err != nil {
+					return err
+				}
+			}
+			printReservations(doShowReservation(showAll, names, distros, profiles, owners, groups, deadline), deadline)
+			return nil
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNoArgs,
@@ -289,6 +459,7 @@ Use the -x flag to render screen output without pretty formatting.
 		distros,
 		profiles []string
 	var showAll bool
+	var endsBefore string
 
 	cmdShowRes.Flags().BoolVarP(&showAll, "all", "a", false, "show all reservations (includes other users)")
 	cmdShowRes.Flags().StringSliceVarP(&names, "names", "n", nil, "search by reservation name(s)")
@@ -296,12 +467,14 @@ Use the -x flag to render screen output without pretty formatting.
 	cmdShowRes.Flags().StringSliceVarP(&groups, "groups", "g", nil, "search by group(s)")
 	cmdShowRes.Flags().StringSliceVarP(&distros, "distros", "d", nil, "search by distro(s)")
 	cmdShowRes.Flags().StringSliceVarP(&profiles, "profiles", "p", nil, "search by profile(s)")
+	cmdShowRes.Flags().StringVar(&endsBefore, "ends-before", "", "search for reservations ending by this datetime or duration from now")
 	cmdShowRes.Flags().BoolVarP(&simplePrint, "simple", "x", false, "use simple text output")
 	_ = registerFlagArgsFunc(cmdShowRes, "names", []string{"NAME1"})
 	_ = registerFlagArgsFunc(cmdShowRes, "owners", []string{"OWNER1"})
 	_ = registerFlagArgsFunc(cmdShowRes, "groups", []string{"GROUP1"})
 	_ = registerFlagArgsFunc(cmdShowRes, "distros", []string{"DIST1"})
 	_ = registerFlagArgsFunc(cmdShowRes, "profiles", []string{"PROF1"})
+	_ = registerFlagArgsFunc(cmdShowRes, "ends-before", []string{"DATETIME|DURATION"})
 
 	return cmdShowRes
 }
@@ -309,14 +482,17 @@ Use the -x flag to render screen output without pretty formatting.
 func newResEditCmd() *cobra.Command {
 
 	cmdEditRes := &cobra.Command{
-		Use: "edit NAME [ {--extend LENGTH | --extend-max} | \n" +
+		Use: "edit NAME [ {--extend LENGTH [--clamp] | --extend-max} | \n" +
 			"       --drop NODES | \n" +
-			"       {-p PROFILE | -d DISTRO} | \n" +
-			"       [-n NAME] [-o OWNER] [-g GROUP] [-k KARGS] [--desc \"DESCRIPTION\"]]",
+			"       {-p PROFILE | -d DISTRO} [--cycle-now | --cycle-at DATETIME] | --cancel-cycle | \n" +
+			"       [-n NAME] [-o OWNER [--keep-co-owners]] [-g GROUP] [-k KARGS] [--desc \"DESCRIPTION\"]\n" +
+			"       [-v VLAN] [--add-co-owner USERS] [--rmv-co-owner USERS] [--notify-also USERS]\n" +
+			"       [--head NODE] [--keep] [--end-power {off|leave-on}] [--net-profile PROFILE]\n" +
+			"       [--data-file FILE]]",
 		Short: "Edit a reservation",
 		Long: `
 Edits a reservation. With the exception of the extend flags (see below) changes
-can only be made by the reservation owner or an admin.
+can only be made by the reservation owner, a co-owner or an admin.
 
 ` + requiredArgs + `
 
@@ -341,8 +517,15 @@ owner and any member of the reservation group if one is assigned.
 Use the --extend-max flag to extend the reservation by the maximum amount of
 time possible. Igor will determine the proper time interval needed.
 
-It is not possible to extend future reservations if their length is already the
-maximum length allowed.
+If the new end time exceeds these limits the extension is rejected and the
+response states the latest end time that can be granted. Add the --clamp flag
+to --extend to instead shorten the new end time to that limit. The response
+will report the end time that was granted.
+
+A reservation that has started may only be extended once it is close to ending,
+if the cluster sets such a window. Future reservations can be extended at any
+time, but not if their length is already the maximum length allowed or the new
+end time runs into a later reservation on the same nodes.
 
 These flags cannot be used with other edit parameters.
 
@@ -371,6 +554,16 @@ profile name must be provided. Alternatively you may use the -d flag to use a
 bare distro instead which uses that distro's default profile. Changing either
 does not take effect until a power-cycle operation is performed on the reserva-
 tion nodes. (See 'igor host power --help' for more information.)
+To boot a different profile or distro without changing the reservation's own
+profile, see 'igor res reimage'.
+
+Add the --cycle-now flag to have igor set up the nodes for the new profile and
+power cycle them right away, or --cycle-at DATETIME (format ` + exStartDts() + `)
+to power cycle them at a later time before the reservation ends. This needs the
+same permission as 'igor host power' on all of the reservation's nodes. A cycle
+set for later is listed in 'igor res show' and can be called off with the
+--cancel-cycle flag. It is skipped if the profile is changed again or the res-
+ervation is deleted before it happens.
 
 These flags cannot be used with other edit parameters.
 
@@ -381,17 +574,88 @@ Use the -n flag to change the reservation name.
 Use the -o flag to transfer ownership to another user. After this change the
 previous owner can no longer edit the reservation. The previous owner will
 retain some access rights if they are a member of the reservation's assigned
-group.
+group. Any co-owners are removed when ownership changes unless the
+--keep-co-owners flag is also given.
 
 Use the -g flag to change/remove a group from the reservation. To remove the
-group use the syntax '-g none'.
+group use the syntax '-g none'. This is refused if the server requires a group
+for every reservation.
 
 Use the -k flag to set kernel arguments you would like to append to the distro
 being used with this reservation. Kernel args can only be used in conjunction
 with the existing distro (temp profile). You cannot specify kernel args while
 also changing the distro.
 
+Use the -v flag to move the reservation to another VLAN, given the same way as
+'igor res create -v'. Give the name of a named network to move a reservation
+onto a VLAN its group keeps (see 'igor network'). Only the owner or an admin
+can change the VLAN. The nodes of a reservation that has started are moved to
+the new VLAN right away.
+
+Use the --net-profile flag to apply a network (QoS) profile to the switch ports
+of the reservation's nodes along with its VLAN, such as to cap the bandwidth of
+a traffic-generation experiment. Only an admin can set it, and only to one of
+the profiles configured on the server. Use '--net-profile none' to remove it.
+The profile is applied right away to a reservation that has started and is
+listed in 'igor res show' for admins and in 'igor vlan show'.
+
 ` + descFlagText + `
+` + resDescMarkdownText + `
+
+` + sBold("END OF RESERVATION POWER:") + `
+
+` + resEndPowerText + ` Once a
+reservation has started only an admin can change it to 'leave-on' if approval
+is required. The choice is listed in 'igor res show' and recorded in history.
+
+` + sBold("HEAD NODE:") + `
+
+Each reservation has a head node, which is listed with its other details. The
+head node is the node with the lowest number unless the --head flag has been
+used to make another node of the reservation the head node. Nodes are always
+listed in the same order, so scripts can rely on the head node and node list
+in igor's JSON responses rather than parsing the node range. If the head node
+is dropped, the node with the lowest number left becomes the head node. The
+change is noted in the reservation history.
+
+` + sBold("CO-OWNERS:") + `
+
+Use the --add-co-owner flag with a comma-delimited list of users to make them
+co-owners of the reservation. Co-owners can make the same edits as the owner
+and receive the same email notifications, but they cannot transfer ownership
+or add/remove other co-owners. Use the --rmv-co-owner flag to remove them.
+Only the owner or an admin can change the list of co-owners.
+
+` + sBold("NOTIFY ALSO:") + `
+
+Use the --notify-also flag with a comma-delimited list of users to copy them on
+the reservation's email, such as expiration warnings, in addition to the owner
+and any notification delegate the owner has set (see 'igor user edit -h'). They
+are not sent email about a change of ownership. The list replaces any set
+before; use '--notify-also none' to clear it. Only igor users can be named, so
+to notify someone outside igor add their address to a group instead.
+
+` + sBold("RESERVATION DATA:") + `
+
+Use the --data-file flag to attach the contents of a file, such as experiment
+configuration, to the reservation for its nodes to fetch when they boot. The
+data is opaque to igor and can be at most 64 KiB. A node of the reservation
+can fetch it once the reservation has started from the callback server at
+
+  http://IGOR-SERVER:CB-PORT/igor/cb/svc/data/NAME
+
+which only answers nodes of the reservation. The owner, co-owners and group
+members can also read it through the igor API. A new file replaces the data
+set before; use '--data-file none' to remove it. The data is deleted with the
+reservation. Only its size and hash are recorded in history.
+
+` + sBold("IDLE RESERVATIONS:") + `
+
+If the cluster has an idle reservation policy, a reservation that has gone a
+number of days without any of its hosts being powered on will be sent a warn-
+ing email. If nothing is done the reservation is then shortened to end soon.
+Use the --keep flag to acknowledge the warning and keep the reservation as it
+is. Powering on any of the reservation's hosts has the same effect.
 `,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
@@ -406,7 +670,23 @@ also changing the distro.
 			owner, _ := flagset.GetString("owner")
 			group, _ := flagset.GetString("group")
 			kernelArgs, _ := flagset.GetString("kernel-args")
-			printRespSimple(doEditReservation(args[0], extend, drop, distro, profile, newName, owner, group, desc, kernelArgs, extendMax))
+			addCoOwners, _ := flagset.GetStringSlice("add-co-owner")
+			rmvCoOwners, _ := flagset.GetStringSlice("rmv-co-owner")
+			keepCoOwners := flagset.Changed("keep-co-owners")
+			clamp := flagset.Changed("clamp")
+			keep := flagset.Changed("keep")
+			head, _ := flagset.GetString("head")
+			vlan, _ := flagset.GetString("vlan")
+			notifyAlso, _ := flagset.GetStringSlice("notify-also")
+			endPower, _ := flagset.GetString("end-power")
+			netProfile, _ := flagset.GetString("net-profile")
+			cycleAt, _ := flagset.GetString("cycle-at")
+			cycleNow := flagset.Changed("cycle-now")
+			cancelCycle := flagset.Changed("cancel-cycle")
+			dataFile, _ := flagset.GetString("data-file")
+			rb := doEditReservation(args[0], extend, drop, distro, profile, newName, owner, group, desc, kernelArgs, head, vlan, endPower, netProfile, cycleAt, dataFile, extendMax, clamp, cycleNow, cancelCycle, addCoOwners, rmvCoOwners, notifyAlso, keepCoOwners, keep)
+			printRespSimple(rb)
+			printKernelLine(rb)
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNameArg,
@@ -420,270 +700,1355 @@ also changing the distro.
 		extend,
 		drop,
 		kernelArgs,
+		head,
+		vlan,
+		endPower,
+		netProfile,
+		cycleAt,
+		dataFile,
 		distro string
-	var extendMax bool
+	var extendMax,
+		clamp,
+		cycleNow,
+		cancelCycle,
+		keepCoOwners,
+		keep bool
+	var addCoOwners,
+		rmvCoOwners,
+		notifyAlso []string
 
 	cmdEditRes.Flags().StringVar(&extend, "extend", "", "extend reservation by provided time")
 	cmdEditRes.Flags().BoolVar(&extendMax, "extend-max", false, "extend reservation by maximum time allowed")
+	cmdEditRes.Flags().BoolVar(&clamp, "clamp", false, "shorten extension to the maximum allowed instead of failing")
 	cmdEditRes.Flags().StringVar(&drop, "drop", "", "drop nodes from the reservation")
 	cmdEditRes.Flags().StringVarP(&distro, "distro", "d", "", "update distro")
 	cmdEditRes.Flags().StringVarP(&profile, "profile", "p", "", "update profile")
+	cmdEditRes.Flags().BoolVar(&cycleNow, "cycle-now", false, "power cycle the nodes right away to boot the new profile or distro")
+	cmdEditRes.Flags().StringVar(&cycleAt, "cycle-at", "", "power cycle the nodes at a later time to boot the new profile or distro")
+	cmdEditRes.Flags().BoolVar(&cancelCycle, "cancel-cycle", false, "cancel a power cycle scheduled with --cycle-at")
 	cmdEditRes.Flags().StringVarP(&name, "name", "n", "", "update reservation name")
 	cmdEditRes.Flags().StringVarP(&owner, "owner", "o", "", "update owner")
 	cmdEditRes.Flags().StringVarP(&group, "group", "g", "", "update group")
 	cmdEditRes.Flags().StringVarP(&kernelArgs, "kernel-args", "k", "", "add kernel args to a distro (temp profile)")
 	cmdEditRes.Flags().StringVar(&desc, "desc", "", "update the description of the reservation")
+	cmdEditRes.Flags().StringSliceVar(&addCoOwners, "add-co-owner", nil, "comma-delimited co-owners to add")
+	cmdEditRes.Flags().StringSliceVar(&rmvCoOwners, "rmv-co-owner", nil, "comma-delimited co-owners to remove")
+	cmdEditRes.Flags().BoolVar(&keepCoOwners, "keep-co-owners", false, "keep existing co-owners when changing owner")
+	cmdEditRes.Flags().StringSliceVar(&notifyAlso, "notify-also", nil, "comma-delimited users copied on reservation email, or 'none'")
+	cmdEditRes.Flags().BoolVar(&keep, "keep", false, "keep an idle reservation from being shortened")
+	cmdEditRes.Flags().StringVar(&head, "head", "", "make a node of the reservation its head node")
+	cmdEditRes.Flags().StringVarP(&vlan, "vlan", "v", "", "vlan number, named network or existing res name")
+	cmdEditRes.Flags().StringVar(&endPower, "end-power", "", "power state of the nodes when the reservation ends (off|leave-on)")
+	cmdEditRes.Flags().StringVar(&netProfile, "net-profile", "", "network profile for the reservation's ports, or 'none' (admin only)")
+	cmdEditRes.Flags().StringVar(&dataFile, "data-file", "", "file of data for the reservation's nodes to fetch, or 'none'")
 	_ = registerFlagArgsFunc(cmdEditRes, "extend", []string{"DATE/DUR"})
 	_ = registerFlagArgsFunc(cmdEditRes, "drop", []string{"NODES"})
 	_ = registerFlagArgsFunc(cmdEditRes, "distro", []string{"DISTRO"})
 	_ = registerFlagArgsFunc(cmdEditRes, "profile", []string{"PROFILE"})
+	_ = registerFlagArgsFunc(cmdEditRes, "cycle-at", []string{"DATETIME"})
 	_ = registerFlagArgsFunc(cmdEditRes, "name", []string{"NAME"})
 	_ = registerFlagArgsFunc(cmdEditRes, "owner", []string{"OWNER"})
 	_ = registerFlagArgsFunc(cmdEditRes, "group", []string{"GROUP"})
 	_ = registerFlagArgsFunc(cmdEditRes, "kernel-args", []string{"\"KARGS\""})
 	_ = registerFlagArgsFunc(cmdEditRes, "desc", []string{"\"DESCRIPTION\""})
+	_ = registerFlagArgsFunc(cmdEditRes, "add-co-owner", []string{"USER1"})
+	_ = registerFlagArgsFunc(cmdEditRes, "rmv-co-owner", []string{"USER1"})
+	_ = registerFlagArgsFunc(cmdEditRes, "notify-also", []string{"USER1"})
+	_ = registerFlagArgsFunc(cmdEditRes, "head", []string{"NODE"})
+	_ = registerFlagArgsFunc(cmdEditRes, "vlan", []string{"ID/NET/RES"})
+	_ = registerFlagArgsFunc(cmdEditRes, "end-power", []string{"off", "leave-on"})
+	_ = registerFlagArgsFunc(cmdEditRes, "net-profile", []string{"PROFILE"})
+	_ = registerFlagArgsFunc(cmdEditRes, "data-file", []string{"FILE"})
 
 	return cmdEditRes
 }
 
-func newResDelCmd() *cobra.Command {
+func newResReimageCmd() *cobra.Command {
 
-	cmdDeleteRes := &cobra.Command{
-		Use:   "del NAME",
-		Short: "Delete a reservation",
+	cmdReimageRes := &cobra.Command{
+		Use:   "reimage NAME [-p PROFILE | -d DISTRO] [--nodes NODES] [--cycle] [--persist]",
+		Short: "Reimage the nodes of a reservation",
 		Long: `
-Deletes a reservation. This can only done by the reservation owner, group 
-member or an admin.
+Rewrites the network boot configuration of an installed reservation's nodes so
+they boot a different profile or distro, or boot the reservation's own profile
+again. This can be done by the reservation owner, a co-owner, a member of the
+reservation's group or an admin, unless the policy of one of the nodes makes
+reimaging admin-only.
 
 ` + requiredArgs + `
 
   NAME : reservation name
+
+` + optionalFlags + `
+
+Use the -p flag to boot an existing profile or the -d flag to boot a bare
+distro. If neither is given the reservation's current profile is used. Unlike
+'igor res edit', the reservation keeps its own profile so it is used again the
+next time the nodes are reimaged without -p or -d. Add the --persist flag to
+make the new profile or distro the reservation's profile as well.
+
+Use the --nodes flag to reimage only some of the reservation's nodes. The
+NODES arg is the same used in 'igor res create'; a comma-delimited list
+(kn1,kn2,...) or a multi-node range (kn[3,16-20,34]). All of the nodes must
+belong to the reservation.
+
+The nodes boot the new image the next time they are power cycled. Use the
+--cycle flag to power cycle the reimaged nodes right away.
+
+The result for each node is listed. Each reimage is noted in the reservation
+history with the profile, the nodes and who ran it. Only one reimage of a
+reservation can run at a time.
 `,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			printRespSimple(doDeleteReservation(args[0]))
+			flagset := cmd.Flags()
+			distro, _ := flagset.GetString("distro")
+			profile, _ := flagset.GetString("profile")
+			nodes, _ := flagset.GetString("nodes")
+			cycle := flagset.Changed("cycle")
+			persist := flagset.Changed("persist")
+			printReimage(doReimageReservation(args[0], distro, profile, nodes, cycle, persist))
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNameArg,
 	}
 
-	return cmdDeleteRes
+	var distro,
+		profile,
+		nodes string
+	var cycle,
+		persist bool
+
+	cmdReimageRes.Flags().StringVarP(&distro, "distro", "d", "", "distro to boot")
+	cmdReimageRes.Flags().StringVarP(&profile, "profile", "p", "", "profile to boot")
+	cmdReimageRes.Flags().StringVar(&nodes, "nodes", "", "reimage only these nodes of the reservation")
+	cmdReimageRes.Flags().BoolVar(&cycle, "cycle", false, "power cycle the reimaged nodes")
+	cmdReimageRes.Flags().BoolVar(&persist, "persist", false, "also make the profile or distro the reservation's profile")
+	_ = registerFlagArgsFunc(cmdReimageRes, "distro", []string{"DISTRO"})
+	_ = registerFlagArgsFunc(cmdReimageRes, "profile", []string{"PROFILE"})
+	_ = registerFlagArgsFunc(cmdReimageRes, "nodes", []string{"NODES"})
+
+	return cmdReimageRes
 }
 
-func doCreateReservation(resName, distro, profile, owner, group, desc, stime, etime, vlan, nodes, kernelArgs string, noCycle *bool) *common.ResponseBodyBasic {
+func newResBootInfoCmd() *cobra.Command {
 
-	params := map[string]interface{}{"name": resName}
+	cmdBootInfoRes := &cobra.Command{
+		Use:   "bootinfo NAME [-x]",
+		Short: "Show how the nodes of a reservation boot",
+		Long: `
+Shows the boot details of each node in a reservation: the boot type, power
+state and any error installing the reservation's profile on the node.
 
-	if nodeCount, err := strconv.Atoi(nodes); err != nil {
-		params["nodeList"] = nodes
-	} else {
-		params["nodeCount"] = nodeCount
-	}
-	if profile != "" {
-		params["profile"] = profile
-	}
-	if distro != "" {
-		params["distro"] = distro
-	}
-	if group != "" {
-		params["group"] = group
-	}
-	if owner != "" {
-		params["owner"] = owner
-	}
-	if stime != "" {
-		if _, err := common.ParseTimeFormat(stime); err != nil {
-			checkClientErr(err)
-		}
-		startTime, _ := time.ParseInLocation(common.DateTimeCompactFormat, stime, cli.tzLoc)
-		params["start"] = startTime.Unix()
-	}
-	if etime != "" {
-		endTime, err := time.ParseInLocation(common.DateTimeCompactFormat, etime, cli.tzLoc)
-		if err != nil {
-			if _, pErr := common.ParseDuration(etime); pErr != nil {
-				checkClientErr(fmt.Errorf("end time format invalid or not recognized: %v; and %v", err, pErr))
-			}
-			params["duration"] = etime
-		} else {
-			params["duration"] = endTime.Unix()
-		}
-	}
-	if vlan != "" {
-		params["vlan"] = vlan
-	}
-	if desc != "" {
-		params["description"] = desc
-	}
-	if kernelArgs != "" {
-		params["kernelArgs"] = kernelArgs
-	}
-	if noCycle != nil && *noCycle {
-		params["noCycle"] = true
-	}
+` + requiredArgs + `
 
-	body := doSend(http.MethodPost, api.Reservations, params)
-	return unmarshalBasicResponse(body)
-}
+  NAME : reservation name
 
-func doShowReservation(showAll *bool, names, distros, profiles, owners, groups []string) *common.ResponseBodyReservations {
+` + optionalFlags + `
 
-	var params string
+If the server is configured with a console URL, the link to each node's serial
+console is listed so a node that fails to boot can be looked at without asking
+an admin. Console links are only shown to the reservation's owner, co-owners
+and group members while the reservation is active, and to admins.
 
-	if showAll != nil {
-		params += "all=" + strconv.FormatBool(*showAll) + "&"
-	}
+If the reservation's distro has usage notes, such as how to log in to the
+nodes, they are shown after the nodes.
 
-	if len(names) > 0 {
-		for _, n := range names {
-			params += "name=" + n + "&"
-		}
-	}
-	if len(distros) > 0 {
-		for _, o := range distros {
-			params += "distro=" + o + "&"
-		}
-	}
-	if len(profiles) > 0 {
-		for _, p := range profiles {
-			params += "profile=" + p + "&"
-		}
-	}
-	if len(owners) > 0 {
-		for _, o := range owners {
-			params += "owner=" + o + "&"
-		}
-	}
-	if len(groups) > 0 {
-		for _, g := range groups {
-			params += "group=" + g + "&"
-		}
-	}
-	if params != "" {
-		params = strings.TrimSuffix(params, "&")
-		params = "?" + params
+Use the -x flag to render screen output without pretty formatting.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			simplePrint = cmd.Flags().Changed("simple")
+			showAll := true
+			rbRes := doShowReservation(&showAll, []string{args[0]}, nil, nil, nil, nil, time.Time{})
+			rbHosts := doShowHosts("", nil, nil, nil, nil, nil, []string{args[0]}, nil, nil)
+			printBootInfo(args[0], rbRes, rbHosts)
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNameArg,
 	}
 
-	apiPath := api.Reservations + params
-	body := doSend(http.MethodGet, apiPath, nil)
-	rb := common.ResponseBodyReservations{}
-	err := json.Unmarshal(*body, &rb)
-	checkUnmarshalErr(err)
-	return &rb
+	cmdBootInfoRes.Flags().BoolVarP(&simplePrint, "simple", "x", false, "use simple text output")
+
+	return cmdBootInfoRes
 }
 
-func doEditReservation(resName, extend, drop, distro, profile, newName, owner, group, desc, kernelArgs string, extendMax bool) *common.ResponseBodyBasic {
-	apiPath := api.Reservations + "/" + resName
-	params := map[string]interface{}{}
+func newResShareCmd() *cobra.Command {
 
-	if extend != "" {
-		endTime, err := time.ParseInLocation(common.DateTimeCompactFormat, extend, cli.tzLoc)
-		if err != nil {
-			if _, pErr := common.ParseDuration(extend); pErr != nil {
-				checkClientErr(fmt.Errorf("end time format invalid or not recognized: %v; and %v", err, pErr))
-			}
-			params["extend"] = extend
-		} else {
-			params["extend"] = endTime.Unix()
-		}
-	}
-	if extendMax {
-		params["extendMax"] = true
-	}
-	if drop != "" {
-		params["drop"] = drop
-	}
-	if distro != "" {
-		params["distro"] = distro
-	}
-	if profile != "" {
-		params["profile"] = profile
-	}
-	if newName != "" {
-		params["name"] = newName
-	}
-	if owner != "" {
-		params["owner"] = owner
-	}
-	if group != "" {
-		params["group"] = group
-	}
-	if desc != "" {
-		params["description"] = desc
-	}
-	if kernelArgs != "" {
-		params["kernelArgs"] = kernelArgs
-	}
+	cmdShareRes := &cobra.Command{
+		Use:   "share NAME [--expires DURATION] | --revoke[=ID]",
+		Short: "Share a read-only view of a reservation",
+		Long: `
+Makes a share link for a reservation that can be handed to someone without an
+igor account, such as an outside collaborator. Anyone holding the link can see
+the reservation's name, description, times, nodes and their power state in
+igorweb, but nothing about its owner or how its nodes boot. Only the owner of
+the reservation can share it.
 
-	body := doSend(http.MethodPatch, apiPath, params)
-	return unmarshalBasicResponse(body)
-}
+` + requiredArgs + `
 
-func doDeleteReservation(resName string) *common.ResponseBodyBasic {
-	apiPath := api.Reservations + "/" + resName
-	body := doSend(http.MethodDelete, apiPath, nil)
-	return unmarshalBasicResponse(body)
-}
+  NAME : reservation name
 
-func printReservations(rb *common.ResponseBodyReservations) {
+` + optionalFlags + `
 
-	checkAndSetColorLevel(rb)
+Use the --expires flag to set how long the link works. The DURATION arg is
+the same used in 'igor res create'; for example 14d or 36h. A link lasts for 7
+days if not given, and never lasts past the end of the reservation. The server
+sets the longest a link can last.
 
-	resList := rb.Data["reservations"]
-	if len(resList) == 0 {
-		printSimple("no reservations to show (yet) or no matches based on search criteria", cRespWarn)
+A link stops working when it expires, when the reservation ends or when the
+reservation changes owner. Each link can only be used a limited number of
+times a minute. The owner sees the ID and expiry of each working link in
+'igor res show'.
+
+Use the --revoke flag to stop all of the reservation's links from working, or
+--revoke=ID to stop a single link. Admins can also revoke links.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			expires, _ := flagset.GetString("expires")
+			revoke, _ := flagset.GetString("revoke")
+			if flagset.Changed("revoke") {
+				if flagset.Changed("expires") {
+					printSimple("--expires cannot be used with --revoke", cRespWarn)
+				}
+				printRespSimple(doRevokeResShare(args[0], revoke))
+			}
+			printResShare(args[0], doShareReservation(args[0], expires))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNameArg,
 	}
 
-	sort.Slice(resList, func(i, j int) bool {
-		return resList[i].End < resList[j].End
-	})
+	var expires,
+		revoke string
 
-	oneYearLater := igorCliNow.Add(time.Hour * 24 * 365).Unix()
+	cmdShareRes.Flags().StringVar(&expires, "expires", "", "how long the share link works")
+	cmdShareRes.Flags().StringVar(&revoke, "revoke", "", "revoke all share links, or the one with the given ID")
+	cmdShareRes.Flags().Lookup("revoke").NoOptDefVal = "all"
+	_ = registerFlagArgsFunc(cmdShareRes, "expires", []string{"DURATION"})
 
-	timeFmt := "Jan 2 3:04 PM"
+	return cmdShareRes
+}
 
-	if simplePrint {
+func newResPauseCmd() *cobra.Command {
 
-		var resInfo string
-		for _, r := range resList {
+	cmdPauseRes := &cobra.Command{
+		Use:   "pause NAME --until DATETIME [--substitute]",
+		Short: "Pause a reservation until a later time",
+		Long: `
+Pauses an active reservation, releasing its nodes for others to use until the
+reservation resumes. The nodes are powered off and uninstalled the same way
+they are when a reservation is deleted, but the reservation keeps its VLAN and
+its booking from the resume time to its current end time. This can only be
+done by the reservation owner, a co-owner or an admin.
 
-			if r.End > oneYearLater {
-				timeFmt = "Jan-02-06.15:04-07:00"
-			}
+` + requiredArgs + `
 
-			resInfo = "RESERVATION: " + r.Name + "\n"
-			resInfo += "  -DESCRIPTION:  " + r.Description + "\n"
-			resInfo += "  -OWNER:        " + r.Owner + "\n"
-			resInfo += "  -GROUP:        " + r.Group + "\n"
+  NAME : reservation name
+
+` + requiredFlags + `
+
+  --until DATETIME : the time the reservation resumes, in the format
+                     ` + exStartDts() + `. It must be before the reservation
+                     ends.
+
+When the resume time arrives the reservation's nodes are installed again the
+same way as when a reservation starts. If another reservation has taken any
+of the nodes in the meantime the reservation stays paused and an email lists
+the nodes that are no longer free. Add the --substitute flag to allow igor to
+resume the reservation on other available nodes in that case.
+
+A paused reservation can be resumed early with 'igor res resume'. Paused res-
+ervations are marked as paused in 'igor res show' and 'igor show'.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			until, _ := flagset.GetString("until")
+			substitute := flagset.Changed("substitute")
+			printRespSimple(doPauseReservation(args[0], until, substitute))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNameArg,
+	}
+
+	var until string
+	var substitute bool
+
+	cmdPauseRes.Flags().StringVar(&until, "until", "", "time the reservation resumes")
+	cmdPauseRes.Flags().BoolVar(&substitute, "substitute", false, "resume on substitute nodes if any are taken")
+	_ = cmdPauseRes.MarkFlagRequired("until")
+	_ = registerFlagArgsFunc(cmdPauseRes, "until", []string{"DATETIME"})
+
+	return cmdPauseRes
+}
+
+func newResResumeCmd() *cobra.Command {
+
+	cmdResumeRes := &cobra.Command{
+		Use:   "resume NAME [--substitute]",
+		Short: "Resume a paused reservation now",
+		Long: `
+Resumes a paused reservation ahead of its scheduled resume time, or retries a
+reservation that could not resume when scheduled. Its nodes are installed the
+same way as when a reservation starts. This can only be done by the reserva-
+tion owner, a co-owner or an admin.
+
+` + requiredArgs + `
+
+  NAME : reservation name
+
+` + optionalFlags + `
+
+If any of the reservation's nodes are no longer free the resume fails and the
+response lists those nodes. Use the --substitute flag to allow igor to resume
+the reservation on other available nodes instead. If not given, the choice
+made when the reservation was paused is used.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var substitute *bool
+			if cmd.Flags().Changed("substitute") {
+				s, _ := cmd.Flags().GetBool("substitute")
+				substitute = &s
+			}
+			printRespSimple(doResumeReservation(args[0], substitute))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNameArg,
+	}
+
+	var substitute bool
+
+	cmdResumeRes.Flags().BoolVar(&substitute, "substitute", false, "resume on substitute nodes if any are taken")
+
+	return cmdResumeRes
+}
+
+func newResClaimCmd() *cobra.Command {
+
+	cmdClaimRes := &cobra.Command{
+		Use:   "claim NAME",
+		Short: "Take over a reservation whose owner is leaving",
+		Long: `
+Makes you the owner of a reservation whose owner's account is pending removal.
+This happens when the owner is no longer listed in LDAP, and the members of
+the reservation's group are sent an email inviting one of them to claim it.
+Only a member of the reservation's group can claim it. If no one claims the
+reservation before the owner's account is removed, it is handed to igor-admin.
+
+` + requiredArgs + `
+
+  NAME : reservation name
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printRespSimple(doClaimReservation(args[0]))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNameArg,
+	}
+
+	return cmdClaimRes
+}
+
+func newResPendingCmd() *cobra.Command {
+
+	cmdPendingRes := &cobra.Command{
+		Use:   "pending [-x]",
+		Short: "Show reservations waiting for admin approval",
+		Long: `
+Shows reservations waiting for an admin to approve them. When the server is
+configured with approval thresholds, a reservation with more nodes or a longer
+duration than allowed is held until an admin approves or denies it. A held
+reservation keeps its nodes but is not installed. If no admin acts before the
+hold time ends the reservation is deleted and its owner is emailed.
+
+The requester can cancel their own pending reservation with 'igor res del'.
+
+` + optionalFlags + `
+
+Use the -x flag to render screen output without pretty formatting.
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			simplePrint = cmd.Flags().Changed("simple")
+			showAll := true
+			printPendingReservations(doShowReservation(&showAll, nil, nil, nil, nil, nil, time.Time{}))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+
+	cmdPendingRes.Flags().BoolVarP(&simplePrint, "simple", "x", false, "use simple text output")
+
+	return cmdPendingRes
+}
+
+func newResApproveCmd() *cobra.Command {
+
+	cmdApproveRes := &cobra.Command{
+		Use:   "approve NAME [--reason TEXT]",
+		Short: "Approve a reservation waiting for admin approval",
+		Long: `
+Approves a reservation that is waiting for admin approval so it goes on as
+normal. If its start time has passed it is installed right away. The owner is
+emailed that the reservation was approved. This command requires admin
+elevated privilege.
+
+` + requiredArgs + `
+
+  NAME : reservation name
+
+` + optionalFlags + `
+
+Use the --reason flag to include a note in the email to the owner.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			reason, _ := cmd.Flags().GetString("reason")
+			printRespSimple(doApproveReservation(args[0], reason))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNameArg,
+	}
+
+	var reason string
+	cmdApproveRes.Flags().StringVar(&reason, "reason", "", "note to include in the email to the owner")
+	_ = registerFlagArgsFunc(cmdApproveRes, "reason", []string{"TEXT"})
+
+	return cmdApproveRes
+}
+
+func newResDenyCmd() *cobra.Command {
+
+	cmdDenyRes := &cobra.Command{
+		Use:   "deny NAME --reason TEXT",
+		Short: "Deny a reservation waiting for admin approval",
+		Long: `
+Denies a reservation that is waiting for admin approval. The reservation is
+deleted, its nodes are released and the owner is emailed the reason. This
+command requires admin elevated privilege.
+
+` + requiredArgs + `
+
+  NAME : reservation name
+
+` + requiredFlags + `
+
+  --reason : why the reservation was denied, sent to the owner
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			reason, _ := cmd.Flags().GetString("reason")
+			printRespSimple(doDenyReservation(args[0], reason))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNameArg,
+	}
+
+	var reason string
+	cmdDenyRes.Flags().StringVar(&reason, "reason", "", "why the reservation was denied")
+	_ = cmdDenyRes.MarkFlagRequired("reason")
+	_ = registerFlagArgsFunc(cmdDenyRes, "reason", []string{"TEXT"})
+
+	return cmdDenyRes
+}
+
+func newResBulkExtendCmd() *cobra.Command {
+
+	cmdBulkExtend := &cobra.Command{
+		Use:   "bulk-extend --owner USER --until DATETIME [--filter-name PREFIX] [--dry-run]",
+		Short: "Extend all reservations of a user " + adminOnly,
+		Long: `
+Extends every reservation owned by a user to end at the same time, such as
+when a course runs longer than planned. Each reservation is checked the same
+way as when its owner extends it, including the time limits of its nodes and
+of the owner's groups, but without the window before the end in which owners
+must ask for more time. A reservation that can't be extended is listed with
+the reason and the rest are still extended.
+
+The owner is emailed about each extended reservation and its history records
+the admin that made the change. At most 100 reservations can be changed at a
+time.
+
+` + requiredFlags + `
+
+  --owner USER     : the owner of the reservations
+  --until DATETIME : the new end time, in the format ` + exEndDts() + `
+
+` + optionalFlags + `
+
+Use the --filter-name flag to only extend reservations whose names start with
+PREFIX.
+
+Use the --dry-run flag to check each reservation and list what would change
+without changing anything.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			owner, _ := flagset.GetString("owner")
+			until, _ := flagset.GetString("until")
+			filterName, _ := flagset.GetString("filter-name")
+			dryRun, _ := flagset.GetBool("dry-run")
+			printBatchResults(doBulkExtendReservations(owner, until, filterName, dryRun))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+
+	var owner, until, filterName string
+	var dryRun bool
+	cmdBulkExtend.Flags().StringVar(&owner, "owner", "", "owner of the reservations")
+	cmdBulkExtend.Flags().StringVar(&until, "until", "", "new end time of the reservations")
+	cmdBulkExtend.Flags().StringVar(&filterName, "filter-name", "", "only reservations whose names start with this")
+	cmdBulkExtend.Flags().BoolVar(&dryRun, "dry-run", false, "list the changes without making them")
+	_ = cmdBulkExtend.MarkFlagRequired("owner")
+	_ = cmdBulkExtend.MarkFlagRequired("until")
+	_ = registerFlagArgsFunc(cmdBulkExtend, "owner", []string{"USER"})
+	_ = registerFlagArgsFunc(cmdBulkExtend, "until", []string{"DATETIME"})
+	_ = registerFlagArgsFunc(cmdBulkExtend, "filter-name", []string{"PREFIX"})
+
+	return cmdBulkExtend
+}
+
+func newResBulkChownCmd() *cobra.Command {
+
+	cmdBulkChown := &cobra.Command{
+		Use:   "bulk-chown --from USER --to USER [--filter-name PREFIX] [--dry-run]",
+		Short: "Give all reservations of a user to another user " + adminOnly,
+		Long: `
+Changes the owner of every reservation owned by one user to another, such as
+when a course is handed to a new instructor. Each reservation is checked the
+same way as when its owner is changed with 'igor res edit -o'. A reservation
+whose owner can't be changed is listed with the reason and the rest are still
+changed.
+
+The new owner is emailed about each reservation they receive and its history
+records the admin that made the change. At most 100 reservations can be
+changed at a time.
+
+` + requiredFlags + `
+
+  --from USER : the current owner of the reservations
+  --to USER   : the new owner of the reservations
+
+` + optionalFlags + `
+
+Use the --filter-name flag to only change reservations whose names start with
+PREFIX.
+
+Use the --dry-run flag to check each reservation and list what would change
+without changing anything.
+
+` + adminOnlyBanner + `
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			from, _ := flagset.GetString("from")
+			to, _ := flagset.GetString("to")
+			filterName, _ := flagset.GetString("filter-name")
+			dryRun, _ := flagset.GetBool("dry-run")
+			printBatchResults(doBulkChownReservations(from, to, filterName, dryRun))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+
+	var from, to, filterName string
+	var dryRun bool
+	cmdBulkChown.Flags().StringVar(&from, "from", "", "current owner of the reservations")
+	cmdBulkChown.Flags().StringVar(&to, "to", "", "new owner of the reservations")
+	cmdBulkChown.Flags().StringVar(&filterName, "filter-name", "", "only reservations whose names start with this")
+	cmdBulkChown.Flags().BoolVar(&dryRun, "dry-run", false, "list the changes without making them")
+	_ = cmdBulkChown.MarkFlagRequired("from")
+	_ = cmdBulkChown.MarkFlagRequired("to")
+	_ = registerFlagArgsFunc(cmdBulkChown, "from", []string{"USER"})
+	_ = registerFlagArgsFunc(cmdBulkChown, "to", []string{"USER"})
+	_ = registerFlagArgsFunc(cmdBulkChown, "filter-name", []string{"PREFIX"})
+
+	return cmdBulkChown
+}
+
+func newResDelCmd() *cobra.Command {
+
+	cmdDeleteRes := &cobra.Command{
+		Use:   "del NAME [--yes | --dry-run]",
+		Short: "Delete a reservation",
+		Long: `
+Deletes a reservation. This can only done by the reservation owner, group 
+member or an admin.
+
+Before deleting a reservation you don't own, its owner, node count and end
+time are shown and you must type the reservation name to confirm. Owners and
+admins are not asked unless confirmDelete is set in the igor config file.
+
+` + requiredArgs + `
+
+  NAME : reservation name
+
+` + optionalFlags + `
+
+Use the --yes flag to skip the confirmation, such as in scripts.
+
+Use the --dry-run flag to show what would be deleted, including whether the
+nodes would go into maintenance, without deleting anything.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			flagset := cmd.Flags()
+			yes, _ := flagset.GetBool("yes")
+			dryRun, _ := flagset.GetBool("dry-run")
+			if yes && dryRun {
+				checkClientErr(fmt.Errorf("--yes and --dry-run cannot be used together"))
+			}
+			if dryRun || !yes {
+				rb := doReadResDelete(args[0])
+				if !rb.IsSuccess() {
+					printRespSimple(rb)
+				}
+				summary := rb.Data["reservation"]
+				if dryRun {
+					fmt.Println(resDeleteSummary(&summary, cliDateFormat(), cli.tzLoc))
+					printSimple("dry run -- nothing was deleted", cRespWarn)
+				}
+				if needDeleteConfirm(&summary, cli.Client.ConfirmDelete) {
+					confirmResDelete(&summary)
+				}
+			}
+			printRespSimple(doDeleteReservation(args[0]))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNameArg,
+	}
+
+	var yes, dryRun bool
+	cmdDeleteRes.Flags().BoolVarP(&yes, "yes", "y", false, "delete without asking for confirmation")
+	cmdDeleteRes.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be deleted without deleting it")
+
+	return cmdDeleteRes
+}
+
+func doCreateReservation(resName, distro, profile, owner, group, desc, stime, etime, vlan, nodes, kernelArgs, endPower string, noCycle *bool, clamp, grantAccess, noDefaultKargs, asap bool, minNodes int) *common.ResponseBodyBasic {
+
+	checkNewName(naming.Reservation, resName)
+	params := map[string]interface{}{"name": resName}
+
+	if nodeCount, err := strconv.Atoi(nodes); err != nil {
+		params["nodeList"] = nodes
+	} else {
+		params["nodeCount"] = nodeCount
+	}
+	if profile != "" {
+		params["profile"] = profile
+	}
+	if distro != "" {
+		params["distro"] = distro
+	}
+	if group != "" {
+		params["group"] = group
+	}
+	if owner != "" {
+		params["owner"] = owner
+	}
+	if stime != "" {
+		if _, err := common.ParseTimeFormat(stime); err != nil {
+			checkClientErr(err)
+		}
+		startTime, _ := time.ParseInLocation(common.DateTimeCompactFormat, stime, cli.tzLoc)
+		params["start"] = startTime.Unix()
+	}
+	if etime != "" {
+		params["duration"] = resDurationParam(etime)
+	}
+	if vlan != "" {
+		params["vlan"] = vlan
+	}
+	if desc != "" {
+		params["description"] = desc
+	}
+	if kernelArgs != "" {
+		params["kernelArgs"] = kernelArgs
+	}
+	if noCycle != nil && *noCycle {
+		params["noCycle"] = true
+	}
+	if clamp {
+		params["clampToLimit"] = true
+	}
+	if grantAccess {
+		params["grantAccess"] = true
+	}
+	if noDefaultKargs {
+		params["noDefaultKernelArgs"] = true
+	}
+	if asap {
+		params["asap"] = true
+	}
+	if minNodes > 0 {
+		params["minNodes"] = minNodes
+	}
+	if endPower != "" {
+		params["endPower"] = endPower
+	}
+
+	// a new key for each invocation lets the server recognize this request if it has to be resent
+	headers := map[string]string{common.IdempotencyHeader: newIdempotencyKey()}
+	body := doSendWithHeaders(http.MethodPost, api.Reservations, params, headers)
+	return unmarshalBasicResponse(body)
+}
+
+func doQuickReservation(etime string) *common.ResponseBodyBasic {
+	params := map[string]interface{}{"quick": true}
+	if etime != "" {
+		params["duration"] = resDurationParam(etime)
+	}
+	headers := map[string]string{common.IdempotencyHeader: newIdempotencyKey()}
+	body := doSendWithHeaders(http.MethodPost, api.Reservations, params, headers)
+	return unmarshalBasicResponse(body)
+}
+
+// resDurationParam returns the duration parameter of a reservation from an -e flag value, which is either a
+// datetime or a duration.
+func resDurationParam(etime string) interface{} {
+	endTime, err := time.ParseInLocation(common.DateTimeCompactFormat, etime, cli.tzLoc)
+	if err != nil {
+		if _, pErr := common.ParseDuration(etime); pErr != nil {
+			checkClientErr(fmt.Errorf("end time format invalid or not recognized: %v; and %v", err, pErr))
+		}
+		return etime
+	}
+	return endTime.Unix()
+}
+
+func doShowReservation(showAll *bool, names, distros, profiles, owners, groups []string, deadline time.Time) *common.ResponseBodyReservations {
+
+	var params string
+
+	if showAll != nil {
+		params += "all=" + strconv.FormatBool(*showAll) + "&"
+	}
+
+	if len(names) > 0 {
+		for _, n := range names {
+			params += "name=" + n + "&"
+		}
+	}
+	if len(distros) > 0 {
+		for _, o := range distros {
+			params += "distro=" + o + "&"
+		}
+	}
+	if len(profiles) > 0 {
+		for _, p := range profiles {
+			params += "profile=" + p + "&"
+		}
+	}
+	if len(owners) > 0 {
+		for _, o := range owners {
+			params += "owner=" + o + "&"
+		}
+	}
+	if len(groups) > 0 {
+		for _, g := range groups {
+			params += "group=" + g + "&"
+		}
+	}
+	if !deadline.IsZero() {
+		params += "to-end=" + endsBeforeParam(deadline) + "&"
+	}
+	if params != "" {
+		params = strings.TrimSuffix(params, "&")
+		params = "?" + params
+	}
+
+	apiPath := api.Reservations + params
+	body := doSend(http.MethodGet, apiPath, nil)
+	rb := common.ResponseBodyReservations{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func doEditReservation(resName, extend, drop, distro, profile, newName, owner, group, desc, kernelArgs, head, vlan, endPower, netProfile, cycleAt, dataFile string, extendMax, clamp, cycleNow, cancelCycle bool, addCoOwners, rmvCoOwners, notifyAlso []string, keepCoOwners, keep bool) *common.ResponseBodyBasic {
+	apiPath := api.Reservations + "/" + resName
+	params := map[string]interface{}{}
+
+	if extend != "" {
+		endTime, err := time.ParseInLocation(common.DateTimeCompactFormat, extend, cli.tzLoc)
+		if err != nil {
+			if _, pErr := common.ParseDuration(extend); pErr != nil {
+				checkClientErr(fmt.Errorf("end time format invalid or not recognized: %v; and %v", err, pErr))
+			}
+			params["extend"] = extend
+		} else {
+			params["extend"] = endTime.Unix()
+		}
+	}
+	if extendMax {
+		params["extendMax"] = true
+	}
+	if clamp {
+		params["clampToLimit"] = true
+	}
+	if drop != "" {
+		params["drop"] = drop
+	}
+	if distro != "" {
+		params["distro"] = distro
+	}
+	if profile != "" {
+		params["profile"] = profile
+	}
+	if cycleNow {
+		params["cycleNow"] = true
+	}
+	if cycleAt != "" {
+		cycleTime, err := time.ParseInLocation(common.DateTimeCompactFormat, cycleAt, cli.tzLoc)
+		if err != nil {
+			checkClientErr(fmt.Errorf("power cycle time format invalid or not recognized: %v", err))
+		}
+		params["cycleAt"] = cycleTime.Unix()
+	}
+	if cancelCycle {
+		params["cancelCycle"] = true
+	}
+	if newName != "" {
+		checkNewName(naming.Reservation, newName)
+		params["name"] = newName
+	}
+	if owner != "" {
+		params["owner"] = owner
+	}
+	if group != "" {
+		params["group"] = group
+	}
+	if desc != "" {
+		params["description"] = desc
+	}
+	if kernelArgs != "" {
+		params["kernelArgs"] = kernelArgs
+	}
+	if len(addCoOwners) > 0 {
+		params["addCoOwners"] = addCoOwners
+	}
+	if len(rmvCoOwners) > 0 {
+		params["rmvCoOwners"] = rmvCoOwners
+	}
+	if keepCoOwners {
+		params["keepCoOwners"] = true
+	}
+	if len(notifyAlso) > 0 {
+		params["notifyAlso"] = notifyAlso
+	}
+	if keep {
+		params["keep"] = true
+	}
+	if head != "" {
+		params["head"] = head
+	}
+	if vlan != "" {
+		params["vlan"] = vlan
+	}
+	if endPower != "" {
+		params["endPower"] = endPower
+	}
+	if netProfile != "" {
+		params["netProfile"] = netProfile
+	}
+	if dataFile == "none" {
+		params["data"] = ""
+	} else if dataFile != "" {
+		data, err := os.ReadFile(dataFile)
+		if err != nil {
+			checkClientErr(fmt.Errorf("unable to read data file: %v", err))
+		}
+		params["data"] = base64.StdEncoding.EncodeToString(data)
+	}
+
+	body := doSend(http.MethodPatch, apiPath, params)
+	return unmarshalBasicResponse(body)
+}
+
+func doReimageReservation(resName, distro, profile, nodes string, cycle, persist bool) *common.ResponseBodyReimage {
+	apiPath := api.Reservations + "/" + resName
+	params := map[string]interface{}{"reimage": true}
+	if distro != "" {
+		params["distro"] = distro
+	}
+	if profile != "" {
+		params["profile"] = profile
+	}
+	if nodes != "" {
+		params["nodes"] = nodes
+	}
+	if cycle {
+		params["cycle"] = true
+	}
+	if persist {
+		params["persist"] = true
+	}
+	body := doSend(http.MethodPatch, apiPath, params)
+	rb := common.ResponseBodyReimage{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func printReimage(rb *common.ResponseBodyReimage) {
+
+	results := rb.Data["hosts"]
+	if !rb.IsSuccess() {
+		printRespSimple(rb)
+	}
+
+	checkColorLevel()
+	for _, hr := range results {
+		if hr.Error != "" {
+			fmt.Printf("  %-12s %s - %s\n", hr.Host, cRespWarn.Sprint(hr.Result), hr.Error)
+		} else {
+			fmt.Printf("  %-12s %s\n", hr.Host, cRespSuccess.Sprint(hr.Result))
+		}
+	}
+	printRespSimple(rb)
+}
+
+func printBootInfo(resName string, rbRes *common.ResponseBodyReservations, rbHosts *common.ResponseBodyHosts) {
+
+	checkAndSetColorLevel(rbRes)
+
+	var res *common.ReservationData
+	for i, r := range rbRes.Data["reservations"] {
+		if r.Name == resName {
+			res = &rbRes.Data["reservations"][i]
+			break
+		}
+	}
+	if res == nil {
+		printSimple(fmt.Sprintf("no reservation named '%s'", resName), cRespWarn)
+		return
+	}
+	checkAndSetColorLevel(rbHosts)
+
+	status := "installed"
+	switch res.State {
+	case common.ResStatePaused, common.ResStateFuture:
+		status = res.State
+	case common.ResStatePendingApproval:
+		status = "pending approval"
+	case common.ResStateStartFailed:
+		status = cInstError.Sprint("start failed")
+	case common.ResStateInstallError:
+		status = cInstError.Sprint("install error")
+	case common.ResStateInstalling:
+		status = "not installed"
+	case common.ResStateExpired:
+		status = "ended, waiting for cleanup"
+	}
+	fmt.Printf("\n%s: profile %s (distro %s) - %s\n", sBold(res.Name), res.Profile, res.Distro, status)
+	if res.KernelLine != "" {
+		fmt.Printf("kernel args: %s\n", res.KernelLine)
+	}
+
+	hosts := rbHosts.Data["hosts"]
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].SequenceID < hosts[j].SequenceID
+	})
+
+	tw := table.NewWriter()
+	header := table.Row{"NODE", "BOOT-TYPE", "POWERED", "INSTALL-ERROR"}
+	if len(res.Consoles) > 0 {
+		header = append(header, "CONSOLE")
+	}
+	tw.AppendHeader(header)
+
+	for _, h := range hosts {
+		row := table.Row{sBold(h.Name), h.BootMode, h.Powered, h.InstallError}
+		if len(res.Consoles) > 0 {
+			row = append(row, res.Consoles[h.Name])
+		}
+		tw.AppendRow(row)
+	}
+
+	if simplePrint {
+		tw.Style().Options.SeparateRows = false
+		tw.Style().Options.SeparateColumns = true
+		tw.Style().Options.DrawBorder = false
+	} else {
+		tw.SetStyle(igorTableStyle)
+	}
+
+	fmt.Printf("\n" + tw.Render() + "\n\n")
+
+	if res.DistroNotes != "" {
+		fmt.Printf("%s\n%s\n\n", sBold("Usage notes for distro "+res.Distro+":"), indentNotes(res.DistroNotes))
+	}
+}
+
+// printKernelLine prints the kernel command line sent back by a create or edit that set kernel args,
+// so it can be checked before nodes boot with it.
+// printResCreate prints the result of creating a reservation. If the schedule was too full and the server
+// found a later time it could start, a hint to use that time with -s is added to the message.
+func printResCreate(rb *common.ResponseBodyBasic, showKernelLine bool) {
+	if showKernelLine {
+		printKernelLine(rb)
+	}
+	if start, ok := rb.Data["suggestedStart"].(float64); ok && !rb.IsSuccess() {
+		rb.Message = strings.TrimSpace(rb.Message) + fmt.Sprintf("\n  re-run with -s %s to start when enough nodes are free",
+			getLocTime(time.Unix(int64(start), 0)).Format(common.DateTimeCompactFormat))
+	}
+	printHostRejections(rb)
+	printRespSimple(rb)
+}
+
+// printHostRejections lists each named node that kept a reservation from being made and why, then
+// adds the nodes that could be used to the response message.
+func printHostRejections(rb *common.ResponseBodyBasic) {
+
+	rejectedData, ok := rb.Data["rejectedHosts"]
+	if rb.IsSuccess() || !ok {
+		return
+	}
+
+	var rejected []common.HostRejectionData
+	raw, err := json.Marshal(rejectedData)
+	checkUnmarshalErr(err)
+	checkUnmarshalErr(json.Unmarshal(raw, &rejected))
+	if len(rejected) == 0 {
+		return
+	}
+
+	var available []string
+	if availData, aOk := rb.Data["availableHosts"].([]interface{}); aOk {
+		for _, a := range availData {
+			if name, nOk := a.(string); nOk {
+				available = append(available, name)
+			}
+		}
+	}
+
+	checkColorLevel()
+	_, layout := resTimeLayouts(cliDateFormat(), false, simplePrint)
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"NODE", "REASON", "RESERVATION", "FROM", "UNTIL"})
+	var rejectedNames []string
+	for _, r := range rejected {
+		rejectedNames = append(rejectedNames, r.Host)
+		var from, until string
+		if r.ResName != "" {
+			from = getLocTime(time.Unix(r.ResStart, 0)).Format(layout)
+			until = getLocTime(time.Unix(r.ResEnd, 0)).Format(layout)
+		}
+		tw.AppendRow([]interface{}{r.Host, r.Reason, r.ResName, from, until})
+	}
+
+	if simplePrint {
+		tw.Style().Options.SeparateRows = false
+		tw.Style().Options.SeparateColumns = true
+		tw.Style().Options.DrawBorder = false
+	} else {
+		tw.SetStyle(igorTableStyle)
+	}
+	fmt.Printf("\n" + tw.Render() + "\n\n")
+
+	if len(available) > 0 {
+		verb := "are"
+		if len(available) == 1 {
+			verb = "is"
+		}
+		rb.Message = strings.TrimSpace(rb.Message) + fmt.Sprintf("\n  %s %s available; retry without %s",
+			common.UnsplitList(available), verb, common.UnsplitList(rejectedNames))
+	}
+}
+
+func printKernelLine(rb *common.ResponseBodyBasic) {
+	if line, ok := rb.Data["kernelLine"].(string); ok && rb.IsSuccess() {
+		if line == "" {
+			line = "(none)"
+		}
+		fmt.Printf("kernel args on the boot line: %s\n", line)
+	}
+}
+
+func doShareReservation(resName, expires string) *common.ResponseBodyResShare {
+	apiPath := api.Reservations + "/" + resName
+	params := map[string]interface{}{"share": expires}
+	body := doSend(http.MethodPatch, apiPath, params)
+	rb := common.ResponseBodyResShare{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func doRevokeResShare(resName, shareID string) *common.ResponseBodyBasic {
+	apiPath := api.Reservations + "/" + resName
+	params := map[string]interface{}{"revokeShare": shareID}
+	body := doSend(http.MethodPatch, apiPath, params)
+	return unmarshalBasicResponse(body)
+}
+
+func printResShare(resName string, rb *common.ResponseBodyResShare) {
+
+	link, ok := rb.Data["share"]
+	if !rb.IsSuccess() || !ok {
+		printRespSimple(rb)
+	}
+
+	checkColorLevel()
+	fmt.Printf("\n%s\n\n  %s\n\n", rb.Message, cRespSuccess.Sprint(link.Token))
+	if !strings.HasPrefix(link.Token, "http") {
+		fmt.Printf("Give the token to igorweb at /share/TOKEN to view the reservation.\n")
+	}
+	fmt.Printf("Anyone with this link can see the reservation - revoke it with 'igor res share %s --revoke=%s'.\n\n", resName, link.ID)
+}
+
+func doPauseReservation(resName, until string, substitute bool) *common.ResponseBodyBasic {
+	apiPath := api.Reservations + "/" + resName
+
+	untilTime, err := time.ParseInLocation(common.DateTimeCompactFormat, until, cli.tzLoc)
+	if err != nil {
+		checkClientErr(fmt.Errorf("resume time format invalid or not recognized: %v", err))
+	}
+	params := map[string]interface{}{"pause": untilTime.Unix()}
+	if substitute {
+		params["substitute"] = true
+	}
+
+	body := doSend(http.MethodPatch, apiPath, params)
+	return unmarshalBasicResponse(body)
+}
+
+func doResumeReservation(resName string, substitute *bool) *common.ResponseBodyBasic {
+	apiPath := api.Reservations + "/" + resName
+	params := map[string]interface{}{"resume": true}
+	if substitute != nil {
+		params["substitute"] = *substitute
+	}
+	body := doSend(http.MethodPatch, apiPath, params)
+	return unmarshalBasicResponse(body)
+}
+
+func doClaimReservation(resName string) *common.ResponseBodyBasic {
+	apiPath := api.Reservations + "/" + resName
+	body := doSend(http.MethodPatch, apiPath, map[string]interface{}{"claim": true})
+	return unmarshalBasicResponse(body)
+}
+
+func doApproveReservation(resName, reason string) *common.ResponseBodyBasic {
+	apiPath := api.Reservations + "/" + resName
+	params := map[string]interface{}{"approve": true}
+	if reason != "" {
+		params["reason"] = reason
+	}
+	body := doSend(http.MethodPatch, apiPath, params)
+	return unmarshalBasicResponse(body)
+}
+
+func doDenyReservation(resName, reason string) *common.ResponseBodyBasic {
+	apiPath := api.Reservations + "/" + resName
+	body := doSend(http.MethodPatch, apiPath, map[string]interface{}{"deny": reason})
+	return unmarshalBasicResponse(body)
+}
+
+func doReadResDelete(resName string) *common.ResponseBodyResDelete {
+	body := doSend(http.MethodGet, api.Reservations+"/"+resName, nil)
+	rb := common.ResponseBodyResDelete{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+// needDeleteConfirm returns true if the user must type the reservation name before it is deleted. Users who
+// don't own the reservation are always asked; owners and admins only if they set confirmDelete.
+func needDeleteConfirm(summary *common.ResDeleteData, confirmDelete bool) bool {
+	return !summary.OwnerOrAdmin || confirmDelete
+}
+
+// resDeleteSummary describes what deleting the reservation will do.
+func resDeleteSummary(summary *common.ResDeleteData, dateFmt string, loc *time.Location) string {
+
+	_, endLayout := resTimeLayouts(dateFmt, false, false)
+	lines := []string{
+		fmt.Sprintf("reservation: %s", summary.Name),
+		fmt.Sprintf("owner:       %s", summary.Owner),
+	}
+	if summary.Group != "" {
+		lines = append(lines, fmt.Sprintf("group:       %s", summary.Group))
+	}
+	lines = append(lines,
+		fmt.Sprintf("nodes:       %d (%s)", summary.HostCount, summary.HostRange),
+		fmt.Sprintf("ends:        %s", time.Unix(summary.End, 0).In(loc).Format(endLayout)),
+	)
+	switch {
+	case !summary.Active:
+		lines = append(lines, "the reservation has not started, its nodes are not affected")
+	case summary.MaintenanceMins > 0:
+		lines = append(lines, fmt.Sprintf("the nodes will be powered off and go into maintenance for %d minutes", summary.MaintenanceMins))
+	default:
+		lines = append(lines, "the nodes will be powered off and returned to the available pool")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// confirmResDelete shows what is about to be deleted and exits unless the user types the reservation name.
+func confirmResDelete(summary *common.ResDeleteData) {
+	fmt.Println(resDeleteSummary(summary, cliDateFormat(), cli.tzLoc))
+	fmt.Print("\ntype the reservation name to delete it: ")
+	reader := bufio.NewReader(os.Stdin)
+	answer, _ := reader.ReadString('\n')
+	if strings.TrimSpace(answer) != summary.Name {
+		checkClientErr(fmt.Errorf("name did not match -- reservation '%s' was not deleted", summary.Name))
+	}
+}
+
+func doBulkExtendReservations(owner, until, filterName string, dryRun bool) *common.ResponseBodyBatch {
+	untilTime, err := time.ParseInLocation(common.DateTimeCompactFormat, until, cli.tzLoc)
+	if err != nil {
+		checkClientErr(fmt.Errorf("end time format invalid or not recognized: %v", err))
+	}
+	params := map[string]interface{}{"owner": owner, "until": untilTime.Unix()}
+	if filterName != "" {
+		params["filterName"] = filterName
+	}
+	if dryRun {
+		params["dryRun"] = true
+	}
+	body := doSend(http.MethodPatch, api.ResBulkExtend, params)
+	return unmarshalBatchResponse(body)
+}
+
+func doBulkChownReservations(from, to, filterName string, dryRun bool) *common.ResponseBodyBatch {
+	params := map[string]interface{}{"from": from, "to": to}
+	if filterName != "" {
+		params["filterName"] = filterName
+	}
+	if dryRun {
+		params["dryRun"] = true
+	}
+	body := doSend(http.MethodPatch, api.ResBulkOwner, params)
+	return unmarshalBatchResponse(body)
+}
+
+func doDeleteReservation(resName string) *common.ResponseBodyBasic {
+	apiPath := api.Reservations + "/" + resName
+	body := doSend(http.MethodDelete, apiPath, nil)
+	return unmarshalBasicResponse(body)
+}
+
+func printReservations(rb *common.ResponseBodyReservations, deadline time.Time) {
+
+	checkAndSetColorLevel(rb)
+
+	resList := rb.Data["reservations"]
+	if len(resList) == 0 {
+		printSimple("no reservations to show (yet) or no matches based on search criteria", cRespWarn)
+	}
+
+	sort.Slice(resList, func(i, j int) bool {
+		return resList[i].End < resList[j].End
+	})
+
+	oneYearLater := igorCliNow.Add(time.Hour * 24 * 365).Unix()
+
+	dateFmt := cliDateFormat()
+	startTimeFmt, timeFmt := resTimeLayouts(dateFmt, false, simplePrint)
+
+	if simplePrint {
+
+		var resInfo string
+		for _, r := range resList {
+
+			if r.End > oneYearLater {
+				_, timeFmt = resTimeLayouts(dateFmt, true, simplePrint)
+			}
+
+			resInfo = "RESERVATION: " + r.Name + "\n"
+			resInfo += "  -DESCRIPTION:  " + r.Description + "\n"
+			resInfo += "  -OWNER:        " + r.Owner + "\n"
+			if len(r.CoOwners) > 0 {
+				resInfo += "  -CO-OWNERS:    " + sortedJoin(r.CoOwners, ",") + "\n"
+			}
+			if len(r.NotifyAlso) > 0 {
+				resInfo += "  -NOTIFY-ALSO:  " + sortedJoin(r.NotifyAlso, ",") + "\n"
+			}
+			resInfo += "  -GROUP:        " + r.Group + "\n"
 			resInfo += "  -PROFILE:      " + r.Profile + "\n"
 			resInfo += "  -DISTRO:       " + r.Distro + "\n"
 			resInfo += "  -HOSTS:        " + r.HostRange + "\n"
+			if r.HeadHost != "" {
+				resInfo += "  -HEAD:         " + r.HeadHost + "\n"
+			}
 			resInfo += "  -VLAN:         " + strconv.Itoa(r.Vlan) + "\n"
+			if isolated := resIsolatedPortsInfo(r); isolated != "" {
+				resInfo += "  -ISOLATED:     " + isolated + "\n"
+			}
+			if r.NetProfile != "" {
+				resInfo += "  -NET-PROFILE:  " + r.NetProfile + "\n"
+			}
+			if r.NeedsGroup {
+				resInfo += "  -NEEDS-GROUP:  made before a group was required, move it with 'igor res edit -g'\n"
+			}
 			resInfo += "  -START:        " + getLocTime(time.Unix(r.Start, 0)).Format(timeFmt) + "\n"
 			resInfo += "  -END:          " + getLocTime(time.Unix(r.End, 0)).Format(timeFmt) + "\n"
 			resInfo += "  -ORIG-END:     " + getLocTime(time.Unix(r.OrigEnd, 0)).Format(timeFmt) + "\n"
 			resInfo += "  -EXTEND-COUNT: " + strconv.Itoa(r.ExtendCount) + "\n"
+			if r.ReqNodeCount > 0 {
+				resInfo += "  -REQUESTED:    " + strconv.Itoa(r.ReqNodeCount) + " nodes for " +
+					common.FormatDuration(time.Duration(r.ReqDuration)*time.Minute, false) + "\n"
+			}
+			resInfo += "  -STATE:        " + r.State + "\n"
 			resInfo += "  -INSTALLED:    " + strconv.FormatBool(r.Installed) + "\n"
+			if r.EndPower != "" {
+				resInfo += "  -END-POWER:    " + r.EndPower + " (nodes stay powered on when it ends)\n"
+			}
+			if r.PendingApproval {
+				resInfo += "  -PENDING:      awaiting admin approval until " + getLocTime(time.Unix(r.ApprovalExpires, 0)).Format(timeFmt) + "\n"
+			}
+			if r.CycleAt > 0 {
+				resInfo += "  -CYCLE-AT:     " + getLocTime(time.Unix(r.CycleAt, 0)).Format(timeFmt) + "\n"
+			}
+			if r.Paused {
+				resInfo += "  -PAUSED-UNTIL: " + getLocTime(time.Unix(r.Start, 0)).Format(timeFmt) + "\n"
+			}
+			if len(r.ResumeError) > 0 {
+				resInfo += "  -RESUME-ERR:   " + r.ResumeError + "\n"
+			}
+			if len(r.StartError) > 0 {
+				resInfo += "  -START-ERR:    " + r.StartError + "\n"
+			}
 			if len(r.InstallError) > 0 {
 				resInfo += "  -INSTALL-ERR:  " + r.InstallError + "\n"
 			}
+			if len(r.InstallErrorHosts) > 0 {
+				resInfo += "  -FAILED-HOSTS: " + common.UnsplitList(r.InstallErrorHosts) + "\n"
+			}
+			for _, sl := range r.Shares {
+				resInfo += "  -SHARE-LINK:   " + sl.ID + " expires " + getLocTime(time.Unix(sl.Expires, 0)).Format(timeFmt) + "\n"
+			}
 			fmt.Print(resInfo + "\n\n")
 		}
 
 	} else {
 
 		tw := table.NewWriter()
-		tw.AppendHeader(table.Row{"NAME", "DESCRIPTION", "OWNER", "GROUP", "PROFILE", "DISTRO", "HOSTS", "DOWN/NA", "VLAN", "START", "END", "EXTEND-COUNT", "INSTALLED", "INSTALL-ERR"})
+		tw.AppendHeader(table.Row{"NAME", "DESCRIPTION", "OWNERS", "GROUP", "PROFILE", "DISTRO", "HOSTS", "DOWN/NA", "VLAN", "START", "END", "EXTEND-COUNT", "INSTALLED", "INSTALL-ERR"})
 		tw.AppendSeparator()
 
 		// for the table version, only put zone on first column
-		startTimeFmt := "Jan 2 3:04 PM MST"
-
 		for _, r := range resList {
 
 			if r.End > oneYearLater {
-				startTimeFmt = "Jan 2 2006 3:04 PM MST"
-				timeFmt = "Jan 2 2006 3:04 PM"
+				startTimeFmt, timeFmt = resTimeLayouts(dateFmt, true, simplePrint)
 			}
 
 			downNA := ""
@@ -695,21 +2060,56 @@ func printReservations(rb *common.ResponseBodyReservations) {
 			}
 			downNA = strings.TrimSuffix(downNA, "/")
 
+			installErr := r.InstallError
+			if len(r.InstallErrorHosts) > 0 {
+				installErr = cAlert.Sprint(common.UnsplitList(r.InstallErrorHosts)) + "\n" + installErr
+			}
+
+			// a paused reservation's start is when it resumes
+			var installed interface{} = r.Installed
+			switch r.State {
+			case common.ResStatePaused:
+				installed = cWarning.Sprint("PAUSED")
+				if r.ResumeError != "" {
+					installErr = cAlert.Sprint("resume failed") + "\n" + r.ResumeError
+				}
+			case common.ResStateStartFailed:
+				installErr = cAlert.Sprint("start failed") + "\n" + r.StartError
+			case common.ResStatePendingApproval:
+				// a reservation awaiting approval holds its nodes but won't be installed until approved
+				installed = cWarning.Sprint("PENDING APPROVAL")
+			case common.ResStateExpired:
+				installed = cWarning.Sprint("ENDED")
+			}
+
+			endTimeStr := getLocTime(time.Unix(r.End, 0)).Format(timeFmt)
+			if !deadline.IsZero() {
+				if hl := endTimeHighlight(getLocTime(time.Unix(r.End, 0)), deadline); hl != nil {
+					endTimeStr = hl.Sprint(endTimeStr)
+				}
+			}
+
+			// the network profile is only sent to admins
+			var vlan interface{} = r.Vlan
+			if r.NetProfile != "" {
+				vlan = strconv.Itoa(r.Vlan) + "\n" + r.NetProfile
+			}
+
 			tw.AppendRow([]interface{}{
 				r.Name,
 				r.Description,
-				r.Owner,
+				resOwners(r),
 				r.Group,
 				r.Profile,
 				r.Distro,
 				r.HostRange,
 				downNA,
-				r.Vlan,
+				vlan,
 				getLocTime(time.Unix(r.Start, 0)).Format(startTimeFmt),
-				getLocTime(time.Unix(r.End, 0)).Format(timeFmt),
+				endTimeStr,
 				r.ExtendCount,
-				r.Installed,
-				r.InstallError,
+				installed,
+				installErr,
 			})
 		}
 
@@ -718,10 +2118,141 @@ func printReservations(rb *common.ResponseBodyReservations) {
 				Name:     "DESCRIPTION",
 				WidthMax: 40,
 			},
+			{
+				Name:     "INSTALL-ERR",
+				WidthMax: 40,
+			},
 		})
 
 		tw.SetStyle(igorTableStyle)
 		fmt.Printf("\n" + tw.Render() + "\n\n")
+
+		// share links are only sent to the owner, so only list them when there are some
+		var shareLines []string
+		for _, r := range resList {
+			for _, sl := range r.Shares {
+				shareLines = append(shareLines, fmt.Sprintf("  %-20s %s  expires %s", r.Name, sl.ID, getLocTime(time.Unix(sl.Expires, 0)).Format(timeFmt)))
+			}
+		}
+		if len(shareLines) > 0 {
+			fmt.Printf("%s\n%s\n\n", sBold("SHARE LINKS"), strings.Join(shareLines, "\n"))
+		}
+
+		// power cycles set with 'igor res edit --cycle-at' that haven't happened yet
+		var cycleLines []string
+		for _, r := range resList {
+			if r.CycleAt > 0 {
+				cycleLines = append(cycleLines, fmt.Sprintf("  %-20s %s", r.Name, getLocTime(time.Unix(r.CycleAt, 0)).Format(timeFmt)))
+			}
+		}
+		if len(cycleLines) > 0 {
+			fmt.Printf("%s\n%s\n\n", sBold("SCHEDULED POWER CYCLES"), strings.Join(cycleLines, "\n"))
+		}
+	}
+
+}
+
+// printPendingReservations lists the reservations waiting for admin approval.
+func printPendingReservations(rb *common.ResponseBodyReservations) {
+
+	checkAndSetColorLevel(rb)
+
+	var pending []common.ReservationData
+	for _, r := range rb.Data["reservations"] {
+		if r.PendingApproval {
+			pending = append(pending, r)
+		}
+	}
+	if len(pending) == 0 {
+		printSimple("no reservations are waiting for approval", cRespWarn)
+		return
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].ApprovalExpires < pending[j].ApprovalExpires
+	})
+
+	_, timeFmt := resTimeLayouts(cliDateFormat(), true, simplePrint)
+
+	if simplePrint {
+		for _, r := range pending {
+			resInfo := "RESERVATION: " + r.Name + "\n"
+			resInfo += "  -OWNER:      " + r.Owner + "\n"
+			resInfo += "  -GROUP:      " + r.Group + "\n"
+			resInfo += "  -HOSTS:      " + r.HostRange + "\n"
+			resInfo += "  -START:      " + getLocTime(time.Unix(r.Start, 0)).Format(timeFmt) + "\n"
+			resInfo += "  -END:        " + getLocTime(time.Unix(r.End, 0)).Format(timeFmt) + "\n"
+			resInfo += "  -REASON:     " + r.ApprovalReason + "\n"
+			resInfo += "  -HELD-UNTIL: " + getLocTime(time.Unix(r.ApprovalExpires, 0)).Format(timeFmt) + "\n"
+			fmt.Print(resInfo + "\n\n")
+		}
+		return
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"NAME", "OWNER", "GROUP", "NODES", "START", "END", "REASON", "HELD-UNTIL"})
+	tw.AppendSeparator()
+	for _, r := range pending {
+		tw.AppendRow([]interface{}{
+			r.Name,
+			r.Owner,
+			r.Group,
+			r.HostRange,
+			getLocTime(time.Unix(r.Start, 0)).Format(timeFmt),
+			getLocTime(time.Unix(r.End, 0)).Format(timeFmt),
+			r.ApprovalReason,
+			getLocTime(time.Unix(r.ApprovalExpires, 0)).Format(timeFmt),
+		})
+	}
+	tw.SetStyle(igorTableStyle)
+	fmt.Printf("\n" + tw.Render() + "\n\n")
+}
+
+// resOwners lists the owner of a reservation followed by any co-owners in sorted order.
+func resOwners(r common.ReservationData) string {
+	if len(r.CoOwners) == 0 {
+		return r.Owner
+	}
+	return r.Owner + "," + sortedJoin(r.CoOwners, ",")
+}
+
+// isResOwner returns true if the named user is the owner or a co-owner of the reservation.
+func isResOwner(r common.ReservationData, user string) bool {
+	if r.Owner == user {
+		return true
+	}
+	for _, c := range r.CoOwners {
+		if c == user {
+			return true
+		}
 	}
+	return false
+}
 
+// resIsolatedPortsInfo describes which interfaces of the reservation's hosts are put in its VLAN,
+// grouping the hosts that have the same ones, ex. 'data,mgmt (kn[1-2]); data (kn3)'.
+func resIsolatedPortsInfo(r common.ReservationData) string {
+	if len(r.IsolatedPorts) == 0 {
+		return ""
+	}
+	var roleSets []string
+	hostsByRoles := map[string][]string{}
+	for _, h := range r.Hosts {
+		roles := strings.Join(r.IsolatedPorts[h], ",")
+		if roles == "" {
+			roles = "none"
+		}
+		if _, ok := hostsByRoles[roles]; !ok {
+			roleSets = append(roleSets, roles)
+		}
+		hostsByRoles[roles] = append(hostsByRoles[roles], h)
+	}
+	if len(roleSets) == 1 {
+		return roleSets[0]
+	}
+	parts := make([]string, len(roleSets))
+	for i, roles := range roleSets {
+		parts[i] = roles + " (" + common.UnsplitList(hostsByRoles[roles]) + ")"
+	}
+	return strings.Join(parts, "; ")
 }
@@ -0,0 +1,71 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorcli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestNeedDeleteConfirm(t *testing.T) {
+	owner := &common.ResDeleteData{OwnerOrAdmin: true}
+	member := &common.ResDeleteData{OwnerOrAdmin: false}
+
+	assert.False(t, needDeleteConfirm(owner, false))
+	assert.True(t, needDeleteConfirm(owner, true), "owner opted in")
+	assert.True(t, needDeleteConfirm(member, false))
+	assert.True(t, needDeleteConfirm(member, true))
+}
+
+func TestResDeleteSummary(t *testing.T) {
+
+	end := time.Date(2023, 4, 15, 17, 30, 0, 0, time.UTC)
+	summary := &common.ResDeleteData{
+		Name:            "res1",
+		Owner:           "alice",
+		Group:           "jedis",
+		HostCount:       3,
+		HostRange:       "kn[1-3]",
+		End:             end.Unix(),
+		Active:          true,
+		MaintenanceMins: 15,
+	}
+
+	text := resDeleteSummary(summary, dateFormatISO, time.UTC)
+	assert.Contains(t, text, "owner:       alice")
+	assert.Contains(t, text, "group:       jedis")
+	assert.Contains(t, text, "nodes:       3 (kn[1-3])")
+	assert.Contains(t, text, "ends:        2023-04-15 17:30")
+	assert.Contains(t, text, "maintenance for 15 minutes")
+
+	summary.MaintenanceMins = 0
+	assert.Contains(t, resDeleteSummary(summary, dateFormatISO, time.UTC), "returned to the available pool")
+
+	summary.Active = false
+	summary.Group = ""
+	text = resDeleteSummary(summary, dateFormatISO, time.UTC)
+	assert.Contains(t, text, "has not started")
+	assert.NotContains(t, text, "group:")
+}
+
+func TestResIsolatedPortsInfo(t *testing.T) {
+
+	r := common.ReservationData{Hosts: []string{"kn1", "kn2", "kn3"}}
+	assert.Empty(t, resIsolatedPortsInfo(r))
+
+	r.IsolatedPorts = map[string][]string{"kn1": {"data", "mgmt"}, "kn2": {"data", "mgmt"}, "kn3": {"data", "mgmt"}}
+	assert.Equal(t, "data,mgmt", resIsolatedPortsInfo(r))
+
+	r.IsolatedPorts["kn3"] = []string{"data"}
+	assert.Equal(t, "data,mgmt (kn[1-2]); data (kn3)", resIsolatedPortsInfo(r))
+
+	// a host without a port in the VLAN still gets listed
+	delete(r.IsolatedPorts, "kn1")
+	assert.Equal(t, "none (kn1); data,mgmt (kn2); data (kn3)", resIsolatedPortsInfo(r))
+}
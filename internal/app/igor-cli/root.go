@@ -55,7 +55,33 @@ word in the command.
 
 Igor defaults using decorative formatting and color in its output. If you wish
 to turn off color, set the NO_COLOR environment variable in your shell or use
--x/--simple flag where available to use ASCII-only, no-color output.
+-x/--simple flag where available to use ASCII-only, no-color output. Color is
+also turned off when output is not going to a terminal, such as a pipe or file.
+
+The --no-header flag can be used with any command to leave out the server time,
+MOTD and table titles, so the output of a command can be compared between runs.
+The order of columns in -x/--simple output is kept the same between releases
+and lists of names within a column are sorted.
+
+` + sBold("Connection Settings:") + `
+
+The --proxy and --ca-bundle flags can be used with any command and take
+precedence over the proxy and caBundle settings in the client config file.
+If neither is set, the standard HTTPS_PROXY and NO_PROXY environment variables
+are honored. The --insecure-skip-verify flag turns off verification of the
+igor-server certificate and should only be used as a last resort.
+
+` + sBold("Date Formats:") + `
+
+Reservation dates are printed month first (us) by default. The --date-format
+flag can be used with any command to print them day first (eu) or in ISO 8601
+form (iso). Set dateFormat in the client config file to change the default.
+
+` + sBold("Versions:") + `
+
+Igor warns when the CLI and igor-server versions differ and refuses to run a
+CLI older than the server supports. Use 'igor version' to compare versions.
+The --skip-version-check flag can be used with any command to run anyway.
 `,
 		Run: func(cmd *cobra.Command, args []string) {
 			flagSet := cmd.Flags()
@@ -69,6 +95,14 @@ to turn off color, set the NO_COLOR environment variable in your shell or use
 	var v bool
 	rootCmd.Flags().BoolVarP(&v, "version", "v", false, "version info")
 
+	rootCmd.PersistentFlags().StringVar(&connFlags.proxy, "proxy", "", "proxy URL used to reach igor-server ('none' for a direct connection)")
+	rootCmd.PersistentFlags().StringVar(&connFlags.caBundle, "ca-bundle", "", "path to a PEM CA bundle used to verify igor-server")
+	rootCmd.PersistentFlags().BoolVar(&connFlags.insecureSkipVerify, "insecure-skip-verify", false, "do not verify the igor-server certificate (unsafe)")
+	rootCmd.PersistentFlags().StringVar(&dateFormatFlag, "date-format", "", "style of printed dates: iso, us or eu")
+	rootCmd.PersistentFlags().BoolVar(&noHeader, "no-header", false, "leave out the server time, MOTD and table titles")
+	rootCmd.PersistentFlags().BoolVar(&skipVersionCheck, "skip-version-check", false, "run even if igor-server no longer supports this CLI version")
+	_ = registerFlagArgsFunc(rootCmd, "date-format", []string{dateFormatISO, dateFormatUS, dateFormatEU})
+
 	rootCmd.AddCommand(newElevateCmd())
 	rootCmd.AddCommand(newServerConfigCmd())
 	rootCmd.AddCommand(newShowCmd())
@@ -79,6 +113,9 @@ to turn off color, set the NO_COLOR environment variable in your shell or use
 	rootCmd.AddCommand(newGroupCmd())
 	rootCmd.AddCommand(newResetSecretCmd())
 	rootCmd.AddCommand(newSyncCmd())
+	rootCmd.AddCommand(newVlanCmd())
+	rootCmd.AddCommand(newNetworkCmd())
+	rootCmd.AddCommand(newAdminCmd())
 	rootCmd.AddCommand(newStatsCmd())
 	rootCmd.AddCommand(newClustersCmd())
 	rootCmd.AddCommand(newHostCmd())
@@ -89,6 +126,7 @@ to turn off color, set the NO_COLOR environment variable in your shell or use
 	rootCmd.AddCommand(newDistroCmd())
 	rootCmd.AddCommand(newProfileCmd())
 	rootCmd.AddCommand(newResCmd())
+	rootCmd.AddCommand(newVersionCmd())
 	rootCmd.AddCommand(newCompletionCmd(rootCmd.Name()))
 
 	return rootCmd
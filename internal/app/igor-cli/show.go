@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"igor2/internal/pkg/api"
 	"net/http"
+	"net/url"
 	"os"
 	"sort"
 	"strconv"
@@ -30,17 +31,26 @@ const (
 	Down       = "DOWN"
 	PowerNA    = "POWER-N/A"
 	Blocked    = "BLOCKED"
+	Draining   = "DRAINING"
 	Reserved   = "RESERVED"
 	Unreserved = "UNRESERVED"
 	Restricted = "RESTRICTED"
 	InstallErr = "INST ERROR"
+	Unavail    = "UNAVAIL SOON"
+)
+
+// values of the show --group-by flag
+const (
+	showGroupByOwner = "owner"
+	showGroupByGroup = "group"
 )
 
 func newShowCmd() *cobra.Command {
 
 	cmdShow := &cobra.Command{
 		Use: "show [-acefgrtx] [--sort-start --sort-name --sort-owner]\n" +
-			"            [-n USER1,... -o OWNER1,...] [--no-color --no-map]",
+			"            [-n USER1,... -o OWNER1,...] [--ends-before DATETIME|DURATION]\n" +
+			"            [--no-color --no-map]",
 		Short: "Display current cluster/reservation status",
 		Long: `
 Displays cluster node statuses and reservation list. 
@@ -57,8 +67,9 @@ Node Reservation Status (background color):
 
   ` + cUnreservedUp.Sprint(Unreserved) + `  : node currently free to reserve
   ` + cBlockedUp.Sprint(Blocked) + `     : node not accepting reservations
+  ` + cDrainingUp.Sprint(Draining) + `    : node finishing current reservation, then blocked
   ` + cRestrictedUp.Sprint(Restricted) + `  : node has group/time access restriction
-  ` + cInstError.Sprint("INSTALL ERR") + ` : reservation failed to install
+  ` + cInstError.Sprint("INSTALL ERR") + ` : node failed to install its reservation
 
   ` + cOwnerRes.Sprint("RESERVED") + `    : node reserved by you or accessible via member group
   ` + cOtherRes.Sprint("RESERVED") + `    : node reserved by another user
@@ -75,7 +86,10 @@ A summary view of power and availability of each host in the cluster.
 ` + sBold("NODE STATUS TABLE:") + `
 
 This table summarizes the status information on the node map to assist color-
-blind users.
+blind users. Nodes that will soon be unavailable because of their host policy or
+the maintenance after a reservation are listed as UNAVAIL SOON along with when
+that starts and ends, even if they are also listed in another row. How far ahead
+igor looks is set by the cluster admin team.
 
 ` + sBold("RESERVATION TABLE:") + `
 
@@ -84,7 +98,8 @@ user has no reservations on the system, they will not see the second table
 unless they include the --all flag to see reservations made by others.
 
 Reservations that are scheduled to finish within the next 24 hours have their
-end times highlighted.
+end times highlighted. When --ends-before is used, the end times of all listed
+reservations are highlighted.
 
 The INFO column uses a shorthand format for information about the reservation
 and is especially useful in combination with the -x flag.
@@ -92,8 +107,12 @@ and is especially useful in combination with the -x flag.
   O: you are the owner
   G: you have group access
   F: future reservation (node column shows nodes to be assigned at startup)
+  P: paused reservation (start column shows when it resumes)
+  A: awaiting admin approval (its nodes are held but not installed)
+  N: res has started but is not installed yet
   I: res is installed
-  E: res has installation error
+  E: res has an installation error or could not start
+  X: res has ended and is waiting to be cleaned up
 
 ` + sBold("ADDITIONAL INFORMATION:") + `
 
@@ -115,11 +134,19 @@ Filtering :
   Use the -c -f and -g flags to exclude reservations based on time or group.
   Use the -n flag for partial match filtering on reservation name list.
   Use the -o flag for full match filtering on owner name list.
+  Use the --ends-before flag to only list reservations that end on or before
+  the given time. The value can be a datetime in the format ` + exStartDts() + `
+  or a duration from now in days(d), hours(h) and minutes(m), such as 3d.
+  Combine with -o to see which of your reservations need to be extended.
 
 Sorting :
   Default order is by reservation end time. 
   Use the --sort-* flags to specify a different sort column.
   Use the -r flag to reverse the sort order to descending values.
+  Use --group-by owner or --group-by group to list reservations in sections,
+  largest first, each ending in a subtotal of its reservations, nodes and the
+  nearest end time, followed by a grand total. Reservations in each section
+  keep the sort order above.
 
 Formatting :
   Use the -t flag to change the end time date column to time remaining format.
@@ -157,7 +184,20 @@ Formatting :
 				return fmt.Errorf("show group-only not compatible with show all reservations")
 			}
 
-			printShow(doShow(), flagset)
+			if groupBy, _ := flagset.GetString("group-by"); groupBy != "" && groupBy != showGroupByOwner && groupBy != showGroupByGroup {
+				return fmt.Errorf("--group-by must be %s or %s", showGroupByOwner, showGroupByGroup)
+			}
+
+			var deadline time.Time
+			if flagset.Changed("ends-before") {
+				endsBefore, _ := flagset.GetString("ends-before")
+				var err error
+				if deadline, err = parseEndsBefore(endsBefore, igorCliNow); err != nil {
+					return err
+				}
+			}
+
+			printShow(doShow(deadline), flagset)
 			return nil
 		},
 		DisableFlagsInUseLine: true,
@@ -177,6 +217,8 @@ Formatting :
 		sortReverse bool
 	var filterResList,
 		filterOwnerList []string
+	var endsBefore,
+		groupBy string
 
 	cmdShow.Flags().BoolVarP(&showAll, "all", "a", false, "show all reservations (includes other users)")
 	cmdShow.Flags().BoolVarP(&showCurrentOnly, "current", "c", false, "show current reservations only")
@@ -193,15 +235,23 @@ Formatting :
 	cmdShow.Flags().BoolVarP(&simplePrint, "simple", "x", false, "use simple text output (no color/map/lines)")
 	cmdShow.Flags().StringSliceVarP(&filterResList, "filter-name", "n", nil, "partial matching by name")
 	cmdShow.Flags().StringSliceVarP(&filterOwnerList, "filter-owner", "o", nil, "matching by owner")
+	cmdShow.Flags().StringVar(&endsBefore, "ends-before", "", "only reservations ending by this datetime or duration from now")
+	cmdShow.Flags().StringVar(&groupBy, "group-by", "", "list reservations in sections by "+showGroupByOwner+" or "+showGroupByGroup+" with subtotals")
 
 	_ = registerFlagArgsFunc(cmdShow, "filter-name", []string{"NAME1"})
 	_ = registerFlagArgsFunc(cmdShow, "filter-owner", []string{"OWNER1"})
+	_ = registerFlagArgsFunc(cmdShow, "ends-before", []string{"DATETIME|DURATION"})
+	_ = registerFlagArgsFunc(cmdShow, "group-by", []string{showGroupByOwner, showGroupByGroup})
 
 	return cmdShow
 }
 
-func doShow() *common.ResponseBodyShow {
-	body := doSend(http.MethodGet, api.BaseUrl, nil)
+func doShow(deadline time.Time) *common.ResponseBodyShow {
+	apiPath := api.BaseUrl
+	if !deadline.IsZero() {
+		apiPath += "?to-end=" + endsBeforeParam(deadline)
+	}
+	body := doSend(http.MethodGet, apiPath, nil)
 	rb := common.ResponseBodyShow{}
 	err := json.Unmarshal(*body, &rb)
 	checkUnmarshalErr(err)
@@ -226,6 +276,12 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 	sortOwnerName := flagset.Changed("sort-owner")
 	sortReverse := flagset.Changed("reverse")
 	remainTime := flagset.Changed("time-left")
+	groupBy, _ := flagset.GetString("group-by")
+	var deadline time.Time
+	if endsBefore, _ := flagset.GetString("ends-before"); endsBefore != "" {
+		// already checked before the request was sent
+		deadline, _ = parseEndsBefore(endsBefore, igorCliNow)
+	}
 
 	checkAndSetColorLevel(rb)
 
@@ -321,7 +377,7 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 			if showCurrentOnly {
 				if resStart.Before(igorCliNow) {
 					if !showGroupOnly {
-						if r.Owner == lastAccessUser || isGroupRes(r) {
+						if isResOwner(r, lastAccessUser) || isGroupRes(r) {
 							inclRes = true
 						}
 					} else if isGroupRes(r) {
@@ -331,7 +387,7 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 			} else if showFutureOnly {
 				if !resStart.Before(igorCliNow) {
 					if !showGroupOnly {
-						if r.Owner == lastAccessUser || isGroupRes(r) {
+						if isResOwner(r, lastAccessUser) || isGroupRes(r) {
 							inclRes = true
 						}
 					} else if isGroupRes(r) {
@@ -341,7 +397,7 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 			} else if showInstallErrOnly {
 				if r.InstallError != "" {
 					if !showGroupOnly {
-						if r.Owner == lastAccessUser || isGroupRes(r) {
+						if isResOwner(r, lastAccessUser) || isGroupRes(r) {
 							inclRes = true
 						}
 					} else if isGroupRes(r) {
@@ -349,7 +405,7 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 					}
 				}
 			} else {
-				if r.Owner == lastAccessUser || isGroupRes(r) {
+				if isResOwner(r, lastAccessUser) || isGroupRes(r) {
 					inclRes = true
 				}
 			}
@@ -357,7 +413,10 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 
 		if inclRes {
 			inclResList = append(inclResList, r)
-			if r.InstallError != "" {
+			if len(r.InstallErrorHosts) > 0 {
+				installErrorNodes = append(installErrorNodes, r.InstallErrorHosts...)
+			} else if r.InstallError != "" {
+				// no per-host detail was provided so mark the whole reservation
 				installErrorNodes = append(installErrorNodes, r.Hosts...)
 			}
 		}
@@ -409,19 +468,16 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 
 	restrictMap := make(map[int]bool)
 	nameFmt := "%" + strconv.Itoa(maxResNameLength) + "v"
-	monthFmt := "Jan "
-	dayYearFmt := "2 " + yearFmt
-	timeFmt := "3:04 PM"
-	if simplePrint {
-		monthFmt = "Jan-"
-		dayYearFmt = "02-06."
-		timeFmt = "15:04"
-	}
+	monthFmt, dayYearFmt, timeFmt := showTimeLayouts(cliDateFormat(), yearFmt, simplePrint)
 
 	// Gather lists of which nodes are blocked, restricted and unreserved
 	var unreservedNodes []string
 	var blockedNodes []string
+	var drainingNodes []string
 	var restrictedNodes []string
+	// nodes that become unavailable soon are grouped by window so each row can say when
+	var unavailWindows []common.UnavailWindow
+	unavailNodes := map[common.UnavailWindow][]string{}
 
 	for i := 0; i < len(showData.Hosts); i++ {
 		h := &showData.Hosts[i]
@@ -429,8 +485,16 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 			restrictedNodes = append(restrictedNodes, h.Name)
 			restrictMap[h.SequenceID] = true
 		}
+		if w := h.NextUnavail; w != nil {
+			if _, ok := unavailNodes[*w]; !ok {
+				unavailWindows = append(unavailWindows, *w)
+			}
+			unavailNodes[*w] = append(unavailNodes[*w], h.Name)
+		}
 		if h.State == strings.ToLower(Blocked) {
 			blockedNodes = append(blockedNodes, h.Name)
+		} else if h.State == strings.ToLower(Draining) {
+			drainingNodes = append(drainingNodes, h.Name)
 		} else if h.State == strings.ToLower(Reserved) {
 			continue
 		} else if !resNodes[i+1] {
@@ -460,7 +524,9 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 	nst.AppendHeader(table.Row{"STATUS", "#", "NODES"})
 
 	statusFormat := "%" + strconv.Itoa(len(Unreserved)) + "v"
-	if len(installErrorNodes) > 0 {
+	if len(unavailWindows) > 0 {
+		statusFormat = "%" + strconv.Itoa(len(Unavail)) + "v"
+	} else if len(installErrorNodes) > 0 {
 		statusFormat = "%" + strconv.Itoa(len(InstallErr)) + "v"
 	}
 
@@ -473,7 +539,7 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 		}
 	}
 
-	makeNodeRow := func(nodes []string, style *color.Style256, rowType string) {
+	makeNodeRow := func(nodes []string, style *color.Style256, rowType string, notes ...string) {
 
 		r := common.Range{
 			Prefix: showData.Cluster.Prefix,
@@ -483,6 +549,9 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 		nodeRange, _ := r.UnsplitRange(nodes)
 
 		nodeLine := multilineRange(MaxNodeColWidth, nodeRange, showData.Cluster.Prefix)
+		for _, note := range notes {
+			nodeLine += "\n" + note
+		}
 
 		nst.AppendRow([]interface{}{
 			rowHeaderName(style, rowType),
@@ -495,6 +564,10 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 	makeNodeRow(unreservedNodes, cUnreservedUp, Unreserved)
 	makeNodeRow(blockedNodes, cBlockedUp, Blocked)
 
+	if len(drainingNodes) > 0 {
+		makeNodeRow(drainingNodes, cDrainingUp, Draining)
+	}
+
 	if len(restrictedNodes) > 0 {
 		makeNodeRow(restrictedNodes, cRestrictedUp, Restricted)
 	}
@@ -503,6 +576,14 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 		makeNodeRow(installErrorNodes, cInstError, InstallErr)
 	}
 
+	sort.Slice(unavailWindows, func(i, j int) bool { return unavailWindows[i].Start < unavailWindows[j].Start })
+	for _, w := range unavailWindows {
+		windowFmt := monthFmt + dayYearFmt + timeFmt
+		note := fmt.Sprintf("%s %s to %s", w.Reason, getLocTime(time.Unix(w.Start, 0)).Format(windowFmt),
+			getLocTime(time.Unix(w.End, 0)).Format(windowFmt))
+		makeNodeRow(unavailNodes[w], cRestrictedUp, Unavail, note)
+	}
+
 	if simplePrint {
 		nst.Style().Options.SeparateRows = false
 		nst.Style().Options.SeparateColumns = false
@@ -517,15 +598,19 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 	nst.Style().Options.DrawBorder = false
 	fmt.Println(nst.Render())
 
-	fmt.Println("\nServer Time : " + adjServerTime)
-	if strings.TrimSpace(showData.Cluster.Motd) != "" {
-		printMotd(showData.Cluster)
-	} else {
+	if noHeader {
 		fmt.Println("")
+	} else {
+		fmt.Println("\nServer Time : " + adjServerTime)
+		if strings.TrimSpace(showData.Cluster.Motd) != "" {
+			printMotd(showData.Cluster)
+		} else {
+			fmt.Println("")
+		}
 	}
 
 	if len(inclResList) == 0 {
-		if len(filterOwnerList) > 0 || len(filterResList) > 0 {
+		if len(filterOwnerList) > 0 || len(filterResList) > 0 || !deadline.IsZero() {
 			fmt.Println(sBold("\nNo reservations returned by this query.\n"))
 		} else {
 			var noRes = "You have no owned or group-affiliated reservations."
@@ -541,78 +626,61 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 		return
 	}
 
-	// Print reservation list
-	tw := table.NewWriter()
-	tw.AppendHeader(table.Row{"NAME", "OWNER", "START", "END", "INFO", "#", "NODES"})
+	endTimeString := func(resEnd time.Time) string {
+		if remainTime {
+			return common.FormatDuration(resEnd.Sub(igorCliNow).Round(time.Minute), true)
+		}
+		if simplePrint {
+			return resEnd.Format(monthFmt + dayYearFmt + timeFmt)
+		}
+		monthStr := resEnd.Format(monthFmt)
+		dayYearStr := resEnd.Format(dayYearFmt)
+		if strings.Index(dayYearStr, " ") == 1 {
+			dayYearStr = " " + dayYearStr
+		}
+		timeStr := resEnd.Format(timeFmt)
+		if strings.Index(timeStr, ":") == 1 {
+			timeStr = " " + timeStr
+		}
+		return monthStr + dayYearStr + timeStr
+	}
 
-	for _, r := range inclResList {
+	resRow := func(r common.ReservationData) table.Row {
 
 		resStart := getLocTime(time.Unix(r.Start, 0))
 		resEnd := getLocTime(time.Unix(r.End, 0))
 
 		var flags string
 
-		if r.Owner == lastAccessUser {
+		if isResOwner(r, lastAccessUser) {
 			flags += "O"
 		} else if isGroupRes(r) {
 			flags += "G"
 		}
 
-		if resStart.After(igorCliNow) {
-			flags += "F"
-		} else {
-			if r.InstallError != "" {
-				flags += "E"
-			} else {
-				flags += "I"
-			}
-		}
+		flags += resStateFlag(r.State)
 
 		var name string
 		if simplePrint {
 			name = r.Name
 		} else {
-			if r.InstallError != "" {
+			switch r.State {
+			case common.ResStateInstallError, common.ResStateStartFailed:
 				name = cInstError.Sprintf(nameFmt, r.Name)
-			} else if r.Owner == lastAccessUser || isGroupRes(r) {
-				if resStart.Before(igorCliNow) {
+			case common.ResStateActive, common.ResStateExpiringSoon, common.ResStateInstalling:
+				if isResOwner(r, lastAccessUser) || isGroupRes(r) {
 					name = cOwnerRes.Sprintf(nameFmt, r.Name)
 				} else {
-					name = cFuture.Sprintf(nameFmt, r.Name)
-				}
-			} else {
-				if resStart.Before(igorCliNow) {
 					name = cOtherRes.Sprintf(nameFmt, r.Name)
-				} else {
-					name = cFuture.Sprintf(nameFmt, r.Name)
 				}
+			default:
+				name = cFuture.Sprintf(nameFmt, r.Name)
 			}
 		}
 
-		var endTimeStr string
-		if !simplePrint {
-			monthStr := resEnd.Format(monthFmt)
-			dayYearStr := resEnd.Format(dayYearFmt)
-			if strings.Index(dayYearStr, " ") == 1 {
-				dayYearStr = " " + dayYearStr
-			}
-			timeStr := resEnd.Format(timeFmt)
-			if strings.Index(timeStr, ":") == 1 {
-				timeStr = " " + timeStr
-			}
-			endTimeStr = monthStr + dayYearStr + timeStr
-		} else {
-			endTimeStr = resEnd.Format(monthFmt + dayYearFmt + timeFmt)
-		}
-
-		durRemaining := resEnd.Sub(igorCliNow)
-		if remainTime {
-			endTimeStr = common.FormatDuration(durRemaining.Round(time.Minute), true)
-		}
-		if durRemaining < 12*time.Hour {
-			endTimeStr = cAlert.Sprintf(endTimeStr)
-		} else if durRemaining < 24*time.Hour && durRemaining >= 12*time.Hour {
-			endTimeStr = cWarning.Sprintf(endTimeStr)
+		endTimeStr := endTimeString(resEnd)
+		if hl := endTimeHighlight(resEnd, deadline); hl != nil {
+			endTimeStr = hl.Sprint(endTimeStr)
 		}
 
 		var startTimeStr string
@@ -633,7 +701,11 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 
 		var hostStatus = ""
 
-		if resStart.After(igorCliNow) {
+		if r.PendingApproval {
+			hostStatus = cWarning.Sprint("PENDING") + " " + cFutureNodes.Sprint(r.HostRange)
+		} else if r.Paused {
+			hostStatus = cWarning.Sprint("PAUSED") + " " + cFutureNodes.Sprint(r.HostRange)
+		} else if resStart.After(igorCliNow) {
 			hostStatus = cFutureNodes.Sprint(r.HostRange)
 		} else {
 			if len(r.HostsDown) == 0 && len(r.HostsPowerNA) == 0 {
@@ -673,43 +745,140 @@ func printShow(rb *common.ResponseBodyShow, flagset *pflag.FlagSet) {
 			if len(blockedHosts) > 0 {
 				hostStatus += blockedLabel + common.UnsplitList(blockedHosts)
 			}
+
+			var drainingLabel = fmt.Sprintf(" / %s ", cDrainingUp.Sprintf(Draining))
+			var drainingHosts []string
+			for _, ah := range showData.Hosts {
+				if ah.State == "draining" {
+					for _, rh := range r.Hosts {
+						if ah.HostName == rh {
+							drainingHosts = append(drainingHosts, ah.HostName)
+						}
+					}
+				}
+			}
+
+			if len(drainingHosts) > 0 {
+				hostStatus += drainingLabel + common.UnsplitList(drainingHosts)
+			}
 		}
 
-		tw.AppendRow([]interface{}{
+		return table.Row{
 			name,
-			r.Owner,
+			resOwners(r),
 			startTimeStr,
 			endTimeStr,
 			flags,
 			strconv.Itoa(len(r.Hosts)),
 			hostStatus,
-		})
+		}
 	}
 
-	if simplePrint {
-		tw.Style().Options.SeparateRows = false
-		tw.Style().Options.SeparateColumns = false
-		tw.SetColumnConfigs([]table.ColumnConfig{
-			{Name: "END", AlignHeader: text.AlignLeft, Align: text.AlignRight},
-		})
-	} else {
-		tw.SetStyle(table.StyleLight)
-		tw.SetTitle("RESERVATIONS")
-		tw.Style().Title.Align = text.AlignCenter
-		tw.Style().Title.Format = text.FormatUpper
-		tw.Style().Title.Colors = text.Colors{text.Bold, text.Faint}
-		tw.SetColumnConfigs([]table.ColumnConfig{
-			{Name: "NAME", AlignHeader: text.AlignRight, Align: text.AlignRight},
-			{Name: "START", AlignHeader: text.AlignLeft, Align: text.AlignRight},
-			{Name: "END", AlignHeader: text.AlignLeft, Align: text.AlignRight},
-		})
+	newResTable := func() table.Writer {
+		tw := table.NewWriter()
+		tw.AppendHeader(table.Row{"NAME", "OWNERS", "START", "END", "INFO", "#", "NODES"})
+		return tw
 	}
 
-	tw.Style().Options.DrawBorder = false
+	styleResTable := func(tw table.Writer) {
+		if simplePrint {
+			tw.Style().Options.SeparateRows = false
+			tw.Style().Options.SeparateColumns = false
+			tw.SetColumnConfigs([]table.ColumnConfig{
+				{Name: "END", AlignHeader: text.AlignLeft, Align: text.AlignRight},
+			})
+		} else {
+			tw.SetStyle(table.StyleLight)
+			if !noHeader {
+				tw.SetTitle("RESERVATIONS")
+			}
+			tw.Style().Title.Align = text.AlignCenter
+			tw.Style().Title.Format = text.FormatUpper
+			tw.Style().Title.Colors = text.Colors{text.Bold, text.Faint}
+			tw.SetColumnConfigs([]table.ColumnConfig{
+				{Name: "NAME", AlignHeader: text.AlignRight, Align: text.AlignRight},
+				{Name: "START", AlignHeader: text.AlignLeft, Align: text.AlignRight},
+				{Name: "END", AlignHeader: text.AlignLeft, Align: text.AlignRight},
+			})
+		}
+
+		tw.Style().Options.DrawBorder = false
+	}
+
+	if groupBy == "" {
+		tw := newResTable()
+		for _, r := range inclResList {
+			tw.AppendRow(resRow(r))
+		}
+		styleResTable(tw)
+		fmt.Println(tw.Render())
+		return
+	}
+
+	// sections per owner or group, largest first, each keeping the sort order of the list
+	key := common.RollupOwner
+	if groupBy == showGroupByGroup {
+		key = common.RollupGroup
+	}
+	rollups, total := common.RollupReservations(inclResList, key)
+
+	if simplePrint {
+		for _, ru := range rollups {
+			fmt.Printf("\n%s: %s\n", strings.ToUpper(groupBy), rollupName(ru.Name))
+			tw := newResTable()
+			for _, r := range inclResList {
+				if key(r) == ru.Name {
+					tw.AppendRow(resRow(r))
+				}
+			}
+			styleResTable(tw)
+			fmt.Println(tw.Render())
+			fmt.Println(rollupSummary("SUBTOTAL", ru, endTimeString))
+		}
+		fmt.Println("\n" + rollupSummary("TOTAL", total, endTimeString))
+		return
+	}
 
+	tw := newResTable()
+	rollupRow := func(label, name string, ru common.ResRollup) table.Row {
+		return table.Row{
+			sBold(label),
+			name,
+			fmt.Sprintf("%d reservation(s)", ru.Reservations),
+			endTimeString(getLocTime(time.Unix(ru.NearestEnd, 0))),
+			"",
+			sBold(strconv.Itoa(ru.Nodes)),
+			"",
+		}
+	}
+	for _, ru := range rollups {
+		for _, r := range inclResList {
+			if key(r) == ru.Name {
+				tw.AppendRow(resRow(r))
+			}
+		}
+		tw.AppendRow(rollupRow("SUBTOTAL", rollupName(ru.Name), ru))
+		tw.AppendSeparator()
+	}
+	tw.AppendRow(rollupRow("TOTAL", "", total))
+	styleResTable(tw)
 	fmt.Println(tw.Render())
 }
 
+// rollupName is the section name shown for an owner or group rollup.
+func rollupName(name string) string {
+	if name == "" {
+		return "(no group)"
+	}
+	return name
+}
+
+// rollupSummary is the line that ends a section of the simple grouped reservation list.
+func rollupSummary(label string, ru common.ResRollup, endTimeString func(time.Time) string) string {
+	return fmt.Sprintf("%s: %d reservation(s), %d node(s), nearest end %s", label, ru.Reservations, ru.Nodes,
+		strings.TrimSpace(endTimeString(getLocTime(time.Unix(ru.NearestEnd, 0)))))
+}
+
 func printNodeMap(cData common.ClusterData, hData []common.HostData, rData []common.ReservationData, userGroups []string, restricted map[int]bool, instErr map[int]bool) {
 	// figure out how many digits we need per node displayed
 	lastNode := hData[len(hData)-1].SequenceID
@@ -744,7 +913,9 @@ func printNodeMap(cData common.ClusterData, hData []common.HostData, rData []com
 	}
 
 	tw := table.NewWriter()
-	tw.SetTitle(cData.Name)
+	if !noHeader {
+		tw.SetTitle(cData.Name)
+	}
 
 	n := 0
 	for i := 0; i < cData.DisplayHeight; i++ {
@@ -775,6 +946,9 @@ func printNodeMap(cData common.ClusterData, hData []common.HostData, rData []com
 				} else if hDataMap[seqID].State == "blocked" {
 					// set node background for blocked
 					row = append(row, colorNode.SetBg(BgBlocked).AddOpts(color.Bold).Sprint(name))
+				} else if hDataMap[seqID].State == "draining" {
+					// set node background for draining
+					row = append(row, colorNode.SetBg(BgDraining).AddOpts(color.Bold).Sprint(name))
 				} else if resIndex, ok := n2r[seqID]; ok {
 
 					// set node background based on user reservation access
@@ -785,7 +959,7 @@ func printNodeMap(cData common.ClusterData, hData []common.HostData, rData []com
 							isGroupRes = true
 						}
 					}
-					if res.Owner == lastAccessUser || isGroupRes {
+					if isResOwner(res, lastAccessUser) || isGroupRes {
 						colorNode.SetBg(BgResYes)
 						row = append(row, colorNode.Sprint(name))
 					} else {
@@ -823,7 +997,7 @@ func printNodeMap(cData common.ClusterData, hData []common.HostData, rData []com
 func printMotd(clusterData common.ClusterData) {
 
 	finalMotd := "\nMOTD: "
-	if (simplePrint || noColor || envNoColor || color.TermColorLevel() == color.LevelNo) && clusterData.MotdUrgent {
+	if colorDisabled() && clusterData.MotdUrgent {
 		finalMotd += " IMPORTANT! - "
 	}
 
@@ -836,6 +1010,26 @@ func printMotd(clusterData common.ClusterData) {
 	}
 }
 
+// resStateFlag returns the INFO column letter for a reservation state sent by the server.
+func resStateFlag(state string) string {
+	switch state {
+	case common.ResStatePendingApproval:
+		return "A"
+	case common.ResStatePaused:
+		return "P"
+	case common.ResStateFuture:
+		return "F"
+	case common.ResStateInstalling:
+		return "N"
+	case common.ResStateInstallError, common.ResStateStartFailed:
+		return "E"
+	case common.ResStateExpired:
+		return "X"
+	default:
+		return "I"
+	}
+}
+
 type byStartTime []common.ReservationData
 
 func (resList byStartTime) Len() int      { return len(resList) }
@@ -867,3 +1061,46 @@ func (resList byResName) Swap(i, j int) { resList[i], resList[j] = resList[j], r
 func (resList byResName) Less(i, j int) bool {
 	return resList[i].Name < resList[j].Name
 }
+
+// parseEndsBefore turns an --ends-before value into a deadline. The value is either a datetime in the
+// format used to create reservations or a duration from now such as 3d or 4h30m. A deadline that isn't
+// in the future is rejected since no active reservation could match it.
+func parseEndsBefore(val string, now time.Time) (time.Time, error) {
+
+	deadline, err := time.ParseInLocation(common.DateTimeCompactFormat, val, cli.tzLoc)
+	if err != nil {
+		d, pErr := common.ParseDuration(val)
+		if pErr != nil {
+			return time.Time{}, fmt.Errorf("--ends-before value '%s' is not a datetime like %s or a duration like 3d", val, exStartDts())
+		}
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("--ends-before duration '%s' must be longer than zero", val)
+		}
+		return now.Add(d), nil
+	}
+	if !deadline.After(now) {
+		return time.Time{}, fmt.Errorf("--ends-before time '%s' is in the past - give a later time or a duration from now like 3d", val)
+	}
+	return deadline, nil
+}
+
+// endsBeforeParam formats a deadline as the to-end search parameter, which the server reads as UTC.
+func endsBeforeParam(deadline time.Time) string {
+	return url.QueryEscape(deadline.UTC().Format(common.DateTimeCompactFormat))
+}
+
+// endTimeHighlight returns the color for a reservation end time that needs attention: alert if it ends
+// within 12 hours and warning within 24. Given a deadline from --ends-before, every reservation ending by
+// then is highlighted however far off its end is. Returns nil if the end time needs no highlight.
+func endTimeHighlight(end, deadline time.Time) *color.Style256 {
+	remaining := end.Sub(igorCliNow)
+	switch {
+	case remaining < 12*time.Hour:
+		return cAlert
+	case remaining < 24*time.Hour:
+		return cWarning
+	case !deadline.IsZero() && !end.After(deadline):
+		return cWarning
+	}
+	return nil
+}
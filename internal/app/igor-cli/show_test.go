@@ -14,6 +14,7 @@ import (
 
 	"github.com/gookit/color"
 	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
 
 	"igor2/internal/pkg/common"
 )
@@ -134,6 +135,7 @@ func getSomeData() *common.ResponseBodyShow {
 		Installed:    true,
 		InstallError: "",
 		RemainHours:  0,
+		State:        common.ResStateExpiringSoon,
 	})
 	r = append(r, common.ReservationData{
 		Name:         "yellow-boots",
@@ -153,6 +155,7 @@ func getSomeData() *common.ResponseBodyShow {
 		Installed:    true,
 		InstallError: "",
 		RemainHours:  0,
+		State:        common.ResStateExpiringSoon,
 	})
 
 	r = append(r, common.ReservationData{
@@ -173,6 +176,7 @@ func getSomeData() *common.ResponseBodyShow {
 		Installed:    false,
 		InstallError: "",
 		RemainHours:  0,
+		State:        common.ResStateFuture,
 	})
 
 	startTime := time.Now().Add(time.Hour * time.Duration(rand.Intn(24*7+1)+24))
@@ -196,6 +200,7 @@ func getSomeData() *common.ResponseBodyShow {
 		Installed:    false,
 		InstallError: "",
 		RemainHours:  0,
+		State:        common.ResStateFuture,
 	})
 
 	r = append(r, common.ReservationData{
@@ -216,6 +221,7 @@ func getSomeData() *common.ResponseBodyShow {
 		Installed:    true,
 		InstallError: "",
 		RemainHours:  0,
+		State:        common.ResStateExpiringSoon,
 	})
 
 	r = append(r, common.ReservationData{
@@ -236,6 +242,7 @@ func getSomeData() *common.ResponseBodyShow {
 		Installed:    true,
 		InstallError: "",
 		RemainHours:  0,
+		State:        common.ResStateExpiringSoon,
 	})
 
 	r = append(r, common.ReservationData{
@@ -256,6 +263,7 @@ func getSomeData() *common.ResponseBodyShow {
 		Installed:    false,
 		InstallError: "error!",
 		RemainHours:  0,
+		State:        common.ResStateInstallError,
 	})
 
 	r = append(r, common.ReservationData{
@@ -278,6 +286,7 @@ func getSomeData() *common.ResponseBodyShow {
 		Installed:    true,
 		InstallError: "",
 		RemainHours:  0,
+		State:        common.ResStateExpiringSoon,
 	})
 
 	showData := common.ShowData{
@@ -373,3 +382,103 @@ func TestShowAllNoMapRemainTime(t *testing.T) {
 	printShow(rb, flagset)
 
 }
+
+func TestShowAllGroupByOwner(t *testing.T) {
+
+	resetGlobalTestVars()
+	fmt.Printf("\nTest: 'igor show --all --group-by owner'\n\n")
+
+	rb := getSomeData()
+	flagset := &pflag.FlagSet{}
+	flagset.Bool("all", true, "")
+	flagset.String("group-by", "", "")
+	args := []string{"--all", "--group-by", "owner"}
+	if err := flagset.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	printShow(rb, flagset)
+}
+
+func TestShowAllGroupByGroupSimple(t *testing.T) {
+
+	resetGlobalTestVars()
+	fmt.Printf("\nTest: 'igor show --all --simple --group-by group'\n\n")
+
+	rb := getSomeData()
+	flagset := &pflag.FlagSet{}
+	flagset.Bool("simple", true, "")
+	flagset.Bool("all", true, "")
+	flagset.String("group-by", "", "")
+	args := []string{"--all", "--simple", "--group-by", "group"}
+	if err := flagset.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	printShow(rb, flagset)
+}
+
+func TestRollupSummary(t *testing.T) {
+
+	resetGlobalTestVars()
+	end := time.Date(2023, 3, 1, 12, 30, 0, 0, cli.tzLoc)
+	ru := common.ResRollup{Name: "", Reservations: 2, Nodes: 5, NearestEnd: end.Unix()}
+	endFmt := func(t time.Time) string { return t.Format("Jan 2 15:04") }
+
+	assert.Equal(t, "(no group)", rollupName(ru.Name))
+	assert.Equal(t, "bob", rollupName("bob"))
+	assert.Equal(t, "SUBTOTAL: 2 reservation(s), 5 node(s), nearest end Mar 1 12:30", rollupSummary("SUBTOTAL", ru, endFmt))
+}
+
+func TestParseEndsBefore(t *testing.T) {
+
+	resetGlobalTestVars()
+	now := igorCliNow
+
+	deadline, err := parseEndsBefore("3d", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(72*time.Hour), deadline)
+
+	future := now.Add(48 * time.Hour).Truncate(time.Minute)
+	deadline, err = parseEndsBefore(future.Format(common.DateTimeCompactFormat), now)
+	assert.NoError(t, err)
+	assert.True(t, future.Equal(deadline))
+
+	_, err = parseEndsBefore(now.Add(-time.Hour).Format(common.DateTimeCompactFormat), now)
+	assert.ErrorContains(t, err, "in the past")
+	_, err = parseEndsBefore("0", now)
+	assert.Error(t, err)
+	_, err = parseEndsBefore("friday", now)
+	assert.Error(t, err)
+}
+
+func TestEndTimeHighlight(t *testing.T) {
+
+	resetGlobalTestVars()
+	now := igorCliNow
+	deadline := now.Add(5 * 24 * time.Hour)
+
+	assert.Equal(t, cAlert, endTimeHighlight(now.Add(time.Hour), time.Time{}))
+	assert.Equal(t, cWarning, endTimeHighlight(now.Add(20*time.Hour), time.Time{}))
+	assert.Nil(t, endTimeHighlight(now.Add(3*24*time.Hour), time.Time{}))
+
+	// a deadline highlights anything ending by then
+	assert.Equal(t, cAlert, endTimeHighlight(now.Add(time.Hour), deadline))
+	assert.Equal(t, cWarning, endTimeHighlight(now.Add(3*24*time.Hour), deadline))
+	assert.Nil(t, endTimeHighlight(now.Add(6*24*time.Hour), deadline))
+}
+
+func TestResStateFlag(t *testing.T) {
+	flags := map[string]string{
+		common.ResStateFuture:          "F",
+		common.ResStatePendingApproval: "A",
+		common.ResStatePaused:          "P",
+		common.ResStateStartFailed:     "E",
+		common.ResStateInstalling:      "N",
+		common.ResStateInstallError:    "E",
+		common.ResStateActive:          "I",
+		common.ResStateExpiringSoon:    "I",
+		common.ResStateExpired:         "X",
+	}
+	for state, flag := range flags {
+		assert.Equal(t, flag, resStateFlag(state), state)
+	}
+}
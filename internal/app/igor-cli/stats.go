@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"sort"
 	"strings"
+	"time"
 
 	"igor2/internal/pkg/api"
 
@@ -123,7 +124,11 @@ func printStats(rb *common.ResponseBodyStats) {
 			for _, r := range stats.Entries {
 				fmt.Printf("Name: %v\tRes ID: %v\n", r.Name, r.Hash)
 				fmt.Printf("Nodes: %v\n", r.Hosts)
-				fmt.Printf("start: %v\toriginal end: %v\tactual end: %v\t # extensions: %v\n\n", r.Start, r.OrigEnd, r.End, r.ExtendCount)
+				fmt.Printf("start: %v\toriginal end: %v\tactual end: %v\t # extensions: %v\n", r.Start, r.OrigEnd, r.End, r.ExtendCount)
+				if r.EndPower != "" {
+					fmt.Printf("end power: %v\n", r.EndPower)
+				}
+				fmt.Println()
 			}
 			fmt.Printf("%v Summary:\n", user)
 			fmt.Printf("Reservation Count: %v\n", stats.ResCount)
@@ -141,4 +146,52 @@ func printStats(rb *common.ResponseBodyStats) {
 	fmt.Printf("Extensions used: %v\n", data.Global.NumExtensions)
 	fmt.Printf("Total Reservation Time: %v\n", data.Global.TotalResTime)
 
+	if len(data.ByMonth) > 0 {
+		months := make([]string, 0, len(data.ByMonth))
+		for m := range data.ByMonth {
+			months = append(months, m)
+		}
+		sort.Strings(months)
+		fmt.Printf("\nRequested vs. Granted by Month:\n")
+		for _, m := range months {
+			d := data.ByMonth[m]
+			fmt.Printf("%s: %d reservations (%d clamped)\trequested node-hours: %.1f\tgranted node-hours: %.1f\n",
+				m, d.ResCount, d.ClampedCount, d.RequestedNodeHours, d.GrantedNodeHours)
+		}
+	}
+
+	if len(data.ImageStore.ByDistro) > 0 {
+		distros := make([]string, 0, len(data.ImageStore.ByDistro))
+		for d := range data.ImageStore.ByDistro {
+			distros = append(distros, d)
+		}
+		sort.Strings(distros)
+		fmt.Printf("\nImage Store Usage:\n")
+		for _, d := range distros {
+			fmt.Printf("%s: %d bytes\n", d, data.ImageStore.ByDistro[d])
+		}
+		fmt.Printf("Total (shared images counted once): %d bytes\n", data.ImageStore.TotalBytes)
+	}
+
+	if len(data.Tasks) > 0 {
+		fmt.Printf("\nServer Tasks:\n")
+		for _, t := range data.Tasks {
+			if !t.Enabled {
+				fmt.Printf("%s: disabled\n", t.Name)
+				continue
+			}
+			fmt.Printf("%s: every %s (jitter %s)\truns: %d\tfailures: %d", t.Name, t.Interval, t.Jitter, t.Runs, t.Failures)
+			if t.LastRun > 0 {
+				fmt.Printf("\tlast: %s %s in %dms", time.Unix(t.LastRun, 0).In(cli.tzLoc).Format(common.DateTimeCompactFormat),
+					t.LastResult, t.LastDurationMs)
+			}
+			if t.NextRun > 0 {
+				fmt.Printf("\tnext: %s", time.Unix(t.NextRun, 0).In(cli.tzLoc).Format(common.DateTimeCompactFormat))
+			}
+			fmt.Println()
+			if t.LastError != "" {
+				fmt.Printf("  last error: %s\n", t.LastError)
+			}
+		}
+	}
 }
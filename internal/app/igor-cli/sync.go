@@ -8,7 +8,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
 	"igor2/internal/pkg/api"
 
@@ -22,13 +24,13 @@ import (
 func newSyncCmd() *cobra.Command {
 
 	cmdSync := &cobra.Command{
-		Use:   "sync {arista} [-f] [-q]",
-		Short: "Report/repair status of vlan service " + adminOnly,
+		Use:   "sync {arista|ldap-groups|ldap-users} [-f] [-q]",
+		Short: "Report/repair status of vlan service or LDAP groups and users " + adminOnly,
 		Long: `
-Displays status and information about the vlan network service based on command
-given.
+Displays status and information about the vlan network service, LDAP-synced
+groups or LDAP-synced user accounts based on command given.
 
-` + requiredArgs + ` (there is only one supported vlan switch type at this time)
+` + requiredArgs + `
 
     arista :
        For each host currently associated with a reservation, sync will report
@@ -36,10 +38,28 @@ given.
        - the vlan value assigned to the host by the reservation
        - whether the reservation is powered
 
+    ldap-groups :
+       For each LDAP-synced group that is out of step with LDAP, sync will
+       report the membership changes and ownership changes the next LDAP
+       group sync would make, including owner accounts it would create and
+       groups that would fall back to igor-admin as owner. Ownership is only
+       synced if the server is configured with group owner attributes.
+
+    ldap-users :
+       Reports the accounts the next LDAP user sync would create and remove.
+       Users no longer in LDAP who own reservations shared with a group are
+       not removed right away but enter pending removal so a group member
+       can claim their reservations with 'igor res claim'. The report lists
+       users that would enter pending removal, users already pending along
+       with when they will be removed, and pending users that are back in
+       LDAP and would be kept.
+
 ` + optionalFlags + `
 
 Use the -f flag to force host vlan ids in the switch to the value indicated by
-the reservation if the values do not match.
+the reservation if the values do not match. For ldap-groups, -f applies the
+reported changes now instead of waiting for the next scheduled sync. The same
+goes for ldap-users.
 
 The the -q flag to only report back on hosts whose reservation vlan value does
 not match what's reported by the switch.
@@ -58,7 +78,7 @@ not match what's reported by the switch.
 			if len(args) != 0 {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
-			return []string{"arista"}, cobra.ShellCompDirectiveNoFileComp
+			return []string{"arista", "ldap-groups", "ldap-users"}, cobra.ShellCompDirectiveNoFileComp
 		},
 	}
 
@@ -102,6 +122,14 @@ func printSync(rb *common.ResponseBodySync) {
 
 	syncData := rb.Data["sync"].(map[string]interface{})
 	command := syncData["command"].(string)
+	if command == "ldap-groups" {
+		printLdapGroupSync(syncData)
+		return
+	}
+	if command == "ldap-users" {
+		printLdapUserSync(syncData)
+		return
+	}
 	force := syncData["force"].(string) == "true"
 	quiet := syncData["quiet"].(string) == "true"
 	report := syncData["report"].(map[string]interface{})
@@ -153,3 +181,137 @@ func printSync(rb *common.ResponseBodySync) {
 		}
 	}
 }
+
+func printLdapGroupSync(syncData map[string]interface{}) {
+
+	force := syncData["force"].(string) == "true"
+	ownerSync := syncData["ownerSync"].(string) == "true"
+
+	report := map[string]common.LdapGroupSyncData{}
+	raw, _ := json.Marshal(syncData["report"])
+	checkUnmarshalErr(json.Unmarshal(raw, &report))
+
+	cRespSuccess.Printf("sync performed on: %s\n", syncData["command"].(string))
+	if force {
+		fmt.Printf("NOTE - the changes below have been applied\n")
+	} else {
+		fmt.Printf("NOTE - dry run, nothing was changed; use -f to apply the changes below\n")
+	}
+	if !ownerSync {
+		fmt.Printf("NOTE - group owner sync is not enabled on the server, only membership is synced\n")
+	}
+	fmt.Println()
+
+	if len(report) == 0 {
+		printSimple("all LDAP groups are in sync", cRespSuccess)
+		return
+	}
+
+	names := make([]string, 0, len(report))
+	for name := range report {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	members := table.NewWriter()
+	members.AppendHeader(table.Row{"GROUP", "ADD MEMBERS", "REMOVE MEMBERS"})
+	owners := table.NewWriter()
+	owners.AppendHeader(table.Row{"GROUP", "ADD OWNERS", "REMOVE OWNERS", "NEW ACCOUNTS", "NOTE"})
+	var failed []string
+
+	for _, name := range names {
+		g := report[name]
+		if g.Error != "" {
+			failed = append(failed, name+": "+g.Error)
+		}
+		if len(g.AddMembers) > 0 || len(g.RmvMembers) > 0 {
+			members.AppendRow(table.Row{name, strings.Join(g.AddMembers, ","), strings.Join(g.RmvMembers, ",")})
+		}
+		if len(g.AddOwners) > 0 || len(g.RmvOwners) > 0 || len(g.NewAccounts) > 0 || g.OwnerFallback {
+			note := ""
+			if g.OwnerFallback {
+				note = color.FgYellow.Sprint("no LDAP owner can own group, igor-admin is owner")
+			}
+			owners.AppendRow(table.Row{name, strings.Join(g.AddOwners, ","), strings.Join(g.RmvOwners, ","), strings.Join(g.NewAccounts, ","), note})
+		}
+	}
+
+	for _, t := range []table.Writer{members, owners} {
+		t.SetStyle(table.StyleLight)
+		t.Style().Options.DrawBorder = false
+	}
+
+	fmt.Println("MEMBERSHIP CHANGES")
+	if members.Length() == 0 {
+		fmt.Printf("  (none)\n\n")
+	} else {
+		fmt.Printf("%s\n\n", members.Render())
+	}
+	if ownerSync {
+		fmt.Println("OWNERSHIP CHANGES")
+		if owners.Length() == 0 {
+			fmt.Printf("  (none)\n\n")
+		} else {
+			fmt.Printf("%s\n\n", owners.Render())
+		}
+	}
+	for _, f := range failed {
+		printSimple(f, cRespWarn)
+	}
+}
+
+func printLdapUserSync(syncData map[string]interface{}) {
+
+	force := syncData["force"].(string) == "true"
+
+	report := common.LdapUserSyncData{}
+	raw, _ := json.Marshal(syncData["report"])
+	checkUnmarshalErr(json.Unmarshal(raw, &report))
+
+	cRespSuccess.Printf("sync performed on: %s\n", syncData["command"].(string))
+	if force {
+		fmt.Printf("NOTE - the changes below have been applied\n")
+	} else {
+		fmt.Printf("NOTE - dry run, nothing was changed; use -f to apply the changes below\n")
+	}
+	if report.GraceDays > 0 {
+		fmt.Printf("NOTE - users who own reservations shared with a group are pending removal for %d day(s)\n", report.GraceDays)
+	} else {
+		fmt.Printf("NOTE - pending removal is turned off, users no longer in LDAP are removed right away\n")
+	}
+	fmt.Println()
+
+	if len(report.NewAccounts) == 0 && len(report.Remove) == 0 && len(report.PendingRemoval) == 0 &&
+		len(report.StillPending) == 0 && len(report.Restored) == 0 {
+		printSimple("all user accounts are in sync", cRespSuccess)
+		return
+	}
+
+	pending := make([]string, 0, len(report.StillPending))
+	for name := range report.StillPending {
+		pending = append(pending, name)
+	}
+	sort.Strings(pending)
+
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"USER", "CHANGE"})
+	for _, name := range report.NewAccounts {
+		tw.AppendRow(table.Row{name, "create account"})
+	}
+	for _, name := range report.Remove {
+		tw.AppendRow(table.Row{name, color.FgYellow.Sprint("remove account")})
+	}
+	for _, name := range report.PendingRemoval {
+		tw.AppendRow(table.Row{name, color.FgYellow.Sprintf("enter pending removal for %d day(s)", report.GraceDays)})
+	}
+	for _, name := range pending {
+		until := time.Unix(report.StillPending[name], 0).Format(common.DateTimeCompactFormat)
+		tw.AppendRow(table.Row{name, "pending removal until " + until})
+	}
+	for _, name := range report.Restored {
+		tw.AppendRow(table.Row{name, "back in LDAP, no longer pending removal"})
+	}
+	tw.SetStyle(table.StyleLight)
+	tw.Style().Options.DrawBorder = false
+	fmt.Println(tw.Render())
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorcli
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	srcFlag   = "flag"
+	srcConfig = "config"
+	srcEnv    = "env"
+)
+
+// connFlags holds the per-invocation connection overrides set on the root command.
+var connFlags struct {
+	proxy              string
+	caBundle           string
+	insecureSkipVerify bool
+}
+
+// resolveProxy determines which proxy (if any) the client should use to reach serverHost. An explicit
+// proxy given as a flag wins over one in the config file, and either of those wins over the standard
+// HTTPS_PROXY/NO_PROXY environment variables. The value "none" given as a flag or config setting
+// disables proxy use entirely. Returns the proxy URL (nil for a direct connection) and where the
+// setting came from.
+func resolveProxy(flagProxy, confProxy, serverHost string, getenv func(string) string) (*url.URL, string, error) {
+
+	explicit, source := strings.TrimSpace(flagProxy), srcFlag
+	if explicit == "" {
+		explicit, source = strings.TrimSpace(confProxy), srcConfig
+	}
+
+	if explicit != "" {
+		if strings.ToLower(explicit) == "none" {
+			return nil, source, nil
+		}
+		u, err := parseProxyURL(explicit)
+		return u, source, err
+	}
+
+	envProxy := getenv("HTTPS_PROXY")
+	if envProxy == "" {
+		envProxy = getenv("https_proxy")
+	}
+	if envProxy == "" {
+		return nil, "", nil
+	}
+
+	noProxy := getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = getenv("no_proxy")
+	}
+	if noProxyMatch(serverHost, noProxy) {
+		return nil, srcEnv, nil
+	}
+
+	u, err := parseProxyURL(envProxy)
+	return u, srcEnv, err
+}
+
+// parseProxyURL parses a proxy value, assuming http:// when no scheme is given as curl and
+// the Go standard library both do.
+func parseProxyURL(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL '%s'", proxy)
+	}
+	return u, nil
+}
+
+// noProxyMatch reports whether host is covered by a NO_PROXY style list. Entries are
+// comma-delimited and may be exact hostnames, domain suffixes (with or without a leading
+// dot), IP addresses, CIDR ranges, or '*' to match everything.
+func noProxyMatch(host, noProxy string) bool {
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	hostIP := net.ParseIP(host)
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		if hostIP != nil {
+			if _, cidr, err := net.ParseCIDR(entry); err == nil && cidr.Contains(hostIP) {
+				return true
+			}
+			if ip := net.ParseIP(entry); ip != nil && ip.Equal(hostIP) {
+				return true
+			}
+			continue
+		}
+		entry = strings.TrimPrefix(entry, ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveCaBundle returns the path of the CA bundle the client should trust in addition to the
+// system roots. A flag value wins over the config file setting.
+func resolveCaBundle(flagBundle, confBundle string) (string, string) {
+	if strings.TrimSpace(flagBundle) != "" {
+		return flagBundle, srcFlag
+	}
+	if strings.TrimSpace(confBundle) != "" {
+		return confBundle, srcConfig
+	}
+	return "", ""
+}
+
+// loadRootCAs builds the cert pool used to verify igor-server. It starts with the system roots
+// and adds the optional mTLS CA cert and custom CA bundle.
+func loadRootCAs(caCert, caBundle string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	for _, f := range []string{caCert, caBundle} {
+		if f == "" {
+			continue
+		}
+		pem, rErr := os.ReadFile(f)
+		if rErr != nil {
+			return nil, fmt.Errorf("error reading CA cert file %s: %v", f, rErr)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA file %s", f)
+		}
+	}
+	return pool, nil
+}
+
+// connSettingsInfo describes the proxy and CA settings in use so connection failures can point the
+// user at a likely misconfiguration.
+func connSettingsInfo() string {
+	serverURL, _ := url.Parse(cli.IgorServerAddr)
+	proxyInfo := "direct (no proxy)"
+	if proxy, source, err := resolveProxy(connFlags.proxy, cli.Client.Proxy, serverURL.Host, os.Getenv); err != nil {
+		proxyInfo = err.Error()
+	} else if proxy != nil {
+		proxyInfo = fmt.Sprintf("%s (from %s)", proxy.Redacted(), source)
+	} else if source != "" {
+		proxyInfo += fmt.Sprintf(" (from %s)", source)
+	}
+
+	caInfo := "system roots"
+	if bundle, source := resolveCaBundle(connFlags.caBundle, cli.Client.CaBundle); bundle != "" {
+		caInfo += fmt.Sprintf(" + %s (from %s)", bundle, source)
+	}
+	if cli.Client.CaCert != "" {
+		caInfo += fmt.Sprintf(" + %s (caCert)", cli.Client.CaCert)
+	}
+	if skipVerify() {
+		caInfo = "certificate verification DISABLED"
+	}
+
+	return fmt.Sprintf("server = %s\n\tproxy  = %s\n\tCA     = %s", cli.IgorServerAddr, proxyInfo, caInfo)
+}
+
+// skipVerify reports whether TLS certificate verification of igor-server has been turned off.
+func skipVerify() bool {
+	return connFlags.insecureSkipVerify || cli.Client.InsecureSkipVerify
+}
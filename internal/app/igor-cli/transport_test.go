@@ -0,0 +1,106 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorcli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeEnv(vals map[string]string) func(string) string {
+	return func(key string) string {
+		return vals[key]
+	}
+}
+
+func TestResolveProxyPrecedence(t *testing.T) {
+
+	env := fakeEnv(map[string]string{"HTTPS_PROXY": "http://env-proxy:8080"})
+	server := "igor.example.com:8443"
+
+	// flag beats config and env
+	u, src, err := resolveProxy("http://flag-proxy:3128", "http://conf-proxy:3128", server, env)
+	assert.NoError(t, err)
+	assert.Equal(t, srcFlag, src)
+	assert.Equal(t, "flag-proxy:3128", u.Host)
+
+	// config beats env
+	u, src, err = resolveProxy("", "http://conf-proxy:3128", server, env)
+	assert.NoError(t, err)
+	assert.Equal(t, srcConfig, src)
+	assert.Equal(t, "conf-proxy:3128", u.Host)
+
+	// env used when nothing explicit is set
+	u, src, err = resolveProxy("", "", server, env)
+	assert.NoError(t, err)
+	assert.Equal(t, srcEnv, src)
+	assert.Equal(t, "env-proxy:8080", u.Host)
+
+	// lowercase env var is honored too
+	u, src, err = resolveProxy("", "", server, fakeEnv(map[string]string{"https_proxy": "lower-proxy:9000"}))
+	assert.NoError(t, err)
+	assert.Equal(t, srcEnv, src)
+	assert.Equal(t, "lower-proxy:9000", u.Host)
+
+	// nothing set at all means a direct connection
+	u, src, err = resolveProxy("", "", server, fakeEnv(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "", src)
+	assert.Nil(t, u)
+
+	// 'none' as a flag disables a configured proxy
+	u, src, err = resolveProxy("none", "http://conf-proxy:3128", server, env)
+	assert.NoError(t, err)
+	assert.Equal(t, srcFlag, src)
+	assert.Nil(t, u)
+
+	// bad explicit value is reported
+	_, _, err = resolveProxy("http://", "", server, env)
+	assert.Error(t, err)
+}
+
+func TestResolveProxyNoProxy(t *testing.T) {
+
+	server := "igor.example.com:8443"
+
+	env := fakeEnv(map[string]string{"HTTPS_PROXY": "http://env-proxy:8080", "NO_PROXY": "localhost,.example.com"})
+	u, src, err := resolveProxy("", "", server, env)
+	assert.NoError(t, err)
+	assert.Equal(t, srcEnv, src)
+	assert.Nil(t, u)
+
+	// NO_PROXY has no effect on an explicit setting
+	u, _, err = resolveProxy("", "http://conf-proxy:3128", server, env)
+	assert.NoError(t, err)
+	assert.NotNil(t, u)
+}
+
+func TestNoProxyMatch(t *testing.T) {
+	assert.True(t, noProxyMatch("igor.example.com:8443", "example.com"))
+	assert.True(t, noProxyMatch("igor.example.com", ".example.com"))
+	assert.True(t, noProxyMatch("igor.example.com", "igor.example.com"))
+	assert.True(t, noProxyMatch("anything", "*"))
+	assert.True(t, noProxyMatch("10.1.2.3:8443", "10.0.0.0/8"))
+	assert.True(t, noProxyMatch("10.1.2.3", "10.1.2.3"))
+	assert.False(t, noProxyMatch("igor.example.com", "other.com"))
+	assert.False(t, noProxyMatch("notexample.com", "example.com"))
+	assert.False(t, noProxyMatch("10.1.2.3", "192.168.0.0/16"))
+	assert.False(t, noProxyMatch("igor.example.com", ""))
+}
+
+func TestResolveCaBundle(t *testing.T) {
+	path, src := resolveCaBundle("/flag/ca.pem", "/conf/ca.pem")
+	assert.Equal(t, "/flag/ca.pem", path)
+	assert.Equal(t, srcFlag, src)
+
+	path, src = resolveCaBundle("", "/conf/ca.pem")
+	assert.Equal(t, "/conf/ca.pem", path)
+	assert.Equal(t, srcConfig, src)
+
+	path, src = resolveCaBundle("", "")
+	assert.Equal(t, "", path)
+	assert.Equal(t, "", src)
+}
@@ -5,10 +5,12 @@
 package igorcli
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"igor2/internal/pkg/api"
 	"net/http"
+	"net/url"
 	"os"
 	"os/user"
 	"sort"
@@ -147,7 +149,7 @@ Use the -x flag to render screen output without pretty formatting.
 func newUserEditCmd() *cobra.Command {
 
 	cmdEditUser := &cobra.Command{
-		Use:   "edit { -e EMAIL -f \"FULLNAME\" (-n NAME) | --password } ",
+		Use:   "edit { -e EMAIL -f \"FULLNAME\" --default-group GROUP --locale LOCALE --notify-delegate USER --notify-prefs PREFS (-n NAME) | --password } ",
 		Short: "Edit user information",
 		Long: `
 Allows editing user information.
@@ -157,6 +159,14 @@ Allows editing user information.
   -e : Changes the user's email address.
     >> AND/OR <<
   -f : Changes the full name (enclose in double-quotes if using spaces).
+    >> AND/OR <<
+  --default-group : Sets the group used for new reservations.
+    >> AND/OR <<
+  --locale : Sets how dates and times are written in email from igor.
+    >> AND/OR <<
+  --notify-delegate : Sets a user copied on email about your reservations.
+    >> AND/OR <<
+  --notify-prefs : Sets which group email you receive.
 
   >> OR <<
 
@@ -176,6 +186,50 @@ If a user cannot remember their password they should request an igor admin to
 reset it. See 'igor user reset -h' for more information. Admins may only reset
 another user's password, not change it.
 
+Use --default-group to choose one of your groups to be used for reservations
+you create without the -g flag. Any reservation defaults set on that group (see
+'igor group edit -h') then fill in values not given to 'igor res create'. The
+order of precedence when creating a reservation is:
+
+  1. the flags given to 'igor res create'
+  2. the defaults of the reservation's group (-g, or else your default group)
+  3. the defaults of the igor server
+
+Use '--default-group none' to clear your default group.
+
+Use --locale to choose how dates and durations appear in reservation email.
+The value 'iso' writes dates in ISO 8601 form (2024-02-03 14:30 UTC), which
+avoids any day/month confusion. Otherwise give a language, optionally with a
+region, such as en-US, en-GB, de, fr or es. Use '--locale none' to go back to
+the server default. To change how dates are shown by this client, see the
+dateFormat setting in the client config file or the --date-format flag.
+
+Use --notify-delegate to name another igor user who is copied on the email
+about reservations you own, such as someone watching them while you are away.
+The delegate gets notices like expiration warnings and extensions, but not
+email about your account or about a reservation being handed to you. The
+delegate must be an igor user; to notify someone outside igor, add their
+address to a group instead. Use '--notify-delegate none' to clear it.
+To copy users on a single reservation's email, see 'igor res edit -h'.
+
+Use --notify-prefs to cut down on email you get as a member of a group. Give a
+comma-separated list of any of these settings:
+
+  res-cc=all|final|none : Which email you are copied on about reservations of
+                          your groups: all of it (the default), only the final
+                          notice before one expires, or none.
+  group=yes|no          : Whether you get email when you are added to or
+                          removed from a group or a group you are in changes.
+
+Email about reservations you own or co-own and about being made a group owner
+is always sent. Use '--notify-prefs default' to go back to receiving all of it.
+
+Use --default-kargs to set kernel args added to every reservation you create,
+such as console settings you always want. They go after any kernel args of the
+distro or profile and before any given with 'igor res create -k', which win
+when both set the same arg. Use 'igor res create --no-default-kargs' to leave
+them off a reservation and '--default-kargs none' to clear them.
+
 ` + sBold("IMPORTANT:") + `
 
 By default this command will use the last known successful igor login to obtain
@@ -203,8 +257,13 @@ they include the -n flag.
 
 			email, _ := flagset.GetString("email")
 			fullName, _ := flagset.GetString("full-name")
+			defaultGroup, _ := flagset.GetString("default-group")
+			locale, _ := flagset.GetString("locale")
+			notifyDelegate, _ := flagset.GetString("notify-delegate")
+			notifyPrefs, _ := flagset.GetString("notify-prefs")
+			defaultKargs, _ := flagset.GetString("default-kargs")
 			changePass := flagset.Changed("password")
-			printRespSimple(doEditUser(name, email, fullName, changePass))
+			printRespSimple(doEditUser(name, email, fullName, defaultGroup, locale, notifyDelegate, notifyPrefs, defaultKargs, changePass))
 			return nil
 		},
 		DisableFlagsInUseLine: true,
@@ -213,15 +272,30 @@ they include the -n flag.
 
 	var email,
 		fullName,
+		defaultGroup,
+		locale,
+		notifyDelegate,
+		notifyPrefs,
+		defaultKargs,
 		name string
 	var changePass bool
 	cmdEditUser.Flags().StringVarP(&email, "email", "e", "", "update user email address")
 	cmdEditUser.Flags().StringVarP(&fullName, "full-name", "f", "", "update user full name")
+	cmdEditUser.Flags().StringVar(&defaultGroup, "default-group", "", "group to use for new reservations, or 'none'")
+	cmdEditUser.Flags().StringVar(&locale, "locale", "", "locale for dates in email (iso, en-US, en-GB, ...), or 'none'")
+	cmdEditUser.Flags().StringVar(&notifyDelegate, "notify-delegate", "", "user copied on email about your reservations, or 'none'")
+	cmdEditUser.Flags().StringVar(&notifyPrefs, "notify-prefs", "", "group email to receive (res-cc=all|final|none,group=yes|no), or 'default'")
+	cmdEditUser.Flags().StringVar(&defaultKargs, "default-kargs", "", "kernel args added to your new reservations, or 'none'")
 	cmdEditUser.Flags().StringVarP(&name, "name", "n", "", "target user name")
 	cmdEditUser.Flags().BoolVar(&changePass, "password", false, "initiate local password change")
 
 	_ = registerFlagArgsFunc(cmdEditUser, "email", []string{"EMAIL"})
 	_ = registerFlagArgsFunc(cmdEditUser, "full-name", []string{"FULLNAME"})
+	_ = registerFlagArgsFunc(cmdEditUser, "default-group", []string{"GROUP"})
+	_ = registerFlagArgsFunc(cmdEditUser, "locale", []string{"iso", "en-US", "en-GB", "de", "fr", "es", "none"})
+	_ = registerFlagArgsFunc(cmdEditUser, "notify-delegate", []string{"USER"})
+	_ = registerFlagArgsFunc(cmdEditUser, "notify-prefs", []string{"res-cc=all", "res-cc=final", "res-cc=none", "group=yes", "group=no", "default"})
+	_ = registerFlagArgsFunc(cmdEditUser, "default-kargs", []string{"\"KARGS\""})
 	_ = registerFlagArgsFunc(cmdEditUser, "name", []string{"NAME"})
 
 	return cmdEditUser
@@ -271,8 +345,23 @@ event.
 
 ` + notesOnUsage + `
 
-This cannot be performed if the user is an owner of a reservation, group or
-distro. These resources must be deleted first or transferred to a new owner.
+A user who is the sole owner of a reservation, group or distro cannot be deleted
+until those resources are deleted or moved to a new owner. The delete can move
+them as part of removing the user:
+
+` + optionalFlags + `
+
+Use the --transfer-to flag to make USER the new owner of everything the deleted
+user owns. USER must be able to take over each resource as with any owner
+change: they need access to the distro of each reservation and must belong to
+the groups each reservation and distro is shared with. Nothing is moved or
+deleted if any resource cannot be transferred.
+
+Use the --force flag to make igor-admin the new owner instead.
+
+The resources to be moved are listed and the user name must be typed to confirm
+the delete. Use the --yes flag to skip the confirmation, such as in scripts.
+Each move is recorded in the server log and in the history of each reservation.
 
 Deleting a user has no disparate impact other than denying access to igor. It
 does not affect any underlying OS user account.
@@ -281,12 +370,43 @@ does not affect any underlying OS user account.
 `,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			printRespSimple(doDeleteUser(args[0]))
+			flagset := cmd.Flags()
+			transferTo, _ := flagset.GetString("transfer-to")
+			force, _ := flagset.GetBool("force")
+			yes, _ := flagset.GetBool("yes")
+			if transferTo != "" && force {
+				checkClientErr(fmt.Errorf("--transfer-to and --force cannot be used together"))
+			}
+			if !yes {
+				rb := doDeleteUser(args[0], transferTo, force, true)
+				if !rb.IsSuccess() {
+					printRespSimple(rb)
+				}
+				if owned := rb.Data["owned"]; len(owned) > 0 {
+					if transferTo == "" && !force {
+						fmt.Println(ownedResourceLines(owned))
+						checkClientErr(fmt.Errorf("user '%s' owns the resources above -- use --transfer-to or --force to move them", args[0]))
+					}
+					confirmUserDelete(args[0], owned)
+				}
+			}
+			rb := doDeleteUser(args[0], transferTo, force, false)
+			if owned := rb.Data["owned"]; !rb.IsSuccess() && len(owned) > 0 {
+				fmt.Println(ownedResourceLines(owned))
+			}
+			printRespSimple(rb)
 		},
 		DisableFlagsInUseLine: true,
 		ValidArgsFunction:     validateNameArg,
 	}
 
+	var transferTo string
+	var force, yes bool
+	cmdDeleteUser.Flags().StringVar(&transferTo, "transfer-to", "", "make this user the owner of the deleted user's resources")
+	cmdDeleteUser.Flags().BoolVar(&force, "force", false, "make igor-admin the owner of the deleted user's resources")
+	cmdDeleteUser.Flags().BoolVarP(&yes, "yes", "y", false, "delete without asking for confirmation")
+	_ = registerFlagArgsFunc(cmdDeleteUser, "transfer-to", []string{"USER"})
+
 	return cmdDeleteUser
 }
 
@@ -300,7 +420,7 @@ func doCreateUser(name string, email string, fullName string) *common.ResponseBo
 	return unmarshalBasicResponse(body)
 }
 
-func doEditUser(name string, email string, fullName string, changePswd bool) *common.ResponseBodyBasic {
+func doEditUser(name string, email string, fullName string, defaultGroup string, locale string, notifyDelegate string, notifyPrefs string, defaultKargs string, changePswd bool) *common.ResponseBodyBasic {
 
 	apiPath := api.Users + "/" + name
 	changes := make(map[string]interface{})
@@ -331,6 +451,26 @@ func doEditUser(name string, email string, fullName string, changePswd bool) *co
 		changes["fullName"] = fullName
 	}
 
+	if defaultGroup != "" {
+		changes["defaultGroup"] = defaultGroup
+	}
+
+	if locale != "" {
+		changes["locale"] = locale
+	}
+
+	if notifyDelegate != "" {
+		changes["notifyDelegate"] = notifyDelegate
+	}
+
+	if notifyPrefs != "" {
+		changes["notifyPrefs"] = notifyPrefs
+	}
+
+	if defaultKargs != "" {
+		changes["defaultKernelArgs"] = defaultKargs
+	}
+
 	body := doSend(http.MethodPatch, apiPath, changes)
 	uBody := unmarshalBasicResponse(body)
 	if changePswd && uBody.IsSuccess() {
@@ -374,10 +514,50 @@ func doShowUsers(names []string) *common.ResponseBodyUsers {
 	return &rb
 }
 
-func doDeleteUser(name string) *common.ResponseBodyBasic {
+func doDeleteUser(name, transferTo string, force, dryRun bool) *common.ResponseBodyUserDelete {
+	params := url.Values{}
+	if transferTo != "" {
+		params.Set("transferTo", transferTo)
+	}
+	if force {
+		params.Set("force", "true")
+	}
+	if dryRun {
+		params.Set("dryRun", "true")
+	}
 	apiPath := api.Users + "/" + name
+	if len(params) > 0 {
+		apiPath += "?" + params.Encode()
+	}
 	body := doSend(http.MethodDelete, apiPath, nil)
-	return unmarshalBasicResponse(body)
+	rb := common.ResponseBodyUserDelete{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+// ownedResourceLines lists the resources a user owns and, if known, who they will move to.
+func ownedResourceLines(owned []common.OwnedResourceData) string {
+	lines := make([]string, 0, len(owned))
+	for _, o := range owned {
+		line := fmt.Sprintf("  %-12s %s", o.Kind, o.Name)
+		if o.To != "" {
+			line += fmt.Sprintf("  (%s -> %s)", o.Owner, o.To)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// confirmUserDelete shows the resources that will change hands and exits unless the user name is typed.
+func confirmUserDelete(name string, owned []common.OwnedResourceData) {
+	fmt.Printf("deleting user '%s' moves these resources to a new owner:\n%s\n", name, ownedResourceLines(owned))
+	fmt.Print("\ntype the user name to delete it: ")
+	reader := bufio.NewReader(os.Stdin)
+	answer, _ := reader.ReadString('\n')
+	if strings.TrimSpace(answer) != name {
+		checkClientErr(fmt.Errorf("name did not match -- user '%s' was not deleted", name))
+	}
 }
 
 func printShowUsers(rb *common.ResponseBodyUsers, showAll bool) {
@@ -394,7 +574,7 @@ func printShowUsers(rb *common.ResponseBodyUsers, showAll bool) {
 	})
 
 	tw := table.NewWriter()
-	tw.AppendHeader(table.Row{"NAME", "FULL NAME", "JOINED", "EMAIL", "GROUPS"})
+	tw.AppendHeader(table.Row{"NAME", "FULL NAME", "JOINED", "EMAIL", "GROUPS", "DEFAULT GROUP", "LOCALE", "NOTIFY DELEGATE", "NOTIFY PREFS", "DEFAULT KARGS"})
 
 	for _, u := range users {
 
@@ -406,10 +586,10 @@ func printShowUsers(rb *common.ResponseBodyUsers, showAll bool) {
 		var groups string
 		var joinTime string
 		if simplePrint {
-			groups = strings.Join(u.Groups, ",")
+			groups = sortedJoin(u.Groups, ",")
 			joinTime = getLocTime(time.Unix(u.JoinDate, 0)).Format("Jan-02-2006")
 		} else {
-			groups = strings.Join(u.Groups, "\n")
+			groups = sortedJoin(u.Groups, "\n")
 			joinTime = getLocTime(time.Unix(u.JoinDate, 0)).Format("Jan 02 2006")
 		}
 
@@ -419,6 +599,11 @@ func printShowUsers(rb *common.ResponseBodyUsers, showAll bool) {
 			joinTime,
 			u.Email,
 			groups,
+			u.DefaultGroup,
+			u.Locale,
+			u.NotifyDelegate,
+			u.NotifyPrefs,
+			u.DefaultKernelArgs,
 		})
 	}
 
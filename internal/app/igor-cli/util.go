@@ -7,6 +7,8 @@ package igorcli
 import (
 	"archive/tar"
 	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
 	"io"
 	"os"
 	"path"
@@ -14,6 +16,8 @@ import (
 	"strings"
 	"time"
 
+	"igor2/internal/pkg/naming"
+
 	"github.com/spf13/cobra"
 )
 
@@ -102,6 +106,33 @@ func validateNameArg(_ *cobra.Command, args []string, _ string) ([]string, cobra
 	return []string{"NAME"}, cobra.ShellCompDirectiveNoFileComp
 }
 
+// validateNewNameArg is like validateNameArg for commands that create a resource of the given kind, but
+// stops suggesting NAME once what has been typed can't become a legal name.
+func validateNewNameArg(kind string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 && naming.CheckPartial(kind, toComplete) != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return validateNameArg(cmd, args, toComplete)
+	}
+}
+
+// checkNewName exits with the naming rule that was broken if name can't be used for a new resource of
+// the given kind. The server checks the same rules, this just saves a trip to find out.
+func checkNewName(kind, name string) {
+	checkClientErr(naming.CheckNew(kind, name))
+}
+
+// newIdempotencyKey returns a random key identifying a single request so the server can
+// tell when it is being sent again.
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		checkClientErr(err)
+	}
+	return hex.EncodeToString(b)
+}
+
 func registerFlagArgsFunc(igorCmd *cobra.Command, flagName string, flagArgs []string) error {
 	return igorCmd.RegisterFlagCompletionFunc(flagName, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return flagArgs, cobra.ShellCompDirectiveNoFileComp
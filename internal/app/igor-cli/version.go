@@ -0,0 +1,148 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorcli
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"igor2/internal/pkg/api"
+	"igor2/internal/pkg/common"
+)
+
+// API compatibility verdicts reported by 'igor version'
+const (
+	compatOK          = "compatible"
+	compatMismatch    = "version mismatch"
+	compatUnsupported = "unsupported"
+	compatUnknown     = "unknown"
+)
+
+// skipVersionCheck lets a client older than the server's minimum run anyway
+var skipVersionCheck bool
+
+// versionChecked is set once the server version has been compared so only one warning is printed
+var versionChecked bool
+
+// versionCompat is the result of comparing the client's version with the one the server reports.
+type versionCompat struct {
+	Client    string
+	Server    string
+	MinClient string
+	Verdict   string
+	Message   string
+}
+
+func newVersionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Show client and server versions",
+		Long: `
+Shows version info of the igor CLI along with the version of igor-server it
+talks to and whether the two are compatible.
+
+The server reports its version and the oldest CLI version it supports with
+every response. When the major or minor version of the CLI and server differ
+each command prints a warning. A CLI older than the oldest supported version
+refuses to run until it is upgraded, though the --skip-version-check flag can
+be used to run it anyway.
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println(common.GetVersion("Igor CLI", false))
+			// the check is done here so a client that is too old can still report the versions
+			versionChecked = true
+			_, header, _ := processRequestWithNoBody(http.MethodGet, cli.IgorServerAddr+api.PublicSettings)
+			printVersionCompat(compareVersions(common.GitTag, header))
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+}
+
+func printVersionCompat(vc versionCompat) {
+	unknown := func(v string) string {
+		if v == "" {
+			return "unknown"
+		}
+		return v
+	}
+	fmt.Println()
+	fmt.Printf("   CLI version: %s\n", unknown(vc.Client))
+	fmt.Printf("Server version: %s\n", unknown(vc.Server))
+	if vc.MinClient != "" {
+		fmt.Printf("Min CLI version: %s\n", vc.MinClient)
+	}
+	fmt.Printf(" Compatibility: %s\n", vc.Verdict)
+	if vc.Message != "" {
+		fmt.Println("\n" + vc.Message)
+	}
+}
+
+// compareVersions compares the client version with the version headers of a server response. A server
+// that doesn't send its version, such as one from before version reporting was added, or a client built
+// without a release tag can't be compared and gets an unknown verdict.
+func compareVersions(clientTag string, header http.Header) versionCompat {
+
+	vc := versionCompat{
+		Server:    header.Get(common.ServerVersionHeader),
+		MinClient: header.Get(common.MinClientVersionHeader),
+		Verdict:   compatUnknown,
+	}
+
+	client, cErr := common.ParseSemVer(clientTag)
+	if cErr != nil {
+		vc.Message = "this igor CLI was not built from a release so its version can't be compared with the server's"
+		return vc
+	}
+	vc.Client = client.String()
+
+	if minClient, err := common.ParseSemVer(vc.MinClient); err == nil && client.Compare(minClient) < 0 {
+		vc.Verdict = compatUnsupported
+		vc.Message = fmt.Sprintf("igor CLI %s is older than %s, the oldest version igor-server supports -- please upgrade the CLI", vc.Client, minClient)
+		return vc
+	}
+
+	server, sErr := common.ParseSemVer(vc.Server)
+	if sErr != nil {
+		vc.Message = "igor-server does not report its version so compatibility can't be checked"
+		return vc
+	}
+
+	if !client.SameMinor(server) {
+		vc.Verdict = compatMismatch
+		vc.Message = fmt.Sprintf("igor CLI %s and igor-server %s are different versions -- some commands may not work as expected", vc.Client, vc.Server)
+		return vc
+	}
+
+	vc.Verdict = compatOK
+	return vc
+}
+
+// checkServerVersion compares the client version with the first server response. A client older than the
+// server's minimum exits with an error unless --skip-version-check was used. Any other mismatch prints a
+// one-line warning.
+func checkServerVersion(header http.Header) {
+
+	if versionChecked || header == nil {
+		return
+	}
+	versionChecked = true
+
+	vc := compareVersions(common.GitTag, header)
+	switch vc.Verdict {
+	case compatUnsupported:
+		if !skipVersionCheck {
+			checkClientErr(fmt.Errorf("%s (use --skip-version-check to run anyway)", vc.Message))
+		}
+		fallthrough
+	case compatMismatch:
+		checkColorLevel()
+		_, _ = fmt.Fprintln(os.Stderr, cWarning.Sprint("warning: "+vc.Message))
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorcli
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"igor2/internal/pkg/common"
+)
+
+func versionHeader(server, minClient string) http.Header {
+	h := http.Header{}
+	if server != "" {
+		h.Set(common.ServerVersionHeader, server)
+	}
+	if minClient != "" {
+		h.Set(common.MinClientVersionHeader, minClient)
+	}
+	return h
+}
+
+func TestCompareVersions(t *testing.T) {
+
+	vc := compareVersions("v2.3.1", versionHeader("2.3.4", "2.0.0"))
+	assert.Equal(t, compatOK, vc.Verdict)
+	assert.Empty(t, vc.Message)
+
+	// old client, new server
+	vc = compareVersions("v2.2.5-3-g1a2b3c4", versionHeader("2.3.0", "2.0.0"))
+	assert.Equal(t, compatMismatch, vc.Verdict)
+	assert.Contains(t, vc.Message, "2.2.5")
+
+	// old client below the server's minimum
+	vc = compareVersions("v2.1.0", versionHeader("2.3.0", "2.2.0"))
+	assert.Equal(t, compatUnsupported, vc.Verdict)
+	assert.Contains(t, vc.Message, "2.2.0")
+
+	// new client, old server that sends no version headers
+	vc = compareVersions("v2.4.0", http.Header{})
+	assert.Equal(t, compatUnknown, vc.Verdict)
+	assert.Empty(t, vc.Server)
+
+	// a minimum is still enforced by a server that doesn't know its own version
+	vc = compareVersions("v2.1.0", versionHeader("", "2.2.0"))
+	assert.Equal(t, compatUnsupported, vc.Verdict)
+
+	// a dev build of the client can't be compared
+	vc = compareVersions("unknown build", versionHeader("2.3.0", "2.2.0"))
+	assert.Equal(t, compatUnknown, vc.Verdict)
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"igor2/internal/pkg/api"
+	"igor2/internal/pkg/common"
+)
+
+func newVlanCmd() *cobra.Command {
+
+	cmdVlan := &cobra.Command{
+		Use:   "vlan",
+		Short: "Perform a vlan command",
+		Long: `
+VLAN primary command. A sub-command must be invoked to do anything.
+
+On a VLAN-enabled cluster each reservation is given a VLAN from the range igor
+manages. An admin can limit the VLANs used by reservations of a group with
+'igor group edit --vlan-range'. A group can hold a VLAN across reservations
+with a named network (see 'igor network').`,
+	}
+
+	cmdVlan.AddCommand(newVlanShowCmd())
+
+	return cmdVlan
+}
+
+func newVlanShowCmd() *cobra.Command {
+
+	cmdShowVlan := &cobra.Command{
+		Use:   "show",
+		Short: "Show the VLANs used by reservations and named networks",
+		Long: `
+Shows the VLAN used by each reservation along with the reservation's owner and
+group, and the range of VLANs the group allows. A VLAN outside of that range,
+such as one assigned before the group's range was set, is flagged. Igor does
+not change these VLANs; they can be fixed by re-creating the reservation.
+
+The PROFILE column lists the network (QoS) profile an admin has applied to the
+reservation's ports along with its VLAN, if any (see 'igor res edit').
+
+Named networks are listed after the reservations with the group that owns
+each one and the reservations using it.
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printVlans(doReadVlans())
+		},
+		DisableFlagsInUseLine: true,
+		ValidArgsFunction:     validateNoArgs,
+	}
+
+	return cmdShowVlan
+}
+
+func doReadVlans() *common.ResponseBodyVlans {
+	body := doSend(http.MethodGet, api.Vlans, nil)
+	rb := common.ResponseBodyVlans{}
+	err := json.Unmarshal(*body, &rb)
+	checkUnmarshalErr(err)
+	return &rb
+}
+
+func printVlans(rb *common.ResponseBodyVlans) {
+
+	checkAndSetColorLevel(rb)
+
+	vlans := rb.Data["vlans"]
+	if len(vlans) == 0 {
+		printSimple("no reservations are using a VLAN", cRespWarn)
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"VLAN", "RESERVATION", "OWNER", "GROUP", "NETWORK", "PROFILE", "ALLOWED", "VIOLATION"})
+
+	violations := 0
+	for _, v := range vlans {
+		violation := v.Violation
+		if violation != "" {
+			violations++
+			if !simplePrint {
+				violation = cAlert.Sprint(violation)
+			}
+		}
+		tw.AppendRow([]interface{}{
+			v.Vlan,
+			v.Reservation,
+			v.Owner,
+			v.Group,
+			v.Network,
+			v.NetProfile,
+			v.AllowedRange,
+			violation,
+		})
+	}
+
+	setVlanTableStyle(tw)
+
+	fmt.Printf("\n" + tw.Render() + "\n\n")
+	if violations > 0 {
+		fmt.Printf("%d reservation(s) use a VLAN outside the range allowed for their group\n\n", violations)
+	}
+
+	if networks := rb.Data["networks"]; len(networks) > 0 {
+		nw := table.NewWriter()
+		nw.AppendHeader(table.Row{"NETWORK", "VLAN", "GROUP", "CREATED-BY", "RESERVATIONS"})
+		for _, n := range networks {
+			var resNames []string
+			for _, v := range vlans {
+				if v.Network == n.Network {
+					resNames = append(resNames, v.Reservation)
+				}
+			}
+			nw.AppendRow([]interface{}{
+				n.Network,
+				n.Vlan,
+				n.Group,
+				n.Owner,
+				sortedJoin(resNames, ","),
+			})
+		}
+		setVlanTableStyle(nw)
+		fmt.Printf(nw.Render() + "\n\n")
+	}
+}
+
+func setVlanTableStyle(tw table.Writer) {
+	if simplePrint {
+		tw.Style().Options.SeparateRows = false
+		tw.Style().Options.SeparateColumns = true
+		tw.Style().Options.DrawBorder = false
+	} else {
+		tw.SetStyle(igorTableStyle)
+	}
+}
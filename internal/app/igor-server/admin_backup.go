@@ -0,0 +1,125 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	zl "github.com/rs/zerolog"
+
+	"igor2/internal/pkg/common"
+)
+
+const (
+	dbBackupPrefix     = "igor-"
+	dbBackupSuffix     = ".db"
+	dbBackupTimeFormat = "20060102-150405"
+)
+
+// dbBackupLock keeps more than one backup from running at a time. It is separate from
+// dbAccess so normal requests are not held up while a snapshot is being written.
+var dbBackupLock sync.Mutex
+
+// doDbBackup writes a consistent snapshot of the igor database to a timestamped file in
+// the configured backup folder, or toDir if one is given. Backups written to the configured
+// folder are pruned down to the configured retention count afterward.
+//
+// The snapshot is made with VACUUM INTO which runs inside a single read transaction. In WAL
+// mode this doesn't block writers, so the dbAccess lock is never taken.
+func doDbBackup(toDir string, clog *zl.Logger) (*common.BackupData, int, error) {
+
+	if !dbBackupLock.TryLock() {
+		return nil, http.StatusConflict, fmt.Errorf("a database backup is already in progress")
+	}
+	defer dbBackupLock.Unlock()
+
+	backupDir := igor.Database.Backup.Dir
+	if toDir != "" {
+		if !filepath.IsAbs(toDir) {
+			return nil, http.StatusBadRequest, fmt.Errorf("backup folder '%s' must be an absolute path", toDir)
+		}
+		if fi, err := os.Stat(toDir); err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("backup folder '%s' is not accessible: %v", toDir, err)
+		} else if !fi.IsDir() {
+			return nil, http.StatusBadRequest, fmt.Errorf("backup folder '%s' is not a folder", toDir)
+		}
+		backupDir = filepath.Clean(toDir)
+	}
+
+	start := time.Now()
+	backupPath := filepath.Join(backupDir, dbBackupPrefix+start.Format(dbBackupTimeFormat)+dbBackupSuffix)
+	if _, err := os.Stat(backupPath); err == nil {
+		return nil, http.StatusConflict, fmt.Errorf("backup file %s already exists", backupPath)
+	}
+
+	db := igor.IGormDb.GetDB()
+	if result := db.Exec("VACUUM INTO ?", backupPath); result.Error != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("database backup failed: %v", result.Error)
+	}
+	elapsed := time.Since(start)
+
+	fi, err := os.Stat(backupPath)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if err = os.Chmod(backupPath, 0640); err != nil {
+		clog.Warn().Msgf("unable to set permissions on backup file %s: %v", backupPath, err)
+	}
+
+	backup := &common.BackupData{
+		Path:     backupPath,
+		Size:     fi.Size(),
+		Duration: elapsed.Round(time.Millisecond).String(),
+	}
+
+	if backupDir == filepath.Clean(igor.Database.Backup.Dir) {
+		backup.Pruned, err = pruneDbBackups(backupDir, igor.Database.Backup.Retain)
+		if err != nil {
+			// the backup itself succeeded so don't fail the request
+			clog.Error().Msgf("problem pruning old database backups in %s: %v", backupDir, err)
+		}
+	}
+
+	return backup, http.StatusOK, nil
+}
+
+// pruneDbBackups removes the oldest backup files in dir so no more than retain remain,
+// and returns the names of the files that were removed.
+func pruneDbBackups(dir string, retain int) ([]string, error) {
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), dbBackupPrefix) && strings.HasSuffix(e.Name(), dbBackupSuffix) {
+			backups = append(backups, e.Name())
+		}
+	}
+	if len(backups) <= retain {
+		return nil, nil
+	}
+
+	// timestamped names sort oldest first
+	sort.Strings(backups)
+
+	var pruned []string
+	for _, name := range backups[:len(backups)-retain] {
+		if rmErr := os.Remove(filepath.Join(dir, name)); rmErr != nil {
+			return pruned, rmErr
+		}
+		pruned = append(pruned, name)
+	}
+	return pruned, nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPruneDbBackups(t *testing.T) {
+
+	dir := t.TempDir()
+	names := []string{
+		"igor-20230101-120000.db",
+		"igor-20230102-120000.db",
+		"igor-20230103-120000.db",
+		"igor-20230104-120000.db",
+		"notes.txt",
+	}
+	for _, n := range names {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, n), []byte("x"), 0600))
+	}
+
+	pruned, err := pruneDbBackups(dir, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"igor-20230101-120000.db", "igor-20230102-120000.db"}, pruned)
+
+	entries, _ := os.ReadDir(dir)
+	var remaining []string
+	for _, e := range entries {
+		remaining = append(remaining, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"igor-20230103-120000.db", "igor-20230104-120000.db", "notes.txt"}, remaining)
+
+	// nothing to do when under the retention count
+	pruned, err = pruneDbBackups(dir, 5)
+	assert.NoError(t, err)
+	assert.Empty(t, pruned)
+}
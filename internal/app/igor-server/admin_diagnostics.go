@@ -0,0 +1,187 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+const (
+	// defaultDiagLogLines is how many recent warn+ log lines a support bundle has unless asked for more
+	defaultDiagLogLines = 100
+	// maxRecentLogLines is how many recent warn+ log lines the server keeps in memory
+	maxRecentLogLines = 500
+	// diagRateWindow is the least time allowed between two support bundles
+	diagRateWindow = time.Minute
+)
+
+var (
+	// serverStarted is used to report the uptime of the server
+	serverStarted = time.Now()
+
+	// diagRateMU guards diagLastRun, the time the last support bundle was gathered
+	diagRateMU  sync.Mutex
+	diagLastRun time.Time
+
+	emailAddrRegex = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+)
+
+// allowDiagRequest reports whether a support bundle can be gathered at the given time. Gathering one
+// walks every table, so only one is allowed per diagRateWindow. If not allowed, it also returns the
+// time left before the next one can be made.
+func allowDiagRequest(now time.Time) (bool, time.Duration) {
+
+	diagRateMU.Lock()
+	defer diagRateMU.Unlock()
+
+	if !diagLastRun.IsZero() && now.Before(diagLastRun.Add(diagRateWindow)) {
+		return false, diagLastRun.Add(diagRateWindow).Sub(now)
+	}
+	diagLastRun = now
+	return true, 0
+}
+
+// configSecrets returns the credentials set in the server config.
+func configSecrets() []string {
+	secrets := []string{igor.Auth.DefaultUserPassword, igor.Auth.Ldap.BindPassword, igor.Auth.Oidc.ClientSecret,
+		igor.Vlan.NetworkPassword, igor.Email.SmtpPassword}
+	for _, s := range igor.Email.SmtpServers {
+		secrets = append(secrets, s.Password)
+	}
+	return secrets
+}
+
+// redactDiagLines masks any email address or configured credential found in the given lines.
+func redactDiagLines(lines []string) []string {
+	secrets := configSecrets()
+	redacted := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = emailAddrRegex.ReplaceAllString(line, "<email>")
+		for _, s := range secrets {
+			if s != "" {
+				line = strings.ReplaceAll(line, s, "*****")
+			}
+		}
+		redacted = append(redacted, line)
+	}
+	return redacted
+}
+
+// doGatherDiagnostics collects the server details an admin needs to report a problem: version,
+// config settings, database and cluster summaries, the last logLines warn+ log lines, task status,
+// Go runtime stats and the catalog of events written to the log.
+func doGatherDiagnostics(logLines int, now time.Time) (*common.DiagnosticsData, int, error) {
+
+	if ok, wait := allowDiagRequest(now); !ok {
+		return nil, http.StatusTooManyRequests, newCodedError(common.ErrRateLimit,
+			"a support bundle was gathered recently - try again in %v", wait.Round(time.Second))
+	}
+
+	diag := &common.DiagnosticsData{
+		Generated: now.Unix(),
+		Version:   common.GetVersion("igor-server", true),
+		Config:    redactDiagLines(configSettingLines(igor.Config, "igor.")),
+		Log:       redactDiagLines(recentLogs.tail(logLines)),
+		Tasks:     managerTaskData(),
+		Runtime:   diagRuntimeStats(now),
+		Events:    logEventList(),
+	}
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	if err := performDbTx(func(tx *gorm.DB) error {
+		var err error
+		if diag.Database, err = diagDatabaseStats(tx); err != nil {
+			return err
+		}
+		diag.Cluster, err = diagClusterStats(now, tx)
+		return err
+	}); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return diag, http.StatusOK, nil
+}
+
+// diagDatabaseStats returns the schema version of the database and the row count of each table.
+func diagDatabaseStats(tx *gorm.DB) (common.DiagnosticsDatabaseData, error) {
+
+	dbStats := common.DiagnosticsDatabaseData{LatestVersion: latestSchemaVersion(), RowCounts: map[string]int{}}
+
+	var err error
+	if dbStats.SchemaVersion, _, err = readSchemaVersion(tx); err != nil {
+		return dbStats, err
+	}
+
+	for _, m := range dbModels {
+		stmt := &gorm.Statement{DB: tx}
+		if err = stmt.Parse(m); err != nil {
+			return dbStats, err
+		}
+		var count int64
+		if result := tx.Table(stmt.Schema.Table).Count(&count); result.Error != nil {
+			return dbStats, result.Error
+		}
+		dbStats.RowCounts[stmt.Schema.Table] = int(count)
+	}
+	return dbStats, nil
+}
+
+// diagClusterStats counts the hosts of the cluster by state and its active and future reservations.
+// It uses the public status summary, leaving out the message of the day and the node list.
+func diagClusterStats(now time.Time, tx *gorm.DB) (common.DiagnosticsClusterData, error) {
+
+	status, err := buildPublicStatus(now, tx)
+	if err != nil {
+		return common.DiagnosticsClusterData{}, err
+	}
+
+	return common.DiagnosticsClusterData{
+		Name:               status.Cluster,
+		Hosts:              len(status.Nodes),
+		HostStates:         status.NodeStates,
+		ActiveReservations: status.ActiveReservations,
+		FutureReservations: status.FutureReservations,
+	}, nil
+}
+
+// diagRuntimeStats is a snapshot of the goroutines and heap of the server.
+func diagRuntimeStats(now time.Time) common.DiagnosticsRuntimeData {
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return common.DiagnosticsRuntimeData{
+		GoVersion:  runtime.Version(),
+		Uptime:     common.FormatDuration(now.Sub(serverStarted).Round(time.Second), false),
+		Goroutines: runtime.NumGoroutine(),
+		GoMaxProcs: runtime.GOMAXPROCS(0),
+		HeapAlloc:  mem.HeapAlloc,
+		HeapSys:    mem.HeapSys,
+		HeapObjs:   mem.HeapObjects,
+		NumGC:      mem.NumGC,
+	}
+}
+
+// diagLogLineCount parses the number of log lines asked for in a support bundle request.
+func diagLogLineCount(val string) (int, error) {
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 0 || n > maxRecentLogLines {
+		return 0, fmt.Errorf("lines must be a number from 0 to %d", maxRecentLogLines)
+	}
+	return n, nil
+}
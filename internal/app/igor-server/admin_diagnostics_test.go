@@ -0,0 +1,97 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	zl "github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestReadDiagnostics(t *testing.T) {
+
+	origConfig, origLogs := igor.Config, recentLogs
+	t.Cleanup(func() {
+		igor.Config, recentLogs = origConfig, origLogs
+		diagLastRun = time.Time{}
+	})
+	diagLastRun = time.Time{}
+	recentLogs = &logRing{size: maxRecentLogLines}
+
+	secrets := []string{"default-pw-1492", "ldap-bind-8812", "oidc-client-7731", "switch-pw-5519", "smtp-pw-3306", "relay-pw-6640"}
+	igor.Auth.DefaultUserPassword = secrets[0]
+	igor.Auth.Ldap.BindPassword = secrets[1]
+	igor.Auth.Oidc.ClientSecret = secrets[2]
+	igor.Vlan.NetworkPassword = secrets[3]
+	igor.Email.SmtpPassword = secrets[4]
+	igor.Email.SmtpServers = []SmtpServerConfig{{Host: "relay.example.com", Port: 25, Username: "igor", Password: secrets[5]}}
+	igor.Email.ReplyTo = "igor-admins@example.com"
+
+	seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+	require.NoError(t, db.Omit(clause.Associations).Create(&Cluster{Name: "krypton", Prefix: "kn"}).Error)
+	require.NoError(t, db.Omit(clause.Associations).Create(&User{Name: "alice", Email: "alice@example.com"}).Error)
+
+	// only warnings and errors are kept for the bundle, with secrets and emails scrubbed on the way out
+	testLog := zl.New(zl.MultiLevelWriter(&warnLevelWriter{w: newConsoleWriter(recentLogs, true)}))
+	testLog.Info().Msg("routine info line")
+	testLog.Warn().Msgf("smtp login as alice@example.com with %s refused", secrets[4])
+	testLog.Error().Msg("ldap bind failed")
+
+	get := func(query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/igor/admin/diagnostics"+query, nil)
+		w := httptest.NewRecorder()
+		handleReadDiagnostics(w, r)
+		return w
+	}
+
+	w := get("?lines=5")
+	require.Equal(t, http.StatusOK, w.Code)
+	for _, s := range append(secrets, "alice@example.com", "igor-admins@example.com") {
+		assert.NotContains(t, w.Body.String(), s)
+	}
+
+	rb := common.ResponseBodyDiagnostics{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rb))
+	diag := rb.Data["diagnostics"]
+	assert.Contains(t, diag.Config, "igor.Auth.Oidc.ClientSecret = *****")
+	assert.Contains(t, diag.Config, "igor.Email.SmtpServers = [relay.example.com:25 (username 'igor')]")
+	require.Len(t, diag.Log, 2)
+	assert.Contains(t, diag.Log[0], "smtp login as <email> with ***** refused")
+	assert.Contains(t, diag.Log[1], "ldap bind failed")
+	assert.Equal(t, latestSchemaVersion(), diag.Database.LatestVersion)
+	assert.Equal(t, 1, diag.Database.RowCounts["users"])
+	assert.Equal(t, 2, diag.Database.RowCounts["hosts"])
+	assert.Equal(t, "krypton", diag.Cluster.Name)
+	assert.Equal(t, map[string]int{"available": 2}, diag.Cluster.HostStates)
+	assert.Positive(t, diag.Runtime.Goroutines)
+	assert.NotEmpty(t, diag.Version)
+	assert.Equal(t, logEventList(), diag.Events)
+
+	// a second bundle right away is refused
+	w = get("")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), common.ErrRateLimit)
+}
+
+func TestLogRingKeepsNewest(t *testing.T) {
+
+	lr := &logRing{size: 3}
+	for _, l := range []string{"one\n", "two\n", "three\n", "four\n"} {
+		_, _ = lr.Write([]byte(l))
+	}
+	assert.Equal(t, []string{"two", "three", "four"}, lr.tail(10))
+	assert.Equal(t, []string{"four"}, lr.tail(1))
+	assert.Empty(t, lr.tail(0))
+}
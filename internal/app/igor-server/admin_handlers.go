@@ -0,0 +1,308 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+
+	"igor2/internal/pkg/common"
+)
+
+func handleDbBackup(w http.ResponseWriter, r *http.Request) {
+
+	backupParams := getBodyFromContext(r)
+	clog := hlog.FromRequest(r)
+	actionPrefix := "database backup"
+	rb := common.NewResponseBodyBackup()
+
+	toDir, _ := backupParams["to"].(string)
+	backup, status, err := doDbBackup(strings.TrimSpace(toDir), clog)
+
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["backup"] = *backup
+		clog.Info().Msgf("%s success - wrote %s (%d bytes) in %s", actionPrefix, backup.Path, backup.Size, backup.Duration)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+func validateBackupParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+
+		if r.Method == http.MethodPost {
+			backupParams := getBodyFromContext(r)
+
+		postParamLoop:
+			for key, val := range backupParams {
+				switch key {
+				case "to":
+					if _, ok := val.(string); !ok {
+						validateErr = NewBadParamTypeError(key, val, "string")
+						break postParamLoop
+					}
+				default:
+					validateErr = NewUnknownParamError(key, val)
+					break postParamLoop
+				}
+			}
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateBackupParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func handleReadHookStatus(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "read hook status"
+	rb := common.NewResponseBodyHooks()
+
+	failedOnly := strings.EqualFold(r.URL.Query().Get("failed"), "true")
+	rb.Data["hooks"] = getHookStatus(failedOnly)
+	clog.Debug().Msgf("%s success", actionPrefix)
+
+	makeJsonResponse(w, http.StatusOK, rb)
+}
+
+func handleReadPxeAudit(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "PXE config audit"
+	rb := common.NewResponseBodyPxeAudit()
+
+	problems, status, err := doPxeAudit(time.Now())
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["problems"] = problems
+		clog.Info().Msgf("%s success - %d problem(s) found", actionPrefix, len(problems))
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// handleFsck runs an integrity check of the database. A POST request also repairs the problems that
+// can be repaired safely.
+func handleFsck(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "integrity check"
+	rb := common.NewResponseBodyFsck()
+
+	repair := r.Method == http.MethodPost
+	if repair {
+		actionPrefix = "integrity check and repair"
+	}
+
+	problems, status, err := doFsck(repair, time.Now(), clog)
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["problems"] = problems
+		clog.Info().Msgf("%s success - %d problem(s) found", actionPrefix, len(problems))
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// handleReadDiagnostics gathers the support bundle an admin sends along with a problem report.
+func handleReadDiagnostics(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "gather diagnostics"
+	rb := common.NewResponseBodyDiagnostics()
+
+	logLines := defaultDiagLogLines
+	if val := r.URL.Query().Get("lines"); val != "" {
+		logLines, _ = diagLogLineCount(val)
+	}
+
+	diag, status, err := doGatherDiagnostics(logLines, time.Now())
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["diagnostics"] = *diag
+		clog.Info().Msgf("%s success", actionPrefix)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+func validateDiagnosticsParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+
+	paramLoop:
+		for key, val := range r.URL.Query() {
+			switch key {
+			case "lines":
+				if len(val) != 1 {
+					validateErr = NewBadParamTypeError(key, val, "number")
+					break paramLoop
+				}
+				if _, err := diagLogLineCount(val[0]); err != nil {
+					validateErr = err
+					break paramLoop
+				}
+			default:
+				validateErr = NewUnknownParamError(key, val)
+				break paramLoop
+			}
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateDiagnosticsParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// handleAdvanceSimClock moves the simulated scheduler clock ahead and runs reservation and maintenance
+// management at the new time so expirations and the end of maintenance happen right away.
+func handleAdvanceSimClock(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "advance scheduler clock"
+	rb := common.NewResponseBody()
+
+	advanceStr, _ := getBodyFromContext(r)["advance"].(string)
+	advance, _ := common.ParseDuration(advanceStr)
+	offset := advanceSchedulerClock(advance)
+	checkTime := schedulerTime(time.Now())
+
+	runManagerTasks(checkTime)
+
+	rb.Data["clock"] = map[string]interface{}{"now": checkTime.Unix(), "offset": common.FormatDuration(offset, false)}
+	rb.Message = "scheduler clock is now " + checkTime.Format(common.DateTimeLongFormat) + ", " +
+		common.FormatDuration(offset, false) + " ahead of real time"
+	clog.Warn().Msgf("%s success - %s", actionPrefix, rb.Message)
+
+	makeJsonResponse(w, http.StatusOK, rb)
+}
+
+func validateSimClockParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+		clockParams := getBodyFromContext(r)
+
+		if _, ok := clockParams["advance"]; !ok {
+			validateErr = NewMissingParamError("advance")
+		}
+
+	postParamLoop:
+		for key, val := range clockParams {
+			switch key {
+			case "advance":
+				if advance, ok := val.(string); !ok {
+					validateErr = NewBadParamTypeError(key, val, "string")
+					break postParamLoop
+				} else if dur, err := common.ParseDuration(advance); err != nil {
+					validateErr = err
+					break postParamLoop
+				} else if dur <= 0 {
+					validateErr = fmt.Errorf("the scheduler clock can only be moved forward")
+					break postParamLoop
+				}
+			default:
+				validateErr = NewUnknownParamError(key, val)
+				break postParamLoop
+			}
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateSimClockParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// handleRunManagerTask runs one of the server's periodic tasks right away, after any scheduled run of
+// it already underway.
+func handleRunManagerTask(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "run task"
+	rb := common.NewResponseBody()
+
+	name, _ := getBodyFromContext(r)["task"].(string)
+	status := http.StatusOK
+	task, err := doRunManagerTask(strings.TrimSpace(name))
+	if err != nil {
+		status = http.StatusInternalServerError
+		if task.Name == "" {
+			status = http.StatusBadRequest
+		}
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Message = fmt.Sprintf("task %s finished in %dms", task.Name, task.LastDurationMs)
+		clog.Info().Msgf("%s success - %s", actionPrefix, rb.Message)
+	}
+	if task.Name != "" {
+		rb.Data["task"] = task
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+func validateRunTaskParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+		taskParams := getBodyFromContext(r)
+
+		if _, ok := taskParams["task"]; !ok {
+			validateErr = NewMissingParamError("task")
+		}
+
+	postParamLoop:
+		for key, val := range taskParams {
+			switch key {
+			case "task":
+				if _, ok := val.(string); !ok {
+					validateErr = NewBadParamTypeError(key, val, "string")
+					break postParamLoop
+				}
+			default:
+				validateErr = NewUnknownParamError(key, val)
+				break postParamLoop
+			}
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateRunTaskParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -6,6 +6,7 @@ package igorserver
 
 import (
 	"errors"
+	"fmt"
 	"igor2/internal/pkg/api"
 	"net/http"
 	"strings"
@@ -35,6 +36,8 @@ func initAuth() {
 	scheme := strings.ToLower(igor.Auth.Scheme)
 	if strings.Contains(scheme, "ldap") {
 		igor.AuthSecondary = NewLdapAuth()
+	} else if scheme == "oidc" {
+		igor.AuthSecondary = NewOidcAuth()
 	} else {
 		igor.AuthSecondary = nil
 	}
@@ -65,8 +68,11 @@ func authnHandler(handler http.Handler) http.Handler {
 				}
 				// token verify failed and auth header was not set to basic,
 				// local/secondary auth will fail
-				// redirect to login if you are not igorweb
-				if r.URL.Path != api.Login && strings.HasPrefix(r.UserAgent(), IgorCliPrefix) {
+				// redirect to login if you are not igorweb. OIDC users can't give a password
+				// so they are told to log in through the provider instead
+				if igor.Auth.Scheme == "oidc" && strings.HasPrefix(r.UserAgent(), IgorCliPrefix) {
+					rb.Message = errLine + " -- run 'igor login --oidc' to sign in ('igor login' for " + IgorAdmin + ")"
+				} else if r.URL.Path != api.Login && strings.HasPrefix(r.UserAgent(), IgorCliPrefix) {
 					http.Redirect(w, r, api.Login, http.StatusTemporaryRedirect)
 					return
 				}
@@ -103,6 +109,11 @@ func findUserForAuthN(username string) (*User, error) {
 		}
 	}
 
+	// an account dropped from LDAP stays around for its reservations to be claimed but can't be used
+	if users[0].isPendingRemoval() {
+		return nil, &BadCredentialsError{msg: fmt.Sprintf("account '%s' is pending removal", username)}
+	}
+
 	return &users[0], nil
 }
 
@@ -0,0 +1,515 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"igor2/internal/pkg/common"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/rs/zerolog/hlog"
+)
+
+const (
+	DefaultOidcUsernameClaim = "preferred_username"
+	DefaultOidcEmailClaim    = "email"
+	DefaultOidcFullNameClaim = "name"
+
+	// oidcLoginTimeout is how long a browser has to come back from the provider's login page
+	oidcLoginTimeout = 10 * time.Minute
+	oidcScopes       = "openid profile email"
+	oidcDeviceGrant  = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+var (
+	oidcHttpClient = &http.Client{Timeout: 15 * time.Second}
+
+	// oidcLogins holds the PKCE verifier of each browser login in progress keyed by its state value
+	oidcLogins = common.NewPassiveTtlMap(oidcLoginTimeout)
+
+	oidcMU       sync.Mutex
+	oidcProvider *oidcProviderInfo
+)
+
+// OidcAuth implements IAuth interface. Users of an OIDC provider sign in through it rather than with
+// a password, so password logins are refused for everyone but igor-admin, which is always checked
+// against its local password.
+type OidcAuth struct{}
+
+// NewOidcAuth instantiates the OIDC implementation of IAuth
+func NewOidcAuth() IAuth {
+	return &OidcAuth{}
+}
+
+func (o *OidcAuth) authenticate(r *http.Request) (*User, error) {
+	return nil, &BadCredentialsError{msg: "oidc login failed - password login is not available, sign in through the identity provider " +
+		"(igor login --oidc)"}
+}
+
+// oidcProviderInfo holds the endpoints discovered from the provider and the keys it signs ID tokens with
+type oidcProviderInfo struct {
+	Issuer                      string `json:"issuer"`
+	AuthorizationEndpoint       string `json:"authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	JwksURI                     string `json:"jwks_uri"`
+	keys                        map[string]interface{}
+}
+
+// oidcTokenResponse is the response of the provider's token endpoint
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// getOidcProvider returns the provider endpoints, discovering them the first time they are needed.
+func getOidcProvider() (*oidcProviderInfo, error) {
+
+	oidcMU.Lock()
+	defer oidcMU.Unlock()
+
+	if oidcProvider != nil {
+		return oidcProvider, nil
+	}
+
+	info := &oidcProviderInfo{}
+	if err := oidcGetJson(igor.Auth.Oidc.IssuerURL+"/.well-known/openid-configuration", info); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed - %v", err)
+	}
+	if strings.TrimSuffix(info.Issuer, "/") != igor.Auth.Oidc.IssuerURL {
+		return nil, fmt.Errorf("oidc discovery failed - provider issuer '%s' does not match configured issuer", info.Issuer)
+	}
+	oidcProvider = info
+	return oidcProvider, nil
+}
+
+// signingKey returns the provider key with the given ID. The key set is read again when the ID isn't
+// known since providers rotate their keys.
+func (p *oidcProviderInfo) signingKey(kid string) (interface{}, error) {
+
+	oidcMU.Lock()
+	defer oidcMU.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := oidcGetJson(p.JwksURI, &jwks); err != nil {
+		return nil, fmt.Errorf("unable to read provider keys - %v", err)
+	}
+
+	p.keys = map[string]interface{}{}
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, nErr := base64.RawURLEncoding.DecodeString(k.N)
+			e, eErr := base64.RawURLEncoding.DecodeString(k.E)
+			if nErr != nil || eErr != nil {
+				continue
+			}
+			p.keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, xErr := base64.RawURLEncoding.DecodeString(k.X)
+			y, yErr := base64.RawURLEncoding.DecodeString(k.Y)
+			if xErr != nil || yErr != nil {
+				continue
+			}
+			p.keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no provider key with ID '%s'", kid)
+}
+
+// verifyIDToken checks the signature, issuer, audience and lifetime of an ID token and returns its claims.
+func (p *oidcProviderInfo) verifyIDToken(idToken string) (jwt.MapClaims, error) {
+
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}))
+	if _, err := parser.ParseWithClaims(idToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.signingKey(kid)
+	}); err != nil {
+		return nil, &BadCredentialsError{msg: fmt.Sprintf("oidc login failed - invalid ID token: %v", err)}
+	}
+
+	if !claims.VerifyIssuer(igor.Auth.Oidc.IssuerURL, true) && !claims.VerifyIssuer(igor.Auth.Oidc.IssuerURL+"/", true) {
+		return nil, &BadCredentialsError{msg: "oidc login failed - ID token was not issued by the configured provider"}
+	}
+	if !claims.VerifyAudience(igor.Auth.Oidc.ClientID, true) {
+		return nil, &BadCredentialsError{msg: "oidc login failed - ID token was not issued to igor"}
+	}
+	return claims, nil
+}
+
+// requestToken posts a token request to the provider and returns the verified claims of the ID token
+// it sends back. A request the provider refused returns its OAuth error code as well.
+func (p *oidcProviderInfo) requestToken(form url.Values) (jwt.MapClaims, string, error) {
+
+	form.Set("client_id", igor.Auth.Oidc.ClientID)
+	if igor.Auth.Oidc.ClientSecret != "" {
+		form.Set("client_secret", igor.Auth.Oidc.ClientSecret)
+	}
+
+	tr := &oidcTokenResponse{}
+	if err := oidcPostForm(p.TokenEndpoint, form, tr); err != nil {
+		return nil, "", err
+	}
+	if tr.Error != "" {
+		return nil, tr.Error, &BadCredentialsError{msg: fmt.Sprintf("oidc login failed - %s %s", tr.Error, tr.ErrorDescription)}
+	}
+	if tr.IDToken == "" {
+		return nil, "", fmt.Errorf("oidc login failed - provider did not return an ID token")
+	}
+
+	claims, err := p.verifyIDToken(tr.IDToken)
+	return claims, "", err
+}
+
+// oidcUser returns the igor user named by the ID token claims. Users igor doesn't know yet get an
+// account made from the claims, the same way the LDAP sync makes them. igor-admin can't sign in
+// through the provider.
+func oidcUser(claims jwt.MapClaims, r *http.Request) (*User, error) {
+
+	oidcConf := igor.Auth.Oidc
+	username, _ := claims[oidcConf.UsernameClaim].(string)
+	username = strings.ToLower(strings.TrimSpace(username))
+	if username == "" {
+		return nil, &BadCredentialsError{msg: fmt.Sprintf("oidc login failed - ID token has no '%s' claim", oidcConf.UsernameClaim)}
+	}
+	if username == IgorAdmin {
+		return nil, &BadCredentialsError{msg: fmt.Sprintf("oidc login failed - %s must sign in with its local password", IgorAdmin)}
+	}
+
+	user, err := findUserForAuthN(username)
+	if err == nil {
+		return user, nil
+	} else if _, status, _ := getUsersTx([]string{username}, true); status != http.StatusNotFound {
+		// known but unable to sign in, such as an account pending removal
+		return nil, err
+	}
+
+	userInfo := map[string]interface{}{"name": username}
+	if email, _ := claims[oidcConf.EmailClaim].(string); email != "" {
+		userInfo["email"] = email
+	} else if igor.Email.DefaultSuffix != "" {
+		userInfo["email"] = fmt.Sprintf("%s@%s", username, igor.Email.DefaultSuffix)
+	} else {
+		return nil, &BadCredentialsError{msg: fmt.Sprintf("oidc login failed - ID token has no '%s' claim to make an account with", oidcConf.EmailClaim)}
+	}
+	if fullName, _ := claims[oidcConf.FullNameClaim].(string); fullName != "" {
+		userInfo["fullName"] = fullName
+	}
+
+	if user, _, err = doCreateUser(userInfo, r); err != nil {
+		return nil, fmt.Errorf("failed to create new user '%s' on first oidc login: %v", username, err)
+	}
+	hlog.FromRequest(r).Info().Msgf("created new user '%s' on first oidc login", user.Name)
+	return user, nil
+}
+
+// oidcLoginHandler starts a browser login by sending it to the provider's login page.
+func oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	rb := common.NewResponseBody()
+
+	provider, err := getOidcProvider()
+	if err != nil {
+		stdErrorResp(rb, http.StatusBadGateway, "oidc login", err, clog)
+		makeJsonResponse(w, http.StatusBadGateway, rb)
+		return
+	}
+
+	state, verifier := oidcRandom(), oidcRandom()
+	oidcLogins.Put(state, verifier)
+	challenge := sha256.Sum256([]byte(verifier))
+
+	authURL, _ := url.Parse(provider.AuthorizationEndpoint)
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", igor.Auth.Oidc.ClientID)
+	q.Set("redirect_uri", igor.Auth.Oidc.RedirectURL)
+	q.Set("scope", oidcScopes)
+	q.Set("state", state)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	authURL.RawQuery = q.Encode()
+
+	http.Redirect(w, r, authURL.String(), http.StatusFound)
+}
+
+// oidcCallbackHandler finishes a browser login once the provider sends it back with an
+// authorization code. The code is exchanged for an ID token and the user gets an igor session.
+func oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "oidc login"
+	rb := common.NewResponseBody()
+	q := r.URL.Query()
+
+	status := http.StatusUnauthorized
+	user, err := func() (*User, error) {
+		if pErr := q.Get("error"); pErr != "" {
+			return nil, &BadCredentialsError{msg: fmt.Sprintf("oidc login failed - provider refused login: %s %s", pErr, q.Get("error_description"))}
+		}
+		state := q.Get("state")
+		verifier, ok := oidcLogins.Get(state).(string)
+		if state == "" || !ok {
+			return nil, &BadCredentialsError{msg: "oidc login failed - login expired or was not started by igor, try again"}
+		}
+		oidcLogins.Remove(state)
+
+		provider, err := getOidcProvider()
+		if err != nil {
+			status = http.StatusBadGateway
+			return nil, err
+		}
+		claims, _, err := provider.requestToken(url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {q.Get("code")},
+			"redirect_uri":  {igor.Auth.Oidc.RedirectURL},
+			"code_verifier": {verifier},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return oidcUser(claims, r)
+	}()
+
+	if err == nil {
+		igor.ElevateMap.Remove(user.Name)
+		if err = startAuthSession(w, user, r); err != nil {
+			status = http.StatusInternalServerError
+		}
+	}
+
+	var bcErr *BadCredentialsError
+	if err != nil && !errors.As(err, &bcErr) && status == http.StatusUnauthorized {
+		status = http.StatusInternalServerError
+	}
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+		if igor.Auth.Oidc.WebURL != "" {
+			http.Redirect(w, r, igor.Auth.Oidc.WebURL+"?oidcError="+url.QueryEscape(rb.Message), http.StatusFound)
+			return
+		}
+		makeJsonResponse(w, status, rb)
+		return
+	}
+
+	clog.Info().Msgf("%s success - '%s' signed in", actionPrefix, user.Name)
+	if igor.Auth.Oidc.WebURL != "" {
+		http.Redirect(w, r, igor.Auth.Oidc.WebURL+"?oidcUser="+url.QueryEscape(user.Name), http.StatusFound)
+		return
+	}
+	rb.Data["user"] = user.Name
+	makeJsonResponse(w, http.StatusOK, rb)
+}
+
+// oidcDeviceHandler starts a device code login for a client without a browser. The response has the
+// URL and code the user enters at the provider and the device code the client polls with.
+func oidcDeviceHandler(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "oidc device login"
+	rb := common.NewResponseBody()
+
+	status := http.StatusBadGateway
+	device, err := func() (*common.OidcDeviceData, error) {
+		provider, err := getOidcProvider()
+		if err != nil {
+			return nil, err
+		}
+		if provider.DeviceAuthorizationEndpoint == "" {
+			status = http.StatusNotImplemented
+			return nil, fmt.Errorf("the identity provider does not support device code login")
+		}
+		form := url.Values{"client_id": {igor.Auth.Oidc.ClientID}, "scope": {oidcScopes}}
+		if igor.Auth.Oidc.ClientSecret != "" {
+			form.Set("client_secret", igor.Auth.Oidc.ClientSecret)
+		}
+		var resp struct {
+			DeviceCode              string `json:"device_code"`
+			UserCode                string `json:"user_code"`
+			VerificationURI         string `json:"verification_uri"`
+			VerificationURIComplete string `json:"verification_uri_complete"`
+			ExpiresIn               int    `json:"expires_in"`
+			Interval                int    `json:"interval"`
+			Error                   string `json:"error"`
+			ErrorDescription        string `json:"error_description"`
+		}
+		if err = oidcPostForm(provider.DeviceAuthorizationEndpoint, form, &resp); err != nil {
+			return nil, err
+		}
+		if resp.DeviceCode == "" {
+			return nil, fmt.Errorf("identity provider did not start a device login - %s %s", resp.Error, resp.ErrorDescription)
+		}
+		device := &common.OidcDeviceData{
+			DeviceCode:              resp.DeviceCode,
+			UserCode:                resp.UserCode,
+			VerificationURI:         resp.VerificationURI,
+			VerificationURIComplete: resp.VerificationURIComplete,
+			ExpiresIn:               resp.ExpiresIn,
+			Interval:                resp.Interval,
+		}
+		if device.Interval <= 0 {
+			device.Interval = 5
+		}
+		return device, nil
+	}()
+
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+		makeJsonResponse(w, status, rb)
+		return
+	}
+
+	rb.Data["device"] = device
+	makeJsonResponse(w, http.StatusOK, rb)
+}
+
+// oidcDeviceTokenHandler checks whether the user has finished a device code login. Until they do it
+// responds 202 Accepted. Once they have, the user gets an igor session.
+func oidcDeviceTokenHandler(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "oidc device login"
+	rb := common.NewResponseBody()
+	body := getBodyFromContext(r)
+	deviceCode, _ := body["deviceCode"].(string)
+
+	status := http.StatusUnauthorized
+	user, err := func() (*User, error) {
+		if deviceCode == "" {
+			status = http.StatusBadRequest
+			return nil, NewMissingParamError("deviceCode")
+		}
+		provider, err := getOidcProvider()
+		if err != nil {
+			status = http.StatusBadGateway
+			return nil, err
+		}
+		claims, oauthErr, err := provider.requestToken(url.Values{"grant_type": {oidcDeviceGrant}, "device_code": {deviceCode}})
+		switch oauthErr {
+		case "authorization_pending", "slow_down":
+			status = http.StatusAccepted
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return oidcUser(claims, r)
+	}()
+
+	if status == http.StatusAccepted {
+		rb.Message = "waiting for the user to finish signing in"
+		makeJsonResponse(w, status, rb)
+		return
+	}
+
+	if err == nil {
+		igor.ElevateMap.Remove(user.Name)
+		if err = startAuthSession(w, user, r); err != nil {
+			status = http.StatusInternalServerError
+		}
+	}
+
+	var bcErr *BadCredentialsError
+	if err != nil && !errors.As(err, &bcErr) && status == http.StatusUnauthorized {
+		status = http.StatusInternalServerError
+	}
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+		makeJsonResponse(w, status, rb)
+		return
+	}
+
+	clog.Info().Msgf("%s success - '%s' signed in", actionPrefix, user.Name)
+	rb.Data["user"] = user.Name
+	makeJsonResponse(w, http.StatusOK, rb)
+}
+
+// oidcRandom returns a random value for a login state or PKCE verifier
+func oidcRandom() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func oidcGetJson(target string, v interface{}) error {
+	resp, err := oidcHttpClient.Get(target)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// oidcPostForm posts a form to a provider endpoint and decodes the JSON response. OAuth error
+// responses are decoded too so the caller can see the error code.
+func oidcPostForm(target string, form url.Values, v interface{}) error {
+	resp, err := oidcHttpClient.PostForm(target, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusUnauthorized {
+		return fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("unable to read response from %s - %v", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOidcProvider serves discovery, a key set and a token endpoint that answers with tokenResp.
+type fakeOidcProvider struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	tokenResp map[string]interface{}
+}
+
+func newFakeOidcProvider(t *testing.T) *fakeOidcProvider {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p := &fakeOidcProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                        p.URL,
+			"authorization_endpoint":        p.URL + "/auth",
+			"token_endpoint":                p.URL + "/token",
+			"device_authorization_endpoint": p.URL + "/device",
+			"jwks_uri":                      p.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "k1", "kty": "RSA", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if _, isErr := p.tokenResp["error"]; isErr {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_ = json.NewEncoder(w).Encode(p.tokenResp)
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	origOidc, origProvider := igor.Auth.Oidc, oidcProvider
+	t.Cleanup(func() { igor.Auth.Oidc, oidcProvider = origOidc, origProvider })
+	igor.Auth.Oidc.IssuerURL = p.URL
+	igor.Auth.Oidc.ClientID = "igor"
+	igor.Auth.Oidc.UsernameClaim = DefaultOidcUsernameClaim
+	igor.Auth.Oidc.EmailClaim = DefaultOidcEmailClaim
+	igor.Auth.Oidc.FullNameClaim = DefaultOidcFullNameClaim
+	oidcProvider = nil
+
+	return p
+}
+
+// idToken signs an ID token for the user with the given issuer and audience.
+func (p *fakeOidcProvider) idToken(t *testing.T, kid, issuer, audience, username string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                issuer,
+		"aud":                audience,
+		"exp":                time.Now().Add(time.Minute).Unix(),
+		"preferred_username": username,
+	})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(p.key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestOidcVerifyIDToken(t *testing.T) {
+
+	p := newFakeOidcProvider(t)
+	provider, err := getOidcProvider()
+	require.NoError(t, err)
+	assert.Equal(t, p.URL+"/token", provider.TokenEndpoint)
+
+	claims, err := provider.verifyIDToken(p.idToken(t, "k1", p.URL, "igor", "alice"))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims["preferred_username"])
+
+	var bcErr *BadCredentialsError
+	for name, token := range map[string]string{
+		"other audience": p.idToken(t, "k1", p.URL, "other", "alice"),
+		"other issuer":   p.idToken(t, "k1", "https://evil.example.com", "igor", "alice"),
+		"unknown key":    p.idToken(t, "k2", p.URL, "igor", "alice"),
+		"not a token":    "abc.def.ghi",
+	} {
+		_, err = provider.verifyIDToken(token)
+		assert.True(t, errors.As(err, &bcErr), name)
+	}
+}
+
+func TestOidcUserRefusesIgorAdmin(t *testing.T) {
+
+	newFakeOidcProvider(t)
+	r := httptest.NewRequest(http.MethodGet, "/login/oidc/callback", nil)
+
+	var bcErr *BadCredentialsError
+	_, err := oidcUser(jwt.MapClaims{"preferred_username": IgorAdmin}, r)
+	assert.True(t, errors.As(err, &bcErr))
+	_, err = oidcUser(jwt.MapClaims{"sub": "1234"}, r)
+	assert.True(t, errors.As(err, &bcErr))
+
+	// igor-admin's password is never checked by the oidc scheme
+	_, err = NewOidcAuth().authenticate(r)
+	assert.True(t, errors.As(err, &bcErr))
+}
+
+func TestOidcDeviceToken(t *testing.T) {
+
+	p := newFakeOidcProvider(t)
+
+	post := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/login/oidc/device/token", nil)
+		r = r.WithContext(context.WithValue(r.Context(), jsonBodyKey{}, body))
+		w := httptest.NewRecorder()
+		oidcDeviceTokenHandler(w, r)
+		return w
+	}
+
+	p.tokenResp = map[string]interface{}{"error": "authorization_pending"}
+	assert.Equal(t, http.StatusAccepted, post(map[string]interface{}{"deviceCode": "dc1"}).Code)
+
+	p.tokenResp = map[string]interface{}{"error": "expired_token", "error_description": "code expired"}
+	assert.Equal(t, http.StatusUnauthorized, post(map[string]interface{}{"deviceCode": "dc1"}).Code)
+
+	p.tokenResp = map[string]interface{}{"id_token": p.idToken(t, "k1", p.URL, "igor", IgorAdmin)}
+	assert.Equal(t, http.StatusUnauthorized, post(map[string]interface{}{"deviceCode": "dc1"}).Code)
+
+	assert.Equal(t, http.StatusBadRequest, post(map[string]interface{}{}).Code)
+}
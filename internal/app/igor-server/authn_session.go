@@ -0,0 +1,316 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+const (
+	// authSessionTouchInterval is how stale the last used time of a session can get before a request
+	// using its token updates it, so a busy client doesn't write to the database on every request
+	authSessionTouchInterval = time.Minute
+	// SessionSourceCli and SessionSourceWeb name the client a login token was issued to
+	SessionSourceCli = "cli"
+	SessionSourceWeb = "web"
+)
+
+// AuthSession records a login token issued by the server. A token is only accepted while its session
+// exists, so deleting the session revokes the token on the next request that uses it. Sessions are
+// removed by the reservation manager once their token expires.
+type AuthSession struct {
+	Base
+	SessionID string `gorm:"unique; notNull"`
+	UserID    int    `gorm:"notNull; index"`
+	User      User
+	Expires   time.Time
+	LastUsed  time.Time
+	// Source is the client the token was issued to and Address is where the login came from
+	Source  string
+	Address string
+}
+
+func (s *AuthSession) getAuthSessionData() common.AuthSessionData {
+	return common.AuthSessionData{
+		ID:       s.SessionID,
+		User:     s.User.Name,
+		Issued:   s.CreatedAt.Unix(),
+		Expires:  s.Expires.Unix(),
+		LastUsed: s.LastUsed.Unix(),
+		Source:   s.Source,
+		Address:  s.Address,
+	}
+}
+
+// newAuthSession records a new login session for the user whose token expires at the given time.
+func newAuthSession(user *User, expires time.Time, r *http.Request) (*AuthSession, error) {
+
+	sessionID, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	source := SessionSourceWeb
+	if strings.HasPrefix(r.UserAgent(), IgorCliPrefix) {
+		source = SessionSourceCli
+	}
+
+	now := time.Now()
+	session := &AuthSession{
+		SessionID: sessionID,
+		UserID:    user.ID,
+		Expires:   expires,
+		LastUsed:  now,
+		Source:    source,
+		Address:   requestAddress(r),
+	}
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+		return tx.Omit("User").Create(session).Error
+	}); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// checkAuthSession returns an error if the login token with the given claims no longer has a session,
+// meaning it was revoked or its session was purged. The last used time of the session is updated if it
+// is older than authSessionTouchInterval.
+func checkAuthSession(claims *MyClaims, now time.Time) error {
+
+	if claims.ID == "" {
+		return &BadCredentialsError{msg: "token has no session, please log in again"}
+	}
+
+	return performDbTx(func(tx *gorm.DB) error {
+
+		var sessions []AuthSession
+		if err := tx.Preload("User").Where("session_id = ?", claims.ID).Find(&sessions).Error; err != nil {
+			return err
+		}
+		if len(sessions) == 0 || sessions[0].User.Name != claims.Username {
+			return &BadCredentialsError{msg: "token has been revoked or its session has ended"}
+		}
+
+		if s := &sessions[0]; now.Sub(s.LastUsed) >= authSessionTouchInterval {
+			return tx.Model(s).UpdateColumn("last_used", now).Error
+		}
+		return nil
+	})
+}
+
+// doReadAuthSessions returns the sessions of unexpired login tokens, newest first. If userName is not
+// blank only that user's sessions are returned.
+func doReadAuthSessions(userName string) (sessions []AuthSession, status int, err error) {
+
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+		if userName != "" {
+			uList, guStatus, guErr := getUsers([]string{userName}, true, tx)
+			if guErr != nil {
+				status = guStatus
+				return guErr
+			}
+			tx = tx.Where("user_id = ?", uList[0].ID)
+		}
+		return tx.Preload("User").Where("expires > ?", time.Now()).Order("created_at desc").Find(&sessions).Error
+	}); err != nil {
+		return
+	}
+
+	status = http.StatusOK
+	return
+}
+
+// doRevokeAuthSessions deletes the login session with the given ID, or every session of the named user
+// if sessionID is blank. Revoking all of a user's sessions also ends any elevation they hold.
+func doRevokeAuthSessions(sessionID, userName string) (msg string, status int, err error) {
+
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+	var revoked int64
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		if sessionID != "" {
+			result := tx.Where("session_id = ?", sessionID).Delete(&AuthSession{})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				status = http.StatusNotFound
+				return fmt.Errorf("no session found with ID '%s'", sessionID)
+			}
+			revoked = result.RowsAffected
+			return nil
+		}
+
+		uList, guStatus, guErr := getUsers([]string{userName}, true, tx)
+		if guErr != nil {
+			status = guStatus
+			return guErr
+		}
+		result := tx.Where("user_id = ?", uList[0].ID).Delete(&AuthSession{})
+		revoked = result.RowsAffected
+		return result.Error
+
+	}); err != nil {
+		return
+	}
+
+	status = http.StatusOK
+	if sessionID != "" {
+		msg = fmt.Sprintf("session '%s' revoked", sessionID)
+	} else {
+		igor.ElevateMap.Remove(userName)
+		msg = fmt.Sprintf("%d session(s) of user '%s' revoked", revoked, userName)
+	}
+	return
+}
+
+// purgeAuthSessions removes the sessions of login tokens that have expired along with lapsed
+// elevation records. It runs as part of the reservation manager.
+func purgeAuthSessions(checkTime *time.Time) error {
+
+	if cleared := igor.ElevateMap.ClearExpired(); cleared > 0 {
+		logger.Debug().Msgf("removed %d lapsed elevation record(s)", cleared)
+	}
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	var purged int64
+	if err := performDbTx(func(tx *gorm.DB) error {
+		result := tx.Where("expires <= ?", *checkTime).Delete(&AuthSession{})
+		purged = result.RowsAffected
+		return result.Error
+	}); err != nil {
+		return fmt.Errorf("problem removing expired login sessions: %v", err)
+	}
+
+	if purged > 0 {
+		logger.Debug().Msgf("removed %d expired login session(s)", purged)
+	}
+	return nil
+}
+
+// newSessionID returns a random ID for a login session, short enough for an admin to type when revoking it.
+func newSessionID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// requestAddress returns the address of the client that sent the request, preferring the first
+// address in X-Forwarded-For when the server is behind a proxy.
+func requestAddress(r *http.Request) string {
+	if fIPList := r.Header.Get(common.XForwardedFor); fIPList != "" {
+		return strings.TrimSpace(strings.Split(fIPList, ",")[0])
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// destination for GET /admin/sessions
+func handleReadAuthSessions(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "read sessions"
+	rb := common.NewResponseBodySessions()
+
+	sessions, status, err := doReadAuthSessions(r.URL.Query().Get("user"))
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		sessionData := make([]common.AuthSessionData, 0, len(sessions))
+		for i := range sessions {
+			sessionData = append(sessionData, sessions[i].getAuthSessionData())
+		}
+		rb.Data["sessions"] = sessionData
+		clog.Debug().Msgf("%s success", actionPrefix)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// destination for DELETE /admin/sessions
+func handleRevokeAuthSessions(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "revoke sessions"
+	rb := common.NewResponseBody()
+
+	queryParams := r.URL.Query()
+	msg, status, err := doRevokeAuthSessions(queryParams.Get("id"), queryParams.Get("user"))
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Message = msg
+		clog.Info().Msgf("%s success - %s", actionPrefix, msg)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+func validateAuthSessionParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+		queryParams := r.URL.Query()
+
+	paramLoop:
+		for key, val := range queryParams {
+			switch key {
+			case "user":
+				if len(val) != 1 || strings.TrimSpace(val[0]) == "" {
+					validateErr = NewBadParamTypeError(key, val, "username")
+					break paramLoop
+				}
+			case "id":
+				if r.Method != http.MethodDelete {
+					validateErr = NewUnknownParamError(key, val)
+					break paramLoop
+				}
+				if b, err := hex.DecodeString(val[0]); len(val) != 1 || err != nil || len(b) != 8 {
+					validateErr = NewBadParamTypeError(key, val, "session ID")
+					break paramLoop
+				}
+			default:
+				validateErr = NewUnknownParamError(key, val)
+				break paramLoop
+			}
+		}
+
+		if validateErr == nil && r.Method == http.MethodDelete {
+			if queryParams.Has("id") == queryParams.Has("user") {
+				validateErr = fmt.Errorf("revoking sessions requires either a session id or a user, but not both")
+			}
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateAuthSessionParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestAuthSessions(t *testing.T) {
+
+	seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	alice := User{Name: "alice", Email: "alice@example.com"}
+	bob := User{Name: "bob", Email: "bob@example.com"}
+	for _, u := range []*User{&alice, &bob} {
+		require.NoError(t, db.Omit(clause.Associations).Create(u).Error)
+	}
+
+	now := time.Now()
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	r.Header.Set("User-Agent", IgorCliPrefix+"/2.0")
+	r.RemoteAddr = "10.0.0.5:40000"
+
+	aliceCli, err := newAuthSession(&alice, now.Add(time.Hour), r)
+	require.NoError(t, err)
+	assert.Equal(t, SessionSourceCli, aliceCli.Source)
+	assert.Equal(t, "10.0.0.5", aliceCli.Address)
+
+	r.Header.Set("User-Agent", "Mozilla/5.0")
+	r.Header.Set(common.XForwardedFor, "192.168.1.9, 10.0.0.1")
+	aliceWeb, err := newAuthSession(&alice, now.Add(time.Hour), r)
+	require.NoError(t, err)
+	assert.Equal(t, SessionSourceWeb, aliceWeb.Source)
+	assert.Equal(t, "192.168.1.9", aliceWeb.Address)
+
+	bobSession, err := newAuthSession(&bob, now.Add(time.Hour), r)
+	require.NoError(t, err)
+
+	claims := func(user string, s *AuthSession) *MyClaims {
+		c := &MyClaims{Username: user}
+		c.ID = s.SessionID
+		return c
+	}
+
+	assert.NoError(t, checkAuthSession(claims("alice", aliceCli), now))
+	assert.Error(t, checkAuthSession(claims("bob", aliceCli), now), "session belongs to another user")
+	assert.Error(t, checkAuthSession(&MyClaims{Username: "alice"}, now), "token without a session")
+
+	// use of a token is recorded once it's been idle long enough
+	later := now.Add(2 * authSessionTouchInterval)
+	require.NoError(t, checkAuthSession(claims("alice", aliceCli), later))
+	var stored AuthSession
+	require.NoError(t, db.Where("session_id = ?", aliceCli.SessionID).First(&stored).Error)
+	assert.WithinDuration(t, later, stored.LastUsed, time.Second)
+
+	sessions, _, err := doReadAuthSessions("alice")
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "alice", sessions[0].getAuthSessionData().User)
+
+	_, status, err := doReadAuthSessions("nobody")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// revoking one session leaves the user's other sessions working
+	_, _, err = doRevokeAuthSessions(aliceCli.SessionID, "")
+	require.NoError(t, err)
+	assert.Error(t, checkAuthSession(claims("alice", aliceCli), now))
+	assert.NoError(t, checkAuthSession(claims("alice", aliceWeb), now))
+
+	_, status, err = doRevokeAuthSessions(aliceCli.SessionID, "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// revoking all of a user's sessions also ends their elevation
+	igor.ElevateMap.Put("alice", elevate)
+	msg, _, err := doRevokeAuthSessions("", "alice")
+	require.NoError(t, err)
+	assert.Contains(t, msg, "1 session(s)")
+	assert.Error(t, checkAuthSession(claims("alice", aliceWeb), now))
+	assert.False(t, igor.ElevateMap.Contains("alice"))
+	assert.NoError(t, checkAuthSession(claims("bob", bobSession), now))
+
+	// only expired sessions are purged
+	expired, err := newAuthSession(&bob, now.Add(-time.Minute), r)
+	require.NoError(t, err)
+	require.NoError(t, purgeAuthSessions(&now))
+	var left []AuthSession
+	require.NoError(t, db.Find(&left).Error)
+	require.Len(t, left, 1)
+	assert.Equal(t, bobSession.SessionID, left[0].SessionID)
+	assert.NotEqual(t, expired.SessionID, left[0].SessionID)
+}
@@ -18,6 +18,7 @@ import (
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
 )
 
 // TokenAuth implements IAuth interface
@@ -77,6 +78,12 @@ func (l *TokenAuth) authenticate(r *http.Request) (*User, error) {
 		return nil, &BadCredentialsError{msg: errLine}
 	}
 
+	// verify the token hasn't been revoked
+	if err = checkAuthSession(claims, time.Now()); err != nil {
+		clog.Warn().Msgf("%s failed - %v", actionPrefix, err)
+		return nil, err
+	}
+
 	// verify Igor knows the user
 	user, err := findUserForAuthN(claims.Username)
 	if err != nil {
@@ -97,12 +104,15 @@ func acquireTokenSecret(token *jwt.Token) (interface{}, error) {
 	return getJwtToken()
 }
 
-func generateToken(username string, exprTime time.Time) (tokenString string, err error) {
+// generateToken returns a signed login token for the user. The session ID is carried as the
+// token ID so the token can be revoked by deleting its session.
+func generateToken(username, sessionID string, exprTime time.Time) (tokenString string, err error) {
 
 	// set token expiration
 	claims := &MyClaims{
 		Username: username,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
 			ExpiresAt: jwt.NewNumericDate(exprTime),
 		},
 	}
@@ -206,7 +216,7 @@ func generateSecret() ([]byte, error) {
 }
 
 // handleResetToken allows admin to generate a new jwt secret key
-// will invalidate all existing authn jwt Tokens
+// will invalidate all existing authn jwt Tokens and remove their sessions
 func handleResetToken(w http.ResponseWriter, r *http.Request) {
 	clog := hlog.FromRequest(r)
 	actionPrefix := "reset JWT secret"
@@ -222,6 +232,13 @@ func handleResetToken(w http.ResponseWriter, r *http.Request) {
 		err = verifyJwtSecret()
 	}
 
+	if err == nil {
+		// no token signed with the old secret can be used again
+		err = performDbTx(func(tx *gorm.DB) error {
+			return tx.Where("1 = 1").Delete(&AuthSession{}).Error
+		})
+	}
+
 	if err != nil {
 		rb.Message = err.Error()
 		status = http.StatusInternalServerError
@@ -61,21 +61,52 @@ func authzHandler(handler http.Handler) http.Handler {
 			return
 		}
 
+		// VLAN allocations are part of the reservation info everyone can view
+		if r.Method == http.MethodGet && r.URL.Path == api.Vlans {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		// named networks belong to groups, membership is checked by the handler
+		if strings.HasPrefix(r.URL.Path, api.VlanNetworks) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		// availability is limited to what the user could book, checked by the handler
+		if r.Method == http.MethodGet && r.URL.Path == api.Availability {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
 		if r.Method == http.MethodGet && r.URL.Path == api.HostPolicy {
 			handler.ServeHTTP(w, r)
 			return
 		}
 
 		if r.URL.Path == api.HostsBlock {
-			// this perm won't match anything assigned to users so will fail, but will pass
-			// the admin permission of '*'
-			p, _ := NewPermission("host-block")
-			if authInfo.IsPermitted(p) {
-				handler.ServeHTTP(w, r)
-			} else {
-				rb.Message = "block/unblock hosts requires admin elevated privilege"
-				makeJsonResponse(w, http.StatusForbidden, rb)
-			}
+			requireAdminOnly(w, r, handler, authInfo, "host-block", "block/unblock hosts requires admin elevated privilege")
+			return
+		}
+
+		if r.URL.Path == api.HostsDrain {
+			requireAdminOnly(w, r, handler, authInfo, "host-drain", "drain/undrain hosts requires admin elevated privilege")
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, api.ReservationsBulk) {
+			requireAdminOnly(w, r, handler, authInfo, "res-bulk", "bulk reservation changes require admin elevated privilege")
+			return
+		}
+
+		if r.URL.Path == api.HostsExplain {
+			requireAdminOnly(w, r, handler, authInfo, "host-explain", "explaining host access requires admin elevated privilege")
+			return
+		}
+
+		// host history is open to reservation owners when the server allows it, checked by the handler
+		if r.Method == http.MethodGet && r.URL.Path == api.HostsHistory {
+			handler.ServeHTTP(w, r)
 			return
 		}
 
@@ -201,12 +232,14 @@ func authzHandler(handler http.Handler) http.Handler {
 
 				if groupSliceContains(user.Groups, GroupAdmins) {
 					rb.Message = "elevated access is required before running this command"
+					rb.ErrorCode = common.ErrElevateRequired
 				} else {
 					rb.Message = fmt.Sprintf("you cannot access the %s '%s'", resourceType, resourceName)
 				}
 			} else {
 				if groupSliceContains(user.Groups, GroupAdmins) {
 					rb.Message = "elevated access is required before running this command"
+					rb.ErrorCode = common.ErrElevateRequired
 				} else {
 					rb.Message = "access denied"
 				}
@@ -217,6 +250,22 @@ func authzHandler(handler http.Handler) http.Handler {
 	})
 }
 
+// requireAdminOnly passes the request to handler only if the user holds the admin permission. The
+// permission named by permName is never assigned to users, so the only permission that matches it
+// is the admin wildcard '*', which a user has only while elevated. Otherwise the request is refused
+// with msg.
+func requireAdminOnly(w http.ResponseWriter, r *http.Request, handler http.Handler, authInfo *UserAuthInfo, permName, msg string) {
+	p, _ := NewPermission(permName)
+	if authInfo.IsPermitted(p) {
+		handler.ServeHTTP(w, r)
+		return
+	}
+	rb := common.NewResponseBody()
+	rb.Message = msg
+	rb.ErrorCode = common.ErrElevateRequired
+	makeJsonResponse(w, http.StatusForbidden, rb)
+}
+
 // getEditPart generates the list of fields for a resource that a request is asking
 // permission to edit. Note that some types of resources have fields that can be
 // edited (call PATCH) by more than just the owner.
@@ -231,6 +280,9 @@ func getEditPart(r *http.Request, resource string) (editPart string) {
 			switch k {
 			case "password", "email", "reset", "fullName":
 				attrs = append(attrs, k)
+			case "defaultGroup", "locale", "notifyDelegate", "notifyPrefs", "defaultKernelArgs":
+				// a personal preference covered by the same permission as the user's name
+				attrs = append(attrs, "fullName")
 			default:
 				continue
 			}
@@ -242,10 +294,48 @@ func getEditPart(r *http.Request, resource string) (editPart string) {
 		attrs := make([]string, 0, len(body))
 		for k := range body {
 			switch k {
-			case "group", "owner", "distro", "profile", "extend", "name", "description", "kernelArgs", "drop":
+			case "group", "owner", "extend", "name", "description", "kernelArgs", "drop":
 				attrs = append(attrs, k)
-			case "extendMax":
+			case "distro", "profile":
+				// a reimage only changes the reservation's image when asked to keep it
+				if _, reimage := body["reimage"]; reimage && body["persist"] != true {
+					continue
+				}
+				attrs = append(attrs, k)
+			case "reimage":
+				// any member of the reservation's group can ask to reimage, the handler checks their node action permission
+				attrs = append(attrs, "extend")
+			case "extendMax", "keep", "endPower":
+				attrs = append(attrs, "extend")
+			case "notifyAlso":
+				// who else is sent the reservation's email is up to those who can describe it
+				attrs = append(attrs, "description")
+			case "head":
+				// naming the head host only changes how the reservation is described to its users
+				attrs = append(attrs, "description")
+			case "data":
+				// the data its nodes fetch is set by those who can describe the reservation
+				attrs = append(attrs, "description")
+			case "pause", "resume", "substitute":
+				// pausing releases the reservation's nodes so it requires the same access as dropping them
+				attrs = append(attrs, "drop")
+			case "claim":
+				// any member of the reservation's group can ask to claim it, the handler decides if they may
+				attrs = append(attrs, "extend")
+			case "approve", "deny":
+				// only an elevated admin can approve or deny a reservation, which the handler checks
 				attrs = append(attrs, "extend")
+			case "addCoOwners", "rmvCoOwners":
+				attrs = append(attrs, "coOwners")
+			case "keepCoOwners", "share", "revokeShare":
+				// only the owner can hand out or take back access to the reservation
+				attrs = append(attrs, "owner")
+			case "vlan":
+				// the VLAN is checked against what the owner can use, so only the owner can change it
+				attrs = append(attrs, "owner")
+			case "netProfile":
+				// only an elevated admin can set the network profile, which the handler checks
+				attrs = append(attrs, "owner")
 			default:
 				continue
 			}
@@ -0,0 +1,369 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	zl "github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+const (
+	// DefaultAvailabilityDays is the span of time reported when no end is given
+	DefaultAvailabilityDays = 14
+	// MaxAvailabilityBuckets limits how finely a span of time can be split
+	MaxAvailabilityBuckets = 1500
+	// availabilityCacheTTL is how long a result is reused since the web calendar polls for it
+	availabilityCacheTTL = 30 * time.Second
+)
+
+// host kinds used when counting node-hours
+const (
+	availOpen       = "open"
+	availBlocked    = "blocked"
+	availRestricted = "restricted"
+)
+
+var availabilityCache = common.NewPassiveTtlMap(availabilityCacheTTL)
+
+// destination for route GET /availability
+func handleReadAvailability(w http.ResponseWriter, r *http.Request) {
+
+	queryMap := r.URL.Query()
+	clog := hlog.FromRequest(r)
+	actionPrefix := "read availability"
+	rb := common.NewResponseBodyAvailability()
+
+	var avail *common.AvailabilityData
+	start, end, bucket, status, err := parseAvailabilityParams(queryMap, time.Now())
+	if err == nil {
+		avail, status, err = doReadAvailability(getUserFromContext(r), queryMap.Get("group"), start, end, bucket, clog)
+	}
+
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["availability"] = *avail
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// parseAvailabilityParams returns the span of time to report on and the size of its buckets. The span
+// starts at the beginning of the current day and lasts DefaultAvailabilityDays unless given, and is cut
+// short at the end of the scheduling window.
+func parseAvailabilityParams(queryMap url.Values, now time.Time) (start, end time.Time, bucket time.Duration, status int, err error) {
+
+	y, m, d := now.Date()
+	start = time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	if val := queryMap.Get("start"); val != "" {
+		ts, _ := strconv.ParseInt(val, 10, 64)
+		start = time.Unix(ts, 0)
+	}
+
+	end = start.AddDate(0, 0, DefaultAvailabilityDays)
+	if val := queryMap.Get("end"); val != "" {
+		ts, _ := strconv.ParseInt(val, 10, 64)
+		end = time.Unix(ts, 0)
+	}
+	if schedEnd := now.AddDate(0, 0, igor.Scheduler.MaxScheduleDays); end.After(schedEnd) {
+		end = schedEnd
+	}
+	if !end.After(start) {
+		return start, end, 0, http.StatusBadRequest, fmt.Errorf("end of the availability window must be after its start and within the scheduling window")
+	}
+
+	bucket = 24 * time.Hour
+	if val := queryMap.Get("bucket"); val != "" {
+		bucket, _ = common.ParseDuration(val)
+	}
+	if bucket < time.Hour {
+		return start, end, 0, http.StatusBadRequest, fmt.Errorf("availability bucket size must be at least 1h")
+	}
+	if n := end.Sub(start) / bucket; n > MaxAvailabilityBuckets {
+		return start, end, 0, http.StatusBadRequest, fmt.Errorf("availability window would have %d buckets, the most allowed is %d -- use a larger bucket size", n, MaxAvailabilityBuckets)
+	}
+
+	return start, end, bucket, http.StatusOK, nil
+}
+
+// availabilityBounds splits the span of time into buckets and returns their boundaries. Buckets that are
+// whole days step by calendar day so they stay aligned to midnight across daylight savings changes. The
+// last bucket ends at the end of the span.
+func availabilityBounds(start, end time.Time, bucket time.Duration) []time.Time {
+
+	bounds := []time.Time{start}
+	days := 0
+	if bucket%(24*time.Hour) == 0 {
+		days = int(bucket / (24 * time.Hour))
+	}
+
+	for b := start; b.Before(end); {
+		if days > 0 {
+			b = b.AddDate(0, 0, days)
+		} else {
+			b = b.Add(bucket)
+		}
+		if b.After(end) {
+			b = end
+		}
+		bounds = append(bounds, b)
+	}
+
+	return bounds
+}
+
+// doReadAvailability counts the node-hours in each bucket that are free, reserved, blocked or restricted
+// by host policy for a user making a reservation with the given group. Access is decided the same way the
+// scheduler decides it: hosts are open if their policy allows the 'all' group or the reservation's group.
+// Results are cached briefly for each access list and span of time.
+func doReadAvailability(user *User, groupName string, start, end time.Time, bucket time.Duration, clog *zl.Logger) (*common.AvailabilityData, int, error) {
+
+	groupAccessList := []string{GroupAll}
+	if groupName != "" && groupName != GroupAll && !strings.HasPrefix(groupName, GroupUserPrefix) {
+		groups, status, err := getGroupsTx([]string{groupName}, true)
+		if err != nil {
+			return nil, status, err
+		}
+		if !user.isMemberOfGroup(&groups[0]) && !userElevated(user.Name) {
+			return nil, http.StatusForbidden, newCodedError(common.ErrNotGroupMember, "user is not a member of group '%s'", groupName)
+		}
+		groupAccessList = append(groupAccessList, groupName)
+	}
+
+	cacheKey := fmt.Sprintf("%s|%d|%d|%d", strings.Join(groupAccessList, ","), start.Unix(), end.Unix(), bucket)
+	if cached, ok := availabilityCache.Get(cacheKey).(*common.AvailabilityData); ok {
+		return cached, http.StatusOK, nil
+	}
+
+	now := time.Now()
+	avail := &common.AvailabilityData{
+		Group:       groupAccessList[len(groupAccessList)-1],
+		Start:       start.Unix(),
+		End:         end.Unix(),
+		BucketSize:  common.FormatDuration(bucket, false),
+		GeneratedAt: now.Unix(),
+	}
+
+	if err := performDbTx(func(tx *gorm.DB) error {
+		var err error
+		avail.Hosts, avail.Buckets, err = dbCountAvailability(groupAccessList, availabilityBounds(start, end, bucket), now, tx, clog)
+		return err
+	}); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	availabilityCache.Put(cacheKey, avail)
+	return avail, http.StatusOK, nil
+}
+
+// availCount is a row of the host and reservation queries made by dbCountAvailability
+type availCount struct {
+	Bucket   int
+	PolicyID int
+	Kind     string
+	Hosts    int
+	Seconds  int64
+}
+
+// dbCountAvailability counts the node-hours of each bucket between the given boundaries. The hosts of
+// each policy and kind and the reserved seconds on them in each bucket are summed by the db. Unreserved
+// time on open hosts during a policy's unavailability periods is counted as restricted.
+func dbCountAvailability(groupAccessList []string, bounds []time.Time, now time.Time, tx *gorm.DB, clog *zl.Logger) (int, []common.AvailabilityBucket, error) {
+
+	kindExpr := "CASE WHEN h.state NOT IN ? THEN '" + availBlocked + "' " +
+		"WHEN h.host_policy_id IN (SELECT gp.host_policy_id FROM groups_policies gp JOIN groups g ON g.id = gp.group_id WHERE g.name IN ?) " +
+		"AND h.host_policy_id NOT IN (SELECT ge.host_policy_id FROM groups_policies_excluded ge JOIN groups g ON g.id = ge.group_id WHERE g.name IN ?) THEN '" + availOpen + "' " +
+		"ELSE '" + availRestricted + "' END"
+
+	var hostCounts []availCount
+	if result := tx.Raw("SELECT h.host_policy_id AS policy_id, "+kindExpr+" AS kind, COUNT(*) AS hosts FROM hosts h GROUP BY policy_id, kind",
+		schedulableHostStates, groupAccessList, groupAccessList).Scan(&hostCounts); result.Error != nil {
+		return 0, nil, result.Error
+	}
+
+	// only the part of each bucket that hasn't passed yet is counted
+	from := make([]time.Time, len(bounds)-1)
+	values := make([]string, len(from))
+	args := make([]interface{}, 0, len(from)*3+2)
+	for i := range from {
+		from[i] = bounds[i]
+		if from[i].Before(now) {
+			from[i] = now
+		}
+		if from[i].After(bounds[i+1]) {
+			from[i] = bounds[i+1]
+		}
+		values[i] = "(?, ?, ?)"
+		args = append(args, i, from[i].Unix(), bounds[i+1].Unix())
+	}
+	args = append(args, schedulableHostStates, groupAccessList, groupAccessList)
+
+	var resCounts []availCount
+	resStart := "CAST(strftime('%s', r.start) AS INTEGER)"
+	resEnd := "CAST(strftime('%s', r.reset_end) AS INTEGER)"
+	query := "WITH buckets(idx, b_start, b_end) AS (VALUES " + strings.Join(values, ", ") + ") " +
+		"SELECT b.idx AS bucket, h.host_policy_id AS policy_id, " + kindExpr + " AS kind, " +
+		"SUM(MIN(" + resEnd + ", b.b_end) - MAX(" + resStart + ", b.b_start)) AS seconds " +
+		"FROM buckets b " +
+		"JOIN reservations r ON " + resStart + " < b.b_end AND " + resEnd + " > b.b_start " +
+		"JOIN reservations_hosts rh ON rh.reservation_id = r.id " +
+		"JOIN hosts h ON h.id = rh.host_id " +
+		"WHERE r.start_error IS NULL OR r.start_error = '' " +
+		"GROUP BY b.idx, policy_id, kind"
+	if result := tx.Raw(query, args...).Scan(&resCounts); result.Error != nil {
+		return 0, nil, result.Error
+	}
+
+	type policyKind struct {
+		policyID int
+		kind     string
+	}
+	reserved := make([]map[policyKind]int64, len(from))
+	for i := range reserved {
+		reserved[i] = map[policyKind]int64{}
+	}
+	for _, rc := range resCounts {
+		reserved[rc.Bucket][policyKind{rc.PolicyID, rc.Kind}] += rc.Seconds
+	}
+
+	// unavailability periods only matter for policies with open hosts
+	unavailable := map[int]ScheduleBlockArray{}
+	policies, err := dbReadHostPolicies(map[string]interface{}{}, tx, clog)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, p := range policies {
+		if len(p.NotAvailable) > 0 {
+			unavailable[p.ID] = p.NotAvailable
+		}
+	}
+
+	totalHosts := 0
+	for _, hc := range hostCounts {
+		totalHosts += hc.Hosts
+	}
+
+	buckets := make([]common.AvailabilityBucket, len(from))
+	for i := range buckets {
+		length := bounds[i+1].Sub(from[i]).Seconds()
+		var free, res, blocked, restricted float64
+		for _, hc := range hostCounts {
+			capacity := float64(hc.Hosts) * length
+			used := math.Min(float64(reserved[i][policyKind{hc.PolicyID, hc.Kind}]), capacity)
+			idle := capacity - used
+			res += used
+			switch hc.Kind {
+			case availBlocked:
+				blocked += idle
+			case availRestricted:
+				restricted += idle
+			default:
+				blockedOff := float64(hc.Hosts) * scheduleBlockOverlap(unavailable[hc.PolicyID], from[i], bounds[i+1]).Seconds()
+				blockedOff = math.Min(blockedOff, idle)
+				restricted += blockedOff
+				free += idle - blockedOff
+			}
+		}
+		buckets[i] = common.AvailabilityBucket{
+			Start:               bounds[i].Unix(),
+			End:                 bounds[i+1].Unix(),
+			FreeNodeHours:       toNodeHours(free),
+			ReservedNodeHours:   toNodeHours(res),
+			BlockedNodeHours:    toNodeHours(blocked),
+			RestrictedNodeHours: toNodeHours(restricted),
+		}
+	}
+
+	return totalHosts, buckets, nil
+}
+
+// scheduleBlockOverlap returns how much of the given span of time falls within the schedule blocks.
+// Overlapping blocks are not merged so the result is capped at the length of the span.
+func scheduleBlockOverlap(sba ScheduleBlockArray, start, end time.Time) time.Duration {
+
+	var total time.Duration
+	for _, sb := range sba {
+		sbDuration, _ := common.ParseDuration(sb.Duration)
+		sbStart, err := parseSBInstance(sb.Start)
+		if err != nil || sbDuration <= 0 {
+			continue
+		}
+		for next := sbStart.Next(start.Add(-sbDuration)); next.Before(end); next = sbStart.Next(next) {
+			from, to := next, next.Add(sbDuration)
+			if from.Before(start) {
+				from = start
+			}
+			if to.After(end) {
+				to = end
+			}
+			if to.After(from) {
+				total += to.Sub(from)
+			}
+		}
+	}
+
+	if span := end.Sub(start); total > span {
+		total = span
+	}
+	return total
+}
+
+// toNodeHours converts node-seconds to node-hours rounded to the hundredth.
+func toNodeHours(seconds float64) float64 {
+	return math.Round(seconds/36) / 100
+}
+
+func validateAvailabilityParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+
+	queryParamLoop:
+		for key, vals := range r.URL.Query() {
+			if len(vals) > 1 {
+				validateErr = fmt.Errorf("parameter '%s' can only be given once", key)
+				break queryParamLoop
+			}
+			switch key {
+			case "start", "end":
+				if _, err := strconv.ParseInt(vals[0], 10, 64); err != nil {
+					validateErr = NewBadParamTypeError(key, vals[0], "unix timestamp")
+					break queryParamLoop
+				}
+			case "bucket":
+				if dur, err := common.ParseDuration(vals[0]); err != nil || dur <= 0 {
+					validateErr = NewBadParamTypeError(key, vals[0], "duration")
+					break queryParamLoop
+				}
+			case "group":
+				if validateErr = checkGroupNameRules(vals[0]); validateErr != nil {
+					break queryParamLoop
+				}
+			default:
+				validateErr = NewUnknownParamError(key, vals)
+				break queryParamLoop
+			}
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateAvailabilityParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestAvailabilityBounds(t *testing.T) {
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	bounds := availabilityBounds(start, start.AddDate(0, 0, 3), 24*time.Hour)
+	require.Len(t, bounds, 4)
+	assert.Equal(t, start.AddDate(0, 0, 2), bounds[2])
+
+	// the last bucket is cut short at the end of the window
+	bounds = availabilityBounds(start, start.Add(15*time.Hour), 6*time.Hour)
+	require.Len(t, bounds, 4)
+	assert.Equal(t, start.Add(15*time.Hour), bounds[3])
+}
+
+func TestParseAvailabilityParams(t *testing.T) {
+
+	origDays := igor.Scheduler.MaxScheduleDays
+	t.Cleanup(func() { igor.Scheduler.MaxScheduleDays = origDays })
+	igor.Scheduler.MaxScheduleDays = 30
+
+	now := time.Date(2024, 3, 1, 15, 30, 0, 0, time.Local)
+	start, end, bucket, _, err := parseAvailabilityParams(url.Values{}, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), start)
+	assert.Equal(t, start.AddDate(0, 0, DefaultAvailabilityDays), end)
+	assert.Equal(t, 24*time.Hour, bucket)
+
+	// the end is capped by the scheduling window
+	far := strconv.FormatInt(now.AddDate(1, 0, 0).Unix(), 10)
+	_, end, _, _, err = parseAvailabilityParams(url.Values{"end": {far}}, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 30), end)
+
+	_, _, _, status, err := parseAvailabilityParams(url.Values{"bucket": {"30m"}}, now)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	igor.Scheduler.MaxScheduleDays = 365
+	_, _, _, status, err = parseAvailabilityParams(url.Values{"end": {far}, "bucket": {"1h"}}, now)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestScheduleBlockOverlap(t *testing.T) {
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	daily := ScheduleBlockArray{{Start: "0 1 * * *", Duration: "2h"}}
+
+	assert.Equal(t, 2*time.Hour, scheduleBlockOverlap(daily, start, start.Add(24*time.Hour)))
+	assert.Equal(t, 4*time.Hour, scheduleBlockOverlap(daily, start, start.Add(48*time.Hour)))
+	assert.Equal(t, time.Hour, scheduleBlockOverlap(daily, start.Add(2*time.Hour), start.Add(6*time.Hour)))
+	assert.Equal(t, time.Duration(0), scheduleBlockOverlap(nil, start, start.Add(24*time.Hour)))
+}
+
+func TestCountAvailability(t *testing.T) {
+
+	availabilityCache.Clear()
+	t.Cleanup(availabilityCache.Clear)
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	// kn3 can only be reserved by members of team-a
+	team := Group{Name: "team-a"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&team).Error)
+	teamOnly := HostPolicy{Name: "team-only", MaxResTime: 72 * time.Hour}
+	require.NoError(t, db.Omit(clause.Associations).Create(&teamOnly).Error)
+	require.NoError(t, db.Model(&teamOnly).Association("AccessGroups").Append(&team))
+	kn3 := Host{Name: "kn3", HostName: "kn3", SequenceID: 3, Mac: "00:00:00:00:00:03", State: HostAvailable, HostPolicyID: teamOnly.ID}
+	require.NoError(t, db.Omit(clause.Associations).Create(&kn3).Error)
+
+	alice := User{Name: "alice", Groups: []Group{team}}
+	require.NoError(t, db.Omit("Groups.*").Create(&alice).Error)
+
+	now := time.Now()
+	res := Reservation{Name: "r1", Hash: "r1", OwnerID: alice.ID, Start: now.Add(time.Hour), End: now.Add(3 * time.Hour),
+		ResetEnd: now.Add(3 * time.Hour)}
+	require.NoError(t, db.Omit(clause.Associations).Create(&res).Error)
+	require.NoError(t, db.Model(&res).Association("Hosts").Append(&hosts[0]))
+	require.NoError(t, db.Model(&Host{}).Where("name = ?", "kn2").Update("state", HostBlocked).Error)
+
+	count := func(access []string) (int, []common.AvailabilityBucket) {
+		var total int
+		var buckets []common.AvailabilityBucket
+		require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+			var err error
+			total, buckets, err = dbCountAvailability(access, []time.Time{now, now.Add(24 * time.Hour)}, now, tx, &logger)
+			return err
+		}))
+		return total, buckets
+	}
+
+	total, buckets := count([]string{GroupAll})
+	assert.Equal(t, 3, total)
+	require.Len(t, buckets, 1)
+	assert.InDelta(t, 22, buckets[0].FreeNodeHours, 0.02)
+	assert.InDelta(t, 2, buckets[0].ReservedNodeHours, 0.02)
+	assert.InDelta(t, 24, buckets[0].BlockedNodeHours, 0.02)
+	assert.InDelta(t, 24, buckets[0].RestrictedNodeHours, 0.02)
+
+	// a reservation made with team-a could also use kn3
+	_, buckets = count([]string{GroupAll, team.Name})
+	assert.InDelta(t, 46, buckets[0].FreeNodeHours, 0.02)
+	assert.InDelta(t, 0, buckets[0].RestrictedNodeHours, 0.02)
+
+	// only members can see the availability of a group
+	_, status, err := doReadAvailability(&User{Name: "bob"}, team.Name, now, now.Add(24*time.Hour), 24*time.Hour, &logger)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	avail, _, err := doReadAvailability(&alice, team.Name, now, now.Add(24*time.Hour), 24*time.Hour, &logger)
+	require.NoError(t, err)
+	assert.Equal(t, team.Name, avail.Group)
+	assert.Equal(t, now.Unix(), avail.Buckets[0].Start)
+	cached, _, err := doReadAvailability(&alice, team.Name, now, now.Add(24*time.Hour), 24*time.Hour, &logger)
+	require.NoError(t, err)
+	assert.Same(t, avail, cached)
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// batchRun applies an operation to many items in one transaction and records the outcome of each. Each
+// item runs in its own savepoint so one that fails is undone without undoing the others. With failFast
+// the first failure is returned instead, which aborts the transaction so nothing is changed.
+type batchRun struct {
+	tx       *gorm.DB
+	failFast bool
+	results  []common.BatchItemResult
+}
+
+func newBatchRun(tx *gorm.DB, failFast bool) *batchRun {
+	return &batchRun{tx: tx, failFast: failFast}
+}
+
+// do runs fn for the named item.
+func (b *batchRun) do(name string, fn func() error) error {
+	savePoint := fmt.Sprintf("batch_item_%d", len(b.results))
+	b.tx.SavePoint(savePoint)
+	if err := fn(); err != nil {
+		b.tx.RollbackTo(savePoint)
+		return b.fail(name, err)
+	}
+	b.succeed(name, "")
+	return nil
+}
+
+// succeed records that the named item succeeded, with an optional message saying what was done.
+func (b *batchRun) succeed(name, message string) {
+	b.results = append(b.results, common.BatchItemResult{Name: name, Result: common.BatchItemOK, Message: message})
+}
+
+// fail records that the named item failed before any change was made for it.
+func (b *batchRun) fail(name string, err error) error {
+	if b.failFast {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	b.results = append(b.results, common.BatchItemResult{Name: name, Result: common.BatchItemFailed, Message: err.Error()})
+	return nil
+}
+
+// batchAbortStatus returns the response status for a batch operation stopped by a failed item.
+func batchAbortStatus(err error) int {
+	if info, ok := common.LookupErrorCode(errorCodeOf(err)); ok && info.Status >= http.StatusBadRequest {
+		return info.Status
+	}
+	return http.StatusConflict
+}
+
+// setBatchResults adds the outcome of each item to the response with a message counting those that
+// succeeded, described by doneVerb, and those that failed. If any failed the response carries the
+// ErrPartial code so clients can tell even though the request succeeded.
+func setBatchResults(rb *common.ResponseBodyBatch, results []common.BatchItemResult, doneVerb string) {
+	rb.Data["results"] = results
+	ok, failed := common.CountBatchResults(results)
+	rb.Message = fmt.Sprintf("%d %s, %d failed", ok, doneVerb, failed)
+	if failed > 0 {
+		rb.SetErrorCode(common.ErrPartial)
+	}
+}
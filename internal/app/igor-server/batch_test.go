@@ -0,0 +1,120 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestBlockHostsBatch(t *testing.T) {
+
+	seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, &User{Name: IgorAdmin}))
+
+	hostState := func(name string) HostState {
+		var h Host
+		require.NoError(t, db.Where("name = ?", name).First(&h).Error)
+		return h.State
+	}
+
+	results, status, err := doUpdateBlockHosts(true, false, []string{"kn1"}, "", r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []common.BatchItemResult{{Name: "kn1", Result: common.BatchItemOK}}, results)
+	assert.Equal(t, HostBlocked, hostState("kn1"))
+
+	// unblocking a host that isn't blocked or doesn't exist doesn't stop the others
+	results, status, err = doUpdateBlockHosts(false, false, []string{"kn1", "kn2", "kn9"}, "", r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	require.Len(t, results, 3)
+	assert.Equal(t, common.BatchItemOK, results[0].Result)
+	assert.Equal(t, common.BatchItemFailed, results[1].Result)
+	assert.Contains(t, results[1].Message, "non-blocked")
+	assert.Equal(t, common.BatchItemFailed, results[2].Result)
+	assert.Contains(t, results[2].Message, "not found")
+	assert.Equal(t, HostAvailable, hostState("kn1"))
+
+	rb := common.NewResponseBodyBatch()
+	setBatchResults(rb, results, "unblocked")
+	assert.Equal(t, "1 unblocked, 2 failed", rb.Message)
+	assert.Equal(t, common.ErrPartial, rb.ErrorCode)
+
+	// with failFast the first failure leaves every host unchanged
+	_, _, err = doUpdateBlockHosts(true, false, []string{"kn1"}, "", r)
+	require.NoError(t, err)
+	results, status, err = doUpdateBlockHosts(false, true, []string{"kn1", "kn2"}, "", r)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "kn2")
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Nil(t, results)
+	assert.Equal(t, HostBlocked, hostState("kn1"))
+	assert.Equal(t, HostAvailable, hostState("kn2"))
+
+	_, status, err = doUpdateBlockHosts(true, true, []string{"kn1", "kn9"}, "", r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestBlockHostsRecordsWhoAndWhy(t *testing.T) {
+
+	seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	admin := &User{Name: "root-admin"}
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, admin))
+
+	readHost := func(name string) Host {
+		var h Host
+		require.NoError(t, db.Preload("HostPolicy").Where("name = ?", name).First(&h).Error)
+		return h
+	}
+
+	before := time.Now().Add(-time.Second)
+	_, _, err := doUpdateBlockHosts(true, false, []string{"kn1"}, "bad DIMM", r)
+	require.NoError(t, err)
+
+	h := readHost("kn1")
+	assert.Equal(t, "root-admin", h.BlockedBy)
+	assert.Equal(t, "bad DIMM", h.BlockReason)
+	assert.True(t, h.BlockedAt.After(before))
+
+	hd := h.getHostData(nil, admin)
+	assert.Equal(t, "root-admin", hd.BlockedBy)
+	assert.Equal(t, h.BlockedAt.Unix(), hd.BlockedAt)
+	assert.Equal(t, "bad DIMM", hd.BlockReason)
+
+	// the public status only says the host is blocked
+	status, err := buildPublicStatus(time.Now(), db)
+	require.NoError(t, err)
+	body, err := json.Marshal(status)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), HostBlocked.String())
+	assert.NotContains(t, string(body), "bad DIMM")
+	assert.NotContains(t, string(body), "root-admin")
+
+	// unblocking clears it all
+	_, _, err = doUpdateBlockHosts(false, false, []string{"kn1"}, "", r)
+	require.NoError(t, err)
+	h = readHost("kn1")
+	assert.Empty(t, h.BlockedBy)
+	assert.Empty(t, h.BlockReason)
+	assert.True(t, h.BlockedAt.IsZero())
+	hd = h.getHostData(nil, admin)
+	assert.Zero(t, hd.BlockedAt)
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloadInterval is how often the certificate and key files are checked for changes.
+const certReloadInterval = time.Minute
+
+// certReloader serves the TLS certificate of the HTTPS listeners and swaps in a new one when the
+// certificate or key file changes on disk. Connections already made keep the certificate they were
+// made with. If the new files can't be loaded the old certificate is kept.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	certMod time.Time // modification time of certFile when last loaded or tried
+	keyMod  time.Time // modification time of keyFile when last loaded or tried
+}
+
+// newCertReloader loads the certificate in certFile and keyFile. An error is returned if the pair
+// can't be loaded.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	certMod, keyMod, err := cr.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := loadCertPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cr.cert, cr.certMod, cr.keyMod = cert, certMod, keyMod
+	return cr, nil
+}
+
+// GetCertificate returns the current certificate. It is used as tls.Config.GetCertificate.
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.cert, nil
+}
+
+// reload loads the certificate again if either file has changed since it was last loaded or tried.
+// It returns true if a new certificate is now being served. A pair that fails to load isn't tried
+// again until one of the files changes again, which also covers a rotation that writes the
+// certificate and key at different times.
+func (cr *certReloader) reload() (bool, error) {
+
+	certMod, keyMod, err := cr.modTimes()
+	if err != nil {
+		return false, err
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	if certMod.Equal(cr.certMod) && keyMod.Equal(cr.keyMod) {
+		return false, nil
+	}
+	cr.certMod, cr.keyMod = certMod, keyMod
+
+	cert, err := loadCertPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return false, err
+	}
+	cr.cert = cert
+	return true, nil
+}
+
+// notAfter returns when the current certificate expires.
+func (cr *certReloader) notAfter() time.Time {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.cert.Leaf.NotAfter
+}
+
+func (cr *certReloader) modTimes() (certMod, keyMod time.Time, err error) {
+	certInfo, err := os.Stat(cr.certFile)
+	if err != nil {
+		return
+	}
+	keyInfo, err := os.Stat(cr.keyFile)
+	if err != nil {
+		return
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// loadCertPair loads a certificate and key with the parsed leaf certificate filled in.
+func loadCertPair(certFile, keyFile string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load certificate %s and key %s: %w", certFile, keyFile, err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, fmt.Errorf("unable to parse certificate %s: %w", certFile, err)
+		}
+	}
+	return &cert, nil
+}
+
+// certReloadManager is called as a go routine to check the certificate and key files for changes
+// and reload them while the server runs.
+func certReloadManager(cr *certReloader) {
+	defer wg.Done()
+	ticker := time.NewTicker(certReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-shutdownChan:
+			logger.Info().Msg("stopping certificate reload background worker")
+			return
+		case <-ticker.C:
+			if reloaded, err := cr.reload(); err != nil {
+				logger.Error().Msgf("certificate reload failed, still serving the old certificate: %v", err)
+			} else if reloaded {
+				logger.Info().Msgf("reloaded TLS certificate %s, valid until %s", cr.certFile,
+					cr.notAfter().Format(time.RFC3339))
+			}
+		}
+	}
+}
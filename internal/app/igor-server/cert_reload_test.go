@@ -0,0 +1,114 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCertPair writes a self-signed certificate with the given serial and its key to the
+// files, setting their modification time to modTime.
+func writeTestCertPair(t *testing.T, certFile, keyFile string, serial int64, modTime time.Time) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Duration(serial) * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func TestCertReload(t *testing.T) {
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "igor.crt"), filepath.Join(dir, "igor.key")
+	modTime := time.Now().Add(-time.Hour)
+	writeTestCertPair(t, certFile, keyFile, 1, modTime)
+
+	cr, err := newCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	// the listener is set up the way runServer does it
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{
+		Handler:   http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		TLSConfig: &tls.Config{GetCertificate: cr.GetCertificate, MinVersion: tls.VersionTLS12},
+	}
+	go func() { _ = srv.ServeTLS(ln, "", "") }()
+	defer srv.Close()
+
+	servedSerial := func() int64 {
+		conn, dErr := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		require.NoError(t, dErr)
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+	assert.Equal(t, int64(1), servedSerial())
+
+	// nothing changed on disk
+	reloaded, err := cr.reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// a new pair is served to new connections
+	modTime = modTime.Add(time.Minute)
+	writeTestCertPair(t, certFile, keyFile, 2, modTime)
+	reloaded, err = cr.reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, int64(2), servedSerial())
+	assert.WithinDuration(t, time.Now().Add(48*time.Hour), cr.notAfter(), time.Minute)
+
+	// a key that doesn't match the certificate keeps the old pair
+	otherDir := t.TempDir()
+	otherCert, otherKey := filepath.Join(otherDir, "igor.crt"), filepath.Join(otherDir, "igor.key")
+	writeTestCertPair(t, otherCert, otherKey, 3, modTime)
+	keyData, err := os.ReadFile(otherKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFile, keyData, 0600))
+	modTime = modTime.Add(time.Minute)
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	reloaded, err = cr.reload()
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+	assert.Equal(t, int64(2), servedSerial())
+
+	// so does a garbled certificate
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0600))
+	modTime = modTime.Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	_, err = cr.reload()
+	assert.Error(t, err)
+	assert.Equal(t, int64(2), servedSerial())
+}
@@ -27,6 +27,9 @@ type Cluster struct {
 	Hosts         []Host
 }
 
+// MaxMotdLength is the longest MOTD in bytes.
+const MaxMotdLength = 1024
+
 func (c *Cluster) getClusterData() common.ClusterData {
 
 	cd := common.ClusterData{
@@ -36,6 +39,7 @@ func (c *Cluster) getClusterData() common.ClusterData {
 		DisplayWidth:  c.DisplayWidth,
 		Motd:          c.Motd,
 		MotdUrgent:    c.MotdUrgent,
+		MotdHTML:      renderMarkdownString(c.Motd),
 	}
 
 	return cd
@@ -47,7 +51,7 @@ func (c *Cluster) getClusterData() common.ClusterData {
 // The HostMap defines each host by node number along with the following parameters:
 //
 //	n:
-//	 eth: (the ethernet switch identifier)
+//	 eth: (the ethernet switch identifier, or role=port pairs for a host with several, ex. data=Et1/7,mgmt=Et2/7)
 //	 ip: (the ip of the node, if static)
 //	 policy: (the HostPolicy name of the node, 'default' by default)
 type ClusterConfig struct {
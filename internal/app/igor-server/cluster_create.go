@@ -171,14 +171,27 @@ func doCreateClusters(r *http.Request) (clusters []Cluster, hostnameList []strin
 					return fmt.Errorf("required bootMode \"%s\" invalid or not found for host %s; host configuration aborted", bootMode, hostname)
 				}
 
+				if ccErr := checkConsoleNameRules(nmv["console"]); ccErr != nil {
+					status = http.StatusBadRequest
+					return fmt.Errorf("%v for host %s; host configuration aborted", ccErr, hostname)
+				}
+
+				eth, ports, ethErr := parseEthSpec(nmv["eth"])
+				if ethErr != nil {
+					status = http.StatusBadRequest
+					return fmt.Errorf("%v for host %s; host configuration aborted", ethErr, hostname)
+				}
+
 				host := &Host{
 					Name:         hname,
 					HostName:     hostname,
-					Eth:          nmv["eth"],
+					Eth:          eth,
+					Ports:        formatHostPorts(ports),
 					SequenceID:   nmk,
 					Mac:          hwAddr.String(),
 					IP:           hostIpBytes,
 					BootMode:     bootMode,
+					Console:      nmv["console"],
 					State:        HostBlocked,
 					HostPolicyID: hostPolicyMap[hostPolicyName].ID,
 					ClusterID:    clusterId,
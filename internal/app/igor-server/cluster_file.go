@@ -0,0 +1,282 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	zl "github.com/rs/zerolog"
+)
+
+const (
+	clusterBackupSuffix     = ".bak"
+	clusterBackupTimeFormat = "20060102-150405"
+	// clusterBackupSystemUser is named in backups made without an acting user
+	clusterBackupSystemUser = "igor"
+)
+
+var (
+	// clusterFileMU guards the pending rewrite of the cluster config file
+	clusterFileMU    sync.Mutex
+	clusterFileTimer *time.Timer
+	clusterFileUsers []string
+
+	// clusterFileWriteMU keeps more than one rewrite of the cluster config file from running at once
+	clusterFileWriteMU sync.Mutex
+)
+
+// queueClusterFileWrite asks for the cluster config file to be rewritten from the database. Host changes
+// made within clusterFile.writeDelay seconds of the first request are written together, so a burst of
+// edits produces a single rewrite and a single backup. The backup is named for every user that asked.
+func queueClusterFileWrite(user string, clog *zl.Logger) {
+
+	clusterFileMU.Lock()
+	defer clusterFileMU.Unlock()
+
+	if user == "" {
+		user = clusterBackupSystemUser
+	}
+	found := false
+	for _, u := range clusterFileUsers {
+		if u == user {
+			found = true
+			break
+		}
+	}
+	if !found {
+		clusterFileUsers = append(clusterFileUsers, user)
+	}
+
+	if clusterFileTimer == nil {
+		delay := time.Duration(igor.ClusterFile.WriteDelay) * time.Second
+		clusterFileTimer = time.AfterFunc(delay, flushClusterFileWrite)
+		clog.Debug().Msgf("cluster config file rewrite queued for %v from now", delay)
+	}
+}
+
+// flushClusterFileWrite rewrites the cluster config file now if a rewrite has been queued. It is run
+// when the write delay is up and when the server shuts down. dbAccess must not be held by the caller.
+func flushClusterFileWrite() {
+
+	clusterFileMU.Lock()
+	if clusterFileTimer == nil {
+		clusterFileMU.Unlock()
+		return
+	}
+	clusterFileTimer.Stop()
+	clusterFileTimer = nil
+	users := clusterFileUsers
+	clusterFileUsers = nil
+	clusterFileMU.Unlock()
+
+	dbAccess.Lock()
+	clusters, err := dbReadClustersTx(nil)
+	dbAccess.Unlock()
+	if err != nil {
+		logger.Error().Msgf("unable to read clusters to rewrite cluster config file - %v", err)
+		return
+	}
+
+	yDoc, err := assembleYamlOutput(clusters)
+	if err != nil {
+		logger.Error().Msgf("unable to build new cluster config file - %v", err)
+		return
+	}
+
+	if finalPath, wErr := updateClusterConfigFile(yDoc, strings.Join(users, "+"), &logger); wErr != nil {
+		logger.Error().Msgf("problem writing new igor-clusters.yaml : %v", wErr)
+	} else {
+		logger.Info().Msgf("%s updated for changes by %s", finalPath, strings.Join(users, ", "))
+	}
+}
+
+// findClusterConfigFile returns the path of the cluster config file, looking in /etc/igor first then
+// in the conf folder of IGOR_HOME.
+func findClusterConfigFile() (string, error) {
+	clusterConfigLocHome := filepath.Join(igor.IgorHome, "conf", IgorClusterConfDefault)
+	for _, p := range []string{IgorClusterConfPathDefault, clusterConfigLocHome} {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("no config found at %s or %s", IgorClusterConfPathDefault, clusterConfigLocHome)
+}
+
+// updateClusterConfigFile replaces the cluster config file with yDoc. The old file is copied to a backup
+// named for the time, a sequence number and the user whose change caused the rewrite, then the oldest
+// backups past clusterFile.retain are removed. Returns the path written.
+func updateClusterConfigFile(yDoc []byte, user string, clog *zl.Logger) (string, error) {
+
+	configPath, err := findClusterConfigFile()
+	if err != nil {
+		return "", err
+	}
+
+	clusterFileWriteMU.Lock()
+	defer clusterFileWriteMU.Unlock()
+
+	backupPath, err := backupClusterConfigFile(configPath, user)
+	if err != nil {
+		return "", err
+	}
+	clog.Info().Msgf("backed up old cluster config file to %s", backupPath)
+
+	if err = writeFileAtomic(configPath, yDoc, 0644); err != nil {
+		return "", err
+	}
+
+	if pruned, pErr := pruneClusterConfigBackups(configPath, igor.ClusterFile.Retain); pErr != nil {
+		// the new file was written so don't fail
+		clog.Error().Msgf("problem pruning old cluster config backups: %v", pErr)
+	} else if len(pruned) > 0 {
+		clog.Debug().Msgf("removed old cluster config backups %v", pruned)
+	}
+
+	return configPath, nil
+}
+
+// backupClusterConfigFile copies the config file to <file>.<time>.<seq>.<user>.bak in the same folder
+// and returns the path of the copy. The sequence number is one more than the highest of any existing backup.
+func backupClusterConfigFile(configPath, user string) (string, error) {
+
+	if user == "" {
+		user = clusterBackupSystemUser
+	}
+
+	backups, err := listClusterConfigBackups(configPath)
+	if err != nil {
+		return "", err
+	}
+	seq := 1
+	if len(backups) > 0 {
+		seq = backups[len(backups)-1].seq + 1
+	}
+
+	doc, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", err
+	}
+
+	backupPath := fmt.Sprintf("%s.%s.%d.%s%s", configPath, time.Now().Format(clusterBackupTimeFormat), seq, user, clusterBackupSuffix)
+	if err = writeFileAtomic(backupPath, doc, 0644); err != nil {
+		return "", err
+	}
+	return backupPath, nil
+}
+
+// writeFileAtomic writes data to a temp file in the same folder as path, flushes it to disk then renames it
+// over path, so path is never left partly written.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) (err error) {
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// make the rename itself durable
+	if d, dErr := os.Open(dir); dErr == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
+
+type clusterBackup struct {
+	name    string
+	seq     int
+	modTime time.Time
+}
+
+// listClusterConfigBackups returns the backups of the config file, oldest first. Backups made before
+// backups were numbered have a sequence number of 0.
+func listClusterConfigBackups(configPath string) ([]clusterBackup, error) {
+
+	entries, err := os.ReadDir(filepath.Dir(configPath))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(configPath) + "."
+	var backups []clusterBackup
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), prefix) || !strings.HasSuffix(e.Name(), clusterBackupSuffix) {
+			continue
+		}
+		info, iErr := e.Info()
+		if iErr != nil {
+			if errors.Is(iErr, os.ErrNotExist) {
+				continue
+			}
+			return nil, iErr
+		}
+		b := clusterBackup{name: e.Name(), modTime: info.ModTime()}
+		// <file>.<time>.<seq>.<user>.bak
+		if parts := strings.SplitN(strings.TrimPrefix(e.Name(), prefix), ".", 3); len(parts) == 3 {
+			if _, tErr := time.Parse(clusterBackupTimeFormat, parts[0]); tErr == nil {
+				b.seq, _ = strconv.Atoi(parts[1])
+			}
+		}
+		backups = append(backups, b)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].seq != backups[j].seq {
+			return backups[i].seq < backups[j].seq
+		}
+		return backups[i].modTime.Before(backups[j].modTime)
+	})
+	return backups, nil
+}
+
+// pruneClusterConfigBackups removes the oldest backups of the config file so no more than retain remain,
+// and returns the names of the files that were removed.
+func pruneClusterConfigBackups(configPath string, retain int) ([]string, error) {
+
+	backups, err := listClusterConfigBackups(configPath)
+	if err != nil || len(backups) <= retain {
+		return nil, err
+	}
+
+	dir := filepath.Dir(configPath)
+	var pruned []string
+	for _, b := range backups[:len(backups)-retain] {
+		if rmErr := os.Remove(filepath.Join(dir, b.name)); rmErr != nil {
+			return pruned, rmErr
+		}
+		pruned = append(pruned, b.name)
+	}
+	return pruned, nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestClusterFile makes a cluster config file in a temp IGOR_HOME and returns its path.
+func setupTestClusterFile(t *testing.T, retain int) string {
+
+	if _, err := os.Stat(IgorClusterConfPathDefault); err == nil {
+		t.Skipf("%s exists and would be rewritten", IgorClusterConfPathDefault)
+	}
+
+	origHome, origCF := igor.IgorHome, igor.ClusterFile
+	t.Cleanup(func() { igor.IgorHome, igor.ClusterFile = origHome, origCF })
+
+	igor.IgorHome = t.TempDir()
+	igor.ClusterFile.Retain = retain
+	igor.ClusterFile.WriteDelay = 3600
+
+	confDir := filepath.Join(igor.IgorHome, "conf")
+	require.NoError(t, os.MkdirAll(confDir, 0755))
+	configPath := filepath.Join(confDir, IgorClusterConfDefault)
+	require.NoError(t, os.WriteFile(configPath, []byte("v0\n"), 0644))
+	return configPath
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestUpdateClusterConfigFile(t *testing.T) {
+
+	configPath := setupTestClusterFile(t, 2)
+	dir := filepath.Dir(configPath)
+
+	// a backup from before backups were numbered is the oldest
+	oldBackup := configPath + ".2023-01-02T03-04-05.000.bak"
+	require.NoError(t, os.WriteFile(oldBackup, []byte("old\n"), 0644))
+
+	for i, doc := range []string{"v1\n", "v2\n", "v3\n"} {
+		path, err := updateClusterConfigFile([]byte(doc), "alice", &logger)
+		require.NoError(t, err)
+		assert.Equal(t, configPath, path)
+
+		backups, err := listClusterConfigBackups(configPath)
+		require.NoError(t, err)
+		assert.Equal(t, i+1, backups[len(backups)-1].seq)
+	}
+
+	raw, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "v3\n", string(raw))
+
+	backups, err := listClusterConfigBackups(configPath)
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, 2, backups[0].seq)
+	assert.True(t, strings.HasSuffix(backups[1].name, ".3.alice.bak"), backups[1].name)
+
+	// the newest backup holds the file as it was before the last rewrite
+	raw, err = os.ReadFile(filepath.Join(dir, backups[1].name))
+	require.NoError(t, err)
+	assert.Equal(t, "v2\n", string(raw))
+
+	assert.NoFileExists(t, oldBackup)
+	for _, name := range dirNames(t, dir) {
+		assert.NotContains(t, name, ".tmp", "temp file left behind")
+	}
+}
+
+func TestQueueClusterFileWrite(t *testing.T) {
+
+	configPath := setupTestClusterFile(t, 5)
+	seedTestHosts(t, newTestDb(t))
+	t.Cleanup(func() {
+		clusterFileMU.Lock()
+		if clusterFileTimer != nil {
+			clusterFileTimer.Stop()
+		}
+		clusterFileTimer, clusterFileUsers = nil, nil
+		clusterFileMU.Unlock()
+		// wait out a write started by the timer
+		clusterFileWriteMU.Lock()
+		clusterFileWriteMU.Unlock()
+	})
+
+	queueClusterFileWrite("alice", &logger)
+	clusterFileMU.Lock()
+	timer := clusterFileTimer
+	clusterFileMU.Unlock()
+	queueClusterFileWrite("bob", &logger)
+	queueClusterFileWrite("alice", &logger)
+
+	clusterFileMU.Lock()
+	assert.Same(t, timer, clusterFileTimer, "a queued write should not be rescheduled")
+	assert.Equal(t, []string{"alice", "bob"}, clusterFileUsers)
+	clusterFileMU.Unlock()
+
+	flushClusterFileWrite()
+
+	backups, err := listClusterConfigBackups(configPath)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.True(t, strings.HasSuffix(backups[0].name, ".1.alice+bob.bak"), backups[0].name)
+
+	// nothing is written once the queue is empty
+	flushClusterFileWrite()
+	backups, _ = listClusterConfigBackups(configPath)
+	assert.Len(t, backups, 1)
+
+	// a queued write runs by itself when the delay is up
+	igor.ClusterFile.WriteDelay = 0
+	queueClusterFileWrite("", &logger)
+	assert.Eventually(t, func() bool {
+		backups, _ = listClusterConfigBackups(configPath)
+		return len(backups) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.True(t, strings.HasSuffix(backups[1].name, ".2."+clusterBackupSystemUser+".bak"), backups[1].name)
+}
+
+func TestHostEditQueuesClusterFileWrite(t *testing.T) {
+
+	setupTestClusterFile(t, 5)
+	db := newTestDb(t)
+	seedTestHosts(t, db)
+	clearQueue := func() {
+		clusterFileMU.Lock()
+		if clusterFileTimer != nil {
+			clusterFileTimer.Stop()
+		}
+		clusterFileTimer, clusterFileUsers = nil, nil
+		clusterFileMU.Unlock()
+	}
+	t.Cleanup(clearQueue)
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, &User{Name: IgorAdmin}))
+	queued := func() bool {
+		clusterFileMU.Lock()
+		defer clusterFileMU.Unlock()
+		return clusterFileTimer != nil
+	}
+
+	// the poll interval isn't kept in the cluster config file, so it isn't rewritten
+	results, _, err := doUpdateHosts([]string{"kn1", "kn2"}, map[string]interface{}{"poll_interval": 120}, r)
+	require.NoError(t, err)
+	assert.Equal(t, HostEditChanged, results[0].Result)
+	assert.False(t, queued())
+
+	// the policy is written to the file even though the host edit hands it off to dbApplyPolicy
+	short := HostPolicy{Name: "short", MaxResTime: 24 * time.Hour}
+	require.NoError(t, db.Create(&short).Error)
+	results, _, err = doUpdateHosts([]string{"kn1"}, map[string]interface{}{"HostPolicy": short}, r)
+	require.NoError(t, err)
+	assert.Equal(t, HostEditChanged, results[0].Result)
+	assert.True(t, queued())
+	var kn1 Host
+	require.NoError(t, db.Where("name = ?", "kn1").First(&kn1).Error)
+	assert.Equal(t, short.ID, kn1.HostPolicyID)
+	clearQueue()
+
+	results, _, err = doUpdateHosts([]string{"kn1"}, map[string]interface{}{"ip": "10.0.0.1"}, r)
+	require.NoError(t, err)
+	assert.Equal(t, HostEditChanged, results[0].Result)
+	assert.True(t, queued())
+}
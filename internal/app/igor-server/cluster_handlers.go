@@ -6,6 +6,7 @@ package igorserver
 
 import (
 	"fmt"
+	"igor2/internal/pkg/api"
 	"igor2/internal/pkg/common"
 	"net/http"
 	"strconv"
@@ -75,7 +76,7 @@ func handleReadClusters(w http.ResponseWriter, r *http.Request) {
 		} else {
 
 			if doFileDump {
-				finalPath, err = updateClusterConfigFile(yDoc, clog)
+				finalPath, err = updateClusterConfigFile(yDoc, getUserFromContext(r).Name, clog)
 
 				if err != nil {
 					rb.Message = err.Error()
@@ -196,10 +197,13 @@ func validateMotdParams(handler http.Handler) http.Handler {
 				for key, val := range clusterParams {
 					switch key {
 					case "motd":
-						// we just check that name is a string
-						if _, ok = val.(string); !ok {
+						// the MOTD may use markdown, so its length is bounded before it is ever rendered
+						if motd, mOk := val.(string); !mOk {
 							validateErr = NewBadParamTypeError(key, val, "string")
 							break patchParamLoop
+						} else if len(motd) > MaxMotdLength {
+							validateErr = fmt.Errorf("motd is %d characters, the limit is %d", len(motd), MaxMotdLength)
+							break patchParamLoop
 						}
 					case "motdUrgent":
 						// we just check that name is a string
@@ -226,3 +230,113 @@ func validateMotdParams(handler http.Handler) http.Handler {
 		handler.ServeHTTP(w, r)
 	})
 }
+
+// destination for routes PATCH /clusters/rename-prefix and PATCH /clusters/renumber
+func handleMigrateClusterHosts(w http.ResponseWriter, r *http.Request) {
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	params := getBodyFromContext(r)
+	clog := hlog.FromRequest(r)
+	rb := common.NewResponseBodyClusterChanges()
+
+	var changes []common.ClusterChangeData
+	var status int
+	var err error
+	var actionPrefix string
+	if r.URL.Path == api.ClusterRenamePfx {
+		actionPrefix = "rename cluster prefix"
+		changes, status, err = doRenameClusterPrefix(params, r)
+	} else {
+		actionPrefix = "renumber cluster hosts"
+		changes, status, err = doRenumberClusterHosts(params, r)
+	}
+
+	if status >= http.StatusBadRequest {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["changes"] = changes
+		if dryRun, _ := params["dryRun"].(bool); dryRun {
+			rb.Message = fmt.Sprintf("dry run - %d record(s) would change", len(changes))
+			clog.Info().Msgf("%s dry run success", actionPrefix)
+		} else {
+			rb.Message = fmt.Sprintf("%d record(s) changed", len(changes))
+			if err != nil {
+				rb.Message += " - " + err.Error()
+				clog.Warn().Msgf("%s success with problems - %v", actionPrefix, err)
+			} else {
+				clog.Info().Msgf("%s success - %s", actionPrefix, rb.Message)
+			}
+		}
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+func validateClusterMigrateParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+		params := getBodyFromContext(r)
+
+		required := []string{"map"}
+		if r.URL.Path == api.ClusterRenamePfx {
+			required = []string{"old", "new"}
+		}
+		for _, key := range required {
+			if _, ok := params[key]; !ok {
+				validateErr = NewMissingParamError(key)
+			}
+		}
+
+	paramLoop:
+		for key, val := range params {
+			switch key {
+			case "old", "new":
+				if r.URL.Path != api.ClusterRenamePfx {
+					validateErr = NewUnknownParamError(key, val)
+					break paramLoop
+				}
+				if _, ok := val.(string); !ok {
+					validateErr = NewBadParamTypeError(key, val, "string")
+					break paramLoop
+				}
+			case "map":
+				if r.URL.Path != api.ClusterRenumber {
+					validateErr = NewUnknownParamError(key, val)
+					break paramLoop
+				}
+				seqMap, ok := val.(map[string]interface{})
+				if !ok || len(seqMap) == 0 {
+					validateErr = NewBadParamTypeError(key, val, "map of host numbers")
+					break paramLoop
+				}
+				for oldSeq, newSeq := range seqMap {
+					n, isNum := newSeq.(float64)
+					if _, err := strconv.Atoi(oldSeq); err != nil || !isNum || n != float64(int(n)) {
+						validateErr = fmt.Errorf("invalid parameter: '%s' must map host numbers to host numbers, found '%s: %v'", key, oldSeq, newSeq)
+						break paramLoop
+					}
+				}
+			case "dryRun":
+				if _, ok := val.(bool); !ok {
+					validateErr = NewBadParamTypeError(key, val, "bool")
+					break paramLoop
+				}
+			default:
+				validateErr = NewUnknownParamError(key, val)
+				break paramLoop
+			}
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateClusterMigrateParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
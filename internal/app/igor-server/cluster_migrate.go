@@ -0,0 +1,478 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"igor2/internal/pkg/common"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+)
+
+// hostRename holds the new identity planned for a host when the cluster prefix is renamed or its hosts
+// are renumbered.
+type hostRename struct {
+	host     Host
+	name     string
+	hostName string
+	console  string
+	seq      int
+}
+
+// checkClusterPrefix returns an error if prefix can't be used as a cluster prefix. Host names are the
+// prefix followed by the sequence number, so a prefix ending in a digit would make them ambiguous.
+func checkClusterPrefix(prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("cluster prefix cannot be empty")
+	}
+	for i, c := range prefix {
+		if c > unicode.MaxASCII || !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '-') {
+			return fmt.Errorf("cluster prefix '%s' can only contain letters, digits and '-'", prefix)
+		}
+		if i == 0 && !unicode.IsLetter(c) {
+			return fmt.Errorf("cluster prefix '%s' must start with a letter", prefix)
+		}
+	}
+	if unicode.IsDigit(rune(prefix[len(prefix)-1])) {
+		return fmt.Errorf("cluster prefix '%s' cannot end with a digit", prefix)
+	}
+	return nil
+}
+
+// planHostRenames works out the new name, host name, console and sequence ID of each host in the cluster
+// when its prefix changes to newPrefix and the sequence IDs that are keys of seqMap are given their mapped
+// values. Host names and console names are only changed when they follow the cluster naming pattern; any
+// that were given custom values in the cluster config are kept. Hosts that don't change are left out.
+func planHostRenames(hosts []Host, oldPrefix, newPrefix string, seqMap map[int]int) ([]hostRename, error) {
+
+	bySeq := make(map[int]Host, len(hosts))
+	for _, h := range hosts {
+		bySeq[h.SequenceID] = h
+	}
+
+	for oldSeq, newSeq := range seqMap {
+		if _, ok := bySeq[oldSeq]; !ok {
+			return nil, fmt.Errorf("there is no host %s%d to renumber", oldPrefix, oldSeq)
+		}
+		if newSeq < 1 {
+			return nil, fmt.Errorf("cannot renumber host %s%d to %d, sequence numbers start at 1", oldPrefix, oldSeq, newSeq)
+		}
+	}
+
+	var plan []hostRename
+	newSeqs := make(map[int]string, len(hosts))
+	newNames := make(map[string]string, len(hosts)*2)
+	for _, h := range hosts {
+		seq := h.SequenceID
+		if newSeq, ok := seqMap[seq]; ok {
+			seq = newSeq
+		}
+		name := newPrefix + strconv.Itoa(seq)
+
+		hostName := h.HostName
+		if hostName == h.Name {
+			hostName = name
+		} else if strings.HasPrefix(hostName, h.Name+".") {
+			// keep the domain of a fully qualified host name
+			hostName = name + strings.TrimPrefix(hostName, h.Name)
+		}
+		console := h.Console
+		if console == h.Name {
+			console = name
+		}
+
+		if other, taken := newSeqs[seq]; taken {
+			return nil, fmt.Errorf("hosts %s and %s would both be numbered %d", other, h.Name, seq)
+		}
+		newSeqs[seq] = h.Name
+		for _, n := range []string{name, hostName} {
+			if other, taken := newNames[n]; taken && other != h.Name {
+				return nil, fmt.Errorf("hosts %s and %s would both be named %s", other, h.Name, n)
+			}
+			newNames[n] = h.Name
+		}
+
+		if name != h.Name || hostName != h.HostName || console != h.Console || seq != h.SequenceID {
+			plan = append(plan, hostRename{host: h, name: name, hostName: hostName, console: console, seq: seq})
+		}
+	}
+
+	sort.Slice(plan, func(i, j int) bool {
+		return plan[i].host.SequenceID < plan[j].host.SequenceID
+	})
+	return plan, nil
+}
+
+// hostRenameMap returns the new name of every host name and Name changed by the plan.
+func hostRenameMap(plan []hostRename) map[string]string {
+	names := make(map[string]string, len(plan)*2)
+	for _, hr := range plan {
+		if hr.name != hr.host.Name {
+			names[hr.host.Name] = hr.name
+		}
+		if hr.hostName != hr.host.HostName {
+			names[hr.host.HostName] = hr.hostName
+		}
+	}
+	return names
+}
+
+// renameHostsInFact returns fact with the host names in its resource part replaced using names, and
+// whether anything changed. Only node action and host permissions name hosts; other facts are returned as-is.
+func renameHostsInFact(fact string, names map[string]string) (string, bool, error) {
+
+	parts := strings.Split(fact, PermDividerToken)
+	if len(parts) < 2 || (parts[0] != PermNodeAction && parts[0] != PermHosts) {
+		return fact, false, nil
+	}
+
+	changed := false
+	subparts := strings.Split(parts[1], PermSubpartToken)
+	for i, s := range subparts {
+		if n, ok := names[s]; ok {
+			subparts[i] = n
+			changed = true
+		}
+	}
+	if !changed {
+		return fact, false, nil
+	}
+
+	parts[1] = strings.Join(subparts, PermSubpartToken)
+	p, err := NewPermission(strings.Join(parts, PermDividerToken))
+	if err != nil {
+		return "", false, fmt.Errorf("unable to rename hosts in permission '%s': %v", fact, err)
+	}
+	return p.Fact, true, nil
+}
+
+// dbInstallingReservationNames returns the names of reservations whose hosts are being installed: those
+// that have started and are not yet installed, those still retrying hosts that failed to install, and
+// those with a reimage in progress.
+func dbInstallingReservationNames(now time.Time, tx *gorm.DB) ([]string, error) {
+
+	var resList []Reservation
+	if result := tx.Where("start <= ? AND end > ?", now, now).Find(&resList); result.Error != nil {
+		return nil, result.Error
+	}
+
+	var names []string
+	for _, r := range resList {
+		if r.isPaused() || r.awaitingApproval() {
+			continue
+		}
+		_, reimage := reimaging.Load(r.ID)
+		if !r.Installed || r.InstallError != "" || reimage {
+			names = append(names, r.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// doRenameClusterPrefix changes the prefix of the cluster and renames its hosts to match.
+func doRenameClusterPrefix(params map[string]interface{}, r *http.Request) ([]common.ClusterChangeData, int, error) {
+	oldPrefix := params["old"].(string)
+	newPrefix := params["new"].(string)
+	dryRun, _ := params["dryRun"].(bool)
+	return doMigrateClusterHosts(oldPrefix, newPrefix, nil, dryRun, r)
+}
+
+// doRenumberClusterHosts changes the sequence IDs of the hosts named in the map, renaming them to match.
+func doRenumberClusterHosts(params map[string]interface{}, r *http.Request) ([]common.ClusterChangeData, int, error) {
+
+	seqMap := make(map[int]int)
+	for k, v := range params["map"].(map[string]interface{}) {
+		// the validator has already checked these are whole numbers
+		oldSeq, _ := strconv.Atoi(k)
+		seqMap[oldSeq] = int(v.(float64))
+	}
+	dryRun, _ := params["dryRun"].(bool)
+	return doMigrateClusterHosts("", "", seqMap, dryRun, r)
+}
+
+// doMigrateClusterHosts renames the cluster prefix from oldPrefix to newPrefix and renumbers the hosts in
+// seqMap. An empty newPrefix keeps the current one. All database records naming the hosts are changed in a
+// single transaction, then the in-memory node range, the PXE ledger and the cluster config file are brought
+// up to date. Reservation history is left as it was recorded. Nothing is changed when dryRun is set; the
+// changes that would be made are returned either way.
+func doMigrateClusterHosts(oldPrefix, newPrefix string, seqMap map[int]int, dryRun bool, r *http.Request) (changes []common.ClusterChangeData, status int, err error) {
+
+	clog := hlog.FromRequest(r)
+	status = http.StatusInternalServerError
+	var cluster Cluster
+	var names map[string]string
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		clusters, rErr := dbReadClusters(nil, tx)
+		if rErr != nil {
+			return rErr
+		}
+		if len(clusters) == 0 {
+			status = http.StatusNotFound
+			return fmt.Errorf("no cluster has been configured")
+		}
+		cluster = clusters[0]
+
+		if oldPrefix != "" && oldPrefix != cluster.Prefix {
+			status = http.StatusNotFound
+			return fmt.Errorf("cluster prefix is '%s', not '%s'", cluster.Prefix, oldPrefix)
+		}
+		if newPrefix == "" {
+			newPrefix = cluster.Prefix
+		} else if pErr := checkClusterPrefix(newPrefix); pErr != nil {
+			status = http.StatusBadRequest
+			return pErr
+		}
+
+		busy, bErr := dbInstallingReservationNames(time.Now(), tx)
+		if bErr != nil {
+			return bErr
+		}
+		if len(busy) > 0 {
+			status = http.StatusConflict
+			return fmt.Errorf("hosts cannot be renamed while reservations are being installed, try again later: %s",
+				strings.Join(busy, ", "))
+		}
+
+		plan, planErr := planHostRenames(cluster.Hosts, cluster.Prefix, newPrefix, seqMap)
+		if planErr != nil {
+			status = http.StatusBadRequest
+			return planErr
+		}
+		if len(plan) == 0 && newPrefix == cluster.Prefix {
+			status = http.StatusBadRequest
+			return fmt.Errorf("no hosts would change")
+		}
+		names = hostRenameMap(plan)
+
+		if newPrefix != cluster.Prefix {
+			changes = append(changes, common.ClusterChangeData{Record: "cluster", Name: cluster.Name, Field: "prefix", Old: cluster.Prefix, New: newPrefix})
+		}
+		for _, hr := range plan {
+			h := hr.host
+			if hr.name != h.Name {
+				changes = append(changes, common.ClusterChangeData{Record: "host", Name: h.Name, Field: "name", Old: h.Name, New: hr.name})
+			}
+			if hr.hostName != h.HostName {
+				changes = append(changes, common.ClusterChangeData{Record: "host", Name: h.Name, Field: "hostName", Old: h.HostName, New: hr.hostName})
+			}
+			if hr.console != h.Console {
+				changes = append(changes, common.ClusterChangeData{Record: "host", Name: h.Name, Field: "console", Old: h.Console, New: hr.console})
+			}
+			if hr.seq != h.SequenceID {
+				changes = append(changes, common.ClusterChangeData{Record: "host", Name: h.Name, Field: "sequenceId", Old: strconv.Itoa(h.SequenceID), New: strconv.Itoa(hr.seq)})
+			}
+		}
+
+		permChanges, facts, fErr := dbPlanHostPermissionRenames(names, tx)
+		if fErr != nil {
+			return fErr
+		}
+		changes = append(changes, permChanges...)
+
+		if dryRun {
+			return nil
+		}
+
+		// names and sequence IDs are unique, so park every changing host on a placeholder first in case
+		// one takes the old name or number of another
+		for _, hr := range plan {
+			placeholder := fmt.Sprintf("igor-rename-%d", hr.host.ID)
+			if result := tx.Model(&Host{}).Where("id = ?", hr.host.ID).
+				Updates(map[string]interface{}{"name": placeholder, "host_name": placeholder, "sequence_id": -hr.host.ID}); result.Error != nil {
+				return result.Error
+			}
+		}
+		for _, hr := range plan {
+			if result := tx.Model(&Host{}).Where("id = ?", hr.host.ID).
+				Updates(map[string]interface{}{"name": hr.name, "host_name": hr.hostName, "console": hr.console, "sequence_id": hr.seq}); result.Error != nil {
+				return result.Error
+			}
+		}
+		if newPrefix != cluster.Prefix {
+			if result := tx.Model(&Cluster{}).Where("id = ?", cluster.ID).Update("prefix", newPrefix); result.Error != nil {
+				return result.Error
+			}
+		}
+		for id, fact := range facts {
+			if result := tx.Model(&Permission{}).Where("id = ?", id).Update("fact", fact); result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+
+	}); err != nil {
+		return nil, status, err
+	}
+
+	ledgerChanges, lErr := renameHostsInPxeLedger(names, dryRun)
+	if lErr != nil {
+		clog.Error().Msgf("problem renaming hosts in the PXE ledger: %v", lErr)
+	}
+	changes = append(changes, ledgerChanges...)
+
+	configPath, _ := findClusterConfigFile()
+	changes = append(changes, common.ClusterChangeData{Record: "configFile", Name: configPath, Field: "hostmap", Old: "", New: "regenerated with a backup"})
+
+	if dryRun {
+		return changes, http.StatusOK, nil
+	}
+
+	clusters, rErr := dbReadClustersTx(nil)
+	if rErr != nil {
+		return changes, http.StatusInternalServerError, fmt.Errorf("hosts were renamed but the cluster could not be read back: %v", rErr)
+	}
+	refreshClusterRange(cluster.Prefix, &clusters[0])
+	renamePowerStatus(names)
+
+	yDoc, yErr := assembleYamlOutput(clusters)
+	if yErr == nil {
+		_, yErr = updateClusterConfigFile(yDoc, getUserFromContext(r).Name, clog)
+	}
+	if yErr != nil {
+		// the hosts have been renamed so this is reported along with the changes rather than as a failure
+		return changes, http.StatusOK, fmt.Errorf("the cluster config file could not be rewritten, use 'igor cluster show --dump' once fixed: %v", yErr)
+	}
+
+	return changes, http.StatusOK, nil
+}
+
+// dbPlanHostPermissionRenames finds the permissions naming any host in names. It returns the changes to
+// report and the new fact of each permission by ID.
+func dbPlanHostPermissionRenames(names map[string]string, tx *gorm.DB) ([]common.ClusterChangeData, map[int]string, error) {
+
+	if len(names) == 0 {
+		return nil, nil, nil
+	}
+
+	var perms []Permission
+	if result := tx.Where("fact LIKE ? OR fact LIKE ?", PermNodeAction+PermDividerToken+"%", PermHosts+PermDividerToken+"%").
+		Order("id").Find(&perms); result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	var groups []Group
+	if result := tx.Select("id", "name").Find(&groups); result.Error != nil {
+		return nil, nil, result.Error
+	}
+	groupNames := make(map[int]string, len(groups))
+	for _, g := range groups {
+		groupNames[g.ID] = g.Name
+	}
+
+	var changes []common.ClusterChangeData
+	facts := make(map[int]string)
+	for _, p := range perms {
+		fact, changed, err := renameHostsInFact(p.Fact, names)
+		if err != nil {
+			return nil, nil, err
+		}
+		if changed {
+			facts[p.ID] = fact
+			changes = append(changes, common.ClusterChangeData{Record: "permission", Name: groupNames[p.GroupID], Field: "fact", Old: p.Fact, New: fact})
+		}
+	}
+	return changes, facts, nil
+}
+
+// renameHostsInPxeLedger renames the hosts recorded in the PXE ledger, or only reports what would change
+// when dryRun is set.
+func renameHostsInPxeLedger(names map[string]string, dryRun bool) ([]common.ClusterChangeData, error) {
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	pxeFilesMU.Lock()
+	defer pxeFilesMU.Unlock()
+
+	ledger, err := readPxeLedger()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(ledger))
+	for path := range ledger {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var changes []common.ClusterChangeData
+	for _, path := range paths {
+		entry := ledger[path]
+		if n, ok := names[entry.Host]; ok {
+			changes = append(changes, common.ClusterChangeData{Record: "pxeLedger", Name: path, Field: "host", Old: entry.Host, New: n})
+			entry.Host = n
+			ledger[path] = entry
+		}
+	}
+
+	if dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+	return changes, writePxeLedger(ledger)
+}
+
+// refreshClusterRange replaces the node range published for the cluster that used oldPrefix with the
+// current range of the cluster.
+func refreshClusterRange(oldPrefix string, c *Cluster) {
+
+	seqs := make([]int, 0, len(c.Hosts))
+	for _, h := range c.Hosts {
+		seqs = append(seqs, h.SequenceID)
+	}
+	if len(seqs) == 0 {
+		return
+	}
+	sort.Ints(seqs)
+
+	r, err := common.NewRange(c.Prefix, seqs[0], seqs[len(seqs)-1])
+	if err != nil {
+		logger.Error().Msgf("unable to publish node range of cluster %s: %v", c.Name, err)
+		return
+	}
+	for i, crange := range igor.ClusterRefs {
+		if crange.Prefix == oldPrefix {
+			igor.ClusterRefs[i] = *r
+			return
+		}
+	}
+	igor.ClusterRefs = append(igor.ClusterRefs, *r)
+}
+
+// renamePowerStatus moves the known power status of renamed hosts to their new host names.
+func renamePowerStatus(names map[string]string) {
+
+	powerMapMU.Lock()
+	defer powerMapMU.Unlock()
+
+	moved := make(map[string]*bool)
+	movedPoll := make(map[string]*powerPollInfo)
+	for oldName, newName := range names {
+		if p, ok := powerMap[oldName]; ok {
+			moved[newName] = p
+			delete(powerMap, oldName)
+		}
+		if info, ok := powerPollMap[oldName]; ok {
+			movedPoll[newName] = info
+			delete(powerPollMap, oldName)
+		}
+	}
+	for name, p := range moved {
+		powerMap[name] = p
+	}
+	for name, info := range movedPoll {
+		powerPollMap[name] = info
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCheckClusterPrefix(t *testing.T) {
+	assert.NoError(t, checkClusterPrefix("kn"))
+	assert.NoError(t, checkClusterPrefix("rack-a-"))
+	assert.NoError(t, checkClusterPrefix("k2n"))
+	assert.Error(t, checkClusterPrefix(""))
+	assert.Error(t, checkClusterPrefix("kn2"))
+	assert.Error(t, checkClusterPrefix("2kn"))
+	assert.Error(t, checkClusterPrefix("k n"))
+	assert.Error(t, checkClusterPrefix("k:n"))
+}
+
+func TestPlanHostRenames(t *testing.T) {
+
+	hosts := []Host{
+		{Name: "kn1", HostName: "kn1", SequenceID: 1, Console: "kn1"},
+		{Name: "kn2", HostName: "kn2.example.com", SequenceID: 2},
+		{Name: "kn3", HostName: "lab-node", SequenceID: 3, Console: "con3"},
+	}
+
+	// a new prefix renames every host, keeping custom host and console names
+	plan, err := planHostRenames(hosts, "kn", "jn", nil)
+	require.NoError(t, err)
+	require.Len(t, plan, 3)
+	assert.Equal(t, hostRename{host: hosts[0], name: "jn1", hostName: "jn1", console: "jn1", seq: 1}, plan[0])
+	assert.Equal(t, hostRename{host: hosts[1], name: "jn2", hostName: "jn2.example.com", seq: 2}, plan[1])
+	assert.Equal(t, hostRename{host: hosts[2], name: "jn3", hostName: "lab-node", console: "con3", seq: 3}, plan[2])
+	assert.Equal(t, map[string]string{"kn1": "jn1", "kn2": "jn2", "kn2.example.com": "jn2.example.com", "kn3": "jn3"}, hostRenameMap(plan))
+
+	// swapping two numbers only changes those hosts
+	plan, err = planHostRenames(hosts, "kn", "kn", map[int]int{1: 2, 2: 1})
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	assert.Equal(t, "kn2", plan[0].name)
+	assert.Equal(t, 2, plan[0].seq)
+	assert.Equal(t, "kn1.example.com", plan[1].hostName)
+
+	// moving to a free number
+	plan, err = planHostRenames(hosts, "kn", "kn", map[int]int{3: 10})
+	require.NoError(t, err)
+	require.Len(t, plan, 1)
+	assert.Equal(t, "kn10", plan[0].name)
+	assert.Equal(t, "lab-node", plan[0].hostName)
+
+	// nothing to do
+	plan, err = planHostRenames(hosts, "kn", "kn", map[int]int{1: 1})
+	require.NoError(t, err)
+	assert.Empty(t, plan)
+
+	_, err = planHostRenames(hosts, "kn", "kn", map[int]int{1: 3})
+	assert.ErrorContains(t, err, "would both be numbered 3")
+	_, err = planHostRenames(hosts, "kn", "kn", map[int]int{7: 8})
+	assert.ErrorContains(t, err, "no host kn7")
+	_, err = planHostRenames(hosts, "kn", "kn", map[int]int{1: 0})
+	assert.ErrorContains(t, err, "start at 1")
+
+	// a custom host name can't be taken by a renamed host
+	hosts[2].HostName = "jn1"
+	_, err = planHostRenames(hosts, "kn", "jn", nil)
+	assert.ErrorContains(t, err, "would both be named")
+}
+
+func TestRenameHostsInFact(t *testing.T) {
+
+	names := map[string]string{"kn1": "jn1", "kn2": "jn2"}
+
+	fact, changed, err := renameHostsInFact("node:kn1,kn2:console,power,reimage", names)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "node:jn1,jn2:console,power,reimage", fact)
+
+	fact, changed, err = renameHostsInFact("hosts:kn2,kn9:view", names)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "hosts:jn2,kn9:view", fact)
+
+	for _, f := range []string{"node:kn3:power", "hosts:*:view", "reservations:kn1:edit:drop", "kn1"} {
+		fact, changed, err = renameHostsInFact(f, names)
+		require.NoError(t, err)
+		assert.False(t, changed, f)
+		assert.Equal(t, f, fact)
+	}
+}
+
+func TestMigrateClusterHosts(t *testing.T) {
+
+	configPath := setupTestClusterFile(t, 5)
+	origTFTP := igor.TFTPPath
+	origRefs := igor.ClusterRefs
+	t.Cleanup(func() { igor.TFTPPath, igor.ClusterRefs = origTFTP, origRefs })
+	igor.TFTPPath = t.TempDir()
+
+	seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	cluster := Cluster{Name: "testc", Prefix: "kn"}
+	require.NoError(t, db.Omit("Hosts").Create(&cluster).Error)
+	require.NoError(t, db.Model(&Host{}).Where("1 = 1").Update("cluster_id", cluster.ID).Error)
+	igor.ClusterRefs = nil
+	refreshClusterRange("kn", &Cluster{Prefix: "kn", Hosts: []Host{{SequenceID: 1}, {SequenceID: 2}}})
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	perm, err := NewPermission(NewPermissionString(PermNodeAction, "kn1,kn2", "console,power,reimage"))
+	require.NoError(t, err)
+	require.NoError(t, dbAppendPermissions(&all, []Permission{*perm}, db))
+
+	pxeFilesMU.Lock()
+	require.NoError(t, writePxeLedger(pxeLedger{"pxelinux.cfg/01-00-00-00-00-00-01": {Owner: "r1", Reservation: "r1", Host: "kn1"}}))
+	pxeFilesMU.Unlock()
+
+	alice := User{Name: "alice"}
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, &alice))
+
+	// a dry run lists every change without making any
+	changes, status, err := doMigrateClusterHosts("kn", "jn", nil, true, r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	records := map[string]int{}
+	for _, c := range changes {
+		records[c.Record]++
+	}
+	assert.Equal(t, map[string]int{"cluster": 1, "host": 4, "permission": 1, "pxeLedger": 1, "configFile": 1}, records)
+	var hostCount int64
+	db.Model(&Host{}).Where("name LIKE ?", "jn%").Count(&hostCount)
+	assert.Zero(t, hostCount)
+	raw, _ := os.ReadFile(configPath)
+	assert.Equal(t, "v0\n", string(raw))
+
+	// the prefix has to match
+	_, status, err = doMigrateClusterHosts("xx", "jn", nil, false, r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// an installing reservation blocks the change
+	res := Reservation{Name: "r1", Start: time.Now().Add(-time.Minute), End: time.Now().Add(time.Hour)}
+	require.NoError(t, db.Omit("Hosts", "CoOwners", "Owner", "Group", "Profile", "Vlan").Create(&res).Error)
+	_, status, err = doMigrateClusterHosts("kn", "jn", nil, false, r)
+	assert.ErrorContains(t, err, "r1")
+	assert.Equal(t, http.StatusConflict, status)
+	require.NoError(t, db.Model(&res).Update("installed", true).Error)
+
+	_, status, err = doMigrateClusterHosts("kn", "jn", nil, false, r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	clusters, err := dbReadClustersTx(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "jn", clusters[0].Prefix)
+	assert.ElementsMatch(t, []string{"jn1", "jn2"}, namesOfHosts(clusters[0].Hosts))
+
+	var perms []Permission
+	require.NoError(t, db.Where("group_id = ?", all.ID).Find(&perms).Error)
+	require.Len(t, perms, 1)
+	assert.Equal(t, "node:jn1,jn2:console,power,reimage", perms[0].Fact)
+
+	pxeFilesMU.Lock()
+	ledger, err := readPxeLedger()
+	pxeFilesMU.Unlock()
+	require.NoError(t, err)
+	assert.Equal(t, "jn1", ledger["pxelinux.cfg/01-00-00-00-00-00-01"].Host)
+
+	// the node range uses the new prefix straight away
+	require.Len(t, igor.ClusterRefs, 1)
+	hostRange, err := igor.ClusterRefs[0].UnsplitRange([]string{"jn1", "jn2"})
+	require.NoError(t, err)
+	assert.Equal(t, "jn[1-2]", hostRange)
+
+	// the config file is regenerated after a backup is made
+	raw, err = os.ReadFile(configPath)
+	require.NoError(t, err)
+	ccMap := map[string]ClusterConfig{}
+	require.NoError(t, yaml.Unmarshal(raw, &ccMap))
+	assert.Equal(t, "jn", ccMap["testc"].Prefix)
+	assert.Equal(t, "jn2", ccMap["testc"].HostMap[2]["hostname"])
+	backups, err := listClusterConfigBackups(configPath)
+	require.NoError(t, err)
+	assert.Len(t, backups, 1)
+
+	// swapping two hosts goes through placeholders so the unique names don't collide
+	_, status, err = doMigrateClusterHosts("", "", map[int]int{1: 2, 2: 1}, false, r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	var h1 Host
+	require.NoError(t, db.Where("mac = ?", "00:00:00:00:00:01").First(&h1).Error)
+	assert.Equal(t, "jn2", h1.Name)
+	assert.Equal(t, 2, h1.SequenceID)
+	require.NoError(t, db.Where("group_id = ?", all.ID).Find(&perms).Error)
+	assert.Equal(t, "node:jn1,jn2:console,power,reimage", perms[0].Fact)
+}
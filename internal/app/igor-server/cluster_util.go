@@ -5,15 +5,6 @@
 package igorserver
 
 import (
-	"errors"
-	"fmt"
-	"os"
-	"path/filepath"
-	"time"
-
-	"igor2/internal/pkg/common"
-
-	zl "github.com/rs/zerolog"
 	"gopkg.in/yaml.v3"
 )
 
@@ -31,10 +22,13 @@ func assembleYamlOutput(clusters []Cluster) ([]byte, error) {
 			tempMap := make(map[string]string)
 			tempMap["mac"] = h.Mac
 			tempMap["hostname"] = h.HostName
-			tempMap["eth"] = h.Eth
+			tempMap["eth"] = h.ethSpec()
 			tempMap["policy"] = h.HostPolicy.Name
 			tempMap["ip"] = h.IP
 			tempMap["bootMode"] = h.BootMode
+			if h.Console != "" {
+				tempMap["console"] = h.Console
+			}
 			cc.HostMap[h.SequenceID] = tempMap
 		}
 		ccs[c.Name] = *cc
@@ -46,45 +40,3 @@ func assembleYamlOutput(clusters []Cluster) ([]byte, error) {
 	}
 	return yDoc, nil
 }
-
-func updateClusterConfigFile(yDoc []byte, clog *zl.Logger) (string, error) {
-
-	// anonymous function backs up file and writes new one with original name
-	moveAndDump := func(filepath string, configDoc string) error {
-		if _, pathErr := os.Stat(filepath); pathErr == nil {
-			moveFilePath := filepath + "." + time.Now().Format(common.DateTimeFilenameFormat) + ".bak"
-			clog.Info().Msgf("moving old cluster config file to %s", moveFilePath)
-
-			if mvErr := os.Rename(filepath, moveFilePath); mvErr != nil {
-				return mvErr
-			} else {
-				_ = common.WriteFile(filepath, configDoc, 0644)
-
-			}
-		} else {
-			return pathErr
-		}
-		return nil
-	}
-
-	clusterConfigLocHome := filepath.Join(igor.IgorHome, "conf", IgorClusterConfDefault)
-
-	if dumpErr := moveAndDump(IgorClusterConfPathDefault, string(yDoc)); dumpErr != nil {
-		var pathErr *os.PathError
-		if errors.As(dumpErr, &pathErr) && pathErr.Op == "stat" {
-			// couldn't locate cluster conf file in /etc/igor
-			clog.Warn().Msgf("%v - trying %s", pathErr, clusterConfigLocHome)
-		}
-
-		if dumpErr = moveAndDump(clusterConfigLocHome, string(yDoc)); dumpErr != nil {
-			var pathErr *os.PathError
-			if errors.As(dumpErr, &pathErr) && pathErr.Op == "stat" {
-				return "", fmt.Errorf("no config found at %s or %s - %w", IgorClusterConfPathDefault, clusterConfigLocHome, pathErr)
-			}
-		} else {
-			return clusterConfigLocHome, nil
-		}
-	}
-
-	return IgorClusterConfPathDefault, nil
-}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -17,6 +18,7 @@ import (
 	"syscall"
 	"time"
 
+	"igor2/internal/pkg/api"
 	"igor2/internal/pkg/common"
 
 	"gopkg.in/yaml.v3"
@@ -36,6 +38,34 @@ const (
 	DefaultMaxReserveTime      = 43200
 	LowestMinReserveTime       = 10
 	DefaultExtendWithin        = 4320
+	DefaultIdleResGraceHours   = 48
+	DefaultMinStartPercent     = 50
+	DefaultUnavailLookahead    = 24
+	DefaultApprovalHoldHours   = 72
+	DefaultRemovalGraceDays    = 7
+	DefaultPowerPollInterval   = 60
+	DefaultPowerPollFailures   = 3
+	MinPowerPollInterval       = 5
+	DefaultDbJournalMode       = "WAL"
+	DefaultDbBusyTimeout       = 5000
+	DefaultDbBackupRetain      = 7
+	DefaultIdempotencyHours    = 24
+	DefaultShareMaxDays        = 30
+	DefaultShareRateLimit      = 30
+	DefaultPublicStatusSecs    = 5
+	DefaultPxeBackupRetain     = 50
+	DefaultMaxBodyMB           = 1
+	DefaultMaxUploadMB         = 8192
+	DefaultHookTimeout         = 60
+	DefaultImageFetchMaxSize   = 2048
+	DefaultImageFetchTimeout   = 600
+	DefaultImageDecompressMax  = 8192
+	DefaultClusterFileDelay    = 5
+	DefaultClusterFileRetain   = 20
+	DefaultSimPowerOnDelay     = 10
+	DefaultSmtpPort            = 587
+	HostTieBreakSequence       = "sequence"
+	HostTieBreakUsage          = "usage"
 
 	//InsomniaPrefix             = "insomnia"
 )
@@ -59,11 +89,28 @@ type Config struct {
 		AllowedOrigins   []string `yaml:"allowedOrigins" json:"allowedOrigins"`
 		DNSServer        string   `yaml:"dnsServer" json:"dnsServer"`
 		AllowPublicShow  bool     `yaml:"allowPublicShow" json:"allowPublicShow"`
+		PublicStatusSecs int      `yaml:"publicStatusSeconds" json:"publicStatusSeconds"`
 		AllowImageUpload bool     `yaml:"allowImageUpload" json:"allowImageUpload"`
 		TFTPRoot         string   `yaml:"tftpRoot" json:"tftpRoot"`
 		ImageStagePath   string   `yaml:"imageStagePath" json:"imageStagePath"`
 		ScriptDir        string   `yaml:"scriptDir" json:"scriptDir"`
 		UserLocalBootDC  bool     `yaml:"userLocalBootDC" json:"userLocalBootDC"`
+		IdempotencyHours int      `yaml:"idempotencyHours" json:"idempotencyHours"`
+		ConsoleURL       string   `yaml:"consoleUrl" json:"consoleUrl"`
+		ShareURL         string   `yaml:"shareUrl" json:"shareUrl"`
+		ExtendLinkURL    string   `yaml:"extendLinkUrl" json:"extendLinkUrl"`
+		ReservationURL   string   `yaml:"reservationUrl" json:"reservationUrl"`
+		ShareMaxDays     int      `yaml:"shareMaxDays" json:"shareMaxDays"`
+		ShareRateLimit   int      `yaml:"shareRateLimit" json:"shareRateLimit"`
+		PxeBackupRetain  int      `yaml:"pxeBackupRetain" json:"pxeBackupRetain"`
+		MinClientVersion string   `yaml:"minClientVersion" json:"minClientVersion"`
+		OwnerHostHistory bool     `yaml:"ownerHostHistory" json:"ownerHostHistory"`
+		// MaxBodyMB is the largest request body in MB accepted by routes that take JSON.
+		MaxBodyMB int `yaml:"maxBodyMB" json:"maxBodyMB"`
+		// MaxUploadMB is the largest request body in MB accepted by the image and kickstart upload routes.
+		MaxUploadMB int `yaml:"maxUploadMB" json:"maxUploadMB"`
+		// BodyLimitsMB overrides the body size limit in MB for the routes under the given API paths.
+		BodyLimitsMB map[string]int `yaml:"bodyLimitsMB" json:"bodyLimitsMB"`
 	} `yaml:"server" json:"server"`
 
 	Auth struct {
@@ -117,8 +164,28 @@ type Config struct {
 				// groupAttributeDisplayName default=blank - the key for the Entity Attribute display name Value.
 				UserDisplayNameAttribute string   `yaml:"userDisplayNameAttribute" json:"userDisplayNameAttribute"`
 				GroupOwnerAttributes     []string `yaml:"groupOwnerAttributes" json:"groupOwnerAttributes"`
+				// RemovalGraceDays: default=7 - days a user dropped from LDAP who owns reservations is kept pending
+				// removal so the reservations can be claimed; a negative value removes the user right away
+				RemovalGraceDays int `yaml:"removalGraceDays" json:"removalGraceDays"`
 			} `yaml:"sync" json:"sync"`
 		} `yaml:"ldap" json:"ldap"`
+
+		Oidc struct {
+			// IssuerURL: the OIDC provider issuer, its endpoints are discovered from it
+			IssuerURL string `yaml:"issuerURL" json:"issuerURL"`
+			// ClientID: the client igor is registered as with the provider
+			ClientID string `yaml:"clientID" json:"clientID"`
+			// ClientSecret: the client secret if igor is a confidential client
+			ClientSecret string `yaml:"clientSecret" json:"-"`
+			// RedirectURL: the igor-server callback the provider sends the browser back to
+			RedirectURL string `yaml:"redirectURL" json:"redirectURL"`
+			// WebURL: the igorweb page the browser goes to once it has a session
+			WebURL string `yaml:"webURL" json:"webURL"`
+			// UsernameClaim, EmailClaim, FullNameClaim: ID token claims that map to the igor user fields
+			UsernameClaim string `yaml:"usernameClaim" json:"usernameClaim"`
+			EmailClaim    string `yaml:"emailClaim" json:"emailClaim"`
+			FullNameClaim string `yaml:"fullNameClaim" json:"fullNameClaim"`
+		} `yaml:"oidc" json:"oidc"`
 	} `yaml:"auth" json:"auth"`
 
 	// Database defines which type of database Gorm should interact with
@@ -127,6 +194,12 @@ type Config struct {
 	Database struct {
 		Adapter      string `yaml:"adapter" json:"adapter"`
 		DbFolderPath string `yaml:"dbFolderPath" json:"dbFolderPath"` // only used for SQLite
+		JournalMode  string `yaml:"journalMode" json:"journalMode"`   // only used for SQLite
+		BusyTimeout  int    `yaml:"busyTimeout" json:"busyTimeout"`   // only used for SQLite
+		Backup       struct {
+			Dir    string `yaml:"dir" json:"dir"`
+			Retain int    `yaml:"retain" json:"retain"`
+		} `yaml:"backup" json:"backup"`
 	} `yaml:"database" json:"database"`
 
 	Log struct {
@@ -154,6 +227,50 @@ type Config struct {
 		// that it can be extended. For example, 24*60 would mean that the
 		// reservation can be extended within 24 hours of its expiration.
 		ExtendWithin int `yaml:"extendWithin" json:"extendWithin"`
+
+		// IdleResDays is the number of days a reservation can be installed without any of its
+		// hosts being powered on before the owner is warned it will be shortened. 0 turns the
+		// idle reservation policy off.
+		IdleResDays int `yaml:"idleResDays" json:"idleResDays"`
+		// IdleResGraceHours is the number of hours after the idle warning is sent before the
+		// reservation is shortened to end soon.
+		IdleResGraceHours int `yaml:"idleResGraceHours" json:"idleResGraceHours"`
+
+		// DefaultUserDistro is the distro used by quick reservations. If blank the default distro used for
+		// host maintenance is used instead.
+		DefaultUserDistro string `yaml:"defaultUserDistro" json:"defaultUserDistro"`
+
+		// MinStartPercent is the percentage of its hosts a reservation must keep to start when some of them
+		// are unavailable at start time. Unavailable hosts are dropped if enough remain, otherwise the
+		// reservation fails to start. A minimum node count given when the reservation is made overrides it.
+		MinStartPercent int `yaml:"minStartPercent" json:"minStartPercent"`
+
+		// ApprovalNodes and ApprovalDays are the node count and length in days a reservation made by a non-admin
+		// can have before it must be approved by an admin. 0 turns off that threshold.
+		ApprovalNodes int `yaml:"approvalNodes" json:"approvalNodes"`
+		ApprovalDays  int `yaml:"approvalDays" json:"approvalDays"`
+		// ApprovalHoldHours is the number of hours a reservation waits for approval before it is released.
+		ApprovalHoldHours int `yaml:"approvalHoldHours" json:"approvalHoldHours"`
+
+		// ApproveLeaveOn makes a reservation made by a non-admin that leaves its nodes powered on when it
+		// ends wait for admin approval, since nodes left on keep drawing power.
+		ApproveLeaveOn bool `yaml:"approveLeaveOn" json:"approveLeaveOn"`
+
+		// RequireReservationGroup refuses reservations made by non-admins that would only belong to the
+		// owner's private group, so every reservation can be attributed to a project group.
+		RequireReservationGroup bool `yaml:"requireReservationGroup" json:"requireReservationGroup"`
+
+		// HostTieBreak decides between blocks of hosts that are equally suited to a reservation made by
+		// node count. HostTieBreakSequence uses the block earliest in sequence and HostTieBreakUsage uses
+		// the block whose hosts have been reserved the least.
+		HostTieBreak string `yaml:"hostTieBreak" json:"hostTieBreak"`
+
+		// UnavailLookaheadHours is how many hours ahead igor looks for a policy window or maintenance that will
+		// make a host unavailable, so the node map can warn of it.
+		UnavailLookaheadHours int `yaml:"unavailLookaheadHours" json:"unavailLookaheadHours"`
+
+		// Tasks overrides the interval and jitter of the server's periodic tasks, keyed by task name.
+		Tasks map[string]TaskSchedule `yaml:"tasks" json:"tasks"`
 	} `yaml:"scheduler" json:"scheduler"`
 
 	Vlan struct {
@@ -171,6 +288,12 @@ type Config struct {
 		// Min/Max: specify a range of VLANs to use
 		RangeMin int `yaml:"rangeMin" json:"rangeMin"`
 		RangeMax int `yaml:"rangeMax" json:"rangeMax"`
+
+		// NetProfiles: names of the network (QoS) profiles an admin may apply to a reservation's ports
+		NetProfiles []string `yaml:"netProfiles" json:"netProfiles"`
+
+		// IsolateRoles: roles of the host switch ports put in a reservation's VLAN, default is data only
+		IsolateRoles []string `yaml:"isolateRoles" json:"isolateRoles"`
 	} `yaml:"vlan" json:"vlan"`
 
 	Email struct {
@@ -184,6 +307,9 @@ type Config struct {
 		ResNotifyOn   *bool  `yaml:"resNotifyOn" json:"resNotifyOn"`
 		// The number of minutes a warning emails should be sent prior to a reservation expiring.
 		ResNotifyTimes string `yaml:"resNotifyTimes" json:"resNotifyTimes"`
+		// SmtpServers are the servers email is sent through, tried in order until one accepts it. A
+		// server given with the smtpServer settings is put at the front of the list.
+		SmtpServers []SmtpServerConfig `yaml:"smtpServers" json:"smtpServers"`
 	} `yaml:"email" json:"email"`
 
 	Maintenance struct {
@@ -196,7 +322,93 @@ type Config struct {
 		PowerOn          string `yaml:"powerOn" json:"powerOn"`
 		PowerOff         string `yaml:"powerOff" json:"powerOff"`
 		PowerCycle       string `yaml:"powerCycle" json:"powerCycle"`
+
+		// PowerStatus is the command used to poll the power state of a single host, ex. an
+		// ipmitool 'chassis power status' call. If blank, host power is found with an nmap sweep.
+		PowerStatus string `yaml:"powerStatus" json:"powerStatus"`
+		// PowerPollInterval is the number of seconds between power status polls of a host. It can
+		// be overridden on individual hosts.
+		PowerPollInterval int `yaml:"powerPollInterval" json:"powerPollInterval"`
+		// PowerPollFailures is the number of polls of a host in a row that can fail before its
+		// power status is reported as unknown.
+		PowerPollFailures int `yaml:"powerPollFailures" json:"powerPollFailures"`
 	} `yaml:"externalCmds" json:"externalCmds"`
+
+	Hooks struct {
+		// Dir is the folder holding hook executables, each named for the event that runs it.
+		Dir string `yaml:"dir" json:"dir"`
+		// Timeout is the number of seconds a hook can run before it is stopped.
+		Timeout int `yaml:"timeout" json:"timeout"`
+	} `yaml:"hooks" json:"hooks"`
+
+	ImageFetch struct {
+		// AllowedHosts lists the hosts image files can be downloaded from. URL registration is
+		// disabled if it is empty.
+		AllowedHosts []string `yaml:"allowedHosts" json:"allowedHosts"`
+		// MaxSize is the largest image file in MB that will be downloaded.
+		MaxSize int `yaml:"maxSize" json:"maxSize"`
+		// Timeout is the number of seconds a single file download can take.
+		Timeout int `yaml:"timeout" json:"timeout"`
+	} `yaml:"imageFetch" json:"imageFetch"`
+
+	ImageCompression struct {
+		// Store is how compressed (.xz, .zst) image files are kept in the image store. 'decompressed'
+		// expands them at registration. 'compressed' keeps initrd files as given for the kernel to
+		// unpack at boot; kernel files are always expanded since PXE loaders can't read them.
+		Store string `yaml:"store" json:"store"`
+		// MaxSize is the largest size in MB a compressed image file can expand to.
+		MaxSize int `yaml:"maxSize" json:"maxSize"`
+	} `yaml:"imageCompression" json:"imageCompression"`
+
+	KernelArgs struct {
+		// BannedKeys are kernel arg keys, the part of an arg before any '=', that can't be set by non-admins.
+		BannedKeys []string `yaml:"bannedKeys" json:"bannedKeys"`
+		// BannedPatterns are regular expressions that no kernel arg set by a non-admin can match.
+		BannedPatterns []string `yaml:"bannedPatterns" json:"bannedPatterns"`
+	} `yaml:"kernelArgs" json:"kernelArgs"`
+
+	ClusterFile struct {
+		// WriteDelay is the number of seconds host changes are gathered before igor-clusters.yaml is rewritten.
+		WriteDelay int `yaml:"writeDelay" json:"writeDelay"`
+		// Retain is the number of igor-clusters.yaml backups kept.
+		Retain int `yaml:"retain" json:"retain"`
+	} `yaml:"clusterFile" json:"clusterFile"`
+
+	Simulation struct {
+		// Enabled swaps host power commands and VLAN switching for in-memory fakes so igor can run
+		// without any real cluster infrastructure.
+		Enabled bool `yaml:"enabled" json:"enabled"`
+		// PowerOnDelay is the number of seconds a simulated host takes to come on after it is powered
+		// on or cycled.
+		PowerOnDelay int `yaml:"powerOnDelay" json:"powerOnDelay"`
+	} `yaml:"simulation" json:"simulation"`
+}
+
+// SmtpServerConfig is one SMTP server igor can send email through. Each server has its own
+// credentials, which are only ever given to that server.
+type SmtpServerConfig struct {
+	Host     string `yaml:"host" json:"host"`
+	Port     int    `yaml:"port" json:"port"`
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"-"`
+}
+
+// String keeps the password out of the config printed to the log.
+func (s SmtpServerConfig) String() string {
+	return fmt.Sprintf("%s:%d (username '%s')", s.Host, s.Port, s.Username)
+}
+
+// checkSmtpServers makes sure every SMTP server has a host and fills in the default port.
+func checkSmtpServers(servers []SmtpServerConfig) error {
+	for i := range servers {
+		if strings.TrimSpace(servers[i].Host) == "" {
+			return fmt.Errorf("email.smtpServers entry %d is missing a host", i+1)
+		}
+		if servers[i].Port <= 0 {
+			servers[i].Port = DefaultSmtpPort
+		}
+	}
+	return nil
 }
 
 func (c *Config) splitRange(s string) []string {
@@ -374,6 +586,13 @@ func initConfigCheck() {
 		logger.Info().Msgf("public reservation info is enabled")
 	}
 
+	if igor.Server.PublicStatusSecs < 0 {
+		exitPrintFatal(fmt.Sprintf("config error - server.publicStatusSeconds (%d) cannot be negative", igor.Server.PublicStatusSecs))
+	} else if igor.Server.PublicStatusSecs == 0 {
+		logger.Info().Msgf("server.publicStatusSeconds not specified, using default : %d", DefaultPublicStatusSecs)
+		igor.Server.PublicStatusSecs = DefaultPublicStatusSecs
+	}
+
 	if igor.Server.AllowImageUpload {
 		logger.Info().Msgf("users are allowed to upload OS images")
 	}
@@ -382,6 +601,101 @@ func initConfigCheck() {
 		logger.Info().Msgf("Local Boot Distro Creation is enabled for non-admin users")
 	}
 
+	if igor.Server.IdempotencyHours <= 0 {
+		logger.Info().Msgf("server.idempotencyHours not specified, using default : %d", DefaultIdempotencyHours)
+		igor.Server.IdempotencyHours = DefaultIdempotencyHours
+	}
+
+	if igor.Server.ConsoleURL != "" {
+		if strings.Count(igor.Server.ConsoleURL, "%s") != 1 || strings.Count(igor.Server.ConsoleURL, "%") != 1 {
+			exitPrintFatal(fmt.Sprintf("config error - server.consoleUrl '%s' must contain exactly one %%s and no other %% characters", igor.Server.ConsoleURL))
+		}
+		if u, err := url.Parse(fmt.Sprintf(igor.Server.ConsoleURL, "host")); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			exitPrintFatal(fmt.Sprintf("config error - server.consoleUrl '%s' is not an http(s) URL", igor.Server.ConsoleURL))
+		}
+		logger.Info().Msgf("host console links use %s", igor.Server.ConsoleURL)
+	}
+
+	if igor.Server.ShareURL != "" {
+		if strings.Count(igor.Server.ShareURL, "%s") != 1 || strings.Count(igor.Server.ShareURL, "%") != 1 {
+			exitPrintFatal(fmt.Sprintf("config error - server.shareUrl '%s' must contain exactly one %%s and no other %% characters", igor.Server.ShareURL))
+		}
+		if u, err := url.Parse(fmt.Sprintf(igor.Server.ShareURL, "token")); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			exitPrintFatal(fmt.Sprintf("config error - server.shareUrl '%s' is not an http(s) URL", igor.Server.ShareURL))
+		}
+	}
+
+	if igor.Server.ExtendLinkURL != "" {
+		if strings.Count(igor.Server.ExtendLinkURL, "%s") != 1 || strings.Count(igor.Server.ExtendLinkURL, "%") != 1 {
+			exitPrintFatal(fmt.Sprintf("config error - server.extendLinkUrl '%s' must contain exactly one %%s and no other %% characters", igor.Server.ExtendLinkURL))
+		}
+		if u, err := url.Parse(fmt.Sprintf(igor.Server.ExtendLinkURL, "token")); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			exitPrintFatal(fmt.Sprintf("config error - server.extendLinkUrl '%s' is not an http(s) URL", igor.Server.ExtendLinkURL))
+		}
+		logger.Info().Msgf("reservation expiration warnings include extend links using %s", igor.Server.ExtendLinkURL)
+	}
+
+	if igor.Server.ReservationURL != "" {
+		if strings.Count(igor.Server.ReservationURL, "%s") != 1 || strings.Count(igor.Server.ReservationURL, "%") != 1 {
+			exitPrintFatal(fmt.Sprintf("config error - server.reservationUrl '%s' must contain exactly one %%s and no other %% characters", igor.Server.ReservationURL))
+		}
+		if u, err := url.Parse(fmt.Sprintf(igor.Server.ReservationURL, "name")); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			exitPrintFatal(fmt.Sprintf("config error - server.reservationUrl '%s' is not an http(s) URL", igor.Server.ReservationURL))
+		}
+	}
+
+	if igor.Server.ShareMaxDays < 0 {
+		exitPrintFatal(fmt.Sprintf("config error - server.shareMaxDays (%d) cannot be negative", igor.Server.ShareMaxDays))
+	} else if igor.Server.ShareMaxDays == 0 {
+		logger.Info().Msgf("server.shareMaxDays not specified, using default : %d", DefaultShareMaxDays)
+		igor.Server.ShareMaxDays = DefaultShareMaxDays
+	}
+
+	if igor.Server.ShareRateLimit < 0 {
+		exitPrintFatal(fmt.Sprintf("config error - server.shareRateLimit (%d) cannot be negative", igor.Server.ShareRateLimit))
+	} else if igor.Server.ShareRateLimit == 0 {
+		logger.Info().Msgf("server.shareRateLimit not specified, using default : %d", DefaultShareRateLimit)
+		igor.Server.ShareRateLimit = DefaultShareRateLimit
+	}
+
+	if igor.Server.PxeBackupRetain < 0 {
+		exitPrintFatal(fmt.Sprintf("config error - server.pxeBackupRetain (%d) cannot be negative", igor.Server.PxeBackupRetain))
+	} else if igor.Server.PxeBackupRetain == 0 {
+		logger.Info().Msgf("server.pxeBackupRetain not specified, using default : %d", DefaultPxeBackupRetain)
+		igor.Server.PxeBackupRetain = DefaultPxeBackupRetain
+	}
+
+	if igor.Server.MaxBodyMB < 0 {
+		exitPrintFatal(fmt.Sprintf("config error - server.maxBodyMB (%d) cannot be negative", igor.Server.MaxBodyMB))
+	} else if igor.Server.MaxBodyMB == 0 {
+		logger.Info().Msgf("server.maxBodyMB not specified, using default : %d", DefaultMaxBodyMB)
+		igor.Server.MaxBodyMB = DefaultMaxBodyMB
+	}
+
+	if igor.Server.MaxUploadMB < 0 {
+		exitPrintFatal(fmt.Sprintf("config error - server.maxUploadMB (%d) cannot be negative", igor.Server.MaxUploadMB))
+	} else if igor.Server.MaxUploadMB == 0 {
+		logger.Info().Msgf("server.maxUploadMB not specified, using default : %d", DefaultMaxUploadMB)
+		igor.Server.MaxUploadMB = DefaultMaxUploadMB
+	}
+
+	for route, limit := range igor.Server.BodyLimitsMB {
+		if !strings.HasPrefix(route, api.BaseUrl+"/") {
+			exitPrintFatal(fmt.Sprintf("config error - server.bodyLimitsMB route '%s' must be an API path starting with %s/", route, api.BaseUrl))
+		} else if limit <= 0 {
+			exitPrintFatal(fmt.Sprintf("config error - server.bodyLimitsMB limit for '%s' (%d) must be greater than zero", route, limit))
+		}
+		logger.Info().Msgf("request bodies for %s are limited to %d MB", route, limit)
+	}
+
+	if igor.Server.MinClientVersion != "" {
+		minClient, err := common.ParseSemVer(igor.Server.MinClientVersion)
+		if err != nil {
+			exitPrintFatal(fmt.Sprintf("config error - server.minClientVersion: %v", err))
+		}
+		igor.Server.MinClientVersion = minClient.String()
+	}
+
 	// TFTPRoot path
 	if igor.Server.TFTPRoot == "" {
 		logger.Warn().Msgf("server.tftpRoot not specified, using default (IGOR_HOME) : %v", igor.IgorHome)
@@ -491,6 +805,8 @@ func initConfigCheck() {
 	} else if strings.EqualFold(igor.Auth.Scheme, "local") {
 		igor.Auth.Scheme = "local"
 		logger.Info().Msgf("igor is using local authentication, LDAP is disabled")
+	} else if strings.EqualFold(igor.Auth.Scheme, "oidc") {
+		igor.Auth.Scheme = "oidc"
 	}
 
 	if igor.Auth.DefaultUserPassword == "" {
@@ -530,6 +846,10 @@ func initConfigCheck() {
 			}
 		}
 
+		if igor.Auth.Ldap.Sync.RemovalGraceDays == 0 {
+			igor.Auth.Ldap.Sync.RemovalGraceDays = DefaultRemovalGraceDays
+		}
+
 		if igor.Auth.Ldap.Sync.EnableGroupSync {
 			if igor.Auth.Ldap.Sync.SyncFrequency <= 0 {
 				igor.Auth.Ldap.Sync.SyncFrequency = 60
@@ -546,6 +866,35 @@ func initConfigCheck() {
 		igor.Auth.Ldap.Sync.EnableGroupSync = false
 	}
 
+	if igor.Auth.Scheme == "oidc" {
+		oidcConf := &igor.Auth.Oidc
+		if u, err := url.Parse(oidcConf.IssuerURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			exitPrintFatal("config error - OIDC auth scheme set but auth.oidc.issuerURL is not an https URL")
+		}
+		if oidcConf.ClientID == "" {
+			exitPrintFatal("config error - OIDC auth scheme set but no auth.oidc.clientID specified")
+		}
+		if u, err := url.Parse(oidcConf.RedirectURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			exitPrintFatal("config error - OIDC auth scheme set but auth.oidc.redirectURL is not a valid URL")
+		}
+		if oidcConf.WebURL != "" {
+			if u, err := url.Parse(oidcConf.WebURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+				exitPrintFatal("config error - auth.oidc.webURL is not a valid URL")
+			}
+		}
+		oidcConf.IssuerURL = strings.TrimSuffix(oidcConf.IssuerURL, "/")
+		if oidcConf.UsernameClaim == "" {
+			oidcConf.UsernameClaim = DefaultOidcUsernameClaim
+		}
+		if oidcConf.EmailClaim == "" {
+			oidcConf.EmailClaim = DefaultOidcEmailClaim
+		}
+		if oidcConf.FullNameClaim == "" {
+			oidcConf.FullNameClaim = DefaultOidcFullNameClaim
+		}
+		logger.Info().Msgf("igor is using OIDC authentication with issuer %s", oidcConf.IssuerURL)
+	}
+
 	if igor.Database.Adapter == "" {
 		exitPrintFatal("config error - database.adapter required but not set")
 	} else {
@@ -571,21 +920,84 @@ func initConfigCheck() {
 		}
 	}
 
-	if len(igor.Email.SmtpServer) == 0 {
+	if igor.Database.JournalMode == "" {
+		igor.Database.JournalMode = DefaultDbJournalMode
+		logger.Info().Msgf("database.journalMode not specified, using default : %s", igor.Database.JournalMode)
+	} else {
+		igor.Database.JournalMode = strings.ToUpper(igor.Database.JournalMode)
+		switch igor.Database.JournalMode {
+		case "WAL", "DELETE", "TRUNCATE", "PERSIST", "MEMORY":
+		default:
+			exitPrintFatal(fmt.Sprintf("config error - database.journalMode setting '%s' not recognized", igor.Database.JournalMode))
+		}
+	}
+
+	if igor.Database.BusyTimeout <= 0 {
+		igor.Database.BusyTimeout = DefaultDbBusyTimeout
+		logger.Info().Msgf("database.busyTimeout not specified, using default : %d", igor.Database.BusyTimeout)
+	}
+
+	if igor.Database.Backup.Dir == "" {
+		igor.Database.Backup.Dir = filepath.Join(igor.Database.DbFolderPath, "backups")
+		logger.Info().Msgf("database.backup.dir not specified, using default : %v", igor.Database.Backup.Dir)
+	}
+	if createErr := os.MkdirAll(igor.Database.Backup.Dir, 0700); createErr != nil {
+		exitPrintFatal(fmt.Sprintf("config error - cannot create igor database backup folder %s - %v", igor.Database.Backup.Dir, createErr))
+	}
+
+	if igor.Database.Backup.Retain < 0 {
+		exitPrintFatal("config error - database.backup.retain cannot be a negative value")
+	} else if igor.Database.Backup.Retain == 0 {
+		igor.Database.Backup.Retain = DefaultDbBackupRetain
+		logger.Info().Msgf("database.backup.retain not specified, using default : %d", igor.Database.Backup.Retain)
+	}
+
+	if err := checkSmtpServers(igor.Email.SmtpServers); err != nil {
+		exitPrintFatal("config error - " + err.Error())
+	}
+	if len(igor.Email.SmtpServer) > 0 {
+		if igor.Email.SmtpPort <= 0 {
+			igor.Email.SmtpPort = DefaultSmtpPort
+			logger.Info().Msgf("email.smtpPort port not specified, using default : %d", igor.Email.SmtpPort)
+		}
+		first := SmtpServerConfig{Host: igor.Email.SmtpServer, Port: igor.Email.SmtpPort,
+			Username: igor.Email.SmtpUsername, Password: igor.Email.SmtpPassword}
+		igor.Email.SmtpServers = append([]SmtpServerConfig{first}, igor.Email.SmtpServers...)
+	}
+	if len(igor.Email.SmtpServers) == 0 {
 		logger.Warn().Msg("email.smtpServer not specified -- igor will not send email")
 		f := false
 		igor.Email.ResNotifyOn = &f
 	} else {
-		logger.Info().Msg("email is enabled")
-		if igor.Email.SmtpPort <= 0 {
-			igor.Email.SmtpPort = 587
-			logger.Info().Msgf("email.smtpPort port not specified, using default : %d", igor.Email.SmtpPort)
+		logger.Info().Msgf("email is enabled with %d SMTP server(s)", len(igor.Email.SmtpServers))
+	}
+
+	if igor.Simulation.Enabled {
+		// a config with real power commands belongs to a real cluster, so igor won't pretend to run it
+		if igor.ExternalCmds.PowerOn != "" || igor.ExternalCmds.PowerOff != "" || igor.ExternalCmds.PowerCycle != "" ||
+			igor.ExternalCmds.PowerStatus != "" {
+			exitPrintFatal("config error - simulation mode cannot be used when externalCmds power commands are set")
+		}
+		if igor.Simulation.PowerOnDelay < 0 {
+			exitPrintFatal("config error - simulation.powerOnDelay cannot be a negative value")
+		} else if igor.Simulation.PowerOnDelay == 0 {
+			logger.Info().Msgf("simulation.powerOnDelay not specified, using default : %d", DefaultSimPowerOnDelay)
+			igor.Simulation.PowerOnDelay = DefaultSimPowerOnDelay
+		}
+		if igor.Vlan.Network != "" && igor.Vlan.Network != SimNetwork {
+			logger.Warn().Msgf("vlan.network setting '%s' is replaced by the simulated network", igor.Vlan.Network)
 		}
+		igor.Vlan.Network = SimNetwork
+		logger.Warn().Msg("SIMULATION MODE - host power and VLANs are simulated, no real hosts or switches are used")
 	}
 
 	// set VLAN settings
 	if len(igor.Vlan.Network) > 0 {
-		if igor.Vlan.Network != "arista" {
+		if igor.Vlan.Network == SimNetwork {
+			if igor.Vlan.RangeMin == 0 || igor.Vlan.RangeMax == 0 || igor.Vlan.RangeMin > igor.Vlan.RangeMax {
+				exitPrintFatal(fmt.Sprintf("config error - vlan.rangeMin/Max is invalid [%d,%d]", igor.Vlan.RangeMin, igor.Vlan.RangeMax))
+			}
+		} else if igor.Vlan.Network != "arista" {
 			logger.Warn().Msgf("vlan.network setting '%s' not recognized - no service is configured!", igor.Vlan.Network)
 		} else {
 			if igor.Vlan.NetworkUser == "" {
@@ -599,12 +1011,30 @@ func initConfigCheck() {
 				exitPrintFatal(fmt.Sprintf("config error - vlan.rangeMin/Max is invalid [%d,%d]", igor.Vlan.RangeMin, igor.Vlan.RangeMax))
 			}
 		}
+		for _, p := range igor.Vlan.NetProfiles {
+			if strings.TrimSpace(p) != p || p == "" || p == GroupNoneAlias {
+				exitPrintFatal(fmt.Sprintf("config error - vlan.netProfiles entry '%s' is not a valid profile name", p))
+			}
+		}
+		if len(igor.Vlan.NetProfiles) > 0 && !networkSupportsProfiles() {
+			logger.Warn().Msgf("vlan.netProfiles are set but network '%s' does not support them", igor.Vlan.Network)
+		}
+		if len(igor.Vlan.IsolateRoles) == 0 {
+			igor.Vlan.IsolateRoles = []string{PortRoleData}
+		}
+		for _, role := range igor.Vlan.IsolateRoles {
+			if !validPortRole(role) {
+				exitPrintFatal(fmt.Sprintf("config error - vlan.isolateRoles entry '%s' is not a port role - must be one of %s",
+					role, strings.Join(AllowedPortRoles[:], ", ")))
+			}
+		}
+		logger.Info().Msgf("host ports with role(s) %s are put in reservation VLANs", strings.Join(igor.Vlan.IsolateRoles, ","))
 	} else {
 		logger.Warn().Msg("no VLAN service is configured")
 	}
 
 	// email settings
-	if len(igor.Email.SmtpServer) > 0 {
+	if len(igor.Email.SmtpServers) > 0 {
 
 		if igor.Email.ResNotifyOn == nil {
 			logger.Warn().Msg("email.resNotifyOn not specified, using default : true")
@@ -707,19 +1137,197 @@ func initConfigCheck() {
 		logger.Warn().Msgf("scheduler.extendWithin -- reservation extend command is disabled!")
 	}
 
+	if igor.Scheduler.IdleResDays < 0 {
+		exitPrintFatal("config error - scheduler.idleResDays cannot be a negative value")
+	} else if igor.Scheduler.IdleResDays == 0 {
+		logger.Info().Msgf("scheduler.idleResDays not specified -- idle reservation policy is disabled")
+	} else {
+		if igor.Scheduler.IdleResGraceHours < 0 {
+			exitPrintFatal("config error - scheduler.idleResGraceHours cannot be a negative value")
+		} else if igor.Scheduler.IdleResGraceHours == 0 {
+			logger.Warn().Msgf("scheduler.idleResGraceHours not specified, using default : %d", DefaultIdleResGraceHours)
+			igor.Scheduler.IdleResGraceHours = DefaultIdleResGraceHours
+		}
+		logger.Warn().Msgf("idle reservation policy is enabled -- reservations with no hosts powered on after %d day(s) will be shortened %d hour(s) after their owner is warned",
+			igor.Scheduler.IdleResDays, igor.Scheduler.IdleResGraceHours)
+	}
+
+	if igor.Scheduler.DefaultUserDistro != "" {
+		if err := checkDistroNameRules(igor.Scheduler.DefaultUserDistro); err != nil {
+			exitPrintFatal(fmt.Sprintf("config error - scheduler.defaultUserDistro: %v", err))
+		}
+	} else {
+		logger.Info().Msgf("scheduler.defaultUserDistro not specified -- quick reservations will use the default distro")
+	}
+
+	if igor.Scheduler.MinStartPercent == 0 {
+		logger.Info().Msgf("scheduler.minStartPercent not specified, using default : %d", DefaultMinStartPercent)
+		igor.Scheduler.MinStartPercent = DefaultMinStartPercent
+	} else if igor.Scheduler.MinStartPercent < 0 || igor.Scheduler.MinStartPercent > 100 {
+		exitPrintFatal(fmt.Sprintf("config error - scheduler.minStartPercent must be between 1 and 100 [%d]", igor.Scheduler.MinStartPercent))
+	}
+
+	switch igor.Scheduler.HostTieBreak {
+	case "":
+		igor.Scheduler.HostTieBreak = HostTieBreakSequence
+	case HostTieBreakSequence:
+	case HostTieBreakUsage:
+		logger.Info().Msg("scheduler.hostTieBreak is usage -- the least reserved hosts are preferred")
+	default:
+		exitPrintFatal(fmt.Sprintf("config error - scheduler.hostTieBreak must be %s or %s [%s]", HostTieBreakSequence,
+			HostTieBreakUsage, igor.Scheduler.HostTieBreak))
+	}
+
+	if igor.Scheduler.UnavailLookaheadHours < 0 {
+		exitPrintFatal(fmt.Sprintf("config error - scheduler.unavailLookaheadHours (%d) cannot be negative", igor.Scheduler.UnavailLookaheadHours))
+	} else if igor.Scheduler.UnavailLookaheadHours == 0 {
+		logger.Info().Msgf("scheduler.unavailLookaheadHours not specified, using default : %d", DefaultUnavailLookahead)
+		igor.Scheduler.UnavailLookaheadHours = DefaultUnavailLookahead
+	}
+
+	if err := applyTaskSchedules(igor.Scheduler.Tasks); err != nil {
+		exitPrintFatal(fmt.Sprintf("config error - scheduler.tasks: %v", err))
+	}
+
+	if igor.Scheduler.ApprovalNodes < 0 || igor.Scheduler.ApprovalDays < 0 {
+		exitPrintFatal("config error - scheduler.approvalNodes and scheduler.approvalDays cannot be negative values")
+	} else if igor.Scheduler.ApprovalNodes == 0 && igor.Scheduler.ApprovalDays == 0 && !igor.Scheduler.ApproveLeaveOn {
+		logger.Info().Msgf("scheduler.approvalNodes, scheduler.approvalDays and scheduler.approveLeaveOn not specified -- reservation approval is disabled")
+	} else {
+		if igor.Scheduler.ApprovalHoldHours < 0 {
+			exitPrintFatal("config error - scheduler.approvalHoldHours cannot be a negative value")
+		} else if igor.Scheduler.ApprovalHoldHours == 0 {
+			logger.Info().Msgf("scheduler.approvalHoldHours not specified, using default : %d", DefaultApprovalHoldHours)
+			igor.Scheduler.ApprovalHoldHours = DefaultApprovalHoldHours
+		}
+		logger.Warn().Msgf("reservation approval is enabled -- reservations of more than %d node(s) or %d day(s) wait up to %d hour(s) for an admin to approve them",
+			igor.Scheduler.ApprovalNodes, igor.Scheduler.ApprovalDays, igor.Scheduler.ApprovalHoldHours)
+		if igor.Scheduler.ApproveLeaveOn {
+			logger.Warn().Msg("reservations that leave their nodes powered on when they end also need approval")
+		}
+	}
+
+	if igor.Scheduler.RequireReservationGroup {
+		logger.Info().Msg("scheduler.requireReservationGroup is set -- new reservations must be given a group")
+	}
+
 	if igor.ExternalCmds.ConcurrencyLimit == 0 {
 		logger.Info().Msgf("externalCmds.concurrencyLimit not specified, using default : 1")
 		igor.ExternalCmds.ConcurrencyLimit = 1
 	}
 
+	if igor.ExternalCmds.PowerStatus == "" {
+		logger.Info().Msgf("externalCmds.powerStatus not specified -- host power status will be found using nmap")
+	} else {
+		if igor.ExternalCmds.PowerPollInterval == 0 {
+			logger.Info().Msgf("externalCmds.powerPollInterval not specified, using default : %d", DefaultPowerPollInterval)
+			igor.ExternalCmds.PowerPollInterval = DefaultPowerPollInterval
+		} else if igor.ExternalCmds.PowerPollInterval < MinPowerPollInterval {
+			exitPrintFatal(fmt.Sprintf("config error - externalCmds.powerPollInterval cannot be less than %d seconds", MinPowerPollInterval))
+		}
+		if igor.ExternalCmds.PowerPollFailures == 0 {
+			logger.Info().Msgf("externalCmds.powerPollFailures not specified, using default : %d", DefaultPowerPollFailures)
+			igor.ExternalCmds.PowerPollFailures = DefaultPowerPollFailures
+		} else if igor.ExternalCmds.PowerPollFailures < 0 {
+			exitPrintFatal("config error - externalCmds.powerPollFailures cannot be a negative value")
+		}
+	}
+
+	if igor.Hooks.Dir == "" {
+		// hooks are kept beside the scripts folder since everything in that folder is served to nodes
+		igor.Hooks.Dir = filepath.Join(filepath.Dir(igor.Server.ScriptDir), "hooks")
+		logger.Info().Msgf("hooks.dir not specified, using default : %v", igor.Hooks.Dir)
+	}
+	if _, err := os.Stat(igor.Hooks.Dir); errors.Is(err, os.ErrNotExist) {
+		if createErr := os.MkdirAll(igor.Hooks.Dir, 0755); createErr != nil {
+			logger.Warn().Msgf("could not create hooks directory at %s - %v -- event hooks are disabled", igor.Hooks.Dir, createErr)
+			igor.Hooks.Dir = ""
+		}
+	}
+
+	if igor.Hooks.Timeout < 0 {
+		exitPrintFatal("config error - hooks.timeout cannot be a negative value")
+	} else if igor.Hooks.Timeout == 0 {
+		logger.Info().Msgf("hooks.timeout not specified, using default : %d", DefaultHookTimeout)
+		igor.Hooks.Timeout = DefaultHookTimeout
+	}
+
+	if len(igor.ImageFetch.AllowedHosts) == 0 {
+		logger.Info().Msg("imageFetch.allowedHosts not specified -- image registration from a URL is disabled")
+	} else {
+		for i, h := range igor.ImageFetch.AllowedHosts {
+			igor.ImageFetch.AllowedHosts[i] = strings.ToLower(strings.TrimSpace(h))
+		}
+		logger.Info().Msgf("image files can be downloaded from: %v", igor.ImageFetch.AllowedHosts)
+	}
+
+	if igor.ImageFetch.MaxSize < 0 {
+		exitPrintFatal("config error - imageFetch.maxSize cannot be a negative value")
+	} else if igor.ImageFetch.MaxSize == 0 {
+		logger.Info().Msgf("imageFetch.maxSize not specified, using default : %d", DefaultImageFetchMaxSize)
+		igor.ImageFetch.MaxSize = DefaultImageFetchMaxSize
+	}
+
+	switch igor.ImageCompression.Store {
+	case "":
+		logger.Info().Msgf("imageCompression.store not specified, using default : %s", ImageStoreDecompressed)
+		igor.ImageCompression.Store = ImageStoreDecompressed
+	case ImageStoreDecompressed, ImageStoreCompressed:
+	default:
+		exitPrintFatal(fmt.Sprintf("config error - imageCompression.store must be '%s' or '%s'", ImageStoreDecompressed, ImageStoreCompressed))
+	}
+
+	if igor.ImageCompression.MaxSize < 0 {
+		exitPrintFatal("config error - imageCompression.maxSize cannot be a negative value")
+	} else if igor.ImageCompression.MaxSize == 0 {
+		logger.Info().Msgf("imageCompression.maxSize not specified, using default : %d", DefaultImageDecompressMax)
+		igor.ImageCompression.MaxSize = DefaultImageDecompressMax
+	}
+
+	if igor.ImageFetch.Timeout < 0 {
+		exitPrintFatal("config error - imageFetch.timeout cannot be a negative value")
+	} else if igor.ImageFetch.Timeout == 0 {
+		logger.Info().Msgf("imageFetch.timeout not specified, using default : %d", DefaultImageFetchTimeout)
+		igor.ImageFetch.Timeout = DefaultImageFetchTimeout
+	}
+
+	if err := setBannedKernelArgs(igor.KernelArgs.BannedKeys, igor.KernelArgs.BannedPatterns); err != nil {
+		exitPrintFatal(fmt.Sprintf("config error - kernelArgs.bannedPatterns: %v", err))
+	} else if len(igor.KernelArgs.BannedKeys)+len(igor.KernelArgs.BannedPatterns) > 0 {
+		logger.Info().Msgf("kernelArgs has %d banned key(s) and %d banned pattern(s) -- only elevated admins can use them",
+			len(igor.KernelArgs.BannedKeys), len(igor.KernelArgs.BannedPatterns))
+	}
+
+	if igor.ClusterFile.WriteDelay < 0 {
+		exitPrintFatal("config error - clusterFile.writeDelay cannot be a negative value")
+	} else if igor.ClusterFile.WriteDelay == 0 {
+		logger.Info().Msgf("clusterFile.writeDelay not specified, using default : %d", DefaultClusterFileDelay)
+		igor.ClusterFile.WriteDelay = DefaultClusterFileDelay
+	}
+
+	if igor.ClusterFile.Retain < 0 {
+		exitPrintFatal("config error - clusterFile.retain cannot be a negative value")
+	} else if igor.ClusterFile.Retain == 0 {
+		logger.Info().Msgf("clusterFile.retain not specified, using default : %d", DefaultClusterFileRetain)
+		igor.ClusterFile.Retain = DefaultClusterFileRetain
+	}
+
 	logger.Warn().Msg("--- end: important notes and applying defaults/overrides")
 	logger.Info().Msg("--- end: config file settings")
 }
 
-// printConfigToLog iterates through the given interface recursively to find all settings in
-// all child data structures and send them to the log.
+// printConfigToLog sends every setting of the given config struct to the log.
 func printConfigToLog(s interface{}, namePrefix string) {
+	for _, line := range configSettingLines(s, namePrefix) {
+		logger.Info().Msg(line)
+	}
+}
+
+// configSettingLines iterates through the given interface recursively to find all settings in
+// all child data structures and formats each as a line. Passwords and secrets are masked.
+func configSettingLines(s interface{}, namePrefix string) []string {
 
+	var lines []string
 	v := reflect.ValueOf(s)
 
 	for i := 0; i < v.NumField(); i++ {
@@ -730,31 +1338,33 @@ func printConfigToLog(s interface{}, namePrefix string) {
 		if p == "struct" {
 			n := v.Type().Field(i).Name
 			name := namePrefix + n + "."
-			printConfigToLog(v.Field(i).Interface(), name)
+			lines = append(lines, configSettingLines(v.Field(i).Interface(), name)...)
 		} else if p == "map" {
 			iter := v.Field(i).MapRange()
 			for iter.Next() {
-				logger.Info().Msgf("%s : %v = %v", finalName, iter.Key(), iter.Value())
+				lines = append(lines, fmt.Sprintf("%s : %v = %v", finalName, iter.Key(), iter.Value()))
 			}
 		} else {
 			if v.Field(i).Kind() == reflect.Ptr {
 				if v.Field(i).IsNil() {
 					// format output of pointer to nil
-					logger.Info().Msgf("%s = <nil>", finalName)
+					lines = append(lines, fmt.Sprintf("%s = <nil>", finalName))
 				} else {
 					// format output of de-referenced pointer
-					logger.Info().Msgf("%s = %v", finalName, v.Field(i).Elem())
+					lines = append(lines, fmt.Sprintf("%s = %v", finalName, v.Field(i).Elem()))
 				}
 			} else {
 				field := v.Field(i).Interface()
-				if strings.Contains(strings.ToLower(finalName), "password") {
+				lowerName := strings.ToLower(finalName)
+				if strings.Contains(lowerName, "password") || strings.Contains(lowerName, "secret") {
 					// format output of password field
-					logger.Info().Msgf("%s = *****", finalName)
+					lines = append(lines, fmt.Sprintf("%s = *****", finalName))
 				} else {
 					// format everything else
-					logger.Info().Msgf("%s = %v", finalName, field)
+					lines = append(lines, fmt.Sprintf("%s = %v", finalName, field))
 				}
 			}
 		}
 	}
+	return lines
 }
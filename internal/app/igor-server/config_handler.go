@@ -7,6 +7,9 @@ package igorserver
 import (
 	"net/http"
 
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
 	"igor2/internal/pkg/common"
 )
 
@@ -17,9 +20,36 @@ func configHandler(w http.ResponseWriter, _ *http.Request) {
 	makeJsonResponse(w, http.StatusOK, rb)
 }
 
-// settingsHandler returns useful server configuration settings as JSON.
-func settingsHandler(w http.ResponseWriter, _ *http.Request) {
+// settingsHandler returns useful server configuration settings as JSON. If the 'nodes' query parameter
+// gives a host range, the longest reservation that can be made on those hosts is included.
+func settingsHandler(w http.ResponseWriter, r *http.Request) {
 	rb := common.NewResponseBody()
-	rb.Data["igor"] = igor.getServerSettings()
+	settings := igor.getServerSettings()
+
+	if nodes := r.URL.Query().Get("nodes"); nodes != "" {
+		clog := hlog.FromRequest(r)
+
+		// settings don't need a login, but a logged-in user sees the group time limits they get
+		var user *User
+		if token, _ := extractToken(r); token != "" {
+			user, _ = igor.AuthToken.authenticate(r)
+		}
+
+		dbAccess.Lock()
+		defer dbAccess.Unlock()
+
+		status := http.StatusInternalServerError
+		var err error
+		if err = performDbTx(func(tx *gorm.DB) error {
+			settings.NodeTimeLimit, status, err = getNodeTimeLimit(nodes, user, tx, clog)
+			return err
+		}); err != nil {
+			stdErrorResp(rb, status, "get settings", err, clog)
+			makeJsonResponse(w, status, rb)
+			return
+		}
+	}
+
+	rb.Data["igor"] = settings
 	makeJsonResponse(w, http.StatusOK, rb)
 }
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -126,6 +127,10 @@ func initDbBackend() {
 			exitPrintFatal(fmt.Sprintf("database error checking maxResTime update for default host policy - %v", err))
 		}
 
+		if err := performDbTx(backfillImageUsage); err != nil {
+			logger.Error().Msgf("database error initializing distro and profile usage from reservation history - %v", err)
+		}
+
 		return
 
 	} else if status >= http.StatusInternalServerError {
@@ -218,6 +223,48 @@ func initDbBackend() {
 
 }
 
+// auditResourceNames logs a warning for each existing reservation, group, distro, profile and
+// host policy whose name breaks the current naming rules. Nothing is changed; the records keep
+// working but can't be renamed to anything that breaks the rules.
+func auditResourceNames(db *gorm.DB) {
+
+	audits := []struct {
+		kind  string
+		model interface{}
+		where string
+	}{
+		{naming.Reservation, &Reservation{}, ""},
+		// user-private groups are named after their user and follow the user naming rules
+		{naming.Group, &Group{}, "is_user_private = false"},
+		{naming.Distro, &Distro{}, ""},
+		{naming.Profile, &Profile{}, ""},
+		{naming.Policy, &HostPolicy{}, ""},
+	}
+
+	count := 0
+	for _, a := range audits {
+		var names []string
+		q := db.Model(a.model)
+		if a.where != "" {
+			q = q.Where(a.where)
+		}
+		if result := q.Pluck("name", &names); result.Error != nil {
+			logger.Warn().Msgf("unable to audit %s names - %v", a.kind, result.Error)
+			continue
+		}
+		for _, n := range names {
+			if err := naming.Check(a.kind, n); err != nil {
+				logger.Warn().Msgf("name audit: existing %v", err)
+				count++
+			}
+		}
+	}
+
+	if count > 0 {
+		logger.Warn().Msgf("name audit: %d existing record(s) have names that no longer meet the naming rules - they have not been changed", count)
+	}
+}
+
 // performDbTx gets the backend database ref then calls the passed in method txFn that is
 // expected for a GORM transaction, returning any errors.
 func performDbTx(txFn func(tx *gorm.DB) error) error {
@@ -18,8 +18,8 @@ import (
 	"gorm.io/gorm"
 )
 
-// SQLiteDbUserVersion This is the latest internal version of the SQLite Igor database. The value
-// should map to the most recent db schema.
+// SQLiteDbUserVersion This is the latest internal version of the SQLite Igor database as upgraded
+// by the db-migrate tool. Schema changes after this one are made by the steps in dbMigrations.
 const SQLiteDbUserVersion = 1 // (for Igor 2.1)
 //const SQLiteDbUserVersion = 0   // (for Igor 2.0)
 
@@ -27,8 +27,21 @@ func init() {
 	sql.Register("sqlite3_igor",
 		&sqlite3.SQLiteDriver{
 			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
-				_, err := conn.Exec("PRAGMA case_sensitive_like = ON", nil)
-				return err
+				if _, err := conn.Exec("PRAGMA case_sensitive_like = ON", nil); err != nil {
+					return err
+				}
+				// wait on a locked database instead of failing immediately
+				if igor.Database.BusyTimeout > 0 {
+					if _, err := conn.Exec(fmt.Sprintf("PRAGMA busy_timeout = %d", igor.Database.BusyTimeout), nil); err != nil {
+						return err
+					}
+				}
+				if igor.Database.JournalMode != "" {
+					if _, err := conn.Exec("PRAGMA journal_mode = "+igor.Database.JournalMode, nil); err != nil {
+						return err
+					}
+				}
+				return nil
 			},
 		})
 }
@@ -36,10 +49,55 @@ func init() {
 // NewSqliteGormBackend returns the instantiation of the implementation
 func NewSqliteGormBackend() IGormDb {
 
+	db, isNewDB := openSqliteDb(true)
+
+	if isNewDB {
+		if err := createDbSchema(db); err != nil {
+			exitPrintFatal(fmt.Sprintf("%v", err))
+		}
+		logger.Info().Msgf("created database schema at version %d", latestSchemaVersion())
+	} else {
+		version, stamped, err := readSchemaVersion(db)
+		if err != nil {
+			exitPrintFatal(fmt.Sprintf("%v", err))
+		}
+		// existing deployments from before schema versioning are recorded as they are
+		if !stamped && version == 1 {
+			if err = stampSchemaVersion(db, dbMigrations[:1]); err != nil {
+				exitPrintFatal(fmt.Sprintf("%v", err))
+			}
+			logger.Info().Msg("existing database matches the baseline schema - stamped as schema version 1")
+		}
+		if err = checkSchemaVersion(version); err != nil {
+			exitPrintFatal(fmt.Sprintf("%v", err))
+		}
+		logger.Info().Msgf("database schema version = %d", version)
+	}
+
+	if count, mErr := migrateNodeActionPermissions(db); mErr != nil {
+		exitPrintFatal(fmt.Sprintf("%v", mErr))
+	} else if count > 0 {
+		logger.Info().Msgf("rewrote %d reservation power permission(s) as node action permissions", count)
+	}
+
+	auditResourceNames(db)
+
+	return &GormBackend{
+		Database: db,
+	}
+}
+
+// openSqliteDb opens the igor database and returns true if it was created. If create is false a
+// missing database is a fatal error. Schema changes are left to the caller.
+func openSqliteDb(create bool) (*gorm.DB, bool) {
+
 	sqliteDbLoc := filepath.Join(igor.Database.DbFolderPath, "igor.db")
 	var isNewDB = false
 
 	if _, err := os.Stat(sqliteDbLoc); errors.Is(err, os.ErrNotExist) {
+		if !create {
+			exitPrintFatal(fmt.Sprintf("no database found at %s", sqliteDbLoc))
+		}
 		if file, crErr := os.OpenFile(sqliteDbLoc, os.O_CREATE, 0640); crErr != nil {
 			exitPrintFatal(fmt.Sprintf("%v", crErr))
 		} else {
@@ -72,6 +130,8 @@ func NewSqliteGormBackend() IGormDb {
 	// SetConnMaxLifetime sets the maximum amount of time a connection may be reused.
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
+	logger.Info().Msgf("sqlite journal mode = %s, busy timeout = %dms", igor.Database.JournalMode, igor.Database.BusyTimeout)
+
 	if isNewDB {
 		_, err = sqlDB.Exec(fmt.Sprintf("PRAGMA user_version = %d", SQLiteDbUserVersion))
 		if err != nil {
@@ -89,18 +149,17 @@ func NewSqliteGormBackend() IGormDb {
 		if userVersion != SQLiteDbUserVersion {
 			exitPrintFatal(fmt.Sprintf("%v", fmt.Errorf("database version = %d, should be %d :: run database upgrade script and re-start", userVersion, SQLiteDbUserVersion)))
 		} else {
-			logger.Info().Msgf("database schema version = %d", userVersion)
+			logger.Info().Msgf("sqlite user_version = %d", userVersion)
 		}
 	}
 
-	logger.Debug().Msg("auto-migrating GORM models...")
-	err = db.AutoMigrate(&Permission{}, &User{}, &Group{}, &Host{}, &HostPolicy{}, &Cluster{}, &Reservation{}, &Kickstart{}, &Distro{}, &Profile{}, &DistroImage{}, &HistoryRecord{}, &MaintenanceRes{})
-	if err != nil {
+	// reservations_hosts stores per-host install status, so both sides of the relationship use the same join model
+	if err = db.SetupJoinTable(&Reservation{}, "Hosts", &ReservationHost{}); err != nil {
 		exitPrintFatal(fmt.Sprintf("%v", err))
 	}
-	logger.Debug().Msg("auto-migration finished")
-
-	return &GormBackend{
-		Database: db,
+	if err = db.SetupJoinTable(&Host{}, "Reservations", &ReservationHost{}); err != nil {
+		exitPrintFatal(fmt.Sprintf("%v", err))
 	}
+
+	return db, isNewDB
 }
@@ -0,0 +1,78 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+// newTestDb sets up an in-memory database with the tables the server migrates and makes it the
+// server's database for the rest of the test.
+func newTestDb(t testing.TB) *gorm.DB {
+	return newTestDbAt(t, "file::memory:")
+}
+
+// newTestDbAt is newTestDb using the given database. An in-memory database is limited to one
+// connection, so code that opens a transaction inside another needs a database file instead.
+func newTestDbAt(t testing.TB, dsn string) *gorm.DB {
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gLogger})
+	require.NoError(t, err)
+	if dsn == "file::memory:" {
+		sqlDb, _ := db.DB()
+		sqlDb.SetMaxOpenConns(1)
+	}
+	require.NoError(t, db.SetupJoinTable(&Reservation{}, "Hosts", &ReservationHost{}))
+	require.NoError(t, db.SetupJoinTable(&Host{}, "Reservations", &ReservationHost{}))
+	require.NoError(t, db.AutoMigrate(dbModels...))
+
+	origDb := igor.IGormDb
+	t.Cleanup(func() { igor.IGormDb = origDb })
+	igor.IGormDb = &GormBackend{Database: db}
+	useElevateMap(t)
+	return db
+}
+
+// useElevateMap gives the test an empty map of elevated users, which permission checks consult.
+func useElevateMap(t testing.TB) {
+	origElevate := igor.ElevateMap
+	t.Cleanup(func() { igor.ElevateMap = origElevate })
+	igor.ElevateMap = common.NewPassiveTtlMap(time.Minute)
+}
+
+// seedTestHosts adds the all group and hosts kn1 and kn2 under a default host policy open to it.
+func seedTestHosts(t testing.TB, db *gorm.DB) []Host {
+	return seedHosts(t, db, HostPolicy{Name: DefaultPolicyName, MaxResTime: 72 * time.Hour})
+}
+
+// seedHosts adds the all group and hosts kn1 and kn2 along with the given host policies, which are
+// open to the all group. kn1 gets the first policy and kn2 the last.
+func seedHosts(t testing.TB, db *gorm.DB, policies ...HostPolicy) []Host {
+
+	all := Group{Name: GroupAll}
+	assert.NoError(t, db.Omit(clause.Associations).Create(&all).Error)
+	for i := range policies {
+		assert.NoError(t, db.Omit(clause.Associations).Create(&policies[i]).Error)
+		assert.NoError(t, db.Model(&policies[i]).Association("AccessGroups").Append(&all))
+	}
+
+	hosts := []Host{
+		{Name: "kn1", HostName: "kn1", SequenceID: 1, Mac: "00:00:00:00:00:01", State: HostAvailable, HostPolicyID: policies[0].ID},
+		{Name: "kn2", HostName: "kn2", SequenceID: 2, Mac: "00:00:00:00:00:02", State: HostAvailable, HostPolicyID: policies[len(policies)-1].ID},
+	}
+	for i := range hosts {
+		assert.NoError(t, db.Omit(clause.Associations).Create(&hosts[i]).Error)
+	}
+	return hosts
+}
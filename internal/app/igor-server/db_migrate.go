@@ -0,0 +1,356 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SchemaVersion records a schema migration that has been applied to the database. The
+// version of the database is the highest one recorded.
+type SchemaVersion struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// dbMigration is one step in the history of the database schema. Up moves a database at
+// Version-1 to Version and Down reverses it, or is nil if the step can't be undone.
+//
+// A new database is made directly from the current models and stamped with the latest
+// version, so a step never runs against it. Steps that add tables or columns should still
+// check for them first since a database brought up from an older baseline also gets them
+// from the current models.
+type dbMigration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+	// AddsColumns are the model fields whose columns the step adds. A database from before
+	// schema versioning is at the baseline without them.
+	AddsColumns []modelField
+	// AddsTables are the models whose tables the step adds, likewise not part of the baseline.
+	AddsTables []interface{}
+}
+
+type modelField struct {
+	Model interface{}
+	Field string
+}
+
+// addColumnsMigration returns a step that adds the columns of the given fields of model,
+// and drops them when it is undone.
+func addColumnsMigration(version int, name string, model interface{}, fields ...string) dbMigration {
+	m := dbMigration{Version: version, Name: name}
+	for _, f := range fields {
+		m.AddsColumns = append(m.AddsColumns, modelField{Model: model, Field: f})
+	}
+	m.Up = func(tx *gorm.DB) error {
+		for _, f := range fields {
+			if !tx.Migrator().HasColumn(model, f) {
+				if err := tx.Migrator().AddColumn(model, f); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	m.Down = func(tx *gorm.DB) error {
+		for _, f := range fields {
+			if tx.Migrator().HasColumn(model, f) {
+				if err := tx.Migrator().DropColumn(model, f); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return m
+}
+
+// addTableMigration returns a step that creates the table of model, and drops it when it is
+// undone.
+func addTableMigration(version int, name string, model interface{}) dbMigration {
+	return dbMigration{
+		Version:    version,
+		Name:       name,
+		AddsTables: []interface{}{model},
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasTable(model) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(model)
+		},
+		Down: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasTable(model) {
+				return nil
+			}
+			return tx.Migrator().DropTable(model)
+		},
+	}
+}
+
+// dbMigrations lists every schema migration in version order. Add new steps to the end;
+// never change or remove one that has been released.
+var dbMigrations = []dbMigration{
+	{Version: 1, Name: "baseline schema", Up: migrateBaselineSchema},
+	addColumnsMigration(2, "compressed image file sizes", &DistroImage{},
+		"KernelCompressedSize", "KernelDecompressedSize", "InitrdCompressedSize", "InitrdDecompressedSize"),
+	addColumnsMigration(3, "scheduled reservation power cycles", &Reservation{}, "CycleAt", "CycleProfileID"),
+	addColumnsMigration(4, "host policy per-user host caps", &HostPolicy{}, "MaxPerUser"),
+	addColumnsMigration(5, "user notification preferences", &User{}, "ResCcMail", "NoGroupMail"),
+	addTableMigration(6, "reservation data", &ResData{}),
+}
+
+// dbModels are the models kept in the database, in the order their tables are created.
+var dbModels = []interface{}{
+	&Permission{}, &User{}, &Group{}, &Host{}, &HostPolicy{}, &GroupTimeLimit{}, &Cluster{}, &Reservation{},
+	&ResShare{}, &ResExtendToken{}, &Kickstart{}, &Distro{}, &Profile{}, &DistroImage{}, &HistoryRecord{},
+	&MaintenanceRes{}, &IdempotencyRecord{}, &AuthSession{}, &NamedNetwork{}, &ResData{},
+}
+
+// latestSchemaVersion is the schema version this build of igor-server runs against.
+func latestSchemaVersion() int {
+	return dbMigrations[len(dbMigrations)-1].Version
+}
+
+// migrateBaselineSchema brings the tables of a database from before schema versioning up to
+// the current models. It only adds what is missing.
+func migrateBaselineSchema(tx *gorm.DB) error {
+	return tx.AutoMigrate(dbModels...)
+}
+
+// hasBaselineSchema returns true if every table and column of the current models is already
+// in the database, other than the tables and columns added by later migrations.
+func hasBaselineSchema(db *gorm.DB) (bool, error) {
+
+	later := map[string]bool{}
+	for _, m := range dbMigrations {
+		for _, mt := range m.AddsTables {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(mt); err != nil {
+				return false, err
+			}
+			later[stmt.Schema.Table] = true
+		}
+		for _, mf := range m.AddsColumns {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(mf.Model); err != nil {
+				return false, err
+			}
+			if f := stmt.Schema.LookUpField(mf.Field); f != nil {
+				later[stmt.Schema.Table+"."+f.DBName] = true
+			}
+		}
+	}
+
+	migrator := db.Migrator()
+	for _, m := range dbModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return false, err
+		}
+		if later[stmt.Schema.Table] {
+			continue
+		}
+		if !migrator.HasTable(stmt.Schema.Table) {
+			return false, nil
+		}
+		for _, f := range stmt.Schema.Fields {
+			if f.DBName != "" && !later[stmt.Schema.Table+"."+f.DBName] && !migrator.HasColumn(m, f.DBName) {
+				return false, nil
+			}
+		}
+		for _, rel := range stmt.Schema.Relationships.Relations {
+			if rel.JoinTable != nil && !migrator.HasTable(rel.JoinTable.Table) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// readSchemaVersion returns the schema version of the database. A database from before
+// schema versioning has no version recorded (stamped is false); it is at version 1 if it
+// already has the baseline schema and 0 otherwise.
+func readSchemaVersion(db *gorm.DB) (version int, stamped bool, err error) {
+
+	if db.Migrator().HasTable(&SchemaVersion{}) {
+		var latest *int
+		if result := db.Model(&SchemaVersion{}).Select("MAX(version)").Scan(&latest); result.Error != nil {
+			return 0, false, result.Error
+		}
+		if latest != nil {
+			return *latest, true, nil
+		}
+	}
+
+	baseline, err := hasBaselineSchema(db)
+	if err != nil || !baseline {
+		return 0, false, err
+	}
+	return 1, false, nil
+}
+
+// stampSchemaVersion records the given migrations as applied without running them.
+func stampSchemaVersion(db *gorm.DB, steps []dbMigration) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&SchemaVersion{}); err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, m := range steps {
+			if result := tx.Create(&SchemaVersion{Version: m.Version, Name: m.Name, AppliedAt: now}); result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+}
+
+// createDbSchema makes the tables of a new database from the current models and stamps it
+// with the latest schema version.
+func createDbSchema(db *gorm.DB) error {
+	if err := db.AutoMigrate(dbModels...); err != nil {
+		return err
+	}
+	return stampSchemaVersion(db, dbMigrations)
+}
+
+// checkSchemaVersion makes sure this igor-server can run against a database at the given
+// schema version.
+func checkSchemaVersion(version int) error {
+	latest := latestSchemaVersion()
+	if version > latest {
+		return fmt.Errorf("database schema version %d is newer than this igor-server supports (%d) :: "+
+			"run 'igor-server -migrate -migrate-to %d' with the newer igor-server to roll it back, or restore a backup", version, latest, latest)
+	}
+	if version < latest {
+		return fmt.Errorf("database schema version %d is older than this igor-server requires (%d) :: "+
+			"run 'igor-server -migrate-dry-run' to review and 'igor-server -migrate' to apply the pending migrations", version, latest)
+	}
+	return nil
+}
+
+// planMigrations returns the steps that take a database at version to target, and whether
+// they are applied in reverse.
+func planMigrations(version, target int) (steps []dbMigration, down bool, err error) {
+
+	latest := latestSchemaVersion()
+	if version > latest {
+		return nil, false, fmt.Errorf("database schema version %d is newer than this igor-server knows about (%d)", version, latest)
+	}
+	if target < 1 || target > latest {
+		return nil, false, fmt.Errorf("schema version %d is not one this igor-server knows about (1-%d)", target, latest)
+	}
+
+	if target >= version {
+		for _, m := range dbMigrations {
+			if m.Version > version && m.Version <= target {
+				steps = append(steps, m)
+			}
+		}
+		return steps, false, nil
+	}
+
+	for i := len(dbMigrations) - 1; i >= 0; i-- {
+		m := dbMigrations[i]
+		if m.Version <= target || m.Version > version {
+			continue
+		}
+		if m.Down == nil {
+			return nil, true, fmt.Errorf("migration %d (%s) cannot be undone", m.Version, m.Name)
+		}
+		steps = append(steps, m)
+	}
+	return steps, true, nil
+}
+
+// runDbMigrate moves the database to the target schema version, or the latest one if target is
+// 0, and writes what it does to w. With dryRun set it only writes what would be done.
+// A backup of the database is taken before any migration is run.
+func runDbMigrate(db *gorm.DB, target int, dryRun bool, w io.Writer) error {
+
+	version, stamped, err := readSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if target == 0 {
+		target = latestSchemaVersion()
+	}
+
+	steps, down, err := planMigrations(version, target)
+	if err != nil {
+		return err
+	}
+
+	if !stamped && version == 1 {
+		if dryRun {
+			_, _ = fmt.Fprintln(w, "existing database matches the baseline schema and will be stamped as version 1")
+		} else {
+			if err = stampSchemaVersion(db, dbMigrations[:1]); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(w, "existing database matches the baseline schema - stamped as version 1")
+		}
+	}
+
+	if len(steps) == 0 {
+		_, _ = fmt.Fprintf(w, "database schema is at version %d - nothing to do\n", version)
+		return nil
+	}
+
+	direction := "up"
+	if down {
+		direction = "down"
+	}
+	_, _ = fmt.Fprintf(w, "database schema is at version %d - %d migration(s) to version %d:\n", version, len(steps), target)
+	for _, m := range steps {
+		_, _ = fmt.Fprintf(w, "  %-4s %3d  %s\n", direction, m.Version, m.Name)
+	}
+	if dryRun {
+		return nil
+	}
+
+	backup, _, err := doDbBackup("", &logger)
+	if err != nil {
+		return fmt.Errorf("no migrations were run - %v", err)
+	}
+	_, _ = fmt.Fprintf(w, "backed up database to %s\n", backup.Path)
+
+	for _, m := range steps {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if down {
+				if dErr := m.Down(tx); dErr != nil {
+					return dErr
+				}
+				return tx.Delete(&SchemaVersion{}, m.Version).Error
+			}
+			if uErr := m.Up(tx); uErr != nil {
+				return uErr
+			}
+			if uErr := tx.AutoMigrate(&SchemaVersion{}); uErr != nil {
+				return uErr
+			}
+			return tx.Create(&SchemaVersion{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) failed and was rolled back - restore %s if the database is not usable: %v",
+				m.Version, m.Name, backup.Path, err)
+		}
+		_, _ = fmt.Fprintf(w, "ran migration %d %s (%s)\n", m.Version, direction, m.Name)
+		logger.Info().Msgf("ran database migration %d %s (%s)", m.Version, direction, m.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newMigrateTestDb opens an empty database file and points the database backup at a
+// temporary folder for the test.
+func newMigrateTestDb(t *testing.T) *gorm.DB {
+
+	dir := t.TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "igor.db")), &gorm.Config{Logger: gLogger})
+	require.NoError(t, err)
+	require.NoError(t, db.SetupJoinTable(&Reservation{}, "Hosts", &ReservationHost{}))
+	require.NoError(t, db.SetupJoinTable(&Host{}, "Reservations", &ReservationHost{}))
+
+	origDb := igor.IGormDb
+	origBackup := igor.Database.Backup
+	t.Cleanup(func() {
+		igor.IGormDb = origDb
+		igor.Database.Backup = origBackup
+	})
+	igor.IGormDb = &GormBackend{Database: db}
+	igor.Database.Backup.Dir = filepath.Join(dir, "backups")
+	igor.Database.Backup.Retain = 5
+	require.NoError(t, os.Mkdir(igor.Database.Backup.Dir, 0700))
+
+	return db
+}
+
+func backupCount(t *testing.T) int {
+	entries, err := os.ReadDir(igor.Database.Backup.Dir)
+	require.NoError(t, err)
+	return len(entries)
+}
+
+// clearBackups removes the backups taken so far. Backup names only go down to the second,
+// so a test that migrates more than once would otherwise collide with its last backup.
+func clearBackups(t *testing.T) {
+	entries, err := os.ReadDir(igor.Database.Backup.Dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		require.NoError(t, os.Remove(filepath.Join(igor.Database.Backup.Dir, e.Name())))
+	}
+}
+
+func TestSchemaVersionBaseline(t *testing.T) {
+
+	db := newMigrateTestDb(t)
+
+	version, stamped, err := readSchemaVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.False(t, stamped)
+
+	// an existing deployment has the tables from before the later migrations but no recorded version
+	require.NoError(t, db.AutoMigrate(dbModels...))
+	require.NoError(t, dbMigrations[1].Down(db))
+	require.NoError(t, dbMigrations[5].Down(db))
+	version, stamped, err = readSchemaVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+	assert.False(t, stamped)
+
+	var out bytes.Buffer
+	require.NoError(t, runDbMigrate(db, 0, true, &out))
+	assert.Contains(t, out.String(), "will be stamped as version 1")
+	_, stamped, _ = readSchemaVersion(db)
+	assert.False(t, stamped)
+
+	out.Reset()
+	require.NoError(t, runDbMigrate(db, 1, false, &out))
+	assert.Contains(t, out.String(), "stamped as version 1")
+	assert.Contains(t, out.String(), "nothing to do")
+	version, stamped, _ = readSchemaVersion(db)
+	assert.Equal(t, 1, version)
+	assert.True(t, stamped)
+	// stamping alone doesn't need a backup
+	assert.Equal(t, 0, backupCount(t))
+	assert.False(t, db.Migrator().HasColumn(&DistroImage{}, "KernelCompressedSize"))
+
+	out.Reset()
+	require.NoError(t, runDbMigrate(db, 0, false, &out))
+	assert.Contains(t, out.String(), "compressed image file sizes")
+	assert.Equal(t, 1, backupCount(t))
+	assert.True(t, db.Migrator().HasColumn(&DistroImage{}, "KernelCompressedSize"))
+	assert.True(t, db.Migrator().HasTable(&ResData{}))
+	version, _, _ = readSchemaVersion(db)
+	assert.Equal(t, latestSchemaVersion(), version)
+}
+
+func TestSchemaVersionMissingColumn(t *testing.T) {
+
+	db := newMigrateTestDb(t)
+	require.NoError(t, db.AutoMigrate(dbModels...))
+	require.NoError(t, db.Migrator().DropColumn(&Host{}, "ports"))
+
+	// a database from an older release is not the baseline yet
+	version, stamped, err := readSchemaVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.False(t, stamped)
+	assert.Error(t, checkSchemaVersion(version))
+
+	var out bytes.Buffer
+	require.NoError(t, runDbMigrate(db, 0, false, &out))
+	assert.Contains(t, out.String(), "up     1  baseline schema")
+	assert.Contains(t, out.String(), "up     2  compressed image file sizes")
+	assert.Contains(t, out.String(), "backed up database")
+	assert.Equal(t, 1, backupCount(t))
+	assert.True(t, db.Migrator().HasColumn(&Host{}, "ports"))
+
+	version, stamped, _ = readSchemaVersion(db)
+	assert.Equal(t, latestSchemaVersion(), version)
+	assert.True(t, stamped)
+	assert.NoError(t, checkSchemaVersion(version))
+}
+
+func TestSchemaMigrateSteps(t *testing.T) {
+
+	type MigrateWidget struct {
+		ID   int
+		Name string
+	}
+
+	orig := dbMigrations
+	t.Cleanup(func() { dbMigrations = orig })
+	dbMigrations = append(dbMigrations[:1:1],
+		dbMigration{
+			Version: 2,
+			Name:    "add widgets",
+			Up:      func(tx *gorm.DB) error { return tx.Migrator().CreateTable(&MigrateWidget{}) },
+			Down:    func(tx *gorm.DB) error { return tx.Migrator().DropTable(&MigrateWidget{}) },
+		},
+		dbMigration{
+			Version: 3,
+			Name:    "add widget names",
+			Up:      func(tx *gorm.DB) error { return nil },
+		},
+	)
+
+	db := newMigrateTestDb(t)
+	require.NoError(t, db.AutoMigrate(dbModels...))
+	require.NoError(t, stampSchemaVersion(db, dbMigrations[:1]))
+
+	assert.ErrorContains(t, checkSchemaVersion(1), "older than this igor-server")
+	assert.ErrorContains(t, checkSchemaVersion(4), "newer than this igor-server")
+	_, _, err := planMigrations(4, 3)
+	assert.Error(t, err)
+	_, _, err = planMigrations(1, 5)
+	assert.Error(t, err)
+
+	// a dry run changes nothing
+	var out bytes.Buffer
+	require.NoError(t, runDbMigrate(db, 0, true, &out))
+	assert.Contains(t, out.String(), "2 migration(s) to version 3")
+	assert.False(t, db.Migrator().HasTable(&MigrateWidget{}))
+	assert.Equal(t, 0, backupCount(t))
+
+	require.NoError(t, runDbMigrate(db, 2, false, &out))
+	clearBackups(t)
+	assert.True(t, db.Migrator().HasTable(&MigrateWidget{}))
+	version, _, _ := readSchemaVersion(db)
+	assert.Equal(t, 2, version)
+
+	require.NoError(t, runDbMigrate(db, 0, false, &out))
+	clearBackups(t)
+	version, _, _ = readSchemaVersion(db)
+	assert.Equal(t, 3, version)
+	assert.NoError(t, checkSchemaVersion(version))
+
+	// step 3 can't be undone so nothing is rolled back
+	assert.ErrorContains(t, runDbMigrate(db, 1, false, &out), "cannot be undone")
+	version, _, _ = readSchemaVersion(db)
+	assert.Equal(t, 3, version)
+
+	steps, down, err := planMigrations(2, 1)
+	require.NoError(t, err)
+	assert.True(t, down)
+	assert.Len(t, steps, 1)
+
+	require.NoError(t, db.Delete(&SchemaVersion{}, 3).Error)
+	require.NoError(t, runDbMigrate(db, 1, false, &out))
+	assert.False(t, db.Migrator().HasTable(&MigrateWidget{}))
+	version, _, _ = readSchemaVersion(db)
+	assert.Equal(t, 1, version)
+}
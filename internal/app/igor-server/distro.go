@@ -53,6 +53,12 @@ type Distro struct {
 	// Distro kernel args are optional but should only be specified if they are critical for the Distro OS to boot
 	// correctly. Otherwise they should be specified in a Profile. Profile kernel args will be appended to Distro kernel args.
 	KernelArgs string
+	// UseCount and LastUsed track how often and how recently reservations were created or reimaged with the distro
+	UseCount int
+	LastUsed time.Time
+	// UsageNotes tells users how to get started with the installed OS, such as its default login. It is
+	// markdown and included in reservation start emails.
+	UsageNotes string
 }
 
 // isPublic returns true if the distro's group contains the all group
@@ -95,7 +101,7 @@ func (d *Distro) hasActiveReservations() (results []string) {
 
 // filters a list of distros to user-consumable objects
 // (removes data users should not have access to)
-func filterDistroList(distroInfo []Distro) []common.DistroData {
+func filterDistroList(distroInfo []Distro, user *User) []common.DistroData {
 	var distroList []common.DistroData
 
 	for _, distro := range distroInfo {
@@ -122,6 +128,8 @@ func filterDistroList(distroInfo []Distro) []common.DistroData {
 			KernelArgs:  distro.KernelArgs,
 			Kickstart:   distro.Kickstart.Name,
 			IsPublic:    isPublic,
+			Usage:       imageUsageData(distro.OwnerID, distro.UseCount, distro.LastUsed, user),
+			UsageNotes:  distro.UsageNotes,
 		})
 	}
 
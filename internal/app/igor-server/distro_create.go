@@ -7,12 +7,18 @@ package igorserver
 import (
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
 	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
 )
 
-func doCreateDistro(r *http.Request) (distro *Distro, code int, err error) {
+// distroCopyFields are the attributes a new distro can inherit from the distro it copies.
+var distroCopyFields = []string{"description", "image", "kickstart", "kernelArgs"}
+
+func doCreateDistro(r *http.Request) (distro *Distro, fetched []common.ImageFetchData, copied *common.DistroCopyData, code int, err error) {
 	// Check for included existing kernel or initrd hash
 	distroName := r.FormValue("name")
 	copyDistro := r.FormValue("copyDistro")
@@ -23,6 +29,7 @@ func doCreateDistro(r *http.Request) (distro *Distro, code int, err error) {
 	public := strings.ToLower(r.FormValue("public")) == "true"
 	kickstart := r.FormValue("kickstart")
 	isDefault := strings.ToLower(r.FormValue("default")) == "true"
+	copyFields, _ := parseDistroCopyFields(r.FormValue("copyFields"))
 
 	// get the requesting user
 	user := getUserFromContext(r)
@@ -35,7 +42,7 @@ func doCreateDistro(r *http.Request) (distro *Distro, code int, err error) {
 			return findErr // uses default err code
 		} else if found {
 			code = http.StatusConflict
-			return fmt.Errorf("distro name already in use: %s", distroName)
+			return newCodedError(common.ErrNameTaken, "distro name already in use: %s", distroName)
 		}
 
 		distro = &Distro{Name: distroName}
@@ -43,20 +50,28 @@ func doCreateDistro(r *http.Request) (distro *Distro, code int, err error) {
 		// determine image to use in distro
 		if copyDistro != "" {
 			// EXISTING DISTRO: user may want to base new distro on an existing distro
-			// if user is owner of the existing distro, new distro inherits Description,
-			// DistroImage, and KernelArgs from existing distro
-			// If DistroImage is a LocalBoot, will also copy kickstart
+			// if user is owner of the existing distro or it is public, new distro inherits
+			// the selected copy fields from the existing distro, which always include its DistroImage
+			// If DistroImage is a LocalBoot, the kickstart can also be copied
 			dList, status, findErr := getDistros([]string{copyDistro}, tx)
 			if findErr != nil {
 				code = status
 				return findErr
 			}
-			edName := dList[0].Owner.Name
-			if user.Name != edName && !userElevated(user.Name) {
+			src := dList[0]
+			isOwner := user.Name == src.Owner.Name || userElevated(user.Name)
+			if !isOwner && !src.isPublic() {
 				code = http.StatusForbidden
-				return fmt.Errorf("must be the owner of the existing distro %s when using to create new", copyDistro)
+				return fmt.Errorf("must be the owner of the existing distro %s when using to create new unless it is public", copyDistro)
 			}
-			doCopyDistro(dList[0], distro)
+			// the kickstart of someone else's public distro is only copied if the user can read it
+			if !isOwner && src.DistroImage.LocalBoot && slices.Contains(copyFields, "kickstart") && kickstart == "" &&
+				src.Kickstart.OwnerID != user.ID {
+				code = http.StatusForbidden
+				return fmt.Errorf("cannot copy kickstart '%s' of distro %s since it belongs to another user - leave kickstart out of the copied fields and give your own kickstart",
+					src.Kickstart.Name, copyDistro)
+			}
+			copied = doCopyDistro(src, distro, copyFields)
 		} else if distro.DistroImage.ImageID == "" && useDistroImage != "" {
 			// USE DISTRO IMAGE: represents the name of an existing Distro
 			// if user is the owner of the existing distro, new distro inherits its Image only
@@ -84,7 +99,7 @@ func doCreateDistro(r *http.Request) (distro *Distro, code int, err error) {
 			}
 		} else if distro.DistroImage.ImageID == "" {
 			// Register files and generate hash/image if files were included with these params
-			if len(r.MultipartForm.File) > 0 {
+			if len(r.MultipartForm.File) > 0 || hasImageURLs(r) {
 				// check to make sure this is allowed
 				if !igor.Server.AllowImageUpload {
 					return fmt.Errorf("uploading images is not permitted, see an admin for assistance with registering a new image to get an image reference value")
@@ -94,13 +109,14 @@ func doCreateDistro(r *http.Request) (distro *Distro, code int, err error) {
 				if kickstart != "" {
 					return fmt.Errorf("distro image intended for local install/boot must be registered as a seperate step")
 				}
-				image, status, err := registerImage(r, tx)
+				image, imgFetched, status, err := registerImage(r, tx)
 				if err != nil {
 					code = status
 					return err
 				}
 				if image != nil {
 					distro.DistroImage = *image
+					fetched = imgFetched
 				} else {
 					return fmt.Errorf("received empty image object when registering image files") // uses default err code
 				}
@@ -175,7 +191,7 @@ func doCreateDistro(r *http.Request) (distro *Distro, code int, err error) {
 			// user must be a member of all groups to be added
 			if member, badGroup := user.isMemberOfGroups(foundGroups); !member {
 				code = http.StatusForbidden
-				return fmt.Errorf("user is not a member of group %s to include in new distro", badGroup)
+				return newCodedError(common.ErrNotGroupMember, "user is not a member of group %s to include in new distro", badGroup)
 			}
 			// now add the owner's pug
 			pug, err := distro.Owner.getPug()
@@ -187,11 +203,21 @@ func doCreateDistro(r *http.Request) (distro *Distro, code int, err error) {
 			distro.Groups = foundGroups
 		}
 
-		// DESCRIPTION: set optional distro description
-		distro.Description = strings.TrimSpace(distroDescription)
+		// DESCRIPTION: set optional distro description, replacing any that was copied
+		if desc := strings.TrimSpace(distroDescription); desc != "" || copied == nil {
+			distro.Description = desc
+			if desc != "" {
+				specifyDistroCopyField(copied, "description")
+			}
+		}
 
-		// KERNELARGS: set optional kernel args
-		distro.KernelArgs = strings.TrimSpace(kernelArgs)
+		// KERNELARGS: set optional kernel args, replacing any that were copied
+		if kargs := normalizeKernelArgs(kernelArgs); kargs != "" || copied == nil {
+			distro.KernelArgs = kargs
+			if kargs != "" {
+				specifyDistroCopyField(copied, "kernelArgs")
+			}
+		}
 
 		// If distro is using a Local Boot image, kickstart is required
 		if distro.DistroImage.LocalBoot {
@@ -216,6 +242,7 @@ func doCreateDistro(r *http.Request) (distro *Distro, code int, err error) {
 				ks := kss[0]
 				distro.Kickstart = ks
 				distro.KickstartID = ks.ID
+				specifyDistroCopyField(copied, "kickstart")
 			}
 		}
 
@@ -266,14 +293,67 @@ func doCreateDistro(r *http.Request) (distro *Distro, code int, err error) {
 	return
 }
 
-func doCopyDistro(src Distro, target *Distro) {
-	// Does not copy name, owner, or group
-	target.Description = src.Description
-	target.DistroImage = src.DistroImage
-	target.KernelArgs = src.KernelArgs
-	if src.DistroImage.LocalBoot {
-		target.Kickstart = src.Kickstart
-		target.KickstartID = src.KickstartID
+// doCopyDistro copies the given fields of the source distro to the target and returns a summary of the
+// attributes that were inherited. It does not copy name, owner, or group. The kickstart is only copied
+// when the source image is a local boot image.
+func doCopyDistro(src Distro, target *Distro, fields []string) *common.DistroCopyData {
+	copied := &common.DistroCopyData{Source: src.Name}
+	for _, f := range fields {
+		switch f {
+		case "description":
+			target.Description = src.Description
+		case "image":
+			target.DistroImage = src.DistroImage
+		case "kernelArgs":
+			target.KernelArgs = src.KernelArgs
+		case "kickstart":
+			if !src.DistroImage.LocalBoot {
+				continue
+			}
+			target.Kickstart = src.Kickstart
+			target.KickstartID = src.KickstartID
+		}
+		copied.Inherited = append(copied.Inherited, f)
+	}
+	return copied
+}
+
+// specifyDistroCopyField records that a field of a copied distro was given in the request rather than
+// inherited. It does nothing if the distro is not a copy.
+func specifyDistroCopyField(copied *common.DistroCopyData, field string) {
+	if copied == nil {
+		return
 	}
+	copied.Inherited = slices.DeleteFunc(copied.Inherited, func(f string) bool { return f == field })
+	copied.Specified = append(copied.Specified, field)
+}
 
+// parseDistroCopyFields reads the comma-separated list of distro attributes to copy from an existing distro.
+// All attributes are copied if the list is empty. The image must always be copied or the new distro isn't
+// a copy.
+func parseDistroCopyFields(val string) ([]string, error) {
+	if strings.TrimSpace(val) == "" {
+		return distroCopyFields, nil
+	}
+	var fields []string
+	for _, f := range strings.Split(val, ",") {
+		f = strings.TrimSpace(f)
+		if !slices.Contains(distroCopyFields, f) {
+			return nil, fmt.Errorf("'%s' is not a distro attribute that can be copied (use %s)", f, strings.Join(distroCopyFields, ", "))
+		}
+		if !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+	if !slices.Contains(fields, "image") {
+		return nil, fmt.Errorf("copied fields must include the image")
+	}
+	// keep the order stable for the response
+	var ordered []string
+	for _, f := range distroCopyFields {
+		if slices.Contains(fields, f) {
+			ordered = append(ordered, f)
+		}
+	}
+	return ordered, nil
 }
@@ -0,0 +1,60 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDistroCopyFields(t *testing.T) {
+
+	fields, err := parseDistroCopyFields("")
+	require.NoError(t, err)
+	assert.Equal(t, distroCopyFields, fields)
+
+	fields, err = parseDistroCopyFields("kickstart, image,kickstart")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"image", "kickstart"}, fields)
+
+	// the image must always be copied
+	_, err = parseDistroCopyFields("kickstart,kernelArgs")
+	assert.Error(t, err)
+
+	_, err = parseDistroCopyFields("image,owner")
+	assert.Error(t, err)
+}
+
+func TestDoCopyDistro(t *testing.T) {
+
+	src := Distro{Name: "src", Description: "desc", KernelArgs: "console=ttyS0",
+		DistroImage: DistroImage{ImageID: "img1", LocalBoot: true}, Kickstart: Kickstart{Name: "ks1"}, KickstartID: 4}
+
+	target := &Distro{Name: "copy"}
+	copied := doCopyDistro(src, target, []string{"image", "kickstart"})
+	assert.Equal(t, "src", copied.Source)
+	assert.Equal(t, []string{"image", "kickstart"}, copied.Inherited)
+	assert.Equal(t, "img1", target.DistroImage.ImageID)
+	assert.Equal(t, 4, target.KickstartID)
+	assert.Empty(t, target.KernelArgs)
+	assert.Empty(t, target.Description)
+
+	// fresh kernel args replace inherited ones in the summary
+	specifyDistroCopyField(copied, "kernelArgs")
+	assert.Equal(t, []string{"kernelArgs"}, copied.Specified)
+
+	// a netboot image has no kickstart to inherit
+	src.DistroImage.LocalBoot = false
+	target = &Distro{Name: "copy2"}
+	copied = doCopyDistro(src, target, distroCopyFields)
+	assert.Equal(t, []string{"description", "image", "kernelArgs"}, copied.Inherited)
+	assert.Zero(t, target.KickstartID)
+
+	specifyDistroCopyField(copied, "description")
+	assert.Equal(t, []string{"image", "kernelArgs"}, copied.Inherited)
+	assert.Equal(t, []string{"description"}, copied.Specified)
+}
@@ -109,6 +109,11 @@ func dbEditDistro(distro *Distro, changes map[string]interface{}, tx *gorm.DB) e
 					return result.Error
 				}
 			}
+			// keep group reservation defaults pointing at the renamed distro, before the rename
+			// replaces distro.Name
+			if result := tx.Model(&Group{}).Where("default_distro = ?", distro.Name).Update("default_distro", name); result.Error != nil {
+				return result.Error
+			}
 			if result := tx.Model(&distro).Update("Name", name); result.Error != nil {
 				return result.Error
 			}
@@ -7,24 +7,40 @@ package igorserver
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
+	zl "github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
 	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
 )
 
-// doDeleteDistro steps through the process of making an update to a distro record.
+// doDeleteDistro steps through the process of deleting a distro record. With force, an elevated admin
+// also deletes every profile using the distro in the same transaction, unless one of those profiles is
+// used by a reservation. The owners of the deleted profiles are sent an email listing what was removed.
 //
 // Returns:
 //
-//	204,nil if delete was successful
+//	200,nil if delete was successful
 //	400,err if delete was not allowed
-//	404,err if user cannot be found
+//	403,err if force was given by someone other than an elevated admin
+//	404,err if distro cannot be found
+//	409,err if force was given but a reservation uses one of the distro's profiles
 //	500,err if an internal error occurred
-func doDeleteDistro(distroName string, r *http.Request) (code int, err error) {
+func doDeleteDistro(distroName string, force bool, r *http.Request) (removed common.DistroRemovedData, code int, err error) {
 
 	clog := hlog.FromRequest(r)
 	code = http.StatusInternalServerError // default status, overridden at end if no errors
 
+	if force {
+		if user := getUserFromContext(r); !userElevated(user.Name) {
+			return removed, http.StatusForbidden, fmt.Errorf("forced distro delete requires admin elevated privilege")
+		}
+	}
+
+	var deletedProfiles []Profile
+
 	if err = performDbTx(func(tx *gorm.DB) error {
 
 		// get the distro object first
@@ -35,15 +51,24 @@ func doDeleteDistro(distroName string, r *http.Request) (code int, err error) {
 		}
 		distro := &distros[0]
 
-		// fail if distro is linked to any profile other than default
-		clog.Debug().Msgf("checking distro '%s' for linked profiles", distroName)
-		linked, profs, lnkErr := distro.isLinkedToProfiles(tx)
-		if lnkErr != nil {
-			return lnkErr // uses default err code
-		}
-		if linked {
-			code = http.StatusBadRequest
-			return fmt.Errorf("cannot delete distro, currently attached to profile(s) %s. Delete these profile(s) before deleting this distro", profs)
+		if force {
+			profiles, dpStatus, dpErr := deleteDistroProfiles(distro, tx, clog)
+			if dpErr != nil {
+				code = dpStatus
+				return dpErr
+			}
+			deletedProfiles = profiles
+		} else {
+			// fail if distro is linked to any profile other than default
+			clog.Debug().Msgf("checking distro '%s' for linked profiles", distroName)
+			linked, profs, lnkErr := distro.isLinkedToProfiles(tx)
+			if lnkErr != nil {
+				return lnkErr // uses default err code
+			}
+			if linked {
+				code = http.StatusBadRequest
+				return fmt.Errorf("cannot delete distro, currently attached to profile(s) %s. Delete these profile(s) before deleting this distro", profs)
+			}
 		}
 
 		// get the distro image name for later
@@ -68,12 +93,87 @@ func doDeleteDistro(distroName string, r *http.Request) (code int, err error) {
 
 		if len(image.Distros) == 0 {
 			// this image no longer has a distro attached to it, destroy it
+			removed.Image = image.Name
 			return deleteDistroImage(&image, tx, clog)
 		}
 
 		return nil
-	}); err == nil {
-		code = http.StatusOK
+	}); err != nil {
+		return
+	}
+
+	removed.Distro = distroName
+	for _, p := range deletedProfiles {
+		removed.Profiles = append(removed.Profiles, common.RemovedProfile{Name: p.Name, Owner: p.Owner.Name})
+	}
+	notifyProfilesRemoved(deletedProfiles, distroName)
+
+	return removed, http.StatusOK, nil
+}
+
+// distroRemovedSummary lists everything removed by a distro delete in one line.
+func distroRemovedSummary(removed common.DistroRemovedData) string {
+	msg := "deleted distro '" + removed.Distro + "'"
+	if removed.Image != "" {
+		msg += ", its image '" + removed.Image + "'"
+	}
+	if len(removed.Profiles) == 0 {
+		return msg
+	}
+	profiles := make([]string, len(removed.Profiles))
+	for i, p := range removed.Profiles {
+		profiles[i] = p.Name + " (" + p.Owner + ")"
+	}
+	return fmt.Sprintf("%s and %d profile(s): %s", msg, len(profiles), strings.Join(profiles, ", "))
+}
+
+// deleteDistroProfiles deletes every profile using the distro and returns them. Nothing is deleted if a
+// reservation uses any of them, and the error names those reservations.
+func deleteDistroProfiles(distro *Distro, tx *gorm.DB, clog *zl.Logger) ([]Profile, int, error) {
+
+	profiles, err := dbReadProfiles(map[string]interface{}{"distro_id": distro.ID}, tx)
+	if err != nil || len(profiles) == 0 {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	resList, err := dbReadReservations(map[string]interface{}{"profile_id": profileIDsOfProfiles(profiles)}, nil, tx)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if len(resList) > 0 {
+		return nil, http.StatusConflict, fmt.Errorf("cannot force delete distro '%s', its profiles are used by reservation(s) %s",
+			distro.Name, strings.Join(resNamesOfResList(resList), ","))
+	}
+
+	for i := range profiles {
+		clog.Info().Msgf("deleting profile '%s' of '%s' with distro '%s'", profiles[i].Name, profiles[i].Owner.Name, distro.Name)
+		if err = dbDeleteProfile(&profiles[i], tx); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+	}
+	return profiles, http.StatusOK, nil
+}
+
+// notifyProfilesRemoved tells the owner of each profile deleted along with a distro which of their
+// profiles were removed.
+func notifyProfilesRemoved(profiles []Profile, distroName string) {
+
+	byOwner := map[string][]string{}
+	owners := map[string]*User{}
+	for i := range profiles {
+		owner := &profiles[i].Owner
+		if owner.Name == IgorAdmin {
+			continue
+		}
+		byOwner[owner.Name] = append(byOwner[owner.Name], profiles[i].Name)
+		owners[owner.Name] = owner
+	}
+
+	for name, profileNames := range byOwner {
+		if event := makeAcctNotifyEvent(EmailAcctProfilesRemoved, owners[name]); event != nil {
+			event.Distro = distroName
+			event.Profiles = profileNames
+			acctNotifyChan <- *event
+		}
 	}
-	return
 }
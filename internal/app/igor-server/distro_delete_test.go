@@ -0,0 +1,126 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+// newForceDeleteTestDb adds a distro with its own image and a profile of it for both alice and bob.
+// It returns alice's reservation, which doesn't use either profile yet.
+func newForceDeleteTestDb(t *testing.T) (*gorm.DB, *Reservation, []Profile) {
+
+	origTFTP, origStore := igor.TFTPPath, igor.ImageStoreDir
+	t.Cleanup(func() { igor.TFTPPath, igor.ImageStoreDir = origTFTP, origStore })
+	igor.TFTPPath, igor.ImageStoreDir = t.TempDir(), "images"
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	res := newStartTestRes(t, db, "cleanup", hosts[:1], false, 0)
+
+	bobPug := Group{Name: GroupUserPrefix + "bob"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&bobPug).Error)
+	bob := User{Name: "bob", Email: "bob@example.com", Groups: []Group{bobPug}}
+	require.NoError(t, db.Omit("Groups.*").Create(&bob).Error)
+
+	image := DistroImage{ImageID: "0123abcd", Type: "KI", Name: "img-0123abcd"}
+	require.NoError(t, db.Create(&image).Error)
+	require.NoError(t, os.MkdirAll(filepath.Join(igor.TFTPPath, igor.ImageStoreDir, image.ImageID), 0755))
+	distro := Distro{Name: "leftover", OwnerID: res.OwnerID, DistroImageID: image.ID}
+	require.NoError(t, db.Omit(clause.Associations).Create(&distro).Error)
+
+	profiles := []Profile{
+		{Name: "alice-prof", OwnerID: res.OwnerID, Owner: res.Owner, DistroID: distro.ID},
+		{Name: "bob-prof", OwnerID: bob.ID, Owner: bob, DistroID: distro.ID},
+	}
+	for i := range profiles {
+		require.NoError(t, dbCreateProfile(&profiles[i], db))
+	}
+	return db, res, profiles
+}
+
+// deleteDistroAs deletes the distro as the given user.
+func deleteDistroAs(user *User, name string, force bool) (common.DistroRemovedData, int, error) {
+	r := httptest.NewRequest(http.MethodDelete, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, user))
+	return doDeleteDistro(name, force, r)
+}
+
+func TestForceDeleteDistroBlocked(t *testing.T) {
+
+	db, res, profiles := newForceDeleteTestDb(t)
+	require.NoError(t, db.Model(&Reservation{}).Where("id = ?", res.ID).Update("profile_id", profiles[1].ID).Error)
+
+	// the usual delete still refuses a distro with profiles
+	_, status, err := deleteDistroAs(&User{Name: IgorAdmin}, "leftover", false)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// only an elevated admin can force it
+	_, status, err = deleteDistroAs(&res.Owner, "leftover", true)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// and not while a reservation uses one of the profiles
+	_, status, err = deleteDistroAs(&User{Name: IgorAdmin}, "leftover", true)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Contains(t, err.Error(), "cleanup")
+
+	var count int64
+	require.NoError(t, db.Model(&Profile{}).Count(&count).Error)
+	assert.EqualValues(t, 2, count)
+	require.NoError(t, db.Model(&Distro{}).Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+}
+
+func TestForceDeleteDistroCascade(t *testing.T) {
+
+	db, _, _ := newForceDeleteTestDb(t)
+	origSmtp := igor.Email.SmtpServers
+	t.Cleanup(func() { igor.Email.SmtpServers = origSmtp })
+	igor.Email.SmtpServers = []SmtpServerConfig{{Host: "smtp.example.com", Port: DefaultSmtpPort}}
+
+	removed, status, err := deleteDistroAs(&User{Name: IgorAdmin}, "leftover", true)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "leftover", removed.Distro)
+	assert.Equal(t, "img-0123abcd", removed.Image)
+	assert.ElementsMatch(t, []common.RemovedProfile{{Name: "alice-prof", Owner: "alice"}, {Name: "bob-prof", Owner: "bob"}}, removed.Profiles)
+	assert.Contains(t, distroRemovedSummary(removed), "2 profile(s)")
+
+	var count int64
+	require.NoError(t, db.Model(&Profile{}).Count(&count).Error)
+	assert.Zero(t, count)
+	require.NoError(t, db.Model(&DistroImage{}).Count(&count).Error)
+	assert.Zero(t, count)
+	assert.NoDirExists(t, filepath.Join(igor.TFTPPath, igor.ImageStoreDir, "0123abcd"))
+
+	// each owner is told which of their profiles went
+	sent := map[string][]string{}
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-acctNotifyChan:
+			assert.Equal(t, EmailAcctProfilesRemoved, event.Type)
+			assert.Equal(t, "leftover", event.Distro)
+			sent[event.User.Name] = event.Profiles
+		default:
+			t.Fatal("profile owner was not notified")
+		}
+	}
+	assert.Equal(t, map[string][]string{"alice": {"alice-prof"}, "bob": {"bob-prof"}}, sent)
+}
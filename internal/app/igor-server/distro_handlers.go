@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"igor2/internal/pkg/common"
 
@@ -20,15 +21,23 @@ func handleCreateDistro(w http.ResponseWriter, r *http.Request) {
 	actionPrefix := "create distro"
 	rb := common.NewResponseBody()
 	var distro *Distro
+	var fetched []common.ImageFetchData
+	var copied *common.DistroCopyData
 	var status int
 	var err error
 
-	distro, status, err = doCreateDistro(r)
+	distro, fetched, copied, status, err = doCreateDistro(r)
 
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
-		rb.Data["distro"] = filterDistroList([]Distro{*distro})
+		rb.Data["distro"] = filterDistroList([]Distro{*distro}, getUserFromContext(r))
+		if len(fetched) > 0 {
+			rb.Data["fetched"] = fetched
+		}
+		if copied != nil {
+			rb.Data["copied"] = copied
+		}
 		clog.Info().Msgf("%s success - '%s' created", actionPrefix, distro.Name)
 	}
 
@@ -43,6 +52,12 @@ func handleReadDistro(w http.ResponseWriter, r *http.Request) {
 	rb := common.NewResponseBody()
 	var distroInfo []Distro
 
+	// verify and unused aren't search params so pull them before parsing the rest
+	verify := queryParams.Get("verify") == "true"
+	queryParams.Del("verify")
+	unused := queryParams.Get("unused")
+	queryParams.Del("unused")
+
 	searchParams, status, err := parseDistroReadParams(queryParams)
 	if err == nil && status != http.StatusNotFound {
 		distroInfo, status, err = doReadDistros(searchParams, r)
@@ -50,13 +65,25 @@ func handleReadDistro(w http.ResponseWriter, r *http.Request) {
 		status = http.StatusOK
 	}
 
+	if err == nil && unused != "" {
+		// the validator already checked the window parses
+		window, _ := common.ParseDuration(unused)
+		if distroInfo, err = filterUnusedDistros(distroInfo, time.Now().Add(-window), igor.IGormDb.GetDB()); err != nil {
+			status = http.StatusInternalServerError
+		}
+	}
+
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
 		if len(distroInfo) == 0 {
 			rb.Message = "search returned no results"
 		} else {
-			rb.Data["distros"] = filterDistroList(distroInfo)
+			distroList := filterDistroList(distroInfo, getUserFromContext(r))
+			if verify {
+				checkDistroImageFiles(distroInfo, distroList, userElevated(getUserFromContext(r).Name))
+			}
+			rb.Data["distros"] = distroList
 		}
 	}
 
@@ -78,16 +105,22 @@ func handleUpdateDistro(w http.ResponseWriter, r *http.Request) {
 	var err error
 	var dList []Distro
 
+	var lostAccess []common.DistroAccessLossData
+
 	dList, status, err = getDistrosTx([]string{distroName})
 	if err == nil {
 		distro := dList[0]
 		// execute update process
-		status, err = doUpdateDistro(&distro, r)
+		lostAccess, status, err = doUpdateDistro(&distro, r)
 	}
 
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
+		if len(lostAccess) > 0 {
+			rb.Data["lostAccess"] = lostAccess
+			rb.Message = distroAccessLossMessage(distroName, lostAccess)
+		}
 		clog.Info().Msgf("%s success - '%s' updated", actionPrefix, distroName)
 	}
 
@@ -105,11 +138,22 @@ func handleDeleteDistro(w http.ResponseWriter, r *http.Request) {
 	actionPrefix := "delete distro"
 	rb := common.NewResponseBody()
 
-	status, err := doDeleteDistro(distroName, r)
+	force := strings.EqualFold(r.URL.Query().Get("force"), "true")
+	if force {
+		actionPrefix = "force delete distro"
+	}
+
+	removed, status, err := doDeleteDistro(distroName, force, r)
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
-		clog.Info().Msgf("%s success - '%s' deleted", actionPrefix, distroName)
+		if force {
+			rb.Data["removed"] = removed
+			rb.Message = distroRemovedSummary(removed)
+			clog.Info().Msgf("%s success - '%s' deleted with %d profile(s)", actionPrefix, distroName, len(removed.Profiles))
+		} else {
+			clog.Info().Msgf("%s success - '%s' deleted", actionPrefix, distroName)
+		}
 	}
 
 	makeJsonResponse(w, status, rb)
@@ -126,7 +170,6 @@ func validateDistroParams(handler http.Handler) http.Handler {
 			if validateErr = r.ParseMultipartForm(MaxMemory); validateErr != nil {
 				clog.Warn().Msgf("validateDistroParams - %v", validateErr)
 				createValidationErrMessage(validateErr, w)
-				handler.ServeHTTP(w, r)
 				return
 			}
 			distroParams := r.PostForm
@@ -135,10 +178,13 @@ func validateDistroParams(handler http.Handler) http.Handler {
 				copyDistro := r.FormValue("copyDistro")
 				useDistroImage := r.FormValue("useDistroImage")
 				imageRef := r.FormValue("imageRef")
+				_, hasCopyFields := distroParams["copyFields"]
 				if name == "" {
 					validateErr = NewMissingParamError("name")
-				} else if copyDistro == "" && useDistroImage == "" && imageRef == "" && (len(r.MultipartForm.File) < 1) {
-					validateErr = fmt.Errorf("a new distro must have ONE of the following: existing distro, existing image, image ref, or kernel file AND initrd file")
+				} else if hasCopyFields && copyDistro == "" {
+					validateErr = fmt.Errorf("copyFields can only be used when copying an existing distro")
+				} else if copyDistro == "" && useDistroImage == "" && imageRef == "" && (len(r.MultipartForm.File) < 1) && !hasImageURLs(r) {
+					validateErr = fmt.Errorf("a new distro must have ONE of the following: existing distro, existing image, image ref, kernel file AND initrd file, or kernel URL AND initrd URL")
 				} else {
 
 				postPutParamLoop:
@@ -162,6 +208,10 @@ func validateDistroParams(handler http.Handler) http.Handler {
 							if validateErr = checkDistroNameRules(val[0]); validateErr != nil {
 								break postPutParamLoop
 							}
+						case "copyFields":
+							if _, validateErr = parseDistroCopyFields(val[0]); validateErr != nil {
+								break postPutParamLoop
+							}
 						case "useDistroImage":
 							if validateErr = checkDistroNameRules(val[0]); validateErr != nil {
 								break postPutParamLoop
@@ -183,12 +233,21 @@ func validateDistroParams(handler http.Handler) http.Handler {
 								break postPutParamLoop
 							}
 						case "kernelArgs":
-							// already a valid string
-							continue
+							if validateErr = checkRequestKernelArgs(val[0], r); validateErr != nil {
+								break postPutParamLoop
+							}
 						case "kickstart":
 							if validateErr = checkFileRules(val[0]); validateErr != nil {
 								break postPutParamLoop
 							}
+						case "kernelUrl", "initrdUrl":
+							if _, validateErr = checkImageURL(val[0]); validateErr != nil {
+								break postPutParamLoop
+							}
+						case "kernelSha256", "initrdSha256":
+							if validateErr = checkSha256(val[0]); validateErr != nil {
+								break postPutParamLoop
+							}
 						case "boot":
 							for _, v := range val {
 								isValid := false
@@ -259,6 +318,16 @@ func validateDistroParams(handler http.Handler) http.Handler {
 							validateErr = fmt.Errorf("default flag must be true")
 							break queryParamLoop
 						}
+					case "verify":
+						if vals[0] != "true" && vals[0] != "false" {
+							validateErr = fmt.Errorf("verify value must be true or false")
+							break queryParamLoop
+						}
+					case "unused":
+						if d, err := common.ParseDuration(vals[0]); err != nil || d <= 0 {
+							validateErr = fmt.Errorf("unused value must be a positive duration like 180d")
+							break queryParamLoop
+						}
 					default:
 						validateErr = NewUnknownParamError(key, vals)
 						break queryParamLoop
@@ -274,7 +343,6 @@ func validateDistroParams(handler http.Handler) http.Handler {
 			if validateErr = r.ParseMultipartForm(MaxMemory); validateErr != nil {
 				clog.Warn().Msgf("validateDistroParams - %v", validateErr)
 				createValidationErrMessage(validateErr, w)
-				handler.ServeHTTP(w, r)
 				return
 			}
 			distroParams := r.PostForm
@@ -318,6 +386,11 @@ func validateDistroParams(handler http.Handler) http.Handler {
 							validateErr = fmt.Errorf("'%s' is not an acceptable value for public parameter (must be 'true')", vals[0])
 							break patchParamLoop
 						}
+					case "notify":
+						if strings.ToLower(vals[0]) != "true" {
+							validateErr = fmt.Errorf("'%s' is not an acceptable value for parameter \"notify\" (must be 'true')", vals[0])
+							break patchParamLoop
+						}
 					case "default":
 						makeDefault := strings.ToLower(vals[0])
 						if makeDefault != "true" {
@@ -331,12 +404,17 @@ func validateDistroParams(handler http.Handler) http.Handler {
 							break patchParamLoop
 						}
 					case "kernelArgs":
-						// already a valid string
-						continue
+						if validateErr = checkRequestKernelArgs(vals[0], r); validateErr != nil {
+							break patchParamLoop
+						}
 					case "kickstart":
 						if validateErr = checkGenericNameRules(vals[0]); validateErr != nil {
 							break patchParamLoop
 						}
+					case "usageNotes":
+						if validateErr = checkDistroNotes(vals[0]); validateErr != nil {
+							break patchParamLoop
+						}
 					default:
 						validateErr = NewUnknownParamError(key, vals)
 						break patchParamLoop
@@ -5,6 +5,8 @@
 package igorserver
 
 import (
+	"os"
+	"path/filepath"
 	"sort"
 
 	"igor2/internal/pkg/common"
@@ -37,6 +39,69 @@ type DistroImage struct {
 	BiosBoot  bool `gorm:"notNull; default:false"`
 	UefiBoot  bool `gorm:"notNull; default:false"`
 	Distros   []Distro
+	// file sizes recorded at registration, 0 if the image predates this
+	KernelSize int64
+	InitrdSize int64
+	// sizes of files given compressed at registration, as given and expanded, 0 if not compressed
+	KernelCompressedSize   int64
+	KernelDecompressedSize int64
+	InitrdCompressedSize   int64
+	InitrdDecompressedSize int64
+}
+
+// imageFiles returns the names of the files backing the image mapped to the size recorded
+// for each when the image was registered.
+func (di *DistroImage) imageFiles() map[string]int64 {
+	switch di.Type {
+	case DistroKI:
+		return map[string]int64{di.Kernel: di.KernelSize, di.Initrd: di.InitrdSize}
+	default:
+		return nil
+	}
+}
+
+// checkImageFiles stats each file backing the image in the image store and reports whether it
+// is present along with its size and modification time. If compareSizes is true the size of
+// each file is also checked against the size recorded when the image was registered.
+func checkImageFiles(image *DistroImage, compareSizes bool) (files []common.ImageFileData, missing bool) {
+
+	imagePath := filepath.Join(igor.TFTPPath, igor.ImageStoreDir, image.ImageID)
+
+	for name, regSize := range image.imageFiles() {
+		fileData := common.ImageFileData{Name: name}
+		if fi, err := os.Stat(filepath.Join(imagePath, name)); err == nil {
+			fileData.Present = true
+			fileData.Size = fi.Size()
+			fileData.ModTime = fi.ModTime().Unix()
+		} else {
+			missing = true
+		}
+		if compareSizes && regSize > 0 {
+			fileData.RegisteredSize = regSize
+			fileData.SizeMismatch = fileData.Present && fileData.Size != regSize
+		}
+		files = append(files, fileData)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	return
+}
+
+// checkDistroImageFiles adds image file health to each entry of distroList using the
+// images of the matching distros.
+func checkDistroImageFiles(distros []Distro, distroList []common.DistroData, compareSizes bool) {
+	images := make(map[string]*DistroImage, len(distros))
+	for i := range distros {
+		images[distros[i].Name] = &distros[i].DistroImage
+	}
+	for i := range distroList {
+		if image, ok := images[distroList[i].Name]; ok {
+			distroList[i].ImageFiles, distroList[i].ImageMissing = checkImageFiles(image, compareSizes)
+		}
+	}
 }
 
 func filterDistroImagesList(distroImages []DistroImage) []common.DistroImageData {
@@ -68,6 +133,13 @@ func filterDistroImagesList(distroImages []DistroImage) []common.DistroImageData
 			Breed:     image.Breed,
 			Local:     local,
 			Boot:      boot,
+
+			KernelSize:             image.KernelSize,
+			InitrdSize:             image.InitrdSize,
+			KernelCompressedSize:   image.KernelCompressedSize,
+			KernelDecompressedSize: image.KernelDecompressedSize,
+			InitrdCompressedSize:   image.InitrdCompressedSize,
+			InitrdDecompressedSize: image.InitrdDecompressedSize,
 		})
 	}
 
@@ -0,0 +1,198 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	zl "github.com/rs/zerolog"
+)
+
+// Settings of imageCompression.store
+const (
+	ImageStoreDecompressed = "decompressed"
+	ImageStoreCompressed   = "compressed"
+)
+
+// imageDecompressors maps the extension of each compressed file type accepted for image files to the
+// command that writes its decompressed content to stdout.
+var imageDecompressors = map[string][]string{
+	".xz":  {"xz", "--decompress", "--stdout"},
+	".zst": {"zstd", "--decompress", "--stdout", "--quiet"},
+}
+
+// imageCompression returns the extension of name if it is a compressed file type igor can
+// decompress, or an empty string otherwise.
+func imageCompression(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if _, ok := imageDecompressors[ext]; ok {
+		return ext
+	}
+	return ""
+}
+
+// stagedImageFile is a compressed image file in the stage path after it has been decompressed.
+type stagedImageFile struct {
+	// Name is the file to register, the decompressed file or the original if it is kept compressed
+	Name             string
+	CompressedSize   int64
+	DecompressedSize int64
+	// SHA256 is the digest of the decompressed content
+	SHA256 string
+}
+
+// decompressStagedFile streams the compressed file name in the stage path through its decompressor,
+// stopping if it expands to more than imageCompression.maxSize. Unless keepCompressed is set the
+// content is written beside it without the compression extension. If wantDigest is given the
+// decompressed content must match it. The original file is left in place.
+func decompressStagedFile(name, wantDigest string, keepCompressed bool) (staged *stagedImageFile, err error) {
+
+	ext := imageCompression(name)
+	args := imageDecompressors[ext]
+	if _, lErr := exec.LookPath(args[0]); lErr != nil {
+		return nil, fmt.Errorf("%s is compressed but %s is not installed on the server to decompress it", name, args[0])
+	}
+
+	srcPath := filepath.Join(igor.Server.ImageStagePath, name)
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return nil, err
+	}
+	staged = &stagedImageFile{Name: name, CompressedSize: fi.Size()}
+
+	out := io.Discard
+	if !keepCompressed {
+		staged.Name = strings.TrimSuffix(name, name[len(name)-len(ext):])
+		if fErr := checkFileRules(staged.Name); fErr != nil {
+			return nil, fmt.Errorf("%s does not have a usable name once decompressed", name)
+		}
+		outPath := filepath.Join(igor.Server.ImageStagePath, staged.Name)
+		file, oErr := os.OpenFile(outPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(oErr, os.ErrExist) {
+			return nil, &FileAlreadyExistsError{msg: fmt.Sprintf("File already exists: %s", outPath)}
+		} else if oErr != nil {
+			return nil, oErr
+		}
+		defer func() {
+			file.Close()
+			if err != nil {
+				_ = os.Remove(outPath)
+			}
+		}()
+		out = file
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = src
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	maxBytes := int64(igor.ImageCompression.MaxSize) * 1024 * 1024
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(out, hash), io.LimitReader(stdout, maxBytes+1))
+	if err == nil && written > maxBytes {
+		err = fmt.Errorf("%s expands to more than the %d MB limit", name, igor.ImageCompression.MaxSize)
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, err
+	}
+	if err = cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("unable to decompress %s - %s", name, strings.TrimSpace(stderr.String()))
+	}
+	if f, ok := out.(*os.File); ok {
+		if err = f.Sync(); err != nil {
+			return nil, err
+		}
+	}
+
+	staged.DecompressedSize = written
+	staged.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	if wantDigest != "" && !strings.EqualFold(wantDigest, staged.SHA256) {
+		err = fmt.Errorf("sha256 of %s decompressed is %s, expected %s", name, staged.SHA256, wantDigest)
+		return nil, err
+	}
+	return staged, nil
+}
+
+// decompressStagedImage decompresses any compressed files of the staged image and points the image
+// at the files to register in their place. The digest checks of kernelSha256 and initrdSha256 are made
+// against the decompressed content. It returns the decompressed digest of each file by its original name.
+// If anything fails no file is left behind other than the ones originally staged.
+func decompressStagedImage(image *DistroImage, r *http.Request, clog *zl.Logger) (digests map[string]string, status int, err error) {
+
+	if image.Type != DistroKI {
+		return nil, http.StatusOK, nil
+	}
+
+	files := []struct {
+		name           *string
+		wantDigest     string
+		keepCompressed bool
+		compressed     *int64
+		decompressed   *int64
+	}{
+		{&image.Kernel, r.FormValue("kernelSha256"), false, &image.KernelCompressedSize, &image.KernelDecompressedSize},
+		{&image.Initrd, r.FormValue("initrdSha256"), igor.ImageCompression.Store == ImageStoreCompressed,
+			&image.InitrdCompressedSize, &image.InitrdDecompressedSize},
+	}
+
+	// the files decompressed so far, removed again if a later one fails
+	var created []string
+	digests = map[string]string{}
+	var originals []string
+
+	for _, f := range files {
+		if imageCompression(*f.name) == "" {
+			continue
+		}
+		staged, dErr := decompressStagedFile(*f.name, f.wantDigest, f.keepCompressed)
+		if dErr != nil {
+			_ = deleteStagedFiles(created)
+			var faeErr *FileAlreadyExistsError
+			if errors.As(dErr, &faeErr) {
+				return nil, http.StatusConflict, dErr
+			}
+			return nil, http.StatusBadRequest, dErr
+		}
+		clog.Info().Msgf("decompressed image file %s (%d bytes expanded to %d, sha256 %s)", *f.name,
+			staged.CompressedSize, staged.DecompressedSize, staged.SHA256)
+		digests[*f.name] = staged.SHA256
+		if staged.Name != *f.name {
+			created = append(created, filepath.Join(igor.Server.ImageStagePath, staged.Name))
+			originals = append(originals, filepath.Join(igor.Server.ImageStagePath, *f.name))
+		}
+		*f.name = staged.Name
+		*f.compressed = staged.CompressedSize
+		*f.decompressed = staged.DecompressedSize
+	}
+
+	// the decompressed files are registered in place of the originals
+	_ = deleteStagedFiles(originals)
+	return digests, http.StatusOK, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// digests of the content of the testdata fixtures once decompressed
+const (
+	fixtureKernelSha256 = "5fffbb4a597bb3abb46858d0ef92d6cf76f9670a912e65fdbda10b7671725281"
+	fixtureInitrdSha256 = "98b4cc61675906a108bdebddca79f7720789895f6c6f008c37663a572c657f8e"
+)
+
+// setupImageDecompress stages copies of the named testdata fixtures in a temporary stage path.
+// The test is skipped if the decompression commands aren't installed.
+func setupImageDecompress(t *testing.T, fixtures ...string) {
+
+	for _, args := range imageDecompressors {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			t.Skipf("%s is not installed", args[0])
+		}
+	}
+
+	origStage, origCompression := igor.Server.ImageStagePath, igor.ImageCompression
+	t.Cleanup(func() { igor.Server.ImageStagePath, igor.ImageCompression = origStage, origCompression })
+
+	igor.Server.ImageStagePath = t.TempDir()
+	igor.ImageCompression.Store = ImageStoreDecompressed
+	igor.ImageCompression.MaxSize = 1
+
+	for _, f := range fixtures {
+		content, err := os.ReadFile(filepath.Join("testdata", f))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(igor.Server.ImageStagePath, f), content, 0644))
+	}
+}
+
+func stagedPath(name string) string {
+	return filepath.Join(igor.Server.ImageStagePath, name)
+}
+
+func TestImageCompression(t *testing.T) {
+	assert.Equal(t, ".xz", imageCompression("vmlinuz.kernel.xz"))
+	assert.Equal(t, ".zst", imageCompression("fs.initrd.ZST"))
+	assert.Empty(t, imageCompression("fs.initrd"))
+	assert.Empty(t, imageCompression("fs.initrd.gz"))
+}
+
+func TestDecompressStagedFile(t *testing.T) {
+
+	setupImageDecompress(t, "vmlinuz.kernel.xz", "fs.initrd.zst", "zeros.initrd.zst")
+
+	staged, err := decompressStagedFile("vmlinuz.kernel.xz", strings.ToUpper(fixtureKernelSha256), false)
+	require.NoError(t, err)
+	assert.Equal(t, "vmlinuz.kernel", staged.Name)
+	assert.Equal(t, int64(84), staged.CompressedSize)
+	assert.Equal(t, int64(17), staged.DecompressedSize)
+	assert.Equal(t, fixtureKernelSha256, staged.SHA256)
+	content, err := os.ReadFile(stagedPath("vmlinuz.kernel"))
+	require.NoError(t, err)
+	assert.Equal(t, "igor test kernel\n", string(content))
+	assert.FileExists(t, stagedPath("vmlinuz.kernel.xz"))
+
+	// the decompressed file is already there
+	_, err = decompressStagedFile("vmlinuz.kernel.xz", "", false)
+	var faeErr *FileAlreadyExistsError
+	assert.ErrorAs(t, err, &faeErr)
+
+	// the digest is of the decompressed content
+	_, err = decompressStagedFile("fs.initrd.zst", fixtureKernelSha256, false)
+	assert.ErrorContains(t, err, "expected")
+	assert.NoFileExists(t, stagedPath("fs.initrd"))
+
+	// kept compressed, the content is still checked
+	staged, err = decompressStagedFile("fs.initrd.zst", fixtureInitrdSha256, true)
+	require.NoError(t, err)
+	assert.Equal(t, "fs.initrd.zst", staged.Name)
+	assert.Equal(t, int64(3400), staged.DecompressedSize)
+	assert.NoFileExists(t, stagedPath("fs.initrd"))
+
+	// a small file that expands past the limit is stopped
+	_, err = decompressStagedFile("zeros.initrd.zst", "", false)
+	assert.ErrorContains(t, err, "1 MB limit")
+	assert.NoFileExists(t, stagedPath("zeros.initrd"))
+
+	// not really compressed
+	require.NoError(t, os.WriteFile(stagedPath("bad.initrd.xz"), []byte("not xz"), 0644))
+	_, err = decompressStagedFile("bad.initrd.xz", "", false)
+	assert.ErrorContains(t, err, "unable to decompress")
+	assert.NoFileExists(t, stagedPath("bad.initrd"))
+}
+
+func TestDecompressStagedImage(t *testing.T) {
+
+	setupImageDecompress(t, "vmlinuz.kernel.xz", "fs.initrd.xz", "fs.initrd.zst")
+
+	newReq := func(form url.Values) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/images", nil)
+		r.Form = form
+		return r
+	}
+
+	// a failure leaves only the files that were staged
+	image := &DistroImage{Type: DistroKI, Kernel: "vmlinuz.kernel.xz", Initrd: "fs.initrd.xz"}
+	_, status, err := decompressStagedImage(image, newReq(url.Values{"initrdSha256": {fixtureKernelSha256}}), &logger)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.NoFileExists(t, stagedPath("vmlinuz.kernel"))
+	assert.FileExists(t, stagedPath("vmlinuz.kernel.xz"))
+	assert.FileExists(t, stagedPath("fs.initrd.xz"))
+
+	image = &DistroImage{Type: DistroKI, Kernel: "vmlinuz.kernel.xz", Initrd: "fs.initrd.xz"}
+	digests, _, err := decompressStagedImage(image, newReq(url.Values{"kernelSha256": {fixtureKernelSha256}}), &logger)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"vmlinuz.kernel.xz": fixtureKernelSha256, "fs.initrd.xz": fixtureInitrdSha256}, digests)
+	assert.Equal(t, "vmlinuz.kernel", image.Kernel)
+	assert.Equal(t, "fs.initrd", image.Initrd)
+	assert.Equal(t, int64(84), image.KernelCompressedSize)
+	assert.Equal(t, int64(17), image.KernelDecompressedSize)
+	assert.Equal(t, int64(116), image.InitrdCompressedSize)
+	assert.Equal(t, int64(3400), image.InitrdDecompressedSize)
+	assert.FileExists(t, stagedPath("fs.initrd"))
+	assert.NoFileExists(t, stagedPath("vmlinuz.kernel.xz"))
+	assert.NoFileExists(t, stagedPath("fs.initrd.xz"))
+
+	// stored compressed, the initrd is left as it is but the kernel is always expanded
+	require.NoError(t, os.Remove(stagedPath("vmlinuz.kernel")))
+	setupImageDecompress(t, "vmlinuz.kernel.xz", "fs.initrd.zst")
+	igor.ImageCompression.Store = ImageStoreCompressed
+	image = &DistroImage{Type: DistroKI, Kernel: "vmlinuz.kernel.xz", Initrd: "fs.initrd.zst"}
+	_, _, err = decompressStagedImage(image, newReq(url.Values{}), &logger)
+	require.NoError(t, err)
+	assert.Equal(t, "vmlinuz.kernel", image.Kernel)
+	assert.Equal(t, "fs.initrd.zst", image.Initrd)
+	assert.Equal(t, int64(39), image.InitrdCompressedSize)
+	assert.Equal(t, int64(3400), image.InitrdDecompressedSize)
+	assert.FileExists(t, stagedPath("fs.initrd.zst"))
+
+	// nothing to do for files that aren't compressed
+	image = &DistroImage{Type: DistroKI, Kernel: "vmlinuz", Initrd: "initrd.img"}
+	digests, _, err = decompressStagedImage(image, newReq(url.Values{}), &logger)
+	require.NoError(t, err)
+	assert.Empty(t, digests)
+	assert.Equal(t, "vmlinuz", image.Kernel)
+}
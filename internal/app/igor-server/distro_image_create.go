@@ -19,13 +19,15 @@ import (
 
 	"github.com/rs/zerolog/hlog"
 	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
 )
 
 // doRegisterImage calls registerImage in a new transaction.
-func doRegisterImage(r *http.Request) (image *DistroImage, status int, err error) {
+func doRegisterImage(r *http.Request) (image *DistroImage, fetched []common.ImageFetchData, status int, err error) {
 	status = http.StatusInternalServerError
 	err = performDbTx(func(tx *gorm.DB) error {
-		image, status, err = registerImage(r, tx)
+		image, fetched, status, err = registerImage(r, tx)
 		return err
 	})
 	return
@@ -35,9 +37,10 @@ func doRegisterImage(r *http.Request) (image *DistroImage, status int, err error
 // or file name references if an admin placed files into the local staged folder manually.
 // It will then locate and hash the files, check if the hash already exists in the db,
 // if the image is new, store the files to the images folder and create a new image entry (KIref, hash, filenames)
-// then return the new/existing image object
+// then return the new/existing image object. Files can also be downloaded from URLs, in which case
+// what was fetched is returned as well.
 // NOTE: For now, we assume we're only dealing with KI pairs
-func registerImage(r *http.Request, tx *gorm.DB) (image *DistroImage, status int, err error) {
+func registerImage(r *http.Request, tx *gorm.DB) (image *DistroImage, fetched []common.ImageFetchData, status int, err error) {
 	clog := hlog.FromRequest(r)
 	// potential way of determining whether files were included and type based on count?
 	clog.Debug().Msgf("Number of files attached: %v", len(r.MultipartForm.File))
@@ -45,11 +48,21 @@ func registerImage(r *http.Request, tx *gorm.DB) (image *DistroImage, status int
 	// net-boot only: we're getting a KI pair
 	// check for included staged file names, admin may have manually placed files in staging folder for us
 	image = detectStagedFiles(r)
-	if image == nil {
+	if image == nil && hasImageURLs(r) {
+		// download the files to the stage folder
+		image, fetched, err = stageFetchedFiles(r, clog)
+		if err != nil {
+			var faeErr *FileAlreadyExistsError
+			if errors.As(err, &faeErr) {
+				return nil, nil, http.StatusConflict, err
+			}
+			return nil, nil, http.StatusBadRequest, err
+		}
+	} else if image == nil {
 		// we need to pull files from the multiform and stage them
 		image, err = stageUploadedFiles(r)
 		if err != nil {
-			return image, http.StatusInternalServerError, err
+			return image, nil, http.StatusInternalServerError, err
 		}
 	}
 
@@ -76,19 +89,50 @@ func registerImage(r *http.Request, tx *gorm.DB) (image *DistroImage, status int
 	breed := strings.ToLower(r.FormValue("breed"))
 	validBreed := hasValidBreed(breed)
 	if breed != "" && !validBreed {
-		return image, http.StatusBadRequest, fmt.Errorf("invalid value for required image breed - %s", breed)
+		if fetched != nil {
+			destroyStagedImages(image)
+		}
+		return image, nil, http.StatusBadRequest, fmt.Errorf("invalid value for required image breed - %s", breed)
 	}
 	if breed == "" {
 		breed = "generic"
 	}
 	image.Breed = breed
 
+	// expand any compressed files before the image is hashed
+	kOrig, iOrig := image.Kernel, image.Initrd
+	digests, dStatus, dErr := decompressStagedImage(image, r, clog)
+	if dErr != nil {
+		if fetched != nil {
+			destroyStagedImages(image)
+		}
+		return image, nil, dStatus, dErr
+	}
+
 	// ensure image file(s) exist in the image store
+	staged := *image
 	image, err = processImage(image, tx)
 	if err != nil {
-		return image, http.StatusInternalServerError, err
+		if fetched != nil {
+			// nobody placed these files, so don't leave them behind
+			destroyStagedImages(&staged)
+		}
+		return image, nil, http.StatusInternalServerError, err
 	}
-	return image, http.StatusOK, nil
+
+	// report the names the files are stored under, which are those of the existing image if it was a duplicate
+	// and the digests of what was stored if the files were decompressed
+	if fetched != nil {
+		fetched[0].File = image.Kernel
+		fetched[1].File = image.Initrd
+		if d, ok := digests[kOrig]; ok {
+			fetched[0].SHA256 = d
+		}
+		if d, ok := digests[iOrig]; ok {
+			fetched[1].SHA256 = d
+		}
+	}
+	return image, fetched, http.StatusOK, nil
 }
 
 // stageUploadedFiles extracts files inside the multipart form and saves them to the
@@ -159,6 +203,13 @@ func processImage(image *DistroImage, tx *gorm.DB) (*DistroImage, error) {
 			return image, err
 		}
 		image.ImageID = hash
+		// record file sizes so later health checks can detect changed files
+		if fi, sErr := os.Stat(kPath); sErr == nil {
+			image.KernelSize = fi.Size()
+		}
+		if fi, sErr := os.Stat(iPath); sErr == nil {
+			image.InitrdSize = fi.Size()
+		}
 	default:
 		return image, fmt.Errorf("image type not recognized: %v", image.Type)
 	}
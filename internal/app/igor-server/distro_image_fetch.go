@@ -0,0 +1,227 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	zl "github.com/rs/zerolog"
+
+	"igor2/internal/pkg/common"
+)
+
+// fetchProgressInterval is how often the progress of an image download is logged
+const fetchProgressInterval = 10 * time.Second
+
+// imageFetchTransport is used to download image files, the default transport if nil
+var imageFetchTransport http.RoundTripper
+
+// checkImageURL returns an error if rawURL can't be used to download an image file. Only HTTPS URLs
+// to hosts in imageFetch.allowedHosts are accepted.
+func checkImageURL(rawURL string) (*url.URL, error) {
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is not a valid URL - %v", rawURL, err)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("image URL '%s' must use https", rawURL)
+	}
+	if u.User != nil {
+		return nil, fmt.Errorf("image URL '%s' cannot include user credentials", rawURL)
+	}
+	if len(igor.ImageFetch.AllowedHosts) == 0 {
+		return nil, fmt.Errorf("image registration from a URL is not enabled on this server")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, h := range igor.ImageFetch.AllowedHosts {
+		if host == h {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("host '%s' is not an allowed image source", u.Hostname())
+}
+
+// fetchImageFile downloads the file at rawURL into the image stage path and returns its staged file
+// name and sha256 digest. If wantDigest is given the file must match it, unless the file is compressed
+// in which case it is checked against the decompressed content later. A partial or rejected file
+// is removed before returning.
+func fetchImageFile(rawURL, wantDigest string, clog *zl.Logger) (fileName, digest string, err error) {
+
+	u, err := checkImageURL(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	fileName = path.Base(u.Path)
+	if fErr := checkFileRules(fileName); fErr != nil || fileName == "/" || fileName == "." {
+		return "", "", fmt.Errorf("image URL '%s' does not end in a usable file name", rawURL)
+	}
+	if imageCompression(fileName) != "" {
+		wantDigest = ""
+	}
+	filePath := filepath.Join(igor.Server.ImageStagePath, fileName)
+	if _, sErr := os.Stat(filePath); sErr == nil {
+		return "", "", &FileAlreadyExistsError{msg: fmt.Sprintf("File already exists: %s", filePath)}
+	}
+
+	maxBytes := int64(igor.ImageFetch.MaxSize) * 1024 * 1024
+	timeout := time.Duration(igor.ImageFetch.Timeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client := &http.Client{
+		Transport: imageFetchTransport,
+		// a redirect has to stay on an allowed host
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("too many redirects")
+			}
+			_, rErr := checkImageURL(req.URL.String())
+			return rErr
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to download %s - %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("unable to download %s - server returned %s", rawURL, resp.Status)
+	}
+	if resp.ContentLength > maxBytes {
+		return "", "", fmt.Errorf("%s is %d MB which is larger than the %d MB limit", rawURL, resp.ContentLength/1024/1024, igor.ImageFetch.MaxSize)
+	}
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		file.Close()
+		if err != nil {
+			if rmErr := os.Remove(filePath); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+				clog.Warn().Msgf("unable to remove partial image file %s - %v", filePath, rmErr)
+			}
+		}
+	}()
+
+	clog.Info().Msgf("downloading image file %s to %s", rawURL, filePath)
+
+	hash := sha256.New()
+	progress := &fetchProgress{url: rawURL, total: resp.ContentLength, clog: clog, next: time.Now().Add(fetchProgressInterval)}
+	written, err := io.Copy(io.MultiWriter(file, hash, progress), io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("download of %s timed out after %v", rawURL, timeout)
+		} else {
+			err = fmt.Errorf("download of %s failed - %v", rawURL, err)
+		}
+		return "", "", err
+	}
+	if written > maxBytes {
+		err = fmt.Errorf("%s is larger than the %d MB limit", rawURL, igor.ImageFetch.MaxSize)
+		return "", "", err
+	}
+	if err = file.Sync(); err != nil {
+		return "", "", err
+	}
+
+	digest = hex.EncodeToString(hash.Sum(nil))
+	if wantDigest != "" && !strings.EqualFold(wantDigest, digest) {
+		err = fmt.Errorf("sha256 of %s is %s, expected %s", rawURL, digest, wantDigest)
+		return "", "", err
+	}
+
+	clog.Info().Msgf("downloaded image file %s (%d bytes, sha256 %s)", rawURL, written, digest)
+	return fileName, digest, nil
+}
+
+// fetchProgress logs the progress of a download as it is written.
+type fetchProgress struct {
+	url     string
+	total   int64
+	written int64
+	next    time.Time
+	clog    *zl.Logger
+}
+
+func (p *fetchProgress) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if now := time.Now(); now.After(p.next) {
+		p.next = now.Add(fetchProgressInterval)
+		if p.total > 0 {
+			p.clog.Info().Msgf("downloading %s - %d of %d MB (%d%%)", p.url, p.written/1024/1024, p.total/1024/1024, p.written*100/p.total)
+		} else {
+			p.clog.Info().Msgf("downloading %s - %d MB", p.url, p.written/1024/1024)
+		}
+	}
+	return len(b), nil
+}
+
+// stageFetchedFiles downloads the kernel and initrd named by the kernelUrl and initrdUrl form values
+// into the stage path. If the second download fails the first file is removed.
+func stageFetchedFiles(r *http.Request, clog *zl.Logger) (*DistroImage, []common.ImageFetchData, error) {
+
+	kURL, iURL := r.FormValue("kernelUrl"), r.FormValue("initrdUrl")
+	if kURL == "" || iURL == "" {
+		return nil, nil, fmt.Errorf("both a kernel URL and an initrd URL are required")
+	}
+
+	kName, kDigest, err := fetchImageFile(kURL, r.FormValue("kernelSha256"), clog)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	iName, iDigest, err := fetchImageFile(iURL, r.FormValue("initrdSha256"), clog)
+	if err != nil {
+		_ = deleteStagedFiles([]string{filepath.Join(igor.Server.ImageStagePath, kName)})
+		return nil, nil, err
+	}
+
+	image := &DistroImage{
+		Type:   DistroKI,
+		Kernel: kName,
+		Initrd: iName,
+	}
+	fetched := []common.ImageFetchData{
+		{URL: kURL, SHA256: kDigest},
+		{URL: iURL, SHA256: iDigest},
+	}
+	return image, fetched, nil
+}
+
+// checkSha256 returns an error if digest is not a hex encoded sha256 digest.
+func checkSha256(digest string) error {
+	if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("'%s' is not a sha256 digest", digest)
+	}
+	return nil
+}
+
+// hasImageURLs reports whether the request asks for the image files to be downloaded.
+func hasImageURLs(r *http.Request) bool {
+	return r.FormValue("kernelUrl") != "" || r.FormValue("initrdUrl") != ""
+}
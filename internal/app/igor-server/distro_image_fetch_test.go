@@ -0,0 +1,124 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupImageFetch starts an HTTPS server for image files and allows igor to download from it.
+func setupImageFetch(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+
+	srv := httptest.NewTLSServer(handler)
+	u, _ := url.Parse(srv.URL)
+
+	origStage, origFetch, origTransport := igor.Server.ImageStagePath, igor.ImageFetch, imageFetchTransport
+	t.Cleanup(func() {
+		srv.Close()
+		igor.Server.ImageStagePath, igor.ImageFetch, imageFetchTransport = origStage, origFetch, origTransport
+	})
+
+	igor.Server.ImageStagePath = t.TempDir()
+	igor.ImageFetch.AllowedHosts = []string{u.Hostname()}
+	igor.ImageFetch.MaxSize = 1
+	igor.ImageFetch.Timeout = 10
+	imageFetchTransport = srv.Client().Transport
+	return srv
+}
+
+func TestCheckImageURL(t *testing.T) {
+
+	origFetch := igor.ImageFetch
+	defer func() { igor.ImageFetch = origFetch }()
+
+	igor.ImageFetch.AllowedHosts = nil
+	_, err := checkImageURL("https://artifacts.example.com/vmlinuz")
+	assert.ErrorContains(t, err, "not enabled")
+
+	igor.ImageFetch.AllowedHosts = []string{"artifacts.example.com"}
+	_, err = checkImageURL("https://Artifacts.example.com:8443/images/vmlinuz")
+	assert.NoError(t, err)
+	_, err = checkImageURL("http://artifacts.example.com/vmlinuz")
+	assert.ErrorContains(t, err, "https")
+	_, err = checkImageURL("https://169.254.169.254/latest")
+	assert.ErrorContains(t, err, "not an allowed image source")
+	_, err = checkImageURL("https://user:pw@artifacts.example.com/vmlinuz")
+	assert.Error(t, err)
+}
+
+func TestFetchImageFile(t *testing.T) {
+
+	content := []byte("kernel contents")
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+
+	srv := setupImageFetch(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vmlinuz", "/other/vmlinuz":
+			_, _ = w.Write(content)
+		case "/big.initrd":
+			_, _ = w.Write([]byte(strings.Repeat("x", 1024*1024+1)))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	name, got, err := fetchImageFile(srv.URL+"/vmlinuz", digest, &logger)
+	require.NoError(t, err)
+	assert.Equal(t, "vmlinuz", name)
+	assert.Equal(t, digest, got)
+	staged, err := os.ReadFile(filepath.Join(igor.Server.ImageStagePath, "vmlinuz"))
+	require.NoError(t, err)
+	assert.Equal(t, content, staged)
+
+	// a file of the same name is already staged
+	_, _, err = fetchImageFile(srv.URL+"/other/vmlinuz", "", &logger)
+	var faeErr *FileAlreadyExistsError
+	assert.ErrorAs(t, err, &faeErr)
+	require.NoError(t, os.Remove(filepath.Join(igor.Server.ImageStagePath, "vmlinuz")))
+
+	// wrong digest leaves nothing behind
+	_, _, err = fetchImageFile(srv.URL+"/vmlinuz", strings.Repeat("0", 64), &logger)
+	assert.ErrorContains(t, err, "expected")
+	assert.NoFileExists(t, filepath.Join(igor.Server.ImageStagePath, "vmlinuz"))
+
+	// too large
+	_, _, err = fetchImageFile(srv.URL+"/big.initrd", "", &logger)
+	assert.ErrorContains(t, err, "limit")
+	assert.NoFileExists(t, filepath.Join(igor.Server.ImageStagePath, "big.initrd"))
+
+	// not found
+	_, _, err = fetchImageFile(srv.URL+"/missing.kernel", "", &logger)
+	assert.ErrorContains(t, err, "404")
+	assert.NoFileExists(t, filepath.Join(igor.Server.ImageStagePath, "missing.kernel"))
+}
+
+func TestFetchImageFileRedirect(t *testing.T) {
+
+	srv := setupImageFetch(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://elsewhere.example.com/vmlinuz", http.StatusFound)
+	})
+
+	_, _, err := fetchImageFile(srv.URL+"/vmlinuz", "", &logger)
+	assert.ErrorContains(t, err, "not an allowed image source")
+	assert.NoFileExists(t, filepath.Join(igor.Server.ImageStagePath, "vmlinuz"))
+}
+
+func TestCheckSha256(t *testing.T) {
+	assert.NoError(t, checkSha256(strings.Repeat("ab", 32)))
+	assert.Error(t, checkSha256("abc"))
+	assert.Error(t, checkSha256(strings.Repeat("zz", 32)))
+}
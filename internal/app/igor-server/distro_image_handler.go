@@ -20,11 +20,14 @@ func handleRegisterDistroImage(w http.ResponseWriter, r *http.Request) {
 	actionPrefix := "register boot image"
 	rb := common.NewResponseBody()
 
-	image, status, err := doRegisterImage(r)
+	image, fetched, status, err := doRegisterImage(r)
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
 		rb.Data["image"] = image
+		if len(fetched) > 0 {
+			rb.Data["fetched"] = fetched
+		}
 		msg := fmt.Sprintf("igor boot image files registered to refID: %s", image.Name)
 		clog.Info().Msgf("%s success -%s", actionPrefix, msg)
 		rb.Message = msg
@@ -99,6 +102,14 @@ func validateDistroImageParams(handler http.Handler) http.Handler {
 						if validateErr = checkFileRules(val[0]); validateErr != nil {
 							break postPutParamLoop
 						}
+					case "kernelUrl", "initrdUrl":
+						if _, validateErr = checkImageURL(val[0]); validateErr != nil {
+							break postPutParamLoop
+						}
+					case "kernelSha256", "initrdSha256":
+						if validateErr = checkSha256(val[0]); validateErr != nil {
+							break postPutParamLoop
+						}
 					case "localBoot":
 						if len(val) > 0 && strings.ToLower(val[0]) != "true" {
 							validateErr = fmt.Errorf("invalid value for localBoot, must be 'true'")
@@ -0,0 +1,51 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckImageFiles(t *testing.T) {
+
+	origTFTPPath, origStoreDir := igor.TFTPPath, igor.ImageStoreDir
+	defer func() { igor.TFTPPath, igor.ImageStoreDir = origTFTPPath, origStoreDir }()
+	igor.TFTPPath = t.TempDir()
+	igor.ImageStoreDir = "igor_images"
+
+	image := &DistroImage{
+		ImageID:    "abc123",
+		Type:       DistroKI,
+		Kernel:     "vmlinuz",
+		Initrd:     "initrd.img",
+		KernelSize: 10,
+		InitrdSize: 4,
+	}
+	imagePath := filepath.Join(igor.TFTPPath, igor.ImageStoreDir, image.ImageID)
+	assert.NoError(t, os.MkdirAll(imagePath, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(imagePath, image.Kernel), []byte("kernel"), 0644))
+
+	// without size comparison only presence is reported
+	files, missing := checkImageFiles(image, false)
+	assert.True(t, missing)
+	assert.Len(t, files, 2)
+	assert.Equal(t, "initrd.img", files[0].Name)
+	assert.False(t, files[0].Present)
+	assert.Equal(t, "vmlinuz", files[1].Name)
+	assert.True(t, files[1].Present)
+	assert.Equal(t, int64(6), files[1].Size)
+	assert.False(t, files[1].SizeMismatch)
+	assert.Zero(t, files[1].RegisteredSize)
+
+	// with size comparison the kernel no longer matches what was registered
+	files, _ = checkImageFiles(image, true)
+	assert.True(t, files[1].SizeMismatch)
+	assert.Equal(t, int64(10), files[1].RegisteredSize)
+	// a missing file isn't also reported as a size mismatch
+	assert.False(t, files[0].SizeMismatch)
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"html/template"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxDistroNotesSize is the largest distro usage notes snippet in bytes
+const MaxDistroNotesSize = 8 << 10
+
+var (
+	notesScriptPattern = regexp.MustCompile(`(?is)<(script|style)\b.*?(</(script|style)\s*>|$)`)
+	notesTagPattern    = regexp.MustCompile(`(?s)<!--.*?-->|</?[a-zA-Z][^>]*>`)
+	notesCodePattern   = regexp.MustCompile("`([^`]+)`")
+	notesBoldPattern   = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+)
+
+// checkDistroNotes makes sure distro usage notes are text within the size limit.
+func checkDistroNotes(notes string) error {
+	if len(notes) > MaxDistroNotesSize {
+		return fmt.Errorf("usage notes are %d bytes, the limit is %d", len(notes), MaxDistroNotesSize)
+	}
+	if !utf8.ValidString(notes) {
+		return fmt.Errorf("usage notes must be UTF-8 text")
+	}
+	return nil
+}
+
+// renderDistroNotes turns distro usage notes written in a small subset of markdown into HTML for
+// an email. Any HTML in the notes is removed, along with the content of script and style elements,
+// and the rest is escaped before the markdown is applied, so the notes can't add markup of their
+// own. Supported markdown is paragraphs, # headings, - or * bullet lists, ``` code blocks, `code`
+// and **bold**.
+func renderDistroNotes(notes string) template.HTML {
+
+	notes = notesScriptPattern.ReplaceAllString(notes, "")
+	notes = notesTagPattern.ReplaceAllString(notes, "")
+	notes = strings.ReplaceAll(notes, "\r\n", "\n")
+
+	var out strings.Builder
+	var para []string
+	inList, inCode := false, false
+
+	endPara := func() {
+		if len(para) > 0 {
+			out.WriteString("<p>" + strings.Join(para, "<br>") + "</p>\n")
+			para = nil
+		}
+	}
+	endList := func() {
+		if inList {
+			out.WriteString("</ul>\n")
+			inList = false
+		}
+	}
+
+	for _, line := range strings.Split(notes, "\n") {
+
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			if inCode {
+				out.WriteString("</pre>\n")
+			} else {
+				endPara()
+				endList()
+				out.WriteString("<pre>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out.WriteString(template.HTMLEscapeString(line) + "\n")
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			endPara()
+			endList()
+		case strings.HasPrefix(trimmed, "#"):
+			endPara()
+			endList()
+			out.WriteString("<p><b>" + notesInline(strings.TrimLeft(trimmed, "# ")) + "</b></p>\n")
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* "):
+			endPara()
+			if !inList {
+				out.WriteString("<ul>\n")
+				inList = true
+			}
+			out.WriteString("<li>" + notesInline(trimmed[2:]) + "</li>\n")
+		default:
+			endList()
+			para = append(para, notesInline(trimmed))
+		}
+	}
+
+	if inCode {
+		out.WriteString("</pre>\n")
+	}
+	endPara()
+	endList()
+
+	return template.HTML(out.String())
+}
+
+// notesInline escapes a line of notes text and applies the inline markdown.
+func notesInline(s string) string {
+	s = template.HTMLEscapeString(s)
+	s = notesCodePattern.ReplaceAllString(s, "<code>$1</code>")
+	return notesBoldPattern.ReplaceAllString(s, "<b>$1</b>")
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderDistroNotes(t *testing.T) {
+
+	notes := "# Logging in\n" +
+		"The default login is `root`.\nChange it **right away**.\n" +
+		"\n" +
+		"- ssh to the head node\n" +
+		"* run <b onmouseover=\"x()\">setup</b>\n" +
+		"\n" +
+		"```\n" +
+		"cat /etc/motd > <out>\n" +
+		"```\n" +
+		"<style>p {display:none}</style><SCRIPT src=\"evil.js\"></SCRIPT>a & b <!-- hidden -->"
+
+	assert.Equal(t, "<p><b>Logging in</b></p>\n"+
+		"<p>The default login is <code>root</code>.<br>Change it <b>right away</b>.</p>\n"+
+		"<ul>\n<li>ssh to the head node</li>\n<li>run setup</li>\n</ul>\n"+
+		"<pre>cat /etc/motd &gt; \n</pre>\n"+
+		"<p>a &amp; b</p>\n", string(renderDistroNotes(notes)))
+
+	// an unclosed script element removes the rest of the notes
+	assert.Equal(t, "<p>before</p>\n", string(renderDistroNotes("before\n<script>alert(1)\nafter")))
+}
+
+func TestCheckDistroNotes(t *testing.T) {
+	assert.NoError(t, checkDistroNotes("log in as root"))
+	assert.NoError(t, checkDistroNotes(strings.Repeat("x", MaxDistroNotesSize)))
+	assert.Error(t, checkDistroNotes(strings.Repeat("x", MaxDistroNotesSize+1)))
+	assert.Error(t, checkDistroNotes("bad \xff bytes"))
+}
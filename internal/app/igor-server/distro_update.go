@@ -6,21 +6,30 @@ package igorserver
 
 import (
 	"fmt"
+	"igor2/internal/pkg/common"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
+	zl "github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
 
 	"gorm.io/gorm"
 )
 
-func doUpdateDistro(target *Distro, r *http.Request) (code int, err error) {
+// doUpdateDistro applies the changes in the request to the distro. If groups are removed from the
+// distro, lostAccess lists the running and future reservations whose owners could only use the
+// distro through one of those groups.
+func doUpdateDistro(target *Distro, r *http.Request) (lostAccess []common.DistroAccessLossData, code int, err error) {
 
 	clog := &logger
 	if r != nil {
 		clog = hlog.FromRequest(r)
 	}
 	var updateParams map[string]interface{}
+	var removedGroups []string
+	var lostRes []Reservation
 	code = http.StatusInternalServerError // default status, overridden at end if no errors
 
 	if err = performDbTx(func(tx *gorm.DB) error {
@@ -39,6 +48,15 @@ func doUpdateDistro(target *Distro, r *http.Request) (code int, err error) {
 
 		}
 
+		// find who loses access before the group list is changed below
+		if gRemove, ok := updateParams["removeGroup"].([]string); ok && updateParams["isPublic"] == nil {
+			removedGroups = gRemove
+			gAdd, _ := updateParams["addGroup"].([]string)
+			if lostRes, err = dbDistroAccessLost(target, gRemove, gAdd, time.Now(), tx); err != nil {
+				return err
+			}
+		}
+
 		// make sure proposed changes will result in a valid distro
 		if vdupStatus, vdupErr := validateDistroUpdatePermissions(target, updateParams, tx); vdupErr != nil {
 			code = vdupStatus
@@ -63,11 +81,116 @@ func doUpdateDistro(target *Distro, r *http.Request) (code int, err error) {
 			}
 		}
 
+		if len(lostRes) > 0 {
+			distroName := target.Name
+			if newName, ok := updateParams["Name"].(string); ok {
+				distroName = newName
+			}
+			var actionUser *User
+			notify := false
+			if r != nil {
+				actionUser = getUserFromContext(r)
+				notify = actionUser != nil && strings.ToLower(r.FormValue("notify")) == "true"
+			}
+			lostAccess = recordDistroAccessLost(distroName, lostRes, removedGroups, actionUser, notify, clog)
+		}
+
 		code = http.StatusOK
 	}
 	return
 }
 
+// dbDistroAccessLost returns the running and future reservations using the distro whose owners are
+// members of a group being removed from it and of none of the groups it will have afterward.
+func dbDistroAccessLost(distro *Distro, removeNames, addNames []string, now time.Time, tx *gorm.DB) ([]Reservation, error) {
+
+	var remaining []Group
+	for _, g := range distro.Groups {
+		if !slices.Contains(removeNames, g.Name) {
+			remaining = append(remaining, g)
+		}
+	}
+	for _, name := range addNames {
+		remaining = append(remaining, Group{Name: name})
+	}
+
+	resList, err := dbReadReservations(map[string]interface{}{"distro_id": []int{distro.ID}}, nil, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var lost []Reservation
+	for _, res := range resList {
+		if !res.End.After(now) {
+			continue
+		}
+		if res.Owner.isMemberOfAnyGroup(distro.Groups) && !res.Owner.isMemberOfAnyGroup(remaining) {
+			lost = append(lost, res)
+		}
+	}
+	return lost, nil
+}
+
+// recordDistroAccessLost records the loss of distro access in the history of each reservation and,
+// if asked, emails the owners about it.
+func recordDistroAccessLost(distroName string, lostRes []Reservation, removedGroups []string, actionUser *User, notify bool, clog *zl.Logger) []common.DistroAccessLossData {
+
+	var clusterName string
+	if notify {
+		if clusters, cErr := dbReadClustersTx(nil); cErr != nil || len(clusters) == 0 {
+			clog.Error().Msgf("unable to read cluster name - owners losing access to distro '%s' will not be emailed", distroName)
+			notify = false
+		} else {
+			clusterName = clusters[0].Name
+		}
+	}
+
+	groups := strings.Join(removedGroups, ",")
+
+	lostAccess := make([]common.DistroAccessLossData, 0, len(lostRes))
+	for i := range lostRes {
+		res := &lostRes[i]
+		res.Profile.Distro.Name = distroName // in case the same edit renamed it
+		if hErr := res.HistCallback(res, HrUpdated+":distro-access-removed,groups="+groups); hErr != nil {
+			clog.Error().Msgf("failed to record reservation '%s' distro access removal to history", res.Name)
+		}
+		loss := common.DistroAccessLossData{
+			Reservation: res.Name,
+			Owner:       res.Owner.Name,
+			Start:       res.Start.Unix(),
+			End:         res.End.Unix(),
+		}
+		if notify {
+			if event := makeResEditNotifyEvent(EmailResDistroAccess, res, clusterName, actionUser, false, groups); event != nil {
+				resNotifyChan <- *event
+				loss.Notified = true
+			}
+		}
+		lostAccess = append(lostAccess, loss)
+	}
+	return lostAccess
+}
+
+// distroAccessLossMessage warns the user editing a distro about reservation owners who lost access to it.
+func distroAccessLossMessage(distroName string, lostAccess []common.DistroAccessLossData) string {
+	var resList []string
+	notified := 0
+	for _, l := range lostAccess {
+		resList = append(resList, fmt.Sprintf("%s (%s)", l.Reservation, l.Owner))
+		if l.Notified {
+			notified++
+		}
+	}
+	msg := fmt.Sprintf("distro '%s' updated - warning: the owners of %d running or future reservation(s) no longer have access to it: %s",
+		distroName, len(lostAccess), strings.Join(resList, ", "))
+	if notified > 0 {
+		msg += fmt.Sprintf(" (%d owner email(s) sent)", notified)
+	} else {
+		msg += " (use --notify to email them)"
+	}
+	return msg
+}
+
 func parseDistroUpdateParams(target *Distro, r *http.Request, tx *gorm.DB) (map[string]interface{}, int, error) {
 	changes := map[string]interface{}{}
 
@@ -81,7 +204,7 @@ func parseDistroUpdateParams(target *Distro, r *http.Request, tx *gorm.DB) (map[
 		if findErr != nil {
 			return nil, http.StatusInternalServerError, findErr // uses default err code
 		} else if found {
-			return nil, http.StatusConflict, fmt.Errorf("%s already in use as distro name", name)
+			return nil, http.StatusConflict, newCodedError(common.ErrNameTaken, "%s already in use as distro name", name)
 		}
 		changes["Name"] = name
 	}
@@ -97,9 +220,13 @@ func parseDistroUpdateParams(target *Distro, r *http.Request, tx *gorm.DB) (map[
 			err := fmt.Errorf("distro kernel args cannot be updated while associated to active Reservations: %s", activeRes)
 			return nil, status, err
 		} else {
-			changes["kernel_args"] = strings.TrimSpace(ka[0])
+			changes["kernel_args"] = normalizeKernelArgs(ka[0])
 		}
 	}
+	// check usage notes, an empty value removes them
+	if notes, ok := r.PostForm["usageNotes"]; ok {
+		changes["usage_notes"] = strings.TrimSpace(notes[0])
+	}
 	// check kickstart
 	if ks, ok := r.PostForm["kickstart"]; ok {
 		// make sure distro isn't currently being used
@@ -292,7 +419,7 @@ func validateDistroUpdatePermissions(target *Distro, updateParams map[string]int
 	// now we can check if the intended owner is a member of all intended outcome groups
 	member, badGroup := intendedOwner.isMemberOfGroups(currentGroups)
 	if !member && intendedOwner.Name != IgorAdmin {
-		return http.StatusBadRequest, fmt.Errorf("intended distro owner %s is not a member of group(s) %s", intendedOwner.Name, badGroup)
+		return http.StatusBadRequest, newCodedError(common.ErrNotGroupMember, "intended distro owner %s is not a member of group(s) %s", intendedOwner.Name, badGroup)
 	}
 
 	return http.StatusOK, nil
@@ -0,0 +1,53 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+)
+
+func TestDistroAccessLost(t *testing.T) {
+
+	hosts := seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	res := newStartTestRes(t, db, "running", hosts[:1], false, 0)
+	team := Group{Name: "team"}
+	other := Group{Name: "other"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&team).Error)
+	require.NoError(t, db.Omit(clause.Associations).Create(&other).Error)
+	require.NoError(t, db.Model(&res.Owner).Association("Groups").Append(&team))
+
+	distro := Distro{Name: "centos", Groups: []Group{team, other}}
+	require.NoError(t, db.Omit("Groups.*").Create(&distro).Error)
+	profile := Profile{Name: "p", OwnerID: res.OwnerID, DistroID: distro.ID}
+	require.NoError(t, db.Omit(clause.Associations).Create(&profile).Error)
+	require.NoError(t, db.Model(&Reservation{}).Where("id = ?", res.ID).Update("profile_id", profile.ID).Error)
+
+	now := time.Now()
+	lost, err := dbDistroAccessLost(&distro, []string{"team"}, nil, now, db)
+	require.NoError(t, err)
+	require.Len(t, lost, 1)
+	assert.Equal(t, "running", lost[0].Name)
+
+	// removing a group the owner isn't in, or adding one back that they are in, changes nothing
+	lost, err = dbDistroAccessLost(&distro, []string{"other"}, nil, now, db)
+	require.NoError(t, err)
+	assert.Empty(t, lost)
+	lost, err = dbDistroAccessLost(&distro, []string{"team"}, []string{GroupAll}, now, db)
+	require.NoError(t, err)
+	assert.Empty(t, lost)
+
+	// reservations that are over don't count
+	lost, err = dbDistroAccessLost(&distro, []string{"team"}, nil, now.Add(2*time.Hour), db)
+	require.NoError(t, err)
+	assert.Empty(t, lost)
+	assert.Len(t, distro.Groups, 2)
+}
@@ -8,16 +8,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"igor2/internal/pkg/naming"
 )
 
 func checkDistroNameRules(name string) error {
-	if len(name) == 0 {
-		return fmt.Errorf("distro name cannot be empty")
-	}
-	if !stdNameCheckPattern.MatchString(name) {
-		return fmt.Errorf("'%s' is not a legal distro name", name)
-	}
-	return isResourceNameMatch(name)
+	return naming.Check(naming.Distro, name)
 }
 
 func checkDistroImageRefRules(ref string) error {
@@ -5,8 +5,10 @@
 package igorserver
 
 import (
+	"errors"
 	"fmt"
 	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
 	"net/http"
 	"reflect"
 	"time"
@@ -16,6 +18,7 @@ import (
 
 func stdErrorResp(rb common.ResponseBody, status int, actionPrefix string, err error, clog *zl.Logger) {
 	rb.SetMessage(err.Error())
+	rb.SetErrorCode(errorCodeOf(err))
 	if status >= http.StatusInternalServerError {
 		clog.Error().Msgf("%s error - %v", actionPrefix, err)
 	} else {
@@ -23,6 +26,66 @@ func stdErrorResp(rb common.ResponseBody, status int, actionPrefix string, err e
 	}
 }
 
+// CodedError attaches one of the common error codes to an error so the code reaches the client
+// in the response body.
+type CodedError struct {
+	code string
+	err  error
+}
+
+// newCodedError makes a CodedError with a message built like fmt.Errorf.
+func newCodedError(code string, format string, a ...interface{}) *CodedError {
+	return &CodedError{code: code, err: fmt.Errorf(format, a...)}
+}
+
+func (e *CodedError) Error() string { return e.err.Error() }
+
+func (e *CodedError) Unwrap() error { return e.err }
+
+// errorCodeOf returns the error code carried by err or implied by its type. It returns an empty
+// string when err has no code, leaving makeJsonResponse to pick one from the response status.
+func errorCodeOf(err error) string {
+	var codedErr *CodedError
+	var badCredsErr *BadCredentialsError
+	var badParamErr *BadParamTypeError
+	var unknownParamErr *UnknownParamError
+	var missingParamErr *MissingParamError
+	var fileExistsErr *FileAlreadyExistsError
+	var policyErr *HostPolicyConflictError
+	var fullErr *ScheduleFullError
+	var nameErr *naming.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &codedErr):
+		return codedErr.code
+	case errors.As(err, &badCredsErr):
+		return common.ErrAuth
+	case errors.As(err, &badParamErr):
+		return common.ErrBadParamType
+	case errors.As(err, &unknownParamErr):
+		return common.ErrUnknownParam
+	case errors.As(err, &missingParamErr):
+		return common.ErrMissingParam
+	case errors.As(err, &nameErr):
+		return common.ErrNameRule
+	case errors.As(err, &fileExistsErr):
+		return common.ErrNameTaken
+	case errors.As(err, &fullErr):
+		return common.ErrResConflict
+	case errors.As(err, &policyErr):
+		switch {
+		case policyErr.groupConflict:
+			return common.ErrPolicyGroup
+		case policyErr.durationConflict:
+			return common.ErrPolicyDuration
+		case policyErr.scheduleConflict:
+			return common.ErrPolicySchedule
+		}
+	}
+	return ""
+}
+
 // BadCredentialsError is invoked when either username or password
 // is incorrect or unrecognized (bad)
 type BadCredentialsError struct {
@@ -95,6 +158,81 @@ type FileAlreadyExistsError struct {
 
 func (e *FileAlreadyExistsError) Error() string { return e.msg }
 
+// PowerHostsError is used when a power command failed for some or all of the hosts it was run
+// on. It holds the error of each host it failed for.
+type PowerHostsError struct {
+	action string
+	failed map[string]error
+}
+
+func (e *PowerHostsError) Error() string {
+	hosts := make([]string, 0, len(e.failed))
+	for h := range e.failed {
+		hosts = append(hosts, h)
+	}
+	return fmt.Sprintf("power %s failed for host(s) %s", e.action, common.UnsplitList(hosts))
+}
+
+// ScheduleFullError is used when not enough hosts are free for a reservation at its start time. It
+// holds how many hosts are free then and, if a search was made, the earliest time enough of them are.
+type ScheduleFullError struct {
+	numHostsReq    int
+	numAvail       int
+	start          time.Time
+	startIsNow     bool
+	searched       bool
+	suggestedStart time.Time
+}
+
+func (e *ScheduleFullError) Error() string {
+
+	const timeFmt = "Jan 2 15:04"
+	when := "now"
+	if !e.startIsNow {
+		when = "at " + e.start.Format(timeFmt)
+	}
+
+	msg := fmt.Sprintf("%s not available %s", nodeCount(e.numHostsReq), when)
+	if !e.suggestedStart.IsZero() {
+		msg += fmt.Sprintf("; %s free starting %s", nodeCount(e.numHostsReq), e.suggestedStart.Format(timeFmt))
+		if e.numAvail > 0 {
+			msg += fmt.Sprintf(", or %s available %s", nodeCount(e.numAvail), when)
+		}
+	} else {
+		if e.numAvail > 0 {
+			msg += fmt.Sprintf("; %s available %s", nodeCount(e.numAvail), when)
+		}
+		if e.searched {
+			msg += "; no later start before the end of the schedule has enough free for this duration"
+		}
+	}
+	return msg + "."
+}
+
+// HostRejectionError wraps the error that stopped a reservation on named hosts with the reason
+// each rejected host can't be used and the hosts that could be. The error code is that of the cause.
+type HostRejectionError struct {
+	cause     error
+	rejected  []common.HostRejectionData
+	available []string
+}
+
+func (e *HostRejectionError) Error() string {
+	return e.cause.Error()
+}
+
+func (e *HostRejectionError) Unwrap() error {
+	return e.cause
+}
+
+// nodeCount returns n followed by node or nodes.
+func nodeCount(n int) string {
+	if n == 1 {
+		return "1 node"
+	}
+	return fmt.Sprintf("%d nodes", n)
+}
+
 type HostPolicyConflictError struct {
 	msg              string
 	groupConflict    bool
@@ -103,20 +241,40 @@ type HostPolicyConflictError struct {
 	scStart          time.Time
 	scEnd            time.Time
 	conflictHosts    []Host
+	// excludedGroup is set for a group conflict caused by the user being in a group the policy excludes
+	excludedGroup string
 }
 
 func (e *HostPolicyConflictError) Error() string {
 
 	relevantHosts := namesOfHosts(e.conflictHosts)
 
-	if e.groupConflict {
+	if e.groupConflict && e.excludedGroup != "" {
+		e.msg = fmt.Sprintf("the following hosts are policy-restricted and unavailable to members of group '%s': %v", e.excludedGroup, relevantHosts)
+	} else if e.groupConflict {
 		e.msg = fmt.Sprintf("the following hosts are policy-restricted and unavailable to the user: %v", relevantHosts)
 	} else if e.durationConflict {
 		e.msg = fmt.Sprintf("%v; reservation duration exceeds maximum allowed for the following policy-restricted hosts: %v", e.msg, relevantHosts)
 	} else if e.scheduleConflict {
-		e.msg = fmt.Sprintf("%v; the following policy-restricted hosts: %v are unavailable for the proposed duration during the times %v and %v", e.msg, relevantHosts, e.scStart, e.scEnd)
+		e.msg = fmt.Sprintf("the following policy-restricted hosts: %v are unavailable for the proposed duration during the times %v and %v", relevantHosts, e.scStart, e.scEnd)
 	} else {
 		e.msg = "unknown error has occurred during policy check"
 	}
 	return e.msg
 }
+
+// hostReason describes the conflict as it applies to any one of its hosts.
+func (e *HostPolicyConflictError) hostReason() string {
+	switch {
+	case e.groupConflict && e.excludedGroup != "":
+		return fmt.Sprintf("host policy excludes group '%s'", e.excludedGroup)
+	case e.groupConflict:
+		return "host policy restricts it to other groups"
+	case e.durationConflict:
+		return "duration exceeds host policy maximum"
+	case e.scheduleConflict:
+		return fmt.Sprintf("host policy makes it unavailable %s to %s",
+			e.scStart.Format(common.DateTimeCompactFormat), e.scEnd.Format(common.DateTimeCompactFormat))
+	}
+	return "host policy conflict"
+}
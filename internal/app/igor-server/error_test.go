@@ -0,0 +1,74 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
+)
+
+func TestErrorCodeOf(t *testing.T) {
+
+	coded := newCodedError(common.ErrNodeLimit, "only admins can make a reservation of more than %v nodes", 10)
+	assert.Equal(t, "only admins can make a reservation of more than 10 nodes", coded.Error())
+
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{coded, common.ErrNodeLimit},
+		{fmt.Errorf("wrapped: %w", coded), common.ErrNodeLimit},
+		{&BadCredentialsError{msg: "bad password"}, common.ErrAuth},
+		{NewBadParamTypeError("name", 1, "string"), common.ErrBadParamType},
+		{NewUnknownParamError("bogus", 1), common.ErrUnknownParam},
+		{NewMissingParamError("name"), common.ErrMissingParam},
+		{&naming.Error{}, common.ErrNameRule},
+		{&FileAlreadyExistsError{msg: "exists"}, common.ErrNameTaken},
+		{&HostPolicyConflictError{groupConflict: true}, common.ErrPolicyGroup},
+		{&HostPolicyConflictError{durationConflict: true}, common.ErrPolicyDuration},
+		{&HostPolicyConflictError{scheduleConflict: true}, common.ErrPolicySchedule},
+		{&HostPolicyConflictError{}, ""},
+		{fmt.Errorf("something broke"), ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		assert.Equalf(t, tt.code, errorCodeOf(tt.err), "error: %v", tt.err)
+	}
+}
+
+func TestMakeJsonResponseErrorCode(t *testing.T) {
+
+	// a failure without a code gets the generic code for its status
+	w := httptest.NewRecorder()
+	rb := common.NewResponseBody()
+	rb.Message = "no such thing"
+	makeJsonResponse(w, http.StatusNotFound, rb)
+	var got common.ResponseBodyBasic
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, common.ErrNotFound, got.ErrorCode)
+
+	// a specific code is kept
+	w = httptest.NewRecorder()
+	rb = common.NewResponseBody()
+	rb.ErrorCode = common.ErrNameTaken
+	makeJsonResponse(w, http.StatusConflict, rb)
+	got = common.ResponseBodyBasic{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, common.ErrNameTaken, got.ErrorCode)
+
+	// successful responses carry no code
+	w = httptest.NewRecorder()
+	makeJsonResponse(w, http.StatusOK, common.NewResponseBody())
+	assert.NotContains(t, w.Body.String(), "errorCode")
+}
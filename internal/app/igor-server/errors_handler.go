@@ -0,0 +1,19 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+
+	"igor2/internal/pkg/common"
+)
+
+// errorCodesHandler returns the registry of error codes attached to failed responses as JSON. It is
+// generated from the common package so it always matches what the server sends.
+func errorCodesHandler(w http.ResponseWriter, _ *http.Request) {
+	rb := common.NewResponseBody()
+	rb.Data["errors"] = common.ErrorCodes()
+	makeJsonResponse(w, http.StatusOK, rb)
+}
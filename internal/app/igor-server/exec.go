@@ -5,6 +5,7 @@
 package igorserver
 
 import (
+	"fmt"
 	"os"
 	"strings"
 	"time"
@@ -28,6 +29,8 @@ func Execute(configFilepath *string) {
 	initConfigCheck()
 	initNotify()
 
+	extCmdTokens = make(chan struct{}, igor.ExternalCmds.ConcurrencyLimit)
+
 	igor.ElevateMap = common.NewPassiveTtlMap(time.Duration(igor.Auth.ElevateTimeout) * time.Minute)
 
 	igor.IPowerStatus = NewNmapPowerStatus()
@@ -47,11 +50,51 @@ func Execute(configFilepath *string) {
 	// need to check igor config to see if nodes have been added or removed
 	syncNodes(hostList)
 
+	// report any drift between reservations, permissions and hosts so it gets looked at
+	logFsckSummary()
+
+	// named networks hold their VLANs out of the range in the config
+	checkNamedNetworkRange()
+
+	if igor.Simulation.Enabled {
+		igor.IPowerStatus = NewSimPowerStatus(hostList)
+	}
+
 	if len(hostList) > 0 {
 		wg.Add(1)
-		go powerStatusManager(hostList)
+		if igor.ExternalCmds.PowerStatus != "" {
+			go powerPollManager(hostList)
+		} else {
+			go powerStatusManager(hostList)
+		}
 	}
 
 	// This call will not return until the server terminates
 	runServer()
 }
+
+// Migrate is the entry point from the main package when igor-server is run to migrate the
+// database schema instead of serving. It moves the database to the target schema version,
+// or the latest if target is 0, then exits. With dryRun set it only prints what would be done.
+func Migrate(configFilepath *string, target int, dryRun bool) {
+
+	if igor.IgorHome = os.Getenv("IGOR_HOME"); strings.TrimSpace(igor.IgorHome) == "" {
+		exitPrintFatal("environment variable IGOR_HOME not defined")
+	}
+
+	initConfig(*configFilepath)
+	initLog()
+	initConfigCheck()
+
+	db, _ := openSqliteDb(false)
+	// the pre-migration backup is taken through the same path as the online backup
+	igor.IGormDb = &GormBackend{Database: db}
+
+	if err := runDbMigrate(db, target, dryRun, os.Stdout); err != nil {
+		exitPrintFatal(err.Error())
+	}
+
+	if version, _, err := readSchemaVersion(db); err == nil && !dryRun {
+		fmt.Printf("database schema is now at version %d\n", version)
+	}
+}
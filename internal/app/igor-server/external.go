@@ -33,12 +33,47 @@ func processWrapper(args ...string) (string, error) {
 	return string(out), err
 }
 
-func runAll(format string, args []string) error {
+// extCmdOutputMax is the most of an external command's output kept in its error.
+const extCmdOutputMax = 200
+
+// ExtCmdError is the failure of an external command along with the end of its output, which
+// usually says why it failed.
+type ExtCmdError struct {
+	err    error
+	output string
+}
+
+func newExtCmdError(err error, out string) *ExtCmdError {
+	out = strings.TrimSpace(out)
+	if len(out) > extCmdOutputMax {
+		out = "..." + out[len(out)-extCmdOutputMax:]
+	}
+	return &ExtCmdError{err: err, output: out}
+}
+
+func (e *ExtCmdError) Error() string {
+	if e.output == "" {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("%v: %s", e.err, e.output)
+}
+
+func (e *ExtCmdError) Unwrap() error { return e.err }
+
+// runAll runs the command made by formatting format with each of hosts, limited by the external
+// command concurrency and retries, and returns the error of each host it failed for.
+func runAll(format string, hosts []string) map[string]error {
 	r := DefaultRunner(func(s string) error {
 		cmd := strings.Split(fmt.Sprintf(format, s), " ")
-		_, err := processWrapper(cmd...)
-		return err
+		out, err := processWrapper(cmd...)
+		if err != nil {
+			return newExtCmdError(err, out)
+		}
+		return nil
 	})
 
-	return r.RunAll(args)
+	if err := r.RunAll(hosts); err == nil {
+		return nil
+	}
+	return r.Errors()
 }
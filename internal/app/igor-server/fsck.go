@@ -0,0 +1,434 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	zl "github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// problems found by an integrity check
+const (
+	FsckMissingOwner  = "missing-owner"
+	FsckMissingGroup  = "missing-group"
+	FsckMissingPerm   = "missing-permission"
+	FsckOrphanPerm    = "orphan-permission"
+	FsckReservedHost  = "reserved-host"
+	FsckOrphanResHost = "orphan-reservation-host"
+	FsckOrphanProfile = "orphan-profile"
+	FsckBannedKArg    = "banned-kernel-arg"
+)
+
+// fsckFinding is a problem found by an integrity check along with the change that repairs it, if
+// the problem is one that can be repaired safely.
+type fsckFinding struct {
+	common.FsckData
+	repair func(tx *gorm.DB) error
+}
+
+// doFsck checks that reservations, permissions, hosts and profiles in the database are consistent
+// with one another and returns each problem found. When repair is true the problems that can be
+// repaired safely are fixed in the same transaction and each repair is logged.
+func doFsck(repair bool, now time.Time, clog *zl.Logger) ([]common.FsckData, int, error) {
+
+	var problems []common.FsckData
+	if err := performDbTx(func(tx *gorm.DB) error {
+
+		findings, err := dbFsckScan(now, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range findings {
+			if repair && f.repair != nil {
+				if rErr := f.repair(tx); rErr != nil {
+					return fmt.Errorf("repairing %s (%s): %v", f.Problem, f.Detail, rErr)
+				}
+				f.Repaired = true
+				clog.Warn().Msgf("integrity check repaired %s: %s", f.Problem, f.Detail)
+			}
+			problems = append(problems, f.FsckData)
+		}
+		return nil
+
+	}); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return problems, http.StatusOK, nil
+}
+
+// logFsckSummary runs an integrity check without repairing anything and logs how many of each
+// problem it found.
+func logFsckSummary() {
+
+	problems, _, err := doFsck(false, time.Now(), &logger)
+	if err != nil {
+		logger.Error().Msgf("integrity check failed: %v", err)
+		return
+	}
+	if len(problems) == 0 {
+		logger.Info().Msg("integrity check found no problems")
+		return
+	}
+
+	counts := map[string]int{}
+	for _, p := range problems {
+		counts[p.Problem]++
+	}
+	var summary []string
+	for problem, count := range counts {
+		summary = append(summary, fmt.Sprintf("%d %s", count, problem))
+	}
+	sort.Strings(summary)
+	logger.Warn().Msgf("integrity check found %d problem(s): %s -- run 'igor admin fsck' for details",
+		len(problems), strings.Join(summary, ", "))
+}
+
+// dbFsckScan looks for the problems an integrity check reports.
+func dbFsckScan(now time.Time, tx *gorm.DB) ([]fsckFinding, error) {
+
+	resList, err := dbReadReservations(map[string]interface{}{}, nil, tx)
+	if err != nil {
+		return nil, err
+	}
+	var groups []Group
+	if result := tx.Select("id", "name").Find(&groups); result.Error != nil {
+		return nil, result.Error
+	}
+	var perms []Permission
+	if result := tx.Order("id").Find(&perms); result.Error != nil {
+		return nil, result.Error
+	}
+	var hosts []Host
+	if result := tx.Order("sequence_id").Find(&hosts); result.Error != nil {
+		return nil, result.Error
+	}
+
+	groupsByID := make(map[int]Group, len(groups))
+	groupsByName := make(map[string]Group, len(groups))
+	for _, g := range groups {
+		groupsByID[g.ID] = g
+		groupsByName[g.Name] = g
+	}
+	held := make(map[int]map[string]bool)
+	for _, p := range perms {
+		if held[p.GroupID] == nil {
+			held[p.GroupID] = map[string]bool{}
+		}
+		held[p.GroupID][p.Fact] = true
+	}
+
+	findings := fsckResPermissions(resList, groupsByName, held, now)
+	findings = append(findings, fsckOrphanPermissions(perms, resList, hosts, groupsByID, now)...)
+	findings = append(findings, fsckReservedHosts(hosts, resList, now)...)
+
+	orphanHosts, err := dbFsckOrphanResHosts(tx)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, orphanHosts...)
+
+	orphanProfiles, err := dbFsckOrphanProfiles(groupsByName, tx)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, orphanProfiles...)
+
+	bannedArgs, err := dbFsckBannedKernelArgs(tx)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, bannedArgs...)
+
+	return findings, nil
+}
+
+// fsckResPermissions finds reservations whose owner or group no longer exists and the permissions
+// that reservations should have given their owner, co-owners and group but don't.
+func fsckResPermissions(resList []Reservation, groupsByName map[string]Group, held map[int]map[string]bool, now time.Time) []fsckFinding {
+
+	var findings []fsckFinding
+
+	// missing adds a finding for a permission fact the group should hold, repaired by granting it
+	missing := func(res *Reservation, group Group, fact string) {
+		if held[group.ID][fact] {
+			return
+		}
+		perm, err := NewPermission(fact)
+		if err != nil || held[group.ID][perm.Fact] {
+			return
+		}
+		findings = append(findings, fsckFinding{
+			FsckData: common.FsckData{
+				Problem:     FsckMissingPerm,
+				Reservation: res.Name,
+				Group:       group.Name,
+				Fact:        perm.Fact,
+				Detail:      fmt.Sprintf("group '%s' is missing permission '%s' of reservation '%s'", group.Name, perm.Fact, res.Name),
+			},
+			repair: func(tx *gorm.DB) error {
+				return dbAppendPermissions(&group, []Permission{*perm}, tx)
+			},
+		})
+	}
+
+	for i := range resList {
+		res := &resList[i]
+
+		if res.Owner.ID == 0 {
+			findings = append(findings, fsckFinding{FsckData: common.FsckData{
+				Problem:     FsckMissingOwner,
+				Reservation: res.Name,
+				ID:          res.OwnerID,
+				Detail:      fmt.Sprintf("reservation '%s' is owned by user ID %d, which does not exist", res.Name, res.OwnerID),
+			}})
+		} else if pug, ok := groupsByName[GroupUserPrefix+res.Owner.Name]; ok {
+			ownerPerms, _ := createResOwnerPerms(res.Name, false)
+			for _, p := range ownerPerms {
+				missing(res, pug, p.Fact)
+			}
+		}
+
+		for _, coOwner := range res.CoOwners {
+			if pug, ok := groupsByName[GroupUserPrefix+coOwner.Name]; ok {
+				coOwnerPerms, _ := createResOwnerPerms(res.Name, true)
+				for _, p := range coOwnerPerms {
+					missing(res, pug, p.Fact)
+				}
+			}
+		}
+
+		if res.Group.ID == 0 {
+			findings = append(findings, fsckFinding{FsckData: common.FsckData{
+				Problem:     FsckMissingGroup,
+				Reservation: res.Name,
+				ID:          res.GroupID,
+				Detail:      fmt.Sprintf("reservation '%s' has group ID %d, which does not exist", res.Name, res.GroupID),
+			}})
+			continue
+		}
+		for _, fact := range makeResGroupPermStrings(res) {
+			missing(res, res.Group, fact)
+		}
+		if res.Installed && res.IsActive(now) && len(res.Hosts) > 0 {
+			missing(res, res.Group, makeNodeActionPerm(res.Hosts))
+		}
+	}
+
+	return findings
+}
+
+// fsckOrphanPermissions finds reservation permissions of reservations that don't exist, node action
+// permissions no active reservation grants and permissions naming hosts that don't exist. They are
+// repaired by deleting them.
+func fsckOrphanPermissions(perms []Permission, resList []Reservation, hosts []Host, groupsByID map[int]Group, now time.Time) []fsckFinding {
+
+	resNames := make(map[string]bool, len(resList))
+	activeNodePerms := map[int]map[string]bool{}
+	for i := range resList {
+		res := &resList[i]
+		resNames[res.Name] = true
+		if res.IsActive(now) && len(res.Hosts) > 0 {
+			if nodePerm, err := NewPermission(makeNodeActionPerm(res.Hosts)); err == nil {
+				if activeNodePerms[res.GroupID] == nil {
+					activeNodePerms[res.GroupID] = map[string]bool{}
+				}
+				activeNodePerms[res.GroupID][nodePerm.Fact] = true
+			}
+		}
+	}
+	hostNames := make(map[string]bool, len(hosts)*2)
+	for _, h := range hosts {
+		hostNames[h.Name] = true
+		hostNames[h.HostName] = true
+	}
+
+	var findings []fsckFinding
+	for _, p := range perms {
+
+		parts := strings.Split(p.Fact, PermDividerToken)
+		if len(parts) < 2 || strings.Contains(parts[1], PermWildcardToken) {
+			continue
+		}
+
+		var detail string
+		switch parts[0] {
+		case PermReservations:
+			if !resNames[parts[1]] {
+				detail = fmt.Sprintf("permission '%s' refers to reservation '%s', which does not exist", p.Fact, parts[1])
+			}
+		case PermNodeAction, PermHosts:
+			for _, h := range strings.Split(parts[1], PermSubpartToken) {
+				if !hostNames[h] {
+					detail = fmt.Sprintf("permission '%s' refers to host '%s', which does not exist", p.Fact, h)
+					break
+				}
+			}
+			if detail == "" && parts[0] == PermNodeAction && !activeNodePerms[p.GroupID][p.Fact] {
+				detail = fmt.Sprintf("node action permission '%s' does not belong to an active reservation", p.Fact)
+			}
+		}
+		if detail == "" {
+			continue
+		}
+
+		perm := p
+		findings = append(findings, fsckFinding{
+			FsckData: common.FsckData{
+				Problem: FsckOrphanPerm,
+				Group:   groupsByID[p.GroupID].Name,
+				Fact:    p.Fact,
+				ID:      p.ID,
+				Detail:  detail,
+			},
+			repair: func(tx *gorm.DB) error {
+				return tx.Delete(&perm).Error
+			},
+		})
+	}
+
+	return findings
+}
+
+// fsckReservedHosts finds hosts in the reserved state that no active reservation holds. Changing the
+// state of a host is left to an admin, so these are not repaired.
+func fsckReservedHosts(hosts []Host, resList []Reservation, now time.Time) []fsckFinding {
+
+	inUse := map[int]bool{}
+	for i := range resList {
+		if resList[i].IsActive(now) {
+			for _, h := range resList[i].Hosts {
+				inUse[h.ID] = true
+			}
+		}
+	}
+
+	var findings []fsckFinding
+	for _, h := range hosts {
+		if h.State == HostReserved && !inUse[h.ID] {
+			findings = append(findings, fsckFinding{FsckData: common.FsckData{
+				Problem: FsckReservedHost,
+				Host:    h.Name,
+				ID:      h.ID,
+				Detail:  fmt.Sprintf("host %s is reserved but no active reservation holds it", h.Name),
+			}})
+		}
+	}
+	return findings
+}
+
+// dbFsckOrphanResHosts finds rows of the reservation host table that point at a reservation or host
+// that doesn't exist. They are repaired by deleting them.
+func dbFsckOrphanResHosts(tx *gorm.DB) ([]fsckFinding, error) {
+
+	var rows []ReservationHost
+	if result := tx.Where("reservation_id NOT IN (SELECT id FROM reservations) OR host_id NOT IN (SELECT id FROM hosts)").
+		Order("reservation_id").Find(&rows); result.Error != nil {
+		return nil, result.Error
+	}
+
+	var findings []fsckFinding
+	for _, row := range rows {
+		rh := row
+		findings = append(findings, fsckFinding{
+			FsckData: common.FsckData{
+				Problem: FsckOrphanResHost,
+				ID:      rh.ReservationID,
+				Detail:  fmt.Sprintf("reservation host row for reservation ID %d and host ID %d refers to a deleted reservation or host", rh.ReservationID, rh.HostID),
+			},
+			repair: func(tx *gorm.DB) error {
+				return tx.Where("reservation_id = ? AND host_id = ?", rh.ReservationID, rh.HostID).Delete(&ReservationHost{}).Error
+			},
+		})
+	}
+	return findings, nil
+}
+
+// dbFsckOrphanProfiles finds default profiles no reservation uses. A default profile is made for a
+// single reservation, so these are repaired by deleting them along with their owner's permissions.
+func dbFsckOrphanProfiles(groupsByName map[string]Group, tx *gorm.DB) ([]fsckFinding, error) {
+
+	var profiles []Profile
+	if result := tx.Joins("Owner").Where("is_default = ? AND profiles.id NOT IN (SELECT profile_id FROM reservations)", true).
+		Order("profiles.id").Find(&profiles); result.Error != nil {
+		return nil, result.Error
+	}
+
+	var findings []fsckFinding
+	for _, p := range profiles {
+		profile := p
+		pug := groupsByName[GroupUserPrefix+profile.Owner.Name]
+		findings = append(findings, fsckFinding{
+			FsckData: common.FsckData{
+				Problem: FsckOrphanProfile,
+				ID:      profile.ID,
+				Detail:  fmt.Sprintf("default profile '%s' of %s is not used by any reservation", profile.Name, profile.Owner.Name),
+			},
+			repair: func(tx *gorm.DB) error {
+				permFind := PermProfiles + PermDividerToken + profile.Name + PermDividerToken + "%"
+				if result := tx.Where("group_id = ? AND fact LIKE ?", pug.ID, permFind).Delete(&Permission{}); result.Error != nil {
+					return result.Error
+				}
+				return tx.Delete(&profile).Error
+			},
+		})
+	}
+	return findings, nil
+}
+
+// dbFsckBannedKernelArgs finds the distros, profiles and users whose stored kernel args use an arg that
+// is banned now. They were allowed when stored, so they are left for an admin to change.
+func dbFsckBannedKernelArgs(tx *gorm.DB) ([]fsckFinding, error) {
+
+	var findings []fsckFinding
+	if len(bannedKernelArgKeys) == 0 && len(bannedKernelArgPatterns) == 0 {
+		return findings, nil
+	}
+
+	banned := func(id int, args, what string) {
+		if arg := bannedKernelArg(args); arg != "" {
+			findings = append(findings, fsckFinding{FsckData: common.FsckData{
+				Problem: FsckBannedKArg,
+				ID:      id,
+				Detail:  fmt.Sprintf("%s uses the banned kernel arg '%s'", what, arg),
+			}})
+		}
+	}
+
+	var distros []Distro
+	if result := tx.Where("kernel_args <> ''").Order("id").Find(&distros); result.Error != nil {
+		return nil, result.Error
+	}
+	for _, d := range distros {
+		banned(d.ID, d.KernelArgs, fmt.Sprintf("distro '%s'", d.Name))
+	}
+
+	var profiles []Profile
+	if result := tx.Joins("Owner").Where("profiles.kernel_args <> ''").Order("profiles.id").Find(&profiles); result.Error != nil {
+		return nil, result.Error
+	}
+	for _, p := range profiles {
+		banned(p.ID, p.KernelArgs, fmt.Sprintf("profile '%s' of %s", p.Name, p.Owner.Name))
+	}
+
+	var users []User
+	if result := tx.Where("default_kernel_args <> ''").Order("id").Find(&users); result.Error != nil {
+		return nil, result.Error
+	}
+	for _, u := range users {
+		banned(u.ID, u.DefaultKernelArgs, fmt.Sprintf("default kernel args of %s", u.Name))
+	}
+
+	return findings, nil
+}
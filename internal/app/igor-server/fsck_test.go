@@ -0,0 +1,112 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestFsck(t *testing.T) {
+
+	hosts := seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	res := newStartTestRes(t, db, "r1", hosts[:1], false, 0)
+	require.NoError(t, db.Model(&Reservation{}).Where("id = ?", res.ID).Update("installed", true).Error)
+	require.NoError(t, db.Model(&Host{}).Where("id IN ?", hostIDsOfHosts(hosts)).Update("state", HostReserved).Error)
+
+	var pug Group
+	require.NoError(t, db.Where("name = ?", GroupUserPrefix+"alice").First(&pug).Error)
+	goneRes, _ := NewPermission(NewPermissionString(PermReservations, "gone", PermEditAction, PermWildcardToken))
+	staleNode, _ := NewPermission(makeNodeActionPerm(hosts[1:]))
+	goneHost, _ := NewPermission(NewPermissionString(PermHosts, "kn9", PermViewAction))
+	require.NoError(t, dbAppendPermissions(&pug, []Permission{*goneRes, *staleNode, *goneHost}, db))
+
+	require.NoError(t, db.Create(&ReservationHost{ReservationID: res.ID + 100, HostID: hosts[0].ID}).Error)
+	require.NoError(t, db.Omit(clause.Associations).Create(&Profile{Name: "gone-default", OwnerID: res.OwnerID, IsDefault: true}).Error)
+
+	count := func(problems []common.FsckData) map[string]int {
+		counts := map[string]int{}
+		for _, p := range problems {
+			counts[p.Problem]++
+		}
+		return counts
+	}
+
+	now := time.Now().Add(time.Second)
+	problems, _, err := doFsck(false, now, &logger)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		FsckMissingPerm:   4, // owner edit, group delete and extend, node actions
+		FsckOrphanPerm:    3,
+		FsckReservedHost:  1,
+		FsckOrphanResHost: 1,
+		FsckOrphanProfile: 1,
+	}, count(problems))
+	for _, p := range problems {
+		assert.False(t, p.Repaired)
+		if p.Problem == FsckReservedHost {
+			assert.Equal(t, "kn2", p.Host)
+		}
+	}
+
+	// a scan changes nothing
+	again, _, err := doFsck(false, now, &logger)
+	require.NoError(t, err)
+	assert.Equal(t, problems, again)
+
+	problems, _, err = doFsck(true, now, &logger)
+	require.NoError(t, err)
+	for _, p := range problems {
+		assert.Equal(t, p.Problem != FsckReservedHost, p.Repaired, p.Detail)
+	}
+
+	problems, _, err = doFsck(false, now, &logger)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{FsckReservedHost: 1}, count(problems))
+
+	perms, err := dbGetPermissionsByName(PermReservations, "r1", db)
+	require.NoError(t, err)
+	assert.Len(t, perms, 3)
+
+	// a reservation whose group is gone is only reported
+	require.NoError(t, db.Model(&Reservation{}).Where("id = ?", res.ID).Update("group_id", pug.ID+100).Error)
+	problems, _, err = doFsck(true, now, &logger)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{FsckMissingGroup: 1, FsckOrphanPerm: 1, FsckReservedHost: 1}, count(problems))
+}
+
+func TestFsckBannedKernelArgs(t *testing.T) {
+
+	seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	alice := User{Name: "alice", Email: "alice@example.com", DefaultKernelArgs: "quiet rd.break"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&alice).Error)
+	require.NoError(t, db.Omit(clause.Associations).Create(&Profile{Name: "debug", OwnerID: alice.ID, KernelArgs: "systemd.debug-shell=1", IsDefault: true}).Error)
+
+	// args stored before anything was banned are fine
+	findings, err := dbFsckBannedKernelArgs(db)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+
+	t.Cleanup(func() { _ = setBannedKernelArgs(nil, nil) })
+	require.NoError(t, setBannedKernelArgs([]string{"systemd.debug-shell"}, []string{`^rd\.break`}))
+	findings, err = dbFsckBannedKernelArgs(db)
+	require.NoError(t, err)
+	if assert.Len(t, findings, 2) {
+		assert.Equal(t, FsckBannedKArg, findings[0].Problem)
+		assert.Contains(t, findings[0].Detail, "profile 'debug' of alice uses the banned kernel arg 'systemd.debug-shell=1'")
+		assert.Contains(t, findings[1].Detail, "default kernel args of alice uses the banned kernel arg 'rd.break'")
+		assert.Nil(t, findings[1].repair)
+	}
+}
@@ -6,6 +6,7 @@ package igorserver
 
 import (
 	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
 	"sort"
 )
 
@@ -19,7 +20,7 @@ const (
 	GroupAll = "all"
 	// GroupUserPrefix is the prefix applied to all user-private groups forming the conjunction prefix+username. This
 	// is a protected group identifier and no other kind of group should start with these characters.
-	GroupUserPrefix = "u_"
+	GroupUserPrefix = naming.GroupUserPrefix
 	// GroupNoneAlias is a group name parameter passed from the client indicating the current group associated with a
 	// resource should be removed. In cases (like reservation) where a group is required, this means reverting the group
 	// to the owner's pug. This is a protected unique name.
@@ -42,6 +43,16 @@ type Group struct {
 	Reservations []Reservation // Group has-many reservations
 	Distros      []Distro      `gorm:"many2many:distros_groups;"`
 	Policies     []HostPolicy  `gorm:"many2many:groups_policies;"`
+	// DefaultDistro is the name of the distro used for reservations made with this group when
+	// no distro or profile is given
+	DefaultDistro string
+	// DefaultDuration is the length used for reservations made with this group when no end
+	// time is given
+	DefaultDuration string
+	// VlanMin and VlanMax limit the VLANs that reservations made with this group can use. Both are 0
+	// when the group has no range of its own.
+	VlanMin int
+	VlanMax int
 }
 
 func (g *Group) getGroupData() *common.GroupData {
@@ -52,9 +63,12 @@ func (g *Group) getGroupData() *common.GroupData {
 	}
 
 	gd := &common.GroupData{
-		Name:        g.Name,
-		Description: g.Description,
-		Owners:      owners,
+		Name:            g.Name,
+		Description:     g.Description,
+		Owners:          owners,
+		DefaultDistro:   g.DefaultDistro,
+		DefaultDuration: g.DefaultDuration,
+		VlanRange:       g.vlanRange(),
 	}
 
 	if len(g.Members) > 0 {
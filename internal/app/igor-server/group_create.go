@@ -6,6 +6,7 @@ package igorserver
 
 import (
 	"fmt"
+	"igor2/internal/pkg/common"
 	"net/http"
 
 	"gorm.io/gorm"
@@ -25,7 +26,7 @@ func doCreateGroup(groupParams map[string]interface{}, r *http.Request) (group *
 		}
 		if exists {
 			status = http.StatusConflict
-			return fmt.Errorf("group '%s' already exists", groupName)
+			return newCodedError(common.ErrNameTaken, "group '%s' already exists", groupName)
 		}
 
 		// need to add additional owners if present and also mark whether the group is LDAP-synced.
@@ -42,12 +43,17 @@ func doCreateGroup(groupParams map[string]interface{}, r *http.Request) (group *
 			if members, owners, ldapErr := executeLdapGroupCreate(group); ldapErr != nil {
 				// status is default
 				return ldapErr
-			} else if len(owners) == 1 && userSliceContains(owners, IgorAdmin) && userElevated(owner.Name) {
-				extraMsg = "LDAP listed owner is not an igor user so sync-group is owned by igor-admin"
 			} else if !(userSliceContains(owners, owner.Name) || userElevated(owner.Name)) {
 				status = http.StatusForbidden
 				return fmt.Errorf("you do not have persmission to add the LDAP group '%s' to igor - must be an owner/delegate of the group or an igor admin", groupName)
 			} else {
+				if len(owners) == 1 && userSliceContains(owners, IgorAdmin) {
+					if ldapOwnerSyncEnabled() {
+						extraMsg = "LDAP listed owner is not an igor user so sync-group is owned by igor-admin"
+					} else {
+						extraMsg = "group owners are not synced from LDAP so sync-group is owned by igor-admin until an owner is added"
+					}
+				}
 				group.Members = members
 				group.Owners = owners
 			}
@@ -9,6 +9,8 @@ import (
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
 )
 
 // dbCreateGroup creates a new group. If the group is not a pug it gathers additional
@@ -115,6 +117,66 @@ func dbReadGroups(queryParams map[string]interface{}, excludePugs bool, tx *gorm
 	return groups, result.Error
 }
 
+// groupAccessListMax is the most names of each kind of resource listed in a group's access.
+const groupAccessListMax = 25
+
+// dbReadGroupAccess returns the reservations, distros and host policies that reference each of the
+// groups in groupIDs, keyed by group ID. Each kind is read with a single join across all the groups.
+// Distros are limited to the ones user could see when reading distros.
+func dbReadGroupAccess(groupIDs []int, user *User, tx *gorm.DB) (map[int]*common.GroupAccessData, error) {
+
+	type accessRow struct {
+		GroupID int
+		Name    string
+	}
+
+	access := make(map[int]*common.GroupAccessData, len(groupIDs))
+	for _, id := range groupIDs {
+		access[id] = &common.GroupAccessData{}
+	}
+	add := func(rows []accessRow, list func(*common.GroupAccessData) *common.GroupAccessList) {
+		for _, row := range rows {
+			l := list(access[row.GroupID])
+			l.Count++
+			if len(l.Names) < groupAccessListMax {
+				l.Names = append(l.Names, row.Name)
+			}
+		}
+	}
+
+	var resRows []accessRow
+	if result := tx.Model(&Reservation{}).Select("group_id, name").
+		Where("group_id IN ?", groupIDs).Order("name COLLATE NOCASE ASC").Scan(&resRows); result.Error != nil {
+		return nil, result.Error
+	}
+	add(resRows, func(a *common.GroupAccessData) *common.GroupAccessList { return &a.Reservations })
+
+	var distroRows []accessRow
+	distroQuery := tx.Model(&Distro{}).Select("distros_groups.group_id, distros.name").
+		Joins("JOIN distros_groups ON distros_groups.distro_id = distros.id").
+		Where("distros_groups.group_id IN ?", groupIDs)
+	if !userElevated(user.Name) {
+		// same rule as scopeDistrosToUser, the user must be in one of the distro's groups
+		distroQuery = distroQuery.Where("distros.id IN (?)",
+			tx.Table("distros_groups").Select("distro_id").Where("group_id IN ?", groupIDsOfGroups(user.Groups)))
+	}
+	if result := distroQuery.Order("distros.name COLLATE NOCASE ASC").Scan(&distroRows); result.Error != nil {
+		return nil, result.Error
+	}
+	add(distroRows, func(a *common.GroupAccessData) *common.GroupAccessList { return &a.Distros })
+
+	var policyRows []accessRow
+	if result := tx.Model(&HostPolicy{}).Select("groups_policies.group_id, host_policies.name").
+		Joins("JOIN groups_policies ON groups_policies.host_policy_id = host_policies.id").
+		Where("groups_policies.group_id IN ?", groupIDs).
+		Order("host_policies.name COLLATE NOCASE ASC").Scan(&policyRows); result.Error != nil {
+		return nil, result.Error
+	}
+	add(policyRows, func(a *common.GroupAccessData) *common.GroupAccessList { return &a.HostPolicies })
+
+	return access, nil
+}
+
 // dbEditGroup edits the properties of a Group.
 func dbEditGroup(group *Group, changes map[string]interface{}, tx *gorm.DB) error {
 
@@ -139,12 +201,36 @@ func dbEditGroup(group *Group, changes map[string]interface{}, tx *gorm.DB) erro
 					return result.Error
 				}
 			}
+			// keep the default group of members pointing at the renamed group
+			if result := tx.Model(&User{}).Where("default_group = ?", group.Name).Update("default_group", name); result.Error != nil {
+				return result.Error
+			}
+			// this also sets group.Name, which owner changes below use to build and look up permission facts
 			if result := tx.Model(&group).Update("Name", name); result.Error != nil {
 				return result.Error
 			}
 		}
 	}
 
+	// Change the reservation defaults of the group
+	if defDistro, ok := changes["defaultDistro"].(string); ok {
+		if result := tx.Model(&group).Update("DefaultDistro", defDistro); result.Error != nil {
+			return result.Error
+		}
+	}
+	if defDuration, ok := changes["defaultDuration"].(string); ok {
+		if result := tx.Model(&group).Update("DefaultDuration", defDuration); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	// Change the VLAN range of the group
+	if vlanMin, ok := changes["vlanMin"].(int); ok {
+		if result := tx.Model(&group).Updates(map[string]interface{}{"vlan_min": vlanMin, "vlan_max": changes["vlanMax"]}); result.Error != nil {
+			return result.Error
+		}
+	}
+
 	// Change the description of the group
 	if desc, ok := changes["description"].(string); ok {
 		if result := tx.Model(&group).Update("Description", desc); result.Error != nil {
@@ -214,10 +300,24 @@ func dbDeleteGroup(group *Group, tx *gorm.DB) error {
 		return err
 	}
 
+	// drop any host policy time limits given to the group
+	if result := tx.Where("group_id = ?", group.ID).Delete(&GroupTimeLimit{}); result.Error != nil {
+		return result.Error
+	}
+
+	// and any host policy exclusions of it
+	if result := tx.Exec("DELETE FROM groups_policies_excluded WHERE group_id = ?", group.ID); result.Error != nil {
+		return result.Error
+	}
+
 	if result := tx.Delete(&group); result.Error != nil {
 		return result.Error
 	}
 
+	if result := tx.Model(&User{}).Where("default_group = ?", group.Name).Update("default_group", ""); result.Error != nil {
+		return result.Error
+	}
+
 	if err := dbDeletePermissionsByName(PermGroups, group.Name, tx); err != nil {
 		return err
 	}
@@ -55,6 +55,14 @@ func doDeleteGroup(groupName string, r *http.Request) (status int, err error) {
 			return fmt.Errorf("cannot delete '%s' - must edit or remove host policies defining access for this group", group.Name)
 		}
 
+		if nList, nErr := dbReadNamedNetworks(map[string]interface{}{"group_id": group.ID}, tx); nErr != nil {
+			status = http.StatusInternalServerError
+			return nErr
+		} else if len(nList) > 0 {
+			status = http.StatusConflict
+			return fmt.Errorf("cannot delete '%s' - must delete the named networks it owns first", group.Name)
+		}
+
 		// all set -- let's try to delete the group
 
 		// drop the group from any reservations -- handle like a res update from the client as this will also
@@ -14,6 +14,7 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
 )
 
 // destination for route POST /groups
@@ -48,6 +49,7 @@ func handleReadGroups(w http.ResponseWriter, r *http.Request) {
 	actionPrefix := "read group(s)"
 	rb := common.NewResponseBodyGroups()
 	var groupList []Group
+	showAccess, _ := strconv.ParseBool(queryMap.Get("showAccess"))
 
 	queryParams, status, err := parseGroupSearchParams(queryMap, r)
 	if err == nil {
@@ -64,14 +66,37 @@ func handleReadGroups(w http.ResponseWriter, r *http.Request) {
 		if len(groupList) == 0 {
 			rb.Message = "search returned no results"
 		} else {
-			for _, g := range groupList {
-				groupType := "member"
+			groupTypes := make([]string, len(groupList))
+			var accessIDs []int
+			for i, g := range groupList {
+				groupTypes[i] = "member"
 				for _, owner := range g.Owners {
 					if owner.Name == actionUser.Name {
-						groupType = "owner"
+						groupTypes[i] = "owner"
 					}
 				}
-				rb.Data[groupType] = append(rb.Data[groupType], *g.getGroupData())
+				if showAccess && (groupTypes[i] == "owner" || userElevated(actionUser.Name)) {
+					accessIDs = append(accessIDs, g.ID)
+				}
+			}
+
+			var access map[int]*common.GroupAccessData
+			if len(accessIDs) > 0 {
+				err = performDbTx(func(tx *gorm.DB) error {
+					access, err = dbReadGroupAccess(accessIDs, actionUser, tx)
+					return err
+				})
+				if err != nil {
+					stdErrorResp(rb, http.StatusInternalServerError, actionPrefix, err, clog)
+					makeJsonResponse(w, http.StatusInternalServerError, rb)
+					return
+				}
+			}
+
+			for i, g := range groupList {
+				gd := g.getGroupData()
+				gd.Access = access[g.ID]
+				rb.Data[groupTypes[i]] = append(rb.Data[groupTypes[i]], *gd)
 			}
 		}
 	}
@@ -89,13 +114,16 @@ func handleUpdateGroup(w http.ResponseWriter, r *http.Request) {
 	actionPrefix := "update group"
 	ps := httprouter.ParamsFromContext(r.Context())
 	name := ps.ByName("groupName")
-	rb := common.NewResponseBody()
+	rb := common.NewResponseBodyBatch()
 
-	status, err := doUpdateGroup(name, editParams, r)
+	results, status, err := doUpdateGroup(name, editParams, r)
 
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
+		if len(results) > 0 {
+			setBatchResults(rb, results, "member(s) updated")
+		}
 		clog.Info().Msgf("%s success - '%s' updated", actionPrefix, name)
 	}
 
@@ -155,7 +183,7 @@ func validateGroupParams(handler http.Handler) http.Handler {
 							if n, ok := val.(string); !ok {
 								validateErr = NewBadParamTypeError(key, val, "string")
 								break postPutParamLoop
-							} else if validateErr = checkGenericNameRules(n); validateErr != nil {
+							} else if validateErr = checkGroupNameRules(n); validateErr != nil {
 								break postPutParamLoop
 							} else if validateErr = checkReservedGroupNames(n); validateErr != nil {
 								break postPutParamLoop
@@ -220,7 +248,7 @@ func validateGroupParams(handler http.Handler) http.Handler {
 							if name, ok := val.(string); !ok {
 								validateErr = NewBadParamTypeError(key, val, "string")
 								break patchParamLoop
-							} else if validateErr = checkGenericNameRules(name); validateErr != nil {
+							} else if validateErr = checkGroupNameRules(name); validateErr != nil {
 								break patchParamLoop
 							} else if validateErr = checkReservedGroupNames(name); validateErr != nil {
 								break patchParamLoop
@@ -232,6 +260,34 @@ func validateGroupParams(handler http.Handler) http.Handler {
 							} else if validateErr = checkDesc(desc); validateErr != nil {
 								break patchParamLoop
 							}
+						case "defaultDistro":
+							if distroName, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if distroName != GroupNoneAlias {
+								if validateErr = checkDistroNameRules(distroName); validateErr != nil {
+									break patchParamLoop
+								}
+							}
+						case "defaultDuration":
+							if dur, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if dur != GroupNoneAlias {
+								if _, pErr := common.ParseDuration(dur); pErr != nil {
+									validateErr = fmt.Errorf("'%s' is not a recognized duration interval", dur)
+									break patchParamLoop
+								}
+							}
+						case "vlanRange":
+							if vlanRange, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if vlanRange != GroupNoneAlias {
+								if _, _, validateErr = parseVlanRange(vlanRange); validateErr != nil {
+									break patchParamLoop
+								}
+							}
 						case "addOwners", "rmvOwners":
 							for _, v := range val.([]interface{}) {
 								if _, ok := v.(string); !ok {
@@ -240,6 +296,11 @@ func validateGroupParams(handler http.Handler) http.Handler {
 									break patchParamLoop
 								}
 							}
+						case "failFast":
+							if _, ok := val.(bool); !ok {
+								validateErr = NewBadParamTypeError(key, val, "bool")
+								break patchParamLoop
+							}
 						case "add", "remove":
 							// members must be a string array
 							for _, v := range val.([]interface{}) {
@@ -280,7 +341,7 @@ func validateGroupParams(handler http.Handler) http.Handler {
 							break queryParamLoop
 						}
 					}
-				case "showMembers":
+				case "showMembers", "showAccess":
 					if len(vals) > 1 {
 						validateErr = fmt.Errorf("invalid parameter: '%s' cannot have multiple values", key)
 						break queryParamLoop
@@ -49,6 +49,8 @@ func parseGroupSearchParams(queryMap map[string][]string, r *http.Request) (map[
 		case "showMembers":
 			showMembers, _ := strconv.ParseBool(val[0])
 			queryParams["showMembers"] = showMembers
+		case "showAccess":
+			// not a search term, handleReadGroups adds the access of the groups found
 		default:
 			clog.Warn().Msgf("parameter '%s' with args '%v' not included in search", key, val)
 		}
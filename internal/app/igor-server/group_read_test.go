@@ -0,0 +1,85 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestReadGroupAccess(t *testing.T) {
+
+	seedTimeLimitHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	team, other, idle := Group{Name: "team"}, Group{Name: "other"}, Group{Name: "idle"}
+	for _, g := range []*Group{&team, &other, &idle} {
+		require.NoError(t, db.Omit(clause.Associations).Create(g).Error)
+	}
+
+	// more reservations than are listed
+	now := time.Now()
+	for i := groupAccessListMax + 2; i > 0; i-- {
+		res := Reservation{Name: fmt.Sprintf("res%02d", i), Hash: fmt.Sprintf("res%02d", i), GroupID: team.ID,
+			Start: now, End: now.Add(time.Hour), ResetEnd: now.Add(time.Hour)}
+		require.NoError(t, db.Omit(clause.Associations).Create(&res).Error)
+	}
+	otherRes := Reservation{Name: "elsewhere", Hash: "elsewhere", GroupID: other.ID, Start: now, End: now.Add(time.Hour)}
+	require.NoError(t, db.Omit(clause.Associations).Create(&otherRes).Error)
+
+	for name, groups := range map[string][]Group{"public": {team, all}, "private": {team}, "shared": {team, other}} {
+		d := Distro{Name: name}
+		require.NoError(t, db.Omit(clause.Associations).Create(&d).Error)
+		require.NoError(t, db.Model(&d).Association("Groups").Append(groups))
+	}
+
+	var short HostPolicy
+	require.NoError(t, db.Where("name = ?", "short").First(&short).Error)
+	require.NoError(t, db.Model(&short).Association("AccessGroups").Append(&team))
+
+	readAccess := func(user *User) map[int]*common.GroupAccessData {
+		var access map[int]*common.GroupAccessData
+		require.NoError(t, performDbTx(func(tx *gorm.DB) (err error) {
+			access, err = dbReadGroupAccess([]int{team.ID, idle.ID}, user, tx)
+			return err
+		}))
+		return access
+	}
+
+	// an admin sees everything the group is given
+	access := readAccess(&User{Name: IgorAdmin})
+	require.Len(t, access, 2)
+	ta := access[team.ID]
+	assert.Equal(t, groupAccessListMax+2, ta.Reservations.Count)
+	assert.Len(t, ta.Reservations.Names, groupAccessListMax)
+	assert.Equal(t, 2, ta.Reservations.More())
+	assert.Equal(t, "res01", ta.Reservations.Names[0])
+	assert.NotContains(t, ta.Reservations.Names, "elsewhere")
+	assert.Equal(t, []string{"private", "public", "shared"}, ta.Distros.Names)
+	assert.Equal(t, 3, ta.Distros.Count)
+	assert.Equal(t, []string{"short"}, ta.HostPolicies.Names)
+	assert.Equal(t, 0, ta.HostPolicies.More())
+	assert.Equal(t, &common.GroupAccessData{}, access[idle.ID])
+
+	// distros are limited to the ones the user can read
+	access = readAccess(&User{Name: "carol", Groups: []Group{all, other}})
+	assert.Equal(t, []string{"public", "shared"}, access[team.ID].Distros.Names)
+	assert.Equal(t, 2, access[team.ID].Distros.Count)
+	assert.Equal(t, groupAccessListMax+2, access[team.ID].Reservations.Count)
+
+	access = readAccess(&User{Name: "alice", Groups: []Group{all, team}})
+	assert.Equal(t, 3, access[team.ID].Distros.Count)
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"github.com/rs/zerolog/hlog"
 	"gorm.io/gorm"
+	"igor2/internal/pkg/common"
 	"net/http"
 	"strings"
 )
@@ -22,7 +23,10 @@ import (
 //	404,error if group, new owner or member cannot be found
 //	409,error if attempting to rename the group and that name is already in use
 //	500,error if an internal error occurred
-func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.Request) (status int, err error) {
+//
+// Members to add or remove that can't be found, or aren't members when being removed, are reported in
+// results without stopping the rest of the edit, unless the failFast parameter is set.
+func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.Request) (results []common.BatchItemResult, status int, err error) {
 
 	// validate changes that don't require DB lookups
 	clog := hlog.FromRequest(r)
@@ -37,7 +41,7 @@ func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.
 		} else {
 			group = &gList[0]
 			groupId = group.ID
-			if group.IsLDAP {
+			if group.IsLDAP && !onlyGroupDefaultEdits(editParams) && !(onlyGroupOwnerEdits(editParams) && !ldapOwnerSyncEnabled()) {
 				clog.Warn().Msgf("user issued a group update command on an LDAP-synced group.")
 				status = http.StatusForbidden
 				return fmt.Errorf("cannot change details of LDAP-synced group '%s' within igor", groupName)
@@ -51,9 +55,10 @@ func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.
 	_, hasName := editParams["name"].(string)
 	if hasName {
 		if groupName == GroupAll || groupName == GroupAdmins || strings.HasPrefix(groupName, GroupUserPrefix) {
-			return http.StatusForbidden, fmt.Errorf("cannot change the name of group '%s'", groupName)
+			return nil, http.StatusForbidden, fmt.Errorf("cannot change the name of group '%s'", groupName)
 		}
 	}
+	failFast, _ := editParams["failFast"].(bool)
 
 	var addOwnerNames []string
 	addOwners, hasOwners := editParams["addOwners"].([]interface{})
@@ -61,7 +66,7 @@ func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.
 		for _, u := range addOwners {
 			newOwn := u.(string)
 			if newOwn == IgorAdmin {
-				return http.StatusForbidden, fmt.Errorf("cannot add %s to any group", IgorAdmin)
+				return nil, http.StatusForbidden, fmt.Errorf("cannot add %s to any group", IgorAdmin)
 			}
 			addOwnerNames = append(addOwnerNames, newOwn)
 		}
@@ -73,7 +78,7 @@ func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.
 		for _, u := range rmvOwners {
 			rmvOwn := u.(string)
 			if rmvOwn == IgorAdmin && groupName == GroupAdmins {
-				return http.StatusForbidden, fmt.Errorf("cannot remove %s from the '%s' group", IgorAdmin, GroupAdmins)
+				return nil, http.StatusForbidden, fmt.Errorf("cannot remove %s from the '%s' group", IgorAdmin, GroupAdmins)
 			}
 			rmvOwnerNames = append(rmvOwnerNames, rmvOwn)
 		}
@@ -85,7 +90,7 @@ func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.
 		for _, u := range add {
 			newMem := u.(string)
 			if newMem == IgorAdmin {
-				return http.StatusForbidden, fmt.Errorf("cannot add %s to any group", IgorAdmin)
+				return nil, http.StatusForbidden, fmt.Errorf("cannot add %s to any group", IgorAdmin)
 			}
 			addMemNames = append(addMemNames, newMem)
 		}
@@ -95,21 +100,21 @@ func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.
 	remove, hasRemove := editParams["remove"].([]interface{})
 	if hasRemove {
 		if groupName == GroupAll {
-			return http.StatusForbidden, fmt.Errorf("cannot remove members from the '%s' group", GroupAll)
+			return nil, http.StatusForbidden, fmt.Errorf("cannot remove members from the '%s' group", GroupAll)
 		}
 		for _, u := range remove {
 			rmName := u.(string)
 			if rmName == IgorAdmin && groupName == GroupAdmins {
-				return http.StatusForbidden, fmt.Errorf("cannot remove %s from the '%s' group", IgorAdmin, GroupAdmins)
+				return nil, http.StatusForbidden, fmt.Errorf("cannot remove %s from the '%s' group", IgorAdmin, GroupAdmins)
 			}
 			for _, oName := range addOwnerNames {
 				if oName == rmName {
-					return http.StatusBadRequest, fmt.Errorf("cannot assign a new owner who is also removed from the group")
+					return nil, http.StatusBadRequest, fmt.Errorf("cannot assign a new owner who is also removed from the group")
 				}
 			}
 			for _, adName := range addMemNames {
 				if rmName == adName {
-					return http.StatusBadRequest, fmt.Errorf("the same user appears in both add and remove oldOwner params")
+					return nil, http.StatusBadRequest, fmt.Errorf("the same user appears in both add and remove oldOwner params")
 				}
 			}
 			rmMemNames = append(rmMemNames, rmName)
@@ -134,7 +139,7 @@ func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.
 				return findErr // uses default err status
 			} else if found {
 				status = http.StatusConflict
-				return fmt.Errorf("group name '%s' already in use", name)
+				return newCodedError(common.ErrNameTaken, "group name '%s' already in use", name)
 			}
 			changes["name"] = name
 			newGroupName = name
@@ -145,12 +150,22 @@ func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.
 			changes["description"] = desc.(string)
 		}
 
+		if dStatus, dErr := parseGroupDefaults(group, editParams, changes, tx); dErr != nil {
+			status = dStatus
+			return dErr
+		}
+
+		if vStatus, vErr := parseGroupVlanRange(group, editParams, changes, getUserFromContext(r)); vErr != nil {
+			status = vStatus
+			return vErr
+		}
+
+		batch := newBatchRun(tx, failFast)
 		if hasAdd {
-			if nml, guStatus, guErr := getUsers(addMemNames, true, tx); guErr != nil {
-				status = guStatus
-				return guErr
-			} else {
-				addUsers = nml
+			var rmcErr error
+			if addUsers, rmcErr = resolveMemberChanges(group, addMemNames, true, batch, tx); rmcErr != nil {
+				status = batchAbortStatus(rmcErr)
+				return rmcErr
 			}
 		}
 
@@ -189,12 +204,12 @@ func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.
 		}
 
 		if hasRemove {
-			if rml, guStatus, guErr := getUsers(rmMemNames, true, tx); guErr != nil {
-				status = guStatus
-				return guErr
-			} else {
-				removeUsers = rml
+			var rmcErr error
+			if removeUsers, rmcErr = resolveMemberChanges(group, rmMemNames, false, batch, tx); rmcErr != nil {
+				status = batchAbortStatus(rmcErr)
+				return rmcErr
 			}
+			rmMemNames = userNamesOfUsers(removeUsers)
 
 			var ownersRemoved = 0
 			for _, o := range group.Owners {
@@ -231,12 +246,15 @@ func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.
 			}
 		}
 
+		// members get the node actions of the group's active reservations through the group, so
+		// changing membership is all it takes to grant or revoke them (see checkNodeAction)
 		if len(addUsers) > 0 {
 			changes["add"] = addUsers
 		}
 		if len(removeUsers) > 0 {
 			changes["remove"] = removeUsers
 		}
+		results = batch.results
 
 		return dbEditGroup(group, changes, tx) // uses default err status
 
@@ -310,3 +328,109 @@ func doUpdateGroup(groupName string, editParams map[string]interface{}, r *http.
 
 	return
 }
+
+// resolveMemberChanges looks up the users to add to or remove from the group and records the outcome
+// for each in batch. A user that can't be found, or that isn't a member of the group when being
+// removed, fails and is left out of the returned list.
+func resolveMemberChanges(group *Group, names []string, adding bool, batch *batchRun, tx *gorm.DB) ([]User, error) {
+
+	found, err := dbReadUsers(map[string]interface{}{"name": names}, tx)
+	if err != nil {
+		return nil, err
+	}
+	usersByName := make(map[string]User, len(found))
+	for _, u := range found {
+		usersByName[u.Name] = u
+	}
+
+	var users []User
+	for _, name := range names {
+		u, ok := usersByName[name]
+		var bErr error
+		switch {
+		case !ok:
+			bErr = batch.fail(name, newCodedError(common.ErrNotFound, "user not found"))
+		case !adding && !u.isMemberOfGroup(group):
+			bErr = batch.fail(name, newCodedError(common.ErrConflict, "not a member of the group"))
+		case adding:
+			users = append(users, u)
+			batch.succeed(name, "added")
+		default:
+			users = append(users, u)
+			batch.succeed(name, "removed")
+		}
+		if bErr != nil {
+			return nil, bErr
+		}
+	}
+	return users, nil
+}
+
+// onlyGroupDefaultEdits returns true if the only changes requested are to the reservation defaults or
+// VLAN range of a group. These are igor settings and can be changed even on groups synced from LDAP.
+func onlyGroupDefaultEdits(editParams map[string]interface{}) bool {
+	for k := range editParams {
+		if k != "failFast" && k != "defaultDistro" && k != "defaultDuration" && k != "vlanRange" {
+			return false
+		}
+	}
+	return true
+}
+
+// onlyGroupOwnerEdits returns true if the only changes requested are to the owners of the group. The
+// owners of an LDAP-synced group can be changed in igor when the sync doesn't manage them.
+func onlyGroupOwnerEdits(editParams map[string]interface{}) bool {
+	for k := range editParams {
+		if k != "failFast" && k != "addOwners" && k != "rmvOwners" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseGroupDefaults checks any requested changes to the reservation defaults of a group and adds them
+// to changes. The default distro must exist and be shared with the group (or everyone), and the default
+// duration must meet the minimum reservation length. A value of 'none' clears the default.
+func parseGroupDefaults(group *Group, editParams map[string]interface{}, changes map[string]interface{}, tx *gorm.DB) (int, error) {
+
+	defDistro, hasDistro := editParams["defaultDistro"].(string)
+	defDuration, hasDuration := editParams["defaultDuration"].(string)
+	if !hasDistro && !hasDuration {
+		return http.StatusOK, nil
+	}
+
+	if group.Name == GroupAll || group.IsUserPrivate {
+		return http.StatusBadRequest, fmt.Errorf("reservation defaults cannot be set on group '%s'", group.Name)
+	}
+
+	if hasDistro {
+		if defDistro = strings.TrimSpace(defDistro); defDistro == GroupNoneAlias {
+			changes["defaultDistro"] = ""
+		} else {
+			dList, gdStatus, gdErr := getDistros([]string{defDistro}, tx)
+			if gdErr != nil {
+				return gdStatus, gdErr
+			}
+			if !groupSliceContains(dList[0].Groups, group.Name) && !groupSliceContains(dList[0].Groups, GroupAll) {
+				return http.StatusBadRequest, fmt.Errorf("distro '%s' is not shared with group '%s' and cannot be its default", defDistro, group.Name)
+			}
+			changes["defaultDistro"] = dList[0].Name
+		}
+	}
+
+	if hasDuration {
+		if defDuration = strings.TrimSpace(defDuration); defDuration == GroupNoneAlias {
+			changes["defaultDuration"] = ""
+		} else {
+			dur, pErr := common.ParseDuration(defDuration)
+			if pErr != nil {
+				return http.StatusBadRequest, fmt.Errorf("'%s' is not a recognized duration interval", defDuration)
+			} else if !meetsMinResDuration(dur) {
+				return http.StatusBadRequest, fmt.Errorf("default duration must be larger than minimum value %v minutes", igor.Scheduler.MinReserveTime)
+			}
+			changes["defaultDuration"] = defDuration
+		}
+	}
+
+	return http.StatusOK, nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gomail "gopkg.in/mail.v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestGroupRenameKeepsResAccess(t *testing.T) {
+
+	origEmail, origRefs, origDial := igor.Email, igor.ClusterRefs, smtpDial
+	t.Cleanup(func() { igor.Email, igor.ClusterRefs, smtpDial = origEmail, origRefs, origDial })
+	notifyOn := true
+	igor.Email.SmtpServers = []SmtpServerConfig{{Host: "smtp.example.com", Port: DefaultSmtpPort}}
+	igor.Email.DefaultSuffix = "example.com"
+	igor.Email.ResNotifyOn = &notifyOn
+	r, _ := common.NewRange("kn", 1, 10)
+	igor.ClusterRefs = []common.Range{*r}
+	initNotify()
+	smtp := &fakeSmtpServer{failAfter: -1}
+	smtpDial = func(*gomail.Dialer) (gomail.SendCloser, error) { return smtp, nil }
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	newUser := func(name string) User {
+		pug := Group{Name: GroupUserPrefix + name, IsUserPrivate: true}
+		require.NoError(t, db.Omit(clause.Associations).Create(&pug).Error)
+		u := User{Name: name, Email: name + "@example.com", Groups: []Group{pug, all}}
+		require.NoError(t, db.Omit("Groups.*").Create(&u).Error)
+		return u
+	}
+	alice, bob := newUser("alice"), newUser("bob")
+
+	team := Group{Name: "team", Owners: []User{alice}, Members: []User{alice, bob}}
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error { return dbCreateGroup(&team, false, tx) }))
+	require.NoError(t, db.Model(&bob).Update("default_group", "team").Error)
+
+	// alice shares a running reservation with the group, which can power its host
+	now := time.Now()
+	res := Reservation{Name: "shared", Hash: "shared", OwnerID: alice.ID, GroupID: team.ID, Start: now, End: now.Add(time.Hour),
+		ResetEnd: now.Add(time.Hour), Hosts: hosts[:1], Installed: true}
+	require.NoError(t, db.Omit("Hosts.*").Omit(clause.Associations).Create(&res).Error)
+	require.NoError(t, db.Model(&res).Association("Hosts").Append(hosts[:1]))
+	var gPerms []Permission
+	for _, fact := range append(makeResGroupPermStrings(&res), makeNodeActionPerm(hosts[:1])) {
+		p, err := NewPermission(fact)
+		require.NoError(t, err)
+		gPerms = append(gPerms, *p)
+	}
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error { return dbAppendPermissions(&team, gPerms, tx) }))
+
+	// the warning is queued with a copy of the reservation from before the rename
+	stored, err := dbReadReservationsTx(map[string]interface{}{"name": "shared"}, nil)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	warn := makeResWarnNotifyEvent(EmailResWarn, 30*time.Minute, &stored[0], "krypton")
+	require.NotNil(t, warn)
+
+	// rename the group and make bob an owner in the same edit
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		gList, err := dbReadGroups(map[string]interface{}{"name": "team"}, true, tx)
+		if err != nil {
+			return err
+		}
+		return dbEditGroup(&gList[0], map[string]interface{}{"name": "squad", "addOwners": []User{bob}}, tx)
+	}))
+
+	var stale int64
+	require.NoError(t, db.Model(&Permission{}).Where("fact LIKE ?", PermGroups+PermDividerToken+"team"+PermDividerToken+"%").Count(&stale).Error)
+	assert.Zero(t, stale)
+	bobOwnerPerms, err := dbGetResourceOwnerPermissions(PermGroups, "squad", &bob, db)
+	require.NoError(t, err)
+	assert.NotEmpty(t, bobOwnerPerms)
+
+	// bob's default group follows the rename
+	var member User
+	require.NoError(t, db.Preload("Groups").First(&member, bob.ID).Error)
+	assert.Equal(t, "squad", member.DefaultGroup)
+
+	// the expiry warning still reaches the group
+	assert.NoError(t, processResNotifyEvent(*warn))
+	assert.Equal(t, 1, smtp.sent)
+
+	// and a member can still power the reservation's host
+	req := httptest.NewRequest(http.MethodPatch, "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), userContextKey{}, &member))
+	_, hostNames, status, err := checkPowerParams(map[string]interface{}{"cmd": "cycle", "resName": "shared"}, req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status, fmt.Sprint(err))
+	assert.Equal(t, []string{"kn1"}, hostNames)
+}
+
+func TestGroupMemberChangeFlipsNodeActions(t *testing.T) {
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	newUser := func(name string) User {
+		pug := Group{Name: GroupUserPrefix + name, IsUserPrivate: true}
+		require.NoError(t, db.Omit(clause.Associations).Create(&pug).Error)
+		u := User{Name: name, Email: name + "@example.com", Groups: []Group{pug, all}}
+		require.NoError(t, db.Omit("Groups.*").Create(&u).Error)
+		return u
+	}
+	alice := newUser("alice")
+	newUser("carol")
+
+	team := Group{Name: "team", Owners: []User{alice}, Members: []User{alice}}
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error { return dbCreateGroup(&team, false, tx) }))
+
+	// alice's installed reservation is shared with the group, which holds its node action permission
+	now := time.Now()
+	res := Reservation{Name: "shared", Hash: "shared", OwnerID: alice.ID, GroupID: team.ID, Start: now, End: now.Add(time.Hour),
+		ResetEnd: now.Add(time.Hour), Hosts: hosts[:1], Installed: true}
+	require.NoError(t, db.Omit("Hosts.*").Omit(clause.Associations).Create(&res).Error)
+	require.NoError(t, db.Model(&res).Association("Hosts").Append(hosts[:1]))
+	nodePerm, err := NewPermission(makeNodeActionPerm(hosts[:1]))
+	require.NoError(t, err)
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error { return dbAppendPermissions(&team, []Permission{*nodePerm}, tx) }))
+
+	// each request authenticates the user again, the same as authnHandler does
+	powerStatus := func(params map[string]interface{}) int {
+		carol, authErr := findUserForAuthN("carol")
+		require.NoError(t, authErr)
+		req := httptest.NewRequest(http.MethodPatch, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey{}, carol))
+		_, _, status, _ := checkPowerParams(params, req)
+		return status
+	}
+	byRes := map[string]interface{}{"cmd": "cycle", "resName": "shared"}
+	byHost := map[string]interface{}{"cmd": "cycle", "hosts": "kn1"}
+
+	editMembers := func(param string) {
+		owner := &User{Name: "alice"}
+		req := httptest.NewRequest(http.MethodPatch, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey{}, owner))
+		_, status, editErr := doUpdateGroup("team", map[string]interface{}{param: []interface{}{"carol"}}, req)
+		require.NoError(t, editErr)
+		require.Equal(t, http.StatusOK, status)
+	}
+
+	assert.Equal(t, http.StatusForbidden, powerStatus(byRes))
+	assert.Equal(t, http.StatusForbidden, powerStatus(byHost))
+
+	editMembers("add")
+	assert.Equal(t, http.StatusOK, powerStatus(byRes))
+	assert.Equal(t, http.StatusOK, powerStatus(byHost))
+
+	editMembers("remove")
+	assert.Equal(t, http.StatusForbidden, powerStatus(byRes))
+	assert.Equal(t, http.StatusForbidden, powerStatus(byHost))
+
+	// the permission itself was never rewritten
+	var facts []string
+	require.NoError(t, db.Model(&Permission{}).Where("group_id = ?", team.ID).Pluck("fact", &facts).Error)
+	assert.Contains(t, facts, nodePerm.Fact)
+}
+
+func TestRenameKeepsDefaults(t *testing.T) {
+
+	seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	pug := Group{Name: GroupUserPrefix + "alice", IsUserPrivate: true}
+	require.NoError(t, db.Omit(clause.Associations).Create(&pug).Error)
+	alice := User{Name: "alice", Email: "alice@example.com", DefaultGroup: "team", Groups: []Group{pug}}
+	require.NoError(t, db.Omit("Groups.*").Create(&alice).Error)
+	distro := Distro{Name: "centos", OwnerID: alice.ID}
+	require.NoError(t, db.Omit(clause.Associations).Create(&distro).Error)
+	team := Group{Name: "team", DefaultDistro: "centos", Owners: []User{alice}, Members: []User{alice}}
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error { return dbCreateGroup(&team, false, tx) }))
+
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		gList, err := dbReadGroups(map[string]interface{}{"name": "team"}, true, tx)
+		if err != nil {
+			return err
+		}
+		return dbEditGroup(&gList[0], map[string]interface{}{"name": "squad"}, tx)
+	}))
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		dList, err := dbReadDistros(map[string]interface{}{"name": "centos"}, tx)
+		if err != nil {
+			return err
+		}
+		return dbEditDistro(&dList[0], map[string]interface{}{"Name": "rocky"}, tx)
+	}))
+
+	var user User
+	require.NoError(t, db.First(&user, alice.ID).Error)
+	assert.Equal(t, "squad", user.DefaultGroup)
+	var group Group
+	require.NoError(t, db.First(&group, team.ID).Error)
+	assert.Equal(t, "squad", group.Name)
+	assert.Equal(t, "rocky", group.DefaultDistro)
+}
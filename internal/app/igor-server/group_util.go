@@ -7,29 +7,18 @@ package igorserver
 import (
 	"fmt"
 	"net/http"
-	"strings"
+
+	"igor2/internal/pkg/naming"
 )
 
 // checkGroupNameRules determines if the input string meets the criteria for a valid group name.
 func checkGroupNameRules(name string) error {
-	if len(name) == 0 {
-		return fmt.Errorf("group name cannot be empty")
-	}
-	if !stdNameCheckPattern.MatchString(name) {
-		return fmt.Errorf("'%s' is not a legal group name", name)
-	}
-
-	return isResourceNameMatch(name)
+	return naming.Check(naming.Group, name)
 }
 
 // Apply this to create/edit/del operations to prevent use of names that are reserved or might be confusing.
 func checkReservedGroupNames(name string) error {
-	lname := strings.ToLower(name)
-	if lname == GroupAll || lname == GroupNoneAlias || strings.HasPrefix(lname, GroupUserPrefix) ||
-		strings.HasPrefix(lname, "admin") {
-		return fmt.Errorf("group name '%s' is not allowed", name)
-	}
-	return nil
+	return naming.CheckReserved(naming.Group, name)
 }
 
 // groupSliceContains returns true if one of the groups in the slice has the given name, false otherwise.
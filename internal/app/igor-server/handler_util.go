@@ -7,6 +7,7 @@ package igorserver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -17,10 +18,11 @@ import (
 
 	"igor2/internal/pkg/api"
 	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
 )
 
 // Regex for simple names. Includes letters, numbers, underscore, dash, and dot. Must be 3-24 characters in
-// length. No whitespace allowed.
+// length. No whitespace allowed. Resource names are checked with the naming package instead.
 var stdNameCheckPattern = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9._-]{2,23}$`)
 
 // Regex for Eth names. Includes letters, numbers, slash. Must be 3-24 characters in
@@ -41,34 +43,17 @@ var stdImageIDCheckPattern = regexp.MustCompile(`^[a-zA-Z0-9]{40}$`)
 // characters in length.
 var descCheckPattern = regexp.MustCompile(`^[a-zA-Z0-9 :,)(.?!_-]{0,256}$`)
 
+// Regex for reservation descriptions. Adds the characters needed for the markdown they may use (see
+// renderMarkdown) to those of descCheckPattern. Max 256 characters in length.
+var resDescCheckPattern = regexp.MustCompile("^[a-zA-Z0-9 :,)(.?!_\\-\\[\\]*`/=&%+#~@']{0,256}$")
+
 // Regex for file names. Cannot start or end with spaces. May have a .ext included at the end, or not.
 var fileNameCheckPattern = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9 ._-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9_-])?$`)
 
-// checkGenericNameRules looks for unwanted patterns in names for various resources. Allows only characters specified
-// in the stdNameCheckPattern but cannot be all digits or all punctuation.
+// checkGenericNameRules checks the names of resources that don't have their own naming rules, such as
+// hosts and kickstarts. See the naming package for the rules.
 func checkGenericNameRules(name string) error {
-	if len(name) == 0 {
-		return fmt.Errorf("name cannot be empty")
-	}
-
-	isNotDigitOrPunc := func(c rune) bool { return (c < '0' || c > '9') && c != '.' && c != '_' && c != '-' }
-	digitsPuncOnly := strings.IndexFunc(name, isNotDigitOrPunc) == -1
-	if !stdNameCheckPattern.MatchString(name) || digitsPuncOnly {
-		return fmt.Errorf("'%s' is not a legal name. (Must be 3-24 chars, start with letter, number or underscore. Can contain dash and dot. Cannot be all digits or all punctuation.)", name)
-	}
-	return isResourceNameMatch(name)
-}
-
-// isResourceNameMatch throws an error if the input value matches a word that could cause a problem with
-// a permissions check or operation. These words should not be used as the name of a given resource.
-func isResourceNameMatch(value string) error {
-	switch value {
-	case PermGroups, PermUsers, PermClusters, PermDistros, PermHosts, PermProfiles, PermReservations,
-		"hostPolicy", "group", "user", "cluster", "distro", "host", "profile", "reservation":
-		return fmt.Errorf("name cannot be restricted word '%s'", value)
-	default:
-		return nil
-	}
+	return naming.Check(naming.Generic, name)
 }
 
 // A very simple panic handler
@@ -92,9 +77,32 @@ func checkDesc(desc string) error {
 	return nil
 }
 
+// checkResDesc is checkDesc for reservation descriptions, which may also use the characters of
+// links, bold, italics and inline code:
+//
+//	[]*`/=&%+#~@'
+func checkResDesc(desc string) error {
+	if !resDescCheckPattern.MatchString(strings.TrimSpace(desc)) {
+		return fmt.Errorf("description field invalid, must be 0-256 characters and may only contain letters, numbers, space and .,_-():?![]*`/=&%%+#~@' characters")
+	}
+	return nil
+}
+
 func createValidationErrMessage(validateErr error, w http.ResponseWriter) {
+	// an upload that passed its size limit while the form was parsed
+	var maxErr *http.MaxBytesError
+	if errors.As(validateErr, &maxErr) {
+		bodyTooLargeResp(w, maxErr.Limit)
+		return
+	}
 	rb := common.NewResponseBody()
 	rb.Message = validateErr.Error()
+	rb.ErrorCode = errorCodeOf(validateErr)
+	// tell the client exactly which naming rule was broken
+	var nameErr *naming.Error
+	if errors.As(validateErr, &nameErr) {
+		rb.Data["nameError"] = nameErr
+	}
 	makeJsonResponse(w, http.StatusBadRequest, rb)
 }
 
@@ -113,6 +121,20 @@ func marshalJSONBody(v interface{}) []byte {
 // if the type can't be determined or a 415 if the type is not 'application/json'. The check
 // is only applied to request types that are allowed to have content (PUT,POST, and PATCH). Other
 // request types pass though.
+// setVersionHeaders adds the server version and the oldest client version it supports to the response so
+// clients can tell when they need to be upgraded. Headers are left out when a value isn't known.
+func setVersionHeaders(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v, ok := common.AppSemVer(); ok {
+			w.Header().Set(common.ServerVersionHeader, v.String())
+		}
+		if igor.Server.MinClientVersion != "" {
+			w.Header().Set(common.MinClientVersionHeader, igor.Server.MinClientVersion)
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
 func checkContentType(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPut || r.Method == http.MethodPost || r.Method == http.MethodPatch {
@@ -128,7 +150,7 @@ func checkContentType(handler http.Handler) http.Handler {
 					makeJsonResponse(w, http.StatusBadRequest, rb)
 					return
 				}
-				if strings.HasPrefix(r.URL.Path, api.Distros) || strings.HasPrefix(r.URL.Path, api.Images) || strings.HasPrefix(r.URL.Path, api.Kickstarts) {
+				if isUploadRoute(r) {
 					if (r.Method == http.MethodPost || r.Method == http.MethodPatch) && mt != common.MFormData {
 						errMsg := fmt.Sprintf("need content-type '%s', but got '%s'", common.MFormData, ct)
 						logger.Error().Msg(errMsg)
@@ -158,36 +180,96 @@ func checkContentType(handler http.Handler) http.Handler {
 	})
 }
 
+// isUploadRoute reports whether the request is for one of the routes that take multipart uploads of
+// image and kickstart files.
+func isUploadRoute(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, api.Distros) || strings.HasPrefix(r.URL.Path, api.Images) ||
+		strings.HasPrefix(r.URL.Path, api.Kickstarts)
+}
+
+// bodyLimitMB returns the largest body in MB accepted for the request. The longest path in
+// server.bodyLimitsMB that the route falls under wins, otherwise upload routes get server.maxUploadMB
+// and all others server.maxBodyMB.
+func bodyLimitMB(r *http.Request) int {
+	limit, matched := 0, ""
+	for route, routeLimit := range igor.Server.BodyLimitsMB {
+		if (r.URL.Path == route || strings.HasPrefix(r.URL.Path, route+"/")) && len(route) > len(matched) {
+			limit, matched = routeLimit, route
+		}
+	}
+	if matched != "" {
+		return limit
+	} else if isUploadRoute(r) {
+		return igor.Server.MaxUploadMB
+	}
+	return igor.Server.MaxBodyMB
+}
+
+// limitRequestBody caps the size of the request body so a client can't make the server buffer more
+// than it allows. A body that declares a length over the limit is refused before any of it is read.
+// Otherwise reading stops with an *http.MaxBytesError once the limit is passed, which the handlers that
+// read bodies turn into a 413 response with bodyTooLargeResp.
+func limitRequestBody(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil && r.Body != http.NoBody {
+			limit := int64(bodyLimitMB(r)) << 20
+			if r.ContentLength > limit {
+				bodyTooLargeResp(w, limit)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// bodyTooLargeResp sends the 413 response for a request whose body was larger than limit bytes. The
+// limit is included in the response so clients can tell the user what is allowed.
+func bodyTooLargeResp(w http.ResponseWriter, limit int64) {
+	rb := common.NewResponseBody()
+	rb.Message = fmt.Sprintf("request body is larger than the %d MB limit", limit>>20)
+	rb.ErrorCode = common.ErrBodyTooLarge
+	rb.Data["limitMB"] = limit >> 20
+	makeJsonResponse(w, http.StatusRequestEntityTooLarge, rb)
+}
+
 // storeJSONBodyHandler extracts the body of an incoming request, unmarshals it from
 // JSON into a map[string]interface{} and stores the map in the context that is forwarded
 // to each subsequent handler. It can be accessed by calling:
 //
 //	myParamMap := getBodyFromContext(r)
 //
-// It will panic if the body encounters a read error (which shouldn't happen).
+// It will panic if the body encounters a read error (which shouldn't happen) other than the
+// body passing the size limit set by limitRequestBody, which returns 413 Request Entity Too Large.
 //
 // If an InvalidUnmarshalError is encountered it will log an error and return 400 Bad Request.
 func storeJSONBodyHandler(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		var mapBody map[string]interface{}
-		if body, readErr := io.ReadAll(r.Body); readErr == nil {
-			if len(body) > 0 {
-				err := json.Unmarshal(body, &mapBody)
-				if err != nil {
-					errMsg := fmt.Sprintf("JSON unmarshal error: %v", err)
-					logger.Error().Msg(errMsg)
-					rb := common.NewResponseBody()
-					rb.Message = err.Error()
-					makeJsonResponse(w, http.StatusBadRequest, rb)
-					return
-				}
+		body, readErr := io.ReadAll(r.Body)
+		if readErr != nil {
+			var maxErr *http.MaxBytesError
+			if !errors.As(readErr, &maxErr) {
+				panic(readErr)
 			}
-			rCopy := addBodyToContext(r, mapBody)
-			handler.ServeHTTP(w, rCopy)
-		} else {
-			panic(readErr)
+			logger.Warn().Msgf("request body for %s is larger than the %d MB limit", r.URL.Path, maxErr.Limit>>20)
+			bodyTooLargeResp(w, maxErr.Limit)
+			return
 		}
+		if len(body) > 0 {
+			err := json.Unmarshal(body, &mapBody)
+			if err != nil {
+				errMsg := fmt.Sprintf("JSON unmarshal error: %v", err)
+				logger.Error().Msg(errMsg)
+				rb := common.NewResponseBody()
+				rb.Message = err.Error()
+				makeJsonResponse(w, http.StatusBadRequest, rb)
+				return
+			}
+		}
+		rCopy := addBodyToContext(r, mapBody)
+		handler.ServeHTTP(w, rCopy)
 	})
 }
 
@@ -195,6 +277,12 @@ func storeJSONBodyHandler(handler http.Handler) http.Handler {
 // ResponseWriter when attaching JSON content.
 func makeJsonResponse(w http.ResponseWriter, status int, rb common.ResponseBody) {
 	rb.SetStatus(status)
+	if status >= http.StatusBadRequest && rb.GetErrorCode() == "" {
+		// every failure gets a code; log the ones that fall back to a generic code so they can be given a proper one
+		code := common.DefaultErrorCode(status)
+		logger.Debug().Msgf("no error code set for %d response '%s', using %s", status, rb.GetMessage(), code)
+		rb.SetErrorCode(code)
+	}
 	w.Header().Set(common.ContentType, common.MAppJson)
 	w.WriteHeader(status)
 	jsonBytes := marshalJSONBody(rb)
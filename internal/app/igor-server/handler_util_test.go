@@ -5,12 +5,21 @@
 package igorserver
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igor2/internal/pkg/api"
+	"igor2/internal/pkg/common"
 )
 
 func TestEmptyJsonBody(t *testing.T) {
@@ -70,3 +79,135 @@ func TestGetJSONBodyFromContext(t *testing.T) {
 	assert.Contains(t, jBodyMap, "one", "did not contain a key named 'one'")
 
 }
+
+func TestSetVersionHeaders(t *testing.T) {
+
+	origTag, origMin := common.GitTag, igor.Server.MinClientVersion
+	t.Cleanup(func() { common.GitTag, igor.Server.MinClientVersion = origTag, origMin })
+
+	handler := setVersionHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	common.GitTag = "v2.3.1-7-g1a2b3c4"
+	igor.Server.MinClientVersion = "2.2.0"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "2.3.1", w.Header().Get(common.ServerVersionHeader))
+	assert.Equal(t, "2.2.0", w.Header().Get(common.MinClientVersionHeader))
+
+	// a dev build has no version to report
+	common.GitTag = ""
+	igor.Server.MinClientVersion = ""
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, w.Header().Get(common.ServerVersionHeader))
+	assert.Empty(t, w.Header().Get(common.MinClientVersionHeader))
+}
+
+// setBodyLimits sets the request body size limits for the length of a test.
+func setBodyLimits(t *testing.T, bodyMB, uploadMB int, routes map[string]int) {
+	origBody, origUpload, origRoutes := igor.Server.MaxBodyMB, igor.Server.MaxUploadMB, igor.Server.BodyLimitsMB
+	t.Cleanup(func() {
+		igor.Server.MaxBodyMB, igor.Server.MaxUploadMB, igor.Server.BodyLimitsMB = origBody, origUpload, origRoutes
+	})
+	igor.Server.MaxBodyMB, igor.Server.MaxUploadMB, igor.Server.BodyLimitsMB = bodyMB, uploadMB, routes
+}
+
+func TestBodyLimitMB(t *testing.T) {
+
+	setBodyLimits(t, 1, 100, map[string]int{api.Distros: 200, api.Distros + "/big": 300, api.Reservations: 2})
+
+	for path, expected := range map[string]int{
+		api.Hosts:                1,
+		api.Images:               100,
+		api.Kickstarts + "/ks1":  100,
+		api.Distros:              200,
+		api.Distros + "/bigger":  200,
+		api.Distros + "/big":     300,
+		api.Reservations + "/r1": 2,
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, http.NoBody)
+		assert.Equalf(t, expected, bodyLimitMB(req), "limit for %s", path)
+	}
+}
+
+func TestJsonBodyTooLarge(t *testing.T) {
+
+	setBodyLimits(t, 1, 100, nil)
+	hc := NewHandlerChain(limitRequestBody, storeJSONBodyHandler)
+	var routed bool
+	handler := hc.ApplyTo(func(w http.ResponseWriter, r *http.Request) { routed = true })
+
+	// a JSON object padded out to the given size in bytes
+	jsonOfSize := func(size int) string {
+		return `{"pad":"` + strings.Repeat("x", size-10) + `"}`
+	}
+
+	send := func(body string, declareLength bool) *httptest.ResponseRecorder {
+		routed = false
+		req := httptest.NewRequest(http.MethodPost, api.Hosts, io.NopCloser(strings.NewReader(body)))
+		if declareLength {
+			req.ContentLength = int64(len(body))
+		} else {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		handler(w, req, nil)
+		return w
+	}
+
+	for _, declared := range []bool{true, false} {
+		w := send(jsonOfSize(1<<20), declared)
+		assert.True(t, routed, "body at the limit was refused")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// one byte over the cap, whether or not the length was declared up front
+		w = send(jsonOfSize(1<<20+1), declared)
+		assert.False(t, routed, "body over the limit was passed on")
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		rb := common.NewResponseBody()
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), rb))
+		assert.Equal(t, common.ErrBodyTooLarge, rb.GetErrorCode())
+		assert.Contains(t, rb.GetMessage(), "1 MB limit")
+		assert.EqualValues(t, 1, rb.Data["limitMB"])
+	}
+}
+
+func TestUploadTooLarge(t *testing.T) {
+
+	setBodyLimits(t, 1, 2, nil)
+	hc := NewHandlerChain(limitRequestBody, validateDistroImageParams)
+	var routed bool
+	handler := hc.ApplyTo(func(w http.ResponseWriter, r *http.Request) { routed = true })
+
+	// stream a multipart upload of a 3 MB file so the length isn't known until it has all been sent
+	pr, pw := io.Pipe()
+	t.Cleanup(func() { _ = pr.Close() })
+	mw := multipart.NewWriter(pw)
+	go func() {
+		_ = mw.WriteField("name", "big")
+		fw, err := mw.CreateFormFile("kernelFile", "big.kernel")
+		if err == nil {
+			chunk := bytes.Repeat([]byte{'k'}, 64<<10)
+			for i := 0; i < 3<<20/len(chunk) && err == nil; i++ {
+				_, err = fw.Write(chunk)
+			}
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		_ = pw.CloseWithError(err)
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, api.Images, pr)
+	req.ContentLength = -1
+	req.Header.Set(common.ContentType, mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	handler(w, req, nil)
+
+	assert.False(t, routed, "upload over the limit was passed on")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	rb := common.NewResponseBody()
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), rb))
+	assert.Equal(t, common.ErrBodyTooLarge, rb.GetErrorCode())
+	assert.EqualValues(t, 2, rb.Data["limitMB"])
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"igor2/internal/pkg/common"
+)
+
+// healthDbTimeout is how long the health check waits for the database to answer.
+const healthDbTimeout = 2 * time.Second
+
+// healthHandler reports the server version and whether the database can be reached. It needs no
+// login and isn't logged per request since monitors and igor-web poll it. It returns 503 if the
+// database didn't answer so a poller doesn't have to read the body to know.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+
+	hd := common.HealthData{Version: common.GitTag, Time: time.Now().Unix()}
+	if hd.Version == "" {
+		hd.Version = "no version info"
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthDbTimeout)
+	defer cancel()
+	if sqlDb, err := igor.IGormDb.GetDB().DB(); err == nil {
+		hd.DbOK = sqlDb.PingContext(ctx) == nil
+	}
+
+	status := http.StatusOK
+	rb := common.NewResponseBody()
+	rb.Data["health"] = hd
+	if !hd.DbOK {
+		status = http.StatusServiceUnavailable
+		rb.Message = "igor-server database is unavailable"
+		rb.SetErrorCode(common.ErrServiceUnavailable)
+	}
+	makeJsonResponse(w, status, rb)
+}
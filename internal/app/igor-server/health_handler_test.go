@@ -0,0 +1,46 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igor2/internal/pkg/api"
+)
+
+func TestHealthHandler(t *testing.T) {
+
+	seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+
+	check := func() (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		healthHandler(w, httptest.NewRequest(http.MethodGet, api.Health, nil))
+		var body struct {
+			Data map[string]map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body.Data["health"]
+	}
+
+	status, health := check()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, health["dbOk"])
+	assert.NotEmpty(t, health["version"])
+
+	// a database that can't be reached is reported as unavailable
+	sqlDb, err := igor.IGormDb.GetDB().DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDb.Close())
+	status, health = check()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, false, health["dbOk"])
+}
@@ -5,6 +5,7 @@
 package igorserver
 
 import (
+	"encoding/json"
 	"strings"
 	"time"
 )
@@ -24,6 +25,9 @@ type HistoryRecord struct {
 	Name        string `gorm:"notNull"`
 	Description string
 	Owner       string
+	// CreatedBy is the user that made the reservation, which is an admin when it was made for the owner.
+	// It is only set on the created record.
+	CreatedBy   string
 	Group       string
 	Profile     string
 	Distro      string
@@ -33,6 +37,13 @@ type HistoryRecord struct {
 	OrigEnd     time.Time
 	ExtendCount int
 	Hosts       string
+	// ReqDuration and ReqNodeCount are what the reservation asked for when it was created
+	ReqDuration  time.Duration
+	ReqNodeCount int
+	// EndPower is what happens to the power of the hosts when the reservation ends
+	EndPower string
+	// Request holds the parameters of the create request as JSON. It is only set on the created record.
+	Request string
 }
 
 func NewHistoryRecord(res *Reservation, status string) *HistoryRecord {
@@ -63,6 +74,10 @@ func NewHistoryRecord(res *Reservation, status string) *HistoryRecord {
 		OrigEnd:     res.OrigEnd,
 		ExtendCount: res.ExtendCount,
 		Hosts:       strings.Join(namesOfHosts(res.Hosts), ","),
+
+		ReqDuration:  res.ReqDuration,
+		ReqNodeCount: res.ReqNodeCount,
+		EndPower:     res.EndPower,
 	}
 
 	return hr
@@ -72,3 +87,14 @@ func doHistoryRecord(res *Reservation, status string) error {
 	hr := NewHistoryRecord(res, status)
 	return dbCreateHistoryRecordTx(hr)
 }
+
+// doCreatedHistoryRecord records the creation of the reservation by the given user along with the
+// parameters of the create request.
+func doCreatedHistoryRecord(res *Reservation, createdBy *User, request map[string]interface{}) error {
+	hr := NewHistoryRecord(res, HrCreated)
+	hr.CreatedBy = createdBy.Name
+	if reqJson, err := json.Marshal(request); err == nil {
+		hr.Request = string(reqJson)
+	}
+	return dbCreateHistoryRecordTx(hr)
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestCreateKeepsRequestedDuration(t *testing.T) {
+
+	origSched, origSchedMinutes, origNotify := igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn
+	t.Cleanup(func() {
+		igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn = origSched, origSchedMinutes, origNotify
+	})
+	notifyOff := false
+	igor.Email.ResNotifyOn = &notifyOff
+	igor.Scheduler.MinReserveTime = 30
+	igor.Scheduler.DefaultReserveTime = 60
+	igor.Scheduler.MaxReserveTime = 30 * 24 * 60
+	igor.Scheduler.NodeReserveLimit = 0
+	MaxScheduleMinutes = 45 * 24 * 60
+
+	seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	pug := Group{Name: GroupUserPrefix + "alice"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&pug).Error)
+	alice := User{Name: "alice", Groups: []Group{pug, all}}
+	require.NoError(t, db.Omit("Groups.*").Create(&alice).Error)
+	distro := Distro{Name: "centos", Groups: []Group{all}}
+	require.NoError(t, db.Omit("Groups.*").Create(&distro).Error)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, &alice))
+
+	// 30 days is more than any host policy allows, so the end is clamped
+	params := map[string]interface{}{"name": "big", "distro": "centos", "nodeCount": float64(1), "duration": "30d", "clampToLimit": true}
+	res, _, _, status, err := doCreateReservation(params, r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+
+	granted := res.OrigEnd.Sub(res.Start)
+	assert.Less(t, granted, 30*24*time.Hour)
+	assert.Equal(t, 30*24*time.Hour, res.ReqDuration)
+	assert.Equal(t, 1, res.ReqNodeCount)
+
+	// the requested values are saved with the reservation
+	stored, err := dbReadReservationsTx(map[string]interface{}{"name": "big"}, nil)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, 30*24*time.Hour, stored[0].ReqDuration)
+	assert.Equal(t, 1, stored[0].ReqNodeCount)
+
+	// and the created history record has the request as it was sent
+	var hrs []HistoryRecord
+	require.NoError(t, db.Where("hash = ?", res.Hash).Find(&hrs).Error)
+	require.Len(t, hrs, 1)
+	assert.Equal(t, HrCreated, hrs[0].Status)
+	assert.Equal(t, "alice", hrs[0].CreatedBy)
+	assert.Equal(t, 30*24*time.Hour, hrs[0].ReqDuration)
+	var request map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(hrs[0].Request), &request))
+	assert.Equal(t, params, request)
+
+	// stats count the demand that was turned away
+	summaries := map[string]common.ResHistory{res.Hash: {
+		Start:        res.Start,
+		OrigEnd:      res.OrigEnd,
+		ReqDuration:  res.ReqDuration,
+		ReqNodeCount: res.ReqNodeCount,
+	}}
+	byMonth := resDemandByMonth(summaries, time.Now().Add(time.Hour))
+	month := byMonth[res.Start.Format("2006-01")]
+	assert.Equal(t, 1, month.ResCount)
+	assert.Equal(t, 1, month.ClampedCount)
+	assert.InDelta(t, 720, month.RequestedNodeHours, 0.01)
+	assert.InDelta(t, granted.Hours(), month.GrantedNodeHours, 0.01)
+}
@@ -0,0 +1,273 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/rs/zerolog/hlog"
+
+	"igor2/internal/pkg/common"
+)
+
+// Events that can have a hook. The hook for an event is the executable file in the hooks
+// folder with the same name as the event.
+const (
+	HookResStart         = "reservation-start"
+	HookResEnd           = "reservation-end"
+	HookHostsDropped     = "hosts-dropped"
+	HookMaintenanceStart = "maintenance-start"
+)
+
+const (
+	// hookStatusLimit is the number of hook runs kept for the admin hook status view
+	hookStatusLimit = 100
+	// hookOutputLimit is the most output kept from a single hook run
+	hookOutputLimit = 1024
+)
+
+// extCmdTokens limits how many external commands (power status polls and hooks) run at once.
+// It holds externalCmds.concurrencyLimit tokens and is made when the server starts.
+var extCmdTokens chan struct{}
+
+var (
+	hookStatus   []common.HookStatusData // most recent hook runs, oldest first
+	hookStatusMU sync.Mutex
+)
+
+// HookPayload is the JSON document written to the stdin of a hook.
+type HookPayload struct {
+	Event       string         `json:"event"`
+	RequestID   string         `json:"requestId"`
+	Time        time.Time      `json:"time"`
+	Reservation *HookResData   `json:"reservation,omitempty"`
+	Maintenance *HookMaintData `json:"maintenance,omitempty"`
+	Hosts       []HookHostData `json:"hosts"`
+}
+
+// HookResData describes the reservation an event happened to.
+type HookResData struct {
+	Name    string    `json:"name"`
+	Owner   string    `json:"owner"`
+	Group   string    `json:"group"`
+	Profile string    `json:"profile"`
+	Distro  string    `json:"distro"`
+	Vlan    int       `json:"vlan"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	// HeadHost is the reservation's head host, see Reservation.headHost
+	HeadHost string `json:"headHost,omitempty"`
+}
+
+// HookMaintData describes the maintenance period hosts were put into.
+type HookMaintData struct {
+	Name string    `json:"name"`
+	End  time.Time `json:"end"`
+}
+
+// HookHostData describes a host involved in an event.
+type HookHostData struct {
+	Name     string `json:"name"`
+	HostName string `json:"hostName"`
+	Mac      string `json:"mac"`
+	IP       string `json:"ip"`
+}
+
+// hookRequestID returns the request ID of r to pass to a hook, or a new ID if the event
+// was not caused by a request.
+func hookRequestID(r *http.Request) string {
+	if r != nil {
+		if id, ok := hlog.IDFromRequest(r); ok {
+			return id.String()
+		}
+	}
+	return xid.New().String()
+}
+
+// newHookPayload builds the payload for an event. The reservation can be nil if the event
+// isn't tied to one.
+func newHookPayload(event, reqID string, res *Reservation, hosts []Host) *HookPayload {
+
+	p := &HookPayload{
+		Event:     event,
+		RequestID: reqID,
+		Time:      time.Now(),
+		Hosts:     make([]HookHostData, 0, len(hosts)),
+	}
+
+	if res != nil {
+		p.Reservation = &HookResData{
+			Name:    res.Name,
+			Owner:   res.Owner.Name,
+			Group:   res.Group.Name,
+			Profile: res.Profile.Name,
+			Distro:  res.Profile.Distro.Name,
+			Vlan:    res.Vlan,
+			Start:   res.Start,
+			End:     res.End,
+		}
+		if head := res.headHost(); head != nil {
+			p.Reservation.HeadHost = head.Name
+		}
+	}
+
+	for _, h := range hosts {
+		p.Hosts = append(p.Hosts, HookHostData{
+			Name:     h.Name,
+			HostName: h.HostName,
+			Mac:      h.Mac,
+			IP:       h.IP,
+		})
+	}
+
+	return p
+}
+
+// fireHook starts the hook for the payload's event in the background if one is installed.
+// A hook never holds up or fails the operation that triggered it.
+func fireHook(payload *HookPayload) {
+
+	hookPath, ok := findHook(payload.Event)
+	if !ok {
+		return
+	}
+
+	go func() {
+		extCmdTokens <- struct{}{}
+		defer func() { <-extCmdTokens }()
+		runHook(hookPath, payload)
+	}()
+}
+
+// findHook returns the path of the hook for an event if it exists and is executable.
+func findHook(event string) (string, bool) {
+
+	if igor.Hooks.Dir == "" {
+		return "", false
+	}
+
+	hookPath := filepath.Join(igor.Hooks.Dir, event)
+	fi, err := os.Stat(hookPath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn().Msgf("unable to read %s hook at %s - %v", event, hookPath, err)
+		}
+		return "", false
+	}
+	if fi.IsDir() || fi.Mode()&0111 == 0 {
+		logger.Warn().Msgf("%s hook at %s is not an executable file - skipping", event, hookPath)
+		return "", false
+	}
+
+	return hookPath, true
+}
+
+// runHook runs a hook with the payload on its stdin and records the result. The hook is
+// stopped if it runs longer than hooks.timeout.
+func runHook(hookPath string, payload *HookPayload) {
+
+	status := common.HookStatusData{
+		Event:     payload.Event,
+		Hook:      hookPath,
+		RequestID: payload.RequestID,
+		Started:   time.Now().Unix(),
+	}
+	if payload.Reservation != nil {
+		status.Reservation = payload.Reservation.Name
+	}
+
+	input, err := json.Marshal(payload)
+	if err != nil {
+		status.Error = fmt.Sprintf("unable to build payload: %v", err)
+		recordHookStatus(status)
+		return
+	}
+
+	timeout := time.Duration(igor.Hooks.Timeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, hookPath)
+	cmd.Dir = igor.Hooks.Dir
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(), "IGOR_HOOK_EVENT="+payload.Event, "IGOR_REQUEST_ID="+payload.RequestID)
+	// don't wait on output pipes held open by anything the hook left running
+	cmd.WaitDelay = 5 * time.Second
+
+	logger.Debug().Str("reqId", payload.RequestID).Msgf("running %s hook %s", payload.Event, hookPath)
+
+	start := time.Now()
+	out, err := cmd.CombinedOutput()
+	status.Duration = time.Since(start).Round(time.Millisecond).String()
+
+	if len(out) > hookOutputLimit {
+		out = out[len(out)-hookOutputLimit:]
+	}
+	status.Output = strings.TrimSpace(string(out))
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		status.ExitCode = -1
+		status.Error = fmt.Sprintf("timed out after %v", timeout)
+	case errors.As(err, &exitErr):
+		status.ExitCode = exitErr.ExitCode()
+		status.Error = err.Error()
+	case err != nil:
+		status.ExitCode = -1
+		status.Error = err.Error()
+	}
+
+	if status.Error != "" {
+		logEvent(&logger, EvHookFailed).Str(LogFieldHook, hookPath).Str(LogFieldError, status.Error).Str("reqId", payload.RequestID).
+			Msgf("%s hook %s failed - %s: %s", payload.Event, hookPath, status.Error, status.Output)
+	} else {
+		logger.Debug().Str("reqId", payload.RequestID).Msgf("%s hook %s finished in %s", payload.Event, hookPath, status.Duration)
+	}
+
+	recordHookStatus(status)
+}
+
+// recordHookStatus saves the result of a hook run, dropping the oldest result once
+// hookStatusLimit runs have been saved.
+func recordHookStatus(status common.HookStatusData) {
+
+	hookStatusMU.Lock()
+	defer hookStatusMU.Unlock()
+
+	hookStatus = append(hookStatus, status)
+	if len(hookStatus) > hookStatusLimit {
+		hookStatus = hookStatus[len(hookStatus)-hookStatusLimit:]
+	}
+}
+
+// getHookStatus returns the saved hook runs, newest first. If failedOnly is set only runs
+// that did not succeed are returned.
+func getHookStatus(failedOnly bool) []common.HookStatusData {
+
+	hookStatusMU.Lock()
+	defer hookStatusMU.Unlock()
+
+	list := make([]common.HookStatusData, 0, len(hookStatus))
+	for i := len(hookStatus) - 1; i >= 0; i-- {
+		if failedOnly && hookStatus[i].Error == "" {
+			continue
+		}
+		list = append(list, hookStatus[i])
+	}
+	return list
+}
@@ -0,0 +1,174 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igor2/internal/pkg/common"
+)
+
+// writeTestHook writes a hook script for event that saves its stdin and request ID env
+// variable next to itself, then exits with the given code.
+func writeTestHook(t *testing.T, dir, event, exitCode string) {
+	script := "#!/bin/sh\ncat > \"$0.payload\"\necho \"$IGOR_REQUEST_ID\" > \"$0.reqid\"\necho hook output\nexit " + exitCode + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, event), []byte(script), 0755))
+}
+
+func setupTestHooks(t *testing.T) string {
+	dir := t.TempDir()
+	igor.Hooks.Dir = dir
+	igor.Hooks.Timeout = 10
+	extCmdTokens = make(chan struct{}, 1)
+	hookStatus = nil
+	t.Cleanup(func() {
+		igor.Hooks.Dir = ""
+		hookStatus = nil
+	})
+	return dir
+}
+
+func TestRunHookPayload(t *testing.T) {
+
+	dir := setupTestHooks(t)
+	writeTestHook(t, dir, HookResStart, "0")
+
+	res := &Reservation{
+		Name:    "myres",
+		Owner:   User{Name: "alice"},
+		Group:   Group{Name: "pug-alice"},
+		Profile: Profile{Name: "myprof", Distro: Distro{Name: "centos"}},
+		Vlan:    101,
+		Start:   time.Now(),
+		End:     time.Now().Add(time.Hour),
+	}
+	hosts := []Host{
+		{Name: "kn1", HostName: "kn1.example.com", Mac: "00:00:00:00:00:01", IP: "10.0.0.1"},
+		{Name: "kn2", HostName: "kn2.example.com", Mac: "00:00:00:00:00:02", IP: "10.0.0.2"},
+	}
+
+	hookPath, ok := findHook(HookResStart)
+	require.True(t, ok)
+	runHook(hookPath, newHookPayload(HookResStart, "req123", res, hosts))
+
+	raw, err := os.ReadFile(hookPath + ".payload")
+	require.NoError(t, err)
+
+	var payload HookPayload
+	require.NoError(t, json.Unmarshal(raw, &payload))
+	assert.Equal(t, HookResStart, payload.Event)
+	assert.Equal(t, "req123", payload.RequestID)
+	require.NotNil(t, payload.Reservation)
+	assert.Equal(t, "myres", payload.Reservation.Name)
+	assert.Equal(t, "alice", payload.Reservation.Owner)
+	assert.Equal(t, "centos", payload.Reservation.Distro)
+	assert.Equal(t, 101, payload.Reservation.Vlan)
+	require.Len(t, payload.Hosts, 2)
+	assert.Equal(t, "kn2.example.com", payload.Hosts[1].HostName)
+
+	reqID, err := os.ReadFile(hookPath + ".reqid")
+	require.NoError(t, err)
+	assert.Equal(t, "req123\n", string(reqID))
+
+	status := getHookStatus(false)
+	require.Len(t, status, 1)
+	assert.Equal(t, "myres", status[0].Reservation)
+	assert.Equal(t, 0, status[0].ExitCode)
+	assert.Empty(t, status[0].Error)
+	assert.Empty(t, getHookStatus(true))
+}
+
+func TestRunHookFailure(t *testing.T) {
+
+	dir := setupTestHooks(t)
+	writeTestHook(t, dir, HookHostsDropped, "3")
+
+	hookPath, ok := findHook(HookHostsDropped)
+	require.True(t, ok)
+	runHook(hookPath, newHookPayload(HookHostsDropped, "req456", nil, []Host{{Name: "kn1"}}))
+
+	status := getHookStatus(true)
+	require.Len(t, status, 1)
+	assert.Equal(t, 3, status[0].ExitCode)
+	assert.NotEmpty(t, status[0].Error)
+	assert.Equal(t, "hook output", status[0].Output)
+	assert.Equal(t, "req456", status[0].RequestID)
+}
+
+func TestRunHookTimeout(t *testing.T) {
+
+	dir := setupTestHooks(t)
+	igor.Hooks.Timeout = 1
+	require.NoError(t, os.WriteFile(filepath.Join(dir, HookResEnd), []byte("#!/bin/sh\nexec sleep 10\n"), 0755))
+
+	hookPath, ok := findHook(HookResEnd)
+	require.True(t, ok)
+	runHook(hookPath, newHookPayload(HookResEnd, "req789", nil, nil))
+
+	status := getHookStatus(true)
+	require.Len(t, status, 1)
+	assert.Equal(t, -1, status[0].ExitCode)
+	assert.Contains(t, status[0].Error, "timed out")
+}
+
+func TestFindHook(t *testing.T) {
+
+	dir := setupTestHooks(t)
+
+	// missing hook
+	_, ok := findHook(HookMaintenanceStart)
+	assert.False(t, ok)
+
+	// hook that isn't executable
+	require.NoError(t, os.WriteFile(filepath.Join(dir, HookMaintenanceStart), []byte("#!/bin/sh\n"), 0644))
+	_, ok = findHook(HookMaintenanceStart)
+	assert.False(t, ok)
+
+	// hooks turned off
+	writeTestHook(t, dir, HookResStart, "0")
+	igor.Hooks.Dir = ""
+	_, ok = findHook(HookResStart)
+	assert.False(t, ok)
+}
+
+func TestFireHookAsync(t *testing.T) {
+
+	dir := setupTestHooks(t)
+	writeTestHook(t, dir, HookResEnd, "0")
+
+	fireHook(newHookPayload(HookResEnd, "reqabc", &Reservation{Name: "r1"}, nil))
+
+	assert.Eventually(t, func() bool {
+		return len(getHookStatus(false)) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	raw, err := os.ReadFile(filepath.Join(dir, HookResEnd+".payload"))
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"reservation-end"`)
+}
+
+func TestRecordHookStatusLimit(t *testing.T) {
+
+	setupTestHooks(t)
+	for i := 0; i < hookStatusLimit+5; i++ {
+		recordHookStatus(hookStatusEntry(i))
+	}
+
+	status := getHookStatus(false)
+	require.Len(t, status, hookStatusLimit)
+	// newest first
+	assert.Equal(t, hookStatusEntry(hookStatusLimit+4), status[0])
+}
+
+func hookStatusEntry(i int) common.HookStatusData {
+	return common.HookStatusData{Event: HookResStart, Started: int64(i)}
+}
@@ -38,12 +38,21 @@ type Host struct {
 	Name           string `gorm:"unique; notNull"`
 	HostName       string `gorm:"unique; notNull"`
 	SequenceID     int    `gorm:"notNull; uniqueIndex:idx_cluster_seq"`
-	Eth            string
+	Eth            string // Eth is the switch port of the host's data interface.
+	Ports          string // Ports are the host's other switch ports as role=port pairs, ex. 'mgmt=Et2/7'.
 	Mac            string `gorm:"unique; notNull"`
 	IP             string
 	BootMode       string    `gorm:"notNull; default:bios"`
 	State          HostState // State is the HostState of this node. Default when created is HostBlocked.
 	RestoreState   HostState // State to return to after Maintenance phase is done. Either HostAvailable or HostBlocked.
+	DrainReason    string    // Admin-supplied reason the host was put into the HostDraining state.
+	BlockedBy      string    // Name of the admin who blocked the host. Cleared when the host is unblocked.
+	BlockedAt      time.Time // When the host was blocked. Zero if it hasn't been blocked through igor since last unblocked.
+	BlockReason    string    // Admin-supplied reason the host was blocked, if any.
+	PollInterval   int       // Seconds between power status polls of this host. 0 uses externalCmds.powerPollInterval.
+	Console        string    // Name of this host on the console server if it differs from Name. Empty uses Name.
+	UsageMinutes   int64     // Minutes the host was held by reservations that have ended, used to even out wear.
+	InstallError   string    `gorm:"-"` // Install failure for this host in a reservation (read from reservations_hosts).
 	ClusterID      int       `gorm:"notNull; uniqueIndex:idx_cluster_seq"`
 	Cluster        Cluster   `gorm:"->;<-:create; notNull"` // read/create only; hosts never change clusters
 	HostPolicyID   int
@@ -68,7 +77,7 @@ func (h *Host) GetHostIPs() ([]net.IP, error) {
 
 func (h *Host) BeforeDelete(_ *gorm.DB) (delErr error) {
 
-	if h.State == HostReserved {
+	if h.State == HostReserved || h.State == HostDraining {
 		return fmt.Errorf("cannot delete node %s - active reservation present", h.Name)
 	}
 
@@ -87,7 +96,7 @@ func (h *Host) getHostData(powered *bool, user *User) common.HostData {
 	}
 
 	// check if restricted by group access
-	restricted := !user.isMemberOfAnyGroup(h.HostPolicy.AccessGroups)
+	restricted := !user.isMemberOfAnyGroup(h.HostPolicy.AccessGroups) || h.HostPolicy.excludedGroupOf(user.groupNames()) != ""
 
 	// then if the user is in an access group, check for time availability conditions
 	if !restricted && len(h.HostPolicy.NotAvailable) > 0 {
@@ -126,16 +135,34 @@ func (h *Host) getHostData(powered *bool, user *User) common.HostData {
 		SequenceID:   h.SequenceID,
 		HostName:     h.HostName,
 		Eth:          h.Eth,
+		Ports:        h.getHostPortData(),
 		IP:           ip,
 		Mac:          h.Mac,
 		BootMode:     h.BootMode,
 		State:        h.State.String(),
+		DrainReason:  h.DrainReason,
+		InstallError: h.InstallError,
 		Powered:      poweredOn,
 		Cluster:      h.Cluster.Name,
 		HostPolicy:   h.HostPolicy.Name,
 		AccessGroups: groups,
 		Restricted:   restricted,
 		Reservations: resNames,
+		NextUnavail:  h.nextUnavailWindow(time.Now(), unavailLookahead()),
+	}
+
+	if !h.BlockedAt.IsZero() {
+		hd.BlockedBy = h.BlockedBy
+		hd.BlockedAt = h.BlockedAt.Unix()
+		hd.BlockReason = h.BlockReason
+	}
+
+	if h.canSeeConsole(user) {
+		hd.Console = h.consoleLink()
+	}
+
+	if user != nil && userElevated(user.Name) {
+		hd.UsageHours = h.UsageMinutes / 60
 	}
 
 	return hd
@@ -165,6 +192,16 @@ func filterHostList(hostList []Host, filterPowered *bool, user *User) []common.H
 			hd = h.getHostData(nil, user)
 		}
 
+		if info, ok := powerPollMap[h.HostName]; ok {
+			if !info.checked.IsZero() {
+				hd.PowerChecked = info.checked.Unix()
+			}
+			if info.latency > 0 && userElevated(user.Name) {
+				hd.PowerPollLatency = info.latency.Round(time.Millisecond).String()
+			}
+		}
+		hd.PowerPollInterval = h.PollInterval
+
 		hostDetails = append(hostDetails, hd)
 	}
 	powerMapMU.Unlock()
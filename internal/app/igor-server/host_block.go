@@ -9,133 +9,147 @@ import (
 	"igor2/internal/pkg/common"
 	"net/http"
 	"sort"
+	"strings"
 	"time"
 
+	"github.com/rs/zerolog/hlog"
 	"gorm.io/gorm"
 )
 
-// Maps the power command parameters to a list of hosts and checks permissions to ensure the user
-// can actually issue a power command for those hosts.
-func checkBlockParams(powerParams map[string]interface{}) (bool, []string, int, error) {
+// Maps the block command parameters to a list of hosts.
+func checkBlockParams(blockParams map[string]interface{}) (bool, bool, []string, string, int, error) {
 
-	block := powerParams["block"].(bool)
-	val := powerParams["hosts"].(string)
+	block := blockParams["block"].(bool)
+	failFast, _ := blockParams["failFast"].(bool)
+	val := blockParams["hosts"].(string)
+	reason, _ := blockParams["reason"].(string)
 
 	hostList := igor.splitRange(val)
 	if len(hostList) == 0 {
-		return block, nil, http.StatusBadRequest, fmt.Errorf("can't parse hosts - %v", val)
+		return block, failFast, nil, reason, http.StatusBadRequest, fmt.Errorf("can't parse hosts - %v", val)
 	}
 	sort.Slice(hostList, func(i, j int) bool {
 		return hostList[i] < hostList[j]
 	})
 
-	return block, hostList, http.StatusOK, nil
+	return block, failFast, hostList, strings.TrimSpace(reason), http.StatusOK, nil
 }
 
-// Runs the actual power command for the service that controls host power options.
-func doUpdateBlockHosts(blockAction bool, hostList []string, r *http.Request) (status int, err error) {
+// doUpdateBlockHosts blocks or unblocks each of the named hosts and reports the outcome for each. A host
+// that can't be found or, when unblocking, isn't blocked fails without stopping the others unless
+// failFast is set, in which case no host is changed. A blocked host records who blocked it, when and
+// the reason given, which are cleared when it is unblocked.
+func doUpdateBlockHosts(blockAction, failFast bool, hostList []string, reason string, r *http.Request) (results []common.BatchItemResult, status int, err error) {
 
 	status = http.StatusInternalServerError // default status, overridden at end if no errors
+	clog := hlog.FromRequest(r)
+	actionUser := getUserFromContext(r)
 
 	if err = performDbTx(func(tx *gorm.DB) error {
 
-		hList, ghStatus, ghErr := getHostsTx(hostList, true)
-		if err != nil {
+		hList, ghStatus, ghErr := getHosts(hostList, false, tx)
+		if ghErr != nil {
 			status = ghStatus
 			return ghErr
 		}
-		if len(hList) == 0 {
-			return fmt.Errorf("no hosts found for given host list: %v", hostList)
+		hostsByName := make(map[string]*Host, len(hList))
+		for i := range hList {
+			hostsByName[hList[i].Name] = &hList[i]
 		}
 
-		if blockAction {
-
-			blockedRes := make(map[string]Reservation)
-			for _, h := range hList {
-				if h.State == HostReserved {
-					for _, res := range h.Reservations {
-						if res.IsActive(time.Now()) {
-							blockedRes[res.Name] = res
-						}
+		now := time.Now()
+		batch := newBatchRun(tx, failFast)
+		var blockedHosts []Host
+
+		for _, name := range hostList {
+			h, found := hostsByName[name]
+			var bErr error
+			switch {
+			case !found:
+				bErr = batch.fail(name, newCodedError(common.ErrNotFound, "host not found"))
+			case blockAction:
+				bErr = batch.do(name, func() error {
+					return dbEditHosts([]Host{*h}, map[string]interface{}{"State": HostBlocked, "DrainReason": "",
+						"BlockedBy": actionUser.Name, "BlockedAt": now, "BlockReason": reason}, tx)
+				})
+				if bErr == nil && batch.results[len(batch.results)-1].Result == common.BatchItemOK {
+					blockedHosts = append(blockedHosts, *h)
+					logEvent(clog, EvHostStateChanged).Str(LogFieldHost, name).Str(LogFieldFrom, h.State.String()).
+						Str(LogFieldTo, HostBlocked.String()).Str(LogFieldUser, actionUser.Name).Str("reason", reason).Msg("host blocked")
+				}
+			case h.State != HostBlocked:
+				bErr = batch.fail(name, newCodedError(common.ErrConflict, "cannot un-block a non-blocked host"))
+			default:
+				// a host with a current reservation goes back to reserved, otherwise it is available
+				state := HostAvailable
+				for _, res := range h.Reservations {
+					if res.IsActive(now) {
+						state = HostReserved
 					}
 				}
+				bErr = batch.do(name, func() error {
+					return dbEditHosts([]Host{*h}, map[string]interface{}{"State": state, "DrainReason": "",
+						"BlockedBy": "", "BlockedAt": time.Time{}, "BlockReason": ""}, tx)
+				})
+				if bErr == nil && batch.results[len(batch.results)-1].Result == common.BatchItemOK {
+					logEvent(clog, EvHostStateChanged).Str(LogFieldHost, name).Str(LogFieldFrom, HostBlocked.String()).
+						Str(LogFieldTo, state.String()).Str(LogFieldUser, actionUser.Name).Str("blockedBy", h.BlockedBy).Msg("host unblocked")
+				}
 			}
-
-			blockErr := dbEditHosts(hList, map[string]interface{}{"State": HostBlocked}, tx)
-			if blockErr != nil {
-				return blockErr
+			if bErr != nil {
+				status = batchAbortStatus(bErr)
+				return bErr
 			}
+		}
+		results = batch.results
 
-			if len(blockedRes) > 0 {
-				actionUser := getUserFromContext(r)
-				isElevated := userElevated(actionUser.Name)
-
-				for _, bRes := range blockedRes {
-					var blockList []string
-					var clusterName = ""
-					for _, host := range hList {
-						for _, hostRes := range host.Reservations {
-							if bRes.Name == hostRes.Name {
-								blockList = append(blockList, host.HostName)
-								clusterName = host.Cluster.Name
-							}
-						}
-					}
-
-					res, _, _ := getReservations([]string{bRes.Name}, tx)
+		notifyBlockedRes(blockedHosts, now, r, tx)
+		return nil
 
-					blockEvent := makeResEditNotifyEvent(EmailResBlock, &res[0], clusterName, actionUser, isElevated, common.UnsplitList(blockList))
-					if blockEvent != nil {
-						resNotifyChan <- *blockEvent
-					}
-				}
-			}
+	}); err == nil {
+		status = http.StatusOK
+	}
+	return
+}
 
-			return nil
-		} else {
+// notifyBlockedRes tells the members of each active reservation that lost hosts to a block which of
+// its hosts were blocked.
+func notifyBlockedRes(blockedHosts []Host, now time.Time, r *http.Request, tx *gorm.DB) {
 
-			for _, h := range hList {
-				if h.State != HostBlocked {
-					status = http.StatusConflict
-					return fmt.Errorf("cannot un-block a non-blocked host: '%s'", h.HostName)
+	blockedRes := make(map[string]Reservation)
+	for _, h := range blockedHosts {
+		if h.State == HostReserved || h.State == HostDraining {
+			for _, res := range h.Reservations {
+				if res.IsActive(now) {
+					blockedRes[res.Name] = res
 				}
 			}
+		}
+	}
+	if len(blockedRes) == 0 {
+		return
+	}
 
-			var hAvailList []Host
-			var hReservedList []Host
-
-			for _, h := range hList {
-				if len(h.Reservations) == 0 {
-					hAvailList = append(hAvailList, h) // no reservations, set to available
-				} else {
-					for _, r := range h.Reservations {
-						if r.IsActive(time.Now()) {
-							hReservedList = append(hReservedList, h) // current reservation, set to reserved
-						} else {
-							hAvailList = append(hAvailList, h) // has a future reservation, set to available
-						}
-					}
+	actionUser := getUserFromContext(r)
+	isElevated := userElevated(actionUser.Name)
+
+	for _, bRes := range blockedRes {
+		var blockList []string
+		var clusterName = ""
+		for _, host := range blockedHosts {
+			for _, hostRes := range host.Reservations {
+				if bRes.Name == hostRes.Name {
+					blockList = append(blockList, host.HostName)
+					clusterName = host.Cluster.Name
 				}
 			}
+		}
 
-			var unblockErr error
-
-			// do unreserved first
-			if len(hAvailList) > 0 {
-				unblockErr = dbEditHosts(hAvailList, map[string]interface{}{"State": HostAvailable}, tx)
-				if unblockErr != nil {
-					return unblockErr
-				}
-			}
+		res, _, _ := getReservations([]string{bRes.Name}, tx)
 
-			if len(hReservedList) > 0 {
-				unblockErr = dbEditHosts(hReservedList, map[string]interface{}{"State": HostReserved}, tx)
-			}
-			return unblockErr
+		blockEvent := makeResEditNotifyEvent(EmailResBlock, &res[0], clusterName, actionUser, isElevated, common.UnsplitList(blockList))
+		if blockEvent != nil {
+			resNotifyChan <- *blockEvent
 		}
-
-	}); err == nil {
-		status = http.StatusOK
 	}
-	return
 }
@@ -0,0 +1,79 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// consoleNamePattern matches the names a host can have on the console server
+var consoleNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._:-]{0,62}$`)
+
+// consoleLink returns the link to the host's serial console rendered from server.consoleUrl, or an empty
+// string if console links are not configured. The host's console name is used if it has one, otherwise
+// its igor name.
+func (h *Host) consoleLink() string {
+	if igor.Server.ConsoleURL == "" {
+		return ""
+	}
+	name := h.Name
+	if h.Console != "" {
+		name = h.Console
+	}
+	return fmt.Sprintf(igor.Server.ConsoleURL, url.PathEscape(name))
+}
+
+// canSeeConsole reports whether the user may see the console link of the host. Only admins and the
+// members of the reservation currently using the host can see it, and only admins if the host's
+// policy restricts the console node action.
+func (h *Host) canSeeConsole(user *User) bool {
+	if userElevated(user.Name) {
+		return true
+	}
+	if h.HostPolicy.restrictsAction(NodeActionConsole) {
+		return false
+	}
+	now := time.Now()
+	for i := range h.Reservations {
+		if h.Reservations[i].IsActive(now) && h.Reservations[i].hasMember(user) {
+			return true
+		}
+	}
+	return false
+}
+
+// canSeeConsoles reports whether the user may see the console links of the reservation's hosts. Only
+// admins and the reservation's members can see them, and only while the reservation is active. Hosts
+// whose policy restricts the console node action are left to the caller.
+func (r *Reservation) canSeeConsoles(user *User) bool {
+	if !r.IsActive(time.Now()) {
+		return false
+	}
+	return userElevated(user.Name) || r.hasMember(user)
+}
+
+// hasMember returns true if the user is the owner or a co-owner of the reservation or belongs to
+// its group. Only the IDs of the reservation's owner and group need to be loaded.
+func (r *Reservation) hasMember(user *User) bool {
+	if r.OwnerID == user.ID || r.isCoOwner(user.Name) {
+		return true
+	}
+	for _, g := range user.Groups {
+		if g.ID == r.GroupID {
+			return true
+		}
+	}
+	return false
+}
+
+func checkConsoleNameRules(name string) error {
+	if name != "" && !consoleNamePattern.MatchString(name) {
+		return fmt.Errorf("'%s' is not a legal console name", name)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setConsoleURL(t *testing.T, tmpl string) {
+	orig := igor.Server.ConsoleURL
+	igor.Server.ConsoleURL = tmpl
+	useElevateMap(t)
+	t.Cleanup(func() { igor.Server.ConsoleURL = orig })
+}
+
+func TestConsoleLink(t *testing.T) {
+
+	setConsoleURL(t, "")
+	h := &Host{Name: "kn1"}
+	assert.Empty(t, h.consoleLink())
+
+	setConsoleURL(t, "https://console.example.com/%s")
+	assert.Equal(t, "https://console.example.com/kn1", h.consoleLink())
+
+	// a console name overrides the host name
+	h.Console = "rack2:kn1-bmc"
+	assert.Equal(t, "https://console.example.com/rack2:kn1-bmc", h.consoleLink())
+
+	assert.NoError(t, checkConsoleNameRules(""))
+	assert.NoError(t, checkConsoleNameRules("rack2:kn1-bmc"))
+	assert.Error(t, checkConsoleNameRules("kn1/../admin"))
+	assert.Error(t, checkConsoleNameRules("kn1?x=1"))
+}
+
+func TestCanSeeConsole(t *testing.T) {
+
+	setConsoleURL(t, "https://console.example.com/%s")
+
+	owner := &User{Base: Base{ID: 1}, Name: "alice"}
+	member := &User{Base: Base{ID: 2}, Name: "bob", Groups: []Group{{Base: Base{ID: 10}, Name: "team"}}}
+	coOwner := &User{Base: Base{ID: 3}, Name: "carol"}
+	other := &User{Base: Base{ID: 4}, Name: "dave", Groups: []Group{{Base: Base{ID: 11}, Name: "others"}}}
+
+	now := time.Now()
+	res := Reservation{
+		OwnerID:  owner.ID,
+		GroupID:  10,
+		CoOwners: []User{*coOwner},
+		Start:    now.Add(-time.Hour),
+		End:      now.Add(time.Hour),
+	}
+	h := &Host{Name: "kn1", Reservations: []Reservation{res}}
+
+	for _, u := range []*User{owner, member, coOwner} {
+		assert.True(t, h.canSeeConsole(u), u.Name)
+		assert.True(t, res.canSeeConsoles(u), u.Name)
+	}
+	assert.False(t, h.canSeeConsole(other))
+	assert.False(t, res.canSeeConsoles(other))
+
+	igor.ElevateMap.Put(other.Name, true)
+	assert.True(t, h.canSeeConsole(other))
+	igor.ElevateMap.Remove(other.Name)
+
+	// a reservation that hasn't started, or is paused, doesn't give access to its hosts' consoles
+	future := res
+	future.Start, future.End = now.Add(time.Hour), now.Add(2*time.Hour)
+	h.Reservations = []Reservation{future}
+	assert.False(t, h.canSeeConsole(owner))
+	assert.False(t, future.canSeeConsoles(owner))
+
+	paused := res
+	paused.PausedUntil = now.Add(30 * time.Minute)
+	h.Reservations = []Reservation{paused}
+	assert.False(t, h.canSeeConsole(owner))
+}
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
 func dbCreateHosts(nodes []Host, tx *gorm.DB) error {
@@ -33,13 +34,17 @@ func dbReadHostsTx(queryParams map[string]interface{}) (hosts []Host, err error)
 // no matches. If no queryParams are provided, all hosts are returned.
 func dbReadHosts(queryParams map[string]interface{}, tx *gorm.DB) (hosts []Host, err error) {
 
-	tx = tx.Preload("Cluster").Preload("HostPolicy").Preload("HostPolicy.AccessGroups").
-		Preload("Reservations")
+	baseTx := tx
+	tx = tx.Preload("Cluster").Preload("HostPolicy").Preload("HostPolicy.AccessGroups").Preload("HostPolicy.ExcludedGroups").
+		Preload("Reservations").Preload("Reservations.CoOwners")
 
 	// if no params given, return all
 	if len(queryParams) == 0 {
 		result := tx.Find(&hosts)
-		return hosts, result.Error
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		return hosts, dbLoadActiveInstallErrors(hosts, baseTx)
 	}
 
 	for key, val := range queryParams {
@@ -59,12 +64,48 @@ func dbReadHosts(queryParams map[string]interface{}, tx *gorm.DB) (hosts []Host,
 		}
 	}
 	result := tx.Find(&hosts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
 
 	sort.Slice(hosts, func(i, j int) bool {
 		return hosts[i].SequenceID < hosts[j].SequenceID
 	})
 
-	return hosts, result.Error
+	return hosts, dbLoadActiveInstallErrors(hosts, baseTx)
+}
+
+// dbLoadActiveInstallErrors fills in the InstallError field of any host in the list that failed to
+// install for the reservation it currently belongs to.
+func dbLoadActiveInstallErrors(hosts []Host, tx *gorm.DB) error {
+
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	hostIDs := make([]int, len(hosts))
+	for i, h := range hosts {
+		hostIDs[i] = h.ID
+	}
+
+	now := time.Now()
+	var rhList []ReservationHost
+	result := tx.Joins("JOIN reservations ON reservations.id = reservations_hosts.reservation_id").
+		Where("reservations_hosts.host_id IN ? AND reservations_hosts.install_error <> ''", hostIDs).
+		Where("reservations.start <= ? AND reservations.end > ?", now, now).
+		Find(&rhList)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	for _, rh := range rhList {
+		for i := range hosts {
+			if hosts[i].ID == rh.HostID {
+				hosts[i].InstallError = rh.InstallError
+			}
+		}
+	}
+	return nil
 }
 
 // dbEditHosts iterates through a list of hosts applying the same changes to each.
@@ -85,6 +126,23 @@ func dbEditHosts(hosts []Host, changes map[string]interface{}, tx *gorm.DB) erro
 	return nil
 }
 
+// dbAddHostUsage adds the minutes a reservation held its hosts, up to now or its end, to their UsageMinutes.
+func dbAddHostUsage(res *Reservation, tx *gorm.DB) error {
+	end := time.Now()
+	if res.End.Before(end) {
+		end = res.End
+	}
+	mins := int64(end.Sub(res.Start).Minutes())
+	if mins <= 0 || len(res.Hosts) == 0 {
+		return nil
+	}
+	ids := make([]int, len(res.Hosts))
+	for i, h := range res.Hosts {
+		ids[i] = h.ID
+	}
+	return tx.Model(&Host{}).Where("id IN ?", ids).UpdateColumn("usage_minutes", gorm.Expr("usage_minutes + ?", mins)).Error
+}
+
 // dbDeleteHosts removes the list of hosts from the DB
 func dbDeleteHosts(targets []Host, tx *gorm.DB) error {
 	if len(targets) == 0 {
@@ -95,15 +153,45 @@ func dbDeleteHosts(targets []Host, tx *gorm.DB) error {
 }
 
 // dbCheckHostAvailable takes a list of hostnames and reports back if any are in a state that don't allow new reservations
-// to be made. If status return is 200/OK, it is assumed all the named hosts are available for scheduling.
-func dbCheckHostAvailable(hosts []string, tx *gorm.DB) (int, error) {
+// starting at start to be made. A host in a maintenance period can be reserved from the time it ends. If status return is
+// 200/OK, it is assumed all the named hosts are available for scheduling.
+func dbCheckHostAvailable(hosts []string, start time.Time, tx *gorm.DB) (int, error) {
 
 	var hostsCurrUnavail []string
+	var hostsDraining []string
 
-	// Check if any of the declared hosts are currently not accepting reservations (draining, blocked or error)
+	// Draining hosts get called out separately so the user knows the node is on its way out of the pool
 	result := tx.Model(&Host{}).
-		Where("name IN ? AND state > ?", hosts, HostReserved).
-		Pluck("name", &hostsCurrUnavail)
+		Where("name IN ? AND state = ?", hosts, HostDraining).
+		Pluck("name", &hostsDraining)
+	if result.RowsAffected > 0 {
+		return http.StatusConflict, fmt.Errorf("the following hosts are draining and not accepting new reservations: %v", hostsDraining)
+	}
+
+	// Hosts in maintenance say when they'll be free
+	maintEnds, err := dbHostMaintenanceEnds(hosts, tx)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	var inMaintenance, maintNames []string
+	for _, name := range hosts {
+		if end, ok := maintEnds[name]; ok {
+			maintNames = append(maintNames, name)
+			if start.Before(end) {
+				inMaintenance = append(inMaintenance, fmt.Sprintf("%s is in maintenance until %s", name, maintenanceEndString(end)))
+			}
+		}
+	}
+	if len(inMaintenance) > 0 {
+		return http.StatusConflict, fmt.Errorf("%s", strings.Join(inMaintenance, "; "))
+	}
+
+	// Check if any of the declared hosts are currently not accepting reservations (blocked or error)
+	unavailQuery := tx.Model(&Host{}).Where("name IN ? AND state > ?", hosts, HostReserved)
+	if len(maintNames) > 0 {
+		unavailQuery = unavailQuery.Where("name NOT IN ?", maintNames)
+	}
+	result = unavailQuery.Pluck("name", &hostsCurrUnavail)
 	if result.RowsAffected > 0 {
 		return http.StatusConflict, fmt.Errorf("the following hosts are not available at this time: %v", hostsCurrUnavail)
 	}
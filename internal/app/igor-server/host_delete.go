@@ -52,26 +52,11 @@ func doDeleteHost(hostName string, r *http.Request) (status int, err error) {
 			}
 		}
 
-		deleteErr := dbDeleteHosts(hList, tx)
-		if deleteErr != nil {
-			return deleteErr
-		} else {
-			var clusters []Cluster
-			var yDoc []byte
-			var cDumpErr error
-			var finalPath string
-			if clusters, cDumpErr = dbReadClusters(nil, tx); cDumpErr == nil {
-				if yDoc, cDumpErr = assembleYamlOutput(clusters); cDumpErr == nil {
-					finalPath, cDumpErr = updateClusterConfigFile(yDoc, clog)
-				}
-			}
-			if cDumpErr == nil {
-				clog.Info().Msgf("%s updated on host delete", finalPath)
-			}
-			return cDumpErr
-		}
+		return dbDeleteHosts(hList, tx)
+
 	}); err == nil {
 		status = http.StatusOK
+		queueClusterFileWrite(getUserFromContext(r).Name, clog)
 	}
 	return
 
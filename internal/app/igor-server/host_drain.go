@@ -0,0 +1,126 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Maps the drain command parameters to a list of hosts.
+func checkDrainParams(drainParams map[string]interface{}) (bool, []string, string, int, error) {
+
+	drain := drainParams["drain"].(bool)
+	val := drainParams["hosts"].(string)
+	reason, _ := drainParams["reason"].(string)
+
+	hostList := igor.splitRange(val)
+	if len(hostList) == 0 {
+		return drain, nil, reason, http.StatusBadRequest, fmt.Errorf("can't parse hosts - %v", val)
+	}
+	sort.Slice(hostList, func(i, j int) bool {
+		return hostList[i] < hostList[j]
+	})
+
+	return drain, hostList, strings.TrimSpace(reason), http.StatusOK, nil
+}
+
+// doUpdateDrainHosts puts hosts into (or takes them out of) the draining state. A draining host
+// finishes its current reservation but is excluded from all new reservations and extensions.
+// Hosts that have no active reservation have nothing to drain and go directly to blocked. The
+// returned list contains the names of any hosts that were moved straight to blocked.
+func doUpdateDrainHosts(drainAction bool, hostList []string, reason string) (blockedNow []string, status int, err error) {
+
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		hList, ghStatus, ghErr := getHosts(hostList, true, tx)
+		if ghErr != nil {
+			status = ghStatus
+			return ghErr
+		}
+		if len(hList) == 0 {
+			return fmt.Errorf("no hosts found for given host list: %v", hostList)
+		}
+
+		now := time.Now()
+
+		if drainAction {
+
+			var hDrainList []Host
+			var hBlockList []Host
+
+			for _, h := range hList {
+				switch h.State {
+				case HostReserved:
+					hDrainList = append(hDrainList, h)
+				case HostAvailable:
+					hBlockList = append(hBlockList, h)
+				default:
+					status = http.StatusConflict
+					return fmt.Errorf("cannot drain host '%s' in state %s", h.HostName, h.State.String())
+				}
+			}
+
+			if len(hDrainList) > 0 {
+				if dErr := dbEditHosts(hDrainList, map[string]interface{}{"State": HostDraining, "DrainReason": reason}, tx); dErr != nil {
+					return dErr
+				}
+			}
+
+			if len(hBlockList) > 0 {
+				if bErr := dbEditHosts(hBlockList, map[string]interface{}{"State": HostBlocked, "DrainReason": reason}, tx); bErr != nil {
+					return bErr
+				}
+				blockedNow = namesOfHosts(hBlockList)
+			}
+
+			return nil
+		}
+
+		var hAvailList []Host
+		var hReservedList []Host
+
+		for _, h := range hList {
+			if h.State != HostDraining {
+				status = http.StatusConflict
+				return fmt.Errorf("cannot undrain a non-draining host: '%s'", h.HostName)
+			}
+			isActive := false
+			for _, res := range h.Reservations {
+				if res.IsActive(now) {
+					isActive = true
+					break
+				}
+			}
+			if isActive {
+				hReservedList = append(hReservedList, h)
+			} else {
+				hAvailList = append(hAvailList, h)
+			}
+		}
+
+		if len(hAvailList) > 0 {
+			if uErr := dbEditHosts(hAvailList, map[string]interface{}{"State": HostAvailable, "DrainReason": ""}, tx); uErr != nil {
+				return uErr
+			}
+		}
+
+		if len(hReservedList) > 0 {
+			return dbEditHosts(hReservedList, map[string]interface{}{"State": HostReserved, "DrainReason": ""}, tx)
+		}
+		return nil
+
+	}); err == nil {
+		status = http.StatusOK
+	}
+	return
+}
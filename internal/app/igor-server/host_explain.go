@@ -0,0 +1,189 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	zl "github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// names of the scheduling checks reported by host explain, in the order they are evaluated
+const (
+	GateHostState       = "host state"
+	GatePolicyGroups    = "policy group membership"
+	GatePolicyAvailable = "policy unavailability"
+	GateMaxResTime      = "max reservation time"
+	GateResConflicts    = "reservation conflicts"
+	GateNodeLimit       = "node reserve limit"
+)
+
+// doExplainHost evaluates every check the scheduler makes when a user reserves a host by name
+// for the given time window and reports the outcome of each. The checks call the same functions
+// used when a reservation is created so the result matches what the user would experience.
+func doExplainHost(hostName, userName string, start time.Time, dur time.Duration, clog *zl.Logger) (explain *common.HostExplainData, status int, err error) {
+
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		hosts, ghStatus, ghErr := getHosts([]string{hostName}, true, tx)
+		if ghErr != nil {
+			status = ghStatus
+			return ghErr
+		}
+		host := &hosts[0]
+
+		users, guStatus, guErr := getUsers([]string{userName}, true, tx)
+		if guErr != nil {
+			status = guStatus
+			return guErr
+		}
+		user := &users[0]
+
+		isElevated := userElevated(user.Name)
+		end := start.Add(dur).Truncate(time.Minute)
+		hostNames := []string{host.Name}
+
+		explain = &common.HostExplainData{
+			Host:     host.Name,
+			User:     user.Name,
+			Elevated: isElevated,
+			Start:    start.Unix(),
+			End:      end.Unix(),
+		}
+		addGate := func(name string, gateErr error, passReason string) {
+			gate := common.HostExplainGate{Name: name, Pass: gateErr == nil, Reason: passReason}
+			if gateErr != nil {
+				gate.Reason = gateErr.Error()
+			}
+			explain.Gates = append(explain.Gates, gate)
+		}
+
+		// host state
+		_, haErr := dbCheckHostAvailable(hostNames, start, tx)
+		addGate(GateHostState, haErr, fmt.Sprintf("host is %s", host.State))
+
+		// the access list is built the same way as when scheduling hosts by name
+		var groupAccessList []string
+		for _, uGroup := range user.Groups {
+			if !strings.HasPrefix(uGroup.Name, GroupUserPrefix) {
+				groupAccessList = append(groupAccessList, uGroup.Name)
+			}
+		}
+
+		policies, hpErr := getHostPoliciesFromHostNames(hostNames)
+		if hpErr != nil {
+			return hpErr
+		}
+		var policyNames []string
+		for _, p := range policies {
+			policyNames = append(policyNames, p.Name)
+		}
+
+		// policy group membership
+		var groupErr error
+		if member, policy := dbCheckHostPolicyGroupConflicts(policies, groupAccessList); !member {
+			groupErr = fmt.Errorf("%s is not a member of any access group of policy '%s': %v", user.Name, policy.Name, groupNamesOfGroups(policy.AccessGroups))
+		} else if policy, excluded := checkHostPolicyExclusions(policies, groupAccessList); excluded != "" {
+			groupErr = fmt.Errorf("%s is a member of group '%s', which policy '%s' excludes", user.Name, excluded, policy.Name)
+		}
+
+		// policy unavailability
+		var availErr error
+		for _, policy := range policies {
+			if conflict, sbStart, sbEnd := hasScheduleBlockConflict(policy.NotAvailable, start, end, clog); conflict {
+				availErr = fmt.Errorf("policy '%s' makes the host unavailable from %s to %s", policy.Name,
+					sbStart.Format(common.DateTimeCompactFormat), sbEnd.Format(common.DateTimeCompactFormat))
+				break
+			}
+		}
+
+		// max reservation time
+		maxTimeErr := checkScheduleLimit(end, isElevated)
+		maxTimeReason := "within the scheduling window"
+		if isElevated {
+			maxTimeReason += "; policy time limits do not apply to elevated admins"
+		} else if maxTimeErr == nil {
+			for _, policy := range policies {
+				if maxTimeErr = checkTimeLimit(len(hostNames), policy.maxResTimeFor(groupAccessList), end.Sub(start)); maxTimeErr != nil {
+					maxTimeErr = fmt.Errorf("policy '%s': %v", policy.Name, maxTimeErr)
+					break
+				}
+			}
+		}
+
+		// The combined policy check is what actually gates a reservation. If it fails, its own
+		// reason replaces the one for the gate it corresponds to.
+		_, pcErr := dbCheckHostPolicyConflicts(hostNames, groupAccessList, groupAccessList, isElevated, start, end, end, clog)
+		var hpcErr *HostPolicyConflictError
+		if errors.As(pcErr, &hpcErr) {
+			switch {
+			case hpcErr.groupConflict && groupErr == nil:
+				groupErr = pcErr
+			case hpcErr.scheduleConflict && availErr == nil:
+				availErr = pcErr
+			case hpcErr.durationConflict && maxTimeErr == nil:
+				maxTimeErr = pcErr
+			}
+		} else if pcErr != nil {
+			return pcErr
+		}
+
+		policyReason := fmt.Sprintf("host policy: %s", strings.Join(policyNames, ", "))
+		addGate(GatePolicyGroups, groupErr, policyReason)
+		addGate(GatePolicyAvailable, availErr, policyReason)
+		addGate(GateMaxResTime, maxTimeErr, maxTimeReason)
+
+		// existing reservation conflicts
+		conflicts, rcStatus, rcErr := dbCheckResvConflicts(hostNames, start, end, tx)
+		if rcErr != nil && rcStatus != http.StatusConflict {
+			return rcErr
+		}
+		if len(conflicts) > 0 {
+			var resDesc []string
+			for _, c := range conflicts {
+				resDesc = append(resDesc, fmt.Sprintf("%s (%s to %s)", c.Name,
+					c.Start.Format(common.DateTimeCompactFormat), c.ResetEnd.Format(common.DateTimeCompactFormat)))
+			}
+			rcErr = fmt.Errorf("%v: %s", rcErr, strings.Join(resDesc, ", "))
+		}
+		addGate(GateResConflicts, rcErr, "no overlapping reservations")
+
+		// node reserve limit
+		var limitErr error
+		limitReason := "no node limit applies"
+		if !isElevated && igor.Scheduler.NodeReserveLimit > 0 {
+			limitReason = fmt.Sprintf("%d node(s) of %d allowed", len(hostNames), igor.Scheduler.NodeReserveLimit)
+			if len(hostNames) > igor.Scheduler.NodeReserveLimit {
+				limitErr = fmt.Errorf("only admins can make a reservation of more than %v nodes", igor.Scheduler.NodeReserveLimit)
+			}
+		}
+		addGate(GateNodeLimit, limitErr, limitReason)
+
+		explain.Allowed = true
+		for _, g := range explain.Gates {
+			if !g.Pass {
+				explain.Allowed = false
+				explain.FirstFailure = g.Name + ": " + g.Reason
+				break
+			}
+		}
+
+		return nil
+
+	}); err == nil {
+		status = http.StatusOK
+	}
+
+	return
+}
@@ -5,10 +5,13 @@
 package igorserver
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"igor2/internal/pkg/common"
 
@@ -56,6 +59,8 @@ func handleReadHosts(w http.ResponseWriter, r *http.Request) {
 }
 
 // destination for route PATCH /hosts/:hostName
+//
+// The host name can also be a range of hosts to apply the same changes to all of them.
 func handleUpdateHost(w http.ResponseWriter, r *http.Request) {
 
 	dbAccess.Lock()
@@ -67,29 +72,39 @@ func handleUpdateHost(w http.ResponseWriter, r *http.Request) {
 
 	ps := httprouter.ParamsFromContext(r.Context())
 	name := ps.ByName("hostName")
-	rb := common.NewResponseBody()
+	rb := common.NewResponseBodyHostEdit()
 
-	changes, status, err := parseHostEditParams(editParams, clog)
-	if err == nil {
-		status, err = doUpdateHost(name, changes, r)
+	var results []common.HostEditResult
+	status := http.StatusBadRequest
+	var err error
+
+	hostNames := igor.splitRange(name)
+	if len(hostNames) == 0 {
+		err = fmt.Errorf("couldn't parse node specification %v", name)
+	} else {
+		var changes map[string]interface{}
+		if changes, status, err = parseHostEditParams(editParams, clog); err == nil {
+			results, status, err = doUpdateHosts(hostNames, changes, r)
+		}
 	}
 
 	if err != nil {
-		if status < http.StatusBadRequest {
-			msg := fmt.Sprintf("'%s' updated but problem writing new igor-clusters.yaml : %v", name, err)
-			clog.Warn().Msgf("%s success - %s", actionPrefix, msg)
-			rb.Message = msg
-			status = http.StatusOK
-		} else {
-			rb.Message = err.Error()
-			if status < http.StatusInternalServerError {
-				clog.Warn().Msgf("%s failed - %v", actionPrefix, err)
-			} else {
-				clog.Error().Msgf("%s error - %v", actionPrefix, err)
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["hosts"] = results
+		var changed, unchanged, failed []string
+		for _, hr := range results {
+			switch hr.Result {
+			case HostEditChanged:
+				changed = append(changed, hr.Host)
+			case HostEditUnchanged:
+				unchanged = append(unchanged, hr.Host)
+			default:
+				failed = append(failed, hr.Host)
 			}
 		}
-	} else {
-		clog.Info().Msgf("%s success - '%s' updated", actionPrefix, name)
+		rb.Message = fmt.Sprintf("%d changed, %d unchanged, %d failed", len(changed), len(unchanged), len(failed))
+		clog.Info().Msgf("%s success - changed %v, unchanged %v, failed %v", actionPrefix, changed, unchanged, failed)
 	}
 	makeJsonResponse(w, status, rb)
 }
@@ -222,7 +237,7 @@ func validateHostParams(handler http.Handler) http.Handler {
 						if _, ok := val.(string); !ok {
 							validateErr = NewBadParamTypeError(key, val, "string")
 							break patchParamLoop
-						} else if validateErr = checkEthRules(val.(string)); validateErr != nil {
+						} else if validateErr = checkEthSpecRules(val.(string)); validateErr != nil {
 							break patchParamLoop
 						}
 					case "hostPolicy":
@@ -262,6 +277,24 @@ func validateHostParams(handler http.Handler) http.Handler {
 							validateErr = fmt.Errorf("invalid boot type given")
 							break patchParamLoop
 						}
+					case "pollInterval":
+						if seconds, ok := val.(float64); !ok {
+							validateErr = NewBadParamTypeError(key, val, "int")
+							break patchParamLoop
+						} else if seconds != 0 && seconds < MinPowerPollInterval {
+							validateErr = fmt.Errorf("poll interval must be at least %d seconds, or 0 to use the default", MinPowerPollInterval)
+							break patchParamLoop
+						} else if seconds != float64(int(seconds)) {
+							validateErr = NewBadParamTypeError(key, val, "int")
+							break patchParamLoop
+						}
+					case "console":
+						if _, ok := val.(string); !ok {
+							validateErr = NewBadParamTypeError(key, val, "string")
+							break patchParamLoop
+						} else if validateErr = checkConsoleNameRules(val.(string)); validateErr != nil {
+							break patchParamLoop
+						}
 					case "mac":
 						if mac, ok := val.(string); !ok {
 							validateErr = NewBadParamTypeError(key, val, "string")
@@ -300,17 +333,29 @@ func handlePowerHosts(w http.ResponseWriter, r *http.Request) {
 	clog := hlog.FromRequest(r)
 	cmd, hostList, status, err := checkPowerParams(powerParams, r)
 	actionPrefix := "power " + cmd + " host(s)"
+	rb := common.NewResponseBodyBatch()
 	if err == nil {
 		status, err = doPowerHosts(cmd, hostList, clog)
+		var powerErr *PowerHostsError
+		if err == nil || errors.As(err, &powerErr) {
+			results, powered := powerHostResults(hostList, powerErr)
+			rb.Data["results"] = results
+			if len(powered) > 0 {
+				// the hosts that failed are reported in the results
+				err = nil
+				setBatchResults(rb, results, "submitted")
+				if cmd != PowerOff {
+					recordResPowerOn(powered, clog)
+				}
+			}
+		}
 	}
 
-	rb := common.NewResponseBody()
-	rb.Data["hosts"] = hostList
 	if err != nil {
 		clog.Error().Msgf("%s error - %v", actionPrefix, err)
 		rb.Message = err.Error()
 	} else {
-		clog.Info().Msgf("%s success", actionPrefix)
+		clog.Info().Msgf("%s - %s", actionPrefix, rb.Message)
 	}
 
 	makeJsonResponse(w, status, rb)
@@ -382,24 +427,26 @@ func handleBlockHosts(w http.ResponseWriter, r *http.Request) {
 	dbAccess.Lock()
 	defer dbAccess.Unlock()
 
-	powerParams := getBodyFromContext(r)
+	blockParams := getBodyFromContext(r)
 	clog := hlog.FromRequest(r)
 	actionPrefix := "block host(s)"
-	block, hostList, status, err := checkBlockParams(powerParams)
+	doneVerb := "blocked"
+	block, failFast, hostList, reason, status, err := checkBlockParams(blockParams)
 	if !block {
 		actionPrefix = "unblock host(s)"
+		doneVerb = "unblocked"
 	}
+	var results []common.BatchItemResult
 	if err == nil {
-		status, err = doUpdateBlockHosts(block, hostList, r)
+		results, status, err = doUpdateBlockHosts(block, failFast, hostList, reason, r)
 	}
 
-	rb := common.NewResponseBody()
-	rb.Data["hosts"] = hostList
+	rb := common.NewResponseBodyBatch()
 	if err != nil {
-		clog.Error().Msgf("%s error - %v", actionPrefix, err)
-		rb.Message = err.Error()
+		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
-		clog.Info().Msgf("%s success [%v]", actionPrefix, strings.Join(hostList, ","))
+		setBatchResults(rb, results, doneVerb)
+		clog.Info().Msgf("%s success - %s [%v]", actionPrefix, rb.Message, strings.Join(hostList, ","))
 	}
 
 	makeJsonResponse(w, status, rb)
@@ -430,11 +477,19 @@ func validateBlockParams(handler http.Handler) http.Handler {
 							validateErr = NewBadParamTypeError(key, val, "string")
 							break patchParamLoop
 						}
-					case "block":
+					case "block", "failFast":
 						if _, ok := val.(bool); !ok {
 							validateErr = NewBadParamTypeError(key, val, "bool")
 							break patchParamLoop
 						}
+					case "reason":
+						if _, ok := val.(string); !ok {
+							validateErr = NewBadParamTypeError(key, val, "string")
+							break patchParamLoop
+						} else if hostParams["block"] != true {
+							validateErr = fmt.Errorf("a reason can only be given when blocking hosts")
+							break patchParamLoop
+						}
 					default:
 						validateErr = NewUnknownParamError(key, val)
 						break patchParamLoop
@@ -453,3 +508,189 @@ func validateBlockParams(handler http.Handler) http.Handler {
 		handler.ServeHTTP(w, r)
 	})
 }
+
+func handleDrainHosts(w http.ResponseWriter, r *http.Request) {
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	drainParams := getBodyFromContext(r)
+	clog := hlog.FromRequest(r)
+	actionPrefix := "drain host(s)"
+	drain, hostList, reason, status, err := checkDrainParams(drainParams)
+	if !drain {
+		actionPrefix = "undrain host(s)"
+	}
+	var blockedNow []string
+	if err == nil {
+		blockedNow, status, err = doUpdateDrainHosts(drain, hostList, reason)
+	}
+
+	rb := common.NewResponseBody()
+	rb.Data["hosts"] = hostList
+	if err != nil {
+		clog.Error().Msgf("%s error - %v", actionPrefix, err)
+		rb.Message = err.Error()
+	} else {
+		if len(blockedNow) > 0 {
+			rb.Message = fmt.Sprintf("no active reservation on %s - moved directly to blocked", strings.Join(blockedNow, ","))
+		}
+		clog.Info().Msgf("%s success [%v]", actionPrefix, strings.Join(hostList, ","))
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+func validateDrainParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+
+		hostParams := getBodyFromContext(r)
+
+		if len(hostParams) > 0 {
+			_, h := hostParams["hosts"]
+			_, d := hostParams["drain"]
+			if !h {
+				validateErr = fmt.Errorf("missing required hosts parameter")
+			} else if !d {
+				validateErr = fmt.Errorf("missing required drain parameter")
+			} else {
+
+			patchParamLoop:
+				for key, val := range hostParams {
+					switch key {
+					case "hosts":
+						if _, ok := val.(string); !ok {
+							validateErr = NewBadParamTypeError(key, val, "string")
+							break patchParamLoop
+						}
+					case "drain":
+						if _, ok := val.(bool); !ok {
+							validateErr = NewBadParamTypeError(key, val, "bool")
+							break patchParamLoop
+						}
+					case "reason":
+						if _, ok := val.(string); !ok {
+							validateErr = NewBadParamTypeError(key, val, "string")
+							break patchParamLoop
+						}
+					default:
+						validateErr = NewUnknownParamError(key, val)
+						break patchParamLoop
+					}
+				}
+			}
+		} else {
+			validateErr = NewMissingParamError("")
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateDrainParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// destination for route GET /hosts-ctrl/explain
+func handleExplainHost(w http.ResponseWriter, r *http.Request) {
+
+	queryMap := r.URL.Query()
+	clog := hlog.FromRequest(r)
+	actionPrefix := "explain host access"
+	rb := common.NewResponseBodyHostExplain()
+
+	var explain *common.HostExplainData
+	start, dur, status, err := parseExplainParams(queryMap)
+	if err == nil {
+		explain, status, err = doExplainHost(queryMap.Get("host"), queryMap.Get("user"), start, dur, clog)
+	}
+
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["explain"] = *explain
+		clog.Info().Msgf("%s success - host %s for user %s allowed=%v", actionPrefix, explain.Host, explain.User, explain.Allowed)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// parseExplainParams returns the start time and duration of the reservation window to explain.
+// The start defaults to now and the duration to the server's default reservation time.
+func parseExplainParams(queryMap url.Values) (time.Time, time.Duration, int, error) {
+
+	var start time.Time
+	if val := queryMap.Get("start"); val != "" {
+		ts, _ := strconv.ParseInt(val, 10, 64)
+		start = time.Unix(ts, 0)
+	}
+	start, _, err := evaluateResStartTime(start)
+	if err != nil {
+		return start, 0, http.StatusBadRequest, err
+	}
+
+	dur := time.Duration(igor.Scheduler.DefaultReserveTime) * time.Minute
+	if val := queryMap.Get("duration"); val != "" {
+		dur, _ = common.ParseDuration(val)
+	}
+
+	return start, dur, http.StatusOK, nil
+}
+
+func validateExplainParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+
+		queryParams := r.URL.Query()
+		if queryParams.Get("host") == "" {
+			validateErr = NewMissingParamError("host")
+		} else if queryParams.Get("user") == "" {
+			validateErr = NewMissingParamError("user")
+		} else {
+
+		queryParamLoop:
+			for key, vals := range queryParams {
+				if len(vals) > 1 {
+					validateErr = fmt.Errorf("parameter '%s' can only be given once", key)
+					break queryParamLoop
+				}
+				switch key {
+				case "host":
+					if validateErr = checkGenericNameRules(vals[0]); validateErr != nil {
+						break queryParamLoop
+					}
+				case "user":
+					if validateErr = checkUsernameRules(vals[0]); validateErr != nil {
+						break queryParamLoop
+					}
+				case "start":
+					if _, err := strconv.ParseInt(vals[0], 10, 64); err != nil {
+						validateErr = NewBadParamTypeError(key, vals[0], "unix timestamp")
+						break queryParamLoop
+					}
+				case "duration":
+					if dur, err := common.ParseDuration(vals[0]); err != nil || dur <= 0 {
+						validateErr = NewBadParamTypeError(key, vals[0], "duration")
+						break queryParamLoop
+					}
+				default:
+					validateErr = NewUnknownParamError(key, vals)
+					break queryParamLoop
+				}
+			}
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateExplainParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,236 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// destination for route GET /hosts-ctrl/history
+func handleHostHistory(w http.ResponseWriter, r *http.Request) {
+
+	queryMap := r.URL.Query()
+	clog := hlog.FromRequest(r)
+	actionPrefix := "host history"
+	rb := common.NewResponseBodyHostHistory()
+
+	from, _ := strconv.ParseInt(queryMap.Get("from"), 10, 64)
+	to := time.Now().Unix()
+	if val := queryMap.Get("to"); val != "" {
+		to, _ = strconv.ParseInt(val, 10, 64)
+	}
+
+	history, status, err := doHostHistory(queryMap.Get("host"), time.Unix(from, 0), time.Unix(to, 0), getUserFromContext(r))
+
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["history"] = history
+		if len(history) == 0 {
+			rb.Message = "no reservations held the host during the time window"
+		}
+		clog.Info().Msgf("%s success - %d reservation(s) held host %s", actionPrefix, len(history), queryMap.Get("host"))
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// doHostHistory returns the reservations that held the host between from and to, read from the
+// reservation history so reservations that are over are included. Elevated admins can look up any
+// host. If server.ownerHostHistory is on, the owner or a co-owner of a running reservation can look
+// up the hosts it holds.
+func doHostHistory(hostName string, from, to time.Time, user *User) (history []common.HostOccupancyData, status int, err error) {
+
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		hosts, ghStatus, ghErr := getHosts([]string{hostName}, true, tx)
+		if ghErr != nil {
+			status = ghStatus
+			return ghErr
+		}
+		host := &hosts[0]
+
+		if !userElevated(user.Name) && !(igor.Server.OwnerHostHistory && holdsHost(host, user, time.Now())) {
+			status = http.StatusForbidden
+			if igor.Server.OwnerHostHistory {
+				return newCodedError(common.ErrElevateRequired, "host history of %s requires admin elevated privilege or owning a reservation that holds it now", host.Name)
+			}
+			return newCodedError(common.ErrElevateRequired, "host history requires admin elevated privilege")
+		}
+
+		records, rErr := dbReadHostHistoryRecords(host.Name, to, tx)
+		if rErr != nil {
+			return rErr
+		}
+		history = hostOccupancy(records, host.Name, from, to)
+		return nil
+
+	}); err == nil {
+		status = http.StatusOK
+	}
+	return
+}
+
+// holdsHost reports whether the user owns or co-owns a reservation holding the host at time t.
+func holdsHost(host *Host, user *User, t time.Time) bool {
+	for i := range host.Reservations {
+		res := &host.Reservations[i]
+		if res.IsActive(t) && (res.OwnerID == user.ID || res.isCoOwner(user.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// dbReadHostHistoryRecords reads every history record of the reservations that started before to
+// and named the host in any of their records, in the order the records were made. The host name
+// match in the query is loose, so callers have to check the host list of each record.
+func dbReadHostHistoryRecords(hostName string, to time.Time, tx *gorm.DB) ([]HistoryRecord, error) {
+	var records []HistoryRecord
+	hashes := tx.Model(&HistoryRecord{}).Select("hash").Where("hosts LIKE ?", "%"+hostName+"%")
+	result := tx.Where("hash IN (?) AND start < ?", hashes, to).Order("created_at, id").Find(&records)
+	return records, result.Error
+}
+
+// hostOccupancy works out when each reservation in the history records held the host and returns
+// those that held it at some point between from and to, in order of start time. The last record of
+// a reservation gives its start and end, where a deleted reservation's end is when it was deleted.
+// If a later record no longer lists the host then it was dropped, and the hold ends when that
+// record was made.
+func hostOccupancy(records []HistoryRecord, hostName string, from, to time.Time) []common.HostOccupancyData {
+
+	type hold struct {
+		last    *HistoryRecord
+		held    bool
+		dropped time.Time
+	}
+
+	var hashes []string
+	holds := map[string]*hold{}
+	for i := range records {
+		rec := &records[i]
+		h, ok := holds[rec.Hash]
+		if !ok {
+			h = &hold{}
+			holds[rec.Hash] = h
+			hashes = append(hashes, rec.Hash)
+		}
+		h.last = rec
+		if rec.Hosts == "" {
+			continue
+		}
+		if slices.Contains(strings.Split(rec.Hosts, ","), hostName) {
+			h.held, h.dropped = true, time.Time{}
+		} else if h.held && h.dropped.IsZero() {
+			h.dropped = rec.CreatedAt
+		}
+	}
+
+	history := []common.HostOccupancyData{}
+	for _, hash := range hashes {
+		h := holds[hash]
+		if !h.held {
+			continue
+		}
+		rec := h.last
+		start, end := rec.Start, rec.End
+		if !h.dropped.IsZero() && h.dropped.Before(end) {
+			end = h.dropped
+		}
+		// a reservation deleted before it started never held the host
+		if !end.After(start) || !start.Before(to) || !end.After(from) {
+			continue
+		}
+
+		var outcome string
+		if strings.HasPrefix(rec.Status, HrDeleted) || rec.Status == HrFinished {
+			outcome = rec.Status
+		} else if !h.dropped.IsZero() {
+			outcome = "host dropped"
+		}
+
+		history = append(history, common.HostOccupancyData{
+			Reservation: rec.Name,
+			Owner:       rec.Owner,
+			Group:       rec.Group,
+			Profile:     rec.Profile,
+			Distro:      rec.Distro,
+			Start:       start.Unix(),
+			End:         end.Unix(),
+			Outcome:     outcome,
+		})
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Start < history[j].Start
+	})
+	return history
+}
+
+func validateHostHistoryParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+
+		queryParams := r.URL.Query()
+		if queryParams.Get("host") == "" {
+			validateErr = NewMissingParamError("host")
+		} else if queryParams.Get("from") == "" {
+			validateErr = NewMissingParamError("from")
+		} else {
+
+		queryParamLoop:
+			for key, vals := range queryParams {
+				if len(vals) > 1 {
+					validateErr = fmt.Errorf("parameter '%s' can only be given once", key)
+					break queryParamLoop
+				}
+				switch key {
+				case "host":
+					if validateErr = checkGenericNameRules(vals[0]); validateErr != nil {
+						break queryParamLoop
+					}
+				case "from", "to":
+					if _, err := strconv.ParseInt(vals[0], 10, 64); err != nil {
+						validateErr = NewBadParamTypeError(key, vals[0], "unix timestamp")
+						break queryParamLoop
+					}
+				default:
+					validateErr = NewUnknownParamError(key, vals)
+					break queryParamLoop
+				}
+			}
+
+			if validateErr == nil && queryParams.Get("to") != "" {
+				from, _ := strconv.ParseInt(queryParams.Get("from"), 10, 64)
+				to, _ := strconv.ParseInt(queryParams.Get("to"), 10, 64)
+				if to <= from {
+					validateErr = fmt.Errorf("the end of the time window must be after its start")
+				}
+			}
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateHostHistoryParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestHostHistory(t *testing.T) {
+
+	origOwnerHistory := igor.Server.OwnerHostHistory
+	t.Cleanup(func() { igor.Server.OwnerHostHistory = origOwnerHistory })
+
+	hosts := seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	base := time.Date(2026, 3, 2, 8, 0, 0, 0, time.Local)
+	at := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+	rec := func(hash, status, hostList string, start, end, made int) HistoryRecord {
+		return HistoryRecord{Base: Base{CreatedAt: at(made)}, Hash: hash, Status: status, Name: hash, Owner: "alice",
+			Group: "pug_alice", Profile: "p", Distro: "centos", Start: at(start), End: at(end), Hosts: hostList}
+	}
+	records := []HistoryRecord{
+		// ran its full time on kn1
+		rec("full", HrCreated, "kn1,kn2", 0, 10, 0),
+		rec("full", HrFinished, "kn1,kn2", 0, 10, 10),
+		// deleted early, the deleted record holds when that happened
+		rec("early", HrCreated, "kn1", 20, 40, 12),
+		rec("early", HrDeleted, "kn1", 20, 25, 25),
+		// kn1 was dropped partway through
+		rec("drop", HrCreated, "kn1,kn2", 30, 50, 12),
+		rec("drop", HrUpdated+":drop", "kn2", 30, 50, 35),
+		// deleted before it started
+		rec("never", HrCreated, "kn1", 60, 70, 12),
+		rec("never", HrDeleted, "kn1", 60, 13, 13),
+		// only held kn10, which the loose query matches
+		rec("other", HrCreated, "kn10", 0, 100, 0),
+	}
+	for i := range records {
+		require.NoError(t, db.Create(&records[i]).Error)
+	}
+
+	found, err := dbReadHostHistoryRecords("kn1", at(100), db)
+	require.NoError(t, err)
+	history := hostOccupancy(found, "kn1", at(0), at(100))
+	require.Len(t, history, 3)
+	assert.Equal(t, common.HostOccupancyData{Reservation: "full", Owner: "alice", Group: "pug_alice", Profile: "p",
+		Distro: "centos", Start: at(0).Unix(), End: at(10).Unix(), Outcome: HrFinished}, history[0])
+	assert.Equal(t, "early", history[1].Reservation)
+	assert.Equal(t, at(25).Unix(), history[1].End)
+	assert.Equal(t, HrDeleted, history[1].Outcome)
+	assert.Equal(t, "drop", history[2].Reservation)
+	assert.Equal(t, at(35).Unix(), history[2].End)
+	assert.Equal(t, "host dropped", history[2].Outcome)
+
+	// the window only catches reservations that held the host during it
+	history = hostOccupancy(found, "kn1", at(26), at(32))
+	require.Len(t, history, 1)
+	assert.Equal(t, "drop", history[0].Reservation)
+	assert.Empty(t, hostOccupancy(found, "kn1", at(50), at(100)))
+
+	// owners can only look up hosts they hold now and only when the server allows it
+	res := newStartTestRes(t, db, "running", hosts[:1], false, 0)
+	var alice User
+	require.NoError(t, db.First(&alice, res.OwnerID).Error)
+
+	igor.Server.OwnerHostHistory = false
+	_, status, err := doHostHistory("kn1", at(0), at(100), &alice)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, common.ErrElevateRequired, errorCodeOf(err))
+
+	igor.Server.OwnerHostHistory = true
+	history, status, err = doHostHistory("kn1", at(0), at(100), &alice)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, history, 3)
+
+	_, status, err = doHostHistory("kn2", at(0), at(100), &alice)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"strings"
+
+	"igor2/internal/pkg/common"
+)
+
+// Roles of the network interfaces of a host, each plugged into its own switch port
+const (
+	PortRoleData  = "data"
+	PortRoleMgmt  = "mgmt"
+	PortRoleExtra = "extra"
+)
+
+var AllowedPortRoles = [...]string{PortRoleData, PortRoleMgmt, PortRoleExtra}
+
+// HostPort is a switch port a host is plugged into and the role of the host interface using it.
+type HostPort struct {
+	Role string
+	Port string
+}
+
+func validPortRole(role string) bool {
+	for _, r := range AllowedPortRoles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEthSpec splits the eth setting of a host into its data port and the rest of its switch ports.
+// A value without any roles, ex. 'Ethernet1/7', is the data port alone as it has always been.
+// Otherwise each port is given with its role, ex. 'data=Ethernet1/7,mgmt=Ethernet2/7'. A host has
+// at most one data port but can have any number of the others.
+func parseEthSpec(spec string) (eth string, ports []HostPort, err error) {
+
+	spec = strings.TrimSpace(spec)
+	if !strings.Contains(spec, "=") {
+		if spec != "" {
+			if err = checkEthRules(spec); err != nil {
+				return "", nil, err
+			}
+		}
+		return spec, nil, nil
+	}
+
+	seen := map[string]bool{}
+	for _, pair := range strings.Split(spec, ",") {
+		role, port, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return "", nil, fmt.Errorf("'%s' must be given as role=port when any port has a role", pair)
+		}
+		if !validPortRole(role) {
+			return "", nil, fmt.Errorf("'%s' is not a port role - must be one of %s", role, strings.Join(AllowedPortRoles[:], ", "))
+		}
+		if err = checkEthRules(port); err != nil {
+			return "", nil, err
+		}
+		if seen[port] {
+			return "", nil, fmt.Errorf("port '%s' is given more than once", port)
+		}
+		seen[port] = true
+		if role == PortRoleData {
+			if eth != "" {
+				return "", nil, fmt.Errorf("a host can only have one %s port", PortRoleData)
+			}
+			eth = port
+			continue
+		}
+		ports = append(ports, HostPort{Role: role, Port: port})
+	}
+	return eth, ports, nil
+}
+
+// formatHostPorts writes ports as the role=port pairs stored in Host.Ports.
+func formatHostPorts(ports []HostPort) string {
+	pairs := make([]string, len(ports))
+	for i, p := range ports {
+		pairs[i] = p.Role + "=" + p.Port
+	}
+	return strings.Join(pairs, ",")
+}
+
+// switchPorts returns every switch port of the host, its data port first.
+func (h *Host) switchPorts() []HostPort {
+	var ports []HostPort
+	if h.Eth != "" {
+		ports = append(ports, HostPort{Role: PortRoleData, Port: h.Eth})
+	}
+	// the stored value was checked when it was set, so anything malformed is just left out
+	for _, pair := range strings.Split(h.Ports, ",") {
+		if role, port, found := strings.Cut(pair, "="); found {
+			ports = append(ports, HostPort{Role: role, Port: port})
+		}
+	}
+	return ports
+}
+
+// ethSpec returns the eth setting of the host as given to parseEthSpec. A host with only a data port
+// gets the plain port name it always had.
+func (h *Host) ethSpec() string {
+	if h.Ports == "" {
+		return h.Eth
+	}
+	return formatHostPorts(h.switchPorts())
+}
+
+// portRoleIsolated returns true if ports with the given role are put in a reservation's VLAN.
+func portRoleIsolated(role string) bool {
+	for _, r := range igor.Vlan.IsolateRoles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// isolatedPorts returns the switch ports of the host that are put in a reservation's VLAN.
+func (h *Host) isolatedPorts() []HostPort {
+	var ports []HostPort
+	for _, p := range h.switchPorts() {
+		if portRoleIsolated(p.Role) {
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+// getHostPortData returns the switch ports of the host as sent to clients.
+func (h *Host) getHostPortData() []common.HostPortData {
+	var ports []common.HostPortData
+	for _, p := range h.switchPorts() {
+		ports = append(ports, common.HostPortData{Role: p.Role, Port: p.Port, Isolated: portRoleIsolated(p.Role)})
+	}
+	return ports
+}
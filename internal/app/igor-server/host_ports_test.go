@@ -0,0 +1,114 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"igor2/internal/pkg/common"
+)
+
+// setIsolateRoles sets the port roles put in reservation VLANs for the test.
+func setIsolateRoles(t *testing.T, roles ...string) {
+	orig := igor.Vlan.IsolateRoles
+	t.Cleanup(func() { igor.Vlan.IsolateRoles = orig })
+	igor.Vlan.IsolateRoles = roles
+}
+
+func TestParseEthSpec(t *testing.T) {
+
+	// a plain value is the data port like it always was
+	eth, ports, err := parseEthSpec("Et4/1/1")
+	require.NoError(t, err)
+	assert.Equal(t, "Et4/1/1", eth)
+	assert.Empty(t, ports)
+
+	eth, ports, err = parseEthSpec("")
+	require.NoError(t, err)
+	assert.Empty(t, eth)
+	assert.Empty(t, ports)
+
+	eth, ports, err = parseEthSpec("mgmt=Ethernet2/7, data=Ethernet1/7,extra=Ethernet3/7")
+	require.NoError(t, err)
+	assert.Equal(t, "Ethernet1/7", eth)
+	assert.Equal(t, []HostPort{{PortRoleMgmt, "Ethernet2/7"}, {PortRoleExtra, "Ethernet3/7"}}, ports)
+
+	// a host doesn't need a data port
+	eth, ports, err = parseEthSpec("mgmt=Ethernet2/7")
+	require.NoError(t, err)
+	assert.Empty(t, eth)
+	assert.Len(t, ports, 1)
+
+	for _, bad := range []string{
+		"data=Ethernet1/7,Ethernet2/7",
+		"ipmi=Ethernet2/7",
+		"data=Ethernet1/7,data=Ethernet2/7",
+		"data=Ethernet1/7,mgmt=Ethernet1/7",
+		"data=",
+		"data=1/7",
+		"bad port",
+	} {
+		_, _, err = parseEthSpec(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestHostSwitchPorts(t *testing.T) {
+
+	setIsolateRoles(t, PortRoleData)
+
+	legacy := Host{Eth: "Et4/1/1"}
+	assert.Equal(t, "Et4/1/1", legacy.ethSpec())
+	assert.Equal(t, []HostPort{{PortRoleData, "Et4/1/1"}}, legacy.isolatedPorts())
+	assert.Equal(t, []common.HostPortData{{Role: PortRoleData, Port: "Et4/1/1", Isolated: true}}, legacy.getHostPortData())
+
+	multi := Host{Eth: "Et1/7", Ports: formatHostPorts([]HostPort{{PortRoleMgmt, "Et2/7"}, {PortRoleExtra, "Et3/7"}})}
+	assert.Equal(t, "data=Et1/7,mgmt=Et2/7,extra=Et3/7", multi.ethSpec())
+	assert.Equal(t, []HostPort{{PortRoleData, "Et1/7"}}, multi.isolatedPorts())
+
+	setIsolateRoles(t, PortRoleData, PortRoleMgmt)
+	assert.Equal(t, []HostPort{{PortRoleData, "Et1/7"}, {PortRoleMgmt, "Et2/7"}}, multi.isolatedPorts())
+	assert.Equal(t, []HostPort{{PortRoleData, "Et4/1/1"}}, legacy.isolatedPorts())
+
+	// the cluster config written out reads back to the same ports
+	hosts := []Host{legacy, multi, {}}
+	for i := range hosts {
+		hosts[i].SequenceID = i + 1
+	}
+	raw, err := assembleYamlOutput([]Cluster{{Name: "krypton", Prefix: "kn", Hosts: hosts}})
+	require.NoError(t, err)
+	ccMap := map[string]ClusterConfig{}
+	require.NoError(t, yaml.Unmarshal(raw, &ccMap))
+	for _, h := range hosts {
+		eth, ports, pErr := parseEthSpec(ccMap["krypton"].HostMap[h.SequenceID]["eth"])
+		require.NoError(t, pErr)
+		assert.Equal(t, h.Eth, eth)
+		assert.Equal(t, h.Ports, formatHostPorts(ports))
+	}
+}
+
+func TestHostEditEth(t *testing.T) {
+
+	multi := Host{Eth: "Et1/7", Ports: "mgmt=Et2/7"}
+
+	changes, _, err := parseHostEditParams(map[string]interface{}{"eth": "data=Et1/7,mgmt=Et2/8"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"ports": "mgmt=Et2/8"}, hostEditDiff(&multi, changes))
+
+	// a plain value leaves the host with only a data port
+	changes, _, err = parseHostEditParams(map[string]interface{}{"eth": "Et1/7"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"ports": ""}, hostEditDiff(&multi, changes))
+
+	_, status, err := parseHostEditParams(map[string]interface{}{"eth": "data=Et1/7,bmc=Et2/7"}, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Error(t, checkEthSpecRules(" "))
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"sort"
 	"strings"
+	"time"
 
 	zl "github.com/rs/zerolog"
 
@@ -37,6 +38,7 @@ func checkPowerParams(powerParams map[string]interface{}, r *http.Request) (stri
 	cmd := strings.ToLower(powerParams["cmd"].(string))
 
 	var err error
+	var hosts []Host
 	var hostNames []string
 
 	if hostExpr, hok := powerParams["hosts"].(string); hok {
@@ -46,6 +48,7 @@ func checkPowerParams(powerParams map[string]interface{}, r *http.Request) (stri
 			if hList, ghStatus, ghErr := getHostsTx(tempHostNames, true); ghErr != nil {
 				return cmd, nil, ghStatus, ghErr
 			} else {
+				hosts = hList
 				hostNames = hostNamesOfHosts(hList)
 			}
 		}
@@ -61,7 +64,13 @@ func checkPowerParams(powerParams map[string]interface{}, r *http.Request) (stri
 			return cmd, nil, http.StatusInternalServerError, rrErr
 		} else {
 			if len(res) == 1 {
-				hostNames = hostNamesOfHosts(res[0].Hosts)
+				// reservation hosts don't come with their policies, which the permission check needs
+				if hList, ghStatus, ghErr := getHostsTx(namesOfHosts(res[0].Hosts), true); ghErr != nil {
+					return cmd, nil, ghStatus, ghErr
+				} else {
+					hosts = hList
+					hostNames = hostNamesOfHosts(hList)
+				}
 			} else {
 				return cmd, nil, http.StatusNotFound, fmt.Errorf("reservation '%s' not found", resName)
 			}
@@ -78,21 +87,42 @@ func checkPowerParams(powerParams map[string]interface{}, r *http.Request) (stri
 		return cmd, hostNames, http.StatusInternalServerError, err
 	}
 
-	for _, h := range hostNames {
-		powerPerm, _ := NewPermission(NewPermissionString(PermPowerAction, h))
-		if !authInfo.IsPermitted(powerPerm) {
-			return cmd, hostNames, http.StatusForbidden, fmt.Errorf("user attempted power command on %v but does not have permission to run power commands on host %v", hostNames, h)
-		}
+	if status, naErr := checkNodeAction(user, authInfo, NodeActionPower, hosts); naErr != nil {
+		return cmd, hostNames, status, naErr
 	}
 
 	return cmd, hostNames, http.StatusOK, nil
 }
 
-// Runs the actual power command for the service that controls host power options.
+// Runs the actual power command for the service that controls host power options. The command is run
+// for each host on its own, and if it fails for some of them a PowerHostsError is returned with the
+// error of each. The status is still OK if it succeeded for any host.
 func doPowerHosts(action string, hostList []string, clog *zl.Logger) (int, error) {
 
 	clog.Info().Msgf("running power operation '%s' on node(s) %v", action, hostList)
 
+	if igor.Simulation.Enabled && (action == PowerOn || action == PowerOff || action == PowerCycle) {
+		simPowerHosts(action, hostList, time.Now())
+		return http.StatusOK, nil
+	}
+
+	failed := map[string]error{}
+	// run runs the command for each host and returns the hosts it worked for, the others go in failed
+	run := func(format string, hosts []string) []string {
+		errs := runAll(format, hosts)
+		var ok []string
+		for _, h := range hosts {
+			if hErr, bad := errs[h]; bad {
+				logEvent(clog, EvPowerCmdFailed).Str(LogFieldHost, h).Str(LogFieldAction, action).Err(hErr).
+					Msgf("power operation '%s' failed on node %s - %v", action, h, hErr)
+				failed[h] = hErr
+			} else {
+				ok = append(ok, h)
+			}
+		}
+		return ok
+	}
+
 	switch action {
 	case PowerOff:
 
@@ -105,9 +135,7 @@ func doPowerHosts(action string, hostList []string, clog *zl.Logger) (int, error
 			return http.StatusInternalServerError, fmt.Errorf("power-off configuration missing")
 		}
 
-		if err := runAll(igor.ExternalCmds.PowerOff, hostList); err != nil {
-			return http.StatusInternalServerError, err
-		}
+		hostList = run(igor.ExternalCmds.PowerOff, hostList)
 
 	case PowerCycle:
 
@@ -144,11 +172,9 @@ func doPowerHosts(action string, hostList []string, clog *zl.Logger) (int, error
 				return http.StatusInternalServerError, fmt.Errorf("power-cycle configuration missing")
 			}
 
-			if err := runAll(igor.ExternalCmds.PowerCycle+oioFlag, hostList); err != nil {
-				return http.StatusInternalServerError, err
-			}
+			hostList = run(igor.ExternalCmds.PowerCycle+oioFlag, hostList)
 			// if power cycle command works on its own, we can return from this point
-			return http.StatusOK, nil
+			return powerHostsResult(action, hostList, failed)
 
 		} else {
 
@@ -156,9 +182,8 @@ func doPowerHosts(action string, hostList []string, clog *zl.Logger) (int, error
 				return http.StatusInternalServerError, fmt.Errorf("power-off configuration missing")
 			}
 
-			if err := runAll(igor.ExternalCmds.PowerOff, hostList); err != nil {
-				return http.StatusInternalServerError, err
-			}
+			// hosts that didn't power off aren't sent the power-on
+			hostList = run(igor.ExternalCmds.PowerOff, hostList)
 		}
 
 		fallthrough // assuming power-off is used in place of power-cycle, execute next case
@@ -174,15 +199,42 @@ func doPowerHosts(action string, hostList []string, clog *zl.Logger) (int, error
 			return http.StatusInternalServerError, fmt.Errorf("power-on configuration missing")
 		}
 
-		if err := runAll(igor.ExternalCmds.PowerOn, hostList); err != nil {
-			return http.StatusInternalServerError, err
-		}
+		hostList = run(igor.ExternalCmds.PowerOn, hostList)
 
 	default:
 		return http.StatusBadRequest, fmt.Errorf("invalid power operation : %s", action)
 	}
 
-	return http.StatusOK, nil
+	return powerHostsResult(action, hostList, failed)
+}
+
+// powerHostsResult returns the outcome of a power command that worked for the hosts in powered and
+// failed for the hosts in failed. It is only an error status if it failed for every host.
+func powerHostsResult(action string, powered []string, failed map[string]error) (int, error) {
+	if len(failed) == 0 {
+		return http.StatusOK, nil
+	}
+	err := &PowerHostsError{action: action, failed: failed}
+	if len(powered) == 0 {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, err
+}
+
+// powerHostResults returns the outcome of a power command for each host in hostList, given the hosts
+// it failed for in powerErr (which may be nil), along with the hosts it worked for.
+func powerHostResults(hostList []string, powerErr *PowerHostsError) (results []common.BatchItemResult, powered []string) {
+	for _, h := range hostList {
+		if powerErr != nil {
+			if hErr, bad := powerErr.failed[h]; bad {
+				results = append(results, common.BatchItemResult{Name: h, Result: common.BatchItemFailed, Message: hErr.Error()})
+				continue
+			}
+		}
+		results = append(results, common.BatchItemResult{Name: h, Result: common.BatchItemOK, Message: "submitted"})
+		powered = append(powered, h)
+	}
+	return results, powered
 }
 
 // powerOffResNodes explicitly sends the power 'off' command to the nodes of a deleted/expired reservation.
@@ -0,0 +1,114 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igor2/internal/pkg/common"
+)
+
+// setupFakePowerCmd points the power commands at a script that fails for the named hosts and
+// records the hosts it succeeded for. It returns the path of that record.
+func setupFakePowerCmd(t *testing.T, failHosts ...string) string {
+
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncase \"$1\" in\n" + strings.Join(failHosts, "|") + ")\n" +
+		"  echo \"no response from BMC of $1\" >&2\n  exit 3\n  ;;\nesac\necho \"$1\" >> \"$0.log\"\n"
+	if len(failHosts) == 0 {
+		script = "#!/bin/sh\necho \"$1\" >> \"$0.log\"\n"
+	}
+	cmd := filepath.Join(dir, "power")
+	require.NoError(t, os.WriteFile(cmd, []byte(script), 0755))
+
+	origCmds, origSim := igor.ExternalCmds, igor.Simulation.Enabled
+	t.Cleanup(func() { igor.ExternalCmds, igor.Simulation.Enabled = origCmds, origSim })
+	igor.Simulation.Enabled = false
+	igor.ExternalCmds.PowerOn = cmd + " %s"
+	igor.ExternalCmds.PowerOff = cmd + " %s"
+	igor.ExternalCmds.PowerCycle = cmd + " %s"
+	igor.ExternalCmds.ConcurrencyLimit = 2
+	igor.ExternalCmds.CommandRetries = 0
+
+	return cmd + ".log"
+}
+
+func poweredHosts(t *testing.T, log string) []string {
+	content, err := os.ReadFile(log)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	require.NoError(t, err)
+	hosts := strings.Fields(string(content))
+	sort.Strings(hosts)
+	return hosts
+}
+
+func TestPowerHostsPartialFailure(t *testing.T) {
+
+	log := setupFakePowerCmd(t, "kn2", "kn4")
+	hosts := []string{"kn1", "kn2", "kn3", "kn4"}
+
+	status, err := doPowerHosts(PowerOn, hosts, &logger)
+	assert.Equal(t, http.StatusOK, status)
+	var powerErr *PowerHostsError
+	require.ErrorAs(t, err, &powerErr)
+	assert.Equal(t, "power on failed for host(s) kn[2,4]", err.Error())
+	assert.Equal(t, []string{"kn1", "kn3"}, poweredHosts(t, log))
+
+	var cmdErr *ExtCmdError
+	require.ErrorAs(t, powerErr.failed["kn2"], &cmdErr)
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, cmdErr, &exitErr)
+	assert.Equal(t, 3, exitErr.ExitCode())
+	assert.Equal(t, "exit status 3: no response from BMC of kn2", cmdErr.Error())
+
+	results, powered := powerHostResults(hosts, powerErr)
+	assert.Equal(t, []string{"kn1", "kn3"}, powered)
+	assert.Equal(t, []common.BatchItemResult{
+		{Name: "kn1", Result: common.BatchItemOK, Message: "submitted"},
+		{Name: "kn2", Result: common.BatchItemFailed, Message: "exit status 3: no response from BMC of kn2"},
+		{Name: "kn3", Result: common.BatchItemOK, Message: "submitted"},
+		{Name: "kn4", Result: common.BatchItemFailed, Message: "exit status 3: no response from BMC of kn4"},
+	}, results)
+
+	// nothing worked
+	status, err = doPowerHosts(PowerCycle, []string{"kn2", "kn4"}, &logger)
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.ErrorAs(t, err, &powerErr)
+}
+
+func TestPowerHostsSuccess(t *testing.T) {
+
+	log := setupFakePowerCmd(t)
+	hosts := []string{"kn1", "kn2", "kn3"}
+
+	status, err := doPowerHosts(PowerOff, hosts, &logger)
+	assert.Equal(t, http.StatusOK, status)
+	require.NoError(t, err)
+	assert.Equal(t, hosts, poweredHosts(t, log))
+
+	results, powered := powerHostResults(hosts, nil)
+	assert.Equal(t, hosts, powered)
+	ok, failed := common.CountBatchResults(results)
+	assert.Equal(t, 3, ok)
+	assert.Equal(t, 0, failed)
+}
+
+func TestExtCmdErrorOutput(t *testing.T) {
+	err := newExtCmdError(errors.New("exit status 1"), "\n"+strings.Repeat("x", extCmdOutputMax+50)+"\n")
+	assert.Equal(t, "exit status 1: ..."+strings.Repeat("x", extCmdOutputMax), err.Error())
+	assert.Equal(t, "exit status 1", newExtCmdError(errors.New("exit status 1"), " \n").Error())
+}
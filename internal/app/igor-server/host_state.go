@@ -11,9 +11,13 @@ const (
 	HostReserved                    // host is running under a current reservation
 	HostBlocked                     // host is blocked from being reserved (present and future)
 	HostError                       // host state is in an error condition
+	HostDraining                    // host is finishing its current reservation but accepting no new ones
 	HostInvalid                     // placeholder for failed validation of State field (not put into DB)
 )
 
+// schedulableHostStates are the host states the scheduler will consider when placing new reservations.
+var schedulableHostStates = []HostState{HostAvailable, HostReserved}
+
 // HostState is an enum value describing a node's current availability for reservation assignment.
 // Hosts accepting reservations would be any HostState == 0.
 //
@@ -21,10 +25,11 @@ const (
 //	1 = reserved    ; running an active reservation
 //	2 = blocked     ; admins have removed this node from the reservable pool
 //	3 = error       ; node unresponsive, needs admin attention
+//	4 = draining    ; current reservation may finish, then node moves to blocked
 type HostState int
 
 func (s HostState) String() string {
-	names := []string{"available", "reserved", "blocked", "error", "invalid"}
+	names := []string{"available", "reserved", "blocked", "error", "draining", "invalid"}
 	i := int(s)
 	switch {
 	case i <= int(HostInvalid):
@@ -36,7 +41,7 @@ func (s HostState) String() string {
 
 // resolveHostState maps the status string to its HostState (int) equivalent.
 func resolveHostState(str string) HostState {
-	names := []string{"available", "reserved", "blocked", "error"}
+	names := []string{"available", "reserved", "blocked", "error", "draining"}
 	for i, name := range names {
 		if str == name {
 			return HostState(i)
@@ -44,3 +49,13 @@ func resolveHostState(str string) HostState {
 	}
 	return HostInvalid
 }
+
+// releasedHostState returns the state a host should move to when the reservation it is
+// running ends or drops it. Blocked hosts stay blocked and draining hosts become blocked
+// so they remain out of the reservable pool until an admin acts on them.
+func releasedHostState(s HostState) HostState {
+	if s == HostBlocked || s == HostDraining {
+		return HostBlocked
+	}
+	return HostAvailable
+}
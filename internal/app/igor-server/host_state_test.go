@@ -22,11 +22,14 @@ func TestGetHostState(t *testing.T) {
 	assert.NotEqual(t, HostReserved, badState, "")
 	assert.NotEqual(t, HostBlocked, badState, "")
 	assert.NotEqual(t, HostError, badState, "")
+	assert.NotEqual(t, HostDraining, badState, "")
 	assert.Equal(t, HostInvalid, badState, "")
 
 }
 
 func TestHostState_String(t *testing.T) {
 	assert.Equal(t, HostAvailable.String(), "available")
+	assert.Equal(t, HostDraining.String(), "draining")
 	assert.Equal(t, HostInvalid.String(), "invalid")
+	assert.Equal(t, HostDraining, resolveHostState("draining"))
 }
@@ -0,0 +1,65 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"time"
+
+	"igor2/internal/pkg/common"
+)
+
+// Reasons given with the next unavailable window of a host.
+const (
+	UnavailPolicy      = "policy"
+	UnavailMaintenance = "maintenance"
+)
+
+// unavailLookahead returns how far ahead to look for a host's next unavailable window.
+func unavailLookahead() time.Duration {
+	return time.Duration(igor.Scheduler.UnavailLookaheadHours) * time.Hour
+}
+
+// nextScheduleBlock returns the earliest instance of the schedule blocks that starts after the given
+// time and before until. An instance already underway at after is not included.
+func nextScheduleBlock(sba ScheduleBlockArray, after, until time.Time) (start, end time.Time, found bool) {
+	for _, sb := range sba {
+		sbDuration, _ := common.ParseDuration(sb.Duration)
+		sbStart, err := parseSBInstance(sb.Start)
+		if err != nil || sbDuration <= 0 {
+			continue
+		}
+		next := sbStart.Next(after)
+		if next.Before(until) && (!found || next.Before(start)) {
+			start, end, found = next, next.Add(sbDuration), true
+		}
+	}
+	return
+}
+
+// nextUnavailWindow returns the first window starting within lookahead of now in which the host can't be
+// reserved, either an unavailable time of its policy or the maintenance after the reservation holding it
+// ends. It returns nil if there is none. The host's policy and reservations must be loaded.
+func (h *Host) nextUnavailWindow(now time.Time, lookahead time.Duration) *common.UnavailWindow {
+
+	if lookahead <= 0 {
+		return nil
+	}
+	until := now.Add(lookahead)
+
+	var window *common.UnavailWindow
+	if start, end, found := nextScheduleBlock(h.HostPolicy.NotAvailable, now, until); found {
+		window = &common.UnavailWindow{Start: start.Unix(), End: end.Unix(), Reason: UnavailPolicy}
+	}
+
+	if maint := time.Duration(igor.Maintenance.HostMaintenanceDuration) * time.Minute; maint > 0 {
+		for _, r := range h.Reservations {
+			if r.IsActive(now) && r.End.Before(until) && !r.leavesHostsOn() && (window == nil || r.End.Unix() < window.Start) {
+				window = &common.UnavailWindow{Start: r.End.Unix(), End: r.End.Add(maint).Unix(), Reason: UnavailMaintenance}
+			}
+		}
+	}
+
+	return window
+}
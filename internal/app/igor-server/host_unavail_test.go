@@ -0,0 +1,63 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestNextUnavailWindow(t *testing.T) {
+
+	origMaint := igor.Config.Maintenance
+	t.Cleanup(func() { igor.Config.Maintenance = origMaint })
+	igor.Config.Maintenance.HostMaintenanceDuration = 30
+
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	block := time.Date(2026, 10, 16, 18, 0, 0, 0, time.UTC)
+	h := Host{
+		Name:       "kn1",
+		HostPolicy: HostPolicy{NotAvailable: ScheduleBlockArray{{Start: "0 18 * * *", Duration: "2h"}}},
+	}
+
+	// nothing is reported when the lookahead is off or doesn't reach the block
+	assert.Nil(t, h.nextUnavailWindow(now, 0))
+	assert.Nil(t, h.nextUnavailWindow(now, 4*time.Hour))
+
+	w := h.nextUnavailWindow(now, 24*time.Hour)
+	require.NotNil(t, w)
+	assert.Equal(t, common.UnavailWindow{Start: block.Unix(), End: block.Add(2 * time.Hour).Unix(), Reason: UnavailPolicy}, *w)
+
+	// the maintenance after a reservation ending before the block comes first
+	resEnd := now.Add(2 * time.Hour)
+	h.Reservations = []Reservation{{Name: "burnin", Start: now.Add(-time.Hour), End: resEnd}}
+	w = h.nextUnavailWindow(now, 24*time.Hour)
+	require.NotNil(t, w)
+	assert.Equal(t, common.UnavailWindow{Start: resEnd.Unix(), End: resEnd.Add(30 * time.Minute).Unix(), Reason: UnavailMaintenance}, *w)
+	w = h.nextUnavailWindow(now, 4*time.Hour)
+	require.NotNil(t, w)
+	assert.Equal(t, UnavailMaintenance, w.Reason)
+
+	// hosts left on at the end don't go into maintenance, and neither do they when it's turned off
+	h.Reservations[0].EndPower = EndPowerLeaveOn
+	w = h.nextUnavailWindow(now, 24*time.Hour)
+	require.NotNil(t, w)
+	assert.Equal(t, UnavailPolicy, w.Reason)
+	h.Reservations[0].EndPower = EndPowerOff
+	igor.Config.Maintenance.HostMaintenanceDuration = 0
+	w = h.nextUnavailWindow(now, 24*time.Hour)
+	require.NotNil(t, w)
+	assert.Equal(t, UnavailPolicy, w.Reason)
+
+	// a future reservation isn't holding the host yet
+	igor.Config.Maintenance.HostMaintenanceDuration = 30
+	h.Reservations[0].Start = now.Add(time.Hour)
+	assert.Nil(t, h.nextUnavailWindow(now, 4*time.Hour))
+}
@@ -12,61 +12,156 @@ import (
 	zl "github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
 	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// singleHostEdits are host fields that must be unique to each host, so they can't be set on more than
+// one host at a time.
+var singleHostEdits = map[string]string{"ip": "ip", "host_name": "hostname", "mac": "mac", "console": "console name"}
+
+// clusterFileHostEdits are the host edits that change what is written to the cluster config file.
+var clusterFileHostEdits = []string{"ip", "host_name", "boot_mode", "mac", "eth", "ports", "console", "HostPolicy"}
+
+// Host edit results
+const (
+	HostEditChanged   = "changed"
+	HostEditUnchanged = "unchanged"
+	HostEditError     = "error"
 )
 
-func doUpdateHost(hostName string, changes map[string]interface{}, r *http.Request) (status int, err error) {
+// doUpdateHosts applies the changes to every named host in one transaction and reports the outcome for
+// each host. Hosts that already match the changes are left alone. A rewrite of the cluster config file
+// is queued if any host changed in a way the file records.
+func doUpdateHosts(hostNames []string, changes map[string]interface{}, r *http.Request) (results []common.HostEditResult, status int, err error) {
 
 	clog := hlog.FromRequest(r)
+	pollChanges := map[string]int{}
+	var configChanged bool
 
 	status = http.StatusInternalServerError // default status, overridden at end if no errors
 
 	if err = performDbTx(func(tx *gorm.DB) error {
 
-		hList, ghStatus, ghErr := getHosts([]string{hostName}, false, tx)
-		if err != nil {
+		hList, ghStatus, ghErr := getHosts(hostNames, true, tx)
+		if ghErr != nil {
 			status = ghStatus
 			return ghErr
 		}
 
-		err = dbEditHosts(hList, changes, tx)
-		if err != nil {
-			return err // uses default err status
-		} else {
-			var doDump bool
-			var cDumpErr error
-			var finalPath string
-
-			for k := range changes {
-				if k == "HostPolicy" || k == "ip" || k == "eth" {
-					if k == "HostPolicy" {
-						k = "hostPolicy"
-					}
-					clog.Debug().Msgf("config field %s changed when editing host %s", k, hostName)
-					doDump = true
+		if len(hList) > 1 {
+			for k, param := range singleHostEdits {
+				if _, ok := changes[k]; ok {
+					status = http.StatusBadRequest
+					return fmt.Errorf("%s cannot be set when editing more than one host -- each host must have its own unique %s", param, param)
 				}
 			}
+		}
 
-			if doDump {
-				clog.Info().Msg("writing new version of cluster config file")
-				var clusters []Cluster
-				var yDoc []byte
-
-				if clusters, cDumpErr = dbReadClusters(nil, tx); cDumpErr == nil {
-					if yDoc, cDumpErr = assembleYamlOutput(clusters); cDumpErr == nil {
-						finalPath, cDumpErr = updateClusterConfigFile(yDoc, clog)
+		var failCount int
+		for i := range hList {
+			h := &hList[i]
+			result := common.HostEditResult{Host: h.Name, Result: HostEditUnchanged}
+
+			hostChanges := hostEditDiff(h, changes)
+			if len(hostChanges) > 0 {
+				// checked before the edit since dbEditHost consumes the policy change
+				var configFields []string
+				for _, k := range clusterFileHostEdits {
+					if _, ok := hostChanges[k]; ok {
+						configFields = append(configFields, k)
+					}
+				}
+				savePoint := fmt.Sprintf("host_edit_%d", h.ID)
+				tx.SavePoint(savePoint)
+				if editErr := dbEditHost(h, hostChanges, tx); editErr != nil {
+					tx.RollbackTo(savePoint)
+					clog.Warn().Msgf("problem updating host %s : %v", h.Name, editErr)
+					result.Result = HostEditError
+					result.Error = editErr.Error()
+					failCount++
+				} else {
+					result.Result = HostEditChanged
+					for _, k := range configFields {
+						clog.Debug().Msgf("config field %s changed when editing host %s", k, h.Name)
+						configChanged = true
+					}
+					if seconds, ok := hostChanges["poll_interval"].(int); ok {
+						pollName := h.HostName
+						if newHostName, hnOk := hostChanges["host_name"].(string); hnOk {
+							pollName = newHostName
+						}
+						pollChanges[pollName] = seconds
 					}
 				}
 			}
-			clog.Info().Msgf("%s updated on host update", finalPath)
-			return cDumpErr
+			results = append(results, result)
+		}
+
+		if failCount == len(hList) {
+			return fmt.Errorf("%s", results[0].Error) // uses default err status
 		}
 
+		return nil
+
 	}); err == nil {
 		status = http.StatusOK
+		if configChanged {
+			queueClusterFileWrite(getUserFromContext(r).Name, clog)
+		}
+		for hostName, seconds := range pollChanges {
+			setPowerPollInterval(hostName, seconds)
+			clog.Info().Msgf("power poll interval of host %s set to %d seconds", hostName, seconds)
+		}
 	}
 	return
 }
 
+// hostEditDiff returns the subset of changes that would alter the given host.
+func hostEditDiff(h *Host, changes map[string]interface{}) map[string]interface{} {
+
+	diff := map[string]interface{}{}
+	for k, v := range changes {
+		var same bool
+		switch k {
+		case "ip":
+			same = h.IP == v
+		case "host_name":
+			same = h.HostName == v
+		case "boot_mode":
+			same = h.BootMode == v
+		case "mac":
+			same = h.Mac == v
+		case "eth":
+			same = h.Eth == v
+		case "ports":
+			same = h.Ports == v
+		case "poll_interval":
+			same = h.PollInterval == v
+		case "console":
+			same = h.Console == v
+		case "HostPolicy":
+			same = h.HostPolicy.ID == v.(HostPolicy).ID
+		}
+		if !same {
+			diff[k] = v
+		}
+	}
+	return diff
+}
+
+// dbEditHost updates a single host. A policy change goes through dbApplyPolicy so it is handled
+// the same way as 'igor policy apply'.
+func dbEditHost(h *Host, changes map[string]interface{}, tx *gorm.DB) error {
+	if hp, ok := changes["HostPolicy"].(HostPolicy); ok {
+		if err := dbApplyPolicy(&hp, []Host{*h}, tx); err != nil {
+			return err
+		}
+		delete(changes, "HostPolicy")
+	}
+	return dbEditHosts([]Host{*h}, changes, tx)
+}
+
 func parseHostEditParams(editParams map[string]interface{}, clog *zl.Logger) (map[string]interface{}, int, error) {
 
 	changes := map[string]interface{}{}
@@ -92,9 +187,22 @@ func parseHostEditParams(editParams map[string]interface{}, clog *zl.Logger) (ma
 			changes["mac"] = val
 		}
 	}
-	// check for eth change
+	// check for eth change, which sets all the switch ports of the host
 	if val, ok := editParams["eth"].(string); ok {
-		changes["eth"] = val
+		eth, ports, err := parseEthSpec(val)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		changes["eth"] = eth
+		changes["ports"] = formatHostPorts(ports)
+	}
+	// check for power poll interval change
+	if val, ok := editParams["pollInterval"].(float64); ok {
+		changes["poll_interval"] = int(val)
+	}
+	// check for console name change, an empty name goes back to using the host name
+	if val, ok := editParams["console"].(string); ok {
+		changes["console"] = val
 	}
 	// determine if new host policy
 	if val, ok := editParams["hostPolicy"].(string); ok {
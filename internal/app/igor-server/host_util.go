@@ -6,10 +6,45 @@ package igorserver
 
 import (
 	"fmt"
+	"igor2/internal/pkg/common"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// checkNodeAction returns an error if the user can't perform the node action on every one of the hosts.
+// The user needs a node action permission covering each host, which the group of the reservation using
+// it holds while the reservation is active, and the host's policy must not restrict the action to
+// admins. The hosts must have their HostPolicy loaded.
+//
+// Node action permissions are granted to the reservation's group, never to its members, and authInfo
+// is built from the groups the user belongs to when the request is authenticated. A change to a
+// group's members therefore takes effect on the user's next request with nothing to rebuild. New node
+// actions should be checked here so they follow the same rule.
+func checkNodeAction(user *User, authInfo *UserAuthInfo, action string, hosts []Host) (int, error) {
+	for _, h := range hosts {
+		perm, _ := NewPermission(NewPermissionString(PermNodeAction, h.HostName, action))
+		if !authInfo.IsPermitted(perm) {
+			return http.StatusForbidden, fmt.Errorf("user does not have permission to %s host %s", action, h.Name)
+		}
+	}
+	return checkNodeActionPolicy(user, action, hosts)
+}
+
+// checkNodeActionPolicy returns an error if the policy of one of the hosts restricts the node action
+// to admins and the user isn't an elevated admin.
+func checkNodeActionPolicy(user *User, action string, hosts []Host) (int, error) {
+	if userElevated(user.Name) {
+		return http.StatusOK, nil
+	}
+	for _, h := range hosts {
+		if h.HostPolicy.restrictsAction(action) {
+			return http.StatusForbidden, newCodedError(common.ErrElevateRequired, "host policy '%s' restricts %s of host %s to admins", h.HostPolicy.Name, action, h.Name)
+		}
+	}
+	return http.StatusOK, nil
+}
+
 // getReservedHosts returns a list of hosts that are currently
 // associated to a reservation
 func getReservedHosts() ([]Host, error) {
@@ -124,6 +159,16 @@ func checkEthRules(ref string) error {
 	return nil
 }
 
+// checkEthSpecRules checks the switch ports given to set on a host, either a single port name or
+// role=port pairs.
+func checkEthSpecRules(spec string) error {
+	if len(strings.TrimSpace(spec)) == 0 {
+		return fmt.Errorf("eth value name cannot be empty")
+	}
+	_, _, err := parseEthSpec(spec)
+	return err
+}
+
 func validBootMode(ref string) bool {
 	for _, bMode := range AllowedBootModes {
 		if ref == bMode {
@@ -7,6 +7,9 @@ package igorserver
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"igor2/internal/pkg/common"
@@ -27,6 +30,17 @@ const DefaultPolicyName = "default" // system default policy name with 24/7 avai
 //	NotAvailable = [] (no restrictions)
 //	MaxResTime = (value set in igor config)
 //	AccessGroups = [ALL]
+//	ExcludedGroups = []
+//
+// ExcludedGroups are checked after AccessGroups. A user in any excluded group can't reserve the policy's
+// nodes even if they are in one of its access groups, so a policy open to everyone can still keep out
+// the members of a group.
+//
+// A policy can give members of particular groups a different MaxResTime with a GroupTimeLimit. A user in
+// several groups with one on the same policy gets the longest of them.
+//
+// MaxPerUser caps how many of the policy's hosts one user can hold in current and future reservations
+// at once. Elevated admins are exempt.
 //
 // Assigning a policy to a node by default does not affect (current or future) reservations already created.
 type HostPolicy struct {
@@ -34,8 +48,24 @@ type HostPolicy struct {
 	Name         string             `gorm:"unique; notNull"` // policy identifier
 	Hosts        []Host             // the hosts this policy is assigned to
 	MaxResTime   time.Duration      // default is config file value
+	GroupLimits  []GroupTimeLimit   // per-group replacements for MaxResTime
 	AccessGroups []Group            `gorm:"many2many:groups_policies;"`       // Only the listed Group(s) may reserve a node assigned to this policy. Defaults to GroupAll.
 	NotAvailable ScheduleBlockArray `gorm:"column:notavailable; type:string"` // Can be empty, meaning nodes attached to this policy would not have any unavailability periods.
+	// ExcludedGroups lists groups whose members may not reserve a node assigned to this policy
+	ExcludedGroups []Group `gorm:"many2many:groups_policies_excluded;"`
+	// RestrictedActions is a comma-separated list of node actions only admins can perform on the policy's hosts
+	RestrictedActions string
+	// MaxPerUser is the most of the policy's hosts a user can hold at once, 0 means no cap
+	MaxPerUser int
+}
+
+// GroupTimeLimit replaces the MaxResTime of a host policy for members of a group.
+type GroupTimeLimit struct {
+	Base
+	HostPolicyID int `gorm:"uniqueIndex:idx_policy_group"`
+	GroupID      int `gorm:"uniqueIndex:idx_policy_group"`
+	Group        Group
+	MaxResTime   time.Duration
 }
 
 type ScheduleBlockArray []common.ScheduleBlock
@@ -51,6 +81,76 @@ func (sla ScheduleBlockArray) Value() (driver.Value, error) {
 	return string(val), err
 }
 
+// maxResTimeFor returns the MaxResTime the policy applies to a member of the named groups. This is the
+// longest limit among the groups that have one on the policy, or the policy's MaxResTime if none do.
+func (h *HostPolicy) maxResTimeFor(groupNames []string) time.Duration {
+	limit, _ := h.groupMaxResTime(groupNames)
+	return limit
+}
+
+// groupMaxResTime works out maxResTimeFor and also returns the name of the group whose limit applies,
+// which is empty when the policy's own MaxResTime does.
+func (h *HostPolicy) groupMaxResTime(groupNames []string) (time.Duration, string) {
+	limit, limitGroup := h.MaxResTime, ""
+	for _, gl := range h.GroupLimits {
+		for _, name := range groupNames {
+			if gl.Group.Name == name && (limitGroup == "" || gl.MaxResTime > limit) {
+				limit, limitGroup = gl.MaxResTime, name
+			}
+		}
+	}
+	return limit, limitGroup
+}
+
+// groupLimitMap returns the group time limits of the policy keyed by group name.
+func (h *HostPolicy) groupLimitMap() map[string]string {
+	if len(h.GroupLimits) == 0 {
+		return nil
+	}
+	limits := make(map[string]string, len(h.GroupLimits))
+	for _, gl := range h.GroupLimits {
+		limits[gl.Group.Name] = gl.MaxResTime.String()
+	}
+	return limits
+}
+
+// restrictedActions returns the node actions only admins can perform on the policy's hosts.
+func (h *HostPolicy) restrictedActions() []string {
+	if h.RestrictedActions == "" {
+		return nil
+	}
+	return strings.Split(h.RestrictedActions, PermSubpartToken)
+}
+
+// restrictsAction returns true if the policy keeps non-admins from performing the node action on its hosts.
+func (h *HostPolicy) restrictsAction(action string) bool {
+	return slices.Contains(h.restrictedActions(), action)
+}
+
+// changeRestrictedActions adds the restrict actions to the policy's restricted node actions and
+// removes the allow actions from them.
+func (h *HostPolicy) changeRestrictedActions(restrict, allow []string) {
+	current := h.restrictedActions()
+	var actions []string
+	for _, a := range nodeActions {
+		if (slices.Contains(current, a) || slices.Contains(restrict, a)) && !slices.Contains(allow, a) {
+			actions = append(actions, a)
+		}
+	}
+	h.RestrictedActions = strings.Join(actions, PermSubpartToken)
+}
+
+// excludedGroupOf returns the first of the excluded groups of the policy that is in groupNames, or
+// an empty string if none are.
+func (h *HostPolicy) excludedGroupOf(groupNames []string) string {
+	for _, g := range h.ExcludedGroups {
+		if slices.Contains(groupNames, g.Name) {
+			return g.Name
+		}
+	}
+	return ""
+}
+
 // removeSBInstance removes the given ScheduleBlock from the given ScheduleBlockArray
 func (h *HostPolicy) removeSBInstance(sb common.ScheduleBlock) ScheduleBlockArray {
 	newSBA := ScheduleBlockArray{}
@@ -85,12 +185,18 @@ func filterHostPoliciesList(hostPolicies []HostPolicy) []common.HostPolicyData {
 		for _, group := range hp.AccessGroups {
 			groups = append(groups, group.Name)
 		}
+		excluded := groupNamesOfGroups(hp.ExcludedGroups)
+		sort.Strings(excluded)
 		result = append(result, common.HostPolicyData{
-			Name:         hp.Name,
-			Hosts:        hostRange,
-			MaxResTime:   hp.MaxResTime.String(),
-			AccessGroups: groups,
-			NotAvailable: hp.NotAvailable,
+			Name:              hp.Name,
+			Hosts:             hostRange,
+			MaxResTime:        hp.MaxResTime.String(),
+			GroupLimits:       hp.groupLimitMap(),
+			AccessGroups:      groups,
+			ExcludedGroups:    excluded,
+			NotAvailable:      hp.NotAvailable,
+			RestrictedActions: hp.restrictedActions(),
+			MaxPerUser:        hp.MaxPerUser,
 		})
 	}
 	return result
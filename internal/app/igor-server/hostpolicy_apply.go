@@ -5,20 +5,23 @@
 package igorserver
 
 import (
+	"net/http"
+
 	"github.com/rs/zerolog"
 	"gorm.io/gorm"
-	"net/http"
+
+	"igor2/internal/pkg/common"
 )
 
-// Maps the power command parameters to a list of hosts and checks permissions to ensure the user
-// can actually issue a power command for those hosts.
-func checkApplyPolicyParams(applyParams map[string]interface{}, clog *zerolog.Logger) (policy *HostPolicy, hosts *[]Host, status int, err error) {
+// Maps the apply parameters to the policy and the list of host names to apply it to.
+func checkApplyPolicyParams(applyParams map[string]interface{}, clog *zerolog.Logger) (policy *HostPolicy, hostList []string, failFast bool, status int, err error) {
 
 	hostPolicyName := applyParams["policy"].(string)
 	val := applyParams["nodeList"].(string)
+	failFast, _ = applyParams["failFast"].(bool)
 	status = http.StatusInternalServerError
 
-	hostList := igor.splitRange(val)
+	hostList = igor.splitRange(val)
 
 	if err = performDbTx(func(tx *gorm.DB) error {
 
@@ -28,14 +31,6 @@ func checkApplyPolicyParams(applyParams map[string]interface{}, clog *zerolog.Lo
 			return ghpErr
 		}
 		policy = &hpList[0]
-
-		hList, ghStatus, ghErr := getHosts(hostList, true, tx)
-		if err != nil {
-			status = ghStatus
-			return ghErr
-		}
-		hosts = &hList
-
 		return nil
 
 	}); err == nil {
@@ -45,17 +40,55 @@ func checkApplyPolicyParams(applyParams map[string]interface{}, clog *zerolog.Lo
 	return
 }
 
-// doApplyPolicy updates the given hosts with the supplied policy.
-func doApplyPolicy(hostPolicy *HostPolicy, hosts *[]Host) (status int, err error) {
+// doApplyPolicy updates each of the named hosts with the supplied policy and reports the outcome for
+// each. A host that can't be updated fails without stopping the others unless failFast is set, in which
+// case no host is changed. A rewrite of the cluster config file is queued on behalf of the named user
+// if any host changed.
+func doApplyPolicy(hostPolicy *HostPolicy, hostList []string, failFast bool, userName string, clog *zerolog.Logger) (results []common.BatchItemResult, status int, err error) {
 
 	status = http.StatusInternalServerError // default status, overridden at end if no errors
 
 	if err = performDbTx(func(tx *gorm.DB) error {
 
-		return dbEditHosts(*hosts, map[string]interface{}{"HostPolicy": *hostPolicy}, tx) // uses default err status
+		hList, ghStatus, ghErr := getHosts(hostList, false, tx)
+		if ghErr != nil {
+			status = ghStatus
+			return ghErr
+		}
+		hostsByName := make(map[string]*Host, len(hList))
+		for i := range hList {
+			hostsByName[hList[i].Name] = &hList[i]
+		}
+
+		batch := newBatchRun(tx, failFast)
+		for _, name := range hostList {
+			var aErr error
+			if h, found := hostsByName[name]; !found {
+				aErr = batch.fail(name, newCodedError(common.ErrNotFound, "host not found"))
+			} else {
+				aErr = batch.do(name, func() error {
+					return dbApplyPolicy(hostPolicy, []Host{*h}, tx)
+				})
+			}
+			if aErr != nil {
+				status = batchAbortStatus(aErr)
+				return aErr
+			}
+		}
+		results = batch.results
+		return nil
 
 	}); err == nil {
 		status = http.StatusOK
+		if ok, _ := common.CountBatchResults(results); ok > 0 {
+			queueClusterFileWrite(userName, clog)
+		}
 	}
 	return
 }
+
+// dbApplyPolicy assigns the policy to the given hosts. This is used both by policy apply and
+// by host edit so the two stay consistent.
+func dbApplyPolicy(hostPolicy *HostPolicy, hosts []Host, tx *gorm.DB) error {
+	return dbEditHosts(hosts, map[string]interface{}{"HostPolicy": *hostPolicy}, tx)
+}
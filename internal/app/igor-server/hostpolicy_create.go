@@ -39,7 +39,7 @@ func doCreateHostPolicy(createHostPolicyParams map[string]interface{}, r *http.R
 		if exists {
 			// the hostPolicy already exists
 			code = http.StatusConflict
-			return fmt.Errorf("host policy '%s' already exists", hostPolicyName)
+			return newCodedError(common.ErrNameTaken, "host policy '%s' already exists", hostPolicyName)
 		}
 
 		// Determine maxDurationTime
@@ -90,6 +90,17 @@ func doCreateHostPolicy(createHostPolicyParams map[string]interface{}, r *http.R
 			groups = []Group{*allGroup}
 		}
 
+		// Determine ExcludedGroups
+		var excluded []Group
+		if names, ok := createHostPolicyParams["excludedGroups"].([]interface{}); ok && len(names) > 0 {
+			foundGroups, status, gErr := getExcludedGroups(names)
+			if gErr != nil {
+				code = status
+				return gErr
+			}
+			excluded = foundGroups
+		}
+
 		// Determine notAvailable entries
 		sba := ScheduleBlockArray{}
 		sbList, ok3 := createHostPolicyParams["notAvailable"].([]interface{})
@@ -100,11 +111,15 @@ func doCreateHostPolicy(createHostPolicyParams map[string]interface{}, r *http.R
 			}
 		}
 
+		maxPerUser, _ := createHostPolicyParams["maxPerUser"].(float64)
+
 		hostPolicy = &HostPolicy{
-			Name:         hostPolicyName,
-			MaxResTime:   maxResTime,
-			AccessGroups: groups,
-			NotAvailable: sba,
+			Name:           hostPolicyName,
+			MaxResTime:     maxResTime,
+			AccessGroups:   groups,
+			NotAvailable:   sba,
+			ExcludedGroups: excluded,
+			MaxPerUser:     int(maxPerUser),
 		}
 
 		return dbCreateHostPolicy(hostPolicy, tx) // uses default err status
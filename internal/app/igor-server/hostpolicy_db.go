@@ -7,6 +7,7 @@ package igorserver
 import (
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"igor2/internal/pkg/common"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func dbCreateHostPolicy(policy *HostPolicy, tx *gorm.DB) error {
@@ -33,7 +35,7 @@ func dbReadHostPoliciesTx(queryParams map[string]interface{}, clog *zl.Logger) (
 
 func dbReadHostPolicies(queryParams map[string]interface{}, tx *gorm.DB, clog *zl.Logger) (policies []HostPolicy, err error) {
 
-	tx = tx.Preload("AccessGroups").Preload("Hosts")
+	tx = tx.Preload("AccessGroups").Preload("ExcludedGroups").Preload("Hosts").Preload("GroupLimits.Group")
 
 	// if no params given, return all host policies
 	if len(queryParams) == 0 {
@@ -81,6 +83,9 @@ func dbEditHostPolicy(targets []HostPolicy, changes map[string]interface{}, tx *
 			// }
 			h.MaxResTime = maxResTime.(time.Duration)
 		}
+		if maxPerUser, ok := changes["maxPerUser"]; ok {
+			h.MaxPerUser = maxPerUser.(int)
+		}
 		policyGroups := h.AccessGroups
 		if remGroups, ok := changes["removeGroups"]; ok {
 			rGroups := remGroups.([]Group)
@@ -125,6 +130,23 @@ func dbEditHostPolicy(targets []HostPolicy, changes map[string]interface{}, tx *
 			h.AccessGroups = []Group{*allGroup}
 		}
 
+		if remExcluded, ok := changes["removeExcludedGroups"]; ok {
+			for _, group := range remExcluded.([]Group) {
+				h.ExcludedGroups = removeGroup(h.ExcludedGroups, &group)
+				if daErr := tx.Model(&h).Association("ExcludedGroups").Delete(group); daErr != nil {
+					return daErr
+				}
+			}
+		}
+
+		if addExcluded, ok := changes["addExcludedGroups"]; ok {
+			for _, group := range addExcluded.([]Group) {
+				if !groupSliceContains(h.ExcludedGroups, group.Name) {
+					h.ExcludedGroups = append(h.ExcludedGroups, group)
+				}
+			}
+		}
+
 		if addSBs, ok := changes["addNotAvailable"]; ok {
 			mySBs := h.NotAvailable
 			for _, sba := range addSBs.(ScheduleBlockArray) {
@@ -147,20 +169,66 @@ func dbEditHostPolicy(targets []HostPolicy, changes map[string]interface{}, tx *
 				h.NotAvailable = h.removeSBInstance(sbi)
 			}
 		}
-		// save any changes made
-		if result := tx.Save(&h); result.Error != nil {
+
+		if rmvLimits, ok := changes["removeGroupLimits"]; ok {
+			groupIDs := groupIDsOfGroups(rmvLimits.([]Group))
+			if result := tx.Where("host_policy_id = ? AND group_id IN ?", h.ID, groupIDs).Delete(&GroupTimeLimit{}); result.Error != nil {
+				return result.Error
+			}
+		}
+
+		if setLimits, ok := changes["groupLimits"]; ok {
+			for _, gl := range setLimits.([]GroupTimeLimit) {
+				result := tx.Model(&GroupTimeLimit{}).Where("host_policy_id = ? AND group_id = ?", h.ID, gl.GroupID).
+					Update("max_res_time", gl.MaxResTime)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					gl.HostPolicyID = h.ID
+					if result = tx.Omit(clause.Associations).Create(&gl); result.Error != nil {
+						return result.Error
+					}
+				}
+			}
+		}
+
+		restrict, rOk := changes["restrictActions"].([]string)
+		allow, aOk := changes["allowActions"].([]string)
+		if rOk || aOk {
+			h.changeRestrictedActions(restrict, allow)
+		}
+
+		// save any changes made, group limits were written above
+		if result := tx.Omit("GroupLimits").Save(&h); result.Error != nil {
 			return result.Error
 		}
 	}
 	return nil
 }
 
+// dbHostIDsRestrictingActionTx returns the IDs of the hosts whose policy restricts the node action to admins.
+func dbHostIDsRestrictingActionTx(action string) (ids []int, err error) {
+	err = performDbTx(func(tx *gorm.DB) error {
+		return tx.Model(&Host{}).Joins("JOIN host_policies ON host_policies.id = hosts.host_policy_id").
+			Where("(',' || host_policies.restricted_actions || ',') LIKE ?", "%,"+action+",%").
+			Pluck("hosts.id", &ids).Error
+	})
+	return
+}
+
 // dbDeleteHostPolicy removes the given host policy from the DB
 func dbDeleteHostPolicy(target *HostPolicy, tx *gorm.DB) error {
 
 	if daErr := tx.Model(&target).Association("AccessGroups").Clear(); daErr != nil {
 		return daErr
 	}
+	if daErr := tx.Model(&target).Association("ExcludedGroups").Clear(); daErr != nil {
+		return daErr
+	}
+	if result := tx.Where("host_policy_id = ?", target.ID).Delete(&GroupTimeLimit{}); result.Error != nil {
+		return result.Error
+	}
 	if result := tx.Delete(&target); result.Error != nil {
 		return result.Error
 	}
@@ -175,7 +243,7 @@ func dbDeleteHostPolicy(target *HostPolicy, tx *gorm.DB) error {
 //	500/ServerError if there was an internal problem.
 //	409/Conflict if one or more policies were found to conflict with the given access groups or time window.
 //	200/OK if no conflicts were found.
-func dbCheckHostPolicyConflicts(hostNames []string, groupAccessList []string, isElevated bool,
+func dbCheckHostPolicyConflicts(hostNames []string, groupAccessList []string, limitGroups []string, isElevated bool,
 	startTime time.Time, currentEndTime time.Time, newEndTime time.Time, clog *zl.Logger) (int, error) {
 	// get all policies associated with the given list of host names
 	myHostPolicies, err := getHostPoliciesFromHostNames(hostNames)
@@ -187,7 +255,14 @@ func dbCheckHostPolicyConflicts(hostNames []string, groupAccessList []string, is
 	if membership, policy := dbCheckHostPolicyGroupConflicts(myHostPolicies, groupAccessList); !membership {
 		// get the intersection of affected policy hosts and requested hosts
 		offendingHosts := getHostIntersection(hostNames, policy.Hosts)
-		return http.StatusConflict, &HostPolicyConflictError{"", true, false, false, time.Time{}, time.Time{}, offendingHosts}
+		return http.StatusConflict, &HostPolicyConflictError{"", true, false, false, time.Time{}, time.Time{}, offendingHosts, ""}
+	}
+
+	// then make sure the user isn't in a group any of the policies exclude
+	memberGroups := append(append([]string{}, groupAccessList...), limitGroups...)
+	if policy, excluded := checkHostPolicyExclusions(myHostPolicies, memberGroups); excluded != "" {
+		offendingHosts := getHostIntersection(hostNames, policy.Hosts)
+		return http.StatusConflict, &HostPolicyConflictError{"", true, false, false, time.Time{}, time.Time{}, offendingHosts, excluded}
 	}
 
 	// determine if any policies conflict based on maxResDuration or unavailability
@@ -196,11 +271,11 @@ func dbCheckHostPolicyConflicts(hostNames []string, groupAccessList []string, is
 		clog.Debug().Msgf("checking HostPolicy: %s", policy.Name)
 		// check that each host can support the desired reservation duration
 		if !isElevated {
-			if err = checkTimeLimit(len(hostNames), policy.MaxResTime, totalResDuration); err != nil {
+			if err = checkTimeLimit(len(hostNames), policy.maxResTimeFor(limitGroups), totalResDuration); err != nil {
 				clog.Warn().Msgf("%v", err)
 				// get the intersection of affected policy hosts and requested hosts
 				offendingHosts := getHostIntersection(hostNames, policy.Hosts)
-				return http.StatusConflict, &HostPolicyConflictError{err.Error(), false, true, false, time.Time{}, time.Time{}, offendingHosts}
+				return http.StatusConflict, &HostPolicyConflictError{err.Error(), false, true, false, time.Time{}, time.Time{}, offendingHosts, ""}
 			}
 		}
 		// iterate through any policy ScheduleBlocks to determine if a conflict exists with the given times
@@ -212,12 +287,63 @@ func dbCheckHostPolicyConflicts(hostNames []string, groupAccessList []string, is
 		if conflict, start, end := hasScheduleBlockConflict(policy.NotAvailable, contextStart, newEndTime, clog); conflict {
 			// get the intersection of affected policy hosts and requested hosts
 			offendingHosts := getHostIntersection(hostNames, policy.Hosts)
-			return http.StatusConflict, &HostPolicyConflictError{err.Error(), false, false, true, start, end, offendingHosts}
+			return http.StatusConflict, &HostPolicyConflictError{"", false, false, true, start, end, offendingHosts, ""}
 		}
 	}
 	return http.StatusOK, nil
 }
 
+// dbCheckPolicyUserCaps makes sure that giving the user the hosts would not leave them holding more of
+// a policy's hosts than its MaxPerUser allows. Hosts the user has in current and future reservations
+// count toward the cap; those of the reservation skipResID are left out so it can be checked against
+// its own hosts when extended.
+//
+//	500/ServerError if there was an internal problem.
+//	409/Conflict if the user would hold more hosts of a policy than it allows.
+//	200/OK if no cap is exceeded.
+func dbCheckPolicyUserCaps(user *User, hosts []Host, skipResID int, tx *gorm.DB) (int, error) {
+
+	hostsByPolicy := map[int][]int{}
+	for _, h := range hosts {
+		hostsByPolicy[h.HostPolicyID] = append(hostsByPolicy[h.HostPolicyID], h.ID)
+	}
+	policyIDs := make([]int, 0, len(hostsByPolicy))
+	for id := range hostsByPolicy {
+		policyIDs = append(policyIDs, id)
+	}
+
+	var policies []HostPolicy
+	if result := tx.Where("id IN ? AND max_per_user > 0", policyIDs).Order("name").Find(&policies); result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+
+	now := time.Now()
+	for _, policy := range policies {
+		var heldIDs []int
+		result := tx.Table("reservations_hosts").Distinct("reservations_hosts.host_id").
+			Joins("JOIN reservations ON reservations.id = reservations_hosts.reservation_id").
+			Joins("JOIN hosts ON hosts.id = reservations_hosts.host_id").
+			Where("hosts.host_policy_id = ? AND reservations.owner_id = ? AND reservations.id <> ? AND reservations.end > ?",
+				policy.ID, user.ID, skipResID, now).
+			Pluck("reservations_hosts.host_id", &heldIDs)
+		if result.Error != nil {
+			return http.StatusInternalServerError, result.Error
+		}
+
+		holding := map[int]bool{}
+		for _, id := range append(heldIDs, hostsByPolicy[policy.ID]...) {
+			holding[id] = true
+		}
+		if len(holding) > policy.MaxPerUser {
+			return http.StatusConflict, newCodedError(common.ErrPolicyUserCap,
+				"host policy '%s' allows each user at most %d of its hosts at once -- %s already holds %d of them in current or future reservations",
+				policy.Name, policy.MaxPerUser, user.Name, len(heldIDs))
+		}
+	}
+
+	return http.StatusOK, nil
+}
+
 func dbCheckHostPolicyGroupConflicts(hostPolicies []HostPolicy, groupAccessList []string) (bool, HostPolicy) {
 	// determine if any policies do not contain at least one group from groupAccessList
 	for _, policy := range hostPolicies {
@@ -239,9 +365,88 @@ func dbCheckHostPolicyGroupConflicts(hostPolicies []HostPolicy, groupAccessList
 	return true, HostPolicy{}
 }
 
+// checkHostPolicyExclusions returns the first policy that excludes one of groupNames along with the
+// name of the excluded group, or an empty group name if no policy excludes any of them.
+func checkHostPolicyExclusions(hostPolicies []HostPolicy, groupNames []string) (HostPolicy, string) {
+	for _, policy := range hostPolicies {
+		if excluded := policy.excludedGroupOf(groupNames); excluded != "" {
+			return policy, excluded
+		}
+	}
+	return HostPolicy{}, ""
+}
+
+// dbReadSchedulableHosts returns the hosts of the policy with the given ID that the scheduler can place
+// reservations on. This includes hosts in a maintenance period that return to the pool when it ends.
+func dbReadSchedulableHosts(policyID int, tx *gorm.DB) ([]Host, error) {
+
+	hosts, err := dbReadHosts(map[string]interface{}{"host_policy_id": policyID, "state": schedulableHostStates}, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	maintEnds, err := dbHostMaintenanceEnds(nil, tx)
+	if err != nil || len(maintEnds) == 0 {
+		return hosts, err
+	}
+	maintNames := make([]string, 0, len(maintEnds))
+	for name := range maintEnds {
+		maintNames = append(maintNames, name)
+	}
+	inMaintenance, err := dbReadHosts(map[string]interface{}{"host_policy_id": policyID, "name": maintNames}, tx)
+	if err != nil {
+		return nil, err
+	}
+	hosts = append(hosts, inMaintenance...)
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].SequenceID < hosts[j].SequenceID
+	})
+	return hosts, nil
+}
+
+// dbGetAccessCeiling returns the most hosts a member of accessGroupList and limitGroups could ever
+// reserve at once, leaving time out of it, along with the names of the policies holding the schedulable
+// hosts they can't use. Policies follow the same access rules as dbGetAccessibleHosts.
+func dbGetAccessCeiling(accessGroupList []string, limitGroups []string, tx *gorm.DB, clog *zl.Logger) (int, []string, int, error) {
+
+	policies, err := dbReadHostPolicies(map[string]interface{}{}, tx, clog)
+	if err != nil {
+		return 0, nil, http.StatusInternalServerError, err
+	}
+
+	memberGroups := append(append([]string{}, accessGroupList...), limitGroups...)
+	ceiling := 0
+	var limitedBy []string
+	for _, policy := range policies {
+		hosts, rhErr := dbReadSchedulableHosts(policy.ID, tx)
+		if rhErr != nil {
+			return 0, nil, http.StatusInternalServerError, rhErr
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+		canAccess := false
+		for _, g := range accessGroupList {
+			if groupSliceContains(policy.AccessGroups, g) {
+				canAccess = true
+				break
+			}
+		}
+		if canAccess && policy.excludedGroupOf(memberGroups) == "" {
+			ceiling += len(hosts)
+		} else {
+			limitedBy = append(limitedBy, policy.Name)
+		}
+	}
+	sort.Strings(limitedBy)
+	return ceiling, limitedBy, http.StatusOK, nil
+}
+
 // dbGetAccessibleHosts determines and returns the Host collections associated with a HostPolicy that
-// does not conflict with the given accessGroupList, startTime or endTime.
-func dbGetAccessibleHosts(accessGroupList []string, isElevated bool, startTime, endTime time.Time, numHostsReq int, tx *gorm.DB, clog *zl.Logger) (map[string][]Host, int, error) {
+// does not conflict with the given accessGroupList, startTime or endTime. Policy time limits are those
+// that apply to a member of limitGroups, and policies that exclude any of accessGroupList or limitGroups
+// are skipped.
+func dbGetAccessibleHosts(accessGroupList []string, limitGroups []string, isElevated bool, startTime, endTime time.Time, numHostsReq int, tx *gorm.DB, clog *zl.Logger) (map[string][]Host, int, error) {
 
 	// get all the hostPolicies that contain at least one of the given accessGroups
 	groupsIDs, status, err := getGroupIDsFromNames(accessGroupList)
@@ -253,6 +458,24 @@ func dbGetAccessibleHosts(accessGroupList []string, isElevated bool, startTime,
 		return nil, http.StatusInternalServerError, err
 	}
 
+	// drop the policies that exclude any of the user's groups. The groups are read from the db for each
+	// request, so changes to them apply to the next reservation.
+	memberGroups := append(append([]string{}, accessGroupList...), limitGroups...)
+	var excludedBy string
+	allowedPolicies := potentialPolicies[:0]
+	for _, policy := range potentialPolicies {
+		if excluded := policy.excludedGroupOf(memberGroups); excluded != "" {
+			clog.Debug().Msgf("skipping hosts of policy %s, which excludes group %s", policy.Name, excluded)
+			excludedBy = excluded
+			continue
+		}
+		allowedPolicies = append(allowedPolicies, policy)
+	}
+	potentialPolicies = allowedPolicies
+	if len(potentialPolicies) == 0 && excludedBy != "" {
+		return nil, http.StatusConflict, newCodedError(common.ErrPolicyGroup, "no hosts are available to members of group '%s'", excludedBy)
+	}
+
 	exceededTimes := make([]bool, len(potentialPolicies))
 	maxPolicyTime := time.Duration(0)
 	validPolicyIDs := map[string]int{} // potential hostpolicy is valid if maxResDuration > givenDuration and no ScheduleBlock conflicts exist
@@ -261,10 +484,11 @@ func dbGetAccessibleHosts(accessGroupList []string, isElevated bool, startTime,
 
 		// check for legal policy MaxResTime duration
 		if !isElevated {
-			if policy.MaxResTime > maxPolicyTime {
-				maxPolicyTime = policy.MaxResTime
+			policyTime := policy.maxResTimeFor(limitGroups)
+			if policyTime > maxPolicyTime {
+				maxPolicyTime = policyTime
 			}
-			if err = checkTimeLimit(numHostsReq, policy.MaxResTime, givenDuration); err != nil {
+			if err = checkTimeLimit(numHostsReq, policyTime, givenDuration); err != nil {
 				exceededTimes[i] = true
 				continue
 			}
@@ -292,13 +516,11 @@ func dbGetAccessibleHosts(accessGroupList []string, isElevated bool, startTime,
 	}
 
 	// collect all hosts attached to valid hostPolicies and states
-	validStates := []HostState{HostAvailable, HostReserved}
-
 	validAccessHosts := make(map[string][]Host)
 	totalValidHosts := 0
 
 	for key, id := range validPolicyIDs {
-		if hosts, rhErr := dbReadHosts(map[string]interface{}{"host_policy_id": id, "state": validStates}, tx); rhErr != nil {
+		if hosts, rhErr := dbReadSchedulableHosts(id, tx); rhErr != nil {
 			return nil, http.StatusInternalServerError, rhErr
 		} else {
 			validAccessHosts[key] = hosts
@@ -313,6 +535,48 @@ func dbGetAccessibleHosts(accessGroupList []string, isElevated bool, startTime,
 	return validAccessHosts, http.StatusOK, nil
 }
 
+// dbGetPolicyTimeLimit returns the host policy time limit that governs a reservation. When hosts are
+// named, the smallest MaxResTime among their policies applies. Otherwise hosts will be drawn from any
+// policy the access groups can use, so the largest MaxResTime among those applies. The MaxResTime of
+// each policy is the one it gives a member of limitGroups.
+func dbGetPolicyTimeLimit(hostNames []string, accessGroupList []string, limitGroups []string, tx *gorm.DB, clog *zl.Logger) (time.Duration, int, error) {
+
+	named := len(hostNames) > 0
+	hpParams := map[string]interface{}{}
+	if named {
+		hostIDs, status, err := getHostIDsFromNames(hostNames)
+		if err != nil {
+			return 0, status, err
+		}
+		hpParams["hosts"] = hostIDs
+	} else {
+		groupIDs, status, err := getGroupIDsFromNames(accessGroupList)
+		if err != nil {
+			return 0, status, err
+		}
+		hpParams["access_groups"] = groupIDs
+	}
+
+	policies, err := dbReadHostPolicies(hpParams, tx, clog)
+	if err != nil {
+		return 0, http.StatusInternalServerError, err
+	}
+
+	limit := time.Duration(0)
+	first := true
+	for _, policy := range policies {
+		if !named && policy.excludedGroupOf(limitGroups) != "" {
+			continue
+		}
+		policyTime := policy.maxResTimeFor(limitGroups)
+		if first || (named && policyTime < limit) || (!named && policyTime > limit) {
+			limit = policyTime
+			first = false
+		}
+	}
+	return limit, http.StatusOK, nil
+}
+
 func hasScheduleBlockConflict(sba ScheduleBlockArray, start time.Time, end time.Time, clog *zl.Logger) (bool, time.Time, time.Time) {
 	for _, sb := range sba {
 		sbDuration, _ := common.ParseDuration(sb.Duration)
@@ -7,13 +7,17 @@ package igorserver
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/rs/zerolog/hlog"
 	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
 )
 
-// delete the given host policy
-func doDeleteHostPolicy(hpName string, r *http.Request) (code int, err error) {
+// delete the given host policy. If reassignTo names a policy, any hosts still assigned to the deleted
+// policy are moved to it in the same transaction and their names are returned in moved.
+func doDeleteHostPolicy(hpName string, reassignTo string, r *http.Request) (moved []string, code int, err error) {
 
 	clog := hlog.FromRequest(r)
 	code = http.StatusInternalServerError // default status, overridden at end if no errors
@@ -32,10 +36,43 @@ func doDeleteHostPolicy(hpName string, r *http.Request) (code int, err error) {
 		}
 		target := &hpList[0]
 
-		// do not allow delete to happen if policy is still attached to a host
 		if len(target.Hosts) > 0 {
-			code = http.StatusConflict
-			return fmt.Errorf("deleting host policy while still assigned to a host is not permitted")
+			hostRange := common.UnsplitList(namesOfHosts(target.Hosts))
+
+			// do not allow delete to happen if policy is still attached to a host and there's nowhere to move it
+			if reassignTo == "" {
+				code = http.StatusConflict
+				return newCodedError(common.ErrConflict, "host policy '%s' is still assigned to hosts %s -- apply another policy to them first or reassign them", hpName, hostRange)
+			}
+			if reassignTo == hpName {
+				code = http.StatusBadRequest
+				return fmt.Errorf("cannot reassign hosts to the policy being deleted")
+			}
+
+			newList, status, ghErr := getHostPolicies([]string{reassignTo}, tx, clog)
+			if ghErr != nil {
+				code = status
+				return ghErr
+			}
+			if daErr := dbApplyPolicy(&newList[0], target.Hosts, tx); daErr != nil {
+				return daErr // uses default err status
+			}
+
+			// reservations already on these hosts were checked against the old policy when they were made
+			// and are left as they are
+			resList, rrErr := dbReadReservations(map[string]interface{}{"hosts": hostIDsOfHosts(target.Hosts)}, nil, tx)
+			if rrErr != nil {
+				return rrErr // uses default err status
+			}
+			now := time.Now()
+			for _, res := range resList {
+				if res.End.After(now) {
+					clog.Info().Msgf("reservation '%s' keeps its schedule - policy of its hosts changed from '%s' to '%s'", res.Name, hpName, reassignTo)
+				}
+			}
+
+			clog.Info().Msgf("hosts %s reassigned from policy '%s' to '%s'", hostRange, hpName, reassignTo)
+			moved = namesOfHosts(target.Hosts)
 		}
 
 		return dbDeleteHostPolicy(target, tx) // uses default err status
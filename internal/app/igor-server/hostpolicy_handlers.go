@@ -7,6 +7,7 @@ package igorserver
 import (
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
 	"igor2/internal/pkg/common"
@@ -95,7 +96,7 @@ func handleUpdateHostPolicy(w http.ResponseWriter, r *http.Request) {
 	makeJsonResponse(w, status, rb)
 }
 
-// destination for route DELETE /hosts/:hostName
+// destination for route DELETE /hostpolicy/:hostpolicyName
 func handleDeleteHostPolicy(w http.ResponseWriter, r *http.Request) {
 
 	dbAccess.Lock()
@@ -107,12 +108,25 @@ func handleDeleteHostPolicy(w http.ResponseWriter, r *http.Request) {
 	actionPrefix := "delete host policy"
 	rb := common.NewResponseBody()
 
-	status, err := doDeleteHostPolicy(name, r)
+	// reassignTo given without a value moves the hosts to the default policy
+	var reassignTo string
+	if vals, ok := r.URL.Query()["reassignTo"]; ok {
+		reassignTo = DefaultPolicyName
+		if len(vals) > 0 && vals[0] != "" {
+			reassignTo = vals[0]
+		}
+	}
+
+	moved, status, err := doDeleteHostPolicy(name, reassignTo, r)
 
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
 		clog.Info().Msgf("%s success - '%s' deleted", actionPrefix, name)
+		if len(moved) > 0 {
+			rb.Message = fmt.Sprintf("policy '%s' deleted - hosts %s moved to policy '%s'", name, common.UnsplitList(moved), reassignTo)
+			queueClusterFileWrite(getUserFromContext(r).Name, clog)
+		}
 	}
 
 	makeJsonResponse(w, status, rb)
@@ -157,7 +171,15 @@ func validateHostPolicyParams(handler http.Handler) http.Handler {
 									break postPutParamLoop
 								}
 							}
-						case "accessGroups":
+						case "maxPerUser":
+							if maxHosts, ok := val.(float64); !ok {
+								validateErr = NewBadParamTypeError(key, val, "float64")
+								break postPutParamLoop
+							} else if maxHosts < 0 || maxHosts != float64(int(maxHosts)) {
+								validateErr = fmt.Errorf("maxPerUser must be a whole number of hosts; 0 removes the cap")
+								break postPutParamLoop
+							}
+						case "accessGroups", "excludedGroups":
 							grNames, ok := val.([]interface{})
 							if !ok {
 								// return internal error instead?
@@ -219,6 +241,31 @@ func validateHostPolicyParams(handler http.Handler) http.Handler {
 			}
 		}
 
+		if r.Method == http.MethodDelete {
+			queryParams := r.URL.Query()
+		deleteParamLoop:
+			for key, vals := range queryParams {
+				switch key {
+				case "reassignTo":
+					if len(vals) > 1 {
+						validateErr = fmt.Errorf("only one policy can be given for %s", key)
+						break deleteParamLoop
+					}
+					for _, val := range vals {
+						if val == "" {
+							continue
+						}
+						if validateErr = checkHostPolicyNameRules(val); validateErr != nil {
+							break deleteParamLoop
+						}
+					}
+				default:
+					validateErr = NewUnknownParamError(key, vals)
+					break deleteParamLoop
+				}
+			}
+		}
+
 		if r.Method == http.MethodPatch {
 
 			hostParams := getBodyFromContext(r)
@@ -247,7 +294,38 @@ func validateHostPolicyParams(handler http.Handler) http.Handler {
 								break patchParamLoop
 							}
 						}
-					case "addGroups", "removeGroups":
+					case "maxPerUser":
+						if maxHosts, ok := val.(float64); !ok {
+							validateErr = NewBadParamTypeError(key, val, "float64")
+							break patchParamLoop
+						} else if maxHosts < 0 || maxHosts != float64(int(maxHosts)) {
+							validateErr = fmt.Errorf("maxPerUser must be a whole number of hosts; 0 removes the cap")
+							break patchParamLoop
+						}
+					case "groupLimits":
+						limits, ok := val.(map[string]interface{})
+						if !ok || len(limits) == 0 {
+							validateErr = NewBadParamTypeError(key, val, "map[string]string")
+							break patchParamLoop
+						}
+						for name, limit := range limits {
+							dur, dOk := limit.(string)
+							if !dOk {
+								validateErr = NewBadParamTypeError(key, limit, "string")
+								break patchParamLoop
+							}
+							if validateErr = checkGroupNameRules(name); validateErr != nil {
+								break patchParamLoop
+							}
+							if duration, err := common.ParseDuration(dur); err != nil {
+								validateErr = err
+								break patchParamLoop
+							} else if duration <= 0 {
+								validateErr = fmt.Errorf("duration expression '%s' for group '%s' must be greater than zero", dur, name)
+								break patchParamLoop
+							}
+						}
+					case "addGroups", "removeGroups", "removeGroupLimits", "addExcludedGroups", "removeExcludedGroups":
 						grNames, ok := val.([]interface{})
 						if !ok {
 							// return internal error instead?
@@ -269,6 +347,21 @@ func validateHostPolicyParams(handler http.Handler) http.Handler {
 						if validateErr != nil {
 							break patchParamLoop
 						}
+					case "restrictActions", "allowActions":
+						actions, ok := val.([]interface{})
+						if !ok {
+							validateErr = NewBadParamTypeError(key, val, "string array")
+							break patchParamLoop
+						}
+						for _, a := range actions {
+							if action, aOk := a.(string); !aOk {
+								validateErr = NewBadParamTypeError(key, a, "string array")
+								break patchParamLoop
+							} else if !slices.Contains(nodeActions, action) {
+								validateErr = fmt.Errorf("'%s' is not a node action, must be one of %v", action, nodeActions)
+								break patchParamLoop
+							}
+						}
 
 					default:
 						validateErr = NewUnknownParamError(key, val)
@@ -328,16 +421,18 @@ func handleApplyPolicy(w http.ResponseWriter, r *http.Request) {
 	applyParams := getBodyFromContext(r)
 	clog := hlog.FromRequest(r)
 	actionPrefix := "apply policy"
-	policy, hosts, status, err := checkApplyPolicyParams(applyParams, clog)
+	policy, hostList, failFast, status, err := checkApplyPolicyParams(applyParams, clog)
+	var results []common.BatchItemResult
 	if err == nil {
-		status, err = doApplyPolicy(policy, hosts)
+		results, status, err = doApplyPolicy(policy, hostList, failFast, getUserFromContext(r).Name, clog)
 	}
 
-	rb := common.NewResponseBody()
+	rb := common.NewResponseBodyBatch()
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
-		clog.Info().Msgf("%s success", actionPrefix)
+		setBatchResults(rb, results, "applied")
+		clog.Info().Msgf("%s success - %s", actionPrefix, rb.Message)
 	}
 
 	makeJsonResponse(w, status, rb)
@@ -384,6 +479,11 @@ func validateApplyPolicyParams(handler http.Handler) http.Handler {
 							validateErr = NewBadParamTypeError(key, val, "bool")
 							break patchParamLoop
 						}
+					case "failFast":
+						if _, ok := val.(bool); !ok {
+							validateErr = NewBadParamTypeError(key, val, "bool")
+							break patchParamLoop
+						}
 					default:
 						validateErr = NewUnknownParamError(key, val)
 						break patchParamLoop
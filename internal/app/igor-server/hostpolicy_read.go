@@ -12,6 +12,8 @@ import (
 	"strings"
 
 	"github.com/rs/zerolog/hlog"
+
+	"igor2/internal/pkg/common"
 )
 
 // doReadHostPolicies performs a DB lookup of HostPolicy records that match the provided queryParams. It will
@@ -90,3 +92,47 @@ func parseHostPolicySearchParams(queryMap map[string][]string, r *http.Request)
 
 	return queryParams, status, nil
 }
+
+// getNodeTimeLimit reports the longest reservation a non-elevated user can make on the hosts in nodeRange,
+// using the same ceiling applied when a reservation on them is created or extended, along with the time
+// limit of each host policy covering them. If user is known, the group time limits they get are applied.
+func getNodeTimeLimit(nodeRange string, user *User, tx *gorm.DB, clog *zl.Logger) (*common.NodeTimeLimitData, int, error) {
+
+	hostNames := igor.splitRange(nodeRange)
+	if len(hostNames) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("couldn't parse node specification %v", nodeRange)
+	}
+
+	hosts, status, err := getHosts(hostNames, true, tx)
+	if err != nil {
+		return nil, status, err
+	}
+
+	limitGroups := user.groupNames()
+	ceiling, status, err := getResTimeCeiling(hostNames, nil, limitGroups, len(hosts), tx, clog)
+	if err != nil {
+		return nil, status, err
+	}
+
+	policies, err := dbReadHostPolicies(map[string]interface{}{"hosts": hostIDsOfHosts(hosts)}, tx, clog)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	limitData := &common.NodeTimeLimitData{
+		Nodes:             common.UnsplitList(hostNames),
+		MaxReserveMinutes: int64(ceiling.Minutes()),
+	}
+	for _, p := range policies {
+		maxResTime, limitGroup := p.groupMaxResTime(limitGroups)
+		limitData.Policies = append(limitData.Policies, common.PolicyTimeLimitData{
+			Name:       p.Name,
+			Hosts:      common.UnsplitList(namesOfHosts(getHostIntersection(hostNames, p.Hosts))),
+			MaxResTime: maxResTime.String(),
+			LimitGroup: limitGroup,
+			MaxPerUser: p.MaxPerUser,
+		})
+	}
+
+	return limitData, http.StatusOK, nil
+}
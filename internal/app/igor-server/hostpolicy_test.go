@@ -0,0 +1,314 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestMaxResTimeFor(t *testing.T) {
+
+	policy := HostPolicy{
+		Name:       "gpu",
+		MaxResTime: 3 * 24 * time.Hour,
+		GroupLimits: []GroupTimeLimit{
+			{Group: Group{Name: "ml-team"}, MaxResTime: 14 * 24 * time.Hour},
+			{Group: Group{Name: "ops"}, MaxResTime: 5 * 24 * time.Hour},
+			{Group: Group{Name: "interns"}, MaxResTime: 24 * time.Hour},
+		},
+	}
+
+	assert.Equal(t, 3*24*time.Hour, policy.maxResTimeFor(nil))
+	assert.Equal(t, 3*24*time.Hour, policy.maxResTimeFor([]string{"all", "other"}))
+	assert.Equal(t, 24*time.Hour, policy.maxResTimeFor([]string{"interns"}), "a group limit can be shorter too")
+
+	// a user in two groups gets the larger of their limits
+	limit, group := policy.groupMaxResTime([]string{"ops", "ml-team"})
+	assert.Equal(t, 14*24*time.Hour, limit)
+	assert.Equal(t, "ml-team", group)
+	limit, group = policy.groupMaxResTime([]string{"interns", "ops"})
+	assert.Equal(t, 5*24*time.Hour, limit)
+	assert.Equal(t, "ops", group)
+}
+
+func TestGroupTimeLimits(t *testing.T) {
+
+	origSched := igor.Scheduler
+	t.Cleanup(func() { igor.Scheduler = origSched })
+	igor.Scheduler.MaxReserveTime = 30 * 24 * 60
+
+	seedTimeLimitHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	ml := Group{Name: "ml-team"}
+	ops := Group{Name: "ops"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&ml).Error)
+	require.NoError(t, db.Omit(clause.Associations).Create(&ops).Error)
+
+	policies, err := dbReadHostPolicies(map[string]interface{}{"name": "short"}, db, &logger)
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	require.NoError(t, dbEditHostPolicy(policies, map[string]interface{}{"groupLimits": []GroupTimeLimit{
+		{GroupID: ml.ID, MaxResTime: 14 * 24 * time.Hour},
+		{GroupID: ops.ID, MaxResTime: 2 * 24 * time.Hour},
+	}}, db))
+
+	both := []string{GroupAll, "ml-team", "ops"}
+	ceiling, _, err := getResTimeCeiling([]string{"kn2"}, nil, both, 1, db, &logger)
+	require.NoError(t, err)
+	assert.Equal(t, 14*24*time.Hour, ceiling)
+
+	ceiling, _, _ = getResTimeCeiling([]string{"kn2"}, nil, []string{GroupAll, "ops"}, 1, db, &logger)
+	assert.Equal(t, 2*24*time.Hour, ceiling)
+	ceiling, _, _ = getResTimeCeiling([]string{"kn2"}, nil, nil, 1, db, &logger)
+	assert.Equal(t, 24*time.Hour, ceiling)
+
+	// the other policy's limit still governs a reservation on both hosts
+	ceiling, _, _ = getResTimeCeiling([]string{"kn1", "kn2"}, nil, both, 2, db, &logger)
+	assert.Equal(t, 72*time.Hour, ceiling)
+
+	// setting a limit again replaces it
+	policies, _ = dbReadHostPolicies(map[string]interface{}{"name": "short"}, db, &logger)
+	require.NoError(t, dbEditHostPolicy(policies, map[string]interface{}{"groupLimits": []GroupTimeLimit{
+		{GroupID: ml.ID, MaxResTime: 7 * 24 * time.Hour},
+	}}, db))
+	policies, _ = dbReadHostPolicies(map[string]interface{}{"name": "short"}, db, &logger)
+	assert.Equal(t, map[string]string{"ml-team": "168h0m0s", "ops": "48h0m0s"}, policies[0].groupLimitMap())
+
+	require.NoError(t, dbEditHostPolicy(policies, map[string]interface{}{"removeGroupLimits": []Group{ops}}, db))
+	policies, _ = dbReadHostPolicies(map[string]interface{}{"name": "short"}, db, &logger)
+	assert.Equal(t, map[string]string{"ml-team": "168h0m0s"}, policies[0].groupLimitMap())
+
+	// deleting a group removes its limits
+	require.NoError(t, dbDeleteGroup(&ml, db))
+	var left int64
+	require.NoError(t, db.Model(&GroupTimeLimit{}).Count(&left).Error)
+	assert.Zero(t, left)
+}
+
+func TestChangeRestrictedActions(t *testing.T) {
+
+	policy := HostPolicy{Name: "gpu"}
+	assert.False(t, policy.restrictsAction(NodeActionReimage))
+
+	policy.changeRestrictedActions([]string{NodeActionReimage, NodeActionConsole}, nil)
+	assert.Equal(t, "console,reimage", policy.RestrictedActions)
+	assert.True(t, policy.restrictsAction(NodeActionReimage))
+	assert.False(t, policy.restrictsAction(NodeActionPower))
+
+	policy.changeRestrictedActions(nil, []string{NodeActionConsole})
+	assert.Equal(t, []string{NodeActionReimage}, policy.restrictedActions())
+
+	policy.changeRestrictedActions(nil, []string{NodeActionReimage})
+	assert.Empty(t, policy.RestrictedActions)
+	assert.Nil(t, policy.restrictedActions())
+}
+
+func TestCheckNodeAction(t *testing.T) {
+
+	useElevateMap(t)
+
+	policy := HostPolicy{Name: "gpu", RestrictedActions: NodeActionReimage}
+	hosts := []Host{
+		{Name: "kn1", HostName: "kn1", HostPolicy: policy},
+		{Name: "kn2", HostName: "kn2", HostPolicy: policy},
+	}
+
+	// the reservation's group holds the node action permission for its hosts
+	nodePerm, err := NewPermission(makeNodeActionPerm(hosts))
+	require.NoError(t, err)
+	member := &User{Name: "bob"}
+	authInfo := &UserAuthInfo{Permissions: []Permission{*nodePerm}}
+
+	status, err := checkNodeAction(member, authInfo, NodeActionPower, hosts)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	// an admin restricted reimage on the hosts' policy
+	status, err = checkNodeAction(member, authInfo, NodeActionReimage, hosts)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, common.ErrElevateRequired, errorCodeOf(err))
+
+	// the permission doesn't reach hosts outside the reservation
+	status, err = checkNodeAction(member, authInfo, NodeActionPower, []Host{{Name: "kn3", HostName: "kn3"}})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// an elevated admin isn't held back by the policy
+	admin := &User{Name: "root-admin"}
+	wildcard, _ := NewPermission(PermWildcardToken)
+	igor.ElevateMap.Put(admin.Name, true)
+	defer igor.ElevateMap.Remove(admin.Name)
+	_, err = checkNodeAction(admin, &UserAuthInfo{Permissions: []Permission{*wildcard}}, NodeActionReimage, hosts)
+	assert.NoError(t, err)
+}
+
+func TestDeleteHostPolicy(t *testing.T) {
+
+	hosts := seedTimeLimitHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+	require.NoError(t, db.Omit(clause.Associations).Create(&HostPolicy{Name: DefaultPolicyName, MaxResTime: 24 * time.Hour}).Error)
+	require.NoError(t, db.Omit(clause.Associations).Create(&HostPolicy{Name: "unused", MaxResTime: 24 * time.Hour}).Error)
+	r := httptest.NewRequest(http.MethodDelete, "/", nil)
+
+	policyOf := func(name string) string {
+		var h Host
+		require.NoError(t, db.Preload("HostPolicy").Where("name = ?", name).First(&h).Error)
+		return h.HostPolicy.Name
+	}
+
+	// no hosts attached
+	moved, status, err := doDeleteHostPolicy("unused", "", r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, moved)
+
+	// some hosts attached must be moved somewhere
+	_, status, err = doDeleteHostPolicy("short", "", r)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.ErrorContains(t, err, "kn2")
+	_, status, _ = doDeleteHostPolicy("short", "short", r)
+	assert.Equal(t, http.StatusBadRequest, status)
+	_, status, _ = doDeleteHostPolicy("short", "nope", r)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "short", policyOf("kn2"), "a failed reassignment leaves the hosts alone")
+
+	moved, status, err = doDeleteHostPolicy("short", DefaultPolicyName, r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"kn2"}, moved)
+	assert.Equal(t, DefaultPolicyName, policyOf("kn2"))
+	assert.Equal(t, "long", policyOf("kn1"))
+
+	// all hosts attached
+	policies, err := dbReadHostPolicies(map[string]interface{}{"name": "long"}, db, &logger)
+	require.NoError(t, err)
+	require.NoError(t, dbApplyPolicy(&policies[0], hosts, db))
+	moved, _, err = doDeleteHostPolicy("long", DefaultPolicyName, r)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"kn1", "kn2"}, moved)
+	assert.Equal(t, DefaultPolicyName, policyOf("kn1"))
+	assert.Equal(t, DefaultPolicyName, policyOf("kn2"))
+
+	_, status, _ = doDeleteHostPolicy(DefaultPolicyName, "", r)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestHostPolicyExclusions(t *testing.T) {
+
+	origSched := igor.Scheduler
+	t.Cleanup(func() { igor.Scheduler = origSched })
+	igor.Scheduler.MaxReserveTime = 30 * 24 * 60
+
+	seedTimeLimitHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	contractors := Group{Name: "contractors"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&contractors).Error)
+
+	policies, err := dbReadHostPolicies(map[string]interface{}{"name": "long"}, db, &logger)
+	require.NoError(t, err)
+	require.NoError(t, dbEditHostPolicy(policies, map[string]interface{}{"addExcludedGroups": []Group{contractors}}, db))
+	policies, _ = dbReadHostPolicies(map[string]interface{}{"name": "long"}, db, &logger)
+	assert.Equal(t, []string{"contractors"}, groupNamesOfGroups(policies[0].ExcludedGroups))
+	assert.Equal(t, []string{GroupAll}, groupNamesOfGroups(policies[0].AccessGroups))
+
+	start := time.Now()
+	end := start.Add(time.Hour)
+	staff := []string{GroupAll}
+	contractor := []string{GroupAll, "contractors"}
+
+	// the policy is open to all, but not to members of the excluded group
+	_, err = dbCheckHostPolicyConflicts([]string{"kn1"}, staff, staff, false, start, end, end, &logger)
+	assert.NoError(t, err)
+	status, err := dbCheckHostPolicyConflicts([]string{"kn1", "kn2"}, contractor, contractor, false, start, end, end, &logger)
+	assert.Equal(t, http.StatusConflict, status)
+	var hpcErr *HostPolicyConflictError
+	require.ErrorAs(t, err, &hpcErr)
+	assert.True(t, hpcErr.groupConflict)
+	assert.Equal(t, []string{"kn1"}, namesOfHosts(hpcErr.conflictHosts))
+	assert.ErrorContains(t, err, "members of group 'contractors'")
+
+	// elevated users are excluded the same as anyone
+	_, err = dbCheckHostPolicyConflicts([]string{"kn1"}, contractor, contractor, true, start, end, end, &logger)
+	assert.Error(t, err)
+
+	// excluded hosts aren't picked when scheduling by count
+	hosts, _, err := dbGetAccessibleHosts(staff, staff, false, start, end, 2, db, &logger)
+	require.NoError(t, err)
+	assert.Contains(t, hosts, "long")
+	hosts, _, err = dbGetAccessibleHosts(staff, contractor, false, start, end, 1, db, &logger)
+	require.NoError(t, err)
+	assert.NotContains(t, hosts, "long")
+	assert.Equal(t, []string{"kn2"}, namesOfHosts(hosts["short"]))
+	_, status, _ = dbGetAccessibleHosts(staff, contractor, false, start, end, 2, db, &logger)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// the time limit of an excluded policy doesn't apply when picking by count
+	ceiling, _, _ := getResTimeCeiling(nil, staff, contractor, 1, db, &logger)
+	assert.Equal(t, 24*time.Hour, ceiling)
+
+	require.NoError(t, dbEditHostPolicy(policies, map[string]interface{}{"removeExcludedGroups": []Group{contractors}}, db))
+	_, err = dbCheckHostPolicyConflicts([]string{"kn1"}, contractor, contractor, false, start, end, end, &logger)
+	assert.NoError(t, err)
+
+	// deleting a group removes its exclusions
+	policies, _ = dbReadHostPolicies(map[string]interface{}{"name": "long"}, db, &logger)
+	require.NoError(t, dbEditHostPolicy(policies, map[string]interface{}{"addExcludedGroups": []Group{contractors}}, db))
+	require.NoError(t, dbDeleteGroup(&contractors, db))
+	policies, _ = dbReadHostPolicies(map[string]interface{}{"name": "long"}, db, &logger)
+	assert.Empty(t, policies[0].ExcludedGroups)
+}
+
+func TestPolicyUserCaps(t *testing.T) {
+
+	hosts := seedTimeLimitHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	// both hosts under one policy that lets a user hold one of them at a time
+	var long HostPolicy
+	require.NoError(t, db.Where("name = ?", "long").First(&long).Error)
+	require.NoError(t, db.Model(&long).Update("max_per_user", 1).Error)
+	require.NoError(t, db.Model(&hosts[1]).Update("host_policy_id", long.ID).Error)
+	hosts[1].HostPolicyID = long.ID
+
+	held := newStartTestRes(t, db, "held", []Host{hosts[0]}, false, 0)
+	alice := &held.Owner
+
+	// alice is at the cap, so another of the policy's hosts is refused
+	status, err := dbCheckPolicyUserCaps(alice, []Host{hosts[1]}, 0, db)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, common.ErrPolicyUserCap, errorCodeOf(err))
+	assert.Contains(t, err.Error(), "host policy 'long' allows each user at most 1 of its hosts")
+	assert.Contains(t, err.Error(), "alice already holds 1 of them")
+
+	// a host alice already holds doesn't add to the count, and alice's own reservation can be extended
+	_, err = dbCheckPolicyUserCaps(alice, []Host{hosts[0]}, 0, db)
+	assert.NoError(t, err)
+	_, err = dbCheckPolicyUserCaps(alice, held.Hosts, held.ID, db)
+	assert.NoError(t, err)
+
+	// raising the cap makes room
+	require.NoError(t, db.Model(&long).Update("max_per_user", 2).Error)
+	_, err = dbCheckPolicyUserCaps(alice, []Host{hosts[1]}, 0, db)
+	assert.NoError(t, err)
+
+	// once the held reservation is over its host no longer counts
+	require.NoError(t, db.Model(&long).Update("max_per_user", 1).Error)
+	require.NoError(t, db.Model(held).Update("end", time.Now().Add(-time.Minute)).Error)
+	_, err = dbCheckPolicyUserCaps(alice, []Host{hosts[1]}, 0, db)
+	assert.NoError(t, err)
+}
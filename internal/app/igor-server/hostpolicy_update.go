@@ -53,7 +53,7 @@ func parseHostPolicyEditParams(editParams map[string]interface{}, clog *zl.Logge
 		if err != nil {
 			return nil, http.StatusInternalServerError, err
 		} else if len(existing) > 0 {
-			return nil, http.StatusConflict, fmt.Errorf("host policy '%s' already exists", val)
+			return nil, http.StatusConflict, newCodedError(common.ErrNameTaken, "host policy '%s' already exists", val)
 		} else {
 			changes["name"] = val
 		}
@@ -65,6 +65,11 @@ func parseHostPolicyEditParams(editParams map[string]interface{}, clog *zl.Logge
 		changes["maxResTime"] = dur
 	}
 
+	// determine change to the per-user host cap
+	if val, ok := editParams["maxPerUser"].(float64); ok {
+		changes["maxPerUser"] = int(val)
+	}
+
 	// determine changes to removeGroup
 	if val, ok := editParams["removeGroups"].([]interface{}); ok {
 		var rGroupNames []string
@@ -101,6 +106,60 @@ func parseHostPolicyEditParams(editParams map[string]interface{}, clog *zl.Logge
 		changes["addGroups"] = groupToAdd
 	}
 
+	// determine changes to excluded groups, which can't be ones every user or only one user is in
+	if val, ok := editParams["addExcludedGroups"].([]interface{}); ok {
+		groups, status, err := getExcludedGroups(val)
+		if err != nil {
+			return nil, status, err
+		}
+		changes["addExcludedGroups"] = groups
+	}
+
+	if val, ok := editParams["removeExcludedGroups"].([]interface{}); ok {
+		var names []string
+		for _, n := range val {
+			names = append(names, n.(string))
+		}
+		groups, status, err := getGroupsTx(names, true)
+		if err != nil {
+			return nil, status, err
+		}
+		changes["removeExcludedGroups"] = groups
+	}
+
+	// determine changes to group time limits, which can't be set on groups every user or only one user is in
+	if val, ok := editParams["groupLimits"].(map[string]interface{}); ok {
+		var names []string
+		for name := range val {
+			if name == GroupAdmins || name == GroupAll || strings.HasPrefix(name, GroupUserPrefix) {
+				return nil, http.StatusConflict, fmt.Errorf("group not allowed to have a time limit: %v", name)
+			}
+			names = append(names, name)
+		}
+		groups, status, err := getGroupsTx(names, true)
+		if err != nil {
+			return nil, status, err
+		}
+		var limits []GroupTimeLimit
+		for _, g := range groups {
+			dur, _ := common.ParseDuration(val[g.Name].(string))
+			limits = append(limits, GroupTimeLimit{GroupID: g.ID, MaxResTime: dur})
+		}
+		changes["groupLimits"] = limits
+	}
+
+	if val, ok := editParams["removeGroupLimits"].([]interface{}); ok {
+		var names []string
+		for _, n := range val {
+			names = append(names, n.(string))
+		}
+		groups, status, err := getGroupsTx(names, true)
+		if err != nil {
+			return nil, status, err
+		}
+		changes["removeGroupLimits"] = groups
+	}
+
 	// determine changes to addNotAvailable
 	sbAdd := ScheduleBlockArray{}
 	sbaList, ok := editParams["addNotAvailable"].([]interface{})
@@ -123,5 +182,36 @@ func parseHostPolicyEditParams(editParams map[string]interface{}, clog *zl.Logge
 		changes["removeNotAvailable"] = sbRemove
 	}
 
+	// determine changes to the node actions restricted to admins
+	for _, key := range []string{"restrictActions", "allowActions"} {
+		if val, ok := editParams[key].([]interface{}); ok {
+			actions := make([]string, 0, len(val))
+			for _, a := range val {
+				actions = append(actions, a.(string))
+			}
+			changes[key] = actions
+		}
+	}
+
 	return changes, http.StatusOK, nil
 }
+
+// getExcludedGroups looks up the named groups to exclude from a host policy. The all and admins groups
+// and user private groups can't be excluded.
+func getExcludedGroups(names []interface{}) ([]Group, int, error) {
+	var groupNames []string
+	for _, n := range names {
+		nm := n.(string)
+		if nm == GroupAdmins || nm == GroupAll || strings.HasPrefix(nm, GroupUserPrefix) {
+			return nil, http.StatusConflict, fmt.Errorf("group not allowed as excluded group: %v", nm)
+		}
+		groupNames = append(groupNames, nm)
+	}
+	groups, status, err := getGroupsTx(groupNames, true)
+	if err != nil {
+		return nil, status, err
+	} else if len(groups) != len(groupNames) {
+		return nil, http.StatusNotFound, fmt.Errorf("requested group(s) to exclude not found")
+	}
+	return groups, http.StatusOK, nil
+}
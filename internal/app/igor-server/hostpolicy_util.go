@@ -4,6 +4,8 @@
 
 package igorserver
 
+import "igor2/internal/pkg/naming"
+
 // hostPolicyIDsOfHostPolicies returns a list of HostPolicy IDs from
 // the provided list of host policies.
 func hostPolicyIDsOfHostPolicies(policies []HostPolicy) []int {
@@ -26,11 +28,7 @@ func hostPolicyNamesOfHostPolicies(policies []HostPolicy) []string {
 
 // checkHostPolicyNameRules validates the host policy name
 func checkHostPolicyNameRules(ref string) error {
-	err := checkGenericNameRules(ref)
-	if err != nil {
-		return err
-	}
-	return nil
+	return naming.Check(naming.Policy, ref)
 }
 
 func getHostPoliciesFromHostNames(hostNames []string) ([]HostPolicy, error) {
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
 )
 
 // Igor holds globals
@@ -60,32 +61,52 @@ func (i *Igor) getServerConfig() interface{} {
 	return igorConfig
 }
 
-func (i *Igor) getServerSettings() interface{} {
+// serverSettings are the server configuration settings that are useful to users.
+type serverSettings struct {
+	LocalAuthEnabled       bool  `json:"localAuthEnabled"`
+	OidcEnabled            bool  `json:"oidcEnabled"`
+	CanUploadImages        bool  `json:"canUploadImages"`
+	VlanEnabled            bool  `json:"vlanEnabled"`
+	VlanRangeMin           int   `json:"vlanRangeMin"`
+	VlanRangeMax           int   `json:"vlanRangeMax"`
+	NodeReservationLimit   int   `json:"nodeReservationLimit"`
+	MaxScheduleDays        int   `json:"maxScheduleDays"`
+	MinReserveMinutes      int64 `json:"minReserveMinutes"`
+	MaxReserveMinutes      int64 `json:"maxReserveMinutes"`
+	DefaultReserveMinutes  int64 `json:"defaultReserveMinutes"`
+	HostMaintenanceMinutes int   `json:"hostMaintenanceMinutes"`
+	// RequireReservationGroup is true if new reservations must be given a group other than the owner's own
+	RequireReservationGroup bool `json:"requireReservationGroup"`
+	MaxBodyMB               int  `json:"maxBodyMB"`
+	MaxUploadMB             int  `json:"maxUploadMB"`
+	// BodyLimitsMB are the body size limits of routes that don't use the ones above
+	BodyLimitsMB map[string]int `json:"bodyLimitsMB,omitempty"`
+	// NameRules are the rules for resource names keyed by kind of resource
+	NameRules map[string]naming.Rule `json:"nameRules"`
+	// NodeTimeLimit is only included when settings are requested for a set of hosts
+	NodeTimeLimit *common.NodeTimeLimitData `json:"nodeTimeLimit,omitempty"`
+}
 
-	igorSettings := struct {
-		LocalAuthEnabled       bool  `json:"localAuthEnabled"`
-		CanUploadImages        bool  `json:"canUploadImages"`
-		VlanEnabled            bool  `json:"vlanEnabled"`
-		VlanRangeMin           int   `json:"vlanRangeMin"`
-		VlanRangeMax           int   `json:"vlanRangeMax"`
-		NodeReservationLimit   int   `json:"nodeReservationLimit"`
-		MaxScheduleDays        int   `json:"maxScheduleDays"`
-		MinReserveMinutes      int64 `json:"minReserveMinutes"`
-		MaxReserveMinutes      int64 `json:"maxReserveMinutes"`
-		DefaultReserveMinutes  int64 `json:"defaultReserveMinutes"`
-		HostMaintenanceMinutes int   `json:"hostMaintenanceMinutes"`
-	}{
-		LocalAuthEnabled:       i.localAuthEnabled(),
-		CanUploadImages:        i.Server.AllowImageUpload,
-		VlanEnabled:            i.vlanEnabled(),
-		VlanRangeMin:           i.Vlan.RangeMin,
-		VlanRangeMax:           i.Vlan.RangeMax,
-		NodeReservationLimit:   i.Scheduler.NodeReserveLimit,
-		MaxScheduleDays:        i.Scheduler.MaxScheduleDays,
-		MinReserveMinutes:      i.Scheduler.MinReserveTime,
-		MaxReserveMinutes:      i.Scheduler.MaxReserveTime,
-		DefaultReserveMinutes:  i.Scheduler.DefaultReserveTime,
-		HostMaintenanceMinutes: igor.Maintenance.HostMaintenanceDuration,
+func (i *Igor) getServerSettings() *serverSettings {
+
+	igorSettings := &serverSettings{
+		LocalAuthEnabled:        i.localAuthEnabled(),
+		OidcEnabled:             i.Auth.Scheme == "oidc",
+		CanUploadImages:         i.Server.AllowImageUpload,
+		VlanEnabled:             i.vlanEnabled(),
+		VlanRangeMin:            i.Vlan.RangeMin,
+		VlanRangeMax:            i.Vlan.RangeMax,
+		NodeReservationLimit:    i.Scheduler.NodeReserveLimit,
+		MaxScheduleDays:         i.Scheduler.MaxScheduleDays,
+		MinReserveMinutes:       i.Scheduler.MinReserveTime,
+		MaxReserveMinutes:       i.Scheduler.MaxReserveTime,
+		DefaultReserveMinutes:   i.Scheduler.DefaultReserveTime,
+		HostMaintenanceMinutes:  igor.Maintenance.HostMaintenanceDuration,
+		RequireReservationGroup: i.Scheduler.RequireReservationGroup,
+		MaxBodyMB:               i.Server.MaxBodyMB,
+		MaxUploadMB:             i.Server.MaxUploadMB,
+		BodyLimitsMB:            i.Server.BodyLimitsMB,
+		NameRules:               naming.Rules(),
 	}
 
 	return igorSettings
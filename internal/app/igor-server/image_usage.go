@@ -0,0 +1,174 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// dbRecordImageUse counts a use of the profile and its distro by a reservation being created or
+// reimaged. It must be called inside the same transaction as the reservation change so a failed
+// change doesn't count. A temporary profile that hasn't been saved only counts toward its distro.
+func dbRecordImageUse(profile *Profile, now time.Time, tx *gorm.DB) error {
+
+	usage := map[string]interface{}{"use_count": gorm.Expr("use_count + 1"), "last_used": now}
+
+	if profile.ID != 0 {
+		if result := tx.Model(&Profile{}).Where("id = ?", profile.ID).UpdateColumns(usage); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	distroID := profile.Distro.ID
+	if distroID == 0 {
+		distroID = profile.DistroID
+	}
+	if distroID != 0 {
+		if result := tx.Model(&Distro{}).Where("id = ?", distroID).UpdateColumns(usage); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	return nil
+}
+
+// imageUsageData returns the usage stats to send with a distro or profile, or nil if the user isn't
+// allowed to see them. Only admins and the owner can.
+func imageUsageData(ownerID, useCount int, lastUsed time.Time, user *User) *common.ImageUsageData {
+	if user == nil || (user.ID != ownerID && !userElevated(user.Name)) {
+		return nil
+	}
+	usage := &common.ImageUsageData{UseCount: useCount}
+	if !lastUsed.IsZero() {
+		usage.LastUsed = lastUsed.Unix()
+	}
+	return usage
+}
+
+// filterUnusedDistros returns the distros that no reservation has used since the cutoff. A distro
+// still counts as used if an existing reservation boots it or a profile made since the cutoff
+// points to it, since either means someone may be about to use it again.
+func filterUnusedDistros(distros []Distro, cutoff time.Time, tx *gorm.DB) ([]Distro, error) {
+
+	var inUse []int
+	if result := tx.Table("reservations").Distinct("profiles.distro_id").
+		Joins("JOIN profiles ON profiles.id = reservations.profile_id").Pluck("profiles.distro_id", &inUse); result.Error != nil {
+		return nil, result.Error
+	}
+	var recentProfiles []int
+	if result := tx.Model(&Profile{}).Distinct("distro_id").Where("created_at >= ?", cutoff).
+		Pluck("distro_id", &recentProfiles); result.Error != nil {
+		return nil, result.Error
+	}
+
+	used := make(map[int]bool, len(inUse)+len(recentProfiles))
+	for _, id := range append(inUse, recentProfiles...) {
+		used[id] = true
+	}
+
+	var unused []Distro
+	for _, d := range distros {
+		if !used[d.ID] && d.LastUsed.Before(cutoff) {
+			unused = append(unused, d)
+		}
+	}
+	return unused, nil
+}
+
+// imageStoreUsage adds up the size of the image files of each distro in the image store. Files
+// that are missing don't count.
+func imageStoreUsage(distros []Distro) common.ImageStoreUsage {
+
+	usage := common.ImageStoreUsage{ByDistro: make(map[string]int64, len(distros))}
+	imageBytes := map[string]int64{}
+
+	for _, d := range distros {
+		size, counted := imageBytes[d.DistroImage.ImageID]
+		if !counted {
+			imagePath := filepath.Join(igor.TFTPPath, igor.ImageStoreDir, d.DistroImage.ImageID)
+			for name := range d.DistroImage.imageFiles() {
+				if fi, err := os.Stat(filepath.Join(imagePath, name)); err == nil {
+					size += fi.Size()
+				}
+			}
+			imageBytes[d.DistroImage.ImageID] = size
+			usage.TotalBytes += size
+		}
+		usage.ByDistro[d.Name] = size
+	}
+
+	return usage
+}
+
+// backfillImageUsage sets the usage stats of distros and profiles that have never been counted from
+// the reservation history, so stats are useful right after they are added to an existing igor.
+// Reservations that were created or reimaged with a distro or profile count as uses.
+func backfillImageUsage(tx *gorm.DB) error {
+
+	type histUse struct {
+		Name     string
+		Owner    string
+		UseCount int
+		LastUsed string
+	}
+
+	useStatus := "(status = ? OR status LIKE ?)"
+	reimaged := HrUpdated + ":reimage%"
+
+	var distroUses []histUse
+	if result := tx.Table("history_records").
+		Select("distro AS name, COUNT(*) AS use_count, MAX(created_at) AS last_used").
+		Where(useStatus+" AND distro != ''", HrCreated, reimaged).Group("distro").Scan(&distroUses); result.Error != nil {
+		return result.Error
+	}
+	for _, u := range distroUses {
+		lastUsed, err := parseHistTime(u.LastUsed)
+		if err != nil {
+			return err
+		}
+		if result := tx.Model(&Distro{}).Where("name = ? AND use_count = 0", u.Name).
+			UpdateColumns(map[string]interface{}{"use_count": u.UseCount, "last_used": lastUsed}); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	// profile names are only unique per owner
+	var profileUses []histUse
+	if result := tx.Table("history_records").
+		Select("profile AS name, owner, COUNT(*) AS use_count, MAX(created_at) AS last_used").
+		Where(useStatus+" AND profile != ''", HrCreated, reimaged).Group("profile, owner").Scan(&profileUses); result.Error != nil {
+		return result.Error
+	}
+	for _, u := range profileUses {
+		lastUsed, err := parseHistTime(u.LastUsed)
+		if err != nil {
+			return err
+		}
+		if result := tx.Model(&Profile{}).Where("name = ? AND use_count = 0 AND owner_id IN (?)", u.Name,
+			tx.Model(&User{}).Select("id").Where("name = ?", u.Owner)).
+			UpdateColumns(map[string]interface{}{"use_count": u.UseCount, "last_used": lastUsed}); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	return nil
+}
+
+// parseHistTime parses a timestamp aggregated from the history records, which sqlite returns as
+// text instead of a time.
+func parseHistTime(ts string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999-07:00", time.RFC3339Nano} {
+		if t, err := time.Parse(layout, ts); err == nil {
+			return t, nil
+		}
+	}
+	return time.Parse("2006-01-02 15:04:05.999999999", ts)
+}
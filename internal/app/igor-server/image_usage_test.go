@@ -0,0 +1,165 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// newImageUsageTestDb adds two distros owned by alice, the first with a profile, to a test db.
+func newImageUsageTestDb(t *testing.T) (*gorm.DB, User, []Distro, Profile) {
+
+	seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	alice := User{Name: "alice", Email: "alice@example.com"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&alice).Error)
+	distros := []Distro{{Name: "rhel", OwnerID: alice.ID}, {Name: "ubuntu", OwnerID: alice.ID}}
+	for i := range distros {
+		require.NoError(t, db.Omit(clause.Associations).Create(&distros[i]).Error)
+	}
+	profile := Profile{Name: "rhel-prof", OwnerID: alice.ID, DistroID: distros[0].ID}
+	require.NoError(t, db.Omit(clause.Associations).Create(&profile).Error)
+	return db, alice, distros, profile
+}
+
+func TestRecordImageUse(t *testing.T) {
+
+	db, _, distros, profile := newImageUsageTestDb(t)
+	now := time.Now().Truncate(time.Second)
+
+	require.NoError(t, dbRecordImageUse(&profile, now, db))
+	require.NoError(t, dbRecordImageUse(&profile, now.Add(time.Minute), db))
+	// an unsaved temp profile only counts toward its distro
+	require.NoError(t, dbRecordImageUse(&Profile{Distro: distros[1]}, now, db))
+
+	var stored Profile
+	require.NoError(t, db.First(&stored, profile.ID).Error)
+	assert.Equal(t, 2, stored.UseCount)
+	assert.True(t, stored.LastUsed.Equal(now.Add(time.Minute)))
+
+	var storedDistros []Distro
+	require.NoError(t, db.Order("id").Find(&storedDistros).Error)
+	assert.Equal(t, 2, storedDistros[0].UseCount)
+	assert.Equal(t, 1, storedDistros[1].UseCount)
+
+	// a failed transaction doesn't count
+	_ = db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, dbRecordImageUse(&profile, now, tx))
+		return errors.New("create failed")
+	})
+	require.NoError(t, db.First(&stored, profile.ID).Error)
+	assert.Equal(t, 2, stored.UseCount)
+}
+
+func TestImageUsageData(t *testing.T) {
+
+	useElevateMap(t)
+	igor.ElevateMap.Put("admin", true)
+
+	lastUsed := time.Unix(1700000000, 0)
+	assert.Equal(t, int64(1700000000), imageUsageData(1, 3, lastUsed, &User{Base: Base{ID: 1}}).LastUsed)
+	assert.Equal(t, 3, imageUsageData(1, 3, lastUsed, &User{Base: Base{ID: 2}, Name: "admin"}).UseCount)
+	assert.Nil(t, imageUsageData(1, 3, lastUsed, &User{Base: Base{ID: 2}, Name: "bob"}))
+	assert.Zero(t, imageUsageData(1, 0, time.Time{}, &User{Base: Base{ID: 1}}).LastUsed)
+}
+
+func TestFilterUnusedDistros(t *testing.T) {
+
+	db, alice, distros, _ := newImageUsageTestDb(t)
+	now := time.Now()
+	cutoff := now.Add(-180 * 24 * time.Hour)
+
+	// rhel has a profile made just now so it counts as used
+	unused, err := filterUnusedDistros(distros, cutoff, db)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ubuntu"}, distroNamesOfDistros(unused))
+
+	// once the profile is old, rhel is unused until a reservation uses it
+	require.NoError(t, db.Model(&Profile{}).Where("name = ?", "rhel-prof").Update("created_at", now.Add(-365*24*time.Hour)).Error)
+	unused, err = filterUnusedDistros(distros, cutoff, db)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"rhel", "ubuntu"}, distroNamesOfDistros(unused))
+
+	distros[1].LastUsed = now.Add(-24 * time.Hour)
+	var profile Profile
+	require.NoError(t, db.Where("name = ?", "rhel-prof").First(&profile).Error)
+	res := Reservation{Name: "r1", OwnerID: alice.ID, ProfileID: profile.ID, Start: now, End: now.Add(time.Hour)}
+	require.NoError(t, db.Omit(clause.Associations).Create(&res).Error)
+	unused, err = filterUnusedDistros(distros, cutoff, db)
+	require.NoError(t, err)
+	assert.Empty(t, unused)
+}
+
+func TestBackfillImageUsage(t *testing.T) {
+
+	db, _, _, profile := newImageUsageTestDb(t)
+	first := time.Date(2024, 1, 10, 8, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 3, 2, 17, 30, 0, 0, time.UTC)
+
+	records := []HistoryRecord{
+		{Hash: "h1", Name: "r1", Status: HrCreated, Owner: "alice", Profile: "rhel-prof", Distro: "rhel"},
+		{Hash: "h1", Name: "r1", Status: HrInstalled, Owner: "alice", Profile: "rhel-prof", Distro: "rhel"},
+		{Hash: "h1", Name: "r1", Status: HrUpdated + ":reimage by alice", Owner: "alice", Profile: "rhel-prof", Distro: "rhel"},
+		{Hash: "h2", Name: "r2", Status: HrCreated, Owner: "bob", Profile: "rhel-prof", Distro: "rhel"},
+	}
+	for i := range records {
+		records[i].CreatedAt = first
+	}
+	records[2].CreatedAt = last
+	require.NoError(t, db.Create(&records).Error)
+
+	// ubuntu has already been counted so it's left alone
+	require.NoError(t, db.Model(&Distro{}).Where("name = ?", "ubuntu").Update("use_count", 5).Error)
+
+	require.NoError(t, backfillImageUsage(db))
+
+	var distros []Distro
+	require.NoError(t, db.Order("id").Find(&distros).Error)
+	assert.Equal(t, 3, distros[0].UseCount)
+	assert.True(t, distros[0].LastUsed.Equal(last))
+	assert.Equal(t, 5, distros[1].UseCount)
+	assert.True(t, distros[1].LastUsed.IsZero())
+
+	// bob's record is for a profile of the same name bob owned so it doesn't count toward alice's
+	var stored Profile
+	require.NoError(t, db.First(&stored, profile.ID).Error)
+	assert.Equal(t, 2, stored.UseCount)
+	assert.True(t, stored.LastUsed.Equal(last))
+}
+
+func TestImageStoreUsage(t *testing.T) {
+
+	origTFTPPath, origStoreDir := igor.TFTPPath, igor.ImageStoreDir
+	defer func() { igor.TFTPPath, igor.ImageStoreDir = origTFTPPath, origStoreDir }()
+	igor.TFTPPath = t.TempDir()
+	igor.ImageStoreDir = "igor_images"
+
+	image := DistroImage{ImageID: "abc123", Type: DistroKI, Kernel: "vmlinuz", Initrd: "initrd.img"}
+	imagePath := filepath.Join(igor.TFTPPath, igor.ImageStoreDir, image.ImageID)
+	require.NoError(t, os.MkdirAll(imagePath, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(imagePath, image.Kernel), []byte("kernel"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(imagePath, image.Initrd), []byte("init"), 0644))
+
+	missing := DistroImage{ImageID: "def456", Type: DistroKI, Kernel: "vmlinuz", Initrd: "initrd.img"}
+
+	usage := imageStoreUsage([]Distro{
+		{Name: "rhel", DistroImage: image},
+		{Name: "rhel-copy", DistroImage: image},
+		{Name: "gone", DistroImage: missing},
+	})
+	assert.Equal(t, map[string]int64{"rhel": 10, "rhel-copy": 10, "gone": 0}, usage.ByDistro)
+	// the shared image is only counted once
+	assert.Equal(t, int64(10), usage.TotalBytes)
+}
@@ -4,11 +4,36 @@
 
 package igorserver
 
+import (
+	"fmt"
+	"strings"
+
+	"igor2/internal/pkg/common"
+)
+
 // IResInstaller is an interface that provides the mechanism for installing and uninstalling reservation OS images on cluster nodes.
 type IResInstaller interface {
-	// Install activates a reservation
+	// Install activates a reservation. Failures on individual hosts should be
+	// reported with a *HostInstallError so they can be tracked (and retried) per host.
 	Install(*Reservation) error
 
 	// Uninstall deactivates a reservation
 	Uninstall(*Reservation) error
 }
+
+// HostInstallError is returned by an installer when the reservation could not be installed on one
+// or more of its hosts. HostErrors maps the name of each failed host to the reason it failed.
+type HostInstallError struct {
+	HostErrors map[string]error
+}
+
+func (e *HostInstallError) Error() string {
+	hostNames := make([]string, 0, len(e.HostErrors))
+	// the same problem usually affects every failed host, so only report distinct reasons
+	reasons := common.NewSet()
+	for name, err := range e.HostErrors {
+		hostNames = append(hostNames, name)
+		reasons.Add(err.Error())
+	}
+	return fmt.Sprintf("install failed on host(s) %s: %s", common.UnsplitList(hostNames), strings.Join(reasons.Elements(), "; "))
+}
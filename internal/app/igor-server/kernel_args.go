@@ -0,0 +1,150 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog/hlog"
+)
+
+// MaxKernelArgsLength is the longest kernel command line igor will write to a PXE config. PXELINUX
+// and GRUB both cut the line off a little above this, and the install adds its own arguments.
+const MaxKernelArgsLength = 2048
+
+// kernelArgsBadQuotes are quotes word processors and wikis swap in for plain ones. Bootloaders pass
+// them through as part of the argument so the kernel never sees a quoted value.
+var kernelArgsBadQuotes = "‘’‚‛“”„‟«»‹›′″"
+
+// checkKernelArgs makes sure kernel args can be written to a boot config as given. Newlines are
+// allowed since they are collapsed when stored, but other control characters and non-ASCII quotes
+// are rejected with the position of the first one found.
+func checkKernelArgs(args string) error {
+	pos := 0
+	for _, c := range args {
+		pos++
+		switch {
+		case c == '\n' || c == '\r' || c == '\t':
+			continue
+		case strings.ContainsRune(kernelArgsBadQuotes, c):
+			return fmt.Errorf("kernel args contain the non-ASCII quote %q at position %d; use a plain ' or \" instead", c, pos)
+		case unicode.IsControl(c):
+			return fmt.Errorf("kernel args contain the control character %U at position %d", c, pos)
+		}
+	}
+	if n := len(normalizeKernelArgs(args)); n > MaxKernelArgsLength {
+		return fmt.Errorf("kernel args are %d characters, the limit is %d", n, MaxKernelArgsLength)
+	}
+	return nil
+}
+
+// bannedKernelArgKeys and bannedKernelArgPatterns hold the kernelArgs.bannedKeys and
+// kernelArgs.bannedPatterns config settings.
+var (
+	bannedKernelArgKeys     = map[string]bool{}
+	bannedKernelArgPatterns []*regexp.Regexp
+)
+
+// setBannedKernelArgs sets the kernel arg keys and patterns that can't be used. It returns an error
+// naming the first pattern that is not a valid regular expression.
+func setBannedKernelArgs(keys, patterns []string) error {
+	bannedKeys := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		bannedKeys[k] = true
+	}
+	var bannedPatterns []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("bad pattern '%s': %v", p, err)
+		}
+		bannedPatterns = append(bannedPatterns, re)
+	}
+	bannedKernelArgKeys, bannedKernelArgPatterns = bannedKeys, bannedPatterns
+	return nil
+}
+
+// bannedKernelArg returns the first arg in args whose key is banned or that matches a banned
+// pattern, or an empty string if none are.
+func bannedKernelArg(args string) string {
+	for _, a := range strings.Fields(args) {
+		if bannedKernelArgKeys[strings.SplitN(a, "=", 2)[0]] {
+			return a
+		}
+		for _, re := range bannedKernelArgPatterns {
+			if re.MatchString(a) {
+				return a
+			}
+		}
+	}
+	return ""
+}
+
+// checkRequestKernelArgs is the check for kernel args given in any request that sets them. Along with
+// the checks of checkKernelArgs it refuses args banned by the server unless the user making the
+// request is elevated, in which case the override is logged.
+func checkRequestKernelArgs(args string, r *http.Request) error {
+	if err := checkKernelArgs(args); err != nil {
+		return err
+	}
+	banned := bannedKernelArg(args)
+	if banned == "" {
+		return nil
+	}
+	if user := getUserFromContext(r); user != nil && userElevated(user.Name) {
+		hlog.FromRequest(r).Warn().Msgf("user '%s' is invoking admin privileges to use the banned kernel arg '%s'", user.Name, banned)
+		return nil
+	}
+	return fmt.Errorf("the kernel arg '%s' is not allowed on this server", banned)
+}
+
+// normalizeKernelArgs collapses line breaks and runs of whitespace in kernel args to single spaces
+// so args pasted across several lines end up on the one line the bootloader reads.
+func normalizeKernelArgs(args string) string {
+	return strings.Join(strings.Fields(args), " ")
+}
+
+// mergeKernelArgs joins kernel args in the order they are given, which is the order they appear on
+// the boot line, skipping any that are empty.
+func mergeKernelArgs(args ...string) string {
+	var parts []string
+	for _, a := range args {
+		if a = normalizeKernelArgs(a); a != "" {
+			parts = append(parts, a)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// withoutKernelArgKeys drops the args from args that set a key also set in override, so override wins
+// when the two are merged. The key of an arg is the part before any '='.
+func withoutKernelArgKeys(args, override string) string {
+	keys := map[string]bool{}
+	for _, a := range strings.Fields(override) {
+		keys[strings.SplitN(a, "=", 2)[0]] = true
+	}
+	var kept []string
+	for _, a := range strings.Fields(args) {
+		if !keys[strings.SplitN(a, "=", 2)[0]] {
+			kept = append(kept, a)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// checkMergedKernelArgs makes sure the boot line built from distro and profile kernel args is
+// within the length limit, since each can pass on its own while the two together do not.
+func checkMergedKernelArgs(distroArgs, profileArgs string) (string, error) {
+	line := mergeKernelArgs(distroArgs, profileArgs)
+	if len(line) > MaxKernelArgsLength {
+		return line, fmt.Errorf("the distro and profile kernel args together are %d characters, the limit is %d",
+			len(line), MaxKernelArgsLength)
+	}
+	return line, nil
+}
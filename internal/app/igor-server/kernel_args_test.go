@@ -0,0 +1,150 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+)
+
+func TestCheckKernelArgs(t *testing.T) {
+
+	assert.NoError(t, checkKernelArgs(`console=ttyS0,115200 quiet`))
+	assert.NoError(t, checkKernelArgs("console=ttyS0\n  quiet\r\n\tip=dhcp"))
+	assert.NoError(t, checkKernelArgs(`init="/bin/sh -c 'echo hi'"`))
+
+	err := checkKernelArgs("quiet root=“/dev/sda1”")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "position 12")
+	}
+	err = checkKernelArgs("quiet\x00splash")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "U+0000")
+		assert.Contains(t, err.Error(), "position 6")
+	}
+
+	assert.NoError(t, checkKernelArgs(strings.Repeat("a", MaxKernelArgsLength)))
+	assert.Error(t, checkKernelArgs(strings.Repeat("a", MaxKernelArgsLength+1)))
+	// collapsed whitespace doesn't count toward the limit
+	assert.NoError(t, checkKernelArgs(strings.Repeat("a", MaxKernelArgsLength)+"\n\n"))
+}
+
+func TestMergeKernelArgs(t *testing.T) {
+
+	assert.Equal(t, "console=ttyS0 quiet ip=dhcp", normalizeKernelArgs("  console=ttyS0\n quiet\r\n\tip=dhcp \n"))
+	assert.Equal(t, "a=1 b=2", mergeKernelArgs("a=1", "", "b=2\n"))
+	assert.Equal(t, "", mergeKernelArgs("", " \n"))
+
+	r := Reservation{Profile: Profile{KernelArgs: "b=2", Distro: Distro{KernelArgs: "a=1\n"}}}
+	assert.Equal(t, "a=1 b=2", r.getKernelArgs())
+
+	line, err := checkMergedKernelArgs(strings.Repeat("a", MaxKernelArgsLength/2), strings.Repeat("b", MaxKernelArgsLength/2))
+	assert.Error(t, err)
+	assert.Len(t, line, MaxKernelArgsLength+1)
+	_, err = checkMergedKernelArgs("a=1", "b=2")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "debug", withoutKernelArgKeys("console=ttyS0,115200 debug", "console=tty1 quiet"))
+	assert.Equal(t, "a=1 b", withoutKernelArgKeys("a=1 b", ""))
+	assert.Equal(t, "", withoutKernelArgKeys("b=1", "b"))
+}
+
+func TestBannedKernelArgs(t *testing.T) {
+
+	useElevateMap(t)
+	t.Cleanup(func() { _ = setBannedKernelArgs(nil, nil) })
+	assert.Error(t, setBannedKernelArgs(nil, []string{"(init"}))
+	require.NoError(t, setBannedKernelArgs([]string{"systemd.debug-shell"}, []string{"^init="}))
+
+	assert.Empty(t, bannedKernelArg("console=ttyS0 quiet systemd.debug-shell-not"))
+	assert.Equal(t, "systemd.debug-shell=1", bannedKernelArg("quiet systemd.debug-shell=1"))
+	assert.Equal(t, "systemd.debug-shell", bannedKernelArg("systemd.debug-shell"))
+	assert.Equal(t, "init=/bin/sh", bannedKernelArg("quiet\ninit=/bin/sh"))
+	assert.Empty(t, bannedKernelArg("rdinit=/bin/sh"))
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	alice := r.WithContext(context.WithValue(r.Context(), userContextKey{}, &User{Name: "alice"}))
+	err := checkRequestKernelArgs("quiet init=/bin/sh", alice)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "'init=/bin/sh'")
+	}
+	assert.NoError(t, checkRequestKernelArgs("quiet", alice))
+	assert.Error(t, checkRequestKernelArgs("quiet\x00", alice), "the usual checks still apply")
+
+	admin := r.WithContext(context.WithValue(r.Context(), userContextKey{}, &User{Name: IgorAdmin}))
+	assert.NoError(t, checkRequestKernelArgs("quiet init=/bin/sh", admin))
+}
+
+func TestCreateDefaultKernelArgs(t *testing.T) {
+
+	origSched, origSchedMinutes, origNotify := igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn
+	t.Cleanup(func() {
+		igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn = origSched, origSchedMinutes, origNotify
+	})
+	notifyOff := false
+	igor.Email.ResNotifyOn = &notifyOff
+	igor.Scheduler.MinReserveTime = 30
+	igor.Scheduler.DefaultReserveTime = 60
+	igor.Scheduler.MaxReserveTime = 30 * 24 * 60
+	igor.Scheduler.NodeReserveLimit = 0
+	MaxScheduleMinutes = 45 * 24 * 60
+	setReimageTestRange(t)
+
+	seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	pug := Group{Name: GroupUserPrefix + "alice", IsUserPrivate: true}
+	require.NoError(t, db.Omit(clause.Associations).Create(&pug).Error)
+	alice := User{Name: "alice", Email: "alice@example.com", Groups: []Group{pug, all}, DefaultKernelArgs: "console=ttyS0,115200 debug"}
+	require.NoError(t, db.Omit("Groups.*").Create(&alice).Error)
+	distro := Distro{Name: "centos", Groups: []Group{all}, KernelArgs: "quiet"}
+	require.NoError(t, db.Omit("Groups.*").Create(&distro).Error)
+	myProf := Profile{Name: "myprof", OwnerID: alice.ID, DistroID: distro.ID, KernelArgs: "nomodeset"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&myProf).Error)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, &alice))
+	create := func(params map[string]interface{}, startIn time.Duration) (*Reservation, string) {
+		params["nodeList"] = "kn1"
+		params["duration"] = "1h"
+		params["start"] = float64(time.Now().Add(startIn).Unix())
+		_, _, msg, status, err := doCreateReservation(params, r)
+		require.NoError(t, err, params["name"])
+		require.Equal(t, http.StatusCreated, status)
+		rList, err := dbReadReservationsTx(map[string]interface{}{"name": params["name"]}, nil)
+		require.NoError(t, err)
+		require.Len(t, rList, 1)
+		return &rList[0], msg
+	}
+
+	// the defaults go after the distro args, and a key given for the reservation wins
+	res, msg := create(map[string]interface{}{"name": "with-kargs", "distro": "centos", "kernelArgs": "console=tty1"}, time.Hour)
+	assert.Equal(t, "quiet debug console=tty1", res.getKernelArgs())
+	assert.Contains(t, msg, "kernel args debug (default of alice)")
+
+	res, msg = create(map[string]interface{}{"name": "no-kargs", "distro": "centos", "noDefaultKernelArgs": true}, 3*time.Hour)
+	assert.Equal(t, "quiet", res.getKernelArgs())
+	assert.NotContains(t, msg, "kernel args")
+
+	// a saved profile is copied to a temp profile so the defaults don't change it
+	res, msg = create(map[string]interface{}{"name": "from-prof", "profile": "myprof"}, 5*time.Hour)
+	assert.Equal(t, "quiet nomodeset console=ttyS0,115200 debug", res.getKernelArgs())
+	assert.True(t, res.Profile.IsDefault)
+	assert.Contains(t, msg, "kernel args console=ttyS0,115200 debug (default of alice)")
+	var stored Profile
+	require.NoError(t, db.First(&stored, myProf.ID).Error)
+	assert.Equal(t, "nomodeset", stored.KernelArgs)
+}
@@ -11,6 +11,8 @@ import (
 	"os"
 	"regexp"
 	"slices"
+	"strings"
+	"time"
 )
 
 func syncPreCheck() error {
@@ -52,7 +54,7 @@ func executeLdapUserSync() {
 		logger.Error().Msgf("%v", err)
 		return
 	} else {
-		if err = syncLdapUsers(conn); err != nil {
+		if _, err = syncLdapUsers(conn, true); err != nil {
 			logger.Error().Msgf("%v", err)
 		}
 	}
@@ -68,7 +70,7 @@ func executeLdapGroupSync() {
 			logger.Error().Msgf("%v", siErr)
 			return
 		}
-		if err = syncLdapGroups(conn, groups, users); err != nil {
+		if _, err = syncLdapGroups(conn, groups, users, true); err != nil {
 			logger.Error().Msgf("%v", err)
 		}
 	}
@@ -182,11 +184,13 @@ func createLdapSyncGroup(conn *ldap.Conn, group *Group) (members []User, owners
 	ldapGroupOwners := common.NewSet()
 	for i := 1; i < len(groupSearchAttributes); i++ {
 		for _, val := range result.Entries[0].GetAttributeValues(groupSearchAttributes[i]) {
-			ldapGroupOwners.Add(uid.FindStringSubmatch(val)[1])
+			if m := uid.FindStringSubmatch(val); m != nil {
+				ldapGroupOwners.Add(m[1])
+			}
 		}
 	}
 
-	if ldapGroupOwners.Size() == 0 {
+	if ldapGroupOwners.Size() == 0 && ldapOwnerSyncEnabled() {
 		err = fmt.Errorf("%s failed - unable to find an owner for group '%s' in LDAP search results", actionPrefix, group.Name)
 		return
 	}
@@ -206,6 +210,7 @@ func createLdapSyncGroup(conn *ldap.Conn, group *Group) (members []User, owners
 	if len(owners) == 0 {
 		ia, _, _ := getIgorAdminTx()
 		owners = append(owners, *ia)
+		members = append(members, *ia)
 	}
 
 	return
@@ -231,9 +236,14 @@ func ldapGroupSyncInfo() ([]Group, []User, error) {
 	return ldapGroupList, igorUsers, nil
 }
 
-func syncLdapGroups(conn *ldap.Conn, ldapGroupList []Group, igorUsers []User) (err error) {
+// syncLdapGroups brings the members of each LDAP-synced igor group in line with its LDAP group. If
+// groupOwnerAttributes are configured the group's owners are also synced, and LDAP owners without an
+// igor account have one created for them. If apply is false nothing is changed and the report shows
+// what the sync would do. The report only includes groups that have changes or errors.
+func syncLdapGroups(conn *ldap.Conn, ldapGroupList []Group, igorUsers []User, apply bool) (report map[string]common.LdapGroupSyncData, err error) {
 	actionPrefix := "LDAP group sync"
 	defer conn.Close()
+	report = make(map[string]common.LdapGroupSyncData)
 	if len(ldapGroupList) == 0 {
 		logger.Warn().Msgf("%s - enabled but no LDAP groups are being tracked by igor - sync aborted", actionPrefix)
 		return
@@ -244,6 +254,7 @@ func syncLdapGroups(conn *ldap.Conn, ldapGroupList []Group, igorUsers []User) (e
 	gcConf := igor.Auth.Ldap.Sync
 	groupSearchAttributes := []string{gcConf.UserListAttribute}
 	groupSearchAttributes = append(groupSearchAttributes, gcConf.GroupOwnerAttributes...)
+	syncOwners := ldapOwnerSyncEnabled()
 	uid := regexp.MustCompile(`uid=(\w+),`)
 
 	for _, group := range ldapGroupList {
@@ -258,21 +269,23 @@ func syncLdapGroups(conn *ldap.Conn, ldapGroupList []Group, igorUsers []User) (e
 		if searchErr != nil {
 			err = fmt.Errorf("%s failed - problem retrieving LDAP search result - %v", actionPrefix, searchErr)
 			logger.Error().Msgf("%v", err)
+			report[group.Name] = common.LdapGroupSyncData{Error: err.Error()}
 			continue
 		}
 
 		if len(result.Entries) < 1 {
 			err = fmt.Errorf("%s failed - no entries returned from LDAP server for given group name '%s'", actionPrefix, group.Name)
 			logger.Error().Msgf("%v", err)
+			report[group.Name] = common.LdapGroupSyncData{Error: err.Error()}
 			continue
 		}
 
 		// get the list of group members
-		ldapGroupMembers := common.NewSet()
-		ldapGroupMembers.Add(result.Entries[0].GetAttributeValues(groupSearchAttributes[0])...)
-		if ldapGroupMembers.Size() == 0 {
-			err = fmt.Errorf("%s failed - group retrieved from LDAP but contained no members - aborted", actionPrefix)
+		ldapGroupMembers := result.Entries[0].GetAttributeValues(groupSearchAttributes[0])
+		if len(ldapGroupMembers) == 0 {
+			err = fmt.Errorf("%s failed - group '%s' retrieved from LDAP but contained no members - aborted", actionPrefix, group.Name)
 			logger.Error().Msgf("%v", err)
+			report[group.Name] = common.LdapGroupSyncData{Error: err.Error()}
 			continue
 		}
 
@@ -280,95 +293,171 @@ func syncLdapGroups(conn *ldap.Conn, ldapGroupList []Group, igorUsers []User) (e
 		ldapGroupOwners := common.NewSet()
 		for i := 1; i < len(groupSearchAttributes); i++ {
 			for _, val := range result.Entries[0].GetAttributeValues(groupSearchAttributes[i]) {
-				ldapGroupOwners.Add(uid.FindStringSubmatch(val)[1])
+				if m := uid.FindStringSubmatch(val); m != nil {
+					ldapGroupOwners.Add(m[1])
+				}
 			}
 		}
 
-		requiresUpdate := false
-		var addOwners, rmvOwners []string
-		groupOwners := usernamesFromNames(igorUsers, ldapGroupOwners.Elements())
-		currOwners := userNamesOfUsers(group.Owners)
-
-		slices.Sort(currOwners)
-		slices.Sort(groupOwners)
-		if !slices.Equal(currOwners, groupOwners) {
-			requiresUpdate = true
-			addOwners = usernameDiff(currOwners, groupOwners)
-			rmvOwners = usernameDiff(groupOwners, currOwners)
-			slices.Sort(rmvOwners)
-		}
-
-		var addMembers, rmvMembers []string
-		ldapGroupMembers.Add(ldapGroupOwners.Elements()...) // owners are members if in Igor but may not be according to LDAP
-		groupMembers := usernamesFromNames(igorUsers, ldapGroupMembers.Elements())
-		currMembers := userNamesOfUsers(group.Members)
-
-		slices.Sort(currMembers)
-		slices.Sort(groupMembers)
-		if !slices.Equal(currMembers, groupMembers) {
-			requiresUpdate = true
-			addMembers = usernameDiff(currMembers, groupMembers)
-			rmvMembers = usernameDiff(groupMembers, currMembers)
-		}
-
-		// don't change anything if igor-admin is involved
-		if slices.Contains(rmvOwners, IgorAdmin) && len(addOwners) == 0 {
-			rmvOwners = nil
-			if len(rmvMembers) == 1 && rmvMembers[0] == IgorAdmin {
-				rmvMembers = nil
-			} else if len(rmvMembers) > 1 {
-				if i := slices.Index(rmvMembers, IgorAdmin); i != -1 {
-					rmvMembers = append(rmvMembers[:i], rmvMembers[i+1:]...)
+		// owners need an igor account to own the group, so make one the same way the user sync does
+		var newAccounts []string
+		if syncOwners {
+			for _, name := range filterNonUsers(igorUsers, ldapGroupOwners.Elements()) {
+				if !apply {
+					newAccounts = append(newAccounts, name)
+					continue
+				}
+				if user, cuErr := createLdapUser(conn, name); cuErr != nil {
+					logger.Error().Msgf("%s - %v", actionPrefix, cuErr)
+				} else if user != nil {
+					newAccounts = append(newAccounts, user.Name)
+					igorUsers = append(igorUsers, *user)
 				}
 			}
-		} else if len(addOwners) == 0 && len(rmvOwners) > 0 && slices.Equal(rmvOwners, currOwners) && !slices.Contains(rmvOwners, IgorAdmin) {
-			// if removing all the group owners who aren't igor-admin and no replacements, igor-admin should take ownership
-			addOwners = append(addOwners, IgorAdmin)
+			slices.Sort(newAccounts)
 		}
 
-		if requiresUpdate {
+		userNames := append(userNamesOfUsers(igorUsers), newAccounts...)
+		plan := planLdapGroupSync(&group, ldapGroupMembers, ldapGroupOwners.Elements(), userNames, syncOwners)
+		plan.NewAccounts = newAccounts
 
-			changes := make(map[string]interface{}, 4)
+		if plan.OwnerFallback && len(plan.AddOwners) > 0 {
+			logger.Warn().Msgf("%s - none of the LDAP owners of group '%s' are igor users - igor-admin will own the group", actionPrefix, group.Name)
+		}
 
-			if len(addMembers) > 0 {
-				members := usersFromNames(igorUsers, addMembers)
-				if len(members) > 0 {
-					changes["add"] = members
-				}
-			}
-			if len(addOwners) > 0 {
-				owners := usersFromNames(igorUsers, addOwners)
-				if len(owners) > 0 {
-					changes["addOwners"] = owners
-				}
-			}
-			if len(rmvMembers) > 0 {
-				changes["remove"] = usersFromNames(group.Members, rmvMembers)
-			}
-			if len(rmvOwners) > 0 {
-				changes["rmvOwners"] = usersFromNames(group.Owners, rmvOwners)
+		if len(plan.AddMembers) == 0 && len(plan.RmvMembers) == 0 && len(plan.AddOwners) == 0 && len(plan.RmvOwners) == 0 {
+			if len(plan.NewAccounts) > 0 {
+				report[group.Name] = plan
 			}
+			continue
+		}
+		report[group.Name] = plan
 
-			// possible that after filtering non-igor users or igor-admin we end up with no changes
-			if len(changes) == 0 {
-				continue
-			}
+		if !apply {
+			continue
+		}
 
-			if guErr := performDbTx(func(tx *gorm.DB) error {
-				logger.Debug().Msgf("performing group update on '%s'", group.Name)
-				return dbEditGroup(&group, changes, tx)
-			}); guErr != nil {
-				err = fmt.Errorf("problem performing group update - %w", guErr)
-				logger.Error().Msgf("%v", err)
-				continue
+		if plan.OwnerFallback && !slices.Contains(userNamesOfUsers(igorUsers), IgorAdmin) {
+			if ia, _, iaErr := getIgorAdminTx(); iaErr == nil {
+				igorUsers = append(igorUsers, *ia)
 			}
 		}
+
+		changes := make(map[string]interface{}, 4)
+		addOwners := usersFromNames(igorUsers, plan.AddOwners)
+		rmvOwners := usersFromNames(group.Owners, plan.RmvOwners)
+		if members := usersFromNames(igorUsers, plan.AddMembers); len(members) > 0 {
+			changes["add"] = members
+		}
+		if len(addOwners) > 0 {
+			changes["addOwners"] = addOwners
+		}
+		if len(plan.RmvMembers) > 0 {
+			changes["remove"] = usersFromNames(group.Members, plan.RmvMembers)
+		}
+		if len(rmvOwners) > 0 {
+			changes["rmvOwners"] = rmvOwners
+		}
+
+		if guErr := performDbTx(func(tx *gorm.DB) error {
+			logger.Debug().Msgf("performing group update on '%s'", group.Name)
+			return dbEditGroup(&group, changes, tx)
+		}); guErr != nil {
+			err = fmt.Errorf("problem performing group update - %w", guErr)
+			logger.Error().Msgf("%v", err)
+			plan.Error = err.Error()
+			report[group.Name] = plan
+			continue
+		}
+
+		if len(addOwners) > 0 || len(rmvOwners) > 0 {
+			logger.Info().Msgf("%s - owners of group '%s' changed: added %v, removed %v", actionPrefix, group.Name, plan.AddOwners, plan.RmvOwners)
+			notifyLdapGroupOwnerChanges(group.Name, addOwners, rmvOwners)
+		}
 	}
 
 	return
 }
 
-func syncLdapUsers(conn *ldap.Conn) error {
+// planLdapGroupSync works out the changes needed to make the group match its LDAP group. Only names in
+// userNames can be added to the group. Owners are always members. If syncOwners is false the group's
+// owners are left as they are, otherwise they are replaced by the LDAP owners. The group is never left
+// without an owner; if none of the LDAP owners can own it igor-admin does.
+func planLdapGroupSync(group *Group, ldapMembers, ldapOwners, userNames []string, syncOwners bool) common.LdapGroupSyncData {
+
+	var plan common.LdapGroupSyncData
+	known := common.NewSet()
+	known.Add(userNames...)
+
+	currOwners := userNamesOfUsers(group.Owners)
+	wantOwners := currOwners
+	if syncOwners {
+		wantOwners = nil
+		for _, o := range ldapOwners {
+			if known.Contains(o) && !slices.Contains(wantOwners, o) {
+				wantOwners = append(wantOwners, o)
+			}
+		}
+		if len(wantOwners) == 0 {
+			wantOwners = []string{IgorAdmin}
+			plan.OwnerFallback = true
+		}
+	}
+
+	// owners are members if in igor but may not be according to LDAP
+	wantMembers := append([]string{}, wantOwners...)
+	for _, m := range ldapMembers {
+		if known.Contains(m) && !slices.Contains(wantMembers, m) {
+			wantMembers = append(wantMembers, m)
+		}
+	}
+	currMembers := userNamesOfUsers(group.Members)
+
+	plan.AddOwners = sortedNames(usernameDiff(currOwners, wantOwners))
+	plan.RmvOwners = sortedNames(usernameDiff(wantOwners, currOwners))
+	plan.AddMembers = sortedNames(usernameDiff(currMembers, wantMembers))
+	plan.RmvMembers = sortedNames(usernameDiff(wantMembers, currMembers))
+	return plan
+}
+
+// ldapOwnerSyncEnabled reports whether the LDAP group sync also manages the owners of synced groups.
+func ldapOwnerSyncEnabled() bool {
+	return len(igor.Auth.Ldap.Sync.GroupOwnerAttributes) > 0
+}
+
+// notifyLdapGroupOwnerChanges sends the same owner change emails as when an owner edits the group.
+func notifyLdapGroupOwnerChanges(groupName string, addOwners, rmvOwners []User) {
+
+	gList, gErr := dbReadGroupsTx(map[string]interface{}{"name": groupName, "showMembers": true}, true)
+	if gErr != nil || len(gList) == 0 {
+		logger.Error().Msgf("unable to read group '%s' to send owner change emails - %v", groupName, gErr)
+		return
+	}
+	group := &gList[0]
+
+	for _, o := range addOwners {
+		if grpEvent := makeGroupNotifyEvent(EmailGroupAddOwner, group, &o, o.Name); grpEvent != nil {
+			groupNotifyChan <- *grpEvent
+		}
+	}
+	for _, o := range rmvOwners {
+		if grpEvent := makeGroupNotifyEvent(EmailGroupRmvOwner, group, &o, o.Name); grpEvent != nil {
+			groupNotifyChan <- *grpEvent
+		}
+	}
+}
+
+func sortedNames(names []string) []string {
+	slices.Sort(names)
+	return names
+}
+
+// syncLdapUsers brings igor's user accounts in line with the members of the LDAP user sync groups. Accounts
+// are made for new LDAP users and removed for users no longer in LDAP, except that a user who owns
+// reservations shared with a group is first held in pending removal for the configured grace period so
+// the group's members can claim them. If apply is false nothing is changed and the report shows what the
+// sync would do.
+func syncLdapUsers(conn *ldap.Conn, apply bool) (*common.LdapUserSyncData, error) {
 	actionPrefix := "LDAP user account sync"
 	defer conn.Close()
 
@@ -377,15 +466,6 @@ func syncLdapUsers(conn *ldap.Conn) error {
 	gcConf := igor.Auth.Ldap.Sync
 	groupSearchAttribute := []string{gcConf.UserListAttribute}
 
-	// build member_attributes if present
-	var memberAttributes []string
-	if gcConf.UserEmailAttribute != "" {
-		memberAttributes = append(memberAttributes, gcConf.UserEmailAttribute)
-	}
-	if gcConf.UserDisplayNameAttribute != "" {
-		memberAttributes = append(memberAttributes, gcConf.UserDisplayNameAttribute)
-	}
-
 	userList := common.NewSet()
 
 	for _, groupFilter := range gcConf.GroupFilters {
@@ -410,152 +490,254 @@ func syncLdapUsers(conn *ldap.Conn) error {
 	}
 
 	if userList.Size() == 0 {
-		return fmt.Errorf("%s failed - no user account names returned given group filters", actionPrefix)
+		return nil, fmt.Errorf("%s failed - no user account names returned given group filters", actionPrefix)
 	}
 
 	// get all Igor users except igor-admin
 	igorUsers, ruErr := dbReadUsersTx(map[string]interface{}{"exclude-admin": true})
 	if ruErr != nil {
-		return fmt.Errorf("%s failed - %w", actionPrefix, ruErr)
+		return nil, fmt.Errorf("%s failed - %w", actionPrefix, ruErr)
 	}
 
-	currLdapUserList := usernamesFromNames(igorUsers, userList.Elements())
-	currIgorUserList := userNamesOfUsers(igorUsers)
+	// the group sync makes accounts for owners of synced groups, so keep them even if they aren't in
+	// any of the user sync groups
+	if igor.Auth.Ldap.Sync.EnableGroupSync && ldapOwnerSyncEnabled() {
+		ldapGroups, rgErr := dbReadGroupsTx(map[string]interface{}{"is_ldap": true, "showMembers": true}, true)
+		if rgErr != nil {
+			return nil, fmt.Errorf("%s failed - %w", actionPrefix, rgErr)
+		}
+		for _, g := range ldapGroups {
+			userList.Add(userNamesOfUsers(g.Owners)...)
+		}
+	}
+
+	claimable, rcErr := claimableResOwners()
+	if rcErr != nil {
+		return nil, fmt.Errorf("%s failed - %w", actionPrefix, rcErr)
+	}
 
-	slices.Sort(currLdapUserList)
-	slices.Sort(currIgorUserList)
-	if !slices.Equal(currLdapUserList, currIgorUserList) {
-		removedUsernames := usernameDiff(currLdapUserList, currIgorUserList)
-		_ = removeSyncedUsers(usersFromNames(igorUsers, removedUsernames))
+	now := time.Now()
+	plan := planLdapUserSync(igorUsers, userList.Elements(), claimable, gcConf.RemovalGraceDays, now)
+	if !apply {
+		return &plan, nil
 	}
 
-	// filter out non-members so we can register them
-	newIgorUsers := filterNonUsers(igorUsers, userList.Elements())
+	if len(plan.Remove) > 0 {
+		_ = removeSyncedUsers(usersFromNames(igorUsers, plan.Remove))
+	}
+	if len(plan.PendingRemoval) > 0 {
+		until := now.AddDate(0, 0, plan.GraceDays)
+		for _, u := range usersFromNames(igorUsers, plan.PendingRemoval) {
+			if err := startPendingRemoval(&u, until); err != nil {
+				logger.Error().Msgf("%s - problem marking user '%s' pending removal - %v", actionPrefix, u.Name, err)
+			}
+		}
+	}
+	for _, u := range usersFromNames(igorUsers, plan.Restored) {
+		if err := performDbTx(func(tx *gorm.DB) error {
+			return dbEditUser(&u, map[string]interface{}{"pending_removal": time.Time{}}, tx)
+		}); err != nil {
+			logger.Error().Msgf("%s - problem restoring user '%s' pending removal - %v", actionPrefix, u.Name, err)
+		} else {
+			logger.Info().Msgf("user '%s' is back in LDAP and no longer pending removal", u.Name)
+		}
+	}
 
 	// stop if no new members need to be registered
-	if len(newIgorUsers) == 0 {
+	if len(plan.NewAccounts) == 0 {
 		logger.Debug().Msgf("no new users to create")
-		return nil
+		return &plan, nil
 	}
 
 	// register each new member
-	for _, member := range newIgorUsers {
-		userFilter := fmt.Sprintf("(uid=%s)", member)
-		userResult, srErr := conn.Search(&ldap.SearchRequest{
-			BaseDN:     baseDN,
-			Scope:      ldap.ScopeWholeSubtree,
-			Filter:     userFilter,
-			Attributes: memberAttributes,
-		})
+	for _, member := range plan.NewAccounts {
+		if _, err := createLdapUser(conn, member); err != nil {
+			return &plan, fmt.Errorf("%s failed - %v", actionPrefix, err)
+		}
+	}
+
+	return &plan, nil
+}
 
-		if srErr != nil {
-			logger.Warn().Msgf("%s failed - search for user '%s' in LDAP - %v", actionPrefix, member, srErr)
+// planLdapUserSync works out the account changes needed to make igor's users match ldapNames. A user no
+// longer in LDAP who owns one of the claimable reservations enters pending removal for graceDays, and
+// stays pending until the grace period ends or none of their reservations are left to claim. Other users
+// no longer in LDAP are removed. A negative graceDays turns off pending removal. Users pending removal
+// who are back in LDAP are restored.
+func planLdapUserSync(igorUsers []User, ldapNames, claimableOwners []string, graceDays int, now time.Time) common.LdapUserSyncData {
+
+	plan := common.LdapUserSyncData{GraceDays: graceDays}
+	inLdap := common.NewSet()
+	inLdap.Add(ldapNames...)
+
+	for _, u := range igorUsers {
+		if inLdap.Contains(u.Name) {
+			if u.isPendingRemoval() {
+				plan.Restored = append(plan.Restored, u.Name)
+			}
 			continue
 		}
-		if len(userResult.Entries) == 0 {
-			logger.Warn().Msgf("%s failed - no user '%s' found", actionPrefix, member)
-			continue
+		ownsRes := slices.Contains(claimableOwners, u.Name)
+		switch {
+		case u.isPendingRemoval() && ownsRes && now.Before(u.PendingRemoval):
+			if plan.StillPending == nil {
+				plan.StillPending = make(map[string]int64)
+			}
+			plan.StillPending[u.Name] = u.PendingRemoval.Unix()
+		case !u.isPendingRemoval() && ownsRes && graceDays > 0:
+			plan.PendingRemoval = append(plan.PendingRemoval, u.Name)
+		default:
+			plan.Remove = append(plan.Remove, u.Name)
 		}
+	}
 
-		userInfo := map[string]interface{}{"name": member}
+	plan.NewAccounts = sortedNames(filterNonUsers(igorUsers, ldapNames))
+	plan.Remove = sortedNames(plan.Remove)
+	plan.PendingRemoval = sortedNames(plan.PendingRemoval)
+	plan.Restored = sortedNames(plan.Restored)
+	return plan
+}
 
-		entry := userResult.Entries[0]
-		memberEmail := ""
-		if gcConf.UserEmailAttribute != "" {
-			if len(entry.GetAttributeValues(gcConf.UserEmailAttribute)) > 0 {
-				memberEmail = entry.GetAttributeValues(gcConf.UserEmailAttribute)[0]
-				userInfo["email"] = memberEmail
-			}
+// claimableResOwners returns the names of the owners of reservations that can be claimed by another user
+// if the owner's account is pending removal. These are the reservations made with a group other than the
+// owner's private group.
+func claimableResOwners() ([]string, error) {
+	rList, err := dbReadReservationsTx(map[string]interface{}{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	owners := common.NewSet()
+	for _, r := range rList {
+		if !strings.HasPrefix(r.Group.Name, GroupUserPrefix) {
+			owners.Add(r.Owner.Name)
 		}
-		if memberEmail == "" {
-			memberEmail = fmt.Sprintf("%s@%s", member, igor.Email.DefaultSuffix)
-			userInfo["email"] = memberEmail
+	}
+	return owners.Elements(), nil
+}
+
+// startPendingRemoval marks the account of a user dropped from LDAP to be removed at the given time and
+// invites the members of the groups of the user's reservations to claim them.
+func startPendingRemoval(u *User, until time.Time) error {
+
+	var rList []Reservation
+	if err := performDbTx(func(tx *gorm.DB) error {
+		if err := dbEditUser(u, map[string]interface{}{"pending_removal": until}, tx); err != nil {
+			return err
 		}
-		memberDisplayName := ""
-		if gcConf.UserDisplayNameAttribute != "" {
-			if len(entry.GetAttributeValues(gcConf.UserDisplayNameAttribute)) > 0 {
-				memberDisplayName = entry.GetAttributeValues(gcConf.UserDisplayNameAttribute)[0]
-				if len(memberDisplayName) > 0 {
-					userInfo["fullName"] = memberDisplayName
-				}
-			}
+		var rErr error
+		rList, rErr = dbReadReservations(map[string]interface{}{"owner_id": u.ID}, nil, tx)
+		return rErr
+	}); err != nil {
+		return err
+	}
+	logger.Info().Msgf("user '%s' is no longer in LDAP and is pending removal until %s", u.Name, until.Format(common.DateTimeLogFormat))
+
+	var clusterName string
+	if clusters, cErr := dbReadClustersTx(nil); cErr != nil {
+		logger.Error().Msgf("%v", cErr)
+	} else {
+		clusterName = clusters[0].Name
+	}
+
+	for _, r := range rList {
+		if strings.HasPrefix(r.Group.Name, GroupUserPrefix) {
+			continue
+		}
+		if claimEvent := makeResWarnNotifyEvent(EmailResClaimInvite, 0, r.DeepCopy(), clusterName); claimEvent != nil {
+			claimEvent.Info = until.Format(common.DateTimeEmailFormat)
+			resNotifyChan <- *claimEvent
 		}
+	}
+	return nil
+}
 
-		if user, _, cuErr := doCreateUser(userInfo, nil); cuErr != nil {
-			return fmt.Errorf("failed to create new user '%s' via LDAP sync manager: %v", member, cuErr)
-		} else {
-			logger.Info().Msgf("created new user '%s' via with LDAP sync manager", user.Name)
+// createLdapUser makes an igor account for the named LDAP user, taking the email and display name from
+// the LDAP entry when configured. If the user can't be found in LDAP a warning is logged and no
+// account or error is returned.
+func createLdapUser(conn *ldap.Conn, name string) (*User, error) {
+
+	actionPrefix := "LDAP user account sync"
+	gcConf := igor.Auth.Ldap.Sync
+
+	// build member_attributes if present
+	var memberAttributes []string
+	if gcConf.UserEmailAttribute != "" {
+		memberAttributes = append(memberAttributes, gcConf.UserEmailAttribute)
+	}
+	if gcConf.UserDisplayNameAttribute != "" {
+		memberAttributes = append(memberAttributes, gcConf.UserDisplayNameAttribute)
+	}
+
+	userFilter := fmt.Sprintf("(uid=%s)", name)
+	userResult, srErr := conn.Search(&ldap.SearchRequest{
+		BaseDN:     igor.Auth.Ldap.BaseDN,
+		Scope:      ldap.ScopeWholeSubtree,
+		Filter:     userFilter,
+		Attributes: memberAttributes,
+	})
+
+	if srErr != nil {
+		logger.Warn().Msgf("%s failed - search for user '%s' in LDAP - %v", actionPrefix, name, srErr)
+		return nil, nil
+	}
+	if len(userResult.Entries) == 0 {
+		logger.Warn().Msgf("%s failed - no user '%s' found", actionPrefix, name)
+		return nil, nil
+	}
+
+	userInfo := map[string]interface{}{"name": name}
+
+	entry := userResult.Entries[0]
+	memberEmail := ""
+	if gcConf.UserEmailAttribute != "" {
+		if len(entry.GetAttributeValues(gcConf.UserEmailAttribute)) > 0 {
+			memberEmail = entry.GetAttributeValues(gcConf.UserEmailAttribute)[0]
+			userInfo["email"] = memberEmail
+		}
+	}
+	if memberEmail == "" {
+		memberEmail = fmt.Sprintf("%s@%s", name, igor.Email.DefaultSuffix)
+		userInfo["email"] = memberEmail
+	}
+	if gcConf.UserDisplayNameAttribute != "" {
+		if len(entry.GetAttributeValues(gcConf.UserDisplayNameAttribute)) > 0 {
+			memberDisplayName := entry.GetAttributeValues(gcConf.UserDisplayNameAttribute)[0]
+			if len(memberDisplayName) > 0 {
+				userInfo["fullName"] = memberDisplayName
+			}
 		}
 	}
 
-	return nil
+	user, _, cuErr := doCreateUser(userInfo, nil)
+	if cuErr != nil {
+		return nil, fmt.Errorf("failed to create new user '%s' via LDAP sync manager: %v", name, cuErr)
+	}
+	logger.Info().Msgf("created new user '%s' via with LDAP sync manager", user.Name)
+	return user, nil
 }
 
 func removeSyncedUsers(users []User) (err error) {
 
 	for _, u := range users {
 
+		var moved []common.OwnedResourceData
+
 		if err = performDbTx(func(tx *gorm.DB) error {
 
-			var sendEmailAlert = false
-			ia, _, _ := getIgorAdmin(tx)
-			groupList := u.singleOwnedGroups()
-
-			// when user is the sole owner of a non-pug group, replace them with igor-admin
-			if len(groupList) > 0 {
-				logger.Info().Msgf("Re-assigning single-owned group(s) for auto-removed user '%s' to igor-admin", u.Name)
-				sendEmailAlert = true
-				changes := make(map[string]interface{})
-				for _, g := range groupList {
-					changes["rmvOwners"] = []User{u}
-					changes["addOwners"] = []User{*ia}
-					logger.Info().Msgf("Changing owner of group '%s' to %v", g.Name, IgorAdmin)
-					if rmErr := dbEditGroup(&g, changes, tx); rmErr != nil {
-						logger.Error().Msgf("problem changing group '%s' from auto-removed owner '%s' to igor-admin: %v", g.Name, u.Name, rmErr)
-					}
-					changes = make(map[string]interface{})
-				}
+			ia, _, iaErr := getIgorAdmin(tx)
+			if iaErr != nil {
+				return iaErr
 			}
 
-			searchByOwnerID := map[string]interface{}{"owner_id": u.ID}
-
-			if orList, orErr := dbReadReservations(searchByOwnerID, nil, tx); orErr != nil {
+			// any group, reservation or distro the user alone owns goes to igor-admin
+			owned, orErr := findOwnedResources(&u, tx)
+			if orErr != nil {
 				return orErr // uses default err status
-			} else {
-				// for any reservation they own, change ownership to igor-admin and send email alert
-				if len(orList) > 0 {
-					logger.Info().Msgf("Re-assigning reservation(s) for auto-removed user '%s' to igor-admin", u.Name)
-					sendEmailAlert = true
-					for _, r := range orList {
-						changes := make(map[string]interface{})
-						changes["owner"] = IgorAdmin
-						logger.Info().Msgf("Changing owner of reservation '%s' to %v", r.Name, IgorAdmin)
-						changes, _, _ = parseResEditParams(&r, changes, tx)
-						if editErr := dbEditReservation(&r, changes, tx); editErr != nil {
-							logger.Error().Msgf("problem changing reservation '%s' from auto-removed owner '%s' to igor-admin: %v", r.Name, u.Name, editErr)
-						}
-						changes = make(map[string]interface{})
-					}
-				}
 			}
-
-			if odList, rdErr := dbReadDistros(searchByOwnerID, tx); rdErr != nil {
-				return rdErr // uses default err status
-			} else {
-				// for any distro they own, change ownership to igor-admin and send email alert
-				if len(odList) > 0 {
-					logger.Info().Msgf("Re-assigning distro(s) for auto-removed user '%s' to igor-admin", u.Name)
-					sendEmailAlert = true
-					for _, d := range odList {
-						changes := make(map[string]interface{})
-						changes["owner"] = ia
-						logger.Info().Msgf("Changing owner of distro '%s' to %v", d.Name, changes)
-						if editErr := dbEditDistro(&d, changes, tx); editErr != nil {
-							logger.Error().Msgf("problem changing distro '%s' from auto-removed owner '%s' to igor-admin: %v", d.Name, u.Name, editErr)
-						}
-						changes = make(map[string]interface{})
-					}
-				}
+			sendEmailAlert := len(owned.list("")) > 0
+			if sendEmailAlert {
+				logger.Info().Msgf("re-assigning resources owned by auto-removed user '%s' to %s", u.Name, IgorAdmin)
+				moved, _, _ = transferOwnedResources(&u, ia, owned, false, tx, &logger)
 			}
 
 			if sendEmailAlert {
@@ -567,7 +749,7 @@ func removeSyncedUsers(users []User) (err error) {
 
 			// *** All good! let's start deleting stuff ***
 
-			if opList, opErr := dbReadProfiles(searchByOwnerID, tx); opErr != nil {
+			if opList, opErr := dbReadProfiles(map[string]interface{}{"owner_id": u.ID}, tx); opErr != nil {
 				return opErr // uses default err status
 			} else {
 				for _, p := range opList {
@@ -601,6 +783,7 @@ func removeSyncedUsers(users []User) (err error) {
 			return dbDeleteUser(&u, tx)
 
 		}); err == nil {
+			recordOwnerTransfers(u.Name, moved, &logger)
 			logger.Debug().Msgf("user '%s' deletion complete", u.Name)
 		}
 	}
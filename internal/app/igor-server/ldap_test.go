@@ -0,0 +1,85 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanLdapGroupSync(t *testing.T) {
+
+	users := func(names ...string) []User {
+		var list []User
+		for _, n := range names {
+			list = append(list, User{Name: n})
+		}
+		return list
+	}
+	known := []string{IgorAdmin, "alice", "bob", "carol", "dave"}
+	group := &Group{Name: "g1", Owners: users("alice"), Members: users("alice", "bob")}
+
+	// owner swapped in LDAP, new owner becomes a member even if LDAP doesn't list them
+	plan := planLdapGroupSync(group, []string{"bob", "carol"}, []string{"dave"}, known, true)
+	assert.Equal(t, []string{"dave"}, plan.AddOwners)
+	assert.Equal(t, []string{"alice"}, plan.RmvOwners)
+	assert.Equal(t, []string{"carol", "dave"}, plan.AddMembers)
+	assert.Equal(t, []string{"alice"}, plan.RmvMembers)
+	assert.False(t, plan.OwnerFallback)
+
+	// nothing to do when igor already matches LDAP
+	plan = planLdapGroupSync(group, []string{"bob"}, []string{"alice"}, known, true)
+	assert.Empty(t, plan.AddOwners)
+	assert.Empty(t, plan.RmvOwners)
+	assert.Empty(t, plan.AddMembers)
+	assert.Empty(t, plan.RmvMembers)
+
+	// owners unknown to igor can't own the group so igor-admin does
+	plan = planLdapGroupSync(group, []string{"bob"}, []string{"eve"}, known, true)
+	assert.True(t, plan.OwnerFallback)
+	assert.Equal(t, []string{IgorAdmin}, plan.AddOwners)
+	assert.Equal(t, []string{"alice"}, plan.RmvOwners)
+	assert.Equal(t, []string{IgorAdmin}, plan.AddMembers)
+
+	// without owner sync the current owners stay and remain members
+	plan = planLdapGroupSync(group, []string{"carol"}, []string{"dave"}, known, false)
+	assert.Empty(t, plan.AddOwners)
+	assert.Empty(t, plan.RmvOwners)
+	assert.Equal(t, []string{"carol"}, plan.AddMembers)
+	assert.Equal(t, []string{"bob"}, plan.RmvMembers)
+	assert.False(t, plan.OwnerFallback)
+}
+
+func TestPlanLdapUserSync(t *testing.T) {
+
+	now := time.Now()
+	users := []User{
+		{Name: "alice"},
+		{Name: "bob"},
+		{Name: "carol"},
+		{Name: "dave", PendingRemoval: now.Add(24 * time.Hour)},
+		{Name: "erin", PendingRemoval: now.Add(-time.Hour)},
+		{Name: "frank", PendingRemoval: now.Add(24 * time.Hour)},
+		{Name: "gina", PendingRemoval: now.Add(24 * time.Hour)},
+	}
+	ldapNames := []string{"alice", "gina", "hank"}
+	owners := []string{"bob", "dave", "erin"}
+
+	// bob owns a reservation so is held, carol doesn't so is removed; dave is still in their grace period
+	// but erin's has ended; frank has nothing left to claim and gina is back in LDAP
+	plan := planLdapUserSync(users, ldapNames, owners, 7, now)
+	assert.Equal(t, []string{"hank"}, plan.NewAccounts)
+	assert.Equal(t, []string{"bob"}, plan.PendingRemoval)
+	assert.Equal(t, []string{"carol", "erin", "frank"}, plan.Remove)
+	assert.Equal(t, map[string]int64{"dave": users[3].PendingRemoval.Unix()}, plan.StillPending)
+	assert.Equal(t, []string{"gina"}, plan.Restored)
+
+	// without a grace period users are removed right away
+	plan = planLdapUserSync(users[:3], ldapNames, owners, -1, now)
+	assert.Empty(t, plan.PendingRemoval)
+	assert.Equal(t, []string{"bob", "carol"}, plan.Remove)
+}
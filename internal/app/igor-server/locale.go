@@ -0,0 +1,111 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"igor2/internal/pkg/common"
+)
+
+const (
+	// LocaleISO is the locale preference that renders dates in unambiguous ISO 8601 form
+	LocaleISO = "iso"
+	// LocaleDefault is used when a user has no locale preference or it isn't recognized
+	LocaleDefault = "en-US"
+)
+
+// localeDateFormats holds the date layout used in email for each supported locale. Locales are
+// matched on the full tag first and then on the language alone.
+var localeDateFormats = map[string]string{
+	LocaleISO: "2006-01-02 15:04 MST",
+	"en-US":   common.DateTimeEmailFormat,
+	"en":      "2 January 2006 - 15:04 MST",
+	"de":      "02.01.2006 15:04 MST",
+	"fr":      "02/01/2006 15:04 MST",
+	"es":      "02/01/2006 15:04 MST",
+}
+
+// durationWords are the words used to describe a length of time in a given language.
+type durationWords struct {
+	Day   string
+	Days  string
+	Hour  string
+	Hours string
+	And   string
+}
+
+// localeDurationWords is keyed by language. English is used for any language not listed.
+var localeDurationWords = map[string]durationWords{
+	"en": {"day", "days", "hour", "hours", "and"},
+	"de": {"Tag", "Tage", "Stunde", "Stunden", "und"},
+	"fr": {"jour", "jours", "heure", "heures", "et"},
+	"es": {"día", "días", "hora", "horas", "y"},
+}
+
+// localeLanguage returns the language part of a locale tag, ex. 'en' for 'en-GB'.
+func localeLanguage(locale string) string {
+	lang, _, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	return strings.ToLower(lang)
+}
+
+// normalizeLocale puts a locale tag into the form used as a key in the locale maps, ex. 'en_gb' becomes
+// 'en-GB'.
+func normalizeLocale(locale string) string {
+	if strings.EqualFold(locale, LocaleISO) {
+		return LocaleISO
+	}
+	lang, region, found := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	if !found {
+		return strings.ToLower(lang)
+	}
+	return strings.ToLower(lang) + "-" + strings.ToUpper(region)
+}
+
+// checkLocaleRules returns an error if the locale is not one igor can render dates for.
+func checkLocaleRules(locale string) error {
+	locale = normalizeLocale(locale)
+	if _, ok := localeDateFormats[locale]; ok {
+		return nil
+	}
+	if _, ok := localeDateFormats[localeLanguage(locale)]; ok {
+		return nil
+	}
+	var supported []string
+	for k := range localeDateFormats {
+		supported = append(supported, k)
+	}
+	sort.Strings(supported)
+	return fmt.Errorf("locale '%s' is not supported - use one of %s (a region may be added to a language, ex. en-GB)",
+		locale, strings.Join(supported, ", "))
+}
+
+// localeDateFormat returns the date layout for the given locale, falling back to the default locale.
+func localeDateFormat(locale string) string {
+	locale = normalizeLocale(locale)
+	if f, ok := localeDateFormats[locale]; ok {
+		return f
+	}
+	if f, ok := localeDateFormats[localeLanguage(locale)]; ok {
+		return f
+	}
+	return localeDateFormats[LocaleDefault]
+}
+
+// localeWords returns the duration words for the language of the given locale, falling back to English.
+func localeWords(locale string) durationWords {
+	if w, ok := localeDurationWords[localeLanguage(locale)]; ok {
+		return w
+	}
+	return localeDurationWords["en"]
+}
+
+// formatLocaleDts formats a datetime for email using the date layout of the recipient's locale.
+func formatLocaleDts(locale string, dts time.Time) string {
+	return dts.Format(localeDateFormat(locale))
+}
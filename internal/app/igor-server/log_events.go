@@ -0,0 +1,96 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"sort"
+
+	zl "github.com/rs/zerolog"
+
+	"igor2/internal/pkg/common"
+)
+
+// LogEvent is the stable name of a significant occurrence written to the server log. It is added to
+// the log line as the event field along with typed fields naming what it concerns, so tools reading
+// the log don't have to depend on the wording of the message. Names are never changed or reused once
+// released; an event that is no longer logged is removed from the catalog instead.
+type LogEvent string
+
+const (
+	EvResInstallFailed    LogEvent = "reservation.install.failed"
+	EvPowerCmdFailed      LogEvent = "power.command.failed"
+	EvPowerStatusUnknown  LogEvent = "power.status.unknown"
+	EvAuthLoginFailed     LogEvent = "auth.login.failed"
+	EvHostStateChanged    LogEvent = "host.state.changed"
+	EvEmailSendFailed     LogEvent = "email.send.failed"
+	EvHookFailed          LogEvent = "hook.failed"
+	EvResPowerCycleFailed LogEvent = "reservation.power-cycle.failed"
+)
+
+// Field names used by log events. The error field is the one zerolog uses for Err.
+const (
+	LogFieldEvent       = "event"
+	LogFieldReservation = "reservation"
+	LogFieldHost        = "host"
+	LogFieldUser        = "user"
+	LogFieldError       = "error"
+	LogFieldAction      = "action"
+	LogFieldFrom        = "from"
+	LogFieldTo          = "to"
+	LogFieldSubject     = "subject"
+	LogFieldHook        = "hook"
+)
+
+type logEventSpec struct {
+	level  zl.Level
+	desc   string
+	fields []string
+}
+
+// logEventCatalog declares each log event with the level it is logged at and the fields it carries
+// besides the event name. The diagnostics endpoint lists it for anyone building alerts on the log.
+var logEventCatalog = map[LogEvent]logEventSpec{
+	EvResInstallFailed: {zl.ErrorLevel, "a reservation could not be installed; host is only set when the failure was on one host",
+		[]string{LogFieldReservation, LogFieldHost, LogFieldError}},
+	EvPowerCmdFailed: {zl.ErrorLevel, "the power command for a host failed",
+		[]string{LogFieldHost, LogFieldAction, LogFieldError}},
+	EvPowerStatusUnknown: {zl.WarnLevel, "the power status of a host could not be read after repeated polls",
+		[]string{LogFieldHost, LogFieldError}},
+	EvAuthLoginFailed: {zl.WarnLevel, "a user gave a wrong password or an unknown or disabled account",
+		[]string{LogFieldUser, LogFieldError}},
+	EvHostStateChanged: {zl.InfoLevel, "an admin blocked or unblocked a host",
+		[]string{LogFieldHost, LogFieldFrom, LogFieldTo, LogFieldUser}},
+	EvEmailSendFailed: {zl.ErrorLevel, "no SMTP server accepted an email",
+		[]string{LogFieldSubject, LogFieldError}},
+	EvHookFailed: {zl.ErrorLevel, "an event hook script failed or timed out",
+		[]string{LogFieldHook, LogFieldError}},
+	EvResPowerCycleFailed: {zl.ErrorLevel, "the hosts of a reservation could not be power cycled",
+		[]string{LogFieldReservation, LogFieldError}},
+}
+
+// logEvent starts a log line for the event at its catalog level with the event field set. The caller
+// adds the fields declared for the event and finishes it with the usual human-readable message.
+func logEvent(l *zl.Logger, ev LogEvent) *zl.Event {
+	spec, ok := logEventCatalog[ev]
+	if !ok {
+		panic("log event '" + string(ev) + "' is not in the catalog")
+	}
+	return l.WithLevel(spec.level).Str(LogFieldEvent, string(ev))
+}
+
+// logEventList returns the event catalog sorted by name.
+func logEventList() []common.LogEventData {
+	events := make([]common.LogEventData, 0, len(logEventCatalog))
+	for ev, spec := range logEventCatalog {
+		events = append(events, common.LogEventData{
+			Name:        string(ev),
+			Level:       spec.level.String(),
+			Description: spec.desc,
+			Fields:      append([]string{LogFieldEvent}, spec.fields...),
+		})
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Name < events[j].Name })
+	return events
+}
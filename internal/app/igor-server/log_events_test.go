@@ -0,0 +1,142 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	zl "github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gomail "gopkg.in/mail.v2"
+)
+
+// loggedEvents parses the JSON log lines in buf, returning those that carry the given event.
+func loggedEvents(t *testing.T, buf *bytes.Buffer, ev LogEvent) []map[string]interface{} {
+	var found []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		line := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		if line[LogFieldEvent] == string(ev) {
+			found = append(found, line)
+		}
+	}
+	return found
+}
+
+// assertEventFields checks the logged event is at its catalog level, still has a message and
+// carries the given field values.
+func assertEventFields(t *testing.T, line map[string]interface{}, ev LogEvent, fields map[string]string) {
+	spec := logEventCatalog[ev]
+	assert.Equal(t, spec.level.String(), line[zl.LevelFieldName])
+	assert.NotEmpty(t, line[zl.MessageFieldName])
+	for name, val := range fields {
+		assert.Contains(t, spec.fields, name, "field %s is not declared for %s", name, ev)
+		assert.Equal(t, val, line[name], "field %s of %s", name, ev)
+	}
+}
+
+func TestLogEventCatalog(t *testing.T) {
+
+	nameRegex := regexp.MustCompile(`^[a-z]+(\.[a-z-]+)+$`)
+	for ev, spec := range logEventCatalog {
+		assert.Regexp(t, nameRegex, string(ev))
+		assert.NotEmpty(t, spec.desc, ev)
+		assert.NotEmpty(t, spec.fields, ev)
+		assert.NotContains(t, spec.fields, LogFieldEvent, ev)
+	}
+
+	events := logEventList()
+	require.Len(t, events, len(logEventCatalog))
+	assert.Equal(t, string(EvAuthLoginFailed), events[0].Name)
+	for _, e := range events {
+		assert.Equal(t, LogFieldEvent, e.Fields[0])
+	}
+
+	assert.Panics(t, func() { logEvent(&logger, LogEvent("not.an.event")) })
+}
+
+func TestLogEventsEmitted(t *testing.T) {
+
+	var buf bytes.Buffer
+	testLog := zl.New(&buf)
+	origLogger, origCmds, origEmail, origDial := logger, igor.ExternalCmds, igor.Email, smtpDial
+	origPowerMap, origPollMap, origAuth, origAuthSecondary := powerMap, powerPollMap, igor.AuthBasic, igor.AuthSecondary
+	t.Cleanup(func() {
+		logger, igor.ExternalCmds, igor.Email, smtpDial = origLogger, origCmds, origEmail, origDial
+		powerMap, powerPollMap, igor.AuthBasic, igor.AuthSecondary = origPowerMap, origPollMap, origAuth, origAuthSecondary
+	})
+	logger = testLog
+
+	seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+
+	// a power command that fails for a host
+	if !DEVMODE {
+		igor.ExternalCmds.PowerOff = "false %s"
+		_, err := doPowerHosts(PowerOff, []string{"kn1"}, &testLog)
+		require.Error(t, err)
+		events := loggedEvents(t, &buf, EvPowerCmdFailed)
+		require.Len(t, events, 1)
+		assertEventFields(t, events[0], EvPowerCmdFailed, map[string]string{LogFieldHost: "kn1", LogFieldAction: PowerOff})
+		assert.NotEmpty(t, events[0][LogFieldError])
+	}
+
+	// a host whose power status can't be read
+	on := true
+	igor.ExternalCmds.PowerPollFailures = 1
+	powerMap = map[string]*bool{"kn2": &on}
+	powerPollMap = map[string]*powerPollInfo{"kn2": {}}
+	recordPowerPoll("kn2", false, time.Second, fmt.Errorf("no route to BMC"))
+	events := loggedEvents(t, &buf, EvPowerStatusUnknown)
+	require.Len(t, events, 1)
+	assertEventFields(t, events[0], EvPowerStatusUnknown, map[string]string{LogFieldHost: "kn2", LogFieldError: "no route to BMC"})
+
+	// a host blocked by an admin
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+	r = r.WithContext(context.WithValue(testLog.WithContext(r.Context()), userContextKey{}, &User{Name: IgorAdmin}))
+	_, _, err := doUpdateBlockHosts(true, false, []string{"kn1"}, "bad DIMM", r)
+	require.NoError(t, err)
+	events = loggedEvents(t, &buf, EvHostStateChanged)
+	require.Len(t, events, 1)
+	assertEventFields(t, events[0], EvHostStateChanged, map[string]string{LogFieldHost: "kn1",
+		LogFieldFrom: HostAvailable.String(), LogFieldTo: HostBlocked.String(), LogFieldUser: IgorAdmin})
+	assert.Equal(t, "host blocked", events[0][zl.MessageFieldName])
+
+	// a login with an unknown account
+	igor.AuthBasic = NewBasicAuth()
+	igor.AuthSecondary = nil
+	r = httptest.NewRequest(http.MethodGet, "/login", nil)
+	r = r.WithContext(testLog.WithContext(r.Context()))
+	r.SetBasicAuth("mallory", "guess")
+	w := httptest.NewRecorder()
+	_, _ = doPasswordAuth(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	events = loggedEvents(t, &buf, EvAuthLoginFailed)
+	require.Len(t, events, 1)
+	assertEventFields(t, events[0], EvAuthLoginFailed, map[string]string{LogFieldUser: "mallory"})
+
+	// an email that no server will take
+	igor.Email.SmtpServers = []SmtpServerConfig{{Host: "relay.example.com", Port: 25}}
+	smtpDial = func(*gomail.Dialer) (gomail.SendCloser, error) { return nil, errors.New("connection refused") }
+	tmpl := template.Must(template.New("test").Parse("hello"))
+	require.Error(t, sendEmail(tmpl, "reservation ending", []string{"alice@example.com"}, nil, nil, false, nil))
+	events = loggedEvents(t, &buf, EvEmailSendFailed)
+	require.Len(t, events, 1)
+	assertEventFields(t, events[0], EvEmailSendFailed, map[string]string{LogFieldSubject: "reservation ending"})
+	assert.Contains(t, events[0][LogFieldError], "connection refused")
+}
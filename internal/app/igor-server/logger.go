@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"igor2/internal/pkg/common"
@@ -39,6 +40,7 @@ var (
 	// logger is our zerolog logging instance. Its level is controlled from the server configuration YAML file.
 	logger           zl.Logger
 	loggerInited     bool
+	recentLogs       = &logRing{size: maxRecentLogLines}
 	zlRequestHandler = hlog.AccessHandler(func(r *http.Request, status, size int, duration time.Duration) {
 
 		user := "-"
@@ -170,6 +172,9 @@ func initLog() {
 		writers = append(writers, consoleOut)
 	}
 
+	// Keep the most recent warnings and errors in memory for the admin support bundle
+	writers = append(writers, &warnLevelWriter{w: newConsoleWriter(recentLogs, true)})
+
 	// Add syslog if specified in config
 	var syslogLevelWriter zl.LevelWriter
 	var syslogErr error
@@ -273,8 +278,9 @@ func newConsoleWriter(w io.Writer, noColor bool) zl.ConsoleWriter {
 	consoleOut.FormatFieldName = func(i interface{}) string {
 		return fmt.Sprintf("%s:", i)
 	}
+	// field values are written as-is so event names and the names of what they concern can be matched exactly
 	consoleOut.FormatFieldValue = func(i interface{}) string {
-		return strings.ToUpper(fmt.Sprintf("%s", i))
+		return fmt.Sprintf("%s", i)
 	}
 	consoleOut.FormatTimestamp = func(i interface{}) string {
 		return colorize(fmt.Sprintf("%s", i), color.New(color.FgWhite), noColor)
@@ -290,6 +296,49 @@ func colorize(s interface{}, c color.Style, disabled bool) string {
 	return c.Sprintf("%s", s)
 }
 
+// warnLevelWriter passes only log events at warn level or above to its writer.
+type warnLevelWriter struct {
+	w io.Writer
+}
+
+func (lw *warnLevelWriter) Write(p []byte) (int, error) {
+	return lw.w.Write(p)
+}
+
+func (lw *warnLevelWriter) WriteLevel(level zl.Level, p []byte) (int, error) {
+	if level < zl.WarnLevel {
+		return len(p), nil
+	}
+	return lw.w.Write(p)
+}
+
+// logRing holds the last size lines written to it.
+type logRing struct {
+	mu    sync.Mutex
+	size  int
+	lines []string
+}
+
+func (lr *logRing) Write(p []byte) (int, error) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	lr.lines = append(lr.lines, strings.TrimRight(string(p), "\n"))
+	if len(lr.lines) > lr.size {
+		lr.lines = append([]string(nil), lr.lines[len(lr.lines)-lr.size:]...)
+	}
+	return len(p), nil
+}
+
+// tail returns a copy of the last n lines held, oldest first.
+func (lr *logRing) tail(n int) []string {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	if n > len(lr.lines) {
+		n = len(lr.lines)
+	}
+	return append([]string{}, lr.lines[len(lr.lines)-n:]...)
+}
+
 type igorSyslogWriter struct {
 	writer *syslog.Writer
 }
@@ -109,7 +109,7 @@ func doPasswordAuth(w http.ResponseWriter, r *http.Request) (user *User, err err
 			// at this point igor CLI came from the /login handler and the
 			// user must have entered their username/password wrong. For igorweb
 			// they will have been on the login page already. So both fail here.
-			clog.Warn().Msgf(errLine)
+			logEvent(clog, EvAuthLoginFailed).Str(LogFieldUser, username).Str(LogFieldError, errLine).Msgf(errLine)
 			makeJsonResponse(w, http.StatusUnauthorized, rb)
 			return
 		default:
@@ -126,14 +126,30 @@ func doPasswordAuth(w http.ResponseWriter, r *http.Request) (user *User, err err
 	}
 
 	// we have successfully logged in, token generation time!
+	if err = startAuthSession(w, user, r); err != nil {
+		errLine := fmt.Sprintf("%s failed - %v", actionPrefix, err)
+		clog.Error().Msgf(errLine)
+		makeJsonResponse(w, http.StatusInternalServerError, rb)
+		return nil, err
+	}
+
+	return
+}
+
+// startAuthSession records a new session for an authenticated user and attaches its auth token
+// cookie to the response writer.
+func startAuthSession(w http.ResponseWriter, user *User, r *http.Request) error {
+
 	exprTime := getTokenExpiration()
 
-	tokenString, gtErr := generateToken(user.Name, exprTime)
+	session, sErr := newAuthSession(user, exprTime, r)
+	if sErr != nil {
+		return fmt.Errorf("unable to record session: %v", sErr)
+	}
+
+	tokenString, gtErr := generateToken(user.Name, session.SessionID, exprTime)
 	if gtErr != nil {
-		errLine := fmt.Sprintf("%s failed - %v", actionPrefix, gtErr)
-		clog.Error().Msgf(errLine)
-		makeJsonResponse(w, http.StatusInternalServerError, rb)
-		return
+		return gtErr
 	}
 
 	http.SetCookie(w, &http.Cookie{
@@ -144,5 +160,5 @@ func doPasswordAuth(w http.ResponseWriter, r *http.Request) (user *User, err err
 		SameSite: http.SameSiteNoneMode,
 	})
 
-	return
+	return nil
 }
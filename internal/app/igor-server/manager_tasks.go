@@ -0,0 +1,275 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"igor2/internal/pkg/common"
+)
+
+// TaskSchedule overrides how often a manager task runs. Interval is in minutes and Jitter is the most
+// seconds each run is randomly put off by. A zero value keeps the task's default.
+type TaskSchedule struct {
+	Interval int `yaml:"interval" json:"interval"`
+	Jitter   int `yaml:"jitter" json:"jitter"`
+}
+
+// managerTask is one piece of the periodic work done by igor-server. Each task runs on its own timer
+// so a slow task doesn't hold up the others, and it can be run on demand by an admin.
+type managerTask struct {
+	name string
+	run  func(*time.Time) error
+	// interval is how long after the start of the last run's wall clock minute the next run happens
+	interval time.Duration
+	// offset is how far into its minute the task runs. Tasks that change reservations are given
+	// offsets that keep them in the order they must happen, ex. hosts are freed before installs.
+	offset time.Duration
+	// jitter is the most each run is randomly put off by, to spread out tasks that can wait
+	jitter time.Duration
+	// resLock tasks change reservation and host state and take turns holding resManageMU
+	resLock bool
+	// enabled returns false if the task has nothing to do with the current config
+	enabled func() bool
+
+	// runMU keeps a scheduled run and one asked for by an admin from overlapping
+	runMU sync.Mutex
+
+	statMU       sync.RWMutex
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastErr      error
+	nextRun      time.Time
+	runs         int
+	failures     int
+}
+
+// managerTasks is the periodic work of igor-server in the order it happens within a minute.
+var managerTasks = newManagerTasks()
+
+func newManagerTasks() []*managerTask {
+	maintenanceOn := func() bool { return igor.Maintenance.HostMaintenanceDuration > 0 }
+	return []*managerTask{
+		{name: "closeoutReservations", run: closeoutReservations, interval: time.Minute, resLock: true},
+		{name: "resumeReservations", run: resumeReservations, interval: time.Minute, resLock: true},
+		{name: "expireApprovalHolds", run: expireApprovalHolds, interval: time.Minute, resLock: true},
+		{name: "finishMaintenance", run: finishMaintenance, interval: time.Minute, resLock: true, enabled: maintenanceOn},
+		// installs wait a moment so hosts freed by the tasks above are ready in the same minute
+		{name: "installReservations", run: installReservations, interval: time.Minute, offset: 2 * time.Second, resLock: true},
+		// scheduled power cycles run once the installs of the same minute have been made
+		{name: "cycleReservations", run: cycleReservations, interval: time.Minute, offset: 5 * time.Second, resLock: true},
+		{name: "checkIdleReservations", run: checkIdleReservations, interval: time.Minute, offset: 10 * time.Second,
+			jitter: 10 * time.Second, resLock: true},
+		{name: "sendExpirationWarnings", run: sendExpirationWarnings, interval: time.Minute, offset: 10 * time.Second,
+			jitter: 10 * time.Second},
+		{name: "purgeIdempotencyRecords", run: purgeIdempotencyRecords, interval: 10 * time.Minute, offset: 30 * time.Second,
+			jitter: 20 * time.Second},
+		{name: "purgeResShares", run: purgeResShares, interval: 10 * time.Minute, offset: 30 * time.Second, jitter: 20 * time.Second},
+		{name: "purgeResExtendTokens", run: purgeResExtendTokens, interval: 10 * time.Minute, offset: 30 * time.Second,
+			jitter: 20 * time.Second},
+		{name: "purgeAuthSessions", run: purgeAuthSessions, interval: 10 * time.Minute, offset: 30 * time.Second,
+			jitter: 20 * time.Second},
+	}
+}
+
+// findManagerTask returns the task with the given name, or nil if there isn't one.
+func findManagerTask(name string) *managerTask {
+	for _, mt := range managerTasks {
+		if mt.name == name {
+			return mt
+		}
+	}
+	return nil
+}
+
+// managerTaskNames returns the names of all manager tasks, sorted.
+func managerTaskNames() []string {
+	names := make([]string, 0, len(managerTasks))
+	for _, mt := range managerTasks {
+		names = append(names, mt.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isEnabled returns true if the task runs with the current config.
+func (mt *managerTask) isEnabled() bool {
+	return mt.enabled == nil || mt.enabled()
+}
+
+// applyTaskSchedules sets the interval and jitter of the manager tasks named in the scheduler config.
+func applyTaskSchedules(schedules map[string]TaskSchedule) error {
+	for name, ts := range schedules {
+		mt := findManagerTask(name)
+		if mt == nil {
+			return fmt.Errorf("unknown task '%s' - must be one of %s", name, strings.Join(managerTaskNames(), ", "))
+		}
+		if ts.Interval < 0 || ts.Jitter < 0 {
+			return fmt.Errorf("task '%s' interval and jitter cannot be negative", name)
+		}
+		if ts.Interval > 0 {
+			mt.interval = time.Duration(ts.Interval) * time.Minute
+		}
+		if ts.Jitter > 0 {
+			mt.jitter = time.Duration(ts.Jitter) * time.Second
+		}
+		if mt.offset+mt.jitter >= mt.interval {
+			return fmt.Errorf("task '%s' jitter of %v is too long for an interval of %v", name, mt.jitter, mt.interval)
+		}
+	}
+	return nil
+}
+
+// logTaskSchedule writes the schedule of every manager task to the log at startup.
+func logTaskSchedule() {
+	for _, mt := range managerTasks {
+		if !mt.isEnabled() {
+			logger.Info().Msgf("manager task %s is disabled", mt.name)
+			continue
+		}
+		logger.Info().Msgf("manager task %s runs every %v at +%v (jitter %v)", mt.name, mt.interval, mt.offset, mt.jitter)
+	}
+}
+
+// startManagerTasks starts a background worker for each enabled manager task.
+func startManagerTasks() {
+	logTaskSchedule()
+	for _, mt := range managerTasks {
+		if mt.isEnabled() {
+			wg.Add(1)
+			go mt.manage()
+		}
+	}
+}
+
+// manage runs the task each time its timer fires until the server shuts down.
+func (mt *managerTask) manage() {
+	defer wg.Done()
+	countdown := NewScheduleTimer(mt.interval + mt.offset)
+	countdown.calcDur = func(off time.Duration) time.Duration {
+		var delay time.Duration
+		if mt.jitter > 0 {
+			delay = time.Duration(rand.Int63n(int64(mt.jitter)))
+		}
+		return getDurationToClockTime(off + delay)
+	}
+	for {
+		select {
+		case <-shutdownChan:
+			logger.Info().Msgf("stopping manager task %s", mt.name)
+			if !countdown.t.Stop() {
+				<-countdown.t.C
+			}
+			return
+		case checkTime := <-countdown.t.C:
+			if err := mt.runAt(schedulerTime(checkTime)); err != nil {
+				logger.Error().Msgf("%v", err)
+			}
+			wait := countdown.calcDur(countdown.off)
+			countdown.t.Reset(wait)
+			mt.statMU.Lock()
+			mt.nextRun = time.Now().Add(wait)
+			mt.statMU.Unlock()
+		}
+	}
+}
+
+// runAt does one run of the task as of checkTime and records how it went.
+func (mt *managerTask) runAt(checkTime time.Time) error {
+
+	mt.runMU.Lock()
+	defer mt.runMU.Unlock()
+	if mt.resLock {
+		resManageMU.Lock()
+		defer resManageMU.Unlock()
+	}
+
+	logger.Debug().Msgf("running manager task %s - %v", mt.name, checkTime.Format(time.RFC3339))
+	started := time.Now()
+	err := mt.run(&checkTime)
+
+	mt.statMU.Lock()
+	mt.lastRun = started
+	mt.lastDuration = time.Since(started)
+	mt.lastErr = err
+	mt.runs++
+	if err != nil {
+		mt.failures++
+	}
+	mt.statMU.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("manager task %s failed: %v", mt.name, err)
+	}
+	return nil
+}
+
+// runManagerTasks runs every enabled manager task once, in order, as of checkTime. A task that fails
+// doesn't stop the ones after it.
+func runManagerTasks(checkTime time.Time) {
+	for _, mt := range managerTasks {
+		if !mt.isEnabled() {
+			continue
+		}
+		if err := mt.runAt(checkTime); err != nil {
+			logger.Error().Msgf("%v", err)
+		}
+	}
+}
+
+// taskData returns how the task is scheduled and how its last run went.
+func (mt *managerTask) taskData() common.ManagerTaskData {
+	mt.statMU.RLock()
+	defer mt.statMU.RUnlock()
+
+	td := common.ManagerTaskData{
+		Name:     mt.name,
+		Enabled:  mt.isEnabled(),
+		Interval: common.FormatDuration(mt.interval, false),
+		Jitter:   mt.jitter.String(),
+		Runs:     mt.runs,
+		Failures: mt.failures,
+	}
+	if !mt.lastRun.IsZero() {
+		td.LastRun = mt.lastRun.Unix()
+		td.LastDurationMs = mt.lastDuration.Milliseconds()
+		td.LastResult = common.BatchItemOK
+		if mt.lastErr != nil {
+			td.LastResult = common.BatchItemFailed
+			td.LastError = mt.lastErr.Error()
+		}
+	}
+	if !mt.nextRun.IsZero() {
+		td.NextRun = mt.nextRun.Unix()
+	}
+	return td
+}
+
+// managerTaskData returns the schedule and last run of every manager task, in the order they run.
+func managerTaskData() []common.ManagerTaskData {
+	tasks := make([]common.ManagerTaskData, 0, len(managerTasks))
+	for _, mt := range managerTasks {
+		tasks = append(tasks, mt.taskData())
+	}
+	return tasks
+}
+
+// doRunManagerTask runs the named task right away, waiting for a scheduled run of it to finish first.
+func doRunManagerTask(name string) (common.ManagerTaskData, error) {
+	mt := findManagerTask(name)
+	if mt == nil {
+		return common.ManagerTaskData{}, fmt.Errorf("unknown task '%s' - must be one of %s", name, strings.Join(managerTaskNames(), ", "))
+	}
+	if !mt.isEnabled() {
+		return common.ManagerTaskData{}, fmt.Errorf("task '%s' is disabled by the server config", name)
+	}
+	err := mt.runAt(schedulerTime(time.Now()))
+	return mt.taskData(), err
+}
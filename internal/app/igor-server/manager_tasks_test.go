@@ -0,0 +1,85 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igor2/internal/pkg/common"
+)
+
+// setManagerTasks replaces the manager tasks for the test.
+func setManagerTasks(t *testing.T, tasks []*managerTask) {
+	orig := managerTasks
+	t.Cleanup(func() { managerTasks = orig })
+	managerTasks = tasks
+}
+
+func TestApplyTaskSchedules(t *testing.T) {
+
+	setManagerTasks(t, newManagerTasks())
+
+	require.NoError(t, applyTaskSchedules(map[string]TaskSchedule{"sendExpirationWarnings": {Interval: 5, Jitter: 30}}))
+	mt := findManagerTask("sendExpirationWarnings")
+	assert.Equal(t, 5*time.Minute, mt.interval)
+	assert.Equal(t, 30*time.Second, mt.jitter)
+
+	// a blank value keeps the default
+	require.NoError(t, applyTaskSchedules(map[string]TaskSchedule{"purgeResShares": {Jitter: 5}}))
+	assert.Equal(t, 10*time.Minute, findManagerTask("purgeResShares").interval)
+
+	err := applyTaskSchedules(map[string]TaskSchedule{"sendWarnings": {Interval: 5}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "installReservations")
+	assert.Error(t, applyTaskSchedules(map[string]TaskSchedule{"installReservations": {Interval: -1}}))
+	assert.Error(t, applyTaskSchedules(map[string]TaskSchedule{"installReservations": {Jitter: 60}}))
+
+	// installs follow the tasks that free hosts within the same minute
+	install := findManagerTask("installReservations")
+	for _, name := range []string{"closeoutReservations", "resumeReservations", "expireApprovalHolds", "finishMaintenance"} {
+		assert.Less(t, findManagerTask(name).offset, install.offset, name)
+	}
+}
+
+func TestRunManagerTasks(t *testing.T) {
+
+	var ran []string
+	task := func(name string, err error) *managerTask {
+		return &managerTask{name: name, interval: time.Minute, resLock: true, run: func(*time.Time) error {
+			ran = append(ran, name)
+			return err
+		}}
+	}
+	off := task("off", nil)
+	off.enabled = func() bool { return false }
+	setManagerTasks(t, []*managerTask{task("first", fmt.Errorf("no database")), off, task("second", nil)})
+
+	// a failed task doesn't keep the rest from running
+	runManagerTasks(time.Now())
+	assert.Equal(t, []string{"first", "second"}, ran)
+
+	first := findManagerTask("first").taskData()
+	assert.Equal(t, common.BatchItemFailed, first.LastResult)
+	assert.Equal(t, "no database", first.LastError)
+	assert.Equal(t, 1, first.Failures)
+	assert.Equal(t, common.BatchItemOK, findManagerTask("second").taskData().LastResult)
+
+	data, err := doRunManagerTask("second")
+	require.NoError(t, err)
+	assert.Equal(t, 2, data.Runs)
+	assert.Equal(t, []string{"first", "second", "second"}, ran)
+
+	_, err = doRunManagerTask("off")
+	assert.Error(t, err)
+	data, err = doRunManagerTask("third")
+	assert.Error(t, err)
+	assert.Empty(t, data.Name)
+	assert.Len(t, managerTaskData(), 3)
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxMarkdownLen is the longest text renderMarkdown will format. Longer text is only escaped. The
+// fields it renders are held to shorter lengths when they are set, so this only bounds the work done
+// on values stored before those limits existed.
+const maxMarkdownLen = 2048
+
+// markdownSchemes are the only URL schemes links are made for.
+var markdownSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+var (
+	mdCodePattern    = regexp.MustCompile("`([^`\n]+)`")
+	mdLinkPattern    = regexp.MustCompile(`(!?)\[([^\[\]\n]+)\]\(([^()\s]+)\)`)
+	mdBareURLPattern = regexp.MustCompile(`https?://[^\s\x00]+`)
+	mdBoldPattern    = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)
+	mdItalicPattern  = regexp.MustCompile(`\*([^*\n]+)\*`)
+	mdUnderPattern   = regexp.MustCompile(`(^|[^\w])_([^_\n]+)_($|[^\w])`)
+	mdHeldPattern    = regexp.MustCompile("\x00([0-9]+)\x00")
+)
+
+// mdURLTrailingPunc is punctuation left out of the end of a bare URL, since it usually ends the sentence.
+const mdURLTrailingPunc = ".,:;!?)"
+
+// renderMarkdown formats the inline markdown of a line or two of text, such as a reservation
+// description or the MOTD, as HTML: links, **bold**, *italics* and `code`. Bare http and https URLs
+// are made into links as well. Images and raw HTML are not supported. Longer text with paragraphs
+// and lists, like distro usage notes, is rendered by renderDistroNotes.
+//
+// The text is HTML-escaped before anything is formatted, so the only markup in the result is the
+// tags made here, and links are only made for the schemes in markdownSchemes. Anything that isn't
+// understood is left as escaped text.
+func renderMarkdown(s string) template.HTML {
+
+	// the NUL character marks spans that are already formatted, so it can't come from the text
+	s = strings.ReplaceAll(s, "\x00", "")
+	if len(s) > maxMarkdownLen {
+		return template.HTML(html.EscapeString(s))
+	}
+
+	var held []string
+	hold := func(formatted string) string {
+		held = append(held, formatted)
+		return "\x00" + strconv.Itoa(len(held)-1) + "\x00"
+	}
+
+	// code spans are formatted first so nothing inside them is
+	text := mdCodePattern.ReplaceAllStringFunc(s, func(m string) string {
+		return hold("<code>" + html.EscapeString(mdCodePattern.FindStringSubmatch(m)[1]) + "</code>")
+	})
+
+	text = mdLinkPattern.ReplaceAllStringFunc(text, func(m string) string {
+		parts := mdLinkPattern.FindStringSubmatch(m)
+		href, ok := markdownHref(parts[3])
+		if parts[1] == "!" || !ok {
+			// images and links to other schemes stay as they were written
+			return m
+		}
+		return hold(fmt.Sprintf(`<a href="%s">%s</a>`, href, formatEmphasis(html.EscapeString(parts[2]))))
+	})
+
+	text = mdBareURLPattern.ReplaceAllStringFunc(text, func(m string) string {
+		link := strings.TrimRight(m, mdURLTrailingPunc)
+		href, ok := markdownHref(link)
+		if !ok {
+			return m
+		}
+		return hold(fmt.Sprintf(`<a href="%s">%s</a>`, href, html.EscapeString(link))) + m[len(link):]
+	})
+
+	text = formatEmphasis(html.EscapeString(text))
+	text = strings.ReplaceAll(text, "\n", "<br>")
+
+	text = mdHeldPattern.ReplaceAllStringFunc(text, func(m string) string {
+		i, _ := strconv.Atoi(strings.Trim(m, "\x00"))
+		return held[i]
+	})
+
+	return template.HTML(text)
+}
+
+// formatEmphasis formats bold and italic spans of text that has already been escaped.
+func formatEmphasis(escaped string) string {
+	escaped = mdBoldPattern.ReplaceAllString(escaped, "<b>$1</b>")
+	escaped = mdItalicPattern.ReplaceAllString(escaped, "<i>$1</i>")
+	return mdUnderPattern.ReplaceAllString(escaped, "$1<i>$2</i>$3")
+}
+
+// markdownHref returns the escaped form of a link target for an href attribute. It returns false if
+// the target isn't an absolute URL with one of the allowed schemes.
+func markdownHref(target string) (string, bool) {
+	u, err := url.Parse(target)
+	if err != nil || !markdownSchemes[strings.ToLower(u.Scheme)] {
+		return "", false
+	}
+	if u.Scheme != "mailto" && u.Host == "" {
+		return "", false
+	}
+	return html.EscapeString(u.String()), true
+}
+
+// renderMarkdownString is renderMarkdown for response fields, which carry the HTML as a string.
+func renderMarkdownString(s string) string {
+	if s == "" {
+		return ""
+	}
+	return string(renderMarkdown(s))
+}
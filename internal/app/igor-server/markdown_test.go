@@ -0,0 +1,69 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "just text", "just text"},
+		{"bold and italics", "**big** and *slanted* and _also_ but not snake_case_name",
+			"<b>big</b> and <i>slanted</i> and <i>also</i> but not snake_case_name"},
+		{"code", "run `igor res show` now", "run <code>igor res show</code> now"},
+		{"code is literal", "`**not bold** <b>`", "<code>**not bold** &lt;b&gt;</code>"},
+		{"link", "see [the **runbook**](https://wiki.example.com/run_book?a=1&b=2)",
+			`see <a href="https://wiki.example.com/run_book?a=1&amp;b=2">the <b>runbook</b></a>`},
+		{"mailto", "[mail us](mailto:ops@example.com)", `<a href="mailto:ops@example.com">mail us</a>`},
+		{"bare url", "docs at https://example.com/a_b_c.", `docs at <a href="https://example.com/a_b_c">https://example.com/a_b_c</a>.`},
+		{"bare url in parens", "(https://example.com)", `(<a href="https://example.com">https://example.com</a>)`},
+		{"newline", "one\ntwo", "one<br>two"},
+
+		// nothing here may produce markup other than the tags made by the renderer
+		{"raw html", "<b onmouseover=alert(1)>hi</b>", "&lt;b onmouseover=alert(1)&gt;hi&lt;/b&gt;"},
+		{"script", "<script>alert('x')</script>", "&lt;script&gt;alert(&#39;x&#39;)&lt;/script&gt;"},
+		{"javascript link", "[click](javascript:alert(1))", "[click](javascript:alert(1))"},
+		{"javascript link no parens", "[click](javascript:alert`1`)", "[click](javascript:alert<code>1</code>)"},
+		{"data link", "[x](data:text/html,<script>)", "[x](data:text/html,&lt;script&gt;)"},
+		{"relative link", "[x](/api/reservations)", "[x](/api/reservations)"},
+		{"image", "![pic](https://example.com/a.png)", `![pic](<a href="https://example.com/a.png">https://example.com/a.png</a>)`},
+		{"quote breaks out of href", `[x](https://example.com/"onclick="alert(1))`,
+			`[x](<a href="https://example.com/%22onclick=%22alert%281">https://example.com/&#34;onclick=&#34;alert(1</a>))`},
+		{"html in link text", "[<img src=x onerror=alert(1)>](https://example.com)",
+			`<a href="https://example.com">&lt;img src=x onerror=alert(1)&gt;</a>`},
+		{"html in bare url", `https://example.com/<script>"`, `<a href="https://example.com/%3Cscript%3E%22">https://example.com/&lt;script&gt;&#34;</a>`},
+		{"placeholder forgery", "\x000\x00`a`", "0<code>a</code>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(renderMarkdown(tt.in)))
+		})
+	}
+
+	// text over the limit is escaped but not formatted
+	long := "**" + strings.Repeat("a", maxMarkdownLen) + "** <script>"
+	out := string(renderMarkdown(long))
+	assert.NotContains(t, out, "<b>")
+	assert.NotContains(t, out, "<script>")
+
+	assert.Empty(t, renderMarkdownString(""))
+}
+
+func TestCheckResDesc(t *testing.T) {
+	assert.NoError(t, checkResDesc("see [runbook](https://wiki.example.com/run?a=1&b=2#top) for **help** with `igor`"))
+	assert.Error(t, checkResDesc("<script>alert(1)</script>"))
+	assert.Error(t, checkResDesc(`say "hi"`))
+	assert.Error(t, checkResDesc(strings.Repeat("a", 257)))
+}
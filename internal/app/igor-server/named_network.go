@@ -0,0 +1,346 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// NamedNetwork pins a VLAN to a name owned by a group so it outlives the reservations that use it.
+// Members of the group give the name in place of a VLAN when making or editing a reservation. The
+// VLAN never goes back to the pool while the named network exists.
+type NamedNetwork struct {
+	Base
+	Name    string `gorm:"unique; notNull"`
+	Vlan    int    `gorm:"unique; notNull"`
+	GroupID int
+	Group   Group
+	// OwnerID is the user who made the named network
+	OwnerID int
+	Owner   User
+}
+
+// destination for route POST /vlans/networks
+func handleCreateNamedNetwork(w http.ResponseWriter, r *http.Request) {
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	createParams := getBodyFromContext(r)
+	clog := hlog.FromRequest(r)
+	actionPrefix := "create named network"
+	rb := common.NewResponseBody()
+
+	network, status, err := doCreateNamedNetwork(createParams, getUserFromContext(r))
+
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		msg := fmt.Sprintf("network '%s' of group '%s' has VLAN %d", network.Name, network.Group.Name, network.Vlan)
+		clog.Info().Msgf("%s success - %s", actionPrefix, msg)
+		rb.Message = msg
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// destination for route DELETE /vlans/networks/:networkName
+func handleDeleteNamedNetwork(w http.ResponseWriter, r *http.Request) {
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	ps := httprouter.ParamsFromContext(r.Context())
+	name := ps.ByName("networkName")
+	clog := hlog.FromRequest(r)
+	actionPrefix := "delete named network"
+	rb := common.NewResponseBody()
+
+	vlan, status, err := doDeleteNamedNetwork(name, getUserFromContext(r), time.Now())
+
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Message = fmt.Sprintf("network '%s' deleted, VLAN %d returned to the pool", name, vlan)
+		clog.Info().Msgf("%s success - %s", actionPrefix, rb.Message)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// doCreateNamedNetwork pins a VLAN to a new named network owned by the given group. The user must be a
+// member of the group. The VLAN is the next free one in the range allowed for the group unless the
+// request gives one, either as an ID or as the name of a reservation of the user, the same way a
+// reservation is given a VLAN.
+func doCreateNamedNetwork(createParams map[string]interface{}, user *User) (network *NamedNetwork, status int, err error) {
+
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+
+	if !igor.vlanEnabled() {
+		return nil, http.StatusBadRequest, fmt.Errorf("VLAN segmentation is not enabled on this server")
+	}
+
+	name := createParams["name"].(string)
+	groupName := createParams["group"].(string)
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		if found, fErr := dbReadNamedNetworks(map[string]interface{}{"name": name}, tx); fErr != nil {
+			return fErr
+		} else if len(found) > 0 {
+			status = http.StatusConflict
+			return newCodedError(common.ErrNameTaken, "%s already in use as network name", name)
+		}
+
+		gList, gStatus, gErr := getGroups([]string{groupName}, true, tx)
+		if gErr != nil {
+			status = gStatus
+			return gErr
+		}
+		group := &gList[0]
+		if group.Name == GroupAll || group.IsUserPrivate {
+			status = http.StatusBadRequest
+			return fmt.Errorf("a named network cannot be owned by group '%s'", group.Name)
+		}
+		if !user.isMemberOfGroup(group) {
+			status = http.StatusForbidden
+			return fmt.Errorf("%s is not a member of group '%s'", user.Name, group.Name)
+		}
+
+		var vlan int
+		if vlanParam, ok := createParams["vlan"].(string); ok {
+			var pvStatus int
+			if vlan, pvStatus, err = parseVLAN(vlanParam, *user, group, tx); err != nil {
+				status = pvStatus
+				return err
+			}
+			if pinned, pErr := dbReadNamedNetworks(map[string]interface{}{"vlan": vlan}, tx); pErr != nil {
+				return pErr
+			} else if len(pinned) > 0 {
+				status = http.StatusConflict
+				return fmt.Errorf("VLAN %d already belongs to network '%s'", vlan, pinned[0].Name)
+			}
+		} else {
+			vlanMin, vlanMax, rangeOk := vlanRangeOf(group)
+			if !rangeOk {
+				status = http.StatusConflict
+				return fmt.Errorf("the VLAN range %s of group '%s' is outside the VLANs managed by igor -- ask an admin to fix it", group.vlanRange(), group.Name)
+			}
+			if vlan, err = nextVLAN(vlanMin, vlanMax, tx); err != nil {
+				status = http.StatusConflict
+				return fmt.Errorf("no VLANs are free in the range %s", formatVlanRange(vlanMin, vlanMax))
+			}
+		}
+
+		network = &NamedNetwork{Name: name, Vlan: vlan, GroupID: group.ID, Group: *group, OwnerID: user.ID, Owner: *user}
+		return dbCreateNamedNetwork(network, tx)
+
+	}); err == nil {
+		status = http.StatusCreated
+	}
+	return
+}
+
+// doDeleteNamedNetwork deletes the named network so its VLAN goes back to the pool. Members of the group
+// that owns it and elevated admins can delete it, but not while a reservation that hasn't ended uses it.
+func doDeleteNamedNetwork(name string, user *User, now time.Time) (vlan int, status int, err error) {
+
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		found, fErr := dbReadNamedNetworks(map[string]interface{}{"name": name}, tx)
+		if fErr != nil {
+			return fErr
+		} else if len(found) == 0 {
+			status = http.StatusNotFound
+			return fmt.Errorf("the network '%s' does not exist", name)
+		}
+		network := &found[0]
+		vlan = network.Vlan
+
+		if !userElevated(user.Name) && !user.isMemberOfGroup(&network.Group) {
+			status = http.StatusForbidden
+			return fmt.Errorf("only members of group '%s' can delete network '%s'", network.Group.Name, network.Name)
+		}
+
+		resList, rErr := dbReadReservations(map[string]interface{}{"vlan": network.Vlan}, nil, tx)
+		if rErr != nil {
+			return rErr
+		}
+		var inUse []string
+		for _, res := range resList {
+			if res.End.After(now) {
+				inUse = append(inUse, res.Name)
+			}
+		}
+		if len(inUse) > 0 {
+			status = http.StatusConflict
+			return fmt.Errorf("network '%s' is in use by reservation(s): %s", network.Name, strings.Join(inUse, ", "))
+		}
+
+		return dbDeleteNamedNetwork(network, tx)
+
+	}); err == nil {
+		status = http.StatusOK
+	}
+	return
+}
+
+// namedNetworkVlan returns the VLAN of the named network if the user can use it for reservations of the
+// given group. found is false if there is no network with the name.
+func namedNetworkVlan(name string, user *User, group *Group, tx *gorm.DB) (vlan int, found bool, status int, err error) {
+
+	networks, err := dbReadNamedNetworks(map[string]interface{}{"name": name}, tx)
+	if err != nil {
+		return -1, false, http.StatusInternalServerError, err
+	} else if len(networks) == 0 {
+		return -1, false, http.StatusOK, nil
+	}
+	status, err = checkNamedNetworkUse(&networks[0], user, group)
+	return networks[0].Vlan, true, status, err
+}
+
+// checkNamedNetworkUse returns an error if the user can't use the named network for reservations of the
+// given group.
+func checkNamedNetworkUse(network *NamedNetwork, user *User, group *Group) (int, error) {
+	if !user.isMemberOfGroup(&network.Group) {
+		return http.StatusForbidden, fmt.Errorf("%s must be a member of group '%s' to use network '%s'", user.Name, network.Group.Name, network.Name)
+	}
+	if err := checkGroupVlan(network.Vlan, group); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("cannot use network '%s': %v", network.Name, err)
+	}
+	return http.StatusOK, nil
+}
+
+// checkPinnedVlans returns an error if a named network has a VLAN outside the range igor manages or if
+// named networks hold every VLAN in it, leaving none for reservations without one.
+func checkPinnedVlans(networks []NamedNetwork, min, max int) error {
+	var outside []string
+	for _, n := range networks {
+		if n.Vlan < min || n.Vlan > max {
+			outside = append(outside, fmt.Sprintf("%s (%d)", n.Name, n.Vlan))
+		}
+	}
+	if len(outside) > 0 {
+		return fmt.Errorf("named network(s) %s have a VLAN outside vlan.rangeMin/Max [%d,%d]", strings.Join(outside, ", "), min, max)
+	}
+	if len(networks) >= max-min+1 {
+		return fmt.Errorf("named networks hold all %d VLANs in vlan.rangeMin/Max [%d,%d]", max-min+1, min, max)
+	}
+	return nil
+}
+
+// checkNamedNetworkRange checks the VLANs held by named networks against the range in the config.
+func checkNamedNetworkRange() {
+	if !igor.vlanEnabled() {
+		return
+	}
+	networks, err := dbReadNamedNetworksTx(map[string]interface{}{})
+	if err != nil {
+		exitPrintFatal(err.Error())
+	}
+	if err = checkPinnedVlans(networks, igor.Vlan.RangeMin, igor.Vlan.RangeMax); err != nil {
+		exitPrintFatal(fmt.Sprintf("config error - %v", err))
+	}
+}
+
+func dbCreateNamedNetwork(network *NamedNetwork, tx *gorm.DB) error {
+	result := tx.Omit("Group", "Owner").Create(network)
+	return result.Error
+}
+
+func dbReadNamedNetworksTx(queryParams map[string]interface{}) (networks []NamedNetwork, err error) {
+	err = performDbTx(func(tx *gorm.DB) error {
+		networks, err = dbReadNamedNetworks(queryParams, tx)
+		return err
+	})
+	return networks, err
+}
+
+// dbReadNamedNetworks returns the named networks matching the given parameters, in order of VLAN.
+func dbReadNamedNetworks(queryParams map[string]interface{}, tx *gorm.DB) (networks []NamedNetwork, err error) {
+
+	tx = tx.Preload("Group").Preload("Owner")
+
+	for key, val := range queryParams {
+		switch val.(type) {
+		case string, int:
+			tx = tx.Where(key, val)
+		case []string, []int:
+			tx = tx.Where(key+" IN ?", val)
+		default:
+			logger.Error().Msgf("Incorrect parameter type received for %s: %v", key, val)
+		}
+	}
+
+	result := tx.Order("vlan").Find(&networks)
+	return networks, result.Error
+}
+
+func dbDeleteNamedNetwork(network *NamedNetwork, tx *gorm.DB) error {
+	result := tx.Delete(network)
+	return result.Error
+}
+
+func validateNamedNetworkParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+
+		createParams := getBodyFromContext(r)
+		if _, ok := createParams["name"]; !ok {
+			validateErr = NewMissingParamError("name")
+		} else if _, ok = createParams["group"]; !ok {
+			validateErr = NewMissingParamError("group")
+		} else {
+
+		postParamLoop:
+			for key, val := range createParams {
+				switch key {
+				case "name":
+					if name, ok := val.(string); !ok {
+						validateErr = NewBadParamTypeError(key, val, "string")
+						break postParamLoop
+					} else if validateErr = checkGenericNameRules(name); validateErr != nil {
+						break postParamLoop
+					}
+				case "group":
+					if group, ok := val.(string); !ok {
+						validateErr = NewBadParamTypeError(key, val, "string")
+						break postParamLoop
+					} else if validateErr = checkGroupNameRules(group); validateErr != nil {
+						break postParamLoop
+					}
+				case "vlan":
+					if vlan, ok := val.(string); !ok || strings.TrimSpace(vlan) == "" {
+						validateErr = NewBadParamTypeError(key, val, "string")
+						break postParamLoop
+					}
+				default:
+					validateErr = NewUnknownParamError(key, val)
+					break postParamLoop
+				}
+			}
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateNamedNetworkParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
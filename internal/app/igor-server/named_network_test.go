@@ -0,0 +1,94 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+)
+
+func TestNamedNetwork(t *testing.T) {
+
+	setVlanTestRange(t, 100, 199)
+	seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	teamA := Group{Name: "team-a", VlanMin: 100, VlanMax: 149}
+	require.NoError(t, db.Omit(clause.Associations).Create(&teamA).Error)
+	alice := User{Name: "alice", Email: "alice@example.com", Groups: []Group{teamA}}
+	require.NoError(t, db.Omit("Groups.*").Create(&alice).Error)
+	bob := User{Name: "bob", Email: "bob@example.com"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&bob).Error)
+
+	now := time.Now()
+	old := Reservation{Name: "oldres", Hash: "h1", OwnerID: alice.ID, Vlan: 100, Start: now, End: now.Add(time.Hour)}
+	require.NoError(t, db.Omit(clause.Associations).Create(&old).Error)
+
+	// the next free VLAN in the group's range is used unless one is given
+	network, status, err := doCreateNamedNetwork(map[string]interface{}{"name": "team-a-net", "group": "team-a"}, &alice)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, 101, network.Vlan)
+
+	// a network can keep the VLAN of a reservation before it ends
+	network, _, err = doCreateNamedNetwork(map[string]interface{}{"name": "keep-net", "group": "team-a", "vlan": "oldres"}, &alice)
+	require.NoError(t, err)
+	assert.Equal(t, 100, network.Vlan)
+
+	_, status, err = doCreateNamedNetwork(map[string]interface{}{"name": "bob-net", "group": "team-a"}, &bob)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+	_, status, err = doCreateNamedNetwork(map[string]interface{}{"name": "team-a-net", "group": "team-a"}, &alice)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// pinned VLANs stay out of the pool
+	next, err := nextVLAN(100, 149, db)
+	require.NoError(t, err)
+	assert.Equal(t, 102, next)
+
+	// only members of the owning group can use the network, by name or by VLAN id
+	vlan, _, err := parseVLAN("team-a-net", alice, &Group{Name: "u_alice"}, db)
+	require.NoError(t, err)
+	assert.Equal(t, 101, vlan)
+	_, status, err = parseVLAN("team-a-net", bob, &Group{Name: "u_bob"}, db)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+	_, status, err = parseVLAN("101", bob, &Group{Name: "u_bob"}, db)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// a network can't be deleted while a reservation that hasn't ended uses it
+	_, status, err = doDeleteNamedNetwork("keep-net", &alice, now)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Contains(t, err.Error(), "oldres")
+	_, status, err = doDeleteNamedNetwork("keep-net", &bob, now.Add(2*time.Hour))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+	vlan, status, err = doDeleteNamedNetwork("keep-net", &alice, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 100, vlan)
+}
+
+func TestCheckPinnedVlans(t *testing.T) {
+
+	networks := []NamedNetwork{{Name: "a", Vlan: 100}, {Name: "b", Vlan: 101}}
+	assert.NoError(t, checkPinnedVlans(networks, 100, 102))
+
+	err := checkPinnedVlans(networks, 100, 101)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "all 2 VLANs")
+
+	err = checkPinnedVlans(networks, 101, 150)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a (100)")
+}
@@ -6,18 +6,26 @@ package igorserver
 
 import (
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 var (
 	networkSetFuncs   map[string]func([]Host, int) error
 	networkClearFuncs map[string]func([]Host) error
 	networkVlanFuncs  map[string]func() (map[string]string, error)
+	// networkProfileFuncs holds the backends that can apply a QoS profile to the ports of nodes. An empty
+	// profile removes whatever profile the ports have.
+	networkProfileFuncs map[string]func([]Host, string) error
 )
 
-// Configure the given nodes into the specified 802.1ad outer VLAN
-func networkSet(nodes []Host, vlan int) error {
+// Configure the given nodes into the specified 802.1ad outer VLAN along with the network profile, if
+// one is given
+func networkSet(nodes []Host, vlan int, profile string) error {
 	// if in dev env, just log and return
 	if DEVMODE {
 		logger.Debug().Msg("in dev env running networkSet(), no external action taken")
@@ -34,7 +42,13 @@ func networkSet(nodes []Host, vlan int) error {
 	if !ok {
 		logger.Error().Msgf("no such network mode: %v", igor.Vlan.Network)
 	}
-	return f(nodes, vlan)
+	if err := f(nodes, vlan); err != nil {
+		return err
+	}
+	if profile == "" {
+		return nil
+	}
+	return networkProfile(nodes, profile)
 }
 
 // Clear any 802.1ad configuration on the given nodes
@@ -55,7 +69,66 @@ func networkClear(nodes []Host) error {
 	if !ok {
 		logger.Error().Msgf("no such network mode: %v", igor.Vlan.Network)
 	}
-	return f(nodes)
+	if err := f(nodes); err != nil {
+		return err
+	}
+	// only clear profiles where they can have been applied
+	if len(igor.Vlan.NetProfiles) == 0 || !networkSupportsProfiles() {
+		return nil
+	}
+	return networkProfile(nodes, "")
+}
+
+// networkSupportsProfiles returns true if the configured network backend can apply network profiles.
+func networkSupportsProfiles() bool {
+	_, ok := networkProfileFuncs[igor.Vlan.Network]
+	return ok
+}
+
+// Apply the named network profile to the ports of the given nodes, or remove their profile if it is empty
+func networkProfile(nodes []Host, profile string) error {
+	// if in dev env, just log and return
+	if DEVMODE {
+		logger.Debug().Msg("in dev env running networkProfile(), no external action taken")
+		return nil
+	}
+
+	if igor.Vlan.Network == "" {
+		// they don't want to do vlan segmentation
+		logger.Debug().Msg("not doing vlan segmentation")
+		return nil
+	}
+
+	f, ok := networkProfileFuncs[igor.Vlan.Network]
+	if !ok {
+		return fmt.Errorf("network profiles are not supported by configured network backend")
+	}
+	return f(nodes, profile)
+}
+
+// parseNetProfile checks a network profile requested for a reservation against those allowed by the
+// server config. A value of 'none' clears the profile, returned as an empty string.
+func parseNetProfile(profile string) (string, int, error) {
+
+	if profile = strings.TrimSpace(profile); profile == GroupNoneAlias {
+		return "", http.StatusOK, nil
+	}
+	if !igor.vlanEnabled() {
+		return "", http.StatusBadRequest, fmt.Errorf("VLAN segmentation is not enabled on this server")
+	}
+	if !networkSupportsProfiles() {
+		return "", http.StatusBadRequest, fmt.Errorf("network profiles are not supported by configured network backend")
+	}
+	for _, p := range igor.Vlan.NetProfiles {
+		if p == profile {
+			return profile, http.StatusOK, nil
+		}
+	}
+	if len(igor.Vlan.NetProfiles) == 0 {
+		return "", http.StatusBadRequest, fmt.Errorf("no network profiles are configured on this server")
+	}
+	return "", http.StatusBadRequest, fmt.Errorf("'%s' is not a network profile allowed on this server -- choose from %s",
+		profile, strings.Join(igor.Vlan.NetProfiles, ", "))
 }
 
 // Collect VLAN status for all nodes
@@ -92,18 +165,28 @@ func networkVlan() (map[string]string, error) {
 	return f()
 }
 
-func nextVLAN() (int, error) {
-	reservations, err := dbReadReservationsTx(map[string]interface{}{}, map[string]time.Time{})
+// nextVLAN returns the lowest VLAN between min and max that no reservation or named network is using.
+func nextVLAN(min, max int, tx *gorm.DB) (int, error) {
+	reservations, err := dbReadReservations(map[string]interface{}{}, map[string]time.Time{}, tx)
+	if err != nil {
+		return 0, err
+	}
+	networks, err := dbReadNamedNetworks(map[string]interface{}{}, tx)
 	if err != nil {
 		return 0, err
 	}
 OuterLoop:
-	for i := igor.Vlan.RangeMin; i <= igor.Vlan.RangeMax; i++ {
+	for i := min; i <= max; i++ {
 		for _, res := range reservations {
 			if i == res.Vlan {
 				continue OuterLoop
 			}
 		}
+		for _, n := range networks {
+			if i == n.Vlan {
+				continue OuterLoop
+			}
+		}
 
 		return i, nil
 	}
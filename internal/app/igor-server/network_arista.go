@@ -23,10 +23,12 @@ func init() {
 		networkSetFuncs = make(map[string]func([]Host, int) error)
 		networkClearFuncs = make(map[string]func([]Host) error)
 		networkVlanFuncs = make(map[string]func() (map[string]string, error))
+		networkProfileFuncs = make(map[string]func([]Host, string) error)
 	}
 	networkSetFuncs["arista"] = aristaSet
 	networkClearFuncs["arista"] = aristaClear
 	networkVlanFuncs["arista"] = aristaVlan
+	networkProfileFuncs["arista"] = aristaProfile
 }
 
 var aristaClearTemplate = `enable
@@ -41,9 +43,16 @@ interface {{ $.Eth }}
 switchport mode dot1q-tunnel
 switchport access vlan {{ $.VLAN }}`
 
+// the named QoS profile must already be defined on the switch with 'qos profile NAME'
+var aristaProfileTemplate = `enable
+configure terminal
+interface {{ $.Eth }}
+{{ if $.Profile }}service-profile {{ $.Profile }}{{ else }}no service-profile{{ end }}`
+
 type AristaConfig struct {
-	Eth  string
-	VLAN int
+	Eth     string
+	VLAN    int
+	Profile string
 }
 
 // Issue the given commands via the specified URL, username, and password.
@@ -107,24 +116,26 @@ func aristaSet(hosts []Host, vlan int) error {
 	t := template.Must(template.New("set").Parse(aristaSetTemplate))
 
 	for _, h := range hosts {
-		var b bytes.Buffer
-		c := &AristaConfig{
-			Eth:  h.Eth,
-			VLAN: vlan,
-		}
-		err := t.Execute(&b, c)
-		if err != nil {
-			return err
+		for _, p := range h.isolatedPorts() {
+			var b bytes.Buffer
+			c := &AristaConfig{
+				Eth:  p.Port,
+				VLAN: vlan,
+			}
+			err := t.Execute(&b, c)
+			if err != nil {
+				return err
+			}
+			// now split b into strings with newlines
+			commands := strings.Split(b.String(), "\n")
+			logger.Debug().Msgf("aristaSet commands being sent: %v", commands)
+
+			result, err := aristaJSONRPC(igor.Vlan.NetworkUser, igor.Vlan.NetworkPassword, igor.Vlan.NetworkURL, commands)
+			if err != nil {
+				return err
+			}
+			logger.Debug().Msgf("aristaSet response received: %v", result)
 		}
-		// now split b into strings with newlines
-		commands := strings.Split(b.String(), "\n")
-		logger.Debug().Msgf("aristaSet commands being sent: %v", commands)
-
-		result, err := aristaJSONRPC(igor.Vlan.NetworkUser, igor.Vlan.NetworkPassword, igor.Vlan.NetworkURL, commands)
-		if err != nil {
-			return err
-		}
-		logger.Debug().Msgf("aristaSet response received: %v", result)
 	}
 
 	return nil
@@ -134,23 +145,54 @@ func aristaClear(hosts []Host) error {
 	t := template.Must(template.New("set").Parse(aristaClearTemplate))
 
 	for _, h := range hosts {
-		var b bytes.Buffer
-		c := &AristaConfig{
-			Eth: h.Eth,
+		for _, p := range h.isolatedPorts() {
+			var b bytes.Buffer
+			c := &AristaConfig{
+				Eth: p.Port,
+			}
+			err := t.Execute(&b, c)
+			if err != nil {
+				return err
+			}
+			// now split b into strings with newlines
+			commands := strings.Split(b.String(), "\n")
+			logger.Debug().Msgf("aristaClear commands being sent: %v", commands)
+
+			result, err := aristaJSONRPC(igor.Vlan.NetworkUser, igor.Vlan.NetworkPassword, igor.Vlan.NetworkURL, commands)
+			if err != nil {
+				return err
+			}
+			logger.Debug().Msgf("aristaClear response received: %v", result)
 		}
-		err := t.Execute(&b, c)
-		if err != nil {
-			return err
-		}
-		// now split b into strings with newlines
-		commands := strings.Split(b.String(), "\n")
-		logger.Debug().Msgf("aristaClear commands being sent: %v", commands)
+	}
 
-		result, err := aristaJSONRPC(igor.Vlan.NetworkUser, igor.Vlan.NetworkPassword, igor.Vlan.NetworkURL, commands)
-		if err != nil {
-			return err
+	return nil
+}
+
+func aristaProfile(hosts []Host, profile string) error {
+	t := template.Must(template.New("profile").Parse(aristaProfileTemplate))
+
+	for _, h := range hosts {
+		for _, p := range h.isolatedPorts() {
+			var b bytes.Buffer
+			c := &AristaConfig{
+				Eth:     p.Port,
+				Profile: profile,
+			}
+			err := t.Execute(&b, c)
+			if err != nil {
+				return err
+			}
+			// now split b into strings with newlines
+			commands := strings.Split(b.String(), "\n")
+			logger.Debug().Msgf("aristaProfile commands being sent: %v", commands)
+
+			result, err := aristaJSONRPC(igor.Vlan.NetworkUser, igor.Vlan.NetworkPassword, igor.Vlan.NetworkURL, commands)
+			if err != nil {
+				return err
+			}
+			logger.Debug().Msgf("aristaProfile response received: %v", result)
 		}
-		logger.Debug().Msgf("aristaClear response received: %v", result)
 	}
 
 	return nil
@@ -179,18 +221,18 @@ func aristaVlan() (map[string]string, error) {
 			ethMap[eth] = key
 		}
 	}
-	keys := make([]string, len(ethMap))
-	i := 0
-	for k := range ethMap {
-		keys[i] = k
-		i++
-	}
-	hosts, err := dbReadHostsTx(map[string]interface{}{"eth": keys})
+	// a host is in the VLAN of the first of its isolated ports found in one
+	hosts, err := dbReadHostsTx(nil)
 	if err != nil {
 		return nil, err
 	}
 	for _, h := range hosts {
-		result[h.Name] = ethMap[h.Eth]
+		for _, p := range h.isolatedPorts() {
+			if vlan, ok := ethMap[p.Port]; ok {
+				result[h.Name] = vlan
+				break
+			}
+		}
 	}
 
 	return result, nil
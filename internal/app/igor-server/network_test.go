@@ -0,0 +1,135 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igor2/internal/pkg/common"
+)
+
+// setNetProfileTest uses the simulated network with the given network profiles allowed.
+func setNetProfileTest(t *testing.T, profiles ...string) {
+	setVlanTestRange(t, 100, 199)
+	origProfiles := igor.Vlan.NetProfiles
+	t.Cleanup(func() { igor.Vlan.NetProfiles = origProfiles })
+	igor.Vlan.Network = SimNetwork
+	igor.Vlan.NetProfiles = profiles
+}
+
+func TestParseNetProfile(t *testing.T) {
+
+	setNetProfileTest(t, "capped-10g", "capped-40g")
+
+	profile, _, err := parseNetProfile(" capped-10g ")
+	require.NoError(t, err)
+	assert.Equal(t, "capped-10g", profile)
+
+	profile, _, err = parseNetProfile(GroupNoneAlias)
+	require.NoError(t, err)
+	assert.Empty(t, profile)
+
+	_, status, err := parseNetProfile("uncapped")
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, err.Error(), "capped-10g, capped-40g")
+
+	// a backend that can't apply profiles rejects them instead of ignoring them
+	igor.Vlan.Network = "other"
+	_, status, err = parseNetProfile("capped-10g")
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, err.Error(), "not supported by configured network backend")
+	assert.Error(t, networkProfile([]Host{{Name: "kn1"}}, "capped-10g"))
+}
+
+func TestNetworkSetProfile(t *testing.T) {
+
+	setNetProfileTest(t, "capped-10g")
+	hosts := []Host{{Name: "kn1"}, {Name: "kn2"}}
+	t.Cleanup(func() { _ = networkClear(hosts) })
+
+	require.NoError(t, networkSet(hosts, 101, "capped-10g"))
+	simVlansMU.Lock()
+	assert.Equal(t, "capped-10g", simNetProfiles["kn1"])
+	assert.Equal(t, "capped-10g", simNetProfiles["kn2"])
+	simVlansMU.Unlock()
+
+	// clearing the nodes removes the profile along with the VLAN
+	require.NoError(t, networkClear(hosts[:1]))
+	simVlansMU.Lock()
+	assert.NotContains(t, simNetProfiles, "kn1")
+	assert.Equal(t, "capped-10g", simNetProfiles["kn2"])
+	simVlansMU.Unlock()
+}
+
+func TestEditResNetProfile(t *testing.T) {
+
+	setNetProfileTest(t, "capped-10g")
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	require.NoError(t, db.Create(&Cluster{Name: "test", Prefix: "kn"}).Error)
+	origRefs := igor.ClusterRefs
+	t.Cleanup(func() { igor.ClusterRefs = origRefs })
+	knRange, err := common.NewRange("kn", 1, 2)
+	require.NoError(t, err)
+	igor.ClusterRefs = []common.Range{*knRange}
+	res := newStartTestRes(t, db, "trafficgen", hosts, false, 0)
+	require.NoError(t, db.Model(res).Update("installed", true).Error)
+	t.Cleanup(func() { _ = simNetworkProfile(hosts, "") })
+
+	editAs := func(user *User, profile string) (int, error) {
+		r := httptest.NewRequest(http.MethodPatch, "/", nil)
+		r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, user))
+		_, status, err := doUpdateReservation(res.Name, map[string]interface{}{"netProfile": profile}, r)
+		return status, err
+	}
+
+	// only an elevated admin can set the profile
+	var alice User
+	require.NoError(t, db.Where("name = ?", "alice").First(&alice).Error)
+	status, err := editAs(&alice, "capped-10g")
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	admin := &User{Name: IgorAdmin}
+	status, err = editAs(admin, "capped-20g")
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// the profile is applied right away to the ports of an installed reservation
+	_, err = editAs(admin, "capped-10g")
+	require.NoError(t, err)
+	stored, err := dbReadReservationsTx(map[string]interface{}{"name": res.Name}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "capped-10g", stored[0].NetProfile)
+	assert.Equal(t, "capped-10g", vlanDataOf(&stored[0]).NetProfile)
+	simVlansMU.Lock()
+	assert.Equal(t, "capped-10g", simNetProfiles["kn1"])
+	assert.Equal(t, "capped-10g", simNetProfiles["kn2"])
+	simVlansMU.Unlock()
+
+	// only admins see it with the reservation
+	summaries := filterReservationList(stored, admin)
+	assert.Equal(t, "capped-10g", summaries[0].NetProfile)
+	summaries = filterReservationList(stored, &alice)
+	assert.Empty(t, summaries[0].NetProfile)
+
+	_, err = editAs(admin, GroupNoneAlias)
+	require.NoError(t, err)
+	stored, err = dbReadReservationsTx(map[string]interface{}{"name": res.Name}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, stored[0].NetProfile)
+	simVlansMU.Lock()
+	assert.NotContains(t, simNetProfiles, "kn1")
+	simVlansMU.Unlock()
+}
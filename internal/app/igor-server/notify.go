@@ -9,6 +9,8 @@ import (
 	"crypto/tls"
 	"fmt"
 	"html/template"
+	"net/url"
+	"slices"
 	"strings"
 	"time"
 
@@ -18,6 +20,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// SmtpConnectTimeout is how long to wait on an SMTP server before moving on to the next one
+const SmtpConnectTimeout = 5 * time.Second
+
 var (
 	ResNotifyTimes = make([]time.Duration, 0)
 	tFuncs         template.FuncMap
@@ -26,21 +31,26 @@ var (
 
 func initNotify() {
 
-	if len(igor.Email.SmtpServer) > 0 {
+	if len(igor.Email.SmtpServers) > 0 {
 
 		tFuncs = template.FuncMap{
-			"safeText":       safeText,
-			"formatDts":      formatDts,
-			"formatHosts":    formatHosts,
-			"remainingTime":  remainingTime,
-			"ifFullName":     ifFullName,
-			"passwordLine":   passwordLine,
-			"passwordAction": passwordAction,
-			"emailOrName":    emailOrName,
-			"isAdmin":        isAdmin,
-			"resEdit":        resEdit,
-			"replaceInfo":    replaceInfo,
-			"ownerEmailList": ownerEmailList,
+			"safeText":        safeText,
+			"markdown":        renderMarkdown,
+			"formatDts":       formatDts,
+			"formatLocaleDts": formatLocaleDts,
+			"formatHosts":     formatHosts,
+			"remainingTime":   remainingTime,
+			"ifFullName":      ifFullName,
+			"passwordLine":    passwordLine,
+			"passwordAction":  passwordAction,
+			"emailOrName":     emailOrName,
+			"isAdmin":         isAdmin,
+			"resEdit":         resEdit,
+			"replaceInfo":     replaceInfo,
+			"ownerEmailList":  ownerEmailList,
+			"consoleLink":     consoleLink,
+			"distroNotes":     distroNotes,
+			"resLink":         resLink,
 		}
 
 		var t *template.Template
@@ -72,6 +82,13 @@ func initNotify() {
 		t, _ = t.Parse(SenderInfoTemplate)
 		tMap[EmailAcctRemovedIssue] = t
 
+		t = template.New("EmailAcctProfilesRemoved")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyAcctProfilesRemovedTemplate)
+		t, _ = t.Parse(SenderInfoTemplate)
+		tMap[EmailAcctProfilesRemoved] = t
+
 		t = template.New("EmailGroupCreated")
 		t.Funcs(tFuncs)
 		t = template.Must(t.Parse(BaseEmailTemplate))
@@ -128,6 +145,13 @@ func initNotify() {
 		setCommonInfo(t)
 		tMap[EmailResNewOwner] = t
 
+		t = template.New("EmailResCreatedForOwner")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResCreatedForOwnerTemplate)
+		setCommonInfo(t)
+		tMap[EmailResCreatedForOwner] = t
+
 		t = template.New("EmailResNewGroup")
 		t.Funcs(tFuncs)
 		t = template.Must(t.Parse(BaseEmailTemplate))
@@ -135,6 +159,90 @@ func initNotify() {
 		setCommonInfo(t)
 		tMap[EmailResNewGroup] = t
 
+		t = template.New("EmailResIdleWarn")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResIdleWarnTemplate)
+		setCommonInfo(t)
+		tMap[EmailResIdleWarn] = t
+
+		t = template.New("EmailResPause")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResPauseTemplate)
+		setCommonInfo(t)
+		tMap[EmailResPause] = t
+
+		t = template.New("EmailResResume")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResResumeTemplate)
+		setCommonInfo(t)
+		tMap[EmailResResume] = t
+
+		t = template.New("EmailResResumeFail")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResResumeFailTemplate)
+		setCommonInfo(t)
+		tMap[EmailResResumeFail] = t
+
+		t = template.New("EmailResInstallFail")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResInstallFailTemplate)
+		setCommonInfo(t)
+		tMap[EmailResInstallFail] = t
+
+		t = template.New("EmailResStartAdjust")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResStartAdjustTemplate)
+		setCommonInfo(t)
+		tMap[EmailResStartAdjust] = t
+
+		t = template.New("EmailResStartFail")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResStartFailTemplate)
+		setCommonInfo(t)
+		tMap[EmailResStartFail] = t
+
+		t = template.New("EmailResClaimInvite")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResClaimInviteTemplate)
+		setCommonInfo(t)
+		tMap[EmailResClaimInvite] = t
+
+		t = template.New("EmailResApproved")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResApprovedTemplate)
+		setCommonInfo(t)
+		tMap[EmailResApproved] = t
+
+		t = template.New("EmailResDenied")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResDeniedTemplate)
+		setCommonInfo(t)
+		tMap[EmailResDenied] = t
+
+		t = template.New("EmailResDistroAccess")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResDistroAccessTemplate)
+		setCommonInfo(t)
+		tMap[EmailResDistroAccess] = t
+
+		t = template.New("EmailResProfileChange")
+		t.Funcs(tFuncs)
+		t = template.Must(t.Parse(BaseEmailTemplate))
+		t, _ = t.Parse(NotifyResProfileChangeTemplate)
+		setCommonInfo(t)
+		tMap[EmailResProfileChange] = t
+
 		// if reservation notification is turned on, load these
 		if *igor.Email.ResNotifyOn {
 
@@ -172,7 +280,7 @@ func initNotify() {
 func safeText(s string) template.HTML { return template.HTML(s) }
 
 func formatDts(dts time.Time) string {
-	return dts.Format(common.DateTimeEmailFormat)
+	return formatLocaleDts(LocaleDefault, dts)
 }
 
 func formatHosts(hosts []Host) string {
@@ -181,27 +289,29 @@ func formatHosts(hosts []Host) string {
 	return hostRange
 }
 
-func remainingTime(end time.Time) string {
+// remainingTime describes the time left until end in the language of the given locale.
+func remainingTime(locale string, end time.Time) string {
 
 	timeLeft := time.Until(end).Round(time.Hour)
 	hours := int(timeLeft.Hours())
 	rDays := hours / 24
 	rHours := hours % 24
 
-	daysStr := "days"
-	hoursStr := "hours"
+	words := localeWords(locale)
+	daysStr := words.Days
+	hoursStr := words.Hours
 	if rDays == 1 {
-		daysStr = "day"
+		daysStr = words.Day
 	}
-	if hours == 1 {
-		hoursStr = "hour"
+	if rHours == 1 {
+		hoursStr = words.Hour
 	}
 
 	if rDays > 0 {
 		if rHours == 0 {
 			return fmt.Sprintf("%d %s", rDays, daysStr)
 		}
-		return fmt.Sprintf("%d %s and %d %s", rDays, daysStr, rHours, hoursStr)
+		return fmt.Sprintf("%d %s %s %d %s", rDays, daysStr, words.And, rHours, hoursStr)
 	}
 	return fmt.Sprintf("%d %s", rHours, hoursStr)
 }
@@ -220,6 +330,27 @@ func isAdmin(elevated bool) string {
 	return "a reservation group member"
 }
 
+// distroNotes returns the usage notes of the reservation's distro as HTML, empty if it has none.
+func distroNotes(r *Reservation) template.HTML {
+	if r.Profile.Distro.UsageNotes == "" {
+		return ""
+	}
+	return renderDistroNotes(r.Profile.Distro.UsageNotes)
+}
+
+// resLink returns the igorweb link to the reservation, empty if reservation links are not configured.
+func resLink(r *Reservation) string {
+	if igor.Server.ReservationURL == "" {
+		return ""
+	}
+	return fmt.Sprintf(igor.Server.ReservationURL, url.PathEscape(r.Name))
+}
+
+// consoleLink returns the console link of the host, empty if console links are not configured.
+func consoleLink(h Host) string {
+	return h.consoleLink()
+}
+
 func ownerEmailList(owners []User) template.HTML {
 	var emails strings.Builder
 	for i := 0; i < len(owners); i++ {
@@ -293,13 +424,16 @@ type AcctNotifyEvent struct {
 	NotifyEvent
 	IsLocal bool
 	User    *User
+	// Distro and Profiles name a force-deleted distro and the user's profiles removed with it
+	Distro   string
+	Profiles []string
 }
 
 // makeAcctNotifyEvent returns a struct to be sent over the 'notify' channel. It returns nil if the email config settings
 // prevent email from being sent.
 func makeAcctNotifyEvent(nType int, u *User) *AcctNotifyEvent {
 
-	if len(igor.Email.SmtpServer) == 0 {
+	if len(igor.Email.SmtpServers) == 0 {
 		logger.Debug().Msgf("no SMTP server defined - user email will not be sent")
 		return nil
 	}
@@ -359,6 +493,10 @@ func processAcctNotifyEvent(msg AcctNotifyEvent) error {
 			addEmailToList(&toList, igor.Email.HelpLink)
 		}
 		t = tMap[EmailAcctRemovedIssue]
+	case EmailAcctProfilesRemoved:
+		subj = "igor profiles removed with distro '" + msg.Distro + "'"
+		addEmailToList(&toList, msg.User.Email)
+		t = tMap[EmailAcctProfilesRemoved]
 	default:
 		err := fmt.Errorf("unrecognized notify type '%d' - aborting email send", msg.Type)
 		logger.Error().Msgf("%v", err)
@@ -384,7 +522,7 @@ type GroupNotifyEvent struct {
 // prevent email from being sent.
 func makeGroupNotifyEvent(nType int, g *Group, m *User, info string) *GroupNotifyEvent {
 
-	if len(igor.Email.SmtpServer) == 0 {
+	if len(igor.Email.SmtpServers) == 0 {
 		logger.Debug().Msgf("no SMTP server defined - user email will not be sent")
 		return nil
 	}
@@ -414,18 +552,16 @@ func processGroupNotifyEvent(msg GroupNotifyEvent) error {
 	case EmailGroupCreated:
 		subj = "new igor group '" + msg.Group.Name + "' created"
 		t = tMap[EmailGroupCreated]
-		for _, u := range msg.Group.Members {
-			addEmailToList(&toList, u.Email)
-		}
+		addGroupMailToList(&toList, msg.Group, msg.Group.Members...)
 	case EmailGroupAddMem:
 		subj = "igor: you have been added to group '" + msg.Group.Name + "'"
 		t = tMap[EmailGroupAddRmvMem]
-		addEmailToList(&toList, msg.Member.Email)
+		addGroupMailToList(&toList, msg.Group, *msg.Member)
 		msg.MemberAction = "added to"
 	case EmailGroupRmvMem:
 		subj = "igor: you have been removed from group '" + msg.Group.Name + "'"
 		t = tMap[EmailGroupAddRmvMem]
-		addEmailToList(&toList, msg.Member.Email)
+		addGroupMailToList(&toList, msg.Group, *msg.Member)
 		msg.MemberAction = "removed from"
 	case EmailGroupAddOwner:
 		subj = "igor: you have been added as an owner of group '" + msg.Group.Name + "'"
@@ -438,15 +574,18 @@ func processGroupNotifyEvent(msg GroupNotifyEvent) error {
 	case EmailGroupChangeName:
 		subj = "igor: group '" + msg.Info + "' has been renamed"
 		t = tMap[EmailGroupChangeName]
-		for _, u := range msg.Group.Members {
-			addEmailToList(&toList, u.Email)
-		}
+		addGroupMailToList(&toList, msg.Group, msg.Group.Members...)
 	default:
 		err := fmt.Errorf("unrecognized notify type '%d' - aborting email send", msg.Type)
 		logger.Error().Msgf("%v", err)
 		return err
 	}
 
+	if len(toList) == 0 {
+		logger.Debug().Msgf("every recipient of '%s' has opted out of group email (no email sent)", subj)
+		return nil
+	}
+
 	if err := sendEmail(t, subj, toList, ccList, bccList, false, msg); err != nil {
 		return err
 	}
@@ -462,13 +601,15 @@ type ResNotifyEvent struct {
 	ActionUser *User
 	IsElevated bool
 	Info       string
+	// ExtendLink is a one-click extend link included in expiration warnings, if enabled
+	ExtendLink string
 }
 
 // makeResWarnNotifyEvent returns a struct to be sent over the 'notify' channel. It returns nil if the email config settings
 // prevent email from being sent.
 func makeResEditNotifyEvent(nType int, r *Reservation, c string, actionUser *User, isElevated bool, info string) *ResNotifyEvent {
 
-	if len(igor.Email.SmtpServer) == 0 {
+	if len(igor.Email.SmtpServers) == 0 {
 		logger.Debug().Msgf("no SMTP server defined - user email will not be sent")
 		return nil
 	}
@@ -492,7 +633,7 @@ func makeResEditNotifyEvent(nType int, r *Reservation, c string, actionUser *Use
 // prevent email from being sent.
 func makeResWarnNotifyEvent(nType int, next time.Duration, r *Reservation, c string) *ResNotifyEvent {
 
-	if len(igor.Email.SmtpServer) == 0 {
+	if len(igor.Email.SmtpServers) == 0 {
 		logger.Debug().Msgf("no SMTP server defined - user email will not be sent")
 		return nil
 	}
@@ -549,9 +690,55 @@ func processResNotifyEvent(msg ResNotifyEvent) error {
 	case EmailResNewOwner:
 		subj = "igor: you are the new owner of reservation " + subjMid
 		t = tMap[EmailResNewOwner]
+	case EmailResCreatedForOwner:
+		subj = "igor: reservation " + subjMid + " has been made for you"
+		t = tMap[EmailResCreatedForOwner]
 	case EmailResNewGroup:
 		subj = "igor reservation " + subjMid + " is now accessible by members of group '" + msg.Res.Group.Name + "'"
 		t = tMap[EmailResNewGroup]
+	case EmailResIdleWarn:
+		subj = "igor reservation " + subjMid + " is idle and will be shortened"
+		t = tMap[EmailResIdleWarn]
+		priority = true
+	case EmailResPause:
+		subj = "igor reservation " + subjMid + " has been paused"
+		t = tMap[EmailResPause]
+	case EmailResResume:
+		subj = "igor reservation " + subjMid + " has resumed"
+		t = tMap[EmailResResume]
+	case EmailResResumeFail:
+		subj = "igor reservation " + subjMid + " could not resume"
+		t = tMap[EmailResResumeFail]
+		priority = true
+	case EmailResInstallFail:
+		subj = "igor reservation " + subjMid + " could not be installed on all of its hosts"
+		t = tMap[EmailResInstallFail]
+		priority = true
+	case EmailResStartAdjust:
+		subj = "igor reservation " + subjMid + " has changed hosts at start"
+		t = tMap[EmailResStartAdjust]
+		priority = true
+	case EmailResStartFail:
+		subj = "igor reservation " + subjMid + " could not start"
+		t = tMap[EmailResStartFail]
+		priority = true
+	case EmailResClaimInvite:
+		subj = "igor reservation " + subjMid + " needs a new owner"
+		t = tMap[EmailResClaimInvite]
+		priority = true
+	case EmailResApproved:
+		subj = "igor reservation " + subjMid + " has been approved"
+		t = tMap[EmailResApproved]
+	case EmailResDenied:
+		subj = "igor reservation " + subjMid + " was not approved"
+		t = tMap[EmailResDenied]
+		priority = true
+	case EmailResDistroAccess:
+		subj = "igor reservation " + subjMid + " uses a distro you no longer have access to"
+		t = tMap[EmailResDistroAccess]
+	case EmailResProfileChange:
+		subj = "igor reservation " + subjMid + " has a new profile"
+		t = tMap[EmailResProfileChange]
 	case EmailResExtend:
 		subj = "igor reservation " + subjMid + " has been extended"
 		t = tMap[EmailResEdit]
@@ -574,26 +761,71 @@ func processResNotifyEvent(msg ResNotifyEvent) error {
 		return err
 	}
 
+	// a warning that can't carry its extend link is still worth sending
+	if (msg.Type == EmailResWarn || msg.Type == EmailResFinalWarn) && extendLinksEnabled() {
+		if link, err := newResExtendLink(msg.Res); err != nil {
+			logger.Error().Msgf("failed to make extend link for reservation '%s': %v", msg.Res.Name, err)
+		} else {
+			msg.ExtendLink = link
+		}
+	}
+
+	// co-owners receive the same mail as the owner, except for notice of an ownership transfer
+	// or of a reservation made for the owner, which only go to the owner. A denied request
+	// was never visible to the group so it only goes to the owner as well, and only the owner
+	// lost access to the distro when its groups changed.
+	ownerOnlyMail := msg.Type == EmailResNewOwner || msg.Type == EmailResCreatedForOwner || msg.Type == EmailResDenied ||
+		msg.Type == EmailResDistroAccess
+	isCoOwnerMail := !ownerOnlyMail
+
 	if strings.HasPrefix(msg.Res.Group.Name, GroupUserPrefix) {
 		toList = append(toList, msg.Res.Owner.Email)
+		if isCoOwnerMail {
+			for _, u := range msg.Res.CoOwners {
+				addEmailToList(&toList, u.Email)
+			}
+		}
 	} else {
-		queryParams := map[string]interface{}{"name": msg.Res.Group.Name, "showMembers": true}
+		// the event holds a copy of the reservation, so look the group up by ID in case it was renamed
+		queryParams := map[string]interface{}{"id": []int{msg.Res.GroupID}, "showMembers": true}
 		if group, err := dbReadGroupsTx(queryParams, true); err != nil {
 			return err
 		} else if len(group) > 0 {
 			for _, u := range group[0].Members {
 				if u.Name == msg.Res.Owner.Name {
 					addEmailToList(&toList, u.Email)
-				} else if msg.Type != EmailResNewOwner {
-					// cc everyone in group except on owner change
+				} else if isCoOwnerMail && msg.Res.isCoOwner(u.Name) {
+					// co-owners in the group are addressed directly below
+					continue
+				} else if !ownerOnlyMail && u.wantsResCcMail(msg.Type) {
+					// cc everyone in group except on owner change, unless they have opted out
 					addEmailToList(&ccList, u.Email)
 				}
 			}
 		} else {
-			err = fmt.Errorf("unrecognized group name '%s' when trying to notify - no email sent", msg.Res.Group.Name)
+			err = fmt.Errorf("unrecognized group '%s' when trying to notify - no email sent", msg.Res.Group.Name)
 			logger.Error().Msgf("%v", err)
 			return err
 		}
+		if isCoOwnerMail {
+			for _, u := range msg.Res.CoOwners {
+				addEmailToList(&toList, u.Email)
+			}
+		}
+	}
+
+	// the owner's delegate and anyone else asked to follow the reservation are copied on all of its
+	// mail except what is meant for the owner alone
+	if !ownerOnlyMail {
+		delegates, dErr := resDelegateEmails(msg.Res)
+		if dErr != nil {
+			return dErr
+		}
+		for _, addr := range delegates {
+			if !slices.Contains(toList, addr) {
+				addEmailToList(&ccList, addr)
+			}
+		}
 	}
 
 	if err := sendEmail(t, subj, toList, ccList, nil, priority, msg); err != nil {
@@ -625,6 +857,115 @@ func processResNotifyEvent(msg ResNotifyEvent) error {
 	return nil
 }
 
+// resDelegateEmails returns the addresses of the reservation owner's notification delegate and the
+// users the reservation names to notify. The delegate is looked up by name so one who has since been
+// removed from igor is skipped.
+func resDelegateEmails(res *Reservation) ([]string, error) {
+
+	var emails []string
+	if res.Owner.NotifyDelegate != "" {
+		delegates, err := dbReadUsersTx(map[string]interface{}{"name": []string{res.Owner.NotifyDelegate}})
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range delegates {
+			emails = append(emails, u.Email)
+		}
+	}
+	for _, u := range res.NotifyAlso {
+		emails = append(emails, u.Email)
+	}
+	return emails, nil
+}
+
+const (
+	// NotifyResCcAll copies a group member on all email about the group's reservations (the default)
+	NotifyResCcAll = "all"
+	// NotifyResCcFinal only copies a group member on final expiration warnings
+	NotifyResCcFinal = "final"
+	// NotifyResCcNone never copies a group member on email about the group's reservations
+	NotifyResCcNone = "none"
+)
+
+// parseNotifyPrefs reads the notification preferences a user gives as a list of key=value
+// pairs, ex. res-cc=final,group=no, and returns the User fields to change. 'default' puts back
+// the preferences every user starts with.
+func parseNotifyPrefs(prefs string) (map[string]interface{}, error) {
+
+	changes := map[string]interface{}{}
+	if strings.TrimSpace(prefs) == "default" {
+		changes["ResCcMail"] = ""
+		changes["NoGroupMail"] = false
+		return changes, nil
+	}
+
+	for _, pref := range strings.Split(prefs, ",") {
+		key, val, found := strings.Cut(strings.TrimSpace(pref), "=")
+		if !found {
+			return nil, fmt.Errorf("notification preference '%s' must be given as key=value", pref)
+		}
+		switch key {
+		case "res-cc":
+			switch val {
+			case NotifyResCcAll:
+				changes["ResCcMail"] = ""
+			case NotifyResCcFinal, NotifyResCcNone:
+				changes["ResCcMail"] = val
+			default:
+				return nil, fmt.Errorf("res-cc must be %s, %s or %s", NotifyResCcAll, NotifyResCcFinal, NotifyResCcNone)
+			}
+		case "group":
+			switch val {
+			case "yes":
+				changes["NoGroupMail"] = false
+			case "no":
+				changes["NoGroupMail"] = true
+			default:
+				return nil, fmt.Errorf("group must be yes or no")
+			}
+		default:
+			return nil, fmt.Errorf("unknown notification preference '%s' - must be res-cc or group", key)
+		}
+	}
+	return changes, nil
+}
+
+// notifyPrefs describes the user's notification preferences in the form parseNotifyPrefs reads.
+func (u *User) notifyPrefs() string {
+	resCc, group := NotifyResCcAll, "yes"
+	if u.ResCcMail != "" {
+		resCc = u.ResCcMail
+	}
+	if u.NoGroupMail {
+		group = "no"
+	}
+	return "res-cc=" + resCc + ",group=" + group
+}
+
+// wantsResCcMail returns true if the user wants to be copied on email of the given type about a
+// reservation of one of their groups. It is not used for reservations the user owns.
+func (u *User) wantsResCcMail(nType int) bool {
+	switch u.ResCcMail {
+	case NotifyResCcNone:
+		return false
+	case NotifyResCcFinal:
+		return nType == EmailResFinalWarn
+	default:
+		return true
+	}
+}
+
+// addGroupMailToList adds the addresses of the given users to the list of recipients of email about
+// the membership of a group, leaving out those who have opted out. Group owners always get it.
+func addGroupMailToList(mList *[]string, g *Group, users ...User) {
+	owners := userNamesOfUsers(g.Owners)
+	for _, u := range users {
+		if !u.NoGroupMail || slices.Contains(owners, u.Name) {
+			addEmailToList(mList, u.Email)
+		}
+	}
+}
+
 func addEmailToList(mList *[]string, addr string) {
 	if addr != "" {
 		*mList = append(*mList, addr)
@@ -636,11 +977,6 @@ func sendEmail(t *template.Template, subject string, toList []string, ccList []s
 	if len(toList) == 0 && len(ccList) == 0 && len(bccList) == 0 {
 		return fmt.Errorf("no recipient address for outbound email, subject: %v", subject)
 	}
-	// Settings for SMTP server
-	d := gomail.NewDialer(igor.Email.SmtpServer, igor.Email.SmtpPort, igor.Email.SmtpUsername, igor.Email.SmtpPassword)
-	d.RetryFailure = false
-	d.TLSConfig = &tls.Config{ServerName: igor.Email.SmtpServer}
-
 	var msgs []*gomail.Message
 
 	for _, info := range mInfo {
@@ -678,13 +1014,62 @@ func sendEmail(t *template.Template, subject string, toList []string, ccList []s
 		msgs = append(msgs, m)
 	}
 
-	if mailErr := d.DialAndSend(msgs...); mailErr != nil {
-		logger.Error().Msgf("%v", mailErr)
+	if mailErr := deliverEmail(igor.Email.SmtpServers, msgs); mailErr != nil {
+		logEvent(&logger, EvEmailSendFailed).Str(LogFieldSubject, subject).Err(mailErr).Msgf("%v", mailErr)
 		return mailErr
 	}
 	return nil
 }
 
+// smtpDial connects to the SMTP server of the dialer. It is a variable so tests can stand in for
+// the server.
+var smtpDial = func(d *gomail.Dialer) (gomail.SendCloser, error) {
+	return d.Dial()
+}
+
+// deliverEmail sends the messages through the first of the SMTP servers that will take them. If a
+// server can't be reached or fails partway through, the messages it hasn't accepted are sent
+// through the next server in the list.
+func deliverEmail(servers []SmtpServerConfig, msgs []*gomail.Message) error {
+
+	if len(servers) == 0 {
+		return fmt.Errorf("no SMTP server is configured")
+	}
+
+	var lastErr error
+	for _, s := range servers {
+
+		d := gomail.NewDialer(s.Host, s.Port, s.Username, s.Password)
+		d.RetryFailure = false
+		d.Timeout = SmtpConnectTimeout
+		d.TLSConfig = &tls.Config{ServerName: s.Host}
+
+		sc, err := smtpDial(d)
+		if err != nil {
+			lastErr = fmt.Errorf("SMTP server %s:%d: %v", s.Host, s.Port, err)
+			logger.Warn().Msgf("unable to connect to %v - trying the next server", lastErr)
+			continue
+		}
+
+		for len(msgs) > 0 {
+			if err = gomail.Send(sc, msgs[0]); err != nil {
+				break
+			}
+			msgs = msgs[1:]
+		}
+		_ = sc.Close()
+
+		if err == nil {
+			logger.Debug().Msgf("email delivered by SMTP server %s:%d", s.Host, s.Port)
+			return nil
+		}
+		lastErr = fmt.Errorf("SMTP server %s:%d: %v", s.Host, s.Port, err)
+		logger.Warn().Msgf("unable to send email through %v - trying the next server", lastErr)
+	}
+
+	return fmt.Errorf("email not sent, no SMTP server accepted it - last error from %v", lastErr)
+}
+
 func dedupeEmailList(emailList []string) []string {
 	emailSet := common.NewSet()
 	emailSet.Add(emailList...)
@@ -698,6 +1083,19 @@ const (
 	EmailResNewGroup
 	EmailResDrop
 	EmailResBlock
+	EmailResIdleWarn
+	EmailResPause
+	EmailResResume
+	EmailResResumeFail
+	EmailResInstallFail
+	EmailResCreatedForOwner
+	EmailResStartAdjust
+	EmailResStartFail
+	EmailResClaimInvite
+	EmailResApproved
+	EmailResDenied
+	EmailResDistroAccess
+	EmailResProfileChange
 	EmailResEdit = 1029
 )
 
@@ -713,6 +1111,7 @@ const (
 	EmailAcctCreated = iota + 1200
 	EmailPasswordReset
 	EmailAcctRemovedIssue
+	EmailAcctProfilesRemoved
 )
 
 const (
@@ -730,10 +1129,20 @@ const (
 {{template "mail-body" .}}
 {{define "res-info"}}
 <p>Reservation Name: {{.Res.Name}}
-<br>Started: {{formatDts .Res.Start}}
-<br>Ends: {{formatDts .Res.End}}
-<br>Hosts: {{formatHosts .Res.Hosts}}</p>
-{{end}}`
+<br>Started: {{formatLocaleDts .Res.Owner.Locale .Res.Start}}
+<br>Ends: {{formatLocaleDts .Res.Owner.Locale .Res.End}}
+<br>Hosts: {{formatHosts .Res.Hosts}}{{with .Res.Description}}
+<br>Description: {{markdown .}}{{end}}</p>
+{{end}}
+{{define "getting-started"}}{{with distroNotes .Res}}
+<p><b>Getting started with {{$.Res.Profile.Distro.Name}}</b></p>
+
+<p>Hosts: {{formatHosts $.Res.Hosts}}{{if $.Res.Vlan}}
+<br>VLAN: {{$.Res.Vlan}}{{end}}{{with resLink $.Res}}
+<br>Details: <a href="{{.}}">{{.}}</a>{{end}}</p>
+
+{{.}}
+{{end}}{{end}}`
 
 	SenderInfoTemplate = `
 {{template "mail-body" .}}
@@ -800,6 +1209,22 @@ const (
 <p>Greetings,</p>
 
 <p>Ownership of the reservation '{{.Res.Name}}' has been transferred to you. If you have questions please contact the former owner, <a href="mailto:{{.ActionUser.Email}}">{{emailOrName .ActionUser}}</a>.
+{{block "getting-started" .}}{{end}}
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+	NotifyResCreatedForOwnerTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>The reservation '{{.Res.Name}}' on the {{.Cluster}} cluster has been made for you by <a href="mailto:{{.ActionUser.Email}}">{{emailOrName .ActionUser}}</a>, acting in their role as {{isAdmin .IsElevated}}. You are its owner and will receive its notices from now on.</p>
+{{if .Info}}
+<p>You have been given access to the distro '{{.Info}}' so it can be installed on the reservation's hosts.</p>
+{{end}}
+{{block "res-info" .}}{{end}}
+
+<p>If you have questions please contact <a href="mailto:{{.ActionUser.Email}}">{{emailOrName .ActionUser}}</a>.</p>
 
 {{block "sender-info" .}}{{end}}
 {{end}}
@@ -836,7 +1261,7 @@ const (
 
 <p>The following reservation was registered on the {{.Cluster}} cluster to start at the date listed below. It is now active.</p>
 
-{{block "res-info" .}}{{end}}
+{{block "res-info" .}}{{end}}{{block "getting-started" .}}{{end}}
 
 {{block "sender-info" .}}{{end}}
 {{end}}`
@@ -846,8 +1271,10 @@ const (
 {{define "mail-body"}}
 <p>Greetings,</p>
 
-<p>The following reservation on the {{.Cluster}} cluster has {{remainingTime .Res.End}} left before it expires. You may use the 'extend' command if you wish to continue using this reservation beyond its current end date.</p>
-
+<p>The following reservation on the {{.Cluster}} cluster has {{remainingTime .Res.Owner.Locale .Res.End}} left before it expires. You may use the 'extend' command if you wish to continue using this reservation beyond its current end date.</p>
+{{with .ExtendLink}}
+<p>You can also <a href="{{.}}">extend this reservation</a> as far as you are allowed with one click. The link works once and stops working if the reservation changes owner.</p>
+{{end}}
 {{block "res-info" .}}{{end}}
 
 {{block "sender-info" .}}{{end}}
@@ -858,9 +1285,205 @@ const (
 {{define "mail-body"}}
 <p>Greetings,</p>
 
-<p>The following reservation on the {{.Cluster}} cluster has {{remainingTime .Res.End}} left before it expires. This is your final notice.</p>
+<p>The following reservation on the {{.Cluster}} cluster has {{remainingTime .Res.Owner.Locale .Res.End}} left before it expires. This is your final notice.</p>
 
 <p>If the administrators have allowed use of the 'extend' command you may be able to continue the reservation beyond its current end date. If you do so a new warning email will be sent at the appropriate time.</p>
+{{with .ExtendLink}}
+<p>You can also <a href="{{.}}">extend this reservation</a> as far as you are allowed with one click. The link works once and stops working if the reservation changes owner.</p>
+{{end}}
+{{block "res-info" .}}{{end}}
+
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyResIdleWarnTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>The following reservation on the {{.Cluster}} cluster has been installed since {{formatLocaleDts .Res.Owner.Locale .Res.Start}}, but none of its hosts have been powered on.</p>
+
+<p>To free up idle hosts for other users, this reservation will be shortened to end one hour after {{.Info}}. To keep the reservation as it is, power on any of its hosts or run 'igor res edit {{.Res.Name}} --keep' before then.</p>
+
+{{block "res-info" .}}{{end}}
+
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyResPauseTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>The reservation '{{.Res.Name}}' on the {{.Cluster}} cluster has been paused by <a href="mailto:{{.ActionUser.Email}}">{{emailOrName .ActionUser}}</a>. Its hosts have been released for use by others until {{.Info}}.</p>
+
+<p>At that time the reservation will resume and its hosts will be installed again. If any of its hosts are no longer free it will not resume, and you will be sent a list of the hosts that are unavailable.</p>
+
+{{block "res-info" .}}{{end}}
+
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyResResumeTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>The paused reservation '{{.Res.Name}}' on the {{.Cluster}} cluster has resumed{{if .ActionUser}} at the request of <a href="mailto:{{.ActionUser.Email}}">{{emailOrName .ActionUser}}</a>{{end}}. Its hosts are being installed and you will be notified when it has started.</p>
+{{if .Info}}
+<p>Some of the reservation's hosts were no longer free and substitutes were used: {{.Info}}</p>
+{{end}}
+{{block "res-info" .}}{{end}}
+
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyResResumeFailTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>The paused reservation '{{.Res.Name}}' on the {{.Cluster}} cluster could not resume at its scheduled time.</p>
+
+<p>{{.Info}}</p>
+
+<p>The reservation remains paused. Run 'igor res resume {{.Res.Name}} --substitute' to resume it on other available hosts, or delete the reservation if it is no longer needed.</p>
+
+{{block "res-info" .}}{{end}}
+
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyResInstallFailTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>The reservation '{{.Res.Name}}' on the {{.Cluster}} cluster has started, but its profile could not be installed on the hosts listed below. igor will keep trying to install them.</p>
+
+<ul>
+{{range .Res.Hosts}}{{if .InstallError}}<li>{{.Name}}: {{.InstallError}}{{with consoleLink .}} (<a href="{{.}}">console</a>){{end}}</li>
+{{end}}{{end}}</ul>
+
+{{block "res-info" .}}{{end}}
+
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyResStartAdjustTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>Some hosts of the reservation '{{.Res.Name}}' on the {{.Cluster}} cluster were blocked, draining or in an error state when it was due to start. The reservation is starting with these changes: {{.Info}}</p>
+
+<p>The modified reservation's current info:</p>
+
+{{block "res-info" .}}{{end}}
+
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyResStartFailTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>The reservation '{{.Res.Name}}' on the {{.Cluster}} cluster could not start because too many of its hosts are unavailable.</p>
+
+<p>{{.Info}}</p>
+
+<p>The reservation will not be installed. Delete it and make a new reservation, or contact an igor admin about the unavailable hosts.</p>
+
+{{block "res-info" .}}{{end}}
+
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyResClaimInviteTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>{{.Res.Owner.Name}}, the owner of reservation '{{.Res.Name}}' on the {{.Cluster}} cluster, is no longer an igor user and their account will be removed on {{.Info}}.</p>
+
+<p>Any member of the group '{{.Res.Group.Name}}' can take over the reservation before then by running:</p>
+
+<p><code>igor res claim {{.Res.Name}}</code></p>
+
+<p>If no one claims it, the reservation will be handed to igor-admin when the account is removed.</p>
+
+{{block "res-info" .}}{{end}}
+
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyResApprovedTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>The reservation '{{.Res.Name}}' on the {{.Cluster}} cluster has been approved by an igor admin and will start as scheduled.</p>
+{{if .Info}}
+<p>Note from the admin: {{.Info}}</p>
+{{end}}
+{{block "res-info" .}}{{end}}
+
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyResDeniedTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>The reservation '{{.Res.Name}}' on the {{.Cluster}} cluster needed approval from an igor admin and was not approved. The reservation has been deleted and its hosts released.</p>
+
+<p>Reason: {{.Info}}</p>
+
+<p>You can make a smaller or shorter reservation, or contact an igor admin about your request.</p>
+
+{{block "res-info" .}}{{end}}
+
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyResDistroAccessTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>The group(s) '{{.Info}}' have been removed from the distro '{{.Res.Profile.Distro.Name}}' on the {{.Cluster}} cluster by <a href="mailto:{{.ActionUser.Email}}">{{emailOrName .ActionUser}}</a>. Your reservation '{{.Res.Name}}' uses this distro and you no longer have access to it through any of your groups.</p>
+
+<p>The reservation will keep running as it is, but you will not be able to use the distro again if you change the reservation's profile or make a new reservation. Contact the distro owner or an igor admin if you still need it.</p>
+
+{{block "res-info" .}}{{end}}
+
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyResProfileChangeTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>The reservation '{{.Res.Name}}' on the {{.Cluster}} cluster has been changed to use profile '{{.Res.Profile.Name}}' (distro '{{.Res.Profile.Distro.Name}}') by <a href="mailto:{{.ActionUser.Email}}">{{emailOrName .ActionUser}}</a>.</p>
+
+<p>{{.Info}}</p>
+
+<p>This action was undertaken in their role as {{isAdmin .IsElevated}}.</p>
 
 {{block "res-info" .}}{{end}}
 
@@ -908,6 +1531,23 @@ const (
 
 <p>Review these resources and either delete or re-assign their ownership to users they were shared with. Check logs for more information.</p>
 
+{{block "sender-info" .}}{{end}}
+{{end}}
+`
+
+	NotifyAcctProfilesRemovedTemplate = `
+{{template "base" .}}
+{{define "mail-body"}}
+<p>Greetings,</p>
+
+<p>The distro '{{.Distro}}' has been deleted by an igor admin. The following profiles of yours used it and were deleted along with it:</p>
+
+<ul>
+{{range .Profiles}}<li>{{.}}</li>
+{{end}}</ul>
+
+<p>If you still need them, make new profiles with another distro using 'igor profile create'.</p>
+
 {{block "sender-info" .}}{{end}}
 {{end}}
 `
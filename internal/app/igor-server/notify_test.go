@@ -0,0 +1,444 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gomail "gopkg.in/mail.v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func renderResEmail(t *testing.T, nType int, res *Reservation) string {
+	return renderResEditEmail(t, nType, res, nil, "")
+}
+
+// renderResEditEmail renders an email about a change to the reservation made by actionUser as an admin.
+func renderResEditEmail(t *testing.T, nType int, res *Reservation, actionUser *User, info string) string {
+
+	origSmtp, origNotify, origRefs := igor.Email.SmtpServers, igor.Email.ResNotifyOn, igor.ClusterRefs
+	defer func() {
+		igor.Email.SmtpServers, igor.Email.ResNotifyOn, igor.ClusterRefs = origSmtp, origNotify, origRefs
+	}()
+
+	notifyOn := true
+	igor.Email.SmtpServers = []SmtpServerConfig{{Host: "smtp.example.com", Port: DefaultSmtpPort}}
+	igor.Email.ResNotifyOn = &notifyOn
+	r, _ := common.NewRange("kn", 1, 10)
+	igor.ClusterRefs = []common.Range{*r}
+	initNotify()
+
+	msg := makeResWarnNotifyEvent(nType, 0, res, "krypton")
+	assert.NotNil(t, msg)
+	msg.ActionUser, msg.IsElevated, msg.Info = actionUser, actionUser != nil, info
+
+	var body bytes.Buffer
+	assert.NoError(t, tMap[nType].Execute(&body, msg))
+	return body.String()
+}
+
+func TestResEmailLocaleDates(t *testing.T) {
+
+	start := time.Date(2024, time.February, 3, 14, 30, 0, 0, time.UTC)
+	res := &Reservation{
+		Name:  "myres",
+		Start: start,
+		End:   time.Now().Add(49*time.Hour + 10*time.Minute),
+		Owner: User{Name: "tombomb", Locale: LocaleISO},
+		Hosts: []Host{{Name: "kn1"}, {Name: "kn2"}},
+	}
+
+	body := renderResEmail(t, EmailResWarn, res)
+	assert.Contains(t, body, "Started: 2024-02-03 14:30 UTC")
+	assert.Contains(t, body, "Ends: "+res.End.Format("2006-01-02 15:04 MST"))
+	assert.Contains(t, body, "has 2 days and 1 hour left")
+	assert.NotContains(t, body, "February 3, 2024")
+
+	// no preference keeps the original US layout
+	res.Owner.Locale = ""
+	body = renderResEmail(t, EmailResWarn, res)
+	assert.Contains(t, body, "Started: February 3, 2024 - 2:30 PM UTC")
+
+	res.Owner.Locale = "de-AT"
+	body = renderResEmail(t, EmailResWarn, res)
+	assert.Contains(t, body, "Started: 03.02.2024 14:30 UTC")
+	assert.Contains(t, body, "has 2 Tage und 1 Stunde left")
+}
+
+func TestCheckLocaleRules(t *testing.T) {
+	assert.NoError(t, checkLocaleRules("ISO"))
+	assert.NoError(t, checkLocaleRules("en_gb"))
+	assert.NoError(t, checkLocaleRules("fr"))
+	assert.Error(t, checkLocaleRules("xx-YY"))
+	assert.Equal(t, "en-GB", normalizeLocale("en_gb"))
+	assert.Equal(t, localeDateFormats["en"], localeDateFormat("en-GB"))
+	assert.Equal(t, localeDateFormats[LocaleDefault], localeDateFormat("xx"))
+	assert.Equal(t, "hours", localeWords("xx").Hours)
+}
+
+func TestResInstallFailEmail(t *testing.T) {
+
+	setConsoleURL(t, "https://console.example.com/%s")
+
+	res := &Reservation{
+		Name:  "myres",
+		Start: time.Now(),
+		End:   time.Now().Add(time.Hour),
+		Owner: User{Name: "tombomb"},
+		Hosts: []Host{
+			{Name: "kn1"},
+			{Name: "kn2", Console: "kn2-bmc", InstallError: "unable to write PXE file"},
+		},
+	}
+
+	body := renderResEmail(t, EmailResInstallFail, res)
+	assert.Contains(t, body, "kn2: unable to write PXE file")
+	assert.Contains(t, body, `<a href="https://console.example.com/kn2-bmc">console</a>`)
+	assert.NotContains(t, body, "console.example.com/kn1")
+
+	// no links without a console URL
+	igor.Server.ConsoleURL = ""
+	body = renderResEmail(t, EmailResInstallFail, res)
+	assert.Contains(t, body, "kn2: unable to write PXE file")
+	assert.NotContains(t, body, "console</a>")
+}
+
+func TestResCreatedForOwnerEmail(t *testing.T) {
+
+	res := &Reservation{
+		Name:  "myres",
+		Start: time.Now(),
+		End:   time.Now().Add(time.Hour),
+		Owner: User{Name: "tombomb"},
+		Hosts: []Host{{Name: "kn1"}},
+	}
+	admin := &User{Name: "boss", Email: "boss@example.com"}
+
+	body := renderResEditEmail(t, EmailResCreatedForOwner, res, admin, "")
+	assert.Contains(t, body, "'myres' on the krypton cluster has been made for you by")
+	assert.Contains(t, body, "mailto:boss@example.com")
+	assert.Contains(t, body, "an igor administrator")
+	assert.NotContains(t, body, "given access to the distro")
+
+	body = renderResEditEmail(t, EmailResCreatedForOwner, res, admin, "cent7")
+	assert.Contains(t, body, "given access to the distro 'cent7'")
+}
+
+func TestResStartEmailDistroNotes(t *testing.T) {
+
+	origURL := igor.Server.ReservationURL
+	t.Cleanup(func() { igor.Server.ReservationURL = origURL })
+	igor.Server.ReservationURL = "https://igorweb.example.com/reservationtable?name=%s"
+
+	res := &Reservation{
+		Name:    "myres",
+		Start:   time.Now(),
+		End:     time.Now().Add(time.Hour),
+		Owner:   User{Name: "tombomb"},
+		Hosts:   []Host{{Name: "kn1"}, {Name: "kn2"}},
+		Vlan:    101,
+		Profile: Profile{Distro: Distro{Name: "cent7"}},
+	}
+	admin := &User{Name: "boss", Email: "boss@example.com"}
+
+	// a distro without notes gets the usual emails
+	body := renderResEmail(t, EmailResStart, res)
+	assert.Contains(t, body, "It is now active.")
+	assert.NotContains(t, body, "Getting started")
+	assert.NotContains(t, body, "igorweb.example.com")
+	body = renderResEditEmail(t, EmailResNewOwner, res, admin, "")
+	assert.NotContains(t, body, "Getting started")
+
+	res.Profile.Distro.UsageNotes = "Log in as `root` with password **changeme**\n<script>alert(1)</script>"
+	for _, nType := range []int{EmailResStart, EmailResNewOwner} {
+		body = renderResEditEmail(t, nType, res, admin, "")
+		assert.Contains(t, body, "Getting started with cent7")
+		assert.Contains(t, body, "Hosts: kn[1-2]")
+		assert.Contains(t, body, "VLAN: 101")
+		assert.Contains(t, body, `<a href="https://igorweb.example.com/reservationtable?name=myres">`)
+		assert.Contains(t, body, "Log in as <code>root</code> with password <b>changeme</b>")
+		assert.NotContains(t, body, "script")
+	}
+}
+
+// fakeSmtpServer stands in for an SMTP server, failing after it accepts failAfter messages when
+// failAfter isn't negative.
+type fakeSmtpServer struct {
+	failAfter int
+	sent      int
+	// rcpts are the recipients of each message accepted
+	rcpts [][]string
+}
+
+func (f *fakeSmtpServer) Send(_ string, to []string, _ io.WriterTo) error {
+	if f.failAfter >= 0 && f.sent >= f.failAfter {
+		return fmt.Errorf("451 try again later")
+	}
+	f.sent++
+	f.rcpts = append(f.rcpts, to)
+	return nil
+}
+
+func (f *fakeSmtpServer) Close() error { return nil }
+
+func TestDeliverEmailFailover(t *testing.T) {
+
+	origDial := smtpDial
+	defer func() { smtpDial = origDial }()
+
+	servers := []SmtpServerConfig{
+		{Host: "down.example.com", Port: 25, Username: "u1", Password: "p1"},
+		{Host: "flaky.example.com", Port: 587, Username: "u2", Password: "p2"},
+		{Host: "up.example.com", Port: 587},
+	}
+	fakes := map[string]*fakeSmtpServer{
+		"flaky.example.com": {failAfter: 1},
+		"up.example.com":    {failAfter: -1},
+	}
+	var tried []string
+	smtpDial = func(d *gomail.Dialer) (gomail.SendCloser, error) {
+		tried = append(tried, fmt.Sprintf("%s:%d %s/%s", d.Host, d.Port, d.Username, d.Password))
+		assert.Equal(t, SmtpConnectTimeout, d.Timeout)
+		if f, ok := fakes[d.Host]; ok {
+			return f, nil
+		}
+		return nil, fmt.Errorf("connection refused")
+	}
+
+	newMsg := func() *gomail.Message {
+		m := gomail.NewMessage()
+		m.SetHeader("From", "igor@example.com")
+		m.SetHeader("To", "alice@example.com")
+		return m
+	}
+
+	assert.NoError(t, deliverEmail(servers, []*gomail.Message{newMsg(), newMsg(), newMsg()}))
+	// each server is given only its own credentials, in the order configured
+	assert.Equal(t, []string{"down.example.com:25 u1/p1", "flaky.example.com:587 u2/p2", "up.example.com:587 /"}, tried)
+	// what the flaky server accepted isn't sent again
+	assert.Equal(t, 1, fakes["flaky.example.com"].sent)
+	assert.Equal(t, 2, fakes["up.example.com"].sent)
+
+	// a healthy first server is the only one tried
+	tried = nil
+	assert.NoError(t, deliverEmail(servers[2:], []*gomail.Message{newMsg()}))
+	assert.Len(t, tried, 1)
+
+	err := deliverEmail(servers[:1], []*gomail.Message{newMsg()})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "down.example.com")
+	}
+}
+
+func TestCheckSmtpServers(t *testing.T) {
+
+	servers := []SmtpServerConfig{{Host: "a.example.com"}, {Host: "b.example.com", Port: 25}}
+	assert.NoError(t, checkSmtpServers(servers))
+	assert.Equal(t, DefaultSmtpPort, servers[0].Port)
+	assert.Equal(t, 25, servers[1].Port)
+
+	err := checkSmtpServers([]SmtpServerConfig{{Host: "a.example.com"}, {Port: 25, Username: "igor"}})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "entry 2")
+	}
+
+	// the password stays out of the logged config
+	assert.NotContains(t, fmt.Sprint([]SmtpServerConfig{{Host: "a.example.com", Password: "secret"}}), "secret")
+}
+
+func TestResNotifyDelegates(t *testing.T) {
+
+	origEmail, origRefs, origDial := igor.Email, igor.ClusterRefs, smtpDial
+	t.Cleanup(func() { igor.Email, igor.ClusterRefs, smtpDial = origEmail, origRefs, origDial })
+	notifyOn := true
+	igor.Email.SmtpServers = []SmtpServerConfig{{Host: "smtp.example.com", Port: DefaultSmtpPort}}
+	igor.Email.DefaultSuffix = "example.com"
+	igor.Email.ResNotifyOn = &notifyOn
+	r, _ := common.NewRange("kn", 1, 10)
+	igor.ClusterRefs = []common.Range{*r}
+	initNotify()
+	smtp := &fakeSmtpServer{failAfter: -1}
+	smtpDial = func(*gomail.Dialer) (gomail.SendCloser, error) { return smtp, nil }
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	newUser := func(name string) User {
+		pug := Group{Name: GroupUserPrefix + name, IsUserPrivate: true}
+		require.NoError(t, db.Omit(clause.Associations).Create(&pug).Error)
+		u := User{Name: name, Email: name + "@example.com", Groups: []Group{pug, all}}
+		require.NoError(t, db.Omit("Groups.*").Create(&u).Error)
+		return u
+	}
+	alice := newUser("alice")
+	newUser("bob")
+	carol := newUser("carol")
+
+	// the delegate must be another igor user
+	req := httptest.NewRequest(http.MethodPatch, "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), userContextKey{}, &alice))
+	_, status, err := doUpdateUser("alice", map[string]interface{}{"notifyDelegate": "alice"}, req)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	_, status, err = doUpdateUser("alice", map[string]interface{}{"notifyDelegate": "mallory"}, req)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+	_, status, err = doUpdateUser("alice", map[string]interface{}{"notifyDelegate": "bob"}, req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	res := newStartTestRes(t, db, "trip", hosts[:1], false, 0)
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		changes, _, pErr := parseResEditParams(res, map[string]interface{}{"notifyAlso": []interface{}{"carol"}}, tx)
+		if pErr != nil {
+			return pErr
+		}
+		return dbEditReservation(res, changes, tx)
+	}))
+
+	sendFor := func(nType int) []string {
+		stored, rErr := dbReadReservationsTx(map[string]interface{}{"name": "trip"}, nil)
+		require.NoError(t, rErr)
+		require.Len(t, stored, 1)
+		sent := smtp.sent
+		var event *ResNotifyEvent
+		if nType == EmailResWarn {
+			event = makeResWarnNotifyEvent(nType, 30*time.Minute, &stored[0], "krypton")
+		} else {
+			event = makeResEditNotifyEvent(nType, &stored[0], "krypton", &carol, false, "")
+		}
+		require.NotNil(t, event)
+		require.NoError(t, processResNotifyEvent(*event))
+		require.Equal(t, sent+1, smtp.sent)
+		return smtp.rcpts[len(smtp.rcpts)-1]
+	}
+
+	// the delegate and the users the reservation names are copied on warnings, but not a change of owner
+	assert.ElementsMatch(t, []string{"alice@example.com", "bob@example.com", "carol@example.com"}, sendFor(EmailResWarn))
+	assert.ElementsMatch(t, []string{"alice@example.com"}, sendFor(EmailResNewOwner))
+
+	// removing the users stops copying them
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		uList, _, gErr := getUsers([]string{"bob", "carol"}, true, tx)
+		if gErr != nil {
+			return gErr
+		}
+		for i := range uList {
+			if dErr := dbDeleteUser(&uList[i], tx); dErr != nil {
+				return dErr
+			}
+		}
+		return nil
+	}))
+	var owner User
+	require.NoError(t, db.First(&owner, alice.ID).Error)
+	assert.Empty(t, owner.NotifyDelegate)
+	assert.ElementsMatch(t, []string{"alice@example.com"}, sendFor(EmailResWarn))
+}
+
+func TestNotifyPrefsGroupMail(t *testing.T) {
+
+	origEmail, origRefs, origDial := igor.Email, igor.ClusterRefs, smtpDial
+	t.Cleanup(func() { igor.Email, igor.ClusterRefs, smtpDial = origEmail, origRefs, origDial })
+	notifyOn := true
+	igor.Email.SmtpServers = []SmtpServerConfig{{Host: "smtp.example.com", Port: DefaultSmtpPort}}
+	igor.Email.DefaultSuffix = "example.com"
+	igor.Email.ResNotifyOn = &notifyOn
+	r, _ := common.NewRange("kn", 1, 10)
+	igor.ClusterRefs = []common.Range{*r}
+	initNotify()
+	smtp := &fakeSmtpServer{failAfter: -1}
+	smtpDial = func(*gomail.Dialer) (gomail.SendCloser, error) { return smtp, nil }
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	res := newStartTestRes(t, db, "crew-res", hosts[:1], false, 0)
+	alice := res.Owner
+	users := map[string]*User{"alice": &alice}
+	for _, name := range []string{"bob", "carol", "dave"} {
+		u := User{Name: name, Email: name + "@example.com"}
+		require.NoError(t, db.Omit(clause.Associations).Create(&u).Error)
+		users[name] = &u
+	}
+	require.NoError(t, db.Model(&alice).Update("email", "alice@example.com").Error)
+
+	crew := Group{Name: "crew"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&crew).Error)
+	require.NoError(t, db.Model(&crew).Association("Owners").Append(&alice))
+	require.NoError(t, db.Model(&crew).Association("Members").Append(&alice, users["bob"], users["carol"], users["dave"]))
+	require.NoError(t, db.Model(&Reservation{}).Where("id = ?", res.ID).Update("group_id", crew.ID).Error)
+
+	// preferences are checked when they are set
+	for _, bad := range []string{"res-cc", "res-cc=some", "group=maybe", "digest=daily"} {
+		_, err := parseNotifyPrefs(bad)
+		assert.Error(t, err, bad)
+	}
+	setPrefs := func(name, prefs string) {
+		changes, err := parseNotifyPrefs(prefs)
+		require.NoError(t, err)
+		require.NoError(t, dbEditUser(users[name], changes, db))
+	}
+	setPrefs("alice", "res-cc=none,group=no")
+	setPrefs("bob", "res-cc=final")
+	setPrefs("carol", "res-cc=none,group=no")
+
+	var stored User
+	require.NoError(t, db.First(&stored, users["bob"].ID).Error)
+	assert.Equal(t, "res-cc=final,group=yes", stored.notifyPrefs())
+
+	lastRcpts := func(send func() error) []string {
+		sent := smtp.sent
+		require.NoError(t, send())
+		if smtp.sent == sent {
+			return nil
+		}
+		return smtp.rcpts[len(smtp.rcpts)-1]
+	}
+	sendRes := func(nType int) []string {
+		return lastRcpts(func() error {
+			resList, err := dbReadReservationsTx(map[string]interface{}{"name": "crew-res"}, nil)
+			require.NoError(t, err)
+			return processResNotifyEvent(*makeResWarnNotifyEvent(nType, 0, &resList[0], "krypton"))
+		})
+	}
+
+	// the owner gets reservation mail whatever their preference, the group is copied per member
+	assert.ElementsMatch(t, []string{"alice@example.com", "dave@example.com"}, sendRes(EmailResWarn))
+	assert.ElementsMatch(t, []string{"alice@example.com", "bob@example.com", "dave@example.com"}, sendRes(EmailResFinalWarn))
+
+	gList, err := dbReadGroupsTx(map[string]interface{}{"name": "crew", "showMembers": true}, true)
+	require.NoError(t, err)
+	sendGroup := func(nType int, member *User) []string {
+		return lastRcpts(func() error {
+			return processGroupNotifyEvent(*makeGroupNotifyEvent(nType, &gList[0], member, "old-crew"))
+		})
+	}
+
+	// group owners always hear about their group, members who opted out don't
+	assert.ElementsMatch(t, []string{"alice@example.com", "bob@example.com", "dave@example.com"}, sendGroup(EmailGroupChangeName, nil))
+	assert.Nil(t, sendGroup(EmailGroupRmvMem, users["carol"]))
+	assert.ElementsMatch(t, []string{"bob@example.com"}, sendGroup(EmailGroupAddMem, users["bob"]))
+
+	// account mail ignores the preferences
+	assert.ElementsMatch(t, []string{"carol@example.com"}, lastRcpts(func() error {
+		return processAcctNotifyEvent(*makeAcctNotifyEvent(EmailAcctProfilesRemoved, users["carol"]))
+	}))
+}
@@ -27,9 +27,23 @@ const (
 	PermViewAction    = "view"
 	PermEditAction    = "edit"
 	PermDeleteAction  = "delete"
-	PermPowerAction   = "power"
+	PermNodeAction    = "node"
+	// PermPowerAction began the power permission facts of older versions, which
+	// migrateNodeActionPermissions rewrites as node action permissions
+	PermPowerAction = "power"
 )
 
+// Node actions are what a node action permission allows on a reservation's hosts.
+const (
+	NodeActionPower   = "power"
+	NodeActionReimage = "reimage"
+	NodeActionConsole = "console"
+)
+
+// nodeActions lists every node action. Reservations grant all of them to their group, host
+// policies can take them away.
+var nodeActions = []string{NodeActionConsole, NodeActionPower, NodeActionReimage}
+
 // Permission is a piece of data that explains what kind of access a user has to a given resource or set of resources.
 // It can be represented by a simple string called a Fact. In operation a fact is represented as an array of Sets that
 // can be used for comparison against another Permission to determine if one implies the other.
@@ -6,7 +6,7 @@ package igorserver
 
 import (
 	"fmt"
-	"sort"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -73,21 +73,48 @@ func dbGetResourceGroupPermissions(resourceType string, resourceName string, gro
 	return
 }
 
-// dbGetHostPowerPermissions retrieves any power permission that matches input group and list of hosts
-func dbGetHostPowerPermissions(group *Group, resHosts []Host, tx *gorm.DB) (perms []Permission, err error) {
+// dbGetNodeActionPermissions retrieves any node action permission that matches input group and list of hosts
+func dbGetNodeActionPermissions(group *Group, resHosts []Host, tx *gorm.DB) (perms []Permission, err error) {
 
-	sort.Slice(resHosts, func(i, j int) bool {
-		return resHosts[i].HostName < resHosts[j].HostName
-	})
+	if len(resHosts) == 0 {
+		return nil, nil
+	}
 
-	var hList string
-	for i := 0; i < len(resHosts)-1; i++ {
-		hList += resHosts[i].HostName + PermSubpartToken
+	// the stored fact is normalized, so build the permission to compare against the same form
+	nodePerm, err := NewPermission(makeNodeActionPerm(resHosts))
+	if err != nil {
+		return nil, err
 	}
-	hList += resHosts[len(resHosts)-1].HostName
+	err = tx.Model(group).Where("fact = ?", nodePerm.Fact).Association("Permissions").Find(&perms)
+	return
+}
 
-	permFind := PermPowerAction + PermDividerToken + hList
-	err = tx.Model(group).Where("fact = ?", permFind).Association("Permissions").Find(&perms)
+// migrateNodeActionPermissions rewrites the power permissions written by older versions, which
+// only covered power commands, as node action permissions for the same hosts. It returns the
+// number of permissions rewritten; once done there is nothing left for it to match.
+func migrateNodeActionPermissions(db *gorm.DB) (count int, err error) {
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var oldPerms []Permission
+		if result := tx.Where("fact LIKE ?", PermPowerAction+PermDividerToken+"%").Find(&oldPerms); result.Error != nil {
+			return result.Error
+		}
+		for _, p := range oldPerms {
+			hostPart := strings.TrimPrefix(p.Fact, PermPowerAction+PermDividerToken)
+			nodePerm, npErr := NewPermission(NewPermissionString(PermNodeAction, hostPart, strings.Join(nodeActions, PermSubpartToken)))
+			if npErr != nil {
+				return fmt.Errorf("unable to migrate permission '%s': %v", p.Fact, npErr)
+			}
+			if result := tx.Model(&p).Update("fact", nodePerm.Fact); result.Error != nil {
+				return result.Error
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		count = 0
+	}
 	return
 }
 
@@ -8,6 +8,7 @@
 package igorserver
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -232,3 +233,49 @@ func TestString(t *testing.T) {
 	newP5, _ := NewPermission(p5.String())
 	assert.True(t, p5.Equals(newP5))
 }
+
+func TestNodeActionPermission(t *testing.T) {
+
+	hosts := []Host{{HostName: "kn2"}, {HostName: "kn1"}}
+	assert.Equal(t, "node:kn1,kn2:console,power,reimage", makeNodeActionPerm(hosts))
+
+	granted, err := NewPermission(makeNodeActionPerm(hosts))
+	assert.NoError(t, err)
+	for _, action := range nodeActions {
+		required, _ := NewPermission(NewPermissionString(PermNodeAction, "kn1", action))
+		assert.True(t, granted.Implies(required), action)
+	}
+	other, _ := NewPermission(NewPermissionString(PermNodeAction, "kn3", NodeActionPower))
+	assert.False(t, granted.Implies(other))
+}
+
+func TestMigrateNodeActionPermissions(t *testing.T) {
+
+	seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	old := Permission{GroupID: 1, Fact: "power:kn1,kn2"}
+	other := Permission{GroupID: 1, Fact: "reservations:res1:delete"}
+	assert.NoError(t, db.Create(&old).Error)
+	assert.NoError(t, db.Create(&other).Error)
+
+	count, err := migrateNodeActionPermissions(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var perms []Permission
+	assert.NoError(t, db.Order("id").Find(&perms).Error)
+	assert.Equal(t, "node:kn1,kn2:console,power,reimage", perms[0].Fact)
+	assert.Equal(t, other.Fact, perms[1].Fact)
+
+	// the rewritten permission is found the same way a new one would be
+	group := Group{Base: Base{ID: 1}}
+	found, err := dbGetNodeActionPermissions(&group, []Host{{HostName: "kn2"}, {HostName: "kn1"}}, db)
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+
+	// nothing is left to migrate the next time the server starts
+	count, err = migrateNodeActionPermissions(db)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
@@ -33,6 +33,10 @@ func powerStatusManager(hosts []Host) {
 
 	ipMap = make(map[string]string, len(hosts))
 	for _, h := range hosts {
+		// simulated hosts don't need to be found on the network
+		if igor.Simulation.Enabled {
+			break
+		}
 		ip := h.IP
 		if ip == "" {
 			// we need to get an IP for this node
@@ -0,0 +1,190 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math/rand"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// powerPollJitter is the largest fraction of a poll interval randomly added to or taken
+// away from the time of a host's next poll.
+const powerPollJitter = 0.1
+
+// powerPollInfo tracks the power status polling of a single host.
+type powerPollInfo struct {
+	interval time.Duration // time between polls
+	next     time.Time     // when the next poll is due
+	polling  bool          // a poll is in progress
+	checked  time.Time     // when a poll last succeeded
+	latency  time.Duration // how long the last poll took
+	failures int           // polls in a row that have failed
+}
+
+// powerPollMap holds the polling info of each host by hostname. It is guarded by powerMapMU
+// and is only populated when power status is found with the powerStatus command.
+var powerPollMap map[string]*powerPollInfo
+
+// powerPollManager is called as a go routine in place of powerStatusManager when a power
+// status command is configured. Each host is polled on its own interval, and no more than
+// externalCmds.concurrencyLimit polls and hooks are run at once.
+func powerPollManager(hosts []Host) {
+	defer wg.Done()
+
+	now := time.Now()
+	powerMapMU.Lock()
+	powerMap = make(map[string]*bool, len(hosts))
+	powerPollMap = make(map[string]*powerPollInfo, len(hosts))
+	for _, h := range hosts {
+		interval := hostPollInterval(h.PollInterval)
+		powerMap[h.HostName] = nil
+		// spread the first round of polls across the interval
+		powerPollMap[h.HostName] = &powerPollInfo{
+			interval: interval,
+			next:     now.Add(time.Duration(rand.Int63n(int64(interval)))),
+		}
+	}
+	powerMapMU.Unlock()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownChan:
+			logger.Info().Msg("stopping node power polling background worker")
+			return
+		case <-refreshPowerChan:
+			// hosts are polled on their own schedule, so requests for a refresh are dropped
+		case checkTime := <-ticker.C:
+			powerMapMU.Lock()
+			for hostName, info := range powerPollMap {
+				if info.polling || checkTime.Before(info.next) {
+					continue
+				}
+				info.polling = true
+				info.next = checkTime.Add(jitterPollInterval(info.interval))
+				go pollHostPower(hostName, info.interval)
+			}
+			powerMapMU.Unlock()
+		}
+	}
+}
+
+// pollHostPower runs the power status command for a host once a token is available and
+// records the result. The command is given no longer than the host's poll interval to finish.
+func pollHostPower(hostName string, timeout time.Duration) {
+
+	extCmdTokens <- struct{}{}
+	defer func() { <-extCmdTokens }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	args := strings.Split(fmt.Sprintf(igor.ExternalCmds.PowerStatus, hostName), " ")
+	start := time.Now()
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	latency := time.Since(start)
+
+	var powered bool
+	if err == nil {
+		powered, err = parsePowerStatus(string(out))
+	} else if ctx.Err() != nil {
+		err = fmt.Errorf("timed out after %v", timeout)
+	}
+
+	recordPowerPoll(hostName, powered, latency, err)
+}
+
+// recordPowerPoll saves the result of polling a host. When the number of failed polls in a row
+// reaches externalCmds.powerPollFailures the host's power status becomes unknown.
+func recordPowerPoll(hostName string, powered bool, latency time.Duration, err error) {
+
+	powerMapMU.Lock()
+	defer powerMapMU.Unlock()
+
+	info, ok := powerPollMap[hostName]
+	if !ok {
+		return
+	}
+	info.polling = false
+	info.latency = latency
+
+	if err != nil {
+		info.failures++
+		logger.Debug().Msgf("power status poll of %s failed (%d in a row): %v", hostName, info.failures, err)
+		if info.failures >= igor.ExternalCmds.PowerPollFailures && powerMap[hostName] != nil {
+			logEvent(&logger, EvPowerStatusUnknown).Str(LogFieldHost, hostName).Err(err).Msgf("power status of %s is unknown after %d failed polls - last error: %v", hostName, info.failures, err)
+			powerMap[hostName] = nil
+		}
+		return
+	}
+
+	if info.failures >= igor.ExternalCmds.PowerPollFailures {
+		logger.Info().Msgf("power status poll of %s succeeded after %d failures", hostName, info.failures)
+	}
+	info.failures = 0
+	info.checked = time.Now()
+	powerMap[hostName] = &powered
+}
+
+// setPowerPollInterval changes how often a host is polled. An interval of 0 uses the configured default.
+func setPowerPollInterval(hostName string, seconds int) {
+
+	powerMapMU.Lock()
+	defer powerMapMU.Unlock()
+
+	if info, ok := powerPollMap[hostName]; ok {
+		info.interval = hostPollInterval(seconds)
+		// don't wait out the old interval if the new one is shorter
+		if next := time.Now().Add(info.interval); next.Before(info.next) {
+			info.next = next
+		}
+	}
+}
+
+// hostPollInterval returns the poll interval for a host given its override in seconds.
+func hostPollInterval(seconds int) time.Duration {
+	if seconds <= 0 {
+		seconds = igor.ExternalCmds.PowerPollInterval
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// jitterPollInterval returns the interval changed by a random amount up to powerPollJitter of its length.
+func jitterPollInterval(interval time.Duration) time.Duration {
+	maxJitter := int64(float64(interval) * powerPollJitter)
+	if maxJitter <= 0 {
+		return interval
+	}
+	return interval + time.Duration(rand.Int63n(2*maxJitter+1)-maxJitter)
+}
+
+// parsePowerStatus reads the output of the power status command. The last word printed must
+// be 'on' or 'off', ex. 'Chassis Power is on'.
+func parsePowerStatus(out string) (bool, error) {
+
+	var last string
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		if fields := strings.Fields(s.Text()); len(fields) > 0 {
+			last = strings.ToLower(fields[len(fields)-1])
+		}
+	}
+
+	switch last {
+	case PowerOn:
+		return true, nil
+	case PowerOff:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unrecognized power status output: %s", strings.TrimSpace(out))
+	}
+}
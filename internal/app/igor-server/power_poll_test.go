@@ -0,0 +1,38 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParsePowerStatus(t *testing.T) {
+
+	powered, err := parsePowerStatus("Chassis Power is on\n")
+	assert.NoError(t, err)
+	assert.True(t, powered)
+
+	powered, err = parsePowerStatus("Chassis Power is OFF\n\n")
+	assert.NoError(t, err)
+	assert.False(t, powered)
+
+	_, err = parsePowerStatus("Error: Unable to establish IPMI v2 / RMCP+ session\n")
+	assert.Error(t, err)
+
+	_, err = parsePowerStatus("")
+	assert.Error(t, err)
+}
+
+func TestJitterPollInterval(t *testing.T) {
+
+	interval := 60 * time.Second
+	for i := 0; i < 100; i++ {
+		j := jitterPollInterval(interval)
+		assert.GreaterOrEqual(t, j, 54*time.Second)
+		assert.LessOrEqual(t, j, 66*time.Second)
+	}
+}
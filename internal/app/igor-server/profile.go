@@ -7,6 +7,7 @@ package igorserver
 import (
 	"igor2/internal/pkg/common"
 	"sort"
+	"time"
 )
 
 const (
@@ -28,6 +29,9 @@ type Profile struct {
 	Distro      Distro
 	IsDefault   bool
 	KernelArgs  string // Added to Distro kernel args if they exist.
+	// UseCount and LastUsed track how often and how recently reservations were created or reimaged with the profile
+	UseCount int
+	LastUsed time.Time
 }
 
 // duplicate makes a deep copy of a profile, setting the given user as the new owner
@@ -41,7 +45,7 @@ func (p *Profile) duplicate(user *User) *Profile {
 	}
 }
 
-func filterProfileList(profiles []Profile) []common.ProfileData {
+func filterProfileList(profiles []Profile, user *User) []common.ProfileData {
 	var profileList []common.ProfileData
 	for _, profile := range profiles {
 		profileList = append(profileList, common.ProfileData{
@@ -50,6 +54,7 @@ func filterProfileList(profiles []Profile) []common.ProfileData {
 			Owner:       profile.Owner.Name,
 			Distro:      profile.Distro.Name,
 			KernelArgs:  profile.KernelArgs,
+			Usage:       imageUsageData(profile.OwnerID, profile.UseCount, profile.LastUsed, user),
 		})
 	}
 
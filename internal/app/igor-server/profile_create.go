@@ -5,7 +5,7 @@
 package igorserver
 
 import (
-	"fmt"
+	"igor2/internal/pkg/common"
 	"net/http"
 
 	"gorm.io/gorm"
@@ -26,7 +26,7 @@ func doCreateProfile(createProfileParams map[string]interface{}, r *http.Request
 		if findErr != nil {
 			return findErr
 		} else if len(profiles) > 0 {
-			return fmt.Errorf("profile '%s' already exists", profileName)
+			return newCodedError(common.ErrNameTaken, "profile '%s' already exists", profileName)
 		}
 
 		var distro *Distro
@@ -42,13 +42,17 @@ func doCreateProfile(createProfileParams map[string]interface{}, r *http.Request
 		desc, _ = createProfileParams["description"].(string)
 		var kernelArgs string
 		kernelArgs, _ = createProfileParams["kernelArgs"].(string)
+		if _, kErr := checkMergedKernelArgs(distro.KernelArgs, kernelArgs); kErr != nil {
+			code = http.StatusBadRequest
+			return kErr
+		}
 
 		profile = &Profile{
 			Name:        profileName,
 			Description: desc,
 			Owner:       *owner,
 			Distro:      *distro,
-			KernelArgs:  kernelArgs,
+			KernelArgs:  normalizeKernelArgs(kernelArgs),
 		}
 
 		return dbCreateProfile(profile, tx) // uses default err code
@@ -31,7 +31,8 @@ func handleCreateProfile(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
-		rb.Data["profile"] = filterProfileList([]Profile{*profile})
+		rb.Data["profile"] = filterProfileList([]Profile{*profile}, getUserFromContext(r))
+		rb.Data["kernelLine"] = mergeKernelArgs(profile.Distro.KernelArgs, profile.KernelArgs)
 		clog.Info().Msgf("%s success - '%s' created", actionPrefix, profile.Name)
 	}
 
@@ -54,7 +55,7 @@ func handleReadProfiles(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
-		rb.Data["profiles"] = filterProfileList(profiles)
+		rb.Data["profiles"] = filterProfileList(profiles, getUserFromContext(r))
 		if len(profiles) == 0 {
 			rb.Message = "search returned no results"
 		}
@@ -77,11 +78,14 @@ func handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
 	ps := httprouter.ParamsFromContext(r.Context())
 	profileName := ps.ByName("profileName")
 
-	status, err := doUpdateProfile(profileName, editParams, r)
+	kernelLine, status, err := doUpdateProfile(profileName, editParams, r)
 
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
+		if _, ok := editParams["kernelArgs"]; ok {
+			rb.Data["kernelLine"] = kernelLine
+		}
 		clog.Info().Msgf("%s success - '%s' updated", actionPrefix, profileName)
 	}
 
@@ -132,9 +136,11 @@ func validateProfileParams(handler http.Handler) http.Handler {
 					for key, val := range profileParams {
 						switch key {
 						case "kernelArgs":
-							if _, ok := val.(string); !ok {
+							if kArgs, ok := val.(string); !ok {
 								validateErr = NewBadParamTypeError(key, val, "string")
 								break postPutParamLoop
+							} else if validateErr = checkRequestKernelArgs(kArgs, r); validateErr != nil {
+								break postPutParamLoop
 							}
 						case "name":
 							if profileName, ok := val.(string); !ok {
@@ -216,9 +222,11 @@ func validateProfileParams(handler http.Handler) http.Handler {
 			for key, val := range profileParams {
 				switch key {
 				case "kernelArgs":
-					if _, ok := val.(string); !ok {
+					if kArgs, ok := val.(string); !ok {
 						validateErr = NewBadParamTypeError(key, val, "string")
 						break patchParamLoop
+					} else if validateErr = checkRequestKernelArgs(kArgs, r); validateErr != nil {
+						break patchParamLoop
 					}
 				case "description":
 					if desc, ok := val.(string); !ok {
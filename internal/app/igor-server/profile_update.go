@@ -5,14 +5,16 @@
 package igorserver
 
 import (
-	"fmt"
 	"github.com/rs/zerolog/hlog"
+	"igor2/internal/pkg/common"
 	"net/http"
 
 	"gorm.io/gorm"
 )
 
-func doUpdateProfile(profileName string, editParams map[string]interface{}, r *http.Request) (code int, err error) {
+// doUpdateProfile applies the edits to the profile. When the kernel args are changed, kernelLine is
+// the boot line they will produce along with the distro kernel args.
+func doUpdateProfile(profileName string, editParams map[string]interface{}, r *http.Request) (kernelLine string, code int, err error) {
 
 	clog := hlog.FromRequest(r)
 	code = http.StatusInternalServerError // default status, overridden at end if no errors
@@ -32,6 +34,9 @@ func doUpdateProfile(profileName string, editParams map[string]interface{}, r *h
 			code = pStatus
 			return pErr
 		}
+		if ka, ok := changes["kernel_args"].(string); ok {
+			kernelLine = mergeKernelArgs(p.Distro.KernelArgs, ka)
+		}
 
 		if name, ok := changes["name"].(string); ok {
 			if snpList, status, findErr := getProfiles([]string{name}, tx); findErr != nil {
@@ -39,7 +44,7 @@ func doUpdateProfile(profileName string, editParams map[string]interface{}, r *h
 				return findErr // uses default err status
 			} else if len(snpList) > 0 {
 				code = http.StatusConflict
-				return fmt.Errorf("profile name '%s' already in use", name)
+				return newCodedError(common.ErrNameTaken, "profile name '%s' already in use", name)
 			}
 		}
 
@@ -79,7 +84,10 @@ func parseProfileEditParams(p *Profile, editParams map[string]interface{}) (map[
 		changes["Description"] = desc
 	}
 	if ka, ok := editParams["kernelArgs"].(string); ok {
-		changes["kernel_args"] = ka
+		if _, err := checkMergedKernelArgs(p.Distro.KernelArgs, ka); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		changes["kernel_args"] = normalizeKernelArgs(ka)
 	}
 
 	// if profile is default and user making valid changes,
@@ -5,31 +5,20 @@
 package igorserver
 
 import (
-	"fmt"
-	"strings"
-
 	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
 )
 
-var tempProfilePrefix = "tpf_"
+var tempProfilePrefix = naming.TempProfilePrefix
 
 // checkProfileNameRules determines if the input string meets the criteria for
 // a valid profile name.
 func checkProfileNameRules(name string) error {
-	if len(name) == 0 {
-		return fmt.Errorf("profile name cannot be empty")
-	}
-	if !stdNameCheckPattern.MatchString(name) {
-		return fmt.Errorf("'%s' is not a legal profile name", name)
-	}
-	return isResourceNameMatch(name)
+	return naming.Check(naming.Profile, name)
 }
 
 func checkReservedProfileNames(name string) error {
-	if strings.HasPrefix(name, tempProfilePrefix) {
-		return fmt.Errorf("profile name with prefix %v not allowed", tempProfilePrefix)
-	}
-	return nil
+	return naming.CheckReserved(naming.Profile, name)
 }
 
 func generateDefaultProfileName(user *User) string {
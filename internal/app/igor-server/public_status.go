@@ -0,0 +1,155 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// publicStatusCache holds the last public status document built. Displays poll the document often,
+// so it is rebuilt at most once every server.publicStatusSeconds and everyone in between is sent the
+// same bytes.
+var publicStatusCache struct {
+	sync.Mutex
+	body      []byte
+	etag      string
+	generated time.Time
+}
+
+// handlePublicStatus returns a summary of the cluster for wall displays. It does not require
+// authentication and is only available when server.allowPublicShow is on.
+func handlePublicStatus(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "public status"
+
+	if !igor.Server.AllowPublicShow {
+		rb := common.NewResponseBody()
+		err := fmt.Errorf("%s has restricted igor reservation data from public view", igor.InstanceName)
+		stdErrorResp(rb, http.StatusForbidden, actionPrefix, err, clog)
+		makeJsonResponse(w, http.StatusForbidden, rb)
+		return
+	}
+
+	body, etag, err := getPublicStatus(time.Now())
+	if err != nil {
+		rb := common.NewResponseBody()
+		stdErrorResp(rb, http.StatusInternalServerError, actionPrefix, err, clog)
+		makeJsonResponse(w, http.StatusInternalServerError, rb)
+		return
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", igor.Server.PublicStatusSecs))
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	clog.Debug().Msgf("%s success", actionPrefix)
+	w.Header().Set(common.ContentType, common.MAppJson)
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(body); err != nil {
+		panic(err)
+	}
+}
+
+// getPublicStatus returns the public status document and its ETag, building a new one if the cached
+// document is older than server.publicStatusSeconds.
+func getPublicStatus(now time.Time) (body []byte, etag string, err error) {
+
+	publicStatusCache.Lock()
+	defer publicStatusCache.Unlock()
+
+	maxAge := time.Duration(igor.Server.PublicStatusSecs) * time.Second
+	if publicStatusCache.body != nil && now.Sub(publicStatusCache.generated) < maxAge {
+		return publicStatusCache.body, publicStatusCache.etag, nil
+	}
+
+	var status *common.PublicStatusData
+	if err = performDbTx(func(tx *gorm.DB) error {
+		var bErr error
+		status, bErr = buildPublicStatus(now, tx)
+		return bErr
+	}); err != nil {
+		return nil, "", err
+	}
+
+	rb := common.NewResponseBody()
+	rb.SetStatus(http.StatusOK)
+	rb.Data["status"] = status
+	body = marshalJSONBody(rb)
+	sum := sha256.Sum256(body)
+
+	publicStatusCache.body = body
+	publicStatusCache.etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	publicStatusCache.generated = now
+	return body, publicStatusCache.etag, nil
+}
+
+// buildPublicStatus counts the nodes in each state and the reservations that are running or yet to
+// start. Only node names and states are included, nothing about who is using them.
+func buildPublicStatus(now time.Time, tx *gorm.DB) (*common.PublicStatusData, error) {
+
+	status := &common.PublicStatusData{
+		Generated:  now.Unix(),
+		NodeStates: map[string]int{},
+		Nodes:      []common.PublicNodeData{},
+	}
+
+	clusters, err := dbReadClusters(map[string]interface{}{}, tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters) > 0 {
+		status.Cluster = clusters[0].Name
+		status.Motd = clusters[0].Motd
+		status.MotdUrgent = clusters[0].MotdUrgent
+		status.MotdHTML = renderMarkdownString(clusters[0].Motd)
+	}
+
+	hosts, err := dbReadHosts(map[string]interface{}{}, tx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].SequenceID < hosts[j].SequenceID
+	})
+
+	powerMapMU.Lock()
+	for _, h := range hosts {
+		node := common.PublicNodeData{Name: h.Name, State: h.State.String(), Unavail: h.nextUnavailWindow(now, unavailLookahead())}
+		if powered := powerMap[h.HostName]; powered != nil {
+			p := *powered
+			node.Powered = &p
+		}
+		status.Nodes = append(status.Nodes, node)
+		status.NodeStates[node.State]++
+	}
+	powerMapMU.Unlock()
+
+	var active, future int64
+	if result := tx.Model(&Reservation{}).Where("start <= ? AND end > ?", now, now).Count(&active); result.Error != nil {
+		return nil, result.Error
+	}
+	if result := tx.Model(&Reservation{}).Where("start > ?", now).Count(&future); result.Error != nil {
+		return nil, result.Error
+	}
+	status.ActiveReservations = int(active)
+	status.FutureReservations = int(future)
+
+	return status, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestPublicStatus(t *testing.T) {
+
+	origServer, origPowerMap := igor.Server, powerMap
+	t.Cleanup(func() {
+		igor.Server, powerMap = origServer, origPowerMap
+		publicStatusCache.body = nil
+	})
+	igor.Server.AllowPublicShow = true
+	igor.Server.PublicStatusSecs = 60
+	publicStatusCache.body = nil
+	on := true
+	powerMap = map[string]*bool{"kn1": &on}
+
+	hosts := seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+	require.NoError(t, db.Omit(clause.Associations).Create(&Cluster{Name: "krypton", Motd: "maintenance friday"}).Error)
+	newStartTestRes(t, db, "secret-project", hosts[:1], false, 0)
+	require.NoError(t, db.Model(&Host{}).Where("name = ?", "kn1").Update("state", HostReserved).Error)
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/igor/public/status", nil)
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		handlePublicStatus(w, r)
+		return w
+	}
+
+	w := get("")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.NotContains(t, w.Body.String(), "alice")
+	assert.NotContains(t, w.Body.String(), "secret-project")
+
+	var rb struct {
+		Data map[string]common.PublicStatusData `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rb))
+	status := rb.Data["status"]
+	assert.Equal(t, "krypton", status.Cluster)
+	assert.Equal(t, "maintenance friday", status.Motd)
+	assert.Equal(t, map[string]int{"reserved": 1, "available": 1}, status.NodeStates)
+	assert.Equal(t, 1, status.ActiveReservations)
+	assert.Zero(t, status.FutureReservations)
+	require.Len(t, status.Nodes, 2)
+	assert.Equal(t, common.PublicNodeData{Name: "kn1", State: "reserved", Powered: &on}, status.Nodes[0])
+	assert.Nil(t, status.Nodes[1].Powered)
+
+	// the cached document is sent until it is old enough to rebuild
+	assert.Equal(t, http.StatusNotModified, get(etag).Code)
+	require.NoError(t, db.Model(&Host{}).Where("name = ?", "kn2").Update("state", HostBlocked).Error)
+	assert.Equal(t, http.StatusNotModified, get(etag).Code)
+	body, newTag, err := getPublicStatus(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.NotEqual(t, etag, newTag)
+	assert.Contains(t, string(body), `"blocked":1`)
+
+	igor.Server.AllowPublicShow = false
+	assert.Equal(t, http.StatusForbidden, get("").Code)
+}
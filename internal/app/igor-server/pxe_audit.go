@@ -0,0 +1,165 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"igor2/internal/pkg/common"
+)
+
+// problems found by a PXE audit
+const (
+	PxeAuditMissing   = "missing"
+	PxeAuditModified  = "modified"
+	PxeAuditUntracked = "untracked"
+	PxeAuditWrongRes  = "wrong-reservation"
+	PxeAuditOrphan    = "orphan"
+	PxeAuditStale     = "stale-record"
+)
+
+type expectedPxeFile struct {
+	res  *Reservation
+	host string
+}
+
+// doPxeAudit compares the PXE config files the installed reservations should have with the files in the PXE
+// config folders and the ledger of files igor wrote, and returns each discrepancy found ordered by path.
+func doPxeAudit(now time.Time) ([]common.PxeAuditData, int, error) {
+
+	resList, err := dbReadReservationsTx(map[string]interface{}{"installed": true}, nil)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return doPxeAuditOf(resList, now)
+}
+
+// doPxeAuditOf audits the PXE config files against the given installed reservations. Files written for host
+// maintenance are not reported.
+func doPxeAuditOf(resList []Reservation, now time.Time) ([]common.PxeAuditData, int, error) {
+
+	expected := map[string]expectedPxeFile{}
+	for i := range resList {
+		res := &resList[i]
+		if res.Start.After(now) || !now.Before(res.End) {
+			continue
+		}
+		for _, h := range res.Hosts {
+			if h.InstallError != "" {
+				continue
+			}
+			if pxePath := getPxePath(&h); pxePath != "" {
+				expected[relTFTPPath(pxePath)] = expectedPxeFile{res: res, host: h.Name}
+			}
+		}
+	}
+
+	pxeFilesMU.Lock()
+	defer pxeFilesMU.Unlock()
+
+	ledger, err := readPxeLedger()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	onDisk, err := listPxeConfigFiles()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	var problems []common.PxeAuditData
+
+	for rel, exp := range expected {
+		p := common.PxeAuditData{Path: rel, Host: exp.host, Reservation: exp.res.Name}
+		e, tracked := ledger[rel]
+		sum, exists, sErr := fileSum(filepath.Join(igor.TFTPPath, rel))
+		if sErr != nil {
+			return nil, http.StatusInternalServerError, sErr
+		}
+		switch {
+		case !exists:
+			p.Problem = PxeAuditMissing
+			p.Detail = "the host's PXE config file does not exist"
+		case !tracked:
+			p.Problem = PxeAuditUntracked
+			p.Detail = "igor has no record of writing this file"
+		case e.Owner != resPxeOwner(exp.res):
+			p.Problem = PxeAuditWrongRes
+			p.Detail = fmt.Sprintf("file was written for host %s of reservation '%s'", e.Host, e.Reservation)
+		case sum != e.Sum:
+			p.Problem = PxeAuditModified
+			p.Detail = fmt.Sprintf("file was changed outside of igor after it was written %s", e.Written.Format(common.DateTimeCompactFormat))
+		default:
+			continue
+		}
+		problems = append(problems, p)
+	}
+
+	for _, rel := range onDisk {
+		if _, ok := expected[rel]; ok {
+			continue
+		}
+		p := common.PxeAuditData{Path: rel, Problem: PxeAuditOrphan}
+		if e, tracked := ledger[rel]; !tracked {
+			p.Detail = "igor has no record of writing this file"
+		} else if e.End.IsZero() {
+			// written for host maintenance
+			continue
+		} else {
+			p.Host, p.Reservation = e.Host, e.Reservation
+			p.Detail = "file was written for a reservation that is no longer installed"
+		}
+		problems = append(problems, p)
+	}
+
+	diskSet := map[string]bool{}
+	for _, rel := range onDisk {
+		diskSet[rel] = true
+	}
+	for rel, e := range ledger {
+		if _, ok := expected[rel]; ok || diskSet[rel] {
+			continue
+		}
+		problems = append(problems, common.PxeAuditData{
+			Path:        rel,
+			Host:        e.Host,
+			Reservation: e.Reservation,
+			Problem:     PxeAuditStale,
+			Detail:      "igor has a record of writing this file but it no longer exists",
+		})
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].Path < problems[j].Path
+	})
+	return problems, http.StatusOK, nil
+}
+
+// listPxeConfigFiles returns the paths, relative to the TFTP root, of the host PXE config files named for MAC
+// addresses in the BIOS and UEFI config folders.
+func listPxeConfigFiles() ([]string, error) {
+	var files []string
+	for dir, prefix := range map[string]string{igor.PXEBIOSDir: "01-", igor.PXEUEFIDir: "grub.cfg-01-"} {
+		entries, err := os.ReadDir(filepath.Join(igor.TFTPPath, dir))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasPrefix(e.Name(), prefix) {
+				files = append(files, filepath.Join(dir, e.Name()))
+			}
+		}
+	}
+	return files, nil
+}
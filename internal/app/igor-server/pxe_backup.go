@@ -0,0 +1,327 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// pxeBackupDir is the folder under the TFTP root holding snapshots of PXE config files and the ledger
+	pxeBackupDir      = "igor-pxe-backups"
+	pxeLedgerFile     = "ledger.json"
+	pxeManifestFile   = "manifest.json"
+	pxeSnapshotFiles  = "files"
+	pxeSnapshotFormat = "20060102-150405"
+)
+
+// pxeFilesMU keeps more than one writer of PXE config files, and the ledger describing them, from running at once
+var pxeFilesMU sync.Mutex
+
+// pxeLedgerEntry records the PXE config file igor last wrote for a reservation host. Owner is the
+// reservation's history hash, or its name for the temporary reservations made for host maintenance.
+type pxeLedgerEntry struct {
+	Owner       string    `json:"owner"`
+	Reservation string    `json:"reservation"`
+	Host        string    `json:"host"`
+	Sum         string    `json:"sha256"`
+	Written     time.Time `json:"written"`
+	// End is when the reservation was due to end when the file was written, zero for maintenance
+	End time.Time `json:"end"`
+}
+
+// active returns true if the file still belongs to its reservation at the given time.
+func (e *pxeLedgerEntry) active(now time.Time) bool {
+	return e.End.IsZero() || now.Before(e.End)
+}
+
+// pxeLedger maps the path of each PXE config file, relative to the TFTP root, to its ledger entry.
+type pxeLedger map[string]pxeLedgerEntry
+
+// pxeSnapshotFile is a file as it was when a snapshot was taken. Existed is false if the file wasn't there.
+type pxeSnapshotFile struct {
+	Path    string `json:"path"`
+	Existed bool   `json:"existed"`
+	Sum     string `json:"sha256,omitempty"`
+}
+
+// pxeSnapshot is the manifest of the files an install was about to write, saved before it wrote them.
+type pxeSnapshot struct {
+	Reservation string            `json:"reservation"`
+	Taken       time.Time         `json:"taken"`
+	Files       []pxeSnapshotFile `json:"files"`
+}
+
+// resPxeOwner returns the key used to tell which reservation a PXE config file belongs to. The history hash
+// stays the same if the reservation is renamed.
+func resPxeOwner(r *Reservation) string {
+	if r.Hash != "" {
+		return r.Hash
+	}
+	return r.Name
+}
+
+func pxeBackupPath() string {
+	return filepath.Join(igor.TFTPPath, pxeBackupDir)
+}
+
+// relTFTPPath returns path relative to the TFTP root.
+func relTFTPPath(path string) string {
+	if rel, err := filepath.Rel(igor.TFTPPath, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// fileSum returns the sha256 of the file at path, and false if there is no such file.
+func fileSum(path string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return contentSum(data), true, nil
+}
+
+func contentSum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readPxeLedger reads the ledger of PXE config files written by igor. pxeFilesMU must be held by the caller.
+func readPxeLedger() (pxeLedger, error) {
+	ledger := pxeLedger{}
+	data, err := os.ReadFile(filepath.Join(pxeBackupPath(), pxeLedgerFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ledger, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &ledger); err != nil {
+		return nil, fmt.Errorf("unable to read PXE ledger: %v", err)
+	}
+	return ledger, nil
+}
+
+// writePxeLedger saves the ledger of PXE config files. pxeFilesMU must be held by the caller.
+func writePxeLedger(ledger pxeLedger) error {
+	if err := os.MkdirAll(pxeBackupPath(), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(pxeBackupPath(), pxeLedgerFile), data, 0644)
+}
+
+// listPxeSnapshots returns the names of the snapshot folders, oldest first. Each is named
+// <seq>-<time>-<reservation>.
+func listPxeSnapshots() ([]string, error) {
+
+	entries, err := os.ReadDir(pxeBackupPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && pxeSnapshotSeq(e.Name()) > 0 {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return pxeSnapshotSeq(names[i]) < pxeSnapshotSeq(names[j])
+	})
+	return names, nil
+}
+
+// pxeSnapshotSeq returns the sequence number of a snapshot folder, 0 if the name isn't one.
+func pxeSnapshotSeq(name string) int {
+	seq, _ := strconv.Atoi(strings.SplitN(name, "-", 2)[0])
+	return seq
+}
+
+// takePxeSnapshot copies the files at the given paths, relative to the TFTP root, into a new snapshot folder
+// and saves a manifest of them, including the ones that don't exist yet. pxeFilesMU must be held by the caller.
+func takePxeSnapshot(resName string, relPaths []string) (string, error) {
+
+	snapshots, err := listPxeSnapshots()
+	if err != nil {
+		return "", err
+	}
+	seq := 1
+	if len(snapshots) > 0 {
+		seq = pxeSnapshotSeq(snapshots[len(snapshots)-1]) + 1
+	}
+
+	now := time.Now()
+	snapDir := filepath.Join(pxeBackupPath(), fmt.Sprintf("%d-%s-%s", seq, now.Format(pxeSnapshotFormat), resName))
+	if err = os.MkdirAll(snapDir, 0755); err != nil {
+		return "", err
+	}
+
+	snapshot := pxeSnapshot{Reservation: resName, Taken: now}
+	for _, rel := range relPaths {
+		data, rErr := os.ReadFile(filepath.Join(igor.TFTPPath, rel))
+		if rErr != nil {
+			if errors.Is(rErr, os.ErrNotExist) {
+				snapshot.Files = append(snapshot.Files, pxeSnapshotFile{Path: rel})
+				continue
+			}
+			return "", rErr
+		}
+		copyPath := filepath.Join(snapDir, pxeSnapshotFiles, rel)
+		if err = os.MkdirAll(filepath.Dir(copyPath), 0755); err != nil {
+			return "", err
+		}
+		if err = writeFileAtomic(copyPath, data, 0644); err != nil {
+			return "", err
+		}
+		snapshot.Files = append(snapshot.Files, pxeSnapshotFile{Path: rel, Existed: true, Sum: contentSum(data)})
+	}
+
+	manifest, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err = writeFileAtomic(filepath.Join(snapDir, pxeManifestFile), manifest, 0644); err != nil {
+		return "", err
+	}
+	return snapDir, nil
+}
+
+// prunePxeSnapshots removes the oldest snapshots so no more than retain remain, and returns the names of
+// the folders that were removed. pxeFilesMU must be held by the caller.
+func prunePxeSnapshots(retain int) ([]string, error) {
+
+	snapshots, err := listPxeSnapshots()
+	if err != nil || len(snapshots) <= retain {
+		return nil, err
+	}
+
+	var pruned []string
+	for _, name := range snapshots[:len(snapshots)-retain] {
+		if rmErr := os.RemoveAll(filepath.Join(pxeBackupPath(), name)); rmErr != nil {
+			return pruned, rmErr
+		}
+		pruned = append(pruned, name)
+	}
+	return pruned, nil
+}
+
+// restorePxeFile puts the file at rel, relative to the TFTP root, back the way it was in the newest snapshot
+// that includes it. If the file didn't exist when that snapshot was taken it is removed. pxeFilesMU must be held
+// by the caller.
+func restorePxeFile(rel string) error {
+
+	snapshots, err := listPxeSnapshots()
+	if err != nil {
+		return err
+	}
+
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		snapDir := filepath.Join(pxeBackupPath(), snapshots[i])
+		data, rErr := os.ReadFile(filepath.Join(snapDir, pxeManifestFile))
+		if rErr != nil {
+			continue
+		}
+		var snapshot pxeSnapshot
+		if json.Unmarshal(data, &snapshot) != nil {
+			continue
+		}
+		for _, f := range snapshot.Files {
+			if f.Path != rel {
+				continue
+			}
+			target := filepath.Join(igor.TFTPPath, rel)
+			if !f.Existed {
+				if rmErr := os.Remove(target); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+					return rmErr
+				}
+				return nil
+			}
+			content, cErr := os.ReadFile(filepath.Join(snapDir, pxeSnapshotFiles, rel))
+			if cErr != nil {
+				return cErr
+			}
+			if contentSum(content) != f.Sum {
+				return fmt.Errorf("backup of %s in snapshot %s does not match its checksum", rel, snapshots[i])
+			}
+			return writeFileAtomic(target, content, 0644)
+		}
+	}
+	return fmt.Errorf("no backup found for %s", rel)
+}
+
+// pxeDirManifest returns the checksum of every file in the PXE config folders keyed by its path relative
+// to the TFTP root.
+func pxeDirManifest() (map[string]string, error) {
+	manifest := map[string]string{}
+	for _, dir := range []string{igor.PXEBIOSDir, igor.PXEUEFIDir} {
+		err := filepath.WalkDir(filepath.Join(igor.TFTPPath, dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			sum, exists, sErr := fileSum(path)
+			if sErr != nil {
+				return sErr
+			}
+			if exists {
+				manifest[relTFTPPath(path)] = sum
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// unexpectedChanges compares manifests taken before and after an install and returns the files that were
+// added, changed or removed other than the ones the install meant to write.
+func unexpectedChanges(before, after map[string]string, intended map[string]bool) []string {
+	var changed []string
+	for path, sum := range after {
+		if intended[path] {
+			continue
+		}
+		if prev, ok := before[path]; !ok || prev != sum {
+			changed = append(changed, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok && !intended[path] {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
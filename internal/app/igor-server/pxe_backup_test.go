@@ -0,0 +1,168 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setPxeTestDirs(t *testing.T) {
+	origTFTP, origBIOS, origUEFI, origRetain := igor.TFTPPath, igor.PXEBIOSDir, igor.PXEUEFIDir, igor.Server.PxeBackupRetain
+	t.Cleanup(func() {
+		igor.TFTPPath, igor.PXEBIOSDir, igor.PXEUEFIDir = origTFTP, origBIOS, origUEFI
+		igor.Server.PxeBackupRetain = origRetain
+	})
+	igor.TFTPPath = t.TempDir()
+	igor.PXEBIOSDir = "pxelinux.cfg"
+	igor.PXEUEFIDir = "uefi"
+	igor.Server.PxeBackupRetain = DefaultPxeBackupRetain
+	for _, dir := range []string{igor.PXEBIOSDir, igor.PXEUEFIDir} {
+		require.NoError(t, os.MkdirAll(filepath.Join(igor.TFTPPath, dir, "igor"), 0755))
+	}
+}
+
+func newPxeTestRes(name, hash, hostName, mac string) *Reservation {
+	now := time.Now()
+	return &Reservation{
+		Name:  name,
+		Hash:  hash,
+		Start: now.Add(-time.Minute),
+		End:   now.Add(time.Hour),
+		Hosts: []Host{{Name: hostName, Mac: mac, BootMode: "bios"}},
+		Profile: Profile{Distro: Distro{
+			Name:        "centos",
+			DistroImage: DistroImage{ImageID: "img1", Kernel: "vmlinuz", Initrd: "initrd.img", Breed: "redhat"},
+		}},
+	}
+}
+
+func TestPxeInstallOwnership(t *testing.T) {
+
+	setPxeTestDirs(t)
+	installer := &TFTPInstaller{}
+
+	res1 := newPxeTestRes("res1", "hash1", "kn1", "aa:bb:cc:dd:ee:01")
+	require.NoError(t, installer.Install(res1))
+
+	rel := relTFTPPath(getPxePath(&res1.Hosts[0]))
+	ledger, err := readPxeLedger()
+	require.NoError(t, err)
+	require.Contains(t, ledger, rel)
+	assert.Equal(t, "hash1", ledger[rel].Owner)
+	assert.Equal(t, "kn1", ledger[rel].Host)
+
+	snapshots, err := listPxeSnapshots()
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 1)
+
+	// a host of another reservation with the same MAC can't take over the file
+	res2 := newPxeTestRes("res2", "hash2", "kn2", "aa:bb:cc:dd:ee:01")
+	err = installer.Install(res2)
+	var hErr *HostInstallError
+	require.True(t, errors.As(err, &hErr))
+	assert.Contains(t, hErr.HostErrors, "kn2")
+
+	ledger, err = readPxeLedger()
+	require.NoError(t, err)
+	assert.Equal(t, "hash1", ledger[rel].Owner)
+
+	// and uninstalling it leaves the file alone
+	require.NoError(t, installer.Uninstall(res2))
+	assert.FileExists(t, filepath.Join(igor.TFTPPath, rel))
+
+	problems, _, err := doPxeAuditOf([]Reservation{*res1}, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	// a changed file is reported by the audit and put back from backup on uninstall
+	require.NoError(t, os.WriteFile(filepath.Join(igor.TFTPPath, rel), []byte("tampered"), 0644))
+	problems, _, err = doPxeAuditOf([]Reservation{*res1}, time.Now())
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	assert.Equal(t, PxeAuditModified, problems[0].Problem)
+
+	require.NoError(t, installer.Uninstall(res1))
+	assert.NoFileExists(t, filepath.Join(igor.TFTPPath, rel), "file didn't exist before install")
+
+	ledger, err = readPxeLedger()
+	require.NoError(t, err)
+	assert.NotContains(t, ledger, rel)
+
+	// once the first reservation is gone the second can install
+	require.NoError(t, installer.Install(res2))
+}
+
+func TestPxeAuditProblems(t *testing.T) {
+
+	setPxeTestDirs(t)
+	installer := &TFTPInstaller{}
+
+	res1 := newPxeTestRes("res1", "hash1", "kn1", "aa:bb:cc:dd:ee:01")
+	res2 := newPxeTestRes("res2", "hash2", "kn2", "aa:bb:cc:dd:ee:02")
+	require.NoError(t, installer.Install(res1))
+	require.NoError(t, installer.Install(res2))
+
+	rel1 := relTFTPPath(getPxePath(&res1.Hosts[0]))
+	rel2 := relTFTPPath(getPxePath(&res2.Hosts[0]))
+	stray := filepath.Join(igor.PXEBIOSDir, "01-aa-bb-cc-dd-ee-99")
+	require.NoError(t, os.WriteFile(filepath.Join(igor.TFTPPath, stray), []byte("stray"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(igor.TFTPPath, rel1)))
+
+	// res2 is no longer installed, so its file is an orphan
+	problems, _, err := doPxeAuditOf([]Reservation{*res1}, time.Now())
+	require.NoError(t, err)
+
+	found := map[string]string{}
+	for _, p := range problems {
+		found[p.Path] = p.Problem
+	}
+	assert.Equal(t, map[string]string{
+		rel1:  PxeAuditMissing,
+		rel2:  PxeAuditOrphan,
+		stray: PxeAuditOrphan,
+	}, found)
+}
+
+func TestPrunePxeSnapshots(t *testing.T) {
+
+	setPxeTestDirs(t)
+	rel := filepath.Join(igor.PXEBIOSDir, "01-aa-bb-cc-dd-ee-01")
+
+	for i := 0; i < 5; i++ {
+		_, err := takePxeSnapshot("res1", []string{rel})
+		require.NoError(t, err)
+	}
+
+	pruned, err := prunePxeSnapshots(3)
+	require.NoError(t, err)
+	assert.Len(t, pruned, 2)
+
+	snapshots, err := listPxeSnapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 3)
+	assert.Equal(t, 3, pxeSnapshotSeq(snapshots[0]))
+	assert.Equal(t, 5, pxeSnapshotSeq(snapshots[2]))
+
+	// numbering carries on after a prune
+	_, err = takePxeSnapshot("res1", []string{rel})
+	require.NoError(t, err)
+	snapshots, err = listPxeSnapshots()
+	require.NoError(t, err)
+	assert.Equal(t, 6, pxeSnapshotSeq(snapshots[len(snapshots)-1]))
+}
+
+func TestUnexpectedChanges(t *testing.T) {
+	before := map[string]string{"a": "1", "b": "2", "c": "3"}
+	after := map[string]string{"a": "1", "b": "9", "d": "4", "e": "5"}
+	intended := map[string]bool{"e": true}
+	assert.Equal(t, []string{"b", "c", "d"}, unexpectedChanges(before, after, intended))
+}
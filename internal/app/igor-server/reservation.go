@@ -6,8 +6,6 @@ package igorserver
 
 import (
 	"fmt"
-	"sort"
-	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -17,6 +15,14 @@ import (
 
 const PermReservations = "reservations"
 
+// What happens to the power of a reservation's hosts when it ends
+const (
+	// EndPowerOff powers the hosts off and puts them through maintenance, if configured
+	EndPowerOff = "off"
+	// EndPowerLeaveOn leaves the hosts running and returns them straight to the pool
+	EndPowerLeaveOn = "leave-on"
+)
+
 // Reservation stores the information about a single reservation.
 type Reservation struct {
 	Base
@@ -33,102 +39,88 @@ type Reservation struct {
 	End         time.Time
 	OrigEnd     time.Time `gorm:"<-:create"`
 	ResetEnd    time.Time
+	// ReqDuration and ReqNodeCount are the length and number of hosts asked for when the res was created,
+	// before the end time was clamped to a time limit. They are kept to measure unmet demand.
+	ReqDuration  time.Duration `gorm:"<-:create"`
+	ReqNodeCount int           `gorm:"<-:create"`
 	// ExtendCount increments each time res is extended
 	ExtendCount  int
+	CoOwners     []User `gorm:"many2many:reservations_coowners;"`
 	Hosts        []Host `gorm:"many2many:reservations_hosts;"`
 	Installed    bool
 	InstallError string
 	CycleOnStart bool
 	NextNotify   time.Duration
+	// LastPowerOn is the last time a user powered on or cycled any of the res hosts, zero if never
+	LastPowerOn time.Time
+	// IdleWarned is when the owner was warned the res would be shortened for being idle, zero if not warned
+	IdleWarned time.Time
+	// KeepIdle is set when the owner acknowledges an idle res should be kept as-is
+	KeepIdle bool
+	// PausedUntil is when a paused res is due to resume, zero if the res is not paused
+	PausedUntil time.Time
+	// ResumeSubstitute allows a paused res to resume on substitute hosts if any of its own are taken
+	ResumeSubstitute bool
+	// ResumeError describes why a paused res could not resume, empty if it hasn't failed
+	ResumeError string
+	// MinNodes is the fewest hosts the owner will accept if some of them are unavailable when the res
+	// starts, 0 to use the configured percentage of the res hosts
+	MinNodes int
+	// HostsByCount is set when the scheduler picked the res hosts from a node count rather than the
+	// owner naming them, which lets unavailable hosts be swapped for others at start time
+	HostsByCount bool `gorm:"<-:create"`
+	// StartError describes why the res could not start, empty if it hasn't failed
+	StartError string
+	// ApprovalUntil is when a res waiting for admin approval is released if no admin has acted, zero if
+	// the res doesn't need approval or has been approved
+	ApprovalUntil time.Time
+	// ApprovalReason says which approval threshold the res is over
+	ApprovalReason string
+	// EndPower is EndPowerLeaveOn if the res hosts are left powered on when it ends, otherwise empty or
+	// EndPowerOff
+	EndPower string
+	// HeadHostID is the host the owner named as the head of the res, 0 to use the first host
+	HeadHostID int
+	// NetProfile is the network (QoS) profile an admin applied to the res ports along with its VLAN, empty
+	// if none
+	NetProfile string
+	// CycleAt is when the res hosts are due to be power cycled to boot a new profile, zero if no cycle is
+	// scheduled
+	CycleAt time.Time
+	// CycleProfileID is the profile the scheduled cycle boots. The cycle is skipped if the res profile has
+	// changed again by the time it is due.
+	CycleProfileID int
+	// Shares are the read-only links to the res the owner has handed out
+	Shares []ResShare
+	// NotifyAlso are the users copied on the reservation's email in addition to the owner's delegate
+	NotifyAlso []User `gorm:"many2many:reservations_notify_also;"`
 	// Hash is the unique ID used for history tracking
 	Hash string `gorm:"<-:create; unique; notNull"`
 	// Callback is the unique ID used for history tracking
 	HistCallback func(res *Reservation, status string) error `gorm:"-"`
 }
 
-func filterReservationList(resList []Reservation, user *User) []common.ReservationData {
-
-	var reportList []common.ReservationData
-
-	refreshPowerChan <- struct{}{}
-
-	for _, r := range resList {
-
-		sort.Slice(r.Hosts, func(i, j int) bool {
-			return r.Hosts[i].SequenceID < r.Hosts[j].SequenceID
-		})
-
-		hostNameList := namesOfHosts(r.Hosts)
-
-		remaining := time.Until(r.End).Round(time.Hour) / time.Hour
-
-		var groupName string
-		if !strings.HasPrefix(r.Group.Name, GroupUserPrefix) {
-			groupName = r.Group.Name
-		}
-
-		hostRange, _ := igor.ClusterRefs[0].UnsplitRange(hostNameList)
-
-		resHostData := filterHostList(r.Hosts, nil, user)
-		var resDownNodes = make([]string, 0, len(r.Hosts))
-		var resPowerNaNodes = make([]string, 0, len(r.Hosts))
-		var resUpNodes = make([]string, 0, len(r.Hosts))
-
-		for _, h := range hostNameList {
-			var isDownOrUnknown = false
-			for _, d := range resHostData {
-				if h == d.Name {
-					if d.Powered == "false" {
-						resDownNodes = append(resDownNodes, h)
-						isDownOrUnknown = true
-						break
-					} else if d.Powered == "unknown" {
-						resDownNodes = append(resPowerNaNodes, h)
-						isDownOrUnknown = true
-						break
-					}
-				}
-			}
-
-			if !isDownOrUnknown {
-				resUpNodes = append(resUpNodes, h)
-			}
-		}
+// ReservationHost is the lookup table entry linking a reservation to one of its hosts. It also records
+// the error, if any, encountered when installing the reservation's profile on that host.
+type ReservationHost struct {
+	ReservationID int `gorm:"primaryKey"`
+	HostID        int `gorm:"primaryKey"`
+	InstallError  string
+}
 
-		hostsUp, _ := igor.ClusterRefs[0].UnsplitRange(resUpNodes)
-		hostsDown, _ := igor.ClusterRefs[0].UnsplitRange(resDownNodes)
-		hostsUnknown, _ := igor.ClusterRefs[0].UnsplitRange(resPowerNaNodes)
-
-		resCopy := common.ReservationData{
-			Name:         r.Name,
-			Description:  r.Description,
-			Owner:        r.Owner.Name,
-			Group:        groupName,
-			Start:        r.Start.Unix(),
-			End:          r.End.Unix(),
-			OrigEnd:      r.OrigEnd.Unix(),
-			ExtendCount:  r.ExtendCount,
-			Installed:    r.Installed,
-			InstallError: r.InstallError,
-			Distro:       r.Profile.Distro.Name,
-			Profile:      r.Profile.Name,
-			Hosts:        hostNameList,
-			HostRange:    hostRange,
-			HostsUp:      hostsUp,
-			HostsDown:    hostsDown,
-			HostsPowerNA: hostsUnknown,
-			Vlan:         r.Vlan,
-			RemainHours:  int(remaining),
-		}
+// TableName keeps the lookup table name used by the many2many relationships of Reservation and Host.
+func (ReservationHost) TableName() string {
+	return "reservations_hosts"
+}
 
-		reportList = append(reportList, resCopy)
+// filterReservationList converts reservations read with their full associations into the data sent
+// to the user.
+func filterReservationList(resList []Reservation, user *User) []common.ReservationData {
+	summaries := make([]resSummary, len(resList))
+	for i := range resList {
+		summaries[i] = summarizeReservation(&resList[i])
 	}
-
-	sort.Slice(reportList, func(i, j int) bool {
-		return reportList[i].Name < reportList[j].Name
-	})
-
-	return reportList
+	return filterResSummaries(summaries, user)
 }
 
 // AfterFind populates the history callback method after a reservation is fetched from the DB but
@@ -143,6 +135,10 @@ func (r *Reservation) DeepCopy() *Reservation {
 
 	clone := *r
 	clone.Owner = r.Owner
+	clone.CoOwners = make([]User, len(r.CoOwners))
+	copy(clone.CoOwners, r.CoOwners)
+	clone.NotifyAlso = make([]User, len(r.NotifyAlso))
+	copy(clone.NotifyAlso, r.NotifyAlso)
 	clone.Group = r.Group
 	clone.Profile = r.Profile
 	clone.Profile.Distro = r.Profile.Distro
@@ -157,9 +153,58 @@ func (r *Reservation) DeepCopy() *Reservation {
 	return &clone
 }
 
-// IsActive returns true if the reservation is active at the given time
+// headHost returns the head host of the reservation. This is the host the owner named if it's still
+// part of the res, otherwise it is the first host, the one with the lowest sequence ID. Nil is
+// returned if the res holds no hosts.
+func (r *Reservation) headHost() *Host {
+	var first *Host
+	for i := range r.Hosts {
+		if r.Hosts[i].ID == r.HeadHostID {
+			return &r.Hosts[i]
+		}
+		if first == nil || r.Hosts[i].SequenceID < first.SequenceID {
+			first = &r.Hosts[i]
+		}
+	}
+	return first
+}
+
+// isCoOwner returns true if the named user is a co-owner of the reservation.
+func (r *Reservation) isCoOwner(name string) bool {
+	for _, u := range r.CoOwners {
+		if u.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isPaused returns true if the reservation has released its hosts until it resumes.
+func (r *Reservation) isPaused() bool {
+	return !r.PausedUntil.IsZero()
+}
+
+// startFailed returns true if the reservation could not start because too many of its hosts
+// were unavailable. It holds no hosts and waits to be deleted or to expire.
+func (r *Reservation) startFailed() bool {
+	return r.StartError != ""
+}
+
+// awaitingApproval returns true if the reservation holds its place on the schedule until an admin
+// approves it. It is not installed until then.
+func (r *Reservation) awaitingApproval() bool {
+	return !r.ApprovalUntil.IsZero()
+}
+
+// cyclePending returns true if a power cycle of the reservation hosts is scheduled.
+func (r *Reservation) cyclePending() bool {
+	return !r.CycleAt.IsZero()
+}
+
+// IsActive returns true if the reservation is active at the given time. A paused reservation, one
+// that failed to start or one still waiting for approval is never active.
 func (r *Reservation) IsActive(t time.Time) bool {
-	return r.Start.Before(t) && r.End.After(t)
+	return !r.isPaused() && !r.startFailed() && !r.awaitingApproval() && r.Start.Before(t) && r.End.After(t)
 }
 
 // Duration returns the duration interval of the reservation. It will
@@ -184,19 +229,10 @@ func (r *Reservation) Remaining(t time.Time) time.Duration {
 // 	return filepath.Join(igor.TFTPPath, igor.PXEDir, "igor", r.Name)
 // }
 
+// getKernelArgs returns the kernel args written to the boot line of the reservation, distro args
+// first followed by the profile args.
 func (r *Reservation) getKernelArgs() string {
-	// profile args should append behind distro args if both exist
-	kArgs := ""
-	if r.Profile.Distro.KernelArgs != "" {
-		kArgs = kArgs + r.Profile.Distro.KernelArgs
-	}
-	if r.Profile.KernelArgs != "" {
-		if kArgs != "" {
-			kArgs = kArgs + " "
-		}
-		kArgs = kArgs + r.Profile.KernelArgs
-	}
-	return kArgs
+	return mergeKernelArgs(r.Profile.Distro.KernelArgs, r.Profile.KernelArgs)
 }
 
 func (r *Reservation) checkHostBootPolicy() error {
@@ -222,3 +258,16 @@ func (r *Reservation) checkHostBootPolicy() error {
 	}
 	return nil
 }
+
+// leavesHostsOn returns true if the res hosts are left powered on when it ends.
+func (r *Reservation) leavesHostsOn() bool {
+	return r.EndPower == EndPowerLeaveOn
+}
+
+// checkEndPower makes sure an end power preference is one that is understood.
+func checkEndPower(endPower string) error {
+	if endPower != EndPowerOff && endPower != EndPowerLeaveOn {
+		return fmt.Errorf("endPower must be '%s' or '%s'", EndPowerOff, EndPowerLeaveOn)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// adviceChoicesTTL is how long the distros, profiles and groups offered to a user are reused, since
+// the web create form asks for advice each time it changes
+const adviceChoicesTTL = time.Minute
+
+var adviceChoicesCache = common.NewPassiveTtlMap(adviceChoicesTTL)
+
+// resAdvice holds what a create learns about a request being advised on that isn't kept with the
+// reservation.
+type resAdvice struct {
+	missing []string
+	// ceiling is the longest reservation allowed on the planned hosts, 0 if there is no limit
+	ceiling time.Duration
+}
+
+// adviceChoices are the distros, profiles and groups a user can pick from when making a reservation.
+type adviceChoices struct {
+	distros  []string
+	profiles []string
+	groups   []string
+}
+
+// destination for route POST /reservations/advise
+func handleAdviseReservation(w http.ResponseWriter, r *http.Request) {
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	resParams := getBodyFromContext(r)
+	clog := hlog.FromRequest(r)
+	actionPrefix := "advise reservation"
+	rb := common.NewResponseBodyResAdvice()
+
+	advice, status, err := doAdviseReservation(resParams, r)
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["advice"] = *advice
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// doAdviseReservation tells what igor would do with the reservation request if it were made now. The
+// request is put through the same checks and scheduling as a real create without saving anything, so
+// it can be partly filled in: without a name or OS the hosts are still planned, and without a node list
+// or count one node is assumed. A request that wouldn't be accepted still gets advice saying why, along
+// with when enough nodes are free if that is the reason.
+func doAdviseReservation(resParams map[string]interface{}, r *http.Request) (*common.ResAdviceData, int, error) {
+
+	user := getUserFromContext(r)
+	owner := user
+	if ownerName, ok := resParams["owner"].(string); ok && ownerName != "" && ownerName != user.Name && userElevated(user.Name) {
+		users, status, err := getUsersTx([]string{ownerName}, true)
+		if err != nil {
+			return nil, status, err
+		}
+		owner = &users[0]
+	}
+
+	choices, err := getAdviceChoices(owner)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	data := &common.ResAdviceData{Distros: choices.distros, Profiles: choices.profiles, Groups: choices.groups}
+
+	params := make(map[string]interface{}, len(resParams)+1)
+	for k, v := range resParams {
+		params[k] = v
+	}
+	_, nl := params["nodeList"]
+	_, nc := params["nodeCount"]
+	if !nl && !nc {
+		params["nodeCount"] = float64(1)
+	}
+
+	if vErr := checkResvParamValues(params, r); vErr != nil {
+		data.Problem = vErr.Error()
+		return data, http.StatusOK, nil
+	}
+
+	advice := &resAdvice{}
+	res, _, msg, _, cErr := createReservation(params, r, advice)
+	data.Missing = advice.missing
+	if !nl && !nc {
+		data.Missing = append(data.Missing, "nodeList or nodeCount")
+	}
+	if advice.ceiling > 0 {
+		data.MaxDuration = common.FormatDuration(advice.ceiling, false)
+	}
+
+	if cErr != nil {
+		data.Problem = cErr.Error()
+		var fullErr *ScheduleFullError
+		if errors.As(cErr, &fullErr) {
+			data.NodesFree = fullErr.numAvail
+			if !fullErr.suggestedStart.IsZero() {
+				data.SuggestedStart = fullErr.suggestedStart.Unix()
+			}
+		}
+		return data, http.StatusOK, nil
+	}
+
+	data.Message = msg
+	data.Start = res.Start.Unix()
+	data.End = res.End.Unix()
+	data.NodeCount = len(res.Hosts)
+	hostNames := namesOfHosts(res.Hosts)
+	data.HostRange, _ = igor.ClusterRefs[0].UnsplitRange(hostNames)
+	data.KernelLine = res.getKernelArgs()
+
+	var freeUntil time.Time
+	if err = performDbTx(func(tx *gorm.DB) error {
+		var fuErr error
+		freeUntil, fuErr = dbHostsFreeUntil(hostNames, res.Start, userElevated(res.Owner.Name), tx)
+		return fuErr
+	}); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	data.AvailableUntil = freeUntil.Unix()
+
+	if len(data.Missing) > 0 {
+		data.Problem = "missing " + strings.Join(data.Missing, ", ")
+	} else {
+		data.Valid = true
+	}
+
+	return data, http.StatusOK, nil
+}
+
+// getAdviceChoices returns the distros the user can boot, the profiles they own and the groups they can
+// make a reservation with. The lists are cached for a short time.
+func getAdviceChoices(user *User) (*adviceChoices, error) {
+
+	if cached, ok := adviceChoicesCache.Get(user.Name).(*adviceChoices); ok {
+		return cached, nil
+	}
+
+	choices := &adviceChoices{distros: []string{}, profiles: []string{}, groups: []string{}}
+	if err := performDbTx(func(tx *gorm.DB) error {
+		distros, err := dbReadDistros(map[string]interface{}{}, tx)
+		if err != nil {
+			return err
+		}
+		for _, d := range distros {
+			if user.isMemberOfAnyGroup(d.Groups) {
+				choices.distros = append(choices.distros, d.Name)
+			}
+		}
+		profiles, err := dbReadProfiles(map[string]interface{}{"owner_id": user.ID}, tx)
+		if err != nil {
+			return err
+		}
+		for _, p := range profiles {
+			// temp profiles are tied to the reservation they were made for
+			if !p.IsDefault {
+				choices.profiles = append(choices.profiles, p.Name)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, g := range user.Groups {
+		if g.Name != GroupAll && !g.IsUserPrivate {
+			choices.groups = append(choices.groups, g.Name)
+		}
+	}
+
+	sort.Strings(choices.distros)
+	sort.Strings(choices.profiles)
+	sort.Strings(choices.groups)
+	adviceChoicesCache.Put(user.Name, choices)
+	return choices, nil
+}
+
+// dbHostsFreeUntil returns when the first reservation on any of the named hosts that starts after the
+// given time begins, or the end of the schedule if there is none.
+func dbHostsFreeUntil(hostNames []string, after time.Time, isElevated bool, tx *gorm.DB) (time.Time, error) {
+
+	var next []Reservation
+	result := tx.Joins("JOIN reservations_hosts rh ON rh.reservation_id = reservations.id").
+		Joins("JOIN hosts h ON h.id = rh.host_id").
+		Where("h.name IN ? AND reservations.start > ?", hostNames, after).
+		Order("reservations.start").Limit(1).Find(&next)
+	if result.Error != nil {
+		return time.Time{}, result.Error
+	}
+	if len(next) == 0 {
+		return getScheduleEnd(isElevated), nil
+	}
+	return next[0].Start, nil
+}
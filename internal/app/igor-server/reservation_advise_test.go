@@ -0,0 +1,133 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestAdviseReservation(t *testing.T) {
+
+	origSched, origSchedMinutes, origNotify := igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn
+	t.Cleanup(func() {
+		igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn = origSched, origSchedMinutes, origNotify
+		adviceChoicesCache.Clear()
+	})
+	notifyOff := false
+	igor.Email.ResNotifyOn = &notifyOff
+	igor.Scheduler.MinReserveTime = 30
+	igor.Scheduler.DefaultReserveTime = 60
+	igor.Scheduler.MaxReserveTime = 30 * 24 * 60
+	igor.Scheduler.NodeReserveLimit = 0
+	MaxScheduleMinutes = 45 * 24 * 60
+	setReimageTestRange(t)
+	adviceChoicesCache.Clear()
+
+	seedTimeLimitHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	pug := Group{Name: GroupUserPrefix + "alice", IsUserPrivate: true}
+	require.NoError(t, db.Omit(clause.Associations).Create(&pug).Error)
+	course := Group{Name: "course"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&course).Error)
+	alice := User{Name: "alice", Email: "alice@example.com", Groups: []Group{pug, all, course}}
+	require.NoError(t, db.Omit("Groups.*").Create(&alice).Error)
+	require.NoError(t, db.Omit("Groups.*").Create(&Distro{Name: "centos", Groups: []Group{all}}).Error)
+	require.NoError(t, db.Omit("Groups.*").Create(&Distro{Name: "secret", Groups: []Group{course}}).Error)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, &alice))
+	countRes := func() int64 {
+		var n int64
+		require.NoError(t, db.Model(&Reservation{}).Count(&n).Error)
+		return n
+	}
+
+	// just a node count and duration: the nodes are planned and what's still needed is listed
+	advice, status, err := doAdviseReservation(map[string]interface{}{"nodeCount": float64(1), "duration": "2h"}, r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.False(t, advice.Valid)
+	assert.Equal(t, []string{"name", "distro or profile"}, advice.Missing)
+	assert.Equal(t, 1, advice.NodeCount)
+	assert.NotEmpty(t, advice.HostRange)
+	assert.InDelta(t, (2 * time.Hour).Seconds(), advice.End-advice.Start, 60)
+	assert.Equal(t, []string{"centos", "secret"}, advice.Distros)
+	assert.Equal(t, []string{"course"}, advice.Groups)
+	assert.Empty(t, advice.Profiles)
+
+	// a complete request on the 24 hour node validates and is free until the end of the schedule
+	full := map[string]interface{}{"name": "res-one", "distro": "centos", "nodeList": "kn2", "duration": "2h"}
+	advice, _, err = doAdviseReservation(full, r)
+	require.NoError(t, err)
+	assert.True(t, advice.Valid, advice.Problem)
+	assert.Empty(t, advice.Problem)
+	assert.Equal(t, "kn2", advice.HostRange)
+	assert.Equal(t, common.FormatDuration(24*time.Hour, false), advice.MaxDuration)
+	assert.Equal(t, getScheduleEnd(false).Unix(), advice.AvailableUntil)
+	assert.Equal(t, int64(0), countRes())
+
+	// the node is free until the next reservation on it starts
+	later := time.Now().Add(10 * time.Hour).Truncate(time.Minute)
+	_, _, _, status, err = doCreateReservation(map[string]interface{}{
+		"name": "later", "distro": "centos", "nodeList": "kn2", "duration": "2h", "start": float64(later.Unix())}, r)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, status)
+	advice, _, err = doAdviseReservation(full, r)
+	require.NoError(t, err)
+	assert.True(t, advice.Valid)
+	assert.Equal(t, later.Unix(), advice.AvailableUntil)
+
+	// the limits a create would apply are reported as the problem
+	full["duration"] = "30h"
+	advice, _, err = doAdviseReservation(full, r)
+	require.NoError(t, err)
+	assert.False(t, advice.Valid)
+	assert.Contains(t, advice.Problem, "latest end")
+
+	// asking for more nodes than are free says how many are and when enough will be
+	advice, _, err = doAdviseReservation(map[string]interface{}{"name": "res-two", "distro": "centos", "nodeCount": float64(2), "duration": "12h"}, r)
+	require.NoError(t, err)
+	assert.False(t, advice.Valid)
+	assert.Equal(t, 1, advice.NodesFree)
+	assert.NotZero(t, advice.SuggestedStart)
+
+	// a taken name and a malformed parameter are problems too
+	advice, _, err = doAdviseReservation(map[string]interface{}{"name": "later", "distro": "centos", "nodeCount": float64(1)}, r)
+	require.NoError(t, err)
+	assert.False(t, advice.Valid)
+	assert.Contains(t, advice.Problem, "already exists")
+	advice, _, err = doAdviseReservation(map[string]interface{}{"nodeCount": "many"}, r)
+	require.NoError(t, err)
+	assert.False(t, advice.Valid)
+	assert.NotEmpty(t, advice.Problem)
+	assert.Equal(t, []string{"centos", "secret"}, advice.Distros)
+
+	// the choices are cached for a short time
+	require.NoError(t, db.Omit("Groups.*").Create(&Distro{Name: "ubuntu", Groups: []Group{all}}).Error)
+	advice, _, err = doAdviseReservation(map[string]interface{}{}, r)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"centos", "secret"}, advice.Distros)
+	adviceChoicesCache.Clear()
+	advice, _, err = doAdviseReservation(map[string]interface{}{}, r)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"centos", "secret", "ubuntu"}, advice.Distros)
+
+	// nothing but the one real create was saved
+	assert.Equal(t, int64(1), countRes())
+}
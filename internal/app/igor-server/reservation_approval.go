@@ -0,0 +1,207 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// approvalNeeded returns why a reservation of the given node count and length must be approved by
+// an admin before it is installed, or an empty string if it doesn't need approval.
+func approvalNeeded(nodes int, length time.Duration) string {
+	if igor.Scheduler.ApprovalNodes > 0 && nodes > igor.Scheduler.ApprovalNodes {
+		return fmt.Sprintf("%d nodes is more than %d", nodes, igor.Scheduler.ApprovalNodes)
+	}
+	if igor.Scheduler.ApprovalDays > 0 && length > time.Duration(igor.Scheduler.ApprovalDays)*24*time.Hour {
+		return fmt.Sprintf("%s is longer than %d days", common.FormatDuration(length, false), igor.Scheduler.ApprovalDays)
+	}
+	return ""
+}
+
+// endPowerApprovalNeeded returns why a reservation made by a non-admin with the given end power
+// preference must be approved by an admin, or an empty string if it doesn't need approval.
+func endPowerApprovalNeeded(endPower string) string {
+	if igor.Scheduler.ApproveLeaveOn && endPower == EndPowerLeaveOn {
+		return "nodes are left powered on when it ends"
+	}
+	return ""
+}
+
+// endPowerEditApproval returns the changes that hold a reservation for approval when a non-admin
+// asks for its nodes to be left powered on and that needs approval. A reservation that has started
+// can't go back to waiting for approval, so only an admin can change it.
+func endPowerEditApproval(res *Reservation, endPower string, now time.Time) (map[string]interface{}, int, error) {
+	reason := endPowerApprovalNeeded(endPower)
+	if reason == "" || res.leavesHostsOn() || res.awaitingApproval() {
+		return nil, http.StatusOK, nil
+	}
+	if res.Installed || !res.Start.After(now) {
+		return nil, http.StatusForbidden, newCodedError(common.ErrElevateRequired,
+			"leaving the nodes of a started reservation powered on when it ends needs admin approval -- ask an igor admin to set it")
+	}
+	return map[string]interface{}{"ApprovalUntil": approvalHoldEnd(now), "ApprovalReason": reason}, http.StatusOK, nil
+}
+
+// approvalHoldEnd returns when a reservation made at the given time stops waiting for approval.
+func approvalHoldEnd(now time.Time) time.Time {
+	return now.Add(time.Duration(igor.Scheduler.ApprovalHoldHours) * time.Hour).Truncate(time.Minute)
+}
+
+// getPendingReservation returns the named reservation if it is waiting for approval. Only an elevated
+// admin can approve or deny a reservation.
+func getPendingReservation(resName string, actionUser *User, tx *gorm.DB) (*Reservation, int, error) {
+
+	if !userElevated(actionUser.Name) {
+		return nil, http.StatusForbidden, newCodedError(common.ErrElevateRequired, "approving or denying a reservation requires admin elevated privilege")
+	}
+
+	rList, status, err := getReservations([]string{resName}, tx)
+	if err != nil {
+		return nil, status, err
+	}
+	res := &rList[0]
+	if !res.awaitingApproval() {
+		return nil, http.StatusConflict, fmt.Errorf("reservation '%s' is not waiting for approval", res.Name)
+	}
+	return res, http.StatusOK, nil
+}
+
+// doApproveReservation lets a reservation waiting for approval go on as normal. If its start time has
+// passed it is installed on the next pass of the reservation manager.
+func doApproveReservation(resName string, reason string, r *http.Request) (msg string, status int, err error) {
+
+	clog := hlog.FromRequest(r)
+	actionUser := getUserFromContext(r)
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+	var res *Reservation
+
+	clusters, cErr := dbReadClustersTx(nil)
+	if cErr != nil {
+		return "", status, cErr
+	}
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+		var gpStatus int
+		if res, gpStatus, err = getPendingReservation(resName, actionUser, tx); err != nil {
+			status = gpStatus
+			return err
+		}
+		return dbEditReservation(res, map[string]interface{}{"ApprovalUntil": time.Time{}, "ApprovalReason": ""}, tx)
+	}); err != nil {
+		return
+	}
+
+	clog.Info().Msgf("reservation '%s' approved by %s", res.Name, actionUser.Name)
+	if hErr := res.HistCallback(res, HrUpdated+":approved"); hErr != nil {
+		clog.Error().Msgf("failed to record reservation '%s' approval to history", res.Name)
+	}
+
+	if approveEvent := makeResEditNotifyEvent(EmailResApproved, res.DeepCopy(), clusters[0].Name, actionUser, true, reason); approveEvent != nil {
+		resNotifyChan <- *approveEvent
+	}
+
+	msg = fmt.Sprintf("reservation '%s' approved", res.Name)
+	if res.Start.Before(time.Now()) {
+		msg += " and will be installed now"
+	}
+	return msg, http.StatusOK, nil
+}
+
+// doDenyReservation deletes a reservation waiting for approval and emails its owner the reason.
+func doDenyReservation(resName string, reason string, r *http.Request) (status int, err error) {
+
+	clog := hlog.FromRequest(r)
+	actionUser := getUserFromContext(r)
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+	var res *Reservation
+
+	clusters, cErr := dbReadClustersTx(nil)
+	if cErr != nil {
+		return status, cErr
+	}
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+		var gpStatus int
+		if res, gpStatus, err = getPendingReservation(resName, actionUser, tx); err != nil {
+			status = gpStatus
+			return err
+		}
+		// the reservation was never installed so it has no hosts to give back
+		_, err = doDeleteRes(res, tx, false, clog)
+		return err
+	}); err != nil {
+		return
+	}
+
+	clog.Info().Msgf("reservation '%s' denied by %s - %s", res.Name, actionUser.Name, reason)
+	if hErr := res.HistCallback(res, HrDeleted+":denied"); hErr != nil {
+		clog.Error().Msgf("failed to record reservation '%s' denial to history", res.Name)
+	}
+
+	if denyEvent := makeResEditNotifyEvent(EmailResDenied, res, clusters[0].Name, actionUser, true, reason); denyEvent != nil {
+		resNotifyChan <- *denyEvent
+	}
+
+	return http.StatusOK, nil
+}
+
+// expireApprovalHolds deletes reservations that have waited for approval past their hold time so they
+// no longer block the schedule. Their owners are emailed.
+func expireApprovalHolds(checkTime *time.Time) error {
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	var resIDs []int
+	if result := igor.IGormDb.GetDB().Model(&Reservation{}).
+		Where("approval_until > ? AND approval_until <= ?", time.Time{}, *checkTime).Pluck("id", &resIDs); result.Error != nil {
+		return result.Error
+	} else if len(resIDs) == 0 {
+		return nil
+	}
+
+	resList, err := dbReadReservationsTx(map[string]interface{}{"id": resIDs}, nil)
+	if err != nil {
+		return err
+	}
+
+	clusters, cErr := dbReadClustersTx(nil)
+	if cErr != nil {
+		return cErr
+	}
+
+	for _, r := range resList {
+
+		resClone := r.DeepCopy()
+
+		// the reservation was never installed so it has no hosts to give back
+		if err = performDbTx(func(tx *gorm.DB) error {
+			_, err = doDeleteRes(&r, tx, false, &logger)
+			return err
+		}); err != nil {
+			logger.Error().Msgf("failed to delete reservation '%s' waiting for approval - %v", r.Name, err)
+			continue
+		}
+
+		if hErr := resClone.HistCallback(resClone, HrDeleted+":approval-expired"); hErr != nil {
+			logger.Error().Msgf("failed to record reservation '%s' approval expiry to history", resClone.Name)
+		}
+
+		logger.Info().Msgf("reservation '%s' was not approved by %s -- deleting", resClone.Name, resClone.ApprovalUntil.Format(common.DateTimeLongFormat))
+		if expireEvent := makeResWarnNotifyEvent(EmailResDenied, 0, resClone, clusters[0].Name); expireEvent != nil {
+			expireEvent.Info = fmt.Sprintf("no igor admin reviewed the request by %s", resClone.ApprovalUntil.Format(common.DateTimeCompactFormat))
+			resNotifyChan <- *expireEvent
+		}
+	}
+
+	return nil
+}
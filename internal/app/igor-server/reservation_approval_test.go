@@ -0,0 +1,159 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+func setApprovalLimits(t *testing.T, nodes, days, holdHours int) {
+	orig := igor.Scheduler
+	t.Cleanup(func() { igor.Scheduler = orig })
+	igor.Scheduler.ApprovalNodes = nodes
+	igor.Scheduler.ApprovalDays = days
+	igor.Scheduler.ApprovalHoldHours = holdHours
+}
+
+// newPendingTestRes adds a reservation starting now that waits for approval until the given time. The
+// owner gets the usual permission on it so it can be deleted.
+func newPendingTestRes(t *testing.T, db *gorm.DB, name string, hosts []Host, until time.Time) *Reservation {
+	res := newStartTestRes(t, db, name, hosts, false, 0)
+	perm, err := NewPermission(NewPermissionString(PermReservations, name, PermWildcardToken))
+	require.NoError(t, err)
+	pug := Group{}
+	require.NoError(t, db.Where("name = ?", GroupUserPrefix+"alice").First(&pug).Error)
+	require.NoError(t, dbAppendPermissions(&pug, []Permission{*perm}, db))
+	if !until.IsZero() {
+		require.NoError(t, db.Model(res).Updates(map[string]interface{}{"approval_until": until, "approval_reason": "too big"}).Error)
+		res.ApprovalUntil = until
+	}
+	return res
+}
+
+func TestApprovalNeeded(t *testing.T) {
+
+	setApprovalLimits(t, 0, 0, 72)
+	assert.Empty(t, approvalNeeded(500, 365*24*time.Hour))
+
+	setApprovalLimits(t, 10, 7, 72)
+	assert.Empty(t, approvalNeeded(10, 7*24*time.Hour))
+	assert.Equal(t, "11 nodes is more than 10", approvalNeeded(11, time.Hour))
+	assert.Contains(t, approvalNeeded(2, 8*24*time.Hour), "longer than 7 days")
+
+	now := time.Date(2024, 3, 1, 9, 30, 15, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 4, 9, 30, 0, 0, time.UTC), approvalHoldEnd(now))
+}
+
+func TestApproveDenyReservation(t *testing.T) {
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	require.NoError(t, db.Omit("Hosts").Create(&Cluster{Name: "testc", Prefix: "kn"}).Error)
+
+	res := newPendingTestRes(t, db, "r1", hosts[:1], time.Now().Add(time.Hour))
+	assert.True(t, res.awaitingApproval())
+	assert.False(t, res.IsActive(time.Now()))
+
+	admin := User{Name: "admin"}
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, &admin))
+
+	// the owner's edit permission isn't enough, an admin must be elevated
+	_, status, err := doApproveReservation("r1", "", r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	igor.ElevateMap.Put(admin.Name, true)
+	t.Cleanup(func() { igor.ElevateMap.Remove(admin.Name) })
+
+	msg, status, err := doApproveReservation("r1", "ok for the test campaign", r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, msg, "installed now")
+	stored, err := dbReadReservationsTx(map[string]interface{}{"name": "r1"}, nil)
+	require.NoError(t, err)
+	assert.False(t, stored[0].awaitingApproval())
+	assert.Empty(t, stored[0].ApprovalReason)
+
+	// only a pending reservation can be approved or denied
+	_, status, err = doApproveReservation("r1", "", r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+	status, err = doDenyReservation("r1", "no", r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+
+	newPendingTestRes(t, db, "r2", hosts[:1], time.Now().Add(time.Hour))
+	status, err = doDenyReservation("r2", "too many nodes this month", r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	stored, err = dbReadReservationsTx(map[string]interface{}{"name": "r2"}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+}
+
+func TestExpireApprovalHolds(t *testing.T) {
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	require.NoError(t, db.Omit("Hosts").Create(&Cluster{Name: "testc", Prefix: "kn"}).Error)
+
+	now := time.Now()
+	newPendingTestRes(t, db, "r1", hosts[:1], now.Add(-time.Minute))
+	newPendingTestRes(t, db, "r2", hosts[1:], now.Add(time.Hour))
+	newPendingTestRes(t, db, "r3", hosts[1:], time.Time{})
+
+	require.NoError(t, expireApprovalHolds(&now))
+
+	resList, err := dbReadReservationsTx(nil, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"r2", "r3"}, resNamesOfResList(resList))
+}
+
+func TestEndPowerApproval(t *testing.T) {
+
+	setApprovalLimits(t, 0, 0, 72)
+	now := time.Now()
+	future := &Reservation{Start: now.Add(time.Hour)}
+
+	// nothing needs approval unless the cluster asks for it
+	assert.Empty(t, endPowerApprovalNeeded(EndPowerLeaveOn))
+	changes, status, err := endPowerEditApproval(future, EndPowerLeaveOn, now)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, changes)
+
+	igor.Scheduler.ApproveLeaveOn = true
+	assert.Empty(t, endPowerApprovalNeeded(EndPowerOff))
+	assert.NotEmpty(t, endPowerApprovalNeeded(EndPowerLeaveOn))
+
+	// a reservation that hasn't started goes back to waiting for approval
+	changes, _, err = endPowerEditApproval(future, EndPowerLeaveOn, now)
+	assert.NoError(t, err)
+	assert.Equal(t, approvalHoldEnd(now), changes["ApprovalUntil"])
+
+	// one that has started can only be changed by an admin
+	started := &Reservation{Start: now.Add(-time.Hour), Installed: true}
+	_, status, err = endPowerEditApproval(started, EndPowerLeaveOn, now)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, common.ErrElevateRequired, errorCodeOf(err))
+
+	// but turning it back to off is always allowed
+	started.EndPower = EndPowerLeaveOn
+	_, status, err = endPowerEditApproval(started, EndPowerOff, now)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
@@ -0,0 +1,274 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/api"
+	"igor2/internal/pkg/common"
+)
+
+// MaxBulkResEdits is the most reservations a single bulk extend or owner change can apply to.
+var MaxBulkResEdits = 100
+
+// bulkResEdit is a change an admin makes to all the reservations of one owner at once, such as at the end
+// of a semester. Only the reservations whose names start with namePrefix are changed if it is set.
+type bulkResEdit struct {
+	owner      string
+	namePrefix string
+	// until is the new end of each reservation for a bulk extend
+	until string
+	// newOwner is who gets the reservations for a bulk owner change
+	newOwner string
+	dryRun   bool
+}
+
+// histStatus is the history status recorded for each reservation the edit changes, which names the admin
+// that made it.
+func (e *bulkResEdit) histStatus(actionUser *User) string {
+	kind := "bulk-owner"
+	if e.until != "" {
+		kind = "bulk-extend"
+	}
+	return HrUpdated + ":" + kind + ",by=" + actionUser.Name
+}
+
+// doBulkEditReservations applies the edit to each matching reservation in one transaction. Every
+// reservation gets the same checks as when it is edited on its own, except that an extension is held to
+// the time limits of the owner instead of skipping them as an elevated admin normally would. One that
+// fails its checks is reported and skipped. With dryRun the checks are made but nothing is changed.
+func doBulkEditReservations(edit *bulkResEdit, r *http.Request) (results []common.BatchItemResult, status int, err error) {
+
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+	clog := hlog.FromRequest(r)
+	actionUser := getUserFromContext(r)
+	var clusterName string
+	var oldOwner User
+	var edited []int
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		clusters, cErr := dbReadClustersTx(nil)
+		if cErr != nil {
+			return cErr
+		}
+		clusterName = clusters[0].Name
+
+		uList, guStatus, guErr := getUsers([]string{edit.owner}, false, tx)
+		if guErr != nil {
+			status = guStatus
+			return guErr
+		}
+		oldOwner = uList[0]
+
+		if edit.newOwner != "" {
+			if _, guStatus, guErr = getUsers([]string{edit.newOwner}, false, tx); guErr != nil {
+				status = guStatus
+				return guErr
+			}
+		}
+
+		allRes, rErr := dbReadReservations(map[string]interface{}{"owner_id": oldOwner.ID}, nil, tx)
+		if rErr != nil {
+			return rErr
+		}
+		var resList []Reservation
+		for _, res := range allRes {
+			if strings.HasPrefix(res.Name, edit.namePrefix) {
+				resList = append(resList, res)
+			}
+		}
+		sort.Slice(resList, func(i, j int) bool { return resList[i].Name < resList[j].Name })
+
+		if len(resList) == 0 {
+			status = http.StatusNotFound
+			return fmt.Errorf("no reservations owned by %s match", oldOwner.Name)
+		} else if len(resList) > MaxBulkResEdits {
+			status = http.StatusBadRequest
+			return fmt.Errorf("%d reservations owned by %s match, more than the limit of %d -- use a name filter to change fewer at a time",
+				len(resList), oldOwner.Name, MaxBulkResEdits)
+		}
+
+		batch := newBatchRun(tx, false)
+		for i := range resList {
+			res := &resList[i]
+			var msg string
+			_ = batch.do(res.Name, func() error {
+				var changes map[string]interface{}
+				var vErr error
+				if edit.until != "" {
+					checks := extendChecks{timeLimits: true, limitGroups: res.Owner.groupNames()}
+					if changes, _, _, vErr = checkExtend(res, edit.until, false, checks, clog, tx); vErr != nil {
+						return vErr
+					}
+					msg = fmt.Sprintf("end %s to %s", res.End.Format(common.DateTimeCompactFormat),
+						changes["End"].(time.Time).Format(common.DateTimeCompactFormat))
+				} else {
+					if changes, _, vErr = parseResEditParams(res, map[string]interface{}{"owner": edit.newOwner}, tx); vErr != nil {
+						return vErr
+					}
+					msg = fmt.Sprintf("owner %s to %s", oldOwner.Name, edit.newOwner)
+				}
+				if edit.dryRun {
+					return nil
+				}
+				return dbEditReservation(res, changes, tx)
+			})
+			if last := &batch.results[len(batch.results)-1]; last.Result == common.BatchItemOK {
+				if edit.dryRun {
+					last.Message = "would change " + msg
+				} else {
+					last.Message = "changed " + msg
+					edited = append(edited, res.ID)
+				}
+			}
+		}
+		results = batch.results
+		return nil
+
+	}); err != nil {
+		return
+	}
+
+	status = http.StatusOK
+
+	for _, id := range edited {
+		rList, _ := dbReadReservationsTx(map[string]interface{}{"ID": id}, nil)
+		res := &rList[0]
+		clog.Info().Msgf("reservation '%s' %s", res.Name, edit.histStatus(actionUser))
+
+		if hErr := res.HistCallback(res, edit.histStatus(actionUser)); hErr != nil {
+			logger.Error().Msgf("failed to record reservation '%s' update to history", res.Name)
+		}
+
+		var resEditEvent *ResNotifyEvent
+		if edit.until != "" {
+			resEditEvent = makeResEditNotifyEvent(EmailResExtend, res, clusterName, actionUser, true, "")
+		} else {
+			resEditEvent = makeResEditNotifyEvent(EmailResNewOwner, res, clusterName, &oldOwner, false, "")
+		}
+		if resEditEvent != nil {
+			resNotifyChan <- *resEditEvent
+		}
+	}
+
+	return
+}
+
+func handleBulkEditReservations(w http.ResponseWriter, r *http.Request) {
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	editParams := getBodyFromContext(r)
+	clog := hlog.FromRequest(r)
+	rb := common.NewResponseBodyBatch()
+
+	edit := &bulkResEdit{}
+	edit.namePrefix, _ = editParams["filterName"].(string)
+	edit.dryRun, _ = editParams["dryRun"].(bool)
+	actionPrefix := "bulk change reservation owner"
+	doneVerb := "changed owner"
+	if r.URL.Path == api.ResBulkExtend {
+		actionPrefix = "bulk extend reservations"
+		doneVerb = "extended"
+		edit.owner, _ = editParams["owner"].(string)
+		edit.until = time.Unix(int64(editParams["until"].(float64)), 0).Format(common.DateTimeCompactFormat)
+	} else {
+		edit.owner, _ = editParams["from"].(string)
+		edit.newOwner, _ = editParams["to"].(string)
+	}
+	if edit.dryRun {
+		doneVerb = "would be " + doneVerb
+	}
+
+	results, status, err := doBulkEditReservations(edit, r)
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		setBatchResults(rb, results, doneVerb)
+		clog.Info().Msgf("%s success - %s", actionPrefix, rb.Message)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+func validateBulkResParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+
+		editParams := getBodyFromContext(r)
+		isExtend := r.URL.Path == api.ResBulkExtend
+		required := []string{"from", "to"}
+		if isExtend {
+			required = []string{"owner", "until"}
+		}
+		for _, key := range required {
+			if _, ok := editParams[key]; !ok {
+				validateErr = NewMissingParamError(key)
+				break
+			}
+		}
+
+		if validateErr == nil {
+		patchParamLoop:
+			for key, val := range editParams {
+				switch {
+				case key == "owner" && isExtend, (key == "from" || key == "to") && !isExtend:
+					if v, ok := val.(string); !ok {
+						validateErr = NewBadParamTypeError(key, val, "string")
+						break patchParamLoop
+					} else if strings.TrimSpace(v) == "" {
+						validateErr = fmt.Errorf("%s cannot be empty", key)
+						break patchParamLoop
+					}
+				case key == "until" && isExtend:
+					if _, ok := val.(float64); !ok {
+						validateErr = NewBadParamTypeError(key, val, "number")
+						break patchParamLoop
+					}
+				case key == "filterName":
+					if _, ok := val.(string); !ok {
+						validateErr = NewBadParamTypeError(key, val, "string")
+						break patchParamLoop
+					}
+				case key == "dryRun":
+					if _, ok := val.(bool); !ok {
+						validateErr = NewBadParamTypeError(key, val, "bool")
+						break patchParamLoop
+					}
+				default:
+					validateErr = NewUnknownParamError(key, val)
+					break patchParamLoop
+				}
+			}
+		}
+
+		if validateErr == nil && r.URL.Path == api.ResBulkOwner {
+			if editParams["to"] == IgorAdmin {
+				validateErr = fmt.Errorf("cannot change reservation owner to igor-admin")
+			} else if editParams["from"] == editParams["to"] {
+				validateErr = fmt.Errorf("the new owner must be someone other than the current owner")
+			}
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateBulkResParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestBulkEditReservations(t *testing.T) {
+
+	origSched, origSchedMinutes, origNotify := igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn
+	t.Cleanup(func() {
+		igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn = origSched, origSchedMinutes, origNotify
+	})
+	notifyOff := false
+	igor.Email.ResNotifyOn = &notifyOff
+	igor.Scheduler.MinReserveTime = 30
+	igor.Scheduler.DefaultReserveTime = 60
+	igor.Scheduler.MaxReserveTime = 30 * 24 * 60
+	igor.Scheduler.NodeReserveLimit = 0
+	igor.Scheduler.ExtendWithin = 60
+	MaxScheduleMinutes = 45 * 24 * 60
+	setReimageTestRange(t)
+
+	seedTimeLimitHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	require.NoError(t, db.Omit("Hosts").Create(&Cluster{Name: "testc", Prefix: "kn"}).Error)
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	users := map[string]*User{}
+	for _, name := range []string{"alice", "bob"} {
+		pug := Group{Name: GroupUserPrefix + name, IsUserPrivate: true}
+		require.NoError(t, db.Omit(clause.Associations).Create(&pug).Error)
+		u := User{Name: name, Email: name + "@example.com", Groups: []Group{pug, all}}
+		require.NoError(t, db.Omit("Groups.*").Create(&u).Error)
+		users[name] = &u
+	}
+	require.NoError(t, db.Omit("Groups.*").Create(&Distro{Name: "centos", Groups: []Group{all}}).Error)
+
+	// kn1 allows 72 hours and kn2 24 hours
+	aliceReq := httptest.NewRequest(http.MethodPost, "/", nil)
+	aliceReq = aliceReq.WithContext(context.WithValue(aliceReq.Context(), userContextKey{}, users["alice"]))
+	later := float64(time.Now().Add(48 * time.Hour).Unix())
+	for _, params := range []map[string]interface{}{
+		{"name": "course-1", "distro": "centos", "nodeList": "kn1", "duration": "2h"},
+		{"name": "course-2", "distro": "centos", "nodeList": "kn2", "duration": "2h"},
+		{"name": "lab-1", "distro": "centos", "nodeList": "kn1", "duration": "2h", "start": later},
+	} {
+		_, _, _, status, err := doCreateReservation(params, aliceReq)
+		require.NoError(t, err, params["name"])
+		require.Equal(t, http.StatusCreated, status)
+	}
+
+	admin := User{Name: "admin"}
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, &admin))
+	readRes := func(name string) *Reservation {
+		rList, err := dbReadReservationsTx(map[string]interface{}{"name": name}, nil)
+		require.NoError(t, err)
+		require.Len(t, rList, 1)
+		return &rList[0]
+	}
+
+	// the owner's time limits still hold, so only the reservation on kn1 can run 30 hours, and the
+	// extend-within window of normal users doesn't apply
+	until := time.Now().Add(30 * time.Hour).Round(time.Minute)
+	edit := &bulkResEdit{owner: "alice", namePrefix: "course-", until: until.Format(common.DateTimeCompactFormat), dryRun: true}
+	results, status, err := doBulkEditReservations(edit, r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	require.Len(t, results, 2)
+	assert.Equal(t, "course-1", results[0].Name)
+	assert.Equal(t, common.BatchItemOK, results[0].Result)
+	assert.Contains(t, results[0].Message, "would change end")
+	assert.Equal(t, "course-2", results[1].Name)
+	assert.Equal(t, common.BatchItemFailed, results[1].Result)
+
+	// a dry run changes nothing
+	assert.True(t, readRes("course-1").End.Before(until))
+
+	edit.dryRun = false
+	results, _, err = doBulkEditReservations(edit, r)
+	require.NoError(t, err)
+	assert.Equal(t, common.BatchItemOK, results[0].Result)
+	assert.Equal(t, common.BatchItemFailed, results[1].Result)
+	assert.True(t, readRes("course-1").End.Equal(until))
+	assert.True(t, readRes("course-2").End.Before(until))
+
+	var hist HistoryRecord
+	require.NoError(t, db.Where("name = ? AND status = ?", "course-1", HrUpdated+":bulk-extend,by=admin").First(&hist).Error)
+	assert.True(t, hist.End.Equal(until))
+
+	// every matching reservation is handed over, including the one that couldn't be extended
+	results, status, err = doBulkEditReservations(&bulkResEdit{owner: "alice", newOwner: "bob"}, r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	require.Len(t, results, 3)
+	for _, result := range results {
+		assert.Equal(t, common.BatchItemOK, result.Result, result.Name)
+		assert.Equal(t, "bob", readRes(result.Name).Owner.Name)
+	}
+	var ownerHist HistoryRecord
+	require.NoError(t, db.Where("name = ? AND status = ?", "lab-1", HrUpdated+":bulk-owner,by=admin").First(&ownerHist).Error)
+	assert.Equal(t, "bob", ownerHist.Owner)
+
+	// alice has nothing left to change
+	_, status, err = doBulkEditReservations(&bulkResEdit{owner: "alice", newOwner: "bob"}, r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// and a batch over the limit is refused outright
+	origMax := MaxBulkResEdits
+	t.Cleanup(func() { MaxBulkResEdits = origMax })
+	MaxBulkResEdits = 2
+	_, status, err = doBulkEditReservations(&bulkResEdit{owner: "bob", newOwner: "alice"}, r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "bob", readRes("course-1").Owner.Name)
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+)
+
+// doClaimReservation makes the requesting user the owner of a reservation whose owner's account is pending
+// removal. Only members of the reservation's group can claim it.
+func doClaimReservation(resName string, r *http.Request) (msg string, status int, err error) {
+
+	clog := hlog.FromRequest(r)
+	actionUser := getUserFromContext(r)
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+	var res *Reservation
+	var oldOwner User
+	var clusterName string
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		clusters, cErr := dbReadClustersTx(nil)
+		if cErr != nil {
+			return cErr
+		}
+		clusterName = clusters[0].Name
+
+		rList, grStatus, grErr := getReservations([]string{resName}, tx)
+		if grErr != nil {
+			status = grStatus
+			return grErr
+		}
+		res = &rList[0]
+		oldOwner = res.Owner
+
+		var cStatus int
+		if cStatus, err = checkResClaim(res, actionUser); err != nil {
+			status = cStatus
+			return err
+		}
+
+		changes, pStatus, pErr := parseResEditParams(res, map[string]interface{}{"owner": actionUser.Name}, tx)
+		if pErr != nil {
+			status = pStatus
+			return pErr
+		}
+		return dbEditReservation(res, changes, tx)
+
+	}); err != nil {
+		return
+	}
+
+	status = http.StatusOK
+	msg = fmt.Sprintf("you are now the owner of reservation '%s'", resName)
+	clog.Info().Msgf("reservation '%s' claimed by '%s' from '%s' who is pending removal", resName, actionUser.Name, oldOwner.Name)
+
+	rList, _ := dbReadReservationsTx(map[string]interface{}{"ID": res.ID}, nil)
+	res = &rList[0]
+	if hErr := res.HistCallback(res, HrUpdated+":claim"); hErr != nil {
+		logger.Error().Msgf("failed to record reservation '%s' claim to history", res.Name)
+	}
+	if resEditEvent := makeResEditNotifyEvent(EmailResNewOwner, res, clusterName, &oldOwner, false, ""); resEditEvent != nil {
+		resNotifyChan <- *resEditEvent
+	}
+
+	return
+}
+
+// checkResClaim returns an error if the user can't claim the reservation. A reservation can only be claimed
+// while its owner is pending removal, and only by another member of the reservation's group.
+func checkResClaim(res *Reservation, user *User) (int, error) {
+	if !res.Owner.isPendingRemoval() {
+		return http.StatusConflict, fmt.Errorf("reservation '%s' can only be claimed when its owner's account is pending removal", res.Name)
+	}
+	if res.Owner.ID == user.ID {
+		return http.StatusConflict, fmt.Errorf("you already own reservation '%s'", res.Name)
+	}
+	if strings.HasPrefix(res.Group.Name, GroupUserPrefix) || !groupSliceContains(user.Groups, res.Group.Name) {
+		return http.StatusForbidden, fmt.Errorf("only members of group '%s' can claim reservation '%s'", res.Group.Name, res.Name)
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckResClaim(t *testing.T) {
+
+	team := Group{Name: "team-a"}
+	owner := User{Base: Base{ID: 1}, Name: "alice", Groups: []Group{team}}
+	member := &User{Base: Base{ID: 2}, Name: "bob", Groups: []Group{team}}
+	outsider := &User{Base: Base{ID: 3}, Name: "carol"}
+	res := &Reservation{Name: "r1", Owner: owner, Group: team}
+
+	// nothing to claim while the owner's account is in good standing
+	status, err := checkResClaim(res, member)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+
+	res.Owner.PendingRemoval = time.Now().Add(24 * time.Hour)
+	status, err = checkResClaim(res, member)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, err = checkResClaim(res, outsider)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, err = checkResClaim(res, &res.Owner)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// a reservation only the owner could use can't be claimed
+	res.Group = Group{Name: GroupUserPrefix + "alice"}
+	_, err = checkResClaim(res, member)
+	assert.Error(t, err)
+}
@@ -8,9 +8,12 @@ import (
 	"crypto/sha1"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -20,29 +23,53 @@ import (
 	"github.com/rs/zerolog/hlog"
 )
 
-func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res *Reservation, resIsNow bool, status int, err error) {
+// doCreateReservation makes a new reservation from the given parameters. Any group, distro or duration
+// not given is filled in from, in order, the owner's default group and that group's defaults, then the
+// server defaults. The returned message notes which values were defaulted and any clamping of the end time.
+//
+// An elevated admin can create a reservation for another owner, who is sent notice of it right away. The owner
+// must have access to the distro used unless grantAccess is set, in which case the owner's private group is added
+// to the distro's groups as part of the same transaction.
+func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res *Reservation, resIsNow bool, resMsg string, status int, err error) {
+	return createReservation(resParams, r, nil)
+}
+
+// errAdviseOnly ends the create transaction of a request being advised on once its hosts are scheduled.
+var errAdviseOnly = errors.New("advise only")
+
+// createReservation does the work of doCreateReservation. When advice is given the reservation is
+// planned with every check a real create makes, up to and including scheduling its hosts, but nothing
+// is saved and no one is notified. The name and the distro or profile can be left out in that case and
+// are noted in advice as missing.
+func createReservation(resParams map[string]interface{}, r *http.Request, advice *resAdvice) (res *Reservation, resIsNow bool, resMsg string, status int, err error) {
 
 	clog := hlog.FromRequest(r)
+	actionUser := getUserFromContext(r)
+	isElevated := userElevated(actionUser.Name)
+	var clampMsg, approvalMsg, startMsg string
+	var defaulted []string
+	var groupDefaulted bool
+	var forOther bool
+	var clusterName, grantedDistro string
 
 	status = http.StatusInternalServerError // default status, overridden at end if no errors
 
 	if err = performDbTx(func(tx *gorm.DB) error {
 
-		resName := resParams["name"].(string)
+		resName, _ := resParams["name"].(string)
 
 		// If the reservation already exists, abort!
-		if found, findErr := resvExists(resName, tx); findErr != nil {
+		if resName == "" && advice != nil {
+			advice.missing = append(advice.missing, "name")
+		} else if found, findErr := resvExists(resName, tx); findErr != nil {
 			return findErr
 		} else if found {
 			status = http.StatusConflict
-			return fmt.Errorf("reservation '%s' already exists", resName)
+			return newCodedError(common.ErrNameTaken, "reservation '%s' already exists", resName)
 		}
 
 		// assume the requesting user will be the reservation owner
-		resOwner := getUserFromContext(r)
-
-		// check if user is requesting as an admin
-		isElevated := userElevated(resOwner.Name)
+		resOwner := actionUser
 
 		// check if the owner param is set and permitted if different from the requesting user
 		if ownerParam, ok := resParams["owner"].(string); ok {
@@ -53,6 +80,7 @@ func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res
 						return guErr
 					} else {
 						resOwner = &users[0]
+						forOther = true
 					}
 				} else {
 					status = http.StatusBadRequest
@@ -61,22 +89,118 @@ func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res
 			}
 		}
 
+		grantAccess, _ := resParams["grantAccess"].(bool)
+		if grantAccess && !forOther {
+			status = http.StatusBadRequest
+			return fmt.Errorf("grantAccess can only be used by an admin creating a reservation for a different owner")
+		}
+
+		if forOther {
+			clusters, cErr := dbReadClustersTx(nil)
+			if cErr != nil {
+				return cErr
+			}
+			clusterName = clusters[0].Name
+		}
+
+		// The default reservation group is the owner's private group
+		group, pugErr := resOwner.getPug()
+		if pugErr != nil {
+			return pugErr
+		}
+
+		// Check if the user specified a group, otherwise use the owner's default group if they have one
+		groupName, hasGroup := resParams["group"].(string)
+		if !hasGroup && resOwner.DefaultGroup != "" {
+			groupName = resOwner.DefaultGroup
+			groupDefaulted = true
+		}
+		if groupName == GroupNoneAlias || groupName == "" {
+			// user explicitly wants no res group. should be pug by default,
+			// group already set to the user's pug directly above.
+		} else if groupName == GroupAll {
+			status = http.StatusBadRequest
+			return fmt.Errorf("reservations cannot be assigned to the '%s' group", GroupAll)
+		} else {
+			groups, ggStatus, ggErr := getGroups([]string{groupName}, true, tx)
+			if ggErr != nil {
+				status = ggStatus
+				if groupDefaulted {
+					status = http.StatusBadRequest
+					return fmt.Errorf("default group '%s' of %s cannot be used (%v) -- specify a group or update the default group", groupName, resOwner.Name, ggErr)
+				}
+				return ggErr
+			}
+			group = &groups[0]
+			// make sure the owner is also a member of the group specified
+			if !resOwner.isMemberOfGroup(group) {
+				if groupDefaulted {
+					status = http.StatusBadRequest
+					return fmt.Errorf("%s is no longer a member of their default group '%s' -- specify a group or update the default group", resOwner.Name, groupName)
+				}
+				return newCodedError(common.ErrNotGroupMember, "user is not a member of group '%s'", groupName)
+			}
+		}
+		if group.IsUserPrivate && igor.Scheduler.RequireReservationGroup {
+			if advice != nil {
+				advice.missing = append(advice.missing, "group")
+			} else if !isElevated {
+				status = http.StatusBadRequest
+				return reservationGroupRequiredError(resOwner)
+			} else {
+				clog.Info().Msgf("user '%s' is invoking admin privileges to create reservation '%s' without a group", actionUser.Name, resName)
+			}
+		}
+		if groupDefaulted {
+			defaulted = append(defaulted, fmt.Sprintf("group %s (default group of %s)", group.Name, resOwner.Name))
+		}
+
 		// does user want to add kernel args to the temp profile?
 		kernelArgs, kOk := resParams["kernelArgs"].(string)
 
+		// the owner's default kernel args go after those of the distro and profile but before any given for
+		// this reservation, which win when both set the same key
+		var ownerArgs string
+		if noDefaults, _ := resParams["noDefaultKernelArgs"].(bool); !noDefaults && resOwner.DefaultKernelArgs != "" {
+			ownerArgs = withoutKernelArgKeys(resOwner.DefaultKernelArgs, kernelArgs)
+		}
+
 		// create the profile from either the given distro or profile name
 		var profile *Profile
-		if distroName, dOk := resParams["distro"].(string); dOk {
+		distroName, dOk := resParams["distro"].(string)
+		_, pOk := resParams["profile"].(string)
+		distroDefaulted := !dOk && !pOk && group.DefaultDistro != ""
+		if distroDefaulted {
+			distroName, dOk = group.DefaultDistro, true
+		}
+		if dOk {
 			distroList, distroStatus, distroErr := getDistros([]string{distroName}, tx)
 			if distroErr != nil {
 				status = distroStatus
+				if distroDefaulted {
+					status = http.StatusBadRequest
+					return fmt.Errorf("default distro '%s' of group '%s' cannot be used (%v) -- specify a distro or profile", distroName, group.Name, distroErr)
+				}
 				return distroErr
 			}
 			distro := &distroList[0]
 
 			if !resOwner.isMemberOfAnyGroup(distro.Groups) {
-				status = http.StatusForbidden
-				return fmt.Errorf("%s does not have access to distro '%s'", resOwner.Name, distro.Name)
+				if grantAccess {
+					if gdaErr := grantDistroAccess(resOwner, distro, tx); gdaErr != nil {
+						return gdaErr
+					}
+					grantedDistro = distro.Name
+				} else {
+					status = http.StatusForbidden
+					if distroDefaulted {
+						return fmt.Errorf("%s does not have access to distro '%s', the default distro of group '%s' -- specify a distro or profile", resOwner.Name, distro.Name, group.Name)
+					}
+					return noDistroAccessError(resOwner, distro, forOther)
+				}
+			}
+			if distroDefaulted {
+				defaulted = append(defaulted, fmt.Sprintf("distro %s (default of group %s)", distro.Name, group.Name))
 			}
 			newProfileName := generateDefaultProfileName(resOwner)
 			profile = &Profile{
@@ -87,8 +211,12 @@ func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res
 				Description: "Default profile for distro " + distro.Name + " for reservation " + resName,
 			}
 
-			if kOk {
-				profile.KernelArgs = kernelArgs
+			if kOk || ownerArgs != "" {
+				if _, kErr := checkMergedKernelArgs(distro.KernelArgs, mergeKernelArgs(ownerArgs, kernelArgs)); kErr != nil {
+					status = http.StatusBadRequest
+					return kErr
+				}
+				profile.KernelArgs = mergeKernelArgs(ownerArgs, kernelArgs)
 			}
 
 		} else if profileName, pOk := resParams["profile"].(string); pOk {
@@ -111,45 +239,54 @@ func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res
 			} else {
 				profDistro := &dList[0]
 				if !resOwner.isMemberOfAnyGroup(profDistro.Groups) {
-					return fmt.Errorf("%s does not currently have access to distro '%s' in profile '%s'", res.Owner.Name, profDistro.Name, profileName)
+					if grantAccess {
+						if gdaErr := grantDistroAccess(resOwner, profDistro, tx); gdaErr != nil {
+							return gdaErr
+						}
+						grantedDistro = profDistro.Name
+					} else {
+						status = http.StatusForbidden
+						if forOther {
+							return noDistroAccessError(resOwner, profDistro, forOther)
+						}
+						return fmt.Errorf("%s does not currently have access to distro '%s' in profile '%s'", resOwner.Name, profDistro.Name, profileName)
+					}
 				}
 			}
 
 			if kOk {
 				return fmt.Errorf("kernel args cannot be added to an existing profile when creating a new reservation -- edit the profile first")
 			}
-		} else {
-			// we got neither a profile nor a distro?
-			status = http.StatusNotFound
-			return fmt.Errorf("must have either a distro or profile to create a reservation")
-		}
-
-		// The default reservation group is the owner's private group
-		group, pugErr := resOwner.getPug()
-		if pugErr != nil {
-			return pugErr
-		}
 
-		// Check if the user specified a group.
-		if groupName, ok := resParams["group"].(string); ok {
-			if groupName == GroupNoneAlias {
-				// user explicitly wants no res group. should be pug by default,
-				// group already set to the user's pug directly above.
-			} else if groupName == GroupAll {
-				status = http.StatusBadRequest
-				return fmt.Errorf("reservations cannot be assigned to the '%s' group", GroupAll)
-			} else {
-				groups, ggStatus, ggErr := getGroups([]string{groupName}, true, tx)
-				if ggErr != nil {
-					status = ggStatus
-					return ggErr
+			// the owner's profile is left as it is and the reservation gets a temp copy with the default args
+			if ownerArgs != "" {
+				profArgs := mergeKernelArgs(profile.KernelArgs, ownerArgs)
+				if _, kErr := checkMergedKernelArgs(profile.Distro.KernelArgs, profArgs); kErr != nil {
+					status = http.StatusBadRequest
+					return kErr
 				}
-				group = &groups[0]
-				// make sure the owner is also a member of the group specified
-				if !resOwner.isMemberOfGroup(group) {
-					return fmt.Errorf("user is not a member of group '%s'", groupName)
+				profile = &Profile{
+					Name:        generateDefaultProfileName(resOwner),
+					Owner:       *resOwner,
+					Distro:      profile.Distro,
+					IsDefault:   true,
+					KernelArgs:  profArgs,
+					Description: "Copy of profile " + profileName + " with default kernel args for reservation " + resName,
 				}
 			}
+		} else if advice != nil {
+			// the hosts can be planned before an OS is picked
+			advice.missing = append(advice.missing, "distro or profile")
+			profile = &Profile{Owner: *resOwner}
+			ownerArgs = ""
+		} else {
+			// we got neither a profile nor a distro, and there's no default to fall back on
+			status = http.StatusBadRequest
+			return fmt.Errorf("must have either a distro or profile to create a reservation; group '%s' has no default distro", group.Name)
+		}
+
+		if ownerArgs != "" {
+			defaulted = append(defaulted, fmt.Sprintf("kernel args %s (default of %s)", ownerArgs, resOwner.Name))
 		}
 
 		// Set the hosts - these are just place-holder or shell hosts for now
@@ -179,9 +316,21 @@ func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res
 			hosts = make([]Host, int(thisNodeCount))
 		}
 
+		// the fewest hosts the owner will accept if some are unavailable when the reservation starts
+		minNodes := 0
+		if mn, mnOk := resParams["minNodes"].(float64); mnOk {
+			minNodes = int(mn)
+			if minNodes > len(hosts) {
+				status = http.StatusBadRequest
+				return fmt.Errorf("minNodes (%d) cannot be more than the %d node(s) requested", minNodes, len(hosts))
+			}
+		}
+
+		endPower, _ := resParams["endPower"].(string)
+
 		// Check against allowed host max limit when not an elevated admin
 		if !isElevated && igor.Scheduler.NodeReserveLimit > 0 && len(hosts) > igor.Scheduler.NodeReserveLimit {
-			err = fmt.Errorf("only admins can make a reservation of more than %v nodes", igor.Scheduler.NodeReserveLimit)
+			err = newCodedError(common.ErrNodeLimit, "only admins can make a reservation of more than %v nodes", igor.Scheduler.NodeReserveLimit)
 			clog.Warn().Msgf("%v", err)
 			status = http.StatusForbidden
 			return err
@@ -206,12 +355,23 @@ func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res
 		sDur, sOk := resParams["duration"].(string)
 
 		if !fOk && !sOk {
-			sDur = strconv.FormatInt(igor.Scheduler.DefaultReserveTime, 10) + "m"
+			if group.DefaultDuration != "" {
+				sDur = group.DefaultDuration
+				defaulted = append(defaulted, fmt.Sprintf("duration %s (default of group %s)", sDur, group.Name))
+			} else {
+				sDur = strconv.FormatInt(igor.Scheduler.DefaultReserveTime, 10) + "m"
+				defaulted = append(defaulted, fmt.Sprintf("duration %s (server default)", sDur))
+			}
 			sOk = true
 		}
 
+		// reqDuration is the length asked for, kept along with the node count before any clamping
+		var reqDuration time.Duration
+		reqNodeCount := len(hosts)
+
 		if fOk {
 			resEnd = time.Unix(int64(fDur), 0)
+			reqDuration = resEnd.Sub(resStart)
 			if !meetsMinResDuration(resEnd.Sub(resStart)) {
 				status = http.StatusBadRequest
 				err = fmt.Errorf("reservation duration must be larger than minimum value %v minutes", igor.Scheduler.MinReserveTime)
@@ -225,11 +385,72 @@ func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res
 				return err
 			}
 			resEnd = resStart.Add(dur).Truncate(time.Minute) // drop any seconds in the value
+			reqDuration = dur
+		}
+
+		// determine the longest reservation that can be granted for the hosts requested
+		var ceiling time.Duration
+		if !isElevated {
+			groupAccessList := []string{GroupAll}
+			if !strings.HasPrefix(group.Name, GroupUserPrefix) {
+				groupAccessList = append(groupAccessList, group.Name)
+			}
+			if ceiling, status, err = getResTimeCeiling(hostNames, groupAccessList, resOwner.groupNames(), len(hosts), tx, clog); err != nil {
+				return err
+			}
+		}
+		if advice != nil {
+			advice.ceiling = ceiling
 		}
 
-		if err = checkScheduleLimit(resEnd, isElevated); err != nil {
+		clamp, _ := resParams["clampToLimit"].(bool)
+		grantedEnd, limitMsg, limitErr := limitResEnd(resStart, resEnd, ceiling, isElevated, clamp)
+		if limitErr != nil {
 			status = http.StatusBadRequest
-			return err
+			return limitErr
+		}
+		if !grantedEnd.Equal(resEnd) {
+			if !meetsMinResDuration(grantedEnd.Sub(resStart)) {
+				status = http.StatusBadRequest
+				return fmt.Errorf("cannot shorten reservation to end by %s; duration would be less than minimum value %v minutes",
+					grantedEnd.Format(common.DateTimeCompactFormat), igor.Scheduler.MinReserveTime)
+			}
+			clampMsg = limitMsg
+			clog.Info().Msgf("reservation '%s' %s", resName, clampMsg)
+			resEnd = grantedEnd
+		}
+
+		// named hosts that are busy can be reserved for the first time they are all free instead
+		if asap, _ := resParams["asap"].(bool); asap {
+			asapStart, asStatus, asErr := findEarliestHostsStart(hostNames, resOwner, resStart, resEnd, tx, clog)
+			if asErr != nil {
+				status = asStatus
+				return asErr
+			}
+			if asapStart.After(resStart) {
+				resEnd = asapStart.Add(resEnd.Sub(resStart)).Round(time.Minute)
+				resStart = asapStart
+				resIsNow = false
+				startMsg = fmt.Sprintf("the requested hosts are not all free until %s, so the reservation starts then",
+					resStart.Format(common.DateTimeCompactFormat))
+				clog.Info().Msgf("reservation '%s' %s", resName, startMsg)
+			}
+		}
+
+		// a reservation over the approval thresholds holds its hosts but isn't installed until an admin approves it
+		var approvalUntil time.Time
+		approvalReason := ""
+		if !isElevated {
+			approvalReason = approvalNeeded(len(hosts), resEnd.Sub(resStart))
+			if approvalReason == "" {
+				approvalReason = endPowerApprovalNeeded(endPower)
+			}
+			if approvalReason != "" {
+				approvalUntil = approvalHoldEnd(time.Now())
+				approvalMsg = fmt.Sprintf("reservation needs admin approval (%s) and is held until %s", approvalReason, approvalUntil.Format(common.DateTimeCompactFormat))
+				clog.Info().Msgf("reservation '%s' %s", resName, approvalMsg)
+				resIsNow = false
+			}
 		}
 
 		// determine reset/maintenance end time
@@ -242,7 +463,7 @@ func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res
 			if thisVlan, ok := resParams["vlan"].(string); ok {
 				// user wants a specific vlan
 				if thisVlan != "" {
-					vlanInt, pvStatus, pvErr := parseVLAN(thisVlan, *resOwner, tx)
+					vlanInt, pvStatus, pvErr := parseVLAN(thisVlan, *resOwner, group, tx)
 					if pvErr != nil {
 						status = pvStatus
 						return pvErr
@@ -254,8 +475,17 @@ func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res
 					return fmt.Errorf("vlan specified in reservation parameters, but no value included")
 				}
 			} else {
-				// pick next available
-				if vlan, err = nextVLAN(); err != nil {
+				// pick next available within the range allowed for the group
+				vlanMin, vlanMax, rangeOk := vlanRangeOf(group)
+				if !rangeOk {
+					status = http.StatusConflict
+					return fmt.Errorf("the VLAN range %s of group '%s' is outside the VLANs managed by igor -- ask an admin to fix it", group.vlanRange(), group.Name)
+				}
+				if vlan, err = nextVLAN(vlanMin, vlanMax, tx); err != nil {
+					if group.hasVlanRange() {
+						status = http.StatusConflict
+						return fmt.Errorf("no VLANs are free in the range %s allowed for group '%s'", group.vlanRange(), group.Name)
+					}
 					clog.Error().Msgf("error - %v", err.Error())
 				}
 			}
@@ -306,20 +536,27 @@ func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res
 
 		// build reservation object
 		res = &Reservation{
-			Name:         resName,
-			Owner:        *resOwner,
-			Group:        *group,
-			Start:        resStart,
-			End:          resEnd,
-			OrigEnd:      resEnd,
-			ResetEnd:     resetEnd,
-			Hosts:        hosts,
-			Profile:      *profile,
-			Vlan:         vlan,
-			CycleOnStart: cycleOnStart,
-			NextNotify:   nextNotify,
-			Hash:         hex.EncodeToString(hash.Sum(nil)),
-			HistCallback: doHistoryRecord,
+			Name:           resName,
+			Owner:          *resOwner,
+			Group:          *group,
+			Start:          resStart,
+			End:            resEnd,
+			OrigEnd:        resEnd,
+			ReqDuration:    reqDuration,
+			ReqNodeCount:   reqNodeCount,
+			MinNodes:       minNodes,
+			EndPower:       endPower,
+			HostsByCount:   ncOk,
+			ResetEnd:       resetEnd,
+			Hosts:          hosts,
+			Profile:        *profile,
+			Vlan:           vlan,
+			CycleOnStart:   cycleOnStart,
+			NextNotify:     nextNotify,
+			Hash:           hex.EncodeToString(hash.Sum(nil)),
+			ApprovalUntil:  approvalUntil,
+			ApprovalReason: approvalReason,
+			HistCallback:   doHistoryRecord,
 		}
 
 		// determine hosts to assign to reservation based on given host names or count requested
@@ -331,27 +568,122 @@ func doCreateReservation(resParams map[string]interface{}, r *http.Request) (res
 		} else {
 			if hostList, sbaStatus, sbaErr := scheduleHostsByAvailability(res, tx, clog); sbaErr != nil {
 				status = sbaStatus
+				// tell the user when the request could be met instead
+				var fullErr *ScheduleFullError
+				if errors.As(sbaErr, &fullErr) {
+					if start, feErr := findEarliestStart(res, tx, clog); feErr != nil {
+						clog.Warn().Msgf("unable to find a later start for reservation '%s': %v", res.Name, feErr)
+					} else {
+						fullErr.searched, fullErr.suggestedStart = true, start
+					}
+				}
 				return sbaErr
 			} else {
 				res.Hosts = hostList
 			}
 		}
+		if !isElevated {
+			if capStatus, capErr := dbCheckPolicyUserCaps(resOwner, res.Hosts, 0, tx); capErr != nil {
+				status = capStatus
+				return capErr
+			}
+		}
+		if advice != nil {
+			return errAdviseOnly
+		}
+
 		// insert new reservation to the db
-		return dbCreateReservation(res, tx)
+		if crErr := dbCreateReservation(res, tx); crErr != nil {
+			return crErr
+		}
+		return dbRecordImageUse(&res.Profile, time.Now(), tx)
 
-	}); err != nil {
+	}); errors.Is(err, errAdviseOnly) {
+		return res, resIsNow, resCreateMessage(defaulted, startMsg, clampMsg, approvalMsg), http.StatusOK, nil
+	} else if err != nil {
 		return
 	}
 
-	if hErr := res.HistCallback(res, HrCreated); hErr != nil {
+	if hErr := doCreatedHistoryRecord(res, actionUser, resParams); hErr != nil {
 		clog.Error().Msgf("failed to record reservation '%s' create to history", res.Name)
 	}
 
-	return res, resIsNow, http.StatusCreated, nil
+	if forOther {
+		clog.Info().Msgf("reservation '%s' created by %s for owner %s", res.Name, actionUser.Name, res.Owner.Name)
+		if grantedDistro != "" {
+			clog.Info().Msgf("%s granted access to distro '%s' for reservation '%s'", res.Owner.Name, grantedDistro, res.Name)
+		}
+		if resEditEvent := makeResEditNotifyEvent(EmailResCreatedForOwner, res, clusterName, actionUser, isElevated, grantedDistro); resEditEvent != nil {
+			resNotifyChan <- *resEditEvent
+		}
+	}
+
+	return res, resIsNow, resCreateMessage(defaulted, startMsg, clampMsg, approvalMsg), http.StatusCreated, nil
+}
+
+// resCreateMessage joins the notes on the defaults used, a later start while the hosts are busy, any
+// shortening of the end time and any approval needed by a new reservation.
+func resCreateMessage(defaulted []string, startMsg, clampMsg, approvalMsg string) string {
+	var msgs []string
+	if len(defaulted) > 0 {
+		msgs = append(msgs, "defaults used: "+strings.Join(defaulted, ", "))
+	}
+	if startMsg != "" {
+		msgs = append(msgs, startMsg)
+	}
+	if clampMsg != "" {
+		msgs = append(msgs, clampMsg)
+	}
+	if approvalMsg != "" {
+		msgs = append(msgs, approvalMsg)
+	}
+	return strings.Join(msgs, "; ")
 }
 
-func parseVLAN(vlan string, user User, tx *gorm.DB) (int, int, error) {
-	// First check to see if we've been handed a reservation name
+// grantDistroAccess adds the owner's private group to the groups of the distro so the owner can use it.
+func grantDistroAccess(owner *User, distro *Distro, tx *gorm.DB) error {
+	pug, err := owner.getPug()
+	if err != nil {
+		return err
+	}
+	return dbEditDistro(distro, map[string]interface{}{"addGroup": []Group{*pug}}, tx)
+}
+
+// noDistroAccessError returns the error given when the reservation owner can't use the distro. When an admin
+// is creating the reservation for someone else the error says how to give the owner access.
+func noDistroAccessError(owner *User, distro *Distro, forOther bool) error {
+	if forOther {
+		return fmt.Errorf("owner %s does not have access to distro '%s' -- add one of the owner's groups to the distro or use grantAccess", owner.Name, distro.Name)
+	}
+	return fmt.Errorf("%s does not have access to distro '%s'", owner.Name, distro.Name)
+}
+
+// reservationGroupRequiredError returns the error given when a reservation is made without a group on a
+// server that requires one, listing the groups the owner could use.
+func reservationGroupRequiredError(owner *User) error {
+	var eligible []string
+	for _, g := range owner.Groups {
+		if !g.IsUserPrivate && g.Name != GroupAll {
+			eligible = append(eligible, g.Name)
+		}
+	}
+	if len(eligible) == 0 {
+		return fmt.Errorf("reservations must belong to a group on this server -- %s is not a member of any group that can be used, ask an admin to be added to one", owner.Name)
+	}
+	sort.Strings(eligible)
+	return fmt.Errorf("reservations must belong to a group on this server -- use -g with one of: %s", strings.Join(eligible, ", "))
+}
+
+// parseVLAN returns the VLAN given as the name of a named network, the name of a reservation of the user
+// to share it with or an ID, checking that it can be used by reservations of the given group. A named
+// network takes precedence over a reservation with the same name.
+func parseVLAN(vlan string, user User, group *Group, tx *gorm.DB) (int, int, error) {
+	// a named network is shared by the members of the group that owns it
+	if netVlan, found, status, err := namedNetworkVlan(vlan, &user, group, tx); found || err != nil {
+		return netVlan, status, err
+	}
+
+	// then check to see if we've been handed a reservation name
 	resList, err := dbReadReservations(map[string]interface{}{"name": vlan}, nil, tx)
 	if err != nil {
 		return -1, http.StatusInternalServerError, err
@@ -360,6 +692,9 @@ func parseVLAN(vlan string, user User, tx *gorm.DB) (int, int, error) {
 		// Check to see if resTarget owner is the same as user
 		resTarget := resList[0]
 		if resTarget.Owner.Name == user.Name {
+			if err = checkGroupVlan(resTarget.Vlan, group); err != nil {
+				return -1, http.StatusBadRequest, fmt.Errorf("cannot use the VLAN of reservation '%s': %v", resTarget.Name, err)
+			}
 			return resTarget.Vlan, http.StatusOK, nil
 		}
 		return -1, http.StatusForbidden, fmt.Errorf("owner of reservation specified for VLAN does not match user")
@@ -369,7 +704,7 @@ func parseVLAN(vlan string, user User, tx *gorm.DB) (int, int, error) {
 	vlanID64, pErr := strconv.ParseInt(vlan, 10, 64)
 	if pErr != nil {
 		// It wasn't an int, either.
-		return -1, http.StatusBadRequest, fmt.Errorf("expected VLAN to be reservation name or VLAN ID: %s", vlan)
+		return -1, http.StatusBadRequest, fmt.Errorf("expected VLAN to be network name, reservation name or VLAN ID: %s", vlan)
 	}
 	vlanID := int(vlanID64)
 
@@ -378,6 +713,17 @@ func parseVLAN(vlan string, user User, tx *gorm.DB) (int, int, error) {
 		// VLAN number isn't in the permitted range
 		return -1, http.StatusBadRequest, fmt.Errorf("VLAN number outside permitted range: %s", vlan)
 	}
+	if err = checkGroupVlan(vlanID, group); err != nil {
+		return -1, http.StatusBadRequest, err
+	}
+
+	// a VLAN held by a named network can only be used by members of its group
+	if networks, nErr := dbReadNamedNetworks(map[string]interface{}{"vlan": vlanID}, tx); nErr != nil {
+		return -1, http.StatusInternalServerError, nErr
+	} else if len(networks) > 0 {
+		status, nErr := checkNamedNetworkUse(&networks[0], &user, group)
+		return vlanID, status, nErr
+	}
 
 	// See who's already using that VLAN ID
 	resList, err = dbReadReservations(map[string]interface{}{"vlan": vlan}, nil, tx)
@@ -0,0 +1,25 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservationGroupRequiredError(t *testing.T) {
+
+	owner := &User{Name: "alice", Groups: []Group{
+		{Name: GroupUserPrefix + "alice", IsUserPrivate: true},
+		{Name: GroupAll},
+	}}
+	err := reservationGroupRequiredError(owner)
+	assert.Contains(t, err.Error(), "alice is not a member of any group that can be used")
+
+	owner.Groups = append(owner.Groups, Group{Name: "sith"}, Group{Name: "jedis"})
+	err = reservationGroupRequiredError(owner)
+	assert.Contains(t, err.Error(), "use -g with one of: jedis, sith")
+}
@@ -0,0 +1,186 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// validateImageEditParams checks the body of a request to change the profile or distro of a reservation.
+// The change can be paired with a request to power cycle the hosts now or at a later time, but not
+// with other reservation edits.
+func validateImageEditParams(resParams map[string]interface{}) error {
+
+	_, doDistro := resParams["distro"]
+	_, doProfile := resParams["profile"]
+	_, doCycleNow := resParams["cycleNow"]
+	_, doCycleAt := resParams["cycleAt"]
+	if doDistro && doProfile {
+		return fmt.Errorf("both profile and distro params found; only one allowed")
+	}
+	if doCycleNow && doCycleAt {
+		return fmt.Errorf("both cycleNow and cycleAt params found; only one allowed")
+	}
+
+	for key, val := range resParams {
+		switch key {
+		case "distro":
+			if distro, ok := val.(string); !ok {
+				return NewBadParamTypeError(key, val, "string")
+			} else if err := checkDistroNameRules(distro); err != nil {
+				return err
+			}
+		case "profile":
+			if profile, ok := val.(string); !ok {
+				return NewBadParamTypeError(key, val, "string")
+			} else if err := checkProfileNameRules(profile); err != nil {
+				return err
+			}
+		case "cycleNow":
+			if cycle, ok := val.(bool); !ok || !cycle {
+				return NewBadParamTypeError(key, val, "bool (true)")
+			}
+		case "cycleAt":
+			if _, ok := val.(float64); !ok {
+				return NewBadParamTypeError(key, val, "float64")
+			}
+		default:
+			return fmt.Errorf("distro and profile changes cannot be mixed with other reservation changes; found %v", resParams)
+		}
+	}
+	return nil
+}
+
+// parseCycleEdit checks a request to power cycle the hosts of a reservation once its profile or distro
+// has changed and returns when the cycle should happen. The user needs the same permission to power
+// the hosts as 'igor host power' requires.
+func parseCycleEdit(res *Reservation, editParams map[string]interface{}, actionUser *User, now time.Time, tx *gorm.DB) (time.Time, int, error) {
+
+	cycleAt := now
+	if at, ok := editParams["cycleAt"].(float64); ok {
+		cycleAt = time.Unix(int64(at), 0)
+		if !cycleAt.After(now) {
+			return cycleAt, http.StatusBadRequest, fmt.Errorf("the power cycle time %s has already passed", cycleAt.Format(common.DateTimeCompactFormat))
+		}
+		if !cycleAt.Before(res.End) {
+			return cycleAt, http.StatusBadRequest, fmt.Errorf("the power cycle time %s must be before reservation '%s' ends at %s",
+				cycleAt.Format(common.DateTimeCompactFormat), res.Name, res.End.Format(common.DateTimeCompactFormat))
+		}
+	}
+
+	if !res.Installed {
+		if res.isPaused() {
+			return cycleAt, http.StatusConflict, fmt.Errorf("reservation '%s' is paused - only the hosts of an installed reservation can be power cycled", res.Name)
+		}
+		return cycleAt, http.StatusConflict, fmt.Errorf("reservation '%s' is not installed - only the hosts of an installed reservation can be power cycled", res.Name)
+	}
+
+	policyHosts, ghStatus, ghErr := getHosts(namesOfHosts(res.Hosts), true, tx)
+	if ghErr != nil {
+		return cycleAt, ghStatus, ghErr
+	}
+	authInfo, err := actionUser.getAuthzInfo()
+	if err != nil {
+		return cycleAt, http.StatusInternalServerError, err
+	}
+	if naStatus, naErr := checkNodeAction(actionUser, authInfo, NodeActionPower, policyHosts); naErr != nil {
+		return cycleAt, naStatus, naErr
+	}
+
+	return cycleAt, http.StatusOK, nil
+}
+
+// installCycleProfile writes the PXE files of the reservation hosts for its new profile ahead of the
+// power cycle that boots it. The caller must hold dbAccess.
+func installCycleProfile(res *Reservation) error {
+	if !beginResInstall(res.ID) {
+		return fmt.Errorf("reservation '%s' is being removed", res.Name)
+	}
+	defer endResInstall(res.ID)
+	return igor.IResInstaller.Install(res)
+}
+
+// cycleNotice describes when the hosts of a reservation will be power cycled to boot its new profile.
+func cycleNotice(res *Reservation) string {
+	if !res.cyclePending() {
+		return "The change takes effect the next time the reservation's hosts are power cycled."
+	}
+	if !res.CycleAt.After(time.Now()) {
+		return "The reservation's hosts are being power cycled now to boot it."
+	}
+	return fmt.Sprintf("The reservation's hosts will be power cycled at %s to boot it.", res.CycleAt.Format(common.DateTimeCompactFormat))
+}
+
+// cycleReservations power cycles the hosts of reservations whose scheduled cycle is due at checkTime.
+// A cycle is skipped if the reservation's profile has changed again since it was scheduled or the
+// reservation is no longer installed.
+func cycleReservations(checkTime *time.Time) error {
+
+	dbAccess.Lock()
+
+	var resIDs []int
+	if result := igor.IGormDb.GetDB().Model(&Reservation{}).
+		Where("cycle_at > ? AND cycle_at <= ?", time.Time{}, *checkTime).Pluck("id", &resIDs); result.Error != nil {
+		dbAccess.Unlock()
+		return result.Error
+	} else if len(resIDs) == 0 {
+		dbAccess.Unlock()
+		return nil
+	}
+
+	resList, err := dbReadReservationsTx(map[string]interface{}{"id": resIDs}, nil)
+	if err != nil {
+		dbAccess.Unlock()
+		return err
+	}
+
+	var dueList []Reservation
+	for i := range resList {
+		r := &resList[i]
+		cycleAt, cycleProfileID := r.CycleAt, r.CycleProfileID
+		if err = performDbTx(func(tx *gorm.DB) error {
+			return dbEditReservation(r, map[string]interface{}{"CycleAt": time.Time{}, "CycleProfileID": 0}, tx)
+		}); err != nil {
+			logger.Error().Msgf("failed to clear the scheduled power cycle of reservation '%s' - %v", r.Name, err)
+			continue
+		}
+		if r.ProfileID != cycleProfileID {
+			logger.Info().Msgf("skipping the power cycle of reservation '%s' scheduled for %s - its profile has changed since",
+				r.Name, cycleAt.Format(common.DateTimeCompactFormat))
+			continue
+		}
+		if !r.Installed {
+			logger.Info().Msgf("skipping the power cycle of reservation '%s' scheduled for %s - it is no longer installed",
+				r.Name, cycleAt.Format(common.DateTimeCompactFormat))
+			continue
+		}
+		dueList = append(dueList, *r)
+	}
+
+	// the hosts are cycled without holding up other requests
+	dbAccess.Unlock()
+
+	for _, r := range dueList {
+		hostNames := hostNamesOfHosts(r.Hosts)
+		logger.Info().Msgf("power cycling host(s) %v of reservation '%s' to boot profile '%s'", namesOfHosts(r.Hosts), r.Name, r.Profile.Name)
+		if _, powerErr := doPowerHosts(PowerCycle, hostNames, &logger); powerErr != nil {
+			logEvent(&logger, EvResPowerCycleFailed).Str(LogFieldReservation, r.Name).Err(powerErr).
+				Msgf("problem power cycling hosts of reservation '%s': %v", r.Name, powerErr)
+			continue
+		}
+		recordResPowerOn(hostNames, &logger)
+		if hErr := r.HistCallback(&r, HrUpdated+":cycle"); hErr != nil {
+			logger.Error().Msgf("failed to record reservation '%s' power cycle to history", r.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateImageEditParams(t *testing.T) {
+
+	assert.NoError(t, validateImageEditParams(map[string]interface{}{"profile": "prof1"}))
+	assert.NoError(t, validateImageEditParams(map[string]interface{}{"profile": "prof1", "cycleNow": true}))
+	assert.NoError(t, validateImageEditParams(map[string]interface{}{"distro": "dist1", "cycleAt": float64(1700000000)}))
+
+	assert.Error(t, validateImageEditParams(map[string]interface{}{"profile": "prof1", "distro": "dist1"}))
+	assert.Error(t, validateImageEditParams(map[string]interface{}{"profile": "prof1", "cycleNow": true, "cycleAt": float64(1700000000)}))
+	assert.Error(t, validateImageEditParams(map[string]interface{}{"profile": "prof1", "cycleNow": false}))
+	assert.Error(t, validateImageEditParams(map[string]interface{}{"profile": "prof1", "cycleAt": "tomorrow"}))
+	assert.Error(t, validateImageEditParams(map[string]interface{}{"profile": "prof1", "name": "res2"}))
+}
+
+func TestCycleReservations(t *testing.T) {
+
+	useSimulation(t)
+	origSimHosts := simHosts
+	t.Cleanup(func() { simHosts = origSimHosts })
+	simHosts = map[string]*simHostPower{}
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	now := time.Now()
+	schedule := func(name string, host Host, at time.Time, sameProfile bool) {
+		res := newStartTestRes(t, db, name, []Host{host}, false, 0)
+		cycleProfileID := res.ProfileID
+		if !sameProfile {
+			cycleProfileID++
+		}
+		require.NoError(t, db.Model(res).Updates(map[string]interface{}{"installed": true, "cycle_at": at,
+			"cycle_profile_id": cycleProfileID}).Error)
+	}
+
+	schedule("due", hosts[0], now.Add(-time.Minute), true)
+	schedule("changed", hosts[1], now.Add(-time.Minute), false)
+	schedule("later", hosts[0], now.Add(time.Hour), true)
+
+	require.NoError(t, cycleReservations(&now))
+
+	// only the reservation still on the profile the cycle was scheduled for is cycled
+	require.Contains(t, simHosts, hosts[0].HostName)
+	assert.False(t, simHosts[hosts[0].HostName].onAt.IsZero())
+	assert.NotContains(t, simHosts, hosts[1].HostName)
+
+	resList, err := dbReadReservationsTx(nil, nil)
+	require.NoError(t, err)
+	require.Len(t, resList, 3)
+	for _, r := range resList {
+		switch r.Name {
+		case "due":
+			assert.False(t, r.cyclePending())
+			assert.False(t, r.LastPowerOn.IsZero())
+		case "changed":
+			assert.False(t, r.cyclePending())
+			assert.True(t, r.LastPowerOn.IsZero())
+		case "later":
+			assert.True(t, r.cyclePending())
+		}
+	}
+
+	// the cycle set for later happens once it is due
+	simHosts = map[string]*simHostPower{}
+	later := now.Add(2 * time.Hour)
+	require.NoError(t, cycleReservations(&later))
+	assert.Contains(t, simHosts, hosts[0].HostName)
+}
@@ -0,0 +1,215 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+// MaxResDataSize is the largest data payload, in bytes, that can be attached to a reservation.
+const MaxResDataSize = 64 * 1024
+
+// ResData is an opaque payload the owner attaches to a reservation, such as experiment configuration,
+// for its nodes to fetch from the callback server when they boot. It is kept apart from the
+// reservation so it is only read when asked for, and is deleted along with the reservation.
+type ResData struct {
+	Base
+	ReservationID int    `gorm:"unique; notNull"`
+	Data          []byte `gorm:"notNull"`
+}
+
+// decodeResData decodes the base64 data param of a reservation edit. An empty value clears the data.
+func decodeResData(val string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(val)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameter 'data': not base64 encoded - %v", err)
+	}
+	if len(data) > MaxResDataSize {
+		return nil, fmt.Errorf("reservation data is %d bytes, larger than the %d bytes allowed", len(data), MaxResDataSize)
+	}
+	return data, nil
+}
+
+// resDataDigest is the hex SHA-256 hash of reservation data.
+func resDataDigest(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// resDataHistStatus describes a change to the reservation data for its history. Only the size and
+// hash of the data are kept, never its contents.
+func resDataHistStatus(data []byte) string {
+	if len(data) == 0 {
+		return "data-cleared"
+	}
+	return fmt.Sprintf("data-size=%d,data-sha256=%s", len(data), resDataDigest(data))
+}
+
+// dbReadResData returns the data attached to the reservation, or nil if it has none.
+func dbReadResData(resID int, tx *gorm.DB) (*ResData, error) {
+	var rd ResData
+	if result := tx.Where("reservation_id = ?", resID).First(&rd); result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &rd, nil
+}
+
+// dbSetResData replaces the data attached to the reservation, or removes it if data is empty.
+func dbSetResData(res *Reservation, data []byte, tx *gorm.DB) error {
+	if len(data) == 0 {
+		return tx.Where("reservation_id = ?", res.ID).Delete(&ResData{}).Error
+	}
+	rd := ResData{ReservationID: res.ID, Data: data}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "reservation_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"data", "updated_at"}),
+	}).Create(&rd).Error
+}
+
+// doReadResData returns the data attached to a reservation. Only the owner, a co-owner, a member of
+// the reservation's group or an elevated admin can read it.
+func doReadResData(resName string, actionUser *User) (*common.ResDataData, int, error) {
+
+	var rd *ResData
+	status := http.StatusInternalServerError
+
+	err := performDbTx(func(tx *gorm.DB) error {
+		rList, grStatus, grErr := getReservations([]string{resName}, tx)
+		if grErr != nil {
+			status = grStatus
+			return grErr
+		}
+		res := &rList[0]
+		if actionUser.Name != res.Owner.Name && !res.isCoOwner(actionUser.Name) &&
+			!actionUser.isMemberOfGroup(&res.Group) && !userElevated(actionUser.Name) {
+			status = http.StatusForbidden
+			return fmt.Errorf("only the owner or group of reservation '%s' can read its data", resName)
+		}
+		var rdErr error
+		rd, rdErr = dbReadResData(res.ID, tx)
+		return rdErr
+	})
+	if err != nil {
+		return nil, status, err
+	}
+	if rd == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("reservation '%s' has no data attached", resName)
+	}
+
+	return &common.ResDataData{
+		Name:    resName,
+		Size:    len(rd.Data),
+		Sha256:  resDataDigest(rd.Data),
+		Updated: rd.UpdatedAt.Unix(),
+		Data:    rd.Data,
+	}, http.StatusOK, nil
+}
+
+// destination for GET /reservations/:resName/data
+func handleReadResData(w http.ResponseWriter, r *http.Request) {
+
+	ps := httprouter.ParamsFromContext(r.Context())
+	resName := ps.ByName("resName")
+	clog := hlog.FromRequest(r)
+	actionPrefix := "read reservation data"
+	rb := common.NewResponseBodyResData()
+
+	data, status, err := doReadResData(resName, getUserFromContext(r))
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["data"] = *data
+		clog.Debug().Msgf("%s success", actionPrefix)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// doReadCbResData returns the data of the named reservation to one of its nodes, found by the
+// address the request came from. The reservation must have started and been installed.
+func doReadCbResData(resName, ip string, now time.Time) ([]byte, int, error) {
+
+	var rd *ResData
+	status := http.StatusInternalServerError
+
+	err := performDbTx(func(tx *gorm.DB) error {
+		hosts, rhErr := dbReadHosts(map[string]interface{}{"ip": ip}, tx)
+		if rhErr != nil {
+			return rhErr
+		}
+		if len(hosts) == 0 {
+			status = http.StatusForbidden
+			return fmt.Errorf("no host found matching IP address %s", ip)
+		}
+
+		rList, rrErr := dbReadReservations(map[string]interface{}{"name": resName}, nil, tx)
+		if rrErr != nil {
+			return rrErr
+		}
+		inRes := false
+		if len(rList) > 0 {
+			inRes, _ = hostSliceContains(rList[0].Hosts, hosts[0].Name)
+		}
+		if !inRes || !rList[0].Installed || !rList[0].Start.Before(now) {
+			// the same answer whether or not the reservation exists, so names can't be probed
+			status = http.StatusForbidden
+			return fmt.Errorf("host %s is not part of an active reservation named '%s'", hosts[0].Name, resName)
+		}
+
+		var rdErr error
+		rd, rdErr = dbReadResData(rList[0].ID, tx)
+		return rdErr
+	})
+	if err != nil {
+		return nil, status, err
+	}
+	if rd == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("reservation '%s' has no data attached", resName)
+	}
+	return rd.Data, http.StatusOK, nil
+}
+
+// handleCbResData serves the data of a reservation to its nodes on the callback server.
+func handleCbResData(w http.ResponseWriter, r *http.Request) {
+
+	ps := httprouter.ParamsFromContext(r.Context())
+	resName := ps.ByName("resName")
+	clog := hlog.FromRequest(r)
+	actionPrefix := "serve reservation data to host"
+
+	ip := strings.Split(r.RemoteAddr, ":")[0]
+	data, status, err := doReadCbResData(resName, ip, time.Now())
+	if err != nil {
+		if status >= http.StatusInternalServerError {
+			clog.Error().Msgf("%s error - %v", actionPrefix, err)
+		} else {
+			clog.Warn().Msgf("%s failed - %v", actionPrefix, err)
+		}
+		w.WriteHeader(status)
+		return
+	}
+
+	clog.Debug().Msgf("%s success - %d bytes of reservation '%s' data sent to %s", actionPrefix, len(data), resName, ip)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(status)
+	if _, err = w.Write(data); err != nil {
+		panic(err)
+	}
+}
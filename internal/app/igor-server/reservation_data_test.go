@@ -0,0 +1,110 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"encoding/base64"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestDecodeResData(t *testing.T) {
+
+	data, err := decodeResData(base64.StdEncoding.EncodeToString([]byte("nodes=2")))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("nodes=2"), data)
+
+	data, err = decodeResData("")
+	require.NoError(t, err)
+	assert.Empty(t, data)
+	assert.Equal(t, "data-cleared", resDataHistStatus(data))
+
+	_, err = decodeResData("not base64!")
+	assert.Error(t, err)
+	_, err = decodeResData(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", MaxResDataSize+1))))
+	assert.Error(t, err)
+
+	// history gets the size and hash, never the contents
+	status := resDataHistStatus([]byte("secret"))
+	assert.Equal(t, "data-size=6,data-sha256=2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b", status)
+	assert.NotContains(t, status, "secret")
+}
+
+func TestResDataAccess(t *testing.T) {
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	require.NoError(t, db.Model(&Host{}).Where("name = ?", "kn1").Update("ip", "10.0.0.1").Error)
+	require.NoError(t, db.Model(&Host{}).Where("name = ?", "kn2").Update("ip", "10.0.0.2").Error)
+
+	res := newStartTestRes(t, db, "exp", hosts[:1], false, 0)
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		return dbSetResData(res, []byte(`{"vlan": 12}`), tx)
+	}))
+
+	// nodes can't fetch it until the reservation is installed
+	now := time.Now().Add(time.Minute)
+	_, status, err := doReadCbResData("exp", "10.0.0.1", now)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	require.NoError(t, db.Model(res).Update("installed", true).Error)
+	data, status, err := doReadCbResData("exp", "10.0.0.1", now)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"vlan": 12}`, string(data))
+
+	// only from a node of the reservation, with the same answer for a name that doesn't exist
+	_, status, _ = doReadCbResData("exp", "10.0.0.2", now)
+	assert.Equal(t, http.StatusForbidden, status)
+	_, status, _ = doReadCbResData("nope", "10.0.0.1", now)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// the owner can read it through the API but other users can't
+	rd, status, err := doReadResData("exp", &res.Owner)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 12, rd.Size)
+	assert.Equal(t, resDataDigest(data), rd.Sha256)
+	_, status, err = doReadResData("exp", &User{Name: "bob"})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// new data replaces the old, and clearing it leaves nothing to fetch
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		return dbSetResData(res, []byte("v2"), tx)
+	}))
+	data, _, err = doReadCbResData("exp", "10.0.0.1", now)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		return dbSetResData(res, nil, tx)
+	}))
+	_, status, _ = doReadCbResData("exp", "10.0.0.1", now)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// the data goes with the reservation
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		return dbSetResData(res, []byte("v3"), tx)
+	}))
+	perms, err := createResOwnerPerms(res.Name, false)
+	require.NoError(t, err)
+	require.NoError(t, dbAppendPermissions(&res.Group, perms, db))
+	require.NoError(t, db.Omit(clause.Associations).Where("group_id = ?", res.GroupID).Find(&perms).Error)
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		return dbDeleteReservation(res, perms, false, tx)
+	}))
+	var count int64
+	require.NoError(t, db.Model(&ResData{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"igor2/internal/pkg/common"
 )
@@ -27,7 +28,7 @@ func dbCreateReservation(res *Reservation, tx *gorm.DB) error {
 		}
 	}
 
-	oPerms, err := createResOwnerPerms(res.Name)
+	oPerms, err := createResOwnerPerms(res.Name, false)
 	if err != nil {
 		return err
 	}
@@ -60,20 +61,31 @@ func dbReadReservationsTx(queryParams map[string]interface{}, timeParams map[str
 	return resList, err
 }
 
+// hostsInSequence orders the hosts of a reservation by sequence ID when they are loaded so every
+// output lists them the same way.
+func hostsInSequence(db *gorm.DB) *gorm.DB {
+	return db.Order("hosts.sequence_id")
+}
+
 // dbReadReservations finds all reservations matching the query and time parameters passed to it within an existing transaction.
 func dbReadReservations(queryParams map[string]interface{}, timeParams map[string]time.Time, tx *gorm.DB) (resList []Reservation, err error) {
 
+	baseTx := tx
+
 	// if no params given, return all reservations
 	if len(queryParams) == 0 && len(timeParams) == 0 {
 		result := tx.Joins("Owner").Joins("Group").Joins("Profile").
 			Preload("Profile.Distro").Preload("Profile.Distro.DistroImage").Preload("Profile.Distro.Kickstart").Preload("Profile.Owner").Preload("Profile.Owner.Groups").
-			Preload("Owner.Groups").Preload("Hosts").Find(&resList)
-		return resList, result.Error
+			Preload("Owner.Groups").Preload("CoOwners.Groups").Preload("NotifyAlso").Preload("Hosts", hostsInSequence).Preload("Shares").Find(&resList)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		return resList, dbLoadHostInstallErrors(resList, baseTx)
 	}
 
 	tx = tx.Preload("Owner").Preload("Group").Preload("Profile").
 		Preload("Profile.Distro").Preload("Profile.Distro.DistroImage").Preload("Profile.Distro.Kickstart").Preload("Profile.Owner").Preload("Profile.Owner.Groups").
-		Preload("Owner.Groups").Preload("Hosts")
+		Preload("Owner.Groups").Preload("CoOwners.Groups").Preload("NotifyAlso").Preload("Hosts", hostsInSequence).Preload("Shares")
 
 	if len(timeParams) > 0 {
 		resolveTimeWhereClauses(timeParams, tx)
@@ -103,11 +115,152 @@ func dbReadReservations(queryParams map[string]interface{}, timeParams map[strin
 	}
 
 	result := tx.Find(&resList)
-	return resList, result.Error
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return resList, dbLoadHostInstallErrors(resList, baseTx)
+}
+
+// dbLoadHostInstallErrors fills in the InstallError field of the hosts of any reservation in the list
+// that failed to install on one or more of them.
+func dbLoadHostInstallErrors(resList []Reservation, tx *gorm.DB) error {
+
+	var failedResIDs []int
+	for _, r := range resList {
+		if r.InstallError != "" {
+			failedResIDs = append(failedResIDs, r.ID)
+		}
+	}
+	if len(failedResIDs) == 0 {
+		return nil
+	}
+
+	var rhList []ReservationHost
+	if result := tx.Where("reservation_id IN ? AND install_error <> ''", failedResIDs).Find(&rhList); result.Error != nil {
+		return result.Error
+	}
+
+	for _, rh := range rhList {
+		for i := range resList {
+			if resList[i].ID != rh.ReservationID {
+				continue
+			}
+			for j := range resList[i].Hosts {
+				if resList[i].Hosts[j].ID == rh.HostID {
+					resList[i].Hosts[j].InstallError = rh.InstallError
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// dbRecordInstallResult saves the outcome of installing a reservation on the given hosts. Any host
+// not named in hostErrors is marked as successfully installed. The reservation's InstallError becomes
+// a summary of all hosts still in a failed state, or is cleared if there are none.
+func dbRecordInstallResult(res *Reservation, hosts []Host, hostErrors map[string]error, tx *gorm.DB) (summary string, err error) {
+
+	for _, h := range hosts {
+		installErr := ""
+		if hErr, ok := hostErrors[h.Name]; ok {
+			installErr = hErr.Error()
+		}
+		result := tx.Model(&ReservationHost{}).Where("reservation_id = ? AND host_id = ?", res.ID, h.ID).
+			Update("install_error", installErr)
+		if result.Error != nil {
+			return "", result.Error
+		}
+	}
+
+	var rhList []ReservationHost
+	if result := tx.Where("reservation_id = ? AND install_error <> ''", res.ID).Find(&rhList); result.Error != nil {
+		return "", result.Error
+	}
+
+	if len(rhList) > 0 {
+		failed := &HostInstallError{HostErrors: map[string]error{}}
+		for _, rh := range rhList {
+			for _, h := range res.Hosts {
+				if h.ID == rh.HostID {
+					failed.HostErrors[h.Name] = fmt.Errorf("%s", rh.InstallError)
+				}
+			}
+		}
+		summary = failed.Error()
+	}
+
+	if result := tx.Model(&res).Update("install_error", summary); result.Error != nil {
+		return "", result.Error
+	}
+	return summary, nil
 }
 
 func dbEditReservation(res *Reservation, changes map[string]interface{}, tx *gorm.DB) error {
 
+	// Remove co-owners and their permissions. This happens before any rename so that only the
+	// permissions of the remaining co-owners need their facts updated.
+	if rmvCoOwners, ok := changes["rmvCoOwners"].([]User); ok {
+		if len(rmvCoOwners) > 0 {
+			if err := tx.Model(&res).Association("CoOwners").Delete(rmvCoOwners); err != nil {
+				return err
+			}
+			for _, coOwner := range rmvCoOwners {
+				if pChanges, gpErr := dbGetResourceOwnerPermissions(PermReservations, res.Name, &coOwner, tx); gpErr != nil {
+					return gpErr
+				} else if len(pChanges) > 0 {
+					if result := tx.Delete(pChanges); result.Error != nil {
+						return result.Error
+					}
+				}
+			}
+		}
+		delete(changes, "rmvCoOwners")
+	}
+
+	// Add co-owners along with the owner-style permissions that let them edit the reservation
+	if addCoOwners, ok := changes["addCoOwners"].([]User); ok {
+		if err := tx.Model(&res).Clauses(clause.OnConflict{DoNothing: true}).Association("CoOwners").Append(addCoOwners); err != nil {
+			return err
+		}
+		for _, coOwner := range addCoOwners {
+			pug, pugErr := coOwner.getPug()
+			if pugErr != nil {
+				return pugErr
+			}
+			cPerms, cErr := createResOwnerPerms(res.Name, true)
+			if cErr != nil {
+				return cErr
+			}
+			if err := dbAppendPermissions(pug, cPerms, tx); err != nil {
+				return err
+			}
+		}
+		delete(changes, "addCoOwners")
+	}
+
+	// Replace or clear the data attached to the reservation
+	if data, ok := changes["data"].([]byte); ok {
+		if err := dbSetResData(res, data, tx); err != nil {
+			return err
+		}
+		delete(changes, "data")
+	}
+
+	// Replace the users copied on the reservation's email
+	if notifyAlso, ok := changes["notifyAlso"].([]User); ok {
+		assoc := tx.Model(&res).Association("NotifyAlso")
+		var err error
+		if len(notifyAlso) == 0 {
+			err = assoc.Clear()
+		} else {
+			err = assoc.Replace(notifyAlso)
+		}
+		if err != nil {
+			return err
+		}
+		delete(changes, "notifyAlso")
+	}
+
 	// Change the name of the reservation
 	if name, ok := changes["Name"].(string); ok {
 		if perms, pResultErr := dbGetPermissionsByName(PermReservations, res.Name, tx); pResultErr != nil {
@@ -136,6 +289,10 @@ func dbEditReservation(res *Reservation, changes map[string]interface{}, tx *gor
 		}
 		delete(changes, "owner-perms")
 		delete(changes, "p-owner-gid")
+		// extend links sent to the old owner must not extend the reservation for the new one
+		if result := tx.Where("reservation_id = ?", res.ID).Delete(&ResExtendToken{}); result.Error != nil {
+			return result.Error
+		}
 	}
 
 	// change the group associated with the reservation
@@ -178,7 +335,7 @@ func dbEditReservation(res *Reservation, changes map[string]interface{}, tx *gor
 	// do drop only
 	if dropHosts, ok := changes["dropHosts"].([]Host); ok {
 
-		// if this reservation is current we need to update the power permissions and change the
+		// if this reservation is current we need to update the node action permissions and change the
 		// dropped hosts' states to available
 		if _, ok = changes["resIsNow"].(bool); ok {
 
@@ -186,7 +343,7 @@ func dbEditReservation(res *Reservation, changes map[string]interface{}, tx *gor
 
 			for _, dropHost := range dropHosts {
 				if dropHost.State != HostBlocked {
-					result = tx.Model(dropHost).Update("State", HostAvailable)
+					result = tx.Model(dropHost).Update("State", releasedHostState(dropHost.State))
 					if result.Error != nil {
 						return result.Error
 					}
@@ -205,6 +362,12 @@ func dbEditReservation(res *Reservation, changes map[string]interface{}, tx *gor
 			return clErr
 		}
 
+		if headID, ok := changes["HeadHostID"]; ok {
+			if result := tx.Model(&res).Update("HeadHostID", headID); result.Error != nil {
+				return result.Error
+			}
+		}
+
 		return nil
 	}
 
@@ -220,15 +383,64 @@ func dbEditReservation(res *Reservation, changes map[string]interface{}, tx *gor
 	return nil
 }
 
+// dbPauseReservation releases the hosts of an active reservation until the given resume time. The
+// reservation's node action permission is removed, its hosts go back to available (or blocked if they were
+// draining) and the reservation start moves to the resume time so the rest of its booking stays in place.
+func dbPauseReservation(res *Reservation, until time.Time, substitute bool, tx *gorm.DB) error {
+
+	powerPerms, err := dbGetNodeActionPermissions(&res.Group, res.Hosts, tx)
+	if err != nil {
+		return err
+	}
+	if len(powerPerms) > 0 {
+		if result := tx.Delete(powerPerms); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	for _, host := range res.Hosts {
+		if host.State != HostBlocked {
+			result := tx.Model(&host).Omit("access_group_id").Update("State", releasedHostState(host.State))
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+	}
+
+	// the hosts are installed fresh on resume so earlier install errors no longer apply
+	result := tx.Model(&ReservationHost{}).Where("reservation_id = ?", res.ID).Update("install_error", "")
+	if result.Error != nil {
+		return result.Error
+	}
+
+	changes := map[string]interface{}{
+		"Start":            until,
+		"PausedUntil":      until,
+		"ResumeSubstitute": substitute,
+		"ResumeError":      "",
+		"Installed":        false,
+		"InstallError":     "",
+	}
+	return dbEditReservation(res, changes, tx)
+}
+
+// dbReplaceResHosts swaps hosts of a reservation that isn't installed for substitute hosts.
+func dbReplaceResHosts(res *Reservation, oldHosts []Host, newHosts []Host, tx *gorm.DB) error {
+	if err := tx.Model(&res).Association("Hosts").Delete(oldHosts); err != nil {
+		return err
+	}
+	return tx.Model(&res).Association("Hosts").Append(newHosts)
+}
+
 func dbDeleteReservation(res *Reservation, perms []Permission, isResNow bool, tx *gorm.DB) error {
 
 	// if this reservation is currently running or already finished (we are cleaning up after a prolonged shutdown),
-	// change state of the reservation hosts back to 'available'
+	// change state of the reservation hosts back to 'available' (or 'blocked' if they were draining)
 	if isResNow {
 
 		for _, host := range res.Hosts {
 			if host.State != HostBlocked {
-				result := tx.Model(&host).Omit("access_group_id").Update("State", HostAvailable)
+				result := tx.Model(&host).Omit("access_group_id").Update("State", releasedHostState(host.State))
 				if result.Error != nil {
 					return result.Error
 				}
@@ -241,6 +453,26 @@ func dbDeleteReservation(res *Reservation, perms []Permission, isResNow bool, tx
 		return clErr
 	}
 
+	// delete the associations with the co-owners table
+	if clErr := tx.Model(&res).Association("CoOwners").Clear(); clErr != nil {
+		return clErr
+	}
+
+	// delete the associations with the notify-also table
+	if clErr := tx.Model(&res).Association("NotifyAlso").Clear(); clErr != nil {
+		return clErr
+	}
+
+	// delete the share links of this reservation
+	if result := tx.Where("reservation_id = ?", res.ID).Delete(&ResShare{}); result.Error != nil {
+		return result.Error
+	}
+
+	// delete the data attached to this reservation
+	if result := tx.Where("reservation_id = ?", res.ID).Delete(&ResData{}); result.Error != nil {
+		return result.Error
+	}
+
 	// delete the permissions for this reservation
 	result := tx.Delete(perms)
 	if result.Error != nil {
@@ -327,9 +559,16 @@ func createResGroupPerms(res *Reservation) ([]Permission, error) {
 	return groupPerms, nil
 }
 
-func createResOwnerPerms(resvName string) ([]Permission, error) {
+// createResOwnerPerms makes the permissions held by the owner of a reservation. When coOwner is
+// true the permissions are instead those held by a co-owner, which cover the same edits as the
+// owner except for transferring ownership and managing co-owners.
+func createResOwnerPerms(resvName string, coOwner bool) ([]Permission, error) {
 	// the owner only needs 'edit:*' permissions. Delete his covered by the group.
-	pstr := NewPermissionString(PermReservations, resvName, PermEditAction, PermWildcardToken)
+	editPart := PermWildcardToken
+	if coOwner {
+		editPart = strings.Join(resCoOwnerEditFields, PermSubpartToken)
+	}
+	pstr := NewPermissionString(PermReservations, resvName, PermEditAction, editPart)
 	ownerResvEdit, err := NewPermission(pstr)
 	if err != nil {
 		return nil, err
@@ -337,6 +576,24 @@ func createResOwnerPerms(resvName string) ([]Permission, error) {
 	return []Permission{*ownerResvEdit}, nil
 }
 
+// dbGetResOwnerPermissions returns the owner-style permissions for a reservation held by its owner
+// and any co-owners.
+func dbGetResOwnerPermissions(res *Reservation, tx *gorm.DB) ([]Permission, error) {
+
+	perms, err := dbGetResourceOwnerPermissions(PermReservations, res.Name, &res.Owner, tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, coOwner := range res.CoOwners {
+		cPerms, cErr := dbGetResourceOwnerPermissions(PermReservations, res.Name, &coOwner, tx)
+		if cErr != nil {
+			return nil, cErr
+		}
+		perms = append(perms, cPerms...)
+	}
+	return perms, nil
+}
+
 // dbCheckResvConflicts scans the database for reservations that conflict with the given slice of host names in the interval
 // specified by the starTime and endTime. A reservation should be good to schedule if the status response is 200/OK. Returns:
 //
@@ -410,21 +667,39 @@ func dbFindOpenSlots(hostNameList []string, startTime time.Time, durNeeded time.
 	// use max end time of last minute of the year that is 25 years from now
 	resDurMinutes := strconv.Itoa(int(durNeeded.Minutes()))
 
+	// hosts in maintenance are open from the end of it
+	maintEnds, err := dbHostMaintenanceEnds(hostNameList, tx)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	maintNames := make([]string, 0, len(maintEnds))
+	for name := range maintEnds {
+		maintNames = append(maintNames, name)
+	}
+	schedulable := tx.Where("h.state IN ?", schedulableHostStates).Or("h.name IN ?", maintNames)
+
 	// get slots on nodes that have no reservations
 	result = tx.Table("hosts h").
 		Select("h.name as hostname, h.sequence_id as hostnum, NULL as res_name, NULL AS res_start, ? AS avail_slot_begin, NULL AS next_res_name, ? AS avail_slot_end", startTime, maxEnd).
 		Joins("LEFT OUTER JOIN reservations_hosts rh ON h.id = rh.host_id").
-		Where("rh.host_id IS NULL AND h.state < ? AND h.name IN (?)", HostBlocked, hostNameList).Scan(&tempSlots)
+		Where("rh.host_id IS NULL AND h.name IN (?)", hostNameList).Where(schedulable).Scan(&tempSlots)
 
 	if result.Error != nil {
 		return nil, http.StatusInternalServerError, result.Error
 	}
 
-	tempTimeSlots := convertToTimeSlotSlice(tempSlots)
+	tempTimeSlots := slotsAfterMaintenance(convertToTimeSlotSlice(tempSlots), maintEnds, durNeeded)
 	timeSlotListAll = append(timeSlotListAll, tempTimeSlots...)
 
-	// if there are enough completely free nodes to satisfy request, then we are good to go.
-	if len(timeSlotListAll) >= numHostsReq {
+	// if there are enough completely free nodes to satisfy request, then we are good to go. Nodes still in
+	// maintenance at the start time don't count.
+	freeAtStart := 0
+	for _, ts := range timeSlotListAll {
+		if !ts.AvailSlotBegin.After(startTime) {
+			freeAtStart++
+		}
+	}
+	if freeAtStart >= numHostsReq {
 		sortTimeSlots(timeSlotListAll)
 		return timeSlotListAll, http.StatusOK, nil
 	}
@@ -436,13 +711,31 @@ func dbFindOpenSlots(hostNameList []string, startTime time.Time, durNeeded time.
 		Select("h.name as hostname, h.sequence_id as hostnum, l.name as res_name, max(l.start) AS res_start, l.reset_end AS avail_slot_begin, NULL AS next_res_name, ? AS avail_slot_end", maxEnd).
 		Joins("INNER JOIN reservations_hosts rhl ON l.id = rhl.reservation_id AND h.id = rhl.host_id").
 		Group("h.name").
-		Where("h.state < ? AND h.name IN (?)", HostBlocked, hostNameList).Scan(&tempSlots)
+		Where(schedulable).Where("h.name IN (?)", hostNameList).Scan(&tempSlots)
+
+	if result.Error != nil {
+		return nil, http.StatusInternalServerError, result.Error
+	}
+
+	tempTimeSlots = slotsAfterMaintenance(convertToTimeSlotSlice(tempSlots), maintEnds, durNeeded)
+	timeSlotListAll = append(timeSlotListAll, tempTimeSlots...)
+	tempSlots = nil
+
+	// get slots that begin at the start time and end when the first reservation on the node starts, such as
+	// nodes released by a paused reservation until it resumes
+	result = tx.Table("reservations r, hosts h").
+		Select("h.name as hostname, h.sequence_id as hostnum, NULL as res_name, NULL AS res_start, ? AS avail_slot_begin, r.name AS next_res_name, min(r.start) AS avail_slot_end", startTime).
+		Joins("INNER JOIN reservations_hosts rhr ON r.id = rhr.reservation_id AND h.id = rhr.host_id").
+		Group("h.name").
+		Where(schedulable).Where("h.name IN (?)", hostNameList).
+		Having("DATETIME(?, '+"+resDurMinutes+" minutes') < DATETIME(min(r.start))", startTime).
+		Scan(&tempSlots)
 
 	if result.Error != nil {
 		return nil, http.StatusInternalServerError, result.Error
 	}
 
-	tempTimeSlots = convertToTimeSlotSlice(tempSlots)
+	tempTimeSlots = slotsAfterMaintenance(convertToTimeSlotSlice(tempSlots), maintEnds, durNeeded)
 	timeSlotListAll = append(timeSlotListAll, tempTimeSlots...)
 	tempSlots = nil
 
@@ -455,14 +748,15 @@ func dbFindOpenSlots(hostNameList []string, startTime time.Time, durNeeded time.
 		Select("h.name as hostname, h.sequence_id as hostnum, l.name as res_name, l.start AS res_start, l.reset_end AS avail_slot_begin, r.name AS next_res_name, r.start AS avail_slot_end").
 		Joins("INNER JOIN reservations_hosts rhl ON l.id = rhl.reservation_id AND h.id = rhl.host_id").
 		Joins("INNER JOIN reservations_hosts rhr ON r.id = rhr.reservation_id AND h.id = rhr.host_id").
-		Where("h.state < ? AND h.name IN (?) AND DATETIME(l.reset_end, '+"+resDurMinutes+" minutes') < DATETIME(r.start) AND NOT EXISTS(?)", HostBlocked, hostNameList, subQuery).
+		Where(schedulable).
+		Where("h.name IN (?) AND DATETIME(l.reset_end, '+"+resDurMinutes+" minutes') < DATETIME(r.start) AND NOT EXISTS(?)", hostNameList, subQuery).
 		Scan(&tempSlots)
 
 	if result.Error != nil {
 		return nil, http.StatusInternalServerError, result.Error
 	}
 
-	tempTimeSlots = convertToTimeSlotSlice(tempSlots)
+	tempTimeSlots = slotsAfterMaintenance(convertToTimeSlotSlice(tempSlots), maintEnds, durNeeded)
 	timeSlotListAll = append(timeSlotListAll, tempTimeSlots...)
 
 	// eliminate duplicates?
@@ -7,8 +7,11 @@ package igorserver
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"igor2/internal/pkg/common"
+
 	zl "github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
 	"gorm.io/gorm"
@@ -39,8 +42,13 @@ func doDeleteReservation(resName string, r *http.Request) (status int, err error
 	res = &rList[0]
 	resClone = res.DeepCopy()
 
-	// is this reservation running now or is it in the future?
-	activeRes := res.Start.Before(time.Now())
+	// is this reservation running now or is it in the future? a paused reservation has already released its hosts
+	// and one that failed to start never took them
+	activeRes := res.Start.Before(time.Now()) && !res.isPaused() && !res.startFailed() && !res.awaitingApproval()
+
+	// wait for any install still writing to the hosts, and uninstall afterward if there was one
+	wasInstalling := beginResTeardown(res.ID)
+	defer endResTeardown(res.ID)
 
 	if err = performDbTx(func(tx *gorm.DB) error {
 		status, err = doDeleteRes(res, tx, activeRes, clog)
@@ -59,10 +67,10 @@ func doDeleteReservation(resName string, r *http.Request) (status int, err error
 			}
 		}
 
-		// power off the nodes and uninstall this res if it was active
-		if activeRes {
+		// power off the nodes and uninstall this res if it was active or being installed
+		if activeRes || wasInstalling {
 
-			if err = uninstallRes(resClone); err != nil {
+			if err = uninstallRes(resClone, hookRequestID(r)); err != nil {
 				status = http.StatusInternalServerError
 				return
 			}
@@ -74,6 +82,40 @@ func doDeleteReservation(resName string, r *http.Request) (status int, err error
 	return
 }
 
+// doReadResDelete returns a summary of the named reservation and what deleting it would do. It is read fresh
+// for each request so a confirmation prompt or dry run never shows stale details.
+func doReadResDelete(resName string, actionUser *User) (*common.ResDeleteData, int, error) {
+
+	rList, status, err := doReadReservations(map[string]interface{}{"name": resName}, map[string]time.Time{})
+	if err != nil {
+		return nil, status, err
+	} else if len(rList) == 0 {
+		return nil, http.StatusNotFound, fmt.Errorf("the reservation '%s' does not exist", resName)
+	}
+	res := &rList[0]
+
+	var groupName string
+	if !strings.HasPrefix(res.Group.Name, GroupUserPrefix) {
+		groupName = res.Group.Name
+	}
+	hostRange, _ := igor.ClusterRefs[0].UnsplitRange(hostNamesOfHosts(res.Hosts))
+
+	summary := &common.ResDeleteData{
+		Name:         res.Name,
+		Owner:        res.Owner.Name,
+		Group:        groupName,
+		HostCount:    len(res.Hosts),
+		HostRange:    hostRange,
+		End:          res.End.Unix(),
+		Active:       res.Start.Before(time.Now()) && !res.isPaused() && !res.startFailed() && !res.awaitingApproval(),
+		OwnerOrAdmin: actionUser.Name == res.Owner.Name || res.isCoOwner(actionUser.Name) || userElevated(actionUser.Name),
+	}
+	if summary.Active {
+		summary.MaintenanceMins = igor.Maintenance.HostMaintenanceDuration
+	}
+	return summary, http.StatusOK, nil
+}
+
 // doDeleteRes deletes a reservation from the DB. It also removes the permissions for the reservation and the
 // hosts it runs on (if the reservation was active). It ends by updating any node that was part of the reservation with
 // a pending change to its access group (HostFuture).
@@ -87,7 +129,7 @@ func doDeleteRes(res *Reservation, tx *gorm.DB, activeRes bool, clog *zl.Logger)
 	// If the server started and a reserved host is in an error state, can't change status to 'available'
 
 	var perms []Permission
-	perms, err = dbGetResourceOwnerPermissions(PermReservations, res.Name, &res.Owner, tx)
+	perms, err = dbGetResOwnerPermissions(res, tx)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
@@ -101,9 +143,13 @@ func doDeleteRes(res *Reservation, tx *gorm.DB, activeRes bool, clog *zl.Logger)
 		perms = append(perms, perms2...)
 	}
 
+	if res.cyclePending() {
+		clog.Info().Msgf("reservation '%s' is being deleted - its power cycle scheduled for %s is dropped", res.Name, res.CycleAt.Format(common.DateTimeCompactFormat))
+	}
+
 	// perform specific tasks if reservation is live (within start/end time)
 	if activeRes {
-		powerPerms, ppErr := dbGetHostPowerPermissions(&res.Group, res.Hosts, tx)
+		powerPerms, ppErr := dbGetNodeActionPermissions(&res.Group, res.Hosts, tx)
 		if ppErr != nil {
 			return http.StatusInternalServerError, ppErr
 		}
@@ -113,8 +159,11 @@ func doDeleteRes(res *Reservation, tx *gorm.DB, activeRes bool, clog *zl.Logger)
 		clog.Debug().Msgf("changing reservation %v's hosts out of 'reserved' state", res.Name)
 
 		var availableHosts []Host
+		var drainedHosts []Host
 		for _, host := range res.Hosts {
-			if host.State != HostBlocked {
+			if host.State == HostDraining {
+				drainedHosts = append(drainedHosts, host)
+			} else if host.State != HostBlocked {
 				availableHosts = append(availableHosts, host)
 			}
 		}
@@ -123,6 +172,19 @@ func doDeleteRes(res *Reservation, tx *gorm.DB, activeRes bool, clog *zl.Logger)
 		if err != nil {
 			return http.StatusInternalServerError, err
 		}
+
+		// draining hosts have finished their last reservation, so they go to blocked until an admin acts
+		if len(drainedHosts) > 0 {
+			clog.Info().Msgf("drained hosts %v moving to blocked state", namesOfHosts(drainedHosts))
+			if err = dbEditHosts(drainedHosts, map[string]interface{}{"State": HostBlocked}, tx); err != nil {
+				return http.StatusInternalServerError, err
+			}
+		}
+
+		// add the time the hosts were held to their usage for the scheduler's usage tie-break
+		if err = dbAddHostUsage(res, tx); err != nil {
+			return http.StatusInternalServerError, err
+		}
 	}
 
 	// grab a copy since the del op will get rid of the
@@ -139,8 +201,13 @@ func doDeleteRes(res *Reservation, tx *gorm.DB, activeRes bool, clog *zl.Logger)
 	return http.StatusOK, nil
 }
 
-func uninstallRes(res *Reservation) (err error) {
+// uninstallRes tears down an active reservation's network, PXE configs and power, then puts its
+// hosts into maintenance if configured. The reservation-end hook is run with reqID.
+func uninstallRes(res *Reservation, reqID string) (err error) {
 	err = nil
+
+	fireHook(newHookPayload(HookResEnd, reqID, res, res.Hosts))
+
 	// skip if not using vlan
 	if igor.Vlan.Network != "" {
 		// clean up the network config
@@ -157,6 +224,14 @@ func uninstallRes(res *Reservation) (err error) {
 		err = fmt.Errorf("%v\n%v", err, uErr)
 	}
 
+	// the owner asked for the nodes to stay on, so they skip power off and maintenance, which would cycle
+	// them, and are available right away
+	if res.leavesHostsOn() {
+		logger.Info().Msgf("leaving nodes %s of reservation '%s' powered on and skipping maintenance as requested",
+			common.UnsplitList(namesOfHosts(res.Hosts)), res.Name)
+		return err
+	}
+
 	// power off the nodes of this reservation
 	pErr := powerOffResNodes(res)
 	if err == nil {
@@ -180,6 +255,8 @@ func uninstallRes(res *Reservation) (err error) {
 			if activeRes == nil {
 				if res.Hosts[i].State == HostReserved {
 					res.Hosts[i].RestoreState = HostAvailable // a reserved host will always return to available
+				} else if res.Hosts[i].State == HostDraining {
+					res.Hosts[i].RestoreState = HostBlocked // a drained host stays out of the pool
 				} else {
 					res.Hosts[i].RestoreState = res.Hosts[i].State
 				}
@@ -200,7 +277,7 @@ func uninstallRes(res *Reservation) (err error) {
 			logger.Error().Msgf("warning - errors detected when creating maintenance reservation %v: %v", res.Name, err)
 		} else {
 			// begin maintenance immediately
-			_ = startMaintenance(maintenanceRes)
+			_ = startMaintenance(maintenanceRes, reqID)
 		}
 	}
 
@@ -0,0 +1,76 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordInstaller is an IResInstaller that only records the reservations it is asked to uninstall.
+type recordInstaller struct {
+	uninstalled []string
+}
+
+func (ri *recordInstaller) Install(*Reservation) error { return nil }
+
+func (ri *recordInstaller) Uninstall(r *Reservation) error {
+	ri.uninstalled = append(ri.uninstalled, r.Name)
+	return nil
+}
+
+func TestExpireLeaveOnRes(t *testing.T) {
+
+	useSimulation(t)
+	origInstaller, origMaint, origSimHosts := igor.IResInstaller, igor.Config.Maintenance, simHosts
+	t.Cleanup(func() { igor.IResInstaller, igor.Config.Maintenance, simHosts = origInstaller, origMaint, origSimHosts })
+	installer := &recordInstaller{}
+	igor.IResInstaller = installer
+	igor.Config.Maintenance.HostMaintenanceDuration = 30
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	require.NoError(t, db.Create(&Cluster{Name: "test", Prefix: "kn"}).Error)
+
+	now := time.Now()
+	expire := func(name string, host Host, endPower string) {
+		res := newStartTestRes(t, db, name, []Host{host}, false, 0)
+		ownerPerms, err := createResOwnerPerms(name, false)
+		require.NoError(t, err)
+		ownerPerms[0].GroupID = res.GroupID
+		require.NoError(t, db.Create(&ownerPerms).Error)
+		require.NoError(t, db.Model(res).Updates(map[string]interface{}{"start": now.Add(-2 * time.Hour),
+			"end": now.Add(-time.Minute), "installed": true, "end_power": endPower}).Error)
+		require.NoError(t, db.Model(&host).Update("state", HostReserved).Error)
+		simHosts = map[string]*simHostPower{host.HostName: {on: true}}
+		require.NoError(t, closeoutReservations(&now))
+	}
+
+	// a leave-on reservation is torn down without any power command or maintenance
+	expire("burnin", hosts[0], EndPowerLeaveOn)
+	assert.Equal(t, []string{"burnin"}, installer.uninstalled)
+	assert.True(t, simHosts["kn1"].on)
+	assert.True(t, simHosts["kn1"].onAt.IsZero())
+	var maintCount int64
+	require.NoError(t, db.Model(&MaintenanceRes{}).Count(&maintCount).Error)
+	assert.Zero(t, maintCount)
+
+	var host Host
+	require.NoError(t, db.First(&host, hosts[0].ID).Error)
+	assert.Equal(t, HostAvailable, host.State)
+
+	var hist HistoryRecord
+	require.NoError(t, db.Where("name = ? AND status = ?", "burnin", HrFinished).First(&hist).Error)
+	assert.Equal(t, EndPowerLeaveOn, hist.EndPower)
+
+	// one that powers off as usual does
+	igor.Config.Maintenance.HostMaintenanceDuration = 0
+	expire("usual", hosts[1], EndPowerOff)
+	assert.False(t, simHosts["kn2"].on)
+}
@@ -0,0 +1,303 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// extendLinkAudience marks a jwt as an extend link token so it can't be mistaken for a login or share token
+const extendLinkAudience = "igor-res-extend"
+
+// errExtendLinkInvalid is the only error given for a bad extend link, so the response doesn't reveal
+// whether a reservation exists or why the link stopped working.
+var errExtendLinkInvalid = errors.New("this extend link is not valid, has expired or has already been used")
+
+// ResExtendToken is a one-time link sent in a reservation's expiration warnings that extends the
+// reservation as far as its owner could with 'igor res edit --extend-max'. The link itself is a signed
+// token naming the record. It stops working once used, when the reservation ends or when the
+// reservation changes owner.
+type ResExtendToken struct {
+	Base
+	TokenID       string `gorm:"unique; notNull"`
+	ReservationID int    `gorm:"notNull; index"`
+	// OwnerID is the reservation owner when the link was made, who the extension is made for
+	OwnerID int `gorm:"notNull"`
+	Expires time.Time
+}
+
+// ResExtendClaims are the claims of an extend link token.
+type ResExtendClaims struct {
+	TokenID string `json:"tid"`
+	// ResHash is the history hash of the reservation, which is never reused
+	ResHash string `json:"res"`
+	jwt.RegisteredClaims
+}
+
+// extendLinksEnabled returns true if expiration warnings carry an extend link.
+func extendLinksEnabled() bool {
+	return igor.Server.ExtendLinkURL != ""
+}
+
+// newResExtendLink makes an extend link for the reservation, good until the reservation ends. It
+// returns the full link to put in the reservation's expiration warning.
+func newResExtendLink(res *Reservation) (string, error) {
+
+	tokenID, err := newShareID()
+	if err != nil {
+		return "", err
+	}
+	et := &ResExtendToken{
+		TokenID:       tokenID,
+		ReservationID: res.ID,
+		OwnerID:       res.OwnerID,
+		Expires:       res.End,
+	}
+
+	dbAccess.Lock()
+	err = performDbTx(func(tx *gorm.DB) error {
+		return tx.Create(et).Error
+	})
+	dbAccess.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	claims := &ResExtendClaims{
+		TokenID: et.TokenID,
+		ResHash: res.Hash,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{extendLinkAudience},
+			ExpiresAt: jwt.NewNumericDate(et.Expires),
+		},
+	}
+	key, err := getJwtToken()
+	if err != nil {
+		return "", err
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(igor.Server.ExtendLinkURL, token), nil
+}
+
+// parseExtendLinkToken checks the signature and expiry of an extend link token and returns its claims.
+func parseExtendLinkToken(token string) (*ResExtendClaims, error) {
+
+	claims := &ResExtendClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, acquireTokenSecret); err != nil {
+		return nil, err
+	}
+	if !claims.VerifyAudience(extendLinkAudience, true) {
+		return nil, fmt.Errorf("not an extend link token")
+	}
+	if claims.TokenID == "" || claims.ResHash == "" {
+		return nil, fmt.Errorf("extend link token is missing its ID or reservation")
+	}
+	return claims, nil
+}
+
+// handlePublicExtendLink shows what using an extend link would do (GET) or uses it (POST). It does not
+// require authentication; the link itself stands for the reservation owner asking for the extension.
+func handlePublicExtendLink(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "check extend link"
+	rb := common.NewResponseBody()
+
+	ps := httprouter.ParamsFromContext(r.Context())
+	token := ps.ByName("extendToken")
+
+	var linkData common.ResExtendLinkData
+	var status int
+	var err error
+	if r.Method == http.MethodPost {
+		actionPrefix = "use extend link"
+		linkData, status, err = doUseExtendLink(token, requestAddress(r), time.Now(), r)
+	} else {
+		linkData, status, err = doReadExtendLink(token, time.Now(), r)
+	}
+
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["extendLink"] = linkData
+		clog.Debug().Msgf("%s success - '%s'", actionPrefix, linkData.Name)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// readExtendLink finds the record and reservation of an extend link token and makes sure the link can
+// still be used. Any problem with the link itself is reported as errExtendLinkInvalid.
+func readExtendLink(token string, now time.Time, tx *gorm.DB) (*ResExtendToken, *Reservation, int, error) {
+
+	if !extendLinksEnabled() {
+		return nil, nil, http.StatusNotFound, errExtendLinkInvalid
+	}
+	claims, err := parseExtendLinkToken(token)
+	if err != nil {
+		logger.Debug().Msgf("extend link token rejected - %v", err)
+		return nil, nil, http.StatusNotFound, errExtendLinkInvalid
+	}
+
+	var tokens []ResExtendToken
+	if tErr := tx.Where("token_id = ?", claims.TokenID).Find(&tokens).Error; tErr != nil {
+		return nil, nil, http.StatusInternalServerError, tErr
+	}
+	if len(tokens) == 0 {
+		logger.Debug().Msgf("extend link %s rejected - not found or already used", claims.TokenID)
+		return nil, nil, http.StatusNotFound, errExtendLinkInvalid
+	}
+	et := &tokens[0]
+
+	rList, rErr := dbReadReservations(map[string]interface{}{"ID": et.ReservationID}, nil, tx)
+	if rErr != nil {
+		return nil, nil, http.StatusInternalServerError, rErr
+	}
+	if len(rList) == 0 {
+		logger.Debug().Msgf("extend link %s rejected - reservation not found", claims.TokenID)
+		return nil, nil, http.StatusNotFound, errExtendLinkInvalid
+	}
+	res := &rList[0]
+
+	if cErr := checkResExtendToken(et, res, claims.ResHash, now); cErr != nil {
+		logger.Debug().Msgf("extend link %s rejected - %v", claims.TokenID, cErr)
+		return nil, nil, http.StatusNotFound, errExtendLinkInvalid
+	}
+	return et, res, http.StatusOK, nil
+}
+
+// checkResExtendToken returns an error if the extend link can no longer be used on the reservation.
+func checkResExtendToken(et *ResExtendToken, res *Reservation, resHash string, now time.Time) error {
+	switch {
+	case res.Hash != resHash:
+		return fmt.Errorf("token is for a different reservation")
+	case !et.Expires.After(now):
+		return fmt.Errorf("expired %s", et.Expires.Format(common.DateTimeCompactFormat))
+	case !res.End.After(now):
+		return fmt.Errorf("reservation '%s' has ended", res.Name)
+	case res.OwnerID != et.OwnerID:
+		return fmt.Errorf("reservation '%s' has changed owner", res.Name)
+	}
+	return nil
+}
+
+// extendLinkChanges checks that the owner could extend the reservation now and returns the changes that
+// would extend it as far as they are allowed. The link only carries who is asking, so it is held to the
+// same rules as the owner extending it themselves.
+func extendLinkChanges(res *Reservation, r *http.Request, tx *gorm.DB) (map[string]interface{}, int, error) {
+	if igor.Scheduler.ExtendWithin < 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("extending a reservation has been disabled for normal users - talk to an igor admin if you wish to change your reservation end time")
+	}
+	checks := extendChecks{userRules: true, timeLimits: true, limitGroups: res.Owner.groupNames()}
+	changes, _, status, err := checkExtend(res, "", false, checks, hlog.FromRequest(r), tx)
+	return changes, status, err
+}
+
+// doReadExtendLink returns the reservation of an extend link with the end time using it would give,
+// without using it. If the reservation can't be extended now the reason is given in the result.
+func doReadExtendLink(token string, now time.Time, r *http.Request) (linkData common.ResExtendLinkData, status int, err error) {
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	err = performDbTx(func(tx *gorm.DB) error {
+		_, res, rStatus, rErr := readExtendLink(token, now, tx)
+		if rErr != nil {
+			status = rStatus
+			return rErr
+		}
+		linkData = common.ResExtendLinkData{Name: res.Name, Owner: res.Owner.Name, End: res.End.Unix()}
+		if changes, _, cErr := extendLinkChanges(res, r, tx); cErr != nil {
+			linkData.Problem = cErr.Error()
+		} else {
+			linkData.NewEnd = changes["End"].(time.Time).Unix()
+		}
+		// the changes are only looked at, never saved
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	return linkData, http.StatusOK, nil
+}
+
+// doUseExtendLink extends the reservation of an extend link as far as its owner is allowed and removes
+// the reservation's extend links so none can be used again. The address the link was used from is
+// logged with the extension.
+func doUseExtendLink(token, fromAddr string, now time.Time, r *http.Request) (linkData common.ResExtendLinkData, status int, err error) {
+
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+	var et *ResExtendToken
+	var res *Reservation
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+		var rStatus int
+		var rErr error
+		if et, res, rStatus, rErr = readExtendLink(token, now, tx); rErr != nil {
+			status = rStatus
+			return rErr
+		}
+		changes, cStatus, cErr := extendLinkChanges(res, r, tx)
+		if cErr != nil {
+			status = cStatus
+			return cErr
+		}
+		linkData = common.ResExtendLinkData{Name: res.Name, Owner: res.Owner.Name, End: res.End.Unix(),
+			NewEnd: changes["End"].(time.Time).Unix(), Extended: true}
+		if eErr := dbEditReservation(res, changes, tx); eErr != nil {
+			return eErr
+		}
+		return tx.Where("reservation_id = ?", res.ID).Delete(&ResExtendToken{}).Error
+	}); err != nil {
+		return
+	}
+
+	logger.Info().Msgf("extend link %s used from %s to extend reservation '%s' of '%s' to %s", et.TokenID, fromAddr, res.Name,
+		res.Owner.Name, time.Unix(linkData.NewEnd, 0).Format(common.DateTimeCompactFormat))
+	if hErr := res.HistCallback(res, HrUpdated+":extendMax,extendLink"); hErr != nil {
+		logger.Error().Msgf("failed to record reservation '%s' extend link use to history", res.Name)
+	}
+
+	return linkData, http.StatusOK, nil
+}
+
+// purgeResExtendTokens removes extend links that can no longer be used because they expired or their
+// reservation ended, was deleted or changed owner. It runs as part of the reservation manager.
+func purgeResExtendTokens(checkTime *time.Time) error {
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	var purged int64
+	if err := performDbTx(func(tx *gorm.DB) error {
+		result := tx.Where("expires <= ? OR NOT EXISTS (SELECT 1 FROM reservations r WHERE r.id = res_extend_tokens.reservation_id "+
+			"AND r.owner_id = res_extend_tokens.owner_id AND r.end > ?)", *checkTime, *checkTime).Delete(&ResExtendToken{})
+		purged = result.RowsAffected
+		return result.Error
+	}); err != nil {
+		return fmt.Errorf("problem removing unusable reservation extend links: %v", err)
+	}
+
+	if purged > 0 {
+		logger.Debug().Msgf("removed %d unusable reservation extend link(s)", purged)
+	}
+	return nil
+}
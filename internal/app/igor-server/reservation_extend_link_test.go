@@ -0,0 +1,156 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igor2/internal/pkg/common"
+)
+
+const testExtendLinkURL = "https://igorweb.example.com/extend/%s"
+
+// setExtendLinkTest turns on extend links with a fresh token key.
+func setExtendLinkTest(t *testing.T) {
+	setShareTokenKey(t)
+	orig := igor.Server.ExtendLinkURL
+	t.Cleanup(func() { igor.Server.ExtendLinkURL = orig })
+	igor.Server.ExtendLinkURL = testExtendLinkURL
+}
+
+// extendLinkToken returns the token part of an extend link.
+func extendLinkToken(t *testing.T, link string) string {
+	prefix := strings.TrimSuffix(testExtendLinkURL, "%s")
+	require.True(t, strings.HasPrefix(link, prefix))
+	return strings.TrimPrefix(link, prefix)
+}
+
+func TestExtendLinkToken(t *testing.T) {
+
+	setExtendLinkTest(t)
+	seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+
+	res := &Reservation{Name: "res1", Hash: "abc123", OwnerID: 1, End: time.Now().Add(time.Hour)}
+	res.ID = 7
+	link, err := newResExtendLink(res)
+	require.NoError(t, err)
+
+	claims, err := parseExtendLinkToken(extendLinkToken(t, link))
+	require.NoError(t, err)
+	assert.Equal(t, res.Hash, claims.ResHash)
+
+	// a share token can't be used as an extend link
+	share := &ResShare{ShareID: "0123456789ab", Expires: time.Now().Add(time.Hour)}
+	shareToken, err := signShareToken(share, res)
+	require.NoError(t, err)
+	_, err = parseExtendLinkToken(shareToken)
+	assert.Error(t, err)
+
+	now := time.Now()
+	et := &ResExtendToken{TokenID: claims.TokenID, ReservationID: res.ID, OwnerID: 1, Expires: res.End}
+	assert.NoError(t, checkResExtendToken(et, res, res.Hash, now))
+	assert.Error(t, checkResExtendToken(et, res, "other", now))
+	assert.Error(t, checkResExtendToken(et, res, res.Hash, res.End))
+	newOwner := *res
+	newOwner.OwnerID = 2
+	assert.Error(t, checkResExtendToken(et, &newOwner, res.Hash, now))
+}
+
+func TestUseExtendLink(t *testing.T) {
+
+	setExtendLinkTest(t)
+	origSchedMinutes := MaxScheduleMinutes
+	t.Cleanup(func() { MaxScheduleMinutes = origSchedMinutes })
+	MaxScheduleMinutes = 45 * 24 * 60
+	origNotify := igor.Email.ResNotifyOn
+	t.Cleanup(func() { igor.Email.ResNotifyOn = origNotify })
+	notifyOff := false
+	igor.Email.ResNotifyOn = &notifyOff
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	res := newStartTestRes(t, db, "soak", hosts, false, 0)
+
+	link, err := newResExtendLink(res)
+	require.NoError(t, err)
+	token := extendLinkToken(t, link)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	// looking at the link shows the new end without using it
+	preview, status, err := doReadExtendLink(token, time.Now(), r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "alice", preview.Owner)
+	assert.Empty(t, preview.Problem)
+	assert.Greater(t, preview.NewEnd, preview.End)
+	assert.False(t, preview.Extended)
+
+	used, status, err := doUseExtendLink(token, "192.0.2.10", time.Now(), r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, used.Extended)
+	stored, err := dbReadReservationsTx(map[string]interface{}{"name": res.Name}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, used.NewEnd, stored[0].End.Unix())
+
+	// the link works only once
+	_, status, err = doUseExtendLink(token, "192.0.2.10", time.Now(), r)
+	assert.ErrorIs(t, err, errExtendLinkInvalid)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// a link sent to the owner stops working when the reservation goes to someone else
+	link, err = newResExtendLink(&stored[0])
+	require.NoError(t, err)
+	bob := User{Name: "bob", Email: "bob@example.com"}
+	require.NoError(t, db.Create(&bob).Error)
+	require.NoError(t, db.Model(&Reservation{}).Where("id = ?", res.ID).Update("owner_id", bob.ID).Error)
+	_, _, err = doReadExtendLink(extendLinkToken(t, link), time.Now(), r)
+	assert.ErrorIs(t, err, errExtendLinkInvalid)
+
+	checkTime := time.Now()
+	require.NoError(t, purgeResExtendTokens(&checkTime))
+	var count int64
+	require.NoError(t, db.Model(&ResExtendToken{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	// nothing works once the feature is turned off
+	igor.Server.ExtendLinkURL = ""
+	_, status, err = doReadExtendLink(token, time.Now(), r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestResWarnEmailExtendLink(t *testing.T) {
+
+	res := &Reservation{
+		Name:  "myres",
+		Start: time.Now().Add(-time.Hour),
+		End:   time.Now().Add(2 * time.Hour),
+		Owner: User{Name: "tombomb"},
+		Hosts: []Host{{Name: "kn1"}},
+	}
+
+	body := renderResEmail(t, EmailResWarn, res)
+	assert.NotContains(t, body, "with one click")
+
+	// the templates are loaded by the render above
+	origRefs := igor.ClusterRefs
+	t.Cleanup(func() { igor.ClusterRefs = origRefs })
+	knRange, err := common.NewRange("kn", 1, 10)
+	require.NoError(t, err)
+	igor.ClusterRefs = []common.Range{*knRange}
+	msg := &ResNotifyEvent{Cluster: "krypton", Res: res, ExtendLink: "https://igorweb.example.com/extend/tok"}
+	msg.Type = EmailResFinalWarn
+	var sb strings.Builder
+	require.NoError(t, tMap[EmailResFinalWarn].Execute(&sb, msg))
+	assert.Contains(t, sb.String(), `<a href="https://igorweb.example.com/extend/tok">extend this reservation</a>`)
+}
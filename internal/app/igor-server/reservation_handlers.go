@@ -5,6 +5,7 @@
 package igorserver
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -31,7 +32,58 @@ func handleCreateReservations(w http.ResponseWriter, r *http.Request) {
 	actionPrefix := "create reservation"
 	rb := common.NewResponseBody()
 
-	res, resIsNow, status, err := doCreateReservation(createParams, r)
+	// a retry of a request sent with an idempotency key gets the original response
+	var idemRec *IdempotencyRecord
+	idemKey := r.Header.Get(common.IdempotencyHeader)
+	if idemKey != "" {
+		bodyHash := hashRequestBody(createParams)
+		prevRec, ikStatus, ikErr := checkIdempotencyKey(idemKey, bodyHash, getUserFromContext(r))
+		if ikErr != nil || prevRec != nil {
+			dbAccess.Unlock()
+			if ikErr != nil {
+				stdErrorResp(rb, ikStatus, actionPrefix, ikErr, clog)
+				makeJsonResponse(w, ikStatus, rb)
+			} else {
+				clog.Info().Msgf("%s - returning original response for repeated idempotency key '%s'", actionPrefix, idemKey)
+				replayIdempotentResponse(w, prevRec)
+			}
+			return
+		}
+		idemRec = &IdempotencyRecord{Key: idemKey, UserID: getUserFromContext(r).ID, BodyHash: bodyHash}
+	}
+
+	var res *Reservation
+	var resIsNow bool
+	var resMsg string
+	var status int
+	var err error
+
+	// a quick reservation is made like any other once its parameters are filled in
+	quick, _ := createParams["quick"].(bool)
+	if quick {
+		createParams, status, err = quickResParams(createParams, getUserFromContext(r), time.Now())
+	}
+	if err == nil {
+		res, resIsNow, resMsg, status, err = doCreateReservation(createParams, r)
+		if quick && status == http.StatusConflict {
+			err = fmt.Errorf("no node is free for the length of a quick reservation right now -- try a shorter time with -e or see 'igor show' for when nodes free up")
+		} else if quick && err == nil {
+			resMsg = quickResMessage(res)
+		}
+	}
+	if idemRec != nil {
+		if err != nil {
+			// a failed create isn't recorded, so a retry with the same key is handled as a new request
+			idemRec = nil
+		} else {
+			idemRec.ResID = res.ID
+			idemRec.Status = status
+			if ikErr := dbCreateIdempotencyRecord(idemRec); ikErr != nil {
+				clog.Error().Msgf("%s - failed to record idempotency key '%s': %v", actionPrefix, idemKey, ikErr)
+				idemRec = nil
+			}
+		}
+	}
 	dbAccess.Unlock()
 
 	if err == nil && resIsNow {
@@ -44,11 +96,35 @@ func handleCreateReservations(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
+		var fullErr *ScheduleFullError
+		if errors.As(err, &fullErr) && !fullErr.suggestedStart.IsZero() {
+			rb.Data["suggestedStart"] = fullErr.suggestedStart.Unix()
+		}
+		var rejectErr *HostRejectionError
+		if errors.As(err, &rejectErr) {
+			rb.Data["rejectedHosts"] = rejectErr.rejected
+			rb.Data["availableHosts"] = rejectErr.available
+		}
 	} else {
 		rb.Data["reservation"] = filterReservationList([]Reservation{*res}, getUserFromContext(r))
+		rb.Data["kernelLine"] = res.getKernelArgs()
+		if noDefaults, _ := createParams["noDefaultKernelArgs"].(bool); !noDefaults && res.Owner.DefaultKernelArgs != "" {
+			rb.Data["defaultKernelArgs"] = res.Owner.DefaultKernelArgs
+		}
+		rb.Message = resMsg
 		clog.Info().Msgf("%s success - '%s' created", actionPrefix, res.Name)
 	}
 
+	if idemRec != nil {
+		rb.SetStatus(status)
+		dbAccess.Lock()
+		ikErr := dbSaveIdempotencyResponse(idemRec, marshalJSONBody(rb))
+		dbAccess.Unlock()
+		if ikErr != nil {
+			clog.Error().Msgf("%s - failed to save response for idempotency key '%s': %v", actionPrefix, idemKey, ikErr)
+		}
+	}
+
 	makeJsonResponse(w, status, rb)
 }
 
@@ -79,27 +155,140 @@ func handleReadReservations(w http.ResponseWriter, r *http.Request) {
 
 func handleUpdateReservation(w http.ResponseWriter, r *http.Request) {
 
+	editParams := getBodyFromContext(r)
+	if _, doReimage := editParams["reimage"]; doReimage {
+		handleReimageReservation(w, r)
+		return
+	}
+	_, doShare := editParams["share"]
+	_, doRevokeShare := editParams["revokeShare"]
+	if doShare || doRevokeShare {
+		handleShareReservation(w, r)
+		return
+	}
+
 	dbAccess.Lock()
-	defer dbAccess.Unlock()
 
-	editParams := getBodyFromContext(r)
 	clog := hlog.FromRequest(r)
 	actionPrefix := "update reservation"
 	ps := httprouter.ParamsFromContext(r.Context())
 	resName := ps.ByName("resName")
 	rb := common.NewResponseBody()
 
-	status, err := doUpdateReservation(resName, editParams, r)
+	var msg string
+	var status int
+	var err error
+	_, doPause := editParams["pause"]
+	_, doResume := editParams["resume"]
+	_, doClaim := editParams["claim"]
+	_, doApprove := editParams["approve"]
+	denyReason, doDeny := editParams["deny"].(string)
+
+	if doPause {
+		actionPrefix = "pause reservation"
+		status, err = doPauseReservation(resName, editParams, r)
+	} else if doResume {
+		actionPrefix = "resume reservation"
+		msg, status, err = doResumeReservation(resName, editParams, r)
+	} else if doClaim {
+		actionPrefix = "claim reservation"
+		msg, status, err = doClaimReservation(resName, r)
+	} else if doApprove {
+		actionPrefix = "approve reservation"
+		reason, _ := editParams["reason"].(string)
+		msg, status, err = doApproveReservation(resName, reason, r)
+	} else if doDeny {
+		actionPrefix = "deny reservation"
+		status, err = doDenyReservation(resName, denyReason, r)
+	} else {
+		msg, status, err = doUpdateReservation(resName, editParams, r)
+	}
+	dbAccess.Unlock()
+
+	// a resumed or approved reservation can be installed right away
+	if err == nil && (doResume || doApprove) {
+		now := time.Now()
+		if mrErr := manageReservations(&now, installReservations); mrErr != nil {
+			clog.Error().Msgf("%v", mrErr)
+		}
+	}
+
+	// a cycle asked for with a profile change doesn't wait for the next manager run
+	if cycleNow, _ := editParams["cycleNow"].(bool); err == nil && cycleNow {
+		now := time.Now()
+		if mrErr := manageReservations(&now, cycleReservations); mrErr != nil {
+			clog.Error().Msgf("%v", mrErr)
+		}
+	}
 
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
+		rb.Message = msg
+		if _, ok := editParams["kernelArgs"]; ok {
+			rb.Data["kernelLine"] = resKernelLine(resName, editParams)
+		}
 		clog.Info().Msgf("%s success - '%s' updated", actionPrefix, resName)
 	}
 
 	makeJsonResponse(w, status, rb)
 }
 
+// resKernelLine reads back the kernel line of a reservation after its kernel args were edited.
+func resKernelLine(resName string, editParams map[string]interface{}) string {
+	if newName, ok := editParams["name"].(string); ok {
+		resName = newName
+	}
+	if resList, err := dbReadReservationsTx(map[string]interface{}{"name": resName}, nil); err == nil && len(resList) > 0 {
+		return resList[0].getKernelArgs()
+	}
+	return ""
+}
+
+// handleReimageReservation handles the reimage form of a reservation update. It manages its own
+// db locking so that writing PXE files and cycling hosts doesn't hold up other requests.
+func handleReimageReservation(w http.ResponseWriter, r *http.Request) {
+
+	editParams := getBodyFromContext(r)
+	clog := hlog.FromRequest(r)
+	actionPrefix := "reimage reservation"
+	ps := httprouter.ParamsFromContext(r.Context())
+	resName := ps.ByName("resName")
+	rb := common.NewResponseBodyReimage()
+
+	results, msg, status, err := doReimageReservation(resName, editParams, r)
+
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["hosts"] = results
+		rb.Message = msg
+		clog.Info().Msgf("%s success - '%s' reimaged", actionPrefix, resName)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// destination for GET /reservations/:resName
+func handleReadResDelete(w http.ResponseWriter, r *http.Request) {
+
+	ps := httprouter.ParamsFromContext(r.Context())
+	resName := ps.ByName("resName")
+	clog := hlog.FromRequest(r)
+	actionPrefix := "read reservation delete summary"
+	rb := common.NewResponseBodyResDelete()
+
+	summary, status, err := doReadResDelete(resName, getUserFromContext(r))
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["reservation"] = *summary
+		clog.Debug().Msgf("%s success", actionPrefix)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
 func handleDeleteReservations(w http.ResponseWriter, r *http.Request) {
 
 	dbAccess.Lock()
@@ -136,131 +325,15 @@ func validateResvParams(handler http.Handler) http.Handler {
 				_, nl := resParams["nodeList"]
 				_, nc := resParams["nodeCount"]
 				_, name := resParams["name"]
-				_, profile := resParams["profile"]
-				_, distro := resParams["distro"]
-				if !name {
+				_, quick := resParams["quick"]
+				if quick {
+					validateErr = validateQuickResParams(resParams)
+				} else if !name {
 					validateErr = fmt.Errorf("missing reservation name (required)")
 				} else if !nl && !nc {
 					validateErr = fmt.Errorf("missing nodeList or nodeCount; one required to create reservation")
-				} else if nl && nc {
-					validateErr = fmt.Errorf("both nodeList and nodeCount found; only one allowed")
-				} else if !distro && !profile {
-					validateErr = fmt.Errorf("missing profile or distro; one required to create reservation")
-				} else if distro && profile {
-					validateErr = fmt.Errorf("both profile and distro found; only one allowed")
 				} else {
-
-				postPutParamLoop:
-					for key, val := range resParams {
-						switch strings.TrimSpace(key) {
-						case "name":
-							if resName, ok := val.(string); !ok {
-								validateErr = NewBadParamTypeError(key, val, "string")
-								break postPutParamLoop
-							} else if validateErr = checkGenericNameRules(resName); validateErr != nil {
-								break postPutParamLoop
-							}
-						case "description":
-							if d, ok := val.(string); !ok {
-								validateErr = NewBadParamTypeError(key, val, "string")
-								break postPutParamLoop
-							} else if validateErr = checkDesc(d); validateErr != nil {
-								break postPutParamLoop
-							}
-						case "distro":
-							if distroName, ok := val.(string); !ok {
-								validateErr = NewBadParamTypeError(key, val, "string")
-								break postPutParamLoop
-							} else if validateErr = checkDistroNameRules(distroName); validateErr != nil {
-								break postPutParamLoop
-							}
-						case "owner":
-							if owner, ok := val.(string); !ok {
-								validateErr = NewBadParamTypeError(key, val, "string")
-								break postPutParamLoop
-							} else if validateErr = checkUsernameRules(owner); validateErr != nil {
-								break postPutParamLoop
-							}
-						case "profile":
-							if profileName, ok := val.(string); !ok {
-								validateErr = NewBadParamTypeError(key, val, "string")
-								break postPutParamLoop
-							} else if validateErr = checkProfileNameRules(profileName); validateErr != nil {
-								break postPutParamLoop
-							}
-						case "group":
-							if grName, ok := val.(string); !ok {
-								validateErr = NewBadParamTypeError(key, val, "string")
-								break postPutParamLoop
-							} else if validateErr = checkGroupNameRules(grName); validateErr != nil {
-								break postPutParamLoop
-							} else if grName == GroupAll {
-								validateErr = fmt.Errorf("reservations cannot be assigned to the 'all' group")
-								break postPutParamLoop
-							}
-						case "noCycle":
-							if _, ok := val.(bool); !ok {
-								validateErr = NewBadParamTypeError(key, val, "bool")
-								break postPutParamLoop
-							}
-						case "vlan":
-							if _, ok := val.(string); !ok {
-								validateErr = NewBadParamTypeError(key, val, "string")
-								break postPutParamLoop
-							}
-						case "nodeList":
-							if thisNodeList, ok := val.(string); !ok {
-								validateErr = NewBadParamTypeError(key, val, "string")
-								break postPutParamLoop
-							} else {
-								if strings.TrimSpace(thisNodeList) != "" {
-									hostNames := igor.splitRange(thisNodeList)
-									if len(hostNames) == 0 {
-										validateErr = fmt.Errorf("couldn't parse node specification %v", thisNodeList)
-										break postPutParamLoop
-									}
-								} else {
-									validateErr = fmt.Errorf("at least 1 host name required to create reservation")
-									break postPutParamLoop
-								}
-							}
-						case "nodeCount":
-							if _, ok := resParams["nodeCount"].(float64); !ok {
-								validateErr = NewBadParamTypeError(key, val, "float64")
-								break postPutParamLoop
-							}
-						case "duration":
-							sDur, sOk := val.(string)
-							_, fOk := val.(float64)
-							if !sOk && !fOk {
-								validateErr = NewBadParamTypeError(key, val, "string | float64")
-								break postPutParamLoop
-							} else if sOk {
-								dur, err := common.ParseDuration(sDur)
-								if err != nil {
-									validateErr = fmt.Errorf("'%s' is not a recognized duration interval", sDur)
-									break postPutParamLoop
-								}
-								if dur <= 0 {
-									validateErr = fmt.Errorf("duration expression '%s' cannot be a negative value", sDur)
-								}
-							}
-						case "start":
-							if _, ok := val.(float64); !ok {
-								validateErr = NewBadParamTypeError(key, val, "float64")
-								break postPutParamLoop
-							}
-						case "kernelArgs":
-							_, ok := val.(string)
-							if !ok {
-								validateErr = NewBadParamTypeError(key, val, "string")
-								break postPutParamLoop
-							}
-						default:
-							validateErr = NewUnknownParamError(key, val)
-							break postPutParamLoop
-						}
-					}
+					validateErr = checkResvParamValues(resParams, r)
 				}
 			} else {
 				validateErr = NewMissingParamError("")
@@ -284,7 +357,7 @@ func validateResvParams(handler http.Handler) http.Handler {
 				case "name":
 					for _, resvName := range vals {
 						resvName = strings.TrimSpace(resvName)
-						if validateErr = checkGenericNameRules(resvName); validateErr != nil {
+						if validateErr = checkResNameRules(resvName); validateErr != nil {
 							break queryParamLoop
 						}
 					}
@@ -316,6 +389,10 @@ func validateResvParams(handler http.Handler) http.Handler {
 							break queryParamLoop
 						}
 					}
+				case "to-end":
+					if validateErr = checkTimeParam(key, vals); validateErr != nil {
+						break queryParamLoop
+					}
 				// case "from-start", "from-end", "to-start", "to-end":
 				// 	if err := common.ValidateTimeFormat(vals[0]); err != nil {
 				// 		validateErr = fmt.Errorf("parameter '%s' is not a recognized time format, found %s", key, vals[0])
@@ -354,10 +431,28 @@ func validateResvParams(handler http.Handler) http.Handler {
 				_, doDistro := resParams["distro"]
 				_, doProfile := resParams["profile"]
 				_, doDrop := resParams["drop"]
+				_, doPause := resParams["pause"]
+				_, doResume := resParams["resume"]
+				_, doReimage := resParams["reimage"]
+				_, doShare := resParams["share"]
+				_, doRevokeShare := resParams["revokeShare"]
+				_, doClaim := resParams["claim"]
+				_, doApprove := resParams["approve"]
+				_, doDeny := resParams["deny"]
+				_, doCycleNow := resParams["cycleNow"]
+				_, doCycleAt := resParams["cycleAt"]
+				_, doCancelCycle := resParams["cancelCycle"]
+				clampVal, doClamp := resParams["clampToLimit"]
 				// if doing an extend command, it must be the only thing updating
 				if doExtend || doExtendMax {
-					if len(resParams) != 1 {
+					extendParamCount := 1
+					if doClamp {
+						extendParamCount++
+					}
+					if len(resParams) != extendParamCount {
 						validateErr = fmt.Errorf("extending a reservation can only be a singluar edit; found %v", resParams)
+					} else if _, ok := clampVal.(bool); doClamp && !ok {
+						validateErr = NewBadParamTypeError("clampToLimit", clampVal, "bool")
 					} else if doExtend {
 						sDur, sOk := resParams["extend"].(string)
 						_, fOk := resParams["extend"].(float64)
@@ -373,6 +468,58 @@ func validateResvParams(handler http.Handler) http.Handler {
 							}
 						}
 					}
+				} else if doClamp {
+					validateErr = fmt.Errorf("clampToLimit can only be used when extending a reservation")
+				} else if doReimage {
+					validateErr = validateReimageParams(resParams)
+				} else if doShare || doRevokeShare {
+					if len(resParams) != 1 {
+						validateErr = fmt.Errorf("sharing a reservation or revoking its share links can only be a singular edit; found %v", resParams)
+					} else if _, ok := resParams["share"].(string); doShare && !ok {
+						validateErr = NewBadParamTypeError("share", resParams["share"], "string")
+					} else if shareID, ok := resParams["revokeShare"].(string); doRevokeShare && !ok {
+						validateErr = NewBadParamTypeError("revokeShare", resParams["revokeShare"], "string")
+					} else if doRevokeShare {
+						validateErr = checkShareIDRules(shareID)
+					}
+				} else if doClaim {
+					if len(resParams) != 1 {
+						validateErr = fmt.Errorf("claiming a reservation can only be a singular edit; found %v", resParams)
+					} else if claim, ok := resParams["claim"].(bool); !ok || !claim {
+						validateErr = NewBadParamTypeError("claim", resParams["claim"], "bool (true)")
+					}
+				} else if doApprove || doDeny {
+					reasonVal, doReason := resParams["reason"]
+					approvalParamCount := 1
+					if doReason {
+						approvalParamCount++
+					}
+					if len(resParams) != approvalParamCount || (doDeny && doReason) {
+						validateErr = fmt.Errorf("approving or denying a reservation can only be a singular edit; found %v", resParams)
+					} else if _, ok := reasonVal.(string); doReason && !ok {
+						validateErr = NewBadParamTypeError("reason", reasonVal, "string")
+					} else if approve, ok := resParams["approve"].(bool); doApprove && (!ok || !approve) {
+						validateErr = NewBadParamTypeError("approve", resParams["approve"], "bool (true)")
+					} else if reason, ok := resParams["deny"].(string); doDeny && !ok {
+						validateErr = NewBadParamTypeError("deny", resParams["deny"], "string")
+					} else if doDeny && strings.TrimSpace(reason) == "" {
+						validateErr = fmt.Errorf("a reason is required to deny a reservation")
+					}
+				} else if doPause || doResume {
+					subVal, doSub := resParams["substitute"]
+					pauseParamCount := 1
+					if doSub {
+						pauseParamCount++
+					}
+					if len(resParams) != pauseParamCount {
+						validateErr = fmt.Errorf("pausing or resuming a reservation can only be a singluar edit; found %v", resParams)
+					} else if _, ok := subVal.(bool); doSub && !ok {
+						validateErr = NewBadParamTypeError("substitute", subVal, "bool")
+					} else if _, ok = resParams["pause"].(float64); doPause && !ok {
+						validateErr = NewBadParamTypeError("pause", resParams["pause"], "float64")
+					} else if resume, ok := resParams["resume"].(bool); doResume && (!ok || !resume) {
+						validateErr = NewBadParamTypeError("resume", resParams["resume"], "bool (true)")
+					}
 				} else if doDrop {
 					if len(resParams) != 1 {
 						validateErr = fmt.Errorf("dropping nodes from a reservation can only be a singluar edit; found %v", resParams)
@@ -391,32 +538,14 @@ func validateResvParams(handler http.Handler) http.Handler {
 						}
 					}
 				} else if doDistro || doProfile {
-					if len(resParams) == 1 && (doDistro || doProfile) {
-						for key, val := range resParams {
-							switch key {
-							case "distro":
-								if distro, ok := val.(string); !ok {
-									validateErr = NewBadParamTypeError(key, val, "string")
-									break
-								} else if validateErr = checkDistroNameRules(distro); validateErr != nil {
-									break
-								}
-							case "profile":
-								if profile, ok := val.(string); !ok {
-									validateErr = NewBadParamTypeError(key, val, "string")
-									break
-								} else if validateErr = checkProfileNameRules(profile); validateErr != nil {
-									break
-								}
-							default:
-								validateErr = NewUnknownParamError(key, val)
-								break
-							}
-						}
-					} else if len(resParams) == 2 && doDistro && doProfile {
-						validateErr = fmt.Errorf("both profile and distro params found; only one allowed")
-					} else {
-						validateErr = fmt.Errorf("distro and profile changes cannot be mixed with other reservation changes; found %v", resParams)
+					validateErr = validateImageEditParams(resParams)
+				} else if doCycleNow || doCycleAt {
+					validateErr = fmt.Errorf("power cycling the hosts can only be requested along with a profile or distro change")
+				} else if doCancelCycle {
+					if len(resParams) != 1 {
+						validateErr = fmt.Errorf("canceling a scheduled power cycle can only be a singular edit; found %v", resParams)
+					} else if cancel, ok := resParams["cancelCycle"].(bool); !ok || !cancel {
+						validateErr = NewBadParamTypeError("cancelCycle", resParams["cancelCycle"], "bool (true)")
 					}
 				} else {
 				patchParamLoop:
@@ -426,14 +555,14 @@ func validateResvParams(handler http.Handler) http.Handler {
 							if name, ok := val.(string); !ok {
 								validateErr = NewBadParamTypeError(key, val, "string")
 								break patchParamLoop
-							} else if validateErr = checkGenericNameRules(name); validateErr != nil {
+							} else if validateErr = checkResNameRules(name); validateErr != nil {
 								break patchParamLoop
 							}
 						case "description":
 							if desc, ok := val.(string); !ok {
 								validateErr = NewBadParamTypeError(key, val, "string")
 								break patchParamLoop
-							} else if validateErr = checkDesc(desc); validateErr != nil {
+							} else if validateErr = checkResDesc(desc); validateErr != nil {
 								break patchParamLoop
 							}
 						case "owner":
@@ -457,10 +586,92 @@ func validateResvParams(handler http.Handler) http.Handler {
 								break patchParamLoop
 							}
 						case "kernelArgs":
-							_, ok := val.(string)
-							if !ok {
+							if kArgs, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if validateErr = checkRequestKernelArgs(kArgs, r); validateErr != nil {
+								break patchParamLoop
+							}
+						case "addCoOwners", "rmvCoOwners":
+							coOwners, ok := val.([]interface{})
+							if !ok || len(coOwners) == 0 {
+								validateErr = NewBadParamTypeError(key, val, "[]string")
+								break patchParamLoop
+							}
+							for _, v := range coOwners {
+								if coOwner, ok := v.(string); !ok {
+									validateErr = NewBadParamTypeError(key, val, "[]string")
+									break patchParamLoop
+								} else if validateErr = checkUsernameRules(coOwner); validateErr != nil {
+									break patchParamLoop
+								}
+							}
+						case "notifyAlso":
+							names, ok := val.([]interface{})
+							if !ok || len(names) == 0 {
+								validateErr = NewBadParamTypeError(key, val, "[]string")
+								break patchParamLoop
+							}
+							for _, v := range names {
+								if name, ok := v.(string); !ok {
+									validateErr = NewBadParamTypeError(key, val, "[]string")
+									break patchParamLoop
+								} else if name != GroupNoneAlias {
+									if validateErr = checkUsernameRules(name); validateErr != nil {
+										break patchParamLoop
+									}
+								}
+							}
+						case "keep":
+							if _, ok := val.(bool); !ok {
+								validateErr = NewBadParamTypeError(key, val, "bool")
+								break patchParamLoop
+							}
+						case "endPower":
+							if endPower, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if validateErr = checkEndPower(endPower); validateErr != nil {
+								break patchParamLoop
+							}
+						case "data":
+							if data, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if _, validateErr = decodeResData(data); validateErr != nil {
+								break patchParamLoop
+							}
+						case "head":
+							if head, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if strings.TrimSpace(head) == "" {
+								validateErr = fmt.Errorf("a host name is required to set the head host")
+								break patchParamLoop
+							}
+						case "vlan":
+							if vlan, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if strings.TrimSpace(vlan) == "" {
+								validateErr = fmt.Errorf("vlan specified in reservation parameters, but no value included")
+								break patchParamLoop
+							}
+						case "netProfile":
+							if netProfile, ok := val.(string); !ok {
 								validateErr = NewBadParamTypeError(key, val, "string")
 								break patchParamLoop
+							} else if strings.TrimSpace(netProfile) == "" {
+								validateErr = fmt.Errorf("netProfile specified in reservation parameters, but no value included")
+								break patchParamLoop
+							}
+						case "keepCoOwners":
+							if _, ok := val.(bool); !ok {
+								validateErr = NewBadParamTypeError(key, val, "bool")
+								break patchParamLoop
+							} else if _, ok = resParams["owner"]; !ok {
+								validateErr = fmt.Errorf("keepCoOwners can only be used when changing the reservation owner")
+								break patchParamLoop
 							}
 						default:
 							validateErr = NewUnknownParamError(key, val)
@@ -482,3 +693,164 @@ func validateResvParams(handler http.Handler) http.Handler {
 		handler.ServeHTTP(w, r)
 	})
 }
+
+// checkTimeParam makes sure a time search parameter has a single value in the compact datetime format.
+// Times given as search parameters are UTC.
+func checkTimeParam(key string, vals []string) error {
+	if len(vals) > 1 {
+		return fmt.Errorf("invalid parameter: '%s' cannot have multiple values", key)
+	}
+	if _, err := time.Parse(common.DateTimeCompactFormat, vals[0]); err != nil {
+		return fmt.Errorf("parameter '%s' is not a recognized time format, found %s", key, vals[0])
+	}
+	return nil
+}
+
+// checkResvParamValues checks the type and form of each parameter of a reservation create request
+// and that no two parameters conflict. It doesn't check that the required parameters are present.
+// Kernel args are checked against the banned args for the user making request r.
+func checkResvParamValues(resParams map[string]interface{}, r *http.Request) (validateErr error) {
+
+	_, nl := resParams["nodeList"]
+	_, nc := resParams["nodeCount"]
+	_, profile := resParams["profile"]
+	_, distro := resParams["distro"]
+	asap, _ := resParams["asap"].(bool)
+	if nl && nc {
+		return fmt.Errorf("both nodeList and nodeCount found; only one allowed")
+	} else if asap && !nl {
+		return fmt.Errorf("asap can only be used when the hosts are named with nodeList")
+	} else if distro && profile {
+		return fmt.Errorf("both profile and distro found; only one allowed")
+	}
+
+postPutParamLoop:
+	for key, val := range resParams {
+		switch strings.TrimSpace(key) {
+		case "name":
+			if resName, ok := val.(string); !ok {
+				validateErr = NewBadParamTypeError(key, val, "string")
+				break postPutParamLoop
+			} else if validateErr = checkResNameRules(resName); validateErr != nil {
+				break postPutParamLoop
+			}
+		case "description":
+			if d, ok := val.(string); !ok {
+				validateErr = NewBadParamTypeError(key, val, "string")
+				break postPutParamLoop
+			} else if validateErr = checkResDesc(d); validateErr != nil {
+				break postPutParamLoop
+			}
+		case "distro":
+			if distroName, ok := val.(string); !ok {
+				validateErr = NewBadParamTypeError(key, val, "string")
+				break postPutParamLoop
+			} else if validateErr = checkDistroNameRules(distroName); validateErr != nil {
+				break postPutParamLoop
+			}
+		case "owner":
+			if owner, ok := val.(string); !ok {
+				validateErr = NewBadParamTypeError(key, val, "string")
+				break postPutParamLoop
+			} else if validateErr = checkUsernameRules(owner); validateErr != nil {
+				break postPutParamLoop
+			}
+		case "profile":
+			if profileName, ok := val.(string); !ok {
+				validateErr = NewBadParamTypeError(key, val, "string")
+				break postPutParamLoop
+			} else if validateErr = checkProfileNameRules(profileName); validateErr != nil {
+				break postPutParamLoop
+			}
+		case "group":
+			if grName, ok := val.(string); !ok {
+				validateErr = NewBadParamTypeError(key, val, "string")
+				break postPutParamLoop
+			} else if validateErr = checkGroupNameRules(grName); validateErr != nil {
+				break postPutParamLoop
+			} else if grName == GroupAll {
+				validateErr = fmt.Errorf("reservations cannot be assigned to the 'all' group")
+				break postPutParamLoop
+			}
+		case "noCycle", "clampToLimit", "grantAccess", "noDefaultKernelArgs", "asap":
+			if _, ok := val.(bool); !ok {
+				validateErr = NewBadParamTypeError(key, val, "bool")
+				break postPutParamLoop
+			}
+		case "vlan":
+			if _, ok := val.(string); !ok {
+				validateErr = NewBadParamTypeError(key, val, "string")
+				break postPutParamLoop
+			}
+		case "nodeList":
+			if thisNodeList, ok := val.(string); !ok {
+				validateErr = NewBadParamTypeError(key, val, "string")
+				break postPutParamLoop
+			} else {
+				if strings.TrimSpace(thisNodeList) != "" {
+					hostNames := igor.splitRange(thisNodeList)
+					if len(hostNames) == 0 {
+						validateErr = fmt.Errorf("couldn't parse node specification %v", thisNodeList)
+						break postPutParamLoop
+					}
+				} else {
+					validateErr = fmt.Errorf("at least 1 host name required to create reservation")
+					break postPutParamLoop
+				}
+			}
+		case "nodeCount":
+			if _, ok := resParams["nodeCount"].(float64); !ok {
+				validateErr = NewBadParamTypeError(key, val, "float64")
+				break postPutParamLoop
+			}
+		case "endPower":
+			if endPower, ok := val.(string); !ok {
+				validateErr = NewBadParamTypeError(key, val, "string")
+				break postPutParamLoop
+			} else if validateErr = checkEndPower(endPower); validateErr != nil {
+				break postPutParamLoop
+			}
+		case "minNodes":
+			if mn, ok := val.(float64); !ok {
+				validateErr = NewBadParamTypeError(key, val, "float64")
+				break postPutParamLoop
+			} else if mn < 1 || mn != float64(int(mn)) {
+				validateErr = fmt.Errorf("minNodes must be a whole number of at least 1")
+				break postPutParamLoop
+			}
+		case "duration":
+			sDur, sOk := val.(string)
+			_, fOk := val.(float64)
+			if !sOk && !fOk {
+				validateErr = NewBadParamTypeError(key, val, "string | float64")
+				break postPutParamLoop
+			} else if sOk {
+				dur, err := common.ParseDuration(sDur)
+				if err != nil {
+					validateErr = fmt.Errorf("'%s' is not a recognized duration interval", sDur)
+					break postPutParamLoop
+				}
+				if dur <= 0 {
+					validateErr = fmt.Errorf("duration expression '%s' cannot be a negative value", sDur)
+				}
+			}
+		case "start":
+			if _, ok := val.(float64); !ok {
+				validateErr = NewBadParamTypeError(key, val, "float64")
+				break postPutParamLoop
+			}
+		case "kernelArgs":
+			if kArgs, ok := val.(string); !ok {
+				validateErr = NewBadParamTypeError(key, val, "string")
+				break postPutParamLoop
+			} else if validateErr = checkRequestKernelArgs(kArgs, r); validateErr != nil {
+				break postPutParamLoop
+			}
+		default:
+			validateErr = NewUnknownParamError(key, val)
+			break postPutParamLoop
+		}
+	}
+
+	return validateErr
+}
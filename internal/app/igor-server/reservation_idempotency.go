@@ -0,0 +1,112 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// IdempotencyRecord remembers the outcome of a reservation create request that was sent with an
+// Idempotency-Key header so a retry of the same request can be answered without creating a
+// second reservation. Keys are scoped to the user that sent them.
+type IdempotencyRecord struct {
+	Base
+	Key      string `gorm:"notNull; uniqueIndex:idx_idempotency_user_key"`
+	UserID   int    `gorm:"notNull; uniqueIndex:idx_idempotency_user_key"`
+	BodyHash string `gorm:"notNull"`
+	ResID    int
+	Status   int
+	Response []byte
+}
+
+// hashRequestBody returns a digest of the request body params used to tell whether a retry with an
+// idempotency key is identical to the original request. The json encoding of a map is ordered by key
+// so the same params always produce the same hash.
+func hashRequestBody(params map[string]interface{}) string {
+	sum := sha256.Sum256(marshalJSONBody(params))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkIdempotencyKey looks for an earlier request from the user with the same key. If one is found
+// and the request body is the same its record is returned so the original response can be sent
+// again. A match on the key with a different body is a conflict.
+func checkIdempotencyKey(key, bodyHash string, user *User) (*IdempotencyRecord, int, error) {
+
+	var records []IdempotencyRecord
+	if err := performDbTx(func(tx *gorm.DB) error {
+		return tx.Where(&IdempotencyRecord{Key: key, UserID: user.ID}).Find(&records).Error
+	}); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if len(records) == 0 {
+		return nil, http.StatusOK, nil
+	}
+
+	rec := &records[0]
+	if rec.BodyHash != bodyHash {
+		return nil, http.StatusConflict, fmt.Errorf("idempotency key '%s' was already used with a different request", key)
+	}
+	if len(rec.Response) == 0 {
+		return nil, http.StatusConflict, fmt.Errorf("the original request with idempotency key '%s' is still being processed", key)
+	}
+	return rec, http.StatusOK, nil
+}
+
+// dbCreateIdempotencyRecord saves the key of a successful create request. It is called while
+// holding the db lock so a retry can't slip in before the key is recorded.
+func dbCreateIdempotencyRecord(rec *IdempotencyRecord) error {
+	return performDbTx(func(tx *gorm.DB) error {
+		return tx.Create(rec).Error
+	})
+}
+
+// dbSaveIdempotencyResponse stores the response sent for the request that created the record. It
+// is called while holding the db lock.
+func dbSaveIdempotencyResponse(rec *IdempotencyRecord, response []byte) error {
+	return performDbTx(func(tx *gorm.DB) error {
+		return tx.Model(rec).Update("response", response).Error
+	})
+}
+
+// replayIdempotentResponse writes the response saved for the original request.
+func replayIdempotentResponse(w http.ResponseWriter, rec *IdempotencyRecord) {
+	w.Header().Set(common.ContentType, common.MAppJson)
+	w.WriteHeader(rec.Status)
+	if _, err := w.Write(rec.Response); err != nil {
+		panic(err)
+	}
+}
+
+// purgeIdempotencyRecords removes keys older than server.idempotencyHours. It runs as part of
+// the reservation manager.
+func purgeIdempotencyRecords(checkTime *time.Time) error {
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	cutoff := checkTime.Add(-time.Duration(igor.Server.IdempotencyHours) * time.Hour)
+	var purged int64
+	if err := performDbTx(func(tx *gorm.DB) error {
+		result := tx.Where("created_at < ?", cutoff).Delete(&IdempotencyRecord{})
+		purged = result.RowsAffected
+		return result.Error
+	}); err != nil {
+		return fmt.Errorf("problem removing expired idempotency keys: %v", err)
+	}
+
+	if purged > 0 {
+		logger.Debug().Msgf("removed %d expired idempotency key(s)", purged)
+	}
+	return nil
+}
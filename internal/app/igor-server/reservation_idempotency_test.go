@@ -0,0 +1,144 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	zl "github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestIdempotentCreate(t *testing.T) {
+
+	origSched, origSchedMinutes, origNotify, origRefs := igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn, igor.ClusterRefs
+	t.Cleanup(func() {
+		igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn, igor.ClusterRefs = origSched, origSchedMinutes, origNotify, origRefs
+	})
+	notifyOff := false
+	igor.Email.ResNotifyOn = &notifyOff
+	igor.Scheduler.MinReserveTime = 30
+	igor.Scheduler.DefaultReserveTime = 60
+	igor.Scheduler.MaxReserveTime = 7 * 24 * 60
+	igor.Scheduler.NodeReserveLimit = 0
+	MaxScheduleMinutes = 45 * 24 * 60
+	refs, _ := common.NewRange("kn", 1, 10)
+	igor.ClusterRefs = []common.Range{*refs}
+
+	db := newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db"))
+	seedTestHosts(t, db)
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	newUser := func(name string) *User {
+		pug := Group{Name: GroupUserPrefix + name, IsUserPrivate: true}
+		require.NoError(t, db.Omit(clause.Associations).Create(&pug).Error)
+		u := User{Name: name, Email: name + "@example.com", Groups: []Group{pug, all}}
+		require.NoError(t, db.Omit("Groups.*").Create(&u).Error)
+		return &u
+	}
+	alice, bob := newUser("alice"), newUser("bob")
+	distro := Distro{Name: "centos", Groups: []Group{all}}
+	require.NoError(t, db.Omit("Groups.*").Create(&distro).Error)
+
+	start := float64(time.Now().Add(time.Hour).Unix())
+	params := func(name, node string) map[string]interface{} {
+		return map[string]interface{}{"name": name, "distro": "centos", "nodeList": node, "duration": "2h", "start": start}
+	}
+	var logBuf bytes.Buffer
+	testLog := zl.New(&logBuf)
+	create := func(user *User, key string, body map[string]interface{}) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set(common.IdempotencyHeader, key)
+		ctx := context.WithValue(testLog.WithContext(r.Context()), userContextKey{}, user)
+		r = r.WithContext(context.WithValue(ctx, jsonBodyKey{}, body))
+		w := httptest.NewRecorder()
+		handleCreateReservations(w, r)
+		return w
+	}
+	resCount := func() int64 {
+		var count int64
+		require.NoError(t, db.Model(&Reservation{}).Count(&count).Error)
+		return count
+	}
+
+	// a retry of a successful create gets the original response without a second reservation
+	first := create(alice, "k1", params("exp", "kn1"))
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	retry := create(alice, "k1", params("exp", "kn1"))
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.EqualValues(t, 1, resCount())
+
+	// reusing the key for a different request is refused
+	w := create(alice, "k1", params("other", "kn2"))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "different request")
+
+	// a retry while the first request with the key is still being processed is refused
+	require.NoError(t, dbCreateIdempotencyRecord(&IdempotencyRecord{Key: "k2", UserID: alice.ID,
+		BodyHash: hashRequestBody(params("slow", "kn2")), Status: http.StatusCreated}))
+	w = create(alice, "k2", params("slow", "kn2"))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "still being processed")
+	assert.EqualValues(t, 1, resCount())
+
+	// a failed create only logs its failure and leaves nothing behind for the key
+	logBuf.Reset()
+	failed := params("broken", "kn2")
+	failed["distro"] = "missing"
+	w = create(alice, "k3", failed)
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	assert.NotContains(t, logBuf.String(), `"level":"error"`)
+	assert.NotContains(t, logBuf.String(), "idempotency")
+	var failedKeys int64
+	require.NoError(t, db.Model(&IdempotencyRecord{}).Where("key = ?", "k3").Count(&failedKeys).Error)
+	assert.Zero(t, failedKeys)
+
+	// keys belong to the user that sent them, so bob's k1 is a new request
+	w = create(bob, "k1", params("bob-exp", "kn2"))
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.EqualValues(t, 2, resCount())
+	var keys int64
+	require.NoError(t, db.Model(&IdempotencyRecord{}).Where("key = ?", "k1").Count(&keys).Error)
+	assert.EqualValues(t, 2, keys)
+}
+
+func TestPurgeIdempotencyRecords(t *testing.T) {
+
+	origHours := igor.Server.IdempotencyHours
+	t.Cleanup(func() { igor.Server.IdempotencyHours = origHours })
+	igor.Server.IdempotencyHours = 24
+
+	db := newTestDb(t)
+	now := time.Now()
+	require.NoError(t, db.Create(&IdempotencyRecord{Base: Base{CreatedAt: now.Add(-25 * time.Hour)}, Key: "old", UserID: 1, BodyHash: "h"}).Error)
+	require.NoError(t, db.Create(&IdempotencyRecord{Base: Base{CreatedAt: now.Add(-time.Hour)}, Key: "new", UserID: 1, BodyHash: "h"}).Error)
+
+	// the reservation manager's task removes keys past server.idempotencyHours
+	var purge *managerTask
+	for _, task := range newManagerTasks() {
+		if task.name == "purgeIdempotencyRecords" {
+			purge = task
+		}
+	}
+	require.NotNil(t, purge)
+	require.NoError(t, purge.run(&now))
+
+	var left []IdempotencyRecord
+	require.NoError(t, db.Find(&left).Error)
+	require.Len(t, left, 1)
+	assert.Equal(t, "new", left[0].Key)
+}
@@ -0,0 +1,165 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"time"
+
+	zl "github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// idleResEndDelay is how long an idle reservation is given to run once it has been shortened.
+const idleResEndDelay = time.Hour
+
+// isIdle returns true if the reservation has been installed for at least idleLimit without any of its
+// hosts being powered on by a user. Reservations owned by igor-admin or that have been acknowledged
+// with keep are never considered idle.
+func (r *Reservation) isIdle(now time.Time, idleLimit time.Duration) bool {
+	if !r.Installed || r.KeepIdle || r.Owner.Name == IgorAdmin || !r.LastPowerOn.IsZero() {
+		return false
+	}
+	return now.Sub(r.Start) >= idleLimit
+}
+
+// checkIdleReservations applies the idle reservation policy. The owner of an idle reservation is sent
+// a warning, and if the reservation is still idle once the grace period after the warning has passed
+// its end time is moved up so it expires through the normal closeout process.
+func checkIdleReservations(checkTime *time.Time) error {
+
+	if igor.Scheduler.IdleResDays <= 0 {
+		return nil
+	}
+
+	idleLimit := time.Duration(igor.Scheduler.IdleResDays) * 24 * time.Hour
+	grace := time.Duration(igor.Scheduler.IdleResGraceHours) * time.Hour
+	var warnEvents []*ResNotifyEvent
+
+	if err := func() error {
+
+		dbAccess.Lock()
+		defer dbAccess.Unlock()
+
+		resList, err := dbReadReservationsTx(map[string]interface{}{"installed": true}, nil)
+		if err != nil {
+			return err
+		}
+
+		clusters, cErr := dbReadClustersTx(nil)
+		if cErr != nil {
+			return cErr
+		}
+
+		for i := range resList {
+
+			r := &resList[i]
+			if !r.isIdle(*checkTime, idleLimit) {
+				continue
+			}
+
+			if r.IdleWarned.IsZero() {
+
+				changes := map[string]interface{}{"IdleWarned": *checkTime}
+				if eErr := performDbTx(func(tx *gorm.DB) error {
+					return dbEditReservation(r, changes, tx)
+				}); eErr != nil {
+					logger.Error().Msgf("problem recording idle warning for reservation '%s': %v", r.Name, eErr)
+					continue
+				}
+
+				shortenAt := checkTime.Add(grace)
+				logger.Warn().Msgf("reservation '%s' has no hosts powered on since it started %s - warning owner '%s' it will be shortened after %s",
+					r.Name, r.Start.Format(common.DateTimeLogFormat), r.Owner.Name, shortenAt.Format(common.DateTimeLogFormat))
+
+				if warnEvent := makeResWarnNotifyEvent(EmailResIdleWarn, 0, r.DeepCopy(), clusters[0].Name); warnEvent != nil {
+					warnEvent.Info = formatLocaleDts(r.Owner.Locale, shortenAt)
+					warnEvents = append(warnEvents, warnEvent)
+				}
+
+			} else if checkTime.Sub(r.IdleWarned) >= grace {
+
+				newEnd := checkTime.Add(idleResEndDelay).Truncate(time.Minute)
+				if !newEnd.Before(r.End) {
+					// already ending on its own
+					continue
+				}
+
+				changes := map[string]interface{}{
+					"End":        newEnd,
+					"ResetEnd":   determineNodeResetTime(newEnd),
+					"NextNotify": time.Duration(0),
+				}
+				// let the normal expiration warnings send a final notice before the res ends
+				if *igor.Email.ResNotifyOn && len(ResNotifyTimes) > 0 {
+					changes["NextNotify"] = ResNotifyTimes[0]
+				}
+
+				if eErr := performDbTx(func(tx *gorm.DB) error {
+					return dbEditReservation(r, changes, tx)
+				}); eErr != nil {
+					logger.Error().Msgf("problem shortening idle reservation '%s': %v", r.Name, eErr)
+					continue
+				}
+
+				logger.Warn().Msgf("idle reservation '%s' owned by '%s' was not acknowledged after warning at %s - end time moved from %s to %s",
+					r.Name, r.Owner.Name, r.IdleWarned.Format(common.DateTimeLogFormat), r.End.Format(common.DateTimeLogFormat), newEnd.Format(common.DateTimeLogFormat))
+
+				r.End = newEnd
+				if hErr := r.HistCallback(r, HrUpdated+":idle-shorten"); hErr != nil {
+					logger.Error().Msgf("failed to record reservation '%s' idle shortening to history", r.Name)
+				}
+			}
+		}
+
+		return nil
+
+	}(); err != nil {
+		return err
+	}
+
+	// sent after releasing the db lock since processing a notification may need it
+	for _, e := range warnEvents {
+		resNotifyChan <- *e
+	}
+
+	return nil
+}
+
+// recordResPowerOn notes the time that a user powered on or cycled hosts belonging to installed
+// reservations. A reservation with a recorded power-on is no longer subject to the idle policy.
+func recordResPowerOn(hostNames []string, clog *zl.Logger) {
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	resList, err := dbReadReservationsTx(map[string]interface{}{"installed": true}, nil)
+	if err != nil {
+		clog.Error().Msgf("problem reading reservations to record power-on of %v: %v", hostNames, err)
+		return
+	}
+
+	poweredHosts := common.NewSet()
+	poweredHosts.Add(hostNames...)
+	now := time.Now()
+
+	for i := range resList {
+		r := &resList[i]
+		for _, h := range r.Hosts {
+			if !poweredHosts.Contains(h.HostName) {
+				continue
+			}
+			if eErr := performDbTx(func(tx *gorm.DB) error {
+				return dbEditReservation(r, map[string]interface{}{"LastPowerOn": now}, tx)
+			}); eErr != nil {
+				clog.Error().Msgf("problem recording power-on for reservation '%s': %v", r.Name, eErr)
+			} else if !r.IdleWarned.IsZero() && !r.KeepIdle {
+				clog.Info().Msgf("idle reservation '%s' had host(s) powered on after its idle warning - it will not be shortened", r.Name)
+			}
+			break
+		}
+	}
+}
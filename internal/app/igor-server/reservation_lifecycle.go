@@ -0,0 +1,95 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"sync"
+)
+
+// resLifecycle tracks the PXE installs running against a reservation and whether it is being torn down.
+type resLifecycle struct {
+	installs int
+	ending   bool
+}
+
+// Installs of a reservation's PXE files and power cycles don't always happen while dbAccess is held (a
+// reimage writes and cycles hosts after letting it go), so a delete or expiry can otherwise uninstall a
+// reservation in the middle of an install and leave the files written after it behind. Each install
+// registers here first and a teardown waits for those in progress to finish before it uninstalls. Once a
+// teardown has begun no new install of the reservation is allowed.
+var (
+	resLifecycleMU   sync.Mutex
+	resLifecycleCond = sync.NewCond(&resLifecycleMU)
+	resLifecycles    = map[int]*resLifecycle{}
+)
+
+// beginResInstall registers an install of the reservation. It returns false if the reservation is being
+// torn down, in which case nothing should be installed. Every true result must be matched by a call to
+// endResInstall.
+func beginResInstall(resID int) bool {
+
+	resLifecycleMU.Lock()
+	defer resLifecycleMU.Unlock()
+
+	rl, ok := resLifecycles[resID]
+	if !ok {
+		rl = &resLifecycle{}
+		resLifecycles[resID] = rl
+	}
+	if rl.ending {
+		return false
+	}
+	rl.installs++
+	return true
+}
+
+// endResInstall marks an install of the reservation as finished, waking any teardown waiting on it.
+func endResInstall(resID int) {
+
+	resLifecycleMU.Lock()
+	defer resLifecycleMU.Unlock()
+
+	if rl, ok := resLifecycles[resID]; ok {
+		rl.installs--
+		if rl.installs <= 0 && !rl.ending {
+			delete(resLifecycles, resID)
+		}
+	}
+	resLifecycleCond.Broadcast()
+}
+
+// beginResTeardown stops any new install of the reservation and waits for those in progress to finish.
+// It returns true if an install was in progress, meaning the reservation has files on its hosts that
+// must be uninstalled even if it wasn't otherwise thought to be active. It must be matched by a call to
+// endResTeardown.
+//
+// An install that is waited on must not need dbAccess to finish, since a teardown usually holds it.
+func beginResTeardown(resID int) (wasInstalling bool) {
+
+	resLifecycleMU.Lock()
+	defer resLifecycleMU.Unlock()
+
+	rl, ok := resLifecycles[resID]
+	if !ok {
+		rl = &resLifecycle{}
+		resLifecycles[resID] = rl
+	}
+	rl.ending = true
+	for rl.installs > 0 {
+		wasInstalling = true
+		resLifecycleCond.Wait()
+	}
+	return
+}
+
+// endResTeardown finishes a teardown of the reservation so it can be installed again if it still exists,
+// such as when the teardown failed or the reservation was only paused.
+func endResTeardown(resID int) {
+
+	resLifecycleMU.Lock()
+	defer resLifecycleMU.Unlock()
+
+	delete(resLifecycles, resID)
+}
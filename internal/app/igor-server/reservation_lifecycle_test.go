@@ -0,0 +1,134 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pxeInstaller is an IResInstaller that keeps track of the hosts it has written PXE files for. Installs
+// wait on release once they have started so a test can act while one is in progress.
+type pxeInstaller struct {
+	mu      sync.Mutex
+	files   map[string]string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (pi *pxeInstaller) Install(r *Reservation) error {
+	pi.started <- struct{}{}
+	<-pi.release
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	for _, h := range r.Hosts {
+		pi.files[h.Name] = r.Name
+	}
+	return nil
+}
+
+func (pi *pxeInstaller) Uninstall(r *Reservation) error {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	for _, h := range r.Hosts {
+		delete(pi.files, h.Name)
+	}
+	return nil
+}
+
+func (pi *pxeInstaller) fileCount() int {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	return len(pi.files)
+}
+
+func TestDeleteWaitsForInstall(t *testing.T) {
+
+	useSimulation(t)
+	setReimageTestRange(t)
+	origInstaller, origMaint, origSimHosts, origNotify := igor.IResInstaller, igor.Config.Maintenance, simHosts, igor.Email.ResNotifyOn
+	t.Cleanup(func() {
+		igor.IResInstaller, igor.Config.Maintenance, simHosts, igor.Email.ResNotifyOn = origInstaller, origMaint, origSimHosts, origNotify
+	})
+	installer := &pxeInstaller{files: map[string]string{}, started: make(chan struct{}), release: make(chan struct{})}
+	igor.IResInstaller = installer
+	igor.Config.Maintenance.HostMaintenanceDuration = 0
+	notifyOff := false
+	igor.Email.ResNotifyOn = &notifyOff
+
+	// the reimage records its history while the delete is writing, so transactions wait on the lock
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")+"?_busy_timeout=5000&_txlock=immediate"))
+	db := igor.IGormDb.GetDB()
+	require.NoError(t, db.Create(&Cluster{Name: "test", Prefix: "kn"}).Error)
+
+	res := newStartTestRes(t, db, "burnin", hosts[:1], false, 0)
+	ownerPerms, err := createResOwnerPerms(res.Name, false)
+	require.NoError(t, err)
+	ownerPerms[0].GroupID = res.GroupID
+	require.NoError(t, db.Create(&ownerPerms).Error)
+	require.NoError(t, db.Model(res).Updates(map[string]interface{}{"start": time.Now().Add(-time.Hour), "installed": true}).Error)
+	require.NoError(t, db.Model(&hosts[0]).Update("state", HostReserved).Error)
+	simHosts = map[string]*simHostPower{hosts[0].HostName: {on: true}}
+
+	var alice User
+	require.NoError(t, db.Preload("Groups").Where("name = ?", "alice").First(&alice).Error)
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, &alice))
+
+	// a reimage starts writing the hosts, which it does without holding dbAccess
+	reimageDone := make(chan error, 1)
+	go func() {
+		_, _, _, rErr := doReimageReservation(res.Name, map[string]interface{}{"reimage": true}, r)
+		reimageDone <- rErr
+	}()
+	<-installer.started
+
+	// the delete is made the way the handler makes it and has to wait for the reimage
+	deleteDone := make(chan error, 1)
+	go func() {
+		dbAccess.Lock()
+		defer dbAccess.Unlock()
+		_, dErr := doDeleteReservation(res.Name, r)
+		deleteDone <- dErr
+	}()
+	require.Eventually(t, func() bool {
+		resLifecycleMU.Lock()
+		defer resLifecycleMU.Unlock()
+		rl, ok := resLifecycles[res.ID]
+		return ok && rl.ending
+	}, time.Second, 5*time.Millisecond)
+	select {
+	case <-deleteDone:
+		t.Fatal("delete finished while the reservation was still being installed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// nothing else can start installing the reservation now
+	assert.False(t, beginResInstall(res.ID))
+
+	close(installer.release)
+	require.NoError(t, <-reimageDone)
+	require.NoError(t, <-deleteDone)
+
+	// the files the reimage wrote were removed after it finished and nothing is left tracked
+	assert.Zero(t, installer.fileCount())
+	var count int64
+	require.NoError(t, db.Model(&Reservation{}).Where("name = ?", res.Name).Count(&count).Error)
+	assert.Zero(t, count)
+	var host Host
+	require.NoError(t, db.First(&host, hosts[0].ID).Error)
+	assert.Equal(t, HostAvailable, host.State)
+	resLifecycleMU.Lock()
+	assert.Empty(t, resLifecycles)
+	resLifecycleMU.Unlock()
+}
@@ -35,6 +35,65 @@ func dbGetMaintenanceRes() (resList []MaintenanceRes, err error) {
 	return resList, err
 }
 
+// dbHostMaintenanceEnds returns when the maintenance period of each host in one ends, keyed by host
+// name. Only hosts that go back into the reservable pool afterward are included, since those can be
+// reserved from the end of it. If hostNames is given only those hosts are looked at.
+func dbHostMaintenanceEnds(hostNames []string, tx *gorm.DB) (map[string]time.Time, error) {
+
+	var rows []struct {
+		Name               string
+		MaintenanceEndTime time.Time
+	}
+	q := tx.Table("maintenance_res m").Select("h.name, m.maintenance_end_time").
+		Joins("INNER JOIN maintenanceres_hosts mh ON mh.maintenance_res_id = m.id").
+		Joins("INNER JOIN hosts h ON h.id = mh.host_id").
+		Where("h.state = ? AND h.restore_state IN ?", HostBlocked, schedulableHostStates)
+	if hostNames != nil {
+		q = q.Where("h.name IN ?", hostNames)
+	}
+	if result := q.Scan(&rows); result.Error != nil {
+		return nil, result.Error
+	}
+
+	ends := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		if end, ok := ends[row.Name]; !ok || row.MaintenanceEndTime.After(end) {
+			ends[row.Name] = row.MaintenanceEndTime
+		}
+	}
+	return ends, nil
+}
+
+// maintenanceEndString formats the end of a maintenance period for a message, leaving out the date
+// if it is today.
+func maintenanceEndString(end time.Time) string {
+	ey, em, ed := end.Date()
+	ny, nm, nd := time.Now().Date()
+	if ey == ny && em == nm && ed == nd {
+		return end.Format("15:04")
+	}
+	return end.Format("Jan 2 15:04")
+}
+
+// slotsAfterMaintenance moves the beginning of each open slot on a host in maintenance to the end of
+// it, given by maintEnds, and drops the slots that are then shorter than durNeeded.
+func slotsAfterMaintenance(slots []ReservationTimeSlot, maintEnds map[string]time.Time, durNeeded time.Duration) []ReservationTimeSlot {
+	if len(maintEnds) == 0 {
+		return slots
+	}
+	kept := slots[:0]
+	for _, s := range slots {
+		if end, ok := maintEnds[s.Hostname]; ok && s.AvailSlotBegin.Before(end) {
+			s.AvailSlotBegin = end
+			if s.AvailSlotEnd.Sub(s.AvailSlotBegin) < durNeeded {
+				continue
+			}
+		}
+		kept = append(kept, s)
+	}
+	return kept
+}
+
 // dbUpdateReservation sets Started to True.
 // func dbUpdateMaintenanceRes(mRes *MaintenanceRes) (err error) {
 // 	err = performDbTx(func(tx *gorm.DB) error {
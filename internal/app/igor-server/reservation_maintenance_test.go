@@ -0,0 +1,130 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newMaintenanceTestDb sets up the time limit test database with kn1 in a maintenance period
+// ending at maintEnd, after which it returns to restore.
+func newMaintenanceTestDb(t *testing.T, maintEnd time.Time, restore HostState) []Host {
+
+	hosts := seedTimeLimitHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	require.NoError(t, db.Model(&hosts[0]).Updates(map[string]interface{}{"state": HostBlocked, "restore_state": restore}).Error)
+	mRes := MaintenanceRes{ReservationName: "finished", MaintenanceEndTime: maintEnd, Hosts: []Host{hosts[0]}}
+	require.NoError(t, db.Omit("Hosts.*").Create(&mRes).Error)
+	return hosts
+}
+
+func TestCheckHostAvailableInMaintenance(t *testing.T) {
+
+	maintEnd := time.Now().Add(20 * time.Minute).Truncate(time.Minute)
+	newMaintenanceTestDb(t, maintEnd, HostAvailable)
+
+	check := func(hosts []string, start time.Time) (status int, err error) {
+		require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+			status, err = dbCheckHostAvailable(hosts, start, tx)
+			return nil
+		}))
+		return
+	}
+
+	// starting now collides with the maintenance period
+	status, err := check([]string{"kn1", "kn2"}, time.Now())
+	assert.Equal(t, http.StatusConflict, status)
+	require.Error(t, err)
+	assert.Equal(t, "kn1 is in maintenance until "+maintenanceEndString(maintEnd), err.Error())
+
+	// an hour from now it's back in the pool
+	status, err = check([]string{"kn1", "kn2"}, time.Now().Add(time.Hour))
+	assert.Equal(t, http.StatusOK, status)
+	assert.NoError(t, err)
+}
+
+func TestCheckHostAvailableMaintenanceStaysBlocked(t *testing.T) {
+
+	newMaintenanceTestDb(t, time.Now().Add(20*time.Minute), HostBlocked)
+
+	var status int
+	var err error
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		status, err = dbCheckHostAvailable([]string{"kn1"}, time.Now().Add(time.Hour), tx)
+		return nil
+	}))
+	assert.Equal(t, http.StatusConflict, status)
+	assert.ErrorContains(t, err, "not available at this time")
+}
+
+func TestFindOpenSlotsInMaintenance(t *testing.T) {
+
+	maintEnd := time.Now().Add(20 * time.Minute).Truncate(time.Second)
+	newMaintenanceTestDb(t, maintEnd, HostAvailable)
+
+	find := func(start time.Time, dur time.Duration, numHosts int) []ReservationTimeSlot {
+		var slots []ReservationTimeSlot
+		require.NoError(t, performDbTx(func(tx *gorm.DB) (err error) {
+			slots, _, err = dbFindOpenSlots([]string{"kn1", "kn2"}, start, dur, start.AddDate(1, 0, 0), numHosts, tx)
+			return err
+		}))
+		return slots
+	}
+
+	// kn1 opens when the maintenance period ends
+	now := time.Now()
+	slots := find(now, time.Hour, 2)
+	require.Len(t, slots, 2)
+	assert.Equal(t, "kn2", slots[0].Hostname)
+	assert.Equal(t, "kn1", slots[1].Hostname)
+	assert.True(t, slots[1].AvailSlotBegin.Equal(maintEnd))
+
+	// and from the start time once it has ended
+	later := now.Add(time.Hour)
+	slots = find(later, time.Hour, 2)
+	require.Len(t, slots, 2)
+	for _, s := range slots {
+		assert.True(t, s.AvailSlotBegin.Equal(later), s.Hostname)
+	}
+}
+
+func TestSlotsAfterMaintenance(t *testing.T) {
+
+	now := time.Now()
+	slots := []ReservationTimeSlot{
+		{Hostname: "kn1", AvailSlotBegin: now, AvailSlotEnd: now.Add(2 * time.Hour)},
+		{Hostname: "kn1", AvailSlotBegin: now.Add(3 * time.Hour), AvailSlotEnd: now.Add(5 * time.Hour)},
+		{Hostname: "kn2", AvailSlotBegin: now, AvailSlotEnd: now.Add(time.Hour + 30*time.Minute)},
+		{Hostname: "kn3", AvailSlotBegin: now, AvailSlotEnd: now.Add(time.Hour)},
+	}
+	maintEnds := map[string]time.Time{"kn1": now.Add(30 * time.Minute), "kn2": now.Add(time.Hour)}
+
+	kept := slotsAfterMaintenance(slots, maintEnds, time.Hour)
+	require.Len(t, kept, 3)
+	assert.Equal(t, now.Add(30*time.Minute), kept[0].AvailSlotBegin)
+	assert.Equal(t, now.Add(3*time.Hour), kept[1].AvailSlotBegin)
+	assert.Equal(t, "kn3", kept[2].Hostname)
+}
+
+func TestReadSchedulableHostsInMaintenance(t *testing.T) {
+
+	hosts := newMaintenanceTestDb(t, time.Now().Add(20*time.Minute), HostAvailable)
+
+	var found []Host
+	require.NoError(t, performDbTx(func(tx *gorm.DB) (err error) {
+		found, err = dbReadSchedulableHosts(hosts[0].HostPolicyID, tx)
+		return err
+	}))
+	require.Len(t, found, 1)
+	assert.Equal(t, "kn1", found[0].Name)
+}
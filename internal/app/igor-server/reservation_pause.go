@@ -0,0 +1,350 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	zl "github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// doPauseReservation releases the hosts of an active reservation until the requested resume time. The
+// hosts are torn down the same way as when a reservation is deleted, but the reservation keeps its
+// booking from the resume time to its end along with its vlan.
+func doPauseReservation(resName string, editParams map[string]interface{}, r *http.Request) (status int, err error) {
+
+	clog := hlog.FromRequest(r)
+	actionUser := getUserFromContext(r)
+	isElevated := userElevated(actionUser.Name)
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+	var res *Reservation
+	var resClone *Reservation
+
+	clusters, cErr := dbReadClustersTx(nil)
+	if cErr != nil {
+		return status, cErr
+	}
+
+	until := time.Unix(int64(editParams["pause"].(float64)), 0).Truncate(time.Minute)
+	substitute, _ := editParams["substitute"].(bool)
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		rList, grStatus, grErr := getReservations([]string{resName}, tx)
+		if grErr != nil {
+			status = grStatus
+			return grErr
+		}
+		res = &rList[0]
+		resClone = res.DeepCopy()
+
+		now := time.Now()
+		if res.isPaused() {
+			status = http.StatusConflict
+			return fmt.Errorf("reservation '%s' is already paused until %s", res.Name, res.PausedUntil.Format(common.DateTimeCompactFormat))
+		}
+		if !res.IsActive(now) {
+			status = http.StatusBadRequest
+			return fmt.Errorf("reservation '%s' has not started - only an active reservation can be paused", res.Name)
+		}
+		if !until.After(now) {
+			status = http.StatusBadRequest
+			return fmt.Errorf("resume time %s is not in the future", until.Format(common.DateTimeCompactFormat))
+		}
+		if !until.Before(res.End) {
+			status = http.StatusBadRequest
+			return fmt.Errorf("resume time %s must be before the reservation ends at %s - delete the reservation instead",
+				until.Format(common.DateTimeCompactFormat), res.End.Format(common.DateTimeCompactFormat))
+		}
+
+		return dbPauseReservation(res, until, substitute, tx)
+
+	}); err != nil {
+		return
+	}
+
+	status = http.StatusOK
+	clog.Info().Msgf("reservation '%s' paused until %s", resName, until.Format(common.DateTimeLogFormat))
+
+	// tear down the hosts just as if the reservation ended, once any reimage still writing to them is done
+	beginResTeardown(resClone.ID)
+	if uErr := uninstallRes(resClone, hookRequestID(r)); uErr != nil {
+		clog.Error().Msgf("problem uninstalling paused reservation '%s': %v", resName, uErr)
+	}
+	endResTeardown(resClone.ID)
+
+	rList, _ := dbReadReservationsTx(map[string]interface{}{"ID": resClone.ID}, nil)
+	res = &rList[0]
+
+	if hErr := res.HistCallback(res, HrUpdated+":pause"); hErr != nil {
+		logger.Error().Msgf("failed to record reservation '%s' pause to history", res.Name)
+	}
+
+	if pauseEvent := makeResEditNotifyEvent(EmailResPause, res, clusters[0].Name, actionUser, isElevated, formatLocaleDts(res.Owner.Locale, until)); pauseEvent != nil {
+		resNotifyChan <- *pauseEvent
+	}
+
+	return
+}
+
+// doResumeReservation resumes a paused reservation ahead of its scheduled resume time, or retries one that
+// failed to resume on schedule. The reservation is installed on the next pass of the reservation manager.
+func doResumeReservation(resName string, editParams map[string]interface{}, r *http.Request) (resumeMsg string, status int, err error) {
+
+	clog := hlog.FromRequest(r)
+	actionUser := getUserFromContext(r)
+	isElevated := userElevated(actionUser.Name)
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+	var res *Reservation
+
+	clusters, cErr := dbReadClustersTx(nil)
+	if cErr != nil {
+		return "", status, cErr
+	}
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		rList, grStatus, grErr := getReservations([]string{resName}, tx)
+		if grErr != nil {
+			status = grStatus
+			return grErr
+		}
+		res = &rList[0]
+
+		if !res.isPaused() {
+			status = http.StatusConflict
+			return fmt.Errorf("reservation '%s' is not paused", res.Name)
+		}
+
+		substitute, ok := editParams["substitute"].(bool)
+		if !ok {
+			substitute = res.ResumeSubstitute
+		}
+
+		resumeMsg, status, err = resumeRes(res, substitute, time.Now(), tx, clog)
+		return err
+
+	}); err != nil {
+		if status == http.StatusConflict && res != nil && res.isPaused() {
+			recordResumeError(res, err)
+		}
+		return
+	}
+
+	status = http.StatusOK
+	clog.Info().Msgf("reservation '%s' resumed by '%s'", resName, actionUser.Name)
+
+	rList, _ := dbReadReservationsTx(map[string]interface{}{"ID": res.ID}, nil)
+	res = &rList[0]
+
+	if hErr := res.HistCallback(res, HrUpdated+":resume"); hErr != nil {
+		logger.Error().Msgf("failed to record reservation '%s' resume to history", res.Name)
+	}
+
+	if resumeEvent := makeResEditNotifyEvent(EmailResResume, res, clusters[0].Name, actionUser, isElevated, resumeMsg); resumeEvent != nil {
+		resNotifyChan <- *resumeEvent
+	}
+
+	return
+}
+
+// resumeReservations resumes any paused reservation whose resume time has been reached. If some of its
+// hosts are no longer free the reservation is moved to substitute hosts when the owner allowed it, otherwise
+// it stays paused and the owner is sent a report of the hosts that are unavailable.
+func resumeReservations(checkTime *time.Time) error {
+
+	var resumeEvents []*ResNotifyEvent
+
+	if err := func() error {
+
+		dbAccess.Lock()
+		defer dbAccess.Unlock()
+
+		resList, err := dbReadReservationsTx(nil, map[string]time.Time{"to-start": *checkTime})
+		if err != nil {
+			return err
+		}
+
+		var clusterName string
+		for i := range resList {
+
+			res := &resList[i]
+			// a reservation that failed to resume waits for its owner to resume it or delete it
+			if !res.isPaused() || res.PausedUntil.After(*checkTime) || res.ResumeError != "" {
+				continue
+			}
+
+			if clusterName == "" {
+				clusters, cErr := dbReadClustersTx(nil)
+				if cErr != nil {
+					return cErr
+				}
+				clusterName = clusters[0].Name
+			}
+
+			var resumeMsg string
+			var status int
+			if rErr := performDbTx(func(tx *gorm.DB) error {
+				var resumeErr error
+				resumeMsg, status, resumeErr = resumeRes(res, res.ResumeSubstitute, *checkTime, tx, &logger)
+				return resumeErr
+			}); rErr != nil {
+				logger.Error().Msgf("failed to resume reservation '%s' - %v", res.Name, rErr)
+				if status == http.StatusConflict {
+					recordResumeError(res, rErr)
+					if failEvent := makeResWarnNotifyEvent(EmailResResumeFail, 0, res.DeepCopy(), clusterName); failEvent != nil {
+						failEvent.Info = rErr.Error()
+						resumeEvents = append(resumeEvents, failEvent)
+					}
+				}
+				continue
+			}
+
+			logger.Info().Msgf("reservation '%s' resumed", res.Name)
+
+			if rList, _ := dbReadReservationsTx(map[string]interface{}{"ID": res.ID}, nil); len(rList) > 0 {
+				res = &rList[0]
+			}
+
+			if hErr := res.HistCallback(res, HrUpdated+":resume"); hErr != nil {
+				logger.Error().Msgf("failed to record reservation '%s' resume to history", res.Name)
+			}
+
+			if resumeEvent := makeResWarnNotifyEvent(EmailResResume, 0, res.DeepCopy(), clusterName); resumeEvent != nil {
+				resumeEvent.Info = resumeMsg
+				resumeEvents = append(resumeEvents, resumeEvent)
+			}
+		}
+
+		return nil
+
+	}(); err != nil {
+		return err
+	}
+
+	// sent after releasing the db lock since processing a notification may need it
+	for _, e := range resumeEvents {
+		resNotifyChan <- *e
+	}
+
+	return nil
+}
+
+// resumeRes takes a reservation out of the paused state so it will be installed by the normal install
+// process. Hosts of the reservation that are no longer free are replaced with substitutes if allowed,
+// in which case the returned message describes the swap. Otherwise, a 409 error reports the hosts that
+// could not be reclaimed.
+func resumeRes(res *Reservation, substitute bool, now time.Time, tx *gorm.DB, clog *zl.Logger) (string, int, error) {
+
+	takenHosts, reasons, err := findTakenResumeHosts(res, now, tx)
+	if err != nil {
+		return "", http.StatusInternalServerError, err
+	}
+
+	var resumeMsg string
+
+	if len(takenHosts) > 0 {
+
+		if !substitute {
+			return "", http.StatusConflict, fmt.Errorf("cannot resume reservation '%s'; host(s) no longer free: %s -- resume with substitutes allowed or delete the reservation",
+				res.Name, strings.Join(reasons, ", "))
+		}
+
+		// look for the same number of hosts that can run from now until the reservation ends
+		subRes := res.DeepCopy()
+		subRes.Hosts = make([]Host, len(takenHosts))
+		subRes.Start = now
+		subRes.End = res.End
+		subHosts, shStatus, shErr := scheduleHostsByAvailability(subRes, tx, clog)
+		if shErr != nil {
+			if shStatus == http.StatusConflict {
+				return "", shStatus, fmt.Errorf("cannot resume reservation '%s'; host(s) no longer free: %s -- no substitutes available: %v",
+					res.Name, strings.Join(reasons, ", "), shErr)
+			}
+			return "", shStatus, shErr
+		}
+
+		if rErr := dbReplaceResHosts(res, takenHosts, subHosts, tx); rErr != nil {
+			return "", http.StatusInternalServerError, rErr
+		}
+
+		resumeMsg = fmt.Sprintf("host(s) %s replaced by %s", common.UnsplitList(namesOfHosts(takenHosts)), common.UnsplitList(namesOfHosts(subHosts)))
+		clog.Info().Msgf("reservation '%s' resuming with substitutes - %s", res.Name, resumeMsg)
+	}
+
+	changes := map[string]interface{}{
+		"PausedUntil":      time.Time{},
+		"ResumeSubstitute": false,
+		"ResumeError":      "",
+	}
+	// resuming ahead of schedule starts the reservation now
+	if now.Before(res.Start) {
+		changes["Start"] = now.Truncate(time.Minute)
+	}
+
+	if eErr := dbEditReservation(res, changes, tx); eErr != nil {
+		return "", http.StatusInternalServerError, eErr
+	}
+
+	return resumeMsg, http.StatusOK, nil
+}
+
+// findTakenResumeHosts returns the hosts of a paused reservation that can't be used if it resumes at the
+// given time, along with a short reason for each. A host is taken if it isn't available or, when resuming
+// ahead of schedule, another reservation was booked on it before the paused reservation's start time.
+func findTakenResumeHosts(res *Reservation, now time.Time, tx *gorm.DB) ([]Host, []string, error) {
+
+	var taken []Host
+	var reasons []string
+
+	for _, h := range res.Hosts {
+
+		if h.State != HostAvailable {
+			taken = append(taken, h)
+			reasons = append(reasons, fmt.Sprintf("%s (%s)", h.Name, h.State.String()))
+			continue
+		}
+
+		if !now.Before(res.Start) {
+			continue
+		}
+
+		conflicts, status, err := dbCheckResvConflicts([]string{h.Name}, now, res.Start, tx)
+		if err != nil && status != http.StatusConflict {
+			return nil, nil, err
+		}
+		var others []string
+		for _, c := range conflicts {
+			if c.ID != res.ID {
+				others = append(others, c.Name)
+			}
+		}
+		if len(others) > 0 {
+			sort.Strings(others)
+			taken = append(taken, h)
+			reasons = append(reasons, fmt.Sprintf("%s (reserved by %s)", h.Name, strings.Join(others, ",")))
+		}
+	}
+
+	return taken, reasons, nil
+}
+
+// recordResumeError saves the reason a paused reservation could not resume so it is visible in
+// reservation listings.
+func recordResumeError(res *Reservation, resumeErr error) {
+	if err := performDbTx(func(tx *gorm.DB) error {
+		return dbEditReservation(res, map[string]interface{}{"ResumeError": resumeErr.Error()}, tx)
+	}); err != nil {
+		logger.Error().Msgf("problem recording resume error for reservation '%s': %v", res.Name, err)
+	}
+}
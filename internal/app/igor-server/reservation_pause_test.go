@@ -0,0 +1,36 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPausedResIsNotActive(t *testing.T) {
+
+	now := time.Now()
+	res := &Reservation{
+		Start: now.Add(-2 * time.Hour),
+		End:   now.Add(48 * time.Hour),
+	}
+	assert.True(t, res.IsActive(now))
+	assert.False(t, res.isPaused())
+
+	// pausing moves the start to the resume time
+	until := now.Add(24 * time.Hour)
+	res.Start = until
+	res.PausedUntil = until
+	assert.True(t, res.isPaused())
+	assert.False(t, res.IsActive(now))
+	assert.Equal(t, 24*time.Hour, res.Remaining(now))
+
+	// a paused res that failed to resume on schedule is still not active
+	assert.False(t, res.IsActive(until.Add(time.Hour)))
+
+	res.PausedUntil = time.Time{}
+	assert.True(t, res.IsActive(until.Add(time.Hour)))
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+const (
+	// quickResUserLen is the most of the username used in a quick reservation name, leaving room for the
+	// date and a sequence number within the reservation name length limit
+	quickResUserLen = 12
+	quickResDate    = "20060102"
+)
+
+var quickResNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// quickResParams turns the parameters of a quick reservation into the parameters of a normal one: a single
+// node picked by the scheduler, booting the default user distro, named for the user and the date. The
+// duration, if given, is passed along unchanged.
+func quickResParams(params map[string]interface{}, user *User, now time.Time) (resParams map[string]interface{}, status int, err error) {
+
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+	resParams = map[string]interface{}{
+		"nodeCount":   float64(1),
+		"description": "quick reservation",
+	}
+	if dur, ok := params["duration"]; ok {
+		resParams["duration"] = dur
+	}
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+		distroName, qdStatus, qdErr := quickResDistro(tx)
+		if qdErr != nil {
+			status = qdStatus
+			return qdErr
+		}
+		resName, qnErr := quickResName(user.Name, now, tx)
+		if qnErr != nil {
+			return qnErr
+		}
+		resParams["distro"] = distroName
+		resParams["name"] = resName
+		return nil
+	}); err != nil {
+		return nil, status, err
+	}
+
+	return resParams, http.StatusOK, nil
+}
+
+// quickResDistro returns the name of the distro used by quick reservations, which is the one named in the
+// server config or else the default distro used for host maintenance.
+func quickResDistro(tx *gorm.DB) (string, int, error) {
+
+	if igor.Scheduler.DefaultUserDistro != "" {
+		distros, status, err := getDistros([]string{igor.Scheduler.DefaultUserDistro}, tx)
+		if err != nil {
+			if status == http.StatusNotFound {
+				return "", http.StatusServiceUnavailable, fmt.Errorf("the default distro '%s' for quick reservations no longer exists -- ask an admin to fix it or use 'igor res create'", igor.Scheduler.DefaultUserDistro)
+			}
+			return "", status, err
+		}
+		return distros[0].Name, http.StatusOK, nil
+	}
+
+	distros, err := dbReadDistros(map[string]interface{}{"is_default": true}, tx)
+	if err != nil {
+		return "", http.StatusInternalServerError, err
+	}
+	if len(distros) == 0 {
+		return "", http.StatusServiceUnavailable, fmt.Errorf("no default distro is configured for quick reservations -- use 'igor res create' with a distro or profile instead")
+	}
+	return distros[0].Name, http.StatusOK, nil
+}
+
+// quickResName returns a name made from the username and date, ex. alice-20230415, that no reservation is
+// using. A sequence number is added for a user's second and later quick reservations of the day.
+func quickResName(userName string, now time.Time, tx *gorm.DB) (string, error) {
+
+	base := quickResNameChars.ReplaceAllString(userName, "_")
+	if len(base) > quickResUserLen {
+		base = base[:quickResUserLen]
+	}
+	base += "-" + now.Format(quickResDate)
+
+	for i := 1; i < 100; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s-%d", base, i)
+		}
+		found, err := resvExists(name, tx)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			return name, nil
+		}
+	}
+	return "", newCodedError(common.ErrRateLimit, "too many quick reservations made today -- use 'igor res create' instead")
+}
+
+// quickResMessage is the one line response to a successful quick reservation.
+func quickResMessage(res *Reservation) string {
+	hostNames := make([]string, 0, len(res.Hosts))
+	for _, h := range res.Hosts {
+		hostNames = append(hostNames, h.Name)
+	}
+	return fmt.Sprintf("reserved %s until %s as reservation '%s'", strings.Join(hostNames, ","),
+		res.End.Format(common.DateTimeCompactFormat), res.Name)
+}
+
+// validateQuickResParams checks the parameters of a quick reservation, which only allows a duration.
+func validateQuickResParams(resParams map[string]interface{}) error {
+	for key, val := range resParams {
+		switch key {
+		case "quick":
+			if quick, ok := val.(bool); !ok || !quick {
+				return NewBadParamTypeError(key, val, "true")
+			}
+		case "duration":
+			sDur, sOk := val.(string)
+			_, fOk := val.(float64)
+			if !sOk && !fOk {
+				return NewBadParamTypeError(key, val, "string | float64")
+			} else if sOk {
+				if dur, err := common.ParseDuration(sDur); err != nil {
+					return fmt.Errorf("'%s' is not a recognized duration interval", sDur)
+				} else if dur <= 0 {
+					return fmt.Errorf("duration expression '%s' cannot be a negative value", sDur)
+				}
+			}
+		default:
+			return NewUnknownParamError(key, val)
+		}
+	}
+	return nil
+}
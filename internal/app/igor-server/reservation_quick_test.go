@@ -0,0 +1,84 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+)
+
+func TestQuickResParams(t *testing.T) {
+
+	seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	origDistro := igor.Scheduler.DefaultUserDistro
+	t.Cleanup(func() { igor.Scheduler.DefaultUserDistro = origDistro })
+	igor.Scheduler.DefaultUserDistro = ""
+
+	user := &User{Name: "alice"}
+	now := time.Date(2023, 4, 15, 10, 0, 0, 0, time.Local)
+
+	// nothing to boot without a default distro
+	_, status, err := quickResParams(map[string]interface{}{"quick": true}, user, now)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	maint := Distro{Name: "maint", IsDefault: true}
+	require.NoError(t, db.Omit(clause.Associations).Create(&maint).Error)
+
+	params, _, err := quickResParams(map[string]interface{}{"quick": true, "duration": "4h"}, user, now)
+	require.NoError(t, err)
+	assert.Equal(t, "alice-20230415", params["name"])
+	assert.Equal(t, "maint", params["distro"])
+	assert.Equal(t, float64(1), params["nodeCount"])
+	assert.Equal(t, "4h", params["duration"])
+	assert.NotContains(t, params, "quick")
+
+	// a second one the same day gets a sequence number
+	require.NoError(t, db.Omit(clause.Associations).Create(&Reservation{Name: "alice-20230415", Hash: "h1", OwnerID: 1}).Error)
+	params, _, err = quickResParams(map[string]interface{}{"quick": true}, user, now)
+	require.NoError(t, err)
+	assert.Equal(t, "alice-20230415-2", params["name"])
+	assert.NotContains(t, params, "duration")
+
+	// the configured distro is used over the maintenance one
+	require.NoError(t, db.Omit(clause.Associations).Create(&Distro{Name: "ubuntu"}).Error)
+	igor.Scheduler.DefaultUserDistro = "ubuntu"
+	params, _, err = quickResParams(map[string]interface{}{"quick": true}, user, now)
+	require.NoError(t, err)
+	assert.Equal(t, "ubuntu", params["distro"])
+
+	igor.Scheduler.DefaultUserDistro = "gone"
+	_, status, err = quickResParams(map[string]interface{}{"quick": true}, user, now)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
+
+func TestQuickResName(t *testing.T) {
+
+	seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+	now := time.Date(2023, 4, 15, 10, 0, 0, 0, time.Local)
+
+	name, err := quickResName("a.very.long.user@name", now, db)
+	require.NoError(t, err)
+	assert.Equal(t, "a.very.long.-20230415", name)
+	assert.NoError(t, checkResNameRules(name+"-99"))
+}
+
+func TestValidateQuickResParams(t *testing.T) {
+	assert.NoError(t, validateQuickResParams(map[string]interface{}{"quick": true}))
+	assert.NoError(t, validateQuickResParams(map[string]interface{}{"quick": true, "duration": "4h"}))
+	assert.NoError(t, validateQuickResParams(map[string]interface{}{"quick": true, "duration": float64(1681560000)}))
+	assert.Error(t, validateQuickResParams(map[string]interface{}{"quick": false}))
+	assert.Error(t, validateQuickResParams(map[string]interface{}{"quick": true, "duration": "soon"}))
+	assert.Error(t, validateQuickResParams(map[string]interface{}{"quick": true, "nodeCount": float64(2)}))
+}
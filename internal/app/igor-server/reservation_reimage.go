@@ -0,0 +1,301 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// Outcomes of reimaging a single host.
+const (
+	ReimageInstalled = "installed"
+	ReimageCycled    = "cycled"
+	ReimageError     = "error"
+)
+
+// reimaging holds the IDs of reservations with a reimage in progress. Only one reimage of a
+// reservation can run at a time.
+var reimaging sync.Map
+
+// doReimageReservation rewrites the PXE files of some or all of the hosts of an installed reservation
+// and optionally power cycles them. The profile or distro to boot can be given, otherwise the
+// reservation's own profile is used. The reservation keeps its stored profile unless persist is set.
+//
+// dbAccess must not be held by the caller. It is only held while the reservation is read and updated
+// so the hosts can be written and cycled without blocking other requests.
+func doReimageReservation(resName string, editParams map[string]interface{}, r *http.Request) (results []common.ReimageHostResult, msg string, status int, err error) {
+
+	clog := hlog.FromRequest(r)
+	actionUser := getUserFromContext(r)
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+
+	nodes, _ := editParams["nodes"].(string)
+	cycle, _ := editParams["cycle"].(bool)
+	persist, _ := editParams["persist"].(bool)
+	_, doDistro := editParams["distro"]
+	_, doProfile := editParams["profile"]
+
+	var res *Reservation
+	var reimageRes *Reservation
+
+	authInfo, err := actionUser.getAuthzInfo()
+	if err != nil {
+		return
+	}
+
+	dbAccess.Lock()
+	err = performDbTx(func(tx *gorm.DB) error {
+
+		rList, grStatus, grErr := getReservations([]string{resName}, tx)
+		if grErr != nil {
+			status = grStatus
+			return grErr
+		}
+		res = &rList[0]
+
+		if !res.Installed {
+			status = http.StatusConflict
+			if res.isPaused() {
+				return fmt.Errorf("reservation '%s' is paused - only an installed reservation can be reimaged", res.Name)
+			}
+			return fmt.Errorf("reservation '%s' is not installed - only an installed reservation can be reimaged", res.Name)
+		}
+
+		hosts, shStatus, shErr := selectReimageHosts(res, nodes)
+		if shErr != nil {
+			status = shStatus
+			return shErr
+		}
+
+		// group members can reimage hosts their node action permission covers, the owner and co-owners
+		// always can, unless a host policy makes reimaging admin-only
+		policyHosts, ghStatus, ghErr := getHosts(namesOfHosts(hosts), true, tx)
+		if ghErr != nil {
+			status = ghStatus
+			return ghErr
+		}
+		var naStatus int
+		var naErr error
+		if res.OwnerID == actionUser.ID || res.isCoOwner(actionUser.Name) {
+			naStatus, naErr = checkNodeActionPolicy(actionUser, NodeActionReimage, policyHosts)
+		} else {
+			naStatus, naErr = checkNodeAction(actionUser, authInfo, NodeActionReimage, policyHosts)
+		}
+		if naErr != nil {
+			status = naStatus
+			return naErr
+		}
+
+		if _, busy := reimaging.LoadOrStore(res.ID, struct{}{}); busy {
+			status = http.StatusConflict
+			return fmt.Errorf("reservation '%s' is busy with another reimage - try again once it finishes", res.Name)
+		}
+		if !beginResInstall(res.ID) {
+			reimaging.Delete(res.ID)
+			status = http.StatusConflict
+			return fmt.Errorf("reservation '%s' is being removed and can't be reimaged", res.Name)
+		}
+
+		reimageRes = res.DeepCopy()
+		reimageRes.Hosts = hosts
+
+		if doDistro || doProfile {
+			changes, piStatus, piErr := parseImageEdits(res, editParams, tx)
+			if piErr != nil {
+				status = piStatus
+				return piErr
+			}
+			reimageRes.Profile = *changes["profile"].(*Profile)
+
+			if persist {
+				if deErr := dbEditReservation(res, changes, tx); deErr != nil {
+					return deErr
+				}
+				reimageRes.Profile = res.Profile
+			}
+		}
+
+		return dbRecordImageUse(&reimageRes.Profile, time.Now(), tx)
+	})
+	dbAccess.Unlock()
+
+	if reimageRes != nil {
+		defer reimaging.Delete(reimageRes.ID)
+	}
+	if err != nil {
+		if reimageRes != nil {
+			endResInstall(reimageRes.ID)
+		}
+		return
+	}
+
+	clog.Info().Msgf("reimaging host(s) %v of reservation '%s' with profile '%s' (distro '%s')", namesOfHosts(reimageRes.Hosts),
+		resName, reimageRes.Profile.Name, reimageRes.Profile.Distro.Name)
+
+	hostErrors := map[string]error{}
+	if irErr := igor.IResInstaller.Install(reimageRes); irErr != nil {
+		var hiErr *HostInstallError
+		if errors.As(irErr, &hiErr) {
+			hostErrors = hiErr.HostErrors
+		} else {
+			for _, h := range reimageRes.Hosts {
+				hostErrors[h.Name] = irErr
+			}
+		}
+	}
+
+	var cycleHosts []Host
+	for _, h := range reimageRes.Hosts {
+		if _, failed := hostErrors[h.Name]; !failed {
+			cycleHosts = append(cycleHosts, h)
+		}
+	}
+
+	var powerErr error
+	var cycled bool
+	if cycle && len(cycleHosts) > 0 {
+		if _, powerErr = doPowerHosts(PowerCycle, hostNamesOfHosts(cycleHosts), clog); powerErr != nil {
+			clog.Error().Msgf("problem power cycling reimaged hosts of reservation '%s': %v", resName, powerErr)
+		} else {
+			cycled = true
+		}
+	}
+
+	// a delete waiting on this reimage holds dbAccess, so let it go ahead before recording the power on
+	endResInstall(reimageRes.ID)
+	if cycled {
+		recordResPowerOn(hostNamesOfHosts(cycleHosts), clog)
+	}
+
+	var failCount int
+	for _, h := range reimageRes.Hosts {
+		result := common.ReimageHostResult{Host: h.Name, Result: ReimageInstalled}
+		if hErr, failed := hostErrors[h.Name]; failed {
+			result.Result = ReimageError
+			result.Error = hErr.Error()
+			failCount++
+		} else if cycle && powerErr != nil {
+			result.Result = ReimageError
+			result.Error = "installed but power cycle failed: " + powerErr.Error()
+			failCount++
+		} else if cycle {
+			result.Result = ReimageCycled
+		}
+		results = append(results, result)
+	}
+
+	// the history record shows the profile and hosts that were reimaged along with who did it
+	status = http.StatusOK
+	histStatus := HrUpdated + ":reimage"
+	if persist {
+		histStatus += ",persist"
+	}
+	if hErr := reimageRes.HistCallback(reimageRes, histStatus+" by "+actionUser.Name); hErr != nil {
+		logger.Error().Msgf("failed to record reservation '%s' reimage to history", resName)
+	}
+
+	image := fmt.Sprintf("profile '%s'", reimageRes.Profile.Name)
+	if doDistro {
+		image = fmt.Sprintf("distro '%s'", reimageRes.Profile.Distro.Name)
+	}
+	msg = fmt.Sprintf("%d of %d host(s) reimaged with %s", len(results)-failCount, len(results), image)
+	if !persist && (doDistro || doProfile) {
+		msg += fmt.Sprintf(" - the reservation profile is still '%s'", res.Profile.Name)
+	}
+	if !cycle {
+		msg += " - power cycle the hosts to boot it"
+	}
+
+	return
+}
+
+// selectReimageHosts returns the hosts of the reservation named in the nodes spec, or all of its hosts
+// if the spec is empty.
+func selectReimageHosts(res *Reservation, nodes string) ([]Host, int, error) {
+
+	if strings.TrimSpace(nodes) == "" {
+		return res.Hosts, http.StatusOK, nil
+	}
+
+	wanted := common.NewSet()
+	wanted.Add(igor.splitRange(nodes)...)
+	if wanted.Size() == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("couldn't parse node specification %v", nodes)
+	}
+
+	var selected []Host
+	for _, h := range res.Hosts {
+		if wanted.Contains(h.Name) {
+			selected = append(selected, h)
+			wanted.Remove(h.Name)
+		}
+	}
+
+	if wanted.Size() > 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("host(s) %s are not part of reservation '%s'", common.UnsplitList(wanted.Elements()), res.Name)
+	}
+
+	return selected, http.StatusOK, nil
+}
+
+// validateReimageParams checks the body of a reimage request. Reimage can't be mixed with other
+// reservation edits.
+func validateReimageParams(resParams map[string]interface{}) error {
+
+	if reimage, ok := resParams["reimage"].(bool); !ok || !reimage {
+		return NewBadParamTypeError("reimage", resParams["reimage"], "bool (true)")
+	}
+
+	for key, val := range resParams {
+		switch key {
+		case "reimage":
+			continue
+		case "distro":
+			if distro, ok := val.(string); !ok {
+				return NewBadParamTypeError(key, val, "string")
+			} else if err := checkDistroNameRules(distro); err != nil {
+				return err
+			}
+		case "profile":
+			if profile, ok := val.(string); !ok {
+				return NewBadParamTypeError(key, val, "string")
+			} else if err := checkProfileNameRules(profile); err != nil {
+				return err
+			}
+		case "nodes":
+			if nodes, ok := val.(string); !ok {
+				return NewBadParamTypeError(key, val, "string")
+			} else if len(igor.splitRange(nodes)) == 0 {
+				return fmt.Errorf("couldn't parse node specification %v", nodes)
+			}
+		case "cycle", "persist":
+			if _, ok := val.(bool); !ok {
+				return NewBadParamTypeError(key, val, "bool")
+			}
+		default:
+			return fmt.Errorf("reimaging a reservation can't be combined with other reservation changes; found %v", key)
+		}
+	}
+
+	_, doDistro := resParams["distro"]
+	_, doProfile := resParams["profile"]
+	if doDistro && doProfile {
+		return fmt.Errorf("both profile and distro params found; only one allowed")
+	}
+	if persist, _ := resParams["persist"].(bool); persist && !doDistro && !doProfile {
+		return fmt.Errorf("persist requires a profile or distro to keep")
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func setReimageTestRange(t *testing.T) {
+	origRefs := igor.ClusterRefs
+	t.Cleanup(func() { igor.ClusterRefs = origRefs })
+	r, _ := common.NewRange("kn", 1, 10)
+	igor.ClusterRefs = []common.Range{*r}
+}
+
+func TestSelectReimageHosts(t *testing.T) {
+
+	setReimageTestRange(t)
+	res := &Reservation{Name: "myres", Hosts: []Host{{Name: "kn1"}, {Name: "kn2"}, {Name: "kn3"}}}
+
+	hosts, _, err := selectReimageHosts(res, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kn1", "kn2", "kn3"}, namesOfHosts(hosts))
+
+	hosts, _, err = selectReimageHosts(res, "kn[1,3]")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kn1", "kn3"}, namesOfHosts(hosts))
+
+	_, _, err = selectReimageHosts(res, "kn[3-4]")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "kn4")
+}
+
+func TestValidateReimageParams(t *testing.T) {
+
+	setReimageTestRange(t)
+
+	assert.NoError(t, validateReimageParams(map[string]interface{}{"reimage": true}))
+	assert.NoError(t, validateReimageParams(map[string]interface{}{
+		"reimage": true, "profile": "myprof", "nodes": "kn[1-2]", "cycle": true, "persist": true,
+	}))
+
+	bad := []map[string]interface{}{
+		{"reimage": false},
+		{"reimage": true, "profile": "myprof", "distro": "centos"},
+		{"reimage": true, "persist": true},
+		{"reimage": true, "cycle": "yes"},
+		{"reimage": true, "extend": "1h"},
+		{"reimage": true, "nodes": 5.0},
+	}
+	for _, params := range bad {
+		assert.Error(t, validateReimageParams(params), "params %v", params)
+	}
+}
+
+// reimageInstaller is an IResInstaller that records the reservation given to each install. Installs
+// signal started and then wait on release so a test can act while one is in progress.
+type reimageInstaller struct {
+	installs []*Reservation
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func (ri *reimageInstaller) Install(r *Reservation) error {
+	ri.started <- struct{}{}
+	<-ri.release
+	ri.installs = append(ri.installs, r)
+	return nil
+}
+
+func (ri *reimageInstaller) Uninstall(*Reservation) error { return nil }
+
+func TestReimageReservation(t *testing.T) {
+
+	setReimageTestRange(t)
+	origInstaller := igor.IResInstaller
+	t.Cleanup(func() { igor.IResInstaller = origInstaller })
+	installer := &reimageInstaller{started: make(chan struct{}, 10), release: make(chan struct{})}
+	igor.IResInstaller = installer
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	res := newStartTestRes(t, db, "burnin", hosts, false, 0)
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	newProfile := func(name, distroName string) Profile {
+		distro := Distro{Name: distroName, Groups: []Group{all}}
+		require.NoError(t, db.Omit("Groups.*").Create(&distro).Error)
+		profile := Profile{Name: name, OwnerID: res.OwnerID, DistroID: distro.ID}
+		require.NoError(t, db.Omit(clause.Associations).Create(&profile).Error)
+		return profile
+	}
+	centos, rocky := newProfile("centos-prof", "centos"), newProfile("rocky-prof", "rocky")
+	require.NoError(t, db.Model(&Reservation{}).Where("id = ?", res.ID).
+		Updates(map[string]interface{}{"profile_id": centos.ID, "installed": true}).Error)
+
+	var alice User
+	require.NoError(t, db.Preload("Groups").Where("name = ?", "alice").First(&alice).Error)
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, &alice))
+	storedProfile := func() string {
+		stored, err := dbReadReservationsTx(map[string]interface{}{"name": "burnin"}, nil)
+		require.NoError(t, err)
+		return stored[0].Profile.Name
+	}
+
+	// a reimage with another profile boots it on the chosen hosts but the reservation keeps its own
+	done := make(chan error, 1)
+	go func() {
+		_, _, _, rErr := doReimageReservation("burnin", map[string]interface{}{"reimage": true, "profile": "rocky-prof", "nodes": "kn1"}, r)
+		done <- rErr
+	}()
+	<-installer.started
+
+	// a second reimage can't start while the first is writing the hosts
+	_, _, status, err := doReimageReservation("burnin", map[string]interface{}{"reimage": true}, r)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Contains(t, err.Error(), "busy with another reimage")
+
+	close(installer.release)
+	require.NoError(t, <-done)
+	require.Len(t, installer.installs, 1)
+	assert.Equal(t, "rocky-prof", installer.installs[0].Profile.Name)
+	assert.Equal(t, []string{"kn1"}, namesOfHosts(installer.installs[0].Hosts))
+	assert.Equal(t, "centos-prof", storedProfile())
+
+	// the history shows the profile and hosts that were reimaged and who did it
+	var hr HistoryRecord
+	require.NoError(t, db.Where("status LIKE ?", HrUpdated+":reimage%").First(&hr).Error)
+	assert.Equal(t, HrUpdated+":reimage by alice", hr.Status)
+	assert.Equal(t, "rocky-prof", hr.Profile)
+	assert.Equal(t, "rocky", hr.Distro)
+	assert.Equal(t, "kn1", hr.Hosts)
+
+	// with persist the reservation keeps the new profile
+	results, _, _, err := doReimageReservation("burnin", map[string]interface{}{"reimage": true, "profile": "rocky-prof", "persist": true}, r)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "rocky-prof", storedProfile())
+	var persistHr HistoryRecord
+	require.NoError(t, db.Where("status = ?", HrUpdated+":reimage,persist by alice").First(&persistHr).Error)
+	assert.Equal(t, "kn1,kn2", persistHr.Hosts)
+	assert.Equal(t, rocky.ID, installer.installs[1].Profile.ID)
+}
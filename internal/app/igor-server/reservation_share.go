@@ -0,0 +1,504 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog/hlog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+const (
+	// DefaultShareExpiry is how long a share link lasts when the owner doesn't say
+	DefaultShareExpiry = 7 * 24 * time.Hour
+	// ShareRevokeAll revokes every share link of a reservation
+	ShareRevokeAll = "all"
+	// shareAudience marks a jwt as a share token so it can't be mistaken for a login token
+	shareAudience = "igor-res-share"
+	// shareRateWindow is the period server.shareRateLimit applies to
+	shareRateWindow = time.Minute
+)
+
+// errShareInvalid is the only error a share link lookup gives for a bad link, so the response
+// doesn't reveal whether a reservation exists or why the link stopped working.
+var errShareInvalid = errors.New("this share link is not valid or has expired")
+
+// ResShare is a read-only link to a reservation that its owner has handed out. The link itself is a
+// signed token naming the share; this record lets the owner list and revoke it. A share stops
+// working when it expires, when the reservation ends or when the reservation changes owner.
+type ResShare struct {
+	Base
+	ShareID       string `gorm:"unique; notNull"`
+	ReservationID int    `gorm:"notNull; index"`
+	// OwnerID is the reservation owner when the share was made
+	OwnerID int `gorm:"notNull"`
+	Expires time.Time
+}
+
+// ResShareClaims are the claims of a share token.
+type ResShareClaims struct {
+	ShareID string `json:"sid"`
+	// ResHash is the history hash of the reservation, which is never reused
+	ResHash string `json:"res"`
+	jwt.RegisteredClaims
+}
+
+var (
+	// shareRateMU guards shareRateHits, the use of each share link in its current rate window
+	shareRateMU   sync.Mutex
+	shareRateHits = make(map[string]*shareRateCount)
+)
+
+type shareRateCount struct {
+	start time.Time
+	count int
+}
+
+// handleShareReservation handles the share and revokeShare forms of a reservation update.
+func handleShareReservation(w http.ResponseWriter, r *http.Request) {
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	editParams := getBodyFromContext(r)
+	clog := hlog.FromRequest(r)
+	actionPrefix := "share reservation"
+	ps := httprouter.ParamsFromContext(r.Context())
+	resName := ps.ByName("resName")
+	rb := common.NewResponseBodyResShare()
+
+	var link common.ResShareLinkData
+	var msg string
+	var status int
+	var err error
+
+	if revoke, doRevoke := editParams["revokeShare"].(string); doRevoke {
+		actionPrefix = "revoke reservation share"
+		msg, status, err = doRevokeResShare(resName, revoke, r)
+	} else {
+		link, msg, status, err = doCreateResShare(resName, editParams["share"].(string), r)
+	}
+
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		if link.ID != "" {
+			rb.Data["share"] = link
+		}
+		rb.Message = msg
+		clog.Info().Msgf("%s success - %s", actionPrefix, msg)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// doCreateResShare makes a share link for the reservation that lasts for the given duration, or
+// DefaultShareExpiry if none is given. Only the owner of the reservation can share it. A link never
+// lasts past the end of the reservation.
+func doCreateResShare(resName, expires string, r *http.Request) (link common.ResShareLinkData, msg string, status int, err error) {
+
+	clog := hlog.FromRequest(r)
+	actionUser := getUserFromContext(r)
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+
+	dur := DefaultShareExpiry
+	if expires != "" {
+		if dur, err = common.ParseDuration(expires); err != nil || dur <= 0 {
+			return link, "", http.StatusBadRequest, fmt.Errorf("'%s' is not a recognized duration interval", expires)
+		}
+	}
+	if maxDur := time.Duration(igor.Server.ShareMaxDays) * 24 * time.Hour; dur > maxDur {
+		return link, "", http.StatusBadRequest, fmt.Errorf("a share link can last at most %d days", igor.Server.ShareMaxDays)
+	}
+
+	var res *Reservation
+	var share *ResShare
+	now := time.Now()
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		rList, grStatus, grErr := getReservations([]string{resName}, tx)
+		if grErr != nil {
+			status = grStatus
+			return grErr
+		}
+		res = &rList[0]
+
+		if res.OwnerID != actionUser.ID {
+			status = http.StatusForbidden
+			return fmt.Errorf("only the owner of reservation '%s' can share it", res.Name)
+		}
+		if !res.End.After(now) {
+			status = http.StatusConflict
+			return fmt.Errorf("reservation '%s' has ended", res.Name)
+		}
+
+		shareID, idErr := newShareID()
+		if idErr != nil {
+			return idErr
+		}
+		share = &ResShare{
+			ShareID:       shareID,
+			ReservationID: res.ID,
+			OwnerID:       res.OwnerID,
+			Expires:       now.Add(dur).Truncate(time.Second),
+		}
+		if share.Expires.After(res.End) {
+			share.Expires = res.End
+		}
+		return tx.Create(share).Error
+
+	}); err != nil {
+		return
+	}
+
+	token, err := signShareToken(share, res)
+	if err != nil {
+		status = http.StatusInternalServerError
+		return
+	}
+
+	link = common.ResShareLinkData{
+		ID:      share.ShareID,
+		Token:   token,
+		Created: share.CreatedAt.Unix(),
+		Expires: share.Expires.Unix(),
+	}
+	if igor.Server.ShareURL != "" {
+		link.Token = fmt.Sprintf(igor.Server.ShareURL, token)
+	}
+
+	if hErr := res.HistCallback(res, HrUpdated+":share"); hErr != nil {
+		clog.Error().Msgf("failed to record reservation '%s' share to history", res.Name)
+	}
+
+	status = http.StatusCreated
+	msg = fmt.Sprintf("share link %s for reservation '%s' expires %s", share.ShareID, res.Name, share.Expires.Format(common.DateTimeCompactFormat))
+	return
+}
+
+// doRevokeResShare removes the share link of the reservation with the given ID, or all of its share
+// links if the ID is ShareRevokeAll. The owner and admins can revoke share links.
+func doRevokeResShare(resName, shareID string, r *http.Request) (msg string, status int, err error) {
+
+	clog := hlog.FromRequest(r)
+	actionUser := getUserFromContext(r)
+	status = http.StatusInternalServerError // default status, overridden at end if no errors
+
+	var res *Reservation
+	var revoked int64
+
+	if err = performDbTx(func(tx *gorm.DB) error {
+
+		rList, grStatus, grErr := getReservations([]string{resName}, tx)
+		if grErr != nil {
+			status = grStatus
+			return grErr
+		}
+		res = &rList[0]
+
+		if res.OwnerID != actionUser.ID && !userElevated(actionUser.Name) {
+			status = http.StatusForbidden
+			return fmt.Errorf("only the owner of reservation '%s' can revoke its share links", res.Name)
+		}
+
+		tx = tx.Where("reservation_id = ?", res.ID)
+		if shareID != ShareRevokeAll {
+			tx = tx.Where("share_id = ?", shareID)
+		}
+		result := tx.Delete(&ResShare{})
+		if result.Error != nil {
+			return result.Error
+		}
+		revoked = result.RowsAffected
+
+		if revoked == 0 && shareID != ShareRevokeAll {
+			status = http.StatusNotFound
+			return fmt.Errorf("reservation '%s' has no share link '%s'", res.Name, shareID)
+		}
+		return nil
+
+	}); err != nil {
+		return
+	}
+
+	if revoked > 0 {
+		if hErr := res.HistCallback(res, HrUpdated+":share-revoke"); hErr != nil {
+			clog.Error().Msgf("failed to record reservation '%s' share revoke to history", res.Name)
+		}
+	}
+
+	status = http.StatusOK
+	msg = fmt.Sprintf("%d share link(s) of reservation '%s' revoked", revoked, res.Name)
+	return
+}
+
+// handlePublicShare returns the status of the reservation named by a share link. It does not
+// require authentication, so each link is limited to server.shareRateLimit lookups a minute.
+func handlePublicShare(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "show shared reservation"
+	rb := common.NewResponseBody()
+
+	ps := httprouter.ParamsFromContext(r.Context())
+	token := ps.ByName("shareToken")
+	shareData, retryAfter, status, err := doReadResShare(token, time.Now())
+
+	if err != nil {
+		if retryAfter > 0 {
+			w.Header().Set("Retry-After", fmt.Sprintf("%d", int(retryAfter.Round(time.Second)/time.Second)+1))
+		}
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["share"] = shareData
+		clog.Debug().Msgf("%s success - '%s'", actionPrefix, shareData.Name)
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// doReadResShare checks a share token and returns the status of its reservation. Any problem with
+// the link itself is reported as errShareInvalid. If the link has been used too often the time until
+// it can be used again is returned.
+func doReadResShare(token string, now time.Time) (shareData common.ResShareData, retryAfter time.Duration, status int, err error) {
+
+	claims, err := parseShareToken(token)
+	if err != nil {
+		logger.Debug().Msgf("share token rejected - %v", err)
+		return shareData, 0, http.StatusNotFound, errShareInvalid
+	}
+
+	if ok, wait := allowShareRequest(claims.ShareID, now); !ok {
+		return shareData, wait, http.StatusTooManyRequests,
+			fmt.Errorf("share link %s has been used too often - try again in %v", claims.ShareID, wait.Round(time.Second))
+	}
+
+	var share ResShare
+	var res *Reservation
+
+	dbAccess.Lock()
+	err = performDbTx(func(tx *gorm.DB) error {
+		var shares []ResShare
+		if sErr := tx.Where("share_id = ?", claims.ShareID).Find(&shares).Error; sErr != nil {
+			return sErr
+		}
+		if len(shares) == 0 {
+			return fmt.Errorf("share %s not found", claims.ShareID)
+		}
+		share = shares[0]
+
+		rList, rErr := dbReadReservations(map[string]interface{}{"ID": share.ReservationID}, nil, tx)
+		if rErr != nil {
+			return rErr
+		}
+		if len(rList) == 0 {
+			return fmt.Errorf("reservation of share %s not found", claims.ShareID)
+		}
+		res = &rList[0]
+		return nil
+	})
+	dbAccess.Unlock()
+
+	if err == nil {
+		err = checkResShare(&share, res, claims.ResHash, now)
+	}
+	if err != nil {
+		logger.Debug().Msgf("share link %s rejected - %v", claims.ShareID, err)
+		return shareData, 0, http.StatusNotFound, errShareInvalid
+	}
+
+	return getResShareData(res, &share), 0, http.StatusOK, nil
+}
+
+// checkResShare returns an error if the share can no longer be used to see the reservation.
+func checkResShare(share *ResShare, res *Reservation, resHash string, now time.Time) error {
+	switch {
+	case res.Hash != resHash:
+		return fmt.Errorf("token is for a different reservation")
+	case !share.Expires.After(now):
+		return fmt.Errorf("expired %s", share.Expires.Format(common.DateTimeCompactFormat))
+	case !res.End.After(now):
+		return fmt.Errorf("reservation '%s' has ended", res.Name)
+	case res.OwnerID != share.OwnerID:
+		return fmt.Errorf("reservation '%s' has changed owner", res.Name)
+	}
+	return nil
+}
+
+// getResShareData returns the status of the reservation shown to share link holders.
+func getResShareData(res *Reservation, share *ResShare) common.ResShareData {
+
+	hostNames := namesOfHosts(res.Hosts)
+	var up, down, unknown []string
+
+	powerMapMU.Lock()
+	for _, h := range res.Hosts {
+		if powered, ok := powerMap[h.HostName]; !ok || powered == nil {
+			unknown = append(unknown, h.Name)
+		} else if *powered {
+			up = append(up, h.Name)
+		} else {
+			down = append(down, h.Name)
+		}
+	}
+	powerMapMU.Unlock()
+
+	hostRange, _ := igor.ClusterRefs[0].UnsplitRange(hostNames)
+	hostsUp, _ := igor.ClusterRefs[0].UnsplitRange(up)
+	hostsDown, _ := igor.ClusterRefs[0].UnsplitRange(down)
+	hostsUnknown, _ := igor.ClusterRefs[0].UnsplitRange(unknown)
+
+	return common.ResShareData{
+		Name:         res.Name,
+		Description:  res.Description,
+		Start:        res.Start.Unix(),
+		End:          res.End.Unix(),
+		RemainHours:  int(time.Until(res.End).Round(time.Hour) / time.Hour),
+		HostRange:    hostRange,
+		HostsUp:      hostsUp,
+		HostsDown:    hostsDown,
+		HostsPowerNA: hostsUnknown,
+		Installed:    res.Installed,
+		Paused:       res.isPaused(),
+		State:        res.resState(time.Now()),
+		LinkExpires:  share.Expires.Unix(),
+	}
+}
+
+// getResShareLinks lists the share links of the reservation that are still usable.
+func (r *Reservation) getResShareLinks() []common.ResShareLinkData {
+	var links []common.ResShareLinkData
+	now := time.Now()
+	for _, s := range r.Shares {
+		if s.OwnerID != r.OwnerID || !s.Expires.After(now) {
+			continue
+		}
+		links = append(links, common.ResShareLinkData{
+			ID:      s.ShareID,
+			Created: s.CreatedAt.Unix(),
+			Expires: s.Expires.Unix(),
+		})
+	}
+	return links
+}
+
+// allowShareRequest counts a lookup made with the share link and reports whether it is within
+// server.shareRateLimit for the current window. If not, it also returns the time left in the window.
+func allowShareRequest(shareID string, now time.Time) (bool, time.Duration) {
+
+	shareRateMU.Lock()
+	defer shareRateMU.Unlock()
+
+	c, ok := shareRateHits[shareID]
+	if !ok || !now.Before(c.start.Add(shareRateWindow)) {
+		c = &shareRateCount{start: now}
+		shareRateHits[shareID] = c
+	}
+	if c.count >= igor.Server.ShareRateLimit {
+		return false, c.start.Add(shareRateWindow).Sub(now)
+	}
+	c.count++
+	return true, 0
+}
+
+// purgeResShares removes share links that can no longer be used because they expired or their
+// reservation ended, was deleted or changed owner. It runs as part of the reservation manager.
+func purgeResShares(checkTime *time.Time) error {
+
+	shareRateMU.Lock()
+	for id, c := range shareRateHits {
+		if !checkTime.Before(c.start.Add(shareRateWindow)) {
+			delete(shareRateHits, id)
+		}
+	}
+	shareRateMU.Unlock()
+
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	var purged int64
+	if err := performDbTx(func(tx *gorm.DB) error {
+		result := tx.Where("expires <= ? OR NOT EXISTS (SELECT 1 FROM reservations r WHERE r.id = res_shares.reservation_id "+
+			"AND r.owner_id = res_shares.owner_id AND r.end > ?)", *checkTime, *checkTime).Delete(&ResShare{})
+		purged = result.RowsAffected
+		return result.Error
+	}); err != nil {
+		return fmt.Errorf("problem removing unusable reservation share links: %v", err)
+	}
+
+	if purged > 0 {
+		logger.Debug().Msgf("removed %d unusable reservation share link(s)", purged)
+	}
+	return nil
+}
+
+// signShareToken returns the signed token for a share of the reservation. It is signed with the same
+// key as login tokens, so resetting that key also invalidates every share link.
+func signShareToken(share *ResShare, res *Reservation) (string, error) {
+
+	claims := &ResShareClaims{
+		ShareID: share.ShareID,
+		ResHash: res.Hash,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{shareAudience},
+			ExpiresAt: jwt.NewNumericDate(share.Expires),
+		},
+	}
+
+	key, err := getJwtToken()
+	if err != nil {
+		return "", err
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+}
+
+// parseShareToken checks the signature and expiry of a share token and returns its claims.
+func parseShareToken(token string) (*ResShareClaims, error) {
+
+	claims := &ResShareClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, acquireTokenSecret); err != nil {
+		return nil, err
+	}
+	if !claims.VerifyAudience(shareAudience, true) {
+		return nil, fmt.Errorf("not a share token")
+	}
+	if claims.ShareID == "" || claims.ResHash == "" {
+		return nil, fmt.Errorf("share token is missing its share or reservation")
+	}
+	return claims, nil
+}
+
+// newShareID returns a random ID for a share link, short enough for the owner to type when revoking it.
+func newShareID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// checkShareIDRules returns an error if id could not be a share link ID.
+func checkShareIDRules(id string) error {
+	if id == ShareRevokeAll {
+		return nil
+	}
+	if b, err := hex.DecodeString(id); err != nil || len(b) != 6 || strings.ToLower(id) != id {
+		return fmt.Errorf("'%s' is not a share link ID", id)
+	}
+	return nil
+}
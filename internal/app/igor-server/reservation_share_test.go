@@ -0,0 +1,148 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+)
+
+func setShareTokenKey(t *testing.T) {
+	orig := igor.AuthTokenKeypath
+	t.Cleanup(func() { igor.AuthTokenKeypath = orig })
+	igor.AuthTokenKeypath = filepath.Join(t.TempDir(), "jkey")
+	require.NoError(t, verifyJwtSecret())
+}
+
+func TestShareToken(t *testing.T) {
+
+	setShareTokenKey(t)
+
+	res := &Reservation{Name: "res1", Hash: "abc123"}
+	share := &ResShare{ShareID: "0123456789ab", Expires: time.Now().Add(time.Hour)}
+
+	token, err := signShareToken(share, res)
+	require.NoError(t, err)
+
+	claims, err := parseShareToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, share.ShareID, claims.ShareID)
+	assert.Equal(t, res.Hash, claims.ResHash)
+
+	// a tampered token is rejected
+	_, err = parseShareToken(token[:len(token)-2] + "xx")
+	assert.Error(t, err)
+
+	// so is an expired one
+	share.Expires = time.Now().Add(-time.Minute)
+	expired, err := signShareToken(share, res)
+	require.NoError(t, err)
+	_, err = parseShareToken(expired)
+	assert.Error(t, err)
+
+	// a login token can't be used as a share token
+	login, err := generateToken("alice", "0123456789abcdef", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = parseShareToken(login)
+	assert.Error(t, err)
+}
+
+func TestCheckResShare(t *testing.T) {
+
+	now := time.Now()
+	res := &Reservation{Name: "res1", Hash: "abc123", OwnerID: 1, Start: now.Add(-time.Hour), End: now.Add(time.Hour)}
+	share := &ResShare{ShareID: "0123456789ab", OwnerID: 1, Expires: now.Add(30 * time.Minute)}
+
+	assert.NoError(t, checkResShare(share, res, res.Hash, now))
+	assert.Error(t, checkResShare(share, res, "other", now))
+	assert.Error(t, checkResShare(share, res, res.Hash, now.Add(45*time.Minute)), "share expired")
+
+	ended := *res
+	ended.End = now.Add(-time.Minute)
+	assert.Error(t, checkResShare(share, &ended, res.Hash, now))
+
+	newOwner := *res
+	newOwner.OwnerID = 2
+	assert.Error(t, checkResShare(share, &newOwner, res.Hash, now))
+
+	// the owner only sees links that still work
+	res.Shares = []ResShare{*share, {ShareID: "ba9876543210", OwnerID: 1, Expires: now.Add(-time.Minute)}}
+	links := res.getResShareLinks()
+	require.Len(t, links, 1)
+	assert.Equal(t, share.ShareID, links[0].ID)
+	assert.Empty(t, links[0].Token)
+	assert.Empty(t, newOwner.getResShareLinks())
+}
+
+func TestAllowShareRequest(t *testing.T) {
+
+	origLimit := igor.Server.ShareRateLimit
+	t.Cleanup(func() {
+		igor.Server.ShareRateLimit = origLimit
+		shareRateMU.Lock()
+		shareRateHits = make(map[string]*shareRateCount)
+		shareRateMU.Unlock()
+	})
+	igor.Server.ShareRateLimit = 3
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		ok, _ := allowShareRequest("aaaaaaaaaaaa", now)
+		assert.True(t, ok)
+	}
+	ok, wait := allowShareRequest("aaaaaaaaaaaa", now.Add(20*time.Second))
+	assert.False(t, ok)
+	assert.Equal(t, 40*time.Second, wait)
+
+	// each link has its own limit
+	ok, _ = allowShareRequest("bbbbbbbbbbbb", now)
+	assert.True(t, ok)
+
+	// and the count starts over when the window is up
+	ok, _ = allowShareRequest("aaaaaaaaaaaa", now.Add(shareRateWindow))
+	assert.True(t, ok)
+}
+
+func TestPurgeResShares(t *testing.T) {
+
+	seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	now := time.Now()
+	live := Reservation{Name: "live", Hash: "h1", OwnerID: 1, Start: now.Add(-time.Hour), End: now.Add(time.Hour)}
+	ended := Reservation{Name: "ended", Hash: "h2", OwnerID: 1, Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)}
+	for _, r := range []*Reservation{&live, &ended} {
+		require.NoError(t, db.Omit(clause.Associations).Create(r).Error)
+	}
+
+	shares := []ResShare{
+		{ShareID: "000000000001", ReservationID: live.ID, OwnerID: 1, Expires: now.Add(time.Hour)},
+		{ShareID: "000000000002", ReservationID: live.ID, OwnerID: 1, Expires: now.Add(-time.Minute)},
+		{ShareID: "000000000003", ReservationID: live.ID, OwnerID: 2, Expires: now.Add(time.Hour)},
+		{ShareID: "000000000004", ReservationID: ended.ID, OwnerID: 1, Expires: now.Add(time.Hour)},
+		{ShareID: "000000000005", ReservationID: 999, OwnerID: 1, Expires: now.Add(time.Hour)},
+	}
+	require.NoError(t, db.Create(&shares).Error)
+
+	require.NoError(t, purgeResShares(&now))
+
+	var left []ResShare
+	require.NoError(t, db.Find(&left).Error)
+	require.Len(t, left, 1)
+	assert.Equal(t, "000000000001", left[0].ShareID)
+}
+
+func TestCheckShareIDRules(t *testing.T) {
+	assert.NoError(t, checkShareIDRules(ShareRevokeAll))
+	assert.NoError(t, checkShareIDRules("0123456789ab"))
+	assert.Error(t, checkShareIDRules("0123456789AB"))
+	assert.Error(t, checkShareIDRules("0123"))
+	assert.Error(t, checkShareIDRules("zz23456789ab"))
+}
@@ -0,0 +1,198 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	zl "github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// startUnavailableHosts returns the hosts of a reservation that can't be used when it starts because
+// they were blocked, drained or put in an error state after the reservation was made, along with a
+// short reason for each.
+func startUnavailableHosts(res *Reservation) ([]Host, []string) {
+
+	var lost []Host
+	var reasons []string
+
+	for _, h := range res.Hosts {
+		switch h.State {
+		case HostBlocked, HostDraining, HostError:
+			lost = append(lost, h)
+			reasons = append(reasons, fmt.Sprintf("%s (%s)", h.Name, h.State.String()))
+		}
+	}
+
+	return lost, reasons
+}
+
+// minStartHosts returns the fewest of its hosts a reservation needs to start. This is the minimum
+// given when the reservation was made or else the configured percentage of its hosts.
+func minStartHosts(res *Reservation) int {
+	if res.MinNodes > 0 {
+		return res.MinNodes
+	}
+	need := int(math.Ceil(float64(len(res.Hosts)) * float64(igor.Scheduler.MinStartPercent) / 100))
+	if need < 1 {
+		need = 1
+	}
+	return need
+}
+
+// startReconciled checks the hosts of a reservation about to be installed for the first time and lets its
+// members know of any hosts that were replaced or dropped. It returns false if the reservation should not
+// be installed, either because it failed to start or the check itself failed and will be tried again.
+func startReconciled(res *Reservation, now time.Time) bool {
+
+	var adjustMsg, failMsg string
+	var dropped []Host
+	if err := performDbTx(func(tx *gorm.DB) error {
+		var rErr error
+		adjustMsg, dropped, failMsg, rErr = reconcileStartHosts(res, now, tx, &logger)
+		return rErr
+	}); err != nil {
+		logger.Error().Msgf("failed to check the hosts of reservation '%s' before install - %v", res.Name, err)
+		return false
+	}
+
+	if adjustMsg == "" && failMsg == "" {
+		return true
+	}
+
+	var clusterName string
+	if clusters, cErr := dbReadClustersTx(nil); cErr != nil {
+		logger.Error().Msgf("%v", cErr)
+	} else {
+		clusterName = clusters[0].Name
+	}
+
+	if failMsg != "" {
+		logger.Error().Msgf("reservation '%s' failed to start - %s", res.Name, failMsg)
+		res.StartError = failMsg
+		if hErr := res.HistCallback(res, HrUpdated+":start-failed"); hErr != nil {
+			logger.Error().Msgf("failed to record reservation '%s' start failure to history", res.Name)
+		}
+		if failEvent := makeResWarnNotifyEvent(EmailResStartFail, 0, res.DeepCopy(), clusterName); failEvent != nil {
+			failEvent.Info = failMsg
+			resNotifyChan <- *failEvent
+		}
+		return false
+	}
+
+	if len(dropped) > 0 {
+		fireHook(newHookPayload(HookHostsDropped, hookRequestID(nil), res, dropped))
+	}
+	if hErr := res.HistCallback(res, HrUpdated+":start-hosts"); hErr != nil {
+		logger.Error().Msgf("failed to record reservation '%s' start host changes to history", res.Name)
+	}
+	if adjustEvent := makeResWarnNotifyEvent(EmailResStartAdjust, 0, res.DeepCopy(), clusterName); adjustEvent != nil {
+		adjustEvent.Info = adjustMsg
+		resNotifyChan <- *adjustEvent
+	}
+
+	return true
+}
+
+// reconcileStartHosts deals with any hosts of a reservation that became unavailable between the time it
+// was made and its start. If the scheduler picked the hosts, it first looks for free hosts to take their
+// place. Remaining unavailable hosts are dropped if enough hosts are left for the reservation to start.
+// The reservation's hosts are updated to the ones it will start with and the returned message describes
+// the change. If too few hosts are left the reservation is marked as failed to start and a message
+// naming the unavailable hosts is returned as failMsg.
+func reconcileStartHosts(res *Reservation, now time.Time, tx *gorm.DB, clog *zl.Logger) (adjustMsg string, dropped []Host, failMsg string, err error) {
+
+	lost, reasons := startUnavailableHosts(res)
+	if len(lost) == 0 {
+		return "", nil, "", nil
+	}
+
+	// substitutes are only used if the reservation can start once the hosts left without one are dropped
+	var subHosts, replaced []Host
+	if res.HostsByCount {
+		if subHosts, replaced, err = findStartSubstitutes(res, lost, now, tx, clog); err != nil {
+			return "", nil, "", err
+		}
+	}
+
+	dropped = lost[len(replaced):]
+	need := minStartHosts(res)
+	if remaining := len(res.Hosts) - len(dropped); remaining < need {
+		failMsg = fmt.Sprintf("only %d of the reservation's %d host(s) are available and at least %d are needed to start; unavailable host(s): %s",
+			remaining, len(res.Hosts), need, strings.Join(reasons, ", "))
+		if eErr := dbEditReservation(res, map[string]interface{}{"StartError": failMsg}, tx); eErr != nil {
+			return "", nil, "", eErr
+		}
+		return "", nil, failMsg, nil
+	}
+
+	var changes []string
+	if len(replaced) > 0 {
+		if rErr := dbReplaceResHosts(res, replaced, subHosts, tx); rErr != nil {
+			return "", nil, "", rErr
+		}
+		res.Hosts = append(keepResHosts(res.Hosts, replaced), subHosts...)
+		changes = append(changes, fmt.Sprintf("host(s) %s replaced by %s", common.UnsplitList(namesOfHosts(replaced)),
+			common.UnsplitList(namesOfHosts(subHosts))))
+	}
+	if len(dropped) > 0 {
+		if eErr := dbEditReservation(res, map[string]interface{}{"dropHosts": dropped}, tx); eErr != nil {
+			return "", nil, "", eErr
+		}
+		res.Hosts = keepResHosts(res.Hosts, dropped)
+		changes = append(changes, fmt.Sprintf("host(s) %s dropped", common.UnsplitList(namesOfHosts(dropped))))
+	}
+
+	adjustMsg = strings.Join(changes, "; ")
+	clog.Info().Msgf("reservation '%s' starting with changed hosts - %s", res.Name, adjustMsg)
+	return adjustMsg, dropped, "", nil
+}
+
+// findStartSubstitutes looks for free hosts that can run from now until the reservation ends to take the
+// place of its unavailable hosts. If there aren't enough for all of them, as many as can be found are
+// used. It returns the substitutes and the unavailable hosts they replace, which are the first of lost.
+func findStartSubstitutes(res *Reservation, lost []Host, now time.Time, tx *gorm.DB, clog *zl.Logger) ([]Host, []Host, error) {
+
+	for n := len(lost); n > 0; n-- {
+		subRes := res.DeepCopy()
+		subRes.Hosts = make([]Host, n)
+		subRes.Start = now
+		subRes.End = res.End
+		subHosts, shStatus, shErr := scheduleHostsByAvailability(subRes, tx, clog)
+		if shErr == nil {
+			return subHosts, lost[:n], nil
+		}
+		if shStatus != http.StatusConflict {
+			return nil, nil, shErr
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// keepResHosts returns the hosts that are not in remove.
+func keepResHosts(hosts []Host, remove []Host) []Host {
+	keep := make([]Host, 0, len(hosts))
+	for _, h := range hosts {
+		found := false
+		for _, rh := range remove {
+			if rh.ID == h.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			keep = append(keep, h)
+		}
+	}
+	return keep
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func setMinStartPercent(t *testing.T, percent int) {
+	orig := igor.Scheduler.MinStartPercent
+	t.Cleanup(func() { igor.Scheduler.MinStartPercent = orig })
+	igor.Scheduler.MinStartPercent = percent
+}
+
+func TestMinStartHosts(t *testing.T) {
+
+	setMinStartPercent(t, 50)
+	hosts := make([]Host, 5)
+
+	assert.Equal(t, 3, minStartHosts(&Reservation{Hosts: hosts}))
+	assert.Equal(t, 1, minStartHosts(&Reservation{Hosts: hosts[:1]}))
+	assert.Equal(t, 4, minStartHosts(&Reservation{Hosts: hosts, MinNodes: 4}))
+
+	igor.Scheduler.MinStartPercent = 100
+	assert.Equal(t, 5, minStartHosts(&Reservation{Hosts: hosts}))
+}
+
+func TestStartUnavailableHosts(t *testing.T) {
+	res := &Reservation{Hosts: []Host{
+		{Name: "kn1", State: HostReserved},
+		{Name: "kn2", State: HostBlocked},
+		{Name: "kn3", State: HostAvailable},
+		{Name: "kn4", State: HostError},
+	}}
+	lost, reasons := startUnavailableHosts(res)
+	assert.Equal(t, []string{"kn2", "kn4"}, namesOfHosts(lost))
+	assert.Len(t, reasons, 2)
+}
+
+// newStartTestRes adds a reservation starting now on the given hosts and returns it as read back from the db.
+func newStartTestRes(t *testing.T, db *gorm.DB, name string, hosts []Host, byCount bool, minNodes int) *Reservation {
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	var alice User
+	pug := Group{Name: GroupUserPrefix + "alice"}
+	if db.Where("name = ?", "alice").First(&alice).Error != nil {
+		require.NoError(t, db.Omit(clause.Associations).Create(&pug).Error)
+		alice = User{Name: "alice", Groups: []Group{pug, all}}
+		require.NoError(t, db.Omit("Groups.*").Create(&alice).Error)
+	} else {
+		require.NoError(t, db.Where("name = ?", pug.Name).First(&pug).Error)
+	}
+
+	now := time.Now()
+	res := Reservation{Name: name, Hash: name, OwnerID: alice.ID, GroupID: pug.ID, Start: now, End: now.Add(time.Hour),
+		Hosts: hosts, HostsByCount: byCount, MinNodes: minNodes}
+	require.NoError(t, db.Omit("Hosts.*").Omit(clause.Associations).Create(&res).Error)
+	require.NoError(t, db.Model(&res).Association("Hosts").Append(hosts))
+
+	stored, err := dbReadReservationsTx(map[string]interface{}{"name": name}, nil)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	return &stored[0]
+}
+
+func TestReconcileStartHosts(t *testing.T) {
+
+	setMinStartPercent(t, 50)
+	origSchedMinutes := MaxScheduleMinutes
+	t.Cleanup(func() { MaxScheduleMinutes = origSchedMinutes })
+	MaxScheduleMinutes = 45 * 24 * 60
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	// kn2 is blocked after the reservation was made with a node list, so it is dropped
+	res := newStartTestRes(t, db, "listed", hosts, false, 0)
+	require.NoError(t, db.Model(&Host{}).Where("name = ?", "kn2").Update("state", HostBlocked).Error)
+	res.Hosts[1].State = HostBlocked
+
+	var adjustMsg, failMsg string
+	var dropped []Host
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		var err error
+		adjustMsg, dropped, failMsg, err = reconcileStartHosts(res, time.Now(), tx, &logger)
+		return err
+	}))
+	assert.Empty(t, failMsg)
+	assert.Contains(t, adjustMsg, "kn2 dropped")
+	assert.Equal(t, []string{"kn2"}, namesOfHosts(dropped))
+	assert.Equal(t, []string{"kn1"}, namesOfHosts(res.Hosts))
+
+	stored, err := dbReadReservationsTx(map[string]interface{}{"name": "listed"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kn1"}, namesOfHosts(stored[0].Hosts))
+	require.NoError(t, db.Delete(&stored[0]).Error)
+	require.NoError(t, db.Exec("DELETE FROM reservations_hosts").Error)
+
+	// the same reservation can't start if it needs both hosts
+	res = newStartTestRes(t, db, "strict", hosts, false, 2)
+	res.Hosts[1].State = HostBlocked
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		var err error
+		adjustMsg, dropped, failMsg, err = reconcileStartHosts(res, time.Now(), tx, &logger)
+		return err
+	}))
+	assert.Empty(t, adjustMsg)
+	assert.Contains(t, failMsg, "kn2 (blocked)")
+
+	stored, err = dbReadReservationsTx(map[string]interface{}{"name": "strict"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, failMsg, stored[0].StartError)
+	assert.Len(t, stored[0].Hosts, 2)
+	assert.False(t, stored[0].IsActive(time.Now()))
+	require.NoError(t, db.Delete(&stored[0]).Error)
+	require.NoError(t, db.Exec("DELETE FROM reservations_hosts").Error)
+
+	// a reservation whose hosts were chosen by igor gets a free host in place of a blocked one
+	require.NoError(t, db.Model(&Host{}).Where("name = ?", "kn2").Update("state", HostAvailable).Error)
+	require.NoError(t, db.Model(&Host{}).Where("name = ?", "kn1").Update("state", HostBlocked).Error)
+	res = newStartTestRes(t, db, "counted", hosts[:1], true, 0)
+	res.Hosts[0].State = HostBlocked
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		var err error
+		adjustMsg, dropped, failMsg, err = reconcileStartHosts(res, time.Now(), tx, &logger)
+		return err
+	}))
+	assert.Empty(t, failMsg)
+	assert.Empty(t, dropped)
+	assert.Contains(t, adjustMsg, "kn1 replaced by kn2")
+
+	stored, err = dbReadReservationsTx(map[string]interface{}{"name": "counted"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kn2"}, namesOfHosts(stored[0].Hosts))
+}
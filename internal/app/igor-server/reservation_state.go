@@ -0,0 +1,54 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"time"
+
+	"igor2/internal/pkg/common"
+)
+
+// DefaultExpiringSoonWindow is how close to its end an active reservation is considered to be expiring
+// soon when reservation warning emails aren't sent.
+const DefaultExpiringSoonWindow = 24 * time.Hour
+
+// expiringSoonWindow returns how close to its end an active reservation is considered to be expiring
+// soon. It matches the window of the final expiration warning when those are sent.
+func expiringSoonWindow() time.Duration {
+	if len(ResNotifyTimes) > 0 {
+		return ResNotifyTimes[0]
+	}
+	return DefaultExpiringSoonWindow
+}
+
+// resState returns the state of the reservation at time t. This is the one place the state is worked
+// out from the raw fields so every client shows the same thing.
+//
+// A reservation past its end is only waiting for the next closeout to release its hosts, so it is
+// expired no matter how its install went. Approval, pause and start failure come before the start
+// time since a reservation can be in any of them before or after it would have started. Install
+// errors are only set by an install, so a future reservation never has one.
+func (r *Reservation) resState(t time.Time) string {
+	switch {
+	case !r.End.After(t):
+		return common.ResStateExpired
+	case r.awaitingApproval():
+		return common.ResStatePendingApproval
+	case r.isPaused():
+		return common.ResStatePaused
+	case r.startFailed():
+		return common.ResStateStartFailed
+	case r.Start.After(t):
+		return common.ResStateFuture
+	case r.InstallError != "":
+		return common.ResStateInstallError
+	case !r.Installed:
+		return common.ResStateInstalling
+	case r.End.Sub(t) <= expiringSoonWindow():
+		return common.ResStateExpiringSoon
+	default:
+		return common.ResStateActive
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestResState(t *testing.T) {
+
+	origTimes := ResNotifyTimes
+	t.Cleanup(func() { ResNotifyTimes = origTimes })
+	ResNotifyTimes = nil
+
+	now := time.Now()
+	past := now.Add(-2 * time.Hour)
+	soon := now.Add(time.Hour)
+	later := now.Add(72 * time.Hour)
+	farLater := now.Add(96 * time.Hour)
+
+	tests := []struct {
+		name  string
+		res   Reservation
+		state string
+	}{
+		{"future", Reservation{Start: soon, End: later}, common.ResStateFuture},
+		// an install error can't be set before the reservation starts, but it doesn't change the state if it is
+		{"future with install error", Reservation{Start: soon, End: later, InstallError: "boom"}, common.ResStateFuture},
+		{"future installed", Reservation{Start: soon, End: later, Installed: true}, common.ResStateFuture},
+		{"installing", Reservation{Start: past, End: later}, common.ResStateInstalling},
+		{"active", Reservation{Start: past, End: later, Installed: true}, common.ResStateActive},
+		{"install error", Reservation{Start: past, End: later, InstallError: "boom"}, common.ResStateInstallError},
+		{"installed with install error", Reservation{Start: past, End: later, Installed: true, InstallError: "boom"},
+			common.ResStateInstallError},
+		{"expiring soon", Reservation{Start: past, End: soon, Installed: true}, common.ResStateExpiringSoon},
+		{"install error expiring soon", Reservation{Start: past, End: soon, InstallError: "boom"}, common.ResStateInstallError},
+		{"installing expiring soon", Reservation{Start: past, End: soon}, common.ResStateInstalling},
+		{"installed past end", Reservation{Start: past, End: now.Add(-time.Minute), Installed: true}, common.ResStateExpired},
+		{"ends now", Reservation{Start: past, End: now, Installed: true}, common.ResStateExpired},
+		{"install error past end", Reservation{Start: past, End: now.Add(-time.Minute), InstallError: "boom"},
+			common.ResStateExpired},
+		{"never installed past end", Reservation{Start: past, End: now.Add(-time.Minute)}, common.ResStateExpired},
+		{"paused", Reservation{Start: later, End: farLater, PausedUntil: later}, common.ResStatePaused},
+		{"paused past end", Reservation{Start: past, End: now.Add(-time.Minute), PausedUntil: past}, common.ResStateExpired},
+		{"pending approval before start", Reservation{Start: soon, End: later, ApprovalUntil: soon}, common.ResStatePendingApproval},
+		{"pending approval after start", Reservation{Start: past, End: later, ApprovalUntil: soon}, common.ResStatePendingApproval},
+		{"start failed", Reservation{Start: past, End: later, StartError: "too many hosts down"}, common.ResStateStartFailed},
+		{"start failed with install error", Reservation{Start: past, End: later, StartError: "too many hosts down",
+			InstallError: "boom"}, common.ResStateStartFailed},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.state, tt.res.resState(now), tt.name)
+	}
+
+	// the window follows the final expiration warning when warnings are sent
+	res := Reservation{Start: past, End: now.Add(36 * time.Hour), Installed: true}
+	assert.Equal(t, common.ResStateActive, res.resState(now))
+	ResNotifyTimes = []time.Duration{48 * time.Hour, 72 * time.Hour}
+	assert.Equal(t, common.ResStateExpiringSoon, res.resState(now))
+	res.End = later
+	assert.Equal(t, common.ResStateActive, res.resState(now))
+}
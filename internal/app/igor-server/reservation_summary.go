@@ -0,0 +1,420 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"igor2/internal/pkg/common"
+)
+
+// resSummary holds only the parts of a reservation that go into the reservation data sent to users.
+// The show path reads these straight from the db with dbReadResSummaries instead of loading every
+// reservation with all of its associations.
+type resSummary struct {
+	ID             int
+	Name           string
+	Description    string
+	OwnerID        int
+	OwnerName      string
+	GroupID        int
+	GroupName      string
+	ProfileName    string
+	DistroName     string
+	DistroNotes    string
+	DistroArgs     string
+	ProfileArgs    string
+	Vlan           int
+	Start          time.Time
+	End            time.Time
+	OrigEnd        time.Time
+	ReqDuration    time.Duration
+	ReqNodeCount   int
+	ExtendCount    int
+	Installed      bool
+	InstallError   string
+	PausedUntil    time.Time
+	ResumeError    string
+	StartError     string
+	ApprovalUntil  time.Time
+	ApprovalReason string
+	HeadHostID     int
+	EndPower       string
+	NetProfile     string
+	CycleAt        time.Time
+	Hosts          []resSummaryHost `gorm:"-"`
+	CoOwners       []string         `gorm:"-"`
+	NotifyAlso     []string         `gorm:"-"`
+	Shares         []ResShare       `gorm:"-"`
+}
+
+// resSummaryHost is a host of a resSummary. InstallError is only set when the reservation failed
+// to install.
+type resSummaryHost struct {
+	ReservationID int
+	ID            int
+	Name          string
+	HostName      string
+	Console       string
+	Eth           string
+	Ports         string
+	SequenceID    int
+	InstallError  string
+}
+
+// summarizeReservation copies the parts of a fully loaded reservation that are sent to users.
+func summarizeReservation(r *Reservation) resSummary {
+
+	s := resSummary{
+		ID:             r.ID,
+		Name:           r.Name,
+		Description:    r.Description,
+		OwnerID:        r.OwnerID,
+		OwnerName:      r.Owner.Name,
+		GroupID:        r.GroupID,
+		GroupName:      r.Group.Name,
+		ProfileName:    r.Profile.Name,
+		DistroName:     r.Profile.Distro.Name,
+		DistroNotes:    r.Profile.Distro.UsageNotes,
+		DistroArgs:     r.Profile.Distro.KernelArgs,
+		ProfileArgs:    r.Profile.KernelArgs,
+		Vlan:           r.Vlan,
+		Start:          r.Start,
+		End:            r.End,
+		OrigEnd:        r.OrigEnd,
+		ReqDuration:    r.ReqDuration,
+		ReqNodeCount:   r.ReqNodeCount,
+		ExtendCount:    r.ExtendCount,
+		Installed:      r.Installed,
+		InstallError:   r.InstallError,
+		PausedUntil:    r.PausedUntil,
+		ResumeError:    r.ResumeError,
+		StartError:     r.StartError,
+		ApprovalUntil:  r.ApprovalUntil,
+		ApprovalReason: r.ApprovalReason,
+		HeadHostID:     r.HeadHostID,
+		EndPower:       r.EndPower,
+		NetProfile:     r.NetProfile,
+		CycleAt:        r.CycleAt,
+		Hosts:          make([]resSummaryHost, len(r.Hosts)),
+		CoOwners:       make([]string, 0, len(r.CoOwners)),
+		Shares:         r.Shares,
+	}
+	for i, h := range r.Hosts {
+		s.Hosts[i] = resSummaryHost{ReservationID: r.ID, ID: h.ID, Name: h.Name, HostName: h.HostName, Console: h.Console,
+			Eth: h.Eth, Ports: h.Ports, SequenceID: h.SequenceID, InstallError: h.InstallError}
+	}
+	for _, u := range r.CoOwners {
+		s.CoOwners = append(s.CoOwners, u.Name)
+	}
+	for _, u := range r.NotifyAlso {
+		s.NotifyAlso = append(s.NotifyAlso, u.Name)
+	}
+	return s
+}
+
+// reservation returns a reservation holding just enough of the summary to check its state and who
+// can see it.
+func (s *resSummary) reservation() *Reservation {
+	r := &Reservation{
+		Base:          Base{ID: s.ID},
+		OwnerID:       s.OwnerID,
+		GroupID:       s.GroupID,
+		Start:         s.Start,
+		End:           s.End,
+		Installed:     s.Installed,
+		InstallError:  s.InstallError,
+		PausedUntil:   s.PausedUntil,
+		StartError:    s.StartError,
+		ApprovalUntil: s.ApprovalUntil,
+		Shares:        s.Shares,
+	}
+	for _, name := range s.CoOwners {
+		r.CoOwners = append(r.CoOwners, User{Name: name})
+	}
+	return r
+}
+
+// dbReadResSummaries reads the summaries of all reservations matching the time parameters. Only the
+// columns needed are selected and the hosts, co-owners and users copied on email are read with one
+// query each. Share links are only read for reservations owned by the user since no one else is sent
+// them.
+func dbReadResSummaries(timeParams map[string]time.Time, user *User, tx *gorm.DB) ([]resSummary, error) {
+
+	q := tx.Table("reservations").
+		Select("reservations.id, reservations.name, reservations.description, reservations.owner_id, owner.name AS owner_name, " +
+			"reservations.group_id, grp.name AS group_name, profiles.name AS profile_name, distros.name AS distro_name, " +
+			"distros.usage_notes AS distro_notes, distros.kernel_args AS distro_args, profiles.kernel_args AS profile_args, " +
+			"reservations.vlan, reservations.start, reservations.end, reservations.orig_end, reservations.req_duration, " +
+			"reservations.req_node_count, reservations.extend_count, reservations.installed, reservations.install_error, " +
+			"reservations.paused_until, reservations.resume_error, reservations.start_error, reservations.approval_until, " +
+			"reservations.approval_reason, reservations.head_host_id, reservations.end_power, " +
+			"reservations.net_profile, reservations.cycle_at").
+		Joins("LEFT JOIN users AS owner ON owner.id = reservations.owner_id").
+		Joins("LEFT JOIN groups AS grp ON grp.id = reservations.group_id").
+		Joins("LEFT JOIN profiles ON profiles.id = reservations.profile_id").
+		Joins("LEFT JOIN distros ON distros.id = profiles.distro_id")
+	if len(timeParams) > 0 {
+		resolveTimeWhereClauses(timeParams, q)
+	}
+
+	var summaries []resSummary
+	if result := q.Scan(&summaries); result.Error != nil {
+		return nil, result.Error
+	}
+	if len(summaries) == 0 {
+		return summaries, nil
+	}
+
+	resIndex := make(map[int]int, len(summaries))
+	resIDs := make([]int, len(summaries))
+	for i := range summaries {
+		resIndex[summaries[i].ID] = i
+		resIDs[i] = summaries[i].ID
+	}
+
+	var hosts []resSummaryHost
+	if result := tx.Table("reservations_hosts").
+		Select("reservations_hosts.reservation_id, hosts.id, hosts.name, hosts.host_name, hosts.console, hosts.eth, hosts.ports, "+
+			"hosts.sequence_id, reservations_hosts.install_error").
+		Joins("JOIN hosts ON hosts.id = reservations_hosts.host_id").
+		Where("reservations_hosts.reservation_id IN ?", resIDs).Scan(&hosts); result.Error != nil {
+		return nil, result.Error
+	}
+	for _, h := range hosts {
+		s := &summaries[resIndex[h.ReservationID]]
+		// as with a fully loaded reservation, host install errors are only kept when the install failed
+		if s.InstallError == "" {
+			h.InstallError = ""
+		}
+		s.Hosts = append(s.Hosts, h)
+	}
+
+	var coOwners []struct {
+		ReservationID int
+		Name          string
+	}
+	if result := tx.Table("reservations_coowners").
+		Select("reservations_coowners.reservation_id, users.name").
+		Joins("JOIN users ON users.id = reservations_coowners.user_id").
+		Where("reservations_coowners.reservation_id IN ?", resIDs).Scan(&coOwners); result.Error != nil {
+		return nil, result.Error
+	}
+	for _, c := range coOwners {
+		s := &summaries[resIndex[c.ReservationID]]
+		s.CoOwners = append(s.CoOwners, c.Name)
+	}
+
+	var notifyAlso []struct {
+		ReservationID int
+		Name          string
+	}
+	if result := tx.Table("reservations_notify_also").
+		Select("reservations_notify_also.reservation_id, users.name").
+		Joins("JOIN users ON users.id = reservations_notify_also.user_id").
+		Where("reservations_notify_also.reservation_id IN ?", resIDs).Scan(&notifyAlso); result.Error != nil {
+		return nil, result.Error
+	}
+	for _, n := range notifyAlso {
+		s := &summaries[resIndex[n.ReservationID]]
+		s.NotifyAlso = append(s.NotifyAlso, n.Name)
+	}
+
+	if user != nil {
+		var shares []ResShare
+		if result := tx.Where("owner_id = ? AND reservation_id IN ?", user.ID, resIDs).Find(&shares); result.Error != nil {
+			return nil, result.Error
+		}
+		for _, sh := range shares {
+			s := &summaries[resIndex[sh.ReservationID]]
+			s.Shares = append(s.Shares, sh)
+		}
+	}
+
+	return summaries, nil
+}
+
+// filterResSummaries converts reservation summaries into the data sent to the user.
+func filterResSummaries(summaries []resSummary, user *User) []common.ReservationData {
+
+	var reportList []common.ReservationData
+
+	refreshPowerChan <- struct{}{}
+
+	// console links of hosts whose policy restricts the console to admins are only shown to admins
+	showConsoles := igor.Server.ConsoleURL != ""
+	var consoleRestricted []int
+	if showConsoles && user != nil && !userElevated(user.Name) {
+		var crErr error
+		if consoleRestricted, crErr = dbHostIDsRestrictingActionTx(NodeActionConsole); crErr != nil {
+			logger.Error().Msgf("unable to read hosts with a restricted console, hiding console links - %v", crErr)
+			showConsoles = false
+		}
+	}
+
+	for _, s := range summaries {
+
+		sort.Slice(s.Hosts, func(i, j int) bool {
+			return s.Hosts[i].SequenceID < s.Hosts[j].SequenceID
+		})
+
+		hostNameList := make([]string, len(s.Hosts))
+		hostPowered := make(map[string]string, len(s.Hosts))
+		var installErrHosts []string
+		var headHost string
+		for i, h := range s.Hosts {
+			hostNameList[i] = h.Name
+			if h.ID == s.HeadHostID || (i == 0 && headHost == "") {
+				headHost = h.Name
+			}
+			if h.InstallError != "" {
+				installErrHosts = append(installErrHosts, h.Name)
+			}
+		}
+
+		powerMapMU.Lock()
+		for _, h := range s.Hosts {
+			hostPowered[h.Name] = "unknown"
+			if powered := powerMap[h.HostName]; powered != nil {
+				hostPowered[h.Name] = "false"
+				if *powered {
+					hostPowered[h.Name] = "true"
+				}
+			}
+		}
+		powerMapMU.Unlock()
+
+		remaining := time.Until(s.End).Round(time.Hour) / time.Hour
+
+		var groupName string
+		if !strings.HasPrefix(s.GroupName, GroupUserPrefix) {
+			groupName = s.GroupName
+		}
+
+		hostRange, _ := igor.ClusterRefs[0].UnsplitRange(hostNameList)
+
+		var resDownNodes = make([]string, 0, len(s.Hosts))
+		var resPowerNaNodes = make([]string, 0, len(s.Hosts))
+		var resUpNodes = make([]string, 0, len(s.Hosts))
+
+		for _, h := range hostNameList {
+			if hostPowered[h] == "false" {
+				resDownNodes = append(resDownNodes, h)
+			} else if hostPowered[h] == "unknown" {
+				resDownNodes = append(resPowerNaNodes, h)
+			} else {
+				resUpNodes = append(resUpNodes, h)
+			}
+		}
+
+		hostsUp, _ := igor.ClusterRefs[0].UnsplitRange(resUpNodes)
+		hostsDown, _ := igor.ClusterRefs[0].UnsplitRange(resDownNodes)
+		hostsUnknown, _ := igor.ClusterRefs[0].UnsplitRange(resPowerNaNodes)
+
+		coOwners := append(make([]string, 0, len(s.CoOwners)), s.CoOwners...)
+		sort.Strings(coOwners)
+		notifyAlso := append([]string(nil), s.NotifyAlso...)
+		sort.Strings(notifyAlso)
+
+		res := s.reservation()
+
+		resCopy := common.ReservationData{
+			Name:              s.Name,
+			Description:       s.Description,
+			Owner:             s.OwnerName,
+			CoOwners:          coOwners,
+			NotifyAlso:        notifyAlso,
+			DescriptionHTML:   renderMarkdownString(s.Description),
+			Group:             groupName,
+			Start:             s.Start.Unix(),
+			End:               s.End.Unix(),
+			OrigEnd:           s.OrigEnd.Unix(),
+			ExtendCount:       s.ExtendCount,
+			Installed:         s.Installed,
+			InstallError:      s.InstallError,
+			InstallErrorHosts: installErrHosts,
+			Distro:            s.DistroName,
+			Profile:           s.ProfileName,
+			Hosts:             hostNameList,
+			HeadHost:          headHost,
+			HostRange:         hostRange,
+			HostsUp:           hostsUp,
+			HostsDown:         hostsDown,
+			HostsPowerNA:      hostsUnknown,
+			Vlan:              s.Vlan,
+			RemainHours:       int(remaining),
+			Paused:            res.isPaused(),
+			ResumeError:       s.ResumeError,
+			StartError:        s.StartError,
+			State:             res.resState(time.Now()),
+		}
+
+		if s.EndPower == EndPowerLeaveOn {
+			resCopy.EndPower = s.EndPower
+		}
+
+		if igor.vlanEnabled() {
+			resCopy.IsolatedPorts = make(map[string][]string, len(s.Hosts))
+			for _, h := range s.Hosts {
+				host := Host{Eth: h.Eth, Ports: h.Ports}
+				for _, p := range host.isolatedPorts() {
+					resCopy.IsolatedPorts[h.Name] = append(resCopy.IsolatedPorts[h.Name], p.Role)
+				}
+			}
+		}
+
+		if res.awaitingApproval() {
+			resCopy.PendingApproval = true
+			resCopy.ApprovalExpires = s.ApprovalUntil.Unix()
+			resCopy.ApprovalReason = s.ApprovalReason
+		}
+
+		if !s.CycleAt.IsZero() {
+			resCopy.CycleAt = s.CycleAt.Unix()
+		}
+
+		if userElevated(user.Name) {
+			resCopy.ReqDuration = int64(s.ReqDuration / time.Minute)
+			resCopy.ReqNodeCount = s.ReqNodeCount
+			resCopy.NetProfile = s.NetProfile
+			// reservations made before a group was required are left alone but flagged so they can be moved
+			resCopy.NeedsGroup = igor.Scheduler.RequireReservationGroup && groupName == ""
+		}
+
+		// notes can hold things like the default login of the distro, so only members get them along
+		// with the kernel line
+		if user != nil && (userElevated(user.Name) || res.hasMember(user)) {
+			resCopy.DistroNotes = s.DistroNotes
+			resCopy.KernelLine = mergeKernelArgs(s.DistroArgs, s.ProfileArgs)
+		}
+
+		if showConsoles && res.canSeeConsoles(user) {
+			resCopy.Consoles = make(map[string]string, len(s.Hosts))
+			for _, h := range s.Hosts {
+				if !slices.Contains(consoleRestricted, h.ID) {
+					host := Host{Name: h.Name, Console: h.Console}
+					resCopy.Consoles[h.Name] = host.consoleLink()
+				}
+			}
+		}
+
+		if user != nil && s.OwnerID == user.ID {
+			resCopy.Shares = res.getResShareLinks()
+		}
+
+		reportList = append(reportList, resCopy)
+	}
+
+	sort.Slice(reportList, func(i, j int) bool {
+		return reportList[i].Name < reportList[j].Name
+	})
+
+	return reportList
+}
@@ -0,0 +1,236 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+// newShowTestDb fills a test db with resCount reservations of 4 hosts each, covering co-owners, share
+// links, install errors and paused, failed and pending reservations. It returns the owner of the
+// reservations.
+func newShowTestDb(t testing.TB, resCount int) *User {
+
+	origRefs, origPower, origPowerChan, origConsole := igor.ClusterRefs, powerMap, refreshPowerChan, igor.Server.ConsoleURL
+	t.Cleanup(func() {
+		igor.ClusterRefs, powerMap, refreshPowerChan, igor.Server.ConsoleURL = origRefs, origPower, origPowerChan, origConsole
+	})
+	r, _ := common.NewRange("kn", 1, 40)
+	igor.ClusterRefs = []common.Range{*r}
+	refreshPowerChan = make(chan struct{}, resCount+10)
+	igor.Server.ConsoleURL = "https://console.example.com/%s"
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	for i := 3; i <= 40; i++ {
+		h := Host{Name: fmt.Sprintf("kn%d", i), HostName: fmt.Sprintf("kn%d", i), SequenceID: i, Mac: fmt.Sprintf("00:00:00:00:01:%02x", i),
+			State: HostReserved, HostPolicyID: hosts[0].HostPolicyID}
+		require.NoError(t, db.Omit(clause.Associations).Create(&h).Error)
+		hosts = append(hosts, h)
+	}
+
+	on, off := true, false
+	powerMap = map[string]*bool{"kn1": &on, "kn2": &off, "kn3": &on, "kn5": &off}
+
+	alice := User{Name: "alice", Email: "alice@example.com"}
+	bob := User{Name: "bob", Email: "bob@example.com"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&alice).Error)
+	require.NoError(t, db.Omit(clause.Associations).Create(&bob).Error)
+	pug := Group{Name: GroupUserPrefix + "alice"}
+	lab := Group{Name: "lab"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&pug).Error)
+	require.NoError(t, db.Omit(clause.Associations).Create(&lab).Error)
+	alice.Groups = []Group{pug, lab}
+
+	distro := Distro{Name: "rhel", OwnerID: alice.ID}
+	require.NoError(t, db.Omit(clause.Associations).Create(&distro).Error)
+	profile := Profile{Name: "rhel-prof", OwnerID: alice.ID, DistroID: distro.ID}
+	require.NoError(t, db.Omit(clause.Associations).Create(&profile).Error)
+
+	now := time.Now().Truncate(time.Second)
+	for i := 0; i < resCount; i++ {
+		res := Reservation{Name: fmt.Sprintf("res%04d", i), Hash: fmt.Sprintf("h%d", i), Description: "test", OwnerID: alice.ID,
+			GroupID: pug.ID, ProfileID: profile.ID, Vlan: i, Start: now.Add(-time.Hour), End: now.Add(time.Duration(i+1) * time.Hour),
+			OrigEnd: now.Add(time.Hour), ReqDuration: 90 * time.Minute, ReqNodeCount: 4, ExtendCount: i % 3, Installed: true}
+		switch i % 5 {
+		case 1:
+			res.GroupID = lab.ID
+			res.InstallError = "install failed"
+		case 2:
+			res.PausedUntil = now.Add(time.Hour)
+			res.Installed = false
+		case 3:
+			res.ApprovalUntil = now.Add(2 * time.Hour)
+			res.ApprovalReason = "too big"
+			res.Installed = false
+		case 4:
+			res.StartError = "no hosts"
+		}
+		require.NoError(t, db.Omit(clause.Associations).Create(&res).Error)
+
+		start := (i * 4) % len(hosts)
+		for j := 0; j < 4; j++ {
+			rh := ReservationHost{ReservationID: res.ID, HostID: hosts[(start+j)%len(hosts)].ID}
+			if i%5 == 1 && j == 0 {
+				rh.InstallError = "timed out"
+			}
+			require.NoError(t, db.Create(&rh).Error)
+		}
+		if i%2 == 0 {
+			require.NoError(t, db.Exec("INSERT INTO reservations_coowners (reservation_id, user_id) VALUES (?, ?)", res.ID, bob.ID).Error)
+		}
+		if i%7 == 0 {
+			share := ResShare{ShareID: fmt.Sprintf("s%d", i), ReservationID: res.ID, OwnerID: alice.ID, Expires: now.Add(24 * time.Hour)}
+			require.NoError(t, db.Create(&share).Error)
+		}
+	}
+
+	return &alice
+}
+
+func TestResSummariesMatchFullRead(t *testing.T) {
+
+	alice := newShowTestDb(t, 25)
+	db := igor.IGormDb.GetDB()
+
+	for _, user := range []*User{alice, {Name: "carol"}, {Name: IgorAdmin}} {
+		for _, timeParams := range []map[string]time.Time{nil, {"to-end": time.Now().Add(10 * time.Hour)}} {
+
+			resList, err := dbReadReservations(nil, timeParams, db)
+			require.NoError(t, err)
+			full, err := json.Marshal(filterReservationList(resList, user))
+			require.NoError(t, err)
+
+			summaries, err := dbReadResSummaries(timeParams, user, db)
+			require.NoError(t, err)
+			light, err := json.Marshal(filterResSummaries(summaries, user))
+			require.NoError(t, err)
+
+			assert.JSONEq(t, string(full), string(light), "user %s, time params %v", user.Name, timeParams)
+			assert.Equal(t, string(full), string(light))
+		}
+	}
+
+	// the fixture covers the states the show output marks
+	summaries, err := dbReadResSummaries(nil, alice, db)
+	require.NoError(t, err)
+	data := filterResSummaries(summaries, alice)
+	require.Len(t, data, 25)
+	assert.Equal(t, []string{"bob"}, data[0].CoOwners)
+	assert.Len(t, data[0].Shares, 1)
+	assert.Equal(t, []string{"kn5"}, data[1].InstallErrorHosts)
+	assert.True(t, data[2].Paused)
+	assert.True(t, data[3].PendingApproval)
+	assert.NotEmpty(t, data[4].StartError)
+	states := make([]string, 5)
+	for i := range states {
+		states[i] = data[i].State
+	}
+	assert.Equal(t, []string{common.ResStateExpiringSoon, common.ResStateInstallError, common.ResStatePaused,
+		common.ResStatePendingApproval, common.ResStateStartFailed}, states)
+}
+
+func benchmarkShowReservations(b *testing.B, read func(*User) []common.ReservationData) {
+	alice := newShowTestDb(b, 1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		refreshPowerChan = make(chan struct{}, 10)
+		if len(read(alice)) != 1000 {
+			b.Fatal("wrong number of reservations")
+		}
+	}
+}
+
+func BenchmarkShowReservationsFullRead(b *testing.B) {
+	benchmarkShowReservations(b, func(user *User) []common.ReservationData {
+		resList, err := dbReadReservations(nil, nil, igor.IGormDb.GetDB())
+		if err != nil {
+			b.Fatal(err)
+		}
+		return filterReservationList(resList, user)
+	})
+}
+
+func BenchmarkShowReservationsSummaryRead(b *testing.B) {
+	benchmarkShowReservations(b, func(user *User) []common.ReservationData {
+		summaries, err := dbReadResSummaries(nil, user, igor.IGormDb.GetDB())
+		if err != nil {
+			b.Fatal(err)
+		}
+		return filterResSummaries(summaries, user)
+	})
+}
+
+func TestResHeadHost(t *testing.T) {
+
+	origRefs, origPowerChan := igor.ClusterRefs, refreshPowerChan
+	t.Cleanup(func() { igor.ClusterRefs, refreshPowerChan = origRefs, origPowerChan })
+	r, _ := common.NewRange("kn", 1, 20)
+	igor.ClusterRefs = []common.Range{*r}
+	refreshPowerChan = make(chan struct{}, 10)
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	for _, seq := range []int{10, 3} {
+		h := Host{Name: fmt.Sprintf("kn%d", seq), HostName: fmt.Sprintf("kn%d", seq), SequenceID: seq,
+			Mac: fmt.Sprintf("00:00:00:00:01:%02x", seq), State: HostAvailable, HostPolicyID: hosts[0].HostPolicyID}
+		require.NoError(t, db.Omit(clause.Associations).Create(&h).Error)
+		hosts = append(hosts, h)
+	}
+
+	// hosts are added out of order but always read in sequence order
+	res := newStartTestRes(t, db, "r1", []Host{hosts[2], hosts[1], hosts[3], hosts[0]}, false, 0)
+	assert.Equal(t, []string{"kn1", "kn2", "kn3", "kn10"}, namesOfHosts(res.Hosts))
+	assert.Equal(t, "kn1", res.headHost().Name)
+
+	changes, status, err := parseResEditParams(res, map[string]interface{}{"head": "kn9"}, db)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	changes, _, err = parseResEditParams(res, map[string]interface{}{"head": "kn3"}, db)
+	require.NoError(t, err)
+	require.NoError(t, dbEditReservation(res, changes, db))
+
+	resList, err := dbReadReservations(map[string]interface{}{"name": "r1"}, nil, db)
+	require.NoError(t, err)
+	res = &resList[0]
+	assert.Equal(t, "kn3", res.headHost().Name)
+	data := filterReservationList(resList, &res.Owner)
+	assert.Equal(t, "kn3", data[0].HeadHost)
+	assert.Equal(t, []string{"kn1", "kn2", "kn3", "kn10"}, data[0].Hosts)
+
+	// dropping another host keeps the head, dropping the head falls back to the first host left
+	require.NoError(t, db.Model(res).Update("start", time.Now().Add(time.Hour)).Error)
+	res.Start = time.Now().Add(time.Hour)
+	changes, _, err = parseDrop(res, "kn1", db)
+	require.NoError(t, err)
+	assert.NotContains(t, changes, "HeadHostID")
+	require.NoError(t, dbEditReservation(res, changes, db))
+
+	resList, err = dbReadReservations(map[string]interface{}{"name": "r1"}, nil, db)
+	require.NoError(t, err)
+	res = &resList[0]
+	assert.Equal(t, "kn3", res.headHost().Name)
+
+	changes, _, err = parseDrop(res, "kn3", db)
+	require.NoError(t, err)
+	require.NoError(t, dbEditReservation(res, changes, db))
+	resList, err = dbReadReservations(map[string]interface{}{"name": "r1"}, nil, db)
+	require.NoError(t, err)
+	assert.Zero(t, resList[0].HeadHostID)
+	assert.Equal(t, "kn2", resList[0].headHost().Name)
+}
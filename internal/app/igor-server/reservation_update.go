@@ -6,26 +6,26 @@ package igorserver
 
 import (
 	"fmt"
-	"math"
 	"net/http"
 	"sort"
 	"strings"
 	"time"
 
+	zl "github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
 	"gorm.io/gorm"
 
 	"igor2/internal/pkg/common"
 )
 
-func doUpdateReservation(resName string, editParams map[string]interface{}, r *http.Request) (status int, err error) {
+func doUpdateReservation(resName string, editParams map[string]interface{}, r *http.Request) (clampMsg string, status int, err error) {
 
 	status = http.StatusInternalServerError // default status, overridden at end if no errors
 	clog := hlog.FromRequest(r)
 	var res *Reservation
 	actionUser := getUserFromContext(r)
 	isElevated := userElevated(actionUser.Name)
-	var extended, renamed, dropped, isNewOwner, isNewGroup bool
+	var extended, renamed, dropped, droppedHead, isNewOwner, isNewGroup, newVlan, newNetProfile, newImage, cycleCanceled bool
 	var clusterName, oldName, newOwnerName string
 	var oldOwner User
 	var droppedHosts []Host
@@ -56,8 +56,24 @@ func doUpdateReservation(resName string, editParams map[string]interface{}, r *h
 		_, renamed = editParams["name"]
 		newOwnerName, isNewOwner = editParams["owner"].(string)
 		_, isNewGroup = editParams["group"]
+		_, newVlan = editParams["vlan"]
+		_, newNetProfile = editParams["netProfile"]
+		_, doCycleNow := editParams["cycleNow"]
+		_, doCycleAt := editParams["cycleAt"]
+		_, cycleCanceled = editParams["cancelCycle"]
 		var changes map[string]interface{}
 		var vErr error
+		if groupName, _ := editParams["group"].(string); groupName == GroupNoneAlias && igor.Scheduler.RequireReservationGroup {
+			if !isElevated {
+				status = http.StatusBadRequest
+				return fmt.Errorf("reservations must belong to a group on this server -- the group of '%s' cannot be removed", resName)
+			}
+			clog.Info().Msgf("user '%s' is invoking admin privileges to remove the group of reservation '%s'", actionUser.Name, resName)
+		}
+		if newNetProfile && !isElevated {
+			status = http.StatusForbidden
+			return fmt.Errorf("setting the network profile of a reservation requires admin elevated privilege")
+		}
 		if doExtendF || doExtendS || doExtendMax {
 
 			if igor.Scheduler.ExtendWithin < 0 {
@@ -73,7 +89,8 @@ func doUpdateReservation(resName string, editParams map[string]interface{}, r *h
 			if doExtendF {
 				extendDur = time.Unix(int64(extendTime), 0).Format(common.DateTimeCompactFormat)
 			}
-			changes, status, vErr = parseExtend(res, extendDur, isElevated, r, tx)
+			clampToLimit, _ := editParams["clampToLimit"].(bool)
+			changes, clampMsg, status, vErr = parseExtend(res, extendDur, clampToLimit, isElevated, r, tx)
 		} else if isNewOwner && newOwnerName == IgorAdmin {
 			status = http.StatusBadRequest
 			clog.Warn().Msgf("'%s' unsuccessully attempted to change reservation owner of '%s' to igor-admin", actionUser.Name, resName)
@@ -83,11 +100,39 @@ func doUpdateReservation(resName string, editParams map[string]interface{}, r *h
 			if vErr == nil {
 				dropped = true
 				droppedHosts = changes["dropHosts"].([]Host)
+				_, droppedHead = changes["HeadHostID"]
 			}
 		} else if doDistro || doProfile {
+			newImage = true
 			changes, status, vErr = parseImageEdits(res, editParams, tx)
+			if vErr == nil && (doCycleNow || doCycleAt) {
+				var cycleAt time.Time
+				if cycleAt, status, vErr = parseCycleEdit(res, editParams, actionUser, time.Now(), tx); vErr == nil {
+					if deErr := dbEditReservation(res, changes, tx); deErr != nil {
+						return deErr
+					}
+					// the cycle boots the profile the res has now, which is only known once it is saved
+					return dbEditReservation(res, map[string]interface{}{"CycleAt": cycleAt, "CycleProfileID": res.Profile.ID}, tx)
+				}
+			}
+		} else if cycleCanceled {
+			if !res.cyclePending() {
+				status = http.StatusConflict
+				return fmt.Errorf("reservation '%s' has no power cycle scheduled", resName)
+			}
+			clog.Info().Msgf("canceling the power cycle of reservation '%s' scheduled for %s", resName, res.CycleAt.Format(common.DateTimeCompactFormat))
+			changes = map[string]interface{}{"CycleAt": time.Time{}, "CycleProfileID": 0}
 		} else {
 			changes, status, vErr = parseResEditParams(res, editParams, tx)
+			if endPower, ok := editParams["endPower"].(string); ok && vErr == nil && !isElevated {
+				var approval map[string]interface{}
+				if approval, status, vErr = endPowerEditApproval(res, endPower, time.Now()); len(approval) > 0 {
+					changes["ApprovalUntil"], changes["ApprovalReason"] = approval["ApprovalUntil"], approval["ApprovalReason"]
+					clampMsg = fmt.Sprintf("reservation needs admin approval (%s) and is held until %s", approval["ApprovalReason"],
+						approval["ApprovalUntil"].(time.Time).Format(common.DateTimeCompactFormat))
+					clog.Info().Msgf("reservation '%s' %s", resName, clampMsg)
+				}
+			}
 		}
 		if vErr != nil {
 			return vErr
@@ -109,12 +154,15 @@ func doUpdateReservation(resName string, editParams map[string]interface{}, r *h
 			clog.Error().Msgf("problem powering off dropped hosts for reservation '%s': %v", resName, powerErr)
 		}
 
+		fireHook(newHookPayload(HookHostsDropped, hookRequestID(r), res, droppedHosts))
+
 		if igor.Config.Maintenance.HostMaintenanceDuration > 0 {
 			logger.Debug().Msgf("putting dropped node(s) for reservation '%s' into maintenance mode", resName)
 
 			// prep for saving the current state so it can be restored after maintenance mode is finished
-			for _, h := range droppedHosts {
-				h.RestoreState = HostAvailable // a dropped host will always return to available
+			for i := range droppedHosts {
+				// a dropped host returns to available unless it was draining
+				droppedHosts[i].RestoreState = releasedHostState(droppedHosts[i].State)
 			}
 
 			now := time.Now()
@@ -130,7 +178,7 @@ func doUpdateReservation(resName string, editParams map[string]interface{}, r *h
 				logger.Error().Msgf("warning - errors detected when creating dropped node maintenance reservation %s: %v", res.Name, cmErr)
 			} else {
 				// begin maintenance immediately
-				_ = startMaintenance(maintenanceResDrop)
+				_ = startMaintenance(maintenanceResDrop, hookRequestID(r))
 			}
 		}
 	}
@@ -138,16 +186,60 @@ func doUpdateReservation(resName string, editParams map[string]interface{}, r *h
 	rList, _ := dbReadReservationsTx(map[string]interface{}{"ID": res.ID}, nil)
 	res = &rList[0]
 
+	// the new profile is written out now so the scheduled cycle boots it
+	if res.cyclePending() && newImage {
+		if irErr := installCycleProfile(res); irErr != nil {
+			clog.Error().Msgf("problem writing the PXE files of reservation '%s' for profile '%s' - %v", res.Name, res.Profile.Name, irErr)
+			if ceErr := performDbTx(func(tx *gorm.DB) error {
+				return dbEditReservation(res, map[string]interface{}{"CycleAt": time.Time{}, "CycleProfileID": 0}, tx)
+			}); ceErr != nil {
+				clog.Error().Msgf("failed to clear the scheduled power cycle of reservation '%s' - %v", res.Name, ceErr)
+			}
+			res.CycleAt = time.Time{}
+			clampMsg = fmt.Sprintf("the hosts could not be set up to boot profile '%s' so they will not be power cycled: %v", res.Profile.Name, irErr)
+		} else if res.CycleAt.After(time.Now()) {
+			clampMsg = fmt.Sprintf("the hosts will be power cycled at %s to boot profile '%s'", res.CycleAt.Format(common.DateTimeCompactFormat), res.Profile.Name)
+		} else {
+			clampMsg = fmt.Sprintf("the hosts are being power cycled to boot profile '%s'", res.Profile.Name)
+		}
+	} else if cycleCanceled {
+		clampMsg = "the scheduled power cycle has been canceled"
+	}
+
+	// the hosts of an installed reservation are already on the old VLAN so move them now
+	if newVlan && res.Installed {
+		if vlanErr := networkSet(res.Hosts, res.Vlan, res.NetProfile); vlanErr != nil {
+			clog.Error().Msgf("vlan error moving reservation '%s' to VLAN %d - %v", res.Name, res.Vlan, vlanErr)
+		}
+	} else if newNetProfile && res.Installed {
+		if npErr := networkProfile(res.Hosts, res.NetProfile); npErr != nil {
+			clog.Error().Msgf("network error applying profile '%s' to reservation '%s' - %v", res.NetProfile, res.Name, npErr)
+		}
+	}
+
 	editKeys := make([]string, 0, len(editParams))
 	for k := range editParams {
 		editKeys = append(editKeys, k)
 	}
 	sort.Strings(editKeys)
+	for i, k := range editKeys {
+		// only the size and hash of new data are recorded
+		if k == "data" {
+			data, _ := decodeResData(editParams["data"].(string))
+			editKeys[i] = k + "," + resDataHistStatus(data)
+		}
+	}
 
 	if hErr := res.HistCallback(res, HrUpdated+":"+strings.Join(editKeys, ",")); hErr != nil {
 		logger.Error().Msgf("failed to record reservation '%s' update to history", res.Name)
 	}
 
+	if droppedHead {
+		if hErr := res.HistCallback(res, HrUpdated+":head-dropped,head="+res.headHost().Name); hErr != nil {
+			logger.Error().Msgf("failed to record reservation '%s' head host change to history", res.Name)
+		}
+	}
+
 	var editEvents []*ResNotifyEvent
 
 	if dropped && actionUser.Name != res.Owner.Name {
@@ -175,6 +267,13 @@ func doUpdateReservation(resName string, editParams map[string]interface{}, r *h
 		}
 	}
 
+	// the owner is told when the hosts will boot the new profile
+	if newImage && (res.cyclePending() || actionUser.Name != res.Owner.Name) {
+		if resEditEvent := makeResEditNotifyEvent(EmailResProfileChange, res, clusterName, actionUser, isElevated, cycleNotice(res)); resEditEvent != nil {
+			editEvents = append(editEvents, resEditEvent)
+		}
+	}
+
 	if isNewGroup && !strings.HasPrefix(res.Group.Name, GroupUserPrefix) {
 		if resEditEvent := makeResEditNotifyEvent(EmailResNewGroup, res, clusterName, actionUser, isElevated, ""); resEditEvent != nil {
 			editEvents = append(editEvents, resEditEvent)
@@ -218,11 +317,19 @@ func parseDrop(res *Reservation, dropList string, tx *gorm.DB) (map[string]inter
 
 	changes["dropHosts"] = dropHosts
 
+	// a named head host that is dropped falls back to the first host left
+	for _, dh := range dropHosts {
+		if dh.ID == res.HeadHostID {
+			changes["HeadHostID"] = 0
+			break
+		}
+	}
+
 	now := time.Now()
 
-	if res.Installed || (res.Start.Before(now) && now.Before(res.End)) {
+	if res.Installed || res.IsActive(now) {
 		changes["resIsNow"] = true
-		if powerPerms, err := dbGetHostPowerPermissions(&res.Group, res.Hosts, tx); err != nil {
+		if powerPerms, err := dbGetNodeActionPermissions(&res.Group, res.Hosts, tx); err != nil {
 			return nil, http.StatusInternalServerError, err
 		} else {
 			powerPerm := powerPerms[0]
@@ -239,7 +346,7 @@ func parseDrop(res *Reservation, dropList string, tx *gorm.DB) (map[string]inter
 					keepHosts = append(keepHosts, h)
 				}
 			}
-			pUpdate, _ := NewPermission(makeNodePowerPerm(keepHosts))
+			pUpdate, _ := NewPermission(makeNodeActionPerm(keepHosts))
 			pUpdate.ID = powerPerm.ID
 			pUpdate.GroupID = powerPerm.GroupID
 			changes["pUpdate"] = *pUpdate
@@ -250,15 +357,44 @@ func parseDrop(res *Reservation, dropList string, tx *gorm.DB) (map[string]inter
 }
 
 // parseExtend checks that the 'extend' parameter has correct syntax and the modified end time
-// it creates doesn't collide with existing reservations and/or host policies.
-func parseExtend(res *Reservation, extendTime string, isActionUserElevated bool, r *http.Request, tx *gorm.DB) (map[string]interface{}, int, error) {
+// it creates doesn't collide with existing reservations and/or host policies. If clampToLimit is
+// set, an end time past the schedule or time limits is shortened to the latest one that can be
+// granted and a message describing the change is returned.
+func parseExtend(res *Reservation, extendTime string, clampToLimit bool, isActionUserElevated bool, r *http.Request, tx *gorm.DB) (changes map[string]interface{}, clampMsg string, status int, err error) {
+	checks := extendChecks{userRules: !isActionUserElevated, timeLimits: !isActionUserElevated,
+		limitGroups: getUserFromContext(r).groupNames()}
+	return checkExtend(res, extendTime, clampToLimit, checks, hlog.FromRequest(r), tx)
+}
 
-	clog := hlog.FromRequest(r)
+// extendChecks picks the rules an extension is held to. A normal user gets all of them and an elevated
+// admin none, while an admin extending many reservations at once is held to the limits of each owner.
+type extendChecks struct {
+	// userRules are the extend-within window and the ban on extending a reservation with blocked hosts
+	userRules bool
+	// timeLimits are the schedule limit, the time limits of the hosts and of limitGroups, and the
+	// per-user host caps of the host policies
+	timeLimits  bool
+	limitGroups []string
+}
+
+// checkExtend does the work of parseExtend with the given checks.
+func checkExtend(res *Reservation, extendTime string, clampToLimit bool, checks extendChecks, clog *zl.Logger, tx *gorm.DB) (changes map[string]interface{}, clampMsg string, status int, err error) {
 
-	if !isActionUserElevated {
+	// draining hosts are on their way out of the pool so no one gets more time on them
+	for _, h := range res.Hosts {
+		if h.State == HostDraining {
+			drainMsg := fmt.Sprintf("cannot extend reservation; node %s is draining", h.Name)
+			if h.DrainReason != "" {
+				drainMsg += " (" + h.DrainReason + ")"
+			}
+			return nil, "", http.StatusConflict, fmt.Errorf("%s -- it will be blocked when this reservation ends", drainMsg)
+		}
+	}
+
+	if checks.userRules {
 		for _, h := range res.Hosts {
 			if h.State == HostBlocked {
-				return nil, http.StatusConflict,
+				return nil, "", http.StatusConflict,
 					fmt.Errorf("cannot extend a reservation containing nodes with a blocked status -- contact cluster admin team")
 			}
 		}
@@ -266,95 +402,107 @@ func parseExtend(res *Reservation, extendTime string, isActionUserElevated bool,
 
 	hostNameList := namesOfHosts(res.Hosts)
 
-	// get the smallest host policy max limit
-	smallestMaxTime := time.Duration(math.MaxInt64)
-
-	hostIDs, status, err := getHostIDsFromNames(hostNameList)
+	// the same ceiling used when creating a reservation on these hosts, with any group time limits
+	// that apply to the user asking for more time
+	limitGroups := checks.limitGroups
+	ceiling, status, err := getResTimeCeiling(hostNameList, nil, limitGroups, len(res.Hosts), tx, clog)
 	if err != nil {
-		return nil, status, err
-	} else {
-		if hpList, rhpErr := dbReadHostPolicies(map[string]interface{}{"hosts": hostIDs}, tx, clog); rhpErr != nil {
-			return nil, http.StatusInternalServerError, rhpErr
-		} else {
-			for _, hp := range hpList {
-				if hp.MaxResTime < smallestMaxTime {
-					smallestMaxTime = hp.MaxResTime
-				}
-			}
-		}
+		return nil, "", status, err
 	}
 
 	now := time.Now()
-	var extendDur time.Duration
+
+	// time limits count from now once the reservation is installed
+	checkStart := res.Start
+	if res.Installed {
+		checkStart = now
+	}
+
+	var newEndTime time.Time
 
 	if extendTime == "" {
 		// extend by maximum allowable
-		extendDur = (smallestMaxTime - res.Remaining(now)).Truncate(time.Minute)
+		newEndTime, _ = getMaxResEnd(checkStart, ceiling, false)
+		if !newEndTime.After(res.End) {
+			return nil, "", http.StatusBadRequest, fmt.Errorf("cannot extend reservation; it already ends at the latest time that can be granted (%s)",
+				newEndTime.Format(common.DateTimeCompactFormat))
+		}
 	} else {
+		var extendDur time.Duration
 		// extend by provided parameter, either a duration or a datetime stamp
 		if extendDur, err = common.ParseDuration(extendTime); err != nil {
 			if extendDts, pErr := common.ParseTimeFormat(extendTime); pErr != nil {
-				return nil, http.StatusBadRequest, fmt.Errorf("%v; and, %v", err, pErr)
+				return nil, "", http.StatusBadRequest, fmt.Errorf("%v; and, %v", err, pErr)
 			} else {
 				if !extendDts.After(res.End) {
-					return nil, http.StatusBadRequest, fmt.Errorf("extend datetime '%s' is earlier than original '%s'",
+					return nil, "", http.StatusBadRequest, fmt.Errorf("extend datetime '%s' is earlier than original '%s'",
 						extendDts.Format(common.DateTimeCompactFormat), res.End.Format(common.DateTimeCompactFormat))
 				}
 				extendDur = extendDts.Sub(res.End).Truncate(time.Minute)
 			}
 		}
+		newEndTime = res.End.Add(extendDur).Round(time.Minute)
 	}
 
-	newEndTime := res.End.Add(extendDur).Round(time.Minute)
-	// determine new reset/maintenance end time from newEndTime
-	resetEnd := determineNodeResetTime(newEndTime)
-
 	// if this is not an elevated admin check for time limits, otherwise pass-through
-	if !isActionUserElevated {
-		// Make sure the reservation doesn't exceed max allowable time for the given number of nodes
-		if err = checkTimeLimit(len(res.Hosts), smallestMaxTime, res.Remaining(now)+extendDur); err != nil {
-			return nil, http.StatusBadRequest, err
-		}
-
+	if checks.userRules {
 		// Make sure that the user is extending a reservation that is near its completion based on the ExtendWithin config.
-		if igor.Scheduler.ExtendWithin > 0 {
+		// A reservation that hasn't started yet can be extended any time, as long as the limits below allow it.
+		if igor.Scheduler.ExtendWithin > 0 && !res.Start.After(now) {
 			remaining := time.Until(res.End)
 			if int(remaining.Minutes()) > igor.Scheduler.ExtendWithin {
 				ewDur := common.FormatDuration(time.Minute*time.Duration(igor.Scheduler.ExtendWithin), false)
-				return nil, http.StatusBadRequest, fmt.Errorf("reservations can only be extended if they are within %v of ending", ewDur)
+				return nil, "", http.StatusBadRequest, fmt.Errorf("reservations can only be extended if they are within %v of ending", ewDur)
 			}
 		}
 	}
 
-	if err = checkScheduleLimit(newEndTime, isActionUserElevated); err != nil {
-		return nil, http.StatusBadRequest, err
+	grantedEnd, limitMsg, limitErr := limitResEnd(checkStart, newEndTime, ceiling, !checks.timeLimits, clampToLimit)
+	if limitErr != nil {
+		return nil, "", http.StatusBadRequest, limitErr
+	}
+	if !grantedEnd.Equal(newEndTime) {
+		if !grantedEnd.After(res.End) {
+			return nil, "", http.StatusBadRequest, fmt.Errorf("cannot extend reservation; it already ends at the latest time that can be granted (%s)",
+				grantedEnd.Format(common.DateTimeCompactFormat))
+		}
+		clampMsg = limitMsg
+		clog.Info().Msgf("reservation '%s' extension %s", res.Name, clampMsg)
+		newEndTime = grantedEnd
 	}
 
+	// determine new reset/maintenance end time from newEndTime
+	resetEnd := determineNodeResetTime(newEndTime)
+
 	// verify extension doesn't conflict with current host policies
 	groupAccessList := []string{GroupAll, res.Group.Name}
-	checkStart := res.Start
-	if res.Installed {
-		checkStart = now
+	if hpStatus, hpErr := dbCheckHostPolicyConflicts(hostNameList, groupAccessList, limitGroups, userElevated(res.Owner.Name), checkStart, res.End, newEndTime, clog); hpErr != nil {
+		return nil, "", hpStatus, hpErr
 	}
-	if hpStatus, hpErr := dbCheckHostPolicyConflicts(hostNameList, groupAccessList, userElevated(res.Owner.Name), checkStart, res.End, newEndTime, clog); hpErr != nil {
-		return nil, hpStatus, hpErr
+
+	// a user kept over a policy's host cap (it was lowered since they reserved) can't hold on longer
+	if checks.timeLimits {
+		if capStatus, capErr := dbCheckPolicyUserCaps(&res.Owner, res.Hosts, res.ID, tx); capErr != nil {
+			return nil, "", capStatus, capErr
+		}
 	}
 
 	// verify extension (plus maintenance, if any) doesn't conflict with existing future reservations utilizing the same hosts
-	resList, rrErr := dbReadReservations(map[string]interface{}{"hosts": hostIDs}, nil, tx)
+	resList, rrErr := dbReadReservations(map[string]interface{}{"hosts": hostIDsOfHosts(res.Hosts)}, nil, tx)
 	if rrErr != nil {
-		return nil, http.StatusInternalServerError, rrErr
+		return nil, "", http.StatusInternalServerError, rrErr
 	}
 
 	for _, otherRes := range resList {
-		if res.Name != otherRes.Name {
+		// only later reservations matter, an earlier one on the same hosts of a future reservation can't be in the way
+		if res.Name != otherRes.Name && !otherRes.Start.Before(res.Start) {
 			if otherRes.Start.Before(resetEnd) {
-				return nil, http.StatusConflict, fmt.Errorf("cannot extend reservation; one or more hosts are reserved prior to the proposed new end time")
+				return nil, "", http.StatusConflict, fmt.Errorf("cannot extend reservation; one or more hosts are reserved prior to the proposed new end time")
 			}
 		}
 	}
 
-	changes := map[string]interface{}{}
+	changes = map[string]interface{}{}
 	changes["End"] = newEndTime
 	changes["ResetEnd"] = resetEnd
 	changes["ExtendCount"] = res.ExtendCount + 1
@@ -370,7 +518,7 @@ func parseExtend(res *Reservation, extendTime string, isActionUserElevated bool,
 		}
 	}
 
-	return changes, http.StatusOK, nil
+	return changes, clampMsg, http.StatusOK, nil
 }
 
 // parseImageEdits ensures that the reservation owner has access to the new distro and/or profile
@@ -446,12 +594,62 @@ func parseResEditParams(res *Reservation, editParams map[string]interface{}, tx
 		changes["Description"] = desc
 	}
 
+	// what happens to the power of the hosts when the reservation ends
+	if endPower, ok := editParams["endPower"].(string); ok {
+		changes["EndPower"] = endPower
+	}
+
+	// acknowledge an idle reservation so the idle policy leaves it alone
+	if keep, ok := editParams["keep"].(bool); ok {
+		changes["KeepIdle"] = keep
+	}
+
+	// name the host that is the head of the reservation
+	if headName, ok := editParams["head"].(string); ok {
+		headName = strings.TrimSpace(headName)
+		found := false
+		for _, h := range res.Hosts {
+			if h.Name == headName {
+				changes["HeadHostID"] = h.ID
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, http.StatusNotFound, fmt.Errorf("%s is not a part of reservation '%s'", headName, res.Name)
+		}
+	}
+
+	// move the reservation to another VLAN, such as that of a named network
+	if vlan, ok := editParams["vlan"].(string); ok {
+		if !igor.vlanEnabled() {
+			return nil, http.StatusBadRequest, fmt.Errorf("VLAN segmentation is not enabled on this server")
+		}
+		vlanID, pvStatus, pvErr := parseVLAN(strings.TrimSpace(vlan), res.Owner, &res.Group, tx)
+		if pvErr != nil {
+			return nil, pvStatus, pvErr
+		}
+		changes["Vlan"] = vlanID
+	}
+
+	// apply a network profile to the reservation's ports, only done by an admin
+	if netProfile, ok := editParams["netProfile"].(string); ok {
+		profile, npStatus, npErr := parseNetProfile(netProfile)
+		if npErr != nil {
+			return nil, npStatus, npErr
+		}
+		changes["NetProfile"] = profile
+	}
+
 	// does user want to add kernel args to the temp profile?
 	kernelArgs, kOk := editParams["kernelArgs"].(string)
 	if kOk {
 		if res.Profile.IsDefault {
 			// ok to modify a temp profile
-			changes["profile_kernel"] = kernelArgs
+			if _, kErr := checkMergedKernelArgs(res.Profile.Distro.KernelArgs, kernelArgs); kErr != nil {
+				return changes, http.StatusBadRequest, kErr
+			}
+			changes["profile_kernel"] = normalizeKernelArgs(kernelArgs)
 		} else {
 			return changes, http.StatusBadRequest, fmt.Errorf("cannot modify permanent profile, edit the profile first")
 		}
@@ -459,6 +657,47 @@ func parseResEditParams(res *Reservation, editParams map[string]interface{}, tx
 	newOwnerName, ownOK := editParams["owner"].(string)
 	groupName, grpOK := editParams["group"].(string)
 
+	// check if co-owners are being added or removed
+	addCoOwners, rmvCoOwners, coStatus, coErr := parseCoOwnerEdits(res, editParams, newOwnerName, tx)
+	if coErr != nil {
+		return nil, coStatus, coErr
+	}
+
+	// co-owners are cleared when ownership changes unless the request explicitly keeps them, and
+	// the new owner is never kept as a co-owner of their own reservation
+	if ownOK {
+		keepCoOwners, _ := editParams["keepCoOwners"].(bool)
+		for _, u := range res.CoOwners {
+			if (!keepCoOwners || u.Name == newOwnerName) && !userSliceContains(rmvCoOwners, u.Name) {
+				rmvCoOwners = append(rmvCoOwners, u)
+			}
+		}
+	}
+
+	if len(addCoOwners) > 0 {
+		changes["addCoOwners"] = addCoOwners
+	}
+	if len(rmvCoOwners) > 0 {
+		changes["rmvCoOwners"] = rmvCoOwners
+	}
+
+	// replace or clear the data the reservation's nodes fetch when they boot
+	if data, ok := editParams["data"].(string); ok {
+		decoded, dErr := decodeResData(data)
+		if dErr != nil {
+			return nil, http.StatusBadRequest, dErr
+		}
+		changes["data"] = decoded
+	}
+
+	if notifyAlso, ok := editParams["notifyAlso"].([]interface{}); ok {
+		users, naStatus, naErr := parseNotifyAlso(res, notifyAlso, tx)
+		if naErr != nil {
+			return nil, naStatus, naErr
+		}
+		changes["notifyAlso"] = users
+	}
+
 	if !ownOK && !grpOK {
 		return changes, http.StatusOK, nil
 	}
@@ -476,8 +715,8 @@ func parseResEditParams(res *Reservation, editParams map[string]interface{}, tx
 		}
 	}
 
-	// get the current power perms (will be empty if reservation hasn't started yet)
-	powerPerms, ppErr := dbGetHostPowerPermissions(&res.Group, res.Hosts, tx)
+	// get the current node action perms (will be empty if reservation hasn't started yet)
+	powerPerms, ppErr := dbGetNodeActionPermissions(&res.Group, res.Hosts, tx)
 	if ppErr != nil {
 		return nil, http.StatusInternalServerError, ppErr
 	}
@@ -523,7 +762,11 @@ func parseResEditParams(res *Reservation, editParams map[string]interface{}, tx
 		if membership, policy := dbCheckHostPolicyGroupConflicts(myHostPolicies, groupAccessList); !membership {
 			// get the intersection of affected policy hosts and requested hosts
 			offendingHosts := getHostIntersection(hostNames, policy.Hosts)
-			return nil, http.StatusConflict, &HostPolicyConflictError{"no group available that matches node restriction", true, false, false, time.Time{}, time.Time{}, offendingHosts}
+			return nil, http.StatusConflict, &HostPolicyConflictError{"no group available that matches node restriction", true, false, false, time.Time{}, time.Time{}, offendingHosts, ""}
+		}
+		if policy, excluded := checkHostPolicyExclusions(myHostPolicies, groupAccessList); excluded != "" {
+			offendingHosts := getHostIntersection(hostNames, policy.Hosts)
+			return nil, http.StatusConflict, &HostPolicyConflictError{"", true, false, false, time.Time{}, time.Time{}, offendingHosts, excluded}
 		}
 
 		// if the reservation group is not going to change (and not a pug), make sure the new owner is also a member
@@ -531,7 +774,7 @@ func parseResEditParams(res *Reservation, editParams map[string]interface{}, tx
 			if userElevated(res.Owner.Name) && newOwner.Name == IgorAdmin {
 				// fall through
 			} else if !groupSliceContains(newOwner.Groups, res.Group.Name) && newOwner.Name != IgorAdmin {
-				return nil, http.StatusConflict, fmt.Errorf("new owner is not a member of current reservation group %v", res.Group.Name)
+				return nil, http.StatusConflict, newCodedError(common.ErrNotGroupMember, "new owner is not a member of current reservation group %v", res.Group.Name)
 			}
 		}
 
@@ -561,7 +804,7 @@ func parseResEditParams(res *Reservation, editParams map[string]interface{}, tx
 				return nil, http.StatusInternalServerError, gpErr
 			}
 
-			// if there are already power permissions prep to change to the new owner
+			// if there are already node action permissions prep to change to the new owner
 			if len(powerPerms) > 0 {
 				// if they do, add them to the change list
 				pgChanges = append(pgChanges, powerPerms...)
@@ -586,7 +829,7 @@ func parseResEditParams(res *Reservation, editParams map[string]interface{}, tx
 					return nil, http.StatusInternalServerError, err
 				}
 
-				// if there are already power permissions prep to change to the new group
+				// if there are already node action permissions prep to change to the new group
 				if len(powerPerms) > 0 {
 					// if they do, add them to the change list
 					pgChanges = append(pgChanges, powerPerms...)
@@ -601,11 +844,11 @@ func parseResEditParams(res *Reservation, editParams map[string]interface{}, tx
 			newGroup := &gList[0]
 
 			if ownOK && !newOwner.isMemberOfGroup(newGroup) {
-				return nil, http.StatusForbidden, fmt.Errorf("user '%s' is not a member of group '%s'", newOwner.Name, groupName)
+				return nil, http.StatusForbidden, newCodedError(common.ErrNotGroupMember, "user '%s' is not a member of group '%s'", newOwner.Name, groupName)
 			}
 
 			if !ownOK && !res.Owner.isMemberOfGroup(newGroup) {
-				return nil, http.StatusForbidden, fmt.Errorf("current owner '%s' is not a member of group '%s'", res.Owner.Name, groupName)
+				return nil, http.StatusForbidden, newCodedError(common.ErrNotGroupMember, "current owner '%s' is not a member of group '%s'", res.Owner.Name, groupName)
 			}
 
 			changes["GroupID"] = newGroup.ID
@@ -618,7 +861,7 @@ func parseResEditParams(res *Reservation, editParams map[string]interface{}, tx
 				return nil, http.StatusInternalServerError, err
 			}
 
-			// if there are already power permissions prep to change to the new group
+			// if there are already node action permissions prep to change to the new group
 			if len(powerPerms) > 0 {
 				// if they do, add them to the change list
 				pgChanges = append(pgChanges, powerPerms...)
@@ -630,3 +873,78 @@ func parseResEditParams(res *Reservation, editParams map[string]interface{}, tx
 
 	return changes, http.StatusOK, nil
 }
+
+// parseCoOwnerEdits resolves the users named in the addCoOwners and rmvCoOwners params. A user being
+// added cannot already be an owner or co-owner of the reservation (including the incoming owner if
+// ownership is also changing), and a user being removed must currently be a co-owner.
+func parseCoOwnerEdits(res *Reservation, editParams map[string]interface{}, newOwnerName string, tx *gorm.DB) (addList []User, rmvList []User, status int, err error) {
+
+	var addNames []string
+	if addCoOwners, ok := editParams["addCoOwners"].([]interface{}); ok {
+		for _, u := range addCoOwners {
+			name := u.(string)
+			if name == IgorAdmin {
+				return nil, nil, http.StatusBadRequest, fmt.Errorf("cannot add %s as a reservation co-owner", IgorAdmin)
+			} else if name == res.Owner.Name || name == newOwnerName {
+				return nil, nil, http.StatusConflict, fmt.Errorf("user '%s' is the owner of reservation '%s'", name, res.Name)
+			} else if res.isCoOwner(name) {
+				return nil, nil, http.StatusConflict, fmt.Errorf("user '%s' is already a co-owner of reservation '%s'", name, res.Name)
+			}
+			addNames = append(addNames, name)
+		}
+	}
+
+	var rmvNames []string
+	if rmvCoOwners, ok := editParams["rmvCoOwners"].([]interface{}); ok {
+		for _, u := range rmvCoOwners {
+			name := u.(string)
+			if !res.isCoOwner(name) {
+				return nil, nil, http.StatusConflict, fmt.Errorf("user '%s' is not a co-owner of reservation '%s'", name, res.Name)
+			}
+			rmvNames = append(rmvNames, name)
+		}
+	}
+
+	if len(addNames) > 0 {
+		if addList, status, err = getUsers(addNames, true, tx); err != nil {
+			return nil, nil, status, err
+		}
+	}
+
+	for _, u := range res.CoOwners {
+		for _, name := range rmvNames {
+			if u.Name == name {
+				rmvList = append(rmvList, u)
+				break
+			}
+		}
+	}
+
+	return addList, rmvList, http.StatusOK, nil
+}
+
+// parseNotifyAlso resolves the users named in the notifyAlso param, which replace those currently
+// copied on the reservation's email. Only igor users can be named so reservation email can't be sent to
+// arbitrary addresses. The 'none' alias alone clears the list.
+func parseNotifyAlso(res *Reservation, notifyAlso []interface{}, tx *gorm.DB) ([]User, int, error) {
+
+	names := make([]string, 0, len(notifyAlso))
+	for _, u := range notifyAlso {
+		name := u.(string)
+		if name == GroupNoneAlias {
+			if len(notifyAlso) > 1 {
+				return nil, http.StatusBadRequest, fmt.Errorf("'%s' cannot be combined with other users", GroupNoneAlias)
+			}
+			return []User{}, http.StatusOK, nil
+		} else if name == res.Owner.Name {
+			return nil, http.StatusConflict, fmt.Errorf("user '%s' is the owner of reservation '%s'", name, res.Name)
+		}
+		names = append(names, name)
+	}
+
+	users, status, err := getUsers(names, true, tx)
+	if err != nil {
+		return nil, status, err
+	}
+	return users, http.StatusOK, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestResCoOwners(t *testing.T) {
+
+	db := newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db"))
+	hosts := seedTestHosts(t, db)
+	newStartTestRes(t, db, "exp", hosts[:1], false, 0)
+	pug := Group{Name: GroupUserPrefix + "bob", IsUserPrivate: true}
+	require.NoError(t, db.Omit(clause.Associations).Create(&pug).Error)
+	require.NoError(t, db.Omit("Groups.*").Create(&User{Name: "bob", Email: "bob@example.com", Groups: []Group{pug}}).Error)
+
+	edit := func(params map[string]interface{}) (int, error) {
+		status := http.StatusInternalServerError
+		err := performDbTx(func(tx *gorm.DB) error {
+			rList, err := dbReadReservations(map[string]interface{}{"name": "exp"}, nil, tx)
+			if err != nil {
+				return err
+			}
+			changes, pStatus, pErr := parseResEditParams(&rList[0], params, tx)
+			if pErr != nil {
+				status = pStatus
+				return pErr
+			}
+			return dbEditReservation(&rList[0], changes, tx)
+		})
+		if err == nil {
+			status = http.StatusOK
+		}
+		return status, err
+	}
+	// bobCan reports whether bob's permissions allow the edit of the given reservation field
+	bobCan := func(field string) bool {
+		var bob User
+		require.NoError(t, db.Preload("Groups").Where("name = ?", "bob").First(&bob).Error)
+		authInfo, err := bob.getAuthzInfo()
+		require.NoError(t, err)
+		p, err := NewPermission(NewPermissionString(PermReservations, "exp", PermEditAction, field))
+		require.NoError(t, err)
+		return authInfo.IsPermitted(p)
+	}
+
+	assert.False(t, bobCan("description"))
+	_, err := edit(map[string]interface{}{"addCoOwners": []interface{}{"bob"}})
+	require.NoError(t, err)
+
+	stored, err := dbReadReservationsTx(map[string]interface{}{"name": "exp"}, nil)
+	require.NoError(t, err)
+	require.Len(t, stored[0].CoOwners, 1)
+	assert.Equal(t, "bob", stored[0].CoOwners[0].Name)
+
+	// a co-owner can make the owner's edits except handing the reservation to someone else
+	assert.True(t, bobCan("description"))
+	assert.True(t, bobCan("extend"))
+	assert.False(t, bobCan("owner"))
+
+	// the owner and existing co-owners can't be added again
+	status, err := edit(map[string]interface{}{"addCoOwners": []interface{}{"alice"}})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+	status, err = edit(map[string]interface{}{"addCoOwners": []interface{}{"bob"}})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// removing bob takes away the permissions that came with it
+	_, err = edit(map[string]interface{}{"rmvCoOwners": []interface{}{"bob"}})
+	require.NoError(t, err)
+	stored, err = dbReadReservationsTx(map[string]interface{}{"name": "exp"}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, stored[0].CoOwners)
+	assert.False(t, bobCan("description"))
+}
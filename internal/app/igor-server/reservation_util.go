@@ -6,35 +6,133 @@ package igorserver
 
 import (
 	"fmt"
+	"net/http"
 	"sort"
+	"strings"
 	"time"
 
 	"igor2/internal/pkg/common"
+	"igor2/internal/pkg/naming"
 
+	zl "github.com/rs/zerolog"
 	"gorm.io/gorm"
 )
 
-func checkTimeLimit(nodeCount int, limit time.Duration, resDur time.Duration) error {
+// checkResNameRules determines if the input string meets the criteria for a valid reservation name.
+func checkResNameRules(name string) error {
+	return naming.Check(naming.Reservation, name)
+}
+
+// maxResDuration returns the longest reservation allowed on nodeCount hosts under the given time limit.
+func maxResDuration(nodeCount int, limit time.Duration) time.Duration {
 	// nodeCount is ignored at the moment.
 	// In old igor, a formula was created that lowered the amount of time allowed for the res
 	// based on how many nodes were requested. More nodes = less reservation time. Doubtful we
 	// will ever go back to that, but the node count is there if needed.
+	return limit
+}
+
+func checkTimeLimit(nodeCount int, limit time.Duration, resDur time.Duration) error {
 
 	if limit <= 0 {
 		// no time limit defined, so return nil
 		return nil
 	}
 
-	logger.Debug().Msgf("checkTimeLimit: requested res duration: %v", resDur)
+	return checkResTimeCeiling(maxResDuration(nodeCount, limit), resDur)
+}
+
+// checkResTimeCeiling returns an error naming the ceiling if resDur is longer than it. A ceiling of 0
+// means there is no limit.
+func checkResTimeCeiling(ceiling time.Duration, resDur time.Duration) error {
+
+	if ceiling <= 0 {
+		return nil
+	}
+
+	logger.Debug().Msgf("checkResTimeCeiling: requested res duration: %v", resDur)
 
 	// lop off some seconds to ensure requesting max allowable time doesn't exceed limit by some tiny fraction
-	if resDur-(time.Second*5) > limit {
-		return fmt.Errorf("max allowable time is %s (you requested %s)", limit.Round(time.Second), resDur.Round(time.Second))
+	if resDur-(time.Second*5) > ceiling {
+		return fmt.Errorf("max allowable time is %s (you requested %s)", ceiling.Round(time.Second), resDur.Round(time.Second))
 	}
 
 	return nil
 }
 
+// resTimeCeiling combines the host policy time limit governing a reservation with the scheduler's
+// maxReserveTime and scales the result for nodeCount hosts. A policyLimit of 0 means no policy limit
+// applies.
+func resTimeCeiling(policyLimit time.Duration, nodeCount int) time.Duration {
+	limit := policyLimit
+	if schedLimit := time.Minute * time.Duration(igor.Scheduler.MaxReserveTime); schedLimit > 0 && (limit <= 0 || schedLimit < limit) {
+		limit = schedLimit
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return maxResDuration(nodeCount, limit)
+}
+
+// getResTimeCeiling returns the longest reservation a non-elevated user may hold on the named hosts, or on
+// nodeCount hosts drawn from the policies open to accessGroupList when no hosts are named. Policies with a
+// time limit for any of limitGroups, the user's groups, apply that instead. It is the one place the limit
+// is worked out, so creating a reservation and extending it are held to the same ceiling.
+func getResTimeCeiling(hostNames []string, accessGroupList []string, limitGroups []string, nodeCount int, tx *gorm.DB, clog *zl.Logger) (time.Duration, int, error) {
+	policyLimit, status, err := dbGetPolicyTimeLimit(hostNames, accessGroupList, limitGroups, tx, clog)
+	if err != nil {
+		return 0, status, err
+	}
+	return resTimeCeiling(policyLimit, nodeCount), http.StatusOK, nil
+}
+
+// getMaxResEnd returns the latest end time that can be granted to a reservation whose time is counted
+// from timeStart, along with the name of the limit that imposes it. A ceiling of 0 means no time limit
+// applies. Elevated users are only bound by the scheduling window.
+func getMaxResEnd(timeStart time.Time, ceiling time.Duration, isElevated bool) (time.Time, string) {
+	maxEnd := getScheduleEnd(isElevated)
+	reason := "schedule limit"
+	if !isElevated && ceiling > 0 {
+		if timeEnd := timeStart.Add(ceiling).Truncate(time.Minute); timeEnd.Before(maxEnd) {
+			maxEnd = timeEnd
+			reason = "time limit"
+		}
+	}
+	return maxEnd, reason
+}
+
+// limitResEnd checks that a reservation whose time is counted from timeStart can end at resEnd under the
+// given ceiling and the scheduling window. When it can't, the error gives the ceiling and the latest end
+// that can be granted, unless clamp is set, in which case that latest end is returned along with a message
+// describing the change.
+func limitResEnd(timeStart, resEnd time.Time, ceiling time.Duration, isElevated bool, clamp bool) (time.Time, string, error) {
+
+	limitErr := checkScheduleLimit(resEnd, isElevated)
+	if limitErr == nil && !isElevated {
+		limitErr = checkResTimeCeiling(ceiling, resEnd.Sub(timeStart))
+	}
+	if limitErr == nil {
+		return resEnd, "", nil
+	}
+
+	maxEnd, limitReason := getMaxResEnd(timeStart, ceiling, isElevated)
+	if !clamp {
+		return resEnd, "", maxEndError(limitErr, maxEnd)
+	}
+	return maxEnd, clampedEndMessage(resEnd, maxEnd, limitReason), nil
+}
+
+// clampedEndMessage reports that a requested reservation end time was shortened to the given one.
+func clampedEndMessage(requested, granted time.Time, reason string) string {
+	return fmt.Sprintf("requested end %s, granted %s due to %s",
+		requested.Format(common.DateTimeCompactFormat), granted.Format(common.DateTimeCompactFormat), reason)
+}
+
+// maxEndError adds the latest grantable end time to an error produced by a time or schedule limit check.
+func maxEndError(err error, maxEnd time.Time) error {
+	return fmt.Errorf("%v; the latest end that can be granted is %s", err, maxEnd.Format(common.DateTimeCompactFormat))
+}
+
 func meetsMinResDuration(duration time.Duration) bool {
 	minReserveTime := time.Duration(igor.Scheduler.MinReserveTime) * time.Minute
 	return duration >= minReserveTime
@@ -62,6 +160,10 @@ func checkScheduleLimit(endTime time.Time, isElevated bool) error {
 	return nil
 }
 
+// resCoOwnerEditFields are the reservation fields a co-owner may edit. This is everything the owner
+// can change except the owner itself and the list of co-owners.
+var resCoOwnerEditFields = []string{"name", "description", "kernelArgs", "distro", "profile", "extend", "drop", "group"}
+
 func makeResGroupPermStrings(res *Reservation) []string {
 	dpstr := NewPermissionString(PermReservations, res.Name, PermDeleteAction)
 	epstr := NewPermissionString(PermReservations, res.Name, PermEditAction, "extend")
@@ -69,20 +171,13 @@ func makeResGroupPermStrings(res *Reservation) []string {
 	return []string{dpstr, epstr}
 }
 
-// Create the permission string for allowing power commands to be performed on a group of hosts.
-func makeNodePowerPerm(hostList []Host) string {
-	var hostPermStr string
+// makeNodeActionPerm creates the permission string that allows every node action (power, reimage and
+// console) to be performed on a group of hosts. It is granted to a reservation's group while the
+// reservation is active.
+func makeNodeActionPerm(hostList []Host) string {
 	hostNames := hostNamesOfHosts(hostList)
-	sort.Slice(hostNames, func(i, j int) bool {
-		return hostNames[i] < hostNames[j]
-	})
-
-	for i := 0; i < len(hostNames)-1; i++ {
-		hostPermStr += hostNames[i] + PermSubpartToken
-	}
-	hostPermStr += hostNames[len(hostNames)-1]
-
-	return NewPermissionString(PermPowerAction, hostPermStr)
+	sort.Strings(hostNames)
+	return NewPermissionString(PermNodeAction, strings.Join(hostNames, PermSubpartToken), strings.Join(nodeActions, PermSubpartToken))
 }
 
 // resNamesOfResList returns a list of Reservation names from
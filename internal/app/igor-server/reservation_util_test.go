@@ -0,0 +1,193 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+func TestGetMaxResEnd(t *testing.T) {
+
+	origSchedMinutes := MaxScheduleMinutes
+	defer func() { MaxScheduleMinutes = origSchedMinutes }()
+	MaxScheduleMinutes = 45 * 24 * 60
+
+	now := time.Now()
+	schedEnd := getScheduleEnd(false)
+
+	// policy limit inside the schedule window wins
+	maxEnd, reason := getMaxResEnd(now, 72*time.Hour, false)
+	assert.Equal(t, now.Add(72*time.Hour).Truncate(time.Minute), maxEnd)
+	assert.Equal(t, "time limit", reason)
+
+	// policy limit beyond the schedule window is capped by the window
+	maxEnd, reason = getMaxResEnd(now, 60*24*time.Hour, false)
+	assert.Equal(t, schedEnd, maxEnd)
+	assert.Equal(t, "schedule limit", reason)
+
+	// no policy limit
+	maxEnd, reason = getMaxResEnd(now, 0, false)
+	assert.Equal(t, schedEnd, maxEnd)
+	assert.Equal(t, "schedule limit", reason)
+
+	// elevated users ignore policy limits
+	maxEnd, reason = getMaxResEnd(now, 72*time.Hour, true)
+	assert.Equal(t, getScheduleEnd(true), maxEnd)
+	assert.Equal(t, "schedule limit", reason)
+}
+
+// seedTimeLimitHosts adds the all group and hosts kn1 and kn2 under host policies with different
+// time limits: 72 hours for kn1 and 24 hours for kn2.
+func seedTimeLimitHosts(t testing.TB, db *gorm.DB) []Host {
+	return seedHosts(t, db, HostPolicy{Name: "long", MaxResTime: 72 * time.Hour}, HostPolicy{Name: "short", MaxResTime: 24 * time.Hour})
+}
+
+func TestResTimeCeilingMixedPolicies(t *testing.T) {
+
+	origSched, origSchedMinutes, origNotify := igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn
+	defer func() {
+		igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn = origSched, origSchedMinutes, origNotify
+	}()
+	notifyOff := false
+	igor.Email.ResNotifyOn = &notifyOff
+	igor.Scheduler.MaxReserveTime = 7 * 24 * 60
+	igor.Scheduler.ExtendWithin = 0
+	MaxScheduleMinutes = 45 * 24 * 60
+
+	hosts := seedTimeLimitHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+
+	// the smallest policy limit governs a reservation on both hosts
+	ceiling, _, err := getResTimeCeiling([]string{"kn1", "kn2"}, []string{GroupAll}, nil, 2, db, &logger)
+	assert.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, ceiling)
+
+	// a server max below the policy limits caps the ceiling
+	igor.Scheduler.MaxReserveTime = 12 * 60
+	ceiling, _, _ = getResTimeCeiling([]string{"kn1"}, nil, nil, 1, db, &logger)
+	assert.Equal(t, 12*time.Hour, ceiling)
+	igor.Scheduler.MaxReserveTime = 7 * 24 * 60
+
+	start := time.Now().Add(time.Hour).Truncate(time.Minute)
+	res := &Reservation{
+		Name:  "mixed",
+		Start: start,
+		End:   start.Add(12 * time.Hour),
+		Hosts: hosts,
+	}
+
+	// create path: a 48 hour reservation starting at the same time
+	ceiling, _, _ = getResTimeCeiling(namesOfHosts(hosts), []string{GroupAll}, nil, len(hosts), db, &logger)
+	_, _, createErr := limitResEnd(start, start.Add(48*time.Hour), ceiling, false, false)
+
+	// extend path: a 12 hour reservation extended by 36 hours
+	_, _, status, extendErr := parseExtend(res, "36h", false, false, r, db)
+
+	assert.Error(t, createErr)
+	assert.Error(t, extendErr)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, createErr.Error(), "max allowable time is 24h0m0s")
+	assert.Equal(t, createErr.Error(), extendErr.Error())
+
+	// both paths clamp to the same end
+	createEnd, _, _ := limitResEnd(start, start.Add(48*time.Hour), ceiling, false, true)
+	changes, clampMsg, _, err := parseExtend(res, "36h", true, false, r, db)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, clampMsg)
+	assert.Equal(t, start.Add(24*time.Hour), createEnd)
+	assert.Equal(t, createEnd, changes["End"])
+
+	// extending by the max allowable reaches the same end
+	changes, _, _, err = parseExtend(res, "", false, false, r, db)
+	assert.NoError(t, err)
+	assert.Equal(t, createEnd, changes["End"])
+}
+
+func TestExtendFutureReservation(t *testing.T) {
+
+	origSched, origSchedMinutes, origNotify := igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn
+	t.Cleanup(func() {
+		igor.Scheduler, MaxScheduleMinutes, igor.Email.ResNotifyOn = origSched, origSchedMinutes, origNotify
+	})
+	notifyOff := false
+	igor.Email.ResNotifyOn = &notifyOff
+	igor.Scheduler.MaxReserveTime = 7 * 24 * 60
+	igor.Scheduler.ExtendWithin = 60
+	MaxScheduleMinutes = 45 * 24 * 60
+
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+
+	// a started reservation can only be extended within an hour of ending
+	current := newStartTestRes(t, db, "current", hosts[:1], false, 0)
+	current.End = time.Now().Add(10 * time.Hour).Truncate(time.Minute)
+	_, _, status, err := parseExtend(current, "1h", false, false, r, db)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, err.Error(), "within 1h")
+
+	// one on the same host starting next month can be extended right away, and the reservation
+	// before it is no obstacle
+	start := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Minute)
+	future := newStartTestRes(t, db, "future", hosts[:1], false, 0)
+	require.NoError(t, db.Model(future).Updates(map[string]interface{}{"start": start, "end": start.Add(24 * time.Hour)}).Error)
+	future.Start, future.End = start, start.Add(24*time.Hour)
+	changes, _, status, err := parseExtend(future, "6h", false, false, r, db)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, start.Add(30*time.Hour), changes["End"])
+
+	// but it can't run into a later reservation on the same host
+	later := newStartTestRes(t, db, "later", hosts[:1], false, 0)
+	require.NoError(t, db.Model(later).Updates(map[string]interface{}{"start": start.Add(36 * time.Hour), "end": start.Add(48 * time.Hour)}).Error)
+	_, _, status, err = parseExtend(future, "1d", false, false, r, db)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// and it is still held to the policy limit on its length
+	_, _, status, err = parseExtend(future, "3d", false, false, r, db)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestReadReservationsToEnd(t *testing.T) {
+
+	seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	now := time.Now().Truncate(time.Minute)
+	for i, end := range []time.Duration{time.Hour, 30 * time.Hour, 72 * time.Hour} {
+		res := Reservation{Name: fmt.Sprintf("res%d", i), Hash: fmt.Sprintf("h%d", i), OwnerID: 1, Start: now, End: now.Add(end)}
+		require.NoError(t, db.Omit(clause.Associations).Create(&res).Error)
+	}
+
+	deadline := now.Add(48 * time.Hour).UTC().Format(common.DateTimeCompactFormat)
+	require.NoError(t, checkTimeParam("to-end", []string{deadline}))
+	assert.Error(t, checkTimeParam("to-end", []string{"friday"}))
+	assert.Error(t, checkTimeParam("to-end", []string{deadline, deadline}))
+
+	toEnd, _ := time.Parse(common.DateTimeCompactFormat, deadline)
+	resList, err := dbReadReservationsTx(nil, map[string]time.Time{"to-end": toEnd})
+	require.NoError(t, err)
+	var names []string
+	for _, r := range resList {
+		names = append(names, r.Name)
+	}
+	assert.ElementsMatch(t, []string{"res0", "res1"}, names)
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 
 	"igor2/internal/pkg/api"
+	"igor2/internal/pkg/common"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/rs/zerolog/hlog"
@@ -31,6 +32,7 @@ func applyCbRoutes(router *httprouter.Router) {
 	hcCb := NewHandlerChain(hlog.NewHandler(logger))
 	router.Handle(http.MethodGet, api.CbLocal, hcCb.ApplyTo(handleCbs))
 	router.Handle(http.MethodGet, api.CbInfo, hcCb.ApplyTo(getInfo))
+	router.Handle(http.MethodGet, api.CbData, hcCb.ApplyTo(handleCbResData))
 	router.Handle(http.MethodGet, api.Public, hcCb.ApplyTo(publicShowHandler))
 	router.ServeFiles(api.CbKS+"/*filepath", http.Dir(filepath.Join(igor.TFTPPath, igor.KickstartDir)))
 	router.ServeFiles(api.CbScript+"/*filepath", http.Dir(igor.Server.ScriptDir))
@@ -49,20 +51,45 @@ func applyApiRoutes(router *httprouter.Router) {
 	//router.Handler(http.MethodGet, api.BaseUrl+"/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
 	//router.Handler(http.MethodGet, api.BaseUrl+"/debug/pprof/block", pprof.Handler("block"))
 
-	// Default route chain includes logging and checking content type if body if attached
+	// Default route chain includes logging, checking content type if body if attached and capping its size
 	hcDefaultChain := NewHandlerChain(hlog.NewHandler(logger))
+	hcDefaultChain.Add(hlog.RequestIDHandler("reqId", common.IgorRequestIDHeader))
 	hcDefaultChain.Add(zlRequestHandler)
+	hcDefaultChain.Add(setVersionHeaders)
 	hcDefaultChain.Add(checkContentType)
+	hcDefaultChain.Add(limitRequestBody)
 
 	// Routes that don't require authentication
 	hcPublicShow := NewHandlerChain()
 	hcPublicShow.Extend(hcDefaultChain)
 	router.Handle(http.MethodGet, api.Public, hcPublicShow.ApplyTo(publicShowHandler))
+	router.Handle(http.MethodGet, api.PublicStatus, hcPublicShow.ApplyTo(handlePublicStatus))
 
 	hcSettings := NewHandlerChain()
 	hcSettings.Extend(hcDefaultChain)
 	router.Handle(http.MethodGet, api.PublicSettings, hcSettings.ApplyTo(settingsHandler))
 
+	// health is polled often, so it skips the request logging of the default chain
+	hcHealth := NewHandlerChain(hlog.NewHandler(logger))
+	hcHealth.Add(setVersionHeaders)
+	router.Handle(http.MethodGet, api.Health, hcHealth.ApplyTo(healthHandler))
+
+	// error codes are documentation so anyone can read them
+	hcErrors := NewHandlerChain()
+	hcErrors.Extend(hcDefaultChain)
+	router.Handle(http.MethodGet, api.Errors, hcErrors.ApplyTo(errorCodesHandler))
+
+	// share links are checked and rate limited by the handler itself
+	hcPublicShare := NewHandlerChain()
+	hcPublicShare.Extend(hcDefaultChain)
+	router.Handle(http.MethodGet, api.ShareToken, hcPublicShare.ApplyTo(handlePublicShare))
+
+	// extend links stand for the reservation owner and are checked by the handler itself
+	hcPublicExtend := NewHandlerChain()
+	hcPublicExtend.Extend(hcDefaultChain)
+	router.Handle(http.MethodGet, api.ExtendLinkToken, hcPublicExtend.ApplyTo(handlePublicExtendLink))
+	router.Handle(http.MethodPost, api.ExtendLinkToken, hcPublicExtend.ApplyTo(handlePublicExtendLink))
+
 	// IAuth will be applied to most routes
 	hcAuthChain := NewHandlerChain(authnHandler, authzHandler)
 
@@ -81,9 +108,24 @@ func applyApiRoutes(router *httprouter.Router) {
 	hcLoginPost.Extend(hcDefaultChain)
 	router.Handle(http.MethodPost, api.Login, hcLoginPost.ApplyTo(loginPostHandler))
 
+	// OIDC logins, only routed when the OIDC scheme is used
+	if igor.Auth.Scheme == "oidc" {
+		hcOidcLogin := NewHandlerChain()
+		hcOidcLogin.Extend(hcDefaultChain)
+		router.Handle(http.MethodGet, api.LoginOidc, hcOidcLogin.ApplyTo(oidcLoginHandler))
+		router.Handle(http.MethodGet, api.LoginOidcCallback, hcOidcLogin.ApplyTo(oidcCallbackHandler))
+		router.Handle(http.MethodPost, api.LoginOidcDevice, hcOidcLogin.ApplyTo(oidcDeviceHandler))
+
+		hcOidcToken := NewHandlerChain()
+		hcOidcToken.Extend(hcDefaultChain)
+		hcOidcToken.Add(storeJSONBodyHandler)
+		router.Handle(http.MethodPost, api.LoginOidcToken, hcOidcToken.ApplyTo(oidcDeviceTokenHandler))
+	}
+
 	hcShow := NewHandlerChain()
 	hcShow.Extend(hcDefaultChain)
 	hcShow.Extend(hcAuthChain)
+	hcShow.Add(validateShowParams)
 	router.Handle(http.MethodGet, api.BaseUrl, hcShow.ApplyTo(showHandler))
 
 	// Create clusters
@@ -109,6 +151,15 @@ func applyApiRoutes(router *httprouter.Router) {
 	hcCreateMotd.Add(validateMotdParams)
 	router.Handle(http.MethodPatch, api.ClusterMotd, hcCreateMotd.ApplyTo(handleUpdateMotd))
 
+	// Rename the cluster prefix or renumber its hosts
+	hcMigrateCluster := NewHandlerChain()
+	hcMigrateCluster.Extend(hcDefaultChain)
+	hcMigrateCluster.Add(storeJSONBodyHandler)
+	hcMigrateCluster.Extend(hcAuthChain)
+	hcMigrateCluster.Add(validateClusterMigrateParams)
+	router.Handle(http.MethodPatch, api.ClusterRenamePfx, hcMigrateCluster.ApplyTo(handleMigrateClusterHosts))
+	router.Handle(http.MethodPatch, api.ClusterRenumber, hcMigrateCluster.ApplyTo(handleMigrateClusterHosts))
+
 	// Read hosts
 	hcReadHosts := NewHandlerChain()
 	hcReadHosts.Extend(hcDefaultChain)
@@ -147,6 +198,28 @@ func applyApiRoutes(router *httprouter.Router) {
 	hcBlockHosts.Add(validateBlockParams)
 	router.Handle(http.MethodPatch, api.HostsBlock, hcBlockHosts.ApplyTo(handleBlockHosts))
 
+	// un/drain hosts
+	hcDrainHosts := NewHandlerChain()
+	hcDrainHosts.Extend(hcDefaultChain)
+	hcDrainHosts.Add(storeJSONBodyHandler)
+	hcDrainHosts.Extend(hcAuthChain)
+	hcDrainHosts.Add(validateDrainParams)
+	router.Handle(http.MethodPatch, api.HostsDrain, hcDrainHosts.ApplyTo(handleDrainHosts))
+
+	// explain host access for a user
+	hcExplainHost := NewHandlerChain()
+	hcExplainHost.Extend(hcDefaultChain)
+	hcExplainHost.Extend(hcAuthChain)
+	hcExplainHost.Add(validateExplainParams)
+	router.Handle(http.MethodGet, api.HostsExplain, hcExplainHost.ApplyTo(handleExplainHost))
+
+	// reservations that held a host during a time window
+	hcHostHistory := NewHandlerChain()
+	hcHostHistory.Extend(hcDefaultChain)
+	hcHostHistory.Extend(hcAuthChain)
+	hcHostHistory.Add(validateHostHistoryParams)
+	router.Handle(http.MethodGet, api.HostsHistory, hcHostHistory.ApplyTo(handleHostHistory))
+
 	hcApplHostPolicy := NewHandlerChain()
 	hcApplHostPolicy.Extend(hcDefaultChain)
 	hcApplHostPolicy.Add(storeJSONBodyHandler)
@@ -192,6 +265,13 @@ func applyApiRoutes(router *httprouter.Router) {
 	hcCreateResv.Add(validateResvParams)
 	router.Handle(http.MethodPost, api.Reservations, hcCreateResv.ApplyTo(handleCreateReservations))
 
+	// Advise on a reservation request without creating it; problems with the request are part of the advice
+	hcAdviseResv := NewHandlerChain()
+	hcAdviseResv.Extend(hcDefaultChain)
+	hcAdviseResv.Add(storeJSONBodyHandler)
+	hcAdviseResv.Extend(hcAuthChain)
+	router.Handle(http.MethodPost, api.ResAdvise, hcAdviseResv.ApplyTo(handleAdviseReservation))
+
 	// Read reservations
 	hcReadResv := NewHandlerChain()
 	hcReadResv.Extend(hcDefaultChain)
@@ -207,6 +287,27 @@ func applyApiRoutes(router *httprouter.Router) {
 	hcUpdateResv.Add(validateResvParams)
 	router.Handle(http.MethodPatch, api.ReservationsName, hcUpdateResv.ApplyTo(handleUpdateReservation))
 
+	// Extend or change the owner of many reservations at once
+	hcBulkResv := NewHandlerChain()
+	hcBulkResv.Extend(hcDefaultChain)
+	hcBulkResv.Add(storeJSONBodyHandler)
+	hcBulkResv.Extend(hcAuthChain)
+	hcBulkResv.Add(validateBulkResParams)
+	router.Handle(http.MethodPatch, api.ResBulkExtend, hcBulkResv.ApplyTo(handleBulkEditReservations))
+	router.Handle(http.MethodPatch, api.ResBulkOwner, hcBulkResv.ApplyTo(handleBulkEditReservations))
+
+	// Read what deleting a reservation would do
+	hcReadResDelete := NewHandlerChain()
+	hcReadResDelete.Extend(hcDefaultChain)
+	hcReadResDelete.Extend(hcAuthChain)
+	router.Handle(http.MethodGet, api.ReservationsName, hcReadResDelete.ApplyTo(handleReadResDelete))
+
+	// Read the data attached to a reservation, access is checked by the handler
+	hcReadResData := NewHandlerChain()
+	hcReadResData.Extend(hcDefaultChain)
+	hcReadResData.Extend(hcAuthChain)
+	router.Handle(http.MethodGet, api.ResData, hcReadResData.ApplyTo(handleReadResData))
+
 	// Delete reservations
 	hcDeleteResv := NewHandlerChain()
 	hcDeleteResv.Extend(hcDefaultChain)
@@ -400,12 +501,99 @@ func applyApiRoutes(router *httprouter.Router) {
 	hcSync.Add(validateSyncParams)
 	router.Handle(http.MethodGet, api.Sync, hcSync.ApplyTo(syncHandler))
 
+	// Run database backup
+	hcDbBackup := NewHandlerChain()
+	hcDbBackup.Extend(hcDefaultChain)
+	hcDbBackup.Add(storeJSONBodyHandler)
+	hcDbBackup.Extend(hcAuthChain)
+	hcDbBackup.Add(validateBackupParams)
+	router.Handle(http.MethodPost, api.AdminBackup, hcDbBackup.ApplyTo(handleDbBackup))
+
+	// Read event hook status
+	hcHookStatus := NewHandlerChain()
+	hcHookStatus.Extend(hcDefaultChain)
+	hcHookStatus.Extend(hcAuthChain)
+	router.Handle(http.MethodGet, api.AdminHooks, hcHookStatus.ApplyTo(handleReadHookStatus))
+
+	// Audit PXE config files
+	hcPxeAudit := NewHandlerChain()
+	hcPxeAudit.Extend(hcDefaultChain)
+	hcPxeAudit.Extend(hcAuthChain)
+	router.Handle(http.MethodGet, api.AdminPxeAudit, hcPxeAudit.ApplyTo(handleReadPxeAudit))
+
+	// Gather a support bundle of server diagnostics
+	hcDiagnostics := NewHandlerChain()
+	hcDiagnostics.Extend(hcDefaultChain)
+	hcDiagnostics.Extend(hcAuthChain)
+	hcDiagnostics.Add(validateDiagnosticsParams)
+	router.Handle(http.MethodGet, api.AdminDiagnostics, hcDiagnostics.ApplyTo(handleReadDiagnostics))
+
+	// Check and repair database integrity
+	hcFsck := NewHandlerChain()
+	hcFsck.Extend(hcDefaultChain)
+	hcFsck.Extend(hcAuthChain)
+	router.Handle(http.MethodGet, api.AdminFsck, hcFsck.ApplyTo(handleFsck))
+	router.Handle(http.MethodPost, api.AdminFsck, hcFsck.ApplyTo(handleFsck))
+
+	// Read and revoke login sessions
+	hcSessions := NewHandlerChain()
+	hcSessions.Extend(hcDefaultChain)
+	hcSessions.Extend(hcAuthChain)
+	hcSessions.Add(validateAuthSessionParams)
+	router.Handle(http.MethodGet, api.AdminSessions, hcSessions.ApplyTo(handleReadAuthSessions))
+	router.Handle(http.MethodDelete, api.AdminSessions, hcSessions.ApplyTo(handleRevokeAuthSessions))
+
+	// Run a periodic server task right away
+	hcRunTask := NewHandlerChain()
+	hcRunTask.Extend(hcDefaultChain)
+	hcRunTask.Add(storeJSONBodyHandler)
+	hcRunTask.Extend(hcAuthChain)
+	hcRunTask.Add(validateRunTaskParams)
+	router.Handle(http.MethodPost, api.AdminTasks, hcRunTask.ApplyTo(handleRunManagerTask))
+
+	// Advance the scheduler clock, only in simulation mode
+	if igor.Simulation.Enabled {
+		hcSimClock := NewHandlerChain()
+		hcSimClock.Extend(hcDefaultChain)
+		hcSimClock.Add(storeJSONBodyHandler)
+		hcSimClock.Extend(hcAuthChain)
+		hcSimClock.Add(validateSimClockParams)
+		router.Handle(http.MethodPost, api.AdminSimClock, hcSimClock.ApplyTo(handleAdvanceSimClock))
+	}
+
 	// Run Token IAuth Secret Reset command
 	hcTokenAuthKeyReset := NewHandlerChain()
 	hcTokenAuthKeyReset.Extend(hcDefaultChain)
 	hcTokenAuthKeyReset.Extend(hcAuthChain)
 	router.Handle(http.MethodPut, api.AuthReset, hcTokenAuthKeyReset.ApplyTo(handleResetToken))
 
+	// Read VLAN allocations
+	hcVlans := NewHandlerChain()
+	hcVlans.Extend(hcDefaultChain)
+	hcVlans.Extend(hcAuthChain)
+	router.Handle(http.MethodGet, api.Vlans, hcVlans.ApplyTo(handleReadVlans))
+
+	// Create named network
+	hcCreateNetwork := NewHandlerChain()
+	hcCreateNetwork.Extend(hcDefaultChain)
+	hcCreateNetwork.Add(storeJSONBodyHandler)
+	hcCreateNetwork.Extend(hcAuthChain)
+	hcCreateNetwork.Add(validateNamedNetworkParams)
+	router.Handle(http.MethodPost, api.VlanNetworks, hcCreateNetwork.ApplyTo(handleCreateNamedNetwork))
+
+	// Delete named network
+	hcDeleteNetwork := NewHandlerChain()
+	hcDeleteNetwork.Extend(hcDefaultChain)
+	hcDeleteNetwork.Extend(hcAuthChain)
+	router.Handle(http.MethodDelete, api.VlanNetworksName, hcDeleteNetwork.ApplyTo(handleDeleteNamedNetwork))
+
+	// Read host availability over time
+	hcAvailability := NewHandlerChain()
+	hcAvailability.Extend(hcDefaultChain)
+	hcAvailability.Extend(hcAuthChain)
+	hcAvailability.Add(validateAvailabilityParams)
+	router.Handle(http.MethodGet, api.Availability, hcAvailability.ApplyTo(handleReadAvailability))
+
 	// Run Stats
 	hcStats := NewHandlerChain()
 	hcStats.Extend(hcDefaultChain)
@@ -140,3 +140,17 @@ func (r *Runner) Error() error {
 	}
 	return fmt.Errorf("hosts with errors: %v", hosts)
 }
+
+// Errors waits for all the functions to finish and returns the error of each host that had one.
+func (r *Runner) Errors() map[string]error {
+	r.wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	errs := make(map[string]error, len(r.errs))
+	for host, err := range r.errs {
+		errs[host] = err
+	}
+	return errs
+}
@@ -5,6 +5,7 @@
 package igorserver
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
@@ -34,27 +35,89 @@ func scheduleHostsByName(res *Reservation, tx *gorm.DB, clog *zl.Logger) (int, e
 	}
 
 	// check if all hosts are in an available state
-	status, err := dbCheckHostAvailable(hostNameList, tx)
+	isElevated := userElevated(res.Owner.Name)
+	status, err := dbCheckHostAvailable(hostNameList, res.Start, tx)
 	if err != nil {
-		return status, err
+		return status, explainHostRejections(res, hostNameList, groupAccessList, isElevated, status, err, tx, clog)
 	}
 
 	// check that no hosts have conflicts in their host policy
-	isElevated := userElevated(res.Owner.Name)
-	status, err = dbCheckHostPolicyConflicts(hostNameList, groupAccessList, isElevated, res.Start, res.End, res.End, clog)
+	status, err = dbCheckHostPolicyConflicts(hostNameList, groupAccessList, groupAccessList, isElevated, res.Start, res.End, res.End, clog)
 	if err != nil {
-		return status, err
+		return status, explainHostRejections(res, hostNameList, groupAccessList, isElevated, status, err, tx, clog)
 	}
 
 	// finally, make sure the hosts aren't already being used for the requested reservation times
 	_, status, err = dbCheckResvConflicts(hostNameList, res.Start, res.End, tx)
 	if err != nil {
-		return status, err
+		return status, explainHostRejections(res, hostNameList, groupAccessList, isElevated, status, err, tx, clog)
 	}
 
 	return status, nil
 }
 
+// explainHostRejections is called when a reservation on named hosts fails a scheduling check. It runs
+// the checks again on each host so every host that can't be used is reported with the first reason
+// it fails, rather than only the hosts that failed the first check. The checks are only repeated on
+// a conflict so requests that succeed do no extra work.
+func explainHostRejections(res *Reservation, hostNameList, groupAccessList []string, isElevated bool, status int, cause error,
+	tx *gorm.DB, clog *zl.Logger) error {
+
+	if status != http.StatusConflict {
+		return cause
+	}
+
+	var hosts []Host
+	if err := tx.Select("name", "state").Where("name IN ?", hostNameList).Find(&hosts).Error; err != nil {
+		clog.Warn().Msgf("unable to explain rejected hosts for reservation '%s': %v", res.Name, err)
+		return cause
+	}
+	hostStates := make(map[string]HostState, len(hosts))
+	for _, h := range hosts {
+		hostStates[h.Name] = h.State
+	}
+	maintEnds, err := dbHostMaintenanceEnds(hostNameList, tx)
+	if err != nil {
+		clog.Warn().Msgf("unable to explain rejected hosts for reservation '%s': %v", res.Name, err)
+		return cause
+	}
+
+	var rejected []common.HostRejectionData
+	var available []string
+	for _, name := range hostNameList {
+		rejection := common.HostRejectionData{Host: name}
+		maintEnd, inMaintenance := maintEnds[name]
+		if inMaintenance && res.Start.Before(maintEnd) {
+			rejection.Reason = "host is in maintenance until " + maintenanceEndString(maintEnd)
+		} else if state := hostStates[name]; !inMaintenance && (state == HostDraining || state > HostReserved) {
+			rejection.Reason = "host is " + state.String()
+		} else if _, pErr := dbCheckHostPolicyConflicts([]string{name}, groupAccessList, groupAccessList, isElevated,
+			res.Start, res.End, res.End, clog); pErr != nil {
+			var policyErr *HostPolicyConflictError
+			if !errors.As(pErr, &policyErr) {
+				clog.Warn().Msgf("unable to explain rejected hosts for reservation '%s': %v", res.Name, pErr)
+				return cause
+			}
+			rejection.Reason = policyErr.hostReason()
+		} else if conflicts, _, rErr := dbCheckResvConflicts([]string{name}, res.Start, res.End, tx); len(conflicts) > 0 {
+			sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Start.Before(conflicts[j].Start) })
+			rejection.Reason = "reserved by '" + conflicts[0].Name + "'"
+			rejection.ResName = conflicts[0].Name
+			rejection.ResStart = conflicts[0].Start.Unix()
+			rejection.ResEnd = conflicts[0].ResetEnd.Unix()
+		} else if rErr != nil {
+			clog.Warn().Msgf("unable to explain rejected hosts for reservation '%s': %v", res.Name, rErr)
+			return cause
+		} else {
+			available = append(available, name)
+			continue
+		}
+		rejected = append(rejected, rejection)
+	}
+
+	return &HostRejectionError{cause: cause, rejected: rejected, available: available}
+}
+
 // scheduleHostsByAvailability finds a suitable block of hosts that are free for the requested duration. If one
 // contiguous block isn't available it will find the smallest number of contiguous blocks possible.
 func scheduleHostsByAvailability(res *Reservation, tx *gorm.DB, clog *zl.Logger) ([]Host, int, error) {
@@ -62,12 +125,18 @@ func scheduleHostsByAvailability(res *Reservation, tx *gorm.DB, clog *zl.Logger)
 	numHostsReq := len(res.Hosts) // number of hosts needed for res
 	isElevated := userElevated(res.Owner.Name)
 
-	groupAccessList := []string{GroupAll}
-	if !strings.HasPrefix(res.Group.Name, GroupUserPrefix) {
-		groupAccessList = append(groupAccessList, res.Group.Name)
+	groupAccessList := resAccessGroups(res)
+
+	// asking for more hosts than the owner can ever use is a matter of access, not of finding a time
+	ceiling, limitedBy, status, err := dbGetAccessCeiling(groupAccessList, res.Owner.groupNames(), tx, clog)
+	if err != nil {
+		return nil, status, err
+	}
+	if numHostsReq > ceiling {
+		return nil, http.StatusConflict, accessCeilingError(numHostsReq, ceiling, limitedBy)
 	}
 
-	validAccessHosts, status, err := dbGetAccessibleHosts(groupAccessList, isElevated, res.Start, res.End, numHostsReq, tx, clog)
+	validAccessHosts, status, err := dbGetAccessibleHosts(groupAccessList, res.Owner.groupNames(), isElevated, res.Start, res.End, numHostsReq, tx, clog)
 	if err != nil {
 		return nil, status, err
 	}
@@ -105,11 +174,25 @@ func scheduleHostsByAvailability(res *Reservation, tx *gorm.DB, clog *zl.Logger)
 
 	// Now we have all the available nodes that can be scheduled during this reservation's requested time slot
 	if totalHostAvail < numHostsReq {
-		return nil, http.StatusConflict,
-			fmt.Errorf("%v hosts cannot be found with enough time available to service this request", numHostsReq)
+		return nil, http.StatusConflict, &ScheduleFullError{
+			numHostsReq: numHostsReq,
+			numAvail:    totalHostAvail,
+			start:       res.Start,
+			startIsNow:  res.Start.Sub(time.Now()) < time.Minute,
+		}
+	}
+
+	var hostUsage map[string]int64
+	if igor.Scheduler.HostTieBreak == HostTieBreakUsage {
+		hostUsage = map[string]int64{}
+		for _, ahList := range validAccessHosts {
+			for _, h := range ahList {
+				hostUsage[h.Name] = h.UsageMinutes
+			}
+		}
 	}
 
-	hostNameList := findBestSolution(validOpenSlotMap, hasRestrictedHosts, numHostsReq)
+	hostNameList := findBestSolution(validOpenSlotMap, hasRestrictedHosts, numHostsReq, hostUsage)
 
 	// now go get those hosts!
 	queryParams := map[string]interface{}{"name": hostNameList}
@@ -121,10 +204,23 @@ func scheduleHostsByAvailability(res *Reservation, tx *gorm.DB, clog *zl.Logger)
 	return hostResList, http.StatusOK, nil
 }
 
+// accessCeilingError explains that a reservation asks for more hosts than its owner can ever reserve,
+// naming the host policies that keep them from the rest when there are any.
+func accessCeilingError(numHostsReq, ceiling int, limitedBy []string) error {
+	msg := fmt.Sprintf("you have access to at most %d node(s); %d requested", ceiling, numHostsReq)
+	if len(limitedBy) > 0 {
+		msg += " - access is limited by host policies " + strings.Join(limitedBy, ", ")
+	}
+	return newCodedError(common.ErrNodeLimit, "%s", msg)
+}
+
 // findBestSolution picks the smallest number of contiguous segments it needs to make the reservation. If the reservation
 // includes a group that is part of a node restriction policy, it will attempt to prioritize use of the policy's nodes first
 // before grabbing nodes from the general open pool of nodes. It returns a list of hostnames included in the segment(s).
-func findBestSolution(validOpenSlotMap map[string][]ReservationTimeSlot, withRestrictedHosts bool, numHostsReq int) []string {
+//
+// When blocks are otherwise equally suitable the one earliest in sequence is used, unless hostUsage is given. It maps
+// host names to the minutes they have been reserved, and the block used the least is chosen to even out wear.
+func findBestSolution(validOpenSlotMap map[string][]ReservationTimeSlot, withRestrictedHosts bool, numHostsReq int, hostUsage map[string]int64) []string {
 
 	hostNameList := make([]string, numHostsReq)
 	validOpenSlots := make([]ReservationTimeSlot, 0)
@@ -198,9 +294,27 @@ func findBestSolution(validOpenSlotMap map[string][]ReservationTimeSlot, withRes
 		}
 	}
 
-	// Now sort the lists by size from largest to smallest
-	sort.Slice(cbList, func(i, j int) bool {
-		return len(cbList[i]) > len(cbList[j])
+	// Now sort the lists by size from largest to smallest. Blocks of the same size stay in sequence order
+	// unless hostUsage is given, then the block whose hosts have been reserved the least comes first.
+	var blockUsage map[string]int64
+	if hostUsage != nil {
+		blockUsage = make(map[string]int64, len(cbList))
+		for _, cb := range cbList {
+			if len(cb) > 0 {
+				for _, s := range cb {
+					blockUsage[cb[0].Hostname] += hostUsage[s.Hostname]
+				}
+			}
+		}
+	}
+	sort.SliceStable(cbList, func(i, j int) bool {
+		if len(cbList[i]) != len(cbList[j]) {
+			return len(cbList[i]) > len(cbList[j])
+		}
+		if blockUsage != nil && len(cbList[i]) > 0 {
+			return blockUsage[cbList[i][0].Hostname] < blockUsage[cbList[j][0].Hostname]
+		}
+		return false
 	})
 
 	// Assign nodes using the smallest number of contiguous blocks possible. Blocks of exact size needed will be
@@ -243,7 +357,14 @@ func findBestSolution(validOpenSlotMap map[string][]ReservationTimeSlot, withRes
 
 		if stillNeeded > 0 {
 			if len(moreCapacity) > 0 {
+				// use the smallest block with enough hosts, the first of that size if there are more than one
 				smc := moreCapacity[len(moreCapacity)-1]
+				for _, i := range moreCapacity {
+					if len(cbList[i]) == len(cbList[smc]) {
+						smc = i
+						break
+					}
+				}
 				assigned = append(assigned, smc)
 				stillNeeded -= len(cbList[smc])
 			} else {
@@ -301,14 +422,20 @@ func closeoutReservations(checkTime *time.Time) error {
 
 			resClone := r.DeepCopy()
 
+			// a reservation that never resumed from a pause, failed to start or was never approved has no hosts to give back
+			noHosts := r.isPaused() || r.startFailed() || r.awaitingApproval()
+			// wait out any reimage still writing to the hosts, which then need uninstalling regardless
+			wasInstalling := beginResTeardown(r.ID)
+
 			// transaction to delete the reservation
 			if err = performDbTx(func(tx *gorm.DB) error {
-				// delete the reservation - this will uninstall from hosts, remove power perms,
+				// delete the reservation - this will uninstall from hosts, remove node action perms,
 				// set hosts back to available, and remove the res from the db
-				_, err = doDeleteRes(&r, tx, true, &logger)
+				_, err = doDeleteRes(&r, tx, !noHosts, &logger)
 				return err
 			}); err != nil {
 				logger.Error().Msgf("failed to delete reservation '%s' - %v", r.Name, err)
+				endResTeardown(r.ID)
 				continue
 			}
 
@@ -323,9 +450,12 @@ func closeoutReservations(checkTime *time.Time) error {
 			}
 
 			// uninstall reservation vlan and tftp
-			if err = uninstallRes(resClone); err != nil {
-				logger.Error().Msgf("%v", err)
+			if !noHosts || wasInstalling {
+				if err = uninstallRes(resClone, hookRequestID(nil)); err != nil {
+					logger.Error().Msgf("%v", err)
+				}
 			}
+			endResTeardown(r.ID)
 
 		}
 
@@ -336,16 +466,11 @@ func closeoutReservations(checkTime *time.Time) error {
 	return nil
 }
 
-// doMaintenance calls the appropriate maintenance management function to operate on the given time parameter.
-func doMaintenance(ct *time.Time, m func(*time.Time) error) error {
-	return m(ct)
-}
-
 // puts the host(s) of an ending reservation into a maintenance/reset period
 // where the host(s) are made unavailable for the configured length of time.
 // If a Distro is declared as a default, it will be installed to the
-// reservation's hosts.
-func startMaintenance(res *MaintenanceRes) error {
+// reservation's hosts. The maintenance-start hook is run with reqID.
+func startMaintenance(res *MaintenanceRes, reqID string) error {
 	// get the admin user
 	admin, _, err := getIgorAdminTx()
 	if err != nil {
@@ -375,6 +500,10 @@ func startMaintenance(res *MaintenanceRes) error {
 		return fmt.Errorf("error in maintenance changing hosts to blocked state - %v", err.Error())
 	}
 
+	hookPayload := newHookPayload(HookMaintenanceStart, reqID, nil, res.Hosts)
+	hookPayload.Maintenance = &HookMaintData{Name: res.ReservationName, End: res.MaintenanceEndTime}
+	fireHook(hookPayload)
+
 	// check for a default distro image
 	hasDefaultDistro := false
 	currentDefaultDistros, err := dbReadDistrosTx(map[string]interface{}{"is_default": true})
@@ -509,67 +638,140 @@ func installReservations(checkTime *time.Time) error {
 		return err
 	} else if len(resList) > 0 {
 		for _, r := range resList {
-			if !r.Installed {
-				// sanity check that the hosts having their state updated should be HOST_AVAILABLE (0)
-				for _, h := range r.Hosts {
-					if h.State > HostAvailable {
-						logger.Error().Msgf("host %s for reservation '%s' start in the state %v before being made available", h.Name, r.Name, h.State)
+			// paused reservations are installed again once they have been resumed, and those that failed to
+			// start wait to be deleted
+			if !r.Installed && !r.isPaused() && !r.startFailed() && !r.awaitingApproval() {
+
+				// a reservation with an install error has already been activated, so only the hosts
+				// that failed to install need to be tried again
+				isRetry := r.InstallError != ""
+				installHosts := r.Hosts
+				if isRetry {
+					installHosts = nil
+					for _, h := range r.Hosts {
+						if h.InstallError != "" {
+							installHosts = append(installHosts, h)
+						}
+					}
+					if len(installHosts) == 0 {
+						installHosts = r.Hosts
+					}
+					logger.Debug().Msgf("retrying install of reservation '%s' on host(s) %v", r.Name, namesOfHosts(installHosts))
+				} else {
+					// deal with hosts that became unavailable since the reservation was made
+					if !startReconciled(&r, *checkTime) {
+						continue
+					}
+					installHosts = r.Hosts
+
+					// sanity check that the hosts having their state updated should be HOST_AVAILABLE (0)
+					for _, h := range r.Hosts {
+						if h.State > HostAvailable {
+							logger.Error().Msgf("host %s for reservation '%s' start in the state %v before being made available", h.Name, r.Name, h.State)
+						}
 					}
 				}
 
+				var installSummary string
+				var hostErrors map[string]error
+
 				if err = performDbTx(func(tx *gorm.DB) error {
 
-					// change the reservation's hosts to 'reserved'
-					logger.Debug().Msg("changing state of reservation hosts to reserved")
-					changes := map[string]interface{}{"State": HostReserved}
-					if ehErr := dbEditHosts(r.Hosts, changes, tx); ehErr != nil {
-						return ehErr
+					if !beginResInstall(r.ID) {
+						return fmt.Errorf("reservation is being removed")
 					}
+					defer endResInstall(r.ID)
+
+					if !isRetry {
+						// change the reservation's hosts to 'reserved'
+						logger.Debug().Msg("changing state of reservation hosts to reserved")
+						changes := map[string]interface{}{"State": HostReserved}
+						if ehErr := dbEditHosts(r.Hosts, changes, tx); ehErr != nil {
+							return ehErr
+						}
 
-					// create the power permission for the reservation's hosts and add it to the permissions table
-					logger.Debug().Msgf("activating power permissions for reservation %s", r.Name)
-					powerPerm, permErr := NewPermission(makeNodePowerPerm(r.Hosts))
-					if permErr != nil {
-						return permErr
-					}
+						// create the node action permission for the reservation's hosts and add it to the permissions table
+						logger.Debug().Msgf("activating node action permissions for reservation %s", r.Name)
+						nodePerm, permErr := NewPermission(makeNodeActionPerm(r.Hosts))
+						if permErr != nil {
+							return permErr
+						}
 
-					if apErr := dbAppendPermissions(&r.Group, []Permission{*powerPerm}, tx); apErr != nil {
-						return apErr
-					}
+						if apErr := dbAppendPermissions(&r.Group, []Permission{*nodePerm}, tx); apErr != nil {
+							return apErr
+						}
 
-					// skip if not using vlan
-					if igor.Vlan.Network != "" {
-						// update network config
-						if nsErr := networkSet(r.Hosts, r.Vlan); nsErr != nil {
-							return fmt.Errorf("error setting network isolation: %v", nsErr)
+						// skip if not using vlan
+						if igor.Vlan.Network != "" {
+							// update network config
+							if nsErr := networkSet(r.Hosts, r.Vlan, r.NetProfile); nsErr != nil {
+								return fmt.Errorf("error setting network isolation: %v", nsErr)
+							}
 						}
 					}
 
 					// install the reservation's profile to its hosts
 					logger.Debug().Msgf("installing PXE files for reservation %s", r.Name)
-					if irErr := igor.IResInstaller.Install(&r); irErr != nil {
-						// update the reservation with the error message
-						if irErr = dbEditReservation(&r, map[string]interface{}{"install_error": irErr.Error()}, tx); irErr != nil {
-							return irErr
+					installRes := r.DeepCopy()
+					installRes.Hosts = installHosts
+					hostErrors = map[string]error{}
+					if irErr := igor.IResInstaller.Install(installRes); irErr != nil {
+						var hiErr *HostInstallError
+						if errors.As(irErr, &hiErr) {
+							hostErrors = hiErr.HostErrors
+						} else {
+							for _, h := range installHosts {
+								hostErrors[h.Name] = irErr
+							}
 						}
-						return irErr
 					}
 
-					if r.CycleOnStart {
+					// record the result for each host and update the reservation's error summary
+					summary, rErr := dbRecordInstallResult(&r, installHosts, hostErrors, tx)
+					if rErr != nil {
+						return rErr
+					}
+					installSummary = summary
+
+					var cycleHosts []Host
+					for _, h := range installHosts {
+						if _, failed := hostErrors[h.Name]; !failed {
+							cycleHosts = append(cycleHosts, h)
+						}
+					}
+
+					if r.CycleOnStart && len(cycleHosts) > 0 {
 						logger.Debug().Msgf("power cycling hosts for reservation '%s'", r.Name)
-						if _, powerErr := doPowerHosts(PowerCycle, hostNamesOfHosts(r.Hosts), &logger); powerErr != nil {
+						if _, powerErr := doPowerHosts(PowerCycle, hostNamesOfHosts(cycleHosts), &logger); powerErr != nil {
 							// don't return this error we still want to mark it installed
-							logger.Error().Msgf("problem powering cycling hosts for reservation '%s': %v", r.Name, powerErr)
+							logEvent(&logger, EvResPowerCycleFailed).Str(LogFieldReservation, r.Name).Err(powerErr).
+								Msgf("problem powering cycling hosts for reservation '%s': %v", r.Name, powerErr)
 						}
-					} else {
+					} else if !r.CycleOnStart && !isRetry {
 						logger.Warn().Msgf("The reservation '%s' was not powered cycled at start", r.Name)
 					}
 
+					// leave the reservation uninstalled so the failed hosts are retried on the next pass
+					if summary != "" {
+						return nil
+					}
+
 					// update the reservation as installed
 					return dbEditReservation(&r, map[string]interface{}{"installed": true}, tx)
 
 				}); err != nil {
-					logger.Error().Msgf("failed to install reservation '%s' - %v", r.Name, err)
+					logEvent(&logger, EvResInstallFailed).Str(LogFieldReservation, r.Name).Err(err).
+						Msgf("failed to install reservation '%s' - %v", r.Name, err)
+					continue
+				}
+
+				if installSummary != "" {
+					logEvent(&logger, EvResInstallFailed).Str(LogFieldReservation, r.Name).Str(LogFieldError, installSummary).
+						Msgf("reservation '%s' is not fully installed and will be retried - %s", r.Name, installSummary)
+					// members are only told about the first failure, not each retry
+					if !isRetry {
+						notifyInstallFail(r.DeepCopy(), hostErrors)
+					}
 					continue
 				}
 
@@ -585,6 +787,8 @@ func installReservations(checkTime *time.Time) error {
 				if startEvent := makeResWarnNotifyEvent(EmailResStart, 0, r.DeepCopy(), clusters[0].Name); startEvent != nil {
 					resNotifyChan <- *startEvent
 				}
+
+				fireHook(newHookPayload(HookResStart, hookRequestID(nil), &r, r.Hosts))
 			}
 		}
 	} else {
@@ -594,6 +798,27 @@ func installReservations(checkTime *time.Time) error {
 	return nil
 }
 
+// notifyInstallFail sends the members of a reservation the hosts that failed to install along with
+// their console links.
+func notifyInstallFail(res *Reservation, hostErrors map[string]error) {
+
+	for i := range res.Hosts {
+		if hErr, ok := hostErrors[res.Hosts[i].Name]; ok {
+			res.Hosts[i].InstallError = hErr.Error()
+		}
+	}
+
+	clusters, cErr := dbReadClustersTx(nil)
+	if cErr != nil {
+		logger.Error().Msgf("unable to send install failure notice for reservation '%s' - %v", res.Name, cErr)
+		return
+	}
+
+	if failEvent := makeResWarnNotifyEvent(EmailResInstallFail, 0, res, clusters[0].Name); failEvent != nil {
+		resNotifyChan <- *failEvent
+	}
+}
+
 // sendExpirationWarnings will check if any reservation at the given time is due to get a warning email and
 // dispatch an event to the notification manager if true.
 func sendExpirationWarnings(checkTime *time.Time) error {
@@ -637,3 +862,197 @@ func sendExpirationWarnings(checkTime *time.Time) error {
 
 	return nil
 }
+
+// resAccessGroups returns the groups whose host policies res can draw hosts from.
+func resAccessGroups(res *Reservation) []string {
+	groupAccessList := []string{GroupAll}
+	if !strings.HasPrefix(res.Group.Name, GroupUserPrefix) {
+		groupAccessList = append(groupAccessList, res.Group.Name)
+	}
+	return groupAccessList
+}
+
+// findEarliestStart returns the earliest time after the start of res, and before the end of the
+// schedule, that enough hosts are free to run it for the same duration. Each candidate time is checked
+// against the host policies the owner can use at that time. It returns a zero time if there is none.
+// This is only meant to be called after scheduleHostsByAvailability fails, so successful requests skip
+// the extra work.
+func findEarliestStart(res *Reservation, tx *gorm.DB, clog *zl.Logger) (time.Time, error) {
+
+	numHostsReq := len(res.Hosts)
+	isElevated := userElevated(res.Owner.Name)
+	groupAccessList := resAccessGroups(res)
+	duration := res.End.Sub(res.Start)
+	paddedDur := determineNodeResetTime(res.End).Sub(res.Start)
+	scheduleEnd := getScheduleEnd(isElevated)
+
+	accessHosts, _, err := dbGetAccessibleHosts(groupAccessList, res.Owner.groupNames(), isElevated, res.Start, res.End, numHostsReq, tx, clog)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var hostNames []string
+	for _, hosts := range accessHosts {
+		hostNames = append(hostNames, namesOfHosts(hosts)...)
+	}
+
+	// ask for more hosts than there are so every open slot comes back, not just the completely free hosts
+	openSlots, _, err := dbFindOpenSlots(hostNames, res.Start, paddedDur, scheduleEnd, len(hostNames)+1, tx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// the number of free hosts can only grow when a slot begins, rounded up to the minute since that's
+	// as fine as a start time can be given
+	var candidates []time.Time
+	seen := map[time.Time]bool{}
+	for _, s := range openSlots {
+		t := s.AvailSlotBegin
+		if t.Truncate(time.Minute) != t {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		}
+		if t.After(res.Start) && !seen[t] {
+			seen[t] = true
+			candidates = append(candidates, t)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	for _, t := range candidates {
+		if t.Add(paddedDur).After(scheduleEnd) {
+			break
+		}
+		policyHosts, _, ahErr := dbGetAccessibleHosts(groupAccessList, res.Owner.groupNames(), isElevated, t, t.Add(duration), numHostsReq, tx, clog)
+		if ahErr != nil {
+			continue
+		}
+		usable := map[string]bool{}
+		for _, hosts := range policyHosts {
+			for _, name := range namesOfHosts(hosts) {
+				usable[name] = true
+			}
+		}
+		free := map[string]bool{}
+		for _, s := range openSlots {
+			if usable[s.Hostname] && !s.AvailSlotBegin.After(t) && !t.Add(paddedDur).After(s.AvailSlotEnd) {
+				free[s.Hostname] = true
+			}
+		}
+		if len(free) >= numHostsReq {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, nil
+}
+
+// findEarliestHostsStart returns the earliest time at or after start, and before the end of the schedule,
+// that all the named hosts are free together to run a reservation from start to end for the same length.
+// It is how a request for named hosts that are busy waits for them instead of failing. If the hosts are
+// never free together it returns a HostRejectionError naming the last reservation in the way on each
+// host that has one.
+func findEarliestHostsStart(hostNames []string, owner *User, start, end time.Time, tx *gorm.DB, clog *zl.Logger) (time.Time, int, error) {
+
+	isElevated := userElevated(owner.Name)
+	var groupAccessList []string
+	for _, uGroup := range owner.Groups {
+		if !strings.HasPrefix(uGroup.Name, GroupUserPrefix) {
+			groupAccessList = append(groupAccessList, uGroup.Name)
+		}
+	}
+	duration := end.Sub(start)
+	paddedDur := determineNodeResetTime(end).Sub(start)
+	scheduleEnd := getScheduleEnd(isElevated)
+
+	// ask for more hosts than there are so every open slot comes back, not just the completely free hosts
+	openSlots, status, err := dbFindOpenSlots(hostNames, start, paddedDur, scheduleEnd, len(hostNames)+1, tx)
+	if err != nil {
+		return time.Time{}, status, err
+	}
+
+	// the hosts can only all become free when one of their slots begins, rounded up to the minute since
+	// that's as fine as a start time can be given
+	candidates := []time.Time{start}
+	seen := map[time.Time]bool{}
+	for _, s := range openSlots {
+		t := s.AvailSlotBegin
+		if t.Truncate(time.Minute) != t {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		}
+		if t.After(start) && !seen[t] {
+			seen[t] = true
+			candidates = append(candidates, t)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	for _, t := range candidates {
+		if t.Add(paddedDur).After(scheduleEnd) {
+			break
+		}
+		free := map[string]bool{}
+		for _, s := range openSlots {
+			if !s.AvailSlotBegin.After(t) && !t.Add(paddedDur).After(s.AvailSlotEnd) {
+				free[s.Hostname] = true
+			}
+		}
+		if len(free) < len(hostNames) {
+			continue
+		}
+		// a policy can keep the hosts unavailable at this time, but any other policy conflict holds for all of them
+		if pStatus, pErr := dbCheckHostPolicyConflicts(hostNames, groupAccessList, groupAccessList, isElevated, t, t.Add(duration), t.Add(duration), clog); pErr != nil {
+			var policyErr *HostPolicyConflictError
+			if errors.As(pErr, &policyErr) && policyErr.scheduleConflict {
+				continue
+			}
+			return time.Time{}, pStatus, pErr
+		}
+		return t, http.StatusOK, nil
+	}
+
+	cause := newCodedError(common.ErrResConflict, "hosts %s are not all free at the same time for %s before the schedule ends at %s",
+		common.UnsplitList(hostNames), common.FormatDuration(duration, true), scheduleEnd.Format(common.DateTimeCompactFormat))
+
+	// hosts the scheduler won't place reservations on have no open slots at all
+	hasSlot := map[string]bool{}
+	for _, s := range openSlots {
+		hasSlot[s.Hostname] = true
+	}
+	var hosts []Host
+	if err = tx.Select("name", "state").Where("name IN ?", hostNames).Find(&hosts).Error; err != nil {
+		clog.Warn().Msgf("unable to find the reservations in the way of hosts %v: %v", hostNames, err)
+		return time.Time{}, http.StatusConflict, cause
+	}
+	hostStates := make(map[string]HostState, len(hosts))
+	for _, h := range hosts {
+		hostStates[h.Name] = h.State
+	}
+
+	var rejected []common.HostRejectionData
+	var available []string
+	for _, name := range hostNames {
+		conflicts, _, rErr := dbCheckResvConflicts([]string{name}, start, scheduleEnd, tx)
+		if rErr != nil && len(conflicts) == 0 {
+			clog.Warn().Msgf("unable to find the reservations in the way of hosts %v: %v", hostNames, rErr)
+			return time.Time{}, http.StatusConflict, cause
+		}
+		if len(conflicts) == 0 {
+			if hasSlot[name] {
+				available = append(available, name)
+			} else {
+				rejected = append(rejected, common.HostRejectionData{Host: name, Reason: "host is " + hostStates[name].String()})
+			}
+			continue
+		}
+		sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].ResetEnd.After(conflicts[j].ResetEnd) })
+		latest := conflicts[0]
+		rejected = append(rejected, common.HostRejectionData{
+			Host:     name,
+			Reason:   "last reserved by '" + latest.Name + "'",
+			ResName:  latest.Name,
+			ResStart: latest.Start.Unix(),
+			ResEnd:   latest.ResetEnd.Unix(),
+		})
+	}
+
+	return time.Time{}, http.StatusConflict, &HostRejectionError{cause: cause, rejected: rejected, available: available}
+}
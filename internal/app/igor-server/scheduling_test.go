@@ -5,9 +5,19 @@
 package igorserver
 
 import (
-	"github.com/stretchr/testify/assert"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
 )
 
 func getMaxEnd() time.Time {
@@ -35,7 +45,7 @@ func TestNoContiguousBlocks(t *testing.T) {
 	testSlotsMap := map[string][]ReservationTimeSlot{}
 	testSlotsMap[DefaultPolicyName] = testSlots
 
-	hostNameList := findBestSolution(testSlotsMap, false, 5)
+	hostNameList := findBestSolution(testSlotsMap, false, 5, nil)
 
 	assert.Contains(t, hostNameList, "kn3", "doesn't contain all nodes")
 	assert.Contains(t, hostNameList, "kn9", "doesn't contain all nodes")
@@ -66,7 +76,7 @@ func TestChooseSmallerContiguousBlock(t *testing.T) {
 	testSlotsMap := map[string][]ReservationTimeSlot{}
 	testSlotsMap[DefaultPolicyName] = testSlots
 
-	hostNameList := findBestSolution(testSlotsMap, false, 4)
+	hostNameList := findBestSolution(testSlotsMap, false, 4, nil)
 
 	assert.Contains(t, hostNameList, "kn22", "doesn't contain all correct nodes")
 	assert.Contains(t, hostNameList, "kn14", "doesn't contain all correct nodes")
@@ -75,7 +85,7 @@ func TestChooseSmallerContiguousBlock(t *testing.T) {
 	assert.NotContains(t, hostNameList, "kn8", "node should not be present")
 	assert.NotContains(t, hostNameList, "kn9", "node should not be present")
 
-	hostNameList = findBestSolution(testSlotsMap, false, 2)
+	hostNameList = findBestSolution(testSlotsMap, false, 2, nil)
 
 	assert.NotContains(t, hostNameList, "kn22", "node should not be present")
 	assert.NotContains(t, hostNameList, "kn14", "node should not be present")
@@ -85,3 +95,331 @@ func TestChooseSmallerContiguousBlock(t *testing.T) {
 	assert.Contains(t, hostNameList, "kn9", "doesn't contain all correct nodes")
 
 }
+
+func TestChooseLeastUsedBlock(t *testing.T) {
+
+	testNow := time.Date(2021, time.April, 1, 10, 0, 0, 0, time.Local)
+	var testSlots []ReservationTimeSlot
+	for _, n := range []int{1, 2, 3, 5, 6, 7} {
+		testSlots = append(testSlots, ReservationTimeSlot{fmt.Sprintf("kn%d", n), n, "", time.Time{}, testNow, "", getMaxEnd()})
+	}
+	testSlotsMap := map[string][]ReservationTimeSlot{DefaultPolicyName: testSlots}
+
+	// blocks of equal size are used in sequence order without usage, or when usage is equal
+	assert.Equal(t, []string{"kn1", "kn2", "kn3"}, findBestSolution(testSlotsMap, false, 3, nil))
+	assert.Equal(t, []string{"kn1", "kn2", "kn3"}, findBestSolution(testSlotsMap, false, 3, map[string]int64{}))
+
+	// the block held the least wins the tie, for an exact fit or a block with room to spare
+	usage := map[string]int64{"kn1": 600, "kn5": 60, "kn6": 60, "kn7": 60}
+	assert.Equal(t, []string{"kn5", "kn6", "kn7"}, findBestSolution(testSlotsMap, false, 3, usage))
+	assert.Equal(t, []string{"kn5", "kn6"}, findBestSolution(testSlotsMap, false, 2, usage))
+}
+
+func TestAddHostUsage(t *testing.T) {
+
+	hosts := seedTimeLimitHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	// a reservation deleted early only counts the time it held its hosts
+	now := time.Now()
+	res := Reservation{Start: now.Add(-90 * time.Minute), End: now.Add(time.Hour), Hosts: hosts[:1]}
+	require.NoError(t, dbAddHostUsage(&res, db))
+	res = Reservation{Start: now.Add(-3 * time.Hour), End: now.Add(-time.Hour), Hosts: hosts}
+	require.NoError(t, dbAddHostUsage(&res, db))
+
+	var usage []int64
+	require.NoError(t, db.Model(&Host{}).Order("name").Pluck("usage_minutes", &usage).Error)
+	assert.Equal(t, []int64{210, 120}, usage)
+}
+
+func TestScheduleFull(t *testing.T) {
+
+	origSchedMinutes := MaxScheduleMinutes
+	t.Cleanup(func() { MaxScheduleMinutes = origSchedMinutes })
+	MaxScheduleMinutes = 45 * 24 * 60
+	hosts := seedTimeLimitHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	// kn1 is busy for the next hour
+	busy := newStartTestRes(t, db, "busy", hosts[:1], true, 0)
+	require.NoError(t, db.Model(busy).Update("reset_end", busy.End).Error)
+
+	req := busy.DeepCopy()
+	req.Name = "wants2"
+	req.Hosts = make([]Host, 2)
+	req.Start = time.Now()
+	req.End = req.Start.Add(2 * time.Hour)
+
+	var fullErr *ScheduleFullError
+	var suggested time.Time
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		_, status, err := scheduleHostsByAvailability(req, tx, &logger)
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, common.ErrResConflict, errorCodeOf(err))
+		require.ErrorAs(t, err, &fullErr)
+		suggested, err = findEarliestStart(req, tx, &logger)
+		return err
+	}))
+	assert.Equal(t, 1, fullErr.numAvail)
+	assert.Equal(t, "2 nodes not available now; 1 node available now.", fullErr.Error())
+
+	// both hosts are free once kn1 is, rounded up to the minute
+	want := busy.End.Truncate(time.Minute).Add(time.Minute)
+	assert.Equal(t, want, suggested)
+	fullErr.searched, fullErr.suggestedStart = true, suggested
+	assert.Equal(t, "2 nodes not available now; 2 nodes free starting "+want.Format("Jan 2 15:04")+
+		", or 1 node available now.", fullErr.Error())
+
+	// nothing is suggested past the end of the schedule
+	MaxScheduleMinutes = 90
+	require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+		var err error
+		suggested, err = findEarliestStart(req, tx, &logger)
+		return err
+	}))
+	assert.True(t, suggested.IsZero())
+	fullErr.suggestedStart = suggested
+	assert.Contains(t, fullErr.Error(), "; 1 node available now; no later start before the end of the schedule")
+}
+
+func TestScheduleByNameRejections(t *testing.T) {
+
+	hosts := seedTimeLimitHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	// kn1 is busy for the next hour
+	busy := newStartTestRes(t, db, "busy", hosts[:1], false, 0)
+	require.NoError(t, db.Model(busy).Update("reset_end", busy.End).Error)
+
+	schedule := func(dur time.Duration) (*HostRejectionError, string) {
+		req := busy.DeepCopy()
+		req.Name = "wants-both"
+		req.Hosts = hosts
+		req.Start = time.Now()
+		req.End = req.Start.Add(dur)
+		var rejectErr *HostRejectionError
+		var code string
+		require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+			status, err := scheduleHostsByName(req, tx, &logger)
+			assert.Equal(t, http.StatusConflict, status)
+			require.ErrorAs(t, err, &rejectErr)
+			code = errorCodeOf(err)
+			return nil
+		}))
+		return rejectErr, code
+	}
+
+	// only kn1 is reserved, so kn2 is suggested
+	rejectErr, _ := schedule(2 * time.Hour)
+	require.Len(t, rejectErr.rejected, 1)
+	assert.Equal(t, "kn1", rejectErr.rejected[0].Host)
+	assert.Equal(t, "busy", rejectErr.rejected[0].ResName)
+	assert.Equal(t, busy.Start.Unix(), rejectErr.rejected[0].ResStart)
+	assert.Equal(t, busy.End.Unix(), rejectErr.rejected[0].ResEnd)
+	assert.Equal(t, []string{"kn2"}, rejectErr.available)
+
+	// the policy check fails first on kn2 but kn1 is still reported for its reservation
+	rejectErr, code := schedule(48 * time.Hour)
+	assert.Equal(t, common.ErrPolicyDuration, code)
+	require.Len(t, rejectErr.rejected, 2)
+	assert.Equal(t, "busy", rejectErr.rejected[0].ResName)
+	assert.Equal(t, "kn2", rejectErr.rejected[1].Host)
+	assert.Contains(t, rejectErr.rejected[1].Reason, "duration")
+	assert.Empty(t, rejectErr.available)
+
+	// the state check comes first for a host
+	require.NoError(t, db.Model(&Host{}).Where("name = ?", "kn2").Update("state", HostBlocked).Error)
+	rejectErr, _ = schedule(48 * time.Hour)
+	require.Len(t, rejectErr.rejected, 2)
+	assert.Equal(t, "host is blocked", rejectErr.rejected[1].Reason)
+	assert.Contains(t, rejectErr.Error(), "not available")
+}
+
+func TestFindEarliestHostsStart(t *testing.T) {
+
+	origSchedMinutes := MaxScheduleMinutes
+	t.Cleanup(func() { MaxScheduleMinutes = origSchedMinutes })
+	MaxScheduleMinutes = 45 * 24 * 60
+	hosts := seedTimeLimitHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+
+	// kn1 is busy for the next hour and kn2 is busy from two to three hours from now
+	busy := newStartTestRes(t, db, "busy", hosts[:1], false, 0)
+	require.NoError(t, db.Model(busy).Update("reset_end", busy.End).Error)
+	later := newStartTestRes(t, db, "later", hosts[1:], false, 0)
+	laterStart := busy.Start.Add(2 * time.Hour)
+	require.NoError(t, db.Model(later).Updates(map[string]interface{}{"start": laterStart,
+		"end": laterStart.Add(time.Hour), "reset_end": laterStart.Add(time.Hour)}).Error)
+
+	users, err := dbReadUsersTx(map[string]interface{}{"name": "alice"})
+	require.NoError(t, err)
+	alice := &users[0]
+	hostNames := namesOfHosts(hosts)
+
+	find := func(dur time.Duration) (time.Time, int, error) {
+		var start time.Time
+		var status int
+		var err error
+		now := time.Now()
+		require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+			start, status, err = findEarliestHostsStart(hostNames, alice, now, now.Add(dur), tx, &logger)
+			return nil
+		}))
+		return start, status, err
+	}
+
+	// a short reservation fits between the end of busy and the start of later
+	start, status, err := find(30 * time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, busy.End.Truncate(time.Minute).Add(time.Minute), start)
+
+	// a longer one has to wait until later is over
+	start, _, err = find(2 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, laterStart.Add(time.Hour).Truncate(time.Minute).Add(time.Minute), start)
+
+	// the hosts are never free together before the end of the schedule
+	MaxScheduleMinutes = 150
+	_, status, err = find(2 * time.Hour)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, common.ErrResConflict, errorCodeOf(err))
+	var rejectErr *HostRejectionError
+	require.ErrorAs(t, err, &rejectErr)
+	require.Len(t, rejectErr.rejected, 2)
+	assert.Equal(t, "busy", rejectErr.rejected[0].ResName)
+	assert.Equal(t, "later", rejectErr.rejected[1].ResName)
+	assert.Equal(t, laterStart.Add(time.Hour).Unix(), rejectErr.rejected[1].ResEnd)
+}
+
+func TestScheduleAccessCeiling(t *testing.T) {
+
+	origSchedMinutes := MaxScheduleMinutes
+	t.Cleanup(func() { MaxScheduleMinutes = origSchedMinutes })
+	MaxScheduleMinutes = 45 * 24 * 60
+	hosts := seedTimeLimitHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	base := newStartTestRes(t, db, "base", hosts[:1], true, 0)
+
+	// kn1 is only open to the lab group and kn3 joins kn2 under the short policy
+	lab := Group{Name: "lab"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&lab).Error)
+	policies, err := dbReadHostPolicies(map[string]interface{}{"name": "long"}, db, &logger)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&policies[0]).Association("AccessGroups").Replace(&lab))
+	kn3 := Host{Name: "kn3", HostName: "kn3", SequenceID: 3, Mac: "00:00:00:00:00:03", State: HostAvailable,
+		HostPolicyID: hosts[1].HostPolicyID}
+	require.NoError(t, db.Omit(clause.Associations).Create(&kn3).Error)
+
+	ceiling, limitedBy, _, err := dbGetAccessCeiling([]string{GroupAll}, nil, db, &logger)
+	require.NoError(t, err)
+	assert.Equal(t, 2, ceiling)
+	assert.Equal(t, []string{"long"}, limitedBy)
+	ceiling, limitedBy, _, err = dbGetAccessCeiling([]string{GroupAll, "lab"}, nil, db, &logger)
+	require.NoError(t, err)
+	assert.Equal(t, 3, ceiling)
+	assert.Empty(t, limitedBy)
+
+	// the request is made after the base reservation so only access limits it
+	schedule := func(count int) ([]Host, int, error) {
+		req := base.DeepCopy()
+		req.Name = fmt.Sprintf("wants%d", count)
+		req.Hosts = make([]Host, count)
+		req.Start = base.End.Add(24 * time.Hour)
+		req.End = req.Start.Add(time.Hour)
+		var scheduled []Host
+		var status int
+		var schedErr error
+		require.NoError(t, performDbTx(func(tx *gorm.DB) error {
+			scheduled, status, schedErr = scheduleHostsByAvailability(req, tx, &logger)
+			return nil
+		}))
+		return scheduled, status, schedErr
+	}
+
+	// below and at the ceiling
+	scheduled, _, err := schedule(1)
+	require.NoError(t, err)
+	assert.Len(t, scheduled, 1)
+	scheduled, _, err = schedule(2)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"kn2", "kn3"}, namesOfHosts(scheduled))
+
+	// above it the error is about access rather than time
+	_, status, err := schedule(3)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, common.ErrNodeLimit, errorCodeOf(err))
+	assert.EqualError(t, err, "you have access to at most 2 node(s); 3 requested - access is limited by host policies long")
+	var fullErr *ScheduleFullError
+	assert.False(t, errors.As(err, &fullErr))
+
+	// a blocked host can't be reserved either
+	require.NoError(t, db.Model(&Host{}).Where("name = ?", "kn3").Update("state", HostBlocked).Error)
+	_, _, err = schedule(2)
+	assert.EqualError(t, err, "you have access to at most 1 node(s); 2 requested - access is limited by host policies long")
+}
+
+// flakyInstaller is an IResInstaller that fails on the hosts in failing and records the hosts each
+// install was asked to write.
+type flakyInstaller struct {
+	failing  map[string]bool
+	installs [][]string
+}
+
+func (fi *flakyInstaller) Install(r *Reservation) error {
+	fi.installs = append(fi.installs, namesOfHosts(r.Hosts))
+	hiErr := &HostInstallError{HostErrors: map[string]error{}}
+	for _, h := range r.Hosts {
+		if fi.failing[h.Name] {
+			hiErr.HostErrors[h.Name] = errors.New("tftp write failed")
+		}
+	}
+	if len(hiErr.HostErrors) > 0 {
+		return hiErr
+	}
+	return nil
+}
+
+func (fi *flakyInstaller) Uninstall(*Reservation) error { return nil }
+
+func TestInstallRetriesFailedHosts(t *testing.T) {
+
+	origInstaller, origSmtp := igor.IResInstaller, igor.Email.SmtpServers
+	t.Cleanup(func() { igor.IResInstaller, igor.Email.SmtpServers = origInstaller, origSmtp })
+	installer := &flakyInstaller{failing: map[string]bool{"kn2": true}}
+	igor.IResInstaller = installer
+	igor.Email.SmtpServers = nil
+	hosts := seedTestHosts(t, newTestDbAt(t, filepath.Join(t.TempDir(), "igor.db")))
+	db := igor.IGormDb.GetDB()
+	require.NoError(t, db.Create(&Cluster{Name: "test", Prefix: "kn"}).Error)
+	newStartTestRes(t, db, "exp", hosts, false, 0)
+
+	readRes := func() *Reservation {
+		stored, err := dbReadReservationsTx(map[string]interface{}{"name": "exp"}, nil)
+		require.NoError(t, err)
+		require.Len(t, stored, 1)
+		return &stored[0]
+	}
+
+	// kn2 fails, so the reservation stays uninstalled with the failure recorded against that host only
+	now := time.Now()
+	require.NoError(t, installReservations(&now))
+	res := readRes()
+	assert.False(t, res.Installed)
+	assert.Contains(t, res.InstallError, "kn2")
+	assert.NotContains(t, res.InstallError, "kn1")
+	assert.Empty(t, res.Hosts[0].InstallError)
+	assert.Equal(t, "tftp write failed", res.Hosts[1].InstallError)
+
+	// the next pass only tries kn2 again and completes the install once it works
+	delete(installer.failing, "kn2")
+	now = time.Now()
+	require.NoError(t, installReservations(&now))
+	require.Len(t, installer.installs, 2)
+	assert.Equal(t, []string{"kn1", "kn2"}, installer.installs[0])
+	assert.Equal(t, []string{"kn2"}, installer.installs[1])
+	res = readRes()
+	assert.True(t, res.Installed)
+	assert.Empty(t, res.InstallError)
+	assert.Empty(t, res.Hosts[1].InstallError)
+}
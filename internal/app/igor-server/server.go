@@ -18,6 +18,8 @@ import (
 
 	"github.com/rs/cors"
 	//_ "net/http/pprof"
+
+	"igor2/internal/pkg/common"
 )
 
 // Global Variables
@@ -29,25 +31,21 @@ var (
 	groupNotifyChan  = make(chan GroupNotifyEvent, 100)
 	refreshPowerChan = make(chan struct{}, 250)
 	shutdownChan     = make(chan struct{})
+	// resManageMU keeps the manager tasks that change reservations and hosts from running at the same time
+	resManageMU sync.Mutex
 )
 
 // runServer sets up and runs the server processes. It blocks until shutdown.
 func runServer() {
 
-	// start reservation manager
-	wg.Add(1)
-	go reservationManager()
-
-	// start maintenance manager if a maintenance period has been specified
-	if igor.Maintenance.HostMaintenanceDuration > 0 {
-		wg.Add(1)
-		go maintenanceManager()
-	} else {
+	// start the periodic reservation and maintenance work
+	startManagerTasks()
+	if igor.Maintenance.HostMaintenanceDuration <= 0 {
 		logger.Warn().Msg("maintenance manager is disabled")
 	}
 
 	// the notification manager will not run if there is no SMTP server configured
-	if len(igor.Email.SmtpServer) > 0 {
+	if len(igor.Email.SmtpServers) > 0 {
 		wg.Add(1)
 		go notificationManager()
 	} else {
@@ -62,14 +60,18 @@ func runServer() {
 		logger.Warn().Msg("LDAP sync manager is disabled")
 	}
 
-	cert, err := tls.LoadX509KeyPair(igor.Server.CertFile, igor.Server.KeyFile)
+	// the certificate is reloaded when its files change so it can be rotated without a restart
+	certs, err := newCertReloader(igor.Server.CertFile, igor.Server.KeyFile)
 	if err != nil {
 		exitPrintFatal(err.Error())
 	}
+	logger.Info().Msgf("loaded TLS certificate %s, valid until %s", igor.Server.CertFile, certs.notAfter().Format(time.RFC3339))
+	wg.Add(1)
+	go certReloadManager(certs)
 
 	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		MinVersion:   tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
 	}
 
 	apiRouter := newRouter()
@@ -95,6 +97,7 @@ func runServer() {
 			http.MethodDelete,
 		},
 		AllowedHeaders:     []string{"*"},
+		ExposedHeaders:     []string{common.ServerVersionHeader, common.MinClientVersionHeader},
 		AllowCredentials:   true, // must be enabled for cross-site requests to have login credentials
 		OptionsPassthrough: true, // depends on HandleOPTIONS setting of httprouter in routes.go
 		MaxAge:             30,
@@ -149,7 +152,7 @@ func runServer() {
 			logger.Info().Msg("node callback service closed")
 		}
 
-		close(shutdownChan) // shuts down reservationManager and notificationManager
+		close(shutdownChan) // shuts down the manager tasks and notificationManager
 	}()
 
 	wg.Add(1)
@@ -192,42 +195,15 @@ func runServer() {
 
 	wg.Wait()
 
+	// write out any host changes still waiting to go to the cluster config file
+	flushClusterFileWrite()
+
 	sqlDb, _ := igor.IGormDb.GetDB().DB()
 	_ = sqlDb.Close()
 	logger.Info().Msg("closed database session")
 	logger.Info().Msg("**** IGOR-SERVER SHUTDOWN COMPLETED ... GOOD-BYE. ****")
 }
 
-// reservationManager uses a timer to fire at the top of every wall clock minute. When this happens reservations
-// that have reached their expiration time are cleaned up, reservations that are scheduled to begin do so, and
-// periodic emails about reservations nearing their end are sent out.
-func reservationManager() {
-	defer wg.Done()
-	countdown := NewScheduleTimer(time.Minute)
-	for {
-		select {
-		case <-shutdownChan:
-			logger.Info().Msg("stopping reservation management background worker")
-			if !countdown.t.Stop() {
-				<-countdown.t.C
-			}
-			return
-		case checkTime := <-countdown.t.C:
-			logger.Debug().Msgf("doing reservation management - %v", checkTime.Format(time.RFC3339))
-			if err := manageReservations(&checkTime, closeoutReservations); err != nil {
-				logger.Error().Msgf("%v", err)
-			}
-			if err := manageReservations(&checkTime, installReservations); err != nil {
-				logger.Error().Msgf("%v", err)
-			}
-			if err := manageReservations(&checkTime, sendExpirationWarnings); err != nil {
-				logger.Error().Msgf("%v", err)
-			}
-			countdown.reset()
-		}
-	}
-}
-
 // notificationManager handles notification events that happen as a result of user or admin actions that require
 // sending emails to affected users.
 func notificationManager() {
@@ -264,33 +240,6 @@ func notificationManager() {
 	}
 }
 
-// maintenanceManager uses a timer to fire at the top of every wall clock minute. When this happens reservations
-// that have reached their expiration time are put into maintenance mode where a function is fired to look for
-// expired reservations placed into the maintenance table and perform maintenance actions on those reservations
-// manager also looks for reservations in maintenance mode where the timer has ended and will perform actions on
-// those reservations to take them out of maintenance mode.
-func maintenanceManager() {
-	defer wg.Done()
-	timer := time.Minute
-	countdown := NewScheduleTimer(timer)
-	for {
-		select {
-		case <-shutdownChan:
-			logger.Info().Msg("stopping maintenance management background worker")
-			if !countdown.t.Stop() {
-				<-countdown.t.C
-			}
-			return
-		case checkTime := <-countdown.t.C:
-			logger.Debug().Msgf("doing maintenance management - %v", checkTime.Format(time.RFC3339))
-			if err := doMaintenance(&checkTime, finishMaintenance); err != nil {
-				logger.Error().Msgf("%v", err)
-			}
-			countdown.reset()
-		}
-	}
-}
-
 // ldapSyncManager uses a configurable timer to fire every given interval. When this happens, the syncLdapUsers()
 // function is called. The function uses configured settings to get a list of members for a given group from
 // LDAP. It then compares the list of members to Igor's user list. Any group members who do not currently have
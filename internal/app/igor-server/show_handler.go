@@ -11,6 +11,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/hlog"
 	"gorm.io/gorm"
@@ -27,8 +28,15 @@ func showHandler(w http.ResponseWriter, r *http.Request) {
 	actionPrefix := "show"
 	rb := common.NewResponseBodyShow()
 
+	// an optional to-end time limits the reservations to those ending by then
+	var timeParams map[string]time.Time
+	if toEnd := r.URL.Query().Get("to-end"); toEnd != "" {
+		t, _ := time.Parse(common.DateTimeCompactFormat, toEnd)
+		timeParams = map[string]time.Time{"to-end": t}
+	}
+
 	user := getUserFromContext(r)
-	result, status, err := getShowData(user)
+	result, status, err := getShowData(user, timeParams)
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
 	} else {
@@ -39,7 +47,7 @@ func showHandler(w http.ResponseWriter, r *http.Request) {
 	makeJsonResponse(w, status, rb)
 }
 
-func getShowData(user *User) (showData common.ShowData, code int, err error) {
+func getShowData(user *User, timeParams map[string]time.Time) (showData common.ShowData, code int, err error) {
 
 	code = http.StatusInternalServerError // default status, overridden at end if no errors
 
@@ -49,11 +57,13 @@ func getShowData(user *User) (showData common.ShowData, code int, err error) {
 
 		showData = common.ShowData{}
 
-		reservations, rErr := dbReadReservations(nil, nil, tx)
+		// the show payload only needs a summary of each reservation, so skip loading its full associations
+		summaries, rErr := dbReadResSummaries(timeParams, user, tx)
 		if rErr != nil {
 			return rErr
 		} else {
-			showData.Reservations = filterReservationList(reservations, user)
+			showData.Reservations = filterResSummaries(summaries, user)
+			showData.Rollups = common.NewShowRollups(showData.Reservations)
 		}
 		hosts, hErr := dbReadHosts(nil, tx)
 		if hErr != nil {
@@ -76,7 +86,7 @@ func getShowData(user *User) (showData common.ShowData, code int, err error) {
 		if pErr != nil {
 			return pErr
 		} else {
-			showData.Profiles = filterProfileList(profiles)
+			showData.Profiles = filterProfileList(profiles, user)
 		}
 		distroParams := map[string]interface{}{}
 		if !userElevated(user.Name) {
@@ -87,7 +97,7 @@ func getShowData(user *User) (showData common.ShowData, code int, err error) {
 		if dErr != nil {
 			return dErr
 		} else {
-			showData.Distros = filterDistroList(distros)
+			showData.Distros = filterDistroList(distros, user)
 		}
 
 		groupNames := groupNamesOfGroups(user.Groups)
@@ -181,3 +191,34 @@ func getPublicShowData() (publicData string, code int, err error) {
 
 	return
 }
+
+func validateShowParams(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var validateErr error
+		clog := hlog.FromRequest(r)
+
+		if r.Method == http.MethodGet {
+		queryParamLoop:
+			for key, vals := range r.URL.Query() {
+				switch key {
+				case "to-end":
+					if validateErr = checkTimeParam(key, vals); validateErr != nil {
+						break queryParamLoop
+					}
+				default:
+					validateErr = NewUnknownParamError(key, vals)
+					break queryParamLoop
+				}
+			}
+		}
+
+		if validateErr != nil {
+			clog.Warn().Msgf("validateShowParams - %v", validateErr)
+			createValidationErrMessage(validateErr, w)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SimNetwork is the vlan.network value used in simulation mode
+const SimNetwork = "simulation"
+
+func init() {
+	if networkSetFuncs == nil {
+		networkSetFuncs = make(map[string]func([]Host, int) error)
+		networkClearFuncs = make(map[string]func([]Host) error)
+		networkVlanFuncs = make(map[string]func() (map[string]string, error))
+		networkProfileFuncs = make(map[string]func([]Host, string) error)
+	}
+	networkSetFuncs[SimNetwork] = simNetworkSet
+	networkClearFuncs[SimNetwork] = simNetworkClear
+	networkVlanFuncs[SimNetwork] = simNetworkVlan
+	networkProfileFuncs[SimNetwork] = simNetworkProfile
+}
+
+var (
+	// simHosts holds the simulated power state of each host by hostname
+	simHosts   map[string]*simHostPower
+	simHostsMU sync.Mutex
+
+	// simVlans holds the simulated VLAN of each host by name
+	simVlans   = map[string]int{}
+	simVlansMU sync.Mutex
+
+	// simNetProfiles holds the simulated network profile applied to each host by name, guarded by simVlansMU
+	simNetProfiles = map[string]string{}
+
+	// simClockOffset is how far the scheduler clock has been moved ahead of real time
+	simClockOffset time.Duration
+	simClockMU     sync.Mutex
+)
+
+// simHostPower is the power state of a simulated host. A host that has been powered on stays off until
+// onAt, when it is due to come on.
+type simHostPower struct {
+	on   bool
+	onAt time.Time
+}
+
+// SimPowerStatus implements IPowerStatus for simulation mode. It reports the power state the simulated
+// hosts have reached as power commands take effect.
+type SimPowerStatus struct{}
+
+// NewSimPowerStatus returns the simulation mode IPowerStatus. Hosts that are reserved start out on
+// and all others start out off.
+func NewSimPowerStatus(hosts []Host) IPowerStatus {
+	simHostsMU.Lock()
+	defer simHostsMU.Unlock()
+	simHosts = make(map[string]*simHostPower, len(hosts))
+	for _, h := range hosts {
+		simHosts[h.HostName] = &simHostPower{on: h.State == HostReserved}
+	}
+	return &SimPowerStatus{}
+}
+
+func (sp *SimPowerStatus) updateHosts(hosts []Host) {
+
+	now := time.Now()
+
+	simHostsMU.Lock()
+	defer simHostsMU.Unlock()
+	powerMapMU.Lock()
+	defer powerMapMU.Unlock()
+
+	for _, h := range hosts {
+		hp, ok := simHosts[h.HostName]
+		if !ok {
+			hp = &simHostPower{}
+			simHosts[h.HostName] = hp
+		}
+		if !hp.onAt.IsZero() && !now.Before(hp.onAt) {
+			hp.on = true
+			hp.onAt = time.Time{}
+		}
+		powered := hp.on
+		powerMap[h.HostName] = &powered
+	}
+}
+
+// simPowerHosts applies a power action to simulated hosts. Powering off takes effect at once. Hosts
+// that are powered on or cycled go off, if they aren't already, and come on once the configured
+// delay has passed.
+func simPowerHosts(action string, hostNames []string, now time.Time) {
+
+	onAt := now.Add(time.Duration(igor.Simulation.PowerOnDelay) * time.Second)
+
+	simHostsMU.Lock()
+	defer simHostsMU.Unlock()
+
+	for _, name := range hostNames {
+		hp, ok := simHosts[name]
+		if !ok {
+			hp = &simHostPower{}
+			simHosts[name] = hp
+		}
+		switch action {
+		case PowerOff:
+			hp.on = false
+			hp.onAt = time.Time{}
+		case PowerCycle:
+			hp.on = false
+			hp.onAt = onAt
+		case PowerOn:
+			if !hp.on && hp.onAt.IsZero() {
+				hp.onAt = onAt
+			}
+		}
+	}
+}
+
+func simNetworkSet(nodes []Host, vlan int) error {
+	simVlansMU.Lock()
+	defer simVlansMU.Unlock()
+	for _, h := range nodes {
+		simVlans[h.Name] = vlan
+	}
+	return nil
+}
+
+func simNetworkClear(nodes []Host) error {
+	simVlansMU.Lock()
+	defer simVlansMU.Unlock()
+	for _, h := range nodes {
+		delete(simVlans, h.Name)
+	}
+	return nil
+}
+
+func simNetworkProfile(nodes []Host, profile string) error {
+	simVlansMU.Lock()
+	defer simVlansMU.Unlock()
+	for _, h := range nodes {
+		if profile == "" {
+			delete(simNetProfiles, h.Name)
+		} else {
+			simNetProfiles[h.Name] = profile
+		}
+	}
+	return nil
+}
+
+func simNetworkVlan() (map[string]string, error) {
+	simVlansMU.Lock()
+	defer simVlansMU.Unlock()
+	result := make(map[string]string, len(simVlans))
+	for name, vlan := range simVlans {
+		result[name] = strconv.Itoa(vlan)
+	}
+	return result, nil
+}
+
+// schedulerTime returns the time the background managers act on for the given clock time. In
+// simulation mode this is moved ahead by however much the scheduler clock has been advanced.
+func schedulerTime(t time.Time) time.Time {
+	if !igor.Simulation.Enabled {
+		return t
+	}
+	simClockMU.Lock()
+	defer simClockMU.Unlock()
+	return t.Add(simClockOffset)
+}
+
+// advanceSchedulerClock moves the simulated scheduler clock ahead and returns the total offset from
+// real time.
+func advanceSchedulerClock(d time.Duration) time.Duration {
+	simClockMU.Lock()
+	defer simClockMU.Unlock()
+	simClockOffset += d
+	return simClockOffset
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func useSimulation(t *testing.T) {
+	origSim, origPowerMap := igor.Simulation, powerMap
+	t.Cleanup(func() {
+		igor.Simulation, powerMap = origSim, origPowerMap
+		simClockOffset = 0
+	})
+	igor.Simulation.Enabled = true
+	igor.Simulation.PowerOnDelay = DefaultSimPowerOnDelay
+	powerMap = map[string]*bool{}
+}
+
+func TestSimPowerHosts(t *testing.T) {
+
+	useSimulation(t)
+
+	hosts := []Host{
+		{Name: "kn1", HostName: "kn1.local", State: HostReserved},
+		{Name: "kn2", HostName: "kn2.local", State: HostAvailable},
+	}
+	ps := NewSimPowerStatus(hosts)
+	ps.updateHosts(hosts)
+	require.NotNil(t, powerMap["kn1.local"])
+	assert.True(t, *powerMap["kn1.local"])
+	assert.False(t, *powerMap["kn2.local"])
+
+	// a cycled host goes off right away and stays off until the delay passes
+	simPowerHosts(PowerCycle, []string{"kn1.local"}, time.Now())
+	simPowerHosts(PowerOn, []string{"kn2.local"}, time.Now())
+	ps.updateHosts(hosts)
+	assert.False(t, *powerMap["kn1.local"])
+	assert.False(t, *powerMap["kn2.local"])
+
+	// hosts powered on in the past have finished coming on
+	delay := time.Duration(DefaultSimPowerOnDelay) * time.Second
+	simPowerHosts(PowerCycle, []string{"kn1.local"}, time.Now().Add(-delay))
+	simPowerHosts(PowerOff, []string{"kn2.local"}, time.Now())
+	simPowerHosts(PowerOn, []string{"kn2.local"}, time.Now().Add(-delay))
+	ps.updateHosts(hosts)
+	assert.True(t, *powerMap["kn1.local"])
+	assert.True(t, *powerMap["kn2.local"])
+
+	simPowerHosts(PowerOff, []string{"kn1.local", "kn2.local"}, time.Now())
+	ps.updateHosts(hosts)
+	assert.False(t, *powerMap["kn1.local"])
+	assert.False(t, *powerMap["kn2.local"])
+}
+
+func TestSimNetwork(t *testing.T) {
+
+	hosts := []Host{{Name: "kn1"}, {Name: "kn2"}}
+	t.Cleanup(func() { _ = simNetworkClear(hosts) })
+
+	require.NoError(t, simNetworkSet(hosts, 101))
+	vlans, err := simNetworkVlan()
+	require.NoError(t, err)
+	assert.Equal(t, "101", vlans["kn1"])
+	assert.Equal(t, "101", vlans["kn2"])
+
+	require.NoError(t, simNetworkClear(hosts[:1]))
+	vlans, err = simNetworkVlan()
+	require.NoError(t, err)
+	assert.NotContains(t, vlans, "kn1")
+	assert.Equal(t, "101", vlans["kn2"])
+}
+
+func TestSchedulerTime(t *testing.T) {
+
+	useSimulation(t)
+	now := time.Now()
+
+	assert.Equal(t, now, schedulerTime(now))
+	assert.Equal(t, 2*time.Hour, advanceSchedulerClock(2*time.Hour))
+	assert.Equal(t, 3*time.Hour, advanceSchedulerClock(time.Hour))
+	assert.Equal(t, now.Add(3*time.Hour), schedulerTime(now))
+
+	// the offset is ignored outside of simulation mode
+	igor.Simulation.Enabled = false
+	assert.Equal(t, now, schedulerTime(now))
+}
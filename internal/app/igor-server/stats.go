@@ -90,12 +90,14 @@ func runStats(optionParams map[string][]string) (stats common.StatsData, status
 	stats.Start = start
 	stats.End = end
 	stats.Verbose = verbose
+	stats.Tasks = managerTaskData()
 
 	var data []common.ResHistory
+	var distros []Distro
 	// query test
 	if err = performDbTx(func(tx *gorm.DB) error {
 		result := tx.Table("history_records h").
-			Select("h.hash AS hash, h.status AS status, h.name AS name, h.owner AS owner, h.profile AS profile, h.distro AS distro, h.vlan AS vlan, h.start AS start, h.end AS end, h.orig_end AS orig_end, h.extend_count AS extend_count, h.hosts AS hosts, h.created_at AS created_at").
+			Select("h.hash AS hash, h.status AS status, h.name AS name, h.owner AS owner, h.created_by AS created_by, h.profile AS profile, h.distro AS distro, h.vlan AS vlan, h.start AS start, h.end AS end, h.orig_end AS orig_end, h.extend_count AS extend_count, h.hosts AS hosts, h.req_duration AS req_duration, h.req_node_count AS req_node_count, h.request AS request, h.end_power AS end_power, h.created_at AS created_at").
 			Order("h.created_at").
 			Where("h.created_at >= ? AND h.created_at <= ?", start, end).
 			Scan(&data)
@@ -103,7 +105,8 @@ func runStats(optionParams map[string][]string) (stats common.StatsData, status
 			return result.Error
 		}
 
-		return nil
+		distros, err = dbReadDistros(map[string]interface{}{}, tx)
+		return err
 	}); err == nil {
 		stats.Records = data
 		status = http.StatusOK
@@ -176,7 +179,33 @@ func runStats(optionParams map[string][]string) (stats common.StatsData, status
 		}
 		stats.ByUser = byUser
 		stats.Global = global
+		stats.ByMonth = resDemandByMonth(summaries, end)
+		stats.ImageStore = imageStoreUsage(distros)
 	}
 
 	return
 }
+
+// resDemandByMonth totals the node-hours asked for and granted at creation by the month each reservation
+// started in. The difference is demand that time limits turned away.
+func resDemandByMonth(summaries map[string]common.ResHistory, end time.Time) map[string]common.ResDemandCount {
+
+	byMonth := map[string]common.ResDemandCount{}
+	for _, rec := range summaries {
+		// skip future reservations and those made before requests were recorded
+		if rec.Start.After(end) || rec.ReqNodeCount == 0 {
+			continue
+		}
+		granted := rec.OrigEnd.Sub(rec.Start)
+		month := rec.Start.Format("2006-01")
+		m := byMonth[month]
+		m.ResCount += 1
+		if granted < rec.ReqDuration {
+			m.ClampedCount += 1
+		}
+		m.RequestedNodeHours += rec.ReqDuration.Hours() * float64(rec.ReqNodeCount)
+		m.GrantedNodeHours += granted.Hours() * float64(rec.ReqNodeCount)
+		byMonth[month] = m
+	}
+	return byMonth
+}
@@ -18,7 +18,7 @@ import (
 
 func syncHandler(w http.ResponseWriter, r *http.Request) {
 	// runs a sync command on a given option
-	// options currently include: arista
+	// options currently include: arista, ldap-groups, ldap-users
 	clog := hlog.FromRequest(r)
 	actionPrefix := "sync"
 	rb := common.NewResponseBody()
@@ -59,6 +59,16 @@ func runSync(params map[string][]string) (result map[string]interface{}, status
 			return nil, http.StatusBadRequest, err
 		}
 		return syncArista(force, quiet)
+	case "ldap-groups":
+		if !igor.Auth.Ldap.Sync.EnableGroupSync {
+			return nil, http.StatusBadRequest, fmt.Errorf("LDAP group sync is not enabled, nothing to sync")
+		}
+		return syncLdapGroupsNow(force, quiet)
+	case "ldap-users":
+		if !igor.Auth.Ldap.Sync.EnableUserSync {
+			return nil, http.StatusBadRequest, fmt.Errorf("LDAP user sync is not enabled, nothing to sync")
+		}
+		return syncLdapUsersNow(force, quiet)
 	default:
 		status = http.StatusBadRequest
 		err = fmt.Errorf("sync command %v not recognized", cmd)
@@ -99,7 +109,7 @@ func syncArista(force, quiet bool) (result map[string]interface{}, status int, e
 		vlan := strconv.Itoa(r.Vlan)
 
 		for _, host := range r.Hosts {
-			withRes[host.Name] = map[string]interface{}{"res_vlan": vlan}
+			withRes[host.Name] = map[string]interface{}{"res_vlan": vlan, "res_profile": r.NetProfile}
 		}
 	}
 
@@ -110,8 +120,10 @@ func syncArista(force, quiet bool) (result map[string]interface{}, status int, e
 	for _, host := range hosts {
 		hostName := host.HostName
 		data := map[string]string{}
+		var netProfile string
 		if resInfo, ok := withRes[hostName]; ok {
 			data["res_vlan"] = resInfo["res_vlan"].(string)
+			netProfile = resInfo["res_profile"].(string)
 			if data["res_vlan"] == "0" || data["res_vlan"] == "" {
 				data["res_vlan"] = "(none)"
 			}
@@ -142,7 +154,7 @@ func syncArista(force, quiet bool) (result map[string]interface{}, status int, e
 			if err != nil {
 				return result, http.StatusInternalServerError, err
 			}
-			if err := networkSet([]Host{host}, vlan); err != nil {
+			if err := networkSet([]Host{host}, vlan, netProfile); err != nil {
 				logger.Error().Msgf("unable to set up network isolation for host %v", hostName)
 				data["status"] = "VLAN correction failed!"
 			} else {
@@ -162,6 +174,74 @@ func syncArista(force, quiet bool) (result map[string]interface{}, status int, e
 	return result, http.StatusOK, nil
 }
 
+// syncLdapGroupsNow runs the LDAP group sync outside its schedule. Unless force is set nothing is
+// changed and the report lists what the sync would do, with membership and ownership changes for
+// each group that is out of step with LDAP.
+func syncLdapGroupsNow(force, quiet bool) (result map[string]interface{}, status int, err error) {
+
+	if err = syncPreCheck(); err != nil {
+		return nil, http.StatusConflict, err
+	}
+
+	// the scheduled sync holds the db lock the whole time it runs, so do the same
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	conn, err := getLDAPConnection()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	groups, users, err := ldapGroupSyncInfo()
+	if err != nil {
+		conn.Close()
+		return nil, http.StatusInternalServerError, err
+	}
+
+	// errors for single groups are part of the report
+	report, _ := syncLdapGroups(conn, groups, users, force)
+
+	result = map[string]interface{}{
+		"command":     "ldap-groups",
+		"report":      report,
+		"force":       strconv.FormatBool(force),
+		"quiet":       strconv.FormatBool(quiet),
+		"ownerSync":   strconv.FormatBool(ldapOwnerSyncEnabled()),
+		"groupsCount": len(groups),
+	}
+	return result, http.StatusOK, nil
+}
+
+// syncLdapUsersNow runs the LDAP user sync outside its schedule. Unless force is set nothing is changed
+// and the report lists the accounts the sync would create and remove, and the users that would enter
+// or leave pending removal.
+func syncLdapUsersNow(force, quiet bool) (result map[string]interface{}, status int, err error) {
+
+	if err = syncPreCheck(); err != nil {
+		return nil, http.StatusConflict, err
+	}
+
+	// the scheduled sync holds the db lock the whole time it runs, so do the same
+	dbAccess.Lock()
+	defer dbAccess.Unlock()
+
+	conn, err := getLDAPConnection()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	report, err := syncLdapUsers(conn, force)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	result = map[string]interface{}{
+		"command": "ldap-users",
+		"report":  report,
+		"force":   strconv.FormatBool(force),
+		"quiet":   strconv.FormatBool(quiet),
+	}
+	return result, http.StatusOK, nil
+}
+
 func validateSyncParams(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"igor2/internal/pkg/api"
 )
@@ -20,18 +21,129 @@ func NewTFTPInstaller() IResInstaller {
 	return &TFTPInstaller{}
 }
 
+// bootFile is a PXE config file an install will write for a host, along with the master copy kept in the
+// igor folder of the PXE config tree.
+type bootFile struct {
+	host       *Host
+	content    string
+	masterPath string
+	pxePath    string
+}
+
+// Install writes the PXE config files of the reservation's hosts. Before anything is written the files are
+// copied into a new snapshot under the TFTP root, and afterward the PXE config folders are checked to make
+// sure nothing but those files changed. A host whose PXE config file belongs to a different reservation
+// that hasn't ended, such as another host with the same MAC address, is not installed.
 func (b *TFTPInstaller) Install(r *Reservation) error {
 	logger.Debug().Msgf("installing Reservation %v", r.Name)
-	for _, host := range r.Hosts {
-		if err := generateBootFile(&host, r); err != nil {
-			return err
+
+	pxeFilesMU.Lock()
+	defer pxeFilesMU.Unlock()
+
+	ledger, err := readPxeLedger()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	owner := resPxeOwner(r)
+	hostErrors := map[string]error{}
+	claimed := map[string]string{}
+	var files []bootFile
+	var touched []string
+
+	for i := range r.Hosts {
+		host := &r.Hosts[i]
+		content, masterPath, err := generateBootFile(host, r)
+		if err != nil {
+			logEvent(&logger, EvResInstallFailed).Str(LogFieldReservation, r.Name).Str(LogFieldHost, host.Name).Err(err).
+				Msgf("install of reservation '%s' failed on host %s - %v", r.Name, host.Name, err)
+			hostErrors[host.Name] = err
+			continue
+		}
+		pxePath := getPxePath(host)
+		rel := relTFTPPath(pxePath)
+		if other, ok := claimed[rel]; ok {
+			hostErrors[host.Name] = fmt.Errorf("PXE config file %s is also used by host %s -- check the MAC address of both hosts", rel, other)
+			logEvent(&logger, EvResInstallFailed).Str(LogFieldReservation, r.Name).Str(LogFieldHost, host.Name).Err(hostErrors[host.Name]).
+				Msgf("install of reservation '%s' failed on host %s - %v", r.Name, host.Name, hostErrors[host.Name])
+			continue
 		}
+		if e, ok := ledger[rel]; ok && e.Owner != owner && e.active(now) {
+			hostErrors[host.Name] = fmt.Errorf("PXE config file %s belongs to host %s of reservation '%s' and was not overwritten", rel, e.Host, e.Reservation)
+			logEvent(&logger, EvResInstallFailed).Str(LogFieldReservation, r.Name).Str(LogFieldHost, host.Name).Err(hostErrors[host.Name]).
+				Msgf("install of reservation '%s' failed on host %s - %v", r.Name, host.Name, hostErrors[host.Name])
+			continue
+		}
+		claimed[rel] = host.Name
+		files = append(files, bootFile{host: host, content: content, masterPath: masterPath, pxePath: pxePath})
+		touched = append(touched, rel, relTFTPPath(masterPath))
 	}
 
+	if len(files) > 0 {
+		// nothing gets written unless it can be put back
+		snapDir, sErr := takePxeSnapshot(r.Name, touched)
+		if sErr != nil {
+			return fmt.Errorf("unable to back up PXE config files before install: %v", sErr)
+		}
+		logger.Debug().Msgf("backed up PXE config files of reservation '%s' to %s", r.Name, snapDir)
+
+		before, mErr := pxeDirManifest()
+		if mErr != nil {
+			logger.Warn().Msgf("unable to list PXE config files before install of reservation '%s' - %v", r.Name, mErr)
+		}
+
+		intended := map[string]bool{}
+		for _, f := range files {
+			intended[relTFTPPath(f.pxePath)] = true
+			intended[relTFTPPath(f.masterPath)] = true
+
+			// Write master to backup
+			if wErr := writeFile(f.masterPath, f.content); wErr != nil {
+				hostErrors[f.host.Name] = wErr
+				continue
+			}
+			if wErr := writeFile(f.pxePath, f.content); wErr != nil {
+				hostErrors[f.host.Name] = wErr
+				continue
+			}
+			ledger[relTFTPPath(f.pxePath)] = pxeLedgerEntry{
+				Owner:       owner,
+				Reservation: r.Name,
+				Host:        f.host.Name,
+				Sum:         contentSum([]byte(f.content)),
+				Written:     now,
+				End:         r.End,
+			}
+		}
+
+		if mErr == nil {
+			if after, aErr := pxeDirManifest(); aErr != nil {
+				logger.Warn().Msgf("unable to list PXE config files after install of reservation '%s' - %v", r.Name, aErr)
+			} else if changed := unexpectedChanges(before, after, intended); len(changed) > 0 {
+				logger.Error().Msgf("PXE config files not part of the install of reservation '%s' changed while it ran: %s", r.Name, strings.Join(changed, ", "))
+			}
+		}
+
+		if lErr := writePxeLedger(ledger); lErr != nil {
+			logger.Error().Msgf("unable to save PXE ledger after install of reservation '%s' - %v", r.Name, lErr)
+		}
+
+		if pruned, pErr := prunePxeSnapshots(igor.Server.PxeBackupRetain); pErr != nil {
+			logger.Error().Msgf("problem pruning old PXE config backups: %v", pErr)
+		} else if len(pruned) > 0 {
+			logger.Debug().Msgf("removed old PXE config backups %v", pruned)
+		}
+	}
+
+	if len(hostErrors) > 0 {
+		return &HostInstallError{HostErrors: hostErrors}
+	}
 	return nil
 }
 
-func generateBootFile(host *Host, r *Reservation) error {
+// generateBootFile returns the content of the PXE config file for the host and the path of its master copy.
+func generateBootFile(host *Host, r *Reservation) (string, string, error) {
 	var content string
 	image := r.Profile.Distro.DistroImage
 	kernelPath := filepath.Join(igor.ImageStoreDir, image.ImageID, image.Kernel)
@@ -41,15 +153,8 @@ func generateBootFile(host *Host, r *Reservation) error {
 	osType := image.Breed
 
 	masterPath := filepath.Join(igor.TFTPPath, igor.PXEBIOSDir, "igor", host.Name)
-	pxePath := getPxePath(host)
 
-	kernel_args := ""
-	if r.Profile.Distro.KernelArgs != "" {
-		kernel_args = fmt.Sprintf("%s %s", kernel_args, r.Profile.Distro.KernelArgs)
-	}
-	if r.Profile.KernelArgs != "" {
-		kernel_args = fmt.Sprintf("%s %s", kernel_args, r.Profile.KernelArgs)
-	}
+	kernel_args := r.getKernelArgs()
 
 	// Construct the auto-install part of the boot file based on OS type
 	autoInstallFilePath := ""
@@ -78,7 +183,7 @@ func generateBootFile(host *Host, r *Reservation) error {
 			case "ubuntu", "debian", "freebsd", "generic", "nexenta", "suse", "unix", "vmware", "windows", "xen":
 				autoInstallPart = fmt.Sprintf(" lang=  netcfg/choose_interface=%s text  auto-install/enable=true priority=critical hostname=%s url=%s domain=local.lan", host.Mac, host.Name, autoInstallFilePath)
 			default:
-				return fmt.Errorf("unknown OS type: %s", osType)
+				return "", "", fmt.Errorf("unknown OS type: %s", osType)
 			}
 		}
 		content = fmt.Sprintf("%s\n%s\n%s\n%s\n%s %s\n", defaultLabel, defaultOptions, biosLabel, kernel, appendStmt, autoInstallPart)
@@ -93,29 +198,55 @@ func generateBootFile(host *Host, r *Reservation) error {
 			case "ubuntu", "debian", "freebsd", "generic", "nexenta", "suse", "unix", "vmware", "windows", "xen":
 				autoInstallPart = fmt.Sprintf(" lang=  netcfg/choose_interface=%s text  auto-install/enable=true priority=critical url=%s", host.Mac, autoInstallFilePath)
 			default:
-				return fmt.Errorf("unknown OS type: %s", osType)
+				return "", "", fmt.Errorf("unknown OS type: %s", osType)
 			}
 		}
 		content = fmt.Sprintf("set default=install-menu\nset timeout=6\n\nmenuentry %s --id install-menu {\n    linuxefi %s %s %s\n    initrdefi %s\n}\n", label, kernelPath, autoInstallPart, kernel_args, initrdPath)
 		masterPath = filepath.Join(igor.TFTPPath, igor.PXEUEFIDir, "igor", host.Name)
 	default:
-		return fmt.Errorf("unknown boot mode: %s", bootMode)
-	}
-
-	// Write master to backup
-	if err := writeFile(masterPath, content); err != nil {
-		return err
+		return "", "", fmt.Errorf("unknown boot mode: %s", bootMode)
 	}
 
-	// Write the content to the file
-	return writeFile(pxePath, content)
+	return content, masterPath, nil
 }
 
+// Uninstall removes the PXE config files of the reservation's hosts. A file that belongs to a different
+// reservation is left alone. A file that no longer holds what igor wrote has been changed by something
+// else, so it is put back the way it was before the install from the newest snapshot that has it.
 func (b *TFTPInstaller) Uninstall(r *Reservation) error {
 	logger.Debug().Msgf("uninstalling reservation %v", r.Name)
+
+	pxeFilesMU.Lock()
+	defer pxeFilesMU.Unlock()
+
+	ledger, err := readPxeLedger()
+	if err != nil {
+		logger.Error().Msgf("%v -- PXE config files of reservation '%s' will be removed without checking them", err, r.Name)
+		ledger = pxeLedger{}
+	}
+	owner := resPxeOwner(r)
+	now := time.Now()
+
 	// Delete all the PXE files in the reservation
 	for _, host := range r.Hosts {
 		pxePath := getPxePath(&host)
+		rel := relTFTPPath(pxePath)
+
+		if e, ok := ledger[rel]; ok {
+			if e.Owner != owner && e.active(now) {
+				logger.Warn().Msgf("pxeconfig file %s for host %v was not removed, it belongs to host %s of reservation '%s'", rel, host.Name, e.Host, e.Reservation)
+				continue
+			}
+			delete(ledger, rel)
+			if sum, exists, _ := fileSum(pxePath); exists && sum != e.Sum {
+				logger.Warn().Msgf("pxeconfig file %s for host %v was changed outside of igor after it was written for reservation '%s', restoring it from backup", rel, host.Name, e.Reservation)
+				if rErr := restorePxeFile(rel); rErr != nil {
+					logger.Error().Msgf("unable to restore %s - %v", rel, rErr)
+				} else {
+					continue
+				}
+			}
+		}
 
 		err := os.Remove(pxePath)
 		if err != nil {
@@ -123,6 +254,10 @@ func (b *TFTPInstaller) Uninstall(r *Reservation) error {
 			logger.Warn().Msgf("pxeconfig file for host %v encountered a problem during uninstall: %v", host.Name, err.Error())
 		}
 	}
+
+	if lErr := writePxeLedger(ledger); lErr != nil {
+		logger.Error().Msgf("unable to save PXE ledger after uninstall of reservation '%s' - %v", r.Name, lErr)
+	}
 	return nil
 }
 
@@ -152,6 +287,7 @@ func writeFile(path string, content string) error {
 	return nil
 }
 
+// setLocalConfig rewrites the host's PXE config file to boot from local disk once its install has finished.
 func setLocalConfig(host *Host, r *Reservation) error {
 	path := getPxePath(host)
 	content := ""
@@ -179,8 +315,30 @@ func setLocalConfig(host *Host, r *Reservation) error {
 		return fmt.Errorf("unknown boot mode: %s", host.BootMode)
 	}
 
-	if err := writeFile(path, content); err != nil {
+	pxeFilesMU.Lock()
+	defer pxeFilesMU.Unlock()
+
+	ledger, err := readPxeLedger()
+	if err != nil {
+		return err
+	}
+	rel := relTFTPPath(path)
+	owner := resPxeOwner(r)
+	if e, ok := ledger[rel]; ok && e.Owner != owner && e.active(time.Now()) {
+		return fmt.Errorf("PXE config file %s belongs to host %s of reservation '%s' and was not overwritten", rel, e.Host, e.Reservation)
+	}
+
+	if err = writeFile(path, content); err != nil {
 		return err
 	}
-	return nil
+
+	ledger[rel] = pxeLedgerEntry{
+		Owner:       owner,
+		Reservation: r.Name,
+		Host:        host.Name,
+		Sum:         contentSum([]byte(content)),
+		Written:     time.Now(),
+		End:         r.End,
+	}
+	return writePxeLedger(ledger)
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"igor2/internal/pkg/common"
 	"strings"
+	"time"
 )
 
 const (
@@ -23,15 +24,43 @@ type User struct {
 	Email    string `gorm:"unique"`
 	PassHash []byte
 	Groups   []Group `gorm:"many2many:groups_users;"`
+	// DefaultGroup is the name of the group used for the user's new reservations when none is given
+	DefaultGroup string
+	// Locale controls how dates and durations are written in email sent to the user
+	Locale string
+	// NotifyDelegate is the name of another user copied on email about the reservations this user owns,
+	// such as someone who watches them while the owner is away
+	NotifyDelegate string
+	// ResCcMail is which email the user is copied on about reservations of their groups that they don't
+	// own: all of it (blank), only final expiration warnings, or none
+	ResCcMail string
+	// NoGroupMail stops email about changes to the membership of the user's groups
+	NoGroupMail bool
+	// DefaultKernelArgs are added to the kernel args of every reservation the user makes, after those of
+	// the distro and profile, unless they are turned off for the reservation
+	DefaultKernelArgs string
+	// PendingRemoval is when the account will be removed after it was dropped from LDAP by the user sync
+	// while owning reservations; it is zero for an account not pending removal
+	PendingRemoval time.Time
+}
+
+// isPendingRemoval returns true if the account is waiting to be removed by the LDAP user sync.
+func (u *User) isPendingRemoval() bool {
+	return !u.PendingRemoval.IsZero()
 }
 
 func (u *User) getUserData(actionUser *User) *common.UserData {
 
-	var email string
+	var email, defaultGroup, locale, notifyDelegate, notifyPrefs, defaultKernelArgs string
 	var groups []string
 
 	if actionUser.ID == u.ID || userElevated(actionUser.Name) {
 		email = u.Email
+		defaultGroup = u.DefaultGroup
+		locale = u.Locale
+		notifyDelegate = u.NotifyDelegate
+		notifyPrefs = u.notifyPrefs()
+		defaultKernelArgs = u.DefaultKernelArgs
 		if len(u.Groups) > 0 {
 			groupNames := groupNamesOfGroups(u.Groups)
 			for _, gn := range groupNames {
@@ -43,11 +72,16 @@ func (u *User) getUserData(actionUser *User) *common.UserData {
 	}
 
 	var userData = &common.UserData{
-		Name:     u.Name,
-		FullName: u.FullName,
-		Email:    email,
-		Groups:   groups,
-		JoinDate: u.CreatedAt.Unix(),
+		Name:              u.Name,
+		FullName:          u.FullName,
+		Email:             email,
+		Groups:            groups,
+		JoinDate:          u.CreatedAt.Unix(),
+		DefaultGroup:      defaultGroup,
+		Locale:            locale,
+		NotifyDelegate:    notifyDelegate,
+		NotifyPrefs:       notifyPrefs,
+		DefaultKernelArgs: defaultKernelArgs,
 	}
 
 	return userData
@@ -111,6 +145,14 @@ func (u *User) getPugID() (int, error) {
 	return pug.ID, nil
 }
 
+// groupNames returns the names of the groups the user belongs to. A nil user belongs to none.
+func (u *User) groupNames() []string {
+	if u == nil {
+		return nil
+	}
+	return groupNamesOfGroups(u.Groups)
+}
+
 // isMemberOfGroup determines whether the user is a member of the given group.
 func (u *User) isMemberOfGroup(g *Group) bool {
 	if g.Name == GroupAll || g.Name == GroupUserPrefix+u.Name {
@@ -65,7 +65,7 @@ func dbReadUsers(queryParams map[string]interface{}, tx *gorm.DB) (userList []Us
 // dbEditUser updates a user with values included in the changes map within an
 // existing transaction.
 func dbEditUser(user *User, changes map[string]interface{}, tx *gorm.DB) error {
-	result := tx.Model(&user).Select("email", "pass_hash", "full_name").Updates(changes)
+	result := tx.Model(&user).Select("email", "pass_hash", "full_name", "default_group", "locale", "notify_delegate", "res_cc_mail", "no_group_mail", "default_kernel_args", "pending_removal").Updates(changes)
 	return result.Error
 }
 
@@ -77,6 +77,19 @@ func dbDeleteUser(user *User, tx *gorm.DB) error {
 		return err
 	}
 
+	// remove the user as a co-owner of any reservations (their permissions go with their PUG)
+	if result := tx.Exec("DELETE FROM reservations_coowners WHERE user_id = ?", user.ID); result.Error != nil {
+		return result.Error
+	}
+
+	// stop copying the user on other users' reservation email
+	if result := tx.Exec("DELETE FROM reservations_notify_also WHERE user_id = ?", user.ID); result.Error != nil {
+		return result.Error
+	}
+	if result := tx.Model(&User{}).Where("notify_delegate = ?", user.Name).Update("notify_delegate", ""); result.Error != nil {
+		return result.Error
+	}
+
 	result := tx.Delete(&user)
 	return result.Error
 }
@@ -6,12 +6,20 @@ package igorserver
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
 	"gorm.io/gorm"
-	"net/http"
+
+	"igor2/internal/pkg/common"
 )
 
-// doDeleteUser steps through the process of making an update to a user record.
+// doDeleteUser steps through the process of deleting a user. The reservations, distros and groups the
+// user alone owns must be moved first: to transferTo, which must be able to take each of them over the
+// same as through an owner change, or to igor-admin if force is set. Without either the delete is
+// refused with the list of what the user owns. A dry run returns the list without changing anything.
 //
 // Returns:
 //
@@ -20,19 +28,24 @@ import (
 //	404,err if user could not be found
 //	409,err if delete was not allowed due to its current state
 //	500,err if an internal error occurred
-func doDeleteUser(username string, r *http.Request) (status int, err error) {
+func doDeleteUser(username, transferTo string, force, dryRun bool, r *http.Request) (owned []common.OwnedResourceData, status int, err error) {
 
 	if err = denySysUserDelete(username); err != nil {
-		return http.StatusForbidden, err
+		return nil, http.StatusForbidden, err
 	}
 
 	if getUserFromContext(r).Name == username {
-		return http.StatusForbidden,
+		return nil, http.StatusForbidden,
 			fmt.Errorf("user not allowed to delete self - do this as %s or another admin", IgorAdmin)
 	}
 
-	// remove user from elevate map if they are in it
-	igor.ElevateMap.Remove(username)
+	if transferTo == username {
+		return nil, http.StatusBadRequest, fmt.Errorf("cannot transfer the resources of '%s' to the same user", username)
+	}
+	if force {
+		transferTo = IgorAdmin
+	}
+
 	clog := hlog.FromRequest(r)
 	status = http.StatusInternalServerError // default status, overridden at end if no errors
 
@@ -48,52 +61,50 @@ func doDeleteUser(username string, r *http.Request) (status int, err error) {
 
 		//  *** check for deletion conflicts with owned resources ***
 
-		ogList := user.singleOwnedGroups()
-		clog.Debug().Msgf("checking for '%s' owned groups", username)
-		if len(ogList) > 0 {
-			var ogNames []string
-			for _, og := range ogList {
-				ogNames = append(ogNames, og.Name)
+		clog.Debug().Msgf("checking for '%s' owned groups, reservations and distros", username)
+		resources, orErr := findOwnedResources(user, tx)
+		if orErr != nil {
+			return orErr // uses default err status
+		}
+		owned = resources.list(transferTo)
+
+		var newOwner *User
+		if transferTo != "" {
+			newOwners, nuStatus, nuErr := getUsers([]string{transferTo}, true, tx)
+			if nuErr != nil {
+				status = nuStatus
+				return nuErr
 			}
-			status = http.StatusConflict
-			return fmt.Errorf("cannot delete user - remove or transfer ownership of groups in list first: %v", ogNames)
+			newOwner = &newOwners[0]
 		}
 
-		searchByOwnerID := map[string]interface{}{"owner_id": user.ID}
+		if dryRun {
+			return nil
+		}
 
-		clog.Debug().Msgf("checking for '%s' current and future reservations", username)
-		if orList, orErr := dbReadReservations(searchByOwnerID, nil, tx); orErr != nil {
-			return orErr // uses default err status
-		} else {
-			if len(orList) > 0 {
-				var orNames []string
-				for _, or := range orList {
-					orNames = append(orNames, or.Name)
-				}
+		if len(owned) > 0 {
+			if newOwner == nil {
 				status = http.StatusConflict
-				return fmt.Errorf("cannot delete user - remove or transfer ownership of reservations in list first: %v", orNames)
+				return newCodedError(common.ErrOwnsResources, "cannot delete user '%s' - they own %s :: "+
+					"transfer them to another user or to %s first", username, ownedSummary(owned), IgorAdmin)
 			}
-		}
 
-		clog.Debug().Msgf("checking for '%s' owned distros", username)
-		if odList, rdErr := dbReadDistros(searchByOwnerID, tx); rdErr != nil {
-			return rdErr // uses default err status
-		} else {
-			if len(odList) > 0 {
-				var odNames []string
-				for _, od := range odList {
-					odNames = append(odNames, od.Name)
-				}
-				status = http.StatusConflict
-				return fmt.Errorf("cannot delete user - remove or transfer ownership of distros in list first: %v", odNames)
+			moved, toStatus, toErr := transferOwnedResources(user, newOwner, resources, true, tx, clog)
+			if toErr != nil {
+				status = toStatus
+				return toErr
 			}
+			owned = moved
 		}
 
+		// remove user from elevate map if they are in it
+		igor.ElevateMap.Remove(username)
+
 		// *** All good! let's start deleting stuff ***
 
 		// remove owned profiles
 		clog.Debug().Msgf("finding '%s' owned profiles", username)
-		if opList, opErr := dbReadProfiles(searchByOwnerID, tx); opErr != nil {
+		if opList, opErr := dbReadProfiles(map[string]interface{}{"owner_id": user.ID}, tx); opErr != nil {
 			return opErr // uses default err status
 		} else {
 			for _, p := range opList {
@@ -127,8 +138,11 @@ func doDeleteUser(username string, r *http.Request) (status int, err error) {
 		return dbDeleteUser(user, tx)
 
 	}); err == nil {
-		clog.Debug().Msgf("user '%s' deletion complete", username)
 		status = http.StatusOK
+		if !dryRun {
+			recordOwnerTransfers(username, owned, clog)
+			clog.Debug().Msgf("user '%s' deletion complete", username)
+		}
 	}
 	return
 }
@@ -139,3 +153,158 @@ func denySysUserDelete(u string) error {
 	}
 	return nil
 }
+
+// ownedResources holds the groups, reservations and distros a user alone owns.
+type ownedResources struct {
+	groups       []Group
+	reservations []Reservation
+	distros      []Distro
+}
+
+// findOwnedResources gathers the resources that must change hands before the user can be deleted.
+// The user must have been fetched with their groups and group owners.
+func findOwnedResources(user *User, tx *gorm.DB) (*ownedResources, error) {
+
+	owned := &ownedResources{groups: user.singleOwnedGroups()}
+	searchByOwnerID := map[string]interface{}{"owner_id": user.ID}
+
+	var err error
+	if owned.reservations, err = dbReadReservations(searchByOwnerID, nil, tx); err != nil {
+		return nil, err
+	}
+	if owned.distros, err = dbReadDistros(searchByOwnerID, tx); err != nil {
+		return nil, err
+	}
+	return owned, nil
+}
+
+// list describes each owned resource and, if given, the user it will be moved to.
+func (o *ownedResources) list(to string) []common.OwnedResourceData {
+
+	var owned []common.OwnedResourceData
+	for _, g := range o.groups {
+		owned = append(owned, common.OwnedResourceData{Kind: "group", Name: g.Name, Owner: g.Owners[0].Name, To: to})
+	}
+	for _, r := range o.reservations {
+		owned = append(owned, common.OwnedResourceData{Kind: "reservation", Name: r.Name, Owner: r.Owner.Name, To: to})
+	}
+	for _, d := range o.distros {
+		owned = append(owned, common.OwnedResourceData{Kind: "distro", Name: d.Name, Owner: d.Owner.Name, To: to})
+	}
+	return owned
+}
+
+// ownedSummary lists owned resources by kind for an error message, e.g. "reservation(s) [r1 r2]".
+func ownedSummary(owned []common.OwnedResourceData) string {
+
+	byKind := map[string][]string{}
+	for _, o := range owned {
+		byKind[o.Kind] = append(byKind[o.Kind], o.Name)
+	}
+
+	var parts []string
+	for _, kind := range []string{"group", "reservation", "distro"} {
+		if names, ok := byKind[kind]; ok {
+			parts = append(parts, fmt.Sprintf("%s(s) %v", kind, names))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// transferOwnedResources makes newOwner the owner of the resources the user alone owns, applying the
+// same checks as an owner change made through the group, reservation or distro commands. Unless the
+// new owner is igor-admin they must have access to the distro of each reservation and belong to the
+// groups each reservation and distro is shared with. When strict, the first resource that cannot be
+// moved fails the transfer; otherwise it is logged and left behind. Returns what was moved.
+func transferOwnedResources(user, newOwner *User, owned *ownedResources, strict bool, tx *gorm.DB, clog *zerolog.Logger) ([]common.OwnedResourceData, int, error) {
+
+	var moved []common.OwnedResourceData
+
+	// skip logs a resource that can't be moved when not strict
+	skip := func(kind, name string, err error) {
+		clog.Error().Msgf("problem changing %s '%s' from owner '%s' to '%s': %v", kind, name, user.Name, newOwner.Name, err)
+	}
+
+	for _, g := range owned.groups {
+		changes := map[string]interface{}{"rmvOwners": []User{*user}, "addOwners": []User{*newOwner}}
+		if !newOwner.isMemberOfGroup(&g) {
+			changes["add"] = []User{*newOwner}
+		}
+		clog.Info().Msgf("changing owner of group '%s' from '%s' to '%s'", g.Name, user.Name, newOwner.Name)
+		if err := dbEditGroup(&g, changes, tx); err != nil {
+			if strict {
+				return nil, http.StatusInternalServerError, err
+			}
+			skip("group", g.Name, err)
+			continue
+		}
+		moved = append(moved, common.OwnedResourceData{Kind: "group", Name: g.Name, Owner: user.Name, To: newOwner.Name})
+	}
+
+	for _, r := range owned.reservations {
+		changes, status, err := parseResEditParams(&r, map[string]interface{}{"owner": newOwner.Name}, tx)
+		if err == nil {
+			clog.Info().Msgf("changing owner of reservation '%s' from '%s' to '%s'", r.Name, user.Name, newOwner.Name)
+			status = http.StatusInternalServerError
+			err = dbEditReservation(&r, changes, tx)
+		}
+		if err != nil {
+			if strict {
+				return nil, status, fmt.Errorf("cannot transfer reservation '%s' to '%s': %w", r.Name, newOwner.Name, err)
+			}
+			skip("reservation", r.Name, err)
+			continue
+		}
+		moved = append(moved, common.OwnedResourceData{Kind: "reservation", Name: r.Name, Owner: user.Name, To: newOwner.Name})
+	}
+
+	for _, d := range owned.distros {
+		if member, badGroup := newOwner.isMemberOfGroups(d.Groups); !member && newOwner.Name != IgorAdmin {
+			err := newCodedError(common.ErrNotGroupMember, "cannot transfer distro '%s' to '%s': not a member of group %s",
+				d.Name, newOwner.Name, badGroup)
+			if strict {
+				return nil, http.StatusConflict, err
+			}
+			skip("distro", d.Name, err)
+			continue
+		}
+		clog.Info().Msgf("changing owner of distro '%s' from '%s' to '%s'", d.Name, user.Name, newOwner.Name)
+		if err := dbEditDistro(&d, map[string]interface{}{"owner": newOwner}, tx); err != nil {
+			if strict {
+				return nil, http.StatusInternalServerError, err
+			}
+			skip("distro", d.Name, err)
+			continue
+		}
+		moved = append(moved, common.OwnedResourceData{Kind: "distro", Name: d.Name, Owner: user.Name, To: newOwner.Name})
+	}
+
+	return moved, http.StatusOK, nil
+}
+
+// recordOwnerTransfers writes the resources moved off a deleted user to the log, and to the history
+// of each moved reservation. Call once the delete has been committed.
+func recordOwnerTransfers(username string, moved []common.OwnedResourceData, clog *zerolog.Logger) {
+
+	var resNames []string
+	for _, m := range moved {
+		clog.Info().Msgf("deleted user '%s': %s '%s' now owned by '%s'", username, m.Kind, m.Name, m.To)
+		if m.Kind == "reservation" {
+			resNames = append(resNames, m.Name)
+		}
+	}
+	if len(resNames) == 0 {
+		return
+	}
+
+	resList, err := dbReadReservationsTx(map[string]interface{}{"name": resNames}, nil)
+	if err != nil {
+		clog.Error().Msgf("failed to read reservations moved off deleted user '%s' - %v", username, err)
+		return
+	}
+	for _, res := range resList {
+		if hErr := res.HistCallback(&res, HrUpdated+":owner,deleted-user="+username); hErr != nil {
+			clog.Error().Msgf("failed to record reservation '%s' owner change to history", res.Name)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"igor2/internal/pkg/common"
+)
+
+// deleteUserAs deletes the user as igor-admin.
+func deleteUserAs(name, transferTo string, force, dryRun bool) ([]common.OwnedResourceData, int, error) {
+	r := httptest.NewRequest(http.MethodDelete, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, &User{Name: IgorAdmin}))
+	return doDeleteUser(name, transferTo, force, dryRun, r)
+}
+
+func TestDeleteUserOwnedResources(t *testing.T) {
+
+	db, res, profiles := newForceDeleteTestDb(t)
+	require.NoError(t, db.Model(&Reservation{}).Where("id = ?", res.ID).Update("profile_id", profiles[1].ID).Error)
+	require.NoError(t, db.Model(&res.Group).Update("is_user_private", true).Error)
+	resPerms, err := createResOwnerPerms(res.Name, false)
+	require.NoError(t, err)
+	groupPerms, err := createResGroupPerms(res)
+	require.NoError(t, err)
+	distroPerms, err := createDistroOwnerPerms("leftover")
+	require.NoError(t, err)
+	require.NoError(t, dbAppendPermissions(&res.Group, append(append(resPerms, groupPerms...), distroPerms...), db))
+
+	var all Group
+	require.NoError(t, db.Where("name = ?", GroupAll).First(&all).Error)
+	adminPug := Group{Name: GroupUserPrefix + IgorAdmin}
+	require.NoError(t, db.Omit(clause.Associations).Create(&adminPug).Error)
+	admin := User{Name: IgorAdmin, Email: "igor-admin@example.com", Groups: []Group{adminPug, all}}
+	require.NoError(t, db.Omit("Groups.*").Create(&admin).Error)
+	require.NoError(t, db.Exec("INSERT INTO groups_users (group_id, user_id) SELECT ?, id FROM users WHERE name = ?", all.ID, "bob").Error)
+
+	// alice alone owns a group that bob doesn't belong to, and alice's distro is shared with it
+	team := Group{Name: "team", Owners: []User{res.Owner}, Members: []User{res.Owner}}
+	require.NoError(t, db.Omit("Owners.*", "Members.*").Create(&team).Error)
+	require.NoError(t, db.Exec("INSERT INTO distros_groups (distro_id, group_id) SELECT id, ? FROM distros", team.ID).Error)
+
+	// without a new owner the delete is refused with the list of what alice owns
+	owned, status, err := deleteUserAs("alice", "", false, false)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, common.ErrOwnsResources, errorCodeOf(err))
+	assert.ElementsMatch(t, []common.OwnedResourceData{
+		{Kind: "group", Name: "team", Owner: "alice"},
+		{Kind: "reservation", Name: "cleanup", Owner: "alice"},
+		{Kind: "distro", Name: "leftover", Owner: "alice"},
+	}, owned)
+
+	// a dry run names the new owner without moving anything
+	owned, status, err = deleteUserAs("alice", "bob", false, true)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, owned, 3)
+	for _, o := range owned {
+		assert.Equal(t, "bob", o.To)
+	}
+
+	// bob can't take over a distro shared with a group bob isn't in, so nothing moves
+	_, status, err = deleteUserAs("alice", "bob", false, false)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, common.ErrNotGroupMember, errorCodeOf(err))
+	var count int64
+	require.NoError(t, db.Model(&User{}).Where("name = ?", "alice").Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+
+	// forcing gives it all to igor-admin and records the reservation's new owner
+	owned, status, err = deleteUserAs("alice", "", true, false)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, owned, 3)
+
+	resList, err := dbReadReservationsTx(map[string]interface{}{"name": "cleanup"}, nil)
+	require.NoError(t, err)
+	require.Len(t, resList, 1)
+	assert.Equal(t, IgorAdmin, resList[0].Owner.Name)
+
+	distros, err := dbReadDistrosTx(map[string]interface{}{"name": "leftover"})
+	require.NoError(t, err)
+	require.Len(t, distros, 1)
+	assert.Equal(t, IgorAdmin, distros[0].Owner.Name)
+
+	groups, _, err := getGroupsTx([]string{"team"}, true)
+	require.NoError(t, err)
+	require.Len(t, groups[0].Owners, 1)
+	assert.Equal(t, IgorAdmin, groups[0].Owners[0].Name)
+
+	var hist []HistoryRecord
+	require.NoError(t, db.Where("hash = ?", "cleanup").Find(&hist).Error)
+	require.NotEmpty(t, hist)
+	assert.Contains(t, hist[len(hist)-1].Status, "deleted-user=alice")
+}
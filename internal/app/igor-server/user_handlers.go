@@ -117,14 +117,28 @@ func handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	name := ps.ByName("userName")
 	clog := hlog.FromRequest(r)
 	actionPrefix := "delete user"
-	rb := common.NewResponseBody()
+	rb := common.NewResponseBodyUserDelete()
+
+	queryParams := r.URL.Query()
+	transferTo := strings.TrimSpace(strings.ToLower(queryParams.Get("transferTo")))
+	force := queryParams.Get("force") == "true"
+	dryRun := queryParams.Get("dryRun") == "true"
 
-	status, err := doDeleteUser(name, r)
+	owned, status, err := doDeleteUser(name, transferTo, force, dryRun, r)
 
 	if err != nil {
 		stdErrorResp(rb, status, actionPrefix, err, clog)
+		if status == http.StatusConflict && owned != nil {
+			rb.Data["owned"] = owned
+		}
+	} else if dryRun {
+		rb.Data["owned"] = owned
 	} else {
 		msg := fmt.Sprintf("user '%s' deleted", name)
+		if len(owned) > 0 {
+			rb.Data["moved"] = owned
+			msg = fmt.Sprintf("%s - %d owned resource(s) transferred to '%s'", msg, len(owned), owned[0].To)
+		}
 		clog.Info().Msgf("%s success - %s", actionPrefix, msg)
 		rb.Message = msg
 	}
@@ -243,6 +257,52 @@ func validateUserParams(handler http.Handler) http.Handler {
 								validateErr = fmt.Errorf("invalid parameter '%s': must be boolean=true to have effect", key)
 								break patchParamLoop
 							}
+						case "defaultGroup":
+							if groupName, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if groupName == GroupAll {
+								validateErr = fmt.Errorf("the '%s' group cannot be used as a default group", GroupAll)
+								break patchParamLoop
+							} else if groupName != GroupNoneAlias {
+								if validateErr = checkGroupNameRules(groupName); validateErr != nil {
+									break patchParamLoop
+								}
+							}
+						case "notifyDelegate":
+							if delegate, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if delegate != GroupNoneAlias {
+								if validateErr = checkUsernameRules(delegate); validateErr != nil {
+									break patchParamLoop
+								}
+							}
+						case "notifyPrefs":
+							if prefs, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if _, validateErr = parseNotifyPrefs(prefs); validateErr != nil {
+								break patchParamLoop
+							}
+						case "defaultKernelArgs":
+							if kArgs, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if kArgs != GroupNoneAlias {
+								if validateErr = checkRequestKernelArgs(kArgs, r); validateErr != nil {
+									break patchParamLoop
+								}
+							}
+						case "locale":
+							if locale, ok := val.(string); !ok {
+								validateErr = NewBadParamTypeError(key, val, "string")
+								break patchParamLoop
+							} else if locale != GroupNoneAlias {
+								if validateErr = checkLocaleRules(locale); validateErr != nil {
+									break patchParamLoop
+								}
+							}
 						default:
 							validateErr = NewUnknownParamError(key, val)
 							break patchParamLoop
@@ -275,6 +335,33 @@ func validateUserParams(handler http.Handler) http.Handler {
 			}
 		}
 
+		// DELETE takes where to move the user's resources in the query
+		if r.Method == http.MethodDelete {
+			queryParams := r.URL.Query()
+			if queryParams.Has("transferTo") && queryParams.Has("force") {
+				validateErr = fmt.Errorf("transferTo and force cannot be used together")
+			} else {
+
+			deleteParamLoop:
+				for key, val := range queryParams {
+					switch key {
+					case "transferTo":
+						if validateErr = checkUsernameRules(strings.TrimSpace(strings.ToLower(val[0]))); validateErr != nil {
+							break deleteParamLoop
+						}
+					case "force", "dryRun":
+						if val[0] != "true" {
+							validateErr = fmt.Errorf("invalid parameter '%s': must be %s=true to have effect", key, key)
+							break deleteParamLoop
+						}
+					default:
+						validateErr = NewUnknownParamError(key, val)
+						break deleteParamLoop
+					}
+				}
+			}
+		}
+
 		if validateErr != nil {
 			clog.Warn().Msgf("validateUserParams - %v", validateErr)
 			createValidationErrMessage(validateErr, w)
@@ -6,6 +6,7 @@ package igorserver
 
 import (
 	"fmt"
+	"igor2/internal/pkg/common"
 	"net/http"
 	"strings"
 
@@ -124,7 +125,7 @@ func checkUniqueUserAttributes(username, email string) (ok bool, status int, err
 		}
 		if exists {
 			status = http.StatusConflict
-			return fmt.Errorf("user '%s' already exists", username)
+			return newCodedError(common.ErrNameTaken, "user '%s' already exists", username)
 		} else {
 			emailList, emErr := dbReadUsers(map[string]interface{}{"email": email}, tx)
 			if emErr != nil {
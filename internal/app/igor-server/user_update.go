@@ -9,6 +9,7 @@ import (
 	"github.com/rs/zerolog/hlog"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"igor2/internal/pkg/common"
 	"net/http"
 	"strings"
 )
@@ -107,6 +108,72 @@ func doUpdateUser(username string, editParams map[string]interface{}, r *http.Re
 			}
 		}
 
+		if defGroup, ok := editParams["defaultGroup"].(string); ok {
+			delete(editParams, "defaultGroup")
+			if defGroup == GroupNoneAlias {
+				editParams["DefaultGroup"] = ""
+			} else {
+				groups, ggStatus, ggErr := getGroups([]string{defGroup}, true, tx)
+				if ggErr != nil {
+					status = ggStatus
+					return ggErr
+				}
+				if groups[0].IsUserPrivate || !user.isMemberOfGroup(&groups[0]) {
+					status = http.StatusBadRequest
+					return newCodedError(common.ErrNotGroupMember, "'%s' is not a member of group '%s'", user.Name, defGroup)
+				}
+				editParams["DefaultGroup"] = groups[0].Name
+			}
+		}
+
+		// the delegate must be an igor user so reservation mail can't be relayed to any address
+		if delegate, ok := editParams["notifyDelegate"].(string); ok {
+			delete(editParams, "notifyDelegate")
+			if delegate == GroupNoneAlias {
+				editParams["NotifyDelegate"] = ""
+			} else if delegate == user.Name {
+				status = http.StatusBadRequest
+				return fmt.Errorf("'%s' cannot be their own notification delegate", user.Name)
+			} else {
+				delegates, duStatus, duErr := getUsers([]string{delegate}, true, tx)
+				if duErr != nil {
+					status = duStatus
+					return duErr
+				}
+				editParams["NotifyDelegate"] = delegates[0].Name
+			}
+		}
+
+		if prefs, ok := editParams["notifyPrefs"].(string); ok {
+			delete(editParams, "notifyPrefs")
+			prefChanges, npErr := parseNotifyPrefs(prefs)
+			if npErr != nil {
+				status = http.StatusBadRequest
+				return npErr
+			}
+			for k, v := range prefChanges {
+				editParams[k] = v
+			}
+		}
+
+		if kArgs, ok := editParams["defaultKernelArgs"].(string); ok {
+			delete(editParams, "defaultKernelArgs")
+			if kArgs == GroupNoneAlias {
+				editParams["DefaultKernelArgs"] = ""
+			} else {
+				editParams["DefaultKernelArgs"] = normalizeKernelArgs(kArgs)
+			}
+		}
+
+		if locale, ok := editParams["locale"].(string); ok {
+			delete(editParams, "locale")
+			if locale == GroupNoneAlias {
+				editParams["Locale"] = ""
+			} else {
+				editParams["Locale"] = normalizeLocale(locale)
+			}
+		}
+
 		clog.Debug().Msgf("applying changes to '%s'", user.Name)
 		return dbEditUser(user, editParams, tx)
 
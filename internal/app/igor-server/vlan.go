@@ -0,0 +1,205 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/hlog"
+
+	"igor2/internal/pkg/common"
+)
+
+// destination for route GET /vlans
+func handleReadVlans(w http.ResponseWriter, r *http.Request) {
+
+	clog := hlog.FromRequest(r)
+	actionPrefix := "read vlans"
+	rb := common.NewResponseBodyVlans()
+
+	vlans, networks, status, err := doReadVlans()
+	if err != nil {
+		stdErrorResp(rb, status, actionPrefix, err, clog)
+	} else {
+		rb.Data["vlans"] = vlans
+		rb.Data["networks"] = networks
+	}
+
+	makeJsonResponse(w, status, rb)
+}
+
+// hasVlanRange returns true if the group limits the VLANs its reservations can use.
+func (g *Group) hasVlanRange() bool {
+	return g.VlanMin > 0 && g.VlanMax > 0
+}
+
+// vlanRange returns the VLAN range of the group, ex. 100-149, or an empty string if it has none.
+func (g *Group) vlanRange() string {
+	if !g.hasVlanRange() {
+		return ""
+	}
+	return formatVlanRange(g.VlanMin, g.VlanMax)
+}
+
+func formatVlanRange(min, max int) string {
+	return fmt.Sprintf("%d-%d", min, max)
+}
+
+// parseVlanRange parses a VLAN range of the form MIN-MAX.
+func parseVlanRange(vlanRange string) (min, max int, err error) {
+	parts := strings.Split(strings.TrimSpace(vlanRange), "-")
+	if len(parts) == 2 {
+		min, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err == nil {
+			max, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		}
+	}
+	if len(parts) != 2 || err != nil || min <= 0 || max < min {
+		return 0, 0, fmt.Errorf("'%s' is not a valid VLAN range -- use the form MIN-MAX, ex. 100-149", vlanRange)
+	}
+	return min, max, nil
+}
+
+// vlanRangeOf returns the VLANs that reservations of the given group can use, which is the range in the
+// server config narrowed to the group's own range if it has one. ok is false if the two don't overlap.
+func vlanRangeOf(group *Group) (min, max int, ok bool) {
+	min, max = igor.Vlan.RangeMin, igor.Vlan.RangeMax
+	if group != nil && group.hasVlanRange() {
+		if group.VlanMin > min {
+			min = group.VlanMin
+		}
+		if group.VlanMax < max {
+			max = group.VlanMax
+		}
+	}
+	return min, max, min <= max
+}
+
+// checkGroupVlan returns an error naming the allowed range if the given VLAN can't be used by
+// reservations of the group.
+func checkGroupVlan(vlan int, group *Group) error {
+	if !group.hasVlanRange() {
+		return nil
+	}
+	if min, max, ok := vlanRangeOf(group); !ok || vlan < min || vlan > max {
+		return fmt.Errorf("VLAN %d is outside the range %s allowed for group '%s'", vlan, group.vlanRange(), group.Name)
+	}
+	return nil
+}
+
+// parseGroupVlanRange checks a requested change to the VLAN range of a group and adds it to changes.
+// Only an elevated admin can set the range, which must fall within the VLANs igor manages. A value of
+// 'none' clears the range. Reservations already using VLANs outside a new range are left as they are.
+func parseGroupVlanRange(group *Group, editParams map[string]interface{}, changes map[string]interface{}, actionUser *User) (int, error) {
+
+	vlanRange, ok := editParams["vlanRange"].(string)
+	if !ok {
+		return http.StatusOK, nil
+	}
+
+	if !userElevated(actionUser.Name) {
+		return http.StatusForbidden, fmt.Errorf("setting the VLAN range of a group requires admin elevated privilege")
+	}
+	if group.Name == GroupAll || group.IsUserPrivate {
+		return http.StatusBadRequest, fmt.Errorf("a VLAN range cannot be set on group '%s'", group.Name)
+	}
+
+	if vlanRange = strings.TrimSpace(vlanRange); vlanRange == GroupNoneAlias {
+		changes["vlanMin"], changes["vlanMax"] = 0, 0
+		return http.StatusOK, nil
+	}
+
+	if !igor.vlanEnabled() {
+		return http.StatusBadRequest, fmt.Errorf("VLAN segmentation is not enabled on this server")
+	}
+	min, max, err := parseVlanRange(vlanRange)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if min < igor.Vlan.RangeMin || max > igor.Vlan.RangeMax {
+		return http.StatusBadRequest, fmt.Errorf("VLAN range %s is not within the range %s managed by igor", vlanRange,
+			formatVlanRange(igor.Vlan.RangeMin, igor.Vlan.RangeMax))
+	}
+	changes["vlanMin"], changes["vlanMax"] = min, max
+	return http.StatusOK, nil
+}
+
+// doReadVlans lists the VLAN of each reservation with the range its group allows, flagging those outside
+// of it. VLANs assigned before a range was set or changed are reported but not changed. It also lists the
+// named networks, and reservations using the VLAN of one name it.
+func doReadVlans() ([]common.VlanData, []common.VlanData, int, error) {
+
+	resList, err := dbReadReservationsTx(map[string]interface{}{}, nil)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	networkList, err := dbReadNamedNetworksTx(map[string]interface{}{})
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+
+	networkOf := make(map[int]string, len(networkList))
+	networks := make([]common.VlanData, 0, len(networkList))
+	for _, n := range networkList {
+		networkOf[n.Vlan] = n.Name
+		nd := common.VlanData{
+			Vlan:    n.Vlan,
+			Owner:   n.Owner.Name,
+			Group:   n.Group.Name,
+			Network: n.Name,
+		}
+		if min, max, ok := vlanRangeOf(&n.Group); ok {
+			nd.AllowedRange = formatVlanRange(min, max)
+		}
+		networks = append(networks, nd)
+	}
+
+	vlans := make([]common.VlanData, 0, len(resList))
+	for _, res := range resList {
+		if res.Vlan == 0 {
+			continue
+		}
+		vd := vlanDataOf(&res)
+		vd.Network = networkOf[res.Vlan]
+		vlans = append(vlans, vd)
+	}
+
+	sort.Slice(vlans, func(i, j int) bool {
+		if vlans[i].Vlan != vlans[j].Vlan {
+			return vlans[i].Vlan < vlans[j].Vlan
+		}
+		return vlans[i].Reservation < vlans[j].Reservation
+	})
+
+	return vlans, networks, http.StatusOK, nil
+}
+
+func vlanDataOf(res *Reservation) common.VlanData {
+
+	vd := common.VlanData{
+		Vlan:        res.Vlan,
+		Reservation: res.Name,
+		Owner:       res.Owner.Name,
+		Group:       res.Group.Name,
+		NetProfile:  res.NetProfile,
+	}
+
+	min, max, ok := vlanRangeOf(&res.Group)
+	if ok {
+		vd.AllowedRange = formatVlanRange(min, max)
+	}
+
+	if res.Vlan < igor.Vlan.RangeMin || res.Vlan > igor.Vlan.RangeMax {
+		vd.Violation = fmt.Sprintf("outside the range %s managed by igor", formatVlanRange(igor.Vlan.RangeMin, igor.Vlan.RangeMax))
+	} else if err := checkGroupVlan(res.Vlan, &res.Group); err != nil {
+		vd.Violation = fmt.Sprintf("outside the range %s of group '%s'", res.Group.vlanRange(), res.Group.Name)
+	}
+
+	return vd
+}
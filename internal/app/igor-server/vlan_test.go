@@ -0,0 +1,155 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorserver
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+)
+
+func setVlanTestRange(t *testing.T, min, max int) {
+	origNetwork, origMin, origMax := igor.Vlan.Network, igor.Vlan.RangeMin, igor.Vlan.RangeMax
+	t.Cleanup(func() {
+		igor.Vlan.Network, igor.Vlan.RangeMin, igor.Vlan.RangeMax = origNetwork, origMin, origMax
+	})
+	igor.Vlan.Network = "arista"
+	igor.Vlan.RangeMin, igor.Vlan.RangeMax = min, max
+}
+
+func TestParseVlanRange(t *testing.T) {
+	min, max, err := parseVlanRange("100-149")
+	require.NoError(t, err)
+	assert.Equal(t, 100, min)
+	assert.Equal(t, 149, max)
+
+	for _, bad := range []string{"100", "149-100", "0-10", "a-b", "100-149-200", ""} {
+		_, _, err = parseVlanRange(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestVlanRangeOf(t *testing.T) {
+
+	setVlanTestRange(t, 100, 199)
+
+	min, max, ok := vlanRangeOf(&Group{Name: "pug-alice"})
+	assert.True(t, ok)
+	assert.Equal(t, []int{100, 199}, []int{min, max})
+
+	min, max, ok = vlanRangeOf(&Group{Name: "team-a", VlanMin: 150, VlanMax: 249})
+	assert.True(t, ok)
+	assert.Equal(t, []int{150, 199}, []int{min, max})
+
+	_, _, ok = vlanRangeOf(&Group{Name: "team-b", VlanMin: 300, VlanMax: 349})
+	assert.False(t, ok)
+
+	teamA := &Group{Name: "team-a", VlanMin: 100, VlanMax: 149}
+	assert.NoError(t, checkGroupVlan(120, teamA))
+	err := checkGroupVlan(150, teamA)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "100-149")
+	assert.NoError(t, checkGroupVlan(150, &Group{Name: "pug-alice"}))
+}
+
+func TestParseVLANGroupRange(t *testing.T) {
+
+	setVlanTestRange(t, 100, 199)
+	seedTestHosts(t, newTestDb(t))
+	db := igor.IGormDb.GetDB()
+
+	alice := User{Name: "alice"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&alice).Error)
+	now := time.Now()
+	for _, r := range []Reservation{
+		{Name: "inside", Hash: "h1", OwnerID: alice.ID, Vlan: 120, Start: now, End: now.Add(time.Hour)},
+		{Name: "outside", Hash: "h2", OwnerID: alice.ID, Vlan: 170, Start: now, End: now.Add(time.Hour)},
+	} {
+		require.NoError(t, db.Omit(clause.Associations).Create(&r).Error)
+	}
+
+	teamA := &Group{Name: "team-a", VlanMin: 100, VlanMax: 149}
+
+	vlan, _, err := parseVLAN("130", alice, teamA, db)
+	require.NoError(t, err)
+	assert.Equal(t, 130, vlan)
+
+	_, status, err := parseVLAN("160", alice, teamA, db)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, err.Error(), "100-149")
+
+	// a VLAN inherited from another reservation must be in range too
+	vlan, _, err = parseVLAN("inside", alice, teamA, db)
+	require.NoError(t, err)
+	assert.Equal(t, 120, vlan)
+
+	_, status, err = parseVLAN("outside", alice, teamA, db)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// groups without a range can use any VLAN igor manages
+	vlan, _, err = parseVLAN("outside", alice, &Group{Name: "pug-alice"}, db)
+	require.NoError(t, err)
+	assert.Equal(t, 170, vlan)
+
+	next, err := nextVLAN(teamA.VlanMin, teamA.VlanMax, db)
+	require.NoError(t, err)
+	assert.Equal(t, 100, next)
+	_, err = nextVLAN(120, 120, db)
+	assert.Error(t, err)
+}
+
+func TestParseGroupVlanRange(t *testing.T) {
+
+	setVlanTestRange(t, 100, 199)
+	useElevateMap(t)
+	admin := &User{Name: IgorAdmin}
+	group := &Group{Name: "team-a"}
+
+	changes := map[string]interface{}{}
+	_, err := parseGroupVlanRange(group, map[string]interface{}{"vlanRange": "100-149"}, changes, admin)
+	require.NoError(t, err)
+	assert.Equal(t, 100, changes["vlanMin"])
+	assert.Equal(t, 149, changes["vlanMax"])
+
+	status, err := parseGroupVlanRange(group, map[string]interface{}{"vlanRange": "100-149"}, map[string]interface{}{}, &User{Name: "alice"})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, err = parseGroupVlanRange(group, map[string]interface{}{"vlanRange": "150-249"}, map[string]interface{}{}, admin)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	_, err = parseGroupVlanRange(&Group{Name: "pug-alice", IsUserPrivate: true}, map[string]interface{}{"vlanRange": "100-149"}, map[string]interface{}{}, admin)
+	assert.Error(t, err)
+
+	changes = map[string]interface{}{}
+	_, err = parseGroupVlanRange(group, map[string]interface{}{"vlanRange": "none"}, changes, admin)
+	require.NoError(t, err)
+	assert.Equal(t, 0, changes["vlanMin"])
+}
+
+func TestVlanDataOf(t *testing.T) {
+
+	setVlanTestRange(t, 100, 199)
+	teamA := Group{Name: "team-a", VlanMin: 100, VlanMax: 149}
+
+	vd := vlanDataOf(&Reservation{Name: "r1", Vlan: 120, Owner: User{Name: "alice"}, Group: teamA})
+	assert.Equal(t, "team-a", vd.Group)
+	assert.Equal(t, "100-149", vd.AllowedRange)
+	assert.Empty(t, vd.Violation)
+
+	vd = vlanDataOf(&Reservation{Name: "r2", Vlan: 170, Owner: User{Name: "alice"}, Group: teamA})
+	assert.Contains(t, vd.Violation, "group 'team-a'")
+
+	vd = vlanDataOf(&Reservation{Name: "r3", Vlan: 250, Owner: User{Name: "bob"}, Group: Group{Name: "pug-bob"}})
+	assert.Equal(t, "100-199", vd.AllowedRange)
+	assert.Contains(t, vd.Violation, "managed by igor")
+}
@@ -6,6 +6,7 @@ package igorweb
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"os"
@@ -20,6 +21,8 @@ const (
 	IgorConfHome        = "/etc/igor/"
 	IgorConfFileDefault = "igor-web.yaml"
 	IgorConfPathDefault = IgorConfHome + IgorConfFileDefault
+
+	DefaultHealthInterval = 10
 )
 
 type Config struct {
@@ -31,6 +34,12 @@ type Config struct {
 		FileDir  string `yaml:"fileDir"`
 	} `yaml:"webserver"`
 
+	IgorServer struct {
+		Url            string `yaml:"url"`
+		CaCert         string `yaml:"caCert"`
+		HealthInterval int    `yaml:"healthInterval"`
+	} `yaml:"igorServer"`
+
 	Log struct {
 		Dir    string `yaml:"dir"`
 		File   string `yaml:"file"`
@@ -162,10 +171,43 @@ func initConfigCheck() {
 		exitPrintFatal(fmt.Sprintf("config error: web app folder '%s' doesn't exist -- aborting", igorweb.WebServer.FileDir))
 	}
 
+	if igorweb.IgorServer.Url == "" {
+		if apiUrl, err := webAppApiUrl(); err != nil {
+			logger.Warn().Msgf("igor-server url not specified and not found in web app config (%v); igor-server health won't be checked", err)
+		} else {
+			igorweb.IgorServer.Url = apiUrl
+			logger.Info().Msgf("igor-server url not specified; using the web app's : %s", igorweb.IgorServer.Url)
+		}
+	}
+
+	if igorweb.IgorServer.HealthInterval <= 0 {
+		igorweb.IgorServer.HealthInterval = DefaultHealthInterval
+		logger.Info().Msgf("igor-server health interval not specified; using default : %d", igorweb.IgorServer.HealthInterval)
+	}
+
 	logger.Warn().Msg("--- end: applying defaults and overrides")
 	logger.Info().Msg("--- end: config file settings")
 }
 
+// webAppApiUrl returns the igor-server API URL the web app is configured to use, read from the
+// config.json file in the web content folder.
+func webAppApiUrl() (string, error) {
+	data, err := os.ReadFile(filepath.Join(igorweb.WebServer.FileDir, "config.json"))
+	if err != nil {
+		return "", err
+	}
+	var appConfig struct {
+		ApiBaseUrl string `json:"IGOR_API_BASE_URL"`
+	}
+	if err = json.Unmarshal(data, &appConfig); err != nil {
+		return "", err
+	}
+	if appConfig.ApiBaseUrl == "" {
+		return "", fmt.Errorf("IGOR_API_BASE_URL is not set")
+	}
+	return appConfig.ApiBaseUrl, nil
+}
+
 // printConfigToLog iterates through the given interface recursively to find all settings in
 // all child data structures and send them to the log.
 func printConfigToLog(s interface{}, namePrefix string) {
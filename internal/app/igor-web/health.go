@@ -0,0 +1,224 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package igorweb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"igor2/internal/pkg/common"
+)
+
+// StatusPath is where igor-web reports whether igor-server is reachable. The web app polls it so
+// it can show a banner instead of waiting on API calls that won't be answered.
+const StatusPath = "/igorweb/status"
+
+// healthCheckTimeout bounds each request made to igor-server's health endpoint.
+const healthCheckTimeout = 5 * time.Second
+
+// WebStatus is the response sent from StatusPath. Maintenance is true while igor-server can't be
+// reached or can't reach its database, and Message is the banner to show the user.
+type WebStatus struct {
+	ServerUp         bool   `json:"serverUp"`
+	Maintenance      bool   `json:"maintenance"`
+	UnavailableSince int64  `json:"unavailableSince,omitempty"`
+	Reason           string `json:"reason,omitempty"`
+	Message          string `json:"message,omitempty"`
+	RetryAfter       int    `json:"retryAfter,omitempty"`
+	ServerVersion    string `json:"serverVersion,omitempty"`
+	Checked          int64  `json:"checked,omitempty"`
+}
+
+// serverHealth is the last known state of igor-server. An outage is logged when it starts and when
+// it ends rather than on every check.
+type serverHealth struct {
+	sync.RWMutex
+	checked   time.Time
+	up        bool
+	downSince time.Time
+	reason    string
+	version   string
+}
+
+var igorHealth = &serverHealth{up: true}
+
+// newHealthClient makes the client used to check igor-server, trusting the system roots and the
+// configured CA certificate.
+func newHealthClient(caCert string) (*http.Client, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if caCert != "" {
+		pem, readErr := os.ReadFile(caCert)
+		if readErr != nil {
+			return nil, readErr
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caCert)
+		}
+	}
+	return &http.Client{
+		Timeout: healthCheckTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}, nil
+}
+
+// checkIgorServer asks igor-server for its health. It returns an error describing why the server
+// can't be used if it couldn't be reached or reports its database is down.
+func checkIgorServer(client *http.Client, serverUrl string) (common.HealthData, error) {
+
+	var hd common.HealthData
+	resp, err := client.Get(strings.TrimSuffix(serverUrl, "/") + "/health")
+	if err != nil {
+		var netErr net.Error
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return hd, fmt.Errorf("connection refused")
+		} else if errors.As(err, &netErr) && netErr.Timeout() {
+			return hd, fmt.Errorf("connection timed out")
+		}
+		return hd, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data struct {
+			Health common.HealthData `json:"health"`
+		} `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return hd, fmt.Errorf("unexpected health response (%s): %v", resp.Status, err)
+	}
+	hd = body.Data.Health
+	if resp.StatusCode == http.StatusServiceUnavailable || !hd.DbOK {
+		return hd, fmt.Errorf("igor-server database unavailable")
+	} else if resp.StatusCode != http.StatusOK {
+		return hd, fmt.Errorf("unexpected health response: %s", resp.Status)
+	}
+	return hd, nil
+}
+
+// update records the result of a health check made at now and logs the start and end of an outage.
+func (sh *serverHealth) update(hd common.HealthData, err error, now time.Time) {
+	sh.Lock()
+	defer sh.Unlock()
+
+	sh.checked = now
+	if hd.Version != "" {
+		sh.version = hd.Version
+	}
+	if err != nil {
+		sh.reason = err.Error()
+		if sh.up {
+			sh.up = false
+			sh.downSince = now
+			logger.Warn().Msgf("igor-server unavailable: %s", sh.reason)
+		}
+		return
+	}
+	if !sh.up {
+		logger.Info().Msgf("igor-server available again after %s", now.Sub(sh.downSince).Round(time.Second))
+	}
+	sh.up = true
+	sh.reason = ""
+	sh.downSince = time.Time{}
+}
+
+// status returns the state of igor-server as sent to the web app.
+func (sh *serverHealth) status() WebStatus {
+	sh.RLock()
+	defer sh.RUnlock()
+
+	ws := WebStatus{ServerUp: sh.up, ServerVersion: sh.version}
+	if !sh.checked.IsZero() {
+		ws.Checked = sh.checked.Unix()
+	}
+	if !sh.up {
+		ws.Maintenance = true
+		ws.UnavailableSince = sh.downSince.Unix()
+		ws.Reason = sh.reason
+		ws.Message = "igor server unavailable since " + sh.downSince.Format("15:04")
+		ws.RetryAfter = igorweb.IgorServer.HealthInterval
+	}
+	return ws
+}
+
+// healthMonitor checks igor-server every HealthInterval seconds until done is closed.
+func healthMonitor(client *http.Client, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(igorweb.IgorServer.HealthInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			hd, err := checkIgorServer(client, igorweb.IgorServer.Url)
+			igorHealth.update(hd, err, time.Now())
+		}
+	}
+}
+
+// statusHandler reports the state of igor-server. It answers 503 with a Retry-After header while the
+// server is unavailable so the web app can tell an outage from an empty result.
+func statusHandler(w http.ResponseWriter, _ *http.Request) {
+	ws := igorHealth.status()
+	status := http.StatusOK
+	if ws.Maintenance {
+		status = http.StatusServiceUnavailable
+		w.Header().Set("Retry-After", strconv.Itoa(ws.RetryAfter))
+	}
+	w.Header().Set(common.ContentType, common.MAppJson)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ws)
+}
+
+// PrintStatus checks igor-server once using the igor-web configuration and prints the result, for
+// admins debugging from the shell.
+func PrintStatus(configFilepath *string) {
+
+	igorweb.IgorHome = os.Getenv("IGOR_HOME")
+	initConfig(*configFilepath)
+
+	serverUrl := igorweb.IgorServer.Url
+	if serverUrl == "" {
+		if igorweb.WebServer.FileDir == "" {
+			igorweb.WebServer.FileDir = "../../web/dist"
+		}
+		apiUrl, err := webAppApiUrl()
+		if err != nil {
+			exitPrintFatal(fmt.Sprintf("igor-server url not configured: %v", err))
+		}
+		serverUrl = apiUrl
+	}
+
+	client, err := newHealthClient(igorweb.IgorServer.CaCert)
+	if err != nil {
+		exitPrintFatal(fmt.Sprintf("igor-server caCert: %v", err))
+	}
+	hd, err := checkIgorServer(client, serverUrl)
+	fmt.Printf("igor-server: %s\n", serverUrl)
+	if hd.Version != "" {
+		fmt.Printf("    version: %s\n", hd.Version)
+	}
+	if err != nil {
+		fmt.Printf("     status: UNAVAILABLE - %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("     status: ok")
+}
@@ -45,6 +45,18 @@ func runServer() error {
 
 	fsHandler := http.FileServer(&spaFileSystem{http.Dir(igorweb.WebServer.FileDir)})
 	http.Handle("/", fsHandler)
+	http.HandleFunc(StatusPath, statusHandler)
+
+	healthDone := make(chan struct{})
+	if igorweb.IgorServer.Url != "" {
+		client, clientErr := newHealthClient(igorweb.IgorServer.CaCert)
+		if clientErr != nil {
+			exitPrintFatal(fmt.Sprintf("config error: igorServer caCert - %v", clientErr))
+		}
+		hd, hErr := checkIgorServer(client, igorweb.IgorServer.Url)
+		igorHealth.update(hd, hErr, time.Now())
+		go healthMonitor(client, healthDone)
+	}
 
 	cert, err := tls.LoadX509KeyPair(igorweb.WebServer.CertFile, igorweb.WebServer.KeyFile)
 	if err != nil {
@@ -63,6 +75,7 @@ func runServer() error {
 	// This method is called during server shutdown so we can do other things
 	webSrv.RegisterOnShutdown(func() {
 		logger.Info().Msg("gracefully shutting down igorweb server")
+		close(healthDone)
 	})
 
 	go func() {
@@ -11,24 +11,44 @@ const (
 	IgorApiVersion = ""
 	BaseUrl        = UrlRoot + IgorApiVersion
 
+	Admin             = BaseUrl + "/admin"
+	AdminBackup       = Admin + "/backup"
+	AdminDiagnostics  = Admin + "/diagnostics"
+	AdminFsck         = Admin + "/fsck"
+	AdminHooks        = Admin + "/hooks"
+	AdminPxeAudit     = Admin + "/pxe-audit"
+	AdminSessions     = Admin + "/sessions"
+	AdminSimClock     = Admin + "/sim-clock"
+	AdminTasks        = Admin + "/tasks"
 	AuthReset         = BaseUrl + "/authreset"
+	Availability      = BaseUrl + "/availability"
 	CbLocal           = BaseUrl + "/cb/svc/local"
 	CbInfo            = BaseUrl + "/cb/svc/info"
 	CbKS              = BaseUrl + "/cb/svc/ks"
 	CbScript          = BaseUrl + "/cb/svc/scripts"
+	CbData            = BaseUrl + "/cb/svc/data/:resName"
 	Clusters          = BaseUrl + "/clusters"
 	ClusterMotd       = Clusters + "/motd"
+	ClusterRenamePfx  = Clusters + "/rename-prefix"
+	ClusterRenumber   = Clusters + "/renumber"
 	Config            = BaseUrl + "/config"
 	Distros           = BaseUrl + "/distros"
 	DistrosName       = Distros + "/:distroName"
 	Elevate           = BaseUrl + "/elevate"
+	Errors            = BaseUrl + "/errors"
+	ExtendLink        = BaseUrl + "/extend-link"
+	ExtendLinkToken   = ExtendLink + "/:extendToken"
 	Groups            = BaseUrl + "/groups"
+	Health            = BaseUrl + "/health"
 	GroupsName        = Groups + "/:groupName"
 	Hosts             = BaseUrl + "/hosts"
 	HostsName         = Hosts + "/:hostName"
 	HostsCtrl         = BaseUrl + "/hosts-ctrl"
 	HostsBlock        = HostsCtrl + "/block"
+	HostsDrain        = HostsCtrl + "/drain"
 	HostsPower        = HostsCtrl + "/power"
+	HostsExplain      = HostsCtrl + "/explain"
+	HostsHistory      = HostsCtrl + "/history"
 	HostApplyPolicy   = HostsCtrl + "/policy"
 	HostPolicy        = BaseUrl + "/hostpolicy"
 	HostPolicyName    = HostPolicy + "/:hostpolicyName"
@@ -39,14 +59,30 @@ const (
 	KickstartsName    = Kickstarts + "/:kickstartName"
 	KickstartRegister = Kickstarts + "/register"
 	Login             = BaseUrl + "/login"
+	LoginOidc         = Login + "/oidc"
+	LoginOidcCallback = LoginOidc + "/callback"
+	LoginOidcDevice   = LoginOidc + "/device"
+	LoginOidcToken    = LoginOidcDevice + "/token"
 	Profiles          = BaseUrl + "/profiles"
 	ProfileName       = Profiles + "/:profileName"
 	Public            = BaseUrl + "/public"
+	PublicStatus      = Public + "/status"
 	PublicSettings    = Config + "/public"
 	Reservations      = BaseUrl + "/reservations"
 	ReservationsName  = Reservations + "/:resName"
+	ResAdvise         = Reservations + "/advise"
+	ResData           = ReservationsName + "/data"
+	ReservationsCtrl  = BaseUrl + "/reservations-ctrl"
+	ReservationsBulk  = ReservationsCtrl + "/bulk"
+	ResBulkExtend     = ReservationsBulk + "/extend"
+	ResBulkOwner      = ReservationsBulk + "/owner"
+	Share             = BaseUrl + "/share"
+	ShareToken        = Share + "/:shareToken"
 	Stats             = BaseUrl + "/stats"
 	Sync              = BaseUrl + "/sync"
 	Users             = BaseUrl + "/users"
 	UsersName         = Users + "/:userName"
+	Vlans             = BaseUrl + "/vlans"
+	VlanNetworks      = Vlans + "/networks"
+	VlanNetworksName  = VlanNetworks + "/:networkName"
 )
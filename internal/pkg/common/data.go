@@ -15,21 +15,52 @@ type ShowData struct {
 	Profiles     []ProfileData     `json:"profiles"`
 	Distros      []DistroData      `json:"distros"`
 	UserGroups   []string          `json:"groups"`
+	// Rollups total the reservations above by owner and by group
+	Rollups ShowRollups `json:"rollups"`
+}
+
+// ResRollup totals the reservations of one owner or group
+type ResRollup struct {
+	Name         string `json:"name"`
+	Reservations int    `json:"reservations"`
+	Nodes        int    `json:"nodes"`
+	// NearestEnd is the soonest end time (unix seconds) of the reservations
+	NearestEnd int64 `json:"nearestEnd"`
+}
+
+type ShowRollups struct {
+	// ByOwner and ByGroup are sorted by descending node count. Reservations without a group are
+	// totaled under an empty name.
+	ByOwner []ResRollup `json:"byOwner"`
+	ByGroup []ResRollup `json:"byGroup"`
+	Total   ResRollup   `json:"total"`
 }
 
 type ReservationData struct {
-	Name         string   `json:"name"`
-	Description  string   `json:"description"`
-	Owner        string   `json:"owner"`
-	Group        string   `json:"group"`
-	Profile      string   `json:"profile"`
-	Distro       string   `json:"distro"`
-	Vlan         int      `json:"vlan"`
-	Start        int64    `json:"start"`
-	End          int64    `json:"end"`
-	OrigEnd      int64    `json:"origEnd"`
-	ExtendCount  int      `json:"extendCount"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Owner       string   `json:"owner"`
+	CoOwners    []string `json:"coOwners"`
+	Group       string   `json:"group"`
+	Profile     string   `json:"profile"`
+	Distro      string   `json:"distro"`
+	// NotifyAlso are the users copied on email about the reservation besides its owner's delegate
+	NotifyAlso []string `json:"notifyAlso,omitempty"`
+	// DescriptionHTML is the description with its markdown rendered as sanitized HTML for web display
+	DescriptionHTML string `json:"descriptionHtml,omitempty"`
+	// DistroNotes are the usage notes of the distro, only sent to members of the reservation
+	DistroNotes string `json:"distroNotes,omitempty"`
+	// KernelLine is the kernel command line written to the boot config, also only sent to members
+	KernelLine  string `json:"kernelLine,omitempty"`
+	Vlan        int    `json:"vlan"`
+	Start       int64  `json:"start"`
+	End         int64  `json:"end"`
+	OrigEnd     int64  `json:"origEnd"`
+	ExtendCount int    `json:"extendCount"`
+	// Hosts are always in sequence order. HeadHost is the host scripts should treat as the head of the
+	// reservation: the one the owner named, otherwise the first host.
 	Hosts        []string `json:"hosts"`
+	HeadHost     string   `json:"headHost"`
 	HostRange    string   `json:"hostRange"`
 	HostsUp      string   `json:"hostsUp"`
 	HostsDown    string   `json:"hostsDown"`
@@ -37,6 +68,185 @@ type ReservationData struct {
 	Installed    bool     `json:"installed"`
 	InstallError string   `json:"installError"`
 	RemainHours  int      `json:"remainHours"`
+	// InstallErrorHosts lists the hosts that failed to install when InstallError is set
+	InstallErrorHosts []string `json:"installErrorHosts"`
+	// Paused is true if the reservation has released its hosts until it resumes at Start
+	Paused      bool   `json:"paused"`
+	ResumeError string `json:"resumeError"`
+	// StartError is set when the reservation could not start because too many of its hosts were unavailable
+	StartError string `json:"startError,omitempty"`
+	// PendingApproval is true while the reservation holds its hosts waiting for an admin to approve it.
+	// ApprovalExpires is when it is released if no admin acts, and ApprovalReason is the threshold it is over.
+	PendingApproval bool   `json:"pendingApproval,omitempty"`
+	ApprovalExpires int64  `json:"approvalExpires,omitempty"`
+	ApprovalReason  string `json:"approvalReason,omitempty"`
+	// EndPower is "leave-on" if the hosts are left powered on when the reservation ends, empty if they
+	// are powered off as usual
+	EndPower string `json:"endPower,omitempty"`
+	// CycleAt is when the hosts are due to be power cycled to boot a new profile, 0 if no cycle is scheduled
+	CycleAt int64 `json:"cycleAt,omitempty"`
+	// Consoles maps host names to their console links, only sent to members of an active reservation
+	Consoles map[string]string `json:"consoles,omitempty"`
+	// Shares lists the reservation's share links, only sent to the owner
+	Shares []ResShareLinkData `json:"shares,omitempty"`
+	// ReqDuration (in minutes) and ReqNodeCount are what was asked for when the reservation was created,
+	// only sent to elevated admins
+	ReqDuration  int64 `json:"reqDuration,omitempty"`
+	ReqNodeCount int   `json:"reqNodeCount,omitempty"`
+	// NetProfile is the network profile applied to the reservation's ports, only sent to elevated admins
+	NetProfile string `json:"netProfile,omitempty"`
+	// NeedsGroup is true for a reservation without a group made before the server began requiring one,
+	// only sent to elevated admins so it can be given one
+	NeedsGroup bool `json:"needsGroup,omitempty"`
+	// IsolatedPorts maps each host of the reservation to the roles of its interfaces put in the
+	// reservation's VLAN, only sent when the server does VLAN segmentation
+	IsolatedPorts map[string][]string `json:"isolatedPorts,omitempty"`
+	// State is one of the ResState values, worked out by the server from the fields above so clients
+	// don't have to
+	State string `json:"state"`
+}
+
+// States of a reservation sent as ReservationData.State
+const (
+	ResStateFuture          = "future"
+	ResStatePendingApproval = "pending-approval"
+	ResStatePaused          = "paused"
+	ResStateStartFailed     = "start-failed"
+	ResStateInstalling      = "installing"
+	ResStateInstallError    = "install-error"
+	ResStateActive          = "active"
+	ResStateExpiringSoon    = "expiring-soon"
+	ResStateExpired         = "expired-pending-cleanup"
+)
+
+// ResShareLinkData describes a share link of a reservation. The token is only included when the
+// link is first made.
+type ResShareLinkData struct {
+	ID      string `json:"id"`
+	Token   string `json:"token,omitempty"`
+	Created int64  `json:"created"`
+	Expires int64  `json:"expires"`
+}
+
+// ResDataData is the data attached to a reservation for its nodes to fetch when they boot. Data is
+// encoded as base64 in JSON.
+type ResDataData struct {
+	Name    string `json:"name"`
+	Size    int    `json:"size"`
+	Sha256  string `json:"sha256"`
+	Updated int64  `json:"updated"`
+	Data    []byte `json:"data"`
+}
+
+// ResDeleteData is a short summary of a reservation and what deleting it would do, used to confirm a delete
+// or show the result of one without doing it.
+type ResDeleteData struct {
+	Name      string `json:"name"`
+	Owner     string `json:"owner"`
+	Group     string `json:"group"`
+	HostCount int    `json:"hostCount"`
+	HostRange string `json:"hostRange"`
+	End       int64  `json:"end"`
+	// Active is true if the reservation has started, so deleting it uninstalls it and powers off its hosts
+	Active bool `json:"active"`
+	// MaintenanceMins is how long the hosts would spend in maintenance afterward, 0 if they won't
+	MaintenanceMins int `json:"maintenanceMins"`
+	// OwnerOrAdmin is true if the requesting user owns or co-owns the reservation or is an elevated admin
+	OwnerOrAdmin bool `json:"ownerOrAdmin"`
+}
+
+// PublicStatusData is the public summary of the cluster for wall displays. It never includes who
+// is using the nodes.
+type PublicStatusData struct {
+	Cluster    string `json:"cluster"`
+	Motd       string `json:"motd"`
+	MotdUrgent bool   `json:"motdUrgent"`
+	// MotdHTML is the MOTD with its markdown rendered as sanitized HTML for web display
+	MotdHTML string `json:"motdHtml,omitempty"`
+	// Generated is when the summary was built, so a display can show how old it is
+	Generated int64 `json:"generated"`
+	// NodeStates counts the nodes in each host state
+	NodeStates         map[string]int `json:"nodeStates"`
+	ActiveReservations int            `json:"activeReservations"`
+	FutureReservations int            `json:"futureReservations"`
+	// Nodes lists every node in sequence order
+	Nodes []PublicNodeData `json:"nodes"`
+}
+
+// PublicNodeData is the state of one node in the public status. Powered is empty if the power
+// state of the node isn't known.
+type PublicNodeData struct {
+	Name    string `json:"n"`
+	State   string `json:"s"`
+	Powered *bool  `json:"p,omitempty"`
+	// Unavail is the node's next unavailable window within the server's lookahead, if any
+	Unavail *UnavailWindow `json:"u,omitempty"`
+}
+
+// HealthData is igor-server's report of whether it can serve requests. It is cheap to build and
+// needs no login so monitors and igor-web can poll it.
+type HealthData struct {
+	// Version is the release tag igor-server was built from
+	Version string `json:"version"`
+	// DbOK is false if the database didn't answer a ping
+	DbOK bool `json:"dbOk"`
+	// Time is when the check was made
+	Time int64 `json:"time"`
+}
+
+// ResShareData is the status of a reservation shown to anyone holding one of its share links.
+// It leaves out anything that identifies the owner's account or how the hosts are booted.
+type ResShareData struct {
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Start        int64  `json:"start"`
+	End          int64  `json:"end"`
+	RemainHours  int    `json:"remainHours"`
+	HostRange    string `json:"hostRange"`
+	HostsUp      string `json:"hostsUp"`
+	HostsDown    string `json:"hostsDown"`
+	HostsPowerNA string `json:"hostsPowerNA"`
+	Installed    bool   `json:"installed"`
+	Paused       bool   `json:"paused"`
+	State        string `json:"state"`
+	// LinkExpires is when the share link used to get this status stops working
+	LinkExpires int64 `json:"linkExpires"`
+}
+
+// ResExtendLinkData is what an extend link from a reservation expiration warning does, or did, to
+// the reservation.
+type ResExtendLinkData struct {
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+	End   int64  `json:"end"`
+	// NewEnd is the end the reservation gets from the link, or 0 if it can't be extended now
+	NewEnd int64 `json:"newEnd"`
+	// Problem is why the reservation can't be extended now
+	Problem  string `json:"problem,omitempty"`
+	Extended bool   `json:"extended"`
+}
+
+// DistroRemovedData is what a distro delete removed. Profiles are only removed by a forced delete and
+// Image is blank if the distro's image is still used by other distros.
+type DistroRemovedData struct {
+	Distro   string           `json:"distro"`
+	Image    string           `json:"image,omitempty"`
+	Profiles []RemovedProfile `json:"profiles,omitempty"`
+}
+
+// OwnedResourceData names a reservation, group or distro owned by a user being deleted and, once a new
+// owner is chosen, who it goes to.
+type OwnedResourceData struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+	To    string `json:"to,omitempty"`
+}
+
+// RemovedProfile names a profile removed along with its distro.
+type RemovedProfile struct {
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
 }
 
 // DistroData contains the filtered contents of a Distro for user consumption
@@ -52,6 +262,29 @@ type DistroData struct {
 	KernelArgs  string   `json:"kernelArgs"`
 	Kickstart   string   `json:"kickstart"`
 	IsPublic    bool     `json:"isPublic"`
+	// ImageFiles and ImageMissing are only filled in when a distro read asks to verify image files
+	ImageFiles   []ImageFileData `json:"imageFiles,omitempty"`
+	ImageMissing bool            `json:"imageMissing,omitempty"`
+	// Usage is only filled in for admins and the distro owner
+	Usage *ImageUsageData `json:"usage,omitempty"`
+	// UsageNotes are markdown notes on how to get started with the distro
+	UsageNotes string `json:"usageNotes,omitempty"`
+}
+
+// ImageUsageData reports how often and how recently reservations used a distro or profile
+type ImageUsageData struct {
+	UseCount int   `json:"useCount"`
+	LastUsed int64 `json:"lastUsed"`
+}
+
+// ImageFileData reports the health of a file backing a distro image
+type ImageFileData struct {
+	Name           string `json:"name"`
+	Present        bool   `json:"present"`
+	Size           int64  `json:"size"`
+	ModTime        int64  `json:"modTime"`
+	RegisteredSize int64  `json:"registeredSize,omitempty"`
+	SizeMismatch   bool   `json:"sizeMismatch,omitempty"`
 }
 
 // DistroImageData contains the filtered contents of a DistroImage for user consumption
@@ -65,6 +298,13 @@ type DistroImageData struct {
 	Breed     string   `json:"breed"`
 	Local     string   `json:"local"`
 	Boot      []string `json:"boot"`
+	// file sizes in the image store, and of files given compressed, their sizes as given and expanded
+	KernelSize             int64 `json:"kernelSize,omitempty"`
+	InitrdSize             int64 `json:"initrdSize,omitempty"`
+	KernelCompressedSize   int64 `json:"kernelCompressedSize,omitempty"`
+	KernelDecompressedSize int64 `json:"kernelDecompressedSize,omitempty"`
+	InitrdCompressedSize   int64 `json:"initrdCompressedSize,omitempty"`
+	InitrdDecompressedSize int64 `json:"initrdDecompressedSize,omitempty"`
 }
 
 // KickstartData contains the filtered contents of a Kickstart for user consumption
@@ -81,6 +321,16 @@ type ProfileData struct {
 	Owner       string `json:"owner"`
 	Distro      string `json:"distro"`
 	KernelArgs  string `json:"kernelArgs"`
+	// Usage is only filled in for admins and the profile owner
+	Usage *ImageUsageData `json:"usage,omitempty"`
+}
+
+// HostPortData is a switch port of a host and the role of the host interface plugged into it.
+// Isolated is true if the port is put in the VLAN of the reservation holding the host.
+type HostPortData struct {
+	Role     string `json:"role"`
+	Port     string `json:"port"`
+	Isolated bool   `json:"isolated"`
 }
 
 type HostData struct {
@@ -92,12 +342,43 @@ type HostData struct {
 	Mac          string   `json:"mac"`
 	BootMode     string   `json:"bootMode"`
 	State        string   `json:"state"`
+	DrainReason  string   `json:"drainReason"`
+	InstallError string   `json:"installError"`
 	Powered      string   `json:"powered"`
 	Cluster      string   `json:"cluster"`
 	HostPolicy   string   `json:"hostPolicy"`
 	AccessGroups []string `json:"accessGroups"`
 	Restricted   bool     `json:"restricted"`
 	Reservations []string `json:"reservations"`
+	// Ports are all the switch ports of the host, its data port (Eth) first
+	Ports []HostPortData `json:"ports,omitempty"`
+	// PowerChecked is when the power status was last read by polling, 0 if never
+	PowerChecked int64 `json:"powerChecked,omitempty"`
+	// PowerPollInterval is the seconds between power polls if overridden on the host
+	PowerPollInterval int `json:"powerPollInterval,omitempty"`
+	// PowerPollLatency is how long the last power poll took (admin only)
+	PowerPollLatency string `json:"powerPollLatency,omitempty"`
+	// UsageHours is how long the host has been held by reservations that have ended (admin only)
+	UsageHours int64 `json:"usageHours,omitempty"`
+	// Console is the link to the host's serial console, only sent to admins and members of the
+	// reservation using the host
+	Console string `json:"console,omitempty"`
+	// BlockedBy, BlockedAt and BlockReason say who blocked the host, when and why. They are only set
+	// while the host is blocked.
+	BlockedBy   string `json:"blockedBy,omitempty"`
+	BlockedAt   int64  `json:"blockedAt,omitempty"`
+	BlockReason string `json:"blockReason,omitempty"`
+	// NextUnavail is the next time the host can't be reserved that starts within the server's lookahead,
+	// nil if there is none. A host restricted now can also have one.
+	NextUnavail *UnavailWindow `json:"nextUnavail,omitempty"`
+}
+
+// UnavailWindow is a coming period when a host can't be reserved. Reason is "policy" for an unavailable
+// time of the host's policy or "maintenance" for the maintenance after the reservation on it ends.
+type UnavailWindow struct {
+	Start  int64  `json:"start"`
+	End    int64  `json:"end"`
+	Reason string `json:"reason"`
 }
 
 type ClusterData struct {
@@ -107,6 +388,18 @@ type ClusterData struct {
 	DisplayWidth  int    `json:"displayWidth"`
 	Motd          string `json:"motd"`
 	MotdUrgent    bool   `json:"motdUrgent"`
+	// MotdHTML is the MOTD with its markdown rendered as sanitized HTML for web display
+	MotdHTML string `json:"motdHtml,omitempty"`
+}
+
+// ClusterChangeData describes one record changed, or that would be changed by a dry run, when the
+// cluster prefix is renamed or its hosts are renumbered.
+type ClusterChangeData struct {
+	Record string `json:"record"` // kind of record: cluster, host, permission, pxeLedger or configFile
+	Name   string `json:"name"`   // the record changed, such as a host or the group holding a permission
+	Field  string `json:"field"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
 }
 
 // UserData is a struct that only contains fields relevant to responses sent
@@ -117,6 +410,16 @@ type UserData struct {
 	Email    string   `json:"email"`
 	Groups   []string `json:"groups"`
 	JoinDate int64    `json:"joinDate"`
+	// DefaultGroup is the group used for new reservations when none is given
+	DefaultGroup string `json:"defaultGroup,omitempty"`
+	// Locale is the user's preference for how dates are written in email
+	Locale string `json:"locale,omitempty"`
+	// NotifyDelegate is the user copied on email about the reservations this user owns
+	NotifyDelegate string `json:"notifyDelegate,omitempty"`
+	// NotifyPrefs is which reservation and group email the user gets as a group member, ex. res-cc=final,group=no
+	NotifyPrefs string `json:"notifyPrefs,omitempty"`
+	// DefaultKernelArgs are added to the kernel args of each reservation the user makes
+	DefaultKernelArgs string `json:"defaultKernelArgs,omitempty"`
 }
 
 // GroupData is textual information about a group that is most relevant to users.
@@ -128,14 +431,344 @@ type GroupData struct {
 	Distros      []string `json:"distros"`
 	Policies     []string `json:"hostPolicies"`
 	Reservations []string `json:"reservations"`
+	// DefaultDistro and DefaultDuration are used for new reservations of group members that don't specify them
+	DefaultDistro   string `json:"defaultDistro,omitempty"`
+	DefaultDuration string `json:"defaultDuration,omitempty"`
+	// VlanRange is the range of VLANs, ex. 100-149, that reservations made with the group can use
+	VlanRange string `json:"vlanRange,omitempty"`
+	// Access lists what the group grants its members, only included for groups the caller owns
+	// or if the caller is an admin
+	Access *GroupAccessData `json:"access,omitempty"`
+}
+
+// GroupAccessData lists the reservations, distros and host policies that reference a group.
+type GroupAccessData struct {
+	Reservations GroupAccessList `json:"reservations"`
+	Distros      GroupAccessList `json:"distros"`
+	HostPolicies GroupAccessList `json:"hostPolicies"`
+}
+
+// GroupAccessList is a list of resource names that may be cut short. Count is always the full
+// number of resources.
+type GroupAccessList struct {
+	Names []string `json:"names"`
+	Count int      `json:"count"`
+}
+
+// More returns the number of resources left out of Names.
+func (l GroupAccessList) More() int {
+	return l.Count - len(l.Names)
 }
 
 type HostPolicyData struct {
-	Name         string          `json:"name"`
-	Hosts        string          `json:"hosts"`
-	MaxResTime   string          `json:"maxResTime"`
-	AccessGroups []string        `json:"accessGroups"`
-	NotAvailable []ScheduleBlock `json:"scheduleBlock"`
+	Name       string `json:"name"`
+	Hosts      string `json:"hosts"`
+	MaxResTime string `json:"maxResTime"`
+	// GroupLimits maps a group name to the max reservation time its members get instead of MaxResTime
+	GroupLimits  map[string]string `json:"groupLimits,omitempty"`
+	AccessGroups []string          `json:"accessGroups"`
+	NotAvailable []ScheduleBlock   `json:"scheduleBlock"`
+	// ExcludedGroups lists the groups whose members can't use the hosts even if they are in an access group
+	ExcludedGroups []string `json:"excludedGroups,omitempty"`
+	// RestrictedActions lists the node actions (power, reimage, console) only admins can perform on the hosts
+	RestrictedActions []string `json:"restrictedActions,omitempty"`
+	// MaxPerUser is the most of the hosts one user can hold at once, 0 means no cap
+	MaxPerUser int `json:"maxPerUser,omitempty"`
+}
+
+// NodeTimeLimitData reports the longest reservation a user can make on a set of hosts along with the
+// host policy limits it was worked out from.
+type NodeTimeLimitData struct {
+	Nodes             string                `json:"nodes"`
+	MaxReserveMinutes int64                 `json:"maxReserveMinutes"`
+	Policies          []PolicyTimeLimitData `json:"policies"`
+}
+
+// PolicyTimeLimitData is the time limit of a host policy that covers some of the hosts in a NodeTimeLimitData.
+type PolicyTimeLimitData struct {
+	Name       string `json:"name"`
+	Hosts      string `json:"hosts"`
+	MaxResTime string `json:"maxResTime"`
+	// LimitGroup names the group whose time limit on the policy applies to the user, if any
+	LimitGroup string `json:"limitGroup,omitempty"`
+	// MaxPerUser is the most of the policy's hosts one user can hold at once, 0 means no cap
+	MaxPerUser int `json:"maxPerUser,omitempty"`
+}
+
+// BackupData describes a database backup snapshot written by the server.
+type BackupData struct {
+	Path     string   `json:"path"`
+	Size     int64    `json:"size"`
+	Duration string   `json:"duration"`
+	Pruned   []string `json:"pruned"`
+}
+
+// HookStatusData describes one run of an event hook on the server.
+type HookStatusData struct {
+	Event       string `json:"event"`
+	Hook        string `json:"hook"`
+	Reservation string `json:"reservation,omitempty"`
+	RequestID   string `json:"requestId"`
+	Started     int64  `json:"started"`
+	Duration    string `json:"duration"`
+	ExitCode    int    `json:"exitCode"`
+	Error       string `json:"error,omitempty"`
+	Output      string `json:"output,omitempty"`
+}
+
+// AuthSessionData describes an unexpired login token issued by the server.
+type AuthSessionData struct {
+	ID       string `json:"id"`
+	User     string `json:"user"`
+	Issued   int64  `json:"issued"`
+	Expires  int64  `json:"expires"`
+	LastUsed int64  `json:"lastUsed"`
+	Source   string `json:"source"`
+	Address  string `json:"address"`
+}
+
+// PxeAuditData describes a PXE config file that doesn't match what the installed reservations expect.
+type PxeAuditData struct {
+	Path        string `json:"path"`
+	Host        string `json:"host,omitempty"`
+	Reservation string `json:"reservation,omitempty"`
+	Problem     string `json:"problem"`
+	Detail      string `json:"detail"`
+}
+
+// FsckData describes an inconsistency between reservations, permissions, hosts and profiles found by
+// an integrity check. ID is the row ID of the permission, host or profile the problem is about, or
+// of the missing owner or group.
+type FsckData struct {
+	Problem     string `json:"problem"`
+	Reservation string `json:"reservation,omitempty"`
+	Host        string `json:"host,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Fact        string `json:"fact,omitempty"`
+	ID          int    `json:"id,omitempty"`
+	Detail      string `json:"detail"`
+	Repaired    bool   `json:"repaired,omitempty"`
+}
+
+// DiagnosticsData is the support bundle an admin gathers to report a server problem. Credentials and
+// email addresses are masked in the config settings and log lines.
+type DiagnosticsData struct {
+	Generated int64                   `json:"generated"`
+	Version   string                  `json:"version"`
+	Config    []string                `json:"config"`
+	Database  DiagnosticsDatabaseData `json:"database"`
+	Cluster   DiagnosticsClusterData  `json:"cluster"`
+	Log       []string                `json:"log"`
+	Tasks     []ManagerTaskData       `json:"tasks"`
+	Runtime   DiagnosticsRuntimeData  `json:"runtime"`
+	Events    []LogEventData          `json:"events"`
+}
+
+// DiagnosticsDatabaseData is the schema version of the database and the number of rows in each table.
+type DiagnosticsDatabaseData struct {
+	SchemaVersion int            `json:"schema_version"`
+	LatestVersion int            `json:"latest_version"`
+	RowCounts     map[string]int `json:"row_counts"`
+}
+
+// DiagnosticsClusterData counts the hosts of the cluster by state and its current and future reservations.
+type DiagnosticsClusterData struct {
+	Name               string         `json:"name"`
+	Hosts              int            `json:"hosts"`
+	HostStates         map[string]int `json:"host_states"`
+	ActiveReservations int            `json:"active_reservations"`
+	FutureReservations int            `json:"future_reservations"`
+}
+
+// DiagnosticsRuntimeData is a snapshot of the Go runtime of the server.
+type DiagnosticsRuntimeData struct {
+	GoVersion  string `json:"go_version"`
+	Uptime     string `json:"uptime"`
+	Goroutines int    `json:"goroutines"`
+	GoMaxProcs int    `json:"gomaxprocs"`
+	HeapAlloc  uint64 `json:"heap_alloc"`
+	HeapSys    uint64 `json:"heap_sys"`
+	HeapObjs   uint64 `json:"heap_objects"`
+	NumGC      uint32 `json:"num_gc"`
+}
+
+// LogEventData describes an event the server writes to its log, with the level it is logged at and
+// the fields it carries.
+type LogEventData struct {
+	Name        string   `json:"name"`
+	Level       string   `json:"level"`
+	Description string   `json:"description"`
+	Fields      []string `json:"fields"`
+}
+
+// AvailabilityData summarizes how the node-hours of the cluster are used over a span of time split
+// into buckets, as seen by a user making a reservation with the given group.
+type AvailabilityData struct {
+	Group       string               `json:"group"`
+	Start       int64                `json:"start"`
+	End         int64                `json:"end"`
+	BucketSize  string               `json:"bucketSize"`
+	Hosts       int                  `json:"hosts"`
+	GeneratedAt int64                `json:"generatedAt"`
+	Buckets     []AvailabilityBucket `json:"buckets"`
+}
+
+// AvailabilityBucket holds the node-hours in each category for one bucket of time. Only the part of
+// a bucket that is still in the future is counted.
+type AvailabilityBucket struct {
+	Start               int64   `json:"start"`
+	End                 int64   `json:"end"`
+	FreeNodeHours       float64 `json:"freeNodeHours"`
+	ReservedNodeHours   float64 `json:"reservedNodeHours"`
+	BlockedNodeHours    float64 `json:"blockedNodeHours"`
+	RestrictedNodeHours float64 `json:"restrictedNodeHours"`
+}
+
+// ResAdviceData is what igor would do with a reservation request if it were made now. Valid is set
+// when the request could be created as given; otherwise Problem says why not and Missing lists the
+// required parameters not given yet. The schedule fields are filled in whenever the nodes could be
+// planned, and the choices are the distros, profiles and groups the owner can use.
+type ResAdviceData struct {
+	Valid   bool     `json:"valid"`
+	Problem string   `json:"problem,omitempty"`
+	Missing []string `json:"missing,omitempty"`
+	// Message notes the defaults used, any shortening of the end time and any approval needed
+	Message   string `json:"message,omitempty"`
+	Start     int64  `json:"start,omitempty"`
+	End       int64  `json:"end,omitempty"`
+	NodeCount int    `json:"nodeCount,omitempty"`
+	HostRange string `json:"hostRange,omitempty"`
+	// KernelLine is the kernel args the reservation would boot with, including the owner's defaults
+	KernelLine string `json:"kernelLine,omitempty"`
+	// AvailableUntil is when the next reservation on the planned nodes starts, or the end of the schedule
+	AvailableUntil int64 `json:"availableUntil,omitempty"`
+	// NodesFree is how many nodes are free at the start when fewer than needed are
+	NodesFree      int   `json:"nodesFree,omitempty"`
+	SuggestedStart int64 `json:"suggestedStart,omitempty"`
+	// MaxDuration is the longest reservation allowed on the nodes, empty if there is no limit
+	MaxDuration string   `json:"maxDuration,omitempty"`
+	Distros     []string `json:"distros"`
+	Profiles    []string `json:"profiles"`
+	Groups      []string `json:"groups"`
+}
+
+// VlanData describes the VLAN used by a reservation along with the range its group allows.
+type VlanData struct {
+	Vlan         int    `json:"vlan"`
+	Reservation  string `json:"reservation"`
+	Owner        string `json:"owner"`
+	Group        string `json:"group"`
+	AllowedRange string `json:"allowedRange"`
+	// Violation explains why the VLAN is outside the allowed range, if it is
+	Violation string `json:"violation,omitempty"`
+	// Network is the named network that holds the VLAN, if any. Entries listing the named networks
+	// themselves have no Reservation and Owner is the user who made the network.
+	Network string `json:"network,omitempty"`
+	// NetProfile is the network profile applied to the reservation's ports along with the VLAN, if any
+	NetProfile string `json:"netProfile,omitempty"`
+}
+
+// HostEditResult is the outcome of editing one host when a host edit is applied to several hosts.
+type HostEditResult struct {
+	Host   string `json:"host"`
+	Result string `json:"result"` // changed, unchanged or error
+	Error  string `json:"error,omitempty"`
+}
+
+// Outcomes of one item of an operation on many resources
+const (
+	BatchItemOK     = "ok"
+	BatchItemFailed = "failed"
+)
+
+// BatchItemResult is the outcome for one item of an operation on many resources, such as one host of
+// a block or policy apply, or one user added to a group.
+type BatchItemResult struct {
+	Name    string `json:"name"`
+	Result  string `json:"result"` // ok or failed
+	Message string `json:"message,omitempty"`
+}
+
+// CountBatchResults returns how many items of an operation on many resources succeeded and failed.
+func CountBatchResults(results []BatchItemResult) (ok, failed int) {
+	for _, r := range results {
+		if r.Result == BatchItemOK {
+			ok++
+		} else {
+			failed++
+		}
+	}
+	return
+}
+
+// ImageFetchData describes an image file the server downloaded to register an image. File is the
+// name the file is stored under in the image.
+type ImageFetchData struct {
+	URL    string `json:"url"`
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+}
+
+// ReimageHostResult is the outcome of reimaging one host of a reservation.
+type ReimageHostResult struct {
+	Host   string `json:"host"`
+	Result string `json:"result"` // installed, cycled or error
+	Error  string `json:"error,omitempty"`
+}
+
+// HostRejectionData is a host named in a reservation request that can't be used and the first
+// reason why. The Res fields are set when the reason is another reservation on the host.
+type HostRejectionData struct {
+	Host     string `json:"host"`
+	Reason   string `json:"reason"`
+	ResName  string `json:"resName,omitempty"`
+	ResStart int64  `json:"resStart,omitempty"`
+	ResEnd   int64  `json:"resEnd,omitempty"`
+}
+
+// HostExplainData describes whether a user can reserve a host during a time window and
+// the outcome of each scheduling check that was evaluated to decide it.
+type HostExplainData struct {
+	Host         string            `json:"host"`
+	User         string            `json:"user"`
+	Elevated     bool              `json:"elevated"`
+	Start        int64             `json:"start"`
+	End          int64             `json:"end"`
+	Allowed      bool              `json:"allowed"`
+	FirstFailure string            `json:"firstFailure,omitempty"`
+	Gates        []HostExplainGate `json:"gates"`
+}
+
+// HostExplainGate is the result of one scheduling check made by host explain.
+type HostExplainGate struct {
+	Name   string `json:"name"`
+	Pass   bool   `json:"pass"`
+	Reason string `json:"reason"`
+}
+
+// DistroAccessLossData is a running or future reservation whose owner lost access to its distro
+// when groups were removed from the distro. Notified is set if the owner was emailed about it.
+type DistroAccessLossData struct {
+	Reservation string `json:"reservation"`
+	Owner       string `json:"owner"`
+	Start       int64  `json:"start"`
+	End         int64  `json:"end"`
+	Notified    bool   `json:"notified"`
+}
+
+// HostOccupancyData is a reservation that held a host during the window of a host history query.
+// Start and End are when the reservation actually held the host, so End is when it was deleted or
+// the host was dropped if that came before the scheduled end. Outcome is the last history status of
+// a reservation that is over, otherwise it is blank.
+type HostOccupancyData struct {
+	Reservation string `json:"reservation"`
+	Owner       string `json:"owner"`
+	Group       string `json:"group"`
+	Profile     string `json:"profile"`
+	Distro      string `json:"distro"`
+	Start       int64  `json:"start"`
+	End         int64  `json:"end"`
+	Outcome     string `json:"outcome,omitempty"`
 }
 
 type StatsData struct {
@@ -146,6 +779,36 @@ type StatsData struct {
 	Records []ResHistory            `json:"records"`
 	ByUser  map[string]ResStatCount `json:"by_user"`
 	Global  ResStatCount            `json:"global"`
+	// ByMonth compares the node-hours asked for with those granted, keyed by the month reservations
+	// started in (ex. 2023-04)
+	ByMonth map[string]ResDemandCount `json:"by_month"`
+	// ImageStore is the disk space used by the image files of each distro
+	ImageStore ImageStoreUsage `json:"image_store"`
+	// Tasks is the schedule and last run of each periodic server task
+	Tasks []ManagerTaskData `json:"tasks"`
+}
+
+// ManagerTaskData is the schedule of a periodic server task and how its last run went. LastResult is
+// BatchItemOK or BatchItemFailed, or blank if the task hasn't run since the server started.
+type ManagerTaskData struct {
+	Name           string `json:"name"`
+	Enabled        bool   `json:"enabled"`
+	Interval       string `json:"interval"`
+	Jitter         string `json:"jitter"`
+	LastRun        int64  `json:"last_run"`
+	LastDurationMs int64  `json:"last_duration_ms"`
+	LastResult     string `json:"last_result"`
+	LastError      string `json:"last_error,omitempty"`
+	NextRun        int64  `json:"next_run"`
+	Runs           int    `json:"runs"`
+	Failures       int    `json:"failures"`
+}
+
+// ImageStoreUsage reports the bytes of image files in the image store. Distros sharing an image each
+// list its full size but it is only counted once in the total.
+type ImageStoreUsage struct {
+	ByDistro   map[string]int64 `json:"by_distro"`
+	TotalBytes int64            `json:"total_bytes"`
 }
 
 // ScheduleBlock contains 2 variables:
@@ -170,6 +833,7 @@ type ResHistory struct {
 	Status      string
 	Name        string
 	Owner       string
+	CreatedBy   string
 	ResGroup    string
 	Profile     string
 	Distro      string
@@ -179,6 +843,21 @@ type ResHistory struct {
 	OrigEnd     time.Time
 	ExtendCount int
 	Hosts       string
+	// ReqDuration, ReqNodeCount and Request describe what was asked for when the reservation was created
+	ReqDuration  time.Duration
+	ReqNodeCount int
+	Request      string
+	// EndPower is "leave-on" if the reservation's hosts were left powered on when it ended
+	EndPower string
+}
+
+// ResDemandCount totals the node-hours reservations asked for and were granted when created. Reservations
+// made before requests were recorded are not counted.
+type ResDemandCount struct {
+	ResCount           int
+	ClampedCount       int
+	RequestedNodeHours float64
+	GrantedNodeHours   float64
 }
 
 // ResStatCount is used to count aspects of reservations either globally or by user.
@@ -191,3 +870,53 @@ type ResStatCount struct {
 	TotalResTime   time.Duration
 	Entries        []ResHistory
 }
+
+// DistroCopyData describes a distro created as a copy of another. It lists the attributes inherited from the
+// source distro and those given when the copy was made.
+type DistroCopyData struct {
+	Source    string   `json:"source"`
+	Inherited []string `json:"inherited"`
+	Specified []string `json:"specified,omitempty"`
+}
+
+// LdapGroupSyncData lists the changes the LDAP group sync makes, or would make, to one LDAP-synced
+// group. Membership and ownership changes are reported separately.
+type LdapGroupSyncData struct {
+	AddMembers []string `json:"addMembers,omitempty"`
+	RmvMembers []string `json:"rmvMembers,omitempty"`
+	AddOwners  []string `json:"addOwners,omitempty"`
+	RmvOwners  []string `json:"rmvOwners,omitempty"`
+	// NewAccounts are LDAP owners without an igor account that are created so they can own the group
+	NewAccounts []string `json:"newAccounts,omitempty"`
+	// OwnerFallback is set when none of the LDAP owners can own the group so igor-admin owns it
+	OwnerFallback bool   `json:"ownerFallback,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// LdapUserSyncData lists the account changes the LDAP user sync makes, or would make. Users no longer in
+// LDAP who own reservations are held in pending removal so the members of their reservation groups can
+// claim them before the account is removed.
+type LdapUserSyncData struct {
+	// NewAccounts are LDAP users without an igor account that are created
+	NewAccounts []string `json:"newAccounts,omitempty"`
+	// Remove are accounts removed now, including those whose removal grace period has ended
+	Remove []string `json:"remove,omitempty"`
+	// PendingRemoval are accounts entering pending removal because they own reservations
+	PendingRemoval []string `json:"pendingRemoval,omitempty"`
+	// StillPending maps accounts already pending removal to the time (unix secs) their grace period ends
+	StillPending map[string]int64 `json:"stillPending,omitempty"`
+	// Restored are accounts pending removal that are back in LDAP and are kept
+	Restored  []string `json:"restored,omitempty"`
+	GraceDays int      `json:"graceDays"`
+}
+
+// OidcDeviceData is what a client needs to finish an OIDC device code login. The user signs in at
+// VerificationURI with UserCode while the client polls with DeviceCode every Interval seconds.
+type OidcDeviceData struct {
+	DeviceCode              string `json:"deviceCode"`
+	UserCode                string `json:"userCode"`
+	VerificationURI         string `json:"verificationUri"`
+	VerificationURIComplete string `json:"verificationUriComplete,omitempty"`
+	ExpiresIn               int    `json:"expiresIn"`
+	Interval                int    `json:"interval"`
+}
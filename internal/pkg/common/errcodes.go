@@ -0,0 +1,134 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package common
+
+import "net/http"
+
+// Error codes attached to every failed API response. Codes are stable and safe for scripts to
+// branch on; the wording of the accompanying message is not. New codes may be added but existing
+// ones must never be renamed or reused for a different cause.
+const (
+	ErrGeneric            = "ERR_GENERIC"
+	ErrBadRequest         = "ERR_BAD_REQUEST"
+	ErrBadParamType       = "ERR_BAD_PARAM_TYPE"
+	ErrUnknownParam       = "ERR_UNKNOWN_PARAM"
+	ErrMissingParam       = "ERR_MISSING_PARAM"
+	ErrNameRule           = "ERR_NAME_RULE"
+	ErrAuth               = "ERR_AUTH"
+	ErrForbidden          = "ERR_FORBIDDEN"
+	ErrElevateRequired    = "ERR_ELEVATE_REQUIRED"
+	ErrNotGroupMember     = "ERR_NOT_GROUP_MEMBER"
+	ErrNotFound           = "ERR_NOT_FOUND"
+	ErrConflict           = "ERR_CONFLICT"
+	ErrNameTaken          = "ERR_NAME_TAKEN"
+	ErrOwnsResources      = "ERR_OWNS_RESOURCES"
+	ErrResConflict        = "ERR_RES_CONFLICT"
+	ErrPolicyGroup        = "ERR_POLICY_GROUP"
+	ErrPolicyDuration     = "ERR_POLICY_DURATION"
+	ErrPolicySchedule     = "ERR_POLICY_SCHEDULE"
+	ErrPolicyUserCap      = "ERR_POLICY_USER_CAP"
+	ErrNodeLimit          = "ERR_NODE_LIMIT"
+	ErrRateLimit          = "ERR_RATE_LIMIT"
+	ErrUnsupportedMedia   = "ERR_UNSUPPORTED_MEDIA"
+	ErrBodyTooLarge       = "ERR_BODY_TOO_LARGE"
+	ErrInternal           = "ERR_INTERNAL"
+	ErrServiceUnavailable = "ERR_SERVICE_UNAVAILABLE"
+	ErrPartial            = "ERR_PARTIAL"
+)
+
+// ErrorCodeInfo describes an error code: the HTTP status it is usually sent with, the exit code the
+// CLI uses when it receives it and what it means.
+type ErrorCodeInfo struct {
+	Code        string `json:"code"`
+	Status      int    `json:"status"`
+	ExitCode    int    `json:"exitCode"`
+	Description string `json:"description"`
+}
+
+// errorCodes is the registry of all codes the server can send. It is the source for the GET /errors
+// endpoint so it must be kept in sync with the constants above.
+var errorCodes = []ErrorCodeInfo{
+	{ErrGeneric, 0, 1, "an unclassified failure; the message has the details"},
+	{ErrBadRequest, http.StatusBadRequest, 2, "the request was malformed or a parameter value was invalid"},
+	{ErrBadParamType, http.StatusBadRequest, 2, "a parameter was not of the expected type"},
+	{ErrUnknownParam, http.StatusBadRequest, 2, "a parameter was not recognized for this request"},
+	{ErrMissingParam, http.StatusBadRequest, 2, "a required parameter was not included"},
+	{ErrNameRule, http.StatusBadRequest, 2, "a name broke one of the naming rules"},
+	{ErrAuth, http.StatusUnauthorized, 3, "the user could not be authenticated"},
+	{ErrForbidden, http.StatusForbidden, 4, "the user does not have permission to perform the action"},
+	{ErrElevateRequired, http.StatusForbidden, 4, "the action is only available to an admin with elevated privileges"},
+	{ErrNotGroupMember, http.StatusForbidden, 4, "the user is not a member of a group the action requires"},
+	{ErrNotFound, http.StatusNotFound, 5, "the requested resource does not exist"},
+	{ErrConflict, http.StatusConflict, 6, "the action conflicts with the current state of the resource"},
+	{ErrNameTaken, http.StatusConflict, 6, "a resource with the same name already exists"},
+	{ErrOwnsResources, http.StatusConflict, 6, "the user owns reservations, groups or distros that must be transferred before the user is deleted"},
+	{ErrResConflict, http.StatusConflict, 7, "not enough hosts are available for the requested time"},
+	{ErrPolicyGroup, http.StatusConflict, 8, "a host policy restricts the requested hosts to groups the user isn't in"},
+	{ErrPolicyDuration, http.StatusConflict, 8, "the reservation is longer than a host policy allows"},
+	{ErrPolicySchedule, http.StatusConflict, 8, "a host policy blocks the requested hosts during the requested time"},
+	{ErrPolicyUserCap, http.StatusConflict, 8, "a host policy caps how many of its hosts one user can hold at once"},
+	{ErrNodeLimit, http.StatusBadRequest, 9, "the reservation asks for more nodes than the user may reserve"},
+	{ErrRateLimit, http.StatusTooManyRequests, 10, "the request was refused because too many were made recently"},
+	{ErrUnsupportedMedia, http.StatusUnsupportedMediaType, 2, "the request body was not of a supported content type"},
+	{ErrBodyTooLarge, http.StatusRequestEntityTooLarge, 2, "the request body was larger than the server accepts for the request"},
+	{ErrInternal, http.StatusInternalServerError, 20, "the server failed to complete the request"},
+	{ErrServiceUnavailable, http.StatusServiceUnavailable, 21, "the server or a service it depends on is unavailable"},
+	{ErrPartial, http.StatusOK, 11, "some items of an operation on many resources failed; the results give the outcome of each"},
+}
+
+// ErrorCodes returns a copy of the error code registry.
+func ErrorCodes() []ErrorCodeInfo {
+	codes := make([]ErrorCodeInfo, len(errorCodes))
+	copy(codes, errorCodes)
+	return codes
+}
+
+// LookupErrorCode returns the registry entry for the code.
+func LookupErrorCode(code string) (ErrorCodeInfo, bool) {
+	for _, info := range errorCodes {
+		if info.Code == code {
+			return info, true
+		}
+	}
+	return ErrorCodeInfo{}, false
+}
+
+// DefaultErrorCode returns the generic code used for a failed response with the given HTTP status
+// when the code path that produced it didn't set a more specific one.
+func DefaultErrorCode(status int) string {
+	switch {
+	case status == http.StatusUnauthorized:
+		return ErrAuth
+	case status == http.StatusForbidden:
+		return ErrForbidden
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusConflict:
+		return ErrConflict
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimit
+	case status == http.StatusUnsupportedMediaType:
+		return ErrUnsupportedMedia
+	case status == http.StatusRequestEntityTooLarge:
+		return ErrBodyTooLarge
+	case status == http.StatusServiceUnavailable:
+		return ErrServiceUnavailable
+	case status >= http.StatusInternalServerError:
+		return ErrInternal
+	case status >= http.StatusBadRequest:
+		return ErrBadRequest
+	default:
+		return ErrGeneric
+	}
+}
+
+// ErrorExitCode returns the process exit code the CLI uses for an error code. Unknown codes, such as
+// ones added by a newer server, map to the exit code of ErrGeneric.
+func ErrorExitCode(code string) int {
+	if info, ok := LookupErrorCode(code); ok {
+		return info.ExitCode
+	}
+	return 1
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package common
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorCodeRegistry(t *testing.T) {
+
+	seen := map[string]bool{}
+	for _, info := range ErrorCodes() {
+		assert.Falsef(t, seen[info.Code], "code %s is registered twice", info.Code)
+		seen[info.Code] = true
+		assert.NotEmpty(t, info.Description)
+		assert.NotZero(t, info.ExitCode)
+	}
+
+	// every fallback code must be documented
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+		http.StatusConflict, http.StatusTooManyRequests, http.StatusUnsupportedMediaType, http.StatusRequestEntityTooLarge, http.StatusInternalServerError,
+		http.StatusServiceUnavailable, http.StatusTeapot} {
+		_, ok := LookupErrorCode(DefaultErrorCode(status))
+		assert.Truef(t, ok, "default code for status %d is not registered", status)
+	}
+}
+
+func TestDefaultErrorCode(t *testing.T) {
+	assert.Equal(t, ErrBadRequest, DefaultErrorCode(http.StatusBadRequest))
+	assert.Equal(t, ErrBadRequest, DefaultErrorCode(http.StatusTeapot))
+	assert.Equal(t, ErrNotFound, DefaultErrorCode(http.StatusNotFound))
+	assert.Equal(t, ErrInternal, DefaultErrorCode(http.StatusBadGateway))
+	assert.Equal(t, ErrGeneric, DefaultErrorCode(http.StatusOK))
+}
+
+func TestErrorExitCode(t *testing.T) {
+	assert.Equal(t, 9, ErrorExitCode(ErrNodeLimit))
+	assert.Equal(t, 2, ErrorExitCode(ErrMissingParam))
+	// codes from a newer server fall back to the generic exit status
+	assert.Equal(t, 1, ErrorExitCode("ERR_SOMETHING_NEW"))
+	assert.Equal(t, 1, ErrorExitCode(""))
+}
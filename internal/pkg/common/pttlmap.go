@@ -56,15 +56,19 @@ func (m *PassiveTtlMap) Clear() {
 	m.l.Unlock()
 }
 
-// ClearExpired deletes all entries in the map that have reached their expiration.
-func (m *PassiveTtlMap) ClearExpired() {
+// ClearExpired deletes all entries in the map that have reached their expiration and returns
+// the number of entries deleted.
+func (m *PassiveTtlMap) ClearExpired() int {
 	m.l.Lock()
+	cleared := 0
 	for k := range m.m {
 		if time.Until(m.m[k].expires) <= 0 {
 			delete(m.m, k)
+			cleared++
 		}
 	}
 	m.l.Unlock()
+	return cleared
 }
 
 // Remove deletes the entry in the map with the provided key. If k doesn't exist
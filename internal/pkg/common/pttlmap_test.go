@@ -79,10 +79,10 @@ func TestClear(t *testing.T) {
 	ptm.Put("test-5", true)
 	ptm.Put("test-6", true)
 	time.Sleep(2 * time.Second)
-	ptm.ClearExpired()
+	assert.Equal(t, 3, ptm.ClearExpired(), "should have cleared three members")
 	assert.Equal(t, 3, ptm.Len(), "should have three members")
 	time.Sleep(3 * time.Second)
 	ptm.Put("test-6", true)
-	ptm.ClearExpired()
+	assert.Equal(t, 2, ptm.ClearExpired(), "should have cleared two members")
 	assert.Equal(t, 1, ptm.Len(), "should have one member")
 }
@@ -21,6 +21,8 @@ type ResponseBody interface {
 	SetMessage(msg string)
 	GetMessage() string
 	GetStatus() string
+	SetErrorCode(code string)
+	GetErrorCode() string
 }
 
 // ResponseBodyBase contains the fields that are common to all structs
@@ -29,6 +31,8 @@ type ResponseBodyBase struct {
 	Status     string `json:"status"`
 	Message    string `json:"message"`
 	ServerTime string `json:"serverTime"`
+	// ErrorCode is a stable code identifying the cause of a failed request; see ErrorCodes
+	ErrorCode string `json:"errorCode,omitempty"`
 }
 
 func NewResponseBodyBase() ResponseBodyBase {
@@ -73,6 +77,14 @@ func getStatus(base *ResponseBodyBase) string {
 	return base.Status
 }
 
+func setErrorCode(base *ResponseBodyBase, code string) {
+	base.ErrorCode = code
+}
+
+func getErrorCode(base *ResponseBodyBase) string {
+	return base.ErrorCode
+}
+
 // ResponseBodyBasic casts its Data field as a map[string]interface{}.
 type ResponseBodyBasic struct {
 	ResponseBodyBase
@@ -116,6 +128,14 @@ func (rb *ResponseBodyBasic) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyBasic) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyBasic) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodyClusters casts its Data field as an array of ClusterData.
 type ResponseBodyClusters struct {
 	ResponseBodyBase
@@ -158,6 +178,14 @@ func (rb *ResponseBodyClusters) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyClusters) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyClusters) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodyHosts casts its Data field as an array of HostData.
 type ResponseBodyHosts struct {
 	ResponseBodyBase
@@ -200,6 +228,14 @@ func (rb *ResponseBodyHosts) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyHosts) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyHosts) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodyShow casts its Data field as ShowData
 type ResponseBodyShow struct {
 	ResponseBodyBase
@@ -242,6 +278,14 @@ func (rb *ResponseBodyShow) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyShow) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyShow) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodyUsers casts its Data field as UserData
 type ResponseBodyUsers struct {
 	ResponseBodyBase
@@ -284,6 +328,14 @@ func (rb *ResponseBodyUsers) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyUsers) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyUsers) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodyGroups casts its Data field as GroupData
 type ResponseBodyGroups struct {
 	ResponseBodyBase
@@ -328,6 +380,14 @@ func (rb *ResponseBodyGroups) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyGroups) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyGroups) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodyPolicies casts its Data field as HostPolicyData
 type ResponseBodyPolicies struct {
 	ResponseBodyBase
@@ -370,6 +430,14 @@ func (rb *ResponseBodyPolicies) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyPolicies) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyPolicies) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodyImages casts its Data field as DistroData
 type ResponseBodyImages struct {
 	ResponseBodyBase
@@ -412,6 +480,14 @@ func (rb *ResponseBodyImages) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyImages) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyImages) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodyKickstarts casts its Data field as KickstartData
 type ResponseBodyKickstarts struct {
 	ResponseBodyBase
@@ -454,6 +530,14 @@ func (rb *ResponseBodyKickstarts) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyKickstarts) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyKickstarts) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodyDistros casts its Data field as DistroData
 type ResponseBodyDistros struct {
 	ResponseBodyBase
@@ -496,6 +580,14 @@ func (rb *ResponseBodyDistros) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyDistros) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyDistros) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodyProfiles casts its Data field as ProfileData
 type ResponseBodyProfiles struct {
 	ResponseBodyBase
@@ -538,6 +630,14 @@ func (rb *ResponseBodyProfiles) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyProfiles) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyProfiles) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodyReservations casts its Data field as ReservationData
 type ResponseBodyReservations struct {
 	ResponseBodyBase
@@ -580,6 +680,14 @@ func (rb *ResponseBodyReservations) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyReservations) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyReservations) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodyStats casts its Data field as StatsData
 type ResponseBodyStats struct {
 	ResponseBodyBase
@@ -622,6 +730,14 @@ func (rb *ResponseBodyStats) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
 
+func (rb *ResponseBodyStats) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyStats) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
 // ResponseBodySync casts its Data field as StatsData
 type ResponseBodySync struct {
 	ResponseBodyBase
@@ -663,3 +779,1011 @@ func (rb *ResponseBodySync) GetMessage() string {
 func (rb *ResponseBodySync) GetStatus() string {
 	return getStatus(&rb.ResponseBodyBase)
 }
+
+func (rb *ResponseBodySync) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodySync) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyBackup casts its Data field as BackupData
+type ResponseBodyBackup struct {
+	ResponseBodyBase
+	Data map[string]BackupData `json:"data"`
+}
+
+func NewResponseBodyBackup() *ResponseBodyBackup {
+	response := &ResponseBodyBackup{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string]BackupData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyBackup) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyBackup) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyBackup) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyBackup) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyBackup) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyBackup) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyBackup) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyBackup) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyBackup) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyHooks casts its Data field as a list of HookStatusData
+type ResponseBodyHooks struct {
+	ResponseBodyBase
+	Data map[string][]HookStatusData `json:"data"`
+}
+
+func NewResponseBodyHooks() *ResponseBodyHooks {
+	response := &ResponseBodyHooks{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string][]HookStatusData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyHooks) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyHooks) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHooks) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHooks) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHooks) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyHooks) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHooks) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHooks) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyHooks) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodySessions casts its Data field as a list of AuthSessionData
+type ResponseBodySessions struct {
+	ResponseBodyBase
+	Data map[string][]AuthSessionData `json:"data"`
+}
+
+func NewResponseBodySessions() *ResponseBodySessions {
+	response := &ResponseBodySessions{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string][]AuthSessionData),
+	}
+	return response
+}
+
+func (rb *ResponseBodySessions) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodySessions) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodySessions) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodySessions) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodySessions) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodySessions) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodySessions) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodySessions) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodySessions) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyPxeAudit casts its Data field as a list of PxeAuditData
+type ResponseBodyPxeAudit struct {
+	ResponseBodyBase
+	Data map[string][]PxeAuditData `json:"data"`
+}
+
+func NewResponseBodyPxeAudit() *ResponseBodyPxeAudit {
+	response := &ResponseBodyPxeAudit{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string][]PxeAuditData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyPxeAudit) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyPxeAudit) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyPxeAudit) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyPxeAudit) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyPxeAudit) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyPxeAudit) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyPxeAudit) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyPxeAudit) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyPxeAudit) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyFsck casts its Data field as a list of FsckData
+type ResponseBodyFsck struct {
+	ResponseBodyBase
+	Data map[string][]FsckData `json:"data"`
+}
+
+func NewResponseBodyFsck() *ResponseBodyFsck {
+	response := &ResponseBodyFsck{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string][]FsckData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyFsck) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyFsck) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyFsck) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyFsck) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyFsck) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyFsck) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyFsck) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyFsck) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyFsck) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyDiagnostics casts its Data field as DiagnosticsData
+type ResponseBodyDiagnostics struct {
+	ResponseBodyBase
+	Data map[string]DiagnosticsData `json:"data"`
+}
+
+func NewResponseBodyDiagnostics() *ResponseBodyDiagnostics {
+	response := &ResponseBodyDiagnostics{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string]DiagnosticsData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyDiagnostics) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyDiagnostics) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyDiagnostics) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyDiagnostics) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyDiagnostics) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyDiagnostics) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyDiagnostics) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyDiagnostics) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyDiagnostics) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyClusterChanges casts its Data field as a list of ClusterChangeData
+type ResponseBodyClusterChanges struct {
+	ResponseBodyBase
+	Data map[string][]ClusterChangeData `json:"data"`
+}
+
+func NewResponseBodyClusterChanges() *ResponseBodyClusterChanges {
+	response := &ResponseBodyClusterChanges{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string][]ClusterChangeData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyClusterChanges) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyClusterChanges) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyClusterChanges) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyClusterChanges) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyClusterChanges) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyClusterChanges) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyClusterChanges) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyClusterChanges) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyClusterChanges) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyVlans casts its Data field as a list of VlanData
+type ResponseBodyVlans struct {
+	ResponseBodyBase
+	Data map[string][]VlanData `json:"data"`
+}
+
+func NewResponseBodyVlans() *ResponseBodyVlans {
+	response := &ResponseBodyVlans{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string][]VlanData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyVlans) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyVlans) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyVlans) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyVlans) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyVlans) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyVlans) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyVlans) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyVlans) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyVlans) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyAvailability casts its Data field as AvailabilityData
+type ResponseBodyAvailability struct {
+	ResponseBodyBase
+	Data map[string]AvailabilityData `json:"data"`
+}
+
+func NewResponseBodyAvailability() *ResponseBodyAvailability {
+	response := &ResponseBodyAvailability{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string]AvailabilityData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyAvailability) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyAvailability) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyAvailability) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyAvailability) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyAvailability) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyAvailability) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyAvailability) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyAvailability) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyAvailability) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyResAdvice casts its Data field as ResAdviceData
+type ResponseBodyResAdvice struct {
+	ResponseBodyBase
+	Data map[string]ResAdviceData `json:"data"`
+}
+
+func NewResponseBodyResAdvice() *ResponseBodyResAdvice {
+	response := &ResponseBodyResAdvice{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string]ResAdviceData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyResAdvice) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyResAdvice) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResAdvice) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResAdvice) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResAdvice) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyResAdvice) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResAdvice) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResAdvice) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyResAdvice) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyHostExplain casts its Data field as HostExplainData
+type ResponseBodyHostExplain struct {
+	ResponseBodyBase
+	Data map[string]HostExplainData `json:"data"`
+}
+
+func NewResponseBodyHostExplain() *ResponseBodyHostExplain {
+	response := &ResponseBodyHostExplain{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string]HostExplainData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyHostExplain) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyHostExplain) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostExplain) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostExplain) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostExplain) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyHostExplain) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostExplain) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostExplain) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyHostExplain) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyHostHistory casts its Data field as a list of HostOccupancyData
+type ResponseBodyHostHistory struct {
+	ResponseBodyBase
+	Data map[string][]HostOccupancyData `json:"data"`
+}
+
+func NewResponseBodyHostHistory() *ResponseBodyHostHistory {
+	response := &ResponseBodyHostHistory{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string][]HostOccupancyData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyHostHistory) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyHostHistory) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostHistory) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostHistory) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostHistory) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyHostHistory) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostHistory) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostHistory) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyHostHistory) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyHostEdit casts its Data field as a list of HostEditResult
+type ResponseBodyHostEdit struct {
+	ResponseBodyBase
+	Data map[string][]HostEditResult `json:"data"`
+}
+
+func NewResponseBodyHostEdit() *ResponseBodyHostEdit {
+	response := &ResponseBodyHostEdit{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string][]HostEditResult),
+	}
+	return response
+}
+
+func (rb *ResponseBodyHostEdit) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyHostEdit) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostEdit) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostEdit) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostEdit) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyHostEdit) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostEdit) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyHostEdit) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyHostEdit) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyBatch casts its Data field as a list of BatchItemResult
+type ResponseBodyBatch struct {
+	ResponseBodyBase
+	Data map[string][]BatchItemResult `json:"data"`
+}
+
+func NewResponseBodyBatch() *ResponseBodyBatch {
+	response := &ResponseBodyBatch{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string][]BatchItemResult),
+	}
+	return response
+}
+
+func (rb *ResponseBodyBatch) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyBatch) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyBatch) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyBatch) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyBatch) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyBatch) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyBatch) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyBatch) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyBatch) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyReimage casts its Data field as a list of ReimageHostResult
+type ResponseBodyReimage struct {
+	ResponseBodyBase
+	Data map[string][]ReimageHostResult `json:"data"`
+}
+
+func NewResponseBodyReimage() *ResponseBodyReimage {
+	response := &ResponseBodyReimage{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string][]ReimageHostResult),
+	}
+	return response
+}
+
+func (rb *ResponseBodyReimage) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyReimage) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyReimage) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyReimage) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyReimage) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyReimage) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyReimage) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyReimage) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyReimage) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyResDelete casts its Data field as a ResDeleteData
+type ResponseBodyResDelete struct {
+	ResponseBodyBase
+	Data map[string]ResDeleteData `json:"data"`
+}
+
+func NewResponseBodyResDelete() *ResponseBodyResDelete {
+	response := &ResponseBodyResDelete{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string]ResDeleteData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyResDelete) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyResDelete) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResDelete) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResDelete) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResDelete) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyResDelete) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResDelete) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResDelete) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyResDelete) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyUserDelete casts its Data field as a list of OwnedResourceData
+type ResponseBodyUserDelete struct {
+	ResponseBodyBase
+	Data map[string][]OwnedResourceData `json:"data"`
+}
+
+func NewResponseBodyUserDelete() *ResponseBodyUserDelete {
+	response := &ResponseBodyUserDelete{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string][]OwnedResourceData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyUserDelete) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyUserDelete) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyUserDelete) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyUserDelete) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyUserDelete) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyUserDelete) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyUserDelete) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyUserDelete) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyUserDelete) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyResData casts its Data field as a ResDataData
+type ResponseBodyResData struct {
+	ResponseBodyBase
+	Data map[string]ResDataData `json:"data"`
+}
+
+func NewResponseBodyResData() *ResponseBodyResData {
+	response := &ResponseBodyResData{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string]ResDataData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyResData) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyResData) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResData) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResData) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResData) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyResData) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResData) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResData) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyResData) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyResShare casts its Data field as a ResShareLinkData
+type ResponseBodyResShare struct {
+	ResponseBodyBase
+	Data map[string]ResShareLinkData `json:"data"`
+}
+
+func NewResponseBodyResShare() *ResponseBodyResShare {
+	response := &ResponseBodyResShare{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string]ResShareLinkData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyResShare) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyResShare) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResShare) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResShare) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResShare) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyResShare) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResShare) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyResShare) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyResShare) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
+
+// ResponseBodyOidcDevice casts its Data field as an OidcDeviceData
+type ResponseBodyOidcDevice struct {
+	ResponseBodyBase
+	Data map[string]OidcDeviceData `json:"data"`
+}
+
+func NewResponseBodyOidcDevice() *ResponseBodyOidcDevice {
+	response := &ResponseBodyOidcDevice{
+		ResponseBodyBase: NewResponseBodyBase(),
+		Data:             make(map[string]OidcDeviceData),
+	}
+	return response
+}
+
+func (rb *ResponseBodyOidcDevice) SetStatus(httpCode int) {
+	setStatus(&rb.ResponseBodyBase, httpCode)
+}
+
+func (rb *ResponseBodyOidcDevice) IsSuccess() bool {
+	return isSuccess(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyOidcDevice) IsFail() bool {
+	return isFail(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyOidcDevice) IsError() bool {
+	return isError(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyOidcDevice) SetMessage(msg string) {
+	setMessage(&rb.ResponseBodyBase, msg)
+}
+
+func (rb *ResponseBodyOidcDevice) GetMessage() string {
+	return getMessage(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyOidcDevice) GetStatus() string {
+	return getStatus(&rb.ResponseBodyBase)
+}
+
+func (rb *ResponseBodyOidcDevice) SetErrorCode(code string) {
+	setErrorCode(&rb.ResponseBodyBase, code)
+}
+
+func (rb *ResponseBodyOidcDevice) GetErrorCode() string {
+	return getErrorCode(&rb.ResponseBodyBase)
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package common
+
+import "sort"
+
+// RollupReservations totals resList into one ResRollup per key, sorted by descending node
+// count and then by name, along with the total of every reservation.
+func RollupReservations(resList []ReservationData, key func(ReservationData) string) (rollups []ResRollup, total ResRollup) {
+
+	index := map[string]int{}
+	for _, r := range resList {
+		k := key(r)
+		i, ok := index[k]
+		if !ok {
+			i = len(rollups)
+			index[k] = i
+			rollups = append(rollups, ResRollup{Name: k})
+		}
+		addToRollup(&rollups[i], r)
+		addToRollup(&total, r)
+	}
+
+	sort.SliceStable(rollups, func(i, j int) bool {
+		if rollups[i].Nodes != rollups[j].Nodes {
+			return rollups[i].Nodes > rollups[j].Nodes
+		}
+		return rollups[i].Name < rollups[j].Name
+	})
+	return rollups, total
+}
+
+func addToRollup(rollup *ResRollup, r ReservationData) {
+	rollup.Reservations++
+	rollup.Nodes += len(r.Hosts)
+	if rollup.NearestEnd == 0 || r.End < rollup.NearestEnd {
+		rollup.NearestEnd = r.End
+	}
+}
+
+// RollupOwner is the key used to total reservations by owner.
+func RollupOwner(r ReservationData) string { return r.Owner }
+
+// RollupGroup is the key used to total reservations by group.
+func RollupGroup(r ReservationData) string { return r.Group }
+
+// NewShowRollups totals resList by owner and by group.
+func NewShowRollups(resList []ReservationData) ShowRollups {
+	var rollups ShowRollups
+	rollups.ByOwner, rollups.Total = RollupReservations(resList, RollupOwner)
+	rollups.ByGroup, _ = RollupReservations(resList, RollupGroup)
+	return rollups
+}
@@ -0,0 +1,37 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewShowRollups(t *testing.T) {
+
+	resList := []ReservationData{
+		{Name: "a1", Owner: "alice", Group: "blue", End: 300, Hosts: []string{"kn1", "kn2"}},
+		{Name: "b1", Owner: "bob", End: 200, Hosts: []string{"kn3", "kn4", "kn5"}},
+		{Name: "a2", Owner: "alice", Group: "red", End: 100, Hosts: []string{"kn6", "kn7"}},
+		{Name: "c1", Owner: "carol", Group: "blue", End: 400, Hosts: []string{"kn8"}},
+	}
+
+	rollups := NewShowRollups(resList)
+	assert.Equal(t, []ResRollup{
+		{Name: "alice", Reservations: 2, Nodes: 4, NearestEnd: 100},
+		{Name: "bob", Reservations: 1, Nodes: 3, NearestEnd: 200},
+		{Name: "carol", Reservations: 1, Nodes: 1, NearestEnd: 400},
+	}, rollups.ByOwner)
+	assert.Equal(t, []ResRollup{
+		{Name: "", Reservations: 1, Nodes: 3, NearestEnd: 200},
+		{Name: "blue", Reservations: 2, Nodes: 3, NearestEnd: 300},
+		{Name: "red", Reservations: 1, Nodes: 2, NearestEnd: 100},
+	}, rollups.ByGroup)
+	assert.Equal(t, ResRollup{Reservations: 4, Nodes: 8, NearestEnd: 100}, rollups.Total)
+
+	rollups = NewShowRollups(nil)
+	assert.Empty(t, rollups.ByOwner)
+	assert.Equal(t, ResRollup{}, rollups.Total)
+}
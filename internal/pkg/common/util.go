@@ -25,7 +25,12 @@ const (
 	DateTimeServerFormat   = "Jan 2 2006 15:04:05 MST(-07:00)"
 	DateTimeEmailFormat    = "January 2, 2006 - 3:04 PM MST"
 
-	IgorRefreshHeader = "X-Igor-Refresh"
+	IgorRefreshHeader   = "X-Igor-Refresh"
+	IdempotencyHeader   = "Idempotency-Key"
+	IgorRequestIDHeader = "X-Igor-Request-Id"
+
+	ServerVersionHeader    = "X-Igor-Server-Version"
+	MinClientVersionHeader = "X-Igor-Min-Client-Version"
 
 	Authorization = "Authorization"
 	ContentLength = "Content-Length"
@@ -6,6 +6,7 @@ package common
 
 import (
 	"fmt"
+	"regexp"
 	"runtime"
 	"runtime/debug"
 	"strconv"
@@ -72,3 +73,59 @@ func GetVersion(appName string, asLog bool) string {
 
 	return strings.TrimSuffix(buildInfo, "\n")
 }
+
+// SemVer holds the major, minor and patch numbers of an igor release.
+type SemVer struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+var semVerRegex = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseSemVer reads the semantic version at the start of s. A leading 'v' and anything after the
+// version, such as the commit count and hash of a git describe tag (v2.1.0-4-g1a2b3c4-dirty), are
+// ignored. The patch number is optional.
+func ParseSemVer(s string) (SemVer, error) {
+	m := semVerRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return SemVer{}, fmt.Errorf("'%s' is not a semantic version (ex. 2.1.0)", s)
+	}
+	v := SemVer{}
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+func (v SemVer) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 if v is older than, the same as or newer than o.
+func (v SemVer) Compare(o SemVer) int {
+	a := []int{v.Major, v.Minor, v.Patch}
+	b := []int{o.Major, o.Minor, o.Patch}
+	for i := range a {
+		if a[i] < b[i] {
+			return -1
+		} else if a[i] > b[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// SameMinor returns true if v and o have the same major and minor numbers.
+func (v SemVer) SameMinor(o SemVer) bool {
+	return v.Major == o.Major && v.Minor == o.Minor
+}
+
+// AppSemVer returns the semantic version of the running app taken from the git tag it was built
+// with. ok is false for builds without a release tag, such as those run from an IDE.
+func AppSemVer() (v SemVer, ok bool) {
+	v, err := ParseSemVer(GitTag)
+	return v, err == nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSemVer(t *testing.T) {
+
+	v, err := ParseSemVer("v2.1.3-4-g1a2b3c4-dirty")
+	require.NoError(t, err)
+	assert.Equal(t, SemVer{2, 1, 3}, v)
+
+	v, err = ParseSemVer("2.4")
+	require.NoError(t, err)
+	assert.Equal(t, "2.4.0", v.String())
+
+	for _, bad := range []string{"", "unknown build", "1a2b3c4 (commit hash)", "v2"} {
+		_, err = ParseSemVer(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestSemVerCompare(t *testing.T) {
+
+	assert.Equal(t, -1, SemVer{2, 1, 9}.Compare(SemVer{2, 2, 0}))
+	assert.Equal(t, 0, SemVer{2, 2, 0}.Compare(SemVer{2, 2, 0}))
+	assert.Equal(t, 1, SemVer{3, 0, 0}.Compare(SemVer{2, 9, 9}))
+	assert.True(t, SemVer{2, 2, 0}.SameMinor(SemVer{2, 2, 7}))
+	assert.False(t, SemVer{2, 2, 0}.SameMinor(SemVer{2, 3, 0}))
+}
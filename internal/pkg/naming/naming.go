@@ -0,0 +1,264 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+// Package naming holds the rules for the names of igor resources. The server uses it to validate
+// names on create and edit, and the CLI uses it to check names before they are sent. The rules are
+// also published in the server settings so other clients can check names the same way.
+package naming
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of named resources.
+const (
+	Reservation = "reservation"
+	Group       = "group"
+	Distro      = "distro"
+	Profile     = "profile"
+	Policy      = "policy"
+	// Generic is used for the names of other resources (hosts, kickstarts, etc.)
+	Generic = "name"
+)
+
+// Names of the rules a name can break.
+const (
+	RuleEmpty          = "empty"
+	RuleLength         = "length"
+	RuleFirstChar      = "first-char"
+	RuleLastChar       = "last-char"
+	RuleCharset        = "charset"
+	RuleDigitsPunct    = "digits-punct-only"
+	RuleReservedWord   = "reserved-word"
+	RuleReservedName   = "reserved-name"
+	RuleReservedPrefix = "reserved-prefix"
+)
+
+const (
+	// GroupUserPrefix starts the name of every user-private group (prefix+username).
+	GroupUserPrefix = "u_"
+	// TempProfilePrefix starts the name of profiles made by the server for reservations created from a distro.
+	TempProfilePrefix = "tpf_"
+
+	minLength   = 3
+	maxLength   = 24
+	charset     = `a-zA-Z0-9._-`
+	firstChars  = `a-zA-Z0-9_`
+	lastChars   = `a-zA-Z0-9_`
+	punctuation = "._-"
+)
+
+// reservedWords can't be used as the name of any resource because they would confuse a
+// permission check or an operation.
+var reservedWords = []string{
+	"groups", "users", "clusters", "distros", "hosts", "profiles", "reservations", "hostPolicy",
+	"group", "user", "cluster", "distro", "host", "profile", "reservation",
+}
+
+// Rule describes what makes a legal name for one kind of resource. Names of new resources
+// must also avoid the reserved names and prefixes.
+type Rule struct {
+	Kind      string `json:"kind"`
+	MinLength int    `json:"minLength"`
+	MaxLength int    `json:"maxLength"`
+	// Pattern is a regular expression every legal name matches, but it doesn't cover every rule
+	Pattern    string `json:"pattern"`
+	Charset    string `json:"charset"`
+	FirstChars string `json:"firstChars"`
+	LastChars  string `json:"lastChars"`
+	// NotOnly lists characters a name can't be made up of entirely (0-9 plus these)
+	NotOnly          string   `json:"notOnly"`
+	ReservedWords    []string `json:"reservedWords"`
+	ReservedNames    []string `json:"reservedNames,omitempty"`
+	ReservedPrefixes []string `json:"reservedPrefixes,omitempty"`
+	// IgnoreCase is set if reserved names and prefixes are matched without regard to case
+	IgnoreCase bool `json:"ignoreCase"`
+}
+
+var stdPattern = regexp.MustCompile(`^[` + firstChars + `][` + charset + `]{` + fmt.Sprint(minLength-2) + `,` + fmt.Sprint(maxLength-2) + `}[` + lastChars + `]$`)
+
+var rules = map[string]Rule{
+	Reservation: newRule(Reservation, nil, nil, false),
+	Group:       newRule(Group, []string{"all", "none"}, []string{GroupUserPrefix, "admin"}, true),
+	Distro:      newRule(Distro, nil, nil, false),
+	Profile:     newRule(Profile, nil, []string{TempProfilePrefix}, false),
+	Policy:      newRule(Policy, nil, nil, false),
+	Generic:     newRule(Generic, nil, nil, false),
+}
+
+func newRule(kind string, names, prefixes []string, ignoreCase bool) Rule {
+	return Rule{
+		Kind:             kind,
+		MinLength:        minLength,
+		MaxLength:        maxLength,
+		Pattern:          stdPattern.String(),
+		Charset:          charset,
+		FirstChars:       firstChars,
+		LastChars:        lastChars,
+		NotOnly:          punctuation,
+		ReservedWords:    reservedWords,
+		ReservedNames:    names,
+		ReservedPrefixes: prefixes,
+		IgnoreCase:       ignoreCase,
+	}
+}
+
+// Rules returns the naming rules of every kind of resource, keyed by kind.
+func Rules() map[string]Rule {
+	r := make(map[string]Rule, len(rules))
+	for k, v := range rules {
+		r[k] = v
+	}
+	return r
+}
+
+// Kinds returns the kinds of resources that have naming rules, sorted.
+func Kinds() []string {
+	kinds := make([]string, 0, len(rules))
+	for k := range rules {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Error is returned when a name breaks one of its rules. Rule is one of the Rule* constants.
+type Error struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Rule   string `json:"rule"`
+	Detail string `json:"detail"`
+}
+
+func (e *Error) Error() string {
+	if e.Rule == RuleEmpty {
+		return fmt.Sprintf("%s name cannot be empty", e.Kind)
+	}
+	return fmt.Sprintf("'%s' is not a legal %s name (%s): %s", e.Name, e.Kind, e.Rule, e.Detail)
+}
+
+// Check returns an *Error if name is not a legal name for the kind of resource. It does not
+// check reserved names or prefixes, so it can be used on names of resources that already exist.
+// An unknown kind is checked with the Generic rules.
+func Check(kind, name string) error {
+	rule := ruleOf(kind)
+	if err := checkFormat(rule, name); err != nil {
+		return err
+	}
+	for _, w := range rule.ReservedWords {
+		if name == w {
+			return &Error{rule.Kind, name, RuleReservedWord, fmt.Sprintf("'%s' is a restricted word", w)}
+		}
+	}
+	return nil
+}
+
+// CheckNew is like Check but also rejects the names and prefixes reserved for the kind of
+// resource. Use it for the name of a resource being created or renamed.
+func CheckNew(kind, name string) error {
+	if err := Check(kind, name); err != nil {
+		return err
+	}
+	return CheckReserved(kind, name)
+}
+
+// CheckReserved returns an *Error if name is one of the names or starts with one of the
+// prefixes reserved for the kind of resource. The format of the name is not checked.
+func CheckReserved(kind, name string) error {
+	rule := ruleOf(kind)
+	match := name
+	if rule.IgnoreCase {
+		match = strings.ToLower(name)
+	}
+	for _, n := range rule.ReservedNames {
+		if match == n {
+			return &Error{rule.Kind, name, RuleReservedName, fmt.Sprintf("'%s' is reserved", n)}
+		}
+	}
+	for _, p := range rule.ReservedPrefixes {
+		if strings.HasPrefix(match, p) {
+			return &Error{rule.Kind, name, RuleReservedPrefix, fmt.Sprintf("names starting with '%s' are reserved", p)}
+		}
+	}
+	return nil
+}
+
+// CheckPartial returns an *Error if name can't be the start of a legal new name for the kind
+// of resource no matter what is typed after it. An empty name passes.
+func CheckPartial(kind, name string) error {
+	if name == "" {
+		return nil
+	}
+	if err := checkChars(ruleOf(kind), name); err != nil {
+		return err
+	}
+	if err := CheckReserved(kind, name); err != nil {
+		var nErr *Error
+		if errors.As(err, &nErr) && nErr.Rule == RuleReservedPrefix {
+			return err
+		}
+	}
+	return nil
+}
+
+func ruleOf(kind string) Rule {
+	if r, ok := rules[kind]; ok {
+		return r
+	}
+	return rules[Generic]
+}
+
+// checkChars checks the rules a name breaks no matter what is added to the end of it.
+func checkChars(rule Rule, name string) error {
+	if len(name) > rule.MaxLength {
+		return &Error{rule.Kind, name, RuleLength, fmt.Sprintf("must be %d-%d characters", rule.MinLength, rule.MaxLength)}
+	}
+	if !strings.ContainsRune(charsOf(rule.FirstChars), rune(name[0])) {
+		return &Error{rule.Kind, name, RuleFirstChar, "must start with a letter, number or underscore"}
+	}
+	if i := strings.IndexFunc(name, func(c rune) bool { return !strings.ContainsRune(charsOf(rule.Charset), c) }); i >= 0 {
+		return &Error{rule.Kind, name, RuleCharset, fmt.Sprintf("character '%c' is not allowed - only letters, numbers, underscore, dash and dot can be used", []rune(name[i:])[0])}
+	}
+	return nil
+}
+
+func checkFormat(rule Rule, name string) error {
+	if name == "" {
+		return &Error{Kind: rule.Kind, Rule: RuleEmpty, Detail: "cannot be empty"}
+	}
+	if err := checkChars(rule, name); err != nil {
+		return err
+	}
+	if len(name) < rule.MinLength {
+		return &Error{rule.Kind, name, RuleLength, fmt.Sprintf("must be %d-%d characters", rule.MinLength, rule.MaxLength)}
+	}
+	if !strings.ContainsRune(charsOf(rule.LastChars), rune(name[len(name)-1])) {
+		return &Error{rule.Kind, name, RuleLastChar, "must end with a letter, number or underscore"}
+	}
+	isNotDigitOrPunc := func(c rune) bool { return (c < '0' || c > '9') && !strings.ContainsRune(rule.NotOnly, c) }
+	if strings.IndexFunc(name, isNotDigitOrPunc) == -1 {
+		return &Error{rule.Kind, name, RuleDigitsPunct, "cannot be all digits or all punctuation"}
+	}
+	return nil
+}
+
+// charsOf expands a regex character class body such as 'a-zA-Z0-9._-' into the characters it matches.
+func charsOf(class string) string {
+	var b strings.Builder
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			for c := class[i]; c <= class[i+2]; c++ {
+				b.WriteByte(c)
+			}
+			i += 2
+			continue
+		}
+		b.WriteByte(class[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 National Technology & Engineering Solutions of Sandia, LLC (NTESS).
+// Under the terms of Contract DE-NA0003525 with NTESS, the U.S. Government retains
+// certain rights in this software.
+
+package naming
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ruleBroken(t *testing.T, err error) string {
+	if err == nil {
+		return ""
+	}
+	var nErr *Error
+	require.True(t, errors.As(err, &nErr))
+	return nErr.Rule
+}
+
+func TestCheck(t *testing.T) {
+
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{"myres", ""},
+		{"my-res_1.2", ""},
+		{"_res", ""},
+		{"", RuleEmpty},
+		{"ab", RuleLength},
+		{"abcdefghijklmnopqrstuvwxy", RuleLength},
+		{"-res", RuleFirstChar},
+		{".res", RuleFirstChar},
+		{"my res!", RuleCharset},
+		{"grp.", RuleLastChar},
+		{"grp-", RuleLastChar},
+		{"123", RuleDigitsPunct},
+		{"1a2", ""},
+		{"1.2-3", RuleDigitsPunct},
+		{"hosts", RuleReservedWord},
+		{"reservation", RuleReservedWord},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.rule, ruleBroken(t, Check(Reservation, tc.name)), "name '%s'", tc.name)
+	}
+}
+
+func TestCheckNew(t *testing.T) {
+
+	// reserved names only matter to new names
+	assert.NoError(t, Check(Group, "u_alice"))
+	assert.Equal(t, RuleReservedPrefix, ruleBroken(t, CheckNew(Group, "u_alice")))
+	assert.Equal(t, RuleReservedPrefix, ruleBroken(t, CheckNew(Group, "Admins2")))
+	assert.Equal(t, RuleReservedName, ruleBroken(t, CheckNew(Group, "ALL")))
+	assert.NoError(t, CheckNew(Group, "mygroup"))
+
+	assert.Equal(t, RuleReservedPrefix, ruleBroken(t, CheckNew(Profile, "tpf_centos")))
+	// profile prefixes match case
+	assert.NoError(t, CheckNew(Profile, "TPF_centos"))
+
+	// other kinds don't reserve group names
+	assert.NoError(t, CheckNew(Reservation, "admin_res"))
+
+	// format rules are checked first
+	assert.Equal(t, RuleLastChar, ruleBroken(t, CheckNew(Group, "u_a.")))
+}
+
+func TestCheckPartial(t *testing.T) {
+
+	assert.NoError(t, CheckPartial(Group, ""))
+	assert.NoError(t, CheckPartial(Group, "a"))
+	// could still become legal
+	assert.NoError(t, CheckPartial(Group, "grp."))
+	assert.NoError(t, CheckPartial(Group, "al"))
+	assert.Equal(t, RuleFirstChar, ruleBroken(t, CheckPartial(Group, "-")))
+	assert.Equal(t, RuleCharset, ruleBroken(t, CheckPartial(Group, "my g")))
+	assert.Equal(t, RuleReservedPrefix, ruleBroken(t, CheckPartial(Group, "u_")))
+}
+
+func TestRulesPattern(t *testing.T) {
+
+	assert.Equal(t, []string{Distro, Group, Generic, Policy, Profile, Reservation}, Kinds())
+
+	// the published pattern agrees with Check on format
+	rule := Rules()[Reservation]
+	re := regexp.MustCompile(rule.Pattern)
+	for _, n := range []string{"myres", "_a_", "grp.", "ab", "my res", "-ab"} {
+		err := checkFormat(rule, n)
+		assert.Equal(t, err == nil, re.MatchString(n), "name '%s'", n)
+	}
+}
@@ -16,6 +16,8 @@ import UserReservations from "./components/UserReservations.vue";
 import SideMenu from "./components/SideMenu.vue";
 import CreateGroup from "./components/CreateGroup.vue";
 import CreateProfile from "./components/CreateProfile.vue";
+import SharedReservation from "./components/SharedReservation.vue";
+import ExtendReservation from "./components/ExtendReservation.vue";
 
 Vue.use(Router);
 let router = new Router({
@@ -153,6 +155,22 @@ let router = new Router({
         requiresAuth: false,
       },
     },
+    {
+      path: "/share/:token",
+      name: "sharedreservation",
+      component: SharedReservation,
+      meta: {
+        requiresAuth: false,
+      },
+    },
+    {
+      path: "/extend/:token",
+      name: "extendreservation",
+      component: ExtendReservation,
+      meta: {
+        requiresAuth: false,
+      },
+    },
     {
       path: "*",
       name: "NotFound",